@@ -76,6 +76,19 @@ type Backstore interface {
 	// Headers. If assertType is not sequence-forming it can
 	// panic.
 	SequenceMemberAfter(assertType *AssertionType, sequenceKey []string, after, maxFormat int) (SequenceMember, error)
+	// SearchBySnapID returns assertions with the given snap-id
+	// header. It invokes foundCb for each found assertion.
+	// Implementations can use an index for this.
+	SearchBySnapID(assertType *AssertionType, snapID string, foundCb func(Assertion), maxFormat int) error
+}
+
+// removingBackstore is implemented by backstores that support removing
+// assertions.
+type removingBackstore interface {
+	// Remove removes the assertion with the given full primary key,
+	// in all its revisions and formats. If the assertion is not
+	// present it returns a NotFoundError.
+	Remove(assertType *AssertionType, key []string) error
 }
 
 type nullBackstore struct{}
@@ -96,6 +109,10 @@ func (nbs nullBackstore) SequenceMemberAfter(t *AssertionType, kp []string, afte
 	return nil, &NotFoundError{Type: t}
 }
 
+func (nbs nullBackstore) SearchBySnapID(t *AssertionType, snapID string, f func(Assertion), maxFormat int) error {
+	return nil
+}
+
 // keyNotFoundError is returned when the key with a given ID cannot be found.
 type keyNotFoundError struct {
 	msg string
@@ -226,6 +243,10 @@ type RODatabase interface {
 	// (trusted or not) based on arbitrary headers.  It returns a
 	// NotFoundError if no assertion can be found.
 	FindManyPredefined(assertionType *AssertionType, headers map[string]string) ([]Assertion, error)
+	// FindManyBySnapID finds the assertions of the given type with
+	// the given snap-id header. It returns a NotFoundError if no
+	// assertion can be found.
+	FindManyBySnapID(assertionType *AssertionType, snapID string) ([]Assertion, error)
 	// FindSequence finds an assertion for the given headers and after for
 	// a sequence-forming type.
 	// The provided headers must contain a sequence key, i.e. a prefix of
@@ -648,6 +669,55 @@ func (db *Database) FindMany(assertionType *AssertionType, headers map[string]st
 	return db.findMany(db.backstores, assertionType, headers)
 }
 
+// FindManyBySnapID finds the assertions of the given type with the given
+// snap-id header. It returns a NotFoundError if no assertion can be found.
+func (db *Database) FindManyBySnapID(assertionType *AssertionType, snapID string) ([]Assertion, error) {
+	err := checkAssertType(assertionType)
+	if err != nil {
+		return nil, err
+	}
+	res := []Assertion{}
+
+	foundCb := func(assert Assertion) {
+		res = append(res, assert)
+	}
+
+	maxFormat := assertionType.MaxSupportedFormat()
+	for _, bs := range db.backstores {
+		err = bs.SearchBySnapID(assertionType, snapID, foundCb, maxFormat)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, &NotFoundError{Type: assertionType, Headers: map[string]string{"snap-id": snapID}}
+	}
+	return res, nil
+}
+
+// Remove removes from the database the assertion with the given
+// reference, in all its revisions and formats. Predefined assertions
+// cannot be removed. It returns a NotFoundError if the assertion is not
+// present.
+func (db *Database) Remove(ref *Ref) error {
+	err := checkAssertType(ref.Type)
+	if err != nil {
+		return err
+	}
+	if len(ref.PrimaryKey) != len(ref.Type.PrimaryKey) {
+		return fmt.Errorf("cannot remove %s assertion: primary key has unexpected length", ref.Type.Name)
+	}
+	if ref.Type.SequenceForming() {
+		return fmt.Errorf("cannot remove %s assertion: type is sequence-forming", ref.Type.Name)
+	}
+	rbs, ok := db.bs.(removingBackstore)
+	if !ok {
+		return fmt.Errorf("cannot remove assertions with the configured assertion backstore")
+	}
+	return rbs.Remove(ref.Type, ref.PrimaryKey)
+}
+
 // FindManyPrefined finds assertions in the predefined sets (trusted
 // or not) based on arbitrary headers.  It returns a NotFoundError if
 // no assertion can be found.
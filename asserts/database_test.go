@@ -827,6 +827,40 @@ func (safs *signAddFindSuite) TestFindPrimaryLeftOut(c *C) {
 	c.Check(retrieved1, IsNil)
 }
 
+func (safs *signAddFindSuite) TestRemove(c *C) {
+	headers := map[string]interface{}{
+		"authority-id": "canonical",
+		"primary-key":  "a",
+	}
+	a, err := safs.signingDB.Sign(asserts.TestOnlyType, headers, nil, safs.signingKeyID)
+	c.Assert(err, IsNil)
+	err = safs.db.Add(a)
+	c.Assert(err, IsNil)
+
+	err = safs.db.Remove(a.Ref())
+	c.Assert(err, IsNil)
+	_, err = a.Ref().Resolve(safs.db.Find)
+	c.Check(err, FitsTypeOf, &asserts.NotFoundError{})
+
+	err = safs.db.Remove(a.Ref())
+	c.Check(err, FitsTypeOf, &asserts.NotFoundError{})
+
+	// predefined assertions are not removed
+	err = safs.db.Remove(&asserts.Ref{Type: asserts.AccountType, PrimaryKey: []string{"predefined"}})
+	c.Check(err, FitsTypeOf, &asserts.NotFoundError{})
+	_, err = safs.db.Find(asserts.AccountType, map[string]string{"account-id": "predefined"})
+	c.Check(err, IsNil)
+}
+
+func (safs *signAddFindSuite) TestFindManyBySnapIDNotFound(c *C) {
+	res, err := safs.db.FindManyBySnapID(asserts.SnapRevisionType, "snap-id-1")
+	c.Check(res, HasLen, 0)
+	c.Check(err, DeepEquals, &asserts.NotFoundError{
+		Type:    asserts.SnapRevisionType,
+		Headers: map[string]string{"snap-id": "snap-id-1"},
+	})
+}
+
 func (safs *signAddFindSuite) TestFindMany(c *C) {
 	headers := map[string]interface{}{
 		"authority-id": "canonical",
@@ -22,6 +22,8 @@ package asserts
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 )
 
 type fetchProgress int
@@ -47,24 +49,73 @@ type Fetcher interface {
 	Save(Assertion) error
 }
 
+// FetcherOptions carries options for the creation of a Fetcher.
+type FetcherOptions struct {
+	// Workers, when greater than 1, is the maximum number of independent
+	// prerequisites of an assertion retrieved concurrently, retrieve
+	// must then be safe for concurrent use. Assertions are still saved
+	// one at a time, prerequisites before dependent assertions.
+	Workers int
+	// SkipPresent, when set, makes the fetcher consider assertions which
+	// are already in the trusted database as fetched, they are then
+	// neither retrieved nor saved again.
+	SkipPresent bool
+}
+
+type prefetchResult struct {
+	a   Assertion
+	err error
+}
+
 type fetcher struct {
 	db          RODatabase
 	retrieve    func(*Ref) (Assertion, error)
 	retrieveSeq func(*AtSequence) (Assertion, error)
 	save        func(Assertion) error
 
+	workers     int
+	skipPresent bool
+
 	fetched map[string]fetchProgress
+	// prefetched holds the prerequisites retrieved concurrently ahead of
+	// being chased
+	prefetched map[string]prefetchResult
+	// chain holds the assertions being chased, dependent assertions
+	// before their prerequisites
+	chain []chainEntry
+}
+
+type chainEntry struct {
+	key  string
+	desc string
 }
 
 // NewFetcher creates a Fetcher which will use trustedDB to determine trusted assertions,
 // will fetch assertions following prerequisites using retrieve, and then will pass
 // them to save, saving prerequisites before dependent assertions.
 func NewFetcher(trustedDB RODatabase, retrieve func(*Ref) (Assertion, error), save func(Assertion) error) Fetcher {
+	return NewFetcherWithOptions(trustedDB, retrieve, save, nil)
+}
+
+// NewFetcherWithOptions is like NewFetcher but the fetcher behavior can be
+// tuned with opts.
+func NewFetcherWithOptions(trustedDB RODatabase, retrieve func(*Ref) (Assertion, error), save func(Assertion) error, opts *FetcherOptions) Fetcher {
+	return newFetcher(trustedDB, retrieve, nil, save, opts)
+}
+
+func newFetcher(trustedDB RODatabase, retrieve func(*Ref) (Assertion, error), retrieveSeq func(*AtSequence) (Assertion, error), save func(Assertion) error, opts *FetcherOptions) *fetcher {
+	if opts == nil {
+		opts = &FetcherOptions{}
+	}
 	return &fetcher{
-		db:       trustedDB,
-		retrieve: retrieve,
-		save:     save,
-		fetched:  make(map[string]fetchProgress),
+		db:          trustedDB,
+		retrieve:    retrieve,
+		retrieveSeq: retrieveSeq,
+		save:        save,
+		workers:     opts.Workers,
+		skipPresent: opts.SkipPresent,
+		fetched:     make(map[string]fetchProgress),
+		prefetched:  make(map[string]prefetchResult),
 	}
 }
 
@@ -84,13 +135,29 @@ type SequenceFormingFetcher interface {
 // will fetch assertions following prerequisites using retrieve and sequence-forming assertions using retrieveSeq, and then will pass
 // them to save, saving prerequisites before dependent assertions.
 func NewSequenceFormingFetcher(trustedDB RODatabase, retrieve func(*Ref) (Assertion, error), retrieveSeq func(*AtSequence) (Assertion, error), save func(Assertion) error) SequenceFormingFetcher {
-	return &fetcher{
-		db:          trustedDB,
-		retrieve:    retrieve,
-		retrieveSeq: retrieveSeq,
-		save:        save,
-		fetched:     make(map[string]fetchProgress),
+	return NewSequenceFormingFetcherWithOptions(trustedDB, retrieve, retrieveSeq, save, nil)
+}
+
+// NewSequenceFormingFetcherWithOptions is like NewSequenceFormingFetcher but
+// the fetcher behavior can be tuned with opts.
+func NewSequenceFormingFetcherWithOptions(trustedDB RODatabase, retrieve func(*Ref) (Assertion, error), retrieveSeq func(*AtSequence) (Assertion, error), save func(Assertion) error, opts *FetcherOptions) SequenceFormingFetcher {
+	return newFetcher(trustedDB, retrieve, retrieveSeq, save, opts)
+}
+
+// circularError returns an error reporting the cycle of prerequisites
+// which leads back to the assertion with the given key.
+func (f *fetcher) circularError(key, desc string) error {
+	var cycle []string
+	for i := len(f.chain) - 1; i >= 0; i-- {
+		if f.chain[i].key == key {
+			for _, e := range f.chain[i:] {
+				cycle = append(cycle, e.desc)
+			}
+			break
+		}
 	}
+	cycle = append(cycle, desc)
+	return fmt.Errorf("circular assertions are not expected: %s", strings.Join(cycle, " -> "))
 }
 
 func (f *fetcher) wasFetched(ref *Ref) (bool, error) {
@@ -98,19 +165,114 @@ func (f *fetcher) wasFetched(ref *Ref) (bool, error) {
 	case fetchSaved:
 		return true, nil // nothing to do
 	case fetchRetrieved:
-		return false, fmt.Errorf("circular assertions are not expected: %s", ref)
+		return false, f.circularError(ref.Unique(), ref.String())
 	}
 	return false, nil
 }
 
-func (f *fetcher) fetchPrerequisitesAndSave(key string, a Assertion) error {
+// needsFetching returns whether the assertion indicated by ref must be
+// retrieved and saved.
+func (f *fetcher) needsFetching(ref *Ref) (bool, error) {
+	// check if ref points to predefined assertion, in which case
+	// there is nothing to do
+	_, err := ref.Resolve(f.db.FindPredefined)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, &NotFoundError{}) {
+		return false, err
+	}
+	if ok, err := f.wasFetched(ref); err != nil || ok {
+		// if ok is true, then the assertion was fetched and err is nil
+		return false, err
+	}
+	if f.skipPresent {
+		_, err := ref.Resolve(f.db.Find)
+		if err == nil {
+			f.fetched[ref.Unique()] = fetchSaved
+			return false, nil
+		}
+		if !errors.Is(err, &NotFoundError{}) {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// prefetch retrieves concurrently the assertions indicated by refs which
+// need fetching, so that they are not retrieved one by one when chased.
+func (f *fetcher) prefetch(refs []*Ref) {
+	if f.workers <= 1 {
+		return
+	}
+	var toRetrieve []*Ref
+	seen := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		key := ref.Unique()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, ok := f.prefetched[key]; ok {
+			continue
+		}
+		// errors are reported when the assertion is chased
+		if ok, err := f.needsFetching(ref); err != nil || !ok {
+			continue
+		}
+		toRetrieve = append(toRetrieve, ref)
+	}
+	if len(toRetrieve) < 2 {
+		// nothing to gain
+		return
+	}
+
+	results := make([]prefetchResult, len(toRetrieve))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, f.workers)
+	for i, ref := range toRetrieve {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, ref *Ref) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			a, err := f.retrieve(ref)
+			results[i] = prefetchResult{a: a, err: err}
+		}(i, ref)
+	}
+	wg.Wait()
+	for i, ref := range toRetrieve {
+		f.prefetched[ref.Unique()] = results[i]
+	}
+}
+
+func (f *fetcher) retrieveRef(ref *Ref) (Assertion, error) {
+	key := ref.Unique()
+	if res, ok := f.prefetched[key]; ok {
+		delete(f.prefetched, key)
+		return res.a, res.err
+	}
+	return f.retrieve(ref)
+}
+
+func (f *fetcher) fetchPrerequisitesAndSave(key, desc string, a Assertion) error {
 	f.fetched[key] = fetchRetrieved
-	for _, preref := range assertionPrereqs(a) {
+	f.chain = append(f.chain, chainEntry{key: key, desc: desc})
+	defer func() {
+		f.chain = f.chain[:len(f.chain)-1]
+	}()
+
+	prereqs := assertionPrereqs(a)
+	keyRef := accountKeyRef(a.SignKeyID())
+	f.prefetch(append(prereqs[:len(prereqs):len(prereqs)], keyRef))
+	for _, preref := range prereqs {
 		if err := f.Fetch(preref); err != nil {
 			return err
 		}
 	}
-	if err := f.fetchAccountKey(a.SignKeyID()); err != nil {
+	if err := f.Fetch(keyRef); err != nil {
 		return err
 	}
 	if err := f.save(a); err != nil {
@@ -121,27 +283,17 @@ func (f *fetcher) fetchPrerequisitesAndSave(key string, a Assertion) error {
 }
 
 func (f *fetcher) chase(ref *Ref, a Assertion) error {
-	// check if ref points to predefined assertion, in which case
-	// there is nothing to do
-	_, err := ref.Resolve(f.db.FindPredefined)
-	if err == nil {
-		return nil
-	}
-	if !errors.Is(err, &NotFoundError{}) {
-		return err
-	}
-	if ok, err := f.wasFetched(ref); err != nil || ok {
-		// if ok is true, then the assertion was fetched and err is nil
+	if ok, err := f.needsFetching(ref); err != nil || !ok {
 		return err
 	}
 	if a == nil {
-		retrieved, err := f.retrieve(ref)
+		retrieved, err := f.retrieveRef(ref)
 		if err != nil {
 			return err
 		}
 		a = retrieved
 	}
-	return f.fetchPrerequisitesAndSave(ref.Unique(), a)
+	return f.fetchPrerequisitesAndSave(ref.Unique(), ref.String(), a)
 }
 
 // Fetch retrieves the assertion indicated by ref then its prerequisites
@@ -155,7 +307,7 @@ func (f *fetcher) wasSeqFetched(seq *AtSequence) (bool, error) {
 	case fetchSaved:
 		return true, nil // nothing to do
 	case fetchRetrieved:
-		return false, fmt.Errorf("circular assertions are not expected: %s", seq)
+		return false, f.circularError(seq.Unique(), seq.String())
 	}
 	return false, nil
 }
@@ -170,7 +322,7 @@ func (f *fetcher) fetchSequence(seq *AtSequence) error {
 	if err != nil {
 		return err
 	}
-	return f.fetchPrerequisitesAndSave(seq.Unique(), a)
+	return f.fetchPrerequisitesAndSave(seq.Unique(), seq.String(), a)
 }
 
 // FetchSequence retrieves the assertion as indicated by its sequence reference.
@@ -181,13 +333,11 @@ func (f *fetcher) FetchSequence(seq *AtSequence) error {
 	return f.fetchSequence(seq)
 }
 
-// fetchAccountKey behaves like Fetch for the account-key with the given key id.
-func (f *fetcher) fetchAccountKey(keyID string) error {
-	keyRef := &Ref{
+func accountKeyRef(keyID string) *Ref {
+	return &Ref{
 		Type:       AccountKeyType,
 		PrimaryKey: []string{keyID},
 	}
-	return f.Fetch(keyRef)
 }
 
 // Save retrieves the prerequisites of the assertion recursively,
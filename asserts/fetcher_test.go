@@ -22,6 +22,7 @@ package asserts_test
 import (
 	"crypto"
 	"fmt"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/sha3"
@@ -156,7 +157,152 @@ func (s *fetcherSuite) TestFetchCircularReference(c *C) {
 	defer r()
 
 	err = f.Fetch(ref)
-	c.Assert(err, ErrorMatches, `circular assertions are not expected: snap-revision \(tzGsQxT_xJGzbnJ_-25Bbj_8lBHY39c5uUuQWgDTGxAEd0NALdxVaSAD59Pou_Ko;\)`)
+	c.Assert(err, ErrorMatches, `circular assertions are not expected: snap-revision \(tzGsQxT_xJGzbnJ_-25Bbj_8lBHY39c5uUuQWgDTGxAEd0NALdxVaSAD59Pou_Ko;\) -> snap-revision \(tzGsQxT_xJGzbnJ_-25Bbj_8lBHY39c5uUuQWgDTGxAEd0NALdxVaSAD59Pou_Ko;\)`)
+}
+
+func (s *fetcherSuite) TestFetchCircularReferencePath(c *C) {
+	s.prereqSnapAssertions(c, 10)
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.storeSigning.Trusted,
+	})
+	c.Assert(err, IsNil)
+
+	revRef := &asserts.Ref{
+		Type:       asserts.SnapRevisionType,
+		PrimaryKey: []string{makeDigest(10)},
+	}
+	declRef := &asserts.Ref{
+		Type:       asserts.SnapDeclarationType,
+		PrimaryKey: []string{"16", "snap-id-1"},
+	}
+
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		return ref.Resolve(s.storeSigning.Find)
+	}
+
+	f := asserts.NewFetcher(db, retrieve, db.Add)
+
+	// mock that the declaration refers back to the revision
+	r := asserts.MockAssertionPrereqs(func(a asserts.Assertion) []*asserts.Ref {
+		if a.Type() == asserts.SnapRevisionType {
+			return []*asserts.Ref{declRef}
+		}
+		return []*asserts.Ref{revRef}
+	})
+	defer r()
+
+	err = f.Fetch(revRef)
+	c.Assert(err, ErrorMatches, `circular assertions are not expected: snap-revision \(tzGsQxT_xJGzbnJ_-25Bbj_8lBHY39c5uUuQWgDTGxAEd0NALdxVaSAD59Pou_Ko;\) -> snap-declaration \(snap-id-1; series:16\) -> snap-revision \(tzGsQxT_xJGzbnJ_-25Bbj_8lBHY39c5uUuQWgDTGxAEd0NALdxVaSAD59Pou_Ko;\)`)
+}
+
+func (s *fetcherSuite) TestFetchConcurrentPrerequisites(c *C) {
+	s.prereqSnapAssertions(c, 10)
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.storeSigning.Trusted,
+	})
+	c.Assert(err, IsNil)
+
+	ref := &asserts.Ref{
+		Type:       asserts.SnapRevisionType,
+		PrimaryKey: []string{makeDigest(10)},
+	}
+
+	// the snap-declaration, account and account-key prerequisites of
+	// the snap-revision are only returned once all of them are being
+	// retrieved
+	const prereqs = 3
+	var mu sync.Mutex
+	inFlight := 0
+	allInFlight := make(chan struct{})
+	retrieved := make(map[string]int)
+	retrieve := func(r *asserts.Ref) (asserts.Assertion, error) {
+		mu.Lock()
+		retrieved[r.Unique()]++
+		if r.Type != asserts.SnapRevisionType {
+			inFlight++
+			if inFlight == prereqs {
+				close(allInFlight)
+			}
+		}
+		mu.Unlock()
+		if r.Type != asserts.SnapRevisionType {
+			select {
+			case <-allInFlight:
+			case <-time.After(5 * time.Second):
+				return nil, fmt.Errorf("prerequisites not retrieved concurrently")
+			}
+		}
+		return r.Resolve(s.storeSigning.Find)
+	}
+
+	var saved []asserts.Assertion
+	save := func(a asserts.Assertion) error {
+		saved = append(saved, a)
+		return db.Add(a)
+	}
+
+	f := asserts.NewFetcherWithOptions(db, retrieve, save, &asserts.FetcherOptions{Workers: prereqs})
+	err = f.Fetch(ref)
+	c.Assert(err, IsNil)
+
+	// each assertion was retrieved once
+	c.Check(retrieved, HasLen, prereqs+1)
+	for k, n := range retrieved {
+		c.Check(n, Equals, 1, Commentf("%s", k))
+	}
+	// and prerequisites were saved first
+	c.Assert(saved, HasLen, prereqs+1)
+	c.Check(saved[prereqs].Type(), Equals, asserts.SnapRevisionType)
+}
+
+func (s *fetcherSuite) TestFetchSkipPresent(c *C) {
+	s.prereqSnapAssertions(c, 10)
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.storeSigning.Trusted,
+	})
+	c.Assert(err, IsNil)
+
+	// the store key and the developer account are present already
+	c.Assert(db.Add(s.storeSigning.StoreAccountKey("")), IsNil)
+	snapDecl, err := s.storeSigning.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "snap-id-1",
+	})
+	c.Assert(err, IsNil)
+	dev1Acct, err := s.storeSigning.Find(asserts.AccountType, map[string]string{
+		"account-id": snapDecl.(*asserts.SnapDeclaration).PublisherID(),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(db.Add(dev1Acct), IsNil)
+
+	ref := &asserts.Ref{
+		Type:       asserts.SnapRevisionType,
+		PrimaryKey: []string{makeDigest(10)},
+	}
+
+	var retrieved []string
+	retrieve := func(r *asserts.Ref) (asserts.Assertion, error) {
+		retrieved = append(retrieved, r.Type.Name)
+		return r.Resolve(s.storeSigning.Find)
+	}
+	var saved []string
+	save := func(a asserts.Assertion) error {
+		saved = append(saved, a.Type().Name)
+		return db.Add(a)
+	}
+
+	f := asserts.NewFetcherWithOptions(db, retrieve, save, &asserts.FetcherOptions{SkipPresent: true})
+	err = f.Fetch(ref)
+	c.Assert(err, IsNil)
+
+	c.Check(retrieved, DeepEquals, []string{"snap-revision", "snap-declaration"})
+	c.Check(saved, DeepEquals, []string{"snap-declaration", "snap-revision"})
 }
 
 func (s *fetcherSuite) TestSave(c *C) {
@@ -299,7 +445,7 @@ func (s *fetcherSuite) TestFetchSequenceCircularReference(c *C) {
 	defer r()
 
 	err = f.FetchSequence(seq)
-	c.Assert(err, ErrorMatches, `circular assertions are not expected: validation-set \(2; series:16 account-id:can0nical name:base-set\)`)
+	c.Assert(err, ErrorMatches, `circular assertions are not expected: validation-set \(2; series:16 account-id:can0nical name:base-set\) -> validation-set \(2; series:16 account-id:can0nical name:base-set\)`)
 }
 
 func (s *fetcherSuite) TestFetchSequenceMultipleSequencesNotSupported(c *C) {
@@ -25,6 +25,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -38,8 +39,9 @@ const (
 )
 
 type filesystemBackstore struct {
-	top string
-	mu  sync.RWMutex
+	top      string
+	indexTop string
+	mu       sync.RWMutex
 }
 
 // OpenFSBackstore opens a filesystem backed assertions backstore under path.
@@ -49,7 +51,10 @@ func OpenFSBackstore(path string) (Backstore, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &filesystemBackstore{top: top}, nil
+	return &filesystemBackstore{
+		top:      top,
+		indexTop: filepath.Join(path, snapIDIndexRoot),
+	}, nil
 }
 
 // guarantees that result assertion is of the expected type (both in the AssertionType and go type sense)
@@ -176,6 +181,10 @@ func (fsbs *filesystemBackstore) Put(assertType *AssertionType, assert Assertion
 	if formatnum > 0 {
 		activeFn = fmt.Sprintf("active.%d", formatnum)
 	}
+	// update the index first, so that it never misses assertions
+	if err := fsbs.updateIndex(assertType, assert, false); err != nil {
+		return err
+	}
 	diskPrimaryPath := filepath.Join(diskPrimaryPathComps(assertType, primaryPath, activeFn)...)
 	err = atomicWriteEntry(Encode(assert), false, fsbs.top, assertType.Name, diskPrimaryPath)
 	if err != nil {
@@ -319,3 +328,268 @@ func (fsbs *filesystemBackstore) SequenceMemberAfter(assertType *AssertionType,
 
 	return nil, &NotFoundError{Type: assertType}
 }
+
+// secondary indexes by snap-id
+
+const snapIDIndexRoot = "snap-id-index-" + assertionsLayoutVersion
+
+// snapIDIndexedTypes are the assertion types for which the filesystem
+// backstore maintains an index by snap-id.
+var snapIDIndexedTypes = map[*AssertionType]bool{
+	SnapDeclarationType: true,
+	SnapRevisionType:    true,
+}
+
+// The index for a type is made of one entry per snap-id under
+// <index-top>/<type>/by-snap-id/, listing the primary keys of the
+// assertions with that snap-id, one per line. The index is considered
+// only if <index-top>/<type>/complete exists, otherwise it is rebuilt
+// from the assertions on the first search. Entries can list primary keys
+// of assertions which are not present, but never miss any.
+
+func encodeIndexKey(key []string) string {
+	escaped := make([]string, len(key))
+	for i, k := range key {
+		escaped[i] = url.QueryEscape(k)
+	}
+	return strings.Join(escaped, "/")
+}
+
+func decodeIndexKey(line string) ([]string, error) {
+	escaped := strings.Split(line, "/")
+	key := make([]string, len(escaped))
+	for i, e := range escaped {
+		k, err := url.QueryUnescape(e)
+		if err != nil {
+			return nil, err
+		}
+		key[i] = k
+	}
+	return key, nil
+}
+
+func (fsbs *filesystemBackstore) indexComplete(assertType *AssertionType) bool {
+	return entryExists(fsbs.indexTop, assertType.Name, "complete")
+}
+
+func (fsbs *filesystemBackstore) invalidateIndex(assertType *AssertionType) error {
+	err := removeEntry(fsbs.indexTop, assertType.Name, "complete")
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("broken assertion storage, cannot invalidate %s index: %v", assertType.Name, err)
+	}
+	return nil
+}
+
+func (fsbs *filesystemBackstore) readIndexEntry(assertType *AssertionType, snapID string) ([]string, error) {
+	data, err := readEntry(fsbs.indexTop, assertType.Name, "by-snap-id", url.QueryEscape(snapID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func (fsbs *filesystemBackstore) writeIndexEntry(assertType *AssertionType, snapID string, lines []string) error {
+	sort.Strings(lines)
+	var data []byte
+	for _, line := range lines {
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+	return atomicWriteEntry(data, false, fsbs.indexTop, assertType.Name, "by-snap-id", url.QueryEscape(snapID))
+}
+
+// updateIndex adds or removes the given assertion from the index of its
+// type, if it is maintained. Failing that the index is invalidated, to
+// be rebuilt.
+func (fsbs *filesystemBackstore) updateIndex(assertType *AssertionType, assert Assertion, remove bool) error {
+	if !snapIDIndexedTypes[assertType] || !fsbs.indexComplete(assertType) {
+		return nil
+	}
+	snapID := assert.HeaderString("snap-id")
+	line := encodeIndexKey(assert.Ref().PrimaryKey)
+	err := func() error {
+		lines, err := fsbs.readIndexEntry(assertType, snapID)
+		if err != nil {
+			return err
+		}
+		updated := make([]string, 0, len(lines)+1)
+		for _, l := range lines {
+			if l != line {
+				updated = append(updated, l)
+			}
+		}
+		if !remove {
+			updated = append(updated, line)
+		}
+		return fsbs.writeIndexEntry(assertType, snapID, updated)
+	}()
+	if err != nil {
+		return fsbs.invalidateIndex(assertType)
+	}
+	return nil
+}
+
+// rebuildIndex rebuilds the index of the given type from the stored
+// assertions.
+func (fsbs *filesystemBackstore) rebuildIndex(assertType *AssertionType) error {
+	if err := fsbs.invalidateIndex(assertType); err != nil {
+		return err
+	}
+	byID := make(map[string][]string)
+	foundCb := func(a Assertion) {
+		snapID := a.HeaderString("snap-id")
+		byID[snapID] = append(byID[snapID], encodeIndexKey(a.Ref().PrimaryKey))
+	}
+	if err := fsbs.searchAll(assertType, foundCb); err != nil {
+		return err
+	}
+
+	byIDDir := filepath.Join(fsbs.indexTop, assertType.Name, "by-snap-id")
+	if err := os.RemoveAll(byIDDir); err != nil {
+		return fmt.Errorf("broken assertion storage, cannot rebuild %s index: %v", assertType.Name, err)
+	}
+	for snapID, lines := range byID {
+		if err := fsbs.writeIndexEntry(assertType, snapID, lines); err != nil {
+			return fmt.Errorf("broken assertion storage, cannot rebuild %s index: %v", assertType.Name, err)
+		}
+	}
+	if err := atomicWriteEntry(nil, false, fsbs.indexTop, assertType.Name, "complete"); err != nil {
+		return fmt.Errorf("broken assertion storage, cannot rebuild %s index: %v", assertType.Name, err)
+	}
+	return nil
+}
+
+func (fsbs *filesystemBackstore) searchAll(assertType *AssertionType, foundCb func(Assertion)) error {
+	n := len(assertType.PrimaryKey)
+	nopt := len(assertType.OptionalPrimaryKeyDefaults)
+	diskPattern := make([]string, n+1)
+	for i := range assertType.PrimaryKey[:n-nopt] {
+		diskPattern[i] = "*"
+	}
+	pattPos := n - nopt
+	return fsbs.searchOptional(assertType, pattPos, pattPos, pattPos, diskPattern, nil, foundCb, assertType.MaxSupportedFormat())
+}
+
+// errStaleIndex marks an index which does not match the stored
+// assertions
+var errStaleIndex = errors.New("stale index")
+
+func (fsbs *filesystemBackstore) searchIndex(assertType *AssertionType, snapID string, foundCb func(Assertion), maxFormat int) error {
+	lines, err := fsbs.readIndexEntry(assertType, snapID)
+	if err != nil {
+		return errStaleIndex
+	}
+	var found []Assertion
+	for _, line := range lines {
+		key, err := decodeIndexKey(line)
+		if err != nil || len(key) != len(assertType.PrimaryKey) {
+			return errStaleIndex
+		}
+		a, err := fsbs.currentAssertion(assertType, key, maxFormat)
+		if err == errNotFound {
+			// not written or removed after the index was updated,
+			// or only present with a newer format
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if a.HeaderString("snap-id") != snapID {
+			return errStaleIndex
+		}
+		found = append(found, a)
+	}
+	for _, a := range found {
+		foundCb(a)
+	}
+	return nil
+}
+
+// SearchBySnapID returns assertions of the given type with the given
+// snap-id header, using the index of the type if it has one.
+func (fsbs *filesystemBackstore) SearchBySnapID(assertType *AssertionType, snapID string, foundCb func(Assertion), maxFormat int) error {
+	if !snapIDIndexedTypes[assertType] {
+		return fsbs.Search(assertType, map[string]string{"snap-id": snapID}, foundCb, maxFormat)
+	}
+
+	fsbs.mu.RLock()
+	if fsbs.indexComplete(assertType) {
+		err := fsbs.searchIndex(assertType, snapID, foundCb, maxFormat)
+		if err != errStaleIndex {
+			fsbs.mu.RUnlock()
+			return err
+		}
+	}
+	fsbs.mu.RUnlock()
+
+	fsbs.mu.Lock()
+	defer fsbs.mu.Unlock()
+	if !fsbs.indexComplete(assertType) || fsbs.searchIndex(assertType, snapID, func(Assertion) {}, maxFormat) == errStaleIndex {
+		if err := fsbs.rebuildIndex(assertType); err != nil {
+			return err
+		}
+	}
+	err := fsbs.searchIndex(assertType, snapID, foundCb, maxFormat)
+	if err == errStaleIndex {
+		return fmt.Errorf("broken assertion storage, cannot use %s index", assertType.Name)
+	}
+	return err
+}
+
+// Remove removes all the revisions and formats of the assertion with the
+// given primary key.
+func (fsbs *filesystemBackstore) Remove(assertType *AssertionType, key []string) error {
+	if assertType.SequenceForming() {
+		return fmt.Errorf("internal error: Backstore.Remove on sequence-forming assertion type %s", assertType.Name)
+	}
+	if len(key) != len(assertType.PrimaryKey) {
+		return fmt.Errorf("internal error: Backstore.Remove given a key of unexpected length for %q: %v", assertType.Name, key)
+	}
+
+	fsbs.mu.Lock()
+	defer fsbs.mu.Unlock()
+
+	var relpaths []string
+	namesCb := func(names []string) error {
+		relpaths = append(relpaths, names...)
+		return nil
+	}
+	comps := diskPrimaryPathComps(assertType, key, "active*")
+	assertTypeTop := filepath.Join(fsbs.top, assertType.Name)
+	if err := findWildcard(assertTypeTop, comps, 0, namesCb); err != nil {
+		return fmt.Errorf("broken assertion storage, looking for %s: %v", assertType.Name, err)
+	}
+	if len(relpaths) == 0 {
+		return &NotFoundError{Type: assertType}
+	}
+
+	// the assertion is read before its files are removed, to find the
+	// index entry listing it
+	a, err := fsbs.currentAssertion(assertType, key, assertType.MaxSupportedFormat())
+	if err != nil && err != errNotFound {
+		return err
+	}
+
+	for _, relpath := range relpaths {
+		if err := removeEntry(fsbs.top, assertType.Name, relpath); err != nil {
+			return fmt.Errorf("broken assertion storage, cannot remove assertion: %v", err)
+		}
+	}
+
+	// entries for assertions which are not present are tolerated by the
+	// index, so the entry is only dropped once the files are gone, and
+	// only if the assertion could be read
+	if a == nil {
+		return nil
+	}
+	return fsbs.updateIndex(assertType, a, true)
+}
@@ -20,14 +20,18 @@
 package asserts_test
 
 import (
+	"crypto"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/testutil"
 )
 
 type fsBackstoreSuite struct{}
@@ -919,3 +923,126 @@ func (fsbss *fsBackstoreSuite) TestOptionalPrimaryKeysSearchTwoOptional(c *C) {
 	})
 
 }
+
+func makeSnapRevision(c *C, snapID string, rev int) asserts.Assertion {
+	h := crypto.SHA3_384.New()
+	fmt.Fprintf(h, "%s-%d", snapID, rev)
+	digest, err := asserts.EncodeDigest(crypto.SHA3_384, h.Sum(nil))
+	c.Assert(err, IsNil)
+	a, err := asserts.Decode([]byte("type: snap-revision\n" +
+		"authority-id: canonical\n" +
+		"snap-sha3-384: " + digest + "\n" +
+		"snap-id: " + snapID + "\n" +
+		"snap-size: 123\n" +
+		fmt.Sprintf("snap-revision: %d\n", rev) +
+		"developer-id: dev-id1\n" +
+		"timestamp: 2023-01-01T00:00:00Z\n" +
+		"sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij" +
+		"\n\n" +
+		"AXNpZw=="))
+	c.Assert(err, IsNil)
+	return a
+}
+
+func snapRevisionsBySnapID(c *C, bs asserts.Backstore, snapID string) []int {
+	var revs []int
+	foundCb := func(a asserts.Assertion) {
+		revs = append(revs, a.(*asserts.SnapRevision).SnapRevision())
+	}
+	err := bs.SearchBySnapID(asserts.SnapRevisionType, snapID, foundCb, 0)
+	c.Assert(err, IsNil)
+	sort.Ints(revs)
+	return revs
+}
+
+func (fsbss *fsBackstoreSuite) TestSearchBySnapID(c *C) {
+	topDir := filepath.Join(c.MkDir(), "asserts-db")
+	bs, err := asserts.OpenFSBackstore(topDir)
+	c.Assert(err, IsNil)
+
+	c.Assert(bs.Put(asserts.SnapRevisionType, makeSnapRevision(c, "snap-id-1", 1)), IsNil)
+	c.Assert(bs.Put(asserts.SnapRevisionType, makeSnapRevision(c, "snap-id-1", 2)), IsNil)
+	c.Assert(bs.Put(asserts.SnapRevisionType, makeSnapRevision(c, "snap-id-2", 3)), IsNil)
+
+	// the index is built on first use
+	indexDir := filepath.Join(topDir, "snap-id-index-v0", "snap-revision")
+	c.Check(filepath.Join(indexDir, "complete"), testutil.FileAbsent)
+	c.Check(snapRevisionsBySnapID(c, bs, "snap-id-1"), DeepEquals, []int{1, 2})
+	c.Check(filepath.Join(indexDir, "complete"), testutil.FilePresent)
+	c.Check(snapRevisionsBySnapID(c, bs, "snap-id-2"), DeepEquals, []int{3})
+	c.Check(snapRevisionsBySnapID(c, bs, "snap-id-3"), HasLen, 0)
+
+	// and then maintained on Put
+	c.Assert(bs.Put(asserts.SnapRevisionType, makeSnapRevision(c, "snap-id-1", 4)), IsNil)
+	c.Check(snapRevisionsBySnapID(c, bs, "snap-id-1"), DeepEquals, []int{1, 2, 4})
+	c.Check(filepath.Join(indexDir, "by-snap-id", "snap-id-1"), testutil.FileContains, "\n")
+
+	// types without an index are searched
+	var found []asserts.Assertion
+	err = bs.SearchBySnapID(asserts.TestOnlyType, "snap-id-1", func(a asserts.Assertion) {
+		found = append(found, a)
+	}, 0)
+	c.Assert(err, IsNil)
+	c.Check(found, HasLen, 0)
+}
+
+func (fsbss *fsBackstoreSuite) TestSearchBySnapIDRebuildsCorruptedIndex(c *C) {
+	topDir := filepath.Join(c.MkDir(), "asserts-db")
+	bs, err := asserts.OpenFSBackstore(topDir)
+	c.Assert(err, IsNil)
+
+	c.Assert(bs.Put(asserts.SnapRevisionType, makeSnapRevision(c, "snap-id-1", 1)), IsNil)
+	c.Assert(bs.Put(asserts.SnapRevisionType, makeSnapRevision(c, "snap-id-2", 2)), IsNil)
+	c.Check(snapRevisionsBySnapID(c, bs, "snap-id-1"), DeepEquals, []int{1})
+
+	// entries listing assertions of another snap are detected
+	indexDir := filepath.Join(topDir, "snap-id-index-v0", "snap-revision")
+	other, err := os.ReadFile(filepath.Join(indexDir, "by-snap-id", "snap-id-2"))
+	c.Assert(err, IsNil)
+	err = os.WriteFile(filepath.Join(indexDir, "by-snap-id", "snap-id-1"), other, 0644)
+	c.Assert(err, IsNil)
+	c.Check(snapRevisionsBySnapID(c, bs, "snap-id-1"), DeepEquals, []int{1})
+
+	// as are malformed entries
+	err = os.WriteFile(filepath.Join(indexDir, "by-snap-id", "snap-id-2"), []byte("%zz\n"), 0644)
+	c.Assert(err, IsNil)
+	c.Check(snapRevisionsBySnapID(c, bs, "snap-id-2"), DeepEquals, []int{2})
+
+	// an incomplete index is rebuilt
+	err = os.RemoveAll(filepath.Join(indexDir, "by-snap-id"))
+	c.Assert(err, IsNil)
+	err = os.Remove(filepath.Join(indexDir, "complete"))
+	c.Assert(err, IsNil)
+	c.Check(snapRevisionsBySnapID(c, bs, "snap-id-1"), DeepEquals, []int{1})
+	c.Check(snapRevisionsBySnapID(c, bs, "snap-id-2"), DeepEquals, []int{2})
+}
+
+func (fsbss *fsBackstoreSuite) TestRemove(c *C) {
+	topDir := filepath.Join(c.MkDir(), "asserts-db")
+	bs, err := asserts.OpenFSBackstore(topDir)
+	c.Assert(err, IsNil)
+
+	rev1 := makeSnapRevision(c, "snap-id-1", 1)
+	c.Assert(bs.Put(asserts.SnapRevisionType, rev1), IsNil)
+	c.Assert(bs.Put(asserts.SnapRevisionType, makeSnapRevision(c, "snap-id-1", 2)), IsNil)
+	c.Check(snapRevisionsBySnapID(c, bs, "snap-id-1"), DeepEquals, []int{1, 2})
+
+	rbs := bs.(interface {
+		Remove(*asserts.AssertionType, []string) error
+	})
+	err = rbs.Remove(asserts.SnapRevisionType, rev1.Ref().PrimaryKey)
+	c.Assert(err, IsNil)
+
+	_, err = bs.Get(asserts.SnapRevisionType, rev1.Ref().PrimaryKey, 0)
+	c.Check(err, FitsTypeOf, &asserts.NotFoundError{})
+	c.Check(snapRevisionsBySnapID(c, bs, "snap-id-1"), DeepEquals, []int{2})
+	indexDir := filepath.Join(topDir, "snap-id-index-v0", "snap-revision")
+	c.Check(filepath.Join(indexDir, "complete"), testutil.FilePresent)
+	// the entry of the removed assertion is dropped from the index
+	entry, err := os.ReadFile(filepath.Join(indexDir, "by-snap-id", "snap-id-1"))
+	c.Assert(err, IsNil)
+	c.Check(strings.Count(string(entry), "\n"), Equals, 1)
+
+	err = rbs.Remove(asserts.SnapRevisionType, rev1.Ref().PrimaryKey)
+	c.Check(err, DeepEquals, &asserts.NotFoundError{Type: asserts.SnapRevisionType})
+}
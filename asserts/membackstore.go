@@ -301,3 +301,39 @@ func (mbs *memoryBackstore) SequenceMemberAfter(assertType *AssertionType, seque
 	}
 	return a.(SequenceMember), err
 }
+
+func (mbs *memoryBackstore) SearchBySnapID(assertType *AssertionType, snapID string, foundCb func(Assertion), maxFormat int) error {
+	return mbs.Search(assertType, map[string]string{"snap-id": snapID}, foundCb, maxFormat)
+}
+
+func (mbs *memoryBackstore) Remove(assertType *AssertionType, key []string) error {
+	if assertType.SequenceForming() {
+		return fmt.Errorf("internal error: Backstore.Remove on sequence-forming assertion type %s", assertType.Name)
+	}
+	if len(key) != len(assertType.PrimaryKey) {
+		return fmt.Errorf("internal error: Backstore.Remove given a key of unexpected length for %q: %v", assertType.Name, key)
+	}
+
+	mbs.mu.Lock()
+	defer mbs.mu.Unlock()
+
+	internalKey := make([]string, 1, 1+len(key))
+	internalKey[0] = assertType.Name
+	internalKey = append(internalKey, key...)
+
+	var node memBSNode = mbs.top
+	n := len(internalKey)
+	for _, k := range internalKey[:n-1] {
+		br, ok := node.(memBSBranch)
+		if !ok || br[k] == nil {
+			return &NotFoundError{Type: assertType}
+		}
+		node = br[k]
+	}
+	leaf, ok := node.(memBSLeaf)
+	if !ok || leaf[internalKey[n-1]] == nil {
+		return &NotFoundError{Type: assertType}
+	}
+	delete(leaf, internalKey[n-1])
+	return nil
+}
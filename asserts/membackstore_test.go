@@ -638,3 +638,21 @@ func (mbss *memBackstoreSuite) TestOptionalPrimaryKeys(c *C) {
 		"k2/A": "a2",
 	})
 }
+
+func (mbss *memBackstoreSuite) TestSearchBySnapIDAndRemove(c *C) {
+	rev1 := makeSnapRevision(c, "snap-id-1", 1)
+	c.Assert(mbss.bs.Put(asserts.SnapRevisionType, rev1), IsNil)
+	c.Assert(mbss.bs.Put(asserts.SnapRevisionType, makeSnapRevision(c, "snap-id-1", 2)), IsNil)
+	c.Assert(mbss.bs.Put(asserts.SnapRevisionType, makeSnapRevision(c, "snap-id-2", 3)), IsNil)
+	c.Check(snapRevisionsBySnapID(c, mbss.bs, "snap-id-1"), DeepEquals, []int{1, 2})
+
+	rbs := mbss.bs.(interface {
+		Remove(*asserts.AssertionType, []string) error
+	})
+	err := rbs.Remove(asserts.SnapRevisionType, rev1.Ref().PrimaryKey)
+	c.Assert(err, IsNil)
+	c.Check(snapRevisionsBySnapID(c, mbss.bs, "snap-id-1"), DeepEquals, []int{2})
+
+	err = rbs.Remove(asserts.SnapRevisionType, rev1.Ref().PrimaryKey)
+	c.Check(err, DeepEquals, &asserts.NotFoundError{Type: asserts.SnapRevisionType})
+}
@@ -463,8 +463,11 @@ func UpdateManagedBootConfigs(dev snap.Device, gadgetSnapOrDir, cmdlineAppend st
 }
 
 func updateManagedBootConfigForBootloader(dev snap.Device, mode, gadgetSnapOrDir, cmdlineAppend string) (updated bool, err error) {
+	if mode == ModeRecover {
+		return updateManagedRecoveryBootConfig()
+	}
 	if mode != ModeRun {
-		return false, fmt.Errorf("internal error: updating boot config of recovery bootloader is not supported yet")
+		return false, fmt.Errorf("internal error: updating boot config for mode %q is not supported", mode)
 	}
 
 	opts := &bootloader.Options{
@@ -487,6 +490,25 @@ func updateManagedBootConfigForBootloader(dev snap.Device, mode, gadgetSnapOrDir
 	return tbl.UpdateBootConfig()
 }
 
+// updateManagedRecoveryBootConfig updates the managed boot config of the
+// recovery bootloader in ubuntu-seed to the edition built into snapd. The
+// static command line of the recovery boot config is the same across its
+// editions, thus unlike for the run mode bootloader there is no change of the
+// command line to observe.
+func updateManagedRecoveryBootConfig() (updated bool, err error) {
+	opts := &bootloader.Options{
+		Role: bootloader.RoleRecovery,
+	}
+	tbl, err := getBootloaderManagingItsAssets(InitramfsUbuntuSeedDir, opts)
+	if err != nil {
+		if err == errBootConfigNotManaged {
+			return false, nil
+		}
+		return false, err
+	}
+	return tbl.UpdateBootConfig()
+}
+
 // UpdateCommandLineForGadgetComponent handles the update of a gadget
 // that contributes to the kernel command line of the run system
 // (appending any additional kernel command line arguments coming from
@@ -525,7 +547,7 @@ func UpdateCommandLineForGadgetComponent(dev snap.Device, gadgetSnapOrDir, cmdli
 	}
 	// update the bootloader environment, maybe clearing the relevant
 	// variables
-	cmdlineVars, err := bootVarsForTrustedCommandLineFromGadget(gadgetSnapOrDir, cmdlineAppend)
+	cmdlineVars, err := bootVarsForTrustedCommandLineFromGadget(gadgetSnapOrDir, cmdlineAppend, ModeRun)
 	if err != nil {
 		return false, fmt.Errorf("cannot prepare bootloader variables for kernel command line: %v", err)
 	}
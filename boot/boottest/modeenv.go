@@ -0,0 +1,37 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boottest
+
+import (
+	"crypto"
+	"encoding/hex"
+	"fmt"
+
+	_ "golang.org/x/crypto/sha3"
+)
+
+// VersionedModeenv returns the content of a modeenv in the versioned format
+// written by snapd, with the given key=value entries.
+func VersionedModeenv(entries string) string {
+	content := "#version=2\n" + entries
+	h := crypto.SHA3_384.New()
+	h.Write([]byte(content))
+	return fmt.Sprintf("%s#sha3-384=%s\n", content, hex.EncodeToString(h.Sum(nil)))
+}
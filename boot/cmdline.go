@@ -104,12 +104,23 @@ func getBootloaderManagingItsAssets(where string, opts *bootloader.Options) (boo
 	return mbl, nil
 }
 
+// kernelCommandLineFromGadgetForMode returns the kernel command line provided
+// by the gadget for booting in the given mode. Recovery systems, booted in
+// either recover or factory-reset mode, use the recovery variant of the
+// command line if the gadget provides one.
+func kernelCommandLineFromGadgetForMode(gadgetDirOrSnapPath, mode string) (cmdline string, full bool, err error) {
+	if mode == ModeRecover || mode == ModeFactoryReset {
+		return gadget.RecoveryKernelCommandLineFromGadget(gadgetDirOrSnapPath)
+	}
+	return gadget.KernelCommandLineFromGadget(gadgetDirOrSnapPath)
+}
+
 // bootVarsForTrustedCommandLineFromGadget returns a set of boot
 // variables that carry the command line arguments defined by the
-// gadget and some system options (cmdlineApped). This is only useful
-// if snapd is managing the boot config.
-func bootVarsForTrustedCommandLineFromGadget(gadgetDirOrSnapPath, cmdlineAppend string) (map[string]string, error) {
-	extraOrFull, full, err := gadget.KernelCommandLineFromGadget(gadgetDirOrSnapPath)
+// gadget for the given mode and some system options (cmdlineApped). This is
+// only useful if snapd is managing the boot config.
+func bootVarsForTrustedCommandLineFromGadget(gadgetDirOrSnapPath, cmdlineAppend, mode string) (map[string]string, error) {
+	extraOrFull, full, err := kernelCommandLineFromGadgetForMode(gadgetDirOrSnapPath, mode)
 	if err != nil {
 		return nil, fmt.Errorf("cannot use kernel command line from gadget: %v", err)
 	}
@@ -136,7 +147,7 @@ const (
 	candidateEdition
 )
 
-func composeCommandLine(currentOrCandidate int, mode, system, gadgetDirOrSnapPath string) (string, error) {
+func composeCommandLine(currentOrCandidate int, mode, system, gadgetDirOrSnapPath, cmdlineAppend string) (string, error) {
 	if mode != ModeRun && mode != ModeRecover && mode != ModeFactoryReset {
 		return "", fmt.Errorf("internal error: unsupported command line mode %q", mode)
 	}
@@ -174,10 +185,11 @@ func composeCommandLine(currentOrCandidate int, mode, system, gadgetDirOrSnapPat
 		return "", err
 	}
 	if gadgetDirOrSnapPath != "" {
-		extraOrFull, full, err := gadget.KernelCommandLineFromGadget(gadgetDirOrSnapPath)
+		extraOrFull, full, err := kernelCommandLineFromGadgetForMode(gadgetDirOrSnapPath, mode)
 		if err != nil {
 			return "", fmt.Errorf("cannot use kernel command line from gadget: %v", err)
 		}
+		extraOrFull = strutil.JoinNonEmpty([]string{extraOrFull, cmdlineAppend}, " ")
 		// gadget provides some part of the kernel command line
 		if full {
 			components.FullArgs = extraOrFull
@@ -198,7 +210,7 @@ func ComposeRecoveryCommandLine(model *asserts.Model, system, gadgetDirOrSnapPat
 	if model.Grade() == asserts.ModelGradeUnset {
 		return "", nil
 	}
-	return composeCommandLine(currentEdition, ModeRecover, system, gadgetDirOrSnapPath)
+	return composeCommandLine(currentEdition, ModeRecover, system, gadgetDirOrSnapPath, "")
 }
 
 // ComposeCommandLine composes the kernel command line used when booting the
@@ -207,7 +219,7 @@ func ComposeCommandLine(model *asserts.Model, gadgetDirOrSnapPath string) (strin
 	if model.Grade() == asserts.ModelGradeUnset {
 		return "", nil
 	}
-	return composeCommandLine(currentEdition, ModeRun, "", gadgetDirOrSnapPath)
+	return composeCommandLine(currentEdition, ModeRun, "", gadgetDirOrSnapPath, "")
 }
 
 // ComposeCandidateCommandLine composes the kernel command line used when
@@ -217,7 +229,7 @@ func ComposeCandidateCommandLine(model *asserts.Model, gadgetDirOrSnapPath strin
 	if model.Grade() == asserts.ModelGradeUnset {
 		return "", nil
 	}
-	return composeCommandLine(candidateEdition, ModeRun, "", gadgetDirOrSnapPath)
+	return composeCommandLine(candidateEdition, ModeRun, "", gadgetDirOrSnapPath, "")
 }
 
 // ComposeCandidateRecoveryCommandLine composes the kernel command line used
@@ -227,7 +239,25 @@ func ComposeCandidateRecoveryCommandLine(model *asserts.Model, system, gadgetDir
 	if model.Grade() == asserts.ModelGradeUnset {
 		return "", nil
 	}
-	return composeCommandLine(candidateEdition, ModeRecover, system, gadgetDirOrSnapPath)
+	return composeCommandLine(candidateEdition, ModeRecover, system, gadgetDirOrSnapPath, "")
+}
+
+// RecoverySystemCommandLine returns the kernel command line that the given
+// recovery system will be booted with in recover mode. This is the static
+// command line of the recovery bootloader, the extra or full arguments from the
+// gadget and the system.kernel.*cmdline-append defaults of the gadget, that is
+// the same set of arguments that is placed in the recovery system environment
+// when it is made bootable. An empty command line is returned when the
+// recovery bootloader does not manage its boot config.
+func RecoverySystemCommandLine(model *asserts.Model, label, gadgetDirOrSnapPath string) (string, error) {
+	if model.Grade() == asserts.ModelGradeUnset {
+		return "", nil
+	}
+	cmdlineAppend, err := buildOptionalKernelCommandLine(model, gadgetDirOrSnapPath)
+	if err != nil {
+		return "", fmt.Errorf("while retrieving system.kernel.*cmdline-append defaults: %v", err)
+	}
+	return composeCommandLine(currentEdition, ModeRecover, label, gadgetDirOrSnapPath, cmdlineAppend)
 }
 
 // observeSuccessfulCommandLine observes a successful boot with a command line
@@ -366,7 +396,7 @@ func observeCommandLineUpdate(model *asserts.Model, reason commandLineUpdateReas
 	}
 
 	expectReseal := true
-	if err := resealKeyToModeenv(dirs.GlobalRootDir, m, expectReseal, nil); err != nil {
+	if err := resealKeyToModeenvNow(dirs.GlobalRootDir, m, expectReseal, nil); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -384,7 +414,7 @@ func kernelCommandLinesForResealWithFallback(modeenv *Modeenv) (cmdlines []strin
 	// there would be no kernel command lines arguments coming from the
 	// gadget either
 	gadgetDir := ""
-	cmdline, err := composeCommandLine(currentEdition, ModeRun, "", gadgetDir)
+	cmdline, err := composeCommandLine(currentEdition, ModeRun, "", gadgetDir, "")
 	if err != nil {
 		return nil, err
 	}
@@ -367,11 +367,106 @@ func (s *kernelCommandLineSuite) TestComposeRecoveryCommandLineWithGadget(c *C)
 	}
 }
 
+func (s *kernelCommandLineSuite) TestRecoverySystemCommandLine(c *C) {
+	tbl := bootloadertest.Mock("btloader", c.MkDir()).WithTrustedAssets()
+	bootloader.Force(tbl)
+	defer bootloader.Force(nil)
+
+	tbl.StaticCommandLine = "panic=-1"
+	tbl.CandidateStaticCommandLine = "candidate panic=0"
+
+	for _, tc := range []struct {
+		grade          string
+		files          [][]string
+		expCommandLine string
+		errMsg         string
+	}{{
+		files: [][]string{
+			{"meta/gadget.yaml", gadgetYaml},
+		},
+		expCommandLine: "snapd_recovery_mode=recover snapd_recovery_system=1234 panic=-1",
+	}, {
+		files: [][]string{
+			{"meta/gadget.yaml", gadgetYaml},
+			{"cmdline.extra", "cmdline extra"},
+		},
+		expCommandLine: "snapd_recovery_mode=recover snapd_recovery_system=1234 panic=-1 cmdline extra",
+	}, {
+		files: [][]string{
+			{"meta/gadget.yaml", gadgetYaml + "defaults:\n  system:\n    system.kernel.cmdline-append: foo=bar\n"},
+			{"cmdline.full", "cmdline full"},
+		},
+		expCommandLine: "snapd_recovery_mode=recover snapd_recovery_system=1234 cmdline full foo=bar",
+	}, {
+		files: [][]string{
+			{"meta/gadget.yaml", gadgetYaml},
+			{"cmdline.extra", "cmdline extra"},
+			{"cmdline.extra.recover", "cmdline extra recover"},
+		},
+		expCommandLine: "snapd_recovery_mode=recover snapd_recovery_system=1234 panic=-1 cmdline extra recover",
+	}, {
+		files: [][]string{
+			{"meta/gadget.yaml", gadgetYaml + "defaults:\n  system:\n    system.kernel.dangerous-cmdline-append: baz\n"},
+			{"cmdline.extra", "cmdline extra"},
+		},
+		expCommandLine: "snapd_recovery_mode=recover snapd_recovery_system=1234 panic=-1 cmdline extra baz",
+	}, {
+		grade: "signed",
+		files: [][]string{
+			{"meta/gadget.yaml", gadgetYaml + "defaults:\n  system:\n    system.kernel.dangerous-cmdline-append: baz\n"},
+			{"cmdline.extra", "cmdline extra"},
+		},
+		expCommandLine: "snapd_recovery_mode=recover snapd_recovery_system=1234 panic=-1 cmdline extra",
+	}, {
+		files: [][]string{
+			{"meta/gadget.yaml", gadgetYaml + "defaults:\n  system:\n    system.kernel.cmdline-append: 1\n"},
+		},
+		errMsg: `while retrieving system.kernel.\*cmdline-append defaults: system.kernel.cmdline-append is not a string`,
+	}, {
+		files: [][]string{
+			{"meta/gadget.yaml", gadgetYaml},
+			{"cmdline.extra", `bad-quote="`},
+		},
+		errMsg: `cannot use kernel command line from gadget: invalid kernel command line in cmdline.extra: unbalanced quoting`,
+	}} {
+		grade := tc.grade
+		if grade == "" {
+			grade = "dangerous"
+		}
+		model := boottest.MakeMockUC20Model(map[string]interface{}{
+			"grade": grade,
+		})
+		sf := snaptest.MakeTestSnapWithFiles(c, gadgetSnapYaml, tc.files)
+		cmdline, err := boot.RecoverySystemCommandLine(model, "1234", sf)
+		if tc.errMsg == "" {
+			c.Assert(err, IsNil)
+			c.Check(cmdline, Equals, tc.expCommandLine)
+		} else {
+			c.Check(err, ErrorMatches, tc.errMsg)
+		}
+	}
+}
+
+func (s *kernelCommandLineSuite) TestRecoverySystemCommandLineNotManaged(c *C) {
+	bl := bootloadertest.Mock("btloader", c.MkDir())
+	bootloader.Force(bl)
+	defer bootloader.Force(nil)
+
+	sf := snaptest.MakeTestSnapWithFiles(c, gadgetSnapYaml, [][]string{
+		{"meta/gadget.yaml", gadgetYaml},
+		{"cmdline.extra", "cmdline extra"},
+	})
+	cmdline, err := boot.RecoverySystemCommandLine(boottest.MakeMockUC20Model(), "1234", sf)
+	c.Assert(err, IsNil)
+	c.Check(cmdline, Equals, "")
+}
+
 func (s *kernelCommandLineSuite) TestBootVarsForGadgetCommandLine(c *C) {
 	for _, tc := range []struct {
 		errMsg        string
 		files         [][]string
 		cmdlineAppend string
+		mode          string
 		expectedVars  map[string]string
 	}{{
 		files: [][]string{
@@ -433,11 +528,71 @@ func (s *kernelCommandLineSuite) TestBootVarsForGadgetCommandLine(c *C) {
 			"snapd_extra_cmdline_args": "",
 			"snapd_full_cmdline_args":  "",
 		},
+	}, {
+		// recovery variants are not used in run mode
+		files: [][]string{
+			{"cmdline.extra", "foo bar baz"},
+			{"cmdline.full.recover", "recover full"},
+		},
+		mode: boot.ModeRun,
+		expectedVars: map[string]string{
+			"snapd_extra_cmdline_args": "foo bar baz",
+			"snapd_full_cmdline_args":  "",
+		},
+	}, {
+		// recovery variants take precedence in recover mode
+		files: [][]string{
+			{"cmdline.extra", "foo bar baz"},
+			{"cmdline.full.recover", "recover full"},
+		},
+		cmdlineAppend: "x=y",
+		mode:          boot.ModeRecover,
+		expectedVars: map[string]string{
+			"snapd_extra_cmdline_args": "",
+			"snapd_full_cmdline_args":  "recover full x=y",
+		},
+	}, {
+		files: [][]string{
+			{"cmdline.extra", "foo bar baz"},
+			{"cmdline.extra.recover", "console=ttyS0"},
+		},
+		mode: boot.ModeRecover,
+		expectedVars: map[string]string{
+			"snapd_extra_cmdline_args": "console=ttyS0",
+			"snapd_full_cmdline_args":  "",
+		},
+	}, {
+		// falling back to the generic variant
+		files: [][]string{
+			{"cmdline.full", "full foo bar baz"},
+		},
+		mode: boot.ModeRecover,
+		expectedVars: map[string]string{
+			"snapd_extra_cmdline_args": "",
+			"snapd_full_cmdline_args":  "full foo bar baz",
+		},
+	}, {
+		files: [][]string{
+			{"cmdline.extra.recover", "foo"},
+			{"cmdline.full.recover", "bar"},
+		},
+		mode:   boot.ModeRecover,
+		errMsg: `cannot use kernel command line from gadget: cannot support both extra and full kernel command lines`,
+	}, {
+		files: [][]string{
+			{"cmdline.extra.recover", "snapd_foo"},
+		},
+		mode:   boot.ModeRecover,
+		errMsg: `cannot use kernel command line from gadget: invalid kernel command line in cmdline.extra.recover: disallowed kernel argument \"snapd_foo\"`,
 	}} {
 		sf := snaptest.MakeTestSnapWithFiles(c, gadgetSnapYaml, append([][]string{
 			{"meta/snap.yaml", gadgetSnapYaml},
 		}, tc.files...))
-		vars, err := boot.BootVarsForTrustedCommandLineFromGadget(sf, tc.cmdlineAppend)
+		mode := tc.mode
+		if mode == "" {
+			mode = boot.ModeRun
+		}
+		vars, err := boot.BootVarsForTrustedCommandLineFromGadget(sf, tc.cmdlineAppend, mode)
 		if tc.errMsg == "" {
 			c.Assert(err, IsNil)
 			c.Assert(vars, DeepEquals, tc.expectedVars)
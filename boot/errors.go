@@ -47,3 +47,30 @@ func isTrySnapError(err error) bool {
 }
 
 var errTrySnapFallback = errors.New("fallback to original snap")
+
+// RecoverySystemBootConfigError indicates that a candidate recovery system
+// cannot be booted because its bootloader configuration is missing or refers
+// to a kernel unsuitable for the recovery bootloader.
+type RecoverySystemBootConfigError struct {
+	System string
+	Reason string
+}
+
+func (e *RecoverySystemBootConfigError) Error() string {
+	return fmt.Sprintf("recovery system %q is missing bootloader configuration: %s", e.System, e.Reason)
+}
+
+// RecoverySystemResealError indicates that the keys could not be resealed to
+// include a candidate recovery system in the recovery boot chains.
+type RecoverySystemResealError struct {
+	System string
+	Err    error
+}
+
+func (e *RecoverySystemResealError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RecoverySystemResealError) Unwrap() error {
+	return e.Err
+}
@@ -67,15 +67,21 @@ var (
 	ObserveSuccessfulBootWithAssets = observeSuccessfulBootAssets
 	SealKeyToModeenv                = sealKeyToModeenvImpl
 	ResealKeyToModeenv              = resealKeyToModeenv
+	ResealKeyToModeenvOrDefer       = resealKeyToModeenvOrDefer
+	ResealKeyToModeenvNow           = resealKeyToModeenvNow
 	RecoveryBootChainsForSystems    = recoveryBootChainsForSystems
 	SealKeyModelParams              = sealKeyModelParams
 
 	BootVarsForTrustedCommandLineFromGadget = bootVarsForTrustedCommandLineFromGadget
 
 	WriteModelToUbuntuBoot = writeModelToUbuntuBoot
+
+	RecoverySystemModelDigest = recoverySystemModelDigest
 )
 
 type BootAssetsMap = bootAssetsMap
+type RecoverySystemModels = recoverySystemModels
+type RecoverySystemBootAssets = recoverySystemBootAssets
 type BootCommandLines = bootCommandLines
 type TrackedAsset = trackedAsset
 
@@ -20,9 +20,12 @@
 package boot
 
 import (
+	"fmt"
+	"os"
 	"os/exec"
 	"time"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
@@ -76,6 +79,30 @@ func InitramfsRunModeSelectSnapsToMount(
 	return m, nil
 }
 
+// InitramfsCheckRecoverySystemModel verifies that the model of the recovery
+// system with the given label matches the model recorded in the modeenv under
+// rootdir when the system was created. Systems with no recorded model, such as
+// those created by older versions of snapd, are accepted with a warning.
+func InitramfsCheckRecoverySystemModel(rootdir, systemLabel string, model *asserts.Model) error {
+	m, err := ReadModeenv(rootdir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logger.Noticef("WARNING: cannot verify model of recovery system %q: modeenv does not exist", systemLabel)
+			return nil
+		}
+		return err
+	}
+	digest, ok := m.RecoverySystemModels[systemLabel]
+	if !ok {
+		logger.Noticef("WARNING: cannot verify model of recovery system %q: model is not tracked", systemLabel)
+		return nil
+	}
+	if digest != recoverySystemModelDigest(model) {
+		return fmt.Errorf("model of recovery system %q does not match the recorded model", systemLabel)
+	}
+	return nil
+}
+
 // EnsureNextBootToRunMode will mark the bootenv of the recovery bootloader such
 // that recover mode is now ready to switch back to run mode upon any reboot.
 func EnsureNextBootToRunMode(systemLabel string) error {
@@ -33,8 +33,10 @@ import (
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/gadget"
 	"github.com/snapcore/snapd/gadget/gadgettest"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil/kcmdline"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
 )
 
 type initramfsSuite struct {
@@ -882,3 +884,43 @@ func (s *initramfsSuite) TestInitramfsWritableDir(c *C) {
 		c.Check(boot.InitramfsWritableDir(tc.model, tc.runMode), Equals, filepath.Join(dirs.GlobalRootDir, tc.expectedDir))
 	}
 }
+
+func (s *initramfsSuite) TestInitramfsCheckRecoverySystemModel(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	model := boottest.MakeMockUC20Model()
+	otherModel := boottest.MakeMockUC20Model(map[string]interface{}{
+		"model": "other-model-uc20",
+	})
+	rootdir := c.MkDir()
+
+	// no modeenv
+	err := boot.InitramfsCheckRecoverySystemModel(rootdir, "1234", model)
+	c.Assert(err, IsNil)
+	c.Check(logbuf.String(), testutil.Contains, `WARNING: cannot verify model of recovery system "1234": modeenv does not exist`)
+	logbuf.Reset()
+
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		CurrentRecoverySystems: []string{"20200825", "1234"},
+		RecoverySystemModels: boot.RecoverySystemModels{
+			"1234": boot.RecoverySystemModelDigest(model),
+		},
+	}
+	c.Assert(modeenv.WriteTo(rootdir), IsNil)
+
+	// model matches
+	err = boot.InitramfsCheckRecoverySystemModel(rootdir, "1234", model)
+	c.Assert(err, IsNil)
+	c.Check(logbuf.String(), Equals, "")
+
+	// model does not match
+	err = boot.InitramfsCheckRecoverySystemModel(rootdir, "1234", otherModel)
+	c.Assert(err, ErrorMatches, `model of recovery system "1234" does not match the recorded model`)
+
+	// system created before the models were tracked
+	err = boot.InitramfsCheckRecoverySystemModel(rootdir, "20200825", otherModel)
+	c.Assert(err, IsNil)
+	c.Check(logbuf.String(), testutil.Contains, `WARNING: cannot verify model of recovery system "20200825": model is not tracked`)
+}
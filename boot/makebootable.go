@@ -307,7 +307,7 @@ func MakeRecoverySystemBootable(model *asserts.Model, rootdir string, relativeRe
 			return fmt.Errorf("while retrieving system.kernel.*cmdline-append defaults: %v", err)
 		}
 		// to set cmdlineAppend.
-		recoveryCmdlineArgs, err := bootVarsForTrustedCommandLineFromGadget(bootWith.GadgetSnapOrDir, cmdlineAppend)
+		recoveryCmdlineArgs, err := bootVarsForTrustedCommandLineFromGadget(bootWith.GadgetSnapOrDir, cmdlineAppend, ModeRecover)
 		if err != nil {
 			return fmt.Errorf("cannot obtain recovery system command line: %v", err)
 		}
@@ -322,6 +322,76 @@ func MakeRecoverySystemBootable(model *asserts.Model, rootdir string, relativeRe
 	return nil
 }
 
+// CheckRecoverySystemBootable verifies that a recovery system prepared with
+// MakeRecoverySystemBootable can be booted by the recovery bootloader. The
+// kernel snap must carry the assets the bootloader boots from, that is
+// kernel.efi, or kernel.img and initrd.img for bootloaders booting an extracted
+// kernel. For bootloaders keeping an environment for each recovery system,
+// the environment must reference the existing kernel snap of the system. A
+// *RecoverySystemBootConfigError is returned when any of the checks fails.
+func CheckRecoverySystemBootable(rootdir string, relativeRecoverySystemDir string, bootWith *RecoverySystemBootableSet) error {
+	opts := &bootloader.Options{
+		PrepareImageTime: bootWith.PrepareImageTime,
+		Role:             bootloader.RoleRecovery,
+	}
+	bl, err := bootloader.Find(rootdir, opts)
+	if err != nil {
+		return fmt.Errorf("internal error: cannot find bootloader: %v", err)
+	}
+	systemLabel := filepath.Base(relativeRecoverySystemDir)
+	notBootable := func(format string, a ...interface{}) error {
+		return &RecoverySystemBootConfigError{
+			System: systemLabel,
+			Reason: fmt.Sprintf(format, a...),
+		}
+	}
+
+	kernelf, err := snapfile.Open(bootWith.KernelPath)
+	if err != nil {
+		return notBootable("cannot open kernel snap: %v", err)
+	}
+	kernelContent, err := kernelf.ListDir(".")
+	if err != nil {
+		return notBootable("cannot list kernel snap content: %v", err)
+	}
+	_, extracted := bl.(bootloader.ExtractedRecoveryKernelImageBootloader)
+	kernelAssets := []string{"kernel.efi"}
+	if extracted {
+		kernelAssets = []string{"kernel.img", "initrd.img"}
+	}
+	for _, asset := range kernelAssets {
+		if !strutil.ListContains(kernelContent, asset) {
+			return notBootable("kernel snap %q does not contain %s required by %s bootloader",
+				bootWith.Kernel.SnapName(), asset, bl.Name())
+		}
+	}
+	if extracted {
+		// the bootloader does not load any environment from the
+		// recovery system
+		return nil
+	}
+
+	rbl, ok := bl.(bootloader.RecoveryAwareBootloader)
+	if !ok {
+		return fmt.Errorf("cannot use %s bootloader: does not support recovery systems", bl.Name())
+	}
+	kernelPath, err := rbl.GetRecoverySystemEnv(relativeRecoverySystemDir, "snapd_recovery_kernel")
+	if err != nil {
+		return notBootable("cannot read recovery system environment: %v", err)
+	}
+	if kernelPath == "" {
+		return notBootable("recovery kernel is not set")
+	}
+	expectedKernelPath, err := filepath.Rel(rootdir, bootWith.KernelPath)
+	if err != nil {
+		return fmt.Errorf("cannot construct kernel boot path: %v", err)
+	}
+	if kernelPath != filepath.Join("/", expectedKernelPath) {
+		return notBootable("unexpected recovery kernel %q", kernelPath)
+	}
+	return nil
+}
+
 type makeRunnableOptions struct {
 	Standalone     bool
 	AfterDataReset bool
@@ -419,6 +489,9 @@ func makeRunnableSystem(model *asserts.Model, bootWith *BootableSet, sealer *Tru
 		Grade:          string(model.Grade()),
 		ModelSignKeyID: model.SignKeyID(),
 	}
+	if recoverySystemLabel != "" {
+		modeenv.setRecoverySystemModel(recoverySystemLabel, model)
+	}
 	// Note on classic systems there is no boot base, the system boots
 	// from debs.
 	if !model.Classic() {
@@ -505,7 +578,7 @@ func makeRunnableSystem(model *asserts.Model, bootWith *BootableSet, sealer *Tru
 		if err != nil {
 			return fmt.Errorf("while retrieving system.kernel.*cmdline-append defaults: %v", err)
 		}
-		cmdlineVars, err := bootVarsForTrustedCommandLineFromGadget(bootWith.UnpackedGadgetDir, cmdlineAppend)
+		cmdlineVars, err := bootVarsForTrustedCommandLineFromGadget(bootWith.UnpackedGadgetDir, cmdlineAppend, ModeRun)
 		if err != nil {
 			return fmt.Errorf("cannot prepare bootloader variables for kernel command line: %v", err)
 		}
@@ -546,6 +619,9 @@ func makeRunnableSystem(model *asserts.Model, bootWith *BootableSet, sealer *Tru
 	if err := MarkRecoveryCapableSystem(recoverySystemLabel); err != nil {
 		return fmt.Errorf("cannot record %q as a recovery capable system: %v", recoverySystemLabel, err)
 	}
+	if err := installRecoverySystemsMenu(model, bootWith.UnpackedGadgetDir, recoverySystemLabel); err != nil {
+		return fmt.Errorf("cannot install the menu of recovery systems: %v", err)
+	}
 	return nil
 }
 
@@ -20,6 +20,7 @@
 package boot_test
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -839,7 +840,8 @@ version: 5.0
 	expectedModeenv := fmt.Sprintf(`mode=run
 recovery_system=20191216
 current_recovery_systems=20191216
-good_recovery_systems=20191216%s
+good_recovery_systems=20191216
+recovery_system_models={"20191216":"75915f75bdb22bb70d167bf80d90bd66a32443ee2d1a38aaf0507b4903948f53cc684a1a654ce8f5e488eaebfe4ea20a"}%s
 gadget=pc_4.snap
 current_kernels=pc-kernel_5.snap
 model=my-brand/my-model-uc20%s
@@ -849,7 +851,7 @@ current_trusted_boot_assets={"grubx64.efi":["5ee042c15e104b825d6bc15c41cdb026589
 current_trusted_recovery_boot_assets={"bootx64.efi":["39efae6545f16e39633fbfbef0d5e9fdd45a25d7df8764978ce4d81f255b038046a38d9855e42e5c7c4024e153fd2e37"],"grubx64.efi":["aa3c1a83e74bf6dd40dd64e5c5bd1971d75cdf55515b23b9eb379f66bf43d4661d22c4b8cf7d7a982d2013ab65c1c4c5"]}
 current_kernel_command_lines=["snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1"]
 `, base, classicLine)
-	c.Check(ubuntuDataModeEnvPath, testutil.FileEquals, expectedModeenv)
+	c.Check(ubuntuDataModeEnvPath, testutil.FileEquals, boottest.VersionedModeenv(expectedModeenv))
 	copiedGrubBin := filepath.Join(
 		dirs.SnapBootAssetsDirUnder(installHostWritableDir),
 		"grub",
@@ -1399,10 +1401,11 @@ version: 5.0
 
 	// ensure modeenv looks correct
 	ubuntuDataModeEnvPath := filepath.Join(s.rootdir, "/run/mnt/ubuntu-data/system-data/var/lib/snapd/modeenv")
-	c.Check(ubuntuDataModeEnvPath, testutil.FileEquals, fmt.Sprintf(`mode=run
+	c.Check(ubuntuDataModeEnvPath, testutil.FileEquals, boottest.VersionedModeenv(fmt.Sprintf(`mode=run
 recovery_system=20191216
 current_recovery_systems=20191216
 good_recovery_systems=20191216
+recovery_system_models={"20191216":"75915f75bdb22bb70d167bf80d90bd66a32443ee2d1a38aaf0507b4903948f53cc684a1a654ce8f5e488eaebfe4ea20a"}
 base=core20_3.snap
 gadget=pc_4.snap
 current_kernels=pc-kernel_5.snap
@@ -1412,7 +1415,7 @@ model_sign_key_id=Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQu
 current_trusted_boot_assets={"grubx64.efi":["5ee042c15e104b825d6bc15c41cdb026589f1ec57ed966dd3f29f961d4d6924efc54b187743fa3a583b62722882d405d"]}
 current_trusted_recovery_boot_assets={"bootx64.efi":["39efae6545f16e39633fbfbef0d5e9fdd45a25d7df8764978ce4d81f255b038046a38d9855e42e5c7c4024e153fd2e37"],"grubx64.efi":["aa3c1a83e74bf6dd40dd64e5c5bd1971d75cdf55515b23b9eb379f66bf43d4661d22c4b8cf7d7a982d2013ab65c1c4c5"]}
 current_kernel_command_lines=["%v"]
-`, cmdlines["run"]))
+`, cmdlines["run"])))
 	// make sure the TPM was provisioned
 	c.Check(provisionCalls, Equals, 1)
 	// make sure SealKey was called for the run object and the fallback object
@@ -1847,17 +1850,18 @@ version: 5.0
 
 	// ensure modeenv looks correct
 	ubuntuDataModeEnvPath := filepath.Join(s.rootdir, "/run/mnt/ubuntu-data/system-data/var/lib/snapd/modeenv")
-	c.Check(ubuntuDataModeEnvPath, testutil.FileEquals, `mode=run
+	c.Check(ubuntuDataModeEnvPath, testutil.FileEquals, boottest.VersionedModeenv(`mode=run
 recovery_system=20191216
 current_recovery_systems=20191216
 good_recovery_systems=20191216
+recovery_system_models={"20191216":"75915f75bdb22bb70d167bf80d90bd66a32443ee2d1a38aaf0507b4903948f53cc684a1a654ce8f5e488eaebfe4ea20a"}
 base=core20_3.snap
 gadget=pc_4.snap
 current_kernels=arm-kernel_5.snap
 model=my-brand/my-model-uc20
 grade=dangerous
 model_sign_key_id=Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij
-`)
+`))
 }
 
 func (s *makeBootable20Suite) TestMakeRecoverySystemBootableAtRuntime20(c *C) {
@@ -1939,6 +1943,90 @@ version: 5.0
 	c.Check(systemGenv.Get("snapd_full_cmdline_args"), Equals, "args from gadget rev 5")
 }
 
+func (s *makeBootable20Suite) TestCheckRecoverySystemBootable20(c *C) {
+	bootloader.Force(nil)
+	model := boottest.MakeMockUC20Model()
+
+	seedSnapsDirs := filepath.Join(s.rootdir, "/snaps")
+	err := os.MkdirAll(seedSnapsDirs, 0755)
+	c.Assert(err, IsNil)
+
+	kernelYaml := "name: pc-kernel\ntype: kernel\nversion: 5.0\n"
+	kernelFn, kernelInfo := makeSnapWithFiles(c, "pc-kernel", kernelYaml, snap.R(5), [][]string{
+		{"kernel.efi", "I'm a kernel.efi"},
+	})
+	kernelInSeed := filepath.Join(seedSnapsDirs, kernelInfo.Filename())
+	c.Assert(os.Rename(kernelFn, kernelInSeed), IsNil)
+	// a kernel that cannot be booted by grub
+	badKernelFn, badKernelInfo := makeSnapWithFiles(c, "pc-kernel", kernelYaml, snap.R(6), [][]string{
+		{"kernel.img", "I'm a kernel.img"},
+	})
+	badKernelInSeed := filepath.Join(seedSnapsDirs, badKernelInfo.Filename())
+	c.Assert(os.Rename(badKernelFn, badKernelInSeed), IsNil)
+	otherKernelFn, otherKernelInfo := makeSnapWithFiles(c, "pc-kernel", kernelYaml, snap.R(7), [][]string{
+		{"kernel.efi", "I'm another kernel.efi"},
+	})
+	otherKernelInSeed := filepath.Join(seedSnapsDirs, otherKernelInfo.Filename())
+	c.Assert(os.Rename(otherKernelFn, otherKernelInSeed), IsNil)
+
+	gadgetFn, gadgetInfo := makeSnapWithFiles(c, "pc", gadgetSnapYaml, snap.R(1), [][]string{
+		{"grub.conf", ""},
+		{"meta/snap.yaml", gadgetSnapYaml},
+		{"meta/gadget.yaml", gadgetYaml},
+	})
+	gadgetInSeed := filepath.Join(seedSnapsDirs, gadgetInfo.Filename())
+	c.Assert(os.Rename(gadgetFn, gadgetInSeed), IsNil)
+
+	snaptest.PopulateDir(s.rootdir, [][]string{
+		{"EFI/ubuntu/grub.cfg", "this is grub"},
+		{"EFI/ubuntu/grubenv", "canary"},
+	})
+
+	recoverySystemDir := "/systems/20191209"
+	bootWith := &boot.RecoverySystemBootableSet{
+		Kernel:          kernelInfo,
+		KernelPath:      kernelInSeed,
+		GadgetSnapOrDir: gadgetInSeed,
+	}
+	err = boot.MakeRecoverySystemBootable(model, s.rootdir, recoverySystemDir, bootWith)
+	c.Assert(err, IsNil)
+
+	err = boot.CheckRecoverySystemBootable(s.rootdir, recoverySystemDir, bootWith)
+	c.Assert(err, IsNil)
+
+	var bootConfigErr *boot.RecoverySystemBootConfigError
+
+	// the bootloader cannot boot the kernel
+	err = boot.CheckRecoverySystemBootable(s.rootdir, recoverySystemDir, &boot.RecoverySystemBootableSet{
+		Kernel:          badKernelInfo,
+		KernelPath:      badKernelInSeed,
+		GadgetSnapOrDir: gadgetInSeed,
+	})
+	c.Assert(errors.As(err, &bootConfigErr), Equals, true)
+	c.Check(bootConfigErr.System, Equals, "20191209")
+	c.Check(err, ErrorMatches, `recovery system "20191209" is missing bootloader configuration: kernel snap "pc-kernel" does not contain kernel.efi required by grub bootloader`)
+
+	// the environment references another kernel
+	err = boot.CheckRecoverySystemBootable(s.rootdir, recoverySystemDir, &boot.RecoverySystemBootableSet{
+		Kernel:          otherKernelInfo,
+		KernelPath:      otherKernelInSeed,
+		GadgetSnapOrDir: gadgetInSeed,
+	})
+	c.Assert(errors.As(err, &bootConfigErr), Equals, true)
+	c.Check(err, ErrorMatches, `recovery system "20191209" is missing bootloader configuration: unexpected recovery kernel "/snaps/pc-kernel_5.snap"`)
+
+	// environment of the system was never written
+	err = boot.CheckRecoverySystemBootable(s.rootdir, "/systems/20200101", bootWith)
+	c.Assert(errors.As(err, &bootConfigErr), Equals, true)
+	c.Check(err, ErrorMatches, `recovery system "20200101" is missing bootloader configuration: recovery kernel is not set`)
+
+	// the kernel snap is gone
+	c.Assert(os.Remove(kernelInSeed), IsNil)
+	err = boot.CheckRecoverySystemBootable(s.rootdir, recoverySystemDir, bootWith)
+	c.Assert(errors.As(err, &bootConfigErr), Equals, true)
+	c.Check(err, ErrorMatches, `recovery system "20191209" is missing bootloader configuration: cannot open kernel snap: .*`)
+}
+
 func (s *makeBootable20Suite) TestMakeBootablePartition(c *C) {
 	bootloader.Force(nil)
 
@@ -2213,6 +2301,7 @@ version: 3.0
 recovery_system=20221004
 current_recovery_systems=20221004
 good_recovery_systems=20221004
+recovery_system_models={"20221004":"75915f75bdb22bb70d167bf80d90bd66a32443ee2d1a38aaf0507b4903948f53cc684a1a654ce8f5e488eaebfe4ea20a"}
 base=core20_3.snap
 gadget=pc_4.snap
 current_kernels=pc-kernel_5.snap
@@ -2221,7 +2310,7 @@ grade=dangerous
 model_sign_key_id=Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij
 current_kernel_command_lines=["snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1"]
 `
-	c.Check(ubuntuDataModeEnvPath, testutil.FileEquals, expectedModeenv)
+	c.Check(ubuntuDataModeEnvPath, testutil.FileEquals, boottest.VersionedModeenv(expectedModeenv))
 }
 
 func (s *makeBootable20Suite) TestMakeStandaloneSystemRunnable20Install(c *C) {
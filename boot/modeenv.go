@@ -21,7 +21,10 @@ package boot
 
 import (
 	"bytes"
+	"crypto"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -35,14 +38,25 @@ import (
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/secboot"
 	"github.com/snapcore/snapd/snapdenv"
+	"github.com/snapcore/snapd/strutil"
 )
 
 type bootAssetsMap map[string][]string
 
+// recoverySystemModels is a map of recovery system labels to model digests,
+// marshalled as JSON.
+type recoverySystemModels map[string]string
+
+// recoverySystemBootAssets is a map of recovery system labels to the trusted
+// boot assets the systems added to the tracked recovery assets, marshalled as
+// JSON.
+type recoverySystemBootAssets map[string]bootAssetsMap
+
 // bootCommandLines is a list of kernel command lines. The command lines are
 // marshalled as JSON as a comma can be present in the module parameters.
 type bootCommandLines []string
@@ -60,9 +74,13 @@ type Modeenv struct {
 	// systems that were tested and are prepared to use for recovering.
 	// The fallback keys are resealed for these systems.
 	GoodRecoverySystems []string `key:"good_recovery_systems"`
-	Base                string   `key:"base"`
-	TryBase             string   `key:"try_base"`
-	BaseStatus          string   `key:"base_status"`
+	// RecoverySystemModels maps recovery system labels to a digest of the
+	// brand, model, grade and sign key of the model the system was created
+	// for. Systems created before the digests were tracked have no entry.
+	RecoverySystemModels recoverySystemModels `key:"recovery_system_models"`
+	Base                 string               `key:"base"`
+	TryBase              string               `key:"try_base"`
+	BaseStatus           string               `key:"base_status"`
 	// Gadget is the currently active gadget snap
 	Gadget         string   `key:"gadget"`
 	CurrentKernels []string `key:"current_kernels"`
@@ -97,6 +115,12 @@ type Modeenv struct {
 	// asset names to a list of hashes of the asset contents. Used similarly
 	// to CurrentTrustedBootAssets.
 	CurrentTrustedRecoveryBootAssets bootAssetsMap `key:"current_trusted_recovery_boot_assets"`
+	// RecoverySystemTrustedBootAssets maps recovery system labels to the
+	// trusted assets of the recovery bootloader which were installed in
+	// the system directory and added to CurrentTrustedRecoveryBootAssets
+	// when the system was created. The entries are used for dropping the
+	// assets once no longer used by any recovery system.
+	RecoverySystemTrustedBootAssets recoverySystemBootAssets `key:"recovery_system_trusted_boot_assets"`
 	// CurrentKernelCommandLines is a list of the expected kernel command
 	// lines when booting into run mode. It will typically only be one
 	// element for normal operations, but may contain two elements during
@@ -156,6 +180,125 @@ func modeenvFile(rootdir string) string {
 	return dirs.SnapModeenvFileUnder(rootdir)
 }
 
+// The modeenv is written in a versioned format, which is the unversioned
+// key=value format of earlier releases enclosed between a comment line with
+// the version of the format and a comment line with a checksum of all what
+// precedes it, like:
+//
+//	#version=2
+//	mode=run
+//	...
+//	#sha3-384=<hex digest>
+//
+// Readers of the unversioned format ignore the comments. For rollback to
+// earlier releases a copy of the modeenv in the unversioned format is kept
+// alongside, which is also used when the versioned modeenv is corrupted.
+// TODO: stop writing the unversioned copy in the next release.
+const (
+	modeenvVersion        = 2
+	modeenvVersionPrefix  = "#version="
+	modeenvChecksumPrefix = "#sha3-384="
+)
+
+func modeenvLegacyFile(modeenvPath string) string {
+	return modeenvPath + ".legacy"
+}
+
+// modeenvChecksum returns the checksum of the given content of a modeenv in
+// the versioned format.
+func modeenvChecksum(data []byte) string {
+	h := crypto.SHA3_384.New()
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// modeenvChecksumError is returned by verifyModeenv when the checksum of a
+// modeenv in the versioned format does not match its content.
+type modeenvChecksumError struct {
+	expected, got string
+	// body are the key=value entries of the modeenv
+	body []byte
+}
+
+func (e *modeenvChecksumError) Error() string {
+	return fmt.Sprintf("modeenv checksum mismatch, expected %s got %s", e.expected, e.got)
+}
+
+// verifyModeenv returns the key=value entries of the given modeenv content,
+// which is either in the versioned format, in which case its version and
+// checksum are verified, or in the unversioned format.
+func verifyModeenv(data []byte) (body []byte, versioned bool, err error) {
+	if !bytes.HasPrefix(data, []byte(modeenvVersionPrefix)) {
+		return data, false, nil
+	}
+	idx := bytes.IndexByte(data, '\n')
+	if idx < 0 {
+		return nil, true, fmt.Errorf("modeenv is truncated")
+	}
+	version := string(data[len(modeenvVersionPrefix):idx])
+	if version != strconv.Itoa(modeenvVersion) {
+		return nil, true, fmt.Errorf("unsupported modeenv format version %q", version)
+	}
+	body = data[idx+1:]
+	if !bytes.HasSuffix(body, []byte("\n")) {
+		return nil, true, fmt.Errorf("modeenv is truncated")
+	}
+	lastIdx := bytes.LastIndexByte(body[:len(body)-1], '\n') + 1
+	lastLine := string(body[lastIdx : len(body)-1])
+	if !strings.HasPrefix(lastLine, modeenvChecksumPrefix) {
+		return nil, true, fmt.Errorf("modeenv checksum is missing")
+	}
+	expected := lastLine[len(modeenvChecksumPrefix):]
+	if sum := modeenvChecksum(data[:idx+1+lastIdx]); sum != expected {
+		return nil, true, &modeenvChecksumError{expected: expected, got: sum, body: body[:lastIdx]}
+	}
+	return body[:lastIdx], true, nil
+}
+
+// modeenvEntriesValid returns true if all the lines of the given modeenv
+// content are key=value entries.
+func modeenvEntriesValid(body []byte) bool {
+	for _, line := range strings.Split(string(body), "\n") {
+		if line == "" {
+			continue
+		}
+		if idx := strings.IndexByte(line, '='); idx <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// readModeenvBody returns the key=value entries of the modeenv at the given
+// path, falling back to the unversioned copy kept alongside if the modeenv
+// is corrupted. A modeenv whose checksum does not match, but whose entries
+// can still be parsed, was modified after being written and is not silently
+// replaced by the copy.
+func readModeenvBody(modeenvPath string) ([]byte, error) {
+	data, err := os.ReadFile(modeenvPath)
+	if err != nil {
+		return nil, err
+	}
+	body, versioned, err := verifyModeenv(data)
+	if err == nil {
+		if !versioned {
+			logger.Debugf("reading modeenv %s in the unversioned format", modeenvPath)
+		}
+		return body, nil
+	}
+	var checksumErr *modeenvChecksumError
+	if errors.As(err, &checksumErr) && modeenvEntriesValid(checksumErr.body) {
+		return nil, fmt.Errorf("cannot read modeenv %s: %v", modeenvPath, err)
+	}
+	legacyPath := modeenvLegacyFile(modeenvPath)
+	legacyData, legacyErr := os.ReadFile(legacyPath)
+	if legacyErr != nil {
+		return nil, fmt.Errorf("cannot read modeenv %s: %v", modeenvPath, err)
+	}
+	logger.Noticef("cannot use modeenv %s: %v, falling back to %s", modeenvPath, err, legacyPath)
+	return legacyData, nil
+}
+
 // ReadModeenv attempts to read the modeenv file at
 // <rootdir>/var/lib/snapd/modeenv.
 func ReadModeenv(rootdir string) (*Modeenv, error) {
@@ -164,9 +307,13 @@ func ReadModeenv(rootdir string) (*Modeenv, error) {
 	}
 
 	modeenvPath := modeenvFile(rootdir)
+	body, err := readModeenvBody(modeenvPath)
+	if err != nil {
+		return nil, err
+	}
 	cfg := goconfigparser.New()
 	cfg.AllowNoSectionHeader = true
-	if err := cfg.ReadFile(modeenvPath); err != nil {
+	if err := cfg.Read(bytes.NewReader(body)); err != nil {
 		return nil, err
 	}
 
@@ -179,6 +326,7 @@ func ReadModeenv(rootdir string) (*Modeenv, error) {
 	unmarshalModeenvValueFromCfg(cfg, "recovery_system", &m.RecoverySystem)
 	unmarshalModeenvValueFromCfg(cfg, "current_recovery_systems", &m.CurrentRecoverySystems)
 	unmarshalModeenvValueFromCfg(cfg, "good_recovery_systems", &m.GoodRecoverySystems)
+	unmarshalModeenvValueFromCfg(cfg, "recovery_system_models", &m.RecoverySystemModels)
 	unmarshalModeenvValueFromCfg(cfg, "boot_flags", &m.BootFlags)
 
 	unmarshalModeenvValueFromCfg(cfg, "mode", &m.Mode)
@@ -209,6 +357,7 @@ func ReadModeenv(rootdir string) (*Modeenv, error) {
 
 	unmarshalModeenvValueFromCfg(cfg, "current_trusted_boot_assets", &m.CurrentTrustedBootAssets)
 	unmarshalModeenvValueFromCfg(cfg, "current_trusted_recovery_boot_assets", &m.CurrentTrustedRecoveryBootAssets)
+	unmarshalModeenvValueFromCfg(cfg, "recovery_system_trusted_boot_assets", &m.RecoverySystemTrustedBootAssets)
 	unmarshalModeenvValueFromCfg(cfg, "current_kernel_command_lines", &m.CurrentKernelCommandLines)
 
 	// save all the rest of the keys we don't understand
@@ -294,6 +443,7 @@ func (m *Modeenv) WriteTo(rootdir string) error {
 	marshalModeenvEntryTo(buf, "recovery_system", m.RecoverySystem)
 	marshalModeenvEntryTo(buf, "current_recovery_systems", m.CurrentRecoverySystems)
 	marshalModeenvEntryTo(buf, "good_recovery_systems", m.GoodRecoverySystems)
+	marshalModeenvEntryTo(buf, "recovery_system_models", m.RecoverySystemModels)
 	marshalModeenvEntryTo(buf, "boot_flags", m.BootFlags)
 	marshalModeenvEntryTo(buf, "base", m.Base)
 	marshalModeenvEntryTo(buf, "try_base", m.TryBase)
@@ -328,6 +478,7 @@ func (m *Modeenv) WriteTo(rootdir string) error {
 	marshalModeenvEntryTo(buf, "try_model_sign_key_id", m.TryModelSignKeyID)
 	marshalModeenvEntryTo(buf, "current_trusted_boot_assets", m.CurrentTrustedBootAssets)
 	marshalModeenvEntryTo(buf, "current_trusted_recovery_boot_assets", m.CurrentTrustedRecoveryBootAssets)
+	marshalModeenvEntryTo(buf, "recovery_system_trusted_boot_assets", m.RecoverySystemTrustedBootAssets)
 	marshalModeenvEntryTo(buf, "current_kernel_command_lines", m.CurrentKernelCommandLines)
 
 	// write all the extra keys at the end
@@ -341,12 +492,80 @@ func (m *Modeenv) WriteTo(rootdir string) error {
 		marshalModeenvEntryTo(buf, k, m.extrakeys[k])
 	}
 
-	if err := osutil.AtomicWriteFile(modeenvPath, buf.Bytes(), 0644, 0); err != nil {
+	versioned := bytes.NewBuffer(nil)
+	fmt.Fprintf(versioned, "%s%d\n", modeenvVersionPrefix, modeenvVersion)
+	versioned.Write(buf.Bytes())
+	fmt.Fprintf(versioned, "%s%s\n", modeenvChecksumPrefix, modeenvChecksum(versioned.Bytes()))
+	if err := osutil.AtomicWriteFile(modeenvPath, versioned.Bytes(), 0644, 0); err != nil {
+		return err
+	}
+	// the unversioned copy is written last, such that it never has
+	// content the versioned modeenv does not have yet; at worst it lags
+	// one write behind when interrupted
+	if err := osutil.AtomicWriteFile(modeenvLegacyFile(modeenvPath), buf.Bytes(), 0644, 0); err != nil {
 		return err
 	}
 	return nil
 }
 
+// ValidateModeenv checks the modeenv for inconsistencies, both between its
+// entries and with the recovery systems present in the given ubuntu-seed
+// directory.
+func ValidateModeenv(m *Modeenv, seedDir string) error {
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if !strutil.ListContains(validModes, m.Mode) {
+		addProblem("unknown mode %q", m.Mode)
+	}
+	switch m.BaseStatus {
+	case DefaultStatus:
+	case TryStatus, TryingStatus:
+		if m.TryBase == "" {
+			addProblem("base status is %q but try base is unset", m.BaseStatus)
+		}
+	default:
+		addProblem("unknown base status %q", m.BaseStatus)
+	}
+	if m.Mode == ModeRun && len(m.CurrentKernels) == 0 {
+		addProblem("no current kernels")
+	}
+	if m.Model != "" && m.BrandID == "" {
+		addProblem("model %q has no brand", m.Model)
+	}
+
+	for _, label := range m.CurrentRecoverySystems {
+		if !osutil.IsDirectory(filepath.Join(seedDir, "systems", label)) {
+			addProblem("current recovery system %q does not exist in %s", label, seedDir)
+		}
+	}
+	for _, label := range m.GoodRecoverySystems {
+		if !strutil.ListContains(m.CurrentRecoverySystems, label) {
+			addProblem("good recovery system %q is not a current recovery system", label)
+		}
+	}
+	labels := make([]string, 0, len(m.RecoverySystemModels))
+	for label := range m.RecoverySystemModels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		if !strutil.ListContains(m.CurrentRecoverySystems, label) {
+			addProblem("model of recovery system %q is tracked but it is not a current recovery system", label)
+		}
+	}
+
+	switch len(problems) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("inconsistent modeenv: %s", problems[0])
+	}
+	return fmt.Errorf("inconsistent modeenv:\n- %s", strings.Join(problems, "\n- "))
+}
+
 // modelForSealing is a helper type that implements
 // github.com/snapcore/secboot.SnapModel interface.
 type modelForSealing struct {
@@ -420,6 +639,42 @@ func (m *Modeenv) clearTryModel() {
 	m.TryModelSignKeyID = ""
 }
 
+// recoverySystemModelDigest returns a digest of the properties identifying the
+// model of a recovery system, that is its brand, name, grade and the key it was
+// signed with.
+func recoverySystemModelDigest(model secboot.ModelForSealing) string {
+	h := crypto.SHA3_384.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n", model.BrandID(), model.Model(), model.Grade(), model.SignKeyID())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// setRecoverySystemModel records the digest of the model of a recovery system
+// with the given label. Returns true if the modeenv was modified.
+func (m *Modeenv) setRecoverySystemModel(systemLabel string, model secboot.ModelForSealing) bool {
+	digest := recoverySystemModelDigest(model)
+	if m.RecoverySystemModels[systemLabel] == digest {
+		return false
+	}
+	if m.RecoverySystemModels == nil {
+		m.RecoverySystemModels = make(recoverySystemModels)
+	}
+	m.RecoverySystemModels[systemLabel] = digest
+	return true
+}
+
+// dropRecoverySystemModel drops the model digest of a recovery system with the
+// given label. Returns true if the modeenv was modified.
+func (m *Modeenv) dropRecoverySystemModel(systemLabel string) bool {
+	if _, ok := m.RecoverySystemModels[systemLabel]; !ok {
+		return false
+	}
+	delete(m.RecoverySystemModels, systemLabel)
+	if len(m.RecoverySystemModels) == 0 {
+		m.RecoverySystemModels = nil
+	}
+	return true
+}
+
 type modeenvValueMarshaller interface {
 	MarshalModeenvValue() (string, error)
 }
@@ -569,6 +824,34 @@ func (b *bootAssetsMap) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+func (r recoverySystemModels) MarshalJSON() ([]byte, error) {
+	asMap := map[string]string(r)
+	return json.Marshal(asMap)
+}
+
+func (r *recoverySystemModels) UnmarshalJSON(data []byte) error {
+	var asMap map[string]string
+	if err := json.Unmarshal(data, &asMap); err != nil {
+		return err
+	}
+	*r = recoverySystemModels(asMap)
+	return nil
+}
+
+func (r recoverySystemBootAssets) MarshalJSON() ([]byte, error) {
+	asMap := map[string]bootAssetsMap(r)
+	return json.Marshal(asMap)
+}
+
+func (r *recoverySystemBootAssets) UnmarshalJSON(data []byte) error {
+	var asMap map[string]bootAssetsMap
+	if err := json.Unmarshal(data, &asMap); err != nil {
+		return err
+	}
+	*r = recoverySystemBootAssets(asMap)
+	return nil
+}
+
 func (s bootCommandLines) MarshalJSON() ([]byte, error) {
 	return json.Marshal([]string(s))
 }
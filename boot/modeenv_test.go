@@ -33,7 +33,9 @@ import (
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/boot/boottest"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/snapdenv"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -60,6 +62,7 @@ func (s *modeenvSuite) TestKnownKnown(c *C) {
 		"recovery_system":          true,
 		"current_recovery_systems": true,
 		"good_recovery_systems":    true,
+		"recovery_system_models":   true,
 		"boot_flags":               true,
 		// keep this comment to make old go fmt happy
 		"base":                  true,
@@ -78,6 +81,7 @@ func (s *modeenvSuite) TestKnownKnown(c *C) {
 		"current_kernel_command_lines":         true,
 		"current_trusted_boot_assets":          true,
 		"current_trusted_recovery_boot_assets": true,
+		"recovery_system_trusted_boot_assets":  true,
 	})
 }
 
@@ -211,8 +215,8 @@ base_status=try
 	c.Assert(dirs.SnapModeenvFileUnder(s.tmpdir), testutil.FilePresent)
 	origBytes, err := ioutil.ReadFile(dirs.SnapModeenvFileUnder(s.tmpdir) + ".orig")
 	c.Assert(err, IsNil)
-	// the files should be the same
-	c.Assert(dirs.SnapModeenvFileUnder(s.tmpdir), testutil.FileEquals, string(origBytes))
+	// the files should have the same entries
+	c.Assert(dirs.SnapModeenvFileUnder(s.tmpdir), testutil.FileEquals, boottest.VersionedModeenv(string(origBytes)))
 }
 
 func (s *modeenvSuite) TestCopyMemoryWriteFails(c *C) {
@@ -378,12 +382,12 @@ a_key=other
 
 	c.Assert(modeenv.Write(), IsNil)
 
-	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=recovery
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, boottest.VersionedModeenv(`mode=recovery
 recovery_system=20191126
 a_key=other
 first_unknown=thing
 unknown_key=some unknown value
-`)
+`))
 }
 
 func (s *modeenvSuite) TestReadModeenvWithUnknownKeysDeepEqualsSameWithoutUnknownKeys(c *C) {
@@ -539,7 +543,7 @@ func (s *modeenvSuite) TestWriteToNonExisting(c *C) {
 	err := modeenv.WriteTo(s.tmpdir)
 	c.Assert(err, IsNil)
 
-	c.Assert(s.mockModeenvPath, testutil.FileEquals, "mode=run\n")
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, boottest.VersionedModeenv("mode=run\n"))
 }
 
 func (s *modeenvSuite) TestWriteToExisting(c *C) {
@@ -551,7 +555,7 @@ func (s *modeenvSuite) TestWriteToExisting(c *C) {
 	err = modeenv.WriteTo(s.tmpdir)
 	c.Assert(err, IsNil)
 
-	c.Assert(s.mockModeenvPath, testutil.FileEquals, "mode=recovery\n")
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, boottest.VersionedModeenv("mode=recovery\n"))
 }
 
 func (s *modeenvSuite) TestWriteExisting(c *C) {
@@ -563,7 +567,7 @@ func (s *modeenvSuite) TestWriteExisting(c *C) {
 	err = modeenv.Write()
 	c.Assert(err, IsNil)
 
-	c.Assert(s.mockModeenvPath, testutil.FileEquals, "mode=recovery\n")
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, boottest.VersionedModeenv("mode=recovery\n"))
 }
 
 func (s *modeenvSuite) TestWriteFreshError(c *C) {
@@ -616,14 +620,14 @@ func (s *modeenvSuite) TestWriteToNonExistingFull(c *C) {
 	err := modeenv.WriteTo(s.tmpdir)
 	c.Assert(err, IsNil)
 
-	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=run
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, boottest.VersionedModeenv(`mode=run
 recovery_system=20191128
 current_recovery_systems=20191128,2020-02-03,20240101-FOO
 base=core20_321.snap
 try_base=core20_322.snap
 base_status=try
 current_kernels=pc-kernel_1.snap,pc-kernel_2.snap
-`)
+`))
 }
 
 func (s *modeenvSuite) TestReadRecoverySystems(c *C) {
@@ -755,11 +759,11 @@ func (s *modeenvSuite) TestMarshalCurrentTrustedBootAssets(c *C) {
 	err := modeenv.WriteTo(s.tmpdir)
 	c.Assert(err, IsNil)
 
-	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=run
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, boottest.VersionedModeenv(`mode=run
 recovery_system=20191128
 current_trusted_boot_assets={"grubx64.efi":["hash1","hash2"]}
 current_trusted_recovery_boot_assets={"bootx64.efi":["shimhash1","shimhash2"],"grubx64.efi":["recovery-hash1"]}
-`)
+`))
 
 	modeenvRead, err := boot.ReadModeenv(s.tmpdir)
 	c.Assert(err, IsNil)
@@ -786,10 +790,10 @@ func (s *modeenvSuite) TestMarshalKernelCommandLines(c *C) {
 	err := modeenv.WriteTo(s.tmpdir)
 	c.Assert(err, IsNil)
 
-	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=run
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, boottest.VersionedModeenv(`mode=run
 recovery_system=20191128
 current_kernel_command_lines=["snapd_recovery_mode=run panic=-1 console=ttyS0,io,9600n8","snapd_recovery_mode=run candidate panic=-1 console=ttyS0,io,9600n8"]
-`)
+`))
 
 	modeenvRead, err := boot.ReadModeenv(s.tmpdir)
 	c.Assert(err, IsNil)
@@ -799,6 +803,61 @@ current_kernel_command_lines=["snapd_recovery_mode=run panic=-1 console=ttyS0,io
 	})
 }
 
+func (s *modeenvSuite) TestMarshalRecoverySystemModels(c *C) {
+	c.Assert(s.mockModeenvPath, testutil.FileAbsent)
+
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		RecoverySystem:         "20191128",
+		CurrentRecoverySystems: []string{"20191128", "20200825"},
+		RecoverySystemModels: boot.RecoverySystemModels{
+			"20191128": "digest-1",
+			"20200825": "digest-2",
+		},
+	}
+	err := modeenv.WriteTo(s.tmpdir)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, boottest.VersionedModeenv(`mode=run
+recovery_system=20191128
+current_recovery_systems=20191128,20200825
+recovery_system_models={"20191128":"digest-1","20200825":"digest-2"}
+`))
+
+	modeenvRead, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Assert(modeenvRead.RecoverySystemModels, DeepEquals, boot.RecoverySystemModels{
+		"20191128": "digest-1",
+		"20200825": "digest-2",
+	})
+}
+
+func (s *modeenvSuite) TestReadRecoverySystemModelsAbsent(c *C) {
+	// modeenv written before the models of recovery systems were tracked
+	s.makeMockModeenvFile(c, `mode=run
+recovery_system=20191128
+current_recovery_systems=20191128
+good_recovery_systems=20191128
+`)
+
+	modeenv, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(modeenv.GoodRecoverySystems, DeepEquals, []string{"20191128"})
+	c.Check(modeenv.RecoverySystemModels, IsNil)
+}
+
+func (s *modeenvSuite) TestRecoverySystemModelDigest(c *C) {
+	model := boottest.MakeMockUC20Model()
+	otherModel := boottest.MakeMockUC20Model(map[string]interface{}{
+		"grade": "signed",
+	})
+
+	digest := boot.RecoverySystemModelDigest(model)
+	c.Check(digest, HasLen, 96)
+	c.Check(boot.RecoverySystemModelDigest(model), Equals, digest)
+	c.Check(boot.RecoverySystemModelDigest(otherModel), Not(Equals), digest)
+}
+
 func (s *modeenvSuite) TestModeenvWithModelGradeSignKeyID(c *C) {
 	s.makeMockModeenvFile(c, `mode=run
 model=canonical/ubuntu-core-20-amd64
@@ -836,14 +895,14 @@ try_model_sign_key_id=EAD4DbLxK_kn0gzNCXOs3kd6DeMU3f-L6BEsSEuJGBqCORR0gXkdDxMbOm
 	// and write it
 	c.Assert(modeenv.Write(), IsNil)
 
-	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=run
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, boottest.VersionedModeenv(`mode=run
 model=developer1/testkeys-snapd-signed-core-20-amd64
 grade=signed
 model_sign_key_id=EAD4DbLxK_kn0gzNCXOs3kd6DeMU3f-L6BEsSEuJGBqCORR0gXkdDxMbOm11mRFu
 try_model=foo/bar
 try_grade=dangerous
 try_model_sign_key_id=9tydnLa6MTJ-jaQTFUXEwHl1yRx7ZS4K5cyFDhYDcPzhS7uyEkDxdUjg9g08BtNn
-`)
+`))
 }
 
 func (s *modeenvSuite) TestModeenvWithClassicModelGradeSignKeyID(c *C) {
@@ -884,7 +943,7 @@ try_model_sign_key_id=EAD4DbLxK_kn0gzNCXOs3kd6DeMU3f-L6BEsSEuJGBqCORR0gXkdDxMbOm
 	// and write it
 	c.Assert(modeenv.Write(), IsNil)
 
-	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=run
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, boottest.VersionedModeenv(`mode=run
 model=developer1/testkeys-snapd-signed-classic-20-amd64
 classic=true
 grade=signed
@@ -892,7 +951,7 @@ model_sign_key_id=EAD4DbLxK_kn0gzNCXOs3kd6DeMU3f-L6BEsSEuJGBqCORR0gXkdDxMbOm11mR
 try_model=foo/bar
 try_grade=dangerous
 try_model_sign_key_id=9tydnLa6MTJ-jaQTFUXEwHl1yRx7ZS4K5cyFDhYDcPzhS7uyEkDxdUjg9g08BtNn
-`)
+`))
 }
 
 func (s *modeenvSuite) TestModelForSealing(c *C) {
@@ -975,3 +1034,182 @@ func (s *modeenvSuite) TestModeenvAccessFailsDuringPreseeding(c *C) {
 	err = modeenv.WriteTo(s.tmpdir)
 	c.Assert(err, ErrorMatches, `internal error: modeenv cannot be written during preseeding`)
 }
+
+func (s *modeenvSuite) TestWriteVersionedFormat(c *C) {
+	modeenv := &boot.Modeenv{Mode: "run"}
+	err := modeenv.WriteTo(s.tmpdir)
+	c.Assert(err, IsNil)
+
+	c.Check(s.mockModeenvPath, testutil.FileEquals, `#version=2
+mode=run
+#sha3-384=abd27a475542f51dcfef57845c2852625a2dfd190b6fef297ee1155eb0a887b74ad055ebb4380f8be380c766c40c5b7c
+`)
+	// the unversioned copy is kept alongside
+	c.Check(s.mockModeenvPath+".legacy", testutil.FileEquals, "mode=run\n")
+
+	// readers of the unversioned format ignore the comments
+	cfg := goconfigparser.New()
+	cfg.AllowNoSectionHeader = true
+	err = cfg.ReadFile(s.mockModeenvPath)
+	c.Assert(err, IsNil)
+	opts, err := cfg.Options("")
+	c.Assert(err, IsNil)
+	c.Check(opts, DeepEquals, []string{"mode"})
+
+	readModeenv, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(readModeenv.Mode, Equals, "run")
+}
+
+func (s *modeenvSuite) TestWriteUnversionedCopyLast(c *C) {
+	modeenv := &boot.Modeenv{Mode: "run", Base: "core20_1.snap"}
+	err := modeenv.WriteTo(s.tmpdir)
+	c.Assert(err, IsNil)
+
+	// the unversioned copy cannot be written
+	err = os.Remove(s.mockModeenvPath + ".legacy")
+	c.Assert(err, IsNil)
+	err = os.Mkdir(s.mockModeenvPath+".legacy", 0755)
+	c.Assert(err, IsNil)
+	modeenv.Base = "core20_2.snap"
+	err = modeenv.WriteTo(s.tmpdir)
+	c.Assert(err, NotNil)
+
+	// but the versioned modeenv was already updated
+	readModeenv, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(readModeenv.Base, Equals, "core20_2.snap")
+}
+
+func (s *modeenvSuite) TestReadCorruptedFallsBackToUnversionedCopy(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	modeenv := &boot.Modeenv{Mode: "run", Base: "core20_1.snap"}
+	err := modeenv.WriteTo(s.tmpdir)
+	c.Assert(err, IsNil)
+
+	data, err := os.ReadFile(s.mockModeenvPath)
+	c.Assert(err, IsNil)
+	// a block of the file was zeroed
+	entry := []byte("base=core20_1.snap")
+	corrupted := bytes.Replace(data, entry, make([]byte, len(entry)), 1)
+	s.makeMockModeenvFile(c, string(corrupted))
+
+	readModeenv, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(readModeenv.Base, Equals, "core20_1.snap")
+	c.Check(logbuf.String(), testutil.Contains, "modeenv checksum mismatch")
+	c.Check(logbuf.String(), testutil.Contains, "falling back to "+s.mockModeenvPath+".legacy")
+
+	// without the unversioned copy the modeenv cannot be read
+	err = os.Remove(s.mockModeenvPath + ".legacy")
+	c.Assert(err, IsNil)
+	_, err = boot.ReadModeenv(s.tmpdir)
+	c.Check(err, ErrorMatches, `cannot read modeenv .*/modeenv: modeenv checksum mismatch, expected [0-9a-f]+ got [0-9a-f]+`)
+}
+
+func (s *modeenvSuite) TestReadModifiedVersionedIsAnError(c *C) {
+	modeenv := &boot.Modeenv{Mode: "run", Base: "core20_1.snap"}
+	err := modeenv.WriteTo(s.tmpdir)
+	c.Assert(err, IsNil)
+
+	data, err := os.ReadFile(s.mockModeenvPath)
+	c.Assert(err, IsNil)
+	// the entries were modified, but can still be parsed
+	modified := bytes.Replace(data, []byte("core20_1"), []byte("core20_2"), 1)
+	s.makeMockModeenvFile(c, string(modified))
+
+	// the unversioned copy is not used in place of the modified modeenv
+	_, err = boot.ReadModeenv(s.tmpdir)
+	c.Check(err, ErrorMatches, `cannot read modeenv .*/modeenv: modeenv checksum mismatch, expected [0-9a-f]+ got [0-9a-f]+`)
+}
+
+func (s *modeenvSuite) TestReadInvalidVersioned(c *C) {
+	for _, tc := range []struct {
+		content string
+		err     string
+	}{
+		{"#version=2", "modeenv is truncated"},
+		{"#version=2\nmode=run", "modeenv is truncated"},
+		{"#version=2\nmode=run\n", "modeenv checksum is missing"},
+		{"#version=3\nmode=run\n#sha3-384=1234\n", `unsupported modeenv format version "3"`},
+		{"#version=2\nmode=run\n#sha3-384=1234\n", "modeenv checksum mismatch, expected 1234 got abd27a47.*"},
+	} {
+		s.makeMockModeenvFile(c, tc.content)
+		_, err := boot.ReadModeenv(s.tmpdir)
+		c.Check(err, ErrorMatches, "cannot read modeenv .*: "+tc.err, Commentf("content: %q", tc.content))
+	}
+}
+
+func (s *modeenvSuite) TestReadUnversionedLogs(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+	os.Setenv("SNAPD_DEBUG", "1")
+	defer os.Unsetenv("SNAPD_DEBUG")
+
+	s.makeMockModeenvFile(c, "mode=run\n")
+	modeenv, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(modeenv.Mode, Equals, "run")
+	c.Check(logbuf.String(), testutil.Contains, "in the unversioned format")
+}
+
+func (s *modeenvSuite) TestValidateModeenvHappy(c *C) {
+	seedDir := c.MkDir()
+	for _, label := range []string{"20191126", "20200101"} {
+		err := os.MkdirAll(filepath.Join(seedDir, "systems", label), 0755)
+		c.Assert(err, IsNil)
+	}
+
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		RecoverySystem:         "20191126",
+		CurrentRecoverySystems: []string{"20191126", "20200101"},
+		GoodRecoverySystems:    []string{"20191126"},
+		Base:                   "core20_1.snap",
+		TryBase:                "core20_2.snap",
+		BaseStatus:             boot.TryStatus,
+		CurrentKernels:         []string{"pc-kernel_1.snap"},
+		Model:                  "my-model",
+		BrandID:                "my-brand",
+	}
+	c.Check(boot.ValidateModeenv(modeenv, seedDir), IsNil)
+}
+
+func (s *modeenvSuite) TestValidateModeenvInconsistent(c *C) {
+	seedDir := c.MkDir()
+	err := os.MkdirAll(filepath.Join(seedDir, "systems", "20191126"), 0755)
+	c.Assert(err, IsNil)
+
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		CurrentRecoverySystems: []string{"20191126", "1234"},
+		CurrentKernels:         []string{"pc-kernel_1.snap"},
+	}
+	err = boot.ValidateModeenv(modeenv, seedDir)
+	c.Check(err, ErrorMatches, `inconsistent modeenv: current recovery system "1234" does not exist in .*`)
+
+	modeenv = &boot.Modeenv{
+		Mode:                   "bogus",
+		BaseStatus:             boot.TryingStatus,
+		CurrentRecoverySystems: []string{"20191126"},
+		GoodRecoverySystems:    []string{"20191126", "20200101"},
+		Model:                  "my-model",
+	}
+	err = boot.ValidateModeenv(modeenv, seedDir)
+	c.Check(err, ErrorMatches, `inconsistent modeenv:
+- unknown mode "bogus"
+- base status is "trying" but try base is unset
+- model "my-model" has no brand
+- good recovery system "20200101" is not a current recovery system`)
+
+	modeenv = &boot.Modeenv{
+		Mode:       "run",
+		BaseStatus: "other",
+	}
+	err = boot.ValidateModeenv(modeenv, seedDir)
+	c.Check(err, ErrorMatches, `inconsistent modeenv:
+- unknown base status "other"
+- no current kernels`)
+}
@@ -0,0 +1,178 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+)
+
+// A reseal batch coalesces the reseals of the encryption keys requested by
+// the multiple steps of a change, like the update of the gadget assets, of
+// the kernel command line and of the kernel itself. The batch is tracked by a
+// marker file, so that it survives restarts of snapd.
+//
+// Only the reseals which drop boot assets, kernels or command lines from the
+// boot chains are deferred, those are performed once when the batch is
+// finished. The reseals for boot chains gaining new boot assets, kernels or
+// command lines are still performed right away, before these reach the disk,
+// so that the keys remain sealed to both the old and the new boot chains
+// should the change be interrupted at any point. As the keys are then sealed
+// to the current modeenv, such a reseal also covers any deferred one.
+
+type resealBatch struct {
+	// Requests is the number of reseals requested since the batch was
+	// started.
+	Requests int `json:"requests"`
+	// Pending is set if any of the requests was deferred and not yet
+	// covered by a reseal.
+	Pending bool `json:"pending,omitempty"`
+	// ExpectReseal is set if any of the pending requests was expecting a
+	// reseal.
+	ExpectReseal bool `json:"expect-reseal,omitempty"`
+}
+
+func resealBatchFileUnder(rootdir string) string {
+	return filepath.Join(dirs.SnapFDEDirUnder(rootdir), "reseal-batch")
+}
+
+// readResealBatch returns the reseal batch in progress, or nil if there is
+// none.
+func readResealBatch(rootdir string) (*resealBatch, error) {
+	data, err := os.ReadFile(resealBatchFileUnder(rootdir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var batch resealBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("cannot decode reseal batch: %v", err)
+	}
+	return &batch, nil
+}
+
+func writeResealBatch(rootdir string, batch *resealBatch) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	path := resealBatchFileUnder(rootdir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(path, data, 0600, 0)
+}
+
+// BeginResealBatch starts coalescing the reseals of the encryption keys
+// which drop parts of the boot chains, until FinishResealBatch is called. Starting a batch when one is already in
+// progress does nothing.
+func BeginResealBatch() error {
+	modeenvLock()
+	defer modeenvUnlock()
+
+	batch, err := readResealBatch(dirs.GlobalRootDir)
+	if err != nil {
+		return err
+	}
+	if batch != nil {
+		return nil
+	}
+	return writeResealBatch(dirs.GlobalRootDir, &resealBatch{})
+}
+
+// FinishResealBatch finishes the reseal batch in progress, if any, and
+// performs a single reseal of the encryption keys to the current modeenv if
+// any deferred reseal is still pending. It returns the number of reseals
+// requested during the batch.
+func FinishResealBatch(unlocker Unlocker) (requests int, err error) {
+	modeenvLock()
+	defer modeenvUnlock()
+
+	batch, err := readResealBatch(dirs.GlobalRootDir)
+	if err != nil {
+		return 0, err
+	}
+	if batch == nil {
+		return 0, nil
+	}
+	if batch.Pending {
+		m, err := loadModeenv()
+		if err != nil {
+			return 0, err
+		}
+		if err := resealKeyToModeenv(dirs.GlobalRootDir, m, batch.ExpectReseal, unlocker); err != nil {
+			// keep the batch around so that the reseal is retried
+			return 0, err
+		}
+		logger.Debugf("resealed once for %d coalesced reseal requests", batch.Requests)
+	}
+	if err := os.Remove(resealBatchFileUnder(dirs.GlobalRootDir)); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	return batch.Requests, nil
+}
+
+// resealKeyToModeenvNow reseals the encryption keys to the modeenv right
+// away. It must be used when boot assets, kernels or command lines are about
+// to be added to the boot chains, as the keys must be sealed to the new boot
+// chains before these reach the disk.
+func resealKeyToModeenvNow(rootdir string, modeenv *Modeenv, expectReseal bool, unlocker Unlocker) error {
+	batch, err := readResealBatch(rootdir)
+	if err != nil {
+		return err
+	}
+	if err := resealKeyToModeenv(rootdir, modeenv, expectReseal, unlocker); err != nil {
+		return err
+	}
+	if batch == nil {
+		return nil
+	}
+	// any deferred reseal is covered now
+	batch.Requests++
+	batch.Pending = false
+	batch.ExpectReseal = false
+	return writeResealBatch(rootdir, batch)
+}
+
+// resealKeyToModeenvOrDefer reseals the encryption keys to the modeenv, or
+// only records that a reseal is needed if a reseal batch is in progress. It
+// must only be used when boot assets, kernels or command lines are dropped
+// from the boot chains.
+func resealKeyToModeenvOrDefer(rootdir string, modeenv *Modeenv, expectReseal bool, unlocker Unlocker) error {
+	batch, err := readResealBatch(rootdir)
+	if err != nil {
+		return err
+	}
+	if batch == nil {
+		return resealKeyToModeenv(rootdir, modeenv, expectReseal, unlocker)
+	}
+	batch.Requests++
+	batch.Pending = true
+	batch.ExpectReseal = batch.ExpectReseal || expectReseal
+	return writeResealBatch(rootdir, batch)
+}
@@ -0,0 +1,188 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot_test
+
+import (
+	"errors"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type resealBatchSuite struct {
+	baseBootenvSuite
+
+	reseals      int
+	expectReseal bool
+	resealErr    error
+}
+
+var _ = Suite(&resealBatchSuite{})
+
+func (s *resealBatchSuite) SetUpTest(c *C) {
+	s.baseBootenvSuite.SetUpTest(c)
+
+	s.reseals = 0
+	s.expectReseal = false
+	s.resealErr = nil
+	s.AddCleanup(boot.MockResealKeyToModeenv(func(rootdir string, m *boot.Modeenv, expectReseal bool, u boot.Unlocker) error {
+		s.reseals++
+		s.expectReseal = expectReseal
+		c.Check(m.Mode, Equals, "run")
+		return s.resealErr
+	}))
+
+	m := &boot.Modeenv{Mode: "run"}
+	c.Assert(m.WriteTo(""), IsNil)
+}
+
+func (s *resealBatchSuite) markerFile() string {
+	return filepath.Join(dirs.SnapFDEDir, "reseal-batch")
+}
+
+func (s *resealBatchSuite) TestNoBatchReseals(c *C) {
+	err := boot.ResealKeyToModeenvOrDefer(dirs.GlobalRootDir, &boot.Modeenv{Mode: "run"}, true, nil)
+	c.Assert(err, IsNil)
+	c.Check(s.reseals, Equals, 1)
+	c.Check(s.markerFile(), testutil.FileAbsent)
+}
+
+func (s *resealBatchSuite) TestBatchCoalescesReseals(c *C) {
+	c.Assert(boot.BeginResealBatch(), IsNil)
+	c.Check(s.markerFile(), testutil.FilePresent)
+
+	m := &boot.Modeenv{Mode: "run"}
+	c.Assert(boot.ResealKeyToModeenvOrDefer(dirs.GlobalRootDir, m, false, nil), IsNil)
+	c.Assert(boot.ResealKeyToModeenvOrDefer(dirs.GlobalRootDir, m, true, nil), IsNil)
+	c.Assert(boot.ResealKeyToModeenvOrDefer(dirs.GlobalRootDir, m, false, nil), IsNil)
+	c.Check(s.reseals, Equals, 0)
+
+	// beginning again keeps the requests
+	c.Assert(boot.BeginResealBatch(), IsNil)
+
+	requests, err := boot.FinishResealBatch(nil)
+	c.Assert(err, IsNil)
+	c.Check(requests, Equals, 3)
+	c.Check(s.reseals, Equals, 1)
+	c.Check(s.expectReseal, Equals, true)
+	c.Check(s.markerFile(), testutil.FileAbsent)
+
+	// the batch is over
+	c.Assert(boot.ResealKeyToModeenvOrDefer(dirs.GlobalRootDir, m, false, nil), IsNil)
+	c.Check(s.reseals, Equals, 2)
+}
+
+func (s *resealBatchSuite) TestFinishNothingRequested(c *C) {
+	c.Assert(boot.BeginResealBatch(), IsNil)
+
+	requests, err := boot.FinishResealBatch(nil)
+	c.Assert(err, IsNil)
+	c.Check(requests, Equals, 0)
+	c.Check(s.reseals, Equals, 0)
+	c.Check(s.markerFile(), testutil.FileAbsent)
+}
+
+func (s *resealBatchSuite) TestFinishNoBatch(c *C) {
+	requests, err := boot.FinishResealBatch(nil)
+	c.Assert(err, IsNil)
+	c.Check(requests, Equals, 0)
+	c.Check(s.reseals, Equals, 0)
+}
+
+func (s *resealBatchSuite) TestFinishResealErrorKeepsBatch(c *C) {
+	c.Assert(boot.BeginResealBatch(), IsNil)
+	c.Assert(boot.ResealKeyToModeenvOrDefer(dirs.GlobalRootDir, &boot.Modeenv{Mode: "run"}, true, nil), IsNil)
+
+	s.resealErr = errors.New("reseal failed")
+	_, err := boot.FinishResealBatch(nil)
+	c.Assert(err, ErrorMatches, "reseal failed")
+	c.Check(s.markerFile(), testutil.FilePresent)
+
+	// retried
+	s.resealErr = nil
+	requests, err := boot.FinishResealBatch(nil)
+	c.Assert(err, IsNil)
+	c.Check(requests, Equals, 1)
+	c.Check(s.reseals, Equals, 2)
+	c.Check(s.markerFile(), testutil.FileAbsent)
+}
+
+func (s *resealBatchSuite) TestBatchResealsNowForNewBootChains(c *C) {
+	c.Assert(boot.BeginResealBatch(), IsNil)
+
+	m := &boot.Modeenv{Mode: "run"}
+	c.Assert(boot.ResealKeyToModeenvNow(dirs.GlobalRootDir, m, true, nil), IsNil)
+	// not deferred
+	c.Check(s.reseals, Equals, 1)
+
+	requests, err := boot.FinishResealBatch(nil)
+	c.Assert(err, IsNil)
+	c.Check(requests, Equals, 1)
+	// nothing left to reseal
+	c.Check(s.reseals, Equals, 1)
+	c.Check(s.markerFile(), testutil.FileAbsent)
+}
+
+func (s *resealBatchSuite) TestBatchResealNowCoversDeferred(c *C) {
+	c.Assert(boot.BeginResealBatch(), IsNil)
+
+	m := &boot.Modeenv{Mode: "run"}
+	c.Assert(boot.ResealKeyToModeenvOrDefer(dirs.GlobalRootDir, m, true, nil), IsNil)
+	c.Check(s.reseals, Equals, 0)
+	c.Assert(boot.ResealKeyToModeenvNow(dirs.GlobalRootDir, m, false, nil), IsNil)
+	c.Check(s.reseals, Equals, 1)
+	c.Check(s.expectReseal, Equals, false)
+
+	requests, err := boot.FinishResealBatch(nil)
+	c.Assert(err, IsNil)
+	c.Check(requests, Equals, 2)
+	c.Check(s.reseals, Equals, 1)
+
+	// a request deferred after that is still performed
+	c.Assert(boot.BeginResealBatch(), IsNil)
+	c.Assert(boot.ResealKeyToModeenvNow(dirs.GlobalRootDir, m, false, nil), IsNil)
+	c.Assert(boot.ResealKeyToModeenvOrDefer(dirs.GlobalRootDir, m, true, nil), IsNil)
+	c.Check(s.reseals, Equals, 2)
+
+	requests, err = boot.FinishResealBatch(nil)
+	c.Assert(err, IsNil)
+	c.Check(requests, Equals, 2)
+	c.Check(s.reseals, Equals, 3)
+	c.Check(s.expectReseal, Equals, true)
+}
+
+func (s *resealBatchSuite) TestBatchResealNowErrorKeepsDeferred(c *C) {
+	c.Assert(boot.BeginResealBatch(), IsNil)
+
+	m := &boot.Modeenv{Mode: "run"}
+	c.Assert(boot.ResealKeyToModeenvOrDefer(dirs.GlobalRootDir, m, true, nil), IsNil)
+	s.resealErr = errors.New("reseal failed")
+	c.Assert(boot.ResealKeyToModeenvNow(dirs.GlobalRootDir, m, false, nil), ErrorMatches, "reseal failed")
+
+	s.resealErr = nil
+	requests, err := boot.FinishResealBatch(nil)
+	c.Assert(err, IsNil)
+	c.Check(requests, Equals, 1)
+	c.Check(s.reseals, Equals, 2)
+}
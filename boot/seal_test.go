@@ -1307,6 +1307,40 @@ func (s *sealSuite) TestRecoveryBootChainsForSystems(c *C) {
 				"snapd_recovery_mode=factory-reset snapd_recovery_system=20200831 console=ttyS0 console=tty1 panic=-1 some-extra-for-20200831",
 			}},
 		},
+		{
+			desc:            "two systems with recovery command lines",
+			recoverySystems: []string{"20200825", "20200831"},
+			modesForSystems: map[string][]string{
+				"20200825": {boot.ModeRecover, boot.ModeFactoryReset},
+				"20200831": {boot.ModeRecover, boot.ModeFactoryReset},
+			},
+			assetsMap: boot.BootAssetsMap{
+				"grubx64.efi": []string{"grub-hash-1", "grub-hash-2"},
+				"bootx64.efi": []string{"shim-hash-1"},
+			},
+			expectedAssets: []boot.BootAsset{
+				{Role: bootloader.RoleRecovery, Name: "bootx64.efi", Hashes: []string{"shim-hash-1"}},
+				{Role: bootloader.RoleRecovery, Name: "grubx64.efi", Hashes: []string{"grub-hash-1", "grub-hash-2"}},
+			},
+			gadgetFilesForSystem: map[string][][]string{
+				"20200825": {
+					{"cmdline.extra", "extra for 20200825"},
+					{"cmdline.extra.recover", "recover extra for 20200825"},
+				},
+				"20200831": {
+					{"cmdline.extra", "some-extra-for-20200831"},
+					{"cmdline.full.recover", "recover-full-for-20200831"},
+				},
+			},
+			expectedKernelRevs: []int{1, 3},
+			expectedCmdlines: [][]string{{
+				"snapd_recovery_mode=recover snapd_recovery_system=20200825 console=ttyS0 console=tty1 panic=-1 recover extra for 20200825",
+				"snapd_recovery_mode=factory-reset snapd_recovery_system=20200825 console=ttyS0 console=tty1 panic=-1 recover extra for 20200825",
+			}, {
+				"snapd_recovery_mode=recover snapd_recovery_system=20200831 recover-full-for-20200831",
+				"snapd_recovery_mode=factory-reset snapd_recovery_system=20200831 recover-full-for-20200831",
+			}},
+		},
 		{
 			desc:            "three systems, one with different model",
 			recoverySystems: []string{"20200825", "20200831", "off-model"},
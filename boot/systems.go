@@ -21,11 +21,18 @@ package boot
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snapfile"
 	"github.com/snapcore/snapd/strutil"
 )
 
@@ -49,10 +56,13 @@ func ClearTryRecoverySystem(dev snap.Device, systemLabel string) error {
 	modeenvLock()
 	defer modeenvUnlock()
 
-	return clearTryRecoverySystem(dev, systemLabel)
+	return clearTryRecoverySystem(dev, systemLabel, nil)
 }
 
-func clearTryRecoverySystem(dev snap.Device, systemLabel string) error {
+// clearTryRecoverySystem clears the candidate recovery system, any extra
+// variables are reset along with the try variables in a single update of the
+// bootloader environment.
+func clearTryRecoverySystem(dev snap.Device, systemLabel string, extraVars map[string]string) error {
 	m, err := loadModeenv()
 	if err != nil {
 		return err
@@ -89,6 +99,9 @@ func clearTryRecoverySystem(dev snap.Device, systemLabel string) error {
 		"try_recovery_system":    "",
 		"recovery_system_status": "",
 	}
+	for k, v := range extraVars {
+		vars[k] = v
+	}
 	// try to clear regardless of reseal failing
 	blErr := bl.SetBootVars(vars)
 
@@ -109,7 +122,21 @@ func clearTryRecoverySystem(dev snap.Device, systemLabel string) error {
 // optionally sets a try model, if the device model is different from the
 // current one, which typically can happen during a remodel. Once done, the
 // caller should request switching to the given recovery system.
-func SetTryRecoverySystem(dev snap.Device, systemLabel string) (err error) {
+func SetTryRecoverySystem(dev snap.Device, systemLabel string) error {
+	const bootIntoSystem = false
+	return setTryRecoverySystem(dev, systemLabel, bootIntoSystem)
+}
+
+// SetTryRecoverySystemForNextBoot is like SetTryRecoverySystem, but also sets
+// up the recovery bootloader to boot into the tried system in recover mode.
+// All the boot variables are set in a single update of the bootloader
+// environment, such that an unexpected reboot cannot leave them inconsistent.
+func SetTryRecoverySystemForNextBoot(dev snap.Device, systemLabel string) error {
+	const bootIntoSystem = true
+	return setTryRecoverySystem(dev, systemLabel, bootIntoSystem)
+}
+
+func setTryRecoverySystem(dev snap.Device, systemLabel string, bootIntoSystem bool) (err error) {
 	if !dev.HasModeenv() {
 		return fmt.Errorf("internal error: recovery systems can only be used on UC20+")
 	}
@@ -147,28 +174,55 @@ func SetTryRecoverySystem(dev snap.Device, systemLabel string) (err error) {
 		m.setTryModel(model)
 		modified = true
 	}
+	// keep track of the model the system was created for, such that it can
+	// be verified when booting the system
+	if m.setRecoverySystemModel(systemLabel, model) {
+		modified = true
+	}
 	if modified {
 		if err := m.Write(); err != nil {
 			return err
 		}
 	}
 
+	// the system and mode to boot into are restored when trying the system
+	// cannot be set up
+	var restoreVars map[string]string
+	if bootIntoSystem {
+		restoreVars, err = bl.GetBootVars("snapd_recovery_system", "snapd_recovery_mode")
+		if err != nil {
+			return err
+		}
+	}
+
 	defer func() {
 		if err == nil {
 			return
 		}
-		if cleanupErr := clearTryRecoverySystem(dev, systemLabel); cleanupErr != nil {
+		// the model of a system that cannot be tried is no longer of
+		// interest
+		if m.dropRecoverySystemModel(systemLabel) {
+			if writeErr := m.Write(); writeErr != nil {
+				err = fmt.Errorf("%v (cleanup failed: %v)", err, writeErr)
+				return
+			}
+		}
+		if cleanupErr := clearTryRecoverySystem(dev, systemLabel, restoreVars); cleanupErr != nil {
 			err = fmt.Errorf("%v (cleanup failed: %v)", err, cleanupErr)
 		}
 	}()
 
-	// even when we unexpectedly reboot after updating the bootenv here, we
-	// should not boot into the tried system, as the caller must explicitly
-	// request that by other means
+	// unless requested by the caller, even when we unexpectedly reboot
+	// after updating the bootenv here, we should not boot into the tried
+	// system, as the caller must explicitly request that by other means
 	vars := map[string]string{
 		"try_recovery_system":    systemLabel,
 		"recovery_system_status": "try",
 	}
+	if bootIntoSystem {
+		vars["snapd_recovery_system"] = systemLabel
+		vars["snapd_recovery_mode"] = "recover"
+	}
 	if err := bl.SetBootVars(vars); err != nil {
 		return err
 	}
@@ -177,7 +231,198 @@ func SetTryRecoverySystem(dev snap.Device, systemLabel string) (err error) {
 	// tried system, data will still be inaccessible and the system will be
 	// considered as nonoperational
 	const expectReseal = true
-	return resealKeyToModeenv(dirs.GlobalRootDir, m, expectReseal, nil)
+	if err := resealKeyToModeenv(dirs.GlobalRootDir, m, expectReseal, nil); err != nil {
+		return &RecoverySystemResealError{System: systemLabel, Err: err}
+	}
+	return nil
+}
+
+// gadgetSeedContentSources returns the gadget sources of the content of the
+// system-seed structure, indexed by the target relative to the root of the
+// structure.
+func gadgetSeedContentSources(info *gadget.Info) map[string]string {
+	sources := make(map[string]string)
+	for _, vol := range info.Volumes {
+		for _, vs := range vol.Structure {
+			if vs.Role != gadget.SystemSeed {
+				continue
+			}
+			for _, vc := range vs.Content {
+				if vc.UnresolvedSource == "" || strings.Contains(vc.UnresolvedSource, ":") {
+					// not a file content, or one coming from the
+					// kernel
+					continue
+				}
+				target := vc.Target
+				if strings.HasSuffix(target, "/") {
+					target = filepath.Join(target, filepath.Base(vc.UnresolvedSource))
+				}
+				target = strings.TrimPrefix(filepath.Clean("/"+target), "/")
+				sources[target] = vc.UnresolvedSource
+			}
+		}
+	}
+	return sources
+}
+
+// InstallRecoverySystemTrustedAssets copies the trusted assets of the recovery
+// bootloader, as provided by the given gadget snap or directory, to the
+// directory of the recovery system with the given label on ubuntu-seed. The
+// assets are added to the boot assets cache and tracked in the modeenv, such
+// that they become part of the recovery boot chains once the keys are
+// resealed, which happens when the system is tried with SetTryRecoverySystem.
+// The assets added by the system are dropped again by DropRecoverySystem. Assets
+// which are not provided by the gadget are skipped, in which case the system
+// boots with the assets already present on ubuntu-seed. Nothing is done when
+// the recovery bootloader has no trusted assets.
+func InstallRecoverySystemTrustedAssets(dev snap.Device, gadgetSnapOrDir, systemLabel string) error {
+	if !dev.HasModeenv() {
+		return fmt.Errorf("internal error: recovery systems can only be used on UC20+")
+	}
+	opts := &bootloader.Options{
+		// setup the recovery bootloader
+		Role: bootloader.RoleRecovery,
+	}
+	bl, trustedAssets, err := findMaybeTrustedBootloaderAndAssets(InitramfsUbuntuSeedDir, opts)
+	if err != nil {
+		return err
+	}
+	if len(trustedAssets) == 0 {
+		return nil
+	}
+
+	snapf, err := snapfile.Open(gadgetSnapOrDir)
+	if err != nil {
+		return err
+	}
+	info, err := gadget.ReadInfoFromSnapFile(snapf, dev.Model())
+	if err != nil {
+		return err
+	}
+	sources := gadgetSeedContentSources(info)
+
+	modeenvLock()
+	defer modeenvUnlock()
+
+	m, err := loadModeenv()
+	if err != nil {
+		return err
+	}
+
+	systemDir := filepath.Join(InitramfsUbuntuSeedDir, "systems", systemLabel)
+	cache := newTrustedAssetsCache(dirs.SnapBootAssetsDir)
+	modified := false
+	for _, asset := range trustedAssets {
+		source, ok := sources[asset]
+		if !ok {
+			logger.Noticef("trusted asset %q of recovery system %q is not provided by the gadget, using the one from ubuntu-seed", asset, systemLabel)
+			continue
+		}
+		data, err := snapf.ReadFile(source)
+		if err != nil {
+			return fmt.Errorf("cannot read trusted asset %q: %v", asset, err)
+		}
+		dst := filepath.Join(systemDir, asset)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := osutil.AtomicWriteFile(dst, data, 0644, 0); err != nil {
+			return err
+		}
+		ta, err := cache.Add(dst, bl.Name(), filepath.Base(asset))
+		if err != nil {
+			return err
+		}
+		if isAssetAlreadyTracked(m.CurrentTrustedRecoveryBootAssets, ta) {
+			// the asset may have been added by another recovery
+			// system, in which case it is kept for as long as any
+			// of the systems uses it, otherwise it is tracked
+			// independently of the recovery systems
+			if !isAssetAddedByRecoverySystem(m.RecoverySystemTrustedBootAssets, ta.name, ta.hash) {
+				continue
+			}
+		} else {
+			if m.CurrentTrustedRecoveryBootAssets == nil {
+				m.CurrentTrustedRecoveryBootAssets = bootAssetsMap{}
+			}
+			m.CurrentTrustedRecoveryBootAssets[ta.name] = append(m.CurrentTrustedRecoveryBootAssets[ta.name], ta.hash)
+		}
+		if m.RecoverySystemTrustedBootAssets == nil {
+			m.RecoverySystemTrustedBootAssets = recoverySystemBootAssets{}
+		}
+		systemAssets := m.RecoverySystemTrustedBootAssets[systemLabel]
+		if isAssetAlreadyTracked(systemAssets, ta) {
+			continue
+		}
+		if systemAssets == nil {
+			systemAssets = bootAssetsMap{}
+		}
+		systemAssets[ta.name] = append(systemAssets[ta.name], ta.hash)
+		m.RecoverySystemTrustedBootAssets[systemLabel] = systemAssets
+		modified = true
+	}
+	if modified {
+		return m.Write()
+	}
+	return nil
+}
+
+// isAssetAddedByRecoverySystem returns true if the asset with the given name
+// and hash was added to the tracked recovery assets by any recovery system.
+func isAssetAddedByRecoverySystem(systemAssets recoverySystemBootAssets, assetName, assetHash string) bool {
+	for _, assets := range systemAssets {
+		if isAssetHashTrackedInMap(assets, assetName, assetHash) {
+			return true
+		}
+	}
+	return false
+}
+
+// dropRecoverySystemTrustedAssets drops the record of trusted assets added by
+// the recovery system with the given label. Assets which are no longer used
+// by any other recovery system are removed from the tracked recovery assets
+// and returned, such that they can be removed from the cache.
+func dropRecoverySystemTrustedAssets(m *Modeenv, systemLabel string) (dropped bootAssetsMap, modified bool) {
+	systemAssets, ok := m.RecoverySystemTrustedBootAssets[systemLabel]
+	if !ok {
+		return nil, false
+	}
+	delete(m.RecoverySystemTrustedBootAssets, systemLabel)
+	if len(m.RecoverySystemTrustedBootAssets) == 0 {
+		m.RecoverySystemTrustedBootAssets = nil
+	}
+	for name, hashes := range systemAssets {
+		for _, hash := range hashes {
+			if isAssetAddedByRecoverySystem(m.RecoverySystemTrustedBootAssets, name, hash) {
+				// still used by another system
+				continue
+			}
+			if !isAssetHashTrackedInMap(m.CurrentTrustedRecoveryBootAssets, name, hash) {
+				continue
+			}
+			var remaining []string
+			for _, h := range m.CurrentTrustedRecoveryBootAssets[name] {
+				if h != hash {
+					remaining = append(remaining, h)
+				}
+			}
+			if len(remaining) == 0 {
+				delete(m.CurrentTrustedRecoveryBootAssets, name)
+			} else {
+				m.CurrentTrustedRecoveryBootAssets[name] = remaining
+			}
+			if isAssetHashTrackedInMap(m.CurrentTrustedBootAssets, name, hash) {
+				// the cached asset is shared with the run mode
+				// bootloader
+				continue
+			}
+			if dropped == nil {
+				dropped = bootAssetsMap{}
+			}
+			dropped[name] = append(dropped[name], hash)
+		}
+	}
+	return dropped, true
 }
 
 type errInconsistentRecoverySystemState struct {
@@ -401,10 +646,59 @@ func PromoteTriedRecoverySystem(dev snap.Device, systemLabel string, triedSystem
 		return fmt.Errorf("system has not been successfully tried")
 	}
 
+	const clearTryVars = false
+	return promoteRecoverySystem(dev, systemLabel, clearTryVars)
+}
+
+// PromoteAndClearTriedRecoverySystem promotes the provided recovery system
+// just like PromoteTriedRecoverySystem does, and additionally clears the try
+// recovery system boot variables if they refer to that system. The boot
+// environment and the modeenv are updated first, so that the keys are resealed
+// only once. The try model in the modeenv is left untouched.
+func PromoteAndClearTriedRecoverySystem(dev snap.Device, systemLabel string, triedSystems []string) error {
+	if !dev.HasModeenv() {
+		return fmt.Errorf("internal error: recovery systems can only be used on UC20+")
+	}
+	modeenvLock()
+	defer modeenvUnlock()
+
+	if !strutil.ListContains(triedSystems, systemLabel) {
+		// system is not among the tried systems
+		return fmt.Errorf("system has not been successfully tried")
+	}
+
+	const clearTryVars = true
+	return promoteRecoverySystem(dev, systemLabel, clearTryVars)
+}
+
+func promoteRecoverySystem(dev snap.Device, systemLabel string, clearTryVars bool) error {
 	m, err := loadModeenv()
 	if err != nil {
 		return err
 	}
+	if clearTryVars {
+		opts := &bootloader.Options{
+			// setup the recovery bootloader
+			Role: bootloader.RoleRecovery,
+		}
+		bl, err := bootloader.Find(InitramfsUbuntuSeedDir, opts)
+		if err != nil {
+			return err
+		}
+		vars, err := bl.GetBootVars("try_recovery_system")
+		if err != nil {
+			return err
+		}
+		// do not touch the variables if another system is being tried
+		if vars["try_recovery_system"] == systemLabel {
+			if err := bl.SetBootVars(map[string]string{
+				"try_recovery_system":    "",
+				"recovery_system_status": "",
+			}); err != nil {
+				return err
+			}
+		}
+	}
 	rewriteModeenv := false
 	if !strutil.ListContains(m.CurrentRecoverySystems, systemLabel) {
 		m.CurrentRecoverySystems = append(m.CurrentRecoverySystems, systemLabel)
@@ -414,6 +708,11 @@ func PromoteTriedRecoverySystem(dev snap.Device, systemLabel string, triedSystem
 		m.GoodRecoverySystems = append(m.GoodRecoverySystems, systemLabel)
 		rewriteModeenv = true
 	}
+	// the model is already recorded when the system was tried, but
+	// systems can be promoted without being tried
+	if m.setRecoverySystemModel(systemLabel, dev.Model()) {
+		rewriteModeenv = true
+	}
 	if rewriteModeenv {
 		if err := m.Write(); err != nil {
 			return err
@@ -430,6 +729,64 @@ func PromoteTriedRecoverySystem(dev snap.Device, systemLabel string, triedSystem
 	return nil
 }
 
+// SetDefaultRecoverySystem marks the provided recovery system as the default
+// one, that is the system the bootloader uses when booting into recovery. The
+// system must have been successfully tried and promoted to the list of good
+// recovery systems in the modeenv already.
+func SetDefaultRecoverySystem(dev snap.Device, systemLabel string) error {
+	if !dev.HasModeenv() {
+		return fmt.Errorf("internal error: recovery systems can only be used on UC20+")
+	}
+	if systemLabel == "" {
+		return fmt.Errorf("internal error: system label is unset")
+	}
+	modeenvLock()
+	defer modeenvUnlock()
+
+	m, err := loadModeenv()
+	if err != nil {
+		return err
+	}
+	if !strutil.ListContains(m.GoodRecoverySystems, systemLabel) {
+		return fmt.Errorf("system has not been successfully tried")
+	}
+
+	opts := &bootloader.Options{
+		// setup the recovery bootloader
+		Role: bootloader.RoleRecovery,
+	}
+	bl, err := bootloader.Find(InitramfsUbuntuSeedDir, opts)
+	if err != nil {
+		return err
+	}
+	// the recovery mode is left untouched, only the system the bootloader
+	// falls back to is updated
+	return bl.SetBootVars(map[string]string{
+		"snapd_recovery_system": systemLabel,
+	})
+}
+
+// DefaultRecoverySystem returns the label of the default recovery system, that
+// is the system the bootloader uses when booting into recovery, or an empty
+// string if none is set.
+func DefaultRecoverySystem(dev snap.Device) (string, error) {
+	if !dev.HasModeenv() {
+		return "", fmt.Errorf("internal error: recovery systems can only be used on UC20+")
+	}
+	opts := &bootloader.Options{
+		Role: bootloader.RoleRecovery,
+	}
+	bl, err := bootloader.Find(InitramfsUbuntuSeedDir, opts)
+	if err != nil {
+		return "", err
+	}
+	vars, err := bl.GetBootVars("snapd_recovery_system")
+	if err != nil {
+		return "", err
+	}
+	return vars["snapd_recovery_system"], nil
+}
+
 // DropRecoverySystem drops a provided system from the list of good and current
 // recovery systems, updates the modeenv and reseals the keys a needed. Note,
 // this call *DOES NOT* clear the boot environment variables.
@@ -457,6 +814,13 @@ func dropRecoverySystem(dev snap.Device, systemLabel string) error {
 		m.CurrentRecoverySystems = updatedCurrent
 		rewriteModeenv = true
 	}
+	if m.dropRecoverySystemModel(systemLabel) {
+		rewriteModeenv = true
+	}
+	droppedAssets, modified := dropRecoverySystemTrustedAssets(m, systemLabel)
+	if modified {
+		rewriteModeenv = true
+	}
 	if rewriteModeenv {
 		if err := m.Write(); err != nil {
 			return err
@@ -464,7 +828,30 @@ func dropRecoverySystem(dev snap.Device, systemLabel string) error {
 	}
 
 	const expectReseal = true
-	return resealKeyToModeenv(dirs.GlobalRootDir, m, expectReseal, nil)
+	if err := resealKeyToModeenv(dirs.GlobalRootDir, m, expectReseal, nil); err != nil {
+		return err
+	}
+	if len(droppedAssets) == 0 {
+		return nil
+	}
+	// the keys no longer use the assets, they can be removed from the cache
+	opts := &bootloader.Options{
+		// setup the recovery bootloader
+		Role: bootloader.RoleRecovery,
+	}
+	bl, err := bootloader.Find(InitramfsUbuntuSeedDir, opts)
+	if err != nil {
+		return err
+	}
+	cache := newTrustedAssetsCache(dirs.SnapBootAssetsDir)
+	for name, hashes := range droppedAssets {
+		for _, hash := range hashes {
+			if err := cache.Remove(bl.Name(), name, hash); err != nil {
+				logger.Noticef("cannot remove unused boot asset %v:%v: %v", name, hash, err)
+			}
+		}
+	}
+	return nil
 }
 
 // MarkRecoveryCapableSystem records a given system as one that we can recover
@@ -515,3 +902,98 @@ func MarkRecoveryCapableSystem(systemLabel string) error {
 		"snapd_good_recovery_systems": systemsForEnv,
 	})
 }
+
+// UpdateRecoverySystemsMenu updates the menu of recovery systems of the
+// recovery bootloader to list the good recovery systems from the modeenv. The
+// menu is only shown when enabled by the gadget, otherwise it is removed.
+// Nothing is done when the recovery bootloader does not support such menu.
+// When the menu is enabled, the managed recovery boot config is first updated
+// to the built-in edition, as the one installed with the device may predate
+// the support for the menu.
+func UpdateRecoverySystemsMenu(dev snap.Device, gadgetSnapOrDir string) error {
+	if !dev.HasModeenv() {
+		return fmt.Errorf("internal error: recovery systems can only be used on UC20+")
+	}
+	mbl, err := findRecoverySystemsMenuBootloader(InitramfsUbuntuSeedDir)
+	if err != nil || mbl == nil {
+		return err
+	}
+	enabled, err := recoverySystemsMenuEnabled(dev.Model(), gadgetSnapOrDir)
+	if err != nil {
+		return err
+	}
+
+	modeenvLock()
+	defer modeenvUnlock()
+
+	m, err := loadModeenv()
+	if err != nil {
+		return err
+	}
+	var systems []string
+	if enabled {
+		if _, err := updateManagedBootConfigForBootloader(dev, ModeRecover, gadgetSnapOrDir, ""); err != nil {
+			return fmt.Errorf("cannot update the recovery boot config: %v", err)
+		}
+		systems = m.GoodRecoverySystems
+	}
+	return mbl.SetRecoverySystemsMenu(recoverySystemsMenuEntries(InitramfsUbuntuSeedDir, systems))
+}
+
+// installRecoverySystemsMenu installs the menu of recovery systems listing the
+// system the device is being installed from, if enabled by the gadget.
+func installRecoverySystemsMenu(model *asserts.Model, gadgetDir, systemLabel string) error {
+	mbl, err := findRecoverySystemsMenuBootloader(InitramfsUbuntuSeedDir)
+	if err != nil || mbl == nil {
+		return err
+	}
+	enabled, err := recoverySystemsMenuEnabled(model, gadgetDir)
+	if err != nil || !enabled {
+		return err
+	}
+	return mbl.SetRecoverySystemsMenu(recoverySystemsMenuEntries(InitramfsUbuntuSeedDir, []string{systemLabel}))
+}
+
+// findRecoverySystemsMenuBootloader returns the recovery bootloader if it
+// supports a menu of recovery systems, or nil otherwise.
+func findRecoverySystemsMenuBootloader(seedDir string) (bootloader.RecoverySystemsMenuBootloader, error) {
+	opts := &bootloader.Options{
+		// setup the recovery bootloader
+		Role: bootloader.RoleRecovery,
+	}
+	bl, err := bootloader.Find(seedDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	mbl, ok := bl.(bootloader.RecoverySystemsMenuBootloader)
+	if !ok {
+		return nil, nil
+	}
+	return mbl, nil
+}
+
+func recoverySystemsMenuEnabled(model *asserts.Model, gadgetSnapOrDir string) (bool, error) {
+	snapf, err := snapfile.Open(gadgetSnapOrDir)
+	if err != nil {
+		return false, err
+	}
+	info, err := gadget.ReadInfoFromSnapFile(snapf, model)
+	if err != nil {
+		return false, err
+	}
+	return info.BootloaderOptions.RecoverySystemsMenu, nil
+}
+
+func recoverySystemsMenuEntries(seedDir string, systems []string) []bootloader.RecoverySystemsMenuEntry {
+	entries := make([]bootloader.RecoverySystemsMenuEntry, 0, len(systems))
+	for _, label := range systems {
+		entry := bootloader.RecoverySystemsMenuEntry{Label: label}
+		// the model of the system is written once, when the system is
+		// created
+		if fi, err := os.Stat(filepath.Join(seedDir, "systems", label, "model")); err == nil {
+			entry.Created = fi.ModTime()
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -20,9 +20,11 @@
 package boot_test
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	. "gopkg.in/check.v1"
 
@@ -31,9 +33,12 @@ import (
 	"github.com/snapcore/snapd/boot/boottest"
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/bootloader/bootloadertest"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/secboot"
 	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/timings"
 )
@@ -202,6 +207,9 @@ func (s *systemsSuite) TestSetTryRecoverySystemEncrypted(c *C) {
 		// keep this comment to make old gofmt happy
 		CurrentRecoverySystems: []string{"20200825", "1234"},
 		GoodRecoverySystems:    []string{"20200825"},
+		RecoverySystemModels: boot.RecoverySystemModels{
+			"1234": boot.RecoverySystemModelDigest(model),
+		},
 		CurrentTrustedRecoveryBootAssets: boot.BootAssetsMap{
 			"asset": []string{"asset-hash-1"},
 		},
@@ -333,6 +341,9 @@ func (s *systemsSuite) TestSetTryRecoverySystemRemodelEncrypted(c *C) {
 		// keep this comment to make old gofmt happy
 		CurrentRecoverySystems: []string{"20200825", "1234"},
 		GoodRecoverySystems:    []string{"20200825"},
+		RecoverySystemModels: boot.RecoverySystemModels{
+			"1234": boot.RecoverySystemModelDigest(newModel),
+		},
 		CurrentTrustedRecoveryBootAssets: boot.BootAssetsMap{
 			"asset": []string{"asset-hash-1"},
 		},
@@ -392,12 +403,361 @@ func (s *systemsSuite) TestSetTryRecoverySystemSimple(c *C) {
 		Mode: "run",
 		// keep this comment to make old gofmt happy
 		CurrentRecoverySystems: []string{"20200825", "1234"},
+		RecoverySystemModels: boot.RecoverySystemModels{
+			"1234": boot.RecoverySystemModelDigest(model),
+		},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	})
+}
+
+func (s *systemsSuite) TestSetAndClearTryRecoverySystemTransactional(c *C) {
+	mtbl := bootloadertest.Mock("trusted", s.bootdir).WithRecoveryAwareTrustedAssets()
+	bootloader.Force(mtbl)
+	defer bootloader.Force(nil)
+
+	model := s.uc20dev.Model()
+	modeenv := &boot.Modeenv{
+		Mode: "run",
+		// keep this comment to make old gofmt happy
+		CurrentRecoverySystems: []string{"20200825"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	err := boot.SetTryRecoverySystem(s.uc20dev, "1234")
+	c.Assert(err, IsNil)
+	// all variables were set in a single call
+	c.Check(mtbl.SetBootVarsWrites.Calls, DeepEquals, []map[string]string{
+		{"try_recovery_system": "1234", "recovery_system_status": "try"},
+	})
+
+	err = boot.ClearTryRecoverySystem(s.uc20dev, "1234")
+	c.Assert(err, IsNil)
+	c.Check(mtbl.SetBootVarsWrites.Calls, DeepEquals, []map[string]string{
+		{"try_recovery_system": "1234", "recovery_system_status": "try"},
+		{"try_recovery_system": "", "recovery_system_status": ""},
+	})
+}
+
+func (s *systemsSuite) TestSetTryRecoverySystemForNextBoot(c *C) {
+	mtbl := bootloadertest.Mock("trusted", s.bootdir).WithRecoveryAwareTrustedAssets()
+	mtbl.BootVars = map[string]string{
+		"snapd_recovery_system": "20200825",
+		"snapd_recovery_mode":   "run",
+	}
+	bootloader.Force(mtbl)
+	defer bootloader.Force(nil)
+
+	model := s.uc20dev.Model()
+	modeenv := &boot.Modeenv{
+		Mode: "run",
+		// keep this comment to make old gofmt happy
+		CurrentRecoverySystems: []string{"20200825"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	err := boot.SetTryRecoverySystemForNextBoot(s.uc20dev, "1234")
+	c.Assert(err, IsNil)
+	// the system is tried and booted into with a single update
+	c.Check(mtbl.SetBootVarsWrites.Calls, DeepEquals, []map[string]string{{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "try",
+		"snapd_recovery_system":  "1234",
+		"snapd_recovery_mode":    "recover",
+	}})
+	c.Check(mtbl.BootVars, DeepEquals, map[string]string{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "try",
+		"snapd_recovery_system":  "1234",
+		"snapd_recovery_mode":    "recover",
+	})
+}
+
+func (s *systemsSuite) TestSetTryRecoverySystemForNextBootErrRestores(c *C) {
+	mtbl := bootloadertest.Mock("trusted", s.bootdir).WithRecoveryAwareTrustedAssets()
+	mtbl.BootVars = map[string]string{
+		"snapd_recovery_system": "20200825",
+		"snapd_recovery_mode":   "run",
+	}
+	mtbl.SetBootVarsWrites = bootloadertest.MockVarsWrites{
+		ErrOnCall: map[int]error{1: fmt.Errorf("io error")},
+	}
+	bootloader.Force(mtbl)
+	defer bootloader.Force(nil)
+
+	model := s.uc20dev.Model()
+	modeenv := &boot.Modeenv{
+		Mode: "run",
+		// keep this comment to make old gofmt happy
+		CurrentRecoverySystems: []string{"20200825"},
 
 		Model:          model.Model(),
 		BrandID:        model.BrandID(),
 		Grade:          string(model.Grade()),
 		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	err := boot.SetTryRecoverySystemForNextBoot(s.uc20dev, "1234")
+	c.Assert(err, ErrorMatches, "io error")
+	// the cleanup restores the system and mode to boot into
+	c.Check(mtbl.SetBootVarsWrites.Calls, DeepEquals, []map[string]string{{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "try",
+		"snapd_recovery_system":  "1234",
+		"snapd_recovery_mode":    "recover",
+	}, {
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+		"snapd_recovery_system":  "20200825",
+		"snapd_recovery_mode":    "run",
+	}})
+	c.Check(mtbl.BootVars, DeepEquals, map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+		"snapd_recovery_system":  "20200825",
+		"snapd_recovery_mode":    "run",
 	})
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20200825"})
+}
+
+const gadgetYamlWithSeedAssets = `
+volumes:
+  pc:
+    bootloader: grub
+    structure:
+      - name: ubuntu-seed
+        role: system-seed
+        filesystem: vfat
+        type: EF,C12A7328-F81F-11D2-BA4B-00A0C93EC93B
+        size: 1200M
+        content:
+          - source: grubx64.efi
+            target: EFI/boot/grubx64.efi
+          - source: shim.efi.signed
+            target: EFI/boot/
+      - name: ubuntu-boot
+        role: system-boot
+        filesystem: ext4
+        type: 83,0FC63DAF-8483-4772-8E79-3D69D8477DE4
+        size: 750M
+      - name: ubuntu-data
+        role: system-data
+        filesystem: ext4
+        type: 83,0FC63DAF-8483-4772-8E79-3D69D8477DE4
+        size: 1G
+`
+
+func (s *systemsSuite) TestInstallRecoverySystemTrustedAssets(c *C) {
+	mtbl := bootloadertest.Mock("trusted", s.bootdir).WithRecoveryAwareTrustedAssets()
+	mtbl.TrustedAssetsList = []string{"EFI/boot/grubx64.efi", "EFI/boot/shim.efi.signed"}
+	bootloader.Force(mtbl)
+	defer bootloader.Force(nil)
+
+	// SHA3-384
+	grubHash := "0fa8abfbdaf924ad307b74dd2ed183b9a4a398891a2f6bac8fd2db7041b77f068580f9c6c66f699b496c2da1cbcc7ed8"
+	shimHash := "dac0063e831d4b2e7a330426720512fc50fa315042f0bb30f9d1db73e4898dcb89119cac41fdfa62137c8931a50f9d7b"
+
+	model := s.uc20dev.Model()
+	modeenv := &boot.Modeenv{
+		Mode: "run",
+		// keep this comment to make old gofmt happy
+		CurrentRecoverySystems: []string{"20200825"},
+		CurrentTrustedRecoveryBootAssets: boot.BootAssetsMap{
+			"grubx64.efi":     []string{"grubhash"},
+			"shim.efi.signed": []string{shimHash},
+		},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	gadgetSnap := snaptest.MakeTestSnapWithFiles(c, gadgetSnapYaml, [][]string{
+		{"meta/gadget.yaml", gadgetYamlWithSeedAssets},
+		{"grubx64.efi", "foobar"},
+		{"shim.efi.signed", "shim"},
+	})
+
+	err := boot.InstallRecoverySystemTrustedAssets(s.uc20dev, gadgetSnap, "1234")
+	c.Assert(err, IsNil)
+
+	systemDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
+	c.Check(filepath.Join(systemDir, "EFI/boot/grubx64.efi"), testutil.FileEquals, "foobar")
+	c.Check(filepath.Join(systemDir, "EFI/boot/shim.efi.signed"), testutil.FileEquals, "shim")
+	c.Check(filepath.Join(dirs.SnapBootAssetsDir, "trusted", "grubx64.efi-"+grubHash), testutil.FileEquals, "foobar")
+	c.Check(filepath.Join(dirs.SnapBootAssetsDir, "trusted", "shim.efi.signed-"+shimHash), testutil.FileEquals, "shim")
+
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	// the new grub is tracked, the shim was already known
+	c.Check(modeenvRead.CurrentTrustedRecoveryBootAssets, DeepEquals, boot.BootAssetsMap{
+		"grubx64.efi":     []string{"grubhash", grubHash},
+		"shim.efi.signed": []string{shimHash},
+	})
+	// and recorded as added by the system
+	c.Check(modeenvRead.RecoverySystemTrustedBootAssets, DeepEquals, boot.RecoverySystemBootAssets{
+		"1234": boot.BootAssetsMap{
+			"grubx64.efi": []string{grubHash},
+		},
+	})
+
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	// assets which are not in the gadget are skipped
+	mtbl.TrustedAssetsList = []string{"EFI/boot/grubx64.efi", "EFI/boot/bootx64.efi"}
+	err = boot.InstallRecoverySystemTrustedAssets(s.uc20dev, gadgetSnap, "1235")
+	c.Assert(err, IsNil)
+	c.Check(logbuf.String(), testutil.Contains, `trusted asset "EFI/boot/bootx64.efi" of recovery system "1235" is not provided by the gadget, using the one from ubuntu-seed`)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1235/EFI/boot/grubx64.efi"), testutil.FileEquals, "foobar")
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1235/EFI/boot/bootx64.efi"), testutil.FileAbsent)
+
+	modeenvRead, err = boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentTrustedRecoveryBootAssets, DeepEquals, boot.BootAssetsMap{
+		"grubx64.efi":     []string{"grubhash", grubHash},
+		"shim.efi.signed": []string{shimHash},
+	})
+	// the grub asset is shared by both systems
+	c.Check(modeenvRead.RecoverySystemTrustedBootAssets, DeepEquals, boot.RecoverySystemBootAssets{
+		"1234": boot.BootAssetsMap{
+			"grubx64.efi": []string{grubHash},
+		},
+		"1235": boot.BootAssetsMap{
+			"grubx64.efi": []string{grubHash},
+		},
+	})
+
+	// nothing to do without trusted assets
+	mtbl.TrustedAssetsList = nil
+	err = boot.InstallRecoverySystemTrustedAssets(s.uc20dev, gadgetSnap, "1236")
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1236"), testutil.FileAbsent)
+}
+
+func (s *systemsSuite) TestDropRecoverySystemDropsTrustedAssets(c *C) {
+	mtbl := bootloadertest.Mock("trusted", s.bootdir).WithRecoveryAwareTrustedAssets()
+	mtbl.TrustedAssetsList = []string{"EFI/boot/grubx64.efi", "EFI/boot/shim.efi.signed"}
+	bootloader.Force(mtbl)
+	defer bootloader.Force(nil)
+
+	// SHA3-384
+	grubHash := "0fa8abfbdaf924ad307b74dd2ed183b9a4a398891a2f6bac8fd2db7041b77f068580f9c6c66f699b496c2da1cbcc7ed8"
+	shimHash := "dac0063e831d4b2e7a330426720512fc50fa315042f0bb30f9d1db73e4898dcb89119cac41fdfa62137c8931a50f9d7b"
+
+	model := s.uc20dev.Model()
+	modeenv := &boot.Modeenv{
+		Mode: "run",
+		// keep this comment to make old gofmt happy
+		CurrentRecoverySystems: []string{"20200825"},
+		GoodRecoverySystems:    []string{"20200825"},
+		CurrentTrustedRecoveryBootAssets: boot.BootAssetsMap{
+			"grubx64.efi":     []string{"grubhash"},
+			"shim.efi.signed": []string{shimHash},
+		},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	gadgetSnap := snaptest.MakeTestSnapWithFiles(c, gadgetSnapYaml, [][]string{
+		{"meta/gadget.yaml", gadgetYamlWithSeedAssets},
+		{"grubx64.efi", "foobar"},
+		{"shim.efi.signed", "shim"},
+	})
+
+	for _, label := range []string{"1234", "1235"} {
+		err := boot.InstallRecoverySystemTrustedAssets(s.uc20dev, gadgetSnap, label)
+		c.Assert(err, IsNil)
+	}
+	cachedGrub := filepath.Join(dirs.SnapBootAssetsDir, "trusted", "grubx64.efi-"+grubHash)
+	cachedShim := filepath.Join(dirs.SnapBootAssetsDir, "trusted", "shim.efi.signed-"+shimHash)
+
+	// the grub asset is still used by the other system
+	err := boot.DropRecoverySystem(s.uc20dev, "1234")
+	c.Assert(err, IsNil)
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentTrustedRecoveryBootAssets, DeepEquals, boot.BootAssetsMap{
+		"grubx64.efi":     []string{"grubhash", grubHash},
+		"shim.efi.signed": []string{shimHash},
+	})
+	c.Check(modeenvRead.RecoverySystemTrustedBootAssets, DeepEquals, boot.RecoverySystemBootAssets{
+		"1235": boot.BootAssetsMap{
+			"grubx64.efi": []string{grubHash},
+		},
+	})
+	c.Check(cachedGrub, testutil.FilePresent)
+
+	// dropping the last user of the asset drops it for good, but the
+	// assets which were tracked before are kept
+	err = boot.DropRecoverySystem(s.uc20dev, "1235")
+	c.Assert(err, IsNil)
+	modeenvRead, err = boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentTrustedRecoveryBootAssets, DeepEquals, boot.BootAssetsMap{
+		"grubx64.efi":     []string{"grubhash"},
+		"shim.efi.signed": []string{shimHash},
+	})
+	c.Check(modeenvRead.RecoverySystemTrustedBootAssets, IsNil)
+	c.Check(cachedGrub, testutil.FileAbsent)
+	c.Check(cachedShim, testutil.FilePresent)
+}
+
+func (s *systemsSuite) TestSetTryRecoverySystemTransactionalErr(c *C) {
+	mtbl := bootloadertest.Mock("trusted", s.bootdir).WithRecoveryAwareTrustedAssets()
+	mtbl.SetBootVarsWrites = bootloadertest.MockVarsWrites{
+		ErrOnKey: map[string]error{"try_recovery_system": fmt.Errorf("io error")},
+	}
+	bootloader.Force(mtbl)
+	defer bootloader.Force(nil)
+
+	model := s.uc20dev.Model()
+	modeenv := &boot.Modeenv{
+		Mode: "run",
+		// keep this comment to make old gofmt happy
+		CurrentRecoverySystems: []string{"20200825"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	err := boot.SetTryRecoverySystem(s.uc20dev, "1234")
+	c.Assert(err, ErrorMatches, `io error \(cleanup failed: io error\)`)
+	// both the attempt and the cleanup were recorded
+	c.Check(mtbl.SetBootVarsWrites.Calls, DeepEquals, []map[string]string{
+		{"try_recovery_system": "1234", "recovery_system_status": "try"},
+		{"try_recovery_system": "", "recovery_system_status": ""},
+	})
+	// but none of the variables were set
+	_, ok := mtbl.BootVars["try_recovery_system"]
+	c.Check(ok, Equals, false)
+	_, ok = mtbl.BootVars["recovery_system_status"]
+	c.Check(ok, Equals, false)
 }
 
 func (s *systemsSuite) TestSetTryRecoverySystemSetBootVarsErr(c *C) {
@@ -650,6 +1010,9 @@ func (s *systemsSuite) TestSetTryRecoverySystemCleanupOnErrorAfterReseal(c *C) {
 
 	err := boot.SetTryRecoverySystem(s.uc20dev, "1234")
 	c.Assert(err, ErrorMatches, "cannot reseal the encryption key: reseal fails")
+	var resealErr *boot.RecoverySystemResealError
+	c.Assert(errors.As(err, &resealErr), Equals, true)
+	c.Check(resealErr.System, Equals, "1234")
 
 	// failed after the call to read the 'try' system seed
 	c.Check(readSeedCalls, Equals, 5)
@@ -1591,6 +1954,95 @@ func (s *systemsSuite) TestPromoteTriedRecoverySystemNotTried(c *C) {
 	})
 }
 
+func (s *systemsSuite) testPromoteAndClearTriedRecoverySystem(c *C, tryVars map[string]string, triedSystems []string, expectedErr string) *bootloadertest.MockRecoveryAwareBootloader {
+	rbl := bootloadertest.Mock("recovery", c.MkDir()).RecoveryAware()
+	bootloader.Force(rbl)
+	defer bootloader.Force(nil)
+	c.Assert(rbl.SetBootVars(tryVars), IsNil)
+
+	model := s.uc20dev.Model()
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		CurrentRecoverySystems: []string{"20200825", "1234"},
+		GoodRecoverySystems:    []string{"20200825"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+		// the system is tried as part of a remodel
+		TryModel:          "new-model",
+		TryBrandID:        model.BrandID(),
+		TryGrade:          string(model.Grade()),
+		TryModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	resealCalls := 0
+	restore := boot.MockResealKeyToModeenv(func(_ string, m *boot.Modeenv, expectReseal bool, _ boot.Unlocker) error {
+		resealCalls++
+		c.Check(expectReseal, Equals, true)
+		c.Check(m.GoodRecoverySystems, DeepEquals, []string{"20200825", "1234"})
+		// boot variables were already updated
+		c.Check(rbl.BootVars["try_recovery_system"], Not(Equals), "1234")
+		return nil
+	})
+	defer restore()
+
+	err := boot.PromoteAndClearTriedRecoverySystem(s.uc20dev, "1234", triedSystems)
+	modeenvRead, mErr := boot.ReadModeenv("")
+	c.Assert(mErr, IsNil)
+	// the try model is never touched
+	c.Check(modeenvRead.TryModel, Equals, "new-model")
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20200825", "1234"})
+	if expectedErr != "" {
+		c.Assert(err, ErrorMatches, expectedErr)
+		c.Check(resealCalls, Equals, 0)
+		c.Check(rbl.BootVars, DeepEquals, tryVars)
+		c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, []string{"20200825"})
+		return rbl
+	}
+	c.Assert(err, IsNil)
+	// keys were resealed only once
+	c.Check(resealCalls, Equals, 1)
+	c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, []string{"20200825", "1234"})
+	return rbl
+}
+
+func (s *systemsSuite) TestPromoteAndClearTriedRecoverySystemHappy(c *C) {
+	rbl := s.testPromoteAndClearTriedRecoverySystem(c, map[string]string{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "tried",
+	}, []string{"1234"}, "")
+
+	c.Check(rbl.BootVars, DeepEquals, map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+	})
+}
+
+func (s *systemsSuite) TestPromoteAndClearTriedRecoverySystemOtherSystemTried(c *C) {
+	// the system was already promoted to the tried ones, and another one
+	// is being tried
+	rbl := s.testPromoteAndClearTriedRecoverySystem(c, map[string]string{
+		"try_recovery_system":    "9999",
+		"recovery_system_status": "try",
+	}, []string{"1234"}, "")
+
+	// boot variables of the other system are left alone
+	c.Check(rbl.BootVars, DeepEquals, map[string]string{
+		"try_recovery_system":    "9999",
+		"recovery_system_status": "try",
+	})
+}
+
+func (s *systemsSuite) TestPromoteAndClearTriedRecoverySystemNotTried(c *C) {
+	s.testPromoteAndClearTriedRecoverySystem(c, map[string]string{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "try",
+	}, []string{"not-here"}, `system has not been successfully tried`)
+}
+
 type recoverySystemDropTestCase struct {
 	systemLabelAddToCurrent bool
 	systemLabelAddToGood    bool
@@ -1642,6 +2094,12 @@ func (s *systemsSuite) testDropRecoverySystem(c *C, systemLabel string, tc recov
 	if tc.systemLabelAddToGood {
 		modeenv.GoodRecoverySystems = append(modeenv.GoodRecoverySystems, systemLabel)
 	}
+	if tc.systemLabelAddToCurrent || tc.systemLabelAddToGood {
+		modeenv.RecoverySystemModels = boot.RecoverySystemModels{
+			"20200825":  boot.RecoverySystemModelDigest(model),
+			systemLabel: boot.RecoverySystemModelDigest(model),
+		}
+	}
 
 	c.Assert(modeenv.WriteTo(""), IsNil)
 
@@ -1700,6 +2158,8 @@ func (s *systemsSuite) testDropRecoverySystem(c *C, systemLabel string, tc recov
 	// current is unchanged
 	c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, tc.expectedCurrentSystemsList)
 	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, tc.expectedGoodSystemsList)
+	// model of the dropped system is no longer tracked
+	c.Check(modeenvRead.RecoverySystemModels[systemLabel], Equals, "")
 }
 
 func (s *systemsSuite) TestDropRecoverySystemHappy(c *C) {
@@ -1748,6 +2208,131 @@ func (s *systemsSuite) TestDropRecoverySystemResealErr(c *C) {
 	})
 }
 
+func (s *systemsSuite) TestSetDefaultRecoverySystemHappy(c *C) {
+	rbl := bootloadertest.Mock("recovery", c.MkDir()).RecoveryAware()
+	bootloader.Force(rbl)
+	defer bootloader.Force(nil)
+	c.Assert(rbl.SetBootVars(map[string]string{
+		"snapd_recovery_system": "20200825",
+		"snapd_recovery_mode":   "run",
+	}), IsNil)
+
+	model := s.uc20dev.Model()
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		CurrentRecoverySystems: []string{"20200825", "1234"},
+		GoodRecoverySystems:    []string{"20200825", "1234"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	err := boot.SetDefaultRecoverySystem(s.uc20dev, "1234")
+	c.Assert(err, IsNil)
+
+	vars, err := rbl.GetBootVars("snapd_recovery_system", "snapd_recovery_mode")
+	c.Assert(err, IsNil)
+	c.Check(vars, DeepEquals, map[string]string{
+		"snapd_recovery_system": "1234",
+		// mode is unchanged
+		"snapd_recovery_mode": "run",
+	})
+
+	// the modeenv is unchanged
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20200825", "1234"})
+	c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, []string{"20200825", "1234"})
+}
+
+func (s *systemsSuite) TestSetDefaultRecoverySystemNotTried(c *C) {
+	rbl := bootloadertest.Mock("recovery", c.MkDir()).RecoveryAware()
+	bootloader.Force(rbl)
+	defer bootloader.Force(nil)
+	c.Assert(rbl.SetBootVars(map[string]string{
+		"snapd_recovery_system": "20200825",
+	}), IsNil)
+
+	model := s.uc20dev.Model()
+	modeenv := &boot.Modeenv{
+		Mode: "run",
+		// the system is current, but is being tried
+		CurrentRecoverySystems: []string{"20200825", "1234"},
+		GoodRecoverySystems:    []string{"20200825"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	err := boot.SetDefaultRecoverySystem(s.uc20dev, "1234")
+	c.Assert(err, ErrorMatches, "system has not been successfully tried")
+
+	// nothing was changed
+	c.Check(rbl.BootVars["snapd_recovery_system"], Equals, "20200825")
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20200825", "1234"})
+	c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, []string{"20200825"})
+}
+
+func (s *systemsSuite) TestSetDefaultRecoverySystemSetBootVarsErr(c *C) {
+	rbl := bootloadertest.Mock("recovery", c.MkDir()).RecoveryAware()
+	bootloader.Force(rbl)
+	defer bootloader.Force(nil)
+
+	model := s.uc20dev.Model()
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		CurrentRecoverySystems: []string{"20200825", "1234"},
+		GoodRecoverySystems:    []string{"20200825", "1234"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	rbl.SetErr = fmt.Errorf("mocked error")
+	err := boot.SetDefaultRecoverySystem(s.uc20dev, "1234")
+	c.Assert(err, ErrorMatches, "mocked error")
+}
+
+func (s *systemsSuite) TestSetDefaultRecoverySystemNonUC20(c *C) {
+	err := boot.SetDefaultRecoverySystem(boottest.MockDevice("pc"), "1234")
+	c.Assert(err, ErrorMatches, "internal error: recovery systems can only be used on UC20\\+")
+}
+
+func (s *systemsSuite) TestDefaultRecoverySystem(c *C) {
+	rbl := bootloadertest.Mock("recovery", c.MkDir()).RecoveryAware()
+	bootloader.Force(rbl)
+	defer bootloader.Force(nil)
+
+	label, err := boot.DefaultRecoverySystem(s.uc20dev)
+	c.Assert(err, IsNil)
+	c.Check(label, Equals, "")
+
+	c.Assert(rbl.SetBootVars(map[string]string{
+		"snapd_recovery_system": "20200825",
+	}), IsNil)
+	label, err = boot.DefaultRecoverySystem(s.uc20dev)
+	c.Assert(err, IsNil)
+	c.Check(label, Equals, "20200825")
+
+	rbl.GetErr = fmt.Errorf("mocked error")
+	_, err = boot.DefaultRecoverySystem(s.uc20dev)
+	c.Assert(err, ErrorMatches, "mocked error")
+
+	_, err = boot.DefaultRecoverySystem(boottest.MockDevice("pc"))
+	c.Assert(err, ErrorMatches, "internal error: recovery systems can only be used on UC20\\+")
+}
+
 func (s *systemsSuite) TestMarkRecoveryCapableSystemHappy(c *C) {
 	rbl := bootloadertest.Mock("recovery", c.MkDir()).RecoveryAware()
 	bootloader.Force(rbl)
@@ -1951,6 +2536,16 @@ func (s *initramfsMarkTryRecoverySystemSuite) TestMarkRecoverySystemBogus(c *C)
 	s.testMarkRecoverySystemForRun(c, boot.TryRecoverySystemOutcomeInconsistent, "")
 }
 
+func (s *initramfsMarkTryRecoverySystemSuite) TestMarkTryRecoverySystemTransactional(c *C) {
+	err := boot.EnsureNextBootToRunModeWithTryRecoverySystemOutcome(boot.TryRecoverySystemOutcomeSuccess)
+	c.Assert(err, IsNil)
+	c.Check(s.bl.SetBootVarsWrites.Calls, DeepEquals, []map[string]string{{
+		"snapd_recovery_mode":    "run",
+		"snapd_recovery_system":  "",
+		"recovery_system_status": "tried",
+	}})
+}
+
 func (s *initramfsMarkTryRecoverySystemSuite) TestMarkRecoverySystemErr(c *C) {
 	s.bl.SetErr = fmt.Errorf("set fails")
 	err := boot.EnsureNextBootToRunModeWithTryRecoverySystemOutcome(boot.TryRecoverySystemOutcomeSuccess)
@@ -2061,3 +2656,110 @@ func (s *initramfsMarkTryRecoverySystemSuite) TestRecoverySystemSuccessDifferent
 	c.Assert(err, IsNil)
 	c.Check(isTry, Equals, false)
 }
+
+func (s *systemsSuite) testUpdateRecoverySystemsMenu(c *C, enabled bool) *bootloadertest.MockRecoverySystemsMenuBootloader {
+	mbl := bootloadertest.Mock("menu", c.MkDir()).WithRecoverySystemsMenu()
+	bootloader.Force(mbl)
+	s.AddCleanup(func() { bootloader.Force(nil) })
+
+	err := s.updateRecoverySystemsMenu(c, enabled)
+	c.Assert(err, IsNil)
+	c.Check(mbl.SetRecoverySystemsMenuCalls, Equals, 1)
+	return mbl
+}
+
+func (s *systemsSuite) updateRecoverySystemsMenu(c *C, enabled bool) error {
+	model := s.uc20dev.Model()
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		CurrentRecoverySystems: []string{"20200825", "1234", "tried"},
+		GoodRecoverySystems:    []string{"20200825", "1234"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	created := time.Date(2020, 8, 25, 12, 0, 0, 0, time.UTC)
+	modelFile := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/20200825/model")
+	c.Assert(os.MkdirAll(filepath.Dir(modelFile), 0755), IsNil)
+	c.Assert(os.WriteFile(modelFile, nil, 0644), IsNil)
+	c.Assert(os.Chtimes(modelFile, created, created), IsNil)
+
+	gadgetDir := c.MkDir()
+	gadgetYamlWithMenu := gadgetYaml
+	if enabled {
+		gadgetYamlWithMenu += `
+bootloader-options:
+  recovery-systems-menu: true
+`
+	}
+	snaptest.PopulateDir(gadgetDir, [][]string{
+		{"meta/snap.yaml", gadgetSnapYaml},
+		{"meta/gadget.yaml", gadgetYamlWithMenu},
+	})
+
+	return boot.UpdateRecoverySystemsMenu(s.uc20dev, gadgetDir)
+}
+
+func (s *systemsSuite) TestUpdateRecoverySystemsMenuEnabled(c *C) {
+	mbl := s.testUpdateRecoverySystemsMenu(c, true)
+	c.Assert(mbl.RecoverySystemsMenu, HasLen, 2)
+	c.Check(mbl.RecoverySystemsMenu[0].Label, Equals, "20200825")
+	c.Check(mbl.RecoverySystemsMenu[0].Created.Equal(time.Date(2020, 8, 25, 12, 0, 0, 0, time.UTC)), Equals, true)
+	// creation time is unknown
+	c.Check(mbl.RecoverySystemsMenu[1], DeepEquals, bootloader.RecoverySystemsMenuEntry{Label: "1234"})
+}
+
+func (s *systemsSuite) TestUpdateRecoverySystemsMenuDisabled(c *C) {
+	mbl := s.testUpdateRecoverySystemsMenu(c, false)
+	// the menu is removed
+	c.Check(mbl.RecoverySystemsMenu, HasLen, 0)
+}
+
+func (s *systemsSuite) TestUpdateRecoverySystemsMenuUpdatesRecoveryBootConfig(c *C) {
+	mbl := bootloadertest.Mock("menu", c.MkDir()).WithTrustedAssetsRecoverySystemsMenu()
+	mbl.Updated = true
+	bootloader.Force(mbl)
+	defer bootloader.Force(nil)
+
+	err := s.updateRecoverySystemsMenu(c, true)
+	c.Assert(err, IsNil)
+	// the recovery boot config may predate the support for the menu
+	c.Check(mbl.UpdateCalls, Equals, 1)
+	c.Check(mbl.RecoverySystemsMenu, HasLen, 2)
+
+	// the boot config is left alone when the menu is disabled
+	err = s.updateRecoverySystemsMenu(c, false)
+	c.Assert(err, IsNil)
+	c.Check(mbl.UpdateCalls, Equals, 1)
+	c.Check(mbl.RecoverySystemsMenu, HasLen, 0)
+}
+
+func (s *systemsSuite) TestUpdateRecoverySystemsMenuUpdateRecoveryBootConfigError(c *C) {
+	mbl := bootloadertest.Mock("menu", c.MkDir()).WithTrustedAssetsRecoverySystemsMenu()
+	mbl.UpdateErr = fmt.Errorf("mocked update error")
+	bootloader.Force(mbl)
+	defer bootloader.Force(nil)
+
+	err := s.updateRecoverySystemsMenu(c, true)
+	c.Assert(err, ErrorMatches, "cannot update the recovery boot config: mocked update error")
+	c.Check(mbl.SetRecoverySystemsMenuCalls, Equals, 0)
+}
+
+func (s *systemsSuite) TestUpdateRecoverySystemsMenuNotSupported(c *C) {
+	rbl := bootloadertest.Mock("recovery", c.MkDir()).RecoveryAware()
+	bootloader.Force(rbl)
+	defer bootloader.Force(nil)
+
+	// the gadget is not even looked at
+	err := boot.UpdateRecoverySystemsMenu(s.uc20dev, "/does/not/exist")
+	c.Assert(err, IsNil)
+}
+
+func (s *systemsSuite) TestUpdateRecoverySystemsMenuNonUC20(c *C) {
+	err := boot.UpdateRecoverySystemsMenu(boottest.MockDevice("some-snap"), "")
+	c.Assert(err, ErrorMatches, "internal error: recovery systems can only be used on UC20\\+")
+}
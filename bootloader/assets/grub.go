@@ -37,10 +37,23 @@ var cmdlineForArch = map[string][]ForEditions{
 	},
 }
 
+// the recovery boot config uses its own static command line, which has not
+// changed across its editions
+var recoveryCmdlineForArch = map[string][]ForEditions{
+	"amd64": {
+		{FirstEdition: 1, Snippet: []byte("console=ttyS0 console=tty1 panic=-1")},
+	},
+	"arm64": {
+		{FirstEdition: 1, Snippet: []byte("panic=-1")},
+	},
+	"i386": {
+		{FirstEdition: 1, Snippet: []byte("console=ttyS0 console=tty1 panic=-1")},
+	},
+}
+
 func registerGrubSnippets() {
-	snippets := cmdlineForArch[arch.DpkgArchitecture()]
-	registerSnippetForEditions("grub.cfg:static-cmdline", snippets)
-	registerSnippetForEditions("grub-recovery.cfg:static-cmdline", snippets)
+	registerSnippetForEditions("grub.cfg:static-cmdline", cmdlineForArch[arch.DpkgArchitecture()])
+	registerSnippetForEditions("grub-recovery.cfg:static-cmdline", recoveryCmdlineForArch[arch.DpkgArchitecture()])
 }
 
 func init() {
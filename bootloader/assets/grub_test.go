@@ -52,7 +52,9 @@ func (s *grubAssetsTestSuite) SetUpTest(c *C) {
 		{FirstEdition: 3, Snippet: []byte("console=ttyS0,115200n8 console=tty1 panic=-1")},
 	}
 	s.AddCleanup(assets.MockSnippetsForEdition("grub.cfg:static-cmdline", snippets))
-	s.AddCleanup(assets.MockSnippetsForEdition("grub-recovery.cfg:static-cmdline", snippets))
+	s.AddCleanup(assets.MockSnippetsForEdition("grub-recovery.cfg:static-cmdline", []assets.ForEditions{
+		{FirstEdition: 1, Snippet: []byte("console=ttyS0 console=tty1 panic=-1")},
+	}))
 }
 
 func (s *grubAssetsTestSuite) testGrubConfigContains(c *C, name string, edition int, keys ...string) {
@@ -76,9 +78,11 @@ func (s *grubAssetsTestSuite) TestGrubConf(c *C) {
 }
 
 func (s *grubAssetsTestSuite) TestGrubRecoveryConf(c *C) {
-	s.testGrubConfigContains(c, "grub-recovery.cfg", 2,
+	s.testGrubConfigContains(c, "grub-recovery.cfg", 3,
 		"snapd_recovery_mode",
 		"snapd_recovery_system",
+		"function snapd_boot_recovery_system {",
+		"source /EFI/ubuntu/recovery-systems.cfg",
 		"set snapd_static_cmdline_args='console=ttyS0 console=tty1 panic=-1'",
 	)
 }
@@ -133,7 +137,7 @@ func (s *grubAssetsTestSuite) TestGrubCmdlineSnippetCrossCheck(c *C) {
 			pattern: "set snapd_static_cmdline_args='%s'\n",
 		},
 		{
-			asset: "grub-recovery.cfg", snippet: "grub-recovery.cfg:static-cmdline", edition: 2,
+			asset: "grub-recovery.cfg", snippet: "grub-recovery.cfg:static-cmdline", edition: 3,
 			content: []byte("console=ttyS0 console=tty1 panic=-1"),
 			pattern: "set snapd_static_cmdline_args='%s'\n",
 		},
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/snapcore/snapd/bootloader/assets"
 	"github.com/snapcore/snapd/dirs"
@@ -238,6 +239,28 @@ type NotScriptableBootloader interface {
 	SetBootVarsFromInitramfs(values map[string]string) error
 }
 
+// RecoverySystemsMenuEntry describes a recovery system listed in the menu of
+// recovery systems.
+type RecoverySystemsMenuEntry struct {
+	// Label is the label of the recovery system.
+	Label string
+	// Created is when the recovery system was created, unset if unknown.
+	Created time.Time
+}
+
+// RecoverySystemsMenuBootloader is a recovery bootloader which can show a
+// menu of the recovery systems to boot into. The menu only carries the
+// entries titles, the kernel command line of the recovery systems is composed
+// by the boot config as usual.
+type RecoverySystemsMenuBootloader interface {
+	Bootloader
+
+	// SetRecoverySystemsMenu writes the menu listing the given recovery
+	// systems, replacing the existing one. The menu is removed when no
+	// systems are given.
+	SetRecoverySystemsMenu(systems []RecoverySystemsMenuEntry) error
+}
+
 // RebootBootloader needs arguments to the reboot syscall when snaps
 // are being updated.
 type RebootBootloader interface {
@@ -316,6 +339,7 @@ var (
 		newAndroidBoot,
 		newLk,
 		newPiboot,
+		newSdboot,
 	}
 )
 
@@ -38,6 +38,9 @@ type MockBootloader struct {
 	SetErr           error
 	SetErrFunc       func() error
 	GetErr           error
+	// SetBootVarsWrites records the SetBootVars calls and allows to fail
+	// some of them
+	SetBootVarsWrites MockVarsWrites
 
 	name    string
 	bootdir string
@@ -84,9 +87,45 @@ func (b *MockBootloader) maybePanic(which string) {
 	}
 }
 
+// MockVarsWrites records calls setting bootloader variables and allows to
+// simulate failures of some of them. A failed call does not set any of the
+// variables.
+type MockVarsWrites struct {
+	// ErrOnCall maps the number of a call, starting at 1, to the error the
+	// call fails with.
+	ErrOnCall map[int]error
+	// ErrOnKey maps a variable to the error any call setting it fails with.
+	ErrOnKey map[string]error
+	// Calls holds the variables passed to each call, including the
+	// failed ones.
+	Calls []map[string]string
+}
+
+// record records a call with the given variables and returns the error the
+// call should fail with, if any.
+func (w *MockVarsWrites) record(values map[string]string) error {
+	call := make(map[string]string, len(values))
+	for k, v := range values {
+		call[k] = v
+	}
+	w.Calls = append(w.Calls, call)
+	if err := w.ErrOnCall[len(w.Calls)]; err != nil {
+		return err
+	}
+	for k := range values {
+		if err := w.ErrOnKey[k]; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (b *MockBootloader) SetBootVars(values map[string]string) error {
 	b.maybePanic("SetBootVars")
 	b.SetBootVarsCalls++
+	if err := b.SetBootVarsWrites.record(values); err != nil {
+		return err
+	}
 	for k, v := range values {
 		b.BootVars[k] = v
 	}
@@ -181,6 +220,9 @@ func (b *MockBootloader) SetMockToPanic(f string) (restore func()) {
 type MockRecoveryAwareMixin struct {
 	RecoverySystemDir      string
 	RecoverySystemBootVars map[string]string
+	// SetRecoverySystemEnvWrites records the SetRecoverySystemEnv calls
+	// and allows to fail some of them
+	SetRecoverySystemEnvWrites MockVarsWrites
 }
 
 // MockRecoveryAwareBootloader mocks a bootloader implementing the
@@ -202,6 +244,9 @@ func (b *MockRecoveryAwareMixin) SetRecoverySystemEnv(recoverySystemDir string,
 	if recoverySystemDir == "" {
 		panic("MockBootloader.SetRecoverySystemEnv called without recoverySystemDir")
 	}
+	if err := b.SetRecoverySystemEnvWrites.record(blVars); err != nil {
+		return err
+	}
 	b.RecoverySystemDir = recoverySystemDir
 	b.RecoverySystemBootVars = blVars
 	return nil
@@ -523,6 +568,41 @@ func (b *MockBootloader) WithRecoveryAwareTrustedAssets() *MockRecoveryAwareTrus
 	}
 }
 
+// MockRecoveryAwareExtractedRunKernelImageBootloader implements the
+// bootloader.RecoveryAwareBootloader and
+// bootloader.ExtractedRunKernelImageBootloader interfaces, like sd-boot does.
+type MockRecoveryAwareExtractedRunKernelImageBootloader struct {
+	*MockBootloader
+
+	MockRecoveryAwareMixin
+	MockExtractedRunKernelImageMixin
+}
+
+// WithRecoveryAwareExtractedRunKernelImage derives a
+// MockRecoveryAwareExtractedRunKernelImageBootloader from a base
+// MockBootloader.
+func (b *MockBootloader) WithRecoveryAwareExtractedRunKernelImage() *MockRecoveryAwareExtractedRunKernelImageBootloader {
+	return &MockRecoveryAwareExtractedRunKernelImageBootloader{
+		MockBootloader: b,
+
+		MockExtractedRunKernelImageMixin: MockExtractedRunKernelImageMixin{
+			runKernelImageMockedErrs:     make(map[string]error),
+			runKernelImageMockedNumCalls: make(map[string]int),
+			maybePanic:                   b.maybePanic,
+		},
+	}
+}
+
+func (b *MockRecoveryAwareExtractedRunKernelImageBootloader) SetEnabledKernel(kernel snap.PlaceInfo) (restore func()) {
+	// pick the right implementation
+	return b.MockExtractedRunKernelImageMixin.SetEnabledKernel(kernel)
+}
+
+func (b *MockRecoveryAwareExtractedRunKernelImageBootloader) SetEnabledTryKernel(kernel snap.PlaceInfo) (restore func()) {
+	// pick the right implementation
+	return b.MockExtractedRunKernelImageMixin.SetEnabledTryKernel(kernel)
+}
+
 // MockNotScriptableBootloader implements the
 // bootloader.NotScriptableBootloader interface.
 type MockNotScriptableBootloader struct {
@@ -597,3 +677,47 @@ func (b *MockBootloader) WithRebootBootloader() *MockRebootBootloader {
 		MockBootloader: b,
 	}
 }
+
+// MockRecoverySystemsMenuBootloader mocks a bootloader implementing the
+// bootloader.RecoverySystemsMenuBootloader interface.
+type MockRecoverySystemsMenuBootloader struct {
+	*MockBootloader
+
+	// RecoverySystemsMenu is the menu set in the last call to
+	// SetRecoverySystemsMenu
+	RecoverySystemsMenu         []bootloader.RecoverySystemsMenuEntry
+	SetRecoverySystemsMenuCalls int
+	SetRecoverySystemsMenuErr   error
+}
+
+func (b *MockBootloader) WithRecoverySystemsMenu() *MockRecoverySystemsMenuBootloader {
+	return &MockRecoverySystemsMenuBootloader{
+		MockBootloader: b,
+	}
+}
+
+// SetRecoverySystemsMenu sets the menu of recovery systems; part of
+// bootloader.RecoverySystemsMenuBootloader.
+func (b *MockRecoverySystemsMenuBootloader) SetRecoverySystemsMenu(systems []bootloader.RecoverySystemsMenuEntry) error {
+	b.SetRecoverySystemsMenuCalls++
+	if b.SetRecoverySystemsMenuErr != nil {
+		return b.SetRecoverySystemsMenuErr
+	}
+	b.RecoverySystemsMenu = systems
+	return nil
+}
+
+// MockTrustedAssetsRecoverySystemsMenuBootloader mocks a bootloader
+// implementing the bootloader.RecoverySystemsMenuBootloader and
+// bootloader.TrustedAssetsBootloader interfaces, like grub does.
+type MockTrustedAssetsRecoverySystemsMenuBootloader struct {
+	*MockRecoverySystemsMenuBootloader
+
+	MockTrustedAssetsMixin
+}
+
+func (b *MockBootloader) WithTrustedAssetsRecoverySystemsMenu() *MockTrustedAssetsRecoverySystemsMenuBootloader {
+	return &MockTrustedAssetsRecoverySystemsMenuBootloader{
+		MockRecoverySystemsMenuBootloader: b.WithRecoverySystemsMenu(),
+	}
+}
@@ -74,6 +74,18 @@ func MockGrubFiles(c *C, rootdir string) {
 	c.Assert(err, IsNil)
 }
 
+func NewSdboot(rootdir string, opts *Options) RecoveryAwareBootloader {
+	return newSdboot(rootdir, opts).(RecoveryAwareBootloader)
+}
+
+func MockSdbootFiles(c *C, rootdir string) {
+	s := &sdboot{rootdir: rootdir}
+	err := os.MkdirAll(filepath.Dir(s.envFile()), 0755)
+	c.Assert(err, IsNil)
+	err = os.WriteFile(s.envFile(), nil, 0644)
+	c.Assert(err, IsNil)
+}
+
 func NewLk(rootdir string, opts *Options) ExtractedRecoveryKernelImageBootloader {
 	if opts == nil {
 		opts = &Options{
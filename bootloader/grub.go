@@ -20,9 +20,11 @@
 package bootloader
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/snapcore/snapd/arch"
@@ -39,6 +41,7 @@ var (
 	_ RecoveryAwareBootloader           = (*grub)(nil)
 	_ ExtractedRunKernelImageBootloader = (*grub)(nil)
 	_ TrustedAssetsBootloader           = (*grub)(nil)
+	_ RecoverySystemsMenuBootloader     = (*grub)(nil)
 )
 
 type grub struct {
@@ -142,6 +145,62 @@ func (g *grub) GetRecoverySystemEnv(recoverySystemDir string, key string) (strin
 	return genv.Get(key), nil
 }
 
+// validRecoverySystemsMenuLabel matches the labels which can be safely used
+// in the menu of recovery systems
+var validRecoverySystemsMenuLabel = regexp.MustCompile(`^[a-zA-Z0-9](?:-?[a-zA-Z0-9])*$`)
+
+func (g *grub) recoverySystemsMenuFile() string {
+	return filepath.Join(g.dir(), "recovery-systems.cfg")
+}
+
+// SetRecoverySystemsMenu writes the menu of recovery systems sourced by the
+// managed recovery boot config. The menu is not a trusted asset, as such its
+// entries only carry the labels of the systems, while the kernel command line
+// is composed by the recovery boot config.
+//
+// Implements RecoverySystemsMenuBootloader for the grub bootloader.
+func (g *grub) SetRecoverySystemsMenu(systems []RecoverySystemsMenuEntry) error {
+	if !g.recovery {
+		return fmt.Errorf("not a recovery bootloader")
+	}
+	menuFile := g.recoverySystemsMenuFile()
+	if len(systems) == 0 {
+		if err := os.Remove(menuFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by snapd, do not edit\n")
+	buf.WriteString("submenu \"Recovery systems\" --id=recovery-systems {\n")
+	for _, system := range systems {
+		if !validRecoverySystemsMenuLabel.MatchString(system.Label) {
+			return fmt.Errorf("cannot use recovery system label %q in the menu", system.Label)
+		}
+		title := system.Label
+		if !system.Created.IsZero() {
+			title = fmt.Sprintf("%s (created %s)", system.Label, system.Created.UTC().Format("2006-01-02"))
+		}
+		for _, entry := range []struct{ mode, desc string }{
+			{"recover", "Recover"},
+			{"install", "Install"},
+			{"factory-reset", "Factory reset"},
+		} {
+			fmt.Fprintf(&buf, "    menuentry \"%s using %s\" --id=recovery-systems-%s-%s %s %s {\n",
+				entry.desc, title, entry.mode, system.Label, entry.mode, system.Label)
+			buf.WriteString("        snapd_boot_recovery_system $2 $3\n")
+			buf.WriteString("    }\n")
+		}
+	}
+	buf.WriteString("}\n")
+
+	if err := os.MkdirAll(filepath.Dir(menuFile), 0755); err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(menuFile, buf.Bytes(), 0644, 0)
+}
+
 func (g *grub) Present() (bool, error) {
 	return osutil.FileExists(filepath.Join(g.dir(), "grub.cfg")), nil
 }
@@ -24,6 +24,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"time"
 
 	"github.com/mvo5/goconfigparser"
 	. "gopkg.in/check.v1"
@@ -347,6 +348,73 @@ func (s *grubTestSuite) TestGetRecoverySystemEnv(c *C) {
 	c.Check(value, Equals, ``)
 }
 
+func (s *grubTestSuite) TestSetRecoverySystemsMenu(c *C) {
+	s.makeFakeGrubEFINativeEnv(c, nil)
+	g := bootloader.NewGrub(s.rootdir, &bootloader.Options{Role: bootloader.RoleRecovery})
+	mbl, ok := g.(bootloader.RecoverySystemsMenuBootloader)
+	c.Assert(ok, Equals, true)
+
+	err := mbl.SetRecoverySystemsMenu([]bootloader.RecoverySystemsMenuEntry{
+		{Label: "20191209", Created: time.Date(2019, 12, 9, 10, 0, 0, 0, time.UTC)},
+		{Label: "1234-foo"},
+	})
+	c.Assert(err, IsNil)
+	menuFile := filepath.Join(s.grubEFINativeDir(), "recovery-systems.cfg")
+	c.Check(menuFile, testutil.FileEquals, `# Generated by snapd, do not edit
+submenu "Recovery systems" --id=recovery-systems {
+    menuentry "Recover using 20191209 (created 2019-12-09)" --id=recovery-systems-recover-20191209 recover 20191209 {
+        snapd_boot_recovery_system $2 $3
+    }
+    menuentry "Install using 20191209 (created 2019-12-09)" --id=recovery-systems-install-20191209 install 20191209 {
+        snapd_boot_recovery_system $2 $3
+    }
+    menuentry "Factory reset using 20191209 (created 2019-12-09)" --id=recovery-systems-factory-reset-20191209 factory-reset 20191209 {
+        snapd_boot_recovery_system $2 $3
+    }
+    menuentry "Recover using 1234-foo" --id=recovery-systems-recover-1234-foo recover 1234-foo {
+        snapd_boot_recovery_system $2 $3
+    }
+    menuentry "Install using 1234-foo" --id=recovery-systems-install-1234-foo install 1234-foo {
+        snapd_boot_recovery_system $2 $3
+    }
+    menuentry "Factory reset using 1234-foo" --id=recovery-systems-factory-reset-1234-foo factory-reset 1234-foo {
+        snapd_boot_recovery_system $2 $3
+    }
+}
+`)
+
+	// the menu is removed when there are no systems
+	err = mbl.SetRecoverySystemsMenu(nil)
+	c.Assert(err, IsNil)
+	c.Check(menuFile, testutil.FileAbsent)
+	// and removing it again is fine
+	err = mbl.SetRecoverySystemsMenu(nil)
+	c.Assert(err, IsNil)
+}
+
+func (s *grubTestSuite) TestSetRecoverySystemsMenuBadLabel(c *C) {
+	s.makeFakeGrubEFINativeEnv(c, nil)
+	g := bootloader.NewGrub(s.rootdir, &bootloader.Options{Role: bootloader.RoleRecovery})
+	mbl, ok := g.(bootloader.RecoverySystemsMenuBootloader)
+	c.Assert(ok, Equals, true)
+
+	err := mbl.SetRecoverySystemsMenu([]bootloader.RecoverySystemsMenuEntry{
+		{Label: `20191209" {`},
+	})
+	c.Assert(err, ErrorMatches, `cannot use recovery system label "20191209\\" {" in the menu`)
+	c.Check(filepath.Join(s.grubEFINativeDir(), "recovery-systems.cfg"), testutil.FileAbsent)
+}
+
+func (s *grubTestSuite) TestSetRecoverySystemsMenuNotRecoveryBootloader(c *C) {
+	s.makeFakeGrubEFINativeEnv(c, nil)
+	g := bootloader.NewGrub(s.rootdir, &bootloader.Options{Role: bootloader.RoleRunMode})
+	mbl, ok := g.(bootloader.RecoverySystemsMenuBootloader)
+	c.Assert(ok, Equals, true)
+
+	err := mbl.SetRecoverySystemsMenu(nil)
+	c.Assert(err, ErrorMatches, "not a recovery bootloader")
+}
+
 func (s *grubTestSuite) makeKernelAssetSnap(c *C, snapFileName string) snap.PlaceInfo {
 	kernelSnap, err := snap.ParsePlaceInfoFromSnapFileName(snapFileName)
 	c.Assert(err, IsNil)
@@ -452,7 +452,11 @@ func (p *piboot) ExtractRecoveryKernelAssets(recoverySystemDir string, s snap.Pl
 		filepath.Join(p.rootdir, recoverySystemDir, "kernel")
 	logger.Debugf("ExtractRecoveryKernelAssets to %s", recoveryKernelAssetsDir)
 
-	return p.layoutKernelAssetsToDir(snapf, recoveryKernelAssetsDir)
+	// the recovery system must never be left with partially extracted
+	// assets
+	return osutil.AtomicReplaceDir(recoveryKernelAssetsDir, func(stagingDir string) error {
+		return p.layoutKernelAssetsToDir(snapf, stagingDir)
+	})
 }
 
 func (p *piboot) RemoveKernelAssets(s snap.PlaceInfo) error {
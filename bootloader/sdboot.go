@@ -0,0 +1,490 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package bootloader
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/bootloader/sdbootenv"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/osutil/kcmdline"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snapfile"
+)
+
+// sdboot implements the required interfaces
+var (
+	_ Bootloader                        = (*sdboot)(nil)
+	_ RecoveryAwareBootloader           = (*sdboot)(nil)
+	_ ExtractedRunKernelImageBootloader = (*sdboot)(nil)
+)
+
+const (
+	sdbootEnvFile = "EFI/ubuntu/sdbootenv"
+	// the kernel images are extracted to the ESP under
+	// EFI/ubuntu/<kernel-snap-file-name>/kernel.efi
+	sdbootKernelDir = "EFI/ubuntu"
+
+	sdbootRunEntry = "snapd-run.conf"
+	// the try entry is given a single boot attempt through the systemd-boot
+	// boot counting, once the attempt is used up the entry is marked as bad
+	// and the run entry is picked again
+	sdbootTryEntry        = "snapd-run-try+1.conf"
+	sdbootTryEntryPattern = "snapd-run-try*.conf"
+
+	sdbootStaticCmdline = "console=ttyS0 console=tty1 panic=-1"
+)
+
+// sdbootRecoveryModes are the modes for which boot entries of each recovery
+// system are generated.
+var sdbootRecoveryModes = []string{"recover", "install", "factory-reset"}
+
+// sdboot is the systemd-boot bootloader. As systemd-boot cannot load kernels
+// from snaps nor run any scripts, the kernel images are extracted to the ESP
+// and snapd generates the boot loader entries from the boot variables kept in
+// an environment file of its own.
+type sdboot struct {
+	rootdir string
+
+	recovery bool
+}
+
+// newSdboot creates a new systemd-boot bootloader object
+func newSdboot(rootdir string, opts *Options) Bootloader {
+	s := &sdboot{rootdir: rootdir}
+	if opts != nil {
+		s.recovery = opts.Role == RoleRecovery
+	}
+	return s
+}
+
+func (s *sdboot) Name() string {
+	return "sd-boot"
+}
+
+func (s *sdboot) dir() string {
+	if s.rootdir == "" {
+		panic("internal error: unset rootdir")
+	}
+	return s.rootdir
+}
+
+func (s *sdboot) envFile() string {
+	return filepath.Join(s.dir(), sdbootEnvFile)
+}
+
+func (s *sdboot) entriesDir() string {
+	return filepath.Join(s.dir(), "loader/entries")
+}
+
+func (s *sdboot) loaderConfFile() string {
+	return filepath.Join(s.dir(), "loader/loader.conf")
+}
+
+// Present returns true only when the environment file of snapd exists, such
+// that a systemd-boot installation which is not managed by snapd is not
+// picked up.
+func (s *sdboot) Present() (bool, error) {
+	return osutil.FileExists(s.envFile()), nil
+}
+
+func (s *sdboot) InstallBootConfig(gadgetDir string, opts *Options) error {
+	if opts == nil || opts.Role == RoleSole {
+		return fmt.Errorf("cannot use %s bootloader on a system without modes", s.Name())
+	}
+	if err := os.MkdirAll(filepath.Dir(s.envFile()), 0755); err != nil {
+		return err
+	}
+	if err := sdbootenv.NewEnv(s.envFile()).Save(); err != nil {
+		return err
+	}
+	return s.writeLoaderConf(sdbootDefaultEntry(nil))
+}
+
+func (s *sdboot) writeLoaderConf(defaultEntry string) error {
+	if err := os.MkdirAll(filepath.Dir(s.loaderConfFile()), 0755); err != nil {
+		return err
+	}
+	content := fmt.Sprintf("# Generated by snapd, do not edit\ntimeout 3\ndefault %s\n", defaultEntry)
+	return osutil.AtomicWriteFile(s.loaderConfFile(), []byte(content), 0644, 0)
+}
+
+// sdbootDefaultEntry returns the default entry for the given environment. A
+// recovery system is booted in the requested mode, otherwise the run mode
+// entries are picked, and out of those the try entry takes precedence over
+// the run entry, unless it was already marked as bad.
+func sdbootDefaultEntry(env *sdbootenv.Env) string {
+	if env != nil {
+		mode := env.Get("snapd_recovery_mode")
+		system := env.Get("snapd_recovery_system")
+		if mode != "" && mode != "run" && system != "" {
+			return sdbootRecoveryEntryName(system, mode)
+		}
+	}
+	return "snapd-run*"
+}
+
+func sdbootRecoveryEntryName(system, mode string) string {
+	return fmt.Sprintf("snapd-recovery-%s-%s.conf", system, mode)
+}
+
+func (s *sdboot) loadEnv() (*sdbootenv.Env, error) {
+	env := sdbootenv.NewEnv(s.envFile())
+	if err := env.Load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return env, nil
+}
+
+func (s *sdboot) GetBootVars(names ...string) (map[string]string, error) {
+	env := sdbootenv.NewEnv(s.envFile())
+	if err := env.Load(); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(names))
+	for _, name := range names {
+		out[name] = env.Get(name)
+	}
+	return out, nil
+}
+
+func (s *sdboot) SetBootVars(values map[string]string) error {
+	env, err := s.loadEnv()
+	if err != nil {
+		return err
+	}
+	// set when the entries need to be generated again
+	rewriteEntries := false
+	// set when the default entry may have changed
+	rewriteLoaderConf := false
+	for k, v := range values {
+		if env.Get(k) == v {
+			continue
+		}
+		env.Set(k, v)
+		switch k {
+		case "snapd_extra_cmdline_args", "snapd_full_cmdline_args":
+			rewriteEntries = true
+		case "snapd_recovery_mode", "snapd_recovery_system":
+			rewriteLoaderConf = true
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.envFile()), 0755); err != nil {
+		return err
+	}
+	if err := env.Save(); err != nil {
+		return err
+	}
+
+	if rewriteEntries && !s.recovery {
+		if err := s.rewriteRunEntries(env); err != nil {
+			return err
+		}
+	}
+	if rewriteLoaderConf && s.recovery {
+		return s.writeLoaderConf(sdbootDefaultEntry(env))
+	}
+	return nil
+}
+
+func sdbootCommandLine(snapdArgs []string, env *sdbootenv.Env) (string, error) {
+	pieces := CommandLineComponents{
+		ExtraArgs: env.Get("snapd_extra_cmdline_args"),
+		FullArgs:  env.Get("snapd_full_cmdline_args"),
+	}
+	if err := pieces.Validate(); err != nil {
+		return "", err
+	}
+	nonSnapdCmdline := pieces.FullArgs
+	if nonSnapdCmdline == "" {
+		nonSnapdCmdline = sdbootStaticCmdline + " " + pieces.ExtraArgs
+	}
+	args, err := kcmdline.Split(nonSnapdCmdline)
+	if err != nil {
+		return "", fmt.Errorf("cannot use badly formatted kernel command line: %v", err)
+	}
+	return strings.Join(append(snapdArgs, args...), " "), nil
+}
+
+type sdbootEntry struct {
+	title   string
+	version string
+	efi     string
+	options string
+}
+
+func (s *sdboot) writeEntry(name string, entry *sdbootEntry) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Generated by snapd, do not edit\n")
+	fmt.Fprintf(&buf, "title %s\n", entry.title)
+	fmt.Fprintf(&buf, "sort-key snapd\n")
+	if entry.version != "" {
+		fmt.Fprintf(&buf, "version %s\n", entry.version)
+	}
+	fmt.Fprintf(&buf, "efi %s\n", entry.efi)
+	fmt.Fprintf(&buf, "options %s\n", entry.options)
+
+	if err := os.MkdirAll(s.entriesDir(), 0755); err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(filepath.Join(s.entriesDir(), name), buf.Bytes(), 0644, 0)
+}
+
+// readEntryEfi returns the EFI binary booted by the given entry.
+func (s *sdboot) readEntryEfi(name string) (string, error) {
+	f, err := os.Open(filepath.Join(s.entriesDir(), name))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "efi" {
+			return fields[1], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("cannot find efi binary in boot entry %s", name)
+}
+
+func (s *sdboot) SetRecoverySystemEnv(recoverySystemDir string, values map[string]string) error {
+	if recoverySystemDir == "" {
+		return fmt.Errorf("internal error: recoverySystemDir unset")
+	}
+	systemDir := filepath.Join(s.dir(), recoverySystemDir)
+	if err := os.MkdirAll(systemDir, 0755); err != nil {
+		return err
+	}
+	env := sdbootenv.NewEnv(filepath.Join(systemDir, "sdbootenv"))
+	for k, v := range values {
+		env.Set(k, v)
+	}
+	if err := env.Save(); err != nil {
+		return err
+	}
+
+	kernelPath := env.Get("snapd_recovery_kernel")
+	if kernelPath == "" {
+		return fmt.Errorf("cannot set up boot entries of recovery system %q: kernel not set", recoverySystemDir)
+	}
+	// systemd-boot cannot load the kernel from inside the snap, extract it
+	// to the recovery system directory
+	kernelf, err := snapfile.Open(filepath.Join(s.dir(), kernelPath))
+	if err != nil {
+		return err
+	}
+	if err := kernelf.Unpack("kernel.efi", systemDir); err != nil {
+		return fmt.Errorf("cannot extract kernel of recovery system %q: %v", recoverySystemDir, err)
+	}
+
+	label := filepath.Base(recoverySystemDir)
+	for _, mode := range sdbootRecoveryModes {
+		cmdline, err := sdbootCommandLine([]string{
+			"snapd_recovery_mode=" + mode,
+			"snapd_recovery_system=" + label,
+		}, env)
+		if err != nil {
+			return err
+		}
+		entry := &sdbootEntry{
+			title:   fmt.Sprintf("Ubuntu Core %s using %s", mode, label),
+			efi:     filepath.Join("/", recoverySystemDir, "kernel.efi"),
+			options: cmdline,
+		}
+		if err := s.writeEntry(sdbootRecoveryEntryName(label, mode), entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sdboot) GetRecoverySystemEnv(recoverySystemDir string, key string) (string, error) {
+	if recoverySystemDir == "" {
+		return "", fmt.Errorf("internal error: recoverySystemDir unset")
+	}
+	env := sdbootenv.NewEnv(filepath.Join(s.dir(), recoverySystemDir, "sdbootenv"))
+	if err := env.Load(); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return env.Get(key), nil
+}
+
+func (s *sdboot) extractedKernelDir(sn snap.PlaceInfo) string {
+	return filepath.Join(s.dir(), sdbootKernelDir, sn.Filename())
+}
+
+func (s *sdboot) ExtractKernelAssets(sn snap.PlaceInfo, snapf snap.Container) error {
+	return extractKernelAssetsToBootDir(s.extractedKernelDir(sn), snapf, []string{"kernel.efi"})
+}
+
+func (s *sdboot) RemoveKernelAssets(sn snap.PlaceInfo) error {
+	return removeKernelAssetsFromBootDir(filepath.Join(s.dir(), sdbootKernelDir), sn)
+}
+
+// writeRunEntry writes the given run mode entry booting the given kernel,
+// which must have been extracted already.
+func (s *sdboot) writeRunEntry(name string, sn snap.PlaceInfo, env *sdbootenv.Env) error {
+	efi := filepath.Join(sdbootKernelDir, sn.Filename(), "kernel.efi")
+	if !osutil.FileExists(filepath.Join(s.dir(), efi)) {
+		return fmt.Errorf("cannot enable %s at %s: %v", name, efi, os.ErrNotExist)
+	}
+	cmdline, err := sdbootCommandLine([]string{"snapd_recovery_mode=run"}, env)
+	if err != nil {
+		return err
+	}
+	entry := &sdbootEntry{
+		title:   fmt.Sprintf("Ubuntu Core (%s)", sn.Filename()),
+		efi:     filepath.Join("/", efi),
+		options: cmdline,
+	}
+	// systemd-boot orders the entries by the version in descending order,
+	// the try entry needs to come first to be picked as the default
+	if name == sdbootRunEntry {
+		entry.version = "1"
+	} else {
+		entry.version = "2"
+	}
+	return s.writeEntry(name, entry)
+}
+
+// rewriteRunEntries generates the run mode entries again, keeping the kernels
+// they boot.
+func (s *sdboot) rewriteRunEntries(env *sdbootenv.Env) error {
+	if sn, err := s.Kernel(); err == nil {
+		if err := s.writeRunEntry(sdbootRunEntry, sn, env); err != nil {
+			return err
+		}
+	}
+	if name, err := s.tryEntry(); err == nil && name != "" {
+		sn, err := s.readEntryKernel(name)
+		if err != nil {
+			return err
+		}
+		// keep the name, as it carries the boot counting state
+		if err := s.writeRunEntry(name, sn, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sdboot) readEntryKernel(name string) (snap.PlaceInfo, error) {
+	efi, err := s.readEntryEfi(name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read boot entry %s: %v", name, err)
+	}
+	kernelSnapFileName := filepath.Base(filepath.Dir(efi))
+	sn, err := snap.ParsePlaceInfoFromSnapFileName(kernelSnapFileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse kernel snap file name from boot entry %s: %v", name, err)
+	}
+	return sn, nil
+}
+
+// tryEntry returns the name of the try entry, which changes as systemd-boot
+// counts the boot attempts, or an empty string if there is none.
+func (s *sdboot) tryEntry() (string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.entriesDir(), sdbootTryEntryPattern))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", nil
+	}
+	return filepath.Base(matches[0]), nil
+}
+
+// EnableKernel writes the run mode boot entry booting the given kernel.
+//
+// Implements ExtractedRunKernelImageBootloader for the sd-boot bootloader.
+func (s *sdboot) EnableKernel(sn snap.PlaceInfo) error {
+	env, err := s.loadEnv()
+	if err != nil {
+		return err
+	}
+	return s.writeRunEntry(sdbootRunEntry, sn, env)
+}
+
+// EnableTryKernel writes the try boot entry booting the given kernel, the
+// entry is booted at most once.
+//
+// Implements ExtractedRunKernelImageBootloader for the sd-boot bootloader.
+func (s *sdboot) EnableTryKernel(sn snap.PlaceInfo) error {
+	env, err := s.loadEnv()
+	if err != nil {
+		return err
+	}
+	// drop the previous try entry, which may have been renamed by the boot
+	// counting
+	if err := s.DisableTryKernel(); err != nil {
+		return err
+	}
+	return s.writeRunEntry(sdbootTryEntry, sn, env)
+}
+
+// DisableTryKernel removes the try boot entry if it exists.
+//
+// Implements ExtractedRunKernelImageBootloader for the sd-boot bootloader.
+func (s *sdboot) DisableTryKernel() error {
+	matches, err := filepath.Glob(filepath.Join(s.entriesDir(), sdbootTryEntryPattern))
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		if err := os.Remove(m); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Kernel returns the kernel booted by the run mode boot entry.
+//
+// Implements ExtractedRunKernelImageBootloader for the sd-boot bootloader.
+func (s *sdboot) Kernel() (snap.PlaceInfo, error) {
+	return s.readEntryKernel(sdbootRunEntry)
+}
+
+// TryKernel returns the kernel booted by the try boot entry, or
+// ErrNoTryKernelRef if there is no such entry.
+//
+// Implements ExtractedRunKernelImageBootloader for the sd-boot bootloader.
+func (s *sdboot) TryKernel() (snap.PlaceInfo, error) {
+	name, err := s.tryEntry()
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, ErrNoTryKernelRef
+	}
+	return s.readEntryKernel(name)
+}
@@ -0,0 +1,290 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package bootloader_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snapfile"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type sdbootTestSuite struct {
+	baseBootenvTestSuite
+}
+
+var _ = Suite(&sdbootTestSuite{})
+
+func (s *sdbootTestSuite) SetUpTest(c *C) {
+	s.baseBootenvTestSuite.SetUpTest(c)
+	bootloader.MockSdbootFiles(c, s.rootdir)
+}
+
+func (s *sdbootTestSuite) runBootloader(c *C) bootloader.ExtractedRunKernelImageBootloader {
+	bl := bootloader.NewSdboot(s.rootdir, &bootloader.Options{Role: bootloader.RoleRunMode})
+	ebl, ok := bl.(bootloader.ExtractedRunKernelImageBootloader)
+	c.Assert(ok, Equals, true)
+	return ebl
+}
+
+func (s *sdbootTestSuite) makeKernelAssets(c *C, snapFileName string) snap.PlaceInfo {
+	kernelSnap, err := snap.ParsePlaceInfoFromSnapFileName(snapFileName)
+	c.Assert(err, IsNil)
+	// as done by ExtractKernelAssets()
+	kernelEfi := filepath.Join(s.rootdir, "EFI/ubuntu", snapFileName, "kernel.efi")
+	c.Assert(os.MkdirAll(filepath.Dir(kernelEfi), 0755), IsNil)
+	c.Assert(os.WriteFile(kernelEfi, nil, 0644), IsNil)
+	return kernelSnap
+}
+
+func (s *sdbootTestSuite) TestNewSdboot(c *C) {
+	bl := bootloader.NewSdboot(s.rootdir, nil)
+	c.Assert(bl, NotNil)
+	c.Check(bl.Name(), Equals, "sd-boot")
+	present, err := bl.Present()
+	c.Assert(err, IsNil)
+	c.Check(present, Equals, true)
+
+	// not present without the environment of snapd
+	bl = bootloader.NewSdboot(c.MkDir(), nil)
+	present, err = bl.Present()
+	c.Assert(err, IsNil)
+	c.Check(present, Equals, false)
+}
+
+func (s *sdbootTestSuite) TestInstallBootConfig(c *C) {
+	rootdir := c.MkDir()
+	gadgetDir := c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(gadgetDir, "sd-boot.conf"), nil, 0644), IsNil)
+
+	opts := &bootloader.Options{Role: bootloader.RoleRunMode, NoSlashBoot: true}
+	err := bootloader.InstallBootConfig(gadgetDir, rootdir, opts)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(rootdir, "loader/loader.conf"), testutil.FileEquals, `# Generated by snapd, do not edit
+timeout 3
+default snapd-run*
+`)
+	present, err := bootloader.NewSdboot(rootdir, opts).Present()
+	c.Assert(err, IsNil)
+	c.Check(present, Equals, true)
+
+	err = bootloader.NewSdboot(rootdir, nil).InstallBootConfig(gadgetDir, nil)
+	c.Assert(err, ErrorMatches, "cannot use sd-boot bootloader on a system without modes")
+}
+
+func (s *sdbootTestSuite) TestSetGetBootVars(c *C) {
+	bl := bootloader.NewSdboot(s.rootdir, nil)
+	err := bl.SetBootVars(map[string]string{
+		"kernel_status": "try",
+		"snap_kernel":   "pc-kernel_1.snap",
+	})
+	c.Assert(err, IsNil)
+
+	m, err := bl.GetBootVars("kernel_status", "snap_kernel", "unset")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"kernel_status": "try",
+		"snap_kernel":   "pc-kernel_1.snap",
+		"unset":         "",
+	})
+}
+
+func (s *sdbootTestSuite) TestExtractKernelAssets(c *C) {
+	bl := s.runBootloader(c)
+
+	files := [][]string{
+		{"kernel.efi", "I'm a kernel.efi"},
+		{"meta/kernel.yaml", "version: 4.2"},
+	}
+	si := &snap.SideInfo{
+		RealName: "ubuntu-kernel",
+		Revision: snap.R(42),
+	}
+	fn := snaptest.MakeTestSnapWithFiles(c, packageKernel, files)
+	snapf, err := snapfile.Open(fn)
+	c.Assert(err, IsNil)
+	info, err := snap.ReadInfoFromSnapFile(snapf, si)
+	c.Assert(err, IsNil)
+
+	err = bl.ExtractKernelAssets(info, snapf)
+	c.Assert(err, IsNil)
+	kernelEfi := filepath.Join(s.rootdir, "EFI/ubuntu/ubuntu-kernel_42.snap/kernel.efi")
+	c.Check(kernelEfi, testutil.FileEquals, "I'm a kernel.efi")
+
+	err = bl.RemoveKernelAssets(info)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Dir(kernelEfi), testutil.FileAbsent)
+}
+
+func (s *sdbootTestSuite) TestEnableKernel(c *C) {
+	bl := s.runBootloader(c)
+	kernel := s.makeKernelAssets(c, "pc-kernel_1.snap")
+
+	err := bl.EnableKernel(kernel)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(s.rootdir, "loader/entries/snapd-run.conf"), testutil.FileEquals, `# Generated by snapd, do not edit
+title Ubuntu Core (pc-kernel_1.snap)
+sort-key snapd
+version 1
+efi /EFI/ubuntu/pc-kernel_1.snap/kernel.efi
+options snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1
+`)
+
+	sn, err := bl.Kernel()
+	c.Assert(err, IsNil)
+	c.Check(sn, DeepEquals, kernel)
+
+	// the kernel must have been extracted
+	err = bl.EnableKernel(snap.MinimalPlaceInfo("pc-kernel", snap.R(2)))
+	c.Assert(err, ErrorMatches, "cannot enable snapd-run.conf at EFI/ubuntu/pc-kernel_2.snap/kernel.efi: file does not exist")
+}
+
+func (s *sdbootTestSuite) TestEnableTryKernel(c *C) {
+	bl := s.runBootloader(c)
+	s.makeKernelAssets(c, "pc-kernel_1.snap")
+	tryKernel := s.makeKernelAssets(c, "pc-kernel_2.snap")
+
+	_, err := bl.TryKernel()
+	c.Assert(err, Equals, bootloader.ErrNoTryKernelRef)
+
+	err = bl.EnableTryKernel(tryKernel)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(s.rootdir, "loader/entries/snapd-run-try+1.conf"), testutil.FileEquals, `# Generated by snapd, do not edit
+title Ubuntu Core (pc-kernel_2.snap)
+sort-key snapd
+version 2
+efi /EFI/ubuntu/pc-kernel_2.snap/kernel.efi
+options snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1
+`)
+	sn, err := bl.TryKernel()
+	c.Assert(err, IsNil)
+	c.Check(sn, DeepEquals, tryKernel)
+
+	// systemd-boot counted the boot attempt
+	err = os.Rename(filepath.Join(s.rootdir, "loader/entries/snapd-run-try+1.conf"),
+		filepath.Join(s.rootdir, "loader/entries/snapd-run-try+0-1.conf"))
+	c.Assert(err, IsNil)
+	sn, err = bl.TryKernel()
+	c.Assert(err, IsNil)
+	c.Check(sn, DeepEquals, tryKernel)
+
+	err = bl.DisableTryKernel()
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(s.rootdir, "loader/entries/snapd-run-try+0-1.conf"), testutil.FileAbsent)
+	_, err = bl.TryKernel()
+	c.Assert(err, Equals, bootloader.ErrNoTryKernelRef)
+
+	// disabling again is fine
+	err = bl.DisableTryKernel()
+	c.Assert(err, IsNil)
+}
+
+func (s *sdbootTestSuite) TestSetBootVarsRewritesRunEntries(c *C) {
+	bl := s.runBootloader(c)
+	kernel := s.makeKernelAssets(c, "pc-kernel_1.snap")
+	c.Assert(bl.EnableKernel(kernel), IsNil)
+
+	err := bl.SetBootVars(map[string]string{
+		"snapd_extra_cmdline_args": "foo bar",
+	})
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(s.rootdir, "loader/entries/snapd-run.conf"), testutil.FileContains,
+		"options snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1 foo bar\n")
+
+	err = bl.SetBootVars(map[string]string{
+		"snapd_extra_cmdline_args": "",
+		"snapd_full_cmdline_args":  "baz",
+	})
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(s.rootdir, "loader/entries/snapd-run.conf"), testutil.FileContains,
+		"options snapd_recovery_mode=run baz\n")
+}
+
+func (s *sdbootTestSuite) TestRecoverySystemEnv(c *C) {
+	bl := bootloader.NewSdboot(s.rootdir, &bootloader.Options{Role: bootloader.RoleRecovery})
+
+	files := [][]string{
+		{"kernel.efi", "I'm a kernel.efi"},
+		{"meta/kernel.yaml", "version: 4.2"},
+	}
+	fn := snaptest.MakeTestSnapWithFiles(c, packageKernel, files)
+	c.Assert(os.MkdirAll(filepath.Join(s.rootdir, "snaps"), 0755), IsNil)
+	c.Assert(os.Rename(fn, filepath.Join(s.rootdir, "snaps/pc-kernel_1.snap")), IsNil)
+
+	err := bl.SetRecoverySystemEnv("/systems/20191209", map[string]string{
+		"snapd_recovery_kernel":    "/snaps/pc-kernel_1.snap",
+		"snapd_extra_cmdline_args": "foo",
+	})
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(s.rootdir, "systems/20191209/kernel.efi"), testutil.FileEquals, "I'm a kernel.efi")
+	c.Check(filepath.Join(s.rootdir, "loader/entries/snapd-recovery-20191209-recover.conf"), testutil.FileEquals, `# Generated by snapd, do not edit
+title Ubuntu Core recover using 20191209
+sort-key snapd
+efi /systems/20191209/kernel.efi
+options snapd_recovery_mode=recover snapd_recovery_system=20191209 console=ttyS0 console=tty1 panic=-1 foo
+`)
+	for _, mode := range []string{"install", "factory-reset"} {
+		c.Check(filepath.Join(s.rootdir, "loader/entries/snapd-recovery-20191209-"+mode+".conf"), testutil.FileContains,
+			"options snapd_recovery_mode="+mode+" snapd_recovery_system=20191209 ")
+	}
+
+	value, err := bl.GetRecoverySystemEnv("/systems/20191209", "snapd_recovery_kernel")
+	c.Assert(err, IsNil)
+	c.Check(value, Equals, "/snaps/pc-kernel_1.snap")
+	value, err = bl.GetRecoverySystemEnv("/systems/20191209", "unset")
+	c.Assert(err, IsNil)
+	c.Check(value, Equals, "")
+	// no such system
+	value, err = bl.GetRecoverySystemEnv("/systems/other", "snapd_recovery_kernel")
+	c.Assert(err, IsNil)
+	c.Check(value, Equals, "")
+}
+
+func (s *sdbootTestSuite) TestRecoverySystemEnvNoKernel(c *C) {
+	bl := bootloader.NewSdboot(s.rootdir, &bootloader.Options{Role: bootloader.RoleRecovery})
+	err := bl.SetRecoverySystemEnv("/systems/20191209", map[string]string{
+		"snapd_extra_cmdline_args": "foo",
+	})
+	c.Assert(err, ErrorMatches, `cannot set up boot entries of recovery system "/systems/20191209": kernel not set`)
+}
+
+func (s *sdbootTestSuite) TestRecoveryDefaultEntry(c *C) {
+	bl := bootloader.NewSdboot(s.rootdir, &bootloader.Options{Role: bootloader.RoleRecovery})
+	loaderConf := filepath.Join(s.rootdir, "loader/loader.conf")
+
+	err := bl.SetBootVars(map[string]string{
+		"snapd_recovery_mode":   "recover",
+		"snapd_recovery_system": "20191209",
+	})
+	c.Assert(err, IsNil)
+	c.Check(loaderConf, testutil.FileContains, "default snapd-recovery-20191209-recover.conf\n")
+
+	err = bl.SetBootVars(map[string]string{
+		"snapd_recovery_mode": "run",
+	})
+	c.Assert(err, IsNil)
+	c.Check(loaderConf, testutil.FileContains, "default snapd-run*\n")
+}
@@ -0,0 +1,97 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package sdbootenv implements the environment file snapd keeps for
+// systemd-boot. Unlike grub, systemd-boot does not read any environment by
+// itself, the file only persists the boot variables from which snapd
+// generates the boot loader entries.
+package sdbootenv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/osutil"
+)
+
+const header = "# snapd systemd-boot environment, do not edit"
+
+type Env struct {
+	env  map[string]string
+	path string
+}
+
+func NewEnv(path string) *Env {
+	return &Env{
+		env:  make(map[string]string),
+		path: path,
+	}
+}
+
+func (e *Env) Get(name string) string {
+	return e.env[name]
+}
+
+func (e *Env) Set(key, value string) {
+	e.env[key] = value
+}
+
+func (e *Env) Load() error {
+	f, err := os.Open(e.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		l := strings.SplitN(line, "=", 2)
+		if len(l) < 2 {
+			return fmt.Errorf("cannot parse %q: invalid line %q", e.path, line)
+		}
+		e.env[l[0]] = l[1]
+	}
+	return scanner.Err()
+}
+
+func (e *Env) Save() error {
+	keys := make([]string, 0, len(e.env))
+	for k := range e.env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var w bytes.Buffer
+	fmt.Fprintln(&w, header)
+	for _, k := range keys {
+		if strings.Contains(e.env[k], "\n") {
+			return fmt.Errorf("cannot save %q: value of %q contains a newline", e.path, k)
+		}
+		fmt.Fprintf(&w, "%s=%s\n", k, e.env[k])
+	}
+	return osutil.AtomicWriteFile(e.path, w.Bytes(), 0644, 0)
+}
@@ -0,0 +1,93 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package sdbootenv_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/bootloader/sdbootenv"
+	"github.com/snapcore/snapd/testutil"
+)
+
+// Hook up check.v1 into the "go test" runner
+func Test(t *testing.T) { TestingT(t) }
+
+type sdbootenvTestSuite struct {
+	envPath string
+	env     *sdbootenv.Env
+}
+
+var _ = Suite(&sdbootenvTestSuite{})
+
+func (s *sdbootenvTestSuite) SetUpTest(c *C) {
+	s.envPath = filepath.Join(c.MkDir(), "sdbootenv")
+	s.env = sdbootenv.NewEnv(s.envPath)
+	c.Assert(s.env, NotNil)
+}
+
+func (s *sdbootenvTestSuite) TestSet(c *C) {
+	s.env.Set("key", "value")
+	c.Check(s.env.Get("key"), Equals, "value")
+}
+
+func (s *sdbootenvTestSuite) TestSaveAndLoad(c *C) {
+	s.env.Set("key2", "value2")
+	s.env.Set("key1", "")
+	s.env.Set("key3", "a=b c")
+
+	err := s.env.Save()
+	c.Assert(err, IsNil)
+	c.Check(s.envPath, testutil.FileEquals, `# snapd systemd-boot environment, do not edit
+key1=
+key2=value2
+key3=a=b c
+`)
+
+	env2 := sdbootenv.NewEnv(s.envPath)
+	err = env2.Load()
+	c.Assert(err, IsNil)
+	c.Check(env2.Get("key1"), Equals, "")
+	c.Check(env2.Get("key2"), Equals, "value2")
+	c.Check(env2.Get("key3"), Equals, "a=b c")
+}
+
+func (s *sdbootenvTestSuite) TestSaveNewline(c *C) {
+	s.env.Set("key", "foo\nbar")
+	err := s.env.Save()
+	c.Assert(err, ErrorMatches, `cannot save ".*/sdbootenv": value of "key" contains a newline`)
+	c.Check(s.envPath, testutil.FileAbsent)
+}
+
+func (s *sdbootenvTestSuite) TestLoadInvalid(c *C) {
+	err := os.WriteFile(s.envPath, []byte("# comment\nfoo=bar\nbaz\n"), 0644)
+	c.Assert(err, IsNil)
+
+	err = s.env.Load()
+	c.Assert(err, ErrorMatches, `cannot parse ".*/sdbootenv": invalid line "baz"`)
+}
+
+func (s *sdbootenvTestSuite) TestLoadMissing(c *C) {
+	err := s.env.Load()
+	c.Check(os.IsNotExist(err), Equals, true)
+}
@@ -211,7 +211,11 @@ func (u *uboot) ExtractRecoveryKernelAssets(recoverySystemDir string, s snap.Pla
 
 	recoverySystemUbootKernelAssetsDir := filepath.Join(u.rootdir, recoverySystemDir, "kernel")
 	assets := []string{"kernel.img", "initrd.img", "dtbs/*"}
-	return extractKernelAssetsToBootDir(recoverySystemUbootKernelAssetsDir, snapf, assets)
+	// the recovery system must never be left with partially extracted
+	// assets
+	return osutil.AtomicReplaceDir(recoverySystemUbootKernelAssetsDir, func(stagingDir string) error {
+		return extractKernelAssetsToBootDir(stagingDir, snapf, assets)
+	})
 }
 
 func (u *uboot) RemoveKernelAssets(s snap.PlaceInfo) error {
@@ -227,6 +227,44 @@ func (s *ubootTestSuite) TestExtractRecoveryKernelAssets(c *C) {
 	}
 }
 
+func (s *ubootTestSuite) TestExtractRecoveryKernelAssetsReplacesPrevious(c *C) {
+	bootloader.MockUbootFiles(c, s.rootdir, nil)
+	u := bootloader.NewUboot(s.rootdir, nil)
+
+	files := [][]string{
+		{"kernel.img", "I'm a new kernel"},
+		{"initrd.img", "...and I'm a new initrd"},
+		{"dtbs/foo.dtb", "foo dtb"},
+		{"meta/kernel.yaml", "version: 4.2"},
+	}
+	fn := snaptest.MakeTestSnapWithFiles(c, packageKernel, files)
+	snapf, err := snapfile.Open(fn)
+	c.Assert(err, IsNil)
+	info, err := snap.ReadInfoFromSnapFile(snapf, &snap.SideInfo{
+		RealName: "ubuntu-kernel",
+		Revision: snap.R(43),
+	})
+	c.Assert(err, IsNil)
+
+	// assets of a previous kernel
+	kernelAssetsDir := filepath.Join(s.rootdir, "recovery-dir", "kernel")
+	c.Assert(os.MkdirAll(kernelAssetsDir, 0755), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(kernelAssetsDir, "kernel.img"), []byte("old kernel"), 0644), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(kernelAssetsDir, "old.dtb"), []byte("old dtb"), 0644), IsNil)
+
+	err = u.ExtractRecoveryKernelAssets("recovery-dir", info, snapf)
+	c.Assert(err, IsNil)
+
+	c.Check(filepath.Join(kernelAssetsDir, "kernel.img"), testutil.FileEquals, "I'm a new kernel")
+	c.Check(filepath.Join(kernelAssetsDir, "initrd.img"), testutil.FileEquals, "...and I'm a new initrd")
+	c.Check(filepath.Join(kernelAssetsDir, "dtbs/foo.dtb"), testutil.FileEquals, "foo dtb")
+	c.Check(filepath.Join(kernelAssetsDir, "old.dtb"), testutil.FileAbsent)
+	// nothing is left behind
+	matches, err := filepath.Glob(filepath.Join(s.rootdir, "recovery-dir", "kernel.*~"))
+	c.Assert(err, IsNil)
+	c.Check(matches, HasLen, 0)
+}
+
 func (s *ubootTestSuite) TestUbootUC20OptsPlacement(c *C) {
 	tt := []struct {
 		blOpts  *bootloader.Options
@@ -55,6 +55,20 @@ type AppInfo struct {
 	Active      bool             `json:"active,omitempty"`
 	CommonID    string           `json:"common-id,omitempty"`
 	Activators  []AppActivator   `json:"activators,omitempty"`
+	// Users holds the status of a user service in the sessions of
+	// the users, when requested.
+	Users []AppUserStatus `json:"users,omitempty"`
+}
+
+// AppUserStatus describes the status of a user service in the session
+// of a single user.
+type AppUserStatus struct {
+	Uid     int  `json:"uid"`
+	Enabled bool `json:"enabled,omitempty"`
+	Active  bool `json:"active,omitempty"`
+	// NoSession is set when the user has no running session, in which
+	// case the service cannot be active.
+	NoSession bool `json:"no-session,omitempty"`
 }
 
 // IsService returns true if the application is a background daemon.
@@ -74,6 +88,10 @@ type AppOptions struct {
 	// If Service is true, only return apps that are services
 	// (app.IsService() is true); otherwise, return all.
 	Service bool
+	// Users selects the users whose sessions are queried for the
+	// status of user services, either "all" for all the users with a
+	// running session or a comma separated list of uids.
+	Users string
 }
 
 // Apps returns information about all matching apps. Each name can be
@@ -87,6 +105,9 @@ func (client *Client) Apps(names []string, opts AppOptions) ([]*AppInfo, error)
 	if opts.Service {
 		q.Add("select", "service")
 	}
+	if opts.Users != "" {
+		q.Add("users", opts.Users)
+	}
 
 	var appInfos []*AppInfo
 	_, err := client.doSync("GET", "/v2/apps", q, nil, nil, &appInfos)
@@ -128,6 +149,12 @@ func (l Log) fmtLog(timezone *time.Location) string {
 
 // Logs asks for the logs of a series of services, by name.
 func (client *Client) Logs(names []string, opts LogOptions) (<-chan Log, error) {
+	return client.LogsWithContext(context.Background(), names, opts)
+}
+
+// LogsWithContext is like Logs, cancelling ctx stops following the logs
+// and closes the returned channel.
+func (client *Client) LogsWithContext(ctx context.Context, names []string, opts LogOptions) (<-chan Log, error) {
 	query := url.Values{}
 	if len(names) > 0 {
 		query.Set("names", strings.Join(names, ","))
@@ -137,7 +164,7 @@ func (client *Client) Logs(names []string, opts LogOptions) (<-chan Log, error)
 		query.Set("follow", strconv.FormatBool(opts.Follow))
 	}
 
-	rsp, err := client.raw(context.Background(), "GET", "/v2/logs", query, nil, nil)
+	rsp, err := client.raw(ctx, "GET", "/v2/logs", query, nil, nil)
 	if err != nil {
 		return nil, err
 	}
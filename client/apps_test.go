@@ -22,12 +22,14 @@ package client_test
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/snap"
 )
 
 func mksvc(snap, app string) *client.AppInfo {
@@ -103,6 +105,33 @@ func (cs *clientSuite) TestClientAppCommonID(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientAppsUsers(c *check.C) {
+	expected := []*client.AppInfo{{
+		Snap:        "foo",
+		Name:        "svc",
+		Daemon:      "simple",
+		DaemonScope: snap.UserDaemon,
+		Enabled:     true,
+		Users: []client.AppUserStatus{
+			{Uid: 1000, Enabled: true, Active: true},
+			{Uid: 1001, NoSession: true},
+		},
+	}}
+	buf, err := json.Marshal(expected)
+	c.Assert(err, check.IsNil)
+	cs.rsp = fmt.Sprintf(`{"type": "sync", "result": %s}`, buf)
+
+	actual, err := cs.cli.Apps([]string{"foo"}, client.AppOptions{Service: true, Users: "1000,1001"})
+	c.Assert(err, check.IsNil)
+	c.Check(actual, check.DeepEquals, expected)
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/apps")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"names":  []string{"foo"},
+		"select": []string{"service"},
+		"users":  []string{"1000,1001"},
+	})
+}
+
 func testClientLogs(cs *clientSuite, c *check.C) ([]client.Log, error) {
 	ch, err := cs.cli.Logs([]string{"foo", "bar"}, client.LogOptions{N: -1, Follow: false})
 	c.Check(cs.req.URL.Path, check.Equals, "/v2/logs")
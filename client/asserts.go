@@ -47,6 +47,33 @@ func (client *Client) Ack(b []byte) error {
 	return nil
 }
 
+// AssertionRef identifies an assertion by its type and the headers of its
+// primary key.
+type AssertionRef struct {
+	Type    string            `json:"type"`
+	Headers map[string]string `json:"headers"`
+}
+
+// AckBatchResult reports which assertions were added by AckBatch and which
+// were already present in the system assertion database.
+type AckBatchResult struct {
+	Added   []AssertionRef `json:"added"`
+	Present []AssertionRef `json:"present"`
+}
+
+// AckBatch adds the stream of assertions to the system assertion database,
+// as Ack does, and reports which assertions were added and which ones were
+// already present. The assertions can be in any order, as long as their
+// prerequisites are either part of the stream or already in the database.
+func (client *Client) AckBatch(b []byte) (*AckBatchResult, error) {
+	var res AckBatchResult
+	q := url.Values{"batch": []string{"true"}}
+	if _, err := client.doSync("POST", "/v2/assertions", q, nil, bytes.NewReader(b), &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
 // AssertionTypes returns a list of assertion type names.
 func (client *Client) AssertionTypes() ([]string, error) {
 	var types struct {
@@ -69,6 +96,12 @@ type KnownOptions struct {
 
 // Known queries assertions with type assertTypeName and matching assertion headers.
 func (client *Client) Known(assertTypeName string, headers map[string]string, opts *KnownOptions) ([]asserts.Assertion, error) {
+	return client.KnownWithContext(context.Background(), assertTypeName, headers, opts)
+}
+
+// KnownWithContext is like Known, cancelling ctx aborts the query, which
+// can be slow when the store is involved.
+func (client *Client) KnownWithContext(ctx context.Context, assertTypeName string, headers map[string]string, opts *KnownOptions) ([]asserts.Assertion, error) {
 	if opts == nil {
 		opts = &KnownOptions{}
 	}
@@ -85,7 +118,7 @@ func (client *Client) Known(assertTypeName string, headers map[string]string, op
 		q.Set("remote", "true")
 	}
 
-	response, cancel, err := client.rawWithTimeout(context.Background(), "GET", path, q, nil, nil, nil)
+	response, cancel, err := client.rawWithTimeout(ctx, "GET", path, q, nil, nil, nil)
 	if err != nil {
 		fmt := "failed to query assertions: %w"
 		return nil, xerrors.Errorf(fmt, err)
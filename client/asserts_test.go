@@ -48,6 +48,29 @@ func (cs *clientSuite) TestClientAssert(c *C) {
 	c.Check(cs.req.URL.Path, Equals, "/v2/assertions")
 }
 
+func (cs *clientSuite) TestClientAckBatch(c *C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"added": [{"type": "account", "headers": {"account-id": "developer1"}}],
+			"present": [{"type": "account-key", "headers": {"public-key-sha3-384": "key-id"}}]
+		}
+	}`
+	a := []byte("Assertion.")
+	res, err := cs.cli.AckBatch(a)
+	c.Assert(err, IsNil)
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, IsNil)
+	c.Check(body, DeepEquals, a)
+	c.Check(cs.req.Method, Equals, "POST")
+	c.Check(cs.req.URL.Path, Equals, "/v2/assertions")
+	c.Check(cs.req.URL.RawQuery, Equals, "batch=true")
+	c.Check(res, DeepEquals, &client.AckBatchResult{
+		Added:   []client.AssertionRef{{Type: "account", Headers: map[string]string{"account-id": "developer1"}}},
+		Present: []client.AssertionRef{{Type: "account-key", Headers: map[string]string{"public-key-sha3-384": "key-id"}}},
+	})
+}
+
 func (cs *clientSuite) TestClientAssertsTypes(c *C) {
 	cs.rsp = `{
     "result": {
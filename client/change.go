@@ -20,10 +20,13 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -80,8 +83,13 @@ type changeAndData struct {
 
 // Change fetches information about a Change given its ID.
 func (client *Client) Change(id string) (*Change, error) {
+	return client.ChangeWithContext(context.Background(), id)
+}
+
+// ChangeWithContext is like Change, but can be cancelled with ctx.
+func (client *Client) ChangeWithContext(ctx context.Context, id string) (*Change, error) {
 	var chgd changeAndData
-	_, err := client.doSync("GET", "/v2/changes/"+id, nil, nil, nil, &chgd)
+	_, err := client.doSyncWithContext(ctx, "GET", "/v2/changes/"+id, nil, nil, nil, &chgd)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +100,11 @@ func (client *Client) Change(id string) (*Change, error) {
 
 // Abort attempts to abort a change that is in not yet ready.
 func (client *Client) Abort(id string) (*Change, error) {
+	return client.AbortWithContext(context.Background(), id)
+}
+
+// AbortWithContext is like Abort, but can be cancelled with ctx.
+func (client *Client) AbortWithContext(ctx context.Context, id string) (*Change, error) {
 	var postData struct {
 		Action string `json:"action"`
 	}
@@ -103,13 +116,116 @@ func (client *Client) Abort(id string) (*Change, error) {
 	}
 
 	var chg Change
-	if _, err := client.doSync("POST", "/v2/changes/"+id, nil, nil, &body, &chg); err != nil {
+	if _, err := client.doSyncWithContext(ctx, "POST", "/v2/changes/"+id, nil, nil, &body, &chg); err != nil {
 		return nil, err
 	}
 
 	return &chg, nil
 }
 
+// Kinds of ChangeUpdate.
+const (
+	ChangeUpdateChangeStatus = "change-status"
+	ChangeUpdateTaskStatus   = "task-status"
+	ChangeUpdateTaskProgress = "task-progress"
+	ChangeUpdateHeartbeat    = "heartbeat"
+)
+
+// A ChangeUpdate reports a status transition of a change or of one of its
+// tasks, or the progress of a task, as streamed by snapd.
+type ChangeUpdate struct {
+	// Seq is the sequence number of the status transitions reported so
+	// far, it can be used to resume the stream after disconnecting.
+	Seq      int       `json:"seq"`
+	Kind     string    `json:"kind"`
+	Time     time.Time `json:"time"`
+	ChangeID string    `json:"change-id"`
+	TaskID   string    `json:"task-id,omitempty"`
+
+	Status   string        `json:"status,omitempty"`
+	Ready    bool          `json:"ready,omitempty"`
+	Err      string        `json:"err,omitempty"`
+	Progress *TaskProgress `json:"progress,omitempty"`
+}
+
+// ChangeUpdates follows the change with the given ID, returning a channel
+// on which its updates are delivered as they happen. Unless after is
+// greater than zero, the first updates report the current status of the
+// change and of its tasks, otherwise the stream resumes after the update
+// with the given sequence number. The channel is closed after the change
+// is ready, or when the stream is interrupted.
+func (client *Client) ChangeUpdates(ctx context.Context, id string, after int) (<-chan ChangeUpdate, error) {
+	query := url.Values{}
+	if after > 0 {
+		query.Set("after", strconv.Itoa(after))
+	}
+
+	rsp, err := client.raw(ctx, "GET", "/v2/changes/"+id+"/events", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if rsp.StatusCode != 200 {
+		var r response
+		defer rsp.Body.Close()
+		if err := decodeInto(rsp.Body, &r); err != nil {
+			return nil, err
+		}
+		return nil, r.err(client, rsp.StatusCode)
+	}
+
+	ch := make(chan ChangeUpdate, 20)
+	go func() {
+		defer rsp.Body.Close()
+		defer close(ch)
+		// updates come in application/json-seq, see Logs
+		scanner := bufio.NewScanner(rsp.Body)
+		for scanner.Scan() {
+			buf := scanner.Bytes()
+			idx := bytes.IndexByte(buf, 0x1E)
+			if idx < 0 {
+				continue
+			}
+			var update ChangeUpdate
+			if err := json.Unmarshal(buf[idx+1:], &update); err != nil {
+				continue
+			}
+			select {
+			case ch <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// how often AbortChange checks whether the aborted change is ready
+var abortChangePollInterval = 100 * time.Millisecond
+
+// AbortChange aborts the change with the given ID and waits for it to be
+// ready, that is for its tasks to be undone, returning the change in its
+// final status. Cancelling ctx stops waiting.
+func (client *Client) AbortChange(ctx context.Context, id string) (*Change, error) {
+	chg, err := client.AbortWithContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for !chg.Ready {
+		select {
+		case <-time.After(abortChangePollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		chg, err = client.ChangeWithContext(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return chg, nil
+}
+
 type ChangeSelector uint8
 
 func (c ChangeSelector) String() string {
@@ -137,6 +253,11 @@ type ChangesOptions struct {
 }
 
 func (client *Client) Changes(opts *ChangesOptions) ([]*Change, error) {
+	return client.ChangesWithContext(context.Background(), opts)
+}
+
+// ChangesWithContext is like Changes, but can be cancelled with ctx.
+func (client *Client) ChangesWithContext(ctx context.Context, opts *ChangesOptions) ([]*Change, error) {
 	query := url.Values{}
 	if opts != nil {
 		if opts.Selector != 0 {
@@ -148,7 +269,7 @@ func (client *Client) Changes(opts *ChangesOptions) ([]*Change, error) {
 	}
 
 	var chgds []changeAndData
-	_, err := client.doSync("GET", "/v2/changes", query, nil, nil, &chgds)
+	_, err := client.doSyncWithContext(ctx, "GET", "/v2/changes", query, nil, nil, &chgds)
 	if err != nil {
 		return nil, err
 	}
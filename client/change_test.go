@@ -20,6 +20,8 @@
 package client_test
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"time"
 
@@ -232,3 +234,84 @@ func (cs *clientSuite) TestClientAbort(c *check.C) {
 
 	c.Assert(string(body), check.Equals, "{\"action\":\"abort\"}\n")
 }
+
+func (cs *clientSuite) TestClientChangeUpdates(c *check.C) {
+	cs.rsp = "\x1e" + `{"seq": 3, "kind": "task-status", "time": "2016-04-21T01:02:03Z", "change-id": "uno", "task-id": "1", "status": "Doing"}
+junk
+` + "\x1e" + `{"seq": 3, "kind": "task-progress", "time": "2016-04-21T01:02:03Z", "change-id": "uno", "task-id": "1", "progress": {"label": "foo", "done": 1, "total": 2}}
+` + "\x1e" + `{"seq": 4, "kind": "change-status", "time": "2016-04-21T01:02:04Z", "change-id": "uno", "status": "Error", "ready": true, "err": "boom"}
+`
+
+	ch, err := cs.cli.ChangeUpdates(context.Background(), "uno", 2)
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/changes/uno/events")
+	c.Check(cs.req.URL.Query().Get("after"), check.Equals, "2")
+
+	var updates []client.ChangeUpdate
+	for update := range ch {
+		updates = append(updates, update)
+	}
+	t0 := time.Date(2016, 04, 21, 1, 2, 3, 0, time.UTC)
+	c.Check(updates, check.DeepEquals, []client.ChangeUpdate{
+		{Seq: 3, Kind: client.ChangeUpdateTaskStatus, Time: t0, ChangeID: "uno", TaskID: "1", Status: "Doing"},
+		{Seq: 3, Kind: client.ChangeUpdateTaskProgress, Time: t0, ChangeID: "uno", TaskID: "1", Progress: &client.TaskProgress{Label: "foo", Done: 1, Total: 2}},
+		{Seq: 4, Kind: client.ChangeUpdateChangeStatus, Time: t0.Add(time.Second), ChangeID: "uno", Status: "Error", Ready: true, Err: "boom"},
+	})
+}
+
+func (cs *clientSuite) TestClientChangeUpdatesError(c *check.C) {
+	cs.status = 404
+	cs.rsp = `{"type": "error", "status-code": 404, "result": {"message": "cannot find change with id \"uno\""}}`
+
+	_, err := cs.cli.ChangeUpdates(context.Background(), "uno", 0)
+	c.Assert(err, check.ErrorMatches, `cannot find change with id "uno"`)
+	c.Check(cs.req.URL.Query().Get("after"), check.Equals, "")
+}
+
+func (cs *clientSuite) TestClientAbortChange(c *check.C) {
+	restore := client.MockAbortChangePollInterval(time.Millisecond)
+	defer restore()
+
+	cs.rsps = []string{
+		`{"type": "sync", "result": {"id": "uno", "status": "Abort", "ready": false}}`,
+		`{"type": "sync", "result": {"id": "uno", "status": "Undoing", "ready": false}}`,
+		`{"type": "sync", "result": {"id": "uno", "status": "Undone", "ready": true, "err": "cannot do it:\n- foo (change aborted)"}}`,
+	}
+
+	chg, err := cs.cli.AbortChange(context.Background(), "uno")
+	c.Assert(err, check.IsNil)
+	c.Check(chg.Status, check.Equals, "Undone")
+	c.Check(chg.Ready, check.Equals, true)
+	c.Assert(cs.reqs, check.HasLen, 3)
+	c.Check(cs.reqs[0].Method, check.Equals, "POST")
+	c.Check(cs.reqs[0].URL.Path, check.Equals, "/v2/changes/uno")
+	for _, req := range cs.reqs[1:] {
+		c.Check(req.Method, check.Equals, "GET")
+		c.Check(req.URL.Path, check.Equals, "/v2/changes/uno")
+	}
+}
+
+func (cs *clientSuite) TestClientAbortChangeCancelled(c *check.C) {
+	restore := client.MockAbortChangePollInterval(time.Hour)
+	defer restore()
+
+	cs.rsp = `{"type": "sync", "result": {"id": "uno", "status": "Abort", "ready": false}}`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := cs.cli.AbortChange(ctx, "uno")
+	c.Assert(err, check.Equals, context.DeadlineExceeded)
+	c.Check(cs.doCalls, check.Equals, 1)
+}
+
+func (cs *clientSuite) TestClientChangeWithContextStopsRetrying(c *check.C) {
+	cs.err = errors.New("ouchie")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := cs.cli.ChangeWithContext(ctx, "uno")
+	c.Assert(err, check.ErrorMatches, "cannot communicate with server: request canceled")
+	c.Check(errors.Is(err, context.Canceled), check.Equals, true)
+	c.Check(cs.doCalls, check.Equals, 1)
+}
@@ -375,13 +375,14 @@ var doNoTimeoutAndRetry = &doOptions{
 // do performs a request and decodes the resulting json into the given
 // value. It's low-level, for testing/experimenting only; you should
 // usually use a higher level interface that builds on this.
-func (client *Client) do(method, path string, query url.Values, headers map[string]string, body io.Reader, v interface{}, opts *doOptions) (statusCode int, err error) {
+//
+// Cancelling the given context aborts the request, and stops retrying it.
+func (client *Client) do(ctx context.Context, method, path string, query url.Values, headers map[string]string, body io.Reader, v interface{}, opts *doOptions) (statusCode int, err error) {
 	opts = ensureDoOpts(opts)
 
 	client.checkMaintenanceJSON()
 
 	var rsp *http.Response
-	ctx := context.Background()
 	if opts.Timeout <= 0 {
 		// no timeout and retries
 		rsp, err = client.raw(ctx, method, path, query, headers, body)
@@ -406,10 +407,17 @@ func (client *Client) do(method, path string, query url.Values, headers map[stri
 			if err == nil || shouldNotRetryError(err) || method != "GET" {
 				break
 			}
+			if ctx.Err() != nil {
+				// cancelled by the caller
+				err = ConnectionError{ctx.Err()}
+				break
+			}
 			select {
 			case <-retry.C:
 				continue
 			case <-timeout.C:
+			case <-ctx.Done():
+				err = ConnectionError{ctx.Err()}
 			}
 			break
 		}
@@ -451,7 +459,13 @@ func decodeInto(reader io.Reader, v interface{}) error {
 // response payload into the given value using the "UseNumber" json decoding
 // which produces json.Numbers instead of float64 types for numbers.
 func (client *Client) doSync(method, path string, query url.Values, headers map[string]string, body io.Reader, v interface{}) (*ResultInfo, error) {
-	return client.doSyncWithOpts(method, path, query, headers, body, v, nil)
+	return client.doSyncWithOpts(context.Background(), method, path, query, headers, body, v, nil)
+}
+
+// doSyncWithContext is like doSync, but the request is aborted if the given context
+// is cancelled.
+func (client *Client) doSyncWithContext(ctx context.Context, method, path string, query url.Values, headers map[string]string, body io.Reader, v interface{}) (*ResultInfo, error) {
+	return client.doSyncWithOpts(ctx, method, path, query, headers, body, v, nil)
 }
 
 // checkMaintenanceJSON checks if there is a maintenance.json file written by
@@ -494,7 +508,7 @@ func (client *Client) checkMaintenanceJSON() {
 	}
 }
 
-func (client *Client) doSyncWithOpts(method, path string, query url.Values, headers map[string]string, body io.Reader, v interface{}, opts *doOptions) (*ResultInfo, error) {
+func (client *Client) doSyncWithOpts(ctx context.Context, method, path string, query url.Values, headers map[string]string, body io.Reader, v interface{}, opts *doOptions) (*ResultInfo, error) {
 	// first check maintenance.json to see if snapd is down for a restart, and
 	// set cli.maintenance as appropriate, then perform the request
 	// TODO: it would be a nice thing to skip the request if we know that snapd
@@ -504,7 +518,7 @@ func (client *Client) doSyncWithOpts(method, path string, query url.Values, head
 	client.checkMaintenanceJSON()
 
 	var rsp response
-	statusCode, err := client.do(method, path, query, headers, body, &rsp, opts)
+	statusCode, err := client.do(ctx, method, path, query, headers, body, &rsp, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -528,13 +542,20 @@ func (client *Client) doSyncWithOpts(method, path string, query url.Values, head
 }
 
 func (client *Client) doAsync(method, path string, query url.Values, headers map[string]string, body io.Reader) (changeID string, err error) {
-	_, changeID, err = client.doAsyncFull(method, path, query, headers, body, nil)
+	_, changeID, err = client.doAsyncFull(context.Background(), method, path, query, headers, body, nil)
+	return
+}
+
+// doAsyncWithContext is like doAsync, but the request is aborted if the given
+// context is cancelled.
+func (client *Client) doAsyncWithContext(ctx context.Context, method, path string, query url.Values, headers map[string]string, body io.Reader) (changeID string, err error) {
+	_, changeID, err = client.doAsyncFull(ctx, method, path, query, headers, body, nil)
 	return
 }
 
-func (client *Client) doAsyncFull(method, path string, query url.Values, headers map[string]string, body io.Reader, opts *doOptions) (result json.RawMessage, changeID string, err error) {
+func (client *Client) doAsyncFull(ctx context.Context, method, path string, query url.Values, headers map[string]string, body io.Reader, opts *doOptions) (result json.RawMessage, changeID string, err error) {
 	var rsp response
-	statusCode, err := client.do(method, path, query, headers, body, &rsp, opts)
+	statusCode, err := client.do(ctx, method, path, query, headers, body, &rsp, opts)
 	if err != nil {
 		return nil, "", err
 	}
@@ -739,7 +760,7 @@ func (client *Client) SysInfo() (*SysInfo, error) {
 		Timeout: 25 * time.Second,
 		Retry:   doRetry,
 	}
-	if _, err := client.doSyncWithOpts("GET", "/v2/system-info", nil, nil, nil, &sysInfo, opts); err != nil {
+	if _, err := client.doSyncWithOpts(context.Background(), "GET", "/v2/system-info", nil, nil, nil, &sysInfo, opts); err != nil {
 		return nil, fmt.Errorf("cannot obtain system details: %v", err)
 	}
 
@@ -785,6 +806,56 @@ func (client *Client) SystemRecoveryKeys(result interface{}) error {
 	return err
 }
 
+// TPMLockoutStatus is the state of the dictionary attack protection of the
+// TPM.
+type TPMLockoutStatus struct {
+	InLockout      bool   `json:"in-lockout"`
+	FailedTries    uint32 `json:"failed-tries"`
+	MaxTries       uint32 `json:"max-tries"`
+	RemainingTries uint32 `json:"remaining-tries"`
+	// LockoutInterval and LockoutRecovery are in seconds.
+	LockoutInterval uint64 `json:"lockout-interval"`
+	LockoutRecovery uint64 `json:"lockout-recovery"`
+	// LastResets are the last attempts at resetting the lockout, oldest
+	// first.
+	LastResets []TPMLockoutReset `json:"last-resets,omitempty"`
+}
+
+// TPMLockoutReset is an attempt at resetting the TPM lockout.
+type TPMLockoutReset struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"`
+}
+
+type SystemSecbootResponse struct {
+	TPMLockout *TPMLockoutStatus `json:"tpm-lockout,omitempty"`
+}
+
+// SystemSecboot returns the state of the secure boot and full disk
+// encryption support of the device.
+func (client *Client) SystemSecboot() (*SystemSecbootResponse, error) {
+	var rsp SystemSecbootResponse
+	if _, err := client.doSync("GET", "/v2/system-secboot", nil, nil, nil, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+// ResetTPMLockout resets the dictionary attack lockout of the TPM, using the
+// lockout authorization stored when the device was installed.
+func (client *Client) ResetTPMLockout() error {
+	body, err := json.Marshal(struct {
+		Action string `json:"action"`
+	}{
+		Action: "reset-tpm-lockout",
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.doSync("POST", "/v2/system-secboot", nil, nil, bytes.NewReader(body), nil)
+	return err
+}
+
 func (c *Client) MigrateSnapHome(snaps []string) (changeID string, err error) {
 	body, err := json.Marshal(struct {
 		Action string   `json:"action"`
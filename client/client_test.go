@@ -681,6 +681,41 @@ func (cs *clientSuite) TestClientSystemRecoveryKeys(c *C) {
 	c.Check(key.RecoveryKey, Equals, "42")
 }
 
+func (cs *clientSuite) TestClientSystemSecboot(c *C) {
+	cs.rsp = `{"type":"sync", "result":{"tpm-lockout":{"in-lockout":false,"failed-tries":2,"max-tries":32,"remaining-tries":30,"lockout-interval":7200,"lockout-recovery":86400,"last-resets":[{"time":"2026-10-01T12:00:00Z","error":"boom"}]}}}`
+
+	rsp, err := cs.cli.SystemSecboot()
+	c.Assert(err, IsNil)
+	c.Check(cs.reqs, HasLen, 1)
+	c.Check(cs.reqs[0].Method, Equals, "GET")
+	c.Check(cs.reqs[0].URL.Path, Equals, "/v2/system-secboot")
+	c.Check(rsp, DeepEquals, &client.SystemSecbootResponse{
+		TPMLockout: &client.TPMLockoutStatus{
+			FailedTries:     2,
+			MaxTries:        32,
+			RemainingTries:  30,
+			LockoutInterval: 7200,
+			LockoutRecovery: 86400,
+			LastResets: []client.TPMLockoutReset{
+				{Time: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC), Error: "boom"},
+			},
+		},
+	})
+}
+
+func (cs *clientSuite) TestClientResetTPMLockout(c *C) {
+	cs.rsp = `{"type":"sync", "result":null}`
+
+	err := cs.cli.ResetTPMLockout()
+	c.Assert(err, IsNil)
+	c.Assert(cs.reqs, HasLen, 1)
+	c.Check(cs.reqs[0].Method, Equals, "POST")
+	c.Check(cs.reqs[0].URL.Path, Equals, "/v2/system-secboot")
+	data, err := io.ReadAll(cs.reqs[0].Body)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `{"action":"reset-tpm-lockout"}`)
+}
+
 func (cs *clientSuite) TestClientDebugEnvVar(c *check.C) {
 	buf, restore := logger.MockLogger()
 	defer restore()
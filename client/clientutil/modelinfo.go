@@ -394,24 +394,26 @@ func PrintSerialAssertionYAML(w *tabwriter.Writer, serialAssertion asserts.Seria
 // PrintModelAssertionJSON will format the provided serial or model assertion based on the parameters given in
 // JSON format. The output will be written to the provided io.Writer.
 func PrintModelAssertionJSON(w *tabwriter.Writer, modelAssertion asserts.Model, serialAssertion *asserts.Serial, opts PrintModelAssertionOptions) error {
-	serializeJSON := func(v interface{}) error {
-		marshalled, err := json.MarshalIndent(v, "", "  ")
-		if err != nil {
-			return err
-		}
+	marshalled, err := json.MarshalIndent(ModelAssertionJSONData(modelAssertion, serialAssertion, opts), "", "  ")
+	if err != nil {
+		return err
+	}
 
-		_, err = w.Write(marshalled)
-		if err != nil {
-			return err
-		}
-		return w.Flush()
+	_, err = w.Write(marshalled)
+	if err != nil {
+		return err
 	}
+	return w.Flush()
+}
 
+// ModelAssertionJSONData returns the data PrintModelAssertionJSON outputs
+// in JSON format for the provided serial or model assertion.
+func ModelAssertionJSONData(modelAssertion asserts.Model, serialAssertion *asserts.Serial, opts PrintModelAssertionOptions) interface{} {
 	if opts.Assertion {
 		modelJSON := ModelAssertJSON{}
 		modelJSON.Headers = modelAssertion.Headers()
 		modelJSON.Body = string(modelAssertion.Body())
-		return serializeJSON(modelJSON)
+		return modelJSON
 	}
 
 	modelData := make(map[string]interface{})
@@ -444,5 +446,5 @@ func PrintModelAssertionJSON(w *tabwriter.Writer, modelAssertion asserts.Model,
 		modelData[headerName] = headerValue
 	}
 
-	return serializeJSON(modelData)
+	return modelData
 }
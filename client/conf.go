@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // SetConf requests a snap to apply the provided patch to the configuration.
@@ -50,3 +51,43 @@ func (client *Client) Conf(snapName string, keys []string) (configuration map[st
 
 	return configuration, nil
 }
+
+// ConfHistoryChange describes the change of a single configuration
+// option.
+type ConfHistoryChange struct {
+	Key string `json:"key"`
+	// Old and New hold the JSON encoding of the values before and after
+	// the change, empty when the option was unset. Large values are
+	// truncated and end with "...".
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+	// Redacted is set when the values were not recorded because the
+	// option holds sensitive data.
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// ConfHistoryEntry holds the configuration changes of a snap made at once
+// by the same origin, for example "api", "hook" or "gadget-defaults".
+type ConfHistoryEntry struct {
+	Time    time.Time            `json:"time"`
+	Origin  string               `json:"origin"`
+	Changes []*ConfHistoryChange `json:"changes"`
+}
+
+// ConfHistory asks for the recent history of the configuration changes
+// of a snap, oldest first. If keys are given, only the changes affecting
+// those options are returned.
+func (client *Client) ConfHistory(snapName string, keys []string) (history []*ConfHistoryEntry, err error) {
+	query := url.Values{}
+	query.Set("history", "true")
+	if len(keys) > 0 {
+		query.Set("keys", strings.Join(keys, ","))
+	}
+
+	_, err = client.doSync("GET", "/v2/snaps/"+snapName+"/conf", query, nil, nil, &history)
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
@@ -21,8 +21,12 @@ package client_test
 
 import (
 	"encoding/json"
+	"net/url"
+	"time"
 
 	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
 )
 
 func (cs *clientSuite) TestClientSetConfCallsEndpoint(c *check.C) {
@@ -103,3 +107,31 @@ func (cs *clientSuite) TestClientGetConfMultipleKeys(c *check.C) {
 		"test-key2": "test-value2",
 	})
 }
+
+func (cs *clientSuite) TestClientConfHistory(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [
+			{"time": "2023-05-01T10:00:00Z", "origin": "api", "changes": [{"key": "foo", "old": "\"bar\"", "new": "\"baz\""}]},
+			{"time": "2023-05-01T11:00:00Z", "origin": "hook", "changes": [{"key": "password", "redacted": true}]}
+		]
+	}`
+	history, err := cs.cli.ConfHistory("snap-name", []string{"foo", "password"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/snap-name/conf")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"history": []string{"true"},
+		"keys":    []string{"foo,password"},
+	})
+	c.Check(history, check.DeepEquals, []*client.ConfHistoryEntry{{
+		Time:    time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC),
+		Origin:  "api",
+		Changes: []*client.ConfHistoryChange{{Key: "foo", Old: `"bar"`, New: `"baz"`}},
+	}, {
+		Time:    time.Date(2023, 5, 1, 11, 0, 0, 0, time.UTC),
+		Origin:  "hook",
+		Changes: []*client.ConfHistoryChange{{Key: "password", Redacted: true}},
+	}})
+}
@@ -20,7 +20,11 @@
 package client
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"net/url"
+	"time"
 )
 
 // Connection describes a connection between a plug and a slot.
@@ -47,6 +51,28 @@ type Connections struct {
 	Undesired []Connection `json:"undesired"`
 	Plugs     []Plug       `json:"plugs"`
 	Slots     []Slot       `json:"slots"`
+	// Stale is the list of connections that cannot be restored as their
+	// plug or slot, or their interface, is gone after a refresh. They are
+	// kept in case of revert.
+	Stale []Connection `json:"stale,omitempty"`
+	// Unsatisfied is the list of plugs that are not connected, only
+	// returned when selecting unsatisfied plugs.
+	Unsatisfied []UnsatisfiedPlug `json:"unsatisfied,omitempty"`
+}
+
+// UnsatisfiedPlug describes a plug that is neither connected nor was
+// explicitly disconnected.
+type UnsatisfiedPlug struct {
+	Plug      PlugRef `json:"plug"`
+	Interface string  `json:"interface"`
+	// Reason tells why the plug was not connected: "no-candidate-slot",
+	// "policy-denied" or "gadget-pending".
+	Reason string `json:"reason"`
+	// Attempts is the number of times the auto-connection of the plug
+	// was retried.
+	Attempts int `json:"attempts,omitempty"`
+	// NextRetry is the time of the next retry, if any.
+	NextRetry time.Time `json:"next-retry,omitempty"`
 }
 
 // ConnectionOptions contains criteria for selecting matching connections, plugs
@@ -60,11 +86,19 @@ type ConnectionOptions struct {
 	// All when true, selects established and undesired connections as well
 	// as all disconnected plugs and slots.
 	All bool
+	// Unsatisfied when true, selects only the plugs that are not
+	// connected, with the reason why they are not.
+	Unsatisfied bool
 }
 
 // Connections returns matching plugs, slots and their connections. Unless
 // specified by matching options, returns established connections.
 func (client *Client) Connections(opts *ConnectionOptions) (Connections, error) {
+	return client.ConnectionsWithContext(context.Background(), opts)
+}
+
+// ConnectionsWithContext is like Connections, but can be cancelled with ctx.
+func (client *Client) ConnectionsWithContext(ctx context.Context, opts *ConnectionOptions) (Connections, error) {
 	var conns Connections
 	query := url.Values{}
 	if opts != nil && opts.Snap != "" {
@@ -76,6 +110,55 @@ func (client *Client) Connections(opts *ConnectionOptions) (Connections, error)
 	if opts != nil && opts.All {
 		query.Set("select", "all")
 	}
-	_, err := client.doSync("GET", "/v2/connections", query, nil, nil, &conns)
+	if opts != nil && opts.Unsatisfied {
+		query.Set("select", "unsatisfied")
+	}
+	_, err := client.doSyncWithContext(ctx, "GET", "/v2/connections", query, nil, nil, &conns)
 	return conns, err
 }
+
+// ConnectionOverride describes a connection remembered as either manually
+// disconnected ("undesired") or requested by the gadget ("by-gadget").
+type ConnectionOverride struct {
+	Plug PlugRef `json:"plug"`
+	Slot SlotRef `json:"slot"`
+	// Interface is the interface of the connection. It must be given when
+	// adding an undesired override for a connection never established.
+	Interface string `json:"interface,omitempty"`
+	// Kind is either "undesired" or "by-gadget".
+	Kind string `json:"kind"`
+}
+
+type connectionOverrideAction struct {
+	Action string `json:"action"`
+	*ConnectionOverride
+}
+
+// ConnectionOverrides returns the connection overrides remembered by snapd.
+func (client *Client) ConnectionOverrides() ([]ConnectionOverride, error) {
+	var overrides []ConnectionOverride
+	_, err := client.doSync("GET", "/v2/connections/overrides", nil, nil, nil, &overrides)
+	return overrides, err
+}
+
+func (client *Client) changeConnectionOverride(action string, override *ConnectionOverride) error {
+	var body bytes.Buffer
+	op := connectionOverrideAction{Action: action, ConnectionOverride: override}
+	if err := json.NewEncoder(&body).Encode(op); err != nil {
+		return err
+	}
+	_, err := client.doSync("POST", "/v2/connections/overrides", nil, nil, &body, nil)
+	return err
+}
+
+// AddConnectionOverride records the given connection override.
+func (client *Client) AddConnectionOverride(override *ConnectionOverride) error {
+	return client.changeConnectionOverride("add", override)
+}
+
+// RemoveConnectionOverride removes the given connection override. Removing
+// an undesired override lets the connection be established automatically
+// again.
+func (client *Client) RemoveConnectionOverride(override *ConnectionOverride) error {
+	return client.changeConnectionOverride("remove", override)
+}
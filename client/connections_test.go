@@ -20,7 +20,11 @@
 package client_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/url"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -33,6 +37,17 @@ func (cs *clientSuite) TestClientConnectionsCallsEndpoint(c *check.C) {
 	c.Check(cs.req.URL.Path, check.Equals, "/v2/connections")
 }
 
+func (cs *clientSuite) TestClientConnectionsWithContextStopsRetrying(c *check.C) {
+	cs.err = errors.New("ouchie")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := cs.cli.ConnectionsWithContext(ctx, nil)
+	c.Assert(err, check.ErrorMatches, "cannot communicate with server: request canceled")
+	c.Check(errors.Is(err, context.Canceled), check.Equals, true)
+	c.Check(cs.doCalls, check.Equals, 1)
+}
+
 func (cs *clientSuite) TestClientConnectionsDefault(c *check.C) {
 	cs.rsp = `{
 		"type": "sync",
@@ -268,3 +283,129 @@ func (cs *clientSuite) TestClientConnectionsFilter(c *check.C) {
 		"snap":      []string{"foo"},
 	})
 }
+
+func (cs *clientSuite) TestClientConnectionsUnsatisfied(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"established": [],
+			"plugs": [],
+			"slots": [],
+			"unsatisfied": [
+				{
+					"plug": {"snap": "foo", "plug": "data"},
+					"interface": "content",
+					"reason": "no-candidate-slot",
+					"attempts": 2,
+					"next-retry": "2023-04-01T12:00:00Z"
+				},
+				{
+					"plug": {"snap": "foo", "plug": "removable-media"},
+					"interface": "removable-media",
+					"reason": "policy-denied"
+				}
+			]
+		}
+	}`
+
+	conns, err := cs.cli.Connections(&client.ConnectionOptions{Unsatisfied: true, Snap: "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/connections")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"select": []string{"unsatisfied"},
+		"snap":   []string{"foo"},
+	})
+	c.Check(conns.Unsatisfied, check.DeepEquals, []client.UnsatisfiedPlug{{
+		Plug:      client.PlugRef{Snap: "foo", Name: "data"},
+		Interface: "content",
+		Reason:    "no-candidate-slot",
+		Attempts:  2,
+		NextRetry: time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC),
+	}, {
+		Plug:      client.PlugRef{Snap: "foo", Name: "removable-media"},
+		Interface: "removable-media",
+		Reason:    "policy-denied",
+	}})
+}
+
+func (cs *clientSuite) TestClientConnectionsStale(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"established": [],
+			"plugs": [],
+			"slots": [],
+			"stale": [
+				{
+					"slot": {"snap": "bar", "slot": "data"},
+					"plug": {"snap": "foo", "plug": "data"},
+					"interface": "content",
+					"manual": true
+				}
+			]
+		}
+	}`
+
+	conns, err := cs.cli.Connections(nil)
+	c.Assert(err, check.IsNil)
+	c.Check(conns.Stale, check.DeepEquals, []client.Connection{{
+		Slot:      client.SlotRef{Snap: "bar", Name: "data"},
+		Plug:      client.PlugRef{Snap: "foo", Name: "data"},
+		Interface: "content",
+		Manual:    true,
+	}})
+}
+
+func (cs *clientSuite) TestClientConnectionOverrides(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [
+			{
+				"plug": {"snap": "foo", "plug": "network"},
+				"slot": {"snap": "core", "slot": "network"},
+				"interface": "network",
+				"kind": "undesired"
+			}
+		]
+	}`
+
+	overrides, err := cs.cli.ConnectionOverrides()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/connections/overrides")
+	c.Check(overrides, check.DeepEquals, []client.ConnectionOverride{{
+		Plug:      client.PlugRef{Snap: "foo", Name: "network"},
+		Slot:      client.SlotRef{Snap: "core", Name: "network"},
+		Interface: "network",
+		Kind:      "undesired",
+	}})
+}
+
+func (cs *clientSuite) TestClientAddRemoveConnectionOverride(c *check.C) {
+	override := &client.ConnectionOverride{
+		Plug: client.PlugRef{Snap: "foo", Name: "network"},
+		Slot: client.SlotRef{Snap: "core", Name: "network"},
+		Kind: "by-gadget",
+	}
+	for _, t := range []struct {
+		action string
+		f      func(*client.ConnectionOverride) error
+	}{
+		{"add", cs.cli.AddConnectionOverride},
+		{"remove", cs.cli.RemoveConnectionOverride},
+	} {
+		cs.rsp = `{"type": "sync", "result": null}`
+		err := t.f(override)
+		c.Assert(err, check.IsNil)
+		c.Check(cs.req.Method, check.Equals, "POST")
+		c.Check(cs.req.URL.Path, check.Equals, "/v2/connections/overrides")
+		var body map[string]interface{}
+		c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+		c.Check(body, check.DeepEquals, map[string]interface{}{
+			"action": t.action,
+			"plug":   map[string]interface{}{"snap": "foo", "plug": "network"},
+			"slot":   map[string]interface{}{"snap": "core", "slot": "network"},
+			"kind":   "by-gadget",
+		})
+	}
+}
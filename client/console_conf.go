@@ -19,7 +19,10 @@
 
 package client
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // InternalConsoleConfStartResponse is the response from console-conf start
 // support
@@ -39,6 +42,6 @@ func (client *Client) InternalConsoleConfStart() ([]string, []string, error) {
 		Timeout: 2 * time.Second,
 		Retry:   1 * time.Hour,
 	}
-	_, err := client.doSyncWithOpts("POST", "/v2/internal/console-conf-start", nil, nil, nil, resp, opts)
+	_, err := client.doSyncWithOpts(context.Background(), "POST", "/v2/internal/console-conf-start", nil, nil, nil, resp, opts)
 	return resp.ActiveAutoRefreshChanges, resp.ActiveAutoRefreshSnaps, err
 }
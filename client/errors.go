@@ -97,11 +97,17 @@ const (
 	// specified architecture. Value has the same format as for
 	// `snap-channel-not-available`.
 	ErrorKindSnapArchitectureNotAvailable ErrorKind = "snap-architecture-not-available"
+	// ErrorKindSnapRevisionUnknown: the requested revision is not
+	// one of the revisions of the installed snap.
+	ErrorKindSnapRevisionUnknown ErrorKind = "snap-revision-unknown"
+	// ErrorKindSnapRevisionNotRetained: the requested revision is
+	// known but its snap file is not retained locally anymore.
+	ErrorKindSnapRevisionNotRetained ErrorKind = "snap-revision-not-retained"
 
 	// ErrorKindSnapChangeConflict: the requested operation would
 	// conflict with currently ongoing change. This is a temporary
 	// error. The error `value` is an object with optional fields
-	// `snap-name`, `change-kind` of the ongoing change.
+	// `snap-name`, `change-kind` and `change-id` of the ongoing change.
 	ErrorKindSnapChangeConflict ErrorKind = "snap-change-conflict"
 
 	// ErrorKindQuotaChangeConflict: the requested operation would
@@ -124,6 +130,11 @@ const (
 	// ErrorKindConfigNoSuchOption: the given configuration option
 	// does not exist.
 	ErrorKindConfigNoSuchOption ErrorKind = "option-not-found"
+	// ErrorKindConfigSchemaViolation: the requested configuration
+	// does not match the configuration schema of the snap. The
+	// `value` of the error is a list of the violations, each with
+	// the `key` of the offending option and a `message`.
+	ErrorKindConfigSchemaViolation ErrorKind = "option-schema-violation"
 
 	// ErrorKindAssertionNotFound: assertion can not be found.
 	ErrorKindAssertionNotFound ErrorKind = "assertion-not-found"
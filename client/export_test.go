@@ -20,9 +20,11 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/url"
+	"time"
 )
 
 // SetDoer sets the client's doer to the given one
@@ -34,7 +36,7 @@ type DoOptions = doOptions
 
 // Do does do.
 func (client *Client) Do(method, path string, query url.Values, body io.Reader, v interface{}, opts *DoOptions) (statusCode int, err error) {
-	return client.do(method, path, query, nil, body, v, opts)
+	return client.do(context.Background(), method, path, query, nil, body, v, opts)
 }
 
 // expose parseError for testing
@@ -61,3 +63,11 @@ func MockStdinReadLimit(new int64) (restore func()) {
 		stdinReadLimit = oldStdinReadLimit
 	}
 }
+
+func MockAbortChangePollInterval(d time.Duration) (restore func()) {
+	old := abortChangePollInterval
+	abortChangePollInterval = d
+	return func() {
+		abortChangePollInterval = old
+	}
+}
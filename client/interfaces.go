@@ -21,6 +21,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/url"
 	"strings"
@@ -92,6 +93,11 @@ type DisconnectOptions struct {
 }
 
 func (client *Client) Interfaces(opts *InterfaceOptions) ([]*Interface, error) {
+	return client.InterfacesWithContext(context.Background(), opts)
+}
+
+// InterfacesWithContext is like Interfaces, but can be cancelled with ctx.
+func (client *Client) InterfacesWithContext(ctx context.Context, opts *InterfaceOptions) ([]*Interface, error) {
 	query := url.Values{}
 	if opts != nil && len(opts.Names) > 0 {
 		query.Set("names", strings.Join(opts.Names, ",")) // Return just those specific interfaces.
@@ -114,7 +120,7 @@ func (client *Client) Interfaces(opts *InterfaceOptions) ([]*Interface, error) {
 		query.Set("select", "all") // Return all interfaces.
 	}
 	var interfaces []*Interface
-	_, err := client.doSync("GET", "/v2/interfaces", query, nil, nil, &interfaces)
+	_, err := client.doSyncWithContext(ctx, "GET", "/v2/interfaces", query, nil, nil, &interfaces)
 
 	return interfaces, err
 }
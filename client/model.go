@@ -56,7 +56,8 @@ func (client *Client) Remodel(b []byte) (changeID string, err error) {
 }
 
 // RemodelOffline tries to remodel the system with the given model assertion
-// and local snaps and assertion files.
+// and local snaps and assertion files. The store is not contacted, all the
+// snaps needed by the new model must be either installed already or provided.
 func (client *Client) RemodelOffline(
 	model []byte, snapPaths, assertPaths []string) (changeID string, err error) {
 
@@ -78,7 +79,7 @@ func (client *Client) RemodelOffline(
 		"Content-Type": mw.FormDataContentType(),
 	}
 
-	_, changeID, err = client.doAsyncFull("POST", "/v2/model", nil, headers, pr, doNoTimeoutAndRetry)
+	_, changeID, err = client.doAsyncFull(context.Background(), "POST", "/v2/model", nil, headers, pr, doNoTimeoutAndRetry)
 	return changeID, err
 }
 
@@ -125,6 +126,11 @@ func sendRemodelFiles(model []byte, paths []string, files, assertFiles []*os.Fil
 		return
 	}
 
+	if err := mw.WriteField("offline", "true"); err != nil {
+		pw.CloseWithError(err)
+		return
+	}
+
 	for _, file := range assertFiles {
 		if err := sendPartFromFile(file,
 			func() (io.Writer, error) {
@@ -189,6 +195,26 @@ func (client *Client) CurrentSerialAssertion() (*asserts.Serial, error) {
 	return serialAssert, nil
 }
 
+type serialActionData struct {
+	Action string `json:"action"`
+}
+
+// RenewSerial asks for the device to get a new serial assertion for a new
+// device key, replacing the current ones once they are usable.
+func (client *Client) RenewSerial() (changeID string, err error) {
+	data, err := json.Marshal(&serialActionData{
+		Action: "renew",
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal serial action data: %v", err)
+	}
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	return client.doAsync("POST", "/v2/model/serial", nil, headers, bytes.NewReader(data))
+}
+
 // helper function for getting assertions from the daemon via a REST path
 func currentAssertion(client *Client, path string) (asserts.Assertion, error) {
 	q := url.Values{}
@@ -134,6 +134,26 @@ func (cs *clientSuite) TestClientRemodel(c *C) {
 	c.Check(jsonBody["new-model"], Equals, string(remodelJsonData))
 }
 
+func (cs *clientSuite) TestClientRenewSerial(c *C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"result": {},
+		"change": "d729"
+	}`
+	id, err := cs.cli.RenewSerial()
+	c.Assert(err, IsNil)
+	c.Check(id, Equals, "d729")
+	c.Check(cs.req.Method, Equals, "POST")
+	c.Check(cs.req.URL.Path, Equals, "/v2/model/serial")
+	c.Assert(cs.req.Header.Get("Content-Type"), Equals, "application/json")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, IsNil)
+	c.Check(string(body), Equals, `{"action":"renew"}`)
+}
+
 func (cs *clientSuite) TestClientGetModelHappy(c *C) {
 	cs.status = 200
 	cs.rsp = happyModelAssertionResponse
@@ -216,6 +236,10 @@ Content-Type: application/x.ubuntu.assertion
 
 some-model
 ` + boundary + `
+Content-Disposition: form-data; name="offline"
+
+true
+` + boundary + `
 Content-Disposition: form-data; name="assertion"
 Content-Type: application/x.ubuntu.assertion
 
@@ -20,6 +20,7 @@
 package client
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
@@ -89,6 +90,15 @@ type Snap struct {
 	Hold *time.Time `json:"hold,omitempty"`
 	// GatingHold is the time until which the snap's refreshes are held by a snap.
 	GatingHold *time.Time `json:"gating-hold,omitempty"`
+	// RefreshInhibit is set when a refresh of the snap is inhibited by its
+	// running apps.
+	RefreshInhibit *SnapRefreshInhibit `json:"refresh-inhibit,omitempty"`
+}
+
+type SnapRefreshInhibit struct {
+	// ProceedTime is the time after which the refresh proceeds even if
+	// apps of the snap are still running.
+	ProceedTime time.Time `json:"proceed-time"`
 }
 
 type SnapHealth struct {
@@ -97,6 +107,8 @@ type SnapHealth struct {
 	Status    string        `json:"status"`
 	Message   string        `json:"message,omitempty"`
 	Code      string        `json:"code,omitempty"`
+	// LastTransition is when the snap started reporting Status.
+	LastTransition time.Time `json:"last-transition,omitempty"`
 }
 
 // Statuses and types a snap may have.
@@ -155,6 +167,12 @@ type Category struct {
 // List returns the list of all snaps installed on the system
 // with names in the given list; if the list is empty, all snaps.
 func (client *Client) List(names []string, opts *ListOptions) ([]*Snap, error) {
+	return client.ListWithContext(context.Background(), names, opts)
+}
+
+// ListWithContext is like List, the request is aborted when ctx is
+// cancelled.
+func (client *Client) ListWithContext(ctx context.Context, names []string, opts *ListOptions) ([]*Snap, error) {
 	if opts == nil {
 		opts = &ListOptions{}
 	}
@@ -167,7 +185,7 @@ func (client *Client) List(names []string, opts *ListOptions) ([]*Snap, error) {
 		q.Add("snaps", strings.Join(names, ","))
 	}
 
-	snaps, _, err := client.snapsFromPath("/v2/snaps", q)
+	snaps, _, err := client.snapsFromPath(ctx, "/v2/snaps", q)
 	if err != nil {
 		return nil, err
 	}
@@ -182,8 +200,13 @@ func (client *Client) List(names []string, opts *ListOptions) ([]*Snap, error) {
 // Sections returns the list of existing snap sections in the store
 // This is deprecated, use Categories() instead.
 func (client *Client) Sections() ([]string, error) {
+	return client.SectionsWithContext(context.Background())
+}
+
+// SectionsWithContext is like Sections, but can be cancelled with ctx.
+func (client *Client) SectionsWithContext(ctx context.Context) ([]string, error) {
 	var sections []string
-	_, err := client.doSync("GET", "/v2/sections", nil, nil, nil, &sections)
+	_, err := client.doSyncWithContext(ctx, "GET", "/v2/sections", nil, nil, nil, &sections)
 	if err != nil {
 		fmt := "cannot get snap sections: %w"
 		return nil, xerrors.Errorf(fmt, err)
@@ -193,17 +216,59 @@ func (client *Client) Sections() ([]string, error) {
 
 // Categories returns the list of existing snap categories in the store
 func (client *Client) Categories() ([]*Category, error) {
+	return client.CategoriesWithContext(context.Background())
+}
+
+// CategoriesWithContext is like Categories, but can be cancelled with ctx.
+func (client *Client) CategoriesWithContext(ctx context.Context) ([]*Category, error) {
 	var categories []*Category
-	_, err := client.doSync("GET", "/v2/categories", nil, nil, nil, &categories)
+	_, err := client.doSyncWithContext(ctx, "GET", "/v2/categories", nil, nil, nil, &categories)
 	if err != nil {
 		return nil, fmt.Errorf("cannot get snap categories: %w", err)
 	}
 	return categories, nil
 }
 
+// RefreshCandidate describes a snap that would be refreshed by the next
+// auto-refresh.
+type RefreshCandidate struct {
+	Name         string        `json:"name"`
+	Current      snap.Revision `json:"current"`
+	Revision     snap.Revision `json:"revision"`
+	Channel      string        `json:"channel,omitempty"`
+	DownloadSize int64         `json:"download-size,omitempty"`
+	// Deferred is why the next auto-refresh will not refresh the snap,
+	// if it will not.
+	Deferred string `json:"deferred,omitempty"`
+}
+
+// RefreshCandidates returns the snaps that would be refreshed by the next
+// auto-refresh, with validation sets and refresh holds taken into account.
+func (client *Client) RefreshCandidates() ([]*RefreshCandidate, error) {
+	return client.RefreshCandidatesWithContext(context.Background())
+}
+
+// RefreshCandidatesWithContext is like RefreshCandidates, but can be
+// cancelled with ctx.
+func (client *Client) RefreshCandidatesWithContext(ctx context.Context) ([]*RefreshCandidate, error) {
+	q := url.Values{"select": []string{"refresh-candidates"}}
+	var candidates []*RefreshCandidate
+	_, err := client.doSyncWithContext(ctx, "GET", "/v2/snaps", q, nil, nil, &candidates)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get refresh candidates: %w", err)
+	}
+	return candidates, nil
+}
+
 // Find returns a list of snaps available for install from the
 // store for this system and that match the query
 func (client *Client) Find(opts *FindOptions) ([]*Snap, *ResultInfo, error) {
+	return client.FindWithContext(context.Background(), opts)
+}
+
+// FindWithContext is like Find, cancelling ctx aborts the request, e.g.
+// when the store is slow to answer.
+func (client *Client) FindWithContext(ctx context.Context, opts *FindOptions) ([]*Snap, *ResultInfo, error) {
 	if opts == nil {
 		opts = &FindOptions{}
 	}
@@ -238,14 +303,19 @@ func (client *Client) Find(opts *FindOptions) ([]*Snap, *ResultInfo, error) {
 		q.Set("scope", opts.Scope)
 	}
 
-	return client.snapsFromPath("/v2/find", q)
+	return client.snapsFromPath(ctx, "/v2/find", q)
 }
 
 func (client *Client) FindOne(name string) (*Snap, *ResultInfo, error) {
+	return client.FindOneWithContext(context.Background(), name)
+}
+
+// FindOneWithContext is like FindOne, but can be cancelled with ctx.
+func (client *Client) FindOneWithContext(ctx context.Context, name string) (*Snap, *ResultInfo, error) {
 	q := url.Values{}
 	q.Set("name", name)
 
-	snaps, ri, err := client.snapsFromPath("/v2/find", q)
+	snaps, ri, err := client.snapsFromPath(ctx, "/v2/find", q)
 	if err != nil {
 		fmt := "cannot find snap %q: %w"
 		return nil, nil, xerrors.Errorf(fmt, name, err)
@@ -258,9 +328,9 @@ func (client *Client) FindOne(name string) (*Snap, *ResultInfo, error) {
 	return snaps[0], ri, nil
 }
 
-func (client *Client) snapsFromPath(path string, query url.Values) ([]*Snap, *ResultInfo, error) {
+func (client *Client) snapsFromPath(ctx context.Context, path string, query url.Values) ([]*Snap, *ResultInfo, error) {
 	var snaps []*Snap
-	ri, err := client.doSync("GET", path, query, nil, nil, &snaps)
+	ri, err := client.doSyncWithContext(ctx, "GET", path, query, nil, nil, &snaps)
 	if e, ok := err.(*Error); ok {
 		return nil, nil, e
 	}
@@ -274,9 +344,14 @@ func (client *Client) snapsFromPath(path string, query url.Values) ([]*Snap, *Re
 // Snap returns the most recently published revision of the snap with the
 // provided name.
 func (client *Client) Snap(name string) (*Snap, *ResultInfo, error) {
+	return client.SnapWithContext(context.Background(), name)
+}
+
+// SnapWithContext is like Snap, but can be cancelled with ctx.
+func (client *Client) SnapWithContext(ctx context.Context, name string) (*Snap, *ResultInfo, error) {
 	var snap *Snap
 	path := fmt.Sprintf("/v2/snaps/%s", name)
-	ri, err := client.doSync("GET", path, nil, nil, nil, &snap)
+	ri, err := client.doSyncWithContext(ctx, "GET", path, nil, nil, nil, &snap)
 	if err != nil {
 		fmt := "cannot retrieve snap %q: %w"
 		return nil, nil, xerrors.Errorf(fmt, name, err)
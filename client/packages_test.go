@@ -443,3 +443,43 @@ func (cs *clientSuite) TestClientFindFromPathErrIsWrapped(c *check.C) {
 	_, err = cs.cli.List([]string{"snap"}, nil)
 	c.Assert(xerrors.As(err, &e), check.Equals, true)
 }
+
+func (cs *clientSuite) TestClientRefreshCandidates(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [{
+			"name": "some-snap",
+			"current": "1",
+			"revision": "2",
+			"channel": "latest/stable",
+			"download-size": 1024
+		}, {
+			"name": "other-snap",
+			"current": "x1",
+			"revision": "5"
+		}]
+	}`
+	candidates, err := cs.cli.RefreshCandidates()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{"select": []string{"refresh-candidates"}})
+	c.Check(candidates, check.DeepEquals, []*client.RefreshCandidate{{
+		Name:         "some-snap",
+		Current:      snap.R(1),
+		Revision:     snap.R(2),
+		Channel:      "latest/stable",
+		DownloadSize: 1024,
+	}, {
+		Name:     "other-snap",
+		Current:  snap.R("x1"),
+		Revision: snap.R(5),
+	}})
+}
+
+func (cs *clientSuite) TestClientRefreshCandidatesErrIsWrapped(c *check.C) {
+	cs.err = errors.New("boom")
+	_, err := cs.cli.RefreshCandidates()
+	var e xerrors.Wrapper
+	c.Assert(err, check.Implements, &e)
+}
@@ -0,0 +1,101 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// EssentialSnapTiming is how long seeding one of the essential snaps took.
+type EssentialSnapTiming struct {
+	Snap     string        `json:"snap"`
+	Duration time.Duration `json:"duration"`
+}
+
+// DiskHealth is the health of the storage media of a physical disk.
+type DiskHealth struct {
+	// Device is the kernel device node of the disk.
+	Device string `json:"device"`
+	// Media is the kind of media, "emmc" or "nvme", empty when unknown.
+	Media string `json:"media,omitempty"`
+	// Status is one of "ok", "warning", "critical" or "unavailable".
+	Status string `json:"status"`
+	// Reason explains why the health information is unavailable.
+	Reason string `json:"reason,omitempty"`
+}
+
+// SeedingInfo holds the details of how the system was seeded, and
+// preseeded if it was. Times which were not recorded are zero.
+type SeedingInfo struct {
+	Seeded bool `json:"seeded,omitempty"`
+	// Preseeded is true if the image was preseeded with snap-preseed.
+	Preseeded bool `json:"preseeded,omitempty"`
+
+	PreseedStartTime time.Time `json:"preseed-start-time,omitempty"`
+	PreseedTime      time.Time `json:"preseed-time,omitempty"`
+	// SeedStartTime is when seeding started, for images which were
+	// not preseeded.
+	SeedStartTime time.Time `json:"seed-start-time,omitempty"`
+	// SeedRestartTime is when seeding was resumed on first boot of a
+	// preseeded image.
+	SeedRestartTime time.Time `json:"seed-restart-time,omitempty"`
+	SeedTime        time.Time `json:"seed-time,omitempty"`
+
+	// PreseedDuration is how long preseeding took.
+	PreseedDuration time.Duration `json:"-"`
+	// SeedDuration is how long seeding took on the device, only
+	// counting from first boot for preseeded images.
+	SeedDuration time.Duration `json:"-"`
+
+	// EssentialSnapTimings are how long seeding each of the essential
+	// snaps took, in seeding order, once seeding finished.
+	EssentialSnapTimings []EssentialSnapTiming `json:"essential-snap-timings,omitempty"`
+
+	// SeedError is the error of the oldest failed seed change if
+	// seeding did not succeed yet.
+	SeedError string `json:"seed-error,omitempty"`
+
+	// DiskHealth is the health of the storage media of the physical
+	// disks, when it could be obtained.
+	DiskHealth []DiskHealth `json:"disk-health,omitempty"`
+}
+
+func durationBetween(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// DebugSeeding returns the details of the seeding of the system.
+func (client *Client) DebugSeeding() (*SeedingInfo, error) {
+	var info SeedingInfo
+	if err := client.DebugGet("seeding", &info, nil); err != nil {
+		return nil, err
+	}
+
+	info.PreseedDuration = durationBetween(info.PreseedStartTime, info.PreseedTime)
+	seedStart := info.SeedStartTime
+	if info.Preseeded {
+		seedStart = info.SeedRestartTime
+	}
+	info.SeedDuration = durationBetween(seedStart, info.SeedTime)
+	return &info, nil
+}
@@ -0,0 +1,98 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"net/url"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientDebugSeedingPreseeded(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {
+			"seeded": true,
+			"preseeded": true,
+			"preseed-start-time": "2023-01-01T10:00:00Z",
+			"preseed-time": "2023-01-01T10:00:05Z",
+			"seed-restart-time": "2023-01-01T11:00:00Z",
+			"seed-time": "2023-01-01T11:00:30Z",
+			"preseed-system-key": {"build-id": "abcde"},
+			"essential-snap-timings": [
+				{"snap": "snapd", "duration": 2000000000},
+				{"snap": "pc-kernel", "duration": 10000000000}
+			]
+		}
+	}`
+
+	info, err := cs.cli.DebugSeeding()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/debug")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"aspect": []string{"seeding"},
+	})
+	c.Check(info, check.DeepEquals, &client.SeedingInfo{
+		Seeded:           true,
+		Preseeded:        true,
+		PreseedStartTime: time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC),
+		PreseedTime:      time.Date(2023, 1, 1, 10, 0, 5, 0, time.UTC),
+		SeedRestartTime:  time.Date(2023, 1, 1, 11, 0, 0, 0, time.UTC),
+		SeedTime:         time.Date(2023, 1, 1, 11, 0, 30, 0, time.UTC),
+		PreseedDuration:  5 * time.Second,
+		SeedDuration:     30 * time.Second,
+		EssentialSnapTimings: []client.EssentialSnapTiming{
+			{Snap: "snapd", Duration: 2 * time.Second},
+			{Snap: "pc-kernel", Duration: 10 * time.Second},
+		},
+	})
+}
+
+func (cs *clientSuite) TestClientDebugSeedingError(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {
+			"seed-start-time": "2023-01-01T10:00:00Z",
+			"seed-error": "cannot perform the following tasks:\n- Mount snap \"core\"",
+			"disk-health": [
+				{"device": "/dev/mmcblk0", "media": "emmc", "status": "critical", "emmc": {"pre-eol": 3}},
+				{"device": "/dev/sda", "status": "unavailable", "reason": "unsupported media"}
+			]
+		}
+	}`
+
+	info, err := cs.cli.DebugSeeding()
+	c.Assert(err, check.IsNil)
+	c.Check(info.Seeded, check.Equals, false)
+	c.Check(info.SeedStartTime, check.Equals, time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC))
+	c.Check(info.SeedDuration, check.Equals, time.Duration(0))
+	c.Check(info.PreseedDuration, check.Equals, time.Duration(0))
+	c.Check(info.SeedError, check.Equals, "cannot perform the following tasks:\n- Mount snap \"core\"")
+	c.Check(info.DiskHealth, check.DeepEquals, []client.DiskHealth{
+		{Device: "/dev/mmcblk0", Media: "emmc", Status: "critical"},
+		{Device: "/dev/sda", Status: "unavailable", Reason: "unsupported media"},
+	})
+}
@@ -271,7 +271,7 @@ func (client *Client) doMultiSnapActionFull(actionName string, snaps []string, o
 		"Content-Type": "application/json",
 	}
 
-	return client.doAsyncFull("POST", "/v2/snaps", nil, headers, bytes.NewBuffer(data), nil)
+	return client.doAsyncFull(context.Background(), "POST", "/v2/snaps", nil, headers, bytes.NewBuffer(data), nil)
 }
 
 // InstallPath sideloads the snap with the given path under optional provided name,
@@ -325,7 +325,7 @@ func (client *Client) sendLocalSnaps(paths []string, files []*os.File, action ac
 		"Content-Type": mw.FormDataContentType(),
 	}
 
-	_, changeID, err := client.doAsyncFull("POST", "/v2/snaps", nil, headers, pr, doNoTimeoutAndRetry)
+	_, changeID, err := client.doAsyncFull(context.Background(), "POST", "/v2/snaps", nil, headers, pr, doNoTimeoutAndRetry)
 	return changeID, err
 }
 
@@ -447,6 +447,12 @@ type DownloadOptions struct {
 
 // Download will stream the given snap to the client
 func (client *Client) Download(name string, options *DownloadOptions) (dlInfo *DownloadInfo, r io.ReadCloser, err error) {
+	return client.DownloadWithContext(context.Background(), name, options)
+}
+
+// DownloadWithContext is like Download, cancelling ctx aborts the
+// download, including while reading the returned stream.
+func (client *Client) DownloadWithContext(ctx context.Context, name string, options *DownloadOptions) (dlInfo *DownloadInfo, r io.ReadCloser, err error) {
 	if options == nil {
 		options = &DownloadOptions{}
 	}
@@ -471,8 +477,7 @@ func (client *Client) Download(name string, options *DownloadOptions) (dlInfo *D
 		headers["range"] = fmt.Sprintf("bytes: %d-", options.Resume)
 	}
 
-	// no deadline for downloads
-	ctx := context.Background()
+	// no deadline for downloads besides the one of ctx
 	rsp, err := client.raw(ctx, "POST", "/v2/download", nil, headers, bytes.NewBuffer(data))
 	if err != nil {
 		return nil, nil, err
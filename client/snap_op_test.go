@@ -20,6 +20,7 @@
 package client_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -28,6 +29,7 @@ import (
 	"mime"
 	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 
@@ -797,6 +799,46 @@ func (cs *clientSuite) TestClientOpDownload(c *check.C) {
 	c.Check(rc.Close(), check.IsNil)
 }
 
+// newChunkedServer returns a server which sends the first chunk of its
+// response and then blocks until the request is cancelled by the client.
+func newChunkedServer(c *check.C, header http.Header, chunk string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(200)
+		_, err := io.WriteString(w, chunk)
+		c.Check(err, check.IsNil)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+}
+
+func (cs *clientSuite) TestClientOpDownloadWithContextCancelledMidBody(c *check.C) {
+	srv := newChunkedServer(c, http.Header{
+		"Content-Disposition": {"attachment; filename=foo_2.snap"},
+	}, "some-foo-data")
+	defer srv.Close()
+	cli := client.New(&client.Config{BaseURL: srv.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dlInfo, rc, err := cli.DownloadWithContext(ctx, "foo", nil)
+	c.Assert(err, check.IsNil)
+	defer rc.Close()
+	c.Check(dlInfo.SuggestedFileName, check.Equals, "foo_2.snap")
+
+	buf := make([]byte, len("some-foo-data"))
+	_, err = io.ReadFull(rc, buf)
+	c.Assert(err, check.IsNil)
+	c.Check(string(buf), check.Equals, "some-foo-data")
+
+	// cancelling aborts the download in the middle of the body
+	cancel()
+	_, err = ioutil.ReadAll(rc)
+	c.Check(err, check.ErrorMatches, "context canceled")
+}
+
 func (cs *clientSuite) TestClientOpDownloadResume(c *check.C) {
 	cs.status = 200
 	cs.header = http.Header{
@@ -50,6 +50,8 @@ type snapshotAction struct {
 	Action string   `json:"action"`
 	Snaps  []string `json:"snaps,omitempty"`
 	Users  []string `json:"users,omitempty"`
+
+	RestoreOptions *snap.SnapshotRestoreOptions `json:"restore-options,omitempty"`
 }
 
 // A Snapshot is a collection of archives with a simple metadata json file
@@ -133,6 +135,18 @@ func (ss SnapshotSet) Time() time.Time {
 	return mint
 }
 
+// SnapshotsUsage holds the disk space used by the snapshots on the system.
+type SnapshotsUsage struct {
+	// Total is the size of all the snapshots
+	Total int64 `json:"total"`
+	// Automatic is the size of the snapshots created automatically
+	// on snap removal
+	Automatic int64 `json:"automatic"`
+	// AutomaticMaxSize is the limit on the size of the automatic
+	// snapshots, if set
+	AutomaticMaxSize int64 `json:"automatic-max-size,omitempty"`
+}
+
 // Size returns the sum of the set's sizes.
 func (ss SnapshotSet) Size() int64 {
 	var sum int64
@@ -182,6 +196,17 @@ func (client *Client) SnapshotSets(setID uint64, snapNames []string) ([]Snapshot
 	return snapshotSets, err
 }
 
+// SnapshotsUsage returns the disk space used by the snapshots in the system.
+func (client *Client) SnapshotsUsage() (*SnapshotsUsage, error) {
+	q := url.Values{"usage": []string{"true"}}
+
+	var usage SnapshotsUsage
+	if _, err := client.doSync("GET", "/v2/snapshots", q, nil, nil, &usage); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
 // ForgetSnapshots permanently removes the snapshot set, limited to the
 // given snaps (if non-empty).
 func (client *Client) ForgetSnapshots(setID uint64, snaps []string) (changeID string, err error) {
@@ -210,11 +235,19 @@ func (client *Client) CheckSnapshots(setID uint64, snaps []string, users []strin
 // If snaps or users are non-empty, limit to checking only those
 // archives of the snapshot.
 func (client *Client) RestoreSnapshots(setID uint64, snaps []string, users []string) (changeID string, err error) {
+	return client.RestoreSnapshotsWithOptions(setID, snaps, users, nil)
+}
+
+// RestoreSnapshotsWithOptions extracts the given snapshot set like
+// RestoreSnapshots, with opts selecting which of the archived data is
+// restored.
+func (client *Client) RestoreSnapshotsWithOptions(setID uint64, snaps []string, users []string, opts *snap.SnapshotRestoreOptions) (changeID string, err error) {
 	return client.snapshotAction(&snapshotAction{
-		SetID:  setID,
-		Action: "restore",
-		Snaps:  snaps,
-		Users:  users,
+		SetID:          setID,
+		Action:         "restore",
+		Snaps:          snaps,
+		Users:          users,
+		RestoreOptions: opts,
 	})
 }
 
@@ -235,7 +268,13 @@ func (client *Client) snapshotAction(action *snapshotAction) (changeID string, e
 //
 // The return value includes the length of the returned stream.
 func (client *Client) SnapshotExport(setID uint64) (stream io.ReadCloser, contentLength int64, err error) {
-	rsp, err := client.raw(context.Background(), "GET", fmt.Sprintf("/v2/snapshots/%v/export", setID), nil, nil, nil)
+	return client.SnapshotExportWithContext(context.Background(), setID)
+}
+
+// SnapshotExportWithContext is like SnapshotExport, cancelling ctx aborts
+// the export, including while reading the returned stream.
+func (client *Client) SnapshotExportWithContext(ctx context.Context, setID uint64) (stream io.ReadCloser, contentLength int64, err error) {
+	rsp, err := client.raw(ctx, "GET", fmt.Sprintf("/v2/snapshots/%v/export", setID), nil, nil, nil)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -20,7 +20,9 @@
 package client_test
 
 import (
+	"context"
 	"crypto/sha256"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -98,6 +100,25 @@ func (cs *clientSuite) TestClientSnapshotSets(c *check.C) {
 	})
 }
 
+func (cs *clientSuite) TestClientSnapshotsUsage(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {"total": 300, "automatic": 100, "automatic-max-size": 200}
+}`
+	usage, err := cs.cli.SnapshotsUsage()
+	c.Assert(err, check.IsNil)
+	c.Check(usage, check.DeepEquals, &client.SnapshotsUsage{
+		Total:            300,
+		Automatic:        100,
+		AutomaticMaxSize: 200,
+	})
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snapshots")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"usage": []string{"true"},
+	})
+}
+
 func (cs *clientSuite) testClientSnapshotActionFull(c *check.C, action string, users []string, f func() (string, error)) {
 	cs.status = 202
 	cs.rsp = `{
@@ -143,6 +164,26 @@ func (cs *clientSuite) TestClientRestoreSnapshots(c *check.C) {
 	cs.testClientSnapshotAction(c, "restore", cs.cli.RestoreSnapshots)
 }
 
+func (cs *clientSuite) TestClientRestoreSnapshotsWithOptions(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"status-code": 202,
+		"type": "async",
+		"change": "1too3"
+	}`
+	opts := &snap.SnapshotRestoreOptions{SkipSystem: true, ForceOwnership: true}
+	id, err := cs.cli.RestoreSnapshotsWithOptions(42, []string{"asnap"}, []string{"auser"}, opts)
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "1too3")
+
+	act, err := client.UnmarshalSnapshotAction(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(act.Action, check.Equals, "restore")
+	c.Check(act.Snaps, check.DeepEquals, []string{"asnap"})
+	c.Check(act.Users, check.DeepEquals, []string{"auser"})
+	c.Check(act.RestoreOptions, check.DeepEquals, opts)
+}
+
 func (cs *clientSuite) TestClientExportSnapshotSpecificErr(c *check.C) {
 	content := `{"type":"error","status-code":400,"result":{"message":"boom","kind":"err-kind","value":"err-value"}}`
 	cs.contentLength = int64(len(content))
@@ -153,6 +194,29 @@ func (cs *clientSuite) TestClientExportSnapshotSpecificErr(c *check.C) {
 	c.Check(err, check.ErrorMatches, "boom")
 }
 
+func (cs *clientSuite) TestClientExportSnapshotWithContextCancelledMidBody(c *check.C) {
+	srv := newChunkedServer(c, http.Header{
+		"Content-Type": {client.SnapshotExportMediaType},
+	}, "some-export-data")
+	defer srv.Close()
+	cli := client.New(&client.Config{BaseURL: srv.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, _, err := cli.SnapshotExportWithContext(ctx, 42)
+	c.Assert(err, check.IsNil)
+	defer r.Close()
+
+	buf := make([]byte, len("some-export-data"))
+	_, err = io.ReadFull(r, buf)
+	c.Assert(err, check.IsNil)
+	c.Check(string(buf), check.Equals, "some-export-data")
+
+	cancel()
+	_, err = ioutil.ReadAll(r)
+	c.Check(err, check.ErrorMatches, "context canceled")
+}
+
 func (cs *clientSuite) TestClientExportSnapshot(c *check.C) {
 	type tableT struct {
 		content     string
@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"golang.org/x/xerrors"
 
@@ -55,6 +56,29 @@ type System struct {
 	Actions []SystemAction `json:"actions,omitempty"`
 }
 
+// PendingFactoryReset describes a factory reset which was requested but has
+// not happened yet.
+type PendingFactoryReset struct {
+	// Label of the recovery system the device is reset from
+	Label string `json:"label"`
+	// Time when the factory reset was requested
+	Time time.Time `json:"time"`
+}
+
+// SystemSnap describes a snap included in a recovery system.
+type SystemSnap struct {
+	Name     string        `json:"name"`
+	Revision snap.Revision `json:"revision"`
+	Channel  string        `json:"channel,omitempty"`
+	// Asserted is true if the snap is backed by assertions in the seed
+	Asserted bool `json:"asserted,omitempty"`
+	// Size of the snap file
+	Size int64 `json:"size"`
+	// Shared is true if the snap file is also used by other recovery
+	// systems
+	Shared bool `json:"shared,omitempty"`
+}
+
 type SystemAction struct {
 	// Title is a user presentable action description
 	Title string `json:"title,omitempty"`
@@ -196,6 +220,16 @@ func (client *Client) SystemDetails(systemLabel string) (*SystemDetails, error)
 	return &rsp, nil
 }
 
+// SystemSnaps returns the snaps included in the given recovery system.
+func (client *Client) SystemSnaps(systemLabel string) ([]SystemSnap, error) {
+	var rsp []SystemSnap
+
+	if _, err := client.doSync("GET", "/v2/systems/"+systemLabel+"/snaps", nil, nil, nil, &rsp); err != nil {
+		return nil, xerrors.Errorf("cannot get snaps of system %q: %v", systemLabel, err)
+	}
+	return rsp, nil
+}
+
 type InstallStep string
 
 const (
@@ -246,3 +280,54 @@ func (client *Client) InstallSystem(systemLabel string, opts *InstallSystemOptio
 	}
 	return chgID, nil
 }
+
+type CreateSystemOptions struct {
+	// Label is the label of the new recovery system, when empty a label
+	// based on the current date is picked.
+	Label string `json:"label,omitempty"`
+
+	// MarkDefault is set when the new recovery system should become the
+	// default one.
+	MarkDefault bool `json:"mark-default,omitempty"`
+
+	// TestSystem is set when the device should reboot into the new
+	// recovery system once to validate it.
+	TestSystem bool `json:"test-system,omitempty"`
+}
+
+// CreateSystem requests the creation of a new recovery system from the snaps
+// currently installed on the device.
+func (client *Client) CreateSystem(opts *CreateSystemOptions) (changeID string, err error) {
+	if opts == nil {
+		opts = &CreateSystemOptions{}
+	}
+
+	// verification is done by the backend
+	req := struct {
+		Action string `json:"action"`
+		*CreateSystemOptions
+	}{
+		Action:              "create",
+		CreateSystemOptions: opts,
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(&req); err != nil {
+		return "", err
+	}
+	chgID, err := client.doAsync("POST", "/v2/systems", nil, nil, &body)
+	if err != nil {
+		return "", xerrors.Errorf("cannot request recovery system creation: %v", err)
+	}
+	return chgID, nil
+}
+
+// Volumes returns how the partitions of the disks of the device map to the
+// structures of the gadget volumes.
+func (client *Client) Volumes() ([]gadget.DiskVolumeReport, error) {
+	var rsp []gadget.DiskVolumeReport
+	if _, err := client.doSync("GET", "/v2/volumes", nil, nil, nil, &rsp); err != nil {
+		return nil, xerrors.Errorf("cannot get volumes: %v", err)
+	}
+	return rsp, nil
+}
@@ -232,6 +232,36 @@ func (cs *clientSuite) TestSystemDetailsNone(c *check.C) {
 	c.Check(cs.req.URL.Path, check.Equals, "/v2/systems/20190102")
 }
 
+func (cs *clientSuite) TestSystemSnapsHappy(c *check.C) {
+	cs.rsp = `{
+	    "type": "sync",
+	    "status-code": 200,
+	    "result": [
+	        {"name": "pc-kernel", "revision": "12", "channel": "20", "asserted": true, "size": 1000, "shared": true},
+	        {"name": "local", "revision": "x1", "size": 100}
+	    ]
+	}`
+	snaps, err := cs.cli.SystemSnaps("20190102")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/systems/20190102/snaps")
+	c.Check(snaps, check.DeepEquals, []client.SystemSnap{
+		{Name: "pc-kernel", Revision: snap.R(12), Channel: "20", Asserted: true, Size: 1000, Shared: true},
+		{Name: "local", Revision: snap.R(-1), Size: 100},
+	})
+}
+
+func (cs *clientSuite) TestSystemSnapsError(c *check.C) {
+	cs.status = 404
+	cs.rsp = `{
+	    "type": "error",
+	    "status-code": 404,
+	    "result": {"message": "requested seed system \"20190102\" does not exist"}
+	}`
+	_, err := cs.cli.SystemSnaps("20190102")
+	c.Assert(err, check.ErrorMatches, `cannot get snaps of system "20190102": requested seed system "20190102" does not exist`)
+}
+
 func (cs *clientSuite) TestSystemDetailsHappy(c *check.C) {
 	cs.rsp = `{
 	    "type": "sync",
@@ -414,3 +444,138 @@ func (cs *clientSuite) TestRequestSystemInstallHappy(c *check.C) {
 		},
 	})
 }
+
+func (cs *clientSuite) TestRequestSystemCreateHappy(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"change": "42"
+	}`
+	opts := &client.CreateSystemOptions{
+		Label:       "1234",
+		MarkDefault: true,
+		TestSystem:  true,
+	}
+	chgID, err := cs.cli.CreateSystem(opts)
+	c.Assert(err, check.IsNil)
+	c.Assert(chgID, check.Equals, "42")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/systems")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var req map[string]interface{}
+	err = json.Unmarshal(body, &req)
+	c.Assert(err, check.IsNil)
+	c.Assert(req, check.DeepEquals, map[string]interface{}{
+		"action":       "create",
+		"label":        "1234",
+		"mark-default": true,
+		"test-system":  true,
+	})
+}
+
+func (cs *clientSuite) TestRequestSystemCreateNoOptions(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"change": "42"
+	}`
+	chgID, err := cs.cli.CreateSystem(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(chgID, check.Equals, "42")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var req map[string]interface{}
+	err = json.Unmarshal(body, &req)
+	c.Assert(err, check.IsNil)
+	c.Assert(req, check.DeepEquals, map[string]interface{}{
+		"action": "create",
+	})
+}
+
+func (cs *clientSuite) TestRequestSystemCreateError(c *check.C) {
+	cs.rsp = `{
+	    "type": "error",
+	    "status-code": 400,
+	    "result": {"message": "cannot create recovery system: creating recovery systems is not supported on this system"}
+	}`
+	_, err := cs.cli.CreateSystem(nil)
+	c.Assert(err, check.ErrorMatches, `cannot request recovery system creation: cannot create recovery system: creating recovery systems is not supported on this system`)
+}
+
+func (cs *clientSuite) TestVolumes(c *check.C) {
+	cs.rsp = `{
+	    "type": "sync",
+	    "status-code": 200,
+	    "result": [
+	        {
+	            "name": "pc",
+	            "device": "/dev/vda",
+	            "schema": "gpt",
+	            "size": 1073741824,
+	            "structure": [
+	                {
+	                    "node": "/dev/vda1",
+	                    "partition-uuid": "C5A930DF-E86A-4BAE-A4C5-C861353796E6",
+	                    "partition-label": "ubuntu-seed",
+	                    "filesystem": "vfat",
+	                    "offset": 1048576,
+	                    "size": 1048576,
+	                    "structure-name": "ubuntu-seed",
+	                    "role": "system-seed"
+	                },
+	                {
+	                    "node": "/dev/vda2",
+	                    "offset": 2097152,
+	                    "size": 1048576,
+	                    "unmapped": true
+	                }
+	            ]
+	        }
+	    ]
+	}`
+	vols, err := cs.cli.Volumes()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/volumes")
+	c.Check(vols, check.DeepEquals, []gadget.DiskVolumeReport{
+		{
+			Name:   "pc",
+			Device: "/dev/vda",
+			Schema: "gpt",
+			Size:   1073741824,
+			Structure: []gadget.DiskStructureReport{
+				{
+					Node:           "/dev/vda1",
+					PartitionUUID:  "C5A930DF-E86A-4BAE-A4C5-C861353796E6",
+					PartitionLabel: "ubuntu-seed",
+					Filesystem:     "vfat",
+					Offset:         1048576,
+					Size:           1048576,
+					StructureName:  "ubuntu-seed",
+					Role:           "system-seed",
+				},
+				{
+					Node:     "/dev/vda2",
+					Offset:   2097152,
+					Size:     1048576,
+					Unmapped: true,
+				},
+			},
+		},
+	})
+}
+
+func (cs *clientSuite) TestVolumesError(c *check.C) {
+	cs.rsp = `{
+	    "type": "error",
+	    "status-code": 500,
+	    "result": {"message": "cannot report gadget volumes: boom"}
+	}`
+	_, err := cs.cli.Volumes()
+	c.Assert(err, check.ErrorMatches, `cannot get volumes: cannot report gadget volumes: boom`)
+}
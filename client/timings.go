@@ -0,0 +1,170 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Timing is a single measured section of a task, with the sections
+// measured inside of it nested under it.
+type Timing struct {
+	Label    string
+	Summary  string
+	Duration time.Duration
+	Nested   []*Timing
+}
+
+// TaskTimings holds the timings recorded for a single task of a change.
+type TaskTimings struct {
+	ID      string
+	Kind    string
+	Status  string
+	Summary string
+	Lane    int
+	// Snap is the instance name of the snap the task operated on, if
+	// the task recorded it.
+	Snap string
+
+	ReadyTime   time.Time
+	DoingTime   time.Duration
+	UndoingTime time.Duration
+
+	DoingTimings   []*Timing
+	UndoingTimings []*Timing
+}
+
+// ChangeTimings holds the timings of the tasks of a change, ordered by
+// task id.
+type ChangeTimings struct {
+	ChangeID string
+	Tasks    []*TaskTimings
+}
+
+type timingJSON struct {
+	Level    int           `json:"level"`
+	Label    string        `json:"label"`
+	Summary  string        `json:"summary"`
+	Duration time.Duration `json:"duration"`
+}
+
+type taskTimingsJSON struct {
+	Status         string        `json:"status"`
+	Kind           string        `json:"kind"`
+	Summary        string        `json:"summary"`
+	Lane           int           `json:"lane"`
+	Snap           string        `json:"snap"`
+	ReadyTime      time.Time     `json:"ready-time"`
+	DoingTime      time.Duration `json:"doing-time"`
+	UndoingTime    time.Duration `json:"undoing-time"`
+	DoingTimings   []*timingJSON `json:"doing-timings"`
+	UndoingTimings []*timingJSON `json:"undoing-timings"`
+}
+
+type changeTimingsJSON struct {
+	ChangeID      string                      `json:"change-id"`
+	ChangeTimings map[string]*taskTimingsJSON `json:"change-timings"`
+}
+
+// ChangeTimings returns the timings recorded for the tasks of the
+// given change.
+func (client *Client) ChangeTimings(changeID string) (*ChangeTimings, error) {
+	var result []*changeTimingsJSON
+	query := url.Values{
+		"aspect":    []string{"change-timings"},
+		"change-id": []string{changeID},
+	}
+	if _, err := client.doSync("GET", "/v2/debug", query, nil, nil, &result); err != nil {
+		return nil, err
+	}
+	if len(result) != 1 {
+		return nil, fmt.Errorf("cannot get timings of change %s: expected 1 result, got %d", changeID, len(result))
+	}
+
+	ct := &ChangeTimings{
+		ChangeID: result[0].ChangeID,
+		Tasks:    make([]*TaskTimings, 0, len(result[0].ChangeTimings)),
+	}
+	for id, t := range result[0].ChangeTimings {
+		ct.Tasks = append(ct.Tasks, &TaskTimings{
+			ID:             id,
+			Kind:           t.Kind,
+			Status:         t.Status,
+			Summary:        t.Summary,
+			Lane:           t.Lane,
+			Snap:           t.Snap,
+			ReadyTime:      t.ReadyTime,
+			DoingTime:      t.DoingTime,
+			UndoingTime:    t.UndoingTime,
+			DoingTimings:   nestTimings(t.DoingTimings),
+			UndoingTimings: nestTimings(t.UndoingTimings),
+		})
+	}
+	sort.Sort(byTaskID(ct.Tasks))
+
+	return ct, nil
+}
+
+type byTaskID []*TaskTimings
+
+func (b byTaskID) Len() int      { return len(b) }
+func (b byTaskID) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byTaskID) Less(i, j int) bool {
+	ni, erri := strconv.Atoi(b[i].ID)
+	nj, errj := strconv.Atoi(b[j].ID)
+	if erri == nil && errj == nil {
+		return ni < nj
+	}
+	return b[i].ID < b[j].ID
+}
+
+// nestTimings rebuilds the tree of measurements from the flat,
+// depth-first list sent by the daemon. Measurements that were too short
+// to be kept may leave gaps in the levels, in which case the orphaned
+// measurements are attached to the deepest measurement seen so far.
+func nestTimings(flat []*timingJSON) []*Timing {
+	var roots []*Timing
+	// parents[i] is the last measurement seen at level i
+	var parents []*Timing
+	for _, tj := range flat {
+		tm := &Timing{
+			Label:    tj.Label,
+			Summary:  tj.Summary,
+			Duration: tj.Duration,
+		}
+		level := tj.Level
+		if level > len(parents) {
+			level = len(parents)
+		}
+		parents = parents[:level]
+		if level == 0 {
+			roots = append(roots, tm)
+		} else {
+			parent := parents[level-1]
+			parent.Nested = append(parent.Nested, tm)
+		}
+		parents = append(parents, tm)
+	}
+	return roots
+}
@@ -0,0 +1,144 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"net/url"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientChangeTimings(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [{
+			"change-id": "1",
+			"change-timings": {
+				"10": {
+					"kind": "setup-profiles",
+					"status": "Done",
+					"summary": "Setup snap \"some-snap\" security profiles",
+					"lane": 1,
+					"snap": "some-snap",
+					"ready-time": "2023-01-01T10:00:00Z",
+					"doing-time": 3000,
+					"doing-timings": [
+						{"label": "setup-security-backend", "summary": "setup security backend", "duration": 2000},
+						{"level": 1, "label": "apparmor", "summary": "setup apparmor", "duration": 1500},
+						{"level": 2, "label": "load", "summary": "load profiles", "duration": 1000},
+						{"level": 1, "label": "seccomp", "summary": "setup seccomp", "duration": 400},
+						{"label": "auto-connect", "summary": "auto connect", "duration": 500}
+					]
+				},
+				"9": {
+					"kind": "copy-snap-data",
+					"status": "Undone",
+					"summary": "Copy snap \"some-snap\" data",
+					"snap": "some-snap",
+					"doing-time": 1000,
+					"undoing-time": 200,
+					"doing-timings": [
+						{"label": "copy-snap-data", "summary": "copy data", "duration": 900}
+					],
+					"undoing-timings": [
+						{"level": 1, "label": "orphan", "summary": "orphaned", "duration": 100}
+					]
+				}
+			}
+		}]
+	}`
+
+	ct, err := cs.cli.ChangeTimings("1")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/debug")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"aspect":    []string{"change-timings"},
+		"change-id": []string{"1"},
+	})
+
+	c.Check(ct, check.DeepEquals, &client.ChangeTimings{
+		ChangeID: "1",
+		Tasks: []*client.TaskTimings{{
+			ID:          "9",
+			Kind:        "copy-snap-data",
+			Status:      "Undone",
+			Summary:     `Copy snap "some-snap" data`,
+			Snap:        "some-snap",
+			DoingTime:   1000,
+			UndoingTime: 200,
+			DoingTimings: []*client.Timing{
+				{Label: "copy-snap-data", Summary: "copy data", Duration: 900},
+			},
+			UndoingTimings: []*client.Timing{
+				{Label: "orphan", Summary: "orphaned", Duration: 100},
+			},
+		}, {
+			ID:        "10",
+			Kind:      "setup-profiles",
+			Status:    "Done",
+			Summary:   `Setup snap "some-snap" security profiles`,
+			Lane:      1,
+			Snap:      "some-snap",
+			ReadyTime: time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC),
+			DoingTime: 3000,
+			DoingTimings: []*client.Timing{{
+				Label:    "setup-security-backend",
+				Summary:  "setup security backend",
+				Duration: 2000,
+				Nested: []*client.Timing{{
+					Label:    "apparmor",
+					Summary:  "setup apparmor",
+					Duration: 1500,
+					Nested: []*client.Timing{
+						{Label: "load", Summary: "load profiles", Duration: 1000},
+					},
+				}, {
+					Label:    "seccomp",
+					Summary:  "setup seccomp",
+					Duration: 400,
+				}},
+			}, {
+				Label:    "auto-connect",
+				Summary:  "auto connect",
+				Duration: 500,
+			}},
+		}},
+	})
+}
+
+func (cs *clientSuite) TestClientChangeTimingsUnexpectedResults(c *check.C) {
+	cs.rsp = `{"type": "sync", "status-code": 200, "result": []}`
+
+	_, err := cs.cli.ChangeTimings("1")
+	c.Check(err, check.ErrorMatches, `cannot get timings of change 1: expected 1 result, got 0`)
+}
+
+func (cs *clientSuite) TestClientChangeTimingsError(c *check.C) {
+	cs.status = 400
+	cs.rsp = `{"type": "error", "status-code": 400, "result": {"message": "cannot find change: 1"}}`
+
+	_, err := cs.cli.ChangeTimings("1")
+	c.Check(err, check.ErrorMatches, `cannot find change: 1`)
+}
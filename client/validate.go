@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"time"
 
 	"golang.org/x/xerrors"
 )
@@ -44,7 +45,13 @@ type ValidationSetResult struct {
 
 	Mode  string `json:"mode"`
 	Valid bool   `json:"valid"`
-	// TODO: flags/states for notes column
+
+	// LastRefresh is the time the validation set was last refreshed from
+	// the store, if ever.
+	LastRefresh *time.Time `json:"last-refresh,omitempty"`
+	// PendingSequence is a newer sequence point which could not be
+	// applied when refreshing, or 0 if none.
+	PendingSequence int `json:"pending-sequence,omitempty"`
 }
 
 type postValidationSetData struct {
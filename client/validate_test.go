@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/url"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -64,7 +65,7 @@ func (cs *clientSuite) TestListValidationsSets(c *check.C) {
 		"status-code": 200,
 		"result": [
 			{"account-id": "abc", "name": "def", "mode": "monitor", "sequence": 0},
-			{"account-id": "ghi", "name": "jkl", "mode": "enforce", "sequence": 2}
+			{"account-id": "ghi", "name": "jkl", "mode": "enforce", "sequence": 2, "last-refresh": "2023-06-01T10:00:00Z", "pending-sequence": 3}
 		]
 	}`
 
@@ -72,9 +73,10 @@ func (cs *clientSuite) TestListValidationsSets(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Check(cs.req.Method, check.Equals, "GET")
 	c.Check(cs.req.URL.Path, check.Equals, "/v2/validation-sets")
+	lastRefresh := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
 	c.Check(vsets, check.DeepEquals, []*client.ValidationSetResult{
 		{AccountID: "abc", Name: "def", Mode: "monitor", Sequence: 0, Valid: false},
-		{AccountID: "ghi", Name: "jkl", Mode: "enforce", Sequence: 2, Valid: false},
+		{AccountID: "ghi", Name: "jkl", Mode: "enforce", Sequence: 2, Valid: false, LastRefresh: &lastRefresh, PendingSequence: 3},
 	})
 }
 
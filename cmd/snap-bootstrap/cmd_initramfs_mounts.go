@@ -229,7 +229,7 @@ func canInstallAndRunAtOnce(mst *initramfsMountsState) (bool, error) {
 	}
 
 	// TODO: relax this condition when "install and run" well tested
-	if !preseedSeed.HasArtifact("preseed.tgz") {
+	if !preseedSeed.HasPreseedArtifact() {
 		return false, nil
 	}
 
@@ -1397,7 +1397,16 @@ func generateMountsModeRecover(mst *initramfsMountsState) error {
 	// ubuntu-data, and as such we can proceed with copying files from there
 	// onto the tmpfs
 	// Proceed only if we trust ubuntu-data to be paired with ubuntu-save
-	if machine.trustData() {
+	trustData := machine.trustData()
+	if trustData {
+		// the model of the recovery system must be the one the system
+		// was created for, otherwise the seed may have been tampered with
+		if err := boot.InitramfsCheckRecoverySystemModel(boot.InitramfsHostWritableDir(model), mst.recoverySystem, model); err != nil {
+			logger.Noticef("WARNING: not trusting ubuntu-data: %v", err)
+			trustData = false
+		}
+	}
+	if trustData {
 		// TODO: erroring here should fallback to copySafeDefaultData and
 		// proceed on with degraded mode anyways
 		if err := copyUbuntuDataAuth(boot.InitramfsHostUbuntuDataDir, boot.InitramfsDataDir); err != nil {
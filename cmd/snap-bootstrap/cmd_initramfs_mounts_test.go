@@ -729,13 +729,13 @@ func (s *initramfsMountsSuite) TestInitramfsMountsInstallModeHappy(c *C) {
 	c.Assert(err, IsNil)
 
 	modeEnv := dirs.SnapModeenvFileUnder(filepath.Join(dirs.GlobalRootDir, "/run/mnt/data/system-data"))
-	c.Check(modeEnv, testutil.FileEquals, `mode=install
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=install
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 	cloudInitDisable := filepath.Join(filepath.Join(dirs.GlobalRootDir, "/run/mnt/data/system-data"), "_writable_defaults/etc/cloud/cloud-init.disabled")
 	c.Check(cloudInitDisable, testutil.FilePresent)
 
@@ -982,13 +982,13 @@ defaults:
 	c.Assert(err, IsNil)
 
 	modeEnv := dirs.SnapModeenvFileUnder(filepath.Join(dirs.GlobalRootDir, "/run/mnt/data/system-data"))
-	c.Check(modeEnv, testutil.FileEquals, `mode=install
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=install
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 
 	cloudInitDisable := filepath.Join(filepath.Join(dirs.GlobalRootDir, "/run/mnt/data/system-data"), "_writable_defaults/etc/cloud/cloud-init.disabled")
 	c.Check(cloudInitDisable, testutil.FilePresent)
@@ -1033,13 +1033,13 @@ func (s *initramfsMountsSuite) TestInitramfsMountsInstallModeBootedKernelPartiti
 	c.Assert(err, IsNil)
 
 	modeEnv := dirs.SnapModeenvFileUnder(filepath.Join(dirs.GlobalRootDir, "/run/mnt/data/system-data"))
-	c.Check(modeEnv, testutil.FileEquals, `mode=install
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=install
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 	cloudInitDisable := filepath.Join(filepath.Join(dirs.GlobalRootDir, "/run/mnt/data/system-data"), "_writable_defaults/etc/cloud/cloud-init.disabled")
 	c.Check(cloudInitDisable, testutil.FilePresent)
 }
@@ -3289,13 +3289,13 @@ func (s *initramfsMountsSuite) testRecoverModeHappy(c *C) {
 	c.Check(sealedKeysLocked, Equals, true)
 
 	modeEnv := filepath.Join(ephemeralUbuntuData, "/system-data/var/lib/snapd/modeenv")
-	c.Check(modeEnv, testutil.FileEquals, `mode=recover
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=recover
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 	for _, p := range mockUnrelatedFiles {
 		c.Check(filepath.Join(ephemeralUbuntuData, p), testutil.FileAbsent)
 	}
@@ -3389,6 +3389,85 @@ func (s *initramfsMountsSuite) TestInitramfsMountsRecoverModeHappy(c *C) {
 	c.Assert(filepath.Join(dirs.SnapRunDir, "boot-flags"), testutil.FileEquals, "")
 }
 
+func (s *initramfsMountsSuite) TestInitramfsMountsRecoverModeRecoverySystemModelMismatch(c *C) {
+	s.mockProcCmdlineContent(c, "snapd_recovery_mode=recover snapd_recovery_system="+s.sysLabel)
+
+	// setup a bootloader for setting the bootenv after we are done
+	bloader := bootloadertest.Mock("mock", c.MkDir())
+	bootloader.Force(bloader)
+	defer bootloader.Force(nil)
+
+	// mock that we don't know which partition uuid the kernel was booted from
+	restore := main.MockPartitionUUIDForBootedKernelDisk("")
+	defer restore()
+
+	restore = disks.MockMountPointDisksToPartitionMapping(
+		map[disks.Mountpoint]*disks.MockDiskMapping{
+			{Mountpoint: boot.InitramfsUbuntuSeedDir}:     defaultBootWithSaveDisk,
+			{Mountpoint: boot.InitramfsUbuntuBootDir}:     defaultBootWithSaveDisk,
+			{Mountpoint: boot.InitramfsHostUbuntuDataDir}: defaultBootWithSaveDisk,
+			{Mountpoint: boot.InitramfsUbuntuSaveDir}:     defaultBootWithSaveDisk,
+		},
+	)
+	defer restore()
+
+	restore = s.mockSystemdMountSequence(c, []systemdMount{
+		s.ubuntuLabelMount("ubuntu-seed", "recover"),
+		s.makeSeedSnapSystemdMount(snap.TypeSnapd),
+		s.makeSeedSnapSystemdMount(snap.TypeKernel),
+		s.makeSeedSnapSystemdMount(snap.TypeBase),
+		s.makeSeedSnapSystemdMount(snap.TypeGadget),
+		{
+			"tmpfs",
+			boot.InitramfsDataDir,
+			tmpfsMountOpts,
+			nil,
+		},
+		{
+			"/dev/disk/by-partuuid/ubuntu-boot-partuuid",
+			boot.InitramfsUbuntuBootDir,
+			needsFsckDiskMountOpts,
+			nil,
+		},
+		{
+			"/dev/disk/by-partuuid/ubuntu-data-partuuid",
+			boot.InitramfsHostUbuntuDataDir,
+			needsNoSuidDiskMountOpts,
+			nil,
+		},
+		{
+			"/dev/disk/by-partuuid/ubuntu-save-partuuid",
+			boot.InitramfsUbuntuSaveDir,
+			mountOpts,
+			nil,
+		},
+	}, nil)
+	defer restore()
+
+	// the modeenv of the host records a different model for the system
+	hostModeenv := &boot.Modeenv{
+		Mode:                   "run",
+		CurrentRecoverySystems: []string{s.sysLabel},
+		RecoverySystemModels: map[string]string{
+			s.sysLabel: "not-the-model-digest",
+		},
+	}
+	c.Assert(hostModeenv.WriteTo(boot.InitramfsHostWritableDir(s.model)), IsNil)
+	// mock auth data in the host's ubuntu-data
+	hostPasswd := filepath.Join(boot.InitramfsHostWritableDir(s.model), "var/lib/extrausers/passwd")
+	c.Assert(os.MkdirAll(filepath.Dir(hostPasswd), 0750), IsNil)
+	c.Assert(os.WriteFile(hostPasswd, []byte("passwd"), 0640), IsNil)
+
+	_, err := main.Parser().ParseArgs([]string{"initramfs-mounts"})
+	c.Assert(err, IsNil)
+
+	c.Check(s.logs.String(), testutil.Contains, fmt.Sprintf(`WARNING: not trusting ubuntu-data: model of recovery system %q does not match the recorded model`, s.sysLabel))
+	// auth data was not copied, safe defaults are used instead
+	ephemeralUbuntuData := filepath.Join(boot.InitramfsRunMntDir, "data/")
+	c.Check(filepath.Join(ephemeralUbuntuData, "system-data/var/lib/extrausers/passwd"), testutil.FileAbsent)
+	c.Check(filepath.Join(ephemeralUbuntuData, "system-data/var/lib/console-conf/complete"), testutil.FilePresent)
+}
+
 func (s *initramfsMountsSuite) TestInitramfsMountsRecoverModeTimeMovesForwardHappy(c *C) {
 	s.mockProcCmdlineContent(c, "snapd_recovery_mode=recover snapd_recovery_system="+s.sysLabel)
 
@@ -4606,13 +4685,13 @@ func (s *initramfsMountsSuite) TestInitramfsMountsRecoverModeEncryptedDegradedDa
 	c.Check(sealedKeysLocked, Equals, true)
 
 	modeEnv := filepath.Join(filepath.Join(dirs.GlobalRootDir, "/run/mnt/data/system-data"), "var/lib/snapd/modeenv")
-	c.Check(modeEnv, testutil.FileEquals, `mode=recover
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=recover
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 
 	checkDegradedJSON(c, "degraded.json", map[string]interface{}{
 		"ubuntu-boot": map[string]interface{}{
@@ -4796,13 +4875,13 @@ func (s *initramfsMountsSuite) TestInitramfsMountsRecoverModeDegradedAbsentDataU
 	c.Check(sealedKeysLocked, Equals, true)
 
 	modeEnv := filepath.Join(filepath.Join(dirs.GlobalRootDir, "/run/mnt/data/system-data"), "var/lib/snapd/modeenv")
-	c.Check(modeEnv, testutil.FileEquals, `mode=recover
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=recover
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 
 	checkDegradedJSON(c, "degraded.json", map[string]interface{}{
 		"ubuntu-boot": map[string]interface{}{
@@ -5396,13 +5475,13 @@ func (s *initramfsMountsSuite) TestInitramfsMountsRecoverModeEncryptedDegradedAb
 	c.Check(sealedKeysLocked, Equals, true)
 
 	modeEnv := filepath.Join(filepath.Join(dirs.GlobalRootDir, "/run/mnt/data/system-data"), "var/lib/snapd/modeenv")
-	c.Check(modeEnv, testutil.FileEquals, `mode=recover
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=recover
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 
 	checkDegradedJSON(c, "degraded.json", map[string]interface{}{
 		"ubuntu-boot": map[string]interface{}{
@@ -5596,13 +5675,13 @@ func (s *initramfsMountsSuite) TestInitramfsMountsRecoverModeEncryptedDegradedDa
 	c.Check(sealedKeysLocked, Equals, true)
 
 	modeEnv := filepath.Join(boot.InitramfsRunMntDir, "data/system-data/var/lib/snapd/modeenv")
-	c.Check(modeEnv, testutil.FileEquals, `mode=recover
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=recover
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 
 	checkDegradedJSON(c, "degraded.json", map[string]interface{}{
 		"ubuntu-boot": map[string]interface{}{
@@ -5777,13 +5856,13 @@ func (s *initramfsMountsSuite) TestInitramfsMountsRecoverModeEncryptedMismatched
 	c.Check(sealedKeysLocked, Equals, true)
 
 	modeEnv := filepath.Join(filepath.Join(dirs.GlobalRootDir, "/run/mnt/data/system-data"), "var/lib/snapd/modeenv")
-	c.Check(modeEnv, testutil.FileEquals, `mode=recover
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=recover
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 
 	checkDegradedJSON(c, "degraded.json", map[string]interface{}{
 		"ubuntu-boot": map[string]interface{}{
@@ -6084,13 +6163,13 @@ func (s *initramfsMountsSuite) testInitramfsMountsInstallRecoverModeMeasure(c *C
 		c.Assert(err, IsNil)
 
 		modeEnv := filepath.Join(boot.InitramfsDataDir, "/system-data/var/lib/snapd/modeenv")
-		c.Check(modeEnv, testutil.FileEquals, `mode=install
+		c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=install
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 	}
 
 	c.Check(measuredModel, NotNil)
@@ -6356,13 +6435,13 @@ func (s *initramfsMountsSuite) TestInitramfsMountsTryRecoveryDifferentSystem(c *
 
 	// modeenv is written as we will seed the recovery system
 	modeEnv := dirs.SnapModeenvFileUnder(filepath.Join(dirs.GlobalRootDir, "/run/mnt/data/system-data"))
-	c.Check(modeEnv, testutil.FileEquals, `mode=recover
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=recover
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 	c.Check(bl.BootVars, DeepEquals, map[string]string{
 		// variables not modified since they were set up for a different
 		// system
@@ -6829,13 +6908,13 @@ func (s *initramfsMountsSuite) TestInitramfsMountsFactoryResetModeHappyEncrypted
 	c.Check(sealedKeysLocked, Equals, true)
 
 	modeEnv := filepath.Join(ephemeralUbuntuData, "/system-data/var/lib/snapd/modeenv")
-	c.Check(modeEnv, testutil.FileEquals, `mode=factory-reset
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=factory-reset
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 
 	// we should have written a boot state file
 	checkDegradedJSON(c, "factory-reset-bootstrap.json", map[string]interface{}{
@@ -6927,13 +7006,13 @@ func (s *initramfsMountsSuite) TestInitramfsMountsFactoryResetModeHappyUnencrypt
 	c.Assert(err, IsNil)
 
 	modeEnv := filepath.Join(ephemeralUbuntuData, "/system-data/var/lib/snapd/modeenv")
-	c.Check(modeEnv, testutil.FileEquals, `mode=factory-reset
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=factory-reset
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 	// we should have written a boot state file
 	checkDegradedJSON(c, "factory-reset-bootstrap.json", map[string]interface{}{
 		"ubuntu-boot": map[string]interface{}{},
@@ -7007,13 +7086,13 @@ func (s *initramfsMountsSuite) TestInitramfsMountsFactoryResetModeHappyUnencrypt
 	c.Assert(err, IsNil)
 
 	modeEnv := filepath.Join(ephemeralUbuntuData, "/system-data/var/lib/snapd/modeenv")
-	c.Check(modeEnv, testutil.FileEquals, `mode=factory-reset
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=factory-reset
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 	// we should have written a boot state file with save marked as
 	// absent-but-optional
 	checkDegradedJSON(c, "factory-reset-bootstrap.json", map[string]interface{}{
@@ -7119,13 +7198,13 @@ func (s *initramfsMountsSuite) TestInitramfsMountsFactoryResetModeUnhappyUnlockE
 	c.Check(sealedKeysLocked, Equals, true)
 
 	modeEnv := filepath.Join(ephemeralUbuntuData, "/system-data/var/lib/snapd/modeenv")
-	c.Check(modeEnv, testutil.FileEquals, `mode=factory-reset
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=factory-reset
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 
 	// we should have written a boot state file
 	checkDegradedJSON(c, "factory-reset-bootstrap.json", map[string]interface{}{
@@ -7230,13 +7309,13 @@ func (s *initramfsMountsSuite) TestInitramfsMountsFactoryResetModeUnhappyMountEn
 	c.Check(sealedKeysLocked, Equals, true)
 
 	modeEnv := filepath.Join(ephemeralUbuntuData, "/system-data/var/lib/snapd/modeenv")
-	c.Check(modeEnv, testutil.FileEquals, `mode=factory-reset
+	c.Check(modeEnv, testutil.FileEquals, boottest.VersionedModeenv(`mode=factory-reset
 recovery_system=20191118
 base=core20_1.snap
 gadget=pc_1.snap
 model=my-brand/my-model
 grade=signed
-`)
+`))
 
 	// we should have written a boot state file
 	checkDegradedJSON(c, "factory-reset-bootstrap.json", map[string]interface{}{
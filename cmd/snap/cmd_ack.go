@@ -20,11 +20,15 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
 )
@@ -32,7 +36,7 @@ import (
 type cmdAck struct {
 	clientMixin
 	AckOptions struct {
-		AssertionFile flags.Filename
+		AssertionFiles []flags.Filename `required:"1"`
 	} `positional-args:"true" required:"true"`
 }
 
@@ -43,6 +47,10 @@ The ack command tries to add an assertion to the system assertion database.
 The assertion may also be a newer revision of a pre-existing assertion that it
 will replace.
 
+Each file may hold a stream of assertions. When several files are given, their
+assertions are added together, so that the prerequisites of an assertion can
+come from any of them.
+
 To succeed the assertion must be valid, its signature verified with a known
 public key and the assertion consistent with and its prerequisite in the
 database.
@@ -68,11 +76,55 @@ func ackFile(cli *client.Client, assertFile string) error {
 	return cli.Ack(assertData)
 }
 
+// ackFiles adds the assertions of all the given files in one go.
+func ackFiles(cli *client.Client, assertFiles []string) error {
+	var buf bytes.Buffer
+	enc := asserts.NewEncoder(&buf)
+	for _, assertFile := range assertFiles {
+		f, err := os.Open(assertFile)
+		if err != nil {
+			return err
+		}
+		err = reencodeAssertions(enc, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("cannot decode %q: %v", assertFile, err)
+		}
+	}
+	return cli.Ack(buf.Bytes())
+}
+
+func reencodeAssertions(enc *asserts.Encoder, r io.Reader) error {
+	dec := asserts.NewDecoder(r)
+	for {
+		a, err := dec.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(a); err != nil {
+			return err
+		}
+	}
+}
+
 func (x *cmdAck) Execute(args []string) error {
 	if len(args) > 0 {
 		return ErrExtraArgs
 	}
-	if err := ackFile(x.client, string(x.AckOptions.AssertionFile)); err != nil {
+	files := make([]string, len(x.AckOptions.AssertionFiles))
+	for i, f := range x.AckOptions.AssertionFiles {
+		files[i] = string(f)
+	}
+	var err error
+	if len(files) == 1 {
+		err = ackFile(x.client, files[0])
+	} else {
+		err = ackFiles(x.client, files)
+	}
+	if err != nil {
 		return fmt.Errorf("cannot assert: %v", err)
 	}
 	return nil
@@ -0,0 +1,115 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestAckOneFile(c *check.C) {
+	data := []byte("some assertion data")
+	fn := filepath.Join(c.MkDir(), "a.assert")
+	c.Assert(os.WriteFile(fn, data, 0644), check.IsNil)
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/assertions")
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, check.IsNil)
+		// the file is sent as is
+		c.Check(body, check.DeepEquals, data)
+		fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"ack", fn})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestAckManyFiles(c *check.C) {
+	storeSigning := assertstest.NewStoreStack("canonical", nil)
+	acct := assertstest.NewAccount(storeSigning, "developer1", nil, "")
+	accKey := storeSigning.StoreAccountKey("")
+
+	dir := c.MkDir()
+	fn1 := filepath.Join(dir, "account.assert")
+	c.Assert(os.WriteFile(fn1, asserts.Encode(acct), 0644), check.IsNil)
+	// a stream of assertions
+	var stream bytes.Buffer
+	enc := asserts.NewEncoder(&stream)
+	c.Assert(enc.Encode(storeSigning.TrustedAccount), check.IsNil)
+	c.Assert(enc.Encode(accKey), check.IsNil)
+	fn2 := filepath.Join(dir, "keys.assert")
+	c.Assert(os.WriteFile(fn2, stream.Bytes(), 0644), check.IsNil)
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/assertions")
+		// all the assertions are sent in one stream
+		dec := asserts.NewDecoder(r.Body)
+		var refs []*asserts.Ref
+		for {
+			a, err := dec.Decode()
+			if err != nil {
+				break
+			}
+			refs = append(refs, a.Ref())
+		}
+		c.Check(refs, check.DeepEquals, []*asserts.Ref{acct.Ref(), storeSigning.TrustedAccount.Ref(), accKey.Ref()})
+		fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"ack", fn1, fn2})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestAckManyFilesInvalid(c *check.C) {
+	dir := c.MkDir()
+	fn1 := filepath.Join(dir, "a.assert")
+	c.Assert(os.WriteFile(fn1, []byte("garbage"), 0644), check.IsNil)
+	fn2 := filepath.Join(dir, "b.assert")
+	c.Assert(os.WriteFile(fn2, []byte("garbage"), 0644), check.IsNil)
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"ack", fn1, fn2})
+	c.Assert(err, check.ErrorMatches, fmt.Sprintf(`cannot assert: cannot decode %q: .*`, fn1))
+}
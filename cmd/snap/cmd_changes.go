@@ -43,6 +43,7 @@ change.
 type cmdChanges struct {
 	clientMixin
 	timeMixin
+	jsonMixin
 	Positional struct {
 		Snap string `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
@@ -55,7 +56,7 @@ type cmdTasks struct {
 
 func init() {
 	addCommand("changes", shortChangesHelp, longChangesHelp,
-		func() flags.Commander { return &cmdChanges{} }, timeDescs, nil)
+		func() flags.Commander { return &cmdChanges{} }, timeDescs.also(jsonDescs), nil)
 	addCommand("tasks", shortTasksHelp, longTasksHelp,
 		func() flags.Commander { return &cmdTasks{} },
 		changeIDMixinOptDesc.also(timeDescs),
@@ -106,13 +107,20 @@ func (c *cmdChanges) Execute(args []string) error {
 		return err
 	}
 
+	sort.Sort(changesByTime(changes))
+
+	if c.JSON {
+		if changes == nil {
+			changes = []*client.Change{}
+		}
+		return c.printJSON(changes)
+	}
+
 	if len(changes) == 0 {
 		fmt.Fprintln(Stderr, i18n.G("no changes found"))
 		return nil
 	}
 
-	sort.Sort(changesByTime(changes))
-
 	w := tabWriter()
 
 	fmt.Fprintf(w, i18n.G("ID\tStatus\tSpawn\tReady\tSummary\n"))
@@ -20,13 +20,16 @@
 package main_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/client"
 	snap "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/testutil"
 )
 
 var mockChangeJSON = `{"type": "sync", "result": {
@@ -250,3 +253,37 @@ func (s *SnapSuite) TestNoChanges(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Check(s.Stderr(), check.Equals, "no changes found\n")
 }
+
+func (s *SnapSuite) TestChangesJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/changes")
+		fmt.Fprintln(w, mockChangesJSON)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"changes", "--json"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stderr(), check.Equals, "")
+
+	var changes []*client.Change
+	c.Assert(json.Unmarshal([]byte(s.Stdout()), &changes), check.IsNil)
+	var ids []string
+	for _, chg := range changes {
+		ids = append(ids, chg.ID)
+	}
+	// sorted by spawn time
+	c.Check(ids, check.DeepEquals, []string{"four", "three", "one", "two"})
+	c.Check(changes[0].Kind, check.Equals, "install-snap")
+	c.Check(changes[0].Tasks, check.HasLen, 1)
+	c.Check(changes[0].Tasks[0].Progress, check.Equals, client.TaskProgress{Done: 0, Total: 1})
+	c.Check(s.Stdout(), testutil.Contains, `"spawn-time": "2015-02-21T01:02:03Z"`)
+}
+
+func (s *SnapSuite) TestNoChangesJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"changes", "--json"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "[]\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
@@ -277,6 +277,81 @@ var fortestingConnectionList = client.Connections{
 	},
 }
 
+var fortestingConnectCompletionList = client.Connections{
+	Slots: []client.Slot{
+		{
+			Snap:      "core",
+			Name:      "x11",
+			Interface: "x11",
+		},
+		{
+			Snap:      "core",
+			Name:      "network-bind",
+			Interface: "network-bind",
+			Connections: []client.PlugRef{
+				{
+					Snap: "web-server",
+					Name: "network-bind",
+				},
+			},
+		},
+		{
+			Snap:      "wake-up-alarm",
+			Name:      "toggle",
+			Interface: "bool-file",
+			Label:     "Alarm toggle",
+		},
+		{
+			Snap:      "canonical-pi2",
+			Name:      "pin-13",
+			Interface: "bool-file",
+			Label:     "Pin 13",
+			Connections: []client.PlugRef{
+				{
+					Snap: "keyboard-lights",
+					Name: "capslock-led",
+				},
+			},
+		},
+	},
+	Plugs: []client.Plug{
+		{
+			Snap:      "paste-daemon",
+			Name:      "network-bind",
+			Interface: "network-bind",
+		},
+		{
+			Snap:      "potato",
+			Name:      "frying",
+			Interface: "frying",
+			Label:     "Ability to fry a network service",
+		},
+		{
+			Snap:      "keyboard-lights",
+			Name:      "capslock-led",
+			Interface: "bool-file",
+			Label:     "Capslock indicator LED",
+			Connections: []client.SlotRef{
+				{
+					Snap: "canonical-pi2",
+					Name: "pin-13",
+				},
+			},
+		},
+		{
+			Snap:      "keyboard-lights",
+			Name:      "numlock-led",
+			Interface: "bool-file",
+			Label:     "Numlock indicator LED",
+		},
+		{
+			Snap:      "x11-app",
+			Name:      "x11",
+			Interface: "x11",
+		},
+	},
+}
+
 func (s *SnapSuite) TestConnectCompletion(c *C) {
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -284,7 +359,7 @@ func (s *SnapSuite) TestConnectCompletion(c *C) {
 			c.Assert(r.Method, Equals, "GET")
 			EncodeResponseBody(c, w, map[string]interface{}{
 				"type":   "sync",
-				"result": fortestingConnectionList,
+				"result": fortestingConnectCompletionList,
 			})
 		default:
 			c.Fatalf("unexpected path %q", r.URL.Path)
@@ -292,37 +367,64 @@ func (s *SnapSuite) TestConnectCompletion(c *C) {
 	})
 	os.Setenv("GO_FLAGS_COMPLETION", "verbose")
 	defer os.Unsetenv("GO_FLAGS_COMPLETION")
+	origArgs := os.Args
+	defer func() { os.Args = origArgs }()
 
 	expected := []flags.Completion{}
 	parser := Parser(Client())
 	parser.CompletionHandler = func(obtained []flags.Completion) {
 		c.Check(obtained, DeepEquals, expected)
 	}
+	complete := func(args ...string) {
+		os.Args = append([]string{"snap"}, args...)
+		_, err := parser.ParseArgs(args)
+		c.Assert(err, IsNil)
+	}
 
-	expected = []flags.Completion{{Item: "core:"}, {Item: "paste-daemon:"}, {Item: "potato:"}}
-	_, err := parser.ParseArgs([]string{"connect", ""})
-	c.Assert(err, IsNil)
+	// only plugs for which there is a slot of the same interface are
+	// offered
+	expected = []flags.Completion{{Item: "keyboard-lights:"}, {Item: "paste-daemon:"}, {Item: "x11-app:"}}
+	complete("connect", "")
+
+	expected = nil
+	complete("connect", "po")
 
 	// connect's first argument can't start with : (only for the 2nd arg, the slot)
 	expected = nil
-	_, err = parser.ParseArgs([]string{"connect", ":"})
-	c.Assert(err, IsNil)
+	complete("connect", ":")
 
-	expected = []flags.Completion{{Item: "paste-daemon:network-listening", Description: "plug"}}
-	_, err = parser.ParseArgs([]string{"connect", "pa"})
-	c.Assert(err, IsNil)
+	expected = []flags.Completion{{Item: "paste-daemon:network-bind", Description: "plug"}}
+	complete("connect", "pa")
 
-	expected = []flags.Completion{{Item: "core:"}, {Item: "wake-up-alarm:"}}
-	_, err = parser.ParseArgs([]string{"connect", "paste-daemon:network-listening", ""})
-	c.Assert(err, IsNil)
+	expected = []flags.Completion{{Item: "keyboard-lights:numlock-led", Description: "plug"}}
+	complete("connect", "keyboard-lights:")
+
+	// only slots of the interface of the plug are offered, even if
+	// connected already
+	expected = []flags.Completion{{Item: "canonical-pi2:"}, {Item: "wake-up-alarm:"}}
+	complete("connect", "keyboard-lights:numlock-led", "")
 
 	expected = []flags.Completion{{Item: "wake-up-alarm:toggle", Description: "slot"}}
-	_, err = parser.ParseArgs([]string{"connect", "paste-daemon:network-listening", "w"})
-	c.Assert(err, IsNil)
+	complete("connect", "keyboard-lights:numlock-led", "w")
 
-	expected = []flags.Completion{{Item: ":x11", Description: "slot"}}
-	_, err = parser.ParseArgs([]string{"connect", "paste-daemon:network-listening", ":"})
-	c.Assert(err, IsNil)
+	expected = []flags.Completion{{Item: ":network-bind", Description: "slot"}}
+	complete("connect", "paste-daemon:network-bind", ":")
+
+	expected = []flags.Completion{{Item: "core:x11", Description: "slot"}}
+	complete("connect", "x11-app:x11", "core:")
+
+	// the interfaces of the plugs of the snap are used when the plug
+	// is not named
+	expected = []flags.Completion{{Item: "core:network-bind", Description: "slot"}}
+	complete("connect", "paste-daemon", "core:")
+
+	// options are skipped when looking for the plug
+	expected = []flags.Completion{{Item: "core:x11", Description: "slot"}}
+	complete("connect", "--no-wait", "x11-app:x11", "core:")
+
+	// without a known plug, the disconnected slots are offered
+	expected = []flags.Completion{{Item: "core:"}, {Item: "wake-up-alarm:"}}
+	complete("connect", "unknown:plug", "")
 
 	c.Assert(s.Stdout(), Equals, "")
 	c.Assert(s.Stderr(), Equals, "")
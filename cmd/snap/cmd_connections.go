@@ -32,7 +32,10 @@ import (
 
 type cmdConnections struct {
 	clientMixin
+	timeMixin
+	jsonMixin
 	All         bool `long:"all"`
+	Unsatisfied bool `long:"unsatisfied"`
 	Positionals struct {
 		Snap installedSnapName
 	} `positional-args:"true"`
@@ -51,14 +54,26 @@ $ snap connections <snap>
 
 Lists connected and unconnected plugs and slots for the specified
 snap.
+
+Connections which could not be restored after a refresh, because their plug
+or slot, or its interface, is gone from the new revision of the snap, are
+listed with the "stale" note. They are restored if the snap is reverted.
+
+$ snap connections --unsatisfied [<snap>]
+
+Lists the plugs which are not connected, with the reason why they were not
+connected automatically. Plugs waiting for a slot to become available are
+periodically retried.
 `)
 
 func init() {
 	addCommand("connections", shortConnectionsHelp, longConnectionsHelp, func() flags.Commander {
 		return &cmdConnections{}
-	}, map[string]string{
+	}, timeDescs.also(jsonDescs).also(map[string]string{
 		"all": i18n.G("Show connected and unconnected plugs and slots"),
-	}, []argDesc{{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"unsatisfied": i18n.G("Show plugs which are not connected and why"),
+	}), []argDesc{{
 		// TRANSLATORS: This needs to be wrapped in <>s.
 		name: "<snap>",
 		// TRANSLATORS: This should not start with a lowercase letter.
@@ -84,6 +99,7 @@ type connection struct {
 	interfaceDeterminant string
 	manual               bool
 	gadget               bool
+	stale                bool
 }
 
 func (cn connection) String() string {
@@ -94,6 +110,9 @@ func (cn connection) String() string {
 	if cn.gadget {
 		opts = append(opts, "gadget")
 	}
+	if cn.stale {
+		opts = append(opts, "stale")
+	}
 	if len(opts) == 0 {
 		return "-"
 	}
@@ -136,6 +155,13 @@ func (x *cmdConnections) Execute(args []string) error {
 		return ErrExtraArgs
 	}
 
+	if x.Unsatisfied {
+		if x.All {
+			return fmt.Errorf(i18n.G("cannot use --all with --unsatisfied"))
+		}
+		return x.showUnsatisfied()
+	}
+
 	opts := client.ConnectionOptions{
 		All: x.All,
 	}
@@ -158,11 +184,14 @@ func (x *cmdConnections) Execute(args []string) error {
 	if err != nil {
 		return err
 	}
-	if len(connections.Plugs) == 0 && len(connections.Slots) == 0 {
+	if x.JSON {
+		return x.printJSON(connections)
+	}
+	if len(connections.Plugs) == 0 && len(connections.Slots) == 0 && len(connections.Stale) == 0 {
 		return nil
 	}
 
-	annotatedConns := make([]connection, 0, len(connections.Established)+len(connections.Undesired))
+	annotatedConns := make([]connection, 0, len(connections.Established)+len(connections.Undesired)+len(connections.Stale))
 	for _, conn := range connections.Established {
 		annotatedConns = append(annotatedConns, connection{
 			plug:                 endpoint(conn.Plug.Snap, conn.Plug.Name),
@@ -173,6 +202,16 @@ func (x *cmdConnections) Execute(args []string) error {
 			interfaceDeterminant: interfaceDeterminant(&conn),
 		})
 	}
+	for _, conn := range connections.Stale {
+		annotatedConns = append(annotatedConns, connection{
+			plug:          endpoint(conn.Plug.Snap, conn.Plug.Name),
+			slot:          endpoint(conn.Slot.Snap, conn.Slot.Name),
+			manual:        conn.Manual,
+			gadget:        conn.Gadget,
+			interfaceName: conn.Interface,
+			stale:         true,
+		})
+	}
 
 	w := tabWriter()
 	fmt.Fprintln(w, i18n.G("Interface\tPlug\tSlot\tNotes"))
@@ -212,3 +251,32 @@ func (x *cmdConnections) Execute(args []string) error {
 	}
 	return nil
 }
+
+func (x *cmdConnections) showUnsatisfied() error {
+	opts := client.ConnectionOptions{
+		Snap:        string(x.Positionals.Snap),
+		Unsatisfied: true,
+	}
+	connections, err := x.client.Connections(&opts)
+	if err != nil {
+		return err
+	}
+	if x.JSON {
+		return x.printJSON(connections)
+	}
+	if len(connections.Unsatisfied) == 0 {
+		return nil
+	}
+
+	w := tabWriter()
+	fmt.Fprintln(w, i18n.G("Interface\tPlug\tReason\tAttempts\tNext retry"))
+	for _, u := range connections.Unsatisfied {
+		nextRetry := "-"
+		if !u.NextRetry.IsZero() {
+			nextRetry = x.fmtTime(u.NextRetry)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", u.Interface, endpoint(u.Plug.Snap, u.Plug.Name), u.Reason, u.Attempts, nextRetry)
+	}
+	w.Flush()
+	return nil
+}
@@ -20,10 +20,12 @@
 package main_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"time"
 
 	. "gopkg.in/check.v1"
 
@@ -62,6 +64,54 @@ func (s *SnapSuite) TestConnectionsNoneConnected(c *C) {
 	c.Assert(s.Stderr(), Equals, "")
 }
 
+func (s *SnapSuite) TestConnectionsJSON(c *C) {
+	result := client.Connections{
+		Established: []client.Connection{
+			{
+				Plug:      client.PlugRef{Snap: "keyboard-lights", Name: "capslock-led"},
+				Slot:      client.SlotRef{Snap: "leds-provider", Name: "capslock-led"},
+				Interface: "leds",
+				Manual:    true,
+			},
+		},
+		Plugs: []client.Plug{
+			{
+				Snap:      "keyboard-lights",
+				Name:      "capslock-led",
+				Interface: "leds",
+				Connections: []client.SlotRef{
+					{Snap: "leds-provider", Name: "capslock-led"},
+				},
+			},
+		},
+		Slots: []client.Slot{
+			{
+				Snap:      "leds-provider",
+				Name:      "capslock-led",
+				Interface: "leds",
+				Connections: []client.PlugRef{
+					{Snap: "keyboard-lights", Name: "capslock-led"},
+				},
+			},
+		},
+	}
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type":   "sync",
+			"result": result,
+		})
+	})
+	_, err := Parser(Client()).ParseArgs([]string{"connections", "--json"})
+	c.Assert(err, IsNil)
+	c.Assert(s.Stderr(), Equals, "")
+	var conns client.Connections
+	c.Assert(json.Unmarshal([]byte(s.Stdout()), &conns), IsNil)
+	c.Check(conns, DeepEquals, result)
+	c.Check(s.Stdout(), Matches, `(?s)\{\n  "established": \[\n.*"undesired": null,\n.*`)
+}
+
 func (s *SnapSuite) TestConnectionsNotInstalled(c *C) {
 	query := url.Values{
 		"snap":   []string{"foo"},
@@ -851,3 +901,101 @@ func (s *SnapSuite) TestConnectionsDefiningAttribute(c *C) {
 	c.Assert(s.Stdout(), Equals, expectedStdout)
 	c.Assert(s.Stderr(), Equals, "")
 }
+
+func (s *SnapSuite) TestConnectionsUnsatisfied(c *C) {
+	result := client.Connections{
+		Unsatisfied: []client.UnsatisfiedPlug{
+			{
+				Plug:      client.PlugRef{Snap: "foo", Name: "data"},
+				Interface: "content",
+				Reason:    "no-candidate-slot",
+				Attempts:  2,
+				NextRetry: time.Date(2023, 5, 4, 10, 0, 0, 0, time.UTC),
+			}, {
+				Plug:      client.PlugRef{Snap: "foo", Name: "camera"},
+				Interface: "camera",
+				Reason:    "policy-denied",
+			},
+		},
+	}
+	query := url.Values{
+		"snap":   []string{"foo"},
+		"select": []string{"unsatisfied"},
+	}
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		c.Check(r.URL.Query(), DeepEquals, query)
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type":   "sync",
+			"result": result,
+		})
+	})
+
+	rest, err := Parser(Client()).ParseArgs([]string{"connections", "--unsatisfied", "--abs-time", "foo"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	expectedStdout := "" +
+		"Interface  Plug        Reason             Attempts  Next retry\n" +
+		"content    foo:data    no-candidate-slot  2         2023-05-04T10:00:00Z\n" +
+		"camera     foo:camera  policy-denied      0         -\n"
+	c.Assert(s.Stdout(), Equals, expectedStdout)
+	c.Assert(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestConnectionsUnsatisfiedWithAll(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+	_, err := Parser(Client()).ParseArgs([]string{"connections", "--unsatisfied", "--all"})
+	c.Assert(err, ErrorMatches, "cannot use --all with --unsatisfied")
+}
+
+func (s *SnapSuite) TestConnectionsStale(c *C) {
+	result := client.Connections{
+		Established: []client.Connection{
+			{
+				Plug:      client.PlugRef{Snap: "foo", Name: "network"},
+				Slot:      client.SlotRef{Snap: "core", Name: "network"},
+				Interface: "network",
+			},
+		},
+		Plugs: []client.Plug{
+			{
+				Snap:      "foo",
+				Name:      "network",
+				Interface: "network",
+				Connections: []client.SlotRef{{
+					Snap: "core",
+					Name: "network",
+				}},
+			},
+		},
+		Stale: []client.Connection{
+			{
+				Plug:      client.PlugRef{Snap: "foo", Name: "data"},
+				Slot:      client.SlotRef{Snap: "bar", Name: "data"},
+				Interface: "content",
+				Manual:    true,
+			},
+		},
+	}
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type":   "sync",
+			"result": result,
+		})
+	})
+
+	rest, err := Parser(Client()).ParseArgs([]string{"connections"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	expectedStdout := "" +
+		"Interface  Plug         Slot      Notes\n" +
+		"content    foo:data     bar:data  manual,stale\n" +
+		"network    foo:network  :network  -\n"
+	c.Assert(s.Stdout(), Equals, expectedStdout)
+	c.Assert(s.Stderr(), Equals, "")
+}
@@ -42,6 +42,11 @@ type cmdBootvarsSet struct {
 	} `positional-args:"yes" required:"yes"`
 }
 
+type cmdValidateModeenv struct {
+	RootDir string `long:"root-dir"`
+	SeedDir string `long:"seed-dir"`
+}
+
 func init() {
 	cmdGet := addDebugCommand("boot-vars",
 		"(internal) obtain the snapd boot variables",
@@ -63,6 +68,16 @@ func init() {
 			"recovery": i18n.G("Manipulate the recovery bootloader (implies UC20+)"),
 		}, nil)
 
+	addDebugCommand("validate-modeenv",
+		"(internal) check the modeenv for inconsistencies",
+		"(internal) check the modeenv for inconsistencies",
+		func() flags.Commander {
+			return &cmdValidateModeenv{}
+		}, map[string]string{
+			"root-dir": i18n.G("Root directory to look for the modeenv in"),
+			"seed-dir": i18n.G("Directory of ubuntu-seed to look for recovery systems in"),
+		}, nil)
+
 	if release.OnClassic {
 		cmdGet.hidden = true
 		cmdSet.hidden = true
@@ -82,3 +97,15 @@ func (x *cmdBootvarsSet) Execute(args []string) error {
 	}
 	return boot.DebugSetBootVars(x.RootDir, x.Recovery, x.Positional.VarEqValue)
 }
+
+func (x *cmdValidateModeenv) Execute(args []string) error {
+	m, err := boot.ReadModeenv(x.RootDir)
+	if err != nil {
+		return err
+	}
+	seedDir := x.SeedDir
+	if seedDir == "" {
+		seedDir = boot.InitramfsUbuntuSeedDir
+	}
+	return boot.ValidateModeenv(m, seedDir)
+}
@@ -20,6 +20,9 @@
 package main_test
 
 import (
+	"os"
+	"path/filepath"
+
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/boot"
@@ -155,3 +158,31 @@ snapd_full_cmdline_args=
 	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "set-boot-vars", "--recovery", "--root-dir", boot.InitramfsUbuntuBootDir, "foo=recovery"})
 	c.Assert(err, check.ErrorMatches, "cannot use run bootloader root-dir with a recovery flag")
 }
+
+func (s *SnapSuite) TestDebugValidateModeenv(c *check.C) {
+	rootDir := c.MkDir()
+	seedDir := c.MkDir()
+	err := os.MkdirAll(filepath.Join(seedDir, "systems", "20191126"), 0755)
+	c.Assert(err, check.IsNil)
+
+	m := &boot.Modeenv{
+		Mode:                   "run",
+		CurrentRecoverySystems: []string{"20191126"},
+		GoodRecoverySystems:    []string{"20191126"},
+		CurrentKernels:         []string{"pc-kernel_1.snap"},
+	}
+	err = m.WriteTo(rootDir)
+	c.Assert(err, check.IsNil)
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "validate-modeenv", "--root-dir", rootDir, "--seed-dir", seedDir})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "")
+
+	m.CurrentRecoverySystems = append(m.CurrentRecoverySystems, "1234")
+	err = m.WriteTo(rootDir)
+	c.Assert(err, check.IsNil)
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "validate-modeenv", "--root-dir", rootDir, "--seed-dir", seedDir})
+	c.Assert(err, check.ErrorMatches, `inconsistent modeenv: current recovery system "1234" does not exist in .*`)
+}
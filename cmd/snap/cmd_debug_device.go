@@ -0,0 +1,78 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDebugDevice struct {
+	waitMixin
+
+	Positional struct {
+		Action string `positional-arg-name:"<action>" required:"1"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func init() {
+	addDebugCommand("device",
+		"Perform actions on the device identity",
+		`The device command performs actions on the device identity.
+
+Supported actions:
+  renew-serial  generate a new device key and get a new serial for it
+`,
+		func() flags.Commander {
+			return &cmdDebugDevice{}
+		}, waitDescs, nil)
+}
+
+func (x *cmdDebugDevice) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	switch x.Positional.Action {
+	case "renew-serial":
+		return x.renewSerial()
+	default:
+		return fmt.Errorf(i18n.G("unsupported device action %q"), x.Positional.Action)
+	}
+}
+
+func (x *cmdDebugDevice) renewSerial() error {
+	changeID, err := x.client.RenewSerial()
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(changeID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+
+	fmt.Fprintln(Stdout, i18n.G("Device serial renewed."))
+	return nil
+}
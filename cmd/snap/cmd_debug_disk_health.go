@@ -0,0 +1,110 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/osutil/disks"
+)
+
+type cmdDebugDiskHealth struct {
+	clientMixin
+	unicodeMixin
+}
+
+func init() {
+	cmd := addDebugCommand("disk-health",
+		"(internal) show the health of the storage media",
+		"(internal) show the health of the storage media",
+		func() flags.Commander {
+			return &cmdDebugDiskHealth{}
+		}, nil, nil)
+	cmd.hidden = true
+}
+
+// emmcLifeTime describes an eMMC life time estimate as a range of used life
+// time.
+func emmcLifeTime(estimate int) string {
+	switch {
+	case estimate >= 1 && estimate <= 10:
+		return fmt.Sprintf("%d-%d%%", (estimate-1)*10, estimate*10)
+	case estimate == 11:
+		return i18n.G("exceeded")
+	}
+	return i18n.G("undefined")
+}
+
+func emmcPreEOL(preEOL int) string {
+	switch preEOL {
+	case 1:
+		return i18n.G("normal")
+	case 2:
+		return i18n.G("warning")
+	case 3:
+		return i18n.G("urgent")
+	}
+	return i18n.G("undefined")
+}
+
+func diskHealthNotes(health *disks.MediaHealth) string {
+	switch {
+	case health.EMMC != nil:
+		return fmt.Sprintf(i18n.G("life time used A: %s, B: %s, pre-EOL: %s"),
+			emmcLifeTime(health.EMMC.LifeTimeEstimateA),
+			emmcLifeTime(health.EMMC.LifeTimeEstimateB),
+			emmcPreEOL(health.EMMC.PreEOL))
+	case health.NVMe != nil:
+		return fmt.Sprintf(i18n.G("used: %d%%, spare: %d%%, media errors: %d, critical warning: 0x%02x"),
+			health.NVMe.PercentageUsed, health.NVMe.AvailableSpare,
+			health.NVMe.MediaErrors, health.NVMe.CriticalWarning)
+	}
+	return health.Reason
+}
+
+func (x *cmdDebugDiskHealth) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	esc := x.getEscapes()
+
+	var health []*disks.MediaHealth
+	if err := x.client.DebugGet("disk-health", &health, nil); err != nil {
+		return err
+	}
+
+	orDash := func(s string) string {
+		if s == "" {
+			return esc.dash
+		}
+		return s
+	}
+
+	w := tabWriter()
+	fmt.Fprintln(w, i18n.G("Device\tMedia\tStatus\tNotes"))
+	for _, h := range health {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", h.Device, orDash(h.Media), h.Status, orDash(diskHealthNotes(h)))
+	}
+	w.Flush()
+
+	return nil
+}
@@ -0,0 +1,67 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestDebugDiskHealth(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.RawQuery, check.Equals, "aspect=disk-health")
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+  {"device": "/dev/mmcblk0", "media": "emmc", "status": "warning", "emmc": {"life-time-estimate-a": 9, "life-time-estimate-b": 11, "pre-eol": 2}},
+  {"device": "/dev/nvme0n1", "media": "nvme", "status": "ok", "nvme": {"critical-warning": 0, "temperature": 310, "available-spare": 100, "available-spare-threshold": 10, "percentage-used": 3, "power-on-hours": 1234, "media-errors": 0}},
+  {"device": "/dev/nvme1n1", "media": "nvme", "status": "unavailable", "reason": "cannot read NVMe SMART log: permission denied"},
+  {"device": "/dev/vda", "status": "unavailable", "reason": "unsupported media"}
+]}`)
+		default:
+			c.Fatalf("expected to get 1 request, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "disk-health"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `
+Device        Media  Status       Notes
+/dev/mmcblk0  emmc   warning      life time used A: 80-90%, B: exceeded, pre-EOL: warning
+/dev/nvme0n1  nvme   ok           used: 3%, spare: 100%, media errors: 0, critical warning: 0x00
+/dev/nvme1n1  nvme   unavailable  cannot read NVMe SMART log: permission denied
+/dev/vda      --     unavailable  unsupported media
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugDiskHealthExtraArgs(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "disk-health", "extra"})
+	c.Assert(err, check.ErrorMatches, "too many arguments for command")
+}
@@ -23,10 +23,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/interfaces"
 )
 
@@ -64,6 +66,8 @@ func (x *cmdSeeding) Execute(args []string) error {
 		SeedRestartSystemKey *json.RawMessage `json:"seed-restart-system-key,omitempty"`
 
 		SeedError string `json:"seed-error,omitempty"`
+
+		DiskHealth []client.DiskHealth `json:"disk-health,omitempty"`
 	}
 	if err := x.client.DebugGet("seeding", &resp, nil); err != nil {
 		return err
@@ -111,6 +115,14 @@ func (x *cmdSeeding) Execute(args []string) error {
 	}
 	fmt.Fprintf(w, "seed-completion:\t%s\n", seedDuration)
 
+	if len(resp.DiskHealth) > 0 {
+		diskHealth := make([]string, 0, len(resp.DiskHealth))
+		for _, h := range resp.DiskHealth {
+			diskHealth = append(diskHealth, fmt.Sprintf("%s: %s", h.Device, h.Status))
+		}
+		fmt.Fprintf(w, "disk-health:\t%s\n", strings.Join(diskHealth, ", "))
+	}
+
 	// we flush the tabwriter now because if we have more output, it will be
 	// the system keys, which are JSON and thus will never display cleanly in
 	// line with the other keys we did above
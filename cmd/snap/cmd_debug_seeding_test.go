@@ -268,6 +268,19 @@ var stillSeedingNoPreseed = `{
     "type": "sync"
 }`
 
+var seededWithDiskHealth = `{
+    "result": {
+        "seeded": true,
+        "disk-health": [
+            {"device": "/dev/mmcblk0", "media": "emmc", "status": "warning", "emmc": {"life-time-estimate-a": 9, "life-time-estimate-b": 2, "pre-eol": 1}},
+            {"device": "/dev/sda", "status": "unavailable", "reason": "unsupported media"}
+        ]
+    },
+    "status": "OK",
+    "status-code": 200,
+    "type": "sync"
+}`
+
 func (s *SnapSuite) TestDebugSeeding(c *C) {
 	tt := []struct {
 		jsonResp   string
@@ -451,6 +464,16 @@ seed-completion:   --
 `[1:],
 			comment: "preseeded, error during seeding",
 		},
+		{
+			jsonResp: seededWithDiskHealth,
+			expStdout: `
+seeded:           true
+preseeded:        false
+seed-completion:  --
+disk-health:      /dev/mmcblk0: warning, /dev/sda: unavailable
+`[1:],
+			comment: "with disk health",
+		},
 	}
 
 	for _, t := range tt {
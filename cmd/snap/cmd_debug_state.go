@@ -34,6 +34,7 @@ import (
 
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/ifacestate/schema"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/strutil"
@@ -52,6 +53,8 @@ type cmdDebugState struct {
 
 	IsSeeded bool `long:"is-seeded"`
 
+	SizeBreakdown bool `long:"size-breakdown"`
+
 	// flags for --change=N output
 	DotOutput bool `long:"dot"` // XXX: mildly useful (too crowded in many cases), but let's have it just in case
 	// When inspecting errors/undone tasks, those in Hold state are usually irrelevant, make it possible to ignore them
@@ -81,6 +84,12 @@ func loadState(path string) (*state.State, error) {
 	}
 	defer r.Close()
 
+	// changes which were not folded into the state file yet are only
+	// recorded in its write-ahead log, which is not replayed here
+	if osutil.FileExists(path + ".wal") {
+		fmt.Fprintf(Stderr, i18n.G("WARNING: the state write-ahead log %s is present, recent changes to the state are not shown\n"), path+".wal")
+	}
+
 	return state.ReadState(nil, r)
 }
 
@@ -89,15 +98,16 @@ func init() {
 		return &cmdDebugState{}
 	}, timeDescs.also(map[string]string{
 		// TRANSLATORS: This should not start with a lowercase letter.
-		"change":      i18n.G("ID of the change to inspect"),
-		"task":        i18n.G("ID of the task to inspect"),
-		"dot":         i18n.G("Dot (graphviz) output"),
-		"no-hold":     i18n.G("Omit tasks in 'Hold' state in the change output"),
-		"changes":     i18n.G("List all changes"),
-		"connections": i18n.G("List all connections"),
-		"connection":  i18n.G("Show details of the matching connections (snap or snap:plug,snap:slot or snap:plug-or-slot"),
-		"is-seeded":   i18n.G("Output seeding status (true or false)"),
-		"check":       i18n.G("Check change consistency"),
+		"change":         i18n.G("ID of the change to inspect"),
+		"task":           i18n.G("ID of the task to inspect"),
+		"dot":            i18n.G("Dot (graphviz) output"),
+		"no-hold":        i18n.G("Omit tasks in 'Hold' state in the change output"),
+		"changes":        i18n.G("List all changes"),
+		"connections":    i18n.G("List all connections"),
+		"connection":     i18n.G("Show details of the matching connections (snap or snap:plug,snap:slot or snap:plug-or-slot"),
+		"is-seeded":      i18n.G("Output seeding status (true or false)"),
+		"check":          i18n.G("Check change consistency"),
+		"size-breakdown": i18n.G("Show the changes and state keys taking up the most space"),
 	}), nil)
 }
 
@@ -302,6 +312,60 @@ func (c *cmdDebugState) showIsSeeded(st *state.State) error {
 	return nil
 }
 
+// number of top contributors listed by --size-breakdown
+const sizeBreakdownTop = 10
+
+func (c *cmdDebugState) showSizeBreakdown(st *state.State) error {
+	st.Lock()
+	defer st.Unlock()
+
+	breakdown, err := st.SizeBreakdown()
+	if err != nil {
+		return err
+	}
+	changesTotal := 0
+	for _, chg := range breakdown.Changes {
+		changesTotal += chg.Size
+	}
+	fmt.Fprintf(Stdout, "Total: %s (changes: %s, orphan tasks: %s, warnings: %s)\n",
+		strutil.SizeToStr(int64(breakdown.Total)),
+		strutil.SizeToStr(int64(changesTotal)),
+		strutil.SizeToStr(int64(breakdown.OrphanTasks)),
+		strutil.SizeToStr(int64(breakdown.Warnings)))
+
+	changes := breakdown.Changes
+	if len(changes) > sizeBreakdownTop {
+		changes = changes[:sizeBreakdownTop]
+	}
+	fmt.Fprintf(Stdout, "\n")
+	w := tabwriter.NewWriter(Stdout, 5, 3, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\tStatus\tTasks\tSize\tLabel\tSummary\n")
+	for _, chg := range changes {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n",
+			chg.ID,
+			chg.Status.String(),
+			chg.Tasks,
+			strutil.SizeToStr(int64(chg.Size)),
+			chg.Kind,
+			chg.Summary)
+	}
+	w.Flush()
+
+	keys := breakdown.Keys
+	if len(keys) > sizeBreakdownTop {
+		keys = keys[:sizeBreakdownTop]
+	}
+	fmt.Fprintf(Stdout, "\n")
+	w = tabwriter.NewWriter(Stdout, 5, 3, 2, ' ', 0)
+	fmt.Fprintf(w, "Key\tSize\n")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\n", k.Key, strutil.SizeToStr(int64(k.Size)))
+	}
+	w.Flush()
+
+	return nil
+}
+
 type connectionInfo struct {
 	PlugSnap string
 	PlugName string
@@ -520,6 +584,9 @@ func (c *cmdDebugState) Execute(args []string) error {
 	if c.Connections {
 		cmds = append(cmds, "--connections")
 	}
+	if c.SizeBreakdown {
+		cmds = append(cmds, "--size-breakdown")
+	}
 	if len(cmds) > 1 {
 		return fmt.Errorf("cannot use %s and %s together", cmds[0], cmds[1])
 	}
@@ -528,6 +595,10 @@ func (c *cmdDebugState) Execute(args []string) error {
 		return c.showIsSeeded(st)
 	}
 
+	if c.SizeBreakdown {
+		return c.showSizeBreakdown(st)
+	}
+
 	if c.DotOutput && c.ChangeID == "" {
 		return fmt.Errorf("--dot can only be used with --change=")
 	}
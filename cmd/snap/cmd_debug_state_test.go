@@ -241,6 +241,39 @@ func (s *SnapSuite) TestDebugChangesMissingState(c *C) {
 	c.Check(err, ErrorMatches, "cannot read the state file: open /missing-state.json: no such file or directory")
 }
 
+func (s *SnapSuite) TestDebugChangesWithStateWAL(c *C) {
+	dir := c.MkDir()
+	stateFile := filepath.Join(dir, "test-state.json")
+	c.Assert(os.WriteFile(stateFile, stateJSON, 0644), IsNil)
+	c.Assert(os.WriteFile(stateFile+".wal", nil, 0644), IsNil)
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--abs-time", "--changes", stateFile})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Matches, "(?s)ID   Status  Spawn.*")
+	c.Check(s.Stderr(), Equals, "WARNING: the state write-ahead log "+stateFile+".wal is present, recent changes to the state are not shown\n")
+}
+
+func (s *SnapSuite) TestDebugSizeBreakdown(c *C) {
+	dir := c.MkDir()
+	stateFile := filepath.Join(dir, "test-state.json")
+	c.Assert(os.WriteFile(stateFile, stateJSON, 0644), IsNil)
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--size-breakdown", stateFile})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, "Total: 1kB (changes: 1kB, orphan tasks: 0B, warnings: 0B)\n"+
+		"\n"+
+		"ID   Status  Tasks  Size  Label         Summary\n"+
+		"10   Done    2      673B  revert-snap   revert c snap\n"+
+		"9    Do      2      513B  install-snap  install a snap\n"+
+		"\n"+
+		"Key     Size\n"+
+		"seeded  4B\n"+
+		"snaps   2B\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
 func (s *SnapSuite) TestDebugTask(c *C) {
 	dir := c.MkDir()
 	stateFile := filepath.Join(dir, "test-state.json")
@@ -308,6 +341,9 @@ func (s *SnapSuite) TestDebugTaskMutuallyExclusiveCommands(c *C) {
 
 	_, err = main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--change=1", "--is-seeded", stateFile})
 	c.Check(err, ErrorMatches, "cannot use --change= and --is-seeded together")
+
+	_, err = main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--changes", "--size-breakdown", stateFile})
+	c.Check(err, ErrorMatches, "cannot use --changes and --size-breakdown together")
 }
 
 func (s *SnapSuite) TestDebugTasks(c *C) {
@@ -0,0 +1,88 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDebugVolumes struct {
+	clientMixin
+	unicodeMixin
+}
+
+func init() {
+	cmd := addDebugCommand("volumes",
+		"(internal) show how disk partitions map to gadget structures",
+		"(internal) show how disk partitions map to gadget structures",
+		func() flags.Commander {
+			return &cmdDebugVolumes{}
+		}, nil, nil)
+	cmd.hidden = true
+}
+
+func (x *cmdDebugVolumes) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	esc := x.getEscapes()
+
+	vols, err := x.client.Volumes()
+	if err != nil {
+		return err
+	}
+
+	orDash := func(s string) string {
+		if s == "" {
+			return esc.dash
+		}
+		return s
+	}
+
+	w := tabWriter()
+	fmt.Fprintln(w, i18n.G("Volume\tDevice\tPartition UUID\tStructure\tRole\tFilesystem\tSize\tNotes"))
+	for _, vol := range vols {
+		if vol.Device == "" {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", vol.Name,
+				esc.dash, esc.dash, esc.dash, esc.dash, esc.dash, esc.dash, "disk-not-found")
+			continue
+		}
+		for _, s := range vol.Structure {
+			var notes []string
+			if s.Encrypted {
+				notes = append(notes, "encrypted")
+			}
+			if s.Unmapped {
+				notes = append(notes, "unmapped")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", vol.Name,
+				s.Node, orDash(s.PartitionUUID), orDash(s.StructureName), orDash(s.Role),
+				orDash(s.Filesystem), s.Size.IECString(), orDash(strings.Join(notes, ",")))
+		}
+	}
+	w.Flush()
+
+	return nil
+}
@@ -0,0 +1,68 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestDebugVolumes(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/volumes")
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+  {"name": "data-mirror"},
+  {"name": "pc", "device": "/dev/vda", "schema": "gpt", "size": 10737418240, "structure": [
+    {"node": "/dev/vda1", "partition-uuid": "C5A930DF-E86A-4BAE-A4C5-C861353796E6", "partition-label": "ubuntu-seed", "filesystem": "vfat", "offset": 1048576, "size": 1258291200, "structure-name": "ubuntu-seed", "role": "system-seed"},
+    {"node": "/dev/vda2", "partition-uuid": "DA2ADBC8-90DF-4B1D-A93F-A92516C12E01", "partition-label": "ubuntu-data-enc", "filesystem": "crypto_LUKS", "offset": 1259339776, "size": 4294967296, "structure-name": "ubuntu-data", "role": "system-data", "encrypted": true},
+    {"node": "/dev/vda3", "offset": 5554307072, "size": 1073741824, "unmapped": true}
+  ]}
+]}`)
+		default:
+			c.Fatalf("expected to get 1 request, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "volumes"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `
+Volume       Device     Partition UUID                        Structure    Role         Filesystem   Size      Notes
+data-mirror  -          -                                     -            -            -            -         disk-not-found
+pc           /dev/vda1  C5A930DF-E86A-4BAE-A4C5-C861353796E6  ubuntu-seed  system-seed  vfat         1.17 GiB  -
+pc           /dev/vda2  DA2ADBC8-90DF-4B1D-A93F-A92516C12E01  ubuntu-data  system-data  crypto_LUKS  4 GiB     encrypted
+pc           /dev/vda3  -                                     -            -            -            1 GiB     unmapped
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugVolumesExtraArgs(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "volumes", "extra"})
+	c.Assert(err, check.ErrorMatches, "too many arguments for command")
+}
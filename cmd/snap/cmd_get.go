@@ -48,10 +48,16 @@ Nested values may be retrieved via a dotted path:
 
     $ snap get snap-name author.name
     frank
+
+With --history, the recent changes of the provided options, or of all
+options if none is provided, are printed instead, together with their
+origin and previous values. Values of options whose name contains
+"password", "passphrase", "secret" or "token" are not recorded.
 `)
 
 type cmdGet struct {
 	clientMixin
+	timeMixin
 	Positional struct {
 		Snap installedSnapName `required:"yes"`
 		Keys []string
@@ -60,18 +66,21 @@ type cmdGet struct {
 	Typed    bool `short:"t"`
 	Document bool `short:"d"`
 	List     bool `short:"l"`
+	History  bool `long:"history"`
 }
 
 func init() {
 	addCommand("get", shortGetHelp, longGetHelp, func() flags.Commander { return &cmdGet{} },
-		map[string]string{
+		timeDescs.also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"d": i18n.G("Always return document, even with single key"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"l": i18n.G("Always return list, even with single key"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"t": i18n.G("Strict typing with nulls and quoted strings"),
-		}, []argDesc{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"history": i18n.G("Show the recent changes of the configuration"),
+		}), []argDesc{
 			{
 				name: "<snap>",
 				// TRANSLATORS: This should not start with a lowercase letter.
@@ -225,6 +234,41 @@ func (x *cmdGet) outputDefault(conf map[string]interface{}, snapName string, con
 
 }
 
+// outputHistory will be used when the user requested the history of
+// configuration changes via the "--history" commandline switch.
+func (x *cmdGet) outputHistory(snapName string, confKeys []string) error {
+	history, err := x.client.ConfHistory(snapName, confKeys)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		fmt.Fprintf(Stderr, i18n.G("No configuration changes recorded for snap %q.\n"), snapName)
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintf(w, i18n.G("Time\tOrigin\tKey\tOld\tNew\n"))
+	for _, entry := range history {
+		for _, change := range entry.Changes {
+			old, new := change.Old, change.New
+			if change.Redacted {
+				old, new = i18n.G("(redacted)"), i18n.G("(redacted)")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", x.fmtTime(entry.Time), entry.Origin, change.Key, fmtHistoryValue(old), fmtHistoryValue(new))
+		}
+	}
+	return nil
+}
+
+func fmtHistoryValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
 func (x *cmdGet) Execute(args []string) error {
 	if len(args) > 0 {
 		// TRANSLATORS: the %s is the list of extra arguments
@@ -242,6 +286,13 @@ func (x *cmdGet) Execute(args []string) error {
 	snapName := string(x.Positional.Snap)
 	confKeys := x.Positional.Keys
 
+	if x.History {
+		if x.Document || x.Typed || x.List {
+			return fmt.Errorf("cannot use --history with -d, -l or -t")
+		}
+		return x.outputHistory(snapName, confKeys)
+	}
+
 	conf, err := x.client.Conf(snapName, confKeys)
 	if err != nil {
 		return err
@@ -144,6 +144,45 @@ func (s *SnapSuite) TestSnapGetNoConfiguration(c *C) {
 	s.runTests(getNoConfigTests, c)
 }
 
+var getHistoryTests = []getCmdArgs{{
+	args: "get --history --abs-time snapname test-key1",
+	stdout: "Time                  Origin           Key        Old         New\n" +
+		"2023-05-01T10:00:00Z  gadget-defaults  test-key1  -           \"default\"\n" +
+		"2023-05-01T11:00:00Z  api              test-key1  \"default\"   \"test-value1\"\n" +
+		"2023-05-01T11:00:00Z  api              password   (redacted)  (redacted)\n",
+}, {
+	args:   "get --history snapname missing-key",
+	stderr: "No configuration changes recorded for snap \"snapname\".\n",
+}, {
+	args:  "get --history -d snapname",
+	error: "cannot use --history with -d, -l or -t",
+}}
+
+func (s *SnapSuite) TestSnapGetHistory(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v2/snaps/snapname/conf")
+		c.Check(r.Method, Equals, "GET")
+
+		query := r.URL.Query()
+		c.Check(query.Get("history"), Equals, "true")
+		switch query.Get("keys") {
+		case "test-key1":
+			fmt.Fprintln(w, `{"type":"sync", "status-code": 200, "result": [
+				{"time": "2023-05-01T10:00:00Z", "origin": "gadget-defaults", "changes": [{"key": "test-key1", "new": "\"default\""}]},
+				{"time": "2023-05-01T11:00:00Z", "origin": "api", "changes": [
+					{"key": "test-key1", "old": "\"default\"", "new": "\"test-value1\""},
+					{"key": "password", "redacted": true}
+				]}
+			]}`)
+		case "missing-key":
+			fmt.Fprintln(w, `{"type":"sync", "status-code": 200, "result": []}`)
+		default:
+			c.Errorf("unexpected keys %q", query.Get("keys"))
+		}
+	})
+	s.runTests(getHistoryTests, c)
+}
+
 func (s *SnapSuite) TestSortByPath(c *C) {
 	values := []snapset.ConfigValue{
 		{Path: "test-key3.b"},
@@ -0,0 +1,105 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+var shortHealthHelp = i18n.G("Show the health of installed snaps")
+var longHealthHelp = i18n.G(`
+The health command shows the health last reported by the check-health hook
+of installed snaps, or of the given snaps.
+
+For each snap it shows when the health was last checked, since when the snap
+is in its current status, and the code and message the snap reported.
+`)
+
+type cmdHealth struct {
+	clientMixin
+	timeMixin
+	Positional struct {
+		Snaps []installedSnapName `positional-arg-name:"<snap>"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	addCommand("health", shortHealthHelp, longHealthHelp, func() flags.Commander { return &cmdHealth{} },
+		timeDescs, nil)
+}
+
+func (x *cmdHealth) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	names := installedSnapNames(x.Positional.Snaps)
+	snaps, err := x.client.List(names, nil)
+	if err != nil {
+		if err == client.ErrNoSnapsInstalled {
+			if len(names) == 0 {
+				fmt.Fprintln(Stderr, i18n.G("No snaps are installed yet."))
+				return nil
+			}
+			return ErrNoMatchingSnaps
+		}
+		return err
+	} else if len(snaps) == 0 {
+		return ErrNoMatchingSnaps
+	}
+	sort.Sort(snapsByName(snaps))
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Snap\tStatus\tSince\tChecked\tCode\tMessage"))
+	for _, snap := range snaps {
+		health := snap.Health
+		if health == nil {
+			fmt.Fprintf(w, "%s\tunknown\t-\t-\t-\t%s\n", snap.Name, i18n.G("health has not been set"))
+			continue
+		}
+		since := "-"
+		if !health.LastTransition.IsZero() {
+			since = x.fmtTime(health.LastTransition)
+		}
+		checked := "-"
+		if !health.Timestamp.IsZero() {
+			checked = x.fmtTime(health.Timestamp)
+		}
+		code := "-"
+		if health.Code != "" {
+			code = health.Code
+		}
+		message := "-"
+		if health.Message != "" {
+			message = health.Message
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", snap.Name, health.Status, since, checked, code, message)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestHealth(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+{"name": "foo", "health": {"revision": "1", "status": "error", "timestamp": "2026-05-01T10:30:00Z", "last-transition": "2026-05-01T09:00:00Z", "code": "db-gone", "message": "database is gone"}},
+{"name": "bar"},
+{"name": "baz", "health": {"revision": "3", "status": "okay", "timestamp": "2026-05-01T10:00:00Z", "last-transition": "2026-04-01T10:00:00Z"}}
+]}`)
+		default:
+			c.Fatalf("expected to get 1 request, now on %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"health", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `
+Snap  Status   Since                 Checked               Code     Message
+bar   unknown  -                     -                     -        health has not been set
+baz   okay     2026-04-01T10:00:00Z  2026-05-01T10:00:00Z  -        -
+foo   error    2026-05-01T09:00:00Z  2026-05-01T10:30:00Z  db-gone  database is gone
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestHealthSnaps(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		c.Check(r.URL.Query().Get("snaps"), check.Equals, "foo")
+		fmt.Fprintln(w, `{"type": "sync", "result": [{"name": "foo", "health": {"revision": "1", "status": "waiting", "timestamp": "2026-05-01T10:30:00Z", "last-transition": "2026-05-01T10:30:00Z"}}]}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"health", "--abs-time", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `
+Snap  Status   Since                 Checked               Code  Message
+foo   waiting  2026-05-01T10:30:00Z  2026-05-01T10:30:00Z  -     -
+`[1:])
+}
+
+func (s *SnapSuite) TestHealthNoMatchingSnaps(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"health", "foo"})
+	c.Assert(err, check.ErrorMatches, "no matching snaps installed")
+}
@@ -215,7 +215,7 @@ var helpCategories = []helpCategory{
 	}, {
 		Label:       i18n.G("Daemons"),
 		Description: i18n.G("manage services"),
-		Commands:    []string{"services", "start", "stop", "restart", "logs"},
+		Commands:    []string{"services", "start", "stop", "restart", "logs", "health"},
 	}, {
 		Label:       i18n.G("Permissions"),
 		Description: i18n.G("manage permissions"),
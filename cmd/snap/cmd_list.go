@@ -48,11 +48,12 @@ type cmdList struct {
 
 	All bool `long:"all"`
 	colorMixin
+	jsonMixin
 }
 
 func init() {
 	addCommand("list", shortListHelp, longListHelp, func() flags.Commander { return &cmdList{} },
-		colorDescs.also(map[string]string{
+		colorDescs.also(jsonDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"all": i18n.G("Show all revisions"),
 		}), nil)
@@ -102,6 +103,9 @@ func (x *cmdList) Execute(args []string) error {
 	if err != nil {
 		if err == client.ErrNoSnapsInstalled {
 			if len(names) == 0 {
+				if x.JSON {
+					return x.printJSON([]*client.Snap{})
+				}
 				fmt.Fprintln(Stderr, i18n.G("No snaps are installed yet. Try 'snap install hello-world'."))
 				return nil
 			} else {
@@ -114,6 +118,10 @@ func (x *cmdList) Execute(args []string) error {
 	}
 	sort.Sort(snapsByName(snaps))
 
+	if x.JSON {
+		return x.printJSON(snaps)
+	}
+
 	esc := x.getEscapes()
 	w := tabWriter()
 
@@ -20,12 +20,14 @@
 package main_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 
 	"gopkg.in/check.v1"
 
 	snap "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/testutil"
 )
 
 func (s *SnapSuite) TestListHelp(c *check.C) {
@@ -43,6 +45,7 @@ indicates that the publisher has been verified.
                                       some things. (default: auto)
       --unicode=[auto|never|always]   Use a little bit of Unicode to improve
                                       legibility. (default: auto)
+      --json                          Output results in JSON format
 `
 	s.testSubCommandHelp(c, "list", msg)
 }
@@ -82,6 +85,48 @@ foo   4.2      17   potatoes  bar        blocked
 	c.Check(s.Stderr(), check.Equals, "")
 }
 
+func (s *SnapSuite) TestListJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		fmt.Fprintln(w, `{"type": "sync", "result": [
+{"name": "foo", "status": "active", "version": "4.2", "revision": 17, "tracking-channel": "potatoes"},
+{"name": "bar", "status": "active", "version": "1.0", "revision": 1, "publisher": {"id": "bar-id", "username": "bar", "display-name": "Bär", "validation": "verified"}}
+]}`)
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"list", "--json"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	var snaps []map[string]interface{}
+	c.Assert(json.Unmarshal([]byte(s.Stdout()), &snaps), check.IsNil)
+	c.Assert(snaps, check.HasLen, 2)
+	// sorted by name
+	c.Check(snaps[0]["name"], check.Equals, "bar")
+	c.Check(snaps[0]["version"], check.Equals, "1.0")
+	c.Check(snaps[0]["revision"], check.Equals, "1")
+	c.Check(snaps[0]["publisher"], check.DeepEquals, map[string]interface{}{
+		"id":           "bar-id",
+		"username":     "bar",
+		"display-name": "Bär",
+		"validation":   "verified",
+	})
+	c.Check(snaps[1]["name"], check.Equals, "foo")
+	c.Check(snaps[1]["tracking-channel"], check.Equals, "potatoes")
+	// unicode is output as is
+	c.Check(s.Stdout(), testutil.Contains, `"display-name": "Bär"`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestListJSONEmpty(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"list", "--json"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "[]\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
 func (s *SnapSuite) TestListAll(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
@@ -25,6 +25,7 @@ import (
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/client/clientutil"
 	"github.com/snapcore/snapd/i18n"
@@ -77,6 +78,7 @@ type cmdModel struct {
 	clientMixin
 	timeMixin
 	colorMixin
+	jsonMixin
 
 	Serial    bool `long:"serial"`
 	Verbose   bool `long:"verbose"`
@@ -89,7 +91,7 @@ func init() {
 		longModelHelp,
 		func() flags.Commander {
 			return &cmdModel{}
-		}, colorDescs.also(timeDescs).also(map[string]string{
+		}, colorDescs.also(timeDescs).also(jsonDescs).also(map[string]string{
 			"assertion": i18n.G("Print the raw assertion."),
 			"verbose":   i18n.G("Print all specific assertion fields."),
 			"serial": i18n.G(
@@ -132,6 +134,10 @@ func (x *cmdModel) Execute(args []string) error {
 		}
 	}
 
+	if x.JSON {
+		return x.printModelJSON(modelAssertion, serialAssertion, serialErr)
+	}
+
 	termWidth, _ := termSize()
 	termWidth -= 3
 	if termWidth > 100 {
@@ -173,3 +179,22 @@ func (x *cmdModel) Execute(args []string) error {
 	}
 	return w.Flush()
 }
+
+func (x *cmdModel) printModelJSON(modelAssertion *asserts.Model, serialAssertion *asserts.Serial, serialErr error) error {
+	if x.Serial {
+		if client.IsAssertionNotFoundError(serialErr) {
+			return errNoSerial
+		}
+		serialJSON := clientutil.ModelAssertJSON{
+			Headers: serialAssertion.Headers(),
+		}
+		if x.Assertion {
+			serialJSON.Body = string(serialAssertion.Body())
+		}
+		return x.printJSON(serialJSON)
+	}
+	opts := clientutil.PrintModelAssertionOptions{
+		Assertion: x.Assertion,
+	}
+	return x.printJSON(clientutil.ModelAssertionJSONData(*modelAssertion, serialAssertion, opts))
+}
@@ -20,6 +20,7 @@
 package main_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -645,3 +646,71 @@ func (s *SnapSuite) TestSerialAssertionSerialAssertionMissing(c *check.C) {
 	c.Assert(s.Stdout(), check.Equals, "")
 	c.Assert(s.Stderr(), check.Equals, "")
 }
+
+func (s *SnapSuite) TestModelJSON(c *check.C) {
+	s.RedirectClientToTestServer(
+		makeHappyTestServerHandler(
+			c,
+			simpleHappyResponder(happyModelAssertionResponse),
+			simpleHappyResponder(happySerialAssertionResponse),
+			simpleAssertionAccountResponder(happyAccountAssertionResponse),
+		))
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"model", "--json"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `{
+  "architecture": "amd64",
+  "base": "core18",
+  "brand-id": "mememe",
+  "gadget": "pc=18",
+  "kernel": "pc-kernel=18",
+  "model": "test-model",
+  "required-snaps": [
+    "core",
+    "hello-world"
+  ],
+  "serial": "serialserial",
+  "store": "mememestore",
+  "system-user-authority": [
+    "youyouyou",
+    "mememe"
+  ],
+  "timestamp": "2017-07-27T00:00:00.0Z"
+}
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestSerialJSON(c *check.C) {
+	s.RedirectClientToTestServer(
+		makeHappyTestServerHandler(
+			c,
+			simpleHappyResponder(happyModelAssertionResponse),
+			simpleHappyResponder(happySerialAssertionResponse),
+			simpleAssertionAccountResponder(happyAccountAssertionResponse),
+		))
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"model", "--serial", "--json"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	var out map[string]interface{}
+	c.Assert(json.Unmarshal([]byte(s.Stdout()), &out), check.IsNil)
+	headers, ok := out["headers"].(map[string]interface{})
+	c.Assert(ok, check.Equals, true)
+	c.Check(headers["serial"], check.Equals, "serialserial")
+	c.Check(headers["brand-id"], check.Equals, "my-brand")
+	c.Check(out["body"], check.IsNil)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestSerialJSONNoSerial(c *check.C) {
+	s.RedirectClientToTestServer(
+		makeHappyTestServerHandler(
+			c,
+			simpleHappyResponder(happyModelAssertionResponse),
+			simpleUnhappyResponder(noSerialAssertionYetResponse),
+			simpleAssertionAccountResponder(happyAccountAssertionResponse),
+		))
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"model", "--serial", "--json"})
+	c.Assert(err, check.ErrorMatches, `device not registered yet \(no serial assertion found\)`)
+	c.Check(s.Stdout(), check.Equals, "")
+}
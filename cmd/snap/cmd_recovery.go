@@ -28,31 +28,57 @@ import (
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/strutil"
 )
 
 type cmdRecovery struct {
-	clientMixin
+	waitMixin
 	colorMixin
 
-	ShowKeys bool `long:"show-keys"`
+	ShowKeys    bool `long:"show-keys"`
+	Create      bool `long:"create"`
+	MarkDefault bool `long:"mark-default"`
+	TestSystem  bool `long:"test-system"`
+	Positional  struct {
+		Label string `positional-arg-name:"<label>"`
+	} `positional-args:"yes"`
 }
 
 var shortRecoveryHelp = i18n.G("List available recovery systems")
 var longRecoveryHelp = i18n.G(`
 The recovery command lists the available recovery systems.
 
+When a recovery system label is given, the snaps included in that recovery
+system are listed instead.
+
 With --show-keys it displays recovery keys that can be used to unlock the encrypted partitions if the device-specific automatic unlocking does not work.
+
+With --create it creates a new recovery system from the snaps currently
+installed on the device, using the given label or a label based on the
+current date. The new system can be made the default recovery system with
+--mark-default, and with --test-system the device reboots into it once to
+validate it.
 `)
 
 func init() {
 	addCommand("recovery", shortRecoveryHelp, longRecoveryHelp, func() flags.Commander {
-		// XXX: if we want more/nicer details we can add `snap recovery <system>` later
 		return &cmdRecovery{}
-	}, colorDescs.also(
+	}, colorDescs.also(waitDescs).also(
 		map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"show-keys": i18n.G("Show recovery keys (if available) to unlock encrypted partitions."),
-		}), nil)
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"create": i18n.G("Create a new recovery system from the installed snaps."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"mark-default": i18n.G("Make the new recovery system the default one (requires --create)."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"test-system": i18n.G("Reboot into the new recovery system once to validate it (requires --create)."),
+		}), []argDesc{{
+		// TRANSLATORS: This needs to begin with < and end with >
+		name: i18n.G("<label>"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("Recovery system to list the snaps of, or to create"),
+	}})
 }
 
 func notesForSystem(sys *client.System) string {
@@ -62,6 +88,44 @@ func notesForSystem(sys *client.System) string {
 	return "-"
 }
 
+func notesForSystemSnap(sn *client.SystemSnap) string {
+	var notes []string
+	if !sn.Asserted {
+		notes = append(notes, "unasserted")
+	}
+	if sn.Shared {
+		notes = append(notes, "shared")
+	}
+	if len(notes) == 0 {
+		return "-"
+	}
+	return strings.Join(notes, ",")
+}
+
+func (x *cmdRecovery) showSnaps(w io.Writer, label string) error {
+	snaps, err := x.client.SystemSnaps(label)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, i18n.G("Name\tRev\tChannel\tSize\tNotes"))
+	for _, sn := range snaps {
+		channel := sn.Channel
+		if channel == "" {
+			channel = "-"
+		}
+		line := []string{
+			sn.Name,
+			sn.Revision.String(),
+			channel,
+			strutil.SizeToStr(sn.Size),
+			notesForSystemSnap(&sn),
+		}
+		fmt.Fprintln(w, strings.Join(line, "\t"))
+	}
+	return nil
+}
+
 func (x *cmdRecovery) showKeys(w io.Writer) error {
 	var srk *client.SystemRecoveryKeysResponse
 	err := x.client.SystemRecoveryKeys(&srk)
@@ -75,18 +139,54 @@ func (x *cmdRecovery) showKeys(w io.Writer) error {
 	return nil
 }
 
+func (x *cmdRecovery) create(label string) error {
+	opts := &client.CreateSystemOptions{
+		Label:       label,
+		MarkDefault: x.MarkDefault,
+		TestSystem:  x.TestSystem,
+	}
+	changeID, err := x.client.CreateSystem(opts)
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(changeID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+	fmt.Fprintf(Stdout, i18n.G("Recovery system created in change %s\n"), changeID)
+	return nil
+}
+
 func (x *cmdRecovery) Execute(args []string) error {
 	if len(args) > 0 {
 		return ErrExtraArgs
 	}
 
+	if (x.MarkDefault || x.TestSystem) && !x.Create {
+		return fmt.Errorf(i18n.G("cannot use --mark-default or --test-system without --create"))
+	}
+	if x.Create {
+		if x.ShowKeys {
+			return fmt.Errorf(i18n.G("cannot use --show-keys with --create"))
+		}
+		return x.create(x.Positional.Label)
+	}
+
 	esc := x.getEscapes()
 	w := tabWriter()
 	defer w.Flush()
 
 	if x.ShowKeys {
+		if x.Positional.Label != "" {
+			return fmt.Errorf(i18n.G("cannot use --show-keys with a recovery system label"))
+		}
 		return x.showKeys(w)
 	}
+	if x.Positional.Label != "" {
+		return x.showSnaps(w, x.Positional.Label)
+	}
 
 	systems, err := x.client.ListSystems()
 	if err != nil {
@@ -31,20 +31,41 @@ import (
 
 func (s *SnapSuite) TestRecoveryHelp(c *C) {
 	msg := `Usage:
-  snap.test recovery [recovery-OPTIONS]
+  snap.test recovery [recovery-OPTIONS] [<label>]
 
 The recovery command lists the available recovery systems.
 
+When a recovery system label is given, the snaps included in that recovery
+system are listed instead.
+
 With --show-keys it displays recovery keys that can be used to unlock the
 encrypted partitions if the device-specific automatic unlocking does not work.
 
+With --create it creates a new recovery system from the snaps currently
+installed on the device, using the given label or a label based on the
+current date. The new system can be made the default recovery system with
+--mark-default, and with --test-system the device reboots into it once to
+validate it.
+
 [recovery command options]
+      --no-wait                       Do not wait for the operation to finish
+                                      but just print the change id.
       --color=[auto|never|always]     Use a little bit of color to highlight
                                       some things. (default: auto)
       --unicode=[auto|never|always]   Use a little bit of Unicode to improve
                                       legibility. (default: auto)
       --show-keys                     Show recovery keys (if available) to
                                       unlock encrypted partitions.
+      --create                        Create a new recovery system from the
+                                      installed snaps.
+      --mark-default                  Make the new recovery system the default
+                                      one (requires --create).
+      --test-system                   Reboot into the new recovery system once
+                                      to validate it (requires --create).
+
+[recovery command arguments]
+  <label>:                            Recovery system to list the snaps of, or
+                                      to create
 `
 	s.testSubCommandHelp(c, "recovery", msg)
 }
@@ -113,6 +134,118 @@ Label     Brand    Model       Notes
 	c.Check(s.Stderr(), Equals, "")
 }
 
+func (s *SnapSuite) TestRecoverySystemSnaps(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, Equals, "GET")
+			c.Check(r.URL.Path, Equals, "/v2/systems/20200101/snaps")
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+    {"name": "snapd", "revision": "10", "channel": "latest/stable", "asserted": true, "size": 31000000, "shared": true},
+    {"name": "pc-kernel", "revision": "12", "channel": "20/stable", "asserted": true, "size": 250000000},
+    {"name": "local", "revision": "x1", "size": 4096}
+]}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "20200101"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, `
+Name       Rev  Channel        Size   Notes
+snapd      10   latest/stable  31MB   shared
+pc-kernel  12   20/stable      250MB  -
+local      x1   -              4kB    unasserted
+`[1:])
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestRecoverySystemSnapsShowKeys(c *C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--show-keys", "20200101"})
+	c.Assert(err, ErrorMatches, "cannot use --show-keys with a recovery system label")
+}
+
+func (s *SnapSuite) TestRecoveryCreate(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/systems")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+				"action":       "create",
+				"label":        "1234",
+				"mark-default": true,
+				"test-system":  true,
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type": "async", "status-code": 202, "change": "42"}`)
+		case 1:
+			c.Check(r.Method, Equals, "GET")
+			c.Check(r.URL.Path, Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--create", "--mark-default", "--test-system", "1234"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(n, Equals, 2)
+	c.Check(s.Stdout(), Equals, "Recovery system created in change 42\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestRecoveryCreateNoLabelNoWait(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/systems")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+				"action": "create",
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type": "async", "status-code": 202, "change": "42"}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--create", "--no-wait"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(n, Equals, 1)
+	c.Check(s.Stdout(), Equals, "42\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestRecoveryCreateUnsupported(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 400, "result": {"message": "cannot create recovery system: creating recovery systems is not supported on this system"}}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--create"})
+	c.Assert(err, ErrorMatches, "cannot request recovery system creation: cannot create recovery system: creating recovery systems is not supported on this system")
+}
+
+func (s *SnapSuite) TestRecoveryCreateBadFlags(c *C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--mark-default"})
+	c.Assert(err, ErrorMatches, "cannot use --mark-default or --test-system without --create")
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--test-system", "1234"})
+	c.Assert(err, ErrorMatches, "cannot use --mark-default or --test-system without --create")
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--create", "--show-keys"})
+	c.Assert(err, ErrorMatches, "cannot use --show-keys with --create")
+}
+
 func (s *SnapSuite) TestNoRecoverySystems(c *C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
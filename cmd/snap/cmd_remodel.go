@@ -22,6 +22,7 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 
 	"github.com/jessevdk/go-flags"
 
@@ -41,6 +42,11 @@ Snaps and assertions are downloaded from the store unless they are provided as
 local files specified by --snap and --assertion options. If using these
 options, it is expected that all the needed snaps and assertions are provided
 locally, otherwise the remodel will fail.
+
+With --offline the store is never contacted. If no --snap or --assertion
+options are given, the *.snap and *.assert files found in the same directory
+as the new model file are used, as when the model and the files it needs are
+copied together to a removable drive.
 `)
 )
 
@@ -48,6 +54,7 @@ type cmdRemodel struct {
 	waitMixin
 	SnapFiles      []string `long:"snap"`
 	AssertionFiles []string `long:"assertion"`
+	Offline        bool     `long:"offline"`
 	RemodelOptions struct {
 		NewModelFile flags.Filename
 	} `positional-args:"true" required:"true"`
@@ -63,6 +70,7 @@ func init() {
 		waitDescs.also(map[string]string{
 			"snap":      i18n.G("Use one or more locally available snaps."),
 			"assertion": i18n.G("Use one or more locally available assertion files."),
+			"offline":   i18n.G("Do not contact the store, only use installed or locally available snaps."),
 		}),
 		[]argDesc{{
 			// TRANSLATORS: This needs to begin with < and end with >
@@ -82,11 +90,19 @@ func (x *cmdRemodel) Execute(args []string) error {
 		return err
 	}
 
+	snapFiles, assertionFiles := x.SnapFiles, x.AssertionFiles
+	if x.Offline && len(snapFiles) == 0 && len(assertionFiles) == 0 {
+		snapFiles, assertionFiles, err = filesNextToModel(string(newModelFile))
+		if err != nil {
+			return err
+		}
+	}
+
 	var changeID string
-	if len(x.SnapFiles) > 0 || len(x.AssertionFiles) > 0 {
+	if x.Offline || len(snapFiles) > 0 || len(assertionFiles) > 0 {
 		// don't log the request's body as it will be large
 		x.client.SetMayLogBody(false)
-		changeID, err = x.client.RemodelOffline(modelData, x.SnapFiles, x.AssertionFiles)
+		changeID, err = x.client.RemodelOffline(modelData, snapFiles, assertionFiles)
 		if err != nil {
 			return fmt.Errorf("cannot do offline remodel: %v", err)
 		}
@@ -106,3 +122,24 @@ func (x *cmdRemodel) Execute(args []string) error {
 	fmt.Fprintf(Stdout, i18n.G("New model %s set\n"), newModelFile)
 	return nil
 }
+
+// filesNextToModel returns the snap and assertion files found in the
+// directory of the given model file, excluding the model file itself.
+func filesNextToModel(modelFile string) (snapFiles, assertionFiles []string, err error) {
+	dir := filepath.Dir(modelFile)
+	snapFiles, err = filepath.Glob(filepath.Join(dir, "*.snap"))
+	if err != nil {
+		return nil, nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*.assert"))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, m := range matches {
+		if m == filepath.Clean(modelFile) {
+			continue
+		}
+		assertionFiles = append(assertionFiles, m)
+	}
+	return snapFiles, assertionFiles, nil
+}
@@ -107,3 +107,63 @@ func (s *SnapSuite) TestRemodelOfflineError(c *C) {
 
 	s.ResetStdStreams()
 }
+
+func (s *SnapSuite) TestRemodelOfflineFilesNextToModel(c *C) {
+	n := 0
+
+	dir := filepath.Join(dirs.GlobalRootDir, "media")
+	c.Assert(os.MkdirAll(dir, 0755), IsNil)
+	modelPath := filepath.Join(dir, "new-model.assert")
+	c.Assert(os.WriteFile(modelPath, []byte("model"), 0644), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "snap1.snap"), []byte("snap1"), 0644), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "snap1.assert"), []byte("snap1-asserts"), 0644), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "README"), []byte("ignored"), 0644), IsNil)
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.URL.Path, Equals, "/v2/model")
+		c.Assert(r.ParseMultipartForm(1<<20), IsNil)
+		c.Check(r.MultipartForm.Value["new-model"], DeepEquals, []string{"model"})
+		c.Check(r.MultipartForm.Value["offline"], DeepEquals, []string{"true"})
+		c.Check(r.MultipartForm.Value["assertion"], DeepEquals, []string{"snap1-asserts"})
+		c.Assert(r.MultipartForm.File["snap"], HasLen, 1)
+		c.Check(r.MultipartForm.File["snap"][0].Filename, Equals, "snap1.snap")
+		w.WriteHeader(202)
+		fmt.Fprint(w, remodelOk)
+		n++
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"remodel", "--no-wait", "--offline", modelPath})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Assert(n, Equals, 1)
+
+	c.Check(s.Stdout(), Matches, "101\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestRemodelOfflineNoFiles(c *C) {
+	n := 0
+
+	dir := filepath.Join(dirs.GlobalRootDir, "media")
+	c.Assert(os.MkdirAll(dir, 0755), IsNil)
+	modelPath := filepath.Join(dir, "new-model")
+	c.Assert(os.WriteFile(modelPath, []byte("model"), 0644), IsNil)
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.URL.Path, Equals, "/v2/model")
+		// still an offline remodel, using only the installed snaps
+		c.Assert(r.ParseMultipartForm(1<<20), IsNil)
+		c.Check(r.MultipartForm.Value["offline"], DeepEquals, []string{"true"})
+		c.Check(r.MultipartForm.Value["assertion"], HasLen, 0)
+		c.Check(r.MultipartForm.File["snap"], HasLen, 0)
+		w.WriteHeader(202)
+		fmt.Fprint(w, remodelOk)
+		n++
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"remodel", "--no-wait", "--offline", modelPath})
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+}
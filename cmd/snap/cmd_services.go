@@ -33,9 +33,11 @@ import (
 
 type svcStatus struct {
 	clientMixin
+	jsonMixin
 	Positional struct {
 		ServiceNames []serviceName
 	} `positional-args:"yes"`
+	Users string `long:"users"`
 }
 
 type svcLogs struct {
@@ -53,6 +55,9 @@ var (
 	longServicesHelp  = i18n.G(`
 The services command lists information about the services specified, or about
 the services in all currently installed snaps.
+
+If the --users option is given, the status of user services in the sessions of
+the given users is listed as well, with one line per user.
 `)
 	shortLogsHelp = i18n.G("Retrieve logs for services")
 	longLogsHelp  = i18n.G(`
@@ -73,6 +78,9 @@ The restart command restarts the given services.
 
 If the --reload option is given, for each service whose app has a reload
 command, a reload is performed instead of a restart.
+
+Services activated by sockets or timers which are not running are left to
+be started by their activation units, which are started if needed.
 `)
 )
 
@@ -83,7 +91,10 @@ func init() {
 		// TRANSLATORS: This should not start with a lowercase letter.
 		desc: i18n.G("A service specification, which can be just a snap name (for all services in the snap), or <snap>.<app> for a single service."),
 	}}
-	addCommand("services", shortServicesHelp, longServicesHelp, func() flags.Commander { return &svcStatus{} }, nil, argdescs)
+	addCommand("services", shortServicesHelp, longServicesHelp, func() flags.Commander { return &svcStatus{} }, jsonDescs.also(map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"users": i18n.G("Also show user services in the sessions of the given users, 'all' or a comma separated list of uids."),
+	}), argdescs)
 	addCommand("logs", shortLogsHelp, longLogsHelp, func() flags.Commander { return &svcLogs{} },
 		timeDescs.also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
@@ -122,11 +133,18 @@ func (s *svcStatus) Execute(args []string) error {
 		return ErrExtraArgs
 	}
 
-	services, err := s.client.Apps(svcNames(s.Positional.ServiceNames), client.AppOptions{Service: true})
+	services, err := s.client.Apps(svcNames(s.Positional.ServiceNames), client.AppOptions{Service: true, Users: s.Users})
 	if err != nil {
 		return err
 	}
 
+	if s.JSON {
+		if services == nil {
+			services = []*client.AppInfo{}
+		}
+		return s.printJSON(services)
+	}
+
 	if len(services) == 0 {
 		fmt.Fprintln(Stderr, i18n.G("There are no services provided by installed snaps."))
 		return nil
@@ -149,6 +167,20 @@ func (s *svcStatus) Execute(args []string) error {
 			current = i18n.G("active")
 		}
 		fmt.Fprintf(w, "%s.%s\t%s\t%s\t%s\n", svc.Snap, svc.Name, startup, current, clientutil.ClientAppInfoNotes(svc))
+		for _, user := range svc.Users {
+			startup := i18n.G("disabled")
+			if user.Enabled {
+				startup = i18n.G("enabled")
+			}
+			current := i18n.G("inactive")
+			switch {
+			case user.NoSession:
+				current = i18n.G("inactive (no session)")
+			case user.Active:
+				current = i18n.G("active")
+			}
+			fmt.Fprintf(w, "%s.%s (uid %d)\t%s\t%s\t-\n", svc.Snap, svc.Name, user.Uid, startup, current)
+		}
 	}
 
 	return nil
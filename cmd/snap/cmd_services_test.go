@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
@@ -240,6 +241,65 @@ foo.zed  enabled  active    -
 	c.Check(n, check.Equals, 1)
 }
 
+func (s *appOpSuite) TestAppStatusUsers(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.URL.Path, check.Equals, "/v2/apps")
+			c.Check(r.URL.Query(), check.DeepEquals, url.Values{
+				"names":  []string{"foo"},
+				"select": []string{"service"},
+				"users":  []string{"1000,1001"},
+			})
+			c.Check(r.Method, check.Equals, "GET")
+			w.WriteHeader(200)
+			enc := json.NewEncoder(w)
+			enc.Encode(map[string]interface{}{
+				"type": "sync",
+				"result": []map[string]interface{}{
+					{
+						"snap":         "foo",
+						"name":         "bar",
+						"daemon":       "simple",
+						"daemon-scope": "system",
+						"active":       true,
+						"enabled":      true,
+					}, {
+						"snap":         "foo",
+						"name":         "qux",
+						"daemon":       "simple",
+						"daemon-scope": "user",
+						"enabled":      true,
+						"users": []map[string]interface{}{
+							{"uid": 1000, "enabled": true, "active": true},
+							{"uid": 1001, "no-session": true},
+						},
+					},
+				},
+				"status":      "OK",
+				"status-code": 200,
+			})
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"services", "--users=1000,1001", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `Service             Startup   Current                Notes
+foo.bar             enabled   active                 -
+foo.qux             enabled   -                      user
+foo.qux (uid 1000)  enabled   active                 -
+foo.qux (uid 1001)  disabled  inactive (no session)  -
+`)
+	// ensure that the fake server api was actually hit
+	c.Check(n, check.Equals, 1)
+}
+
 func (s *appOpSuite) TestServiceCompletion(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
@@ -316,6 +376,85 @@ func (s *appOpSuite) TestAppStatusNoServices(c *check.C) {
 	c.Check(n, check.Equals, 1)
 }
 
+func (s *appOpSuite) TestAppStatusJSON(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.URL.Path, check.Equals, "/v2/apps")
+			c.Check(r.Method, check.Equals, "GET")
+			w.WriteHeader(200)
+			enc := json.NewEncoder(w)
+			enc.Encode(map[string]interface{}{
+				"type": "sync",
+				"result": []map[string]interface{}{
+					{
+						"snap":         "foo",
+						"name":         "bar",
+						"daemon":       "simple",
+						"daemon-scope": "system",
+						"active":       true,
+						"enabled":      true,
+					}, {
+						"snap":         "foo",
+						"name":         "qux",
+						"daemon":       "simple",
+						"daemon-scope": "user",
+						"enabled":      true,
+						"users": []map[string]interface{}{
+							{"uid": 1000, "enabled": true, "active": true},
+						},
+					},
+				},
+				"status":      "OK",
+				"status-code": 200,
+			})
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"services", "--json"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	var services []map[string]interface{}
+	c.Assert(json.Unmarshal([]byte(s.Stdout()), &services), check.IsNil)
+	c.Check(services, check.DeepEquals, []map[string]interface{}{
+		{
+			"snap":         "foo",
+			"name":         "bar",
+			"daemon":       "simple",
+			"daemon-scope": "system",
+			"active":       true,
+			"enabled":      true,
+		}, {
+			"snap":         "foo",
+			"name":         "qux",
+			"daemon":       "simple",
+			"daemon-scope": "user",
+			"enabled":      true,
+			"users": []interface{}{
+				map[string]interface{}{"uid": float64(1000), "enabled": true, "active": true},
+			},
+		},
+	})
+	// ensure that the fake server api was actually hit
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *appOpSuite) TestAppStatusJSONNoServices(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": [], "status": "OK", "status-code": 200}`)
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"services", "--json"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "[]\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
 func (s *appOpSuite) TestLogsCommand(c *check.C) {
 	n := 0
 	timestamp := "2021-08-16T17:33:55Z"
@@ -130,6 +130,7 @@ and the snap can easily be enabled again.
 
 type cmdRemove struct {
 	waitMixin
+	conflictWaitMixin
 
 	Revision   string `long:"revision"`
 	Purge      bool   `long:"purge"`
@@ -141,7 +142,9 @@ type cmdRemove struct {
 func (x *cmdRemove) removeOne(opts *client.SnapOptions) error {
 	name := string(x.Positional.Snaps[0])
 
-	changeID, err := x.client.Remove(name, opts)
+	changeID, err := x.retryOnConflict(x.client, func() (string, error) {
+		return x.client.Remove(name, opts)
+	})
 	if err != nil {
 		msg, err := errorToCmdMessage(name, "remove", err, opts)
 		if err != nil {
@@ -168,7 +171,9 @@ func (x *cmdRemove) removeOne(opts *client.SnapOptions) error {
 
 func (x *cmdRemove) removeMany(opts *client.SnapOptions) error {
 	names := installedSnapNames(x.Positional.Snaps)
-	changeID, err := x.client.RemoveMany(names, opts)
+	changeID, err := x.retryOnConflict(x.client, func() (string, error) {
+		return x.client.RemoveMany(names, opts)
+	})
 	if err != nil {
 		var name string
 		if cerr, ok := err.(*client.Error); ok {
@@ -228,7 +233,7 @@ func (x *cmdRemove) Execute([]string) error {
 }
 
 type channelMixin struct {
-	Channel string `long:"channel"`
+	Channel channelName `long:"channel"`
 
 	// shortcuts
 	EdgeChannel      bool `long:"edge"`
@@ -279,12 +284,12 @@ func (mx *channelMixin) setChannelFromCommandline() error {
 		if mx.Channel != "" {
 			return fmt.Errorf("Please specify a single channel")
 		}
-		mx.Channel = ch.chName
+		mx.Channel = channelName(ch.chName)
 	}
 
 	if mx.Channel != "" {
-		if _, err := channel.Parse(mx.Channel, ""); err != nil {
-			full, er := channel.Full(mx.Channel)
+		if _, err := channel.Parse(string(mx.Channel), ""); err != nil {
+			full, er := channel.Full(string(mx.Channel))
 			if er != nil {
 				// the parse error has more detailed info
 				return err
@@ -295,7 +300,7 @@ func (mx *channelMixin) setChannelFromCommandline() error {
 			msg := i18n.G("Specifying a channel %q is relying on undefined behaviour. Interpreting it as %q for now, but this will be an error later.\n")
 			warn := fill(fmt.Sprintf(msg, mx.Channel, full), utf8.RuneCountInString(head)+1) // +1 for the space
 			fmt.Fprint(Stderr, head, " ", warn, "\n\n")
-			mx.Channel = full // so a malformed-but-eh channel will always be full, i.e. //stable// -> latest/stable
+			mx.Channel = channelName(full) // so a malformed-but-eh channel will always be full, i.e. //stable// -> latest/stable
 		}
 	}
 
@@ -497,6 +502,7 @@ func (mx modeMixin) setModes(opts *client.SnapOptions) {
 type cmdInstall struct {
 	colorMixin
 	waitMixin
+	conflictWaitMixin
 
 	channelMixin
 	modeMixin
@@ -532,13 +538,17 @@ func (x *cmdInstall) installOne(nameOrPath, desiredName string, opts *client.Sna
 		// don't log the request's body because the encoded snap is large.
 		x.client.SetMayLogBody(false)
 		path = nameOrPath
-		changeID, err = x.client.InstallPath(path, x.Name, opts)
+		changeID, err = x.retryOnConflict(x.client, func() (string, error) {
+			return x.client.InstallPath(path, x.Name, opts)
+		})
 	} else {
 		snapName = nameOrPath
 		if desiredName != "" {
 			return errors.New(i18n.G("cannot use explicit name when installing from store"))
 		}
-		changeID, err = x.client.Install(snapName, opts)
+		changeID, err = x.retryOnConflict(x.client, func() (string, error) {
+			return x.client.Install(snapName, opts)
+		})
 	}
 	if err != nil {
 		msg, err := errorToCmdMessage(nameOrPath, "install", err, opts)
@@ -586,13 +596,17 @@ func (x *cmdInstall) installMany(names []string, opts *client.SnapOptions) error
 	if isLocal {
 		// don't log the request's body because the encoded snap is large
 		x.client.SetMayLogBody(false)
-		changeID, err = x.client.InstallPathMany(names, opts)
+		changeID, err = x.retryOnConflict(x.client, func() (string, error) {
+			return x.client.InstallPathMany(names, opts)
+		})
 	} else {
 		if x.asksForMode() {
 			return errors.New(i18n.G("cannot specify mode for multiple store snaps (only for one store snap or several local ones)"))
 		}
 
-		changeID, err = x.client.InstallMany(names, opts)
+		changeID, err = x.retryOnConflict(x.client, func() (string, error) {
+			return x.client.InstallMany(names, opts)
+		})
 	}
 
 	if err != nil {
@@ -658,7 +672,7 @@ func (x *cmdInstall) Execute([]string) error {
 
 	dangerous := x.Dangerous || x.ForceDangerous
 	opts := &client.SnapOptions{
-		Channel:          x.Channel,
+		Channel:          string(x.Channel),
 		Revision:         x.Revision,
 		Dangerous:        dangerous,
 		Unaliased:        x.Unaliased,
@@ -702,6 +716,7 @@ type cmdRefresh struct {
 	colorMixin
 	timeMixin
 	waitMixin
+	conflictWaitMixin
 	channelMixin
 	modeMixin
 
@@ -722,7 +737,9 @@ type cmdRefresh struct {
 }
 
 func (x *cmdRefresh) refreshMany(snaps []string, opts *client.SnapOptions) error {
-	changeID, err := x.client.RefreshMany(snaps, opts)
+	changeID, err := x.retryOnConflict(x.client, func() (string, error) {
+		return x.client.RefreshMany(snaps, opts)
+	})
 	if err != nil {
 		return err
 	}
@@ -750,7 +767,9 @@ func (x *cmdRefresh) refreshMany(snaps []string, opts *client.SnapOptions) error
 }
 
 func (x *cmdRefresh) refreshOne(name string, opts *client.SnapOptions) error {
-	changeID, err := x.client.Refresh(name, opts)
+	changeID, err := x.retryOnConflict(x.client, func() (string, error) {
+		return x.client.Refresh(name, opts)
+	})
 	if err != nil {
 		msg, err := errorToCmdMessage(name, "refresh", err, opts)
 		if err != nil {
@@ -854,6 +873,42 @@ func (x *cmdRefresh) listRefresh() error {
 	return nil
 }
 
+// listAutoRefreshCandidates shows the snaps that the next auto-refresh would
+// refresh, as used by 'snap refresh --list --time'.
+func (x *cmdRefresh) listAutoRefreshCandidates() error {
+	candidates, err := x.client.RefreshCandidates()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(Stdout)
+	if len(candidates) == 0 {
+		fmt.Fprintln(Stdout, i18n.G("No snaps to refresh with the next auto-refresh."))
+		return nil
+	}
+
+	w := tabWriter()
+	fmt.Fprintln(w, i18n.G("Name\tCurrent\tRev\tTracking\tSize\tNotes"))
+	var deferred []*client.RefreshCandidate
+	for _, cand := range candidates {
+		notes := "-"
+		if cand.Deferred != "" {
+			notes = i18n.G("deferred")
+			deferred = append(deferred, cand)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", cand.Name, cand.Current, cand.Revision, fmtChannel(cand.Channel), strutil.SizeToStr(cand.DownloadSize), notes)
+	}
+	w.Flush()
+
+	if len(deferred) > 0 {
+		fmt.Fprintln(Stdout)
+		for _, cand := range deferred {
+			fmt.Fprintf(Stdout, i18n.G("Auto-refresh of %q is deferred: %s\n"), cand.Name, cand.Deferred)
+		}
+	}
+
+	return nil
+}
+
 func (x *cmdRefresh) Execute([]string) error {
 	if err := x.setChannelFromCommandline(); err != nil {
 		return err
@@ -866,7 +921,16 @@ func (x *cmdRefresh) Execute([]string) error {
 		if x.asksForMode() || x.asksForChannel() {
 			return errors.New(i18n.G("--time does not take mode or channel flags"))
 		}
-		return x.showRefreshTimes()
+		if err := x.showRefreshTimes(); err != nil {
+			return err
+		}
+		if x.List {
+			if len(x.Positional.Snaps) > 0 {
+				return errors.New(i18n.G("--list does not accept additional arguments"))
+			}
+			return x.listAutoRefreshCandidates()
+		}
+		return nil
 	}
 
 	if x.List {
@@ -900,7 +964,7 @@ func (x *cmdRefresh) Execute([]string) error {
 	if len(names) == 1 {
 		opts := &client.SnapOptions{
 			Amend:            x.Amend,
-			Channel:          x.Channel,
+			Channel:          string(x.Channel),
 			IgnoreValidation: x.IgnoreValidation,
 			IgnoreRunning:    x.IgnoreRunning,
 			Revision:         x.Revision,
@@ -1178,6 +1242,9 @@ and will use the original data that was associated with that revision,
 discarding any data changes that were done by the latest revision. As
 an exception, data which the snap explicitly chooses to share across
 revisions is not touched by the revert process.
+
+With --revision the snap is reverted to the given revision instead, which
+must be one of the revisions still retained locally (see 'snap list --all').
 `)
 
 func (x *cmdRevert) Execute(args []string) error {
@@ -1197,7 +1264,12 @@ func (x *cmdRevert) Execute(args []string) error {
 	x.setModes(opts)
 	changeID, err := x.client.Revert(name, opts)
 	if err != nil {
-		return err
+		msg, err := errorToCmdMessage(name, "revert", err, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(Stderr, msg)
+		return nil
 	}
 
 	chg, err := x.wait(changeID)
@@ -1275,14 +1347,14 @@ func (x cmdSwitch) Execute(args []string) error {
 
 func init() {
 	addCommand("remove", shortRemoveHelp, longRemoveHelp, func() flags.Commander { return &cmdRemove{} },
-		waitDescs.also(map[string]string{
+		waitDescs.also(conflictWaitDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"revision": i18n.G("Remove only the given revision"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"purge": i18n.G("Remove the snap without saving a snapshot of its data"),
 		}), nil)
 	addCommand("install", shortInstallHelp, longInstallHelp, func() flags.Commander { return &cmdInstall{} },
-		colorDescs.also(waitDescs).also(channelDescs).also(modeDescs).also(map[string]string{
+		colorDescs.also(waitDescs).also(conflictWaitDescs).also(channelDescs).also(modeDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"revision": i18n.G("Install the given revision of a snap"),
 			// TRANSLATORS: This should not start with a lowercase letter.
@@ -1307,13 +1379,13 @@ func init() {
 			"prefer": i18n.G("Enable all aliases of the given snap in preference to conflicting aliases of other snaps"),
 		}), nil)
 	addCommand("refresh", shortRefreshHelp, longRefreshHelp, func() flags.Commander { return &cmdRefresh{} },
-		colorDescs.also(waitDescs).also(channelDescs).also(modeDescs).also(timeDescs).also(map[string]string{
+		colorDescs.also(waitDescs).also(conflictWaitDescs).also(channelDescs).also(modeDescs).also(timeDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"amend": i18n.G("Allow refresh attempt on snap unknown to the store"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"revision": i18n.G("Refresh to the given revision"),
 			// TRANSLATORS: This should not start with a lowercase letter.
-			"list": i18n.G("Show the new versions of snaps that would be updated with the next refresh"),
+			"list": i18n.G("Show the new versions of snaps that would be updated with the next refresh (with --time, those of the next auto-refresh)"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"time": i18n.G("Show auto refresh information but do not perform a refresh"),
 			// TRANSLATORS: This should not start with a lowercase letter.
@@ -27,12 +27,14 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/jessevdk/go-flags"
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/client"
@@ -1419,6 +1421,30 @@ func (s *SnapOpSuite) TestRevertMissingName(c *check.C) {
 	c.Assert(err, check.ErrorMatches, "the required argument `<snap>` was not provided")
 }
 
+func (s *SnapOpSuite) TestRevertRevisionNotRetained(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "error", "result": {"message": "cannot revert snap \"foo\" to revision 2: revision is not retained locally", "value": "foo", "kind": "snap-revision-not-retained"}, "status-code": 400}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"revert", "--revision=2", "foo"})
+	c.Check(err, check.ErrorMatches, `snap "foo" revision 2 is not retained locally anymore`)
+
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapOpSuite) TestRevertRevisionUnknown(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "error", "result": {"message": "cannot find revision 2 for snap \"foo\"", "value": "foo", "kind": "snap-revision-unknown"}, "status-code": 400}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"revert", "--revision=2", "foo"})
+	c.Check(err, check.ErrorMatches, `snap "foo" has no revision 2 installed \(see 'snap list --all'\)`)
+
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
 func (s *SnapSuite) TestRefreshListLessOptions(c *check.C) {
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
 		c.Fatal("expected to get 0 requests")
@@ -1511,6 +1537,101 @@ next: 2017-04-26T00:58:00+02:00
 	c.Check(n, check.Equals, 1)
 }
 
+func (s *SnapSuite) TestRefreshListTime(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/system-info")
+			fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": {"refresh": {"timer": "0:00-24:00/4", "last": "2017-04-25T17:35:00+02:00", "next": "2017-04-26T00:58:00+02:00"}}}`)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			c.Check(r.URL.Query(), check.DeepEquals, url.Values{"select": []string{"refresh-candidates"}})
+			fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": [{"name": "foo", "current": "1", "revision": "2", "channel": "latest/stable", "download-size": 1024}, {"name": "bar", "current": "x1", "revision": "5", "channel": "2.0/edge/fix"}]}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--list", "--time", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `timer: 0:00-24:00/4
+last: 2017-04-25T17:35:00+02:00
+next: 2017-04-26T00:58:00+02:00
+
+Name  Current  Rev  Tracking       Size  Notes
+foo   1        2    latest/stable  1kB   -
+bar   x1       5    2.0/edge/…     0B    -
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *SnapSuite) TestRefreshListTimeDeferred(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.URL.Path, check.Equals, "/v2/system-info")
+			fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": {"refresh": {"timer": "0:00-24:00/4", "last": "2017-04-25T17:35:00+02:00", "next": "2017-04-26T00:58:00+02:00"}}}`)
+		case 1:
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			c.Check(r.URL.Query().Get("select"), check.Equals, "refresh-candidates")
+			fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": [{"name": "foo", "current": "1", "revision": "2", "channel": "latest/stable", "download-size": 1024, "deferred": "snap health is \"error\""}]}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--list", "--time", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `timer: 0:00-24:00/4
+last: 2017-04-25T17:35:00+02:00
+next: 2017-04-26T00:58:00+02:00
+
+Name  Current  Rev  Tracking       Size  Notes
+foo   1        2    latest/stable  1kB   deferred
+
+Auto-refresh of "foo" is deferred: snap health is "error"
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *SnapSuite) TestRefreshListTimeNoCandidates(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.URL.Path, check.Equals, "/v2/system-info")
+			fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": {"refresh": {"timer": "0:00-24:00/4", "last": "2017-04-25T17:35:00+02:00", "next": "2017-04-26T00:58:00+02:00"}}}`)
+		case 1:
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			c.Check(r.URL.Query().Get("select"), check.Equals, "refresh-candidates")
+			fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": []}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--list", "--time", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `timer: 0:00-24:00/4
+last: 2017-04-25T17:35:00+02:00
+next: 2017-04-26T00:58:00+02:00
+
+No snaps to refresh with the next auto-refresh.
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 2)
+}
+
 func (s *SnapSuite) TestRefreshTimeShowsHolds(c *check.C) {
 	type testcase struct {
 		in  string
@@ -2626,6 +2747,89 @@ func (s *SnapOpSuite) TestRefreshInsufficientDiskSpace(c *check.C) {
 	c.Check(s.Stderr(), check.Equals, "")
 }
 
+const snapChangeConflictRsp = `{
+	"type": "error",
+	"result": {
+		"message": "snap \"foo\" has \"auto-refresh\" change in progress",
+		"kind": "snap-change-conflict",
+		"value": {
+			"snap-name": "foo",
+			"change-kind": "auto-refresh",
+			"change-id": "7"
+		}
+	},
+	"status-code": 409
+}`
+
+func (s *SnapOpSuite) TestInstallChangeConflict(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(409)
+		fmt.Fprintln(w, snapChangeConflictRsp)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"install", "foo"})
+	c.Check(err, check.ErrorMatches, `snap "foo" has "auto-refresh" change in progress: blocked by change 7 \(auto-refresh\), try 'snap watch 7'`)
+	c.Check(client.IsRetryable(err), check.Equals, true)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapOpSuite) TestInstallWaitOnChangeConflict(c *check.C) {
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+	}
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		switch n {
+		case 1:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+			w.WriteHeader(409)
+			fmt.Fprintln(w, snapChangeConflictRsp)
+		case 2, 3:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/7")
+			fmt.Fprintf(w, `{"type": "sync", "result": {"id": "7", "kind": "auto-refresh", "ready": %v, "status": "Doing"}}\n`, n == 3)
+		default:
+			// the install is retried once the conflicting change is done
+			s.srv.handle(w, r)
+		}
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"install", "--wait", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?sm).*foo 1.0 from Bar installed`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.srv.n, check.Equals, s.srv.total)
+}
+
+func (s *SnapOpSuite) TestRemoveWaitOnChangeConflictOnlyOnce(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		switch n {
+		case 1, 3:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+			w.WriteHeader(409)
+			fmt.Fprintln(w, snapChangeConflictRsp)
+		case 2:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/7")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"id": "7", "kind": "auto-refresh", "ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("unexpected request %d", n)
+		}
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"remove", "--wait", "foo"})
+	c.Check(err, check.ErrorMatches, `snap "foo" has "auto-refresh" change in progress: blocked by change 7 \(auto-refresh\), try 'snap watch 7'`)
+	c.Check(n, check.Equals, 3)
+}
+
 func (s *SnapOpSuite) TestRemoveRevision(c *check.C) {
 	s.srv.total = 3
 	s.srv.checker = func(r *http.Request) {
@@ -3114,3 +3318,107 @@ func (s *SnapOpSuite) TestWaitReportsInfoStatus(c *check.C) {
 	c.Check(meter.Notices, testutil.Contains, "INFO: Task set to wait until a manual system restart allows to continue")
 	c.Check(n, check.Equals, 2)
 }
+
+func (s *SnapOpSuite) TestInstalledSnapNameCompletionCached(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		n++
+		fmt.Fprintln(w, `{"type": "sync", "result": [{"name": "foo"}, {"name": "bar"}]}`)
+	})
+	cacheFile := snap.InstalledSnapNamesCacheFile()
+	c.Assert(os.MkdirAll(filepath.Dir(cacheFile), 0700), check.IsNil)
+
+	c.Check(snap.InstalledSnapNameCompletion("f"), check.DeepEquals, []flags.Completion{{Item: "foo"}})
+	c.Check(n, check.Equals, 1)
+	c.Check(cacheFile, testutil.FileEquals, "foo\nbar\n")
+
+	// the cached names are used while fresh
+	c.Check(snap.InstalledSnapNameCompletion(""), check.DeepEquals, []flags.Completion{{Item: "foo"}, {Item: "bar"}})
+	c.Check(n, check.Equals, 1)
+
+	restore := snap.MockTimeNow(func() time.Time { return time.Now().Add(time.Minute) })
+	defer restore()
+	c.Check(snap.InstalledSnapNameCompletion("b"), check.DeepEquals, []flags.Completion{{Item: "bar"}})
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *SnapOpSuite) TestInstalledSnapNameCompletionNoRuntimeDir(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		fmt.Fprintln(w, `{"type": "sync", "result": [{"name": "foo"}]}`)
+	})
+
+	for i := 0; i < 2; i++ {
+		c.Check(snap.InstalledSnapNameCompletion(""), check.DeepEquals, []flags.Completion{{Item: "foo"}})
+	}
+	c.Check(n, check.Equals, 2)
+	c.Check(snap.InstalledSnapNamesCacheFile(), testutil.FileAbsent)
+}
+
+func (s *SnapOpSuite) TestInstalledSnapNameCompletionSnapdDown(c *check.C) {
+	restore := snap.MockCompletionTimeout(50 * time.Millisecond)
+	defer restore()
+	server := httptest.NewServer(nil)
+	server.Close()
+	snap.ClientConfig.BaseURL = server.URL
+	defer func() { snap.ClientConfig.BaseURL = "" }()
+
+	c.Check(snap.InstalledSnapNameCompletion(""), check.HasLen, 0)
+	c.Check(snap.ChannelNameCompletion("s"), check.DeepEquals, []flags.Completion{{Item: "stable"}})
+}
+
+func (s *SnapOpSuite) TestChannelCompletion(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/find")
+		switch name := r.URL.Query().Get("name"); name {
+		case "foo":
+			fmt.Fprintln(w, `{"type": "sync", "result": [{"name": "foo", "channels": {
+"latest/stable": {"channel": "stable"},
+"latest/edge": {"channel": "edge"},
+"2.0/stable": {"channel": "2.0/stable"}}}]}`)
+		default:
+			w.WriteHeader(404)
+			fmt.Fprintln(w, `{"type": "error", "result": {"message": "not found", "kind": "snap-not-found"}}`)
+		}
+	})
+	origArgs := os.Args
+	defer func() { os.Args = origArgs }()
+
+	os.Args = []string{"snap", "switch", "foo", "--channel", ""}
+	c.Check(snap.ChannelNameCompletion(""), check.DeepEquals, []flags.Completion{
+		{Item: "2.0/stable"}, {Item: "edge"}, {Item: "latest/edge"}, {Item: "latest/stable"}, {Item: "stable"},
+	})
+	c.Check(snap.ChannelNameCompletion("latest/s"), check.DeepEquals, []flags.Completion{{Item: "latest/stable"}})
+
+	// options and their arguments are skipped when looking for the snap
+	os.Args = []string{"snap", "refresh", "--cohort", "MSBlPuEE4jhu", "foo_instance", "--channel", "2"}
+	c.Check(snap.ChannelNameCompletion("2"), check.DeepEquals, []flags.Completion{{Item: "2.0/stable"}})
+
+	// the risks are offered for an unknown snap, or without snap
+	for _, args := range [][]string{
+		{"snap", "switch", "bar", "--channel", ""},
+		{"snap", "switch", "--channel", ""},
+	} {
+		os.Args = args
+		c.Check(snap.ChannelNameCompletion(""), check.DeepEquals, []flags.Completion{
+			{Item: "stable"}, {Item: "candidate"}, {Item: "beta"}, {Item: "edge"},
+		})
+	}
+
+	// the completer is used for the --channel option of the commands
+	os.Setenv("GO_FLAGS_COMPLETION", "verbose")
+	defer os.Unsetenv("GO_FLAGS_COMPLETION")
+	os.Args = []string{"snap", "switch", "foo", "--channel", "l"}
+	parser := snap.Parser(snap.Client())
+	var obtained []flags.Completion
+	parser.CompletionHandler = func(comps []flags.Completion) {
+		obtained = comps
+	}
+	_, err := parser.ParseArgs(os.Args[1:])
+	c.Assert(err, check.IsNil)
+	c.Check(obtained, check.DeepEquals, []flags.Completion{{Item: "latest/edge"}, {Item: "latest/stable"}})
+}
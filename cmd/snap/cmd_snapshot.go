@@ -28,7 +28,10 @@ import (
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/strutil/quantity"
 )
@@ -50,6 +53,11 @@ var (
 var longSavedHelp = i18n.G(`
 The saved command displays a list of snapshots that have been created
 previously with the 'save' command.
+
+With --total, the disk space used by all the snapshots on the system, and
+by the ones created automatically on snap removal, is shown after the list.
+The latter are forgotten, oldest first, once they exceed the limit set by
+the snapshots.automatic.max-size system option.
 `)
 var longSaveHelp = i18n.G(`
 The save command creates a snapshot of the current user, system and
@@ -97,9 +105,14 @@ By default, this command restores all the data in a snapshot.
 Alternatively, you can specify the data of which snaps to restore, or
 for which users, or a combination of these.
 
-If a snap is included in a restore operation, excluding its system and
-configuration data from the restore is not currently possible. This
-restriction may be lifted in the future.
+With --skip-system, the system data and configuration of the included
+snaps are left untouched and only user data is restored. With
+--common-only, only the data that is shared across revisions (the
+"common" directories) is restored.
+
+The data of users that no longer exist on the system is not restored,
+unless --force-ownership is given, in which case it is restored to
+their home directory under /home, owned by root.
 `)
 
 var longExportSnapshotHelp = i18n.G(`
@@ -114,7 +127,9 @@ with a new snapshot ID and can be restored using the restore command.
 type savedCmd struct {
 	clientMixin
 	durationMixin
+	jsonMixin
 	ID         snapshotID `long:"id"`
+	Total      bool       `long:"total"`
 	Positional struct {
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
@@ -134,13 +149,33 @@ func (x *savedCmd) Execute([]string) error {
 	if err != nil {
 		return err
 	}
-	if len(list) == 0 {
+	if len(list) == 0 && !x.JSON {
 		fmt.Fprintln(Stdout, i18n.G("No snapshots found."))
 		return nil
 	}
 
+	var usage *client.SnapshotsUsage
+	if x.Total {
+		usage, err = x.client.SnapshotsUsage()
+		if err != nil {
+			return err
+		}
+	}
+
+	if x.JSON {
+		if list == nil {
+			list = []client.SnapshotSet{}
+		}
+		if usage != nil {
+			return x.printJSON(struct {
+				Sets  []client.SnapshotSet   `json:"sets"`
+				Usage *client.SnapshotsUsage `json:"usage"`
+			}{list, usage})
+		}
+		return x.printJSON(list)
+	}
+
 	w := tabWriter()
-	defer w.Flush()
 
 	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 		// TRANSLATORS: 'Set' as in group or bag of things
@@ -172,6 +207,19 @@ func (x *savedCmd) Execute([]string) error {
 			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", sg.ID, sh.Snap, age, sh.Version, sh.Revision, size, note)
 		}
 	}
+	w.Flush()
+
+	if usage != nil {
+		total := strings.TrimSpace(fmtSize(usage.Total))
+		automatic := strings.TrimSpace(fmtSize(usage.Automatic))
+		if usage.AutomaticMaxSize > 0 {
+			// TRANSLATORS: the first %s is the size of all the snapshots, the second the size of the automatic ones and the third the limit on the latter
+			fmt.Fprintf(Stdout, i18n.G("\nTotal: %s (automatic: %s, limit %s)\n"), total, automatic, strings.TrimSpace(fmtSize(usage.AutomaticMaxSize)))
+		} else {
+			// TRANSLATORS: the first %s is the size of all the snapshots, the second the size of the automatic ones
+			fmt.Fprintf(Stdout, i18n.G("\nTotal: %s (automatic: %s)\n"), total, automatic)
+		}
+	}
 	return nil
 }
 
@@ -282,8 +330,11 @@ func (x *checkSnapshotCmd) Execute([]string) error {
 
 type restoreCmd struct {
 	waitMixin
-	Users      string `long:"users"`
-	Positional struct {
+	Users          string `long:"users"`
+	SkipSystem     bool   `long:"skip-system"`
+	CommonOnly     bool   `long:"common-only"`
+	ForceOwnership bool   `long:"force-ownership"`
+	Positional     struct {
 		ID    snapshotID          `positional-arg-name:"<id>"`
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
 	} `positional-args:"yes" required:"yes"`
@@ -296,7 +347,15 @@ func (x *restoreCmd) Execute([]string) error {
 	}
 	snaps := installedSnapNames(x.Positional.Snaps)
 	users := strutil.CommaSeparatedList(x.Users)
-	changeID, err := x.client.RestoreSnapshots(setID, snaps, users)
+	var opts *snap.SnapshotRestoreOptions
+	if x.SkipSystem || x.CommonOnly || x.ForceOwnership {
+		opts = &snap.SnapshotRestoreOptions{
+			SkipSystem:     x.SkipSystem,
+			CommonOnly:     x.CommonOnly,
+			ForceOwnership: x.ForceOwnership,
+		}
+	}
+	changeID, err := x.client.RestoreSnapshotsWithOptions(setID, snaps, users, opts)
 	if err != nil {
 		return err
 	}
@@ -326,9 +385,11 @@ func init() {
 		func() flags.Commander {
 			return &savedCmd{}
 		},
-		durationDescs.also(map[string]string{
+		durationDescs.also(jsonDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"id": i18n.G("Show only a specific snapshot."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"total": i18n.G("Show the disk space used by all snapshots."),
 		}),
 		nil)
 
@@ -350,6 +411,12 @@ func init() {
 		}, waitDescs.also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"users": i18n.G("Restore data of only specific users (comma-separated) (default: all users)"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"skip-system": i18n.G("Do not restore system data and configuration"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"common-only": i18n.G("Restore only the data shared across revisions"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"force-ownership": i18n.G("Restore data of users that do not exist, owned by root"),
 		}), []argDesc{
 			{
 				name: "<id>",
@@ -468,7 +535,11 @@ func (x *exportSnapshotCmd) Execute([]string) (err error) {
 		return fmt.Errorf(i18n.G("cannot reserve disk space for snapshot: %v"), err)
 	}
 
-	n, err := io.Copy(f, r)
+	pb := progress.MakeProgressBar(Stdout)
+	// TRANSLATORS: %s is the identifier of the snapshot
+	pb.Start(fmt.Sprintf(i18n.G("Exporting snapshot #%s"), x.Positional.ID), float64(expectedSize))
+	n, err := io.Copy(io.MultiWriter(f, pb), r)
+	pb.Finished()
 	if err != nil {
 		return err
 	}
@@ -505,7 +576,10 @@ func (x *importSnapshotCmd) Execute([]string) error {
 		return fmt.Errorf("cannot stat file: %v", err)
 	}
 
-	importSet, err := x.client.SnapshotImport(f, st.Size())
+	pb := progress.MakeProgressBar(Stdout)
+	pb.Start(i18n.G("Importing snapshot"), float64(st.Size()))
+	importSet, err := x.client.SnapshotImport(io.TeeReader(f, pb), st.Size())
+	pb.Finished()
 	if err != nil {
 		return err
 	}
@@ -20,6 +20,8 @@
 package main_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -31,6 +33,8 @@ import (
 
 	"github.com/snapcore/snapd/client"
 	main "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/progress/progresstest"
 	"github.com/snapcore/snapd/strutil/quantity"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -47,6 +51,9 @@ var snapshotsTests = []getCmdArgs{{
 }, {
 	args:   "saved",
 	stdout: "Set  Snap  Age    Version  Rev   Size    Notes\n1    htop  .*  2        1168      1B  -\n",
+}, {
+	args:   "saved --total",
+	stdout: "Set  Snap  Age    Version  Rev   Size    Notes\n1    htop  .*  2        1168      1B  -\n\nTotal: 3.00MB \\(automatic: 1.00MB, limit 2.00MB\\)\n",
 }, {
 	args:  "forget x",
 	error: `invalid argument for snapshot set id: expected a non-negative integer argument \(see 'snap help saved'\)`,
@@ -113,11 +120,61 @@ func (s *SnapSuite) TestSnapshotExportHappy(c *C) {
 	c.Check(exportedSnapshotPath+".part", testutil.FileAbsent)
 }
 
+func (s *SnapSuite) TestSnapshotExportProgress(c *C) {
+	s.mockSnapshotsServer(c)
+	meter := &progresstest.Meter{}
+	defer progress.MockMeter(meter)()
+
+	exportedSnapshotPath := filepath.Join(c.MkDir(), "export-snapshot.snapshot")
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"export-snapshot", "1", exportedSnapshotPath})
+	c.Assert(err, IsNil)
+	c.Check(meter.Labels, DeepEquals, []string{"Exporting snapshot #1"})
+	c.Check(meter.Totals, DeepEquals, []float64{float64(len("Hello World!"))})
+	c.Check(string(bytes.Join(meter.Written, nil)), Equals, "Hello World!")
+	c.Check(meter.Finishes, Equals, 1)
+}
+
+func (s *SnapSuite) TestSnapshotRestoreWithOptions(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		switch r.URL.Path {
+		case "/v2/snapshots":
+			c.Check(r.Method, Equals, "POST")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+				"set":    json.Number("1"),
+				"action": "restore",
+				"snaps":  []interface{}{"htop"},
+				"users":  []interface{}{"alice"},
+				"restore-options": map[string]interface{}{
+					"common-only":     true,
+					"force-ownership": true,
+				},
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "9"}`)
+		case "/v2/changes/9":
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {}}}`)
+		default:
+			c.Errorf("unexpected path %q", r.URL.Path)
+		}
+	})
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"restore", "--users=alice", "--common-only", "--force-ownership", "1", "htop"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "Restored snapshot #1 of snaps \"htop\".\n")
+	c.Check(n, Equals, 2)
+}
+
 func (s *SnapSuite) mockSnapshotsServer(c *C) {
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case "/v2/snapshots":
 			if r.Method == "GET" {
+				if r.URL.Query().Get("usage") == "true" {
+					fmt.Fprintln(w, `{"type":"sync","status-code":200,"status":"OK","result":{"total":3000000,"automatic":1000000,"automatic-max-size":2000000}}`)
+					return
+				}
 				// simulate a 1-month old snapshot
 				snapshotTime := time.Now().AddDate(0, -1, 0).Format(time.RFC3339)
 				if r.URL.Query().Get("set") == "3" {
@@ -146,6 +203,49 @@ func (s *SnapSuite) mockSnapshotsServer(c *C) {
 	})
 }
 
+func (s *SnapSuite) TestSnapshotSavedJSON(c *C) {
+	s.mockSnapshotsServer(c)
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"saved", "--json"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stderr(), Equals, "")
+	var sets []client.SnapshotSet
+	c.Assert(json.Unmarshal(s.stdout.Bytes(), &sets), IsNil)
+	c.Assert(sets, HasLen, 1)
+	c.Check(sets[0].ID, Equals, uint64(1))
+	c.Assert(sets[0].Snapshots, HasLen, 1)
+	c.Check(sets[0].Snapshots[0].Snap, Equals, "htop")
+	c.Check(sets[0].Snapshots[0].Version, Equals, "2")
+	c.Check(sets[0].Snapshots[0].Size, Equals, int64(1))
+
+	s.ResetStdStreams()
+	_, err = main.Parser(main.Client()).ParseArgs([]string{"saved", "--json", "--total"})
+	c.Assert(err, IsNil)
+	var withUsage struct {
+		Sets  []client.SnapshotSet   `json:"sets"`
+		Usage *client.SnapshotsUsage `json:"usage"`
+	}
+	c.Assert(json.Unmarshal(s.stdout.Bytes(), &withUsage), IsNil)
+	c.Check(withUsage.Sets, HasLen, 1)
+	c.Check(withUsage.Usage, DeepEquals, &client.SnapshotsUsage{
+		Total:            3000000,
+		Automatic:        1000000,
+		AutomaticMaxSize: 2000000,
+	})
+}
+
+func (s *SnapSuite) TestSnapshotSavedJSONNone(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v2/snapshots")
+		fmt.Fprintln(w, `{"type":"sync","status-code":200,"status":"OK","result":[]}`)
+	})
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"saved", "--json"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "[]\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
 func (s *SnapSuite) TestSnapshotImportHappy(c *C) {
 	// mockSnapshotServer will return set-id 42 and three snaps for all
 	// import calls
@@ -167,3 +267,20 @@ Set  Snap  Age    Version  Rev   Size    Notes
 1    htop  %-6s 2        1168      1B  -
 `, ageStr))
 }
+
+func (s *SnapSuite) TestSnapshotImportProgress(c *C) {
+	s.mockSnapshotsServer(c)
+	meter := &progresstest.Meter{}
+	defer progress.MockMeter(meter)()
+
+	data := "this is really snapshot zip file data"
+	exportedSnapshotPath := filepath.Join(c.MkDir(), "mocked-snapshot.snapshot")
+	c.Assert(os.WriteFile(exportedSnapshotPath, []byte(data), 0644), IsNil)
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"import-snapshot", exportedSnapshotPath})
+	c.Assert(err, IsNil)
+	c.Check(meter.Labels, DeepEquals, []string{"Importing snapshot"})
+	c.Check(meter.Totals, DeepEquals, []float64{float64(len(data))})
+	c.Check(string(bytes.Join(meter.Written, nil)), Equals, data)
+	c.Check(meter.Finishes, Equals, 1)
+}
@@ -41,6 +41,7 @@ type cmdValidate struct {
 		ValidationSet string `positional-arg-name:"<validation-set>"`
 	} `positional-args:"yes"`
 	colorMixin
+	timeMixin
 	waitMixin
 }
 
@@ -56,7 +57,7 @@ operations which would result in snaps breaking the validation set's constraints
 `)
 
 func init() {
-	addCommand("validate", shortValidateHelp, longValidateHelp, func() flags.Commander { return &cmdValidate{} }, waitDescs.also(colorDescs.also(map[string]string{
+	addCommand("validate", shortValidateHelp, longValidateHelp, func() flags.Commander { return &cmdValidate{} }, waitDescs.also(colorDescs.also(timeDescs.also(map[string]string{
 		// TRANSLATORS: This should not start with a lowercase letter.
 		"monitor": i18n.G("Monitor the given validations set"),
 		// TRANSLATORS: This should not start with a lowercase letter.
@@ -65,7 +66,7 @@ func init() {
 		"forget": i18n.G("Forget the given validation set"),
 		// TRANSLATORS: This should not start with a lowercase letter.
 		"refresh": i18n.G("Refresh or install snaps to satisfy enforced validation sets"),
-	})), []argDesc{{
+	}))), []argDesc{{
 		// TRANSLATORS: This needs to begin with < and end with >
 		name: i18n.G("<validation-set>"),
 		// TRANSLATORS: This should not start with a lowercase letter.
@@ -73,6 +74,19 @@ func init() {
 	}})
 }
 
+func (cmd *cmdValidate) fmtNotes(res *client.ValidationSetResult) string {
+	var notes []string
+	if res.PendingSequence != 0 {
+		// TRANSLATORS: %d is a validation set sequence point which could not be applied
+		notes = append(notes, fmt.Sprintf(i18n.G("pending seq %d"), res.PendingSequence))
+	}
+	if res.LastRefresh != nil {
+		// TRANSLATORS: %s is the time the validation set was last refreshed
+		notes = append(notes, fmt.Sprintf(i18n.G("refreshed %s"), cmd.fmtTime(*res.LastRefresh)))
+	}
+	return strings.Join(notes, ", ")
+}
+
 func fmtValid(res *client.ValidationSetResult) string {
 	if res.Valid {
 		return "valid"
@@ -192,15 +206,13 @@ func (cmd *cmdValidate) Execute(args []string) error {
 		// TRANSLATORS: the %s is to insert a filler escape sequence (please keep it flush to the column header, with no extra spaces)
 		fmt.Fprintf(w, i18n.G("Validation\tMode\tSeq\tCurrent\t%s\tNotes\n"), fillerPublisher(esc))
 		for _, res := range vsets {
-			// TODO: fill notes when've clarity about them
-			var notes string
 			// doing it this way because otherwise it's a sea of %s\t%s\t%s
 			line := []string{
 				fmtValidationSet(res),
 				res.Mode,
 				fmt.Sprintf("%d", res.Sequence),
 				fmtValid(res),
-				notes,
+				cmd.fmtNotes(res),
 			}
 			fmt.Fprintln(w, strings.Join(line, "\t"))
 		}
@@ -221,6 +221,25 @@ func (s *validateSuite) TestValidationSetsList(c *check.C) {
 	)
 }
 
+func (s *validateSuite) TestValidationSetsListRefreshStatus(c *check.C) {
+	restore := main.MockIsStdinTTY(true)
+	defer restore()
+
+	s.RedirectClientToTestServer(makeFakeListValidationsSetsHandler(c, `{"type": "sync", "status-code": 200, "result": [
+		{"account-id":"foo","name":"bar","mode":"monitor","sequence":3,"valid":true,"last-refresh":"2023-06-01T10:00:00Z"},
+		{"account-id":"foo","name":"baz","mode":"enforce","sequence":1,"valid":true,"last-refresh":"2023-06-01T10:00:00Z","pending-sequence":2}
+	]}`))
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"validate", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, "Validation  Mode     Seq  Current       Notes\n"+
+		"foo/bar     monitor  3    valid    refreshed 2023-06-01T10:00:00Z\n"+
+		"foo/baz     enforce  1    valid    pending seq 2, refreshed 2023-06-01T10:00:00Z\n",
+	)
+}
+
 func (s *validateSuite) TestValidationSetsListEmpty(c *check.C) {
 	restore := main.MockIsStdinTTY(true)
 	defer restore()
@@ -21,10 +21,16 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 
@@ -32,21 +38,104 @@ import (
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
 )
 
-type installedSnapName string
+var (
+	// completionTimeout bounds the time a completion handler waits for
+	// snapd, so that completion degrades to no candidates instead of
+	// hanging the shell when snapd is down or busy
+	completionTimeout = 2 * time.Second
 
-func (s installedSnapName) Complete(match string) []flags.Completion {
-	snaps, err := mkClient().List(nil, nil)
+	// installedSnapNamesCacheExpiry is how long the list of installed
+	// snap names is reused by subsequent completions
+	installedSnapNamesCacheExpiry = 5 * time.Second
+)
+
+// completionContext returns the context for the requests to snapd done
+// by the completion handlers.
+func completionContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), completionTimeout)
+}
+
+// completionPositionals returns the words of the command line after the
+// name of the command and before the word being completed that are not
+// options, so that a completion handler can narrow down its candidates
+// based on the arguments given before.
+//
+// The arguments of options given as separate words cannot be told apart
+// from positional arguments, callers need to cope with that.
+func completionPositionals() []string {
+	if len(os.Args) < 3 {
+		return nil
+	}
+	var words []string
+	for _, arg := range os.Args[2 : len(os.Args)-1] {
+		if arg == "" || arg == "=" || strings.HasPrefix(arg, "-") {
+			continue
+		}
+		words = append(words, arg)
+	}
+	return words
+}
+
+// installedSnapNamesCacheFile is where the list of installed snap names
+// is cached for completion; it lives in the runtime directory of the
+// user, which is not created if missing.
+func installedSnapNamesCacheFile() string {
+	return filepath.Join(dirs.XdgRuntimeDirBase, strconv.Itoa(os.Getuid()), "snap-completion-snaps")
+}
+
+func cachedInstalledSnapNames() (names []string, ok bool) {
+	cacheFile := installedSnapNamesCacheFile()
+	st, err := os.Stat(cacheFile)
+	if err != nil || timeNow().Sub(st.ModTime()) > installedSnapNamesCacheExpiry {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(cacheFile)
 	if err != nil {
+		return nil, false
+	}
+	return strings.Fields(string(data)), true
+}
+
+// installedSnapNamesForCompletion returns the names of the installed
+// snaps, from the cache if it is fresh enough.
+func installedSnapNamesForCompletion() []string {
+	if names, ok := cachedInstalledSnapNames(); ok {
+		return names
+	}
+
+	ctx, cancel := completionContext()
+	defer cancel()
+	snaps, err := mkClient().ListWithContext(ctx, nil, nil)
+	if err != nil && err != client.ErrNoSnapsInstalled {
 		return nil
 	}
+	names := make([]string, len(snaps))
+	for i, snap := range snaps {
+		names[i] = snap.Name
+	}
+	// caching is best effort
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintln(&buf, name)
+	}
+	osutil.AtomicWriteFile(installedSnapNamesCacheFile(), buf.Bytes(), 0600, 0)
 
-	ret := make([]flags.Completion, 0, len(snaps))
-	for _, snap := range snaps {
-		if strings.HasPrefix(snap.Name, match) {
-			ret = append(ret, flags.Completion{Item: snap.Name})
+	return names
+}
+
+type installedSnapName string
+
+func (s installedSnapName) Complete(match string) []flags.Completion {
+	names := installedSnapNamesForCompletion()
+
+	ret := make([]flags.Completion, 0, len(names))
+	for _, name := range names {
+		if strings.HasPrefix(name, match) {
+			ret = append(ret, flags.Completion{Item: name})
 		}
 	}
 
@@ -105,7 +194,9 @@ func (s remoteSnapName) Complete(match string) []flags.Completion {
 	if len(match) < 3 {
 		return nil
 	}
-	snaps, _, err := mkClient().Find(&client.FindOptions{
+	ctx, cancel := completionContext()
+	defer cancel()
+	snaps, _, err := mkClient().FindWithContext(ctx, &client.FindOptions{
 		Query:  match,
 		Prefix: true,
 	})
@@ -146,10 +237,70 @@ func (s anySnapName) Complete(match string) []flags.Completion {
 	return res
 }
 
+// completionRisks are offered for channels when the channels of the snap
+// cannot be found.
+var completionRisks = []string{"stable", "candidate", "beta", "edge"}
+
+// channelName is the name of a channel, completed with the channels of the
+// snap named on the command line.
+type channelName string
+
+func (s channelName) Complete(match string) []flags.Completion {
+	channels := completionRisks
+	if snapChannels := snapChannelsForCompletion(); len(snapChannels) > 0 {
+		channels = snapChannels
+	}
+
+	var ret []flags.Completion
+	for _, ch := range channels {
+		if strings.HasPrefix(ch, match) {
+			ret = append(ret, flags.Completion{Item: ch})
+		}
+	}
+
+	return ret
+}
+
+// snapChannelsForCompletion returns the channels of the first snap named
+// on the command line, as known to the store. Channels of the default track
+// are also returned by their risk alone.
+func snapChannelsForCompletion() []string {
+	var snapName string
+	for _, word := range completionPositionals() {
+		if snap.ValidateInstanceName(word) == nil {
+			snapName = word
+			break
+		}
+	}
+	if snapName == "" {
+		return nil
+	}
+
+	ctx, cancel := completionContext()
+	defer cancel()
+	remote, _, err := mkClient().FindOneWithContext(ctx, snap.InstanceSnap(snapName))
+	if err != nil {
+		return nil
+	}
+
+	var channels []string
+	for name := range remote.Channels {
+		channels = append(channels, name)
+		if strings.HasPrefix(name, "latest/") {
+			channels = append(channels, strings.TrimPrefix(name, "latest/"))
+		}
+	}
+	sort.Strings(channels)
+
+	return channels
+}
+
 type changeID string
 
 func (s changeID) Complete(match string) []flags.Completion {
-	changes, err := mkClient().Changes(&client.ChangesOptions{Selector: client.ChangesAll})
+	ctx, cancel := completionContext()
+	defer cancel()
+	changes, err := mkClient().ChangesWithContext(ctx, &client.ChangesOptions{Selector: client.ChangesAll})
 	if err != nil {
 		return nil
 	}
@@ -244,6 +395,7 @@ func (cps connectPlugSpec) Complete(match string) []flags.Completion {
 		plugs:        true,
 		connected:    false,
 		disconnected: true,
+		connectable:  true,
 	}
 	return spec.Complete(match)
 }
@@ -252,7 +404,6 @@ type connectSlotSpec struct {
 	SnapAndName
 }
 
-// TODO: look at what the previous arg is, and filter accordingly
 func (css connectSlotSpec) Complete(match string) []flags.Completion {
 	spec := &interfaceSpec{
 		SnapAndName:  css.SnapAndName,
@@ -260,6 +411,7 @@ func (css connectSlotSpec) Complete(match string) []flags.Completion {
 		plugs:        false,
 		connected:    false,
 		disconnected: true,
+		connectable:  true,
 	}
 	return spec.Complete(match)
 }
@@ -285,6 +437,12 @@ type interfaceSpec struct {
 	plugs        bool
 	connected    bool
 	disconnected bool
+	// connectable restricts the candidates to the plugs and slots that
+	// could be connected together, see connectableInterfaces
+	connectable bool
+
+	// interfaces, if set, are the only interfaces of the candidates
+	interfaces map[string]bool
 }
 
 func (spec *interfaceSpec) connFilter(numConns int) bool {
@@ -298,6 +456,50 @@ func (spec *interfaceSpec) connFilter(numConns int) bool {
 	return false
 }
 
+// filter returns whether a plug or slot of the given interface and with
+// the given number of connections is a candidate.
+func (spec *interfaceSpec) filter(iface string, numConns int) bool {
+	if spec.interfaces != nil && !spec.interfaces[iface] {
+		return false
+	}
+	return spec.connFilter(numConns)
+}
+
+// connectableInterfaces returns the interfaces of the plugs or slots that
+// are plausible candidates for snap connect. When completing a plug,
+// those are the interfaces of the existing slots. When completing a slot,
+// those are the interfaces of the plugs named by the argument given before
+// it, if any, in which case plugGiven is true.
+func connectableInterfaces(ifaces client.Connections, slots bool) (interfaces map[string]bool, plugGiven bool) {
+	interfaces = make(map[string]bool)
+	if !slots {
+		for _, slot := range ifaces.Slots {
+			interfaces[slot.Interface] = true
+		}
+		return interfaces, false
+	}
+
+	positionals := completionPositionals()
+	if len(positionals) == 0 {
+		return nil, false
+	}
+	parts := strings.SplitN(positionals[0], ":", 2)
+	for _, plug := range ifaces.Plugs {
+		if plug.Snap != parts[0] {
+			continue
+		}
+		if len(parts) == 2 && parts[1] != "" && plug.Name != parts[1] {
+			continue
+		}
+		interfaces[plug.Interface] = true
+	}
+	if len(interfaces) == 0 {
+		// not a known plug, do not filter
+		return nil, false
+	}
+	return interfaces, true
+}
+
 func (spec *interfaceSpec) Complete(match string) []flags.Completion {
 	// Parse what the user typed so far, it can be either
 	// nothing (""), a "snap", a "snap:" or a "snap:name".
@@ -307,11 +509,23 @@ func (spec *interfaceSpec) Complete(match string) []flags.Completion {
 	opts := client.ConnectionOptions{
 		All: true,
 	}
-	ifaces, err := mkClient().Connections(&opts)
+	ctx, cancel := completionContext()
+	defer cancel()
+	ifaces, err := mkClient().ConnectionsWithContext(ctx, &opts)
 	if err != nil {
 		return nil
 	}
 
+	if spec.connectable {
+		var plugGiven bool
+		spec.interfaces, plugGiven = connectableInterfaces(ifaces, spec.slots)
+		if plugGiven {
+			// slots can usually be connected to several plugs,
+			// offer them even if already connected
+			spec.connected = true
+		}
+	}
+
 	snaps := make(map[string]bool)
 
 	var ret []flags.Completion
@@ -329,14 +543,14 @@ func (spec *interfaceSpec) Complete(match string) []flags.Completion {
 		snapPrefix := parts[0]
 		if spec.plugs {
 			for _, plug := range ifaces.Plugs {
-				if strings.HasPrefix(plug.Snap, snapPrefix) && spec.connFilter(len(plug.Connections)) {
+				if strings.HasPrefix(plug.Snap, snapPrefix) && spec.filter(plug.Interface, len(plug.Connections)) {
 					snaps[plug.Snap] = true
 				}
 			}
 		}
 		if spec.slots {
 			for _, slot := range ifaces.Slots {
-				if strings.HasPrefix(slot.Snap, snapPrefix) && spec.connFilter(len(slot.Connections)) {
+				if strings.HasPrefix(slot.Snap, snapPrefix) && spec.filter(slot.Interface, len(slot.Connections)) {
 					snaps[slot.Snap] = true
 				}
 			}
@@ -351,7 +565,7 @@ func (spec *interfaceSpec) Complete(match string) []flags.Completion {
 					actualName = "core"
 				}
 				for _, plug := range ifaces.Plugs {
-					if plug.Snap == actualName && strings.HasPrefix(plug.Name, prefix) && spec.connFilter(len(plug.Connections)) {
+					if plug.Snap == actualName && strings.HasPrefix(plug.Name, prefix) && spec.filter(plug.Interface, len(plug.Connections)) {
 						// TODO: in the future annotate plugs that can take
 						// multiple connection sensibly and don't skip those even
 						// if they have connections already.
@@ -364,7 +578,7 @@ func (spec *interfaceSpec) Complete(match string) []flags.Completion {
 					actualName = "core"
 				}
 				for _, slot := range ifaces.Slots {
-					if slot.Snap == actualName && strings.HasPrefix(slot.Name, prefix) && spec.connFilter(len(slot.Connections)) {
+					if slot.Snap == actualName && strings.HasPrefix(slot.Name, prefix) && spec.filter(slot.Interface, len(slot.Connections)) {
 						ret = append(ret, flags.Completion{Item: fmt.Sprintf("%s:%s", snapName, slot.Name), Description: "slot"})
 					}
 				}
@@ -375,7 +589,7 @@ func (spec *interfaceSpec) Complete(match string) []flags.Completion {
 		for snapName := range snaps {
 			if spec.plugs {
 				for _, plug := range ifaces.Plugs {
-					if plug.Snap == snapName && spec.connFilter(len(plug.Connections)) {
+					if plug.Snap == snapName && spec.filter(plug.Interface, len(plug.Connections)) {
 						ret = append(ret, flags.Completion{Item: fmt.Sprintf("%s:", snapName)})
 						continue snaps
 					}
@@ -383,7 +597,7 @@ func (spec *interfaceSpec) Complete(match string) []flags.Completion {
 			}
 			if spec.slots {
 				for _, slot := range ifaces.Slots {
-					if slot.Snap == snapName && spec.connFilter(len(slot.Connections)) {
+					if slot.Snap == snapName && spec.filter(slot.Interface, len(slot.Connections)) {
 						ret = append(ret, flags.Completion{Item: fmt.Sprintf("%s:", snapName)})
 						continue snaps
 					}
@@ -398,7 +612,9 @@ func (spec *interfaceSpec) Complete(match string) []flags.Completion {
 type interfaceName string
 
 func (s interfaceName) Complete(match string) []flags.Completion {
-	ifaces, err := mkClient().Interfaces(nil)
+	ctx, cancel := completionContext()
+	defer cancel()
+	ifaces, err := mkClient().InterfacesWithContext(ctx, nil)
 	if err != nil {
 		return nil
 	}
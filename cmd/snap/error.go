@@ -90,6 +90,29 @@ func fill(para string, indent int) string {
 // errorToCmdMessage returns the appropriate error message and value based on the
 // client error and some context information. The opName is the lowercase name
 // of the failed operation (e.g., "refresh").
+// withBlockingChange returns a copy of the change conflict error err
+// mentioning the change in progress blocking the operation, if known.
+func withBlockingChange(err *client.Error) *client.Error {
+	values, ok := err.Value.(map[string]interface{})
+	if !ok {
+		return err
+	}
+	changeID, _ := values["change-id"].(string)
+	if changeID == "" {
+		return err
+	}
+	changeKind, _ := values["change-kind"].(string)
+	e := *err
+	if changeKind != "" {
+		// TRANSLATORS: the first %s is an error message, the others a change id, its kind and the change id again
+		e.Message = fmt.Sprintf(i18n.G("%s: blocked by change %s (%s), try 'snap watch %s'"), err.Message, changeID, changeKind, changeID)
+	} else {
+		// TRANSLATORS: the first %s is an error message, the others a change id and the change id again
+		e.Message = fmt.Sprintf(i18n.G("%s: blocked by change %s, try 'snap watch %s'"), err.Message, changeID, changeID)
+	}
+	return &e
+}
+
 func errorToCmdMessage(snapName string, opName string, e error, opts *client.SnapOptions) (string, error) {
 	// do this here instead of in the caller for more DRY
 	err, ok := e.(*client.Error)
@@ -98,7 +121,7 @@ func errorToCmdMessage(snapName string, opName string, e error, opts *client.Sna
 	}
 	// retryable errors are just passed through
 	if client.IsRetryable(err) {
-		return "", err
+		return "", withBlockingChange(err)
 	}
 
 	// ensure the "real" error is available if we ask for it
@@ -170,6 +193,20 @@ Try 'snapcraft prime' in your project directory, then 'snap try' again.`)
 				msg = fmt.Sprintf(i18n.G(`snap %[1]q not available on channel %q (see 'snap info %[1]s')`), snapName, opts.Channel)
 			}
 		}
+	case client.ErrorKindSnapRevisionUnknown:
+		usesSnapName = false
+		msg = err.Message
+		if opts != nil && opts.Revision != "" {
+			// TRANSLATORS: %q is the snap name; %s is whatever the user used for --revision=
+			msg = fmt.Sprintf(i18n.G(`snap %q has no revision %s installed (see 'snap list --all')`), snapName, opts.Revision)
+		}
+	case client.ErrorKindSnapRevisionNotRetained:
+		usesSnapName = false
+		msg = err.Message
+		if opts != nil && opts.Revision != "" {
+			// TRANSLATORS: %q is the snap name; %s is whatever the user used for --revision=
+			msg = fmt.Sprintf(i18n.G(`snap %q revision %s is not retained locally anymore`), snapName, opts.Revision)
+		}
 	case client.ErrorKindSnapAlreadyInstalled:
 		isError = false
 		msg = i18n.G(`snap %q is already installed, see 'snap help refresh'`)
@@ -233,6 +233,24 @@ func AssertTypeNameCompletion(match string) []flags.Completion {
 	return assertTypeName("").Complete(match)
 }
 
+func InstalledSnapNameCompletion(match string) []flags.Completion {
+	return installedSnapName("").Complete(match)
+}
+
+func ChannelNameCompletion(match string) []flags.Completion {
+	return channelName("").Complete(match)
+}
+
+var InstalledSnapNamesCacheFile = installedSnapNamesCacheFile
+
+func MockCompletionTimeout(d time.Duration) (restore func()) {
+	old := completionTimeout
+	completionTimeout = d
+	return func() {
+		completionTimeout = old
+	}
+}
+
 func MockIsStdoutTTY(t bool) (restore func()) {
 	oldIsStdoutTTY := isStdoutTTY
 	isStdoutTTY = t
@@ -0,0 +1,46 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+// jsonMixin adds a --json option to commands listing things, for scripts
+// to consume the data the command lists instead of its columnar output.
+type jsonMixin struct {
+	JSON bool `long:"json"`
+}
+
+var jsonDescs = mixinDescs{
+	// TRANSLATORS: This should not start with a lowercase letter.
+	"json": i18n.G("Output results in JSON format"),
+}
+
+// printJSON writes v to stdout in JSON format. The field names are the ones
+// of the JSON representation of the client structs, which are stable.
+func (mx jsonMixin) printJSON(v interface{}) error {
+	enc := json.NewEncoder(Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -196,3 +196,60 @@ func lastLogStr(logs []string) string {
 	}
 	return logs[len(logs)-1]
 }
+
+// conflictWaitMixin provides the --wait option for operations that can fail
+// because of a conflicting change in progress.
+type conflictWaitMixin struct {
+	WaitConflict bool `long:"wait"`
+}
+
+var conflictWaitDescs = mixinDescs{
+	// TRANSLATORS: This should not start with a lowercase letter.
+	"wait": i18n.G("If blocked by a change in progress, wait for it to finish and try once more"),
+}
+
+// conflictingChangeID returns the id of the change in progress that err
+// reports the operation was conflicting with, if known.
+func conflictingChangeID(err error) string {
+	e, ok := err.(*client.Error)
+	if !ok || e.Kind != client.ErrorKindSnapChangeConflict {
+		return ""
+	}
+	values, ok := e.Value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	id, _ := values["change-id"].(string)
+	return id
+}
+
+// retryOnConflict calls op and, if --wait was given and op was blocked by a
+// change in progress, waits for that change to be ready and calls op once
+// more.
+func (cwx conflictWaitMixin) retryOnConflict(cli *client.Client, op func() (string, error)) (string, error) {
+	changeID, err := op()
+	if !cwx.WaitConflict {
+		return changeID, err
+	}
+	id := conflictingChangeID(err)
+	if id == "" {
+		return changeID, err
+	}
+
+	pb := progress.MakeProgressBar(Stdout)
+	for {
+		chg, err := cli.Change(id)
+		if err != nil {
+			pb.Finished()
+			return "", err
+		}
+		if chg.Ready {
+			break
+		}
+		pb.Spin(fmt.Sprintf(i18n.G("Waiting for change %s (%s) to finish"), id, chg.Kind))
+		time.Sleep(pollTime)
+	}
+	pb.Finished()
+
+	return op()
+}
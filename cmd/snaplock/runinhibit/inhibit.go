@@ -22,10 +22,12 @@
 package runinhibit
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
@@ -149,11 +151,73 @@ func IsLocked(snapName string) (Hint, error) {
 // file to exist in the first place and non-privileged processes cannot create
 // it.
 //
-// The function does not fail if the inhibition lock does not exist.
+// The function does not fail if the inhibition lock does not exist. Any
+// refresh inhibition information for the snap is removed as well.
 func RemoveLockFile(snapName string) error {
 	err := os.Remove(HintFile(snapName))
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
+	return ClearRefreshInhibition(snapName)
+}
+
+// RefreshInhibition describes a refresh of a snap that is being postponed
+// because applications of the snap are running.
+type RefreshInhibition struct {
+	// FirstInhibited is the time when the refresh was first postponed.
+	FirstInhibited time.Time `json:"first-inhibited"`
+	// ProceedTime is the time after which the refresh proceeds even if
+	// applications of the snap are still running.
+	ProceedTime time.Time `json:"proceed-time"`
+}
+
+// RefreshInhibitionFile returns the full path of the file describing the
+// postponed refresh of the given snap.
+func RefreshInhibitionFile(snapName string) string {
+	return filepath.Join(InhibitDir, snapName+".refresh")
+}
+
+// SetRefreshInhibition records that the refresh of the given snap is being
+// postponed.
+//
+// The file is world-readable so that agents in the user session can warn
+// about the refresh before it is forced.
+func SetRefreshInhibition(snapName string, inhibition *RefreshInhibition) error {
+	if err := os.MkdirAll(InhibitDir, 0755); err != nil {
+		return err
+	}
+	buf, err := json.Marshal(inhibition)
+	if err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(RefreshInhibitionFile(snapName), buf, 0644, 0)
+}
+
+// ReadRefreshInhibition returns the information about the postponed refresh
+// of the given snap, or nil if the refresh is not being postponed.
+func ReadRefreshInhibition(snapName string) (*RefreshInhibition, error) {
+	buf, err := ioutil.ReadFile(RefreshInhibitionFile(snapName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var inhibition RefreshInhibition
+	if err := json.Unmarshal(buf, &inhibition); err != nil {
+		return nil, fmt.Errorf("cannot decode refresh inhibition of snap %q: %v", snapName, err)
+	}
+	return &inhibition, nil
+}
+
+// ClearRefreshInhibition removes the information about the postponed refresh
+// of the given snap.
+//
+// The function does not fail if the refresh is not being postponed.
+func ClearRefreshInhibition(snapName string) error {
+	err := os.Remove(RefreshInhibitionFile(snapName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	return nil
 }
@@ -20,9 +20,11 @@
 package runinhibit_test
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	. "gopkg.in/check.v1"
 
@@ -154,3 +156,49 @@ func (s *runInhibitSuite) TestRemoveLockFile(c *C) {
 	// Removing an absent lock file is not an error.
 	c.Assert(runinhibit.RemoveLockFile("pkg"), IsNil)
 }
+
+func (s *runInhibitSuite) TestRemoveLockFileRemovesRefreshInhibition(c *C) {
+	c.Assert(runinhibit.LockWithHint("pkg", runinhibit.HintInhibitedForRefresh), IsNil)
+	c.Assert(runinhibit.SetRefreshInhibition("pkg", &runinhibit.RefreshInhibition{}), IsNil)
+
+	c.Assert(runinhibit.RemoveLockFile("pkg"), IsNil)
+	c.Check(filepath.Join(runinhibit.InhibitDir, "pkg.lock"), testutil.FileAbsent)
+	c.Check(filepath.Join(runinhibit.InhibitDir, "pkg.refresh"), testutil.FileAbsent)
+}
+
+func (s *runInhibitSuite) TestRefreshInhibition(c *C) {
+	inhibition, err := runinhibit.ReadRefreshInhibition("pkg")
+	c.Assert(err, IsNil)
+	c.Check(inhibition, IsNil)
+
+	first := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	expected := &runinhibit.RefreshInhibition{
+		FirstInhibited: first,
+		ProceedTime:    first.Add(14 * 24 * time.Hour),
+	}
+	c.Assert(runinhibit.SetRefreshInhibition("pkg", expected), IsNil)
+
+	fname := filepath.Join(runinhibit.InhibitDir, "pkg.refresh")
+	c.Check(runinhibit.RefreshInhibitionFile("pkg"), Equals, fname)
+	c.Check(fname, testutil.FileEquals, `{"first-inhibited":"2023-05-01T10:00:00Z","proceed-time":"2023-05-15T10:00:00Z"}`)
+	fi, err := os.Stat(fname)
+	c.Assert(err, IsNil)
+	c.Check(fi.Mode().Perm(), Equals, os.FileMode(0644))
+
+	inhibition, err = runinhibit.ReadRefreshInhibition("pkg")
+	c.Assert(err, IsNil)
+	c.Check(inhibition, DeepEquals, expected)
+
+	c.Assert(runinhibit.ClearRefreshInhibition("pkg"), IsNil)
+	c.Check(fname, testutil.FileAbsent)
+	// Clearing an absent refresh inhibition is not an error.
+	c.Assert(runinhibit.ClearRefreshInhibition("pkg"), IsNil)
+}
+
+func (s *runInhibitSuite) TestReadRefreshInhibitionInvalid(c *C) {
+	c.Assert(os.MkdirAll(runinhibit.InhibitDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(runinhibit.RefreshInhibitionFile("pkg"), []byte("garbage"), 0644), IsNil)
+
+	_, err := runinhibit.ReadRefreshInhibition("pkg")
+	c.Check(err, ErrorMatches, `cannot decode refresh inhibition of snap "pkg": .*`)
+}
@@ -140,6 +140,43 @@ func (ac rootAccess) CheckAccess(d *Daemon, r *http.Request, ucred *ucrednet, us
 	return Forbidden("access denied")
 }
 
+// rootOrPolkitAccess allows requests from the root uid, or from users
+// granted access to the given action by Polkit, provided they were not
+// received on snapd-snap.socket. Unlike with authenticatedAccess, macaroon
+// authentication does not grant access, this is meant for operations
+// affecting the whole device, which site policy may want to restrict to
+// some administrators.
+type rootOrPolkitAccess struct {
+	Polkit string
+}
+
+func (ac rootOrPolkitAccess) CheckAccess(d *Daemon, r *http.Request, ucred *ucrednet, user *auth.UserState) *apiError {
+	if rspe := requireSnapdSocket(ucred); rspe != nil {
+		return rspe
+	}
+
+	if ucred.Uid == 0 {
+		return nil
+	}
+
+	if ac.Polkit != "" {
+		return checkPolkitAction(r, ucred, ac.Polkit)
+	}
+
+	return Forbidden("access denied")
+}
+
+// checkRequestAccess checks the access to the request with the given access
+// checker, for handlers which need to restrict some of the operations
+// they implement further than their command does.
+func checkRequestAccess(d *Daemon, r *http.Request, user *auth.UserState, ac accessChecker) *apiError {
+	ucred, err := ucrednetGet(r.RemoteAddr)
+	if err != nil && err != errNoID {
+		return InternalError(err.Error())
+	}
+	return ac.CheckAccess(d, r, ucred, user)
+}
+
 // snapAccess allows requests from the snapd-snap.socket
 type snapAccess struct{}
 
@@ -214,6 +214,48 @@ func (s *accessSuite) TestRootAccess(c *C) {
 	c.Check(ac.CheckAccess(nil, nil, ucred, nil), IsNil)
 }
 
+func (s *accessSuite) TestRootOrPolkitAccess(c *C) {
+	var ac daemon.AccessChecker = daemon.RootOrPolkitAccess{Polkit: "action-id"}
+
+	req := httptest.NewRequest("POST", "/", nil)
+	user := &auth.UserState{}
+
+	// polkit is not checked if any of:
+	//   * ucred is missing
+	//   * the request was received on snapd-snap.socket
+	//   * user is root
+	restore := daemon.MockCheckPolkitAction(func(r *http.Request, ucred *daemon.Ucrednet, action string) *daemon.APIError {
+		c.Fail()
+		return daemon.Forbidden("access denied")
+	})
+	defer restore()
+	c.Check(ac.CheckAccess(nil, req, nil, nil), DeepEquals, errForbidden)
+	c.Check(ac.CheckAccess(nil, req, nil, user), DeepEquals, errForbidden)
+	ucred := &daemon.Ucrednet{Uid: 42, Pid: 100, Socket: dirs.SnapSocket}
+	c.Check(ac.CheckAccess(nil, req, ucred, nil), DeepEquals, errForbidden)
+	ucred = &daemon.Ucrednet{Uid: 0, Pid: 100, Socket: dirs.SnapdSocket}
+	c.Check(ac.CheckAccess(nil, req, ucred, nil), IsNil)
+
+	// polkit is checked for regular users, even with macaroon auth
+	var checked int
+	restore = daemon.MockCheckPolkitAction(func(r *http.Request, u *daemon.Ucrednet, action string) *daemon.APIError {
+		checked++
+		c.Check(r, Equals, req)
+		c.Check(u, Equals, ucred)
+		c.Check(action, Equals, "action-id")
+		return errUnauthorized
+	})
+	defer restore()
+	ucred = &daemon.Ucrednet{Uid: 42, Pid: 100, Socket: dirs.SnapdSocket}
+	c.Check(ac.CheckAccess(nil, req, ucred, nil), DeepEquals, errUnauthorized)
+	c.Check(ac.CheckAccess(nil, req, ucred, user), DeepEquals, errUnauthorized)
+	c.Check(checked, Equals, 2)
+
+	// without a polkit action, regular users are forbidden
+	ac = daemon.RootOrPolkitAccess{}
+	c.Check(ac.CheckAccess(nil, req, ucred, user), DeepEquals, errForbidden)
+}
+
 func (s *accessSuite) TestSnapAccess(c *C) {
 	var ac daemon.AccessChecker = daemon.SnapAccess{}
 
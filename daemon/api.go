@@ -52,6 +52,7 @@ var api = []*Command{
 	assertsCmd,
 	assertsFindManyCmd,
 	stateChangeCmd,
+	changeEventsCmd,
 	stateChangesCmd,
 	createUserCmd,
 	buyCmd,
@@ -69,17 +70,21 @@ var api = []*Command{
 	snapshotCmd,
 	snapshotExportCmd,
 	connectionsCmd,
+	connectionOverridesCmd,
 	modelCmd,
 	cohortsCmd,
 	serialModelCmd,
 	systemsCmd,
 	systemsActionCmd,
+	systemSnapsCmd,
 	themesCmd,
 	accessoriesChangeCmd,
 	validationSetsListCmd,
 	validationSetsCmd,
 	routineConsoleConfStartCmd,
 	systemRecoveryKeysCmd,
+	systemSecbootCmd,
+	volumesCmd,
 	quotaGroupsCmd,
 	quotaGroupInfoCmd,
 	aspectsCmd,
@@ -89,6 +94,11 @@ const (
 	polkitActionLogin            = "io.snapcraft.snapd.login"
 	polkitActionManage           = "io.snapcraft.snapd.manage"
 	polkitActionManageInterfaces = "io.snapcraft.snapd.manage-interfaces"
+	polkitActionManageSystems    = "io.snapcraft.snapd.manage-systems"
+	polkitActionFactoryReset     = "io.snapcraft.snapd.factory-reset"
+	polkitActionRemodel          = "io.snapcraft.snapd.remodel"
+	polkitActionManageSerial     = "io.snapcraft.snapd.manage-serial"
+	polkitActionManageTPMLockout = "io.snapcraft.snapd.manage-tpm-lockout"
 )
 
 // userFromRequest extracts user information from request and return the respective user in state, if valid
@@ -139,6 +149,7 @@ var (
 	snapstateInstallPath                    = snapstate.InstallPath
 	snapstateInstallPathMany                = snapstate.InstallPathMany
 	snapstateRefreshCandidates              = snapstate.RefreshCandidates
+	snapstateRefreshCandidatesPreview       = snapstate.RefreshCandidatesPreview
 	snapstateTryPath                        = snapstate.TryPath
 	snapstateUpdate                         = snapstate.Update
 	snapstateUpdateMany                     = snapstate.UpdateMany
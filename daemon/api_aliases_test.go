@@ -145,6 +145,7 @@ func (s *aliasesSuite) TestAliasChangeConflict(c *check.C) {
 			"kind":    "snap-change-conflict",
 			"value": map[string]interface{}{
 				"change-kind": "manip",
+				"change-id":   "1",
 				"snap-name":   "alias-snap",
 			},
 		},
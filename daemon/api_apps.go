@@ -71,6 +71,13 @@ func getAppsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 	}
 
 	sd := servicestate.NewStatusDecorator(progress.Null)
+	if users := query.Get("users"); users != "" {
+		uids, rspe := userSessionUids(r, users)
+		if rspe != nil {
+			return rspe
+		}
+		sd = servicestate.NewStatusDecoratorForUsers(progress.Null, uids)
+	}
 
 	clientAppInfos, err := clientutil.ClientAppInfosFromSnapAppInfos(appInfos, sd)
 	if err != nil {
@@ -80,6 +87,38 @@ func getAppsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(clientAppInfos)
 }
 
+// userSessionUids parses the users whose sessions should be queried for
+// the status of user services, either "all" for all users with a running
+// session (returned as an empty list) or a comma separated list of uids.
+// Only root can query the sessions of other users.
+func userSessionUids(r *http.Request, users string) ([]int, *apiError) {
+	var uids []int
+	if users != "all" {
+		for _, s := range strutil.CommaSeparatedList(users) {
+			uid, err := strconv.ParseUint(s, 10, 32)
+			if err != nil {
+				return nil, BadRequest("invalid users parameter: %q", users)
+			}
+			uids = append(uids, int(uid))
+		}
+		if len(uids) == 0 {
+			return nil, BadRequest("invalid users parameter: %q", users)
+		}
+	}
+
+	ucred, err := ucrednetGet(r.RemoteAddr)
+	if err != nil {
+		return nil, Forbidden("cannot query user sessions: %v", err)
+	}
+	if ucred.Uid == 0 {
+		return uids, nil
+	}
+	if len(uids) != 1 || uint32(uids[0]) != ucred.Uid {
+		return nil, Forbidden("cannot query the sessions of other users")
+	}
+	return uids, nil
+}
+
 type appInfoOptions struct {
 	service bool
 }
@@ -37,6 +37,7 @@ import (
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -334,6 +335,65 @@ func (s *appsSuite) TestGetAppsInfoBadSelect(c *check.C) {
 	c.Assert(rspe.Status, check.Equals, 400)
 }
 
+func (s *appsSuite) TestGetAppsInfoUsers(c *check.C) {
+	// global enablement of the user service
+	s.SysctlBufs = [][]byte{[]byte("enabled\n")}
+
+	req, err := http.NewRequest("GET", "/v2/apps?select=service&names=snap-e&users=1000", nil)
+	c.Assert(err, check.IsNil)
+	s.asRootAuth(req)
+
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, []client.AppInfo{{
+		Snap:        "snap-e",
+		Name:        "svc4",
+		Daemon:      "simple",
+		DaemonScope: snap.UserDaemon,
+		Enabled:     true,
+		// no session agent is running
+		Users: []client.AppUserStatus{{Uid: 1000, NoSession: true}},
+	}})
+}
+
+func (s *appsSuite) TestGetAppsInfoUsersOwnSession(c *check.C) {
+	s.SysctlBufs = [][]byte{[]byte("enabled\n")}
+
+	req, err := http.NewRequest("GET", "/v2/apps?select=service&names=snap-e&users=1000", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = fmt.Sprintf("pid=100;uid=1000;socket=%s;", dirs.SnapdSocket)
+
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, check.Equals, 200)
+	apps := rsp.Result.([]client.AppInfo)
+	c.Assert(apps, check.HasLen, 1)
+	c.Check(apps[0].Users, check.DeepEquals, []client.AppUserStatus{{Uid: 1000, NoSession: true}})
+}
+
+func (s *appsSuite) TestGetAppsInfoUsersOtherSessions(c *check.C) {
+	for _, users := range []string{"all", "1001", "1000,1001"} {
+		req, err := http.NewRequest("GET", "/v2/apps?select=service&users="+users, nil)
+		c.Assert(err, check.IsNil)
+		req.RemoteAddr = fmt.Sprintf("pid=100;uid=1000;socket=%s;", dirs.SnapdSocket)
+
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, 403, check.Commentf(users))
+		c.Check(rspe.Message, check.Equals, "cannot query the sessions of other users")
+	}
+}
+
+func (s *appsSuite) TestGetAppsInfoBadUsers(c *check.C) {
+	for _, users := range []string{"potato", "-1", ","} {
+		req, err := http.NewRequest("GET", "/v2/apps?select=service&users="+users, nil)
+		c.Assert(err, check.IsNil)
+		s.asRootAuth(req)
+
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, 400, check.Commentf(users))
+		c.Check(rspe.Message, check.Equals, fmt.Sprintf("invalid users parameter: %q", users))
+	}
+}
+
 func (s *appsSuite) TestGetAppsInfoBadName(c *check.C) {
 	req, err := http.NewRequest("GET", "/v2/apps?names=potato", nil)
 	c.Assert(err, check.IsNil)
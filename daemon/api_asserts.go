@@ -100,6 +100,18 @@ func getAssertTypeNames(c *Command, r *http.Request, user *auth.UserState) Respo
 }
 
 func doAssert(c *Command, r *http.Request, user *auth.UserState) Response {
+	var batchMode bool
+	switch v := r.URL.Query().Get("batch"); v {
+	case "true", "false":
+		batchMode, _ = strconv.ParseBool(v)
+	case "":
+	default:
+		return BadRequest(`"batch" query parameter when used must be set to "true" or "false" or left unset`)
+	}
+	if batchMode {
+		return importAssertBundle(c, r)
+	}
+
 	batch := asserts.NewBatch(nil)
 	_, err := batch.AddStream(r.Body)
 	if err != nil {
@@ -119,6 +131,52 @@ func doAssert(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(nil)
 }
 
+// assertionRefJSON aids in marshaling a reference to an assertion into JSON.
+type assertionRefJSON struct {
+	Type    string            `json:"type"`
+	Headers map[string]string `json:"headers"`
+}
+
+// bundleImportJSON reports which assertions of a bundle were added and
+// which were already present.
+type bundleImportJSON struct {
+	Added   []assertionRefJSON `json:"added"`
+	Present []assertionRefJSON `json:"present"`
+}
+
+func assertionRefsJSON(refs []*asserts.Ref) ([]assertionRefJSON, error) {
+	refsJSON := make([]assertionRefJSON, 0, len(refs))
+	for _, ref := range refs {
+		headers, err := asserts.HeadersFromPrimaryKey(ref.Type, ref.PrimaryKey)
+		if err != nil {
+			return nil, err
+		}
+		refsJSON = append(refsJSON, assertionRefJSON{Type: ref.Type.Name, Headers: headers})
+	}
+	return refsJSON, nil
+}
+
+// importAssertBundle adds the stream of assertions from the request,
+// reporting which ones were added and which ones were already present.
+func importAssertBundle(c *Command, r *http.Request) Response {
+	state := c.d.overlord.State()
+	state.Lock()
+	defer state.Unlock()
+
+	res, err := assertstate.ImportBundle(state, r.Body)
+	if err != nil {
+		return BadRequest("cannot import assertions: %v", err)
+	}
+	var rsp bundleImportJSON
+	if rsp.Added, err = assertionRefsJSON(res.Added); err != nil {
+		return InternalError("%v", err)
+	}
+	if rsp.Present, err = assertionRefsJSON(res.Present); err != nil {
+		return InternalError("%v", err)
+	}
+	return SyncResponse(rsp)
+}
+
 func assertsFindOneRemote(c *Command, at *asserts.AssertionType, headers map[string]string, user *auth.UserState) ([]asserts.Assertion, error) {
 	primaryKeys, err := asserts.PrimaryKeyFromHeaders(at, headers)
 	if err != nil {
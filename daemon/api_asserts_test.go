@@ -152,6 +152,58 @@ func (s *assertsSuite) TestAssertError(c *check.C) {
 	c.Check(rec.Body.String(), testutil.Contains, "assert failed")
 }
 
+func (s *assertsSuite) TestAssertBatch(c *check.C) {
+	// add store key
+	s.addAsserts()
+
+	acct := assertstest.NewAccount(s.StoreSigning, "developer1", nil, "")
+	buf := &bytes.Buffer{}
+	enc := asserts.NewEncoder(buf)
+	c.Assert(enc.Encode(acct), check.IsNil)
+	c.Assert(enc.Encode(s.StoreSigning.StoreAccountKey("")), check.IsNil)
+
+	req, err := http.NewRequest("POST", "/v2/assertions?batch=true", buf)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, daemon.BundleImportJSON{
+		Added: []daemon.AssertionRefJSON{{
+			Type:    "account",
+			Headers: map[string]string{"account-id": acct.AccountID()},
+		}},
+		Present: []daemon.AssertionRefJSON{{
+			Type:    "account-key",
+			Headers: map[string]string{"public-key-sha3-384": s.StoreSigning.StoreAccountKey("").PublicKeyID()},
+		}},
+	})
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	_, err = assertstate.DB(st).Find(asserts.AccountType, map[string]string{
+		"account-id": acct.AccountID(),
+	})
+	c.Check(err, check.IsNil)
+}
+
+func (s *assertsSuite) TestAssertBatchError(c *check.C) {
+	acct := assertstest.NewAccount(s.StoreSigning, "developer1", nil, "")
+	buf := bytes.NewBuffer(asserts.Encode(acct))
+	req, err := http.NewRequest("POST", "/v2/assertions?batch=true", buf)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Matches, "cannot import assertions: cannot resolve prerequisite assertion: account-key .*")
+}
+
+func (s *assertsSuite) TestAssertBatchInvalidQuery(c *check.C) {
+	req, err := http.NewRequest("POST", "/v2/assertions?batch=maybe", bytes.NewBufferString(""))
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `"batch" query parameter when used must be set to "true" or "false" or left unset`)
+}
+
 func (s *assertsSuite) TestAssertsFindManyAll(c *check.C) {
 	acct := assertstest.NewAccount(s.StoreSigning, "developer1", map[string]interface{}{
 		"account-id": "developer1-id",
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"time"
@@ -87,6 +88,7 @@ type apiBaseSuite struct {
 	SysctlBufs        [][]byte
 
 	connectivityResult map[string]bool
+	activeBaseURL      *url.URL
 
 	restoreSanitize func()
 	restoreMuxVars  func()
@@ -163,6 +165,12 @@ func (s *apiBaseSuite) ConnectivityCheck() (map[string]bool, error) {
 	return s.connectivityResult, s.err
 }
 
+func (s *apiBaseSuite) ActiveBaseURL() *url.URL {
+	s.pokeStateLock()
+
+	return s.activeBaseURL
+}
+
 func (s *apiBaseSuite) muxVars(*http.Request) map[string]string {
 	return s.vars
 }
@@ -0,0 +1,411 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var changeEventsCmd = &Command{
+	Path:       "/v2/changes/{id}/events",
+	GET:        getChangeEvents,
+	ReadAccess: openAccess{},
+}
+
+var (
+	// how often the progress of the tasks of a change is checked
+	changeEventsProgressInterval = 500 * time.Millisecond
+	// how long a stream can stay silent before a heartbeat is sent
+	changeEventsHeartbeatInterval = 15 * time.Second
+	// how long the events of a ready change are remembered
+	changeEventsReadyExpiration = 10 * time.Minute
+)
+
+// maxChangeEvents is the number of status events remembered for each change
+// to let clients resume their stream.
+const maxChangeEvents = 256
+
+// changeEvents records the status transitions of changes and of their tasks
+// as reported by the state, numbering them with an increasing sequence
+// number, and wakes up the streams following the changes.
+type changeEvents struct {
+	mu sync.Mutex
+
+	seq     int
+	changes map[string]*changeEventsLog
+}
+
+type changeEventsLog struct {
+	events    []*client.ChangeUpdate
+	readyTime time.Time
+	waiters   map[chan struct{}]bool
+}
+
+func newChangeEvents(st *state.State) *changeEvents {
+	evs := &changeEvents{
+		changes: make(map[string]*changeEventsLog),
+	}
+	st.AddTaskStatusChangedHandler(evs.taskStatusChanged)
+	st.AddChangeStatusChangedHandler(evs.changeStatusChanged)
+	return evs
+}
+
+// changeEvents returns the change events tracker of the daemon, setting it
+// up on first use.
+//
+// The state must be locked by the caller.
+func (d *Daemon) changeEvents() *changeEvents {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.changeEventsTracker == nil {
+		d.changeEventsTracker = newChangeEvents(d.overlord.State())
+	}
+	return d.changeEventsTracker
+}
+
+// the handlers are invoked with the state locked, possibly by the
+// taskrunner, so they must not block
+func (evs *changeEvents) taskStatusChanged(t *state.Task, old, new state.Status) {
+	chg := t.Change()
+	if chg == nil {
+		return
+	}
+	evs.add(&client.ChangeUpdate{
+		Kind:     client.ChangeUpdateTaskStatus,
+		ChangeID: chg.ID(),
+		TaskID:   t.ID(),
+		Status:   new.String(),
+		Ready:    new.Ready(),
+	})
+}
+
+func (evs *changeEvents) changeStatusChanged(chg *state.Change, old, new state.Status) {
+	evs.add(changeStatusUpdate(chg, new))
+}
+
+func changeStatusUpdate(chg *state.Change, status state.Status) *client.ChangeUpdate {
+	update := &client.ChangeUpdate{
+		Kind:     client.ChangeUpdateChangeStatus,
+		ChangeID: chg.ID(),
+		Status:   status.String(),
+		Ready:    status.Ready(),
+	}
+	if status.Ready() {
+		if err := chg.Err(); err != nil {
+			update.Err = err.Error()
+		}
+	}
+	return update
+}
+
+func (evs *changeEvents) add(update *client.ChangeUpdate) {
+	evs.mu.Lock()
+	defer evs.mu.Unlock()
+
+	now := time.Now()
+	evs.expire(now)
+
+	evs.seq++
+	update.Seq = evs.seq
+	update.Time = now
+
+	log := evs.changes[update.ChangeID]
+	if log == nil {
+		log = &changeEventsLog{waiters: make(map[chan struct{}]bool)}
+		evs.changes[update.ChangeID] = log
+	}
+	log.events = append(log.events, update)
+	if len(log.events) > maxChangeEvents {
+		log.events = log.events[len(log.events)-maxChangeEvents:]
+	}
+	if update.Kind == client.ChangeUpdateChangeStatus && update.Ready {
+		log.readyTime = now
+	}
+	for w := range log.waiters {
+		select {
+		case w <- struct{}{}:
+		default:
+			// already woken up
+		}
+	}
+}
+
+// expire forgets the events of changes which have been ready for a while
+// and aren't followed anymore.
+func (evs *changeEvents) expire(now time.Time) {
+	for chgID, log := range evs.changes {
+		if len(log.waiters) == 0 && !log.readyTime.IsZero() && now.Sub(log.readyTime) > changeEventsReadyExpiration {
+			delete(evs.changes, chgID)
+		}
+	}
+}
+
+// subscribe returns a channel woken up whenever new events are recorded for
+// the given change, and the recorded events with a sequence number greater
+// than after.
+func (evs *changeEvents) subscribe(chgID string, after int) (wake chan struct{}, events []*client.ChangeUpdate, seq int) {
+	evs.mu.Lock()
+	defer evs.mu.Unlock()
+
+	log := evs.changes[chgID]
+	if log == nil {
+		log = &changeEventsLog{waiters: make(map[chan struct{}]bool)}
+		evs.changes[chgID] = log
+	}
+	wake = make(chan struct{}, 1)
+	log.waiters[wake] = true
+	return wake, log.since(after), evs.seq
+}
+
+func (evs *changeEvents) unsubscribe(chgID string, wake chan struct{}) {
+	evs.mu.Lock()
+	defer evs.mu.Unlock()
+
+	if log := evs.changes[chgID]; log != nil {
+		delete(log.waiters, wake)
+	}
+}
+
+// since returns the events of the change with a sequence number greater
+// than after.
+func (evs *changeEvents) since(chgID string, after int) []*client.ChangeUpdate {
+	evs.mu.Lock()
+	defer evs.mu.Unlock()
+
+	if log := evs.changes[chgID]; log != nil {
+		return log.since(after)
+	}
+	return nil
+}
+
+func (log *changeEventsLog) since(after int) []*client.ChangeUpdate {
+	for i, ev := range log.events {
+		if ev.Seq > after {
+			return append([]*client.ChangeUpdate(nil), log.events[i:]...)
+		}
+	}
+	return nil
+}
+
+func getChangeEvents(c *Command, r *http.Request, user *auth.UserState) Response {
+	chID := muxVars(r)["id"]
+
+	var after int
+	if s := r.URL.Query().Get("after"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return BadRequest("invalid after parameter: %q", s)
+		}
+		after = n
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(chID)
+	if chg == nil {
+		return NotFound("cannot find change with id %q", chID)
+	}
+
+	return &changeEventsResponse{
+		st:     st,
+		evs:    c.d.changeEvents(),
+		chgID:  chID,
+		after:  after,
+		replay: after > 0,
+	}
+}
+
+// changeEventsResponse streams the status transitions and the progress of
+// the tasks of a change as application/json-seq, until the change is ready.
+//
+// Unless resuming a previous stream, the stream starts with the current
+// status of the tasks and of the change. When resuming, the recorded events
+// following the given sequence number are sent first.
+type changeEventsResponse struct {
+	st     *state.State
+	evs    *changeEvents
+	chgID  string
+	after  int
+	replay bool
+}
+
+type taskProgress struct {
+	label       string
+	done, total int
+}
+
+func (cr *changeEventsResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json-seq")
+
+	flusher, hasFlusher := w.(http.Flusher)
+	writer := bufio.NewWriter(w)
+	enc := json.NewEncoder(writer)
+
+	seq := cr.after
+	lastWrite := time.Now()
+	send := func(updates []*client.ChangeUpdate) (ready bool, err error) {
+		for _, update := range updates {
+			if update.Seq > seq {
+				seq = update.Seq
+			} else if update.Seq == 0 {
+				update.Seq = seq
+			}
+			if update.Time.IsZero() {
+				update.Time = time.Now()
+			}
+			writer.WriteByte(0x1E) // RS -- see ascii(7), and RFC7464
+			if err := enc.Encode(update); err != nil {
+				return false, err
+			}
+		}
+		ready = hasReadyUpdate(updates)
+		if err := writer.Flush(); err != nil {
+			return false, err
+		}
+		if hasFlusher {
+			flusher.Flush()
+		}
+		lastWrite = time.Now()
+		return ready, nil
+	}
+
+	progress := make(map[string]taskProgress)
+	// checkProgress returns the progress updates of the tasks since the
+	// last check.
+	checkProgress := func(chg *state.Change) []*client.ChangeUpdate {
+		var updates []*client.ChangeUpdate
+		for _, t := range chg.Tasks() {
+			label, done, total := t.Progress()
+			cur := taskProgress{label: label, done: done, total: total}
+			if prev, ok := progress[t.ID()]; ok && prev == cur {
+				continue
+			}
+			progress[t.ID()] = cur
+			updates = append(updates, &client.ChangeUpdate{
+				Kind:     client.ChangeUpdateTaskProgress,
+				ChangeID: cr.chgID,
+				TaskID:   t.ID(),
+				Progress: &client.TaskProgress{Label: label, Done: done, Total: total},
+			})
+		}
+		return updates
+	}
+
+	cr.st.Lock()
+	chg := cr.st.Change(cr.chgID)
+	if chg == nil {
+		cr.st.Unlock()
+		writer.Flush()
+		return
+	}
+	wake, updates, cur := cr.evs.subscribe(cr.chgID, cr.after)
+	defer cr.evs.unsubscribe(cr.chgID, wake)
+	if !cr.replay {
+		// events up to now are superseded by the snapshot of the
+		// current status
+		updates = nil
+		seq = cur
+		for _, t := range chg.Tasks() {
+			status := t.Status()
+			updates = append(updates, &client.ChangeUpdate{
+				Kind:     client.ChangeUpdateTaskStatus,
+				ChangeID: cr.chgID,
+				TaskID:   t.ID(),
+				Status:   status.String(),
+				Ready:    status.Ready(),
+			})
+		}
+	}
+	updates = append(updates, checkProgress(chg)...)
+	if status := chg.Status(); !cr.replay || (status.Ready() && !hasReadyUpdate(updates)) {
+		// when resuming, the terminal status is sent even if it was
+		// not recorded, e.g. because snapd was restarted
+		updates = append(updates, changeStatusUpdate(chg, status))
+	}
+	cr.st.Unlock()
+
+	ready, err := send(updates)
+
+	progressTicker := time.NewTicker(changeEventsProgressInterval)
+	defer progressTicker.Stop()
+	heartbeatTicker := time.NewTicker(changeEventsHeartbeatInterval)
+	defer heartbeatTicker.Stop()
+
+	for !ready && err == nil {
+		var updates []*client.ChangeUpdate
+		select {
+		case <-r.Context().Done():
+			return
+		case <-wake:
+			// the events are recorded with the state locked, this
+			// ensures that the events recorded together, like the
+			// final status of a task and of its change, are sent
+			// together
+			cr.st.Lock()
+			updates = cr.evs.since(cr.chgID, seq)
+			cr.st.Unlock()
+		case <-progressTicker.C:
+			cr.st.Lock()
+			chg := cr.st.Change(cr.chgID)
+			if chg == nil {
+				// pruned
+				cr.st.Unlock()
+				return
+			}
+			updates = checkProgress(chg)
+			cr.st.Unlock()
+		case <-heartbeatTicker.C:
+			if time.Since(lastWrite) < changeEventsHeartbeatInterval {
+				continue
+			}
+			updates = []*client.ChangeUpdate{{
+				Kind:     client.ChangeUpdateHeartbeat,
+				ChangeID: cr.chgID,
+			}}
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		ready, err = send(updates)
+	}
+	if err != nil {
+		logger.Noticef("cannot stream events of change %q: %v", cr.chgID, err)
+	}
+}
+
+func hasReadyUpdate(updates []*client.ChangeUpdate) bool {
+	for _, update := range updates {
+		if update.Kind == client.ChangeUpdateChangeStatus && update.Ready {
+			return true
+		}
+	}
+	return false
+}
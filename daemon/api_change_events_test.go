@@ -0,0 +1,239 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var _ = Suite(&changeEventsSuite{})
+
+type changeEventsSuite struct {
+	apiBaseSuite
+
+	chgID string
+	t1ID  string
+	t2ID  string
+}
+
+func (s *changeEventsSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+	s.expectOpenAccess()
+	s.daemonWithOverlordMock()
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.NewChange("install", "install...")
+	t1 := st.NewTask("download", "1...")
+	t2 := st.NewTask("link", "2...")
+	t2.WaitFor(t1)
+	chg.AddAll(state.NewTaskSet(t1, t2))
+	t1.SetStatus(state.DoneStatus)
+	t2.SetStatus(state.DoingStatus)
+	t2.SetProgress("linking", 1, 2)
+	s.chgID, s.t1ID, s.t2ID = chg.ID(), t1.ID(), t2.ID()
+}
+
+// flushRecorder signals each flush of the streamed response
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed chan struct{}
+}
+
+func (r *flushRecorder) Flush() {
+	r.ResponseRecorder.Flush()
+	r.flushed <- struct{}{}
+}
+
+func (s *changeEventsSuite) stream(c *C, ctx context.Context, query string) (rec *flushRecorder, done chan struct{}) {
+	req, err := http.NewRequest("GET", "/v2/changes/"+s.chgID+"/events"+query, nil)
+	c.Assert(err, IsNil)
+	req = req.WithContext(ctx)
+	rsp := s.req(c, req, nil)
+
+	rec = &flushRecorder{
+		ResponseRecorder: httptest.NewRecorder(),
+		flushed:          make(chan struct{}, 10),
+	}
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		rsp.ServeHTTP(rec, req)
+	}()
+	return rec, done
+}
+
+func (s *changeEventsSuite) waitFlush(c *C, rec *flushRecorder) {
+	select {
+	case <-rec.flushed:
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for the stream")
+	}
+}
+
+func (s *changeEventsSuite) waitDone(c *C, done chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for the stream to end")
+	}
+}
+
+func decodeChangeUpdates(c *C, rec *flushRecorder) []client.ChangeUpdate {
+	c.Check(rec.Header().Get("Content-Type"), Equals, "application/json-seq")
+	var updates []client.ChangeUpdate
+	for _, buf := range bytes.Split(rec.Body.Bytes(), []byte{0x1E}) {
+		if len(buf) == 0 {
+			continue
+		}
+		var update client.ChangeUpdate
+		c.Assert(json.Unmarshal(buf, &update), IsNil)
+		c.Check(update.Time.IsZero(), Equals, false)
+		update.Time = time.Time{}
+		updates = append(updates, update)
+	}
+	return updates
+}
+
+func (s *changeEventsSuite) finish() {
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	st.Task(s.t2ID).SetStatus(state.DoneStatus)
+}
+
+func (s *changeEventsSuite) TestStreamUntilReady(c *C) {
+	rec, done := s.stream(c, context.Background(), "")
+	// the current status was sent
+	s.waitFlush(c, rec)
+
+	s.finish()
+	s.waitDone(c, done)
+
+	c.Check(decodeChangeUpdates(c, rec), DeepEquals, []client.ChangeUpdate{
+		{Kind: client.ChangeUpdateTaskStatus, ChangeID: s.chgID, TaskID: s.t1ID, Status: "Done", Ready: true},
+		{Kind: client.ChangeUpdateTaskStatus, ChangeID: s.chgID, TaskID: s.t2ID, Status: "Doing"},
+		{Kind: client.ChangeUpdateTaskProgress, ChangeID: s.chgID, TaskID: s.t1ID, Progress: &client.TaskProgress{Label: "", Done: 1, Total: 1}},
+		{Kind: client.ChangeUpdateTaskProgress, ChangeID: s.chgID, TaskID: s.t2ID, Progress: &client.TaskProgress{Label: "linking", Done: 1, Total: 2}},
+		{Kind: client.ChangeUpdateChangeStatus, ChangeID: s.chgID, Status: "Doing"},
+		{Seq: 1, Kind: client.ChangeUpdateChangeStatus, ChangeID: s.chgID, Status: "Done", Ready: true},
+		{Seq: 2, Kind: client.ChangeUpdateTaskStatus, ChangeID: s.chgID, TaskID: s.t2ID, Status: "Done", Ready: true},
+	})
+}
+
+func (s *changeEventsSuite) TestResume(c *C) {
+	rec, done := s.stream(c, context.Background(), "")
+	s.waitFlush(c, rec)
+	s.finish()
+	s.waitDone(c, done)
+
+	// resuming after the first transition replays the following ones
+	rec, done = s.stream(c, context.Background(), "?after=1")
+	s.waitDone(c, done)
+	c.Check(decodeChangeUpdates(c, rec), DeepEquals, []client.ChangeUpdate{
+		{Seq: 2, Kind: client.ChangeUpdateTaskStatus, ChangeID: s.chgID, TaskID: s.t2ID, Status: "Done", Ready: true},
+		{Seq: 2, Kind: client.ChangeUpdateTaskProgress, ChangeID: s.chgID, TaskID: s.t1ID, Progress: &client.TaskProgress{Label: "", Done: 1, Total: 1}},
+		{Seq: 2, Kind: client.ChangeUpdateTaskProgress, ChangeID: s.chgID, TaskID: s.t2ID, Progress: &client.TaskProgress{Label: "linking", Done: 1, Total: 2}},
+		{Seq: 2, Kind: client.ChangeUpdateChangeStatus, ChangeID: s.chgID, Status: "Done", Ready: true},
+	})
+}
+
+func (s *changeEventsSuite) TestResumeReadyNotRecorded(c *C) {
+	s.finish()
+
+	// the terminal status is sent even if the transition to it was
+	// not recorded
+	rec, done := s.stream(c, context.Background(), "?after=5")
+	s.waitDone(c, done)
+	updates := decodeChangeUpdates(c, rec)
+	c.Assert(updates, Not(HasLen), 0)
+	c.Check(updates[len(updates)-1], DeepEquals, client.ChangeUpdate{
+		Seq: 5, Kind: client.ChangeUpdateChangeStatus, ChangeID: s.chgID, Status: "Done", Ready: true,
+	})
+}
+
+func (s *changeEventsSuite) TestProgress(c *C) {
+	restore := daemon.MockChangeEventsIntervals(time.Millisecond, time.Hour)
+	defer restore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rec, done := s.stream(c, ctx, "")
+	s.waitFlush(c, rec)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	st.Task(s.t2ID).SetProgress("linking", 2, 2)
+	st.Unlock()
+	s.waitFlush(c, rec)
+	cancel()
+	s.waitDone(c, done)
+
+	updates := decodeChangeUpdates(c, rec)
+	c.Assert(updates, HasLen, 6)
+	c.Check(updates[5], DeepEquals, client.ChangeUpdate{
+		Kind: client.ChangeUpdateTaskProgress, ChangeID: s.chgID, TaskID: s.t2ID, Progress: &client.TaskProgress{Label: "linking", Done: 2, Total: 2},
+	})
+}
+
+func (s *changeEventsSuite) TestHeartbeat(c *C) {
+	restore := daemon.MockChangeEventsIntervals(time.Hour, time.Millisecond)
+	defer restore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rec, done := s.stream(c, ctx, "")
+	s.waitFlush(c, rec)
+	s.waitFlush(c, rec)
+	cancel()
+	s.waitDone(c, done)
+
+	updates := decodeChangeUpdates(c, rec)
+	c.Assert(len(updates) > 5, Equals, true)
+	for _, update := range updates[5:] {
+		c.Check(update, DeepEquals, client.ChangeUpdate{
+			Kind: client.ChangeUpdateHeartbeat, ChangeID: s.chgID,
+		})
+	}
+}
+
+func (s *changeEventsSuite) TestErrors(c *C) {
+	req, err := http.NewRequest("GET", "/v2/changes/99/events", nil)
+	c.Assert(err, IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, Equals, 404)
+	c.Check(rspe.Message, Equals, `cannot find change with id "99"`)
+
+	req, err = http.NewRequest("GET", "/v2/changes/"+s.chgID+"/events?after=x", nil)
+	c.Assert(err, IsNil)
+	rspe = s.errorReq(c, req, nil)
+	c.Check(rspe.Status, Equals, 400)
+	c.Check(rspe.Message, Equals, `invalid after parameter: "x"`)
+}
@@ -20,6 +20,7 @@
 package daemon
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"sort"
@@ -31,11 +32,21 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 )
 
-var connectionsCmd = &Command{
-	Path:       "/v2/connections",
-	GET:        getConnections,
-	ReadAccess: openAccess{},
-}
+var (
+	connectionsCmd = &Command{
+		Path:       "/v2/connections",
+		GET:        getConnections,
+		ReadAccess: openAccess{},
+	}
+
+	connectionOverridesCmd = &Command{
+		Path:        "/v2/connections/overrides",
+		GET:         getConnectionOverrides,
+		POST:        postConnectionOverrides,
+		ReadAccess:  openAccess{},
+		WriteAccess: authenticatedAccess{Polkit: polkitActionManageInterfaces},
+	}
+)
 
 type collectFilter struct {
 	snapName  string
@@ -136,21 +147,31 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 			return nil, err
 		}
 
-		// plug or slot not in the repository, e.g. cref is referring to an
-		// inactive revision of the snap; this can happen when the new revision
-		// doesn't have given plug/slot anymore (but the connection state is
-		// kept in case of revert).
-		// XXX: if we decide to show such connections with special tags, then
-		// this needs to be tweaked together with collectFilter definition and
-		// connectionJSON output.
-		if repo.Plug(cref.PlugRef.Snap, cref.PlugRef.Name) == nil || repo.Slot(cref.SlotRef.Snap, cref.SlotRef.Name) == nil {
+		if !filter.plugOrConnectedSlotMatches(&cref.PlugRef, nil) && !filter.slotOrConnectedPlugMatches(&cref.SlotRef, nil) {
+			continue
+		}
+		if !filter.ifaceMatches(cstate.Interface) {
 			continue
 		}
 
-		if !filter.plugOrConnectedSlotMatches(&cref.PlugRef, nil) && !filter.slotOrConnectedPlugMatches(&cref.SlotRef, nil) {
+		// plug or slot not in the repository, e.g. cref is referring to an
+		// inactive revision of the snap; this can happen when the new revision
+		// doesn't have given plug/slot anymore (but the connection state is
+		// kept in case of revert). Connections flagged as stale after a
+		// refresh are reported as such, others are ignored.
+		if cstate.Stale && !cstate.Undesired {
+			connsjson.Stale = append(connsjson.Stale, connectionJSON{
+				Slot:      interfaces.SlotRef{Snap: cref.SlotRef.Snap, Name: cref.SlotRef.Name},
+				Plug:      interfaces.PlugRef{Snap: cref.PlugRef.Snap, Name: cref.PlugRef.Name},
+				Manual:    !cstate.Auto,
+				Gadget:    cstate.ByGadget,
+				Interface: cstate.Interface,
+				PlugAttrs: mergeAttrs(cstate.StaticPlugAttrs, cstate.DynamicPlugAttrs),
+				SlotAttrs: mergeAttrs(cstate.StaticSlotAttrs, cstate.DynamicSlotAttrs),
+			})
 			continue
 		}
-		if !filter.ifaceMatches(cstate.Interface) {
+		if repo.Plug(cref.PlugRef.Snap, cref.PlugRef.Name) == nil || repo.Slot(cref.SlotRef.Snap, cref.SlotRef.Name) == nil {
 			continue
 		}
 		plugRef := interfaces.PlugRef{Snap: cref.PlugRef.Snap, Name: cref.PlugRef.Name}
@@ -232,6 +253,38 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 	return &connsjson, nil
 }
 
+// collectUnsatisfiedPlugs returns the plugs which are neither connected nor
+// were explicitly disconnected, with the reason why they are not connected.
+func collectUnsatisfiedPlugs(ifaceMgr *ifacestate.InterfaceManager, filter collectFilter) (*connectionsJSON, error) {
+	unsatisfied, err := ifaceMgr.UnsatisfiedPlugs()
+	if err != nil {
+		return nil, err
+	}
+	connsjson := &connectionsJSON{
+		Established: []connectionJSON{},
+		Plugs:       []*plugJSON{},
+		Slots:       []*slotJSON{},
+		Unsatisfied: make([]unsatisfiedPlugJSON, 0, len(unsatisfied)),
+	}
+	for _, u := range unsatisfied {
+		if !filter.ifaceMatches(u.Interface) || !filter.plugOrConnectedSlotMatches(&u.Plug, nil) {
+			continue
+		}
+		uj := unsatisfiedPlugJSON{
+			Plug:      u.Plug,
+			Interface: u.Interface,
+			Reason:    u.Reason,
+			Attempts:  u.Attempts,
+		}
+		if !u.NextRetry.IsZero() {
+			nextRetry := u.NextRetry
+			uj.NextRetry = &nextRetry
+		}
+		connsjson.Unsatisfied = append(connsjson.Unsatisfied, uj)
+	}
+	return connsjson, nil
+}
+
 type byCrefConnJSON []connectionJSON
 
 func (b byCrefConnJSON) Len() int      { return len(b) }
@@ -258,7 +311,7 @@ func getConnections(c *Command, r *http.Request, user *auth.UserState) Response
 	snapName := query.Get("snap")
 	ifaceName := query.Get("interface")
 	qselect := query.Get("select")
-	if qselect != "all" && qselect != "" {
+	if qselect != "all" && qselect != "unsatisfied" && qselect != "" {
 		return BadRequest("unsupported select qualifier")
 	}
 	onlyConnected := qselect == ""
@@ -273,6 +326,17 @@ func getConnections(c *Command, r *http.Request, user *auth.UserState) Response
 		}
 	}
 
+	if qselect == "unsatisfied" {
+		connsjson, err := collectUnsatisfiedPlugs(c.d.overlord.InterfaceManager(), collectFilter{
+			snapName:  snapName,
+			ifaceName: ifaceName,
+		})
+		if err != nil {
+			return InternalError("collecting unsatisfied plugs failed: %v", err)
+		}
+		return SyncResponse(connsjson)
+	}
+
 	connsjson, err := collectConnections(c.d.overlord.InterfaceManager(), collectFilter{
 		snapName:  snapName,
 		ifaceName: ifaceName,
@@ -286,3 +350,69 @@ func getConnections(c *Command, r *http.Request, user *auth.UserState) Response
 
 	return SyncResponse(connsjson)
 }
+
+func getConnectionOverrides(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	overrides, err := ifacestate.ConnectionOverrides(st)
+	if err != nil {
+		return InternalError("cannot get connection overrides: %v", err)
+	}
+	overridesJSON := make([]connectionOverrideJSON, 0, len(overrides))
+	for _, o := range overrides {
+		overridesJSON = append(overridesJSON, connectionOverrideJSON{
+			Plug:      o.Ref.PlugRef,
+			Slot:      o.Ref.SlotRef,
+			Interface: o.Interface,
+			Kind:      o.Kind,
+		})
+	}
+	return SyncResponse(overridesJSON)
+}
+
+type connectionOverrideAction struct {
+	Action string `json:"action"`
+	connectionOverrideJSON
+}
+
+// postConnectionOverrides adds or removes a connection override.
+func postConnectionOverrides(c *Command, r *http.Request, user *auth.UserState) Response {
+	var a connectionOverrideAction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&a); err != nil {
+		return BadRequest("cannot decode request body into a connection override action: %v", err)
+	}
+	if a.Plug.Snap == "" || a.Plug.Name == "" || a.Slot.Snap == "" || a.Slot.Name == "" {
+		return BadRequest("connection override requires a plug and a slot")
+	}
+	a.Plug.Snap = ifacestate.RemapSnapFromRequest(a.Plug.Snap)
+	a.Slot.Snap = ifacestate.RemapSnapFromRequest(a.Slot.Snap)
+	connRef := &interfaces.ConnRef{PlugRef: a.Plug, SlotRef: a.Slot}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var err error
+	switch a.Action {
+	case "add":
+		err = ifacestate.AddConnectionOverride(st, &ifacestate.ConnectionOverride{
+			Ref:       *connRef,
+			Interface: a.Interface,
+			Kind:      a.Kind,
+		})
+	case "remove":
+		err = ifacestate.RemoveConnectionOverride(st, connRef, a.Kind)
+	default:
+		return BadRequest("unsupported connection override action: %q", a.Action)
+	}
+	if errors.Is(err, ifacestate.ErrNoConnectionOverride) {
+		return NotFound("cannot remove %s override of connection %q: %v", a.Kind, connRef.ID(), err)
+	}
+	if err != nil {
+		return errToResponse(err, []string{a.Plug.Snap, a.Slot.Snap}, BadRequest, "%v")
+	}
+	return SyncResponse(nil)
+}
@@ -24,9 +24,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 
 	"gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/builtin"
@@ -348,6 +350,76 @@ func (s *interfacesSuite) TestConnectionsMissingPlugSlotFilteredOut(c *check.C)
 	}
 }
 
+func (s *interfacesSuite) TestConnectionsMissingPlugSlotStale(c *check.C) {
+	restore := builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"})
+	defer restore()
+
+	d := s.daemon(c)
+
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.testConnectionsConnected(c, d, "/v2/connections?snap=producer", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test",
+		},
+		"consumer:plug2 producer:slot": map[string]interface{}{
+			"interface": "test",
+			"auto":      true,
+			"stale":     true,
+		},
+	},
+		[]string{"consumer:plug producer:slot"},
+		map[string]interface{}{
+			"result": map[string]interface{}{
+				"plugs": []interface{}{
+					map[string]interface{}{
+						"snap":      "consumer",
+						"plug":      "plug",
+						"interface": "test",
+						"attrs":     map[string]interface{}{"key": "value"},
+						"apps":      []interface{}{"app"},
+						"label":     "label",
+						"connections": []interface{}{
+							map[string]interface{}{"snap": "producer", "slot": "slot"},
+						},
+					},
+				},
+				"slots": []interface{}{
+					map[string]interface{}{
+						"snap":      "producer",
+						"slot":      "slot",
+						"interface": "test",
+						"attrs":     map[string]interface{}{"key": "value"},
+						"apps":      []interface{}{"app"},
+						"label":     "label",
+						"connections": []interface{}{
+							map[string]interface{}{"snap": "consumer", "plug": "plug"},
+						},
+					},
+				},
+				"established": []interface{}{
+					map[string]interface{}{
+						"plug":      map[string]interface{}{"snap": "consumer", "plug": "plug"},
+						"slot":      map[string]interface{}{"snap": "producer", "slot": "slot"},
+						"manual":    true,
+						"interface": "test",
+					},
+				},
+				"stale": []interface{}{
+					map[string]interface{}{
+						"plug":      map[string]interface{}{"snap": "consumer", "plug": "plug2"},
+						"slot":      map[string]interface{}{"snap": "producer", "slot": "slot"},
+						"interface": "test",
+					},
+				},
+			},
+			"status":      "OK",
+			"status-code": 200.0,
+			"type":        "sync",
+		})
+}
+
 func (s *interfacesSuite) TestConnectionsBySnapAlias(c *check.C) {
 	restore := builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"})
 	defer restore()
@@ -863,6 +935,59 @@ func (s *interfacesSuite) TestConnectionsOnlyUndesired(c *check.C) {
 	})
 }
 
+func (s *interfacesSuite) TestConnectionsUnsatisfied(c *check.C) {
+	restore := builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"})
+	defer restore()
+
+	d := s.daemon(c)
+
+	// no slot is available for the plug
+	s.mockSnap(c, consumerYaml)
+
+	st := d.Overlord().State()
+	st.Lock()
+	st.Set("auto-connect-retries", map[string]interface{}{
+		"consumer:plug": map[string]interface{}{
+			"attempts":   2,
+			"next-retry": "2023-04-01T12:00:00Z",
+		},
+	})
+	st.Unlock()
+
+	expected := map[string]interface{}{
+		"result": map[string]interface{}{
+			"established": []interface{}{},
+			"plugs":       []interface{}{},
+			"slots":       []interface{}{},
+			"unsatisfied": []interface{}{
+				map[string]interface{}{
+					"plug":       map[string]interface{}{"snap": "consumer", "plug": "plug"},
+					"interface":  "test",
+					"reason":     "no-candidate-slot",
+					"attempts":   2.0,
+					"next-retry": "2023-04-01T12:00:00Z",
+				},
+			},
+		},
+		"status":      "OK",
+		"status-code": 200.0,
+		"type":        "sync",
+	}
+	s.testConnections(c, "/v2/connections?select=unsatisfied", expected)
+	s.testConnections(c, "/v2/connections?select=unsatisfied&snap=consumer&interface=test", expected)
+
+	s.testConnections(c, "/v2/connections?select=unsatisfied&interface=other", map[string]interface{}{
+		"result": map[string]interface{}{
+			"established": []interface{}{},
+			"plugs":       []interface{}{},
+			"slots":       []interface{}{},
+		},
+		"status":      "OK",
+		"status-code": 200.0,
+		"type":        "sync",
+	})
+}
+
 func (s *interfacesSuite) TestConnectionsHotplugGone(c *check.C) {
 	restore := builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"})
 	defer restore()
@@ -1042,3 +1167,144 @@ slots:
 		"type":        "sync",
 	})
 }
+
+// Tests for /v2/connections/overrides
+
+func (s *interfacesSuite) TestGetConnectionOverrides(c *check.C) {
+	d := s.daemon(c)
+
+	st := d.Overlord().State()
+	st.Lock()
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot":   map[string]interface{}{"interface": "test", "auto": true, "by-gadget": true},
+		"consumer:plug2 producer:slot2": map[string]interface{}{"interface": "test2", "auto": true, "undesired": true},
+		"consumer:plug3 producer:slot3": map[string]interface{}{"interface": "test3", "auto": true},
+	})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/connections/overrides", nil)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	s.req(c, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	var body map[string]interface{}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &body), check.IsNil)
+	c.Check(body["result"], check.DeepEquals, []interface{}{
+		map[string]interface{}{
+			"plug":      map[string]interface{}{"snap": "consumer", "plug": "plug"},
+			"slot":      map[string]interface{}{"snap": "producer", "slot": "slot"},
+			"interface": "test",
+			"kind":      "by-gadget",
+		},
+		map[string]interface{}{
+			"plug":      map[string]interface{}{"snap": "consumer", "plug": "plug2"},
+			"slot":      map[string]interface{}{"snap": "producer", "slot": "slot2"},
+			"interface": "test2",
+			"kind":      "undesired",
+		},
+	})
+}
+
+func (s *interfacesSuite) TestGetConnectionOverridesEmpty(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/connections/overrides", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, []daemon.ConnectionOverrideJSON{})
+}
+
+func (s *interfacesSuite) TestPostConnectionOverrides(c *check.C) {
+	d := s.daemon(c)
+
+	st := d.Overlord().State()
+	st.Lock()
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "auto": true},
+	})
+	st.Unlock()
+
+	for _, body := range []string{
+		`{"action": "add", "kind": "by-gadget", "plug": {"snap": "consumer", "plug": "plug"}, "slot": {"snap": "producer", "slot": "slot"}}`,
+		`{"action": "add", "kind": "undesired", "interface": "test2", "plug": {"snap": "consumer", "plug": "plug2"}, "slot": {"snap": "producer", "slot": "slot2"}}`,
+	} {
+		req, err := http.NewRequest("POST", "/v2/connections/overrides", strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+		s.syncReq(c, req, nil)
+	}
+
+	st.Lock()
+	var conns map[string]interface{}
+	c.Assert(st.Get("conns", &conns), check.IsNil)
+	st.Unlock()
+	c.Check(conns, check.DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot":   map[string]interface{}{"interface": "test", "auto": true, "by-gadget": true},
+		"consumer:plug2 producer:slot2": map[string]interface{}{"interface": "test2", "auto": true, "undesired": true},
+	})
+
+	for _, body := range []string{
+		`{"action": "remove", "kind": "by-gadget", "plug": {"snap": "consumer", "plug": "plug"}, "slot": {"snap": "producer", "slot": "slot"}}`,
+		`{"action": "remove", "kind": "undesired", "plug": {"snap": "consumer", "plug": "plug2"}, "slot": {"snap": "producer", "slot": "slot2"}}`,
+	} {
+		req, err := http.NewRequest("POST", "/v2/connections/overrides", strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+		s.syncReq(c, req, nil)
+	}
+
+	st.Lock()
+	conns = nil
+	c.Assert(st.Get("conns", &conns), check.IsNil)
+	st.Unlock()
+	c.Check(conns, check.DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "auto": true},
+	})
+}
+
+func (s *interfacesSuite) TestPostConnectionOverridesErrors(c *check.C) {
+	d := s.daemon(c)
+
+	st := d.Overlord().State()
+	st.Lock()
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "auto": true},
+	})
+	st.Unlock()
+
+	for _, t := range []struct {
+		body   string
+		status int
+		msg    string
+	}{
+		{`garbage`, 400, `cannot decode request body into a connection override action: .*`},
+		{`{"action": "add", "kind": "undesired", "plug": {"snap": "consumer", "plug": "plug"}}`, 400, `connection override requires a plug and a slot`},
+		{`{"action": "frob", "kind": "undesired", "plug": {"snap": "consumer", "plug": "plug"}, "slot": {"snap": "producer", "slot": "slot"}}`, 400, `unsupported connection override action: "frob"`},
+		{`{"action": "add", "kind": "frob", "plug": {"snap": "consumer", "plug": "plug"}, "slot": {"snap": "producer", "slot": "slot"}}`, 400, `unsupported connection override kind "frob"`},
+		{`{"action": "add", "kind": "undesired", "plug": {"snap": "consumer", "plug": "plug"}, "slot": {"snap": "producer", "slot": "slot"}}`, 400, `cannot record undesired connection "consumer:plug producer:slot": connection is established, disconnect it instead`},
+		{`{"action": "remove", "kind": "undesired", "plug": {"snap": "consumer", "plug": "plug"}, "slot": {"snap": "producer", "slot": "slot"}}`, 404, `cannot remove undesired override of connection "consumer:plug producer:slot": no such connection override`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/connections/overrides", strings.NewReader(t.body))
+		c.Assert(err, check.IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, t.status, check.Commentf(t.body))
+		c.Check(rspe.Message, check.Matches, t.msg, check.Commentf(t.body))
+	}
+}
+
+func (s *interfacesSuite) TestPostConnectionOverridesConflict(c *check.C) {
+	d := s.daemon(c)
+
+	st := d.Overlord().State()
+	st.Lock()
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "auto": true},
+	})
+	st.Unlock()
+	s.simulateConflict("producer")
+
+	body := `{"action": "add", "kind": "by-gadget", "plug": {"snap": "consumer", "plug": "plug"}, "slot": {"snap": "producer", "slot": "slot"}}`
+	req, err := http.NewRequest("POST", "/v2/connections/overrides", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 409)
+	c.Check(rspe.Kind, check.Equals, client.ErrorKindSnapChangeConflict)
+}
@@ -92,11 +92,21 @@ func checkConnectivity(st *state.State) Response {
 	return SyncResponse(status)
 }
 
+func getStoreURL(st *state.State) Response {
+	theStore := snapstate.Store(st, nil)
+	st.Unlock()
+	defer st.Lock()
+	return SyncResponse(map[string]interface{}{
+		"url": theStore.ActiveBaseURL().String(),
+	})
+}
+
 type changeTimings struct {
 	Status         string                `json:"status,omitempty"`
 	Kind           string                `json:"kind,omitempty"`
 	Summary        string                `json:"summary,omitempty"`
 	Lane           int                   `json:"lane,omitempty"`
+	Snap           string                `json:"snap,omitempty"`
 	ReadyTime      time.Time             `json:"ready-time,omitempty"`
 	DoingTime      time.Duration         `json:"doing-time,omitempty"`
 	UndoingTime    time.Duration         `json:"undoing-time,omitempty"`
@@ -140,8 +150,12 @@ func collectChangeTimings(st *state.State, changeID string) (map[string]*changeT
 
 	doingTimingsByTask := make(map[string][]*timings.TimingJSON)
 	undoingTimingsByTask := make(map[string][]*timings.TimingJSON)
+	snapByTask := make(map[string]string)
 	for _, tm := range stateTimings {
 		taskID := tm.Tags["task-id"]
+		if snapName, ok := tm.Tags["snap"]; ok {
+			snapByTask[taskID] = snapName
+		}
 		if status, ok := tm.Tags["task-status"]; ok {
 			switch {
 			case status == state.DoingStatus.String():
@@ -161,6 +175,7 @@ func collectChangeTimings(st *state.State, changeID string) (map[string]*changeT
 			Status:         t.Status().String(),
 			Summary:        t.Summary(),
 			Lane:           minLane(t),
+			Snap:           snapByTask[t.ID()],
 			ReadyTime:      t.ReadyTime(),
 			DoingTime:      t.DoingTime(),
 			UndoingTime:    t.UndoingTime(),
@@ -324,11 +339,39 @@ func getDisks(st *state.State) Response {
 	return SyncResponse(vols)
 }
 
+var disksAllPhysicalDisks = disks.AllPhysicalDisks
+
+// collectDiskHealth returns the health of the storage media of all physical
+// disks, which includes the boot disk.
+func collectDiskHealth() ([]*disks.MediaHealth, error) {
+	allDisks, err := disksAllPhysicalDisks()
+	if err != nil {
+		return nil, err
+	}
+	health := make([]*disks.MediaHealth, 0, len(allDisks))
+	for _, d := range allDisks {
+		health = append(health, disks.HealthOfDisk(d))
+	}
+	return health, nil
+}
+
+func getDiskHealth(st *state.State) Response {
+	// querying the devices does not need the state
+	st.Unlock()
+	defer st.Lock()
+
+	health, err := collectDiskHealth()
+	if err != nil {
+		return InternalError("cannot get disk health: %v", err)
+	}
+	return SyncResponse(health)
+}
+
 func createRecovery(st *state.State, label string) Response {
 	if label == "" {
 		return BadRequest("cannot create a recovery system with no label")
 	}
-	chg, err := devicestate.CreateRecoverySystem(st, label)
+	chg, err := devicestate.CreateRecoverySystem(st, label, devicestate.CreateRecoverySystemOptions{TestSystem: true})
 	if err != nil {
 		return InternalError("cannot create recovery system %q: %v", label, err)
 	}
@@ -347,6 +390,8 @@ func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 		return getBaseDeclaration(st)
 	case "connectivity":
 		return checkConnectivity(st)
+	case "store-url":
+		return getStoreURL(st)
 	case "model":
 		model, err := c.d.overlord.DeviceManager().Model()
 		if err != nil {
@@ -368,6 +413,8 @@ func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 		return getGadgetDiskMapping(st)
 	case "disks":
 		return getDisks(st)
+	case "disk-health":
+		return getDiskHealth(st)
 	default:
 		return BadRequest("unknown debug aspect %q", aspect)
 	}
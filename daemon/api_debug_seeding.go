@@ -23,6 +23,8 @@ import (
 	"errors"
 	"time"
 
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil/disks"
 	"github.com/snapcore/snapd/overlord/state"
 )
 
@@ -58,10 +60,23 @@ type seedingInfo struct {
 	// preseeded image.
 	SeedRestartSystemKey interface{} `json:"seed-restart-system-key,omitempty"`
 
+	// EssentialSnapTimings are how long seeding each of the essential
+	// snaps took, in seeding order, once seeding finished.
+	EssentialSnapTimings []essentialSnapTiming `json:"essential-snap-timings,omitempty"`
+
 	// SeedError is set if no seed change succeeded yet and at
 	// least one was in error. It is set to the error of the
 	// oldest known in error one.
 	SeedError string `json:"seed-error,omitempty"`
+
+	// DiskHealth is the health of the storage media of the physical
+	// disks, when it could be obtained.
+	DiskHealth []*disks.MediaHealth `json:"disk-health,omitempty"`
+}
+
+type essentialSnapTiming struct {
+	Snap     string        `json:"snap"`
+	Duration time.Duration `json:"duration"`
 }
 
 func getSeedingInfo(st *state.State) Response {
@@ -82,6 +97,11 @@ func getSeedingInfo(st *state.State) Response {
 		return InternalError(err.Error())
 	}
 
+	var essentialSnapTimings []essentialSnapTiming
+	if err := st.Get("seed-essential-snap-timings", &essentialSnapTimings); err != nil && !errors.Is(err, state.ErrNoState) {
+		return InternalError(err.Error())
+	}
+
 	var seedError string
 	var seedErrorChangeTime time.Time
 	if !seeded {
@@ -104,6 +124,7 @@ func getSeedingInfo(st *state.State) Response {
 		Preseeded:            preseeded,
 		PreseedSystemKey:     preseedSysKey,
 		SeedRestartSystemKey: seedRestartSysKey,
+		EssentialSnapTimings: essentialSnapTimings,
 	}
 
 	for _, t := range []struct {
@@ -125,6 +146,18 @@ func getSeedingInfo(st *state.State) Response {
 		}
 	}
 
+	// failing media is a common cause of seeding failures, querying the
+	// devices does not need the state
+	st.Unlock()
+	diskHealth, err := collectDiskHealth()
+	st.Lock()
+	if err != nil {
+		logger.Debugf("cannot get disk health: %v", err)
+	}
+	if len(diskHealth) > 0 {
+		data.DiskHealth = diskHealth
+	}
+
 	// XXX: consistency & validity checks, e.g. if preseeded, then need to have
 	// preseed-start-time, preseeded-time, preseed-system-key etc?
 
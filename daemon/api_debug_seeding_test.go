@@ -20,12 +20,14 @@
 package daemon_test
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/osutil/disks"
 	"github.com/snapcore/snapd/overlord/state"
 )
 
@@ -38,6 +40,10 @@ type seedingDebugSuite struct {
 func (s *seedingDebugSuite) SetUpTest(c *C) {
 	s.apiBaseSuite.SetUpTest(c)
 	s.daemonWithOverlordMock()
+
+	s.AddCleanup(daemon.MockDisksAllPhysicalDisks(func() ([]disks.Disk, error) {
+		return nil, nil
+	}))
 }
 
 func (s *seedingDebugSuite) getSeedingDebug(c *C) interface{} {
@@ -85,6 +91,11 @@ func (s *seedingDebugSuite) TestSeedingDebug(c *C) {
 	st.Set("preseed-time", preseedTime)
 	st.Set("seed-time", seedTime)
 
+	st.Set("seed-essential-snap-timings", []map[string]interface{}{
+		{"snap": "snapd", "duration": 2 * time.Second},
+		{"snap": "pc-kernel", "duration": 3 * time.Second},
+	})
+
 	st.Unlock()
 
 	data := s.getSeedingDebug(c)
@@ -97,6 +108,10 @@ func (s *seedingDebugSuite) TestSeedingDebug(c *C) {
 		PreseedTime:          &preseedTime,
 		SeedRestartTime:      &seedRestartTime,
 		SeedTime:             &seedTime,
+		EssentialSnapTimings: []daemon.EssentialSnapTiming{
+			{Snap: "snapd", Duration: 2 * time.Second},
+			{Snap: "pc-kernel", Duration: 3 * time.Second},
+		},
 	})
 }
 
@@ -120,6 +135,40 @@ func (s *seedingDebugSuite) TestSeedingDebugSeededNoTimes(c *C) {
 	})
 }
 
+func (s *seedingDebugSuite) TestSeedingDebugDiskHealth(c *C) {
+	st := s.d.Overlord().State()
+	s.AddCleanup(daemon.MockDisksAllPhysicalDisks(func() ([]disks.Disk, error) {
+		// the state is not locked while the devices are queried
+		st.Lock()
+		st.Unlock()
+		return []disks.Disk{
+			&disks.MockDiskMapping{
+				DevNode: "/dev/vda",
+				DevPath: "/sys/devices/pci0000:00/0000:00:03.0/virtio1/block/vda",
+			},
+		}, nil
+	}))
+
+	data := s.getSeedingDebug(c)
+	c.Check(data, DeepEquals, &daemon.SeedingInfo{
+		DiskHealth: []*disks.MediaHealth{{
+			Device: "/dev/vda",
+			Status: disks.HealthUnavailable,
+			Reason: "unsupported media",
+		}},
+	})
+}
+
+func (s *seedingDebugSuite) TestSeedingDebugDiskHealthError(c *C) {
+	s.AddCleanup(daemon.MockDisksAllPhysicalDisks(func() ([]disks.Disk, error) {
+		return nil, errors.New("boom")
+	}))
+
+	// the rest of the report is still available
+	data := s.getSeedingDebug(c)
+	c.Check(data, DeepEquals, &daemon.SeedingInfo{})
+}
+
 func (s *seedingDebugSuite) TestSeedingDebugPreseededStillSeeding(c *C) {
 	preseedStartTime, err := time.Parse(time.RFC3339, "2020-01-01T10:00:00Z")
 	c.Assert(err, IsNil)
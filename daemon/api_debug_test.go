@@ -24,12 +24,14 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/osutil/disks"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
@@ -99,6 +101,62 @@ func (s *postDebugSuite) TestDebugConnectivityUnhappy(c *check.C) {
 	})
 }
 
+func (s *postDebugSuite) TestDebugStoreURL(c *check.C) {
+	_ = s.daemon(c)
+
+	u, err := url.Parse("http://proxy.internal:8080")
+	c.Assert(err, check.IsNil)
+	s.activeBaseURL = u
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=store-url", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, map[string]interface{}{
+		"url": "http://proxy.internal:8080",
+	})
+}
+
+func (s *postDebugSuite) TestGetDebugDiskHealth(c *check.C) {
+	_ = s.daemon(c)
+
+	restore := daemon.MockDisksAllPhysicalDisks(func() ([]disks.Disk, error) {
+		return []disks.Disk{
+			&disks.MockDiskMapping{
+				DevNode: "/dev/vda",
+				DevPath: "/sys/devices/pci0000:00/0000:00:03.0/virtio1/block/vda",
+			},
+		}, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=disk-health", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, []*disks.MediaHealth{{
+		Device: "/dev/vda",
+		Status: disks.HealthUnavailable,
+		Reason: "unsupported media",
+	}})
+}
+
+func (s *postDebugSuite) TestGetDebugDiskHealthError(c *check.C) {
+	_ = s.daemon(c)
+
+	restore := daemon.MockDisksAllPhysicalDisks(func() ([]disks.Disk, error) {
+		return nil, errors.New("boom")
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=disk-health", nil)
+	c.Assert(err, check.IsNil)
+
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 500)
+	c.Check(rspe.Message, check.Equals, "cannot get disk health: boom")
+}
+
 func (s *postDebugSuite) TestGetDebugBaseDeclaration(c *check.C) {
 	_ = s.daemon(c)
 
@@ -143,8 +201,10 @@ func (s *postDebugSuite) getDebugTimings(c *check.C, request string) []interface
 	chg3 := st.NewChange("foo", "...")
 	task3 := st.NewTask("bar", "...")
 	chg3.AddTask(task3)
+	task3.SetStatus(state.DoingStatus)
 
 	tm1 := state.TimingsForTask(task3)
+	state.TagTimingsWithSnap(tm1, "some-snap")
 	sp1 := tm1.StartSpan("span", "span...")
 	sp1.Stop()
 	tm1.Save(st)
@@ -184,6 +244,20 @@ func (s *postDebugSuite) TestGetDebugTimingsSingleChange(c *check.C) {
 	c.Check(tmData["change-timings"], check.NotNil)
 }
 
+func (s *postDebugSuite) TestGetDebugTimingsSingleChangeWithSnap(c *check.C) {
+	dataJSON := s.getDebugTimings(c, "/v2/debug?aspect=change-timings&change-id=3")
+
+	c.Assert(dataJSON, check.HasLen, 1)
+	tmData := dataJSON[0].(map[string]interface{})
+	c.Check(tmData["change-id"], check.DeepEquals, "3")
+	chgTimings := tmData["change-timings"].(map[string]interface{})
+	c.Assert(chgTimings, check.HasLen, 1)
+	taskTimings := chgTimings["3"].(map[string]interface{})
+	c.Check(taskTimings["kind"], check.Equals, "bar")
+	c.Check(taskTimings["snap"], check.Equals, "some-snap")
+	c.Check(taskTimings["doing-timings"], check.HasLen, 1)
+}
+
 func (s *postDebugSuite) TestGetDebugTimingsEnsureLatest(c *check.C) {
 	dataJSON := s.getDebugTimings(c, "/v2/debug?aspect=change-timings&ensure=foo&all=false")
 	c.Assert(dataJSON, check.HasLen, 1)
@@ -444,6 +444,7 @@ func (s *interfacesSuite) TestConnectPlugChangeConflict(c *check.C) {
 			"kind":    "snap-change-conflict",
 			"value": map[string]interface{}{
 				"change-kind": "manip",
+				"change-id":   "1",
 				"snap-name":   "consumer",
 			},
 		},
@@ -868,6 +869,7 @@ func (s *interfacesSuite) TestDisconnectConflict(c *check.C) {
 			"kind":    "snap-change-conflict",
 			"value": map[string]interface{}{
 				"change-kind": "manip",
+				"change-id":   "1",
 				"snap-name":   "consumer",
 			},
 		},
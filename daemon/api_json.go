@@ -20,6 +20,8 @@
 package daemon
 
 import (
+	"time"
+
 	"github.com/snapcore/snapd/interfaces"
 )
 
@@ -88,4 +90,28 @@ type connectionsJSON struct {
 	Undesired   []connectionJSON `json:"undesired,omitempty"`
 	Plugs       []*plugJSON      `json:"plugs"`
 	Slots       []*slotJSON      `json:"slots"`
+	// Stale lists the connections that cannot be restored as their plug
+	// or slot, or their interface, is gone after a refresh.
+	Stale []connectionJSON `json:"stale,omitempty"`
+	// Unsatisfied is only set when selecting unsatisfied plugs.
+	Unsatisfied []unsatisfiedPlugJSON `json:"unsatisfied,omitempty"`
+}
+
+// unsatisfiedPlugJSON aids in marshaling information about a plug which is
+// not connected into JSON.
+type unsatisfiedPlugJSON struct {
+	Plug      interfaces.PlugRef `json:"plug"`
+	Interface string             `json:"interface"`
+	Reason    string             `json:"reason"`
+	Attempts  int                `json:"attempts,omitempty"`
+	NextRetry *time.Time         `json:"next-retry,omitempty"`
+}
+
+// connectionOverrideJSON aids in marshaling information about a connection
+// override into JSON.
+type connectionOverrideJSON struct {
+	Plug      interfaces.PlugRef `json:"plug"`
+	Slot      interfaces.SlotRef `json:"slot"`
+	Interface string             `json:"interface,omitempty"`
+	Kind      string             `json:"kind"`
 }
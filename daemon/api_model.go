@@ -28,6 +28,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/snapcore/snapd/asserts"
@@ -46,14 +47,14 @@ var (
 		GET:         getSerial,
 		POST:        postSerial,
 		ReadAccess:  openAccess{},
-		WriteAccess: rootAccess{},
+		WriteAccess: rootOrPolkitAccess{Polkit: polkitActionManageSerial},
 	}
 	modelCmd = &Command{
 		Path:        "/v2/model",
 		POST:        postModel,
 		GET:         getModel,
 		ReadAccess:  openAccess{},
-		WriteAccess: rootAccess{},
+		WriteAccess: rootOrPolkitAccess{Polkit: polkitActionRemodel},
 	}
 )
 
@@ -64,6 +65,7 @@ var (
 
 type postModelData struct {
 	NewModel string `json:"new-model"`
+	Offline  bool   `json:"offline,omitempty"`
 }
 
 func postModel(c *Command, r *http.Request, _ *auth.UserState) Response {
@@ -116,7 +118,10 @@ func storeRemodel(c *Command, r *http.Request) Response {
 	st.Lock()
 	defer st.Unlock()
 
-	chg, err := devicestateRemodel(st, newModel, nil, nil)
+	opts := devicestate.RemodelOptions{
+		Offline: data.Offline,
+	}
+	chg, err := devicestateRemodel(st, newModel, nil, nil, opts)
 	if err != nil {
 		return BadRequest("cannot remodel device: %v", err)
 	}
@@ -157,8 +162,8 @@ func readOfflineRemodelForm(form *Form) (*asserts.Model, []*uploadedSnap, *asser
 }
 
 func startOfflineRemodelChange(st *state.State, newModel *asserts.Model,
-	snapFiles []*uploadedSnap, batch *asserts.Batch, pathsToNotRemove *[]string) (
-	*state.Change, *apiError) {
+	snapFiles []*uploadedSnap, batch *asserts.Batch, opts devicestate.RemodelOptions,
+	pathsToNotRemove *[]string) (*state.Change, *apiError) {
 
 	st.Lock()
 	defer st.Unlock()
@@ -190,7 +195,7 @@ func startOfflineRemodelChange(st *state.State, newModel *asserts.Model,
 	}
 
 	// Now create and start the remodel change
-	chg, err := devicestateRemodel(st, newModel, slInfo.sideInfos, slInfo.tmpPaths)
+	chg, err := devicestateRemodel(st, newModel, slInfo.sideInfos, slInfo.tmpPaths, opts)
 	if err != nil {
 		return nil, BadRequest("cannot remodel device: %v", err)
 	}
@@ -218,10 +223,20 @@ func offlineRemodel(c *Command, r *http.Request, contentTypeParams map[string]st
 	if errRsp != nil {
 		return errRsp
 	}
+	// providing snaps implies an offline remodel, but it can also be
+	// requested explicitly when only assertions are provided
+	var opts devicestate.RemodelOptions
+	if vals := form.Values["offline"]; len(vals) > 0 {
+		offline, err := strconv.ParseBool(vals[0])
+		if err != nil {
+			return BadRequest("cannot parse offline value: %q", vals[0])
+		}
+		opts.Offline = offline
+	}
 
 	// Create and start the change using the form data
 	chg, errRsp := startOfflineRemodelChange(c.d.overlord.State(),
-		newModel, snapFiles, batch, &pathsToNotRemove)
+		newModel, snapFiles, batch, opts, &pathsToNotRemove)
 	if errRsp != nil {
 		return errRsp
 	}
@@ -314,7 +329,10 @@ type postSerialData struct {
 	NoRegistrationUntilReboot bool   `json:"no-registration-until-reboot"`
 }
 
-var devicestateDeviceManagerUnregister = (*devicestate.DeviceManager).Unregister
+var (
+	devicestateDeviceManagerUnregister = (*devicestate.DeviceManager).Unregister
+	devicestateRenewSerial             = devicestate.RenewSerial
+)
 
 func postSerial(c *Command, r *http.Request, _ *auth.UserState) Response {
 	var postData postSerialData
@@ -328,12 +346,17 @@ func postSerial(c *Command, r *http.Request, _ *auth.UserState) Response {
 	}
 	switch postData.Action {
 	case "forget":
+		return forgetSerial(c, &postData)
+	case "renew":
+		return renewSerial(c)
 	case "":
 		return BadRequest("missing serial action")
 	default:
 		return BadRequest("unsupported serial action %q", postData.Action)
 	}
+}
 
+func forgetSerial(c *Command, postData *postSerialData) Response {
 	st := c.d.overlord.State()
 	st.Lock()
 	defer st.Unlock()
@@ -350,3 +373,17 @@ func postSerial(c *Command, r *http.Request, _ *auth.UserState) Response {
 
 	return SyncResponse(nil)
 }
+
+func renewSerial(c *Command) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	chg, err := devicestateRenewSerial(st)
+	if err != nil {
+		return errToResponse(err, nil, BadRequest, "cannot renew serial: %v")
+	}
+	ensureStateSoon(st)
+
+	return AsyncResponse(nil, chg.ID())
+}
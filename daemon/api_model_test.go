@@ -44,6 +44,7 @@ import (
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
 	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 )
@@ -63,7 +64,7 @@ type modelSuite struct {
 func (s *modelSuite) TestPostRemodelUnhappy(c *check.C) {
 	s.daemon(c)
 
-	s.expectRootAccess()
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.remodel"})
 
 	data, err := json.Marshal(daemon.PostModelData{NewModel: "invalid model"})
 	c.Check(err, check.IsNil)
@@ -78,7 +79,7 @@ func (s *modelSuite) TestPostRemodelUnhappy(c *check.C) {
 func (s *modelSuite) TestPostRemodelUnhappyWrongAssertion(c *check.C) {
 	s.daemon(c)
 
-	s.expectRootAccess()
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.remodel"})
 
 	acct := assertstest.NewAccount(s.StoreSigning, "developer1", nil, "")
 	buf := bytes.NewBuffer(asserts.Encode(acct))
@@ -94,7 +95,7 @@ func (s *modelSuite) TestPostRemodelUnhappyWrongAssertion(c *check.C) {
 }
 
 func (s *modelSuite) TestPostRemodel(c *check.C) {
-	s.expectRootAccess()
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.remodel"})
 
 	oldModel := s.Brands.Model("my-brand", "my-old-model", modelDefaults)
 	newModel := s.Brands.Model("my-brand", "my-old-model", modelDefaults, map[string]interface{}{
@@ -123,8 +124,10 @@ func (s *modelSuite) TestPostRemodel(c *check.C) {
 	defer restore()
 
 	var devicestateRemodelGotModel *asserts.Model
-	defer daemon.MockDevicestateRemodel(func(st *state.State, nm *asserts.Model, sis []*snap.SideInfo, paths []string) (*state.Change, error) {
+	var devicestateRemodelGotOpts devicestate.RemodelOptions
+	defer daemon.MockDevicestateRemodel(func(st *state.State, nm *asserts.Model, sis []*snap.SideInfo, paths []string, opts devicestate.RemodelOptions) (*state.Change, error) {
 		devicestateRemodelGotModel = nm
+		devicestateRemodelGotOpts = opts
 		chg := st.NewChange("remodel", "...")
 		return chg, nil
 	})()
@@ -142,6 +145,7 @@ func (s *modelSuite) TestPostRemodel(c *check.C) {
 	rsp := s.asyncReq(c, req, nil)
 	c.Assert(rsp.Status, check.Equals, 202)
 	c.Check(devicestateRemodelGotModel, check.DeepEquals, newModel)
+	c.Check(devicestateRemodelGotOpts, check.Equals, devicestate.RemodelOptions{})
 
 	st.Lock()
 	defer st.Unlock()
@@ -158,7 +162,7 @@ func (s *modelSuite) TestPostRemodel(c *check.C) {
 }
 
 func (s *modelSuite) TestPostRemodelWrongBody(c *check.C) {
-	s.expectRootAccess()
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.remodel"})
 
 	d := s.daemonWithOverlordMockAndStore()
 	hookMgr, err := hookstate.Manager(d.Overlord().State(), d.Overlord().TaskRunner())
@@ -188,7 +192,7 @@ func (s *modelSuite) TestPostRemodelWrongBody(c *check.C) {
 }
 
 func (s *modelSuite) TestPostRemodelWrongContentType(c *check.C) {
-	s.expectRootAccess()
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.remodel"})
 
 	d := s.daemonWithOverlordMockAndStore()
 	hookMgr, err := hookstate.Manager(d.Overlord().State(), d.Overlord().TaskRunner())
@@ -464,6 +468,8 @@ func (s *modelSuite) TestGetModelJSONHasSerialAssertion(c *check.C) {
 }
 
 func (s *userSuite) TestPostSerialBadAction(c *check.C) {
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.manage-serial"})
+
 	buf := bytes.NewBufferString(`{"action":"what"}`)
 	req, err := http.NewRequest("POST", "/v2/model/serial", buf)
 	c.Assert(err, check.IsNil)
@@ -473,6 +479,8 @@ func (s *userSuite) TestPostSerialBadAction(c *check.C) {
 }
 
 func (s *userSuite) TestPostSerialForget(c *check.C) {
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.manage-serial"})
+
 	unregister := 0
 	defer daemon.MockDevicestateDeviceManagerUnregister(func(mgr *devicestate.DeviceManager, opts *devicestate.UnregisterOptions) error {
 		unregister++
@@ -492,6 +500,8 @@ func (s *userSuite) TestPostSerialForget(c *check.C) {
 }
 
 func (s *userSuite) TestPostSerialForgetNoRegistrationUntilReboot(c *check.C) {
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.manage-serial"})
+
 	unregister := 0
 	defer daemon.MockDevicestateDeviceManagerUnregister(func(mgr *devicestate.DeviceManager, opts *devicestate.UnregisterOptions) error {
 		unregister++
@@ -511,6 +521,8 @@ func (s *userSuite) TestPostSerialForgetNoRegistrationUntilReboot(c *check.C) {
 }
 
 func (s *userSuite) TestPostSerialForgetError(c *check.C) {
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.manage-serial"})
+
 	defer daemon.MockDevicestateDeviceManagerUnregister(func(mgr *devicestate.DeviceManager, opts *devicestate.UnregisterOptions) error {
 		return errors.New("boom")
 	})()
@@ -523,12 +535,80 @@ func (s *userSuite) TestPostSerialForgetError(c *check.C) {
 	c.Check(rspe, check.DeepEquals, daemon.InternalError(`forgetting serial failed: boom`))
 }
 
-func multipartBody(c *check.C, model, snap, assertion string) (bytes.Buffer, string) {
+func (s *userSuite) TestPostSerialRenew(c *check.C) {
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.manage-serial"})
+
+	soon := 0
+	_, restore := daemon.MockEnsureStateSoon(func(st *state.State) {
+		soon++
+	})
+	defer restore()
+
+	defer daemon.MockDevicestateRenewSerial(func(st *state.State) (*state.Change, error) {
+		return st.NewChange("renew-serial", "..."), nil
+	})()
+
+	buf := bytes.NewBufferString(`{"action":"renew"}`)
+	req, err := http.NewRequest("POST", "/v2/model/serial", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.asyncReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 202)
+	c.Check(soon, check.Equals, 1)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "renew-serial")
+}
+
+func (s *userSuite) TestPostSerialRenewError(c *check.C) {
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.manage-serial"})
+
+	defer daemon.MockDevicestateRenewSerial(func(st *state.State) (*state.Change, error) {
+		return nil, errors.New("cannot renew serial of a device that is not registered yet")
+	})()
+
+	buf := bytes.NewBufferString(`{"action":"renew"}`)
+	req, err := http.NewRequest("POST", "/v2/model/serial", buf)
+	c.Assert(err, check.IsNil)
+
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe, check.DeepEquals, daemon.BadRequest(`cannot renew serial: cannot renew serial of a device that is not registered yet`))
+}
+
+func (s *userSuite) TestPostSerialRenewConflict(c *check.C) {
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.manage-serial"})
+
+	defer daemon.MockDevicestateRenewSerial(func(st *state.State) (*state.Change, error) {
+		return nil, &snapstate.ChangeConflictError{
+			Message:    "serial renewal in progress, no other registration changes allowed until this is done",
+			ChangeKind: "renew-serial",
+			ChangeID:   "1",
+		}
+	})()
+
+	buf := bytes.NewBufferString(`{"action":"renew"}`)
+	req, err := http.NewRequest("POST", "/v2/model/serial", buf)
+	c.Assert(err, check.IsNil)
+
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 409)
+	c.Check(rspe.Kind, check.Equals, client.ErrorKindSnapChangeConflict)
+}
+
+func multipartBody(c *check.C, model, snap, assertion, offline string) (bytes.Buffer, string) {
 	var b bytes.Buffer
 	w := multipart.NewWriter(&b)
 
 	err := w.WriteField("new-model", model)
 	c.Assert(err, check.IsNil)
+	if offline != "" {
+		err = w.WriteField("offline", offline)
+		c.Assert(err, check.IsNil)
+	}
 	part, err := w.CreateFormFile("snap", "snap_1.snap")
 	c.Assert(err, check.IsNil)
 	_, err = part.Write([]byte(snap))
@@ -549,12 +629,21 @@ func (s *modelSuite) TestPostOfflineRemodelBadModel(c *check.C) {
 	s.testPostOfflineRemodel(c, &testPostOfflineRemodelParams{badModel: true})
 }
 
+func (s *modelSuite) TestPostOfflineRemodelExplicitOffline(c *check.C) {
+	s.testPostOfflineRemodel(c, &testPostOfflineRemodelParams{offline: "true"})
+}
+
+func (s *modelSuite) TestPostOfflineRemodelBadOffline(c *check.C) {
+	s.testPostOfflineRemodel(c, &testPostOfflineRemodelParams{offline: "maybe"})
+}
+
 type testPostOfflineRemodelParams struct {
 	badModel bool
+	offline  string
 }
 
 func (s *modelSuite) testPostOfflineRemodel(c *check.C, params *testPostOfflineRemodelParams) {
-	s.expectRootAccess()
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.remodel"})
 
 	oldModel := s.Brands.Model("my-brand", "my-old-model", modelDefaults)
 	newModel := s.Brands.Model("my-brand", "my-old-model", modelDefaults, map[string]interface{}{
@@ -585,8 +674,10 @@ func (s *modelSuite) testPostOfflineRemodel(c *check.C, params *testPostOfflineR
 	snapName := "snap1"
 	snapRev := 1001
 	var devicestateRemodelGotModel *asserts.Model
+	var devicestateRemodelGotOpts devicestate.RemodelOptions
 	defer daemon.MockDevicestateRemodel(func(st *state.State, nm *asserts.Model,
-		sis []*snap.SideInfo, paths []string) (*state.Change, error) {
+		sis []*snap.SideInfo, paths []string, opts devicestate.RemodelOptions) (*state.Change, error) {
+		devicestateRemodelGotOpts = opts
 		c.Check(len(sis), check.Equals, 1)
 		c.Check(sis[0].RealName, check.Equals, snapName)
 		c.Check(sis[0].Revision, check.Equals, snap.Revision{N: snapRev})
@@ -624,7 +715,7 @@ func (s *modelSuite) testPostOfflineRemodel(c *check.C, params *testPostOfflineR
 	}).(*asserts.SnapRevision)
 
 	// create multipart data
-	body, boundary := multipartBody(c, modelEncoded, "snap_data", string(revAssert.Body()))
+	body, boundary := multipartBody(c, modelEncoded, "snap_data", string(revAssert.Body()), params.offline)
 
 	// set it and validate that this is what we passed to devicestateRemodel
 	req, err := http.NewRequest("POST", "/v2/model", &body)
@@ -632,15 +723,23 @@ func (s *modelSuite) testPostOfflineRemodel(c *check.C, params *testPostOfflineR
 	req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
 	req.Header.Set("Content-Length", strconv.Itoa(body.Len()))
 
-	if params.badModel {
+	switch {
+	case params.badModel:
 		rsp := s.errorReq(c, req, nil)
 		c.Assert(rsp.Status, check.Equals, 400)
 		c.Check(rsp.Error(), check.Equals, "cannot decode new model assertion: assertion content/signature separator not found (api)")
-	} else {
+	case params.offline == "maybe":
+		rsp := s.errorReq(c, req, nil)
+		c.Assert(rsp.Status, check.Equals, 400)
+		c.Check(rsp.Error(), check.Equals, `cannot parse offline value: "maybe" (api)`)
+	default:
 		rsp := s.asyncReq(c, req, nil)
 		c.Assert(rsp.Status, check.Equals, 202)
 		c.Check(rsp.Change, check.DeepEquals, "1")
 		c.Check(devicestateRemodelGotModel, check.DeepEquals, newModel)
+		c.Check(devicestateRemodelGotOpts, check.Equals, devicestate.RemodelOptions{
+			Offline: params.offline == "true",
+		})
 
 		st.Lock()
 		defer st.Unlock()
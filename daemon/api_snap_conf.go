@@ -22,12 +22,14 @@ package daemon
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/jsonutil"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/configstate/configschema"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/strutil"
@@ -50,6 +52,10 @@ func getSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 	keys := strutil.CommaSeparatedList(r.URL.Query().Get("keys"))
 
 	s := c.d.overlord.State()
+	if r.URL.Query().Get("history") == "true" {
+		return getSnapConfHistory(s, snapName, keys)
+	}
+
 	s.Lock()
 	tr := config.NewTransaction(s)
 	s.Unlock()
@@ -91,6 +97,57 @@ func getSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(currentConfValues)
 }
 
+// getSnapConfHistory returns the recorded history of configuration
+// changes of the snap, limited to the changes affecting the given keys
+// if any.
+func getSnapConfHistory(st *state.State, snapName string, keys []string) Response {
+	for _, key := range keys {
+		if _, err := config.ParseKey(key); err != nil {
+			return BadRequest("%v", err)
+		}
+	}
+
+	st.Lock()
+	defer st.Unlock()
+
+	history, err := config.GetHistory(st, snapName)
+	if err != nil {
+		return InternalError("%v", err)
+	}
+
+	result := make([]*config.HistoryEntry, 0, len(history))
+	for _, entry := range history {
+		if len(keys) == 0 {
+			result = append(result, entry)
+			continue
+		}
+		var changes []*config.HistoryChange
+		for _, change := range entry.Changes {
+			for _, key := range keys {
+				if keysOverlap(key, change.Key) {
+					changes = append(changes, change)
+					break
+				}
+			}
+		}
+		if len(changes) > 0 {
+			result = append(result, &config.HistoryEntry{
+				Time:    entry.Time,
+				Origin:  entry.Origin,
+				Changes: changes,
+			})
+		}
+	}
+
+	return SyncResponse(result)
+}
+
+// keysOverlap returns whether one of the given dotted keys is the same as,
+// or nested in, the other one.
+func keysOverlap(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".")
+}
+
 func setSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 	vars := muxVars(r)
 	snapName := configstate.RemapSnapFromRequest(vars["name"])
@@ -110,6 +167,14 @@ func setSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 		if _, ok := err.(*snap.NotInstalledError); ok {
 			return SnapNotFound(snapName, err)
 		}
+		if verr, ok := err.(*configschema.ValidationError); ok {
+			return &apiError{
+				Status:  400,
+				Message: err.Error(),
+				Kind:    client.ErrorKindConfigSchemaViolation,
+				Value:   verr.Violations,
+			}
+		}
 		return errToResponse(err, []string{snapName}, InternalError, "%v")
 	}
 
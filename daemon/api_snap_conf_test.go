@@ -121,6 +121,53 @@ func (s *snapConfSuite) TestGetRootDocument(c *check.C) {
 	c.Check(result, check.DeepEquals, map[string]interface{}{"test-key1": "test-value1", "test-key2": "test-value2"})
 }
 
+func (s *snapConfSuite) TestGetConfHistory(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+
+	st := d.Overlord().State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	tr.SetOrigin(config.OriginAPI)
+	c.Assert(tr.Set("config-snap", "foo", "bar"), check.IsNil)
+	c.Assert(tr.Set("config-snap", "a.b", 1), check.IsNil)
+	tr.Commit()
+	tr = config.NewTransaction(st)
+	tr.SetOrigin(config.OriginHook)
+	c.Assert(tr.Set("config-snap", "a", map[string]interface{}{"c": 2}), check.IsNil)
+	tr.Commit()
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/snaps/config-snap/conf?history=true&keys=a.b", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	history, ok := rsp.Result.([]*config.HistoryEntry)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(history, check.HasLen, 2)
+	c.Check(history[0].Origin, check.Equals, "api")
+	c.Check(history[0].Changes, check.DeepEquals, []*config.HistoryChange{{Key: "a.b", New: "1"}})
+	c.Check(history[1].Origin, check.Equals, "hook")
+	c.Check(history[1].Changes, check.DeepEquals, []*config.HistoryChange{{Key: "a", Old: `{"b":1}`, New: `{"c":2}`}})
+
+	req, err = http.NewRequest("GET", "/v2/snaps/config-snap/conf?history=true", nil)
+	c.Assert(err, check.IsNil)
+	rsp = s.syncReq(c, req, nil)
+	history = rsp.Result.([]*config.HistoryEntry)
+	c.Assert(history, check.HasLen, 2)
+	c.Check(history[0].Changes, check.HasLen, 2)
+
+	req, err = http.NewRequest("GET", "/v2/snaps/config-snap/conf?history=true&keys=other", nil)
+	c.Assert(err, check.IsNil)
+	rsp = s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.HasLen, 0)
+
+	req, err = http.NewRequest("GET", "/v2/snaps/config-snap/conf?history=true&keys=Bad", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `invalid option name: "Bad"`)
+}
+
 func (s *snapConfSuite) TestGetConfBadKey(c *check.C) {
 	s.daemon(c)
 	// TODO: this one in particular should really be a 400 also
@@ -302,6 +349,42 @@ func (s *snapConfSuite) TestSetConfBadSnap(c *check.C) {
 		"type": "error"})
 }
 
+func (s *snapConfSuite) TestSetConfSchemaViolation(c *check.C) {
+	s.daemon(c)
+	info := s.mockSnap(c, configYaml)
+	schema := `{"properties": {"debug": {"type": "boolean"}, "mode": {"enum": ["fast", "slow"]}}}`
+	c.Assert(os.WriteFile(filepath.Join(info.MountDir(), "meta", "config-schema.json"), []byte(schema), 0644), check.IsNil)
+
+	text, err := json.Marshal(map[string]interface{}{"debug": "true", "mode": "fast"})
+	c.Assert(err, check.IsNil)
+
+	buffer := bytes.NewBuffer(text)
+	req, err := http.NewRequest("PUT", "/v2/snaps/config-snap/conf", buffer)
+	c.Assert(err, check.IsNil)
+
+	rec := httptest.NewRecorder()
+	s.req(c, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 400)
+
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Assert(err, check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{
+		"status-code": 400.,
+		"status":      "Bad Request",
+		"result": map[string]interface{}{
+			"message": `invalid configuration: debug: expected boolean, got string`,
+			"kind":    "option-schema-violation",
+			"value": []interface{}{
+				map[string]interface{}{
+					"key":     "debug",
+					"message": "expected boolean, got string",
+				},
+			},
+		},
+		"type": "error"})
+}
+
 func (s *snapConfSuite) TestSetConfChangeConflict(c *check.C) {
 	s.daemon(c)
 	s.mockSnap(c, configYaml)
@@ -330,6 +413,7 @@ func (s *snapConfSuite) TestSetConfChangeConflict(c *check.C) {
 			"kind":    "snap-change-conflict",
 			"value": map[string]interface{}{
 				"change-kind": "manip",
+				"change-id":   "1",
 				"snap-name":   "config-snap",
 			},
 		},
@@ -901,6 +901,8 @@ func getSnapsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 		all = true
 	case "enabled", "":
 		all = false
+	case "refresh-candidates":
+		return getRefreshCandidates(c, r)
 	default:
 		return BadRequest("invalid select parameter: %q", sel)
 	}
@@ -945,6 +947,30 @@ func getSnapsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 	}
 }
 
+func getRefreshCandidates(c *Command, r *http.Request) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	candidates, err := snapstateRefreshCandidatesPreview(r.Context(), st)
+	if err != nil {
+		return errToResponse(err, nil, InternalError, "cannot list refresh candidates: %v")
+	}
+
+	results := make([]*client.RefreshCandidate, 0, len(candidates))
+	for _, cand := range candidates {
+		results = append(results, &client.RefreshCandidate{
+			Name:         cand.InstanceName,
+			Current:      cand.Current,
+			Revision:     cand.Revision,
+			Channel:      cand.Channel,
+			DownloadSize: cand.DownloadSize,
+			Deferred:     cand.Deferred,
+		})
+	}
+	return SyncResponse(results)
+}
+
 func shouldSearchStore(r *http.Request) bool {
 	// we should jump to the old behaviour iff q is given, or if
 	// sources is given and either empty or contains the word
@@ -426,6 +426,65 @@ func (s *snapsSuite) TestSnapsInfoFilterRemote(c *check.C) {
 	c.Assert(rsp.Result, check.NotNil)
 }
 
+func (s *snapsSuite) TestSnapsInfoRefreshCandidates(c *check.C) {
+	d := s.daemon(c)
+
+	var called bool
+	restore := daemon.MockSnapstateRefreshCandidatesPreview(func(ctx context.Context, st *state.State) ([]*snapstate.RefreshCandidateInfo, error) {
+		called = true
+		c.Check(st, check.Equals, d.Overlord().State())
+		return []*snapstate.RefreshCandidateInfo{{
+			InstanceName: "other-snap",
+			Current:      snap.R(1),
+			Revision:     snap.R(2),
+			Channel:      "latest/edge",
+			DownloadSize: 88,
+		}, {
+			InstanceName: "some-snap",
+			Current:      snap.R(5),
+			Revision:     snap.R(6),
+			Channel:      "latest/stable",
+			Deferred:     `snap health is "error"`,
+		}}, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/snaps?select=refresh-candidates", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	c.Check(called, check.Equals, true)
+	c.Check(rsp.Result, check.DeepEquals, []*client.RefreshCandidate{{
+		Name:         "other-snap",
+		Current:      snap.R(1),
+		Revision:     snap.R(2),
+		Channel:      "latest/edge",
+		DownloadSize: 88,
+	}, {
+		Name:     "some-snap",
+		Current:  snap.R(5),
+		Revision: snap.R(6),
+		Channel:  "latest/stable",
+		Deferred: `snap health is "error"`,
+	}})
+}
+
+func (s *snapsSuite) TestSnapsInfoRefreshCandidatesError(c *check.C) {
+	s.daemon(c)
+
+	restore := daemon.MockSnapstateRefreshCandidatesPreview(func(ctx context.Context, st *state.State) ([]*snapstate.RefreshCandidateInfo, error) {
+		return nil, errors.New("boom")
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/snaps?select=refresh-candidates", nil)
+	c.Assert(err, check.IsNil)
+
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 500)
+	c.Check(rspe.Message, check.Equals, "cannot list refresh candidates: boom")
+}
+
 func (s *snapsSuite) TestPostSnapsVerifyMultiSnapInstruction(c *check.C) {
 	s.daemonWithOverlordMockAndStore()
 
@@ -1271,6 +1330,43 @@ func (s *snapsSuite) TestSnapInfoReturnsHolds(c *check.C) {
 	c.Check(snapInfo.GatingHold.Equal(gatingHold), check.Equals, true, testCmt)
 }
 
+func (s *snapsSuite) TestSnapInfoReturnsRefreshInhibit(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v0", snap.R(5), true, "")
+
+	inhibitedTime := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	st := d.Overlord().State()
+	st.Lock()
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(st, "foo", &snapst), check.IsNil)
+	snapst.RefreshInhibitedTime = &inhibitedTime
+	snapstate.Set(st, "foo", &snapst)
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+
+	c.Assert(rsp.Result, check.FitsTypeOf, &client.Snap{})
+	snapInfo := rsp.Result.(*client.Snap)
+	c.Assert(snapInfo.RefreshInhibit, check.NotNil)
+	c.Check(snapInfo.RefreshInhibit.ProceedTime, check.Equals, time.Date(2023, 5, 15, 9, 59, 59, 0, time.UTC))
+}
+
+func (s *snapsSuite) TestSnapInfoNoRefreshInhibit(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v0", snap.R(5), true, "")
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+
+	c.Assert(rsp.Result, check.FitsTypeOf, &client.Snap{})
+	c.Check(rsp.Result.(*client.Snap).RefreshInhibit, check.IsNil)
+}
+
 func (s *snapsSuite) TestSnapManyInfosReturnsHolds(c *check.C) {
 	d := s.daemon(c)
 	s.mkInstalledInState(c, d, "snap-a", "bar", "v0", snap.R(5), true, "")
@@ -33,6 +33,7 @@ import (
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/snapshotstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/strutil"
 )
 
@@ -59,6 +60,7 @@ var (
 	snapshotSave    = snapshotstate.Save
 	snapshotExport  = snapshotstate.Export
 	snapshotImport  = snapshotstate.Import
+	snapshotUsage   = snapshotstate.Usage
 )
 
 func listSnapshots(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -72,10 +74,24 @@ func listSnapshots(c *Command, r *http.Request, user *auth.UserState) Response {
 		}
 	}
 
+	snapNames := strutil.CommaSeparatedList(query.Get("snaps"))
+
 	st := c.d.overlord.State()
 	st.Lock()
 	defer st.Unlock()
-	sets, err := snapshotList(context.TODO(), st, setID, strutil.CommaSeparatedList(r.URL.Query().Get("snaps")))
+
+	if query.Get("usage") == "true" {
+		if setID != 0 || len(snapNames) > 0 {
+			return BadRequest("'usage' cannot be combined with 'set' or 'snaps'")
+		}
+		usage, err := snapshotUsage(context.TODO(), st)
+		if err != nil {
+			return InternalError("%v", err)
+		}
+		return SyncResponse(usage)
+	}
+
+	sets, err := snapshotList(context.TODO(), st, setID, snapNames)
 	if err != nil {
 		return InternalError("%v", err)
 	}
@@ -89,6 +105,8 @@ type snapshotAction struct {
 	Action string   `json:"action"`
 	Snaps  []string `json:"snaps,omitempty"`
 	Users  []string `json:"users,omitempty"`
+
+	RestoreOptions *snap.SnapshotRestoreOptions `json:"restore-options,omitempty"`
 }
 
 func (action snapshotAction) String() string {
@@ -135,11 +153,15 @@ func changeSnapshots(c *Command, r *http.Request, user *auth.UserState) Response
 	st.Lock()
 	defer st.Unlock()
 
+	if action.RestoreOptions != nil && action.Action != "restore" {
+		return BadRequest("snapshot %q operation cannot specify restore options", action.Action)
+	}
+
 	switch action.Action {
 	case "check":
 		affected, ts, err = snapshotCheck(st, action.SetID, action.Snaps, action.Users)
 	case "restore":
-		affected, ts, err = snapshotRestore(st, action.SetID, action.Snaps, action.Users)
+		affected, ts, err = snapshotRestore(st, action.SetID, action.Snaps, action.Users, action.RestoreOptions)
 	case "forget":
 		if len(action.Users) != 0 {
 			return BadRequest(`snapshot "forget" operation cannot specify users`)
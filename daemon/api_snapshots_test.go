@@ -152,6 +152,36 @@ func (s *snapshotSuite) TestListSnapshotsFiltering(c *check.C) {
 	c.Check(rsp.Result, check.DeepEquals, []client.SnapshotSet{{ID: 42}})
 }
 
+func (s *snapshotSuite) TestListSnapshotsUsage(c *check.C) {
+	s.expectOpenAccess()
+
+	defer daemon.MockSnapshotList(func(context.Context, *state.State, uint64, []string) ([]client.SnapshotSet, error) {
+		c.Fatal("snapshotList should not be reached")
+		return nil, nil
+	})()
+	defer daemon.MockSnapshotUsage(func(context.Context, *state.State) (*client.SnapshotsUsage, error) {
+		return &client.SnapshotsUsage{Total: 300, Automatic: 100, AutomaticMaxSize: 200}, nil
+	})()
+
+	req, err := http.NewRequest("GET", "/v2/snapshots?usage=true", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, &client.SnapshotsUsage{Total: 300, Automatic: 100, AutomaticMaxSize: 200})
+}
+
+func (s *snapshotSuite) TestListSnapshotsUsageWithFilters(c *check.C) {
+	s.expectOpenAccess()
+
+	req, err := http.NewRequest("GET", "/v2/snapshots?usage=true&set=42", nil)
+	c.Assert(err, check.IsNil)
+
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `'usage' cannot be combined with 'set' or 'snaps'`)
+}
+
 func (s *snapshotSuite) TestListSnapshotsBadFiltering(c *check.C) {
 	s.expectOpenAccess()
 
@@ -258,7 +288,7 @@ func (s *snapshotSuite) TestChangeSnapshots404(c *check.C) {
 		done = "check"
 		return nil, nil, expectedError
 	})()
-	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string, *snap.SnapshotRestoreOptions) ([]string, *state.TaskSet, error) {
 		done = "restore"
 		return nil, nil, expectedError
 	})()
@@ -289,7 +319,7 @@ func (s *snapshotSuite) TestChangeSnapshots500(c *check.C) {
 		done = "check"
 		return nil, nil, expectedError
 	})()
-	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string, *snap.SnapshotRestoreOptions) ([]string, *state.TaskSet, error) {
 		done = "restore"
 		return nil, nil, expectedError
 	})()
@@ -316,7 +346,7 @@ func (s *snapshotSuite) TestChangeSnapshot(c *check.C) {
 		done = "check"
 		return []string{"foo"}, state.NewTaskSet(), nil
 	})()
-	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string, *snap.SnapshotRestoreOptions) ([]string, *state.TaskSet, error) {
 		done = "restore"
 		return []string{"foo"}, state.NewTaskSet(), nil
 	})()
@@ -356,6 +386,35 @@ func (s *snapshotSuite) TestChangeSnapshot(c *check.C) {
 	}
 }
 
+func (s *snapshotSuite) TestChangeSnapshotRestoreOptions(c *check.C) {
+	var restoreOpts *snap.SnapshotRestoreOptions
+	defer daemon.MockSnapshotRestore(func(_ *state.State, _ uint64, _ []string, users []string, opts *snap.SnapshotRestoreOptions) ([]string, *state.TaskSet, error) {
+		c.Check(users, check.DeepEquals, []string{"alice"})
+		restoreOpts = opts
+		return []string{"foo"}, state.NewTaskSet(), nil
+	})()
+
+	body := `{"set": 42, "action": "restore", "users": ["alice"], "restore-options": {"common-only": true, "force-ownership": true}}`
+	req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+
+	rsp := s.asyncReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 202)
+	c.Check(restoreOpts, check.DeepEquals, &snap.SnapshotRestoreOptions{CommonOnly: true, ForceOwnership: true})
+}
+
+func (s *snapshotSuite) TestChangeSnapshotRestoreOptionsNotRestore(c *check.C) {
+	for _, action := range []string{"check", "forget"} {
+		body := fmt.Sprintf(`{"set": 42, "action": %q, "restore-options": {"common-only": true}}`, action)
+		req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, 400)
+		c.Check(rspe.Message, check.Equals, fmt.Sprintf(`snapshot %q operation cannot specify restore options`, action))
+	}
+}
+
 func (s *snapshotSuite) TestExportSnapshots(c *check.C) {
 	var snapshotExportCalled int
 
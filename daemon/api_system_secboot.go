@@ -0,0 +1,110 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate"
+)
+
+var systemSecbootCmd = &Command{
+	Path:        "/v2/system-secboot",
+	GET:         getSystemSecboot,
+	POST:        postSystemSecboot,
+	ReadAccess:  rootOrPolkitAccess{Polkit: polkitActionManageTPMLockout},
+	WriteAccess: rootOrPolkitAccess{Polkit: polkitActionManageTPMLockout},
+}
+
+var (
+	deviceManagerTPMLockoutStatus = (*devicestate.DeviceManager).TPMLockoutStatus
+	deviceManagerResetTPMLockout  = (*devicestate.DeviceManager).ResetTPMLockout
+)
+
+func getSystemSecboot(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	deviceMgr := c.d.overlord.DeviceManager()
+	status, err := deviceManagerTPMLockoutStatus(deviceMgr)
+	if err != nil {
+		return InternalError("cannot obtain TPM lockout status: %v", err)
+	}
+	resets, err := deviceMgr.TPMLockoutResets()
+	if err != nil {
+		return InternalError(err.Error())
+	}
+
+	tpmLockout := &client.TPMLockoutStatus{
+		InLockout:       status.InLockout,
+		FailedTries:     status.FailedTries,
+		MaxTries:        status.MaxTries,
+		RemainingTries:  status.RemainingTries(),
+		LockoutInterval: uint64(status.LockoutInterval / time.Second),
+		LockoutRecovery: uint64(status.LockoutRecovery / time.Second),
+	}
+	for _, reset := range resets {
+		tpmLockout.LastResets = append(tpmLockout.LastResets, client.TPMLockoutReset{
+			Time:  reset.Time,
+			Error: reset.Error,
+		})
+	}
+	return SyncResponse(&client.SystemSecbootResponse{
+		TPMLockout: tpmLockout,
+	})
+}
+
+type postSystemSecbootData struct {
+	Action string `json:"action"`
+}
+
+func postSystemSecboot(c *Command, r *http.Request, user *auth.UserState) Response {
+	var postData postSystemSecbootData
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&postData); err != nil {
+		return BadRequest("cannot decode secboot action data from request body: %v", err)
+	}
+	if decoder.More() {
+		return BadRequest("spurious content after secboot action")
+	}
+	switch postData.Action {
+	case "":
+		return BadRequest("missing secboot action")
+	case "reset-tpm-lockout":
+		// handled below
+	default:
+		return BadRequest("unsupported secboot action %q", postData.Action)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if err := deviceManagerResetTPMLockout(c.d.overlord.DeviceManager()); err != nil {
+		return InternalError(err.Error())
+	}
+	return SyncResponse(nil)
+}
@@ -0,0 +1,149 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/secboot"
+)
+
+var _ = Suite(&systemSecbootSuite{})
+
+type systemSecbootSuite struct {
+	apiBaseSuite
+}
+
+func (s *systemSecbootSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.expectReadAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.manage-tpm-lockout"})
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.manage-tpm-lockout"})
+}
+
+func (s *systemSecbootSuite) TestGetSystemSecboot(c *C) {
+	d := s.daemon(c)
+
+	defer daemon.MockDeviceManagerTPMLockoutStatus(func() (*secboot.TPMLockoutStatus, error) {
+		return &secboot.TPMLockoutStatus{
+			FailedTries:     2,
+			MaxTries:        32,
+			LockoutInterval: 2 * time.Hour,
+			LockoutRecovery: 24 * time.Hour,
+		}, nil
+	})()
+
+	resetTime := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	st := d.Overlord().State()
+	st.Lock()
+	st.Set("tpm-lockout-resets", []devicestate.TPMLockoutReset{
+		{Time: resetTime, Error: "boom"},
+	})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/system-secboot", nil)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, &client.SystemSecbootResponse{
+		TPMLockout: &client.TPMLockoutStatus{
+			FailedTries:     2,
+			MaxTries:        32,
+			RemainingTries:  30,
+			LockoutInterval: 7200,
+			LockoutRecovery: 86400,
+			LastResets: []client.TPMLockoutReset{
+				{Time: resetTime, Error: "boom"},
+			},
+		},
+	})
+}
+
+func (s *systemSecbootSuite) TestGetSystemSecbootError(c *C) {
+	s.daemon(c)
+
+	defer daemon.MockDeviceManagerTPMLockoutStatus(func() (*secboot.TPMLockoutStatus, error) {
+		return nil, errors.New("boom")
+	})()
+
+	req, err := http.NewRequest("GET", "/v2/system-secboot", nil)
+	c.Assert(err, IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe, DeepEquals, daemon.InternalError("cannot obtain TPM lockout status: boom"))
+}
+
+func (s *systemSecbootSuite) TestPostSystemSecbootResetTPMLockout(c *C) {
+	s.daemon(c)
+
+	var resetErr error
+	called := 0
+	defer daemon.MockDeviceManagerResetTPMLockout(func() error {
+		called++
+		return resetErr
+	})()
+
+	buf := bytes.NewBufferString(`{"action":"reset-tpm-lockout"}`)
+	req, err := http.NewRequest("POST", "/v2/system-secboot", buf)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 200)
+	c.Check(called, Equals, 1)
+
+	resetErr = errors.New("cannot reset TPM lockout: boom")
+	buf = bytes.NewBufferString(`{"action":"reset-tpm-lockout"}`)
+	req, err = http.NewRequest("POST", "/v2/system-secboot", buf)
+	c.Assert(err, IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe, DeepEquals, daemon.InternalError("cannot reset TPM lockout: boom"))
+	c.Check(called, Equals, 2)
+}
+
+func (s *systemSecbootSuite) TestPostSystemSecbootBadAction(c *C) {
+	s.daemon(c)
+
+	called := 0
+	defer daemon.MockDeviceManagerResetTPMLockout(func() error {
+		called++
+		return nil
+	})()
+
+	for _, tc := range []struct {
+		body string
+		err  string
+	}{
+		{`{"action":"unknown"}`, `unsupported secboot action "unknown"`},
+		{`{}`, `missing secboot action`},
+		{`{"action":"reset-tpm-lockout"}{}`, `spurious content after secboot action`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/system-secboot", bytes.NewBufferString(tc.body))
+		c.Assert(err, IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe, DeepEquals, daemon.BadRequest(tc.err))
+	}
+	c.Check(called, Equals, 0)
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/install"
+	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 )
 
@@ -43,7 +44,7 @@ var systemsCmd = &Command{
 	// this command, so we need to set the POST for this command to essentially
 	// forward to that one
 	POST:        postSystemsAction,
-	WriteAccess: rootAccess{},
+	WriteAccess: rootOrPolkitAccess{Polkit: polkitActionManageSystems},
 }
 
 var systemsActionCmd = &Command{
@@ -52,17 +53,38 @@ var systemsActionCmd = &Command{
 	ReadAccess: rootAccess{},
 
 	POST:        postSystemsAction,
-	WriteAccess: rootAccess{},
+	WriteAccess: rootOrPolkitAccess{Polkit: polkitActionManageSystems},
+}
+
+var systemSnapsCmd = &Command{
+	Path:       "/v2/systems/{label}/snaps",
+	GET:        getSystemSnaps,
+	ReadAccess: authenticatedAccess{},
 }
 
 type systemsResponse struct {
 	Systems []client.System `json:"systems,omitempty"`
+	// PendingFactoryReset is set when a factory reset was requested
+	// and the device has not rebooted into it yet
+	PendingFactoryReset *client.PendingFactoryReset `json:"pending-factory-reset,omitempty"`
 }
 
 func getAllSystems(c *Command, r *http.Request, user *auth.UserState) Response {
 	var rsp systemsResponse
 
-	seedSystems, err := c.d.overlord.DeviceManager().Systems()
+	var systems []client.System
+	var err error
+	dm := c.d.overlord.DeviceManager()
+	if dm.SystemMode(devicestate.SysHasModeenv) == "run" {
+		// the recovery systems are listed as they are tracked in the
+		// modeenv
+		systems, err = recoverySystems(dm)
+		if err == nil {
+			rsp.PendingFactoryReset, err = pendingFactoryReset(c.d.overlord.State())
+		}
+	} else {
+		systems, err = seedSystems(dm)
+	}
 	if err != nil {
 		if err == devicestate.ErrNoSystems {
 			// no systems available
@@ -71,21 +93,71 @@ func getAllSystems(c *Command, r *http.Request, user *auth.UserState) Response {
 
 		return InternalError(err.Error())
 	}
+	rsp.Systems = systems
+	return SyncResponse(&rsp)
+}
 
-	rsp.Systems = make([]client.System, 0, len(seedSystems))
+func pendingFactoryReset(st *state.State) (*client.PendingFactoryReset, error) {
+	st.Lock()
+	defer st.Unlock()
+	req, err := devicestate.PendingFactoryReset(st)
+	if err != nil || req == nil {
+		return nil, err
+	}
+	return &client.PendingFactoryReset{
+		Label: req.Label,
+		Time:  req.Time,
+	}, nil
+}
 
-	for _, ss := range seedSystems {
-		// untangle the model
+func systemActions(actions []devicestate.SystemAction) []client.SystemAction {
+	clientActions := make([]client.SystemAction, 0, len(actions))
+	for _, sa := range actions {
+		clientActions = append(clientActions, client.SystemAction{
+			Title: sa.Title,
+			Mode:  sa.Mode,
+		})
+	}
+	return clientActions
+}
 
-		actions := make([]client.SystemAction, 0, len(ss.Actions))
-		for _, sa := range ss.Actions {
-			actions = append(actions, client.SystemAction{
-				Title: sa.Title,
-				Mode:  sa.Mode,
-			})
-		}
+func recoverySystems(dm *devicestate.DeviceManager) ([]client.System, error) {
+	listed, err := devicestate.ListRecoverySystems()
+	if err != nil {
+		return nil, err
+	}
+	systems := make([]client.System, 0, len(listed))
+	for _, rs := range listed {
+		current, actions := dm.RecoverySystemActions(rs)
+		systems = append(systems, client.System{
+			Current: current,
+			Label:   rs.Label,
+			Model: client.SystemModelData{
+				Model:       rs.Model,
+				BrandID:     rs.BrandID,
+				DisplayName: rs.DisplayName,
+			},
+			Brand: snap.StoreAccount{
+				ID:          rs.Brand.AccountID(),
+				Username:    rs.Brand.Username(),
+				DisplayName: rs.Brand.DisplayName(),
+				Validation:  rs.Brand.Validation(),
+			},
+			Actions: systemActions(actions),
+		})
+	}
+	return systems, nil
+}
 
-		rsp.Systems = append(rsp.Systems, client.System{
+func seedSystems(dm *devicestate.DeviceManager) ([]client.System, error) {
+	seeded, err := dm.Systems()
+	if err != nil {
+		return nil, err
+	}
+	systems := make([]client.System, 0, len(seeded))
+	for _, ss := range seeded {
+		// untangle the model
+		systems = append(systems, client.System{
 			Current: ss.Current,
 			Label:   ss.Label,
 			Model: client.SystemModelData{
@@ -99,10 +171,10 @@ func getAllSystems(c *Command, r *http.Request, user *auth.UserState) Response {
 				DisplayName: ss.Brand.DisplayName(),
 				Validation:  ss.Brand.Validation(),
 			},
-			Actions: actions,
+			Actions: systemActions(ss.Actions),
 		})
 	}
-	return SyncResponse(&rsp)
+	return systems, nil
 }
 
 // wrapped for unit tests
@@ -138,6 +210,7 @@ func storageEncryption(encInfo *install.EncryptionSupportInfo) *client.StorageEn
 var (
 	devicestateInstallFinish                 = devicestate.InstallFinish
 	devicestateInstallSetupStorageEncryption = devicestate.InstallSetupStorageEncryption
+	devicestateCreateRecoverySystem          = devicestate.CreateRecoverySystem
 )
 
 func getSystemDetails(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -174,11 +247,43 @@ func getSystemDetails(c *Command, r *http.Request, user *auth.UserState) Respons
 	return SyncResponse(rsp)
 }
 
+// wrapped for unit tests
+var deviceManagerSystemSnaps = func(dm *devicestate.DeviceManager, systemLabel string) ([]*devicestate.SystemSnap, error) {
+	return dm.SystemSnaps(systemLabel)
+}
+
+func getSystemSnaps(c *Command, r *http.Request, user *auth.UserState) Response {
+	systemLabel := muxVars(r)["label"]
+
+	// the state is not locked here, reading the seed can be slow
+	snaps, err := deviceManagerSystemSnaps(c.d.overlord.DeviceManager(), systemLabel)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NotFound("requested seed system %q does not exist", systemLabel)
+		}
+		return InternalError(err.Error())
+	}
+
+	rsp := make([]client.SystemSnap, 0, len(snaps))
+	for _, sn := range snaps {
+		rsp = append(rsp, client.SystemSnap{
+			Name:     sn.Name,
+			Revision: sn.Revision,
+			Channel:  sn.Channel,
+			Asserted: sn.Asserted,
+			Size:     sn.Size,
+			Shared:   sn.Shared,
+		})
+	}
+	return SyncResponse(rsp)
+}
+
 type systemActionRequest struct {
 	Action string `json:"action"`
 
 	client.SystemAction
 	client.InstallSystemOptions
+	client.CreateSystemOptions
 }
 
 func postSystemsAction(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -192,6 +297,13 @@ func postSystemsAction(c *Command, r *http.Request, user *auth.UserState) Respon
 	if decoder.More() {
 		return BadRequest("extra content found in request body")
 	}
+	if req.Mode == "factory-reset" && (req.Action == "do" || req.Action == "reboot") {
+		// factory reset needs its own authorization on top of the
+		// one to manage systems
+		if rspe := checkRequestAccess(c.d, r, user, rootOrPolkitAccess{Polkit: polkitActionFactoryReset}); rspe != nil {
+			return rspe
+		}
+	}
 	switch req.Action {
 	case "do":
 		return postSystemActionDo(c, systemLabel, &req)
@@ -199,6 +311,8 @@ func postSystemsAction(c *Command, r *http.Request, user *auth.UserState) Respon
 		return postSystemActionReboot(c, systemLabel, &req)
 	case "install":
 		return postSystemActionInstall(c, systemLabel, &req)
+	case "create":
+		return postSystemActionCreate(c, systemLabel, &req)
 	default:
 		return BadRequest("unsupported action %q", req.Action)
 	}
@@ -271,3 +385,24 @@ func postSystemActionInstall(c *Command, systemLabel string, req *systemActionRe
 		return BadRequest("unsupported install step %q", req.Step)
 	}
 }
+
+func postSystemActionCreate(c *Command, systemLabel string, req *systemActionRequest) Response {
+	if systemLabel != "" {
+		return BadRequest("cannot create a recovery system from an existing one, the label must be passed in the request body")
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	opts := devicestate.CreateRecoverySystemOptions{
+		MarkDefault: req.MarkDefault,
+		TestSystem:  req.TestSystem,
+	}
+	chg, err := devicestateCreateRecoverySystem(st, req.Label, opts)
+	if err != nil {
+		return BadRequest("cannot create recovery system: %v", err)
+	}
+	ensureStateSoon(st)
+	return AsyncResponse(nil, chg.ID())
+}
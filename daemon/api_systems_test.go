@@ -70,6 +70,7 @@ func (s *systemsSuite) SetUpTest(c *check.C) {
 	s.apiBaseSuite.SetUpTest(c)
 
 	s.expectRootAccess()
+	s.expectWriteAccess(daemon.RootOrPolkitAccess{Polkit: "io.snapcraft.snapd.manage-systems"})
 }
 
 var pcGadgetUCYaml = `
@@ -203,12 +204,20 @@ func (s *systemsSuite) TestSystemsGetSome(c *check.C) {
 		"revision": 2, "timestamp": "2009-11-10T23:00:00Z",
 		"seed-time": "2009-11-10T23:00:00Z",
 	}})
+	resetTime := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	st.Set("pending-factory-reset", &devicestate.FactoryResetRequest{
+		Label: "20200318",
+		Time:  resetTime,
+	})
 	st.Unlock()
 
 	s.expectAuthenticatedAccess()
 
 	restore := s.mockSystemSeeds(c)
 	defer restore()
+	// in run mode, the seed directory is ubuntu-seed
+	c.Assert(os.MkdirAll(filepath.Dir(boot.InitramfsUbuntuSeedDir), 0755), check.IsNil)
+	c.Assert(os.Symlink(dirs.SnapSeedDir, boot.InitramfsUbuntuSeedDir), check.IsNil)
 
 	req, err := http.NewRequest("GET", "/v2/systems", nil)
 	c.Assert(err, check.IsNil)
@@ -257,7 +266,12 @@ func (s *systemsSuite) TestSystemsGetSome(c *check.C) {
 					{Title: "Run normally", Mode: "run"},
 				},
 			},
-		}})
+		},
+		PendingFactoryReset: &client.PendingFactoryReset{
+			Label: "20200318",
+			Time:  resetTime,
+		},
+	})
 }
 
 func (s *systemsSuite) TestSystemsGetNone(c *check.C) {
@@ -650,6 +664,12 @@ func (s *systemsSuite) TestSystemActionNonRoot(c *check.C) {
 	c.Assert(err, check.IsNil)
 	d.Overlord().AddManager(mgr)
 
+	restore := daemon.MockCheckPolkitAction(func(r *http.Request, ucred *daemon.Ucrednet, action string) *daemon.APIError {
+		c.Check(action, check.Equals, "io.snapcraft.snapd.manage-systems")
+		return daemon.Unauthorized("access denied")
+	})
+	defer restore()
+
 	body := `{"action":"do","title":"reinstall","mode":"install"}`
 
 	// pretend to be a simple user
@@ -660,7 +680,7 @@ func (s *systemsSuite) TestSystemActionNonRoot(c *check.C) {
 
 	rec := httptest.NewRecorder()
 	s.serveHTTP(c, rec, req)
-	c.Assert(rec.Code, check.Equals, 403)
+	c.Assert(rec.Code, check.Equals, 401)
 
 	var rspBody map[string]interface{}
 	err = json.Unmarshal(rec.Body.Bytes(), &rspBody)
@@ -670,12 +690,60 @@ func (s *systemsSuite) TestSystemActionNonRoot(c *check.C) {
 			"message": "access denied",
 			"kind":    "login-required",
 		},
-		"status":      "Forbidden",
-		"status-code": 403.0,
+		"status":      "Unauthorized",
+		"status-code": 401.0,
 		"type":        "error",
 	})
 }
 
+func (s *systemsSuite) TestSystemActionPolkit(c *check.C) {
+	s.daemon(c)
+
+	for _, tc := range []struct {
+		mode         string
+		allowed      map[string]bool
+		expectedCode int
+	}{
+		// managing systems is enough for most actions
+		{"run", map[string]bool{"io.snapcraft.snapd.manage-systems": true}, 200},
+		{"run", nil, 401},
+		// a factory reset needs its own authorization
+		{"factory-reset", map[string]bool{"io.snapcraft.snapd.manage-systems": true}, 401},
+		{"factory-reset", map[string]bool{"io.snapcraft.snapd.factory-reset": true}, 401},
+		{"factory-reset", map[string]bool{"io.snapcraft.snapd.manage-systems": true, "io.snapcraft.snapd.factory-reset": true}, 200},
+	} {
+		comment := check.Commentf("%s %v", tc.mode, tc.allowed)
+		restore := daemon.MockCheckPolkitAction(func(r *http.Request, ucred *daemon.Ucrednet, action string) *daemon.APIError {
+			c.Check(ucred.Uid, check.Equals, uint32(42))
+			if tc.allowed[action] {
+				return nil
+			}
+			return daemon.Unauthorized("access denied")
+		})
+		defer restore()
+		called := 0
+		restore = daemon.MockDeviceManagerReboot(func(dm *devicestate.DeviceManager, systemLabel, mode string) error {
+			called++
+			return nil
+		})
+		defer restore()
+
+		body := fmt.Sprintf(`{"action":"reboot", "mode":"%s"}`, tc.mode)
+		req, err := http.NewRequest("POST", "/v2/systems/20200101", strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+		req.RemoteAddr = fmt.Sprintf("pid=100;uid=42;socket=%s;", dirs.SnapdSocket)
+
+		rec := httptest.NewRecorder()
+		s.serveHTTP(c, rec, req)
+		c.Check(rec.Code, check.Equals, tc.expectedCode, comment)
+		if tc.expectedCode == 200 {
+			c.Check(called, check.Equals, 1, comment)
+		} else {
+			c.Check(called, check.Equals, 0, comment)
+		}
+	}
+}
+
 func (s *systemsSuite) TestSystemRebootNeedsRoot(c *check.C) {
 	s.daemon(c)
 
@@ -692,9 +760,14 @@ func (s *systemsSuite) TestSystemRebootNeedsRoot(c *check.C) {
 	// non root
 	s.asUserAuth(c, req)
 
+	restore = daemon.MockCheckPolkitAction(func(r *http.Request, ucred *daemon.Ucrednet, action string) *daemon.APIError {
+		return daemon.Unauthorized("access denied")
+	})
+	defer restore()
+
 	rec := httptest.NewRecorder()
 	s.serveHTTP(c, rec, req)
-	c.Check(rec.Code, check.Equals, 403)
+	c.Check(rec.Code, check.Equals, 401)
 }
 
 func (s *systemsSuite) TestSystemRebootHappy(c *check.C) {
@@ -1217,3 +1290,128 @@ func (s *systemsSuite) TestSystemInstallActionError(c *check.C) {
 	rspe := s.errorReq(c, req, nil)
 	c.Check(rspe.Error(), check.Equals, `unsupported install step "unknown-install-step" (api)`)
 }
+
+func (s *systemsSuite) TestSystemCreateActionCallsDevicestate(c *check.C) {
+	d := s.daemon(c)
+	st := d.Overlord().State()
+
+	soon := 0
+	_, restore := daemon.MockEnsureStateSoon(func(st *state.State) {
+		soon++
+	})
+	defer restore()
+
+	nCalls := 0
+	var gotLabel string
+	var gotOpts devicestate.CreateRecoverySystemOptions
+	r := daemon.MockDevicestateCreateRecoverySystem(func(st *state.State, label string, opts devicestate.CreateRecoverySystemOptions) (*state.Change, error) {
+		gotLabel = label
+		gotOpts = opts
+		nCalls++
+		return st.NewChange("create-recovery-system", "..."), nil
+	})
+	defer r()
+
+	body := map[string]interface{}{
+		"action":       "create",
+		"label":        "1234",
+		"mark-default": true,
+		"test-system":  true,
+	}
+	b, err := json.Marshal(body)
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/systems", bytes.NewBuffer(b))
+	c.Assert(err, check.IsNil)
+
+	rsp := s.asyncReq(c, req, nil)
+
+	st.Lock()
+	chg := st.Change(rsp.Change)
+	st.Unlock()
+	c.Check(chg, check.NotNil)
+	c.Check(nCalls, check.Equals, 1)
+	c.Check(gotLabel, check.Equals, "1234")
+	c.Check(gotOpts, check.Equals, devicestate.CreateRecoverySystemOptions{
+		MarkDefault: true,
+		TestSystem:  true,
+	})
+	c.Check(soon, check.Equals, 1)
+
+	// the label is optional
+	req, err = http.NewRequest("POST", "/v2/systems", strings.NewReader(`{"action": "create"}`))
+	c.Assert(err, check.IsNil)
+	s.asyncReq(c, req, nil)
+	c.Check(nCalls, check.Equals, 2)
+	c.Check(gotLabel, check.Equals, "")
+	c.Check(gotOpts, check.Equals, devicestate.CreateRecoverySystemOptions{})
+}
+
+func (s *systemsSuite) TestSystemCreateActionErrors(c *check.C) {
+	s.daemon(c)
+
+	r := daemon.MockDevicestateCreateRecoverySystem(func(st *state.State, label string, opts devicestate.CreateRecoverySystemOptions) (*state.Change, error) {
+		return nil, devicestate.ErrRecoverySystemsUnsupported
+	})
+	defer r()
+
+	req, err := http.NewRequest("POST", "/v2/systems", strings.NewReader(`{"action": "create"}`))
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, "cannot create recovery system: creating recovery systems is not supported on this system")
+
+	req, err = http.NewRequest("POST", "/v2/systems/20191119", strings.NewReader(`{"action": "create"}`))
+	c.Assert(err, check.IsNil)
+	rspe = s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Matches, "cannot create a recovery system from an existing one, .*")
+}
+
+func (s *systemsSuite) TestSystemSnaps(c *check.C) {
+	s.daemon(c)
+	s.expectReadAccess(daemon.AuthenticatedAccess{})
+
+	r := daemon.MockDeviceManagerSystemSnaps(func(mgr *devicestate.DeviceManager, label string) ([]*devicestate.SystemSnap, error) {
+		c.Check(label, check.Equals, "20191119")
+		return []*devicestate.SystemSnap{
+			{Name: "pc-kernel", Revision: snap.R(12), Channel: "20", Asserted: true, Size: 1000, Shared: true},
+			{Name: "local", Revision: snap.R(-1), Size: 100},
+		}, nil
+	})
+	defer r()
+
+	req, err := http.NewRequest("GET", "/v2/systems/20191119/snaps", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, []client.SystemSnap{
+		{Name: "pc-kernel", Revision: snap.R(12), Channel: "20", Asserted: true, Size: 1000, Shared: true},
+		{Name: "local", Revision: snap.R(-1), Size: 100},
+	})
+}
+
+func (s *systemsSuite) TestSystemSnapsErrors(c *check.C) {
+	s.daemon(c)
+	s.expectReadAccess(daemon.AuthenticatedAccess{})
+
+	var snapsErr error
+	r := daemon.MockDeviceManagerSystemSnaps(func(mgr *devicestate.DeviceManager, label string) ([]*devicestate.SystemSnap, error) {
+		return nil, snapsErr
+	})
+	defer r()
+
+	snapsErr = os.ErrNotExist
+	req, err := http.NewRequest("GET", "/v2/systems/missing/snaps", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 404)
+	c.Check(rspe.Message, check.Equals, `requested seed system "missing" does not exist`)
+
+	snapsErr = fmt.Errorf("boom")
+	req, err = http.NewRequest("GET", "/v2/systems/broken/snaps", nil)
+	c.Assert(err, check.IsNil)
+	rspe = s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 500)
+	c.Check(rspe.Message, check.Equals, `boom`)
+}
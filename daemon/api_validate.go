@@ -26,6 +26,7 @@ import (
 	"net/http"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/snapasserts"
@@ -60,7 +61,21 @@ type validationSetResult struct {
 	Mode      string `json:"mode,omitempty"`
 	Sequence  int    `json:"sequence,omitempty"`
 	Valid     bool   `json:"valid"`
-	// TODO: attributes for Notes column
+
+	LastRefresh     *time.Time `json:"last-refresh,omitempty"`
+	PendingSequence int        `json:"pending-sequence,omitempty"`
+}
+
+// addRefreshStatus fills in the outcome of the last refresh of the
+// validation set from the store, if any.
+func (res *validationSetResult) addRefreshStatus(status map[string]*assertstate.ValidationSetRefreshStatus) {
+	st := status[assertstate.ValidationSetKey(res.AccountID, res.Name)]
+	if st == nil {
+		return
+	}
+	lastRefresh := st.LastRefresh
+	res.LastRefresh = &lastRefresh
+	res.PendingSequence = st.PendingSequence
 }
 
 func modeString(mode assertstate.ValidationSetMode) (string, error) {
@@ -110,6 +125,11 @@ func listValidationSets(c *Command, r *http.Request, _ *auth.UserState) Response
 		return InternalError(err.Error())
 	}
 
+	refreshStatus, err := assertstate.ValidationSetsRefreshStatus(st)
+	if err != nil {
+		return InternalError("accessing validation sets refresh status failed: %v", err)
+	}
+
 	results := make([]validationSetResult, len(names))
 	for i, vs := range names {
 		tr := validationSets[vs]
@@ -131,6 +151,7 @@ func listValidationSets(c *Command, r *http.Request, _ *auth.UserState) Response
 			Sequence:  tr.Sequence(),
 			Valid:     validErr == nil,
 		}
+		results[i].addRefreshStatus(refreshStatus)
 	}
 
 	return SyncResponse(results)
@@ -155,15 +176,22 @@ func validationSetResultFromTracking(st *state.State, tr *assertstate.Validation
 		return nil, err
 	}
 
+	refreshStatus, err := assertstate.ValidationSetsRefreshStatus(st)
+	if err != nil {
+		return nil, err
+	}
+
 	validErr := checkInstalledSnaps(sets, snaps, nil)
-	return &validationSetResult{
+	res := &validationSetResult{
 		AccountID: tr.AccountID,
 		Name:      tr.Name,
 		PinnedAt:  tr.PinnedAt,
 		Mode:      modeStr,
 		Sequence:  tr.Sequence(),
 		Valid:     validErr == nil,
-	}, nil
+	}
+	res.addRefreshStatus(refreshStatus)
+	return res, nil
 }
 
 func getValidationSet(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -25,6 +25,7 @@ import (
 	"net/url"
 	"sort"
 	"strings"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -254,6 +255,39 @@ func (s *apiValidationSetsSuite) TestListValidationSets(c *check.C) {
 	})
 }
 
+func (s *apiValidationSetsSuite) TestListValidationSetsRefreshStatus(c *check.C) {
+	lastRefresh := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	s.mockValidationSetsTracking(st)
+	assertstatetest.AddMany(st, s.dev1acct, s.acct1Key)
+	as := s.mockAssert(c, "foo", "9")
+	err := assertstate.Add(st, as)
+	c.Check(err, check.IsNil)
+	as = s.mockAssert(c, "baz", "2")
+	err = assertstate.Add(st, as)
+	st.Set("validation-sets-refresh", map[string]*assertstate.ValidationSetRefreshStatus{
+		fmt.Sprintf("%s/baz", s.dev1acct.AccountID()): {LastRefresh: lastRefresh, PendingSequence: 3},
+	})
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("GET", "/v2/validation-sets", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, check.Equals, 200)
+	res := rsp.Result.([]daemon.ValidationSetResult)
+	c.Assert(res, check.HasLen, 2)
+	c.Assert(res[0].LastRefresh, check.NotNil)
+	c.Check(res[0].LastRefresh.Equal(lastRefresh), check.Equals, true)
+	c.Check(res[0].PendingSequence, check.Equals, 3)
+	// never refreshed
+	c.Check(res[1].Name, check.Equals, "foo")
+	c.Check(res[1].LastRefresh, check.IsNil)
+	c.Check(res[1].PendingSequence, check.Equals, 0)
+}
+
 func (s *apiValidationSetsSuite) TestGetValidationSetOne(c *check.C) {
 	s.mockSeqFormingAssertionFn = func(assertType *asserts.AssertionType, sequenceKey []string, sequence int, user *auth.UserState) (asserts.Assertion, error) {
 		return nil, &asserts.NotFoundError{
@@ -0,0 +1,97 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"errors"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/gadget/quantity"
+)
+
+var _ = Suite(&volumesSuite{})
+
+type volumesSuite struct {
+	apiBaseSuite
+}
+
+func (s *volumesSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.expectOpenAccess()
+}
+
+func (s *volumesSuite) TestGetVolumes(c *C) {
+	s.daemon(c)
+
+	report := []gadget.DiskVolumeReport{
+		{
+			Name:   "pc",
+			Device: "/dev/vda",
+			Schema: "gpt",
+			Size:   10 * quantity.SizeGiB,
+			Structure: []gadget.DiskStructureReport{
+				{
+					Node:           "/dev/vda1",
+					PartitionUUID:  "C5A930DF-E86A-4BAE-A4C5-C861353796E6",
+					PartitionLabel: "ubuntu-seed",
+					Filesystem:     "vfat",
+					Offset:         quantity.OffsetMiB,
+					Size:           1200 * quantity.SizeMiB,
+					StructureName:  "ubuntu-seed",
+					Role:           "system-seed",
+				},
+				{
+					Node:     "/dev/vda2",
+					Offset:   1201 * quantity.OffsetMiB,
+					Size:     quantity.SizeGiB,
+					Unmapped: true,
+				},
+			},
+		},
+	}
+	defer daemon.MockDeviceManagerDiskVolumesReport(func() ([]gadget.DiskVolumeReport, error) {
+		return report, nil
+	})()
+
+	req, err := http.NewRequest("GET", "/v2/volumes", nil)
+	c.Assert(err, IsNil)
+	s.checkGetOnly(c, req)
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, report)
+}
+
+func (s *volumesSuite) TestGetVolumesError(c *C) {
+	s.daemon(c)
+
+	defer daemon.MockDeviceManagerDiskVolumesReport(func() ([]gadget.DiskVolumeReport, error) {
+		return nil, errors.New("boom")
+	})()
+
+	req, err := http.NewRequest("GET", "/v2/volumes", nil)
+	c.Assert(err, IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe, DeepEquals, daemon.InternalError("cannot report gadget volumes: boom"))
+}
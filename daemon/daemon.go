@@ -88,6 +88,9 @@ type Daemon struct {
 
 	expectedRebootDidNotHappen bool
 
+	// set up on first use by the change events endpoint
+	changeEventsTracker *changeEvents
+
 	mu sync.Mutex
 }
 
@@ -199,6 +199,9 @@ func SnapChangeConflict(cce *snapstate.ChangeConflictError) *apiError {
 	if cce.ChangeKind != "" {
 		value["change-kind"] = cce.ChangeKind
 	}
+	if cce.ChangeID != "" {
+		value["change-id"] = cce.ChangeID
+	}
 
 	return &apiError{
 		Status:  409,
@@ -329,6 +332,12 @@ func errToResponse(err error, snaps []string, fallback errorResponder, format st
 		case *snapstate.SnapNotClassicError:
 			kind = client.ErrorKindSnapNotClassic
 			snapName = err.Snap
+		case *snapstate.UnknownRevisionError:
+			kind = client.ErrorKindSnapRevisionUnknown
+			snapName = err.Snap
+		case *snapstate.RevisionNotRetainedError:
+			kind = client.ErrorKindSnapRevisionNotRetained
+			snapName = err.Snap
 		case *snapstate.InsufficientSpaceError:
 			return InsufficientSpace(err)
 		case net.Error:
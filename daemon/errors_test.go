@@ -127,6 +127,8 @@ func (s *errorsSuite) TestErrToResponse(c *C) {
 	nc := &snapstate.SnapNotClassicError{Snap: "foo"}
 	nce := &snapstate.SnapNeedsClassicError{Snap: "foo"}
 	ncse := &snapstate.SnapNeedsClassicSystemError{Snap: "foo"}
+	ure := &snapstate.UnknownRevisionError{Snap: "foo", Revision: snap.R(7)}
+	rnre := &snapstate.RevisionNotRetainedError{Snap: "foo", Revision: snap.R(7)}
 	netoe := fakeNetError{message: "other"}
 	nettoute := fakeNetError{message: "timeout", timeout: true}
 	nettmpe := fakeNetError{message: "temp", temporary: true}
@@ -165,6 +167,8 @@ func (s *errorsSuite) TestErrToResponse(c *C) {
 		{nc, makeErrorRsp(client.ErrorKindSnapNotClassic, nc, "foo"), false},
 		{nce, makeErrorRsp(client.ErrorKindSnapNeedsClassic, nce, "foo"), false},
 		{ncse, makeErrorRsp(client.ErrorKindSnapNeedsClassicSystem, ncse, "foo"), false},
+		{ure, makeErrorRsp(client.ErrorKindSnapRevisionUnknown, ure, "foo"), false},
+		{rnre, makeErrorRsp(client.ErrorKindSnapRevisionNotRetained, rnre, "foo"), false},
 		{cce, daemon.SnapChangeConflict(cce), false},
 		{nettoute, makeErrorRsp(client.ErrorKindNetworkTimeout, nettoute, ""), false},
 		{netoe, daemon.BadRequest("ERR: %v", netoe), false},
@@ -193,6 +197,24 @@ func (s *errorsSuite) TestErrToResponse(c *C) {
 	}
 }
 
+func (s *errorsSuite) TestSnapChangeConflict(c *C) {
+	rspe := daemon.SnapChangeConflict(&snapstate.ChangeConflictError{
+		Snap:       "foo",
+		ChangeKind: "auto-refresh",
+		ChangeID:   "7",
+	})
+	c.Check(rspe, DeepEquals, &daemon.APIError{
+		Status:  409,
+		Message: `snap "foo" has "auto-refresh" change in progress`,
+		Kind:    client.ErrorKindSnapChangeConflict,
+		Value: map[string]interface{}{
+			"snap-name":   "foo",
+			"change-kind": "auto-refresh",
+			"change-id":   "7",
+		},
+	})
+}
+
 func (errorsSuite) TestErrorResponderPrintfsWithArgs(c *C) {
 	teapot := daemon.MakeErrorResponder(418)
 
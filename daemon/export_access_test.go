@@ -31,6 +31,7 @@ type (
 	OpenAccess                   = openAccess
 	AuthenticatedAccess          = authenticatedAccess
 	RootAccess                   = rootAccess
+	RootOrPolkitAccess           = rootOrPolkitAccess
 	SnapAccess                   = snapAccess
 	InterfaceOpenAccess          = interfaceOpenAccess
 	InterfaceAuthenticatedAccess = interfaceAuthenticatedAccess
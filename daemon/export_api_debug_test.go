@@ -19,6 +19,10 @@
 
 package daemon
 
+import (
+	"github.com/snapcore/snapd/osutil/disks"
+)
+
 type (
 	ConnectivityStatus = connectivityStatus
 )
@@ -26,3 +30,11 @@ type (
 var (
 	MinLane = minLane
 )
+
+func MockDisksAllPhysicalDisks(f func() ([]disks.Disk, error)) (restore func()) {
+	old := disksAllPhysicalDisks
+	disksAllPhysicalDisks = f
+	return func() {
+		disksAllPhysicalDisks = old
+	}
+}
@@ -26,7 +26,7 @@ import (
 	"github.com/snapcore/snapd/snap"
 )
 
-func MockDevicestateRemodel(mock func(*state.State, *asserts.Model, []*snap.SideInfo, []string) (*state.Change, error)) (restore func()) {
+func MockDevicestateRemodel(mock func(*state.State, *asserts.Model, []*snap.SideInfo, []string, devicestate.RemodelOptions) (*state.Change, error)) (restore func()) {
 	oldDevicestateRemodel := devicestateRemodel
 	devicestateRemodel = mock
 	return func() {
@@ -42,6 +42,14 @@ func MockDevicestateDeviceManagerUnregister(mock func(*devicestate.DeviceManager
 	}
 }
 
+func MockDevicestateRenewSerial(mock func(*state.State) (*state.Change, error)) (restore func()) {
+	oldDevicestateRenewSerial := devicestateRenewSerial
+	devicestateRenewSerial = mock
+	return func() {
+		devicestateRenewSerial = oldDevicestateRenewSerial
+	}
+}
+
 type (
 	PostModelData = postModelData
 )
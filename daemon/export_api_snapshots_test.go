@@ -48,6 +48,14 @@ func MockSnapshotList(newList func(context.Context, *state.State, uint64, []stri
 	}
 }
 
+func MockSnapshotUsage(newUsage func(context.Context, *state.State) (*client.SnapshotsUsage, error)) (restore func()) {
+	oldUsage := snapshotUsage
+	snapshotUsage = newUsage
+	return func() {
+		snapshotUsage = oldUsage
+	}
+}
+
 func MockSnapshotExport(newExport func(context.Context, *state.State, uint64) (*snapshotstate.SnapshotExport, error)) (restore func()) {
 	oldExport := snapshotExport
 	snapshotExport = newExport
@@ -64,7 +72,7 @@ func MockSnapshotCheck(newCheck func(*state.State, uint64, []string, []string) (
 	}
 }
 
-func MockSnapshotRestore(newRestore func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error)) (restore func()) {
+func MockSnapshotRestore(newRestore func(*state.State, uint64, []string, []string, *snap.SnapshotRestoreOptions) ([]string, *state.TaskSet, error)) (restore func()) {
 	oldRestore := snapshotRestore
 	snapshotRestore = newRestore
 	return func() {
@@ -45,6 +45,12 @@ func MockDeviceManagerSystemAndGadgetAndEncryptionInfo(f func(*devicestate.Devic
 	return restore
 }
 
+func MockDeviceManagerSystemSnaps(f func(*devicestate.DeviceManager, string) ([]*devicestate.SystemSnap, error)) (restore func()) {
+	restore = testutil.Backup(&deviceManagerSystemSnaps)
+	deviceManagerSystemSnaps = f
+	return restore
+}
+
 func MockDevicestateInstallFinish(f func(*state.State, string, map[string]*gadget.Volume) (*state.Change, error)) (restore func()) {
 	restore = testutil.Backup(&devicestateInstallFinish)
 	devicestateInstallFinish = f
@@ -56,3 +62,9 @@ func MockDevicestateInstallSetupStorageEncryption(f func(*state.State, string, m
 	devicestateInstallSetupStorageEncryption = f
 	return restore
 }
+
+func MockDevicestateCreateRecoverySystem(f func(*state.State, string, devicestate.CreateRecoverySystemOptions) (*state.Change, error)) (restore func()) {
+	restore = testutil.Backup(&devicestateCreateRecoverySystem)
+	devicestateCreateRecoverySystem = f
+	return restore
+}
@@ -239,6 +239,14 @@ func MockSnapstateProceedWithRefresh(f func(st *state.State, gatingSnap string,
 	}
 }
 
+func MockSnapstateRefreshCandidatesPreview(f func(ctx context.Context, st *state.State) ([]*snapstate.RefreshCandidateInfo, error)) (restore func()) {
+	old := snapstateRefreshCandidatesPreview
+	snapstateRefreshCandidatesPreview = f
+	return func() {
+		snapstateRefreshCandidatesPreview = old
+	}
+}
+
 func MockSnapstateHoldRefreshesBySystem(f func(st *state.State, level snapstate.HoldLevel, time string, snaps []string) error) (restore func()) {
 	old := snapstateHoldRefreshesBySystem
 	snapstateHoldRefreshesBySystem = f
@@ -298,11 +306,14 @@ func MockSideloadSnapsInfo(sis []*snap.SideInfo) (restore func()) {
 }
 
 type (
-	RespJSON        = respJSON
-	FileResponse    = fileResponse
-	APIError        = apiError
-	ErrorResult     = errorResult
-	SnapInstruction = snapInstruction
+	RespJSON               = respJSON
+	FileResponse           = fileResponse
+	APIError               = apiError
+	ErrorResult            = errorResult
+	SnapInstruction        = snapInstruction
+	ConnectionOverrideJSON = connectionOverrideJSON
+	BundleImportJSON       = bundleImportJSON
+	AssertionRefJSON       = assertionRefJSON
 )
 
 func (inst *snapInstruction) Dispatch() snapActionFunc {
@@ -45,6 +45,8 @@ type aboutSnap struct {
 
 	hold       time.Time
 	gatingHold time.Time
+
+	refreshInhibitProceedTime time.Time
 }
 
 // localSnapInfo returns the information about the current snap for the given
@@ -88,6 +90,8 @@ func localSnapInfo(st *state.State, name string) (aboutSnap, error) {
 		health:     clientHealthFromHealthstate(health),
 		hold:       userHold,
 		gatingHold: gatingHold,
+
+		refreshInhibitProceedTime: snapst.RefreshInhibitProceedTime(st),
 	}, nil
 }
 
@@ -131,6 +135,7 @@ func allLocalSnapInfos(st *state.State, all bool, wanted map[string]bool) ([]abo
 		if err != nil {
 			return nil, err
 		}
+		refreshInhibitProceedTime := snapst.RefreshInhibitProceedTime(st)
 
 		var aboutThis []aboutSnap
 		var info *snap.Info
@@ -158,6 +163,8 @@ func allLocalSnapInfos(st *state.State, all bool, wanted map[string]bool) ([]abo
 					health:     health,
 					hold:       userHold,
 					gatingHold: gatingHold,
+
+					refreshInhibitProceedTime: refreshInhibitProceedTime,
 				}
 				aboutThis = append(aboutThis, abSnap)
 			}
@@ -178,6 +185,8 @@ func allLocalSnapInfos(st *state.State, all bool, wanted map[string]bool) ([]abo
 				health:     health,
 				hold:       userHold,
 				gatingHold: gatingHold,
+
+				refreshInhibitProceedTime: refreshInhibitProceedTime,
 			}
 			aboutThis = append(aboutThis, abSnap)
 		}
@@ -192,11 +201,12 @@ func clientHealthFromHealthstate(h *healthstate.HealthState) *client.SnapHealth
 		return nil
 	}
 	return &client.SnapHealth{
-		Revision:  h.Revision,
-		Timestamp: h.Timestamp,
-		Status:    h.Status.String(),
-		Message:   h.Message,
-		Code:      h.Code,
+		Revision:       h.Revision,
+		Timestamp:      h.Timestamp,
+		Status:         h.Status.String(),
+		Message:        h.Message,
+		Code:           h.Code,
+		LastTransition: h.LastTransition,
 	}
 }
 
@@ -239,6 +249,11 @@ func mapLocal(about aboutSnap, sd clientutil.StatusDecorator) *client.Snap {
 	if !about.gatingHold.IsZero() {
 		result.GatingHold = &about.gatingHold
 	}
+	if !about.refreshInhibitProceedTime.IsZero() {
+		result.RefreshInhibit = &client.SnapRefreshInhibit{
+			ProceedTime: about.refreshInhibitProceedTime,
+		}
+	}
 
 	return result
 }
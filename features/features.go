@@ -68,6 +68,9 @@ const (
 	//  * journal quotas are still experimental
 	// while guota groups creation and management and memory, cpu, quotas are no longer experimental.
 	QuotaGroups
+	// StateWriteAheadLog controls whether changes to the snapd state are
+	// appended to a write-ahead log instead of rewriting the state file.
+	StateWriteAheadLog
 
 	// lastFeature is the final known feature, it is only used for testing.
 	lastFeature
@@ -108,6 +111,8 @@ var featureNames = map[SnapdFeature]string{
 	GateAutoRefreshHook: "gate-auto-refresh-hook",
 
 	QuotaGroups: "quota-groups",
+
+	StateWriteAheadLog: "state-write-ahead-log",
 }
 
 // featuresEnabledWhenUnset contains a set of features that are enabled when not explicitly configured.
@@ -129,6 +134,8 @@ var featuresExported = map[SnapdFeature]bool{
 	RobustMountNamespaceUpdates:   true,
 	HiddenSnapDataHomeDir:         true,
 	MoveSnapHomeDir:               true,
+
+	StateWriteAheadLog: true,
 }
 
 // String returns the name of a snapd feature.
@@ -36,7 +36,6 @@ var (
 
 	SetImplicitForVolumeStructure = setImplicitForVolumeStructure
 
-	ResolveVolume      = resolveVolume
 	CanUpdateStructure = canUpdateStructure
 	CanUpdateVolume    = canUpdateVolume
 
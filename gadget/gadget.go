@@ -115,6 +115,14 @@ type KernelCmdline struct {
 	Allow []kcmdline.ArgumentPattern `yaml:"allow"`
 }
 
+// BootloaderOptions carries the options of the bootloader of the system.
+type BootloaderOptions struct {
+	// RecoverySystemsMenu enables a menu listing the good recovery
+	// systems in the recovery bootloader, when supported by the
+	// bootloader.
+	RecoverySystemsMenu bool `yaml:"recovery-systems-menu"`
+}
+
 type Info struct {
 	Volumes map[string]*Volume `yaml:"volumes,omitempty"`
 
@@ -124,6 +132,8 @@ type Info struct {
 	Connections []Connection `yaml:"connections"`
 
 	KernelCmdline KernelCmdline `yaml:"kernel-cmdline"`
+
+	BootloaderOptions BootloaderOptions `yaml:"bootloader-options"`
 }
 
 // PartialProperty is a gadget property that can be partially defined.
@@ -515,6 +525,10 @@ func (vc VolumeContent) String() string {
 type VolumeUpdate struct {
 	Edition  edition.Number `yaml:"edition" json:"edition"`
 	Preserve []string       `yaml:"preserve" json:"preserve"`
+	// GrowToEnd requests that the structure is grown to fill the remaining
+	// space on the disk when the gadget is updated. Only the last structure
+	// of a volume with the system-data role can be grown.
+	GrowToEnd bool `yaml:"grow-to-end" json:"grow-to-end,omitempty"`
 }
 
 // DiskVolumeDeviceTraits is a set of traits about a disk that were measured at
@@ -676,6 +690,48 @@ func LoadDiskVolumesDeviceTraits(dir string) (map[string]DiskVolumeDeviceTraits,
 	return mapping, nil
 }
 
+// findDiskDeviceForVolume returns the kernel device node of the disk holding
+// the given volume, or an empty string if none of the partitions of the volume
+// could be found.
+func findDiskDeviceForVolume(vol *Volume) (string, error) {
+	// try to find a device for a structure inside the volume, we have a
+	// loop to attempt to use all structures in the volume in case there are
+	// partitions we can't map to a device directly at first using the
+	// device symlinks that FindDeviceForStructure uses
+	for _, vs := range vol.Structure {
+		// TODO: This code works for volumes that have at least one
+		// partition (i.e. not type: bare structure), but does not work for
+		// volumes which contain only type: bare structures with no other
+		// structures on them. It is entirely unclear how to identify such
+		// a volume, since there is no information on the disk about where
+		// such raw structures begin and end and thus no way to validate
+		// that a given disk "has" such raw structures at particular
+		// locations, aside from potentially reading and comparing the bytes
+		// at the expected locations, but that is probably fragile and very
+		// non-performant.
+
+		if !vs.IsPartition() {
+			// skip trying to find non-partitions on disk, it won't work
+			continue
+		}
+
+		structureDevice, err := FindDeviceForStructure(&vs)
+		if err != nil && err != ErrDeviceNotFound {
+			return "", err
+		}
+		if structureDevice != "" {
+			// we found a device for this structure, get the parent disk
+			// and use that as the device for this volume
+			disk, err := disks.DiskFromPartitionDeviceNode(structureDevice)
+			if err != nil {
+				return "", err
+			}
+			return disk.KernelDeviceNode(), nil
+		}
+	}
+	return "", nil
+}
+
 // AllDiskVolumeDeviceTraits takes a mapping of volume name to Volume
 // and produces a map of volume name to DiskVolumeDeviceTraits. Since
 // doing so uses DiskVolumeDeviceTraitsForDevice, it will also
@@ -689,45 +745,10 @@ func AllDiskVolumeDeviceTraits(allVols map[string]*Volume, optsPerVolume map[str
 	// find all devices which map to volumes to save the current state of the
 	// system
 	for name, vol := range allVols {
-		// try to find a device for a structure inside the volume, we have a
-		// loop to attempt to use all structures in the volume in case there are
-		// partitions we can't map to a device directly at first using the
-		// device symlinks that FindDeviceForStructure uses
-		dev := ""
-		for _, vs := range vol.Structure {
-			// TODO: This code works for volumes that have at least one
-			// partition (i.e. not type: bare structure), but does not work for
-			// volumes which contain only type: bare structures with no other
-			// structures on them. It is entirely unclear how to identify such
-			// a volume, since there is no information on the disk about where
-			// such raw structures begin and end and thus no way to validate
-			// that a given disk "has" such raw structures at particular
-			// locations, aside from potentially reading and comparing the bytes
-			// at the expected locations, but that is probably fragile and very
-			// non-performant.
-
-			if !vs.IsPartition() {
-				// skip trying to find non-partitions on disk, it won't work
-				continue
-			}
-
-			structureDevice, err := FindDeviceForStructure(&vs)
-			if err != nil && err != ErrDeviceNotFound {
-				return nil, err
-			}
-			if structureDevice != "" {
-				// we found a device for this structure, get the parent disk
-				// and save that as the device for this volume
-				disk, err := disks.DiskFromPartitionDeviceNode(structureDevice)
-				if err != nil {
-					return nil, err
-				}
-
-				dev = disk.KernelDeviceNode()
-				break
-			}
+		dev, err := findDiskDeviceForVolume(vol)
+		if err != nil {
+			return nil, err
 		}
-
 		if dev == "" {
 			return nil, fmt.Errorf("cannot find disk for volume %s from gadget", name)
 		}
@@ -1003,13 +1024,13 @@ func InfoFromGadgetYaml(gadgetYaml []byte, model Model) (*Info, error) {
 			// pass
 		case "grub", "u-boot", "android-boot", "lk":
 			bootloadersFound += 1
-		case "piboot":
+		case "piboot", "sd-boot":
 			if !compatWithPibootOrIndeterminate(model) {
-				return nil, errors.New("piboot bootloader valid only for UC20 onwards")
+				return nil, fmt.Errorf("%s bootloader valid only for UC20 onwards", v.Bootloader)
 			}
 			bootloadersFound += 1
 		default:
-			return nil, errors.New("bootloader must be one of grub, u-boot, android-boot, piboot or lk")
+			return nil, errors.New("bootloader must be one of grub, u-boot, android-boot, piboot, sd-boot or lk")
 		}
 	}
 	switch {
@@ -1567,6 +1588,15 @@ func validateStructureUpdate(vs *VolumeStructure, v *Volume) error {
 		}
 		names[n] = true
 	}
+
+	if vs.Update.GrowToEnd {
+		if vs.Role != SystemData {
+			return errors.New(`"grow-to-end" is only supported for structures with the system-data role`)
+		}
+		if vs.YamlIndex != len(v.Structure)-1 {
+			return errors.New(`"grow-to-end" is only supported for the last structure of the volume`)
+		}
+	}
 	return nil
 }
 
@@ -1642,30 +1672,56 @@ func (s *RelativeOffset) UnmarshalYAML(unmarshal func(interface{}) error) error
 	return nil
 }
 
-// IsCompatible checks whether the current and an update are compatible. Returns
-// nil or an error describing the incompatibility.
-// TODO: make this reasonably consistent with Update for multi-volume scenarios
-func IsCompatible(current, new *Info) error {
-	// XXX: the only compatibility we have now is making sure that the new
-	// layout can be used on an existing volume
-	if len(new.Volumes) > 1 {
-		return fmt.Errorf("gadgets with multiple volumes are unsupported")
-	}
+// IncompatibleLayoutError describes all the changes of a gadget update which
+// are not compatible with the layout of the current gadget.
+type IncompatibleLayoutError struct {
+	// Changes has a description of each incompatible change, prefixed with
+	// the volume it applies to.
+	Changes []string
+}
 
-	// XXX: the code below errors out with more than 1 volume in the current
-	// gadget, we allow this scenario in update but better bail out here and
-	// have users fix their gadgets
-	currentVol, newVol, err := resolveVolume(current, new)
-	if err != nil {
-		return err
+func (e *IncompatibleLayoutError) Error() string {
+	if len(e.Changes) == 1 {
+		return "incompatible layout change: " + e.Changes[0]
 	}
+	return "incompatible layout change:\n- " + strings.Join(e.Changes, "\n- ")
+}
 
-	if err := isLayoutCompatible(currentVol, newVol); err != nil {
-		return fmt.Errorf("incompatible layout change: %v", err)
+// IsCompatible checks whether the current and an update are compatible, that
+// is the update has the same volumes with compatible structures. Returns nil
+// or an *IncompatibleLayoutError listing all the incompatible changes.
+func IsCompatible(current, new *Info) error {
+	var changes []string
+	for _, name := range sortedVolumeNames(current.Volumes) {
+		if _, ok := new.Volumes[name]; !ok {
+			changes = append(changes, fmt.Sprintf("volume %q was removed", name))
+		}
+	}
+	for _, name := range sortedVolumeNames(new.Volumes) {
+		currentVol, ok := current.Volumes[name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("volume %q was added", name))
+			continue
+		}
+		for _, change := range layoutIncompatibilities(currentVol, new.Volumes[name]) {
+			changes = append(changes, fmt.Sprintf("volume %q: %s", name, change))
+		}
+	}
+	if len(changes) != 0 {
+		return &IncompatibleLayoutError{Changes: changes}
 	}
 	return nil
 }
 
+func sortedVolumeNames(vols map[string]*Volume) []string {
+	names := make([]string, 0, len(vols))
+	for name := range vols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // checkCompatibleSchema checks if the schema in a new volume we are
 // updating to is compatible with the old volume.
 func checkCompatibleSchema(old, new *Volume) error {
@@ -1784,32 +1840,59 @@ func KernelCommandLineFromGadget(gadgetDirOrSnapPath string) (cmdline string, fu
 	if err != nil {
 		return "", false, fmt.Errorf("cannot open gadget snap: %v", err)
 	}
-	contentExtra, err := sf.ReadFile("cmdline.extra")
+	cmdline, full, _, err = kernelCommandLineFromGadget(sf, "")
+	return cmdline, full, err
+}
+
+// RecoveryKernelCommandLineFromGadget returns the desired kernel command line
+// of recovery systems provided by the gadget. The recovery specific
+// cmdline.extra.recover and cmdline.full.recover files take precedence, when
+// neither is present the generic cmdline.extra or cmdline.full are used.
+func RecoveryKernelCommandLineFromGadget(gadgetDirOrSnapPath string) (cmdline string, full bool, err error) {
+	sf, err := snapfile.Open(gadgetDirOrSnapPath)
+	if err != nil {
+		return "", false, fmt.Errorf("cannot open gadget snap: %v", err)
+	}
+	cmdline, full, found, err := kernelCommandLineFromGadget(sf, ".recover")
+	if err != nil || found {
+		return cmdline, full, err
+	}
+	cmdline, full, _, err = kernelCommandLineFromGadget(sf, "")
+	return cmdline, full, err
+}
+
+// kernelCommandLineFromGadget reads the command line from the cmdline.extra
+// or cmdline.full files with the given suffix. The found flag indicates
+// whether either of the files was present.
+func kernelCommandLineFromGadget(sf snap.Container, suffix string) (cmdline string, full, found bool, err error) {
+	extraFile := "cmdline.extra" + suffix
+	fullFile := "cmdline.full" + suffix
+	contentExtra, err := sf.ReadFile(extraFile)
 	if err != nil && !os.IsNotExist(err) {
-		return "", false, err
+		return "", false, false, err
 	}
 	// TODO: should we enforce the maximum kernel command line for cmdline.full?
-	contentFull, err := sf.ReadFile("cmdline.full")
+	contentFull, err := sf.ReadFile(fullFile)
 	if err != nil && !os.IsNotExist(err) {
-		return "", false, err
+		return "", false, false, err
 	}
 	content := contentExtra
-	whichFile := "cmdline.extra"
+	whichFile := extraFile
 	switch {
 	case contentExtra != nil && contentFull != nil:
-		return "", false, fmt.Errorf("cannot support both extra and full kernel command lines")
+		return "", false, true, fmt.Errorf("cannot support both extra and full kernel command lines")
 	case contentExtra == nil && contentFull == nil:
-		return "", false, nil
+		return "", false, false, nil
 	case contentFull != nil:
 		content = contentFull
-		whichFile = "cmdline.full"
+		whichFile = fullFile
 		full = true
 	}
 	parsed, err := parseCommandLineFromGadget(content)
 	if err != nil {
-		return "", full, fmt.Errorf("invalid kernel command line in %v: %v", whichFile, err)
+		return "", full, true, fmt.Errorf("invalid kernel command line in %v: %v", whichFile, err)
 	}
-	return parsed, full, nil
+	return parsed, full, true, nil
 }
 
 // parseCommandLineFromGadget parses the command line file and returns a
@@ -952,7 +952,25 @@ volumes:
 	c.Assert(err, IsNil)
 
 	_, err = gadget.ReadInfo(s.dir, nil)
-	c.Assert(err, ErrorMatches, "bootloader must be one of grub, u-boot, android-boot, piboot or lk")
+	c.Assert(err, ErrorMatches, "bootloader must be one of grub, u-boot, android-boot, piboot, sd-boot or lk")
+}
+
+func (s *gadgetYamlTestSuite) TestReadGadgetYamlSdbootBootloader(c *C) {
+	mockGadgetYaml := []byte(`
+volumes:
+ name:
+  bootloader: sd-boot
+`)
+
+	err := os.WriteFile(s.gadgetYamlPath, mockGadgetYaml, 0644)
+	c.Assert(err, IsNil)
+
+	ginfo, err := gadget.ReadInfo(s.dir, uc20Mod)
+	c.Assert(err, IsNil)
+	c.Check(ginfo.Volumes["name"].Bootloader, Equals, "sd-boot")
+
+	_, err = gadget.ReadInfo(s.dir, coreMod)
+	c.Assert(err, ErrorMatches, "sd-boot bootloader valid only for UC20 onwards")
 }
 
 func (s *gadgetYamlTestSuite) TestReadGadgetYamlEmptyBootloader(c *C) {
@@ -1770,6 +1788,33 @@ func (s *gadgetYamlTestSuite) TestValidateStructureUpdatePreserveDuplicates(c *C
 	c.Check(err, ErrorMatches, `duplicate "preserve" entry "foo"`)
 }
 
+func (s *gadgetYamlTestSuite) TestValidateStructureUpdateGrowToEnd(c *C) {
+	growToEnd := `
+        update:
+          grow-to-end: true
+`[1:]
+
+	yaml := gadgettest.SingleVolumeUC20GadgetYaml + growToEnd
+	info, err := gadget.InfoFromGadgetYaml([]byte(yaml), uc20Mod)
+	c.Assert(err, IsNil)
+	c.Check(info.Volumes["pc"].Structure[5].Name, Equals, "ubuntu-data")
+	c.Check(info.Volumes["pc"].Structure[5].Update.GrowToEnd, Equals, true)
+
+	// not system-data
+	yaml = strings.Replace(gadgettest.SingleVolumeUC20GadgetYaml, "        size: 16M\n", "        size: 16M\n"+growToEnd, 1)
+	_, err = gadget.InfoFromGadgetYaml([]byte(yaml), uc20Mod)
+	c.Check(err, ErrorMatches, `invalid volume "pc": invalid structure #4 \("ubuntu-save"\): "grow-to-end" is only supported for structures with the system-data role`)
+
+	// not the last structure
+	yaml = gadgettest.SingleVolumeUC20GadgetYaml + growToEnd + `
+      - name: other
+        type: 83,0FC63DAF-8483-4772-8E79-3D69D8477DE4
+        size: 1M
+`[1:]
+	_, err = gadget.InfoFromGadgetYaml([]byte(yaml), uc20Mod)
+	c.Check(err, ErrorMatches, `invalid volume "pc": invalid structure #5 \("ubuntu-data"\): "grow-to-end" is only supported for the last structure of the volume`)
+}
+
 func (s *gadgetYamlTestSuite) TestValidateStructureSizeRequired(c *C) {
 
 	gv := &gadget.Volume{Schema: "gpt"}
@@ -2641,12 +2686,12 @@ volumes:
 		gadgetYaml []byte
 		err        string
 	}{
-		{mockOtherYaml, `cannot find entry for volume "volumename" in updated gadget info`},
-		{mockManyYaml, "gadgets with multiple volumes are unsupported"},
-		{mockNewStructuresYaml, `incompatible layout change: incompatible change in the number of structures from 0 to 1`},
-		{mockBadIDYaml, "incompatible layout change: incompatible ID change from 0C to 0D"},
-		{mockSchemaYaml, "incompatible layout change: incompatible schema change from mbr to gpt"},
-		{mockBootloaderYaml, "incompatible layout change: incompatible bootloader change from u-boot to grub"},
+		{mockOtherYaml, `incompatible layout change:\n- volume "volumename" was removed\n- volume "volumename-other" was added`},
+		{mockManyYaml, `incompatible layout change: volume "volumename-many" was added`},
+		{mockNewStructuresYaml, `incompatible layout change: volume "volumename": incompatible change in the number of structures from 0 to 1`},
+		{mockBadIDYaml, `incompatible layout change: volume "volumename": incompatible ID change from 0C to 0D`},
+		{mockSchemaYaml, `incompatible layout change: volume "volumename": incompatible schema change from mbr to gpt`},
+		{mockBootloaderYaml, `incompatible layout change: volume "volumename": incompatible bootloader change from u-boot to grub`},
 	} {
 		c.Logf("trying: %v\n", string(tc.gadgetYaml))
 		gi, err := gadget.InfoFromGadgetYaml(mockYaml, coreMod)
@@ -2719,11 +2764,11 @@ volumes:
 		err        string
 	}{
 		{mockYaml, ``},
-		{mockBadStructureTypeYaml, `incompatible layout change: incompatible structure #0 \("legit"\) change: cannot change structure type from "00000000-0000-0000-0000-0000deadbeef" to "00000000-0000-0000-0000-0000deadcafe"`},
-		{mockBadFsYaml, `incompatible layout change: incompatible structure #0 \("legit"\) change: cannot change filesystem from "ext4" to "vfat"`},
-		{mockBadOffsetYaml, `incompatible layout change: incompatible structure #0 \("legit"\) change: new valid structure offset range \[2097152, 2097152\] is not compatible with current \(\[1048576, 1048576\]\)`},
-		{mockBadLabelYaml, `incompatible layout change: incompatible structure #0 \("legit"\) change: cannot change filesystem label from "fs-legit" to "fs-non-legit"`},
-		{mockGPTBadNameYaml, `incompatible layout change: incompatible structure #0 \("non-legit"\) change: cannot change structure name from "legit" to "non-legit"`},
+		{mockBadStructureTypeYaml, `incompatible layout change: volume "volumename": incompatible structure #0 \("legit"\) change: cannot change structure type from "00000000-0000-0000-0000-0000deadbeef" to "00000000-0000-0000-0000-0000deadcafe"`},
+		{mockBadFsYaml, `incompatible layout change: volume "volumename": incompatible structure #0 \("legit"\) change: cannot change filesystem from "ext4" to "vfat"`},
+		{mockBadOffsetYaml, `incompatible layout change: volume "volumename": incompatible structure #0 \("legit"\) change: new valid structure offset range \[2097152, 2097152\] is not compatible with current \(\[1048576, 1048576\]\)`},
+		{mockBadLabelYaml, `incompatible layout change: volume "volumename": incompatible structure #0 \("legit"\) change: cannot change filesystem label from "fs-legit" to "fs-non-legit"`},
+		{mockGPTBadNameYaml, `incompatible layout change: volume "volumename": incompatible structure #0 \("non-legit"\) change: cannot change structure name from "legit" to "non-legit"`},
 	} {
 		c.Logf("trying: %d %v\n", i, string(tc.gadgetYaml))
 		gi, err := gadget.InfoFromGadgetYaml([]byte(mockYaml), coreMod)
@@ -2740,6 +2785,142 @@ volumes:
 	}
 }
 
+func (s *gadgetCompatibilityTestSuite) TestGadgetIsCompatibleListsAllChanges(c *C) {
+	var mockYaml = `
+volumes:
+  pc:
+    schema: gpt
+    bootloader: grub
+    structure:
+      - name: seed
+        size: 2M
+        type: 00000000-0000-0000-0000-0000deadbeef
+        filesystem: vfat
+      - name: data
+        size: 10M
+        type: 00000000-0000-0000-0000-0000deadbeef
+        filesystem: ext4
+  other:
+    schema: gpt
+    structure:
+      - name: foo
+        size: 2M
+        type: 00000000-0000-0000-0000-0000deadbeef
+`
+	var mockNewYaml = `
+volumes:
+  pc:
+    schema: gpt
+    bootloader: grub
+    structure:
+      - name: seed
+        size: 2M
+        type: 00000000-0000-0000-0000-0000deadbeef
+        filesystem: ext4
+      - name: data
+        size: 10M
+        type: 00000000-0000-0000-0000-0000deadbeef
+        filesystem: vfat
+  other:
+    schema: mbr
+    structure:
+      - name: foo
+        size: 2M
+        type: 0C
+`
+	gi, err := gadget.InfoFromGadgetYaml([]byte(mockYaml), coreMod)
+	c.Assert(err, IsNil)
+	giNew, err := gadget.InfoFromGadgetYaml([]byte(mockNewYaml), coreMod)
+	c.Assert(err, IsNil)
+
+	err = gadget.IsCompatible(gi, giNew)
+	c.Assert(err, FitsTypeOf, &gadget.IncompatibleLayoutError{})
+	c.Check(err.(*gadget.IncompatibleLayoutError).Changes, DeepEquals, []string{
+		`volume "other": incompatible schema change from gpt to mbr`,
+		`volume "other": incompatible structure #0 ("foo") change: cannot change structure type from "00000000-0000-0000-0000-0000deadbeef" to "0C"`,
+		`volume "pc": incompatible structure #0 ("seed") change: cannot change filesystem from "vfat" to "ext4"`,
+		`volume "pc": incompatible structure #1 ("data") change: cannot change filesystem from "ext4" to "vfat"`,
+	})
+	c.Check(err, ErrorMatches, `incompatible layout change:
+- volume "other": incompatible schema change from gpt to mbr
+- volume "other": .*
+- volume "pc": .*
+- volume "pc": .*`)
+}
+
+func (s *gadgetCompatibilityTestSuite) TestGadgetIsCompatibleGrowLastPartition(c *C) {
+	var baseYaml = `
+volumes:
+  pc:
+    schema: gpt
+    bootloader: grub
+    structure:
+      - name: seed
+        size: 2M
+        type: 00000000-0000-0000-0000-0000deadbeef
+        filesystem: vfat`
+	var mockYaml = baseYaml + `
+      - name: data
+        size: 10M
+        type: 00000000-0000-0000-0000-0000deadbeef
+        filesystem: ext4
+`
+	var mockGrowYaml = baseYaml + `
+      - name: data
+        size: 20M
+        type: 00000000-0000-0000-0000-0000deadbeef
+        filesystem: ext4
+`
+	var mockShrinkYaml = baseYaml + `
+      - name: data
+        size: 5M
+        type: 00000000-0000-0000-0000-0000deadbeef
+        filesystem: ext4
+`
+	var mockGrowNotExt4Yaml = baseYaml + `
+      - name: data
+        size: 20M
+        type: 00000000-0000-0000-0000-0000deadbeef
+`
+	var mockGrowNotLastYaml = `
+volumes:
+  pc:
+    schema: gpt
+    bootloader: grub
+    structure:
+      - name: seed
+        size: 4M
+        type: 00000000-0000-0000-0000-0000deadbeef
+        filesystem: vfat
+      - name: data
+        size: 10M
+        type: 00000000-0000-0000-0000-0000deadbeef
+        filesystem: ext4
+`
+
+	for i, tc := range []struct {
+		gadgetYaml string
+		err        string
+	}{
+		{mockGrowYaml, ``},
+		{mockShrinkYaml, `incompatible layout change: volume "pc": incompatible structure #1 \("data"\) change: new valid structure size range \[5242880, 5242880\] is not compatible with current \(\[10485760, 10485760\]\)`},
+		{mockGrowNotExt4Yaml, `incompatible layout change: volume "pc": incompatible structure #1 \("data"\) change: new valid structure size range \[20971520, 20971520\] is not compatible with current \(\[10485760, 10485760\]\)`},
+		{mockGrowNotLastYaml, `(?s)incompatible layout change:\n- volume "pc": incompatible structure #0 \("seed"\) change: new valid structure size range.*\n- volume "pc": incompatible structure #1 \("data"\) change: new valid structure offset range.*`},
+	} {
+		c.Logf("trying: %d %v\n", i, tc.gadgetYaml)
+		gi, err := gadget.InfoFromGadgetYaml([]byte(mockYaml), coreMod)
+		c.Assert(err, IsNil)
+		giNew, err := gadget.InfoFromGadgetYaml([]byte(tc.gadgetYaml), coreMod)
+		c.Assert(err, IsNil)
+		err = gadget.IsCompatible(gi, giNew)
+		if tc.err == "" {
+			c.Check(err, IsNil)
+		} else {
+			c.Check(err, ErrorMatches, tc.err)
+		}
+	}
+}
+
 func (s *gadgetCompatibilityTestSuite) TestGadgetIsCompatibleStructureNameMBR(c *C) {
 	var baseYaml = `
 volumes:
@@ -2862,6 +3043,78 @@ func (s *gadgetYamlTestSuite) TestKernelCommandLineBasic(c *C) {
 	}
 }
 
+func (s *gadgetYamlTestSuite) TestRecoveryKernelCommandLineFromGadget(c *C) {
+	for _, tc := range []struct {
+		files   [][]string
+		cmdline string
+		full    bool
+		err     string
+	}{{
+		// generic files are used when there is no recovery variant
+		files: [][]string{
+			{"cmdline.extra", "foo bar baz just-extra\n"},
+		},
+		cmdline: "foo bar baz just-extra", full: false,
+	}, {
+		files: [][]string{
+			{"cmdline.full", "foo bar baz full\n"},
+		},
+		cmdline: "foo bar baz full", full: true,
+	}, {
+		files: [][]string{
+			{"cmdline.extra", "foo bar baz just-extra"},
+			{"cmdline.extra.recover", "console=ttyS0"},
+		},
+		cmdline: "console=ttyS0", full: false,
+	}, {
+		files: [][]string{
+			{"cmdline.extra", "foo bar baz just-extra"},
+			{"cmdline.full.recover", "panic=-1 console=ttyS0"},
+		},
+		cmdline: "panic=-1 console=ttyS0", full: true,
+	}, {
+		// an empty recovery variant still takes precedence
+		files: [][]string{
+			{"cmdline.full", "foo bar baz full"},
+			{"cmdline.extra.recover", ""},
+		},
+		cmdline: "", full: false,
+	}, {
+		// the generic files are not checked when a recovery variant is
+		// present
+		files: [][]string{
+			{"cmdline.extra", "extra"},
+			{"cmdline.full", "full"},
+			{"cmdline.extra.recover", "recover"},
+		},
+		cmdline: "recover", full: false,
+	}, {
+		files: [][]string{
+			{"cmdline.extra", "foo"},
+			{"cmdline.extra.recover", "foo bad ="},
+		},
+		full: false, err: `invalid kernel command line in cmdline\.extra\.recover: unexpected assignment`,
+	}, {
+		files: [][]string{
+			{"cmdline.extra.recover", "extra"},
+			{"cmdline.full.recover", "full"},
+		},
+		err: "cannot support both extra and full kernel command lines",
+	}} {
+		c.Logf("files: %q", tc.files)
+		snapPath := snaptest.MakeTestSnapWithFiles(c, string(mockSnapYaml), tc.files)
+		cmdline, full, err := gadget.RecoveryKernelCommandLineFromGadget(snapPath)
+		if tc.err != "" {
+			c.Assert(err, ErrorMatches, tc.err)
+			c.Check(cmdline, Equals, "")
+		} else {
+			c.Assert(err, IsNil)
+			c.Check(cmdline, Equals, tc.cmdline)
+		}
+		c.Check(full, Equals, tc.full)
+	}
+}
+
 func (s *gadgetYamlTestSuite) testKernelCommandLineArgs(c *C, whichCmdline string) {
 	c.Logf("checking %v", whichCmdline)
 	// mock test snap creates a snap directory
@@ -4386,6 +4639,26 @@ kernel-cmdline:
 	}
 }
 
+func (s *gadgetYamlTestSuite) TestBootloaderOptions(c *C) {
+	gi, err := gadget.InfoFromGadgetYaml([]byte(`
+volumes:
+  pc:
+    bootloader: grub
+`), uc20Mod)
+	c.Assert(err, IsNil)
+	c.Check(gi.BootloaderOptions.RecoverySystemsMenu, Equals, false)
+
+	gi, err = gadget.InfoFromGadgetYaml([]byte(`
+volumes:
+  pc:
+    bootloader: grub
+bootloader-options:
+  recovery-systems-menu: true
+`), uc20Mod)
+	c.Assert(err, IsNil)
+	c.Check(gi.BootloaderOptions.RecoverySystemsMenu, Equals, true)
+}
+
 func (s *gadgetYamlTestSuite) testVolumeMinSize(c *C, gadgetYaml []byte, volSizes map[string]quantity.Size) {
 	ginfo, err := gadget.InfoFromGadgetYaml(gadgetYaml, nil)
 	c.Assert(err, IsNil)
@@ -5028,7 +5301,7 @@ func (s *gadgetCompatibilityTestSuite) TestPartialGadgetIsCompatible(c *C) {
 	gi1.Volumes["pc"].Schema = "gpt"
 	gi2 = newPartialGadgetYaml(c)
 	err = gadget.IsCompatible(gi1, gi2)
-	c.Check(err.Error(), Equals, "incompatible layout change: new schema is partial, while old was not")
+	c.Check(err.Error(), Equals, `incompatible layout change: volume "pc": new schema is partial, while old was not`)
 
 	// set filesystems in new
 	gi1 = newPartialGadgetYaml(c)
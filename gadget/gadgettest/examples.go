@@ -1204,6 +1204,22 @@ var VMSystemVolumeDiskMapping = &disks.MockDiskMapping{
 	},
 }
 
+// VMSystemVolumeGrownDiskMapping is the same as VMSystemVolumeDiskMapping,
+// but after the disk of the VM was grown from 5G to 10G, leaving free space
+// after the ubuntu-data partition.
+var VMSystemVolumeGrownDiskMapping = &disks.MockDiskMapping{
+	DevNode: "/dev/vda",
+	DevPath: "/sys/devices/pci0000:00/0000:00:03.0/virtio1/block/vda",
+	DevNum:  "600:1",
+	// assume 34 sectors at end for GPT headers backup
+	DiskUsableSectorEnd: 10240*oneMeg/512 - 34,
+	DiskSizeInBytes:     10240 * oneMeg,
+	SectorSizeBytes:     512,
+	DiskSchema:          "gpt",
+	ID:                  "f0eef013-a777-4a27-aaf0-dbb5cf68c2b6",
+	Structure:           VMSystemVolumeDiskMapping.Structure,
+}
+
 var VMSystemVolumeDiskMappingSeedFsLabelCaps = &disks.MockDiskMapping{
 	DevNode: "/dev/vda",
 	DevPath: "/sys/devices/pci0000:00/0000:00:03.0/virtio1/block/vda",
@@ -522,30 +522,61 @@ func ShiftStructureTo(ps LaidOutStructure, offset quantity.Offset) LaidOutStruct
 	return newPs
 }
 
-func isLayoutCompatible(current, new *Volume) error {
+// layoutIncompatibilities returns a description of each change between the
+// current and the new volume which is not compatible with the current layout.
+func layoutIncompatibilities(current, new *Volume) []string {
+	var changes []string
 	if current.ID != new.ID {
-		return fmt.Errorf("incompatible ID change from %v to %v", current.ID, new.ID)
+		changes = append(changes, fmt.Sprintf("incompatible ID change from %v to %v", current.ID, new.ID))
 	}
 	if err := checkCompatibleSchema(current, new); err != nil {
-		return err
+		changes = append(changes, err.Error())
 	}
 	if current.Bootloader != new.Bootloader {
-		return fmt.Errorf("incompatible bootloader change from %v to %v",
-			current.Bootloader, new.Bootloader)
+		changes = append(changes, fmt.Sprintf("incompatible bootloader change from %v to %v",
+			current.Bootloader, new.Bootloader))
 	}
 
 	// XXX: the code below asssumes both volumes have the same number of
 	// structures, this limitation may be lifted later
 	if len(current.Structure) != len(new.Structure) {
-		return fmt.Errorf("incompatible change in the number of structures from %v to %v",
-			len(current.Structure), len(new.Structure))
+		return append(changes, fmt.Sprintf("incompatible change in the number of structures from %v to %v",
+			len(current.Structure), len(new.Structure)))
 	}
 
-	// at the structure level we expect the volume to be identical
+	// at the structure level we expect the volume to be identical, except
+	// for growing the last partition which is done during the update
 	for i := range current.Structure {
-		if err := canUpdateStructure(current, i, new, i); err != nil {
-			return fmt.Errorf("incompatible structure #%d (%q) change: %v", new.Structure[i].YamlIndex, new.Structure[i].Name, err)
+		to := new
+		if canGrowStructure(current, new, i) {
+			to = withStructureSizeOf(new, current, i)
+		}
+		if err := canUpdateStructure(current, i, to, i); err != nil {
+			changes = append(changes, fmt.Sprintf("incompatible structure #%d (%q) change: %v",
+				new.Structure[i].YamlIndex, new.Structure[i].Name, err))
 		}
 	}
-	return nil
+	return changes
+}
+
+// canGrowStructure returns true if the structure at the given index is grown
+// by the new volume in a way that is supported by a gadget update, that is it
+// is the last partition of the volume and carries an ext4 filesystem.
+func canGrowStructure(current, new *Volume, idx int) bool {
+	from := &current.Structure[idx]
+	to := &new.Structure[idx]
+	if idx != len(new.Structure)-1 || !to.IsPartition() || to.Filesystem != "ext4" {
+		return false
+	}
+	return to.MinSize > effectivePartSize(from)
+}
+
+// withStructureSizeOf returns a copy of the volume where the size of the
+// structure at the given index is taken from the other volume.
+func withStructureSizeOf(vol, other *Volume, idx int) *Volume {
+	v := *vol
+	v.Structure = append([]VolumeStructure(nil), vol.Structure...)
+	v.Structure[idx].MinSize = other.Structure[idx].MinSize
+	v.Structure[idx].Size = other.Structure[idx].Size
+	return &v
 }
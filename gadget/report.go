@@ -0,0 +1,185 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gadget
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/osutil/disks"
+)
+
+// DiskVolumeReport describes the disk a gadget volume was found on and how
+// the partitions of that disk map to the structures of the volume.
+type DiskVolumeReport struct {
+	// Name is the name of the volume in the gadget.
+	Name string `json:"name"`
+	// Device is the device node of the disk, it is empty when no disk
+	// could be found for the volume.
+	Device string `json:"device,omitempty"`
+	// Schema is the partitioning schema of the disk, gpt or dos.
+	Schema string `json:"schema,omitempty"`
+	// Size is the size of the disk.
+	Size quantity.Size `json:"size,omitempty"`
+	// Structure lists all the partitions found on the disk, in the order
+	// they appear on the disk.
+	Structure []DiskStructureReport `json:"structure,omitempty"`
+}
+
+// DiskStructureReport describes a partition on disk and the gadget structure
+// it corresponds to.
+type DiskStructureReport struct {
+	// Node is the device node of the partition, i.e. /dev/vda1.
+	Node string `json:"node"`
+	// PartitionUUID is the partuuid of the partition.
+	PartitionUUID string `json:"partition-uuid,omitempty"`
+	// PartitionLabel is the label of the partition, it is empty on dos
+	// disks.
+	PartitionLabel string `json:"partition-label,omitempty"`
+	// Filesystem is the type of the filesystem found on the partition.
+	Filesystem string `json:"filesystem,omitempty"`
+	// Offset is the start offset of the partition on the disk.
+	Offset quantity.Offset `json:"offset"`
+	// Size is the size of the partition.
+	Size quantity.Size `json:"size"`
+	// Encrypted is set when the partition holds an encrypted device.
+	Encrypted bool `json:"encrypted,omitempty"`
+	// StructureName is the name of the gadget structure matching the
+	// partition.
+	StructureName string `json:"structure-name,omitempty"`
+	// Role is the role of the gadget structure matching the partition.
+	Role string `json:"role,omitempty"`
+	// Unmapped is set for partitions that do not correspond to any
+	// structure of the gadget volume, i.e. partitions created outside of
+	// snapd.
+	Unmapped bool `json:"unmapped,omitempty"`
+}
+
+// DiskVolumesReport finds the disks holding the given gadget volumes and
+// reports how their partitions map to the gadget structures. Unlike
+// AllDiskVolumeDeviceTraits, partitions that are not described by the gadget
+// do not cause an error but are reported as unmapped. The reports are sorted
+// by volume name.
+func DiskVolumesReport(allVols map[string]*Volume, optsPerVolume map[string]*DiskVolumeValidationOptions) ([]DiskVolumeReport, error) {
+	reports := make([]DiskVolumeReport, 0, len(allVols))
+	for _, name := range sortedVolumeNames(allVols) {
+		opts := optsPerVolume[name]
+		if opts == nil {
+			opts = &DiskVolumeValidationOptions{}
+		}
+		report, err := diskVolumeReport(name, allVols[name], opts)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func diskVolumeReport(name string, vol *Volume, opts *DiskVolumeValidationOptions) (DiskVolumeReport, error) {
+	report := DiskVolumeReport{Name: name}
+
+	dev, err := findDiskDeviceForVolume(vol)
+	if err != nil {
+		return report, err
+	}
+	if dev == "" {
+		// the disk may be missing or replaced, which is exactly what the
+		// report is meant to help with, so do not fail
+		return report, nil
+	}
+
+	disk, err := disks.DiskFromDeviceName(dev)
+	if err != nil {
+		return report, fmt.Errorf("cannot get disk for device %s: %v", dev, err)
+	}
+	diskLayout, err := OnDiskVolumeFromDisk(disk)
+	if err != nil {
+		return report, fmt.Errorf("cannot read %v partitions for volume %s: %v", dev, name, err)
+	}
+	diskPartitions, err := disk.Partitions()
+	if err != nil {
+		return report, fmt.Errorf("cannot get partitions for disk device %s: %v", dev, err)
+	}
+
+	// partitions not described in the gadget are expected to be found
+	// here, treat the volume as one with a partial structure so that they
+	// are tolerated
+	partialVol := *vol
+	partialVol.Partial = append([]PartialProperty{PartialStructure}, vol.Partial...)
+	volCompatOpts := &VolumeCompatibilityOptions{
+		AssumeCreatablePartitionsCreated: true,
+		AllowImplicitSystemData:          opts.AllowImplicitSystemData,
+		ExpectedStructureEncryption:      opts.ExpectedStructureEncryption,
+		AllowPartitionGrowth:             opts.AllowPartitionGrowth,
+	}
+	gadgetToDiskStruct, err := EnsureVolumeCompatibility(&partialVol, diskLayout, volCompatOpts)
+	if err != nil {
+		return report, fmt.Errorf("volume %s is not compatible with disk %s: %v", name, dev, err)
+	}
+
+	structuresByOffset := make(map[quantity.Offset]*VolumeStructure, len(gadgetToDiskStruct))
+	for i := range vol.Structure {
+		vs := &vol.Structure[i]
+		if !vs.IsPartition() {
+			continue
+		}
+		if ds, ok := gadgetToDiskStruct[vs.YamlIndex]; ok {
+			structuresByOffset[ds.StartOffset] = vs
+		}
+	}
+
+	sort.Slice(diskPartitions, func(i, j int) bool {
+		return diskPartitions[i].DiskIndex < diskPartitions[j].DiskIndex
+	})
+
+	report.Device = diskLayout.Device
+	report.Schema = diskLayout.Schema
+	report.Size = diskLayout.Size
+	for _, part := range diskPartitions {
+		ds, err := OnDiskStructureFromPartition(part)
+		if err != nil {
+			return report, err
+		}
+		sr := DiskStructureReport{
+			Node:           part.KernelDeviceNode,
+			PartitionUUID:  part.PartitionUUID,
+			PartitionLabel: ds.Name,
+			Filesystem:     part.FilesystemType,
+			Offset:         ds.StartOffset,
+			Size:           ds.Size,
+			Encrypted:      part.FilesystemType == "crypto_LUKS",
+		}
+		switch vs, ok := structuresByOffset[ds.StartOffset]; {
+		case ok:
+			sr.StructureName = vs.Name
+			sr.Role = vs.Role
+		case opts.AllowImplicitSystemData && onDiskStructureIsLikelyImplicitSystemDataRole(vol, diskLayout, ds):
+			// created by ubuntu-image without being in the gadget
+			sr.Role = SystemData
+		default:
+			sr.Unmapped = true
+		}
+		report.Structure = append(report.Structure, sr)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,154 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gadget_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/gadget/gadgettest"
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/osutil/disks"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type reportTestSuite struct {
+	testutil.BaseTest
+
+	vols map[string]*gadget.Volume
+}
+
+var _ = Suite(&reportTestSuite{})
+
+func (s *reportTestSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	vol, err := gadgettest.LayoutFromYaml(c.MkDir(), gadgettest.MockExtraVolumeYAML, nil)
+	c.Assert(err, IsNil)
+	s.vols = map[string]*gadget.Volume{
+		"foo": vol.Volume,
+	}
+}
+
+func (s *reportTestSuite) mockDisk(c *C, mapping *disks.MockDiskMapping) {
+	c.Assert(os.MkdirAll(filepath.Join(dirs.GlobalRootDir, "/dev/disk/by-partlabel"), 0755), IsNil)
+	fakedevicepart := filepath.Join(dirs.GlobalRootDir, "/dev/foo1")
+	c.Assert(os.WriteFile(fakedevicepart, nil, 0644), IsNil)
+	c.Assert(os.Symlink(fakedevicepart, filepath.Join(dirs.GlobalRootDir, "/dev/disk/by-partlabel/nofspart")), IsNil)
+
+	s.AddCleanup(disks.MockDeviceNameToDiskMapping(map[string]*disks.MockDiskMapping{
+		"/dev/foo": mapping,
+	}))
+	s.AddCleanup(disks.MockPartitionDeviceNodeToDiskMapping(map[string]*disks.MockDiskMapping{
+		fakedevicepart: mapping,
+	}))
+}
+
+var expectedMockExtraVolumeReport = gadget.DiskVolumeReport{
+	Name:   "foo",
+	Device: "/dev/foo",
+	Schema: "gpt",
+	Size:   6000 * quantity.SizeMiB,
+	Structure: []gadget.DiskStructureReport{
+		{
+			Node:           "/dev/foo1",
+			PartitionUUID:  "C5A930DF-E86A-4BAE-A4C5-C861353796E6",
+			PartitionLabel: "nofspart",
+			Offset:         quantity.OffsetMiB + quantity.OffsetKiB,
+			Size:           4096,
+			StructureName:  "nofspart",
+		},
+		{
+			Node:           "/dev/foo2",
+			PartitionUUID:  "DA2ADBC8-90DF-4B1D-A93F-A92516C12E01",
+			PartitionLabel: "some-filesystem",
+			Filesystem:     "ext4",
+			Offset:         quantity.OffsetMiB + quantity.OffsetKiB + 4096,
+			Size:           quantity.SizeGiB,
+			StructureName:  "some-filesystem",
+		},
+	},
+}
+
+func (s *reportTestSuite) TestDiskVolumesReportHappy(c *C) {
+	s.mockDisk(c, gadgettest.MockExtraVolumeDiskMapping)
+
+	reports, err := gadget.DiskVolumesReport(s.vols, nil)
+	c.Assert(err, IsNil)
+	c.Check(reports, DeepEquals, []gadget.DiskVolumeReport{expectedMockExtraVolumeReport})
+}
+
+func (s *reportTestSuite) TestDiskVolumesReportUnmappedPartition(c *C) {
+	mapping := *gadgettest.MockExtraVolumeDiskMapping
+	mapping.Structure = append([]disks.Partition{}, mapping.Structure...)
+	mapping.Structure = append(mapping.Structure, disks.Partition{
+		PartitionLabel:   "extra",
+		PartitionUUID:    "F5B8AB5B-3B1C-4E1F-9B0E-1C5C1E5D2B7A",
+		PartitionType:    "0FC63DAF-8483-4772-8E79-3D69D8477DE4",
+		FilesystemLabel:  "extra",
+		FilesystemType:   "crypto_LUKS",
+		KernelDeviceNode: "/dev/foo3",
+		KernelDevicePath: "/sys/block/foo/foo3",
+		DiskIndex:        3,
+		StartInBytes:     uint64(quantity.OffsetMiB+quantity.OffsetKiB+4096) + uint64(quantity.SizeGiB),
+		SizeInBytes:      uint64(quantity.SizeGiB),
+	})
+	s.mockDisk(c, &mapping)
+
+	reports, err := gadget.DiskVolumesReport(s.vols, nil)
+	c.Assert(err, IsNil)
+	expected := expectedMockExtraVolumeReport
+	expected.Structure = append([]gadget.DiskStructureReport{}, expected.Structure...)
+	expected.Structure = append(expected.Structure, gadget.DiskStructureReport{
+		Node:           "/dev/foo3",
+		PartitionUUID:  "F5B8AB5B-3B1C-4E1F-9B0E-1C5C1E5D2B7A",
+		PartitionLabel: "extra",
+		Filesystem:     "crypto_LUKS",
+		Offset:         quantity.OffsetMiB + quantity.OffsetKiB + 4096 + quantity.Offset(quantity.SizeGiB),
+		Size:           quantity.SizeGiB,
+		Encrypted:      true,
+		Unmapped:       true,
+	})
+	c.Check(reports, DeepEquals, []gadget.DiskVolumeReport{expected})
+}
+
+func (s *reportTestSuite) TestDiskVolumesReportNoDisk(c *C) {
+	// no device symlinks, the disk of the volume cannot be found
+	reports, err := gadget.DiskVolumesReport(s.vols, nil)
+	c.Assert(err, IsNil)
+	c.Check(reports, DeepEquals, []gadget.DiskVolumeReport{{Name: "foo"}})
+}
+
+func (s *reportTestSuite) TestDiskVolumesReportIncompatible(c *C) {
+	mapping := *gadgettest.MockExtraVolumeDiskMapping
+	mapping.Structure = append([]disks.Partition{}, mapping.Structure...)
+	mapping.Structure[1].FilesystemType = "vfat"
+	s.mockDisk(c, &mapping)
+
+	_, err := gadget.DiskVolumesReport(s.vols, nil)
+	c.Assert(err, ErrorMatches, `volume foo is not compatible with disk /dev/foo: cannot find gadget structure "some-filesystem" on disk`)
+}
@@ -0,0 +1,260 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gadget
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/osutil/disks"
+)
+
+// structureResize describes a partition that is grown as part of a gadget
+// update.
+type structureResize struct {
+	volume    *Volume
+	structure *VolumeStructure
+	// device is the device node of the disk
+	device string
+	// node is the device node of the partition
+	node string
+	// cryptName is the name of the mapping of the unlocked encrypted device
+	// when the partition is encrypted
+	cryptName string
+	// fsNode is the device node holding the filesystem, which is either
+	// the partition or the mapping of the encrypted device
+	fsNode string
+	// diskIndex is the 1-based index of the partition on the disk
+	diskIndex int
+	// sizeInSectors is the current size of the partition
+	sizeInSectors uint64
+	// newSizeInSectors is the size the partition is grown to
+	newSizeInSectors uint64
+	sectorSize       quantity.Size
+}
+
+func onDiskVolumeForPartition(node string) (*OnDiskVolume, error) {
+	disk, err := disks.DiskFromPartitionDeviceNode(node)
+	if err != nil {
+		return nil, err
+	}
+	return OnDiskVolumeFromDisk(disk)
+}
+
+// resolveResizes finds the partitions of the new volume that need to be grown,
+// either because the partition on disk is smaller than the new gadget requires
+// or because the new gadget asks for the structure to be grown to the end of
+// the disk. Only the last partition on the disk can be grown and partitions are
+// never shrunk.
+func resolveResizes(oldVol, newVol *Volume, gadgetToDiskStruct map[int]*OnDiskStructure) ([]structureResize, error) {
+	var resizes []structureResize
+	for idx := range newVol.Structure {
+		to := &newVol.Structure[idx]
+		if !to.IsPartition() {
+			continue
+		}
+		ds := gadgetToDiskStruct[to.YamlIndex]
+		if ds == nil {
+			continue
+		}
+
+		if from := oldVol.StructFromYamlIndex(to.YamlIndex); from != nil && effectivePartSize(to) < from.MinSize {
+			return nil, fmt.Errorf("cannot shrink structure %v from %s to %s", fmtIndexAndName(to.YamlIndex, to.Name),
+				from.MinSize.IECString(), effectivePartSize(to).IECString())
+		}
+
+		if ds.Size >= to.MinSize && !to.Update.GrowToEnd {
+			continue
+		}
+
+		if idx != len(newVol.Structure)-1 {
+			return nil, fmt.Errorf("cannot resize structure %v: only the last partition of the volume can be resized",
+				fmtIndexAndName(to.YamlIndex, to.Name))
+		}
+
+		dl, err := onDiskVolumeForPartition(ds.Node)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resize structure %v: %v", fmtIndexAndName(to.YamlIndex, to.Name), err)
+		}
+		for _, s := range dl.Structure {
+			if s.StartOffset > ds.StartOffset {
+				return nil, fmt.Errorf("cannot resize structure %v: partition %s is not the last partition on disk %s",
+					fmtIndexAndName(to.YamlIndex, to.Name), ds.Node, dl.Device)
+			}
+		}
+
+		sectorSize := uint64(dl.SectorSize)
+		startInSectors := uint64(ds.StartOffset) / sectorSize
+		sizeInSectors := uint64(ds.Size) / sectorSize
+		availableInSectors := dl.UsableSectorsEnd - startInSectors
+
+		newSizeInSectors := availableInSectors
+		if !to.Update.GrowToEnd {
+			newSizeInSectors = uint64(effectivePartSize(to)) / sectorSize
+			if newSizeInSectors > availableInSectors {
+				return nil, fmt.Errorf("cannot resize structure %v: not enough space on disk %s, need %s but only %s is available",
+					fmtIndexAndName(to.YamlIndex, to.Name), dl.Device,
+					effectivePartSize(to).IECString(), quantity.Size(availableInSectors*sectorSize).IECString())
+			}
+		}
+		if newSizeInSectors <= sizeInSectors {
+			// already as large as it can get
+			continue
+		}
+
+		fsType := ds.PartitionFSType
+		fsNode := ds.Node
+		var cryptName string
+		if fsType == "crypto_LUKS" {
+			// the encrypted device is grown together with the
+			// partition, which is only possible while it is unlocked,
+			// the filesystem inside is the one declared by the gadget
+			cryptName, err = unlockedEncryptedDeviceName(ds.Node)
+			if err != nil {
+				return nil, fmt.Errorf("cannot resize structure %v: %v", fmtIndexAndName(to.YamlIndex, to.Name), err)
+			}
+			fsType = to.LinuxFilesystem()
+			fsNode = filepath.Join("/dev/mapper", cryptName)
+		}
+		if fsType != "ext4" {
+			return nil, fmt.Errorf("cannot resize structure %v: unsupported filesystem %q",
+				fmtIndexAndName(to.YamlIndex, to.Name), fsType)
+		}
+
+		resizes = append(resizes, structureResize{
+			volume:           newVol,
+			structure:        to,
+			device:           dl.Device,
+			node:             ds.Node,
+			cryptName:        cryptName,
+			fsNode:           fsNode,
+			diskIndex:        ds.DiskIndex,
+			sizeInSectors:    sizeInSectors,
+			newSizeInSectors: newSizeInSectors,
+			sectorSize:       dl.SectorSize,
+		})
+	}
+	return resizes, nil
+}
+
+// unlockedEncryptedDeviceName returns the name of the device mapper device
+// through which the encrypted partition with the given device node is
+// unlocked.
+func unlockedEncryptedDeviceName(node string) (string, error) {
+	holdersDir := filepath.Join(dirs.SysfsDir, "class/block", filepath.Base(node), "holders")
+	holders, err := os.ReadDir(holdersDir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("cannot list holders of encrypted partition %s: %v", node, err)
+	}
+	if len(holders) != 1 {
+		return "", fmt.Errorf("cannot find the unlocked device of encrypted partition %s", node)
+	}
+	name, err := os.ReadFile(filepath.Join(dirs.SysfsDir, "class/block", holders[0].Name(), "dm/name"))
+	if err != nil {
+		return "", fmt.Errorf("cannot find the unlocked device of encrypted partition %s: %v", node, err)
+	}
+	return strings.TrimSpace(string(name)), nil
+}
+
+// setPartitionSize changes the size of the partition in the partition table
+// and makes the kernel aware of the new size. The start of the partition is
+// kept.
+func setPartitionSize(r *structureResize, sizeInSectors uint64) error {
+	idx := strconv.Itoa(r.diskIndex)
+
+	cmd := exec.Command("sfdisk", "--no-reread", "--no-tell-kernel", "-N", idx, r.device)
+	cmd.Stdin = strings.NewReader(fmt.Sprintf(",%d\n", sizeInSectors))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot change size of partition %s: %v", r.node, osutil.OutputErr(output, err))
+	}
+
+	if output, err := exec.Command("partx", "-u", "--nr", idx, r.device).CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot update partition %s: %v", r.node, osutil.OutputErr(output, err))
+	}
+	return nil
+}
+
+// growFilesystem grows the encrypted device, if any, and the ext4 filesystem
+// to the size of the grown partition, which is done online as the partition
+// may be mounted.
+func growFilesystem(r *structureResize) error {
+	if r.cryptName != "" {
+		if output, err := exec.Command("cryptsetup", "resize", r.cryptName).CombinedOutput(); err != nil {
+			return fmt.Errorf("cannot grow encrypted device %s: %v", r.cryptName, osutil.OutputErr(output, err))
+		}
+	}
+	if output, err := exec.Command("resize2fs", r.fsNode).CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot grow filesystem on %s: %v", r.fsNode, osutil.OutputErr(output, err))
+	}
+	return nil
+}
+
+func fmtResize(r *structureResize) string {
+	return fmt.Sprintf("%v on volume %s", fmtIndexAndName(r.structure.YamlIndex, r.structure.Name), r.volume.Name)
+}
+
+// growPartitions grows the partitions in the partition tables. The
+// filesystems are left as they are, so this can be undone with
+// restorePartitions. Partitions grown before a failure are restored.
+func growPartitions(resizes []structureResize) error {
+	for i := range resizes {
+		r := &resizes[i]
+		logger.Noticef("growing structure %v from %s to %s", fmtResize(r),
+			quantity.Size(r.sizeInSectors*uint64(r.sectorSize)).IECString(),
+			quantity.Size(r.newSizeInSectors*uint64(r.sectorSize)).IECString())
+		if err := setPartitionSize(r, r.newSizeInSectors); err != nil {
+			restorePartitions(resizes[:i+1])
+			return fmt.Errorf("cannot resize structure %v: %v", fmtResize(r), err)
+		}
+	}
+	return nil
+}
+
+// restorePartitions restores the original size of the partitions in the
+// partition tables.
+func restorePartitions(resizes []structureResize) {
+	for i := range resizes {
+		r := &resizes[i]
+		logger.Noticef("restoring size of structure %v", fmtResize(r))
+		if err := setPartitionSize(r, r.sizeInSectors); err != nil {
+			logger.Noticef("cannot restore size of structure %v: %v", fmtResize(r), err)
+		}
+	}
+}
+
+// growFilesystems grows the encrypted devices and filesystems of the grown
+// partitions, which cannot be undone.
+func growFilesystems(resizes []structureResize) error {
+	for i := range resizes {
+		r := &resizes[i]
+		if err := growFilesystem(r); err != nil {
+			return fmt.Errorf("cannot resize structure %v: %v", fmtResize(r), err)
+		}
+	}
+	return nil
+}
@@ -157,6 +157,7 @@ func searchVolumeWithTraitsAndMatchParts(vol *Volume, traits DiskVolumeDeviceTra
 			AssumeCreatablePartitionsCreated: true,
 			AllowImplicitSystemData:          validateOpts.AllowImplicitSystemData,
 			ExpectedStructureEncryption:      validateOpts.ExpectedStructureEncryption,
+			AllowPartitionGrowth:             validateOpts.AllowPartitionGrowth,
 		}
 		gadgetStructToDiskStruct, ensureErr := EnsureVolumeCompatibility(vol, diskLayout, opts)
 		if ensureErr != nil {
@@ -318,6 +319,12 @@ type VolumeCompatibilityOptions struct {
 	// about the encrypted partitions that can be used to validate whether a
 	// given structure should be accepted as an encrypted partition.
 	ExpectedStructureEncryption map[string]StructureEncryptionParameters
+
+	// AllowPartitionGrowth allows partitions on disk to be smaller than the
+	// minimum size declared in the gadget volume, which is the case when a
+	// gadget update asks for a partition to be grown. Whether the partition
+	// can actually be grown is checked separately.
+	AllowPartitionGrowth bool
 }
 
 // EnsureVolumeCompatibility checks compatibility between a gadget volume and a
@@ -350,8 +357,8 @@ func EnsureVolumeCompatibility(gadgetVolume *Volume, diskVolume *OnDiskVolume, o
 
 		maxSz := effectivePartSize(gs)
 		switch {
-		// on disk size too small
-		case ds.Size < gs.MinSize:
+		// on disk size too small, unless it is to be grown
+		case ds.Size < gs.MinSize && !(opts.AllowPartitionGrowth && gs.IsPartition()):
 			return false, fmt.Sprintf("on disk size %d (%s) is smaller than gadget min size %d (%s)",
 				ds.Size, ds.Size.IECString(), gs.MinSize, gs.MinSize.IECString())
 
@@ -644,6 +651,9 @@ type DiskVolumeValidationOptions struct {
 	// names) of partitions that are encrypted on the volume and information
 	// about that encryption.
 	ExpectedStructureEncryption map[string]StructureEncryptionParameters
+	// AllowPartitionGrowth has the same meaning as the eponymously named
+	// field in VolumeCompatibilityOptions.
+	AllowPartitionGrowth bool
 }
 
 // DiskTraitsFromDeviceAndValidate takes a gadget volume and an
@@ -671,6 +681,7 @@ func DiskTraitsFromDeviceAndValidate(vol *Volume, dev string, opts *DiskVolumeVa
 		// provide the other opts as we were provided
 		AllowImplicitSystemData:     opts.AllowImplicitSystemData,
 		ExpectedStructureEncryption: opts.ExpectedStructureEncryption,
+		AllowPartitionGrowth:        opts.AllowPartitionGrowth,
 	}
 	gadgetToDiskStruct, err := EnsureVolumeCompatibility(vol, diskLayout, volCompatOpts)
 	if err != nil {
@@ -914,6 +925,8 @@ func buildNewVolumeToDeviceMapping(mod Model, old GadgetData, vols map[string]*V
 	validateOpts := &DiskVolumeValidationOptions{
 		// allow implicit system-data on pre-uc20 only
 		AllowImplicitSystemData: isPreUC20,
+		// the new volume may ask for partitions to be grown
+		AllowPartitionGrowth: true,
 	}
 
 	// setup encrypted structure information to perform validation if this
@@ -1018,6 +1031,9 @@ func buildVolumeStructureToLocation(mod Model,
 			// implicit system-data role only allowed on pre UC20 systems
 			AllowImplicitSystemData:     isPreUC20,
 			ExpectedStructureEncryption: diskDeviceTraits.StructureEncryption,
+			// partitions that are to be grown are still smaller on
+			// disk, resolveResizes checks whether growing is possible
+			AllowPartitionGrowth: true,
 		}
 
 		disk, gadgetToDiskStruct, err := searchVolumeWithTraitsAndMatchParts(newVol, diskDeviceTraits, validateOpts)
@@ -1315,6 +1331,7 @@ func Update(model Model, old, new GadgetData, rollbackDirPath string, updatePoli
 	}
 
 	allUpdates := []updatePair{}
+	allResizes := []structureResize{}
 	laidOutVols := map[string]*LaidOutVolume{}
 	for volName, oldVol := range old.Info.Volumes {
 		newVol := new.Info.Volumes[volName]
@@ -1369,6 +1386,16 @@ func Update(model Model, old, new GadgetData, rollbackDirPath string, updatePoli
 			}
 		}
 
+		// find the partitions that need to be grown, note that only volumes
+		// that were matched to a disk can have their partitions resized
+		if gadgetToDiskStruct, ok := volToPartsMap[volName]; ok {
+			resizes, err := resolveResizes(oldVol, newVol, gadgetToDiskStruct)
+			if err != nil {
+				return fmt.Errorf("cannot apply update to volume %s: %v", volName, err)
+			}
+			allResizes = append(allResizes, resizes...)
+		}
+
 		// collect updates per volume into a single set of updates to perform
 		// at once
 		allUpdates = append(allUpdates, updates...)
@@ -1381,7 +1408,7 @@ func Update(model Model, old, new GadgetData, rollbackDirPath string, updatePoli
 		return fmt.Errorf("gadget does not consume any of the kernel assets needing synced update %s", strutil.Quoted(allKernelAssets))
 	}
 
-	if len(allUpdates) == 0 {
+	if len(allUpdates) == 0 && len(allResizes) == 0 {
 		// nothing to update
 		return ErrNoUpdate
 	}
@@ -1412,33 +1439,29 @@ func Update(model Model, old, new GadgetData, rollbackDirPath string, updatePoli
 		}
 	}
 
-	// apply all updates at once
-	if err := applyUpdates(structureLocations, new, allUpdates, rollbackDirPath, observer); err != nil {
+	// grow the partitions first so that the updated content can use the
+	// new space, their original size is restored if updating the content
+	// fails
+	if err := growPartitions(allResizes); err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func resolveVolume(old *Info, new *Info) (oldVol, newVol *Volume, err error) {
-	// support only one volume
-	if len(new.Volumes) != 1 || len(old.Volumes) != 1 {
-		return nil, nil, errors.New("cannot update with more than one volume")
+	// apply all updates at once
+	updateErr := ErrNoUpdate
+	if len(allUpdates) != 0 {
+		updateErr = applyUpdates(structureLocations, new, allUpdates, rollbackDirPath, observer)
 	}
-
-	var name string
-	for n := range old.Volumes {
-		name = n
-		break
+	if updateErr != nil && updateErr != ErrNoUpdate {
+		restorePartitions(allResizes)
+		return updateErr
 	}
-	oldV := old.Volumes[name]
-
-	newV, ok := new.Volumes[name]
-	if !ok {
-		return nil, nil, fmt.Errorf("cannot find entry for volume %q in updated gadget info", name)
+	if len(allResizes) == 0 {
+		return updateErr
 	}
 
-	return oldV, newV, nil
+	// growing the encrypted devices and filesystems cannot be undone, so it
+	// is done only once the content was updated successfully
+	return growFilesystems(allResizes)
 }
 
 func isLegacyMBRTransition(from *VolumeStructure, to *VolumeStructure) bool {
@@ -67,58 +67,6 @@ func (s *updateTestSuite) SetUpTest(c *C) {
 	})
 }
 
-func (u *updateTestSuite) TestResolveVolumeDifferentName(c *C) {
-	oldInfo := &gadget.Info{
-		Volumes: map[string]*gadget.Volume{
-			"old": {},
-		},
-	}
-	noMatchInfo := &gadget.Info{
-		Volumes: map[string]*gadget.Volume{
-			"not-old": {},
-		},
-	}
-	oldVol, newVol, err := gadget.ResolveVolume(oldInfo, noMatchInfo)
-	c.Assert(err, ErrorMatches, `cannot find entry for volume "old" in updated gadget info`)
-	c.Assert(oldVol, IsNil)
-	c.Assert(newVol, IsNil)
-}
-
-func (u *updateTestSuite) TestResolveVolumeTooMany(c *C) {
-	oldInfo := &gadget.Info{
-		Volumes: map[string]*gadget.Volume{
-			"old":         {},
-			"another-one": {},
-		},
-	}
-	noMatchInfo := &gadget.Info{
-		Volumes: map[string]*gadget.Volume{
-			"old": {},
-		},
-	}
-	oldVol, newVol, err := gadget.ResolveVolume(oldInfo, noMatchInfo)
-	c.Assert(err, ErrorMatches, `cannot update with more than one volume`)
-	c.Assert(oldVol, IsNil)
-	c.Assert(newVol, IsNil)
-}
-
-func (u *updateTestSuite) TestResolveVolumeSimple(c *C) {
-	oldInfo := &gadget.Info{
-		Volumes: map[string]*gadget.Volume{
-			"old": {Bootloader: "u-boot"},
-		},
-	}
-	noMatchInfo := &gadget.Info{
-		Volumes: map[string]*gadget.Volume{
-			"old": {Bootloader: "grub"},
-		},
-	}
-	oldVol, newVol, err := gadget.ResolveVolume(oldInfo, noMatchInfo)
-	c.Assert(err, IsNil)
-	c.Assert(oldVol, DeepEquals, &gadget.Volume{Bootloader: "u-boot"})
-	c.Assert(newVol, DeepEquals, &gadget.Volume{Bootloader: "grub"})
-}
-
 type canUpdateTestCase struct {
 	from   gadget.VolumeStructure
 	to     gadget.VolumeStructure
@@ -5954,3 +5902,320 @@ func (s *updateTestSuite) TestVolumeStructureToLocationMapMissingInitialTraitsMa
 
 	c.Assert(mockLogBuf.String(), testutil.Contains, "WARNING: gadget has multiple volumes but updates are only being performed for volume pc")
 }
+
+func (u *updateTestSuite) setupGrowSystemDataTest(c *C, diskMapping *disks.MockDiskMapping) (oldData, newData gadget.GadgetData) {
+	u.restoreVolumeStructureToLocationMap()
+	oldData = gadget.GadgetData{
+		Info: &gadget.Info{
+			Volumes: map[string]*gadget.Volume{},
+		},
+		RootDir: c.MkDir(),
+	}
+	newData = gadget.GadgetData{
+		Info: &gadget.Info{
+			Volumes: map[string]*gadget.Volume{},
+		},
+		RootDir: c.MkDir(),
+	}
+
+	allLaidOutVolumes, err := gadgettest.LayoutMultiVolumeFromYaml(c.MkDir(), "", gadgettest.SingleVolumeUC20GadgetYaml, uc20Model)
+	c.Assert(err, IsNil)
+	for volName, laidOutVol := range allLaidOutVolumes {
+		oldData.Info.Volumes[volName] = laidOutVol.Volume.Copy()
+		newData.Info.Volumes[volName] = laidOutVol.Volume.Copy()
+	}
+
+	// setup symlink for the BIOS Boot partition
+	err = os.MkdirAll(filepath.Join(dirs.GlobalRootDir, "/dev/disk/by-partlabel"), 0755)
+	c.Assert(err, IsNil)
+	fakedevicepart := filepath.Join(dirs.GlobalRootDir, "/dev/vda1")
+	err = os.Symlink(fakedevicepart, filepath.Join(dirs.GlobalRootDir, "/dev/disk/by-partlabel", disks.BlkIDEncodeLabel("BIOS Boot")))
+	c.Assert(err, IsNil)
+	err = os.WriteFile(fakedevicepart, nil, 0644)
+	c.Assert(err, IsNil)
+
+	// mock the partition device nodes to the mock disk
+	restore := disks.MockPartitionDeviceNodeToDiskMapping(map[string]*disks.MockDiskMapping{
+		filepath.Join(dirs.GlobalRootDir, "/dev/vda1"): diskMapping,
+		"/dev/vda5": diskMapping,
+	})
+	u.AddCleanup(restore)
+
+	// and the device name to the disk itself
+	restore = disks.MockDeviceNameToDiskMapping(map[string]*disks.MockDiskMapping{
+		"/dev/vda": diskMapping,
+	})
+	u.AddCleanup(restore)
+
+	restore = osutil.MockMountInfo(
+		fmt.Sprintf(
+			`
+27 27 600:3 / %[1]s/run/mnt/ubuntu-seed rw,relatime shared:7 - vfat %[1]s/dev/vda2 rw
+28 27 600:4 / %[1]s/run/mnt/ubuntu-boot rw,relatime shared:7 - vfat %[1]s/dev/vda3 rw
+29 27 600:5 / %[1]s/run/mnt/ubuntu-save rw,relatime shared:7 - vfat %[1]s/dev/vda4 rw
+30 27 600:6 / %[1]s/run/mnt/data rw,relatime shared:7 - vfat %[1]s/dev/vda5 rw`[1:],
+			dirs.GlobalRootDir,
+		),
+	)
+	u.AddCleanup(restore)
+
+	// no content is updated
+	restore = gadget.MockUpdaterForStructure(func(loc gadget.StructureLocation, ps *gadget.LaidOutStructure, psRootDir, psRollbackDir string, observer gadget.ContentUpdateObserver) (gadget.Updater, error) {
+		c.Fatalf("unexpected call")
+		return nil, errors.New("not called")
+	})
+	u.AddCleanup(restore)
+
+	return oldData, newData
+}
+
+type growCommands struct {
+	sfdisk    *testutil.MockCmd
+	partx     *testutil.MockCmd
+	resize2fs *testutil.MockCmd
+
+	sfdiskInput string
+}
+
+func mockGrowCommands(c *C) *growCommands {
+	cmds := &growCommands{
+		sfdiskInput: filepath.Join(c.MkDir(), "sfdisk-input"),
+	}
+	cmds.sfdisk = testutil.MockCommand(c, "sfdisk", fmt.Sprintf("cat > %s", cmds.sfdiskInput))
+	cmds.partx = testutil.MockCommand(c, "partx", "")
+	cmds.resize2fs = testutil.MockCommand(c, "resize2fs", "")
+	return cmds
+}
+
+func (cmds *growCommands) Restore() {
+	cmds.sfdisk.Restore()
+	cmds.partx.Restore()
+	cmds.resize2fs.Restore()
+}
+
+func (cmds *growCommands) checkGrown(c *C, sizeInSectors uint64) {
+	c.Check(cmds.sfdisk.Calls(), DeepEquals, [][]string{
+		{"sfdisk", "--no-reread", "--no-tell-kernel", "-N", "5", "/dev/vda"},
+	})
+	c.Check(cmds.sfdiskInput, testutil.FileEquals, fmt.Sprintf(",%d\n", sizeInSectors))
+	c.Check(cmds.partx.Calls(), DeepEquals, [][]string{
+		{"partx", "-u", "--nr", "5", "/dev/vda"},
+	})
+	c.Check(cmds.resize2fs.Calls(), DeepEquals, [][]string{
+		{"resize2fs", "/dev/vda5"},
+	})
+}
+
+func (cmds *growCommands) checkNotGrown(c *C) {
+	c.Check(cmds.sfdisk.Calls(), HasLen, 0)
+	c.Check(cmds.partx.Calls(), HasLen, 0)
+	c.Check(cmds.resize2fs.Calls(), HasLen, 0)
+}
+
+func (u *updateTestSuite) TestUpdateApplyUC20GrowSystemDataToEnd(c *C) {
+	oldData, newData := u.setupGrowSystemDataTest(c, gadgettest.VMSystemVolumeGrownDiskMapping)
+	cmds := mockGrowCommands(c)
+	defer cmds.Restore()
+
+	newData.Info.Volumes["pc"].Structure[5].Update.GrowToEnd = true
+
+	err := gadget.Update(uc20Model, oldData, newData, c.MkDir(), nil, nil)
+	c.Assert(err, IsNil)
+
+	// ubuntu-data starts at 1968M and is grown to the last usable sector of
+	// the 10G disk
+	cmds.checkGrown(c, (10240*1024*1024/512-34)-(1+1+1200+750+16)*1024*1024/512)
+}
+
+func (u *updateTestSuite) TestUpdateApplyUC20GrowSystemDataToEndNoSpace(c *C) {
+	oldData, newData := u.setupGrowSystemDataTest(c, gadgettest.VMSystemVolumeDiskMapping)
+	cmds := mockGrowCommands(c)
+	defer cmds.Restore()
+
+	// ubuntu-data already fills the disk
+	newData.Info.Volumes["pc"].Structure[5].Update.GrowToEnd = true
+
+	err := gadget.Update(uc20Model, oldData, newData, c.MkDir(), nil, nil)
+	c.Assert(err, Equals, gadget.ErrNoUpdate)
+	cmds.checkNotGrown(c)
+}
+
+func (u *updateTestSuite) TestUpdateApplyUC20GrowSystemDataSize(c *C) {
+	oldData, newData := u.setupGrowSystemDataTest(c, gadgettest.VMSystemVolumeGrownDiskMapping)
+	cmds := mockGrowCommands(c)
+	defer cmds.Restore()
+
+	newData.Info.Volumes["pc"].Structure[5].Size = 4 * quantity.SizeGiB
+	newData.Info.Volumes["pc"].Structure[5].MinSize = 4 * quantity.SizeGiB
+
+	err := gadget.Update(uc20Model, oldData, newData, c.MkDir(), nil, nil)
+	c.Assert(err, IsNil)
+	cmds.checkGrown(c, 4*1024*1024*1024/512)
+}
+
+func (u *updateTestSuite) TestUpdateApplyUC20GrowSystemDataSizeNotEnoughSpace(c *C) {
+	oldData, newData := u.setupGrowSystemDataTest(c, gadgettest.VMSystemVolumeDiskMapping)
+	cmds := mockGrowCommands(c)
+	defer cmds.Restore()
+
+	newData.Info.Volumes["pc"].Structure[5].Size = 4 * quantity.SizeGiB
+	newData.Info.Volumes["pc"].Structure[5].MinSize = 4 * quantity.SizeGiB
+
+	err := gadget.Update(uc20Model, oldData, newData, c.MkDir(), nil, nil)
+	c.Assert(err, ErrorMatches, `cannot apply update to volume pc: cannot resize structure #5 \("ubuntu-data"\): not enough space on disk /dev/vda, need 4 GiB but only 3.08 GiB is available`)
+	cmds.checkNotGrown(c)
+}
+
+func (u *updateTestSuite) TestUpdateApplyUC20GrowSystemDataShrinkRefused(c *C) {
+	oldData, newData := u.setupGrowSystemDataTest(c, gadgettest.VMSystemVolumeGrownDiskMapping)
+	cmds := mockGrowCommands(c)
+	defer cmds.Restore()
+
+	newData.Info.Volumes["pc"].Structure[5].Size = 512 * quantity.SizeMiB
+	newData.Info.Volumes["pc"].Structure[5].MinSize = 512 * quantity.SizeMiB
+
+	err := gadget.Update(uc20Model, oldData, newData, c.MkDir(), nil, nil)
+	c.Assert(err, ErrorMatches, `cannot apply update to volume pc: cannot shrink structure #5 \("ubuntu-data"\) from 1 GiB to 512 MiB`)
+	cmds.checkNotGrown(c)
+}
+
+func (u *updateTestSuite) mockGrowVolumeStructureToLocationMap(onDiskData *gadget.OnDiskStructure) {
+	r := gadget.MockVolumeStructureToLocationMap(func(gd gadget.GadgetData, _ gadget.Model, _ map[string]*gadget.Volume) (map[string]map[int]gadget.StructureLocation, map[string]map[int]*gadget.OnDiskStructure, error) {
+		onDisk := gadget.OnDiskStructsFromGadget(gd.Info.Volumes["pc"])
+		if onDiskData != nil {
+			onDisk[5] = onDiskData
+		}
+		return map[string]map[int]gadget.StructureLocation{
+				"pc": {},
+			}, map[string]map[int]*gadget.OnDiskStructure{
+				"pc": onDisk,
+			},
+			nil
+	})
+	u.AddCleanup(r)
+}
+
+func (u *updateTestSuite) TestUpdateApplyUC20GrowNotLastPartitionRefused(c *C) {
+	oldData, newData := u.setupGrowSystemDataTest(c, gadgettest.VMSystemVolumeGrownDiskMapping)
+	u.mockGrowVolumeStructureToLocationMap(nil)
+	cmds := mockGrowCommands(c)
+	defer cmds.Restore()
+
+	// ubuntu-save
+	newData.Info.Volumes["pc"].Structure[4].Size = 32 * quantity.SizeMiB
+	newData.Info.Volumes["pc"].Structure[4].MinSize = 32 * quantity.SizeMiB
+
+	err := gadget.Update(uc20Model, oldData, newData, c.MkDir(), nil, nil)
+	c.Assert(err, ErrorMatches, `cannot apply update to volume pc: cannot resize structure #4 \("ubuntu-save"\): only the last partition of the volume can be resized`)
+	cmds.checkNotGrown(c)
+}
+
+func (u *updateTestSuite) mockEncryptedSystemData(c *C) {
+	dl, err := gadget.OnDiskVolumeFromDevice("/dev/vda")
+	c.Assert(err, IsNil)
+	c.Assert(dl.Structure, HasLen, 5)
+	onDiskData := dl.Structure[4]
+	c.Assert(onDiskData.Name, Equals, "ubuntu-data")
+	onDiskData.PartitionFSLabel = "ubuntu-data-enc"
+	onDiskData.PartitionFSType = "crypto_LUKS"
+	u.mockGrowVolumeStructureToLocationMap(&onDiskData)
+}
+
+func mockUnlockedDevice(c *C, partition, holder, name string) {
+	holdersDir := filepath.Join(dirs.SysfsDir, "class/block", partition, "holders")
+	c.Assert(os.MkdirAll(filepath.Join(holdersDir, holder), 0755), IsNil)
+	dmDir := filepath.Join(dirs.SysfsDir, "class/block", holder, "dm")
+	c.Assert(os.MkdirAll(dmDir, 0755), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dmDir, "name"), []byte(name+"\n"), 0644), IsNil)
+}
+
+func (u *updateTestSuite) TestUpdateApplyUC20GrowEncryptedSystemData(c *C) {
+	oldData, newData := u.setupGrowSystemDataTest(c, gadgettest.VMSystemVolumeGrownDiskMapping)
+	u.mockEncryptedSystemData(c)
+	mockUnlockedDevice(c, "vda5", "dm-0", "ubuntu-data-3776bab4-8bcc-46b7-9da2-6a84ce7f93b4")
+
+	cmds := mockGrowCommands(c)
+	defer cmds.Restore()
+	cryptsetup := testutil.MockCommand(c, "cryptsetup", "")
+	defer cryptsetup.Restore()
+
+	newData.Info.Volumes["pc"].Structure[5].Update.GrowToEnd = true
+
+	err := gadget.Update(uc20Model, oldData, newData, c.MkDir(), nil, nil)
+	c.Assert(err, IsNil)
+
+	c.Check(cmds.sfdisk.Calls(), DeepEquals, [][]string{
+		{"sfdisk", "--no-reread", "--no-tell-kernel", "-N", "5", "/dev/vda"},
+	})
+	c.Check(cmds.sfdiskInput, testutil.FileEquals, fmt.Sprintf(",%d\n", (10240*1024*1024/512-34)-(1+1+1200+750+16)*1024*1024/512))
+	c.Check(cmds.partx.Calls(), DeepEquals, [][]string{
+		{"partx", "-u", "--nr", "5", "/dev/vda"},
+	})
+	// the encrypted device is grown before the filesystem inside it
+	c.Check(cryptsetup.Calls(), DeepEquals, [][]string{
+		{"cryptsetup", "resize", "ubuntu-data-3776bab4-8bcc-46b7-9da2-6a84ce7f93b4"},
+	})
+	c.Check(cmds.resize2fs.Calls(), DeepEquals, [][]string{
+		{"resize2fs", "/dev/mapper/ubuntu-data-3776bab4-8bcc-46b7-9da2-6a84ce7f93b4"},
+	})
+}
+
+func (u *updateTestSuite) TestUpdateApplyUC20GrowEncryptedSystemDataLockedRefused(c *C) {
+	oldData, newData := u.setupGrowSystemDataTest(c, gadgettest.VMSystemVolumeGrownDiskMapping)
+	u.mockEncryptedSystemData(c)
+
+	cmds := mockGrowCommands(c)
+	defer cmds.Restore()
+
+	newData.Info.Volumes["pc"].Structure[5].Update.GrowToEnd = true
+
+	err := gadget.Update(uc20Model, oldData, newData, c.MkDir(), nil, nil)
+	c.Assert(err, ErrorMatches, `cannot apply update to volume pc: cannot resize structure #5 \("ubuntu-data"\): cannot find the unlocked device of encrypted partition /dev/vda5`)
+	cmds.checkNotGrown(c)
+}
+
+func (u *updateTestSuite) TestUpdateApplyUC20GrowSystemDataContentUpdateFails(c *C) {
+	oldData, newData := u.setupGrowSystemDataTest(c, gadgettest.VMSystemVolumeGrownDiskMapping)
+	cmds := mockGrowCommands(c)
+	defer cmds.Restore()
+
+	restore := gadget.MockUpdaterForStructure(func(loc gadget.StructureLocation, ps *gadget.LaidOutStructure, psRootDir, psRollbackDir string, observer gadget.ContentUpdateObserver) (gadget.Updater, error) {
+		c.Check(ps.Name(), Equals, "ubuntu-boot")
+		return &mockUpdater{
+			updateCb: func() error {
+				return errors.New("failed")
+			},
+		}, nil
+	})
+	defer restore()
+
+	// ubuntu-boot content is updated together with growing ubuntu-data
+	newData.Info.Volumes["pc"].Structure[3].Update.Edition = 1
+	newData.Info.Volumes["pc"].Structure[5].Update.GrowToEnd = true
+
+	err := gadget.Update(uc20Model, oldData, newData, c.MkDir(), nil, nil)
+	c.Assert(err, ErrorMatches, `cannot update volume structure #3 \("ubuntu-boot"\) on volume pc: failed`)
+
+	// the partition was grown and then restored to its original size
+	c.Check(cmds.sfdisk.Calls(), DeepEquals, [][]string{
+		{"sfdisk", "--no-reread", "--no-tell-kernel", "-N", "5", "/dev/vda"},
+		{"sfdisk", "--no-reread", "--no-tell-kernel", "-N", "5", "/dev/vda"},
+	})
+	c.Check(cmds.sfdiskInput, testutil.FileEquals, fmt.Sprintf(",%d\n", 1*1024*1024*1024/512))
+	c.Check(cmds.partx.Calls(), HasLen, 2)
+	// and the filesystem was left alone
+	c.Check(cmds.resize2fs.Calls(), HasLen, 0)
+}
+
+func (u *updateTestSuite) TestUpdateApplyUC20GrowSystemDataFails(c *C) {
+	oldData, newData := u.setupGrowSystemDataTest(c, gadgettest.VMSystemVolumeGrownDiskMapping)
+	cmds := mockGrowCommands(c)
+	defer cmds.Restore()
+	cmds.resize2fs.Restore()
+	cmds.resize2fs = testutil.MockCommand(c, "resize2fs", "echo 'resize failed'; exit 1")
+
+	newData.Info.Volumes["pc"].Structure[5].Update.GrowToEnd = true
+
+	err := gadget.Update(uc20Model, oldData, newData, c.MkDir(), nil, nil)
+	c.Assert(err, ErrorMatches, `cannot resize structure #5 \("ubuntu-data"\) on volume pc: cannot grow filesystem on /dev/vda5: resize failed`)
+}
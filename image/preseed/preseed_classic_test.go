@@ -343,6 +343,7 @@ func (s *preseedSuite) TestReset(c *C) {
 			symlinkTarget string
 		}{
 			{dirs.SnapStateFile, ""},
+			{dirs.SnapStateFile + ".wal", ""},
 			{dirs.SnapSystemKeyFile, ""},
 			{filepath.Join(dirs.SnapDesktopFilesDir, "foo.desktop"), ""},
 			{filepath.Join(dirs.SnapDesktopIconsDir, "foo.png"), ""},
@@ -98,6 +98,7 @@ var ResetPreseededChroot = func(preseedChroot string) error {
 	// globs that yield individual files
 	globs := []string{
 		dirs.SnapStateFile,
+		dirs.SnapStateFile + ".wal",
 		dirs.SnapSystemKeyFile,
 		filepath.Join(dirs.SnapBlobDir, "*.snap"),
 		filepath.Join(dirs.SnapUdevRulesDir, "*-snap.*.rules"),
@@ -116,6 +117,7 @@ var ResetPreseededChroot = func(preseedChroot string) error {
 		filepath.Join(dirs.SnapUserServicesDir, "sockets.target.wants", "snap.*.socket"),
 		filepath.Join(dirs.SnapUserServicesDir, "timers.target.wants", "snap.*.timer"),
 		filepath.Join(runinhibit.InhibitDir, "*.lock"),
+		filepath.Join(runinhibit.InhibitDir, "*.refresh"),
 	}
 
 	for _, gl := range globs {
@@ -342,6 +342,9 @@ type profilePathsResults struct {
 	changed   []string
 	unchanged []string
 	removed   []string
+	// keys maps the paths of the changed and unchanged profiles to the
+	// hash of their text.
+	keys map[string]string
 }
 
 func (b *Backend) prepareProfiles(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository) (prof *profilePathsResults, err error) {
@@ -429,7 +432,14 @@ func (b *Backend) prepareProfiles(snapInfo *snap.Info, opts interfaces.Confineme
 		unchangedPaths[i] = filepath.Join(dir, profile)
 	}
 
-	return &profilePathsResults{changed: changedPaths, removed: removedPaths, unchanged: unchangedPaths}, nil
+	keys := make(map[string]string, len(content))
+	for name, state := range content {
+		if mem, ok := state.(*osutil.MemoryFileState); ok {
+			keys[filepath.Join(dir, name)] = profileKey(mem.Content)
+		}
+	}
+
+	return &profilePathsResults{changed: changedPaths, removed: removedPaths, unchanged: unchangedPaths, keys: keys}, nil
 }
 
 // Setup creates and loads apparmor profiles specific to a given snap.
@@ -459,16 +469,20 @@ func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions,
 
 	// Load all unchanged profiles anyway. This ensures those are correct in
 	// the kernel even if the files on disk were not changed. We rely on
-	// apparmor cache to make this performant.
+	// apparmor cache to make this performant, unless the profiles were not
+	// compiled from their current text with the current apparmor parser and
+	// kernel features, then they are compiled again.
+	cache, cached, recompile := b.splitUnchangedProfiles(prof)
 	var errReloadOther error
 	aaFlags = 0
 	if b.preseed {
 		aaFlags |= apparmor_sandbox.SkipKernelLoad
 	}
-	timings.Run(tm, "load-profiles[unchanged]", fmt.Sprintf("load unchanged security profiles of snap %q", snapInfo.InstanceName()), func(nesttm timings.Measurer) {
-		errReloadOther = loadProfiles(prof.unchanged, apparmor_sandbox.CacheDir, aaFlags)
+	timings.Run(tm, "load-profiles[unchanged]", fmt.Sprintf("load unchanged security profiles of snap %q (%d recompiled)", snapInfo.InstanceName(), len(recompile)), func(nesttm timings.Measurer) {
+		errReloadOther = loadUnchangedProfiles(cached, recompile, aaFlags)
 	})
 	errRemoveCached := removeCachedProfiles(prof.removed, apparmor_sandbox.CacheDir)
+	b.updateProfileCache(cache, prof.changed, errReloadChanged, prof.unchanged, errReloadOther, prof)
 	if errReloadChanged != nil {
 		return errReloadChanged
 	}
@@ -478,6 +492,55 @@ func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions,
 	return errRemoveCached
 }
 
+// splitUnchangedProfiles splits the unchanged profiles between those which
+// can be loaded from the cache of apparmor_parser, as they were compiled
+// already from their current text with the current apparmor features or
+// were not recorded at all, and those which must be compiled again. The profile cache is not used when
+// preseeding, as profiles are not loaded into the kernel then.
+func (b *Backend) splitUnchangedProfiles(prof *profilePathsResults) (cache *profileCache, cached, recompile []string) {
+	if b.preseed || !useProfileCache {
+		return nil, prof.unchanged, nil
+	}
+	cache = loadProfileCache()
+	cached, recompile = cache.splitCached(prof.unchanged, prof.keys)
+	return cache, cached, recompile
+}
+
+// loadUnchangedProfiles loads all the given unchanged profiles, asking
+// apparmor_parser to skip reading its cache for those which must be compiled
+// again.
+func loadUnchangedProfiles(cached, recompile []string, aaFlags apparmor_sandbox.AaParserFlags) error {
+	errRecompile := loadProfiles(recompile, apparmor_sandbox.CacheDir, aaFlags|apparmor_sandbox.SkipReadCache)
+	errCached := loadProfiles(cached, apparmor_sandbox.CacheDir, aaFlags)
+	if errRecompile != nil {
+		return errRecompile
+	}
+	return errCached
+}
+
+// updateProfileCache records the profiles which were successfully loaded,
+// invalidates those which failed to load and forgets those which were
+// removed.
+func (b *Backend) updateProfileCache(cache *profileCache, changed []string, errChanged error, unchanged []string, errUnchanged error, prof *profilePathsResults) {
+	if cache == nil {
+		return
+	}
+	if errChanged != nil {
+		cache.invalidate(changed)
+	} else {
+		cache.record(changed, prof.keys)
+	}
+	if errUnchanged != nil {
+		cache.invalidate(unchanged)
+	} else {
+		cache.record(unchanged, prof.keys)
+	}
+	cache.forget(removedProfilePaths(prof.removed))
+	if err := cache.save(); err != nil {
+		logger.Noticef("cannot save apparmor profile cache: %v", err)
+	}
+}
+
 // SetupMany creates and loads apparmor profiles for multiple snaps.
 // The snaps can be in developer mode to make security violations non-fatal to
 // the offending application process.
@@ -486,7 +549,7 @@ func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions,
 //
 // This method is useful mainly for regenerating profiles.
 func (b *Backend) SetupMany(snaps []*snap.Info, confinement func(snapName string) interfaces.ConfinementOptions, repo *interfaces.Repository, tm timings.Measurer) []error {
-	var allChangedPaths, allUnchangedPaths, allRemovedPaths []string
+	all := &profilePathsResults{keys: make(map[string]string)}
 	var fallback bool
 	for _, snapInfo := range snaps {
 		opts := confinement(snapInfo.InstanceName())
@@ -495,9 +558,12 @@ func (b *Backend) SetupMany(snaps []*snap.Info, confinement func(snapName string
 			fallback = true
 			break
 		}
-		allChangedPaths = append(allChangedPaths, prof.changed...)
-		allUnchangedPaths = append(allUnchangedPaths, prof.unchanged...)
-		allRemovedPaths = append(allRemovedPaths, prof.removed...)
+		all.changed = append(all.changed, prof.changed...)
+		all.unchanged = append(all.unchanged, prof.unchanged...)
+		all.removed = append(all.removed, prof.removed...)
+		for path, key := range prof.keys {
+			all.keys[path] = key
+		}
 	}
 
 	if !fallback {
@@ -507,19 +573,21 @@ func (b *Backend) SetupMany(snaps []*snap.Info, confinement func(snapName string
 		}
 		var errReloadChanged error
 		timings.Run(tm, "load-profiles[changed-many]", fmt.Sprintf("load changed security profiles of %d snaps", len(snaps)), func(nesttm timings.Measurer) {
-			errReloadChanged = loadProfiles(allChangedPaths, apparmor_sandbox.CacheDir, aaFlags)
+			errReloadChanged = loadProfiles(all.changed, apparmor_sandbox.CacheDir, aaFlags)
 		})
 
+		cache, cached, recompile := b.splitUnchangedProfiles(all)
 		aaFlags = apparmor_sandbox.ConserveCPU
 		if b.preseed {
 			aaFlags |= apparmor_sandbox.SkipKernelLoad
 		}
 		var errReloadOther error
-		timings.Run(tm, "load-profiles[unchanged-many]", fmt.Sprintf("load unchanged security profiles %d snaps", len(snaps)), func(nesttm timings.Measurer) {
-			errReloadOther = loadProfiles(allUnchangedPaths, apparmor_sandbox.CacheDir, aaFlags)
+		timings.Run(tm, "load-profiles[unchanged-many]", fmt.Sprintf("load unchanged security profiles %d snaps (%d recompiled)", len(snaps), len(recompile)), func(nesttm timings.Measurer) {
+			errReloadOther = loadUnchangedProfiles(cached, recompile, aaFlags)
 		})
 
-		errRemoveCached := removeCachedProfiles(allRemovedPaths, apparmor_sandbox.CacheDir)
+		errRemoveCached := removeCachedProfiles(all.removed, apparmor_sandbox.CacheDir)
+		b.updateProfileCache(cache, all.changed, errReloadChanged, all.unchanged, errReloadOther, all)
 		if errReloadChanged != nil {
 			logger.Noticef("failed to batch-reload changed profiles: %s", errReloadChanged)
 			fallback = true
@@ -577,6 +645,7 @@ func (b *Backend) Remove(snapName string) error {
 	_, removed, errEnsure := osutil.EnsureDirStateGlobs(dir, globs, nil)
 	// always try to remove affected profiles from the cache
 	errRemoveCached := removeCachedProfiles(removed, cache)
+	forgetRemovedProfiles(removed)
 	if errEnsure != nil {
 		return fmt.Errorf("cannot synchronize security files for snap %q: %s", snapName, errEnsure)
 	}
@@ -597,6 +666,7 @@ func (b *Backend) RemoveLate(snapName string, rev snap.Revision, typ snap.Type)
 	// possible to ensure all snap services are stopped at this time and so
 	// it is not safe to unload the profile
 	errRemoveCached := removeCachedProfiles(removed, apparmor_sandbox.CacheDir)
+	forgetRemovedProfiles(removed)
 	if errEnsure != nil {
 		return fmt.Errorf("cannot remove security profiles for snap %q (%s): %s", snapName, rev, errEnsure)
 	}
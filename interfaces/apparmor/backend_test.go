@@ -20,6 +20,7 @@
 package apparmor_test
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -94,6 +95,11 @@ func (s *backendSuite) SetUpTest(c *C) {
 	restore = apparmor_sandbox.MockFeatures(nil, nil, nil, nil)
 	s.AddCleanup(restore)
 
+	// most tests check how profiles are loaded regardless of the cache of
+	// compiled profiles, which is tested separately
+	s.AddCleanup(apparmor.MockUseProfileCache(false))
+	s.AddCleanup(apparmor.MockParserMtime(func() int64 { return 1 }))
+
 	s.loadProfilesCalls = nil
 	s.loadProfilesReturn = nil
 	s.removeCachedProfilesCalls = nil
@@ -724,6 +730,189 @@ func (s *backendSuite) TestSetupManyApparmorBatchProcessingErrorWithFallbackPart
 	}
 }
 
+// Profile cache tests
+
+func (s *backendSuite) TestSetupRecompilesOnlyProfilesNotCompiledYet(c *C) {
+	s.AddCleanup(apparmor.MockUseProfileCache(true))
+
+	opts := interfaces.ConfinementOptions{}
+	snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 1)
+	updateNSProfile := filepath.Join(dirs.SnapAppArmorDir, "snap-update-ns.samba")
+	profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+	// the profiles were compiled when installing
+	c.Check(s.loadProfilesCalls, DeepEquals, []loadProfilesParams{
+		{[]string{updateNSProfile, profile}, fmt.Sprintf("%s/var/cache/apparmor", s.RootDir), apparmor_sandbox.SkipReadCache},
+	})
+
+	// nothing changed, the profiles are still loaded but from the cache
+	// of apparmor_parser
+	s.loadProfilesCalls = nil
+	c.Assert(s.Backend.Setup(snapInfo, opts, s.Repo, s.meas), IsNil)
+	c.Check(s.loadProfilesCalls, DeepEquals, []loadProfilesParams{
+		{[]string{updateNSProfile, profile}, fmt.Sprintf("%s/var/cache/apparmor", s.RootDir), 0},
+	})
+
+	// a profile is changed, only that one is compiled again
+	s.loadProfilesCalls = nil
+	c.Assert(os.WriteFile(profile, []byte("# an outdated profile"), 0644), IsNil)
+	c.Assert(s.Backend.Setup(snapInfo, opts, s.Repo, s.meas), IsNil)
+	c.Check(s.loadProfilesCalls, DeepEquals, []loadProfilesParams{
+		{[]string{profile}, fmt.Sprintf("%s/var/cache/apparmor", s.RootDir), apparmor_sandbox.SkipReadCache},
+		{[]string{updateNSProfile}, fmt.Sprintf("%s/var/cache/apparmor", s.RootDir), 0},
+	})
+
+	// the cache was updated
+	s.loadProfilesCalls = nil
+	c.Assert(s.Backend.Setup(snapInfo, opts, s.Repo, s.meas), IsNil)
+	c.Check(s.loadProfilesCalls, DeepEquals, []loadProfilesParams{
+		{[]string{updateNSProfile, profile}, fmt.Sprintf("%s/var/cache/apparmor", s.RootDir), 0},
+	})
+
+	// the removed profiles are forgotten
+	s.RemoveSnap(c, snapInfo)
+	data, err := os.ReadFile(filepath.Join(dirs.SnapAppArmorDir, "..", "profiles-cache.json"))
+	c.Assert(err, IsNil)
+	c.Check(string(data), Not(testutil.Contains), "samba")
+}
+
+func (s *backendSuite) TestSetupTrustsProfilesNotRecorded(c *C) {
+	// the profiles are compiled before the profile cache is used
+	opts := interfaces.ConfinementOptions{}
+	snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 1)
+	updateNSProfile := filepath.Join(dirs.SnapAppArmorDir, "snap-update-ns.samba")
+	profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+	cacheFile := filepath.Join(dirs.SnapAppArmorDir, "..", "profiles-cache.json")
+	c.Assert(cacheFile, testutil.FileAbsent)
+
+	// profiles which were not recorded are loaded from the cache of
+	// apparmor_parser
+	s.AddCleanup(apparmor.MockUseProfileCache(true))
+	s.loadProfilesCalls = nil
+	c.Assert(s.Backend.Setup(snapInfo, opts, s.Repo, s.meas), IsNil)
+	c.Check(s.loadProfilesCalls, DeepEquals, []loadProfilesParams{
+		{[]string{updateNSProfile, profile}, fmt.Sprintf("%s/var/cache/apparmor", s.RootDir), 0},
+	})
+
+	// and are recorded with their current key
+	data, err := os.ReadFile(cacheFile)
+	c.Assert(err, IsNil)
+	var cache struct {
+		Features string            `json:"features"`
+		Profiles map[string]string `json:"profiles"`
+	}
+	c.Assert(json.Unmarshal(data, &cache), IsNil)
+	c.Check(cache.Profiles, HasLen, 2)
+	c.Check(cache.Profiles[profile], Not(Equals), "")
+	c.Check(cache.Profiles[updateNSProfile], Not(Equals), "")
+
+	// a profile recorded with another key is compiled again
+	cache.Profiles[profile] = "other"
+	data, err = json.Marshal(cache)
+	c.Assert(err, IsNil)
+	c.Assert(os.WriteFile(cacheFile, data, 0644), IsNil)
+	s.loadProfilesCalls = nil
+	c.Assert(s.Backend.Setup(snapInfo, opts, s.Repo, s.meas), IsNil)
+	c.Check(s.loadProfilesCalls, DeepEquals, []loadProfilesParams{
+		{[]string{profile}, fmt.Sprintf("%s/var/cache/apparmor", s.RootDir), apparmor_sandbox.SkipReadCache},
+		{[]string{updateNSProfile}, fmt.Sprintf("%s/var/cache/apparmor", s.RootDir), 0},
+	})
+}
+
+func (s *backendSuite) TestSetupRecompilesWhenFeaturesChange(c *C) {
+	s.AddCleanup(apparmor.MockUseProfileCache(true))
+
+	opts := interfaces.ConfinementOptions{}
+	snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 1)
+	updateNSProfile := filepath.Join(dirs.SnapAppArmorDir, "snap-update-ns.samba")
+	profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+
+	for _, t := range []struct {
+		comment string
+		mock    func() (restore func())
+	}{{
+		comment: "kernel feature added",
+		mock: func() func() {
+			return apparmor_sandbox.MockFeatures([]string{"network"}, nil, nil, nil)
+		},
+	}, {
+		comment: "parser feature added",
+		mock: func() func() {
+			return apparmor_sandbox.MockFeatures([]string{"network"}, nil, []string{"unsafe"}, nil)
+		},
+	}, {
+		comment: "parser updated",
+		mock: func() func() {
+			r1 := apparmor_sandbox.MockFeatures([]string{"network"}, nil, []string{"unsafe"}, nil)
+			r2 := apparmor.MockParserMtime(func() int64 { return 2 })
+			return func() { r2(); r1() }
+		},
+	}} {
+		restore := t.mock()
+		s.AddCleanup(restore)
+
+		// the unchanged profiles are compiled again, ignoring the
+		// cache of apparmor_parser
+		s.loadProfilesCalls = nil
+		c.Assert(s.Backend.Setup(snapInfo, opts, s.Repo, s.meas), IsNil)
+		c.Check(s.loadProfilesCalls, DeepEquals, []loadProfilesParams{
+			{[]string{updateNSProfile, profile}, fmt.Sprintf("%s/var/cache/apparmor", s.RootDir), apparmor_sandbox.SkipReadCache},
+		}, Commentf(t.comment))
+
+		// and are cached with the new features
+		s.loadProfilesCalls = nil
+		c.Assert(s.Backend.Setup(snapInfo, opts, s.Repo, s.meas), IsNil)
+		c.Check(s.loadProfilesCalls, DeepEquals, []loadProfilesParams{
+			{[]string{updateNSProfile, profile}, fmt.Sprintf("%s/var/cache/apparmor", s.RootDir), 0},
+		}, Commentf(t.comment))
+	}
+}
+
+func (s *backendSuite) TestSetupForgetsProfilesFailingToLoad(c *C) {
+	s.AddCleanup(apparmor.MockUseProfileCache(true))
+
+	opts := interfaces.ConfinementOptions{}
+	snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 1)
+	updateNSProfile := filepath.Join(dirs.SnapAppArmorDir, "snap-update-ns.samba")
+	profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+	c.Assert(os.WriteFile(profile, []byte("# an outdated profile"), 0644), IsNil)
+	s.loadProfilesReturn = errors.New("apparmor_parser crash")
+	c.Assert(s.Backend.Setup(snapInfo, opts, s.Repo, s.meas), ErrorMatches, "apparmor_parser crash")
+
+	// the profiles are compiled again
+	s.loadProfilesReturn = nil
+	s.loadProfilesCalls = nil
+	c.Assert(s.Backend.Setup(snapInfo, opts, s.Repo, s.meas), IsNil)
+	c.Check(s.loadProfilesCalls, DeepEquals, []loadProfilesParams{
+		{[]string{updateNSProfile, profile}, fmt.Sprintf("%s/var/cache/apparmor", s.RootDir), apparmor_sandbox.SkipReadCache},
+	})
+}
+
+func (s *backendSuite) TestSetupManyRecompilesOnlyProfilesNotCompiledYet(c *C) {
+	s.AddCleanup(apparmor.MockUseProfileCache(true))
+
+	opts := interfaces.ConfinementOptions{}
+	snapInfo1 := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 1)
+	snapInfo2 := s.InstallSnap(c, opts, "", ifacetest.SomeSnapYamlV1, 1)
+	s.loadProfilesCalls = nil
+
+	snap1nsProfile := filepath.Join(dirs.SnapAppArmorDir, "snap-update-ns.samba")
+	snap1AAprofile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+	snap2nsProfile := filepath.Join(dirs.SnapAppArmorDir, "snap-update-ns.some-snap")
+	snap2AAprofile := filepath.Join(dirs.SnapAppArmorDir, "snap.some-snap.someapp")
+	c.Assert(os.WriteFile(snap1AAprofile, []byte("# an outdated profile"), 0644), IsNil)
+
+	setupManyInterface, ok := s.Backend.(interfaces.SecurityBackendSetupMany)
+	c.Assert(ok, Equals, true)
+	errs := setupManyInterface.SetupMany([]*snap.Info{snapInfo1, snapInfo2}, func(snapName string) interfaces.ConfinementOptions { return opts }, s.Repo, s.meas)
+	c.Assert(errs, HasLen, 0)
+
+	// only the changed profile was compiled again, but all were loaded
+	c.Check(s.loadProfilesCalls, DeepEquals, []loadProfilesParams{
+		{[]string{snap1AAprofile}, fmt.Sprintf("%s/var/cache/apparmor", s.RootDir), apparmor_sandbox.SkipReadCache | apparmor_sandbox.ConserveCPU},
+		{[]string{snap1nsProfile, snap2nsProfile, snap2AAprofile}, fmt.Sprintf("%s/var/cache/apparmor", s.RootDir), apparmor_sandbox.ConserveCPU},
+	})
+}
+
 const snapcraftPrYaml = `name: snapcraft-pr
 version: 1
 apps:
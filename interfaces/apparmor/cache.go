@@ -0,0 +1,187 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package apparmor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	apparmor_sandbox "github.com/snapcore/snapd/sandbox/apparmor"
+)
+
+var (
+	parserMtime = apparmor_sandbox.ParserMtime
+
+	// useProfileCache allows to skip compiling unchanged profiles which
+	// were already compiled.
+	useProfileCache = true
+)
+
+// profileCache records the profiles compiled by apparmor_parser, with the
+// hash of their text, for the apparmor features they were compiled with.
+// This allows to skip compiling profiles again when neither their text, nor
+// the parser, nor the kernel changed. It does not reflect what is loaded
+// into the kernel, profiles are always loaded. Profiles without a record,
+// e.g. those compiled before the cache existed, are trusted to be in the
+// cache of apparmor_parser already.
+type profileCache struct {
+	// Features is the hash of the apparmor parser version and features
+	// and of the kernel features the profiles were compiled with.
+	Features string `json:"features"`
+	// Profiles maps the path of loaded profiles to the hash of their
+	// text. An empty hash records a profile which must be compiled again,
+	// because it failed to load or was compiled with other features.
+	Profiles map[string]string `json:"profiles"`
+}
+
+func profileCacheFile() string {
+	return filepath.Join(filepath.Dir(dirs.SnapAppArmorDir), "profiles-cache.json")
+}
+
+// featuresKey returns the hash identifying the apparmor parser and kernel
+// features profiles are compiled with. The mtime of the parser stands in
+// for its version, as is done for the system key.
+func featuresKey() string {
+	h := sha256.New()
+	kFeatures, _ := kernelFeatures()
+	pFeatures, _ := parserFeatures()
+	fmt.Fprintf(h, "kernel:%s\n", strings.Join(kFeatures, ","))
+	fmt.Fprintf(h, "parser:%s\n", strings.Join(pFeatures, ","))
+	fmt.Fprintf(h, "parser-mtime:%d\n", parserMtime())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func profileKey(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
+
+// loadProfileCache returns the recorded profile cache. If the features
+// changed since the profiles were compiled, all recorded profiles are
+// invalidated, so that they get compiled again.
+func loadProfileCache() *profileCache {
+	features := featuresKey()
+	cache := &profileCache{Features: features, Profiles: make(map[string]string)}
+	f, err := os.Open(profileCacheFile())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Noticef("cannot open apparmor profile cache: %v", err)
+		}
+		return cache
+	}
+	defer f.Close()
+	var recorded profileCache
+	if err := json.NewDecoder(f).Decode(&recorded); err != nil {
+		logger.Noticef("cannot read apparmor profile cache: %v", err)
+		return cache
+	}
+	if recorded.Profiles == nil {
+		recorded.Profiles = make(map[string]string)
+	}
+	if recorded.Features != features {
+		// the parser or kernel changed, everything must be
+		// compiled again
+		recorded.Features = features
+		for path := range recorded.Profiles {
+			recorded.Profiles[path] = ""
+		}
+	}
+	return &recorded
+}
+
+func (c *profileCache) save() error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(profileCacheFile()), 0755); err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(profileCacheFile(), data, 0644, 0)
+}
+
+// needsCompile returns whether the profile at the given path must be
+// compiled again as it was recorded with a different key. A profile which
+// was not recorded is assumed to be in the cache of apparmor_parser.
+func (c *profileCache) needsCompile(path, key string) bool {
+	recorded, ok := c.Profiles[path]
+	return ok && recorded != key
+}
+
+// splitCached splits the given unchanged profiles between those that were
+// compiled already and those that must be compiled again.
+func (c *profileCache) splitCached(unchanged []string, keys map[string]string) (cached, recompile []string) {
+	for _, path := range unchanged {
+		if c.needsCompile(path, keys[path]) {
+			recompile = append(recompile, path)
+		} else {
+			cached = append(cached, path)
+		}
+	}
+	return cached, recompile
+}
+
+func (c *profileCache) record(paths []string, keys map[string]string) {
+	for _, path := range paths {
+		c.Profiles[path] = keys[path]
+	}
+}
+
+// invalidate records that the given profiles must be compiled again.
+func (c *profileCache) invalidate(paths []string) {
+	for _, path := range paths {
+		c.Profiles[path] = ""
+	}
+}
+
+func (c *profileCache) forget(paths []string) {
+	for _, path := range paths {
+		delete(c.Profiles, path)
+	}
+}
+
+// forgetRemovedProfiles drops the given profiles, relative to
+// dirs.SnapAppArmorDir, from the profile cache.
+func forgetRemovedProfiles(removed []string) {
+	if !useProfileCache || len(removed) == 0 {
+		return
+	}
+	cache := loadProfileCache()
+	cache.forget(removedProfilePaths(removed))
+	if err := cache.save(); err != nil {
+		logger.Noticef("cannot save apparmor profile cache: %v", err)
+	}
+}
+
+func removedProfilePaths(removed []string) []string {
+	paths := make([]string, len(removed))
+	for i, name := range removed {
+		paths[i] = filepath.Join(dirs.SnapAppArmorDir, name)
+	}
+	return paths
+}
@@ -41,6 +41,18 @@ func MockLoadProfiles(f func(fnames []string, cacheDir string, flags apparmor_sa
 	return r
 }
 
+func MockUseProfileCache(use bool) (restore func()) {
+	r := testutil.Backup(&useProfileCache)
+	useProfileCache = use
+	return r
+}
+
+func MockParserMtime(f func() int64) (restore func()) {
+	r := testutil.Backup(&parserMtime)
+	parserMtime = f
+	return r
+}
+
 func MockRemoveCachedProfiles(f func(fnames []string, cacheDir string) error) (restore func()) {
 	r := testutil.Backup(&removeCachedProfiles)
 	removeCachedProfiles = f
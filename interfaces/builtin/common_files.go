@@ -36,6 +36,83 @@ type commonFilesInterface struct {
 
 	apparmorHeader    string
 	extraPathValidate func(string) error
+	// readOnly is set for interfaces which only grant read access, the
+	// "write" attribute is then refused.
+	readOnly bool
+}
+
+// FilesInterfaceOptions describes a parametrized file access interface,
+// with plugs listing the paths to grant access to in their "read" and
+// "write" attributes, as with system-files and personal-files.
+type FilesInterfaceOptions struct {
+	Name    string
+	Summary string
+
+	ImplicitOnCore    bool
+	ImplicitOnClassic bool
+
+	BaseDeclarationPlugs string
+	BaseDeclarationSlots string
+
+	// AppArmorHeader is added to the AppArmor snippet before the rules
+	// granting access to the paths of the plug.
+	AppArmorHeader string
+	// SecCompSnippet is an optional seccomp snippet for connected plugs.
+	SecCompSnippet string
+
+	// ValidatePath is called for each path of the plugs, after the
+	// checks common to all the file access interfaces. It is mandatory,
+	// see ValidateSystemFilesPath and ValidatePersonalFilesPath.
+	ValidatePath func(path string) error
+	// ReadOnly makes the interface refuse plugs with a "write"
+	// attribute and only ever grant read access.
+	ReadOnly bool
+}
+
+// NewFilesInterface returns a file access interface as described by the
+// given options.
+func NewFilesInterface(opts *FilesInterfaceOptions) interfaces.Interface {
+	if opts.ValidatePath == nil {
+		panic("ValidatePath must be set when building a files interface")
+	}
+	return &commonFilesInterface{
+		commonInterface: commonInterface{
+			name:                 opts.Name,
+			summary:              opts.Summary,
+			implicitOnCore:       opts.ImplicitOnCore,
+			implicitOnClassic:    opts.ImplicitOnClassic,
+			baseDeclarationPlugs: opts.BaseDeclarationPlugs,
+			baseDeclarationSlots: opts.BaseDeclarationSlots,
+			connectedPlugSecComp: opts.SecCompSnippet,
+		},
+		apparmorHeader:    opts.AppArmorHeader,
+		extraPathValidate: opts.ValidatePath,
+		readOnly:          opts.ReadOnly,
+	}
+}
+
+// ValidateSystemFilesPath checks that the path is absolute and does not
+// use $HOME, as required by system-files.
+func ValidateSystemFilesPath(path string) error {
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf(`%q must start with "/"`, path)
+	}
+	if strings.Contains(path, "$HOME") {
+		return fmt.Errorf(`$HOME cannot be used in %q`, path)
+	}
+	return nil
+}
+
+// ValidatePersonalFilesPath checks that the path starts with $HOME and
+// does not use it elsewhere, as required by personal-files.
+func ValidatePersonalFilesPath(path string) error {
+	if !strings.HasPrefix(path, "$HOME/") {
+		return fmt.Errorf(`%q must start with "$HOME/"`, path)
+	}
+	if strings.Count(path, "$HOME") > 1 {
+		return fmt.Errorf(`$HOME must only be used at the start of the path of %q`, path)
+	}
+	return nil
 }
 
 // filesAAPerm can either be files{Read,Write} and converted to a string
@@ -132,6 +209,9 @@ func (iface *commonFilesInterface) BeforePreparePlug(plug *snap.PlugInfo) error
 		if _, ok := plug.Attrs[att]; !ok {
 			continue
 		}
+		if att == "write" && iface.readOnly {
+			return fmt.Errorf(`cannot add %s plug: "write" attribute is not supported, access is read-only`, iface.name)
+		}
 		paths, ok := plug.Attrs[att].([]interface{})
 		if !ok {
 			return fmt.Errorf("cannot add %s plug: %q must be a list of strings", iface.name, att)
@@ -142,6 +222,9 @@ func (iface *commonFilesInterface) BeforePreparePlug(plug *snap.PlugInfo) error
 		hasValidAttr = true
 	}
 	if !hasValidAttr {
+		if iface.readOnly {
+			return fmt.Errorf(`cannot add %s plug: needs valid "read" attribute`, iface.name)
+		}
 		return fmt.Errorf(`cannot add %s plug: needs valid "read" or "write" attribute`, iface.name)
 	}
 
@@ -151,7 +234,9 @@ func (iface *commonFilesInterface) BeforePreparePlug(plug *snap.PlugInfo) error
 func (iface *commonFilesInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
 	var reads, writes []interface{}
 	_ = plug.Attr("read", &reads)
-	_ = plug.Attr("write", &writes)
+	if !iface.readOnly {
+		_ = plug.Attr("write", &writes)
+	}
 
 	errPrefix := fmt.Sprintf(`cannot connect plug %s: `, plug.Name())
 	buf := bytes.NewBufferString(iface.apparmorHeader)
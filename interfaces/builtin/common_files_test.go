@@ -0,0 +1,180 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+type filesInterfaceSuite struct {
+	iface interfaces.Interface
+	slot  *interfaces.ConnectedSlot
+}
+
+var _ = Suite(&filesInterfaceSuite{})
+
+func (s *filesInterfaceSuite) SetUpTest(c *C) {
+	s.iface = builtin.NewFilesInterface(&builtin.FilesInterfaceOptions{
+		Name:           "test-files",
+		Summary:        "allows access to test files",
+		AppArmorHeader: "# test files\n",
+		SecCompSnippet: "# test seccomp\n",
+		ValidatePath:   builtin.ValidateSystemFilesPath,
+	})
+	slotInfo := &snap.SlotInfo{
+		Snap:      &snap.Info{SuggestedName: "core", SnapType: snap.TypeOS},
+		Name:      "test-files",
+		Interface: "test-files",
+	}
+	s.slot = interfaces.NewConnectedSlot(slotInfo, nil, nil)
+}
+
+func (s *filesInterfaceSuite) mockPlug(c *C, attrs string) *snap.PlugInfo {
+	const mockSnapYaml = `name: other
+version: 1.0
+plugs:
+ test-files:
+  %s
+apps:
+ app:
+  command: foo
+  plugs: [test-files]
+`
+	info := snaptest.MockInfo(c, fmt.Sprintf(mockSnapYaml, attrs), nil)
+	return info.Plugs["test-files"]
+}
+
+func (s *filesInterfaceSuite) TestStaticInfo(c *C) {
+	c.Check(s.iface.Name(), Equals, "test-files")
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Check(si.Summary, Equals, "allows access to test files")
+}
+
+func (s *filesInterfaceSuite) TestConnectedPlugSnippets(c *C) {
+	plugInfo := s.mockPlug(c, `read: [/etc/foo]
+  write: [/var/bar]`)
+	c.Assert(interfaces.BeforePreparePlug(s.iface, plugInfo), IsNil)
+	plug := interfaces.NewConnectedPlug(plugInfo, nil, nil)
+
+	apparmorSpec := &apparmor.Specification{}
+	c.Assert(apparmorSpec.AddConnectedPlug(s.iface, plug, s.slot), IsNil)
+	c.Check(apparmorSpec.SnippetForTag("snap.other.app"), Equals, `# test files
+"/etc/foo{,/,/**}" rk,
+"/var/bar{,/,/**}" rwkl,
+`)
+
+	seccompSpec := &seccomp.Specification{}
+	c.Assert(seccompSpec.AddConnectedPlug(s.iface, plug, s.slot), IsNil)
+	c.Check(seccompSpec.SnippetForTag("snap.other.app"), Equals, "# test seccomp\n\n")
+}
+
+func (s *filesInterfaceSuite) TestPathValidation(c *C) {
+	for _, t := range []struct {
+		path string
+		err  string
+	}{
+		{"/etc/foo", ""},
+		{"/etc/foo.d", ""},
+		{"/etc/foo..bar", ""},
+		// trailing slashes
+		{"/etc/foo/", `"/etc/foo/" cannot end with "/"`},
+		{"/", `"/" cannot end with "/"`},
+		{"/etc//foo", `cannot use "/etc//foo": try "/etc/foo"`},
+		// globs and other apparmor regular expressions
+		{"/etc/*", `"/etc/\*" contains a reserved apparmor char from .*`},
+		{"/etc/**/foo", `"/etc/\*\*/foo" contains a reserved apparmor char from .*`},
+		{"/etc/fo?", `"/etc/fo\?" contains a reserved apparmor char from .*`},
+		{"/etc/{a,b}", `"/etc/{a,b}" contains a reserved apparmor char from .*`},
+		// parent directories
+		{"/etc/..", `cannot use "/etc/..": try "/"`},
+		{"/etc/../root", `cannot use "/etc/../root": try "/root"`},
+		{"/etc/foo/../../..", `cannot use "/etc/foo/../../..": try "/"`},
+		{"../etc", `"../etc" must start with "/"`},
+		{"/etc/.", `cannot use "/etc/.": try "/etc"`},
+		// variables
+		{"$HOME/foo", `"\$HOME/foo" must start with "/"`},
+		{"/etc/$HOME", `\$HOME cannot be used in "/etc/\$HOME"`},
+		{"/etc/~", `"/etc/~" cannot contain "~"`},
+	} {
+		plugInfo := s.mockPlug(c, fmt.Sprintf("read: [%q]", t.path))
+		err := interfaces.BeforePreparePlug(s.iface, plugInfo)
+		if t.err == "" {
+			c.Check(err, IsNil, Commentf("path %q", t.path))
+		} else {
+			c.Check(err, ErrorMatches, "cannot add test-files plug: "+t.err, Commentf("path %q", t.path))
+		}
+	}
+}
+
+func (s *filesInterfaceSuite) TestPersonalPathValidation(c *C) {
+	iface := builtin.NewFilesInterface(&builtin.FilesInterfaceOptions{
+		Name:         "test-files",
+		ValidatePath: builtin.ValidatePersonalFilesPath,
+	})
+	for _, t := range []struct {
+		path string
+		err  string
+	}{
+		{"$HOME/.config/foo", ""},
+		{"$HOME/", `"\$HOME/" cannot end with "/"`},
+		{"$HOME", `"\$HOME" must start with "\$HOME/"`},
+		{"$HOME/..", `cannot use "\$HOME/..": try "."`},
+		{"$HOME/foo/../bar", `cannot use "\$HOME/foo/../bar": try "\$HOME/bar"`},
+		{"$HOME/*", `"\$HOME/\*" contains a reserved apparmor char from .*`},
+		{"/home/foo", `"/home/foo" must start with "\$HOME/"`},
+		{"$HOME/foo/$HOME", `\$HOME must only be used at the start of the path of "\$HOME/foo/\$HOME"`},
+		{"$SNAP/foo", `"\$SNAP/foo" must start with "\$HOME/"`},
+	} {
+		plugInfo := s.mockPlug(c, fmt.Sprintf("read: [%q]", t.path))
+		err := interfaces.BeforePreparePlug(iface, plugInfo)
+		if t.err == "" {
+			c.Check(err, IsNil, Commentf("path %q", t.path))
+		} else {
+			c.Check(err, ErrorMatches, "cannot add test-files plug: "+t.err, Commentf("path %q", t.path))
+		}
+	}
+}
+
+func (s *filesInterfaceSuite) TestNewFilesInterfaceNeedsValidatePath(c *C) {
+	c.Check(func() {
+		builtin.NewFilesInterface(&builtin.FilesInterfaceOptions{Name: "test-files"})
+	}, PanicMatches, "ValidatePath must be set when building a files interface")
+}
+
+func (s *filesInterfaceSuite) TestReadOnly(c *C) {
+	iface := builtin.NewFilesInterface(&builtin.FilesInterfaceOptions{
+		Name:         "test-files",
+		ValidatePath: builtin.ValidateSystemFilesPath,
+		ReadOnly:     true,
+	})
+	plugInfo := s.mockPlug(c, `read: [/etc/foo]`)
+	c.Check(interfaces.BeforePreparePlug(iface, plugInfo), IsNil)
+	plugInfo = s.mockPlug(c, `write: [/etc/foo]`)
+	c.Check(interfaces.BeforePreparePlug(iface, plugInfo), ErrorMatches, `cannot add test-files plug: "write" attribute is not supported, access is read-only`)
+}
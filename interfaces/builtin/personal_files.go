@@ -19,11 +19,6 @@
 
 package builtin
 
-import (
-	"fmt"
-	"strings"
-)
-
 const personalFilesSummary = `allows access to personal files or directories`
 
 const personalFilesBaseDeclarationPlugs = `
@@ -46,33 +41,15 @@ const personalFilesConnectedPlugAppArmor = `
 # This is restricted because it gives file access to arbitrary locations.
 `
 
-type personalFilesInterface struct {
-	commonFilesInterface
-}
-
-func validateSinglePathHome(np string) error {
-	if !strings.HasPrefix(np, "$HOME/") {
-		return fmt.Errorf(`%q must start with "$HOME/"`, np)
-	}
-	if strings.Count(np, "$HOME") > 1 {
-		return fmt.Errorf(`$HOME must only be used at the start of the path of %q`, np)
-	}
-	return nil
-}
-
 func init() {
-	registerIface(&personalFilesInterface{
-		commonFilesInterface{
-			commonInterface: commonInterface{
-				name:                 "personal-files",
-				summary:              personalFilesSummary,
-				implicitOnCore:       true,
-				implicitOnClassic:    true,
-				baseDeclarationPlugs: personalFilesBaseDeclarationPlugs,
-				baseDeclarationSlots: personalFilesBaseDeclarationSlots,
-			},
-			apparmorHeader:    personalFilesConnectedPlugAppArmor,
-			extraPathValidate: validateSinglePathHome,
-		},
-	})
+	registerIface(NewFilesInterface(&FilesInterfaceOptions{
+		Name:                 "personal-files",
+		Summary:              personalFilesSummary,
+		ImplicitOnCore:       true,
+		ImplicitOnClassic:    true,
+		BaseDeclarationPlugs: personalFilesBaseDeclarationPlugs,
+		BaseDeclarationSlots: personalFilesBaseDeclarationSlots,
+		AppArmorHeader:       personalFilesConnectedPlugAppArmor,
+		ValidatePath:         ValidatePersonalFilesPath,
+	}))
 }
@@ -0,0 +1,45 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+// snap-control is an empty interface with no actual apparmor/seccomp
+// rules. The slot is provided by an application snap and allows the snaps
+// with a connected plug (via explicit check for snap-control connection
+// done by hookstate) to control its services with "snapctl restart
+// --other". It auto-connects only between snaps of the same publisher.
+const snapControlSummary = `allows control via snapctl over the services of the slot snap`
+
+const snapControlBaseDeclarationSlots = `
+  snap-control:
+    allow-installation:
+      slot-snap-type:
+        - app
+    allow-auto-connection:
+      plug-publisher-id:
+        - $SLOT_PUBLISHER_ID
+`
+
+func init() {
+	registerIface(&commonInterface{
+		name:                 "snap-control",
+		summary:              snapControlSummary,
+		baseDeclarationSlots: snapControlBaseDeclarationSlots,
+	})
+}
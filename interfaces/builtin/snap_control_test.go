@@ -0,0 +1,89 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type SnapControlInterfaceSuite struct {
+	iface    interfaces.Interface
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&SnapControlInterfaceSuite{
+	iface: builtin.MustInterface("snap-control"),
+})
+
+func (s *SnapControlInterfaceSuite) SetUpTest(c *C) {
+	consumingSnapInfo := snaptest.MockInfo(c, `
+name: consumer
+version: 0
+apps:
+  app:
+    command: foo
+    plugs: [snap-control]
+`, nil)
+	providerSnapInfo := snaptest.MockInfo(c, `
+name: provider
+version: 0
+apps:
+  svc:
+    command: foo
+    daemon: simple
+    slots: [snap-control]
+`, nil)
+	s.slotInfo = providerSnapInfo.Slots["snap-control"]
+	s.slot = interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+	s.plugInfo = consumingSnapInfo.Plugs["snap-control"]
+	s.plug = interfaces.NewConnectedPlug(s.plugInfo, nil, nil)
+}
+
+func (s *SnapControlInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "snap-control")
+}
+
+func (s *SnapControlInterfaceSuite) TestUsedSecuritySystems(c *C) {
+	// connected plugs have nil security snippet for apparmor and seccomp
+	apparmorSpec := &apparmor.Specification{}
+	err := apparmorSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(apparmorSpec.SecurityTags(), IsNil)
+	c.Assert(apparmorSpec.Snippets(), HasLen, 0)
+
+	seccompSpec := &seccomp.Specification{}
+	c.Assert(seccompSpec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(seccompSpec.Snippets(), HasLen, 0)
+}
+
+func (s *SnapControlInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
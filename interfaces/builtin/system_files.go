@@ -19,11 +19,6 @@
 
 package builtin
 
-import (
-	"fmt"
-	"strings"
-)
-
 const systemFilesSummary = `allows access to system files or directories`
 
 const systemFilesBaseDeclarationPlugs = `
@@ -45,34 +40,15 @@ const systemFilesConnectedPlugAppArmor = `
 # This is restricted because it gives file access to arbitrary locations.
 `
 
-type systemFilesInterface struct {
-	commonFilesInterface
-}
-
-func validateSinglePathSystem(np string) error {
-	if !strings.HasPrefix(np, "/") {
-		return fmt.Errorf(`%q must start with "/"`, np)
-	}
-	if strings.Contains(np, "$HOME") {
-		return fmt.Errorf(`$HOME cannot be used in %q`, np)
-	}
-
-	return nil
-}
-
 func init() {
-	registerIface(&systemFilesInterface{
-		commonFilesInterface{
-			commonInterface: commonInterface{
-				name:                 "system-files",
-				summary:              systemFilesSummary,
-				implicitOnCore:       true,
-				implicitOnClassic:    true,
-				baseDeclarationPlugs: systemFilesBaseDeclarationPlugs,
-				baseDeclarationSlots: systemFilesBaseDeclarationSlots,
-			},
-			apparmorHeader:    systemFilesConnectedPlugAppArmor,
-			extraPathValidate: validateSinglePathSystem,
-		},
-	})
+	registerIface(NewFilesInterface(&FilesInterfaceOptions{
+		Name:                 "system-files",
+		Summary:              systemFilesSummary,
+		ImplicitOnCore:       true,
+		ImplicitOnClassic:    true,
+		BaseDeclarationPlugs: systemFilesBaseDeclarationPlugs,
+		BaseDeclarationSlots: systemFilesBaseDeclarationSlots,
+		AppArmorHeader:       systemFilesConnectedPlugAppArmor,
+		ValidatePath:         ValidateSystemFilesPath,
+	}))
 }
@@ -0,0 +1,55 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+const systemFilesReadOnlySummary = `allows read-only access to system files or directories`
+
+const systemFilesReadOnlyBaseDeclarationPlugs = `
+  system-files-read-only:
+    allow-installation: false
+    deny-auto-connection: true
+`
+
+const systemFilesReadOnlyBaseDeclarationSlots = `
+  system-files-read-only:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+const systemFilesReadOnlyConnectedPlugAppArmor = `
+# Description: Can read specific system files or directories.
+# This is restricted because it gives read access to arbitrary locations.
+`
+
+func init() {
+	registerIface(NewFilesInterface(&FilesInterfaceOptions{
+		Name:                 "system-files-read-only",
+		Summary:              systemFilesReadOnlySummary,
+		ImplicitOnCore:       true,
+		ImplicitOnClassic:    true,
+		BaseDeclarationPlugs: systemFilesReadOnlyBaseDeclarationPlugs,
+		BaseDeclarationSlots: systemFilesReadOnlyBaseDeclarationSlots,
+		AppArmorHeader:       systemFilesReadOnlyConnectedPlugAppArmor,
+		ValidatePath:         ValidateSystemFilesPath,
+		ReadOnly:             true,
+	}))
+}
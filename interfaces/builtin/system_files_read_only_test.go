@@ -0,0 +1,140 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type systemFilesReadOnlyInterfaceSuite struct {
+	iface    interfaces.Interface
+	slot     *interfaces.ConnectedSlot
+	slotInfo *snap.SlotInfo
+	plug     *interfaces.ConnectedPlug
+	plugInfo *snap.PlugInfo
+}
+
+var _ = Suite(&systemFilesReadOnlyInterfaceSuite{
+	iface: builtin.MustInterface("system-files-read-only"),
+})
+
+func (s *systemFilesReadOnlyInterfaceSuite) SetUpTest(c *C) {
+	const mockPlugSnapInfo = `name: other
+version: 1.0
+plugs:
+ system-files-read-only:
+  read: [/etc/read-dir, /etc/read-file]
+apps:
+ app:
+  command: foo
+  plugs: [system-files-read-only]
+`
+	s.slotInfo = &snap.SlotInfo{
+		Snap:      &snap.Info{SuggestedName: "core", SnapType: snap.TypeOS},
+		Name:      "system-files-read-only",
+		Interface: "system-files-read-only",
+	}
+	s.slot = interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+	plugSnap := snaptest.MockInfo(c, mockPlugSnapInfo, nil)
+	s.plugInfo = plugSnap.Plugs["system-files-read-only"]
+	s.plug = interfaces.NewConnectedPlug(s.plugInfo, nil, nil)
+}
+
+func (s *systemFilesReadOnlyInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "system-files-read-only")
+}
+
+func (s *systemFilesReadOnlyInterfaceSuite) TestConnectedPlugAppArmor(c *C) {
+	apparmorSpec := &apparmor.Specification{}
+	err := apparmorSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(apparmorSpec.SecurityTags(), DeepEquals, []string{"snap.other.app"})
+	c.Check(apparmorSpec.SnippetForTag("snap.other.app"), Equals, `
+# Description: Can read specific system files or directories.
+# This is restricted because it gives read access to arbitrary locations.
+"/etc/read-dir{,/,/**}" rk,
+"/etc/read-file{,/,/**}" rk,
+`)
+}
+
+func (s *systemFilesReadOnlyInterfaceSuite) TestConnectedPlugAppArmorIgnoresWrite(c *C) {
+	// the plug is refused at installation, make sure write access is not
+	// granted even if the attribute made it into the connection
+	plug := interfaces.NewConnectedPlug(s.plugInfo, nil, map[string]interface{}{
+		"read":  []interface{}{"/etc/read-dir"},
+		"write": []interface{}{"/etc/write-dir"},
+	})
+	apparmorSpec := &apparmor.Specification{}
+	err := apparmorSpec.AddConnectedPlug(s.iface, plug, s.slot)
+	c.Assert(err, IsNil)
+	snippet := apparmorSpec.SnippetForTag("snap.other.app")
+	c.Check(snippet, testutil.Contains, `"/etc/read-dir{,/,/**}" rk,`)
+	c.Check(snippet, Not(testutil.Contains), "write-dir")
+}
+
+func (s *systemFilesReadOnlyInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+}
+
+func (s *systemFilesReadOnlyInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+}
+
+func (s *systemFilesReadOnlyInterfaceSuite) TestSanitizePlugUnhappy(c *C) {
+	const mockSnapYaml = `name: system-files-plug-snap
+version: 1.0
+plugs:
+ system-files-read-only:
+  $t
+`
+	errPrefix := `cannot add system-files-read-only plug: `
+	var testCases = []struct {
+		inp    string
+		errStr string
+	}{
+		{`write: [ "/etc/dir1" ]`, `"write" attribute is not supported, access is read-only`},
+		{`read: [ "/etc/file1" ]
+  write: [ "/etc/dir1" ]`, `"write" attribute is not supported, access is read-only`},
+		{`foo: bar`, `needs valid "read" attribute`},
+		{`read: [ "$HOME/foo" ]`, `"\$HOME/foo" must start with "/"`},
+		{`read: [ "/home/$HOME/foo" ]`, `\$HOME cannot be used in "/home/\$HOME/foo"`},
+	}
+
+	for _, t := range testCases {
+		yml := strings.Replace(mockSnapYaml, "$t", t.inp, -1)
+		info := snaptest.MockInfo(c, yml, nil)
+		plug := info.Plugs["system-files-read-only"]
+
+		c.Check(interfaces.BeforePreparePlug(s.iface, plug), ErrorMatches, errPrefix+t.errStr, Commentf("unexpected error for %q", t.inp))
+	}
+}
+
+func (s *systemFilesReadOnlyInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -385,6 +385,30 @@ plugs:
 	c.Check(err, NotNil)
 }
 
+func (s *baseDeclSuite) TestAutoConnectionSnapControl(c *C) {
+	// random snaps cannot connect with snap-control
+	cand := s.connectCand(c, "snap-control", "", "")
+	_, err := cand.CheckAutoConnect()
+	c.Check(err, NotNil)
+
+	slotDecl1 := s.mockSnapDecl(c, "slot-snap", "slot-snap-id", "pub1", "")
+	plugDecl1 := s.mockSnapDecl(c, "plug-snap", "plug-snap-id", "pub1", "")
+	plugDecl2 := s.mockSnapDecl(c, "plug-snap", "plug-snap-id", "pub2", "")
+
+	// same publisher
+	cand.SlotSnapDeclaration = slotDecl1
+	cand.PlugSnapDeclaration = plugDecl1
+	arity, err := cand.CheckAutoConnect()
+	c.Check(err, IsNil)
+	c.Check(arity.SlotsPerPlugAny(), Equals, false)
+
+	// different publisher
+	cand.SlotSnapDeclaration = slotDecl1
+	cand.PlugSnapDeclaration = plugDecl2
+	_, err = cand.CheckAutoConnect()
+	c.Check(err, NotNil)
+}
+
 func (s *baseDeclSuite) TestAutoConnectionSharedMemory(c *C) {
 	// random snaps cannot connect with shared-memory
 	// (Sanitize* will now also block this)
@@ -843,6 +867,7 @@ var (
 		"scsi-generic":              {"core"},
 		"sd-control":                {"core"},
 		"serial-port":               {"core", "gadget"},
+		"snap-control":              {"app"},
 		"spi":                       {"core", "gadget"},
 		"steam-support":             {"core"},
 		"storage-framework-service": {"app"},
@@ -1021,6 +1046,7 @@ func (s *baseDeclSuite) TestPlugInstallation(c *C) {
 		"snapd-control":          true,
 		"steam-support":          true,
 		"system-files":           true,
+		"system-files-read-only": true,
 		"tee":                    true,
 		"uinput":                 true,
 		"unity8":                 true,
@@ -1306,6 +1332,7 @@ func (s *baseDeclSuite) TestValidity(c *C) {
 		"snapd-control":          true,
 		"steam-support":          true,
 		"system-files":           true,
+		"system-files-read-only": true,
 		"tee":                    true,
 		"udisks2":                true,
 		"uinput":                 true,
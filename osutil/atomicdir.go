@@ -0,0 +1,178 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package osutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+var (
+	osRename       = os.Rename
+	renameExchange = sysRenameExchange
+)
+
+// AtomicReplaceDir replaces the directory targetDir with a new directory
+// populated by build. The new directory is staged next to targetDir, thus on
+// the same filesystem, as targetDir.new~, and the staging directory is passed
+// to build. Once build succeeded the staged tree is synced to disk and
+// swapped with targetDir, after which the displaced previous directory is
+// removed. If targetDir does not exist yet, the staged tree is simply renamed
+// into place.
+//
+// The directories are swapped with renameat2(2) RENAME_EXCHANGE, such that
+// at any point in time either the complete previous or the complete new tree
+// is found at targetDir. When the kernel or the filesystem do not support
+// RENAME_EXCHANGE, the previous directory is first renamed to targetDir.old~
+// and the new one is then renamed into place. A crash between the two renames
+// leaves no directory at targetDir, in which case the previous directory is
+// restored by the next call to AtomicReplaceDir for the same target.
+//
+// Callers must serialize calls for the same targetDir.
+func AtomicReplaceDir(targetDir string, build func(stagingDir string) error) (err error) {
+	targetDir = filepath.Clean(targetDir)
+	stagingDir := targetDir + ".new~"
+	displacedDir := targetDir + ".old~"
+
+	if err := recoverReplaceDir(targetDir, displacedDir); err != nil {
+		return err
+	}
+	// a leftover staging directory is either incomplete or was
+	// already displaced
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(targetDir), 0755); err != nil {
+		return err
+	}
+	if err := os.Mkdir(stagingDir, 0755); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(stagingDir)
+		}
+	}()
+
+	if err := build(stagingDir); err != nil {
+		return err
+	}
+	if !snapdUnsafeIO {
+		if err := syncTree(stagingDir); err != nil {
+			return err
+		}
+	}
+
+	exists, isDir, err := DirExists(targetDir)
+	if err != nil {
+		return err
+	}
+	if exists && !isDir {
+		return &os.PathError{Op: "replace", Path: targetDir, Err: syscall.ENOTDIR}
+	}
+	if !exists {
+		return atomicRenameWith(osRename, stagingDir, targetDir)
+	}
+
+	err = renameExchange(stagingDir, targetDir)
+	switch {
+	case err == nil:
+		// the staging directory now holds the previous tree
+		if err := syncDir(filepath.Dir(targetDir)); err != nil {
+			return err
+		}
+		return os.RemoveAll(stagingDir)
+	case errors.Is(err, syscall.ENOSYS) || errors.Is(err, syscall.EINVAL):
+		// not supported by the kernel or the filesystem
+	default:
+		return err
+	}
+
+	if err := atomicRenameWith(osRename, targetDir, displacedDir); err != nil {
+		return err
+	}
+	if err := atomicRenameWith(osRename, stagingDir, targetDir); err != nil {
+		// restore the previous tree, if that fails too it is
+		// restored by the next call
+		osRename(displacedDir, targetDir)
+		return err
+	}
+	return os.RemoveAll(displacedDir)
+}
+
+// recoverReplaceDir cleans up after an interrupted AtomicReplaceDir, falling
+// back to the previous tree if the new tree was not renamed into place.
+func recoverReplaceDir(targetDir, displacedDir string) error {
+	if exists, _, err := DirExists(displacedDir); err != nil || !exists {
+		return err
+	}
+	exists, _, err := DirExists(targetDir)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return os.RemoveAll(displacedDir)
+	}
+	return atomicRenameWith(osRename, displacedDir, targetDir)
+}
+
+// atomicRenameWith renames oldName to newName with rename and syncs the
+// parent directory, both names must be in the same directory.
+func atomicRenameWith(rename func(oldName, newName string) error, oldName, newName string) error {
+	if err := rename(oldName, newName); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(newName))
+}
+
+func syncDir(dir string) error {
+	if snapdUnsafeIO {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// syncTree syncs all files and directories under root to disk.
+func syncTree(root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			// symlinks and special files are synced along
+			// with their directory
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return f.Sync()
+	})
+}
@@ -0,0 +1,270 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package osutil_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type atomicReplaceDirSuite struct {
+	testutil.BaseTest
+
+	target string
+}
+
+var _ = Suite(&atomicReplaceDirSuite{})
+
+func (s *atomicReplaceDirSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.target = filepath.Join(c.MkDir(), "parent", "target")
+}
+
+func populateTree(content string) func(string) error {
+	return func(dir string) error {
+		if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "sub", "file"), []byte(content), 0644); err != nil {
+			return err
+		}
+		return os.Symlink("sub/file", filepath.Join(dir, "link"))
+	}
+}
+
+func (s *atomicReplaceDirSuite) checkContent(c *C, content string) {
+	c.Check(filepath.Join(s.target, "sub", "file"), testutil.FileEquals, content)
+	c.Check(filepath.Join(s.target, "link"), testutil.FileEquals, content)
+	// no leftovers
+	c.Check(s.target+".new~", testutil.FileAbsent)
+	c.Check(s.target+".old~", testutil.FileAbsent)
+}
+
+func (s *atomicReplaceDirSuite) TestNewTarget(c *C) {
+	// exercise syncing too
+	s.AddCleanup(osutil.SetUnsafeIO(false))
+
+	var staging string
+	err := osutil.AtomicReplaceDir(s.target, func(dir string) error {
+		staging = dir
+		return populateTree("new")(dir)
+	})
+	c.Assert(err, IsNil)
+	c.Check(staging, Equals, s.target+".new~")
+	s.checkContent(c, "new")
+}
+
+func (s *atomicReplaceDirSuite) TestReplace(c *C) {
+	s.AddCleanup(osutil.SetUnsafeIO(false))
+
+	c.Assert(osutil.AtomicReplaceDir(s.target, populateTree("old")), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(s.target, "stale"), nil, 0644), IsNil)
+
+	err := osutil.AtomicReplaceDir(s.target, func(dir string) error {
+		// the previous tree is still in place
+		c.Check(filepath.Join(s.target, "sub", "file"), testutil.FileEquals, "old")
+		return populateTree("new")(dir)
+	})
+	c.Assert(err, IsNil)
+	s.checkContent(c, "new")
+	c.Check(filepath.Join(s.target, "stale"), testutil.FileAbsent)
+}
+
+func (s *atomicReplaceDirSuite) TestReplaceFallback(c *C) {
+	c.Assert(osutil.AtomicReplaceDir(s.target, populateTree("old")), IsNil)
+
+	for _, errno := range []syscall.Errno{syscall.ENOSYS, syscall.EINVAL} {
+		exchangeCalls := 0
+		restore := osutil.MockRenameExchange(func(oldName, newName string) error {
+			exchangeCalls++
+			c.Check(oldName, Equals, s.target+".new~")
+			c.Check(newName, Equals, s.target)
+			return &os.LinkError{Op: "renameat2", Old: oldName, New: newName, Err: errno}
+		})
+
+		err := osutil.AtomicReplaceDir(s.target, populateTree(errno.Error()))
+		restore()
+		c.Assert(err, IsNil)
+		c.Check(exchangeCalls, Equals, 1)
+		s.checkContent(c, errno.Error())
+	}
+}
+
+func (s *atomicReplaceDirSuite) TestReplaceExchangeError(c *C) {
+	c.Assert(osutil.AtomicReplaceDir(s.target, populateTree("old")), IsNil)
+
+	s.AddCleanup(osutil.MockRenameExchange(func(oldName, newName string) error {
+		return &os.LinkError{Op: "renameat2", Old: oldName, New: newName, Err: syscall.EPERM}
+	}))
+
+	err := osutil.AtomicReplaceDir(s.target, populateTree("new"))
+	c.Assert(err, ErrorMatches, `renameat2 .*/target.new~ .*/target: operation not permitted`)
+	s.checkContent(c, "old")
+}
+
+func (s *atomicReplaceDirSuite) TestBuildError(c *C) {
+	c.Assert(osutil.AtomicReplaceDir(s.target, populateTree("old")), IsNil)
+
+	err := osutil.AtomicReplaceDir(s.target, func(dir string) error {
+		if err := populateTree("new")(dir); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	})
+	c.Assert(err, ErrorMatches, "boom")
+	s.checkContent(c, "old")
+}
+
+func (s *atomicReplaceDirSuite) TestTargetNotADirectory(c *C) {
+	c.Assert(os.MkdirAll(filepath.Dir(s.target), 0755), IsNil)
+	c.Assert(os.WriteFile(s.target, nil, 0644), IsNil)
+
+	err := osutil.AtomicReplaceDir(s.target, populateTree("new"))
+	c.Assert(err, ErrorMatches, `replace .*/target: not a directory`)
+	c.Check(s.target+".new~", testutil.FileAbsent)
+}
+
+// crash simulates a crash by panicking, deferred cleanups still run but
+// observe no error, like after a reboot nothing gets cleaned up.
+type crash struct{}
+
+func (s *atomicReplaceDirSuite) replaceAndCrash(c *C, content string) {
+	defer func() {
+		c.Assert(recover(), Equals, crash{})
+	}()
+	osutil.AtomicReplaceDir(s.target, populateTree(content))
+	c.Fatalf("AtomicReplaceDir did not crash")
+}
+
+func (s *atomicReplaceDirSuite) TestCrashBeforeSwap(c *C) {
+	c.Assert(osutil.AtomicReplaceDir(s.target, populateTree("old")), IsNil)
+
+	restore := osutil.MockRenameExchange(func(oldName, newName string) error {
+		panic(crash{})
+	})
+	s.replaceAndCrash(c, "new")
+	restore()
+
+	// the previous tree is intact, the staging tree is left behind
+	c.Check(filepath.Join(s.target, "sub", "file"), testutil.FileEquals, "old")
+	c.Check(filepath.Join(s.target+".new~", "sub", "file"), testutil.FileEquals, "new")
+
+	// and the leftover is discarded by the next replacement
+	c.Assert(osutil.AtomicReplaceDir(s.target, populateTree("newer")), IsNil)
+	s.checkContent(c, "newer")
+}
+
+func (s *atomicReplaceDirSuite) TestCrashAfterSwap(c *C) {
+	c.Assert(osutil.AtomicReplaceDir(s.target, populateTree("old")), IsNil)
+
+	restore := osutil.MockRenameExchange(func(oldName, newName string) error {
+		// pretend that the swap happened
+		c.Assert(os.Rename(newName, newName+".tmp"), IsNil)
+		c.Assert(os.Rename(oldName, newName), IsNil)
+		c.Assert(os.Rename(newName+".tmp", oldName), IsNil)
+		panic(crash{})
+	})
+	s.replaceAndCrash(c, "new")
+	restore()
+
+	// the new tree is in place, the previous tree is left behind
+	c.Check(filepath.Join(s.target, "sub", "file"), testutil.FileEquals, "new")
+	c.Check(filepath.Join(s.target+".new~", "sub", "file"), testutil.FileEquals, "old")
+
+	err := osutil.AtomicReplaceDir(s.target, func(dir string) error {
+		c.Check(filepath.Join(s.target, "sub", "file"), testutil.FileEquals, "new")
+		return populateTree("newer")(dir)
+	})
+	c.Assert(err, IsNil)
+	s.checkContent(c, "newer")
+}
+
+func (s *atomicReplaceDirSuite) TestCrashBetweenFallbackRenames(c *C) {
+	c.Assert(osutil.AtomicReplaceDir(s.target, populateTree("old")), IsNil)
+
+	s.AddCleanup(osutil.MockRenameExchange(func(oldName, newName string) error {
+		return &os.LinkError{Op: "renameat2", Old: oldName, New: newName, Err: syscall.EINVAL}
+	}))
+	renameCalls := 0
+	restore := osutil.MockOsRename(func(oldName, newName string) error {
+		renameCalls++
+		if renameCalls == 2 {
+			panic(crash{})
+		}
+		return os.Rename(oldName, newName)
+	})
+	s.replaceAndCrash(c, "new")
+	restore()
+	c.Check(renameCalls, Equals, 2)
+
+	// there is no tree in place, but the previous one is kept aside
+	c.Check(s.target, testutil.FileAbsent)
+	c.Check(filepath.Join(s.target+".old~", "sub", "file"), testutil.FileEquals, "old")
+	c.Check(filepath.Join(s.target+".new~", "sub", "file"), testutil.FileEquals, "new")
+
+	// the next replacement restores the previous tree first
+	err := osutil.AtomicReplaceDir(s.target, func(dir string) error {
+		c.Check(filepath.Join(s.target, "sub", "file"), testutil.FileEquals, "old")
+		c.Check(s.target+".old~", testutil.FileAbsent)
+		return populateTree("newer")(dir)
+	})
+	c.Assert(err, IsNil)
+	s.checkContent(c, "newer")
+}
+
+func (s *atomicReplaceDirSuite) TestCrashAfterFallbackRenames(c *C) {
+	c.Assert(osutil.AtomicReplaceDir(s.target, populateTree("old")), IsNil)
+
+	s.AddCleanup(osutil.MockRenameExchange(func(oldName, newName string) error {
+		return &os.LinkError{Op: "renameat2", Old: oldName, New: newName, Err: syscall.ENOSYS}
+	}))
+	renameCalls := 0
+	restore := osutil.MockOsRename(func(oldName, newName string) error {
+		renameCalls++
+		if err := os.Rename(oldName, newName); err != nil {
+			return err
+		}
+		if renameCalls == 2 {
+			panic(crash{})
+		}
+		return nil
+	})
+	s.replaceAndCrash(c, "new")
+	restore()
+
+	// the new tree is in place, the previous one is left behind
+	c.Check(filepath.Join(s.target, "sub", "file"), testutil.FileEquals, "new")
+	c.Check(filepath.Join(s.target+".old~", "sub", "file"), testutil.FileEquals, "old")
+
+	err := osutil.AtomicReplaceDir(s.target, func(dir string) error {
+		c.Check(filepath.Join(s.target, "sub", "file"), testutil.FileEquals, "new")
+		c.Check(s.target+".old~", testutil.FileAbsent)
+		return populateTree("newer")(dir)
+	})
+	c.Assert(err, IsNil)
+	s.checkContent(c, "newer")
+}
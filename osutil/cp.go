@@ -21,6 +21,7 @@ package osutil
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -61,11 +62,25 @@ func doOpenFile(name string, flag int, perm os.FileMode) (fileish, error) {
 
 // CopyFile copies src to dst
 func CopyFile(src, dst string, flags CopyFlag) (err error) {
+	return CopyFileWithContext(context.Background(), src, dst, flags)
+}
+
+// CopyFileWithContext copies src to dst like CopyFile, giving up when ctx
+// is cancelled. Copies with CopyFlagPreserveAll are stopped midway, in which
+// case dst may be left partially copied, other copies are only checked for
+// cancellation before they start.
+func CopyFileWithContext(ctx context.Context, src, dst string, flags CopyFlag) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if flags&CopyFlagPreserveAll != 0 {
 		// Our native copy code does not preserve all attributes
 		// (yet). If the user needs this functionality we just
 		// fallback to use the system's "cp" binary to do the copy.
-		if err := runCpPreserveAll(src, dst, "copy all"); err != nil {
+		if err := runCpPreserveAll(ctx, src, dst, "copy all"); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			return err
 		}
 		if flags&CopyFlagSync != 0 {
@@ -183,14 +198,14 @@ func runSync(args ...string) error {
 	return runCmd(exec.Command("sync", args...), "sync")
 }
 
-func runCpPreserveAll(path, dest, errdesc string) error {
-	return runCmd(exec.Command("cp", "-av", path, dest), errdesc)
+func runCpPreserveAll(ctx context.Context, path, dest, errdesc string) error {
+	return runCmd(exec.CommandContext(ctx, "cp", "-av", path, dest), errdesc)
 }
 
 // CopySpecialFile is used to copy all the things that are not files
 // (like device nodes, named pipes etc)
 func CopySpecialFile(path, dest string) error {
-	if err := runCpPreserveAll(path, dest, "copy device node"); err != nil {
+	if err := runCpPreserveAll(context.Background(), path, dest, "copy device node"); err != nil {
 		return err
 	}
 	return runSync(filepath.Dir(dest))
@@ -20,6 +20,7 @@
 package osutil_test
 
 import (
+	"context"
 	"errors"
 	"os"
 	"os/exec"
@@ -297,6 +298,37 @@ func (s *cpSuite) TestCopyPreserveAllSyncSyncFailure(c *C) {
 	})
 }
 
+func (s *cpSuite) TestCopyFileWithContextCancelled(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := osutil.CopyFileWithContext(ctx, s.f1, s.f2, osutil.CopyFlagDefault)
+	c.Assert(err, Equals, context.Canceled)
+	c.Check(s.f2, testutil.FileAbsent)
+}
+
+func (s *cpSuite) TestCopyFileWithContextPreserveAllCancelledMidway(c *C) {
+	dir := c.MkDir()
+	mocked := testutil.MockCommand(c, "cp", "exec sleep 10").Also("sync", "")
+	defer mocked.Restore()
+
+	src := filepath.Join(dir, "meep")
+	dst := filepath.Join(dir, "copied-meep")
+
+	err := os.WriteFile(src, []byte(nil), 0644)
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	err = osutil.CopyFileWithContext(ctx, src, dst, osutil.CopyFlagPreserveAll|osutil.CopyFlagSync)
+	c.Assert(err, Equals, context.Canceled)
+	// no sync after the copy was stopped
+	c.Check(mocked.Calls(), DeepEquals, [][]string{
+		{"cp", "-av", src, dst},
+	})
+}
+
 func (s *cpSuite) TestAtomicWriteFileCopySimple(c *C) {
 	err := osutil.AtomicWriteFileCopy(s.f2, s.f1, 0)
 	c.Assert(err, IsNil)
@@ -26,3 +26,11 @@ var (
 
 	FilesystemTypeForPartition = filesystemTypeForPartition
 )
+
+func MockNvmeSmartLog(f func(devNode string) ([]byte, error)) (restore func()) {
+	old := nvmeSmartLog
+	nvmeSmartLog = f
+	return func() {
+		nvmeSmartLog = old
+	}
+}
@@ -0,0 +1,245 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package disks
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HealthOK is the status of media with no sign of wear out.
+	HealthOK = "ok"
+	// HealthWarning is the status of media that is close to its end of
+	// life.
+	HealthWarning = "warning"
+	// HealthCritical is the status of media that reached its end of life
+	// or reports a critical condition.
+	HealthCritical = "critical"
+	// HealthUnavailable is the status of media for which no health
+	// information could be obtained.
+	HealthUnavailable = "unavailable"
+)
+
+// MediaHealth describes the health of the storage media of a disk.
+type MediaHealth struct {
+	// Device is the kernel device node of the disk, e.g. /dev/mmcblk0.
+	Device string `json:"device"`
+	// Media is the kind of media, "emmc" or "nvme", empty when unknown.
+	Media string `json:"media,omitempty"`
+	// Status is one of HealthOK, HealthWarning, HealthCritical or
+	// HealthUnavailable.
+	Status string `json:"status"`
+	// Reason explains why the health information is unavailable.
+	Reason string `json:"reason,omitempty"`
+
+	EMMC *EMMCHealth `json:"emmc,omitempty"`
+	NVMe *NVMeHealth `json:"nvme,omitempty"`
+}
+
+// EMMCHealth carries the health report of an eMMC device, as defined by
+// JEDEC JESD84-B51.
+type EMMCHealth struct {
+	// LifeTimeEstimateA and LifeTimeEstimateB are the estimated used life
+	// time of the type A and type B memory of the device in steps of 10%,
+	// 1 means 0-10% used, 10 means 90-100% used, 11 means the estimated
+	// life time was exceeded, 0 means undefined.
+	LifeTimeEstimateA int `json:"life-time-estimate-a"`
+	LifeTimeEstimateB int `json:"life-time-estimate-b"`
+	// PreEOL is the consumption of reserved blocks, 1 is normal, 2 is
+	// warning (80% consumed), 3 is urgent, 0 means undefined.
+	PreEOL int `json:"pre-eol"`
+}
+
+// NVMeHealth carries the relevant parts of the SMART / Health Information
+// log of an NVMe device.
+type NVMeHealth struct {
+	// CriticalWarning is the bit field of critical warnings of the
+	// controller, any bit set indicates a critical condition.
+	CriticalWarning uint8 `json:"critical-warning"`
+	// Temperature is the composite temperature in Kelvin.
+	Temperature int `json:"temperature"`
+	// AvailableSpare is the remaining spare capacity in percent.
+	AvailableSpare int `json:"available-spare"`
+	// AvailableSpareThreshold is the threshold of AvailableSpare under
+	// which a critical warning is raised.
+	AvailableSpareThreshold int `json:"available-spare-threshold"`
+	// PercentageUsed is the vendor estimate of the used life time of the
+	// device in percent, it can exceed 100.
+	PercentageUsed int `json:"percentage-used"`
+	// PowerOnHours is the number of hours the device was powered on.
+	PowerOnHours uint64 `json:"power-on-hours"`
+	// MediaErrors is the number of unrecovered data integrity errors.
+	MediaErrors uint64 `json:"media-errors"`
+}
+
+const (
+	emmcLifeTimeExceeded = 0x0b
+	emmcPreEOLWarning    = 2
+	emmcPreEOLUrgent     = 3
+
+	nvmeSmartLogSize = 512
+)
+
+// nvmeSmartLog returns the raw SMART / Health Information log page of the
+// NVMe device at the given device node.
+var nvmeSmartLog = nvmeSmartLogFromDevice
+
+// HealthOfDisk returns the health of the storage media of the given disk.
+// eMMC devices are inspected through sysfs, NVMe devices by reading their
+// SMART log. For other media or when the health information cannot be
+// obtained the status is HealthUnavailable.
+func HealthOfDisk(d Disk) *MediaHealth {
+	devNode := d.KernelDeviceNode()
+	devPath := d.KernelDevicePath()
+	name := filepath.Base(devPath)
+	switch {
+	case strings.HasPrefix(name, "mmcblk"):
+		return emmcHealth(devNode, devPath)
+	case strings.HasPrefix(name, "nvme"):
+		return nvmeHealth(devNode)
+	}
+	return &MediaHealth{
+		Device: devNode,
+		Status: HealthUnavailable,
+		Reason: "unsupported media",
+	}
+}
+
+func readSysfsHexValues(path string) ([]int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(content))
+	values := make([]int, 0, len(fields))
+	for _, f := range fields {
+		v, err := strconv.ParseUint(f, 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %v", filepath.Base(path), err)
+		}
+		values = append(values, int(v))
+	}
+	return values, nil
+}
+
+func emmcHealth(devNode, devPath string) *MediaHealth {
+	unavailable := func(format string, a ...interface{}) *MediaHealth {
+		return &MediaHealth{
+			Device: devNode,
+			Status: HealthUnavailable,
+			Reason: fmt.Sprintf(format, a...),
+		}
+	}
+
+	deviceDir := filepath.Join(devPath, "device")
+	mmcType, err := os.ReadFile(filepath.Join(deviceDir, "type"))
+	if err != nil {
+		return unavailable("cannot read MMC device type: %v", err)
+	}
+	if strings.TrimSpace(string(mmcType)) != "MMC" {
+		// SD cards and SDIO devices do not report their health
+		return unavailable("unsupported media")
+	}
+
+	lifeTime, err := readSysfsHexValues(filepath.Join(deviceDir, "life_time"))
+	if err != nil {
+		return unavailable("cannot read eMMC life time estimate: %v", err)
+	}
+	if len(lifeTime) != 2 {
+		return unavailable("cannot read eMMC life time estimate: unexpected content")
+	}
+	preEOL, err := readSysfsHexValues(filepath.Join(deviceDir, "pre_eol_info"))
+	if err != nil {
+		return unavailable("cannot read eMMC pre-EOL information: %v", err)
+	}
+	if len(preEOL) != 1 {
+		return unavailable("cannot read eMMC pre-EOL information: unexpected content")
+	}
+
+	health := &EMMCHealth{
+		LifeTimeEstimateA: lifeTime[0],
+		LifeTimeEstimateB: lifeTime[1],
+		PreEOL:            preEOL[0],
+	}
+	maxLifeTime := health.LifeTimeEstimateA
+	if health.LifeTimeEstimateB > maxLifeTime {
+		maxLifeTime = health.LifeTimeEstimateB
+	}
+	status := HealthOK
+	switch {
+	case health.PreEOL >= emmcPreEOLUrgent || maxLifeTime >= emmcLifeTimeExceeded:
+		status = HealthCritical
+	case health.PreEOL == emmcPreEOLWarning || maxLifeTime >= 9:
+		// more than 80% of the life time is used
+		status = HealthWarning
+	}
+	return &MediaHealth{
+		Device: devNode,
+		Media:  "emmc",
+		Status: status,
+		EMMC:   health,
+	}
+}
+
+func nvmeHealth(devNode string) *MediaHealth {
+	log, err := nvmeSmartLog(devNode)
+	if err == nil && len(log) < nvmeSmartLogSize {
+		err = fmt.Errorf("short log page of %d bytes", len(log))
+	}
+	if err != nil {
+		return &MediaHealth{
+			Device: devNode,
+			Media:  "nvme",
+			Status: HealthUnavailable,
+			Reason: fmt.Sprintf("cannot read NVMe SMART log: %v", err),
+		}
+	}
+
+	// see "SMART / Health Information" in the NVMe base specification,
+	// the 128-bit counters are little endian, only the low 64 bits are
+	// used
+	health := &NVMeHealth{
+		CriticalWarning:         log[0],
+		Temperature:             int(binary.LittleEndian.Uint16(log[1:3])),
+		AvailableSpare:          int(log[3]),
+		AvailableSpareThreshold: int(log[4]),
+		PercentageUsed:          int(log[5]),
+		PowerOnHours:            binary.LittleEndian.Uint64(log[128:136]),
+		MediaErrors:             binary.LittleEndian.Uint64(log[160:168]),
+	}
+	status := HealthOK
+	switch {
+	case health.CriticalWarning != 0 || health.PercentageUsed >= 100:
+		status = HealthCritical
+	case health.PercentageUsed >= 90:
+		status = HealthWarning
+	}
+	return &MediaHealth{
+		Device: devNode,
+		Media:  "nvme",
+		Status: status,
+		NVMe:   health,
+	}
+}
@@ -0,0 +1,223 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package disks_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil/disks"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type healthSuite struct {
+	testutil.BaseTest
+}
+
+var _ = Suite(&healthSuite{})
+
+func (s *healthSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+}
+
+// mockEMMC creates a canned sysfs tree of an MMC block device with the
+// given device attributes.
+func (s *healthSuite) mockEMMC(c *C, attrs map[string]string) disks.Disk {
+	devPath := filepath.Join(dirs.SysfsDir, "devices/platform/fe320000.mmc/mmc_host/mmc0/mmc0:0001/block/mmcblk0")
+	deviceDir := filepath.Join(devPath, "device")
+	c.Assert(os.MkdirAll(deviceDir, 0755), IsNil)
+	for name, content := range attrs {
+		c.Assert(os.WriteFile(filepath.Join(deviceDir, name), []byte(content), 0644), IsNil)
+	}
+	return &disks.MockDiskMapping{
+		DevNode: "/dev/mmcblk0",
+		DevPath: devPath,
+	}
+}
+
+func (s *healthSuite) TestEMMCHealth(c *C) {
+	for _, tc := range []struct {
+		lifeTimeA, lifeTimeB int
+		preEOL               int
+		status               string
+	}{
+		{0x01, 0x01, 0x01, disks.HealthOK},
+		{0x02, 0x05, 0x01, disks.HealthOK},
+		{0x09, 0x02, 0x01, disks.HealthWarning},
+		{0x03, 0x03, 0x02, disks.HealthWarning},
+		{0x0a, 0x0b, 0x01, disks.HealthCritical},
+		{0x03, 0x03, 0x03, disks.HealthCritical},
+	} {
+		c.Logf("tc: %v", tc)
+		d := s.mockEMMC(c, map[string]string{
+			"type":         "MMC\n",
+			"life_time":    fmt.Sprintf("0x%02x 0x%02x\n", tc.lifeTimeA, tc.lifeTimeB),
+			"pre_eol_info": fmt.Sprintf("0x%02x\n", tc.preEOL),
+		})
+
+		c.Check(disks.HealthOfDisk(d), DeepEquals, &disks.MediaHealth{
+			Device: "/dev/mmcblk0",
+			Media:  "emmc",
+			Status: tc.status,
+			EMMC: &disks.EMMCHealth{
+				LifeTimeEstimateA: tc.lifeTimeA,
+				LifeTimeEstimateB: tc.lifeTimeB,
+				PreEOL:            tc.preEOL,
+			},
+		})
+	}
+}
+
+func (s *healthSuite) TestEMMCHealthUnavailable(c *C) {
+	for _, tc := range []struct {
+		attrs  map[string]string
+		reason string
+	}{{
+		attrs:  map[string]string{"type": "SD\n"},
+		reason: "unsupported media",
+	}, {
+		attrs:  nil,
+		reason: "cannot read MMC device type: open .*/type: no such file or directory",
+	}, {
+		attrs:  map[string]string{"type": "MMC\n", "pre_eol_info": "0x01\n"},
+		reason: "cannot read eMMC life time estimate: open .*/life_time: no such file or directory",
+	}, {
+		attrs:  map[string]string{"type": "MMC\n", "life_time": "0x01\n", "pre_eol_info": "0x01\n"},
+		reason: "cannot read eMMC life time estimate: unexpected content",
+	}, {
+		attrs:  map[string]string{"type": "MMC\n", "life_time": "0x01 foo\n", "pre_eol_info": "0x01\n"},
+		reason: `cannot read eMMC life time estimate: cannot parse life_time: strconv.ParseUint: parsing "foo": invalid syntax`,
+	}, {
+		attrs:  map[string]string{"type": "MMC\n", "life_time": "0x01 0x01\n", "pre_eol_info": "\n"},
+		reason: "cannot read eMMC pre-EOL information: unexpected content",
+	}} {
+		// start from a clean tree
+		c.Assert(os.RemoveAll(dirs.SysfsDir), IsNil)
+		d := s.mockEMMC(c, tc.attrs)
+
+		health := disks.HealthOfDisk(d)
+		c.Check(health.Device, Equals, "/dev/mmcblk0")
+		c.Check(health.Status, Equals, disks.HealthUnavailable)
+		c.Check(health.Reason, Matches, tc.reason)
+		c.Check(health.EMMC, IsNil)
+	}
+}
+
+func mockSmartLog(criticalWarning uint8, spare, percentageUsed uint8, powerOnHours, mediaErrors uint64) []byte {
+	log := make([]byte, 512)
+	log[0] = criticalWarning
+	binary.LittleEndian.PutUint16(log[1:3], 310)
+	log[3] = spare
+	log[4] = 10
+	log[5] = percentageUsed
+	binary.LittleEndian.PutUint64(log[128:136], powerOnHours)
+	binary.LittleEndian.PutUint64(log[160:168], mediaErrors)
+	return log
+}
+
+func (s *healthSuite) TestNVMeHealth(c *C) {
+	d := &disks.MockDiskMapping{
+		DevNode: "/dev/nvme0n1",
+		DevPath: filepath.Join(dirs.SysfsDir, "devices/pci0000:00/0000:00:1d.0/0000:3d:00.0/nvme/nvme0/nvme0n1"),
+	}
+
+	for _, tc := range []struct {
+		criticalWarning uint8
+		spare           uint8
+		percentageUsed  uint8
+		status          string
+	}{
+		{0, 100, 3, disks.HealthOK},
+		{0, 80, 90, disks.HealthWarning},
+		{0, 80, 100, disks.HealthCritical},
+		{0x01, 5, 50, disks.HealthCritical},
+	} {
+		c.Logf("tc: %v", tc)
+		restore := disks.MockNvmeSmartLog(func(devNode string) ([]byte, error) {
+			c.Check(devNode, Equals, "/dev/nvme0n1")
+			return mockSmartLog(tc.criticalWarning, tc.spare, tc.percentageUsed, 1234, 2), nil
+		})
+		health := disks.HealthOfDisk(d)
+		restore()
+
+		c.Check(health, DeepEquals, &disks.MediaHealth{
+			Device: "/dev/nvme0n1",
+			Media:  "nvme",
+			Status: tc.status,
+			NVMe: &disks.NVMeHealth{
+				CriticalWarning:         tc.criticalWarning,
+				Temperature:             310,
+				AvailableSpare:          int(tc.spare),
+				AvailableSpareThreshold: 10,
+				PercentageUsed:          int(tc.percentageUsed),
+				PowerOnHours:            1234,
+				MediaErrors:             2,
+			},
+		})
+	}
+}
+
+func (s *healthSuite) TestNVMeHealthUnavailable(c *C) {
+	d := &disks.MockDiskMapping{
+		DevNode: "/dev/nvme0n1",
+		DevPath: filepath.Join(dirs.SysfsDir, "devices/pci0000:00/0000:00:1d.0/0000:3d:00.0/nvme/nvme0/nvme0n1"),
+	}
+
+	for _, tc := range []struct {
+		log    []byte
+		err    error
+		reason string
+	}{
+		{nil, fmt.Errorf("permission denied"), "cannot read NVMe SMART log: permission denied"},
+		{make([]byte, 64), nil, "cannot read NVMe SMART log: short log page of 64 bytes"},
+	} {
+		restore := disks.MockNvmeSmartLog(func(devNode string) ([]byte, error) {
+			return tc.log, tc.err
+		})
+		health := disks.HealthOfDisk(d)
+		restore()
+
+		c.Check(health, DeepEquals, &disks.MediaHealth{
+			Device: "/dev/nvme0n1",
+			Media:  "nvme",
+			Status: disks.HealthUnavailable,
+			Reason: tc.reason,
+		})
+	}
+}
+
+func (s *healthSuite) TestUnsupportedMedia(c *C) {
+	d := &disks.MockDiskMapping{
+		DevNode: "/dev/vda",
+		DevPath: filepath.Join(dirs.SysfsDir, "devices/pci0000:00/0000:00:03.0/virtio1/block/vda"),
+	}
+	c.Check(disks.HealthOfDisk(d), DeepEquals, &disks.MediaHealth{
+		Device: "/dev/vda",
+		Status: disks.HealthUnavailable,
+		Reason: "unsupported media",
+	})
+}
@@ -166,6 +166,22 @@ func GetUnsafeIO() bool {
 	return snapdUnsafeIO
 }
 
+func MockOsRename(f func(oldName, newName string) error) (restore func()) {
+	old := osRename
+	osRename = f
+	return func() {
+		osRename = old
+	}
+}
+
+func MockRenameExchange(f func(oldName, newName string) error) (restore func()) {
+	old := renameExchange
+	renameExchange = f
+	return func() {
+		renameExchange = old
+	}
+}
+
 func MockOsReadlink(f func(string) (string, error)) func() {
 	realOsReadlink := osReadlink
 	osReadlink = f
@@ -20,22 +20,34 @@
 package assertstate
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/asserts/sysdb"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 )
 
+var timeNow = time.Now
+
+// validationSetsRefreshInterval is how often tracked validation sets are
+// refreshed from the store if no auto-refresh did it in the meantime,
+// matching the default auto-refresh schedule of four times a day.
+var validationSetsRefreshInterval = 6 * time.Hour
+
 // AssertManager is responsible for the enforcement of assertions in
 // system states. It manipulates the observed system state to ensure
 // nothing in it violates existing assertions, or misses required
 // ones.
-type AssertManager struct{}
+type AssertManager struct {
+	state *state.State
+}
 
 // Manager returns a new assertion manager.
 func Manager(s *state.State, runner *state.TaskRunner) (*AssertManager, error) {
@@ -52,14 +64,59 @@ func Manager(s *state.State, runner *state.TaskRunner) (*AssertManager, error) {
 	ReplaceDB(s, db)
 	s.Unlock()
 
-	return &AssertManager{}, nil
+	return &AssertManager{state: s}, nil
 }
 
 // Ensure implements StateManager.Ensure.
 func (m *AssertManager) Ensure() error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	if err := m.ensureValidationSetsRefreshed(); err != nil {
+		logger.Noticef("cannot refresh validation set assertions: %v", err)
+	}
 	return nil
 }
 
+// ensureValidationSetsRefreshed refreshes the tracked validation sets
+// from the store when they were not refreshed for a full interval, for
+// instance because snap auto-refreshes are held.
+func (m *AssertManager) ensureValidationSetsRefreshed() error {
+	var seeded bool
+	if err := m.state.Get("seeded", &seeded); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	if !seeded {
+		return nil
+	}
+	vsets, err := ValidationSets(m.state)
+	if err != nil {
+		return err
+	}
+	if len(vsets) == 0 {
+		return nil
+	}
+
+	var lastRefresh time.Time
+	if err := m.state.Get("last-validation-sets-refresh", &lastRefresh); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	now := timeNow()
+	if lastRefresh.IsZero() {
+		// start counting from now, the regular auto-refresh takes care
+		// of the first refresh
+		m.state.Set("last-validation-sets-refresh", now)
+		return nil
+	}
+	if now.Sub(lastRefresh) < validationSetsRefreshInterval {
+		return nil
+	}
+	// do not retry at every ensure if the refresh fails
+	m.state.Set("last-validation-sets-refresh", now)
+
+	return RefreshValidationSetAssertions(m.state, 0, &RefreshAssertionsOptions{IsAutoRefresh: true})
+}
+
 type cachedDBKey struct{}
 
 // ReplaceDB replaces the assertion database used by the manager.
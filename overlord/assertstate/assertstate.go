@@ -377,6 +377,40 @@ func delayedCrossMgrInit() {
 	snapstate.EnforceValidationSets = ApplyEnforcedValidationSets
 	// hook helper for enforcing already existing validation set assertions
 	snapstate.EnforceLocalValidationSets = ApplyLocalEnforcedValidationSets
+	// hook helper for fetching the assertions of downloaded snaps
+	snapstate.FetchSnapFileAssertions = fetchSnapFileAssertions
+	// hook helper for pruning the assertions of discarded snap revisions
+	snapstate.PruneSnapRevisionAssertions = pruneSnapRevisionAssertions
+}
+
+// pruneSnapRevisionAssertions removes from the system assertion database
+// the snap-revision assertions for the given snap-id that are not for one
+// of the revisions to keep.
+func pruneSnapRevisionAssertions(st *state.State, snapID string, keep []snap.Revision) error {
+	db := cachedDB(st)
+	snapRevs, err := db.FindManyBySnapID(asserts.SnapRevisionType, snapID)
+	if errors.Is(err, &asserts.NotFoundError{}) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[snap.Revision]bool, len(keep))
+	for _, rev := range keep {
+		kept[rev] = true
+	}
+	for _, a := range snapRevs {
+		snapRev := a.(*asserts.SnapRevision)
+		if kept[snap.R(snapRev.SnapRevision())] {
+			continue
+		}
+		// predefined assertions cannot be removed
+		if err := db.Remove(snapRev.Ref()); err != nil && !errors.Is(err, &asserts.NotFoundError{}) {
+			return err
+		}
+	}
+	return nil
 }
 
 // AutoRefreshAssertions tries to refresh all assertions
@@ -456,13 +490,23 @@ func RefreshValidationSetAssertions(s *state.State, userID int, opts *RefreshAss
 		return nil
 	}
 
+	if opts.IsAutoRefresh {
+		s.Set("last-validation-sets-refresh", timeNow())
+	}
+
 	if err := bulkRefreshValidationSetAsserts(s, monitorModeSets, nil, userID, deviceCtx, opts); err != nil {
 		return err
 	}
 	if err := updateTracking(monitorModeSets); err != nil {
 		return err
 	}
+	if err := updateValidationSetsRefreshStatus(s, monitorModeSets, nil, timeNow()); err != nil {
+		return err
+	}
 
+	// latest sequence points found for the sets in enforce mode, used to
+	// report the ones which cannot be applied
+	fetched := make(map[string]int, len(enforceModeSets))
 	checkConflictsAndPresence := func(db *asserts.Database, bs asserts.Backstore) error {
 		vsets := snapasserts.NewValidationSets()
 		tmpDb := db.WithStackedBackstore(bs)
@@ -491,6 +535,7 @@ func RefreshValidationSetAssertions(s *state.State, userID int, opts *RefreshAss
 			if err := vsets.Add(vsass); err != nil {
 				return fmt.Errorf("internal error: cannot check validation sets conflicts: %v", err)
 			}
+			fetched[ValidationSetKey(vs.AccountID, vs.Name)] = vsass.Sequence()
 		}
 		if err := vsets.Conflict(); err != nil {
 			return err
@@ -512,21 +557,33 @@ func RefreshValidationSetAssertions(s *state.State, userID int, opts *RefreshAss
 	}
 
 	if err := bulkRefreshValidationSetAsserts(s, enforceModeSets, checkConflictsAndPresence, userID, deviceCtx, opts); err != nil {
-		if _, ok := err.(*snapasserts.ValidationSetsConflictError); ok {
-			logger.Noticef("cannot refresh to conflicting validation set assertions: %v", err)
-			return nil
+		var msg string
+		switch err.(type) {
+		case *snapasserts.ValidationSetsConflictError:
+			msg = "cannot refresh to conflicting validation set assertions"
+		case *snapasserts.ValidationSetsValidationError:
+			msg = "cannot refresh to validation set assertions that do not satisfy installed snaps"
+		default:
+			return err
 		}
-		if _, ok := err.(*snapasserts.ValidationSetsValidationError); ok {
-			logger.Noticef("cannot refresh to validation set assertions that do not satisfy installed snaps: %v", err)
-			return nil
+		logger.Noticef("%s: %v", msg, err)
+		// the new sequence points are not applied but this must not fail
+		// the refresh, record them as pending and warn about the
+		// constraints which cannot be satisfied instead
+		s.Warnf("%s: %v", msg, err)
+		pending := make(map[string]int, len(fetched))
+		for key, seq := range fetched {
+			if seq > enforceModeSets[key].Sequence() {
+				pending[key] = seq
+			}
 		}
-		return err
+		return updateValidationSetsRefreshStatus(s, enforceModeSets, pending, timeNow())
 	}
 	if err := updateTracking(enforceModeSets); err != nil {
 		return err
 	}
 
-	return nil
+	return updateValidationSetsRefreshStatus(s, enforceModeSets, nil, timeNow())
 }
 
 // ResolveOptions carries extra options for ValidationSetAssertionForMonitor.
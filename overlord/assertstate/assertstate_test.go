@@ -25,6 +25,9 @@ import (
 	"crypto"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"sort"
 	"strings"
 	"testing"
@@ -766,6 +769,64 @@ func (s *assertMgrSuite) TestValidateSnap(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *assertMgrSuite) TestFetchSnapFileAssertions(c *C) {
+	paths, digests := s.prereqSnapAssertions(c, 10)
+	snapPath := paths[10]
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	info := &snap.Info{
+		SideInfo: snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "snap-id-1",
+			Revision: snap.R(10),
+		},
+	}
+	as, err := assertstate.FetchSnapFileAssertions(s.state, snapPath, info, 0, s.trivialDeviceCtx)
+	c.Assert(err, IsNil)
+
+	// prerequisites first
+	c.Assert(as, Not(HasLen), 0)
+	types := make(map[string]bool)
+	for _, a := range as {
+		types[a.Type().Name] = true
+	}
+	c.Check(types, DeepEquals, map[string]bool{
+		"account":          true,
+		"account-key":      true,
+		"snap-declaration": true,
+		"snap-revision":    true,
+	})
+	snapRev, ok := as[len(as)-1].(*asserts.SnapRevision)
+	c.Assert(ok, Equals, true)
+	c.Check(snapRev.SnapSHA3_384(), Equals, digests[10])
+
+	// the system database is left untouched
+	_, err = assertstate.DB(s.state).Find(asserts.SnapRevisionType, map[string]string{
+		"snap-sha3-384": digests[10],
+	})
+	c.Check(errors.Is(err, &asserts.NotFoundError{}), Equals, true)
+}
+
+func (s *assertMgrSuite) TestFetchSnapFileAssertionsCrossCheckFails(c *C) {
+	paths, _ := s.prereqSnapAssertions(c, 10)
+	snapPath := paths[10]
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	info := &snap.Info{
+		SideInfo: snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "snap-id-1",
+			Revision: snap.R(12),
+		},
+	}
+	_, err := assertstate.FetchSnapFileAssertions(s.state, snapPath, info, 0, s.trivialDeviceCtx)
+	c.Assert(err, ErrorMatches, `snap "foo" does not have expected ID or revision according to assertions \(metadata is broken or tampered\): 12 / snap-id-1 != 10 / snap-id-1`)
+}
+
 func (s *assertMgrSuite) TestValidateSnapStoreNotFound(c *C) {
 	paths, digests := s.prereqSnapAssertions(c, 10)
 
@@ -2139,6 +2200,93 @@ func (s *assertMgrSuite) TestValidateRefreshesValidationOK(c *C) {
 	c.Check(validated, DeepEquals, []*snap.Info{fooRefresh, fooInstanceRefresh})
 }
 
+func (s *assertMgrSuite) TestValidateRefreshesSharesAssertionFetches(c *C) {
+	// a mock assertion service serving from storeSigning that counts
+	// the requests it gets
+	requests := make(map[string]int)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+
+		comps := strings.Split(strings.TrimPrefix(r.URL.Path, "/v2/assertions/"), "/")
+		assertType := asserts.Type(comps[0])
+		c.Assert(assertType, NotNil)
+		ref := &asserts.Ref{Type: assertType, PrimaryKey: comps[1:]}
+		a, err := ref.Resolve(s.storeSigning.Find)
+		if errors.Is(err, &asserts.NotFoundError{}) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(404)
+			w.Write([]byte(`{"error-list":[{"code":"not-found","message":"not found"}]}`))
+			return
+		}
+		c.Assert(err, IsNil)
+		w.Header().Set("Content-Type", asserts.MediaType)
+		w.Write(asserts.Encode(a))
+	}))
+	defer mockServer.Close()
+
+	mockServerURL, err := url.Parse(mockServer.URL)
+	c.Assert(err, IsNil)
+	sto := store.New(&store.Config{AssertionsBaseURL: mockServerURL}, nil)
+	deviceCtx := &snapstatetest.TrivialDeviceContext{
+		CtxStore: sto,
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapDeclFoo := s.snapDecl(c, "foo", nil)
+	snapDeclBar := s.snapDecl(c, "bar", map[string]interface{}{
+		"refresh-control": []interface{}{"foo-id"},
+	})
+	s.stateFromDecl(c, snapDeclFoo, "", snap.R(7))
+	s.stateFromDecl(c, snapDeclFoo, "foo_instance", snap.R(7))
+	s.stateFromDecl(c, snapDeclBar, "", snap.R(3))
+
+	headers := map[string]interface{}{
+		"series":                 "16",
+		"snap-id":                "bar-id",
+		"approved-snap-id":       "foo-id",
+		"approved-snap-revision": "9",
+		"timestamp":              time.Now().Format(time.RFC3339),
+	}
+	barValidation, err := s.dev1Signing.Sign(asserts.ValidationType, headers, nil, "")
+	c.Assert(err, IsNil)
+	err = s.storeSigning.Add(barValidation)
+	c.Assert(err, IsNil)
+
+	err = assertstate.Add(s.state, s.storeSigning.StoreAccountKey(""))
+	c.Assert(err, IsNil)
+	err = assertstate.Add(s.state, s.dev1Acct)
+	c.Assert(err, IsNil)
+	err = assertstate.Add(s.state, snapDeclFoo)
+	c.Assert(err, IsNil)
+	err = assertstate.Add(s.state, snapDeclBar)
+	c.Assert(err, IsNil)
+
+	fooRefresh := &snap.Info{
+		SideInfo: snap.SideInfo{RealName: "foo", SnapID: "foo-id", Revision: snap.R(9)},
+	}
+	fooInstanceRefresh := &snap.Info{
+		SideInfo:    snap.SideInfo{RealName: "foo", SnapID: "foo-id", Revision: snap.R(9)},
+		InstanceKey: "instance",
+	}
+
+	validated, err := assertstate.ValidateRefreshes(s.state, []*snap.Info{fooRefresh, fooInstanceRefresh}, nil, 0, deviceCtx)
+	c.Assert(err, IsNil)
+	c.Check(validated, DeepEquals, []*snap.Info{fooRefresh, fooInstanceRefresh})
+
+	// both candidates need the same validation and its prerequisites
+	// but each was requested only once
+	c.Check(requests, DeepEquals, map[string]int{
+		"/v2/assertions/validation/16/bar-id/foo-id/9":                                   1,
+		"/v2/assertions/snap-declaration/16/bar-id":                                      1,
+		"/v2/assertions/snap-declaration/16/foo-id":                                      1,
+		"/v2/assertions/account/" + s.dev1Acct.AccountID():                               1,
+		"/v2/assertions/account-key/" + s.dev1Signing.KeyID:                              1,
+		"/v2/assertions/account-key/" + s.storeSigning.StoreAccountKey("").PublicKeyID(): 1,
+	})
+}
+
 func (s *assertMgrSuite) TestValidateRefreshesRevokedValidation(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -2985,9 +3133,26 @@ func (s *assertMgrSuite) TestRefreshValidationSetAssertionsEnforcingModeConflict
 	}
 	assertstate.UpdateValidationSet(s.state, &tr)
 
+	now := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	restoreTime := assertstate.MockTimeNow(now)
+	defer restoreTime()
+
 	c.Assert(assertstate.RefreshValidationSetAssertions(s.state, 0, nil), IsNil)
 	c.Assert(logbuf.String(), Matches, `.*cannot refresh to conflicting validation set assertions: validation sets are in conflict:\n- cannot constrain snap "foo" as both invalid .* and required at revision 1.*\n`)
 
+	// the violated constraint is reported with a warning
+	warns := s.state.AllWarnings()
+	c.Assert(warns, HasLen, 1)
+	c.Check(warns[0].String(), Matches, `cannot refresh to conflicting validation set assertions: validation sets are in conflict:\n- cannot constrain snap "foo" as both invalid .* and required at revision 1.*`)
+
+	// and the new sequence point is pending
+	status, err := assertstate.ValidationSetsRefreshStatus(s.state)
+	c.Assert(err, IsNil)
+	c.Check(status, DeepEquals, map[string]*assertstate.ValidationSetRefreshStatus{
+		fmt.Sprintf("%s/foo", s.dev1Acct.AccountID()): {LastRefresh: now, PendingSequence: 2},
+		fmt.Sprintf("%s/bar", s.dev1Acct.AccountID()): {LastRefresh: now},
+	})
+
 	a, err := assertstate.DB(s.state).Find(asserts.ValidationSetType, map[string]string{
 		"series":     "16",
 		"account-id": s.dev1Acct.AccountID(),
@@ -3016,6 +3181,100 @@ func (s *assertMgrSuite) TestRefreshValidationSetAssertionsEnforcingModeConflict
 	c.Check(tr.Current, Equals, 1)
 }
 
+func (s *assertMgrSuite) TestEnsureRefreshesValidationSetAssertions(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// have a model and the store assertion available
+	storeAs := s.setupModelAndStore(c)
+	err := s.storeSigning.Add(storeAs)
+	c.Assert(err, IsNil)
+
+	// store key already present
+	c.Assert(assertstate.Add(s.state, s.storeSigning.StoreAccountKey("")), IsNil)
+	c.Assert(assertstate.Add(s.state, s.dev1Acct), IsNil)
+	c.Assert(assertstate.Add(s.state, s.dev1AcctKey), IsNil)
+
+	vsetAs1 := s.validationSetAssert(c, "bar", "1", "1", "required", "1")
+	c.Assert(assertstate.Add(s.state, vsetAs1), IsNil)
+
+	// newer sequence in the store
+	vsetAs2 := s.validationSetAssert(c, "bar", "2", "1", "required", "1")
+	c.Assert(s.storeSigning.Add(vsetAs2), IsNil)
+
+	tr := assertstate.ValidationSetTracking{
+		AccountID: s.dev1Acct.AccountID(),
+		Name:      "bar",
+		Mode:      assertstate.Monitor,
+		Current:   1,
+	}
+	assertstate.UpdateValidationSet(s.state, &tr)
+
+	t0 := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	ensureAt := func(t time.Time) {
+		restore := assertstate.MockTimeNow(t)
+		defer restore()
+		s.state.Unlock()
+		defer s.state.Lock()
+		c.Assert(s.mgr.Ensure(), IsNil)
+	}
+
+	// the first ensure starts counting the interval
+	ensureAt(t0)
+	c.Check(s.fakeStore.(*fakeStore).requestedTypes, HasLen, 0)
+	var lastRefresh time.Time
+	c.Assert(s.state.Get("last-validation-sets-refresh", &lastRefresh), IsNil)
+	c.Check(lastRefresh.Equal(t0), Equals, true)
+
+	// too early
+	ensureAt(t0.Add(time.Hour))
+	c.Check(s.fakeStore.(*fakeStore).requestedTypes, HasLen, 0)
+
+	t1 := t0.Add(6 * time.Hour)
+	ensureAt(t1)
+	c.Check(s.fakeStore.(*fakeStore).requestedTypes, DeepEquals, [][]string{
+		{"account", "account-key", "validation-set"},
+	})
+	c.Check(s.fakeStore.(*fakeStore).opts.Scheduled, Equals, true)
+
+	c.Assert(assertstate.GetValidationSet(s.state, s.dev1Acct.AccountID(), "bar", &tr), IsNil)
+	c.Check(tr.Current, Equals, 2)
+
+	status, err := assertstate.ValidationSetsRefreshStatus(s.state)
+	c.Assert(err, IsNil)
+	c.Check(status, DeepEquals, map[string]*assertstate.ValidationSetRefreshStatus{
+		fmt.Sprintf("%s/bar", s.dev1Acct.AccountID()): {LastRefresh: t1},
+	})
+	c.Assert(s.state.Get("last-validation-sets-refresh", &lastRefresh), IsNil)
+	c.Check(lastRefresh.Equal(t1), Equals, true)
+
+	// forgetting the set forgets its refresh status
+	c.Assert(assertstate.ForgetValidationSet(s.state, s.dev1Acct.AccountID(), "bar"), IsNil)
+	status, err = assertstate.ValidationSetsRefreshStatus(s.state)
+	c.Assert(err, IsNil)
+	c.Check(status, HasLen, 0)
+}
+
+func (s *assertMgrSuite) TestEnsureRefreshValidationSetAssertionsNotSeeded(c *C) {
+	s.state.Lock()
+	tr := assertstate.ValidationSetTracking{
+		AccountID: s.dev1Acct.AccountID(),
+		Name:      "bar",
+		Mode:      assertstate.Monitor,
+		Current:   1,
+	}
+	assertstate.UpdateValidationSet(s.state, &tr)
+	s.state.Unlock()
+
+	c.Assert(s.mgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	var lastRefresh time.Time
+	c.Check(s.state.Get("last-validation-sets-refresh", &lastRefresh), testutil.ErrorIs, state.ErrNoState)
+	c.Check(s.fakeStore.(*fakeStore).requestedTypes, HasLen, 0)
+}
+
 func (s *assertMgrSuite) TestRefreshValidationSetAssertionsEnforcingModeMissingSnap(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -4878,3 +5137,31 @@ func (s *assertMgrSuite) TestForgetValidationSetPreferEnforcedByModelHappy(c *C)
 	err := assertstate.ForgetValidationSet(s.state, s.dev1Acct.AccountID(), "foo")
 	c.Check(err, IsNil)
 }
+
+func (s *assertMgrSuite) TestPruneSnapRevisionAssertions(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(assertstate.Add(s.state, s.storeSigning.StoreAccountKey("")), IsNil)
+	c.Assert(assertstate.Add(s.state, s.dev1Acct), IsNil)
+	c.Assert(assertstate.Add(s.state, s.snapDecl(c, "foo", nil)), IsNil)
+	for _, rev := range []int{10, 11, 12} {
+		c.Assert(assertstate.Add(s.state, s.snapRevision(c, rev)), IsNil)
+	}
+
+	err := assertstate.PruneSnapRevisionAssertions(s.state, "foo-id", []snap.Revision{snap.R(11)})
+	c.Assert(err, IsNil)
+
+	snapRevs, err := assertstate.DB(s.state).FindManyBySnapID(asserts.SnapRevisionType, "foo-id")
+	c.Assert(err, IsNil)
+	c.Assert(snapRevs, HasLen, 1)
+	c.Check(snapRevs[0].(*asserts.SnapRevision).SnapRevision(), Equals, 11)
+
+	// the snap-declaration is kept
+	_, err = assertstate.DB(s.state).FindManyBySnapID(asserts.SnapDeclarationType, "foo-id")
+	c.Check(err, IsNil)
+
+	// nothing to prune
+	err = assertstate.PruneSnapRevisionAssertions(s.state, "bar-id", nil)
+	c.Check(err, IsNil)
+}
@@ -0,0 +1,70 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assertstate
+
+import (
+	"io"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// BundleImportResult reports the outcome of importing a bundle of
+// assertions.
+type BundleImportResult struct {
+	// Added are the assertions which were added to the system assertion
+	// database.
+	Added []*asserts.Ref
+	// Present are the assertions of the bundle which were already in the
+	// database, with the same or a newer revision.
+	Present []*asserts.Ref
+}
+
+// ImportBundle adds to the system assertion database the assertions from
+// the given stream, as produced elsewhere, e.g. by "snap known --remote" or
+// the store, for devices which cannot reach the store themselves. The
+// assertions can be in any order, they are added in the order of their
+// prerequisites, which must be found either in the stream or in the
+// database. Nothing is added unless all the assertions can be.
+func ImportBundle(s *state.State, r io.Reader) (*BundleImportResult, error) {
+	batch := asserts.NewBatch(nil)
+	refs, err := batch.AddStream(r)
+	if err != nil {
+		return nil, err
+	}
+
+	added := make(map[string]bool, len(refs))
+	observe := func(a asserts.Assertion) {
+		added[a.Ref().Unique()] = true
+	}
+	if err := batch.CommitToAndObserve(cachedDB(s), observe, &asserts.CommitOptions{Precheck: true}); err != nil {
+		return nil, err
+	}
+
+	res := &BundleImportResult{}
+	for _, ref := range refs {
+		if added[ref.Unique()] {
+			res.Added = append(res.Added, ref)
+		} else {
+			res.Present = append(res.Present, ref)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,113 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assertstate_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/assertstate"
+)
+
+func (s *assertMgrSuite) snapRevision(c *C, rev int) *asserts.SnapRevision {
+	headers := map[string]interface{}{
+		"snap-id":       "foo-id",
+		"snap-sha3-384": makeDigest(rev),
+		"snap-size":     fmt.Sprintf("%d", len(fakeSnap(rev))),
+		"snap-revision": fmt.Sprintf("%d", rev),
+		"developer-id":  s.dev1Acct.AccountID(),
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}
+	snapRev, err := s.storeSigning.Sign(asserts.SnapRevisionType, headers, nil, "")
+	c.Assert(err, IsNil)
+	return snapRev.(*asserts.SnapRevision)
+}
+
+func (s *assertMgrSuite) TestImportBundle(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the store key is already present
+	err := assertstate.Add(s.state, s.storeSigning.StoreAccountKey(""))
+	c.Assert(err, IsNil)
+
+	snapDecl := s.snapDecl(c, "foo", nil)
+	snapRev := s.snapRevision(c, 10)
+
+	b := &bytes.Buffer{}
+	enc := asserts.NewEncoder(b)
+	// not in prerequisite order
+	for _, a := range []asserts.Assertion{snapRev, snapDecl, s.storeSigning.StoreAccountKey(""), s.dev1Acct} {
+		c.Assert(enc.Encode(a), IsNil)
+	}
+
+	res, err := assertstate.ImportBundle(s.state, b)
+	c.Assert(err, IsNil)
+	c.Check(res.Added, DeepEquals, []*asserts.Ref{
+		snapRev.Ref(),
+		snapDecl.Ref(),
+		s.dev1Acct.Ref(),
+	})
+	c.Check(res.Present, DeepEquals, []*asserts.Ref{
+		s.storeSigning.StoreAccountKey("").Ref(),
+	})
+
+	_, err = snapRev.Ref().Resolve(assertstate.DB(s.state).Find)
+	c.Check(err, IsNil)
+
+	// importing again is a no-op
+	b.Reset()
+	c.Assert(asserts.NewEncoder(b).Encode(snapDecl), IsNil)
+	res, err = assertstate.ImportBundle(s.state, b)
+	c.Assert(err, IsNil)
+	c.Check(res.Added, HasLen, 0)
+	c.Check(res.Present, DeepEquals, []*asserts.Ref{snapDecl.Ref()})
+}
+
+func (s *assertMgrSuite) TestImportBundleMissingPrerequisite(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapDecl := s.snapDecl(c, "foo", nil)
+	b := &bytes.Buffer{}
+	enc := asserts.NewEncoder(b)
+	c.Assert(enc.Encode(s.dev1Acct), IsNil)
+	c.Assert(enc.Encode(snapDecl), IsNil)
+
+	_, err := assertstate.ImportBundle(s.state, b)
+	c.Check(err, ErrorMatches, `cannot resolve prerequisite assertion: account-key .*`)
+
+	// nothing was added
+	_, err = s.dev1Acct.Ref().Resolve(assertstate.DB(s.state).Find)
+	c.Check(err, FitsTypeOf, &asserts.NotFoundError{})
+}
+
+func (s *assertMgrSuite) TestImportBundleInvalidStream(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := assertstate.ImportBundle(s.state, strings.NewReader("type: account\n\nsig"))
+	c.Check(err, ErrorMatches, `assertion: "sign-key-sha3-384" header is mandatory`)
+}
@@ -19,6 +19,10 @@
 
 package assertstate
 
+import (
+	"time"
+)
+
 // expose for testing
 var (
 	DoFetch                                   = doFetch
@@ -26,6 +30,8 @@ var (
 	ValidationSetAssertionForMonitor          = validationSetAssertionForMonitor
 	AddCurrentTrackingToValidationSetsHistory = addCurrentTrackingToValidationSetsHistory
 	ValidationSetsHistoryTop                  = validationSetsHistoryTop
+	FetchSnapFileAssertions                   = fetchSnapFileAssertions
+	PruneSnapRevisionAssertions               = pruneSnapRevisionAssertions
 )
 
 func MockMaxGroups(n int) (restore func()) {
@@ -43,3 +49,21 @@ func MockMaxValidationSetsHistorySize(n int) (restore func()) {
 		maxValidationSetsHistorySize = oldMaxValidationSetsHistorySize
 	}
 }
+
+func MockTimeNow(t time.Time) (restore func()) {
+	oldTimeNow := timeNow
+	timeNow = func() time.Time {
+		return t
+	}
+	return func() {
+		timeNow = oldTimeNow
+	}
+}
+
+func MockValidationSetsRefreshInterval(d time.Duration) (restore func()) {
+	oldInterval := validationSetsRefreshInterval
+	validationSetsRefreshInterval = d
+	return func() {
+		validationSetsRefreshInterval = oldInterval
+	}
+}
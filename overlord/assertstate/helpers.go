@@ -20,11 +20,16 @@
 package assertstate
 
 import (
+	"fmt"
+
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/asserts/sysdb"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 )
 
 // TODO: snapstate also has this, move to auth, or change a bit the approach now that we have DeviceAndAuthContext in the store?
@@ -89,3 +94,64 @@ func doFetch(s *state.State, userID int, deviceCtx snapstate.DeviceContext, batc
 
 	return nil
 }
+
+// fetchSnapFileAssertions fetches the assertions supporting the given snap
+// file from the store, cross-checks them and returns them, prerequisites
+// first. The system assertion database is left untouched.
+func fetchSnapFileAssertions(s *state.State, snapPath string, info *snap.Info, userID int, deviceCtx snapstate.DeviceContext) ([]asserts.Assertion, error) {
+	sha3_384, snapSize, err := asserts.SnapFileSHA3_384(snapPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// use a separate database so that all the needed assertions are
+	// collected, including the ones already in the system database
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   sysdb.Trusted(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := userFromUserID(s, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sto := snapstate.Store(s, deviceCtx)
+
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		return sto.Assertion(ref.Type, ref.PrimaryKey, user)
+	}
+	var fetched []asserts.Assertion
+	save := func(a asserts.Assertion) error {
+		if err := db.Add(a); err != nil {
+			if _, ok := err.(*asserts.RevisionError); ok {
+				return nil
+			}
+			return fmt.Errorf("cannot add assertion %v: %v", a.Ref(), err)
+		}
+		fetched = append(fetched, a)
+		return nil
+	}
+	f := asserts.NewFetcher(db, retrieve, save)
+
+	expectedProv := info.Provenance()
+	s.Unlock()
+	err = snapasserts.FetchSnapAssertions(f, sha3_384, expectedProv)
+	s.Lock()
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch snap signatures/assertions: %v", err)
+	}
+
+	verifiedRev, err := snapasserts.CrossCheck(info.InstanceName(), sha3_384, expectedProv, snapSize, &info.SideInfo, nil, db)
+	if err != nil {
+		return nil, err
+	}
+	if err := snapasserts.CheckProvenanceWithVerifiedRevision(snapPath, verifiedRev); err != nil {
+		return nil, err
+	}
+
+	return fetched, nil
+}
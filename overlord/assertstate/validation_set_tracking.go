@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/snapasserts"
@@ -139,6 +140,9 @@ func ForgetValidationSet(st *state.State, accountID, name string) error {
 
 	delete(vsmap, ValidationSetKey(accountID, name))
 	st.Set("validation-sets", vsmap)
+	if err := forgetValidationSetRefreshStatus(st, ValidationSetKey(accountID, name)); err != nil {
+		return err
+	}
 	return addCurrentTrackingToValidationSetsHistory(st)
 }
 
@@ -178,6 +182,60 @@ func ValidationSets(st *state.State) (map[string]*ValidationSetTracking, error)
 	return vsmap, nil
 }
 
+// ValidationSetRefreshStatus holds the outcome of the last refresh of a
+// tracked validation set from the store.
+type ValidationSetRefreshStatus struct {
+	// LastRefresh is the time the validation set was last refreshed.
+	LastRefresh time.Time `json:"last-refresh"`
+	// PendingSequence is a newer sequence point available in the store
+	// which could not be applied, or 0 if none.
+	PendingSequence int `json:"pending-sequence,omitempty"`
+}
+
+// ValidationSetsRefreshStatus retrieves the refresh status of tracked
+// validation sets, keyed by validation set key.
+func ValidationSetsRefreshStatus(st *state.State) (map[string]*ValidationSetRefreshStatus, error) {
+	var statusMap map[string]*ValidationSetRefreshStatus
+	if err := st.Get("validation-sets-refresh", &statusMap); err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, err
+	}
+	return statusMap, nil
+}
+
+func updateValidationSetsRefreshStatus(st *state.State, vsets map[string]*ValidationSetTracking, pending map[string]int, now time.Time) error {
+	if len(vsets) == 0 {
+		return nil
+	}
+	statusMap, err := ValidationSetsRefreshStatus(st)
+	if err != nil {
+		return err
+	}
+	if statusMap == nil {
+		statusMap = make(map[string]*ValidationSetRefreshStatus)
+	}
+	for key := range vsets {
+		statusMap[key] = &ValidationSetRefreshStatus{
+			LastRefresh:     now,
+			PendingSequence: pending[key],
+		}
+	}
+	st.Set("validation-sets-refresh", statusMap)
+	return nil
+}
+
+func forgetValidationSetRefreshStatus(st *state.State, key string) error {
+	statusMap, err := ValidationSetsRefreshStatus(st)
+	if err != nil {
+		return err
+	}
+	if _, ok := statusMap[key]; !ok {
+		return nil
+	}
+	delete(statusMap, key)
+	st.Set("validation-sets-refresh", statusMap)
+	return nil
+}
+
 // TrackedEnforcedValidationSets returns ValidationSets object with all currently tracked
 // validation sets that are in enforcing mode. If extraVss is not nil then they are
 // added to the returned set and replaces validation sets with same account/name
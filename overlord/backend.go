@@ -25,13 +25,77 @@ import (
 	"github.com/snapcore/snapd/osutil"
 )
 
+var (
+	// the write-ahead log is folded into the state file once it grows
+	// beyond this size or after this long
+	stateWALMaxSize      int64 = 4 * 1024 * 1024
+	stateWALFoldInterval       = 1 * time.Hour
+
+	timeNow = time.Now
+)
+
 type overlordStateBackend struct {
 	path         string
 	ensureBefore func(d time.Duration)
+
+	// useWAL is set when the changes to the state are appended to a
+	// write-ahead log, which is folded into the state file from time
+	// to time, rather than rewriting the state file each time
+	useWAL   bool
+	wal      *stateWAL
+	lastFold time.Time
+	// last is the data of the last checkpoint
+	last []byte
+	// rewrites counts the times the state file was written in full and
+	// written the amount of data written to the state file and the log
+	rewrites int
+	written  int64
+}
+
+func newOverlordStateBackend(path string, ensureBefore func(d time.Duration), useWAL bool) *overlordStateBackend {
+	return &overlordStateBackend{
+		path:         path,
+		ensureBefore: ensureBefore,
+		useWAL:       useWAL,
+		wal:          &stateWAL{path: path + ".wal"},
+	}
 }
 
 func (osb *overlordStateBackend) Checkpoint(data []byte) error {
-	return osutil.AtomicWriteFile(osb.path, data, 0600, 0)
+	osb.last = data
+	if osb.useWAL && osb.wal.sums != nil && timeNow().Sub(osb.lastFold) < stateWALFoldInterval && osb.wal.size < stateWALMaxSize {
+		size := osb.wal.size
+		err := osb.wal.appendChanges(data)
+		osb.written += osb.wal.size - size
+		return err
+	}
+	return osb.rewrite(data)
+}
+
+// rewrite writes data to the state file in full, folding the write-ahead log
+// if any.
+func (osb *overlordStateBackend) rewrite(data []byte) error {
+	if err := osutil.AtomicWriteFile(osb.path, data, 0600, 0); err != nil {
+		return err
+	}
+	osb.rewrites++
+	osb.written += int64(len(data))
+	osb.lastFold = timeNow()
+	if osb.useWAL {
+		return osb.wal.reset(data)
+	}
+	// a log may be left behind from when it was used
+	return osb.wal.remove()
+}
+
+// foldWAL folds the write-ahead log, if any, into the state file. The state
+// must be locked.
+func (osb *overlordStateBackend) foldWAL() error {
+	if osb.wal.f == nil {
+		// nothing was appended since the state file was written
+		return nil
+	}
+	return osb.rewrite(osb.last)
 }
 
 func (osb *overlordStateBackend) EnsureBefore(d time.Duration) {
@@ -21,6 +21,7 @@ package config
 
 import (
 	"encoding/json"
+	"time"
 )
 
 var PurgeNulls = purgeNulls
@@ -40,3 +41,11 @@ func ClearExternalConfigMap() {
 
 	externalConfigMap = nil
 }
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = f
+	return func() {
+		timeNow = old
+	}
+}
@@ -269,8 +269,13 @@ func DiscardRevisionConfig(st *state.State, snapName string, rev snap.Revision)
 	return nil
 }
 
-// DeleteSnapConfig removed configuration of given snap from the state.
+// DeleteSnapConfig removed configuration of given snap from the state,
+// together with its history.
 func DeleteSnapConfig(st *state.State, snapName string) error {
+	if err := deleteHistory(st, snapName); err != nil {
+		return err
+	}
+
 	var config map[string]map[string]*json.RawMessage // snap => key => value
 
 	err := st.Get("config", &config)
@@ -0,0 +1,268 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// Origins of configuration changes recorded in the history.
+const (
+	// OriginSnapd is used for changes made by snapd itself.
+	OriginSnapd = "snapd"
+	// OriginAPI is used for changes requested through the API, for
+	// example with "snap set".
+	OriginAPI = "api"
+	// OriginHook is used for changes made with "snapctl set" from a
+	// hook of the snap.
+	OriginHook = "hook"
+	// OriginSnapctl is used for changes made with "snapctl set" from an
+	// app of the snap.
+	OriginSnapctl = "snapctl"
+	// OriginGadgetDefaults is used for the defaults of the gadget.
+	OriginGadgetDefaults = "gadget-defaults"
+)
+
+const (
+	// maxHistoryEntries is the number of history entries kept for each
+	// snap.
+	maxHistoryEntries = 20
+	// maxHistoryValueSize is the size in bytes above which values are
+	// truncated in the history.
+	maxHistoryValueSize = 256
+)
+
+// sensitiveKeyPatterns are the substrings which mark an option as holding
+// sensitive data when found in any of the components of its key, or in the
+// keys of maps nested in its value. Values of such options are redacted
+// in the history.
+var sensitiveKeyPatterns = []string{"password", "passphrase", "secret", "token"}
+
+var timeNow = time.Now
+
+// HistoryChange records the change of a single option.
+type HistoryChange struct {
+	Key string `json:"key"`
+	// Old and New hold the JSON encoding of the values before and after
+	// the change, empty when the option was unset. Values larger than
+	// 256 bytes are truncated and end with "...".
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+	// Redacted is set when the values are not recorded because the
+	// option holds sensitive data.
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// HistoryEntry records the changes made to the configuration of a snap
+// by a committed transaction.
+type HistoryEntry struct {
+	Time    time.Time        `json:"time"`
+	Origin  string           `json:"origin"`
+	Changes []*HistoryChange `json:"changes"`
+}
+
+// GetHistory returns the recorded history of configuration changes of
+// the given snap, oldest first.
+func GetHistory(st *state.State, snapName string) ([]*HistoryEntry, error) {
+	var history map[string][]*HistoryEntry
+	err := st.Get("config-history", &history)
+	if errors.Is(err, state.ErrNoState) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("internal error: cannot unmarshal configuration history: %v", err)
+	}
+	return history[snapName], nil
+}
+
+func deleteHistory(st *state.State, snapName string) error {
+	var history map[string][]*HistoryEntry
+	err := st.Get("config-history", &history)
+	if errors.Is(err, state.ErrNoState) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("internal error: cannot unmarshal configuration history: %v", err)
+	}
+	if _, ok := history[snapName]; ok {
+		delete(history, snapName)
+		st.Set("config-history", history)
+	}
+	return nil
+}
+
+// recordHistory adds to the history of the given snap the changes of the
+// given keys, grouped by their origin, from the old to the new
+// configuration.
+func recordHistory(st *state.State, instanceName string, origins map[string]string, old, new map[string]*json.RawMessage) {
+	keysByOrigin := make(map[string][]string)
+	for key, origin := range origins {
+		keysByOrigin[origin] = append(keysByOrigin[origin], key)
+	}
+	originNames := make([]string, 0, len(keysByOrigin))
+	for origin := range keysByOrigin {
+		originNames = append(originNames, origin)
+	}
+	sort.Strings(originNames)
+
+	now := timeNow()
+	var entries []*HistoryEntry
+	for _, origin := range originNames {
+		keys := keysByOrigin[origin]
+		sort.Strings(keys)
+		entry := &HistoryEntry{Time: now, Origin: origin}
+		for _, key := range keys {
+			if change := historyChange(instanceName, key, old, new); change != nil {
+				entry.Changes = append(entry.Changes, change)
+			}
+		}
+		if len(entry.Changes) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	var history map[string][]*HistoryEntry
+	if err := st.Get("config-history", &history); err != nil && !errors.Is(err, state.ErrNoState) {
+		panic(fmt.Errorf("internal error: cannot unmarshal configuration history: %v", err))
+	}
+	if history == nil {
+		history = make(map[string][]*HistoryEntry)
+	}
+	snapHistory := append(history[instanceName], entries...)
+	if len(snapHistory) > maxHistoryEntries {
+		snapHistory = snapHistory[len(snapHistory)-maxHistoryEntries:]
+	}
+	history[instanceName] = snapHistory
+	st.Set("config-history", history)
+}
+
+func historyChange(instanceName, key string, old, new map[string]*json.RawMessage) *HistoryChange {
+	subkeys, err := ParseKey(key)
+	if err != nil {
+		return nil
+	}
+	// unset options are reported as nil
+	var oldValue, newValue interface{}
+	if err := getFromConfig(instanceName, subkeys, 0, old, &oldValue); err != nil {
+		oldValue = nil
+	}
+	if err := getFromConfig(instanceName, subkeys, 0, new, &newValue); err != nil {
+		newValue = nil
+	}
+	oldData := historyValue(oldValue)
+	newData := historyValue(newValue)
+	if oldData == newData {
+		return nil
+	}
+	change := &HistoryChange{Key: key}
+	if isSensitive(subkeys, oldValue) || isSensitive(subkeys, newValue) {
+		change.Redacted = true
+		return change
+	}
+	change.Old = truncateHistoryValue(oldData)
+	change.New = truncateHistoryValue(newData)
+	return change
+}
+
+// historyValue returns the JSON encoding of the given value, or an empty
+// string for unset values.
+func historyValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func truncateHistoryValue(data string) string {
+	if len(data) <= maxHistoryValueSize {
+		return data
+	}
+	// do not cut in the middle of a character
+	end := maxHistoryValueSize
+	for end > 0 && !utf8.RuneStart(data[end]) {
+		end--
+	}
+	return data[:end] + "..."
+}
+
+func isSensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range sensitiveKeyPatterns {
+		if strings.Contains(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSensitive(subkeys []string, value interface{}) bool {
+	for _, subkey := range subkeys {
+		if isSensitiveName(subkey) {
+			return true
+		}
+	}
+	return hasSensitiveKeys(value)
+}
+
+func hasSensitiveKeys(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, nested := range v {
+			if isSensitiveName(name) || hasSensitiveKeys(nested) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, nested := range v {
+			if hasSensitiveKeys(nested) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyConfig returns a shallow copy of the given configuration of a
+// snap, enough to read values from it after the original is modified.
+func copyConfig(config map[string]*json.RawMessage) map[string]*json.RawMessage {
+	out := make(map[string]*json.RawMessage, len(config))
+	for k, v := range config {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,185 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package config_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type historySuite struct {
+	state *state.State
+	now   time.Time
+}
+
+var _ = Suite(&historySuite{})
+
+func (s *historySuite) SetUpTest(c *C) {
+	s.state = state.New(nil)
+	s.now = time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+}
+
+func (s *historySuite) commit(c *C, origin string, values map[string]interface{}) {
+	defer config.MockTimeNow(func() time.Time { return s.now })()
+
+	tr := config.NewTransaction(s.state)
+	if origin != "" {
+		tr.SetOrigin(origin)
+	}
+	c.Assert(config.Patch(tr, "test-snap", values), IsNil)
+	tr.Commit()
+}
+
+func (s *historySuite) TestHistoryRecorded(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.commit(c, config.OriginAPI, map[string]interface{}{"foo": "bar", "a.b": 1})
+	s.now = s.now.Add(time.Hour)
+	s.commit(c, "", map[string]interface{}{"foo": "baz", "a": nil})
+
+	history, err := config.GetHistory(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(history, DeepEquals, []*config.HistoryEntry{{
+		Time:   time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC),
+		Origin: "api",
+		Changes: []*config.HistoryChange{
+			{Key: "a.b", New: "1"},
+			{Key: "foo", New: `"bar"`},
+		},
+	}, {
+		Time:   time.Date(2023, 5, 1, 11, 0, 0, 0, time.UTC),
+		Origin: "snapd",
+		Changes: []*config.HistoryChange{
+			{Key: "a", Old: `{"b":1}`},
+			{Key: "foo", Old: `"bar"`, New: `"baz"`},
+		},
+	}})
+
+	history, err = config.GetHistory(s.state, "other-snap")
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 0)
+}
+
+func (s *historySuite) TestHistoryMultipleOrigins(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.SetOrigin(config.OriginGadgetDefaults)
+	c.Assert(tr.Set("test-snap", "foo", "default"), IsNil)
+	tr.SetOrigin(config.OriginHook)
+	c.Assert(tr.Set("test-snap", "bar", true), IsNil)
+	tr.Commit()
+
+	history, err := config.GetHistory(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	c.Check(history[0].Origin, Equals, "gadget-defaults")
+	c.Check(history[0].Changes, DeepEquals, []*config.HistoryChange{{Key: "foo", New: `"default"`}})
+	c.Check(history[1].Origin, Equals, "hook")
+	c.Check(history[1].Changes, DeepEquals, []*config.HistoryChange{{Key: "bar", New: "true"}})
+}
+
+func (s *historySuite) TestHistoryUnchangedNotRecorded(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.commit(c, config.OriginAPI, map[string]interface{}{"foo": "bar"})
+	s.commit(c, config.OriginAPI, map[string]interface{}{"foo": "bar", "unset": nil})
+
+	history, err := config.GetHistory(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 1)
+}
+
+func (s *historySuite) TestHistoryBounded(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for i := 0; i < 25; i++ {
+		s.commit(c, config.OriginAPI, map[string]interface{}{"count": i})
+	}
+
+	history, err := config.GetHistory(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 20)
+	c.Check(history[0].Changes, DeepEquals, []*config.HistoryChange{{Key: "count", Old: "4", New: "5"}})
+	c.Check(history[19].Changes, DeepEquals, []*config.HistoryChange{{Key: "count", Old: "23", New: "24"}})
+}
+
+func (s *historySuite) TestHistoryRedacted(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.commit(c, config.OriginAPI, map[string]interface{}{
+		"db.password":  "hunter2",
+		"api-token":    "abc",
+		"credentials":  map[string]interface{}{"user": "foo", "Secret": "bar"},
+		"servers":      []interface{}{map[string]interface{}{"host": "a", "passphrase": "b"}},
+		"not-password": nil,
+		"user":         "foo",
+	})
+
+	history, err := config.GetHistory(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Changes, DeepEquals, []*config.HistoryChange{
+		{Key: "api-token", Redacted: true},
+		{Key: "credentials", Redacted: true},
+		{Key: "db.password", Redacted: true},
+		{Key: "servers", Redacted: true},
+		{Key: "user", New: `"foo"`},
+	})
+}
+
+func (s *historySuite) TestHistoryTruncated(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	large := strings.Repeat("x", 300)
+	s.commit(c, config.OriginAPI, map[string]interface{}{"large": large, "html": "<a>&"})
+
+	history, err := config.GetHistory(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Changes, DeepEquals, []*config.HistoryChange{
+		{Key: "html", New: `"<a>&"`},
+		{Key: "large", New: fmt.Sprintf(`"%s...`, large[:255])},
+	})
+}
+
+func (s *historySuite) TestDeleteSnapConfigDeletesHistory(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.commit(c, config.OriginAPI, map[string]interface{}{"foo": "bar"})
+	c.Assert(config.DeleteSnapConfig(s.state, "test-snap"), IsNil)
+
+	history, err := config.GetHistory(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 0)
+}
@@ -44,14 +44,19 @@ type Transaction struct {
 	state    *state.State
 	pristine map[string]map[string]*json.RawMessage // snap => key => value
 	changes  map[string]map[string]interface{}
+
+	// origin is recorded in the history for the changes made by Set
+	origin  string
+	origins map[string]map[string]string // snap => key => origin
 }
 
 // NewTransaction creates a new configuration transaction initialized with the given state.
 //
 // The provided state must be locked by the caller.
 func NewTransaction(st *state.State) *Transaction {
-	transaction := &Transaction{state: st}
+	transaction := &Transaction{state: st, origin: OriginSnapd}
 	transaction.changes = make(map[string]map[string]interface{})
+	transaction.origins = make(map[string]map[string]string)
 
 	// Record the current state of the map containing the config of every snap
 	// in the system. We'll use it for this transaction.
@@ -69,6 +74,15 @@ func (t *Transaction) State() *state.State {
 	return t.state
 }
 
+// SetOrigin sets the origin recorded in the configuration history for
+// the changes made by subsequent calls to Set. The origin of a new
+// transaction is OriginSnapd.
+func (t *Transaction) SetOrigin(origin string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.origin = origin
+}
+
 func changes(cfgStr string, cfg map[string]interface{}) []string {
 	var out []string
 	for k := range cfg {
@@ -184,6 +198,10 @@ func (t *Transaction) Set(instanceName, key string, value interface{}) error {
 	}
 
 	t.changes[instanceName] = config
+	if t.origins[instanceName] == nil {
+		t.origins[instanceName] = make(map[string]string)
+	}
+	t.origins[instanceName][key] = t.origin
 	return nil
 }
 
@@ -335,15 +353,19 @@ func (t *Transaction) Commit() {
 		if config == nil {
 			config = make(map[string]*json.RawMessage)
 		}
+		old := copyConfig(config)
 		applyChanges(config, snapChanges)
 		purgeNulls(config)
 		t.pristine[instanceName] = config
+
+		recordHistory(t.state, instanceName, t.origins[instanceName], old, config)
 	}
 
 	t.state.Set("config", t.pristine)
 
 	// The cache has been flushed, reset it.
 	t.changes = make(map[string]map[string]interface{})
+	t.origins = make(map[string]map[string]string)
 }
 
 func applyChanges(config map[string]*json.RawMessage, changes map[string]interface{}) {
@@ -32,6 +32,7 @@ import (
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/strutil"
 )
 
 var (
@@ -116,14 +117,26 @@ func validateProxyStore(tr RunTransaction) error {
 	st.Lock()
 	defer st.Unlock()
 
-	store, err := assertstate.Store(st, proxyStore)
-	if errors.Is(err, &asserts.NotFoundError{}) {
-		return fmt.Errorf("cannot set proxy.store to %q without a matching store assertion", proxyStore)
-	}
-	if err == nil && store.URL() == nil {
-		return fmt.Errorf("cannot set proxy.store to %q with a matching store assertion with url unset", proxyStore)
+	// proxy.store can list more than one store, in order of preference
+	seen := make(map[string]bool)
+	for _, storeID := range strutil.CommaSeparatedList(proxyStore) {
+		if seen[storeID] {
+			return fmt.Errorf("cannot set proxy.store to %q: store %q listed more than once", proxyStore, storeID)
+		}
+		seen[storeID] = true
+
+		store, err := assertstate.Store(st, storeID)
+		if errors.Is(err, &asserts.NotFoundError{}) {
+			return fmt.Errorf("cannot set proxy.store to %q without a matching store assertion", storeID)
+		}
+		if err != nil {
+			return err
+		}
+		if store.URL() == nil {
+			return fmt.Errorf("cannot set proxy.store to %q with a matching store assertion with url unset", storeID)
+		}
 	}
-	return err
+	return nil
 }
 
 func handleProxyStore(tr RunTransaction, opts *fsOnlyContext) error {
@@ -233,3 +233,57 @@ func (s *proxySuite) TestConfigureProxyStoreNoURL(c *C) {
 	err = configcore.Run(coreDev, conf)
 	c.Check(err, ErrorMatches, `cannot set proxy.store to "foo" with a matching store assertion with url unset`)
 }
+
+func (s *proxySuite) TestConfigureProxyStoreList(c *C) {
+	defer configcore.MockDevicestateResetSession(func(s *state.State) error {
+		return nil
+	})()
+
+	operatorAcct := assertstest.NewAccount(s.storeSigning, "foo-operator", nil, "")
+	fooAs, err := s.storeSigning.Sign(asserts.StoreType, map[string]interface{}{
+		"store":       "foo",
+		"operator-id": operatorAcct.AccountID(),
+		"url":         "http://store.interal:9943",
+		"timestamp":   time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	func() {
+		s.state.Lock()
+		defer s.state.Unlock()
+		assertstatetest.AddMany(s.state, operatorAcct, fooAs)
+	}()
+
+	conf := &mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"proxy.store": "foo, bar",
+		},
+	}
+	err = configcore.Run(classicDev, conf)
+	c.Check(err, ErrorMatches, `cannot set proxy.store to "bar" without a matching store assertion`)
+
+	barAs, err := s.storeSigning.Sign(asserts.StoreType, map[string]interface{}{
+		"store":       "bar",
+		"operator-id": operatorAcct.AccountID(),
+		"url":         "http://store-fallback.interal:9943",
+		"timestamp":   time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	func() {
+		s.state.Lock()
+		defer s.state.Unlock()
+		assertstatetest.AddMany(s.state, barAs)
+	}()
+
+	err = configcore.Run(classicDev, conf)
+	c.Check(err, IsNil)
+
+	conf = &mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"proxy.store": "foo,bar,foo",
+		},
+	}
+	err = configcore.Run(classicDev, conf)
+	c.Check(err, ErrorMatches, `cannot set proxy.store to "foo,bar,foo": store "foo" listed more than once`)
+}
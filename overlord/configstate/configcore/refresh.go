@@ -37,6 +37,9 @@ func init() {
 	supportedConfigurations["core.refresh.metered"] = true
 	supportedConfigurations["core.refresh.retain"] = true
 	supportedConfigurations["core.refresh.rate-limit"] = true
+	supportedConfigurations["core.refresh.max-parallel-downloads"] = true
+	supportedConfigurations["core.refresh.max-inhibition"] = true
+	supportedConfigurations["core.refresh.block-on-unhealthy"] = true
 }
 
 func reportOrIgnoreInvalidManageRefreshes(tr RunTransaction, optName string) error {
@@ -145,3 +148,42 @@ func validateRefreshRateLimit(tr RunTransaction) error {
 	}
 	return nil
 }
+
+func validateRefreshMaxParallelDownloads(tr RunTransaction) error {
+	maxDownloadsStr, err := coreCfg(tr, "refresh.max-parallel-downloads")
+	if err != nil {
+		return err
+	}
+	if maxDownloadsStr != "" {
+		if n, err := strconv.ParseUint(maxDownloadsStr, 10, 8); err != nil || (n < 1 || n > 10) {
+			return fmt.Errorf("max-parallel-downloads must be a number between 1 and 10, not %q", maxDownloadsStr)
+		}
+	}
+	return nil
+}
+
+// refresh.max-inhibition limits for how long running apps can postpone a
+// refresh of their snap.
+const (
+	minRefreshMaxInhibition = time.Hour
+	maxRefreshMaxInhibition = 90 * 24 * time.Hour
+)
+
+func validateRefreshMaxInhibition(tr RunTransaction) error {
+	maxInhibitionStr, err := coreCfg(tr, "refresh.max-inhibition")
+	if err != nil {
+		return err
+	}
+	if maxInhibitionStr == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(maxInhibitionStr)
+	if err != nil || d < minRefreshMaxInhibition || d > maxRefreshMaxInhibition {
+		return fmt.Errorf("max-inhibition must be a duration between %v and %v, not %q", minRefreshMaxInhibition, maxRefreshMaxInhibition, maxInhibitionStr)
+	}
+	return nil
+}
+
+func validateRefreshBlockOnUnhealthy(tr RunTransaction) error {
+	return validateBoolFlag(tr, "refresh.block-on-unhealthy")
+}
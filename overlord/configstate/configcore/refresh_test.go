@@ -21,6 +21,7 @@
 package configcore_test
 
 import (
+	"fmt"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -220,3 +221,69 @@ func (s *refreshSuite) TestConfigureRefreshRetainInvalid(c *C) {
 	})
 	c.Assert(err, ErrorMatches, `retain must be a number between 2 and 20, not "invalid"`)
 }
+
+func (s *refreshSuite) TestConfigureRefreshMaxParallelDownloadsHappy(c *C) {
+	err := configcore.Run(classicDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.max-parallel-downloads": "4",
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *refreshSuite) TestConfigureRefreshMaxParallelDownloadsInvalid(c *C) {
+	for _, val := range []string{"0", "11", "invalid"} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"refresh.max-parallel-downloads": val,
+			},
+		})
+		c.Check(err, ErrorMatches, fmt.Sprintf(`max-parallel-downloads must be a number between 1 and 10, not %q`, val))
+	}
+}
+
+func (s *refreshSuite) TestConfigureRefreshMaxInhibitionHappy(c *C) {
+	for _, val := range []string{"1h", "72h", "2160h"} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"refresh.max-inhibition": val,
+			},
+		})
+		c.Check(err, IsNil)
+	}
+}
+
+func (s *refreshSuite) TestConfigureRefreshMaxInhibitionInvalid(c *C) {
+	for _, val := range []string{"59m", "2161h", "14d", "invalid"} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"refresh.max-inhibition": val,
+			},
+		})
+		c.Check(err, ErrorMatches, fmt.Sprintf(`max-inhibition must be a duration between 1h0m0s and 2160h0m0s, not %q`, val))
+	}
+}
+
+func (s *refreshSuite) TestConfigureRefreshBlockOnUnhealthy(c *C) {
+	for _, val := range []string{"true", "false"} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"refresh.block-on-unhealthy": val,
+			},
+		})
+		c.Check(err, IsNil)
+	}
+
+	err := configcore.Run(classicDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.block-on-unhealthy": "maybe",
+		},
+	})
+	c.Check(err, ErrorMatches, `refresh.block-on-unhealthy can only be set to 'true' or 'false'`)
+}
@@ -59,7 +59,12 @@ func init() {
 	validateOnly := &flags{validatedOnlyStateConfig: true}
 	addWithStateHandler(validateRefreshSchedule, nil, validateOnly)
 	addWithStateHandler(validateRefreshRateLimit, nil, validateOnly)
+	addWithStateHandler(validateRefreshMaxParallelDownloads, nil, validateOnly)
+	addWithStateHandler(validateRefreshMaxInhibition, nil, validateOnly)
+	addWithStateHandler(validateRefreshBlockOnUnhealthy, nil, validateOnly)
 	addWithStateHandler(validateAutomaticSnapshotsExpiration, nil, validateOnly)
+	addWithStateHandler(validateAutomaticSnapshotsMaxSize, nil, validateOnly)
+	addWithStateHandler(validateStorageGCFreeSpaceThreshold, nil, validateOnly)
 
 	// netplan.*
 	addWithStateHandler(validateNetplanSettings, handleNetplanConfiguration, coreOnly)
@@ -23,11 +23,14 @@ package configcore
 import (
 	"fmt"
 	"time"
+
+	"github.com/snapcore/snapd/gadget/quantity"
 )
 
 func init() {
 	// add supported configuration of this module
 	supportedConfigurations["core.snapshots.automatic.retention"] = true
+	supportedConfigurations["core.snapshots.automatic.max-size"] = true
 }
 
 func validateAutomaticSnapshotsExpiration(tr RunTransaction) error {
@@ -46,3 +49,18 @@ func validateAutomaticSnapshotsExpiration(tr RunTransaction) error {
 	}
 	return nil
 }
+
+func validateAutomaticSnapshotsMaxSize(tr RunTransaction) error {
+	maxSizeStr, err := coreCfg(tr, "snapshots.automatic.max-size")
+	if err != nil {
+		return err
+	}
+	if maxSizeStr == "" {
+		return nil
+	}
+	// 0 is special and means unlimited
+	if _, err := quantity.ParseSize(maxSizeStr); err != nil {
+		return fmt.Errorf("snapshots.automatic.max-size cannot be parsed: %v", err)
+	}
+	return nil
+}
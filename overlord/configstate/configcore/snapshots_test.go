@@ -71,3 +71,25 @@ func (s *refreshSuite) TestConfigureAutomaticSnapshotsExpirationInvalid(c *C) {
 	})
 	c.Assert(err, ErrorMatches, `snapshots.automatic.retention cannot be parsed:.*`)
 }
+
+func (s *snapshotsSuite) TestConfigureAutomaticSnapshotsMaxSizeHappy(c *C) {
+	for _, maxSize := range []string{"0", "500M", "2G"} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"snapshots.automatic.max-size": maxSize,
+			},
+		})
+		c.Check(err, IsNil, Commentf(maxSize))
+	}
+}
+
+func (s *snapshotsSuite) TestConfigureAutomaticSnapshotsMaxSizeInvalid(c *C) {
+	err := configcore.Run(classicDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"snapshots.automatic.max-size": "lots",
+		},
+	})
+	c.Assert(err, ErrorMatches, `snapshots.automatic.max-size cannot be parsed: .*`)
+}
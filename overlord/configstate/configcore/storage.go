@@ -0,0 +1,51 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//go:build !nomanagers
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/strutil"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.storage.gc-free-space-threshold"] = true
+}
+
+func validateStorageGCFreeSpaceThreshold(tr RunTransaction) error {
+	thresholdStr, err := coreCfg(tr, "storage.gc-free-space-threshold")
+	if err != nil {
+		return err
+	}
+	// reset is fine
+	if thresholdStr == "" {
+		return nil
+	}
+	threshold, err := strutil.ParseByteSize(thresholdStr)
+	if err != nil {
+		return fmt.Errorf("storage.gc-free-space-threshold cannot be parsed: %v", err)
+	}
+	if threshold <= 0 {
+		return fmt.Errorf("storage.gc-free-space-threshold must be a positive size, not %q", thresholdStr)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//go:build !nomanagers
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type storageSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&storageSuite{})
+
+func (s *storageSuite) TestConfigureGCFreeSpaceThresholdHappy(c *C) {
+	for _, val := range []string{"2GB", "512MB", ""} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"storage.gc-free-space-threshold": val,
+			},
+		})
+		c.Check(err, IsNil, Commentf("%q", val))
+	}
+}
+
+func (s *storageSuite) TestConfigureGCFreeSpaceThresholdInvalid(c *C) {
+	err := configcore.Run(classicDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"storage.gc-free-space-threshold": "invalid",
+		},
+	})
+	c.Assert(err, ErrorMatches, `storage.gc-free-space-threshold cannot be parsed: .*`)
+
+	err = configcore.Run(classicDev, &mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"storage.gc-free-space-threshold": "0B",
+		},
+	})
+	c.Assert(err, ErrorMatches, `storage.gc-free-space-threshold must be a positive size, not "0B"`)
+}
@@ -0,0 +1,352 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package configschema implements validation of snap configuration
+// against the schema a snap may ship in meta/config-schema.json.
+//
+// Only a subset of JSON Schema is supported: the "type", "enum",
+// "required", "pattern", "properties", "additionalProperties" (as a
+// boolean) and "items" keywords.
+package configschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/jsonutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var validTypes = []string{"object", "array", "string", "integer", "number", "boolean", "null"}
+
+// keywords that carry no constraints and are accepted as is
+var annotationKeywords = []string{"$schema", "$id", "$comment", "title", "description", "default", "examples"}
+
+// Schema holds a parsed configuration schema.
+type Schema struct {
+	types                []string
+	enum                 []interface{}
+	required             []string
+	pattern              *regexp.Regexp
+	properties           map[string]*Schema
+	additionalProperties *bool
+	items                *Schema
+}
+
+// Parse parses a configuration schema.
+func Parse(data []byte) (*Schema, error) {
+	var def map[string]json.RawMessage
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("cannot parse configuration schema: %v", err)
+	}
+	schema, err := parse(def, "")
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse configuration schema%s", err)
+	}
+	return schema, nil
+}
+
+// schemaError is an error in the definition of the schema of the value
+// at path.
+type schemaError struct {
+	path string
+	err  error
+}
+
+// Error returns the error as a suffix for "cannot parse configuration
+// schema".
+func (e *schemaError) Error() string {
+	if e.path == "" {
+		return fmt.Sprintf(": %v", e.err)
+	}
+	return fmt.Sprintf(" for %q: %v", e.path, e.err)
+}
+
+func parse(def map[string]json.RawMessage, path string) (*Schema, error) {
+	s := &Schema{}
+	// sort the keywords for deterministic errors
+	keywords := make([]string, 0, len(def))
+	for keyword := range def {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		if err := s.parseKeyword(keyword, def[keyword], path); err != nil {
+			if _, ok := err.(*schemaError); ok {
+				return nil, err
+			}
+			return nil, &schemaError{path: path, err: err}
+		}
+	}
+	return s, nil
+}
+
+func (s *Schema) parseKeyword(keyword string, raw json.RawMessage, path string) error {
+	switch keyword {
+	case "type":
+		var typ string
+		if err := json.Unmarshal(raw, &typ); err == nil {
+			s.types = []string{typ}
+		} else if err := json.Unmarshal(raw, &s.types); err != nil {
+			return fmt.Errorf(`"type" must be a string or a list of strings`)
+		}
+		for _, typ := range s.types {
+			if !strutil.ListContains(validTypes, typ) {
+				return fmt.Errorf("unsupported type %q", typ)
+			}
+		}
+	case "enum":
+		if err := jsonutil.DecodeWithNumber(bytes.NewReader(raw), &s.enum); err != nil || len(s.enum) == 0 {
+			return fmt.Errorf(`"enum" must be a non-empty list`)
+		}
+	case "required":
+		if err := json.Unmarshal(raw, &s.required); err != nil {
+			return fmt.Errorf(`"required" must be a list of strings`)
+		}
+	case "pattern":
+		var pattern string
+		if err := json.Unmarshal(raw, &pattern); err != nil {
+			return fmt.Errorf(`"pattern" must be a string`)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		s.pattern = re
+	case "properties":
+		var props map[string]map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &props); err != nil {
+			return fmt.Errorf(`"properties" must map names to schemas`)
+		}
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		s.properties = make(map[string]*Schema, len(props))
+		for _, name := range names {
+			prop, err := parse(props[name], joinPath(path, name))
+			if err != nil {
+				return err
+			}
+			s.properties[name] = prop
+		}
+	case "additionalProperties":
+		var allowed bool
+		if err := json.Unmarshal(raw, &allowed); err != nil {
+			return fmt.Errorf(`"additionalProperties" must be a boolean`)
+		}
+		s.additionalProperties = &allowed
+	case "items":
+		var itemsDef map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &itemsDef); err != nil {
+			return fmt.Errorf(`"items" must be a schema`)
+		}
+		items, err := parse(itemsDef, path+"[]")
+		if err != nil {
+			return err
+		}
+		s.items = items
+	default:
+		if !strutil.ListContains(annotationKeywords, keyword) {
+			return fmt.Errorf("unsupported keyword %q", keyword)
+		}
+	}
+	return nil
+}
+
+// ReadSnapSchema reads the configuration schema shipped by the given
+// snap. It returns a nil schema if the snap does not ship one.
+func ReadSnapSchema(info *snap.Info) (*Schema, error) {
+	data, err := os.ReadFile(filepath.Join(info.MountDir(), "meta", "config-schema.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	schema, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("snap %q has invalid meta/config-schema.json: %v", info.InstanceName(), err)
+	}
+	return schema, nil
+}
+
+// Violation describes a configuration value not matching the schema.
+type Violation struct {
+	// Key is the dotted path of the offending value, empty for the
+	// whole configuration.
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+func (v *Violation) String() string {
+	if v.Key == "" {
+		return v.Message
+	}
+	return fmt.Sprintf("%s: %s", v.Key, v.Message)
+}
+
+// ValidationError is returned when configuration does not match the
+// schema of its snap.
+type ValidationError struct {
+	Violations []*Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(msgs, "; "))
+}
+
+// Validate checks the given configuration, as decoded from JSON, against
+// the schema. All the violations found are reported in a
+// *ValidationError.
+func (s *Schema) Validate(value interface{}) error {
+	var violations []*Violation
+	s.validate(value, "", &violations)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func (s *Schema) validate(value interface{}, path string, violations *[]*Violation) {
+	report := func(format string, a ...interface{}) {
+		*violations = append(*violations, &Violation{Key: path, Message: fmt.Sprintf(format, a...)})
+	}
+
+	typ := typeOf(value)
+	if len(s.types) > 0 && !s.hasType(typ) {
+		report("expected %s, got %s", strings.Join(s.types, " or "), typ)
+		return
+	}
+
+	if len(s.enum) > 0 && !s.inEnum(value) {
+		report("value %s is not one of the allowed values", encode(value))
+	}
+
+	switch v := value.(type) {
+	case string:
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			report("value %q does not match pattern %q", v, s.pattern)
+		}
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				*violations = append(*violations, &Violation{Key: joinPath(path, name), Message: "required value is missing"})
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop := s.properties[name]
+			if prop == nil {
+				if s.additionalProperties != nil && !*s.additionalProperties {
+					*violations = append(*violations, &Violation{Key: joinPath(path, name), Message: "unexpected value"})
+				}
+				continue
+			}
+			prop.validate(v[name], joinPath(path, name), violations)
+		}
+	case []interface{}:
+		if s.items != nil {
+			for i, item := range v {
+				s.items.validate(item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	}
+}
+
+func (s *Schema) hasType(typ string) bool {
+	for _, t := range s.types {
+		if t == typ || (t == "number" && typ == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) inEnum(value interface{}) bool {
+	enc := encode(value)
+	for _, allowed := range s.enum {
+		if encode(allowed) == enc {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type of a value decoded from JSON,
+// either with or without json.Number.
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case int, int64:
+		return "integer"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func encode(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
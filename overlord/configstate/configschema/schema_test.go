@@ -0,0 +1,184 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configschema_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/jsonutil"
+	"github.com/snapcore/snapd/overlord/configstate/configschema"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type schemaSuite struct{}
+
+var _ = Suite(&schemaSuite{})
+
+const testSchema = `{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"type": "object",
+	"required": ["mode"],
+	"properties": {
+		"mode": {"type": "string", "enum": ["fast", "slow"]},
+		"debug": {"type": "boolean"},
+		"port": {"type": "integer"},
+		"ratio": {"type": "number"},
+		"name": {"type": "string", "pattern": "^[a-z]+$"},
+		"servers": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"required": ["host"],
+				"additionalProperties": false,
+				"properties": {"host": {"type": "string"}}
+			}
+		}
+	}
+}`
+
+func decode(c *C, doc string) interface{} {
+	var value interface{}
+	c.Assert(jsonutil.DecodeWithNumber(strings.NewReader(doc), &value), IsNil)
+	return value
+}
+
+func (s *schemaSuite) TestValidateHappy(c *C) {
+	schema, err := configschema.Parse([]byte(testSchema))
+	c.Assert(err, IsNil)
+
+	for _, doc := range []string{
+		`{"mode": "fast"}`,
+		`{"mode": "slow", "debug": false, "port": 8080, "ratio": 0.5, "name": "foo"}`,
+		`{"mode": "slow", "ratio": 2, "servers": [{"host": "a"}, {"host": "b"}]}`,
+		`{"mode": "slow", "unknown": "is allowed"}`,
+	} {
+		c.Check(schema.Validate(decode(c, doc)), IsNil, Commentf("%s", doc))
+	}
+}
+
+func (s *schemaSuite) TestValidateViolations(c *C) {
+	schema, err := configschema.Parse([]byte(testSchema))
+	c.Assert(err, IsNil)
+
+	for _, t := range []struct {
+		doc        string
+		violations []*configschema.Violation
+	}{{
+		doc: `{"mode": "fast", "debug": "true"}`,
+		violations: []*configschema.Violation{
+			{Key: "debug", Message: "expected boolean, got string"},
+		},
+	}, {
+		doc: `{"port": 1.5}`,
+		violations: []*configschema.Violation{
+			{Key: "mode", Message: "required value is missing"},
+			{Key: "port", Message: "expected integer, got number"},
+		},
+	}, {
+		doc: `{"mode": "medium", "name": "Foo"}`,
+		violations: []*configschema.Violation{
+			{Key: "mode", Message: `value "medium" is not one of the allowed values`},
+			{Key: "name", Message: `value "Foo" does not match pattern "^[a-z]+$"`},
+		},
+	}, {
+		doc: `{"mode": "fast", "servers": [{"host": "a"}, {"port": 1}]}`,
+		violations: []*configschema.Violation{
+			{Key: "servers[1].host", Message: "required value is missing"},
+			{Key: "servers[1].port", Message: "unexpected value"},
+		},
+	}, {
+		doc: `"fast"`,
+		violations: []*configschema.Violation{
+			{Key: "", Message: "expected object, got string"},
+		},
+	}} {
+		err := schema.Validate(decode(c, t.doc))
+		c.Assert(err, FitsTypeOf, &configschema.ValidationError{}, Commentf("%s", t.doc))
+		c.Check(err.(*configschema.ValidationError).Violations, DeepEquals, t.violations, Commentf("%s", t.doc))
+	}
+}
+
+func (s *schemaSuite) TestValidationErrorMessage(c *C) {
+	schema, err := configschema.Parse([]byte(testSchema))
+	c.Assert(err, IsNil)
+
+	err = schema.Validate(decode(c, `{"debug": 1}`))
+	c.Check(err, ErrorMatches, `invalid configuration: mode: required value is missing; debug: expected boolean, got integer`)
+}
+
+func (s *schemaSuite) TestValidateMultipleTypes(c *C) {
+	schema, err := configschema.Parse([]byte(`{"properties": {"a": {"type": ["string", "null"]}}}`))
+	c.Assert(err, IsNil)
+
+	c.Check(schema.Validate(decode(c, `{"a": "x"}`)), IsNil)
+	c.Check(schema.Validate(decode(c, `{"a": null}`)), IsNil)
+	c.Check(schema.Validate(decode(c, `{"a": 1}`)), ErrorMatches, `invalid configuration: a: expected string or null, got integer`)
+}
+
+func (s *schemaSuite) TestParseErrors(c *C) {
+	for _, t := range []struct {
+		schema string
+		err    string
+	}{
+		{`[]`, `cannot parse configuration schema: .*`},
+		{`{"type": "map"}`, `cannot parse configuration schema: unsupported type "map"`},
+		{`{"type": 1}`, `cannot parse configuration schema: "type" must be a string or a list of strings`},
+		{`{"enum": []}`, `cannot parse configuration schema: "enum" must be a non-empty list`},
+		{`{"required": "a"}`, `cannot parse configuration schema: "required" must be a list of strings`},
+		{`{"minLength": 1}`, `cannot parse configuration schema: unsupported keyword "minLength"`},
+		{`{"additionalProperties": {}}`, `cannot parse configuration schema: "additionalProperties" must be a boolean`},
+		{`{"properties": {"a": {"properties": {"b": {"pattern": "("}}}}}`, `cannot parse configuration schema for "a.b": invalid pattern "\(": .*`},
+		{`{"properties": {"a": {"items": {"type": "str"}}}}`, `cannot parse configuration schema for "a\[\]": unsupported type "str"`},
+	} {
+		_, err := configschema.Parse([]byte(t.schema))
+		c.Check(err, ErrorMatches, t.err, Commentf("%s", t.schema))
+	}
+}
+
+func (s *schemaSuite) TestReadSnapSchema(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("")
+
+	info := snaptest.MockSnap(c, "name: foo\nversion: 1", &snap.SideInfo{Revision: snap.R(1)})
+
+	// no schema
+	schema, err := configschema.ReadSnapSchema(info)
+	c.Assert(err, IsNil)
+	c.Check(schema, IsNil)
+
+	schemaFile := filepath.Join(info.MountDir(), "meta", "config-schema.json")
+	c.Assert(os.WriteFile(schemaFile, []byte(testSchema), 0644), IsNil)
+	schema, err = configschema.ReadSnapSchema(info)
+	c.Assert(err, IsNil)
+	c.Check(schema.Validate(decode(c, `{"mode": "fast"}`)), IsNil)
+
+	c.Assert(os.WriteFile(schemaFile, []byte(`{"type": "map"}`), 0644), IsNil)
+	_, err = configschema.ReadSnapSchema(info)
+	c.Check(err, ErrorMatches, `snap "foo" has invalid meta/config-schema.json: cannot parse configuration schema: unsupported type "map"`)
+}
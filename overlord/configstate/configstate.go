@@ -95,11 +95,16 @@ func canConfigure(st *state.State, snapName string) error {
 
 // ConfigureInstalled returns a taskset to apply the given
 // configuration patch for an installed snap. It returns
-// snap.NotInstalledError if the snap is not installed.
+// snap.NotInstalledError if the snap is not installed, and a
+// *configschema.ValidationError if the resulting configuration does not
+// match the configuration schema of the snap.
 func ConfigureInstalled(st *state.State, snapName string, patch map[string]interface{}, flags int) (*state.TaskSet, error) {
 	if err := canConfigure(st, snapName); err != nil {
 		return nil, err
 	}
+	if err := validatePatch(st, snapName, patch); err != nil {
+		return nil, err
+	}
 
 	taskset := Configure(st, snapName, patch, flags)
 	return taskset, nil
@@ -22,6 +22,8 @@ package configstate_test
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -33,11 +35,13 @@ import (
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/overlord/configstate/configschema"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/sysconfig"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -179,6 +183,43 @@ func (s *tasksetsSuite) TestConfigureInstalledConflict(c *C) {
 	c.Check(err, ErrorMatches, `snap "test-snap" has "other-change" change in progress`)
 }
 
+func (s *tasksetsSuite) TestConfigureInstalledSchemaViolation(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("")
+
+	info := snaptest.MockSnap(c, "name: test-snap\nversion: 1", &snap.SideInfo{Revision: snap.R(1)})
+	schema := `{"properties": {"foo": {"type": "boolean"}}, "required": ["foo"]}`
+	c.Assert(os.WriteFile(filepath.Join(info.MountDir(), "meta", "config-schema.json"), []byte(schema), 0644), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "test-snap", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		Active:   true,
+		SnapType: "app",
+	})
+
+	_, err := configstate.ConfigureInstalled(s.state, "test-snap", map[string]interface{}{"foo": "true"}, 0)
+	c.Assert(err, FitsTypeOf, &configschema.ValidationError{})
+	c.Check(err.(*configschema.ValidationError).Violations, DeepEquals, []*configschema.Violation{
+		{Key: "foo", Message: "expected boolean, got string"},
+	})
+
+	ts, err := configstate.ConfigureInstalled(s.state, "test-snap", map[string]interface{}{"foo": true}, 0)
+	c.Assert(err, IsNil)
+	c.Check(ts.Tasks(), HasLen, 1)
+
+	// unsetting a required value is a violation too
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("test-snap", "foo", true), IsNil)
+	tr.Commit()
+	_, err = configstate.ConfigureInstalled(s.state, "test-snap", map[string]interface{}{"foo": nil}, 0)
+	c.Check(err, ErrorMatches, `invalid configuration: foo: required value is missing`)
+}
+
 func (s *tasksetsSuite) TestConfigureNotInstalled(c *C) {
 	patch := map[string]interface{}{"foo": "bar"}
 	s.state.Lock()
@@ -36,6 +36,7 @@ import (
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/overlord/configstate/configschema"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -117,6 +118,29 @@ func (s *configureHandlerSuite) TestBeforeInitializesTransaction(c *C) {
 	c.Check(value, Equals, "bar")
 }
 
+func (s *configureHandlerSuite) TestHistoryOrigins(c *C) {
+	s.context.Lock()
+	s.context.Set("patch", map[string]interface{}{"foo": "bar"})
+	s.context.Unlock()
+
+	c.Assert(s.handler.Before(), IsNil)
+
+	s.context.Lock()
+	defer s.context.Unlock()
+	// as done by snapctl set from the hook
+	tr := configstate.ContextTransaction(s.context)
+	c.Assert(tr.Set("test-snap", "derived", "baz"), IsNil)
+	c.Assert(s.context.Done(), IsNil)
+
+	history, err := config.GetHistory(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	c.Check(history[0].Origin, Equals, config.OriginAPI)
+	c.Check(history[0].Changes, DeepEquals, []*config.HistoryChange{{Key: "foo", New: `"bar"`}})
+	c.Check(history[1].Origin, Equals, config.OriginHook)
+	c.Check(history[1].Changes, DeepEquals, []*config.HistoryChange{{Key: "derived", New: `"baz"`}})
+}
+
 func makeModel(override map[string]interface{}) *asserts.Model {
 	model := map[string]interface{}{
 		"type":         "model",
@@ -263,6 +287,124 @@ volumes:
 	c.Check(err, ErrorMatches, `cannot apply gadget config defaults for snap "test-snap", no configure hook`)
 }
 
+const testSnapConfigSchema = `{
+	"type": "object",
+	"properties": {
+		"foo": {"type": "string"},
+		"bar": {"type": "string", "enum": ["qux"]}
+	}
+}`
+
+func (s *configureHandlerSuite) mockSnapWithConfigSchema(c *C) {
+	const mockTestSnapYaml = `
+name: test-snap
+hooks:
+    configure:
+`
+	info := snaptest.MockSnap(c, mockTestSnapYaml, &snap.SideInfo{Revision: snap.R(11)})
+	err := os.WriteFile(filepath.Join(info.MountDir(), "meta", "config-schema.json"), []byte(testSnapConfigSchema), 0644)
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "test-snap", Revision: snap.R(11), SnapID: "testsnapidididididididididididid"},
+		},
+		Current:  snap.R(11),
+		SnapType: "app",
+	})
+}
+
+func (s *configureHandlerSuite) TestBeforePatchSchemaViolation(c *C) {
+	s.mockSnapWithConfigSchema(c)
+
+	s.context.Lock()
+	s.context.Set("patch", map[string]interface{}{"foo": 42})
+	s.context.Unlock()
+
+	err := s.handler.Before()
+	c.Check(err, ErrorMatches, `invalid configuration: foo: expected string, got integer`)
+	c.Check(err, FitsTypeOf, &configschema.ValidationError{})
+}
+
+func (s *configureHandlerSuite) TestDoneSchemaViolation(c *C) {
+	s.mockSnapWithConfigSchema(c)
+
+	s.context.Lock()
+	s.context.Set("patch", map[string]interface{}{"foo": "bar"})
+	s.context.Unlock()
+	c.Assert(s.handler.Before(), IsNil)
+	c.Check(s.handler.Done(), IsNil)
+
+	// the hook ignored a failing snapctl set
+	s.context.Lock()
+	tr := configstate.ContextTransaction(s.context)
+	c.Assert(tr.Set("test-snap", "bar", "baz"), IsNil)
+	s.context.Unlock()
+
+	c.Check(s.handler.Done(), ErrorMatches, `invalid configuration: bar: value "baz" is not one of the allowed values`)
+}
+
+func (s *configureHandlerSuite) TestBeforeUseDefaultsSchemaViolation(c *C) {
+	r := release.MockOnClassic(false)
+	defer r()
+
+	const mockGadgetSnapYaml = `
+name: canonical-pc
+type: gadget
+`
+	var mockGadgetYaml = []byte(`
+defaults:
+  testsnapidididididididididididid:
+      bar: baz
+
+volumes:
+    volume-id:
+        bootloader: grub
+`)
+
+	info := snaptest.MockSnap(c, mockGadgetSnapYaml, &snap.SideInfo{Revision: snap.R(1)})
+	err := os.WriteFile(filepath.Join(info.MountDir(), "meta", "gadget.yaml"), mockGadgetYaml, 0644)
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	snapstate.Set(s.state, "canonical-pc", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "canonical-pc", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "gadget",
+	})
+	s.state.Unlock()
+
+	r = snapstatetest.MockDeviceModel(makeModel(map[string]interface{}{
+		"gadget": "canonical-pc",
+	}))
+	defer r()
+
+	s.mockSnapWithConfigSchema(c)
+
+	s.context.Lock()
+	s.context.Set("use-defaults", true)
+	s.context.Unlock()
+
+	// invalid defaults do not fail the hook
+	c.Assert(s.handler.Before(), IsNil)
+
+	s.context.Lock()
+	defer s.context.Unlock()
+	tr := configstate.ContextTransaction(s.context)
+	var value string
+	c.Check(config.IsNoOption(tr.Get("test-snap", "bar", &value)), Equals, true)
+
+	warns := s.state.AllWarnings()
+	c.Assert(warns, HasLen, 1)
+	c.Check(warns[0].String(), Equals, `cannot apply gadget config defaults for snap "test-snap": invalid configuration: bar: value "baz" is not one of the allowed values`)
+}
+
 type defaultConfigureHandlerSuite struct {
 	testutil.BaseTest
 
@@ -24,6 +24,7 @@ import (
 	"fmt"
 
 	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/configstate/configschema"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -84,22 +85,53 @@ func (h *configureHandler) Before() error {
 				patch = nil
 			}
 		}
+		patch, err = validateDefaults(st, instanceName, patch)
+		if err != nil {
+			return err
+		}
 	} else {
 		if err := h.context.Get("patch", &patch); err != nil && !errors.Is(err, state.ErrNoState) {
 			return err
 		}
 	}
 
+	if useDefaults {
+		tr.SetOrigin(config.OriginGadgetDefaults)
+	} else {
+		tr.SetOrigin(config.OriginAPI)
+	}
 	if err := config.Patch(tr, instanceName, patch); err != nil {
 		return err
 	}
+	tr.SetOrigin(hookOrigin(instanceName))
 
+	if len(patch) != 0 {
+		return ValidateTransaction(tr, instanceName)
+	}
 	return nil
 }
 
+// hookOrigin returns the origin of the changes made while running the
+// configure hooks of the given snap.
+func hookOrigin(instanceName string) string {
+	// the configure hook of core is run by snapd itself
+	if instanceName == "core" {
+		return config.OriginSnapd
+	}
+	return config.OriginHook
+}
+
 // Done is called by the HookManager after the configure hook has exited
 // successfully.
 func (h *configureHandler) Done() error {
+	h.context.Lock()
+	defer h.context.Unlock()
+
+	// the hook may have ignored a failure of snapctl set
+	tr := ContextTransaction(h.context)
+	if len(tr.Changes()) != 0 {
+		return ValidateTransaction(tr, h.context.InstanceName())
+	}
 	return nil
 }
 
@@ -152,10 +184,16 @@ func (h *defaultConfigureHandler) Before() error {
 		if err != nil && !errors.Is(err, state.ErrNoState) {
 			return err
 		}
+		patch, err = validateDefaults(st, instanceName, patch)
+		if err != nil {
+			return err
+		}
 
+		tr.SetOrigin(config.OriginGadgetDefaults)
 		if err := config.Patch(tr, instanceName, patch); err != nil {
 			return err
 		}
+		tr.SetOrigin(config.OriginHook)
 	}
 
 	return nil
@@ -173,6 +211,26 @@ func (h *defaultConfigureHandler) Error(err error) (bool, error) {
 	return false, nil
 }
 
+// validateDefaults checks the gadget config defaults of the given snap
+// against the configuration schema of the snap. Defaults not matching
+// the schema must not prevent seeding, they are dropped with a warning
+// instead.
+func validateDefaults(st *state.State, instanceName string, patch map[string]interface{}) (map[string]interface{}, error) {
+	if len(patch) == 0 {
+		return patch, nil
+	}
+	err := validatePatch(st, instanceName, patch)
+	var verr *configschema.ValidationError
+	if errors.As(err, &verr) {
+		st.Warnf("cannot apply gadget config defaults for snap %q: %v", instanceName, err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
 // cachedTransaction is the index into the context cache where the initialized
 // transaction is stored.
 type cachedTransaction struct{}
@@ -188,6 +246,11 @@ func ContextTransaction(context *hookstate.Context) *config.Transaction {
 
 	// It wasn't already cached, so create and cache a new one
 	tr = config.NewTransaction(context.State())
+	if context.IsEphemeral() {
+		tr.SetOrigin(config.OriginSnapctl)
+	} else {
+		tr.SetOrigin(hookOrigin(context.InstanceName()))
+	}
 
 	context.OnDone(func() error {
 		tr.Commit()
@@ -0,0 +1,85 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configstate
+
+import (
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/configstate/configschema"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// snapConfigSchema returns the configuration schema shipped by the
+// current revision of the given snap, or nil if it does not ship one.
+func snapConfigSchema(st *state.State, instanceName string) (*configschema.Schema, error) {
+	// the configuration of core is handled internally
+	if instanceName == "core" {
+		return nil, nil
+	}
+	info, err := snapstate.CurrentInfo(st, instanceName)
+	if _, ok := err.(*snap.NotInstalledError); ok {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return configschema.ReadSnapSchema(info)
+}
+
+func validateConfig(tr *config.Transaction, instanceName string, schema *configschema.Schema) error {
+	var cfg interface{}
+	if err := tr.Get(instanceName, "", &cfg); err != nil && !config.IsNoOption(err) {
+		return err
+	}
+	if cfg == nil {
+		cfg = map[string]interface{}{}
+	}
+	return schema.Validate(cfg)
+}
+
+// ValidateTransaction checks the configuration of the given snap, as seen
+// by the transaction, against the schema the snap ships in
+// meta/config-schema.json, if any. A *configschema.ValidationError is
+// returned if the configuration does not match the schema.
+//
+// The state must be locked by the caller.
+func ValidateTransaction(tr *config.Transaction, instanceName string) error {
+	schema, err := snapConfigSchema(tr.State(), instanceName)
+	if err != nil || schema == nil {
+		return err
+	}
+	return validateConfig(tr, instanceName, schema)
+}
+
+// validatePatch checks that applying the given patch to the current
+// configuration of the snap yields a configuration matching the schema
+// of the snap, if any.
+func validatePatch(st *state.State, instanceName string, patch map[string]interface{}) error {
+	schema, err := snapConfigSchema(st, instanceName)
+	if err != nil || schema == nil {
+		return err
+	}
+	tr := config.NewTransaction(st)
+	if err := config.Patch(tr, instanceName, patch); err != nil {
+		return err
+	}
+	return validateConfig(tr, instanceName, schema)
+}
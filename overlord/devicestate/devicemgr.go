@@ -27,6 +27,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/snapcore/snapd/asserts"
@@ -99,6 +100,9 @@ type DeviceManager struct {
 	bootRevisionsUpdated bool
 
 	seedTimings *timings.Timings
+	// snaps of recovery systems recently loaded, see SystemSnaps
+	systemSnapsMu    sync.Mutex
+	systemSnapsCache map[string]*cachedSystemSnaps
 	// this is used during early phases until seeding is under way
 	earlyDeviceSeed     seed.Seed
 	seedLabel, seedMode string
@@ -176,9 +180,12 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 
 	hookManager.Register(regexp.MustCompile("^prepare-device$"), newBasicHookStateHandler)
 	hookManager.Register(regexp.MustCompile("^install-device$"), newBasicHookStateHandler)
+	hookManager.Register(regexp.MustCompile("^prepare-recovery-system$"), newBasicHookStateHandler)
 
 	runner.AddHandler("generate-device-key", m.doGenerateDeviceKey, nil)
 	runner.AddHandler("request-serial", m.doRequestSerial, nil)
+	runner.AddHandler("prepare-serial-renewal", m.doPrepareSerialRenewal, m.undoPrepareSerialRenewal)
+	runner.AddHandler("finish-serial-renewal", m.doFinishSerialRenewal, nil)
 	runner.AddHandler("mark-preseeded", m.doMarkPreseeded, nil)
 	runner.AddHandler("mark-seeded", m.doMarkSeeded, nil)
 	runner.AddHandler("setup-ubuntu-save", m.doSetupUbuntuSave, nil)
@@ -203,7 +210,7 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 	// kernel command line updates from a gadget supplied file
 	runner.AddHandler("update-gadget-cmdline", m.doUpdateGadgetCommandLine, m.undoUpdateGadgetCommandLine)
 	// recovery systems
-	runner.AddHandler("create-recovery-system", m.doCreateRecoverySystem, m.undoCreateRecoverySystem)
+	runner.AddHandlerWithContext("create-recovery-system", m.doCreateRecoverySystem, m.undoCreateRecoverySystem)
 	runner.AddHandler("finalize-recovery-system", m.doFinalizeTriedRecoverySystem, m.undoFinalizeTriedRecoverySystem)
 	runner.AddCleanup("finalize-recovery-system", m.cleanupRecoverySystem)
 
@@ -316,6 +323,12 @@ func (m *DeviceManager) StartUp() error {
 		logger.Noticef("%v", fmt.Errorf("cannot ensure device file/dir permissions: %v", err))
 	}
 
+	if m.SystemMode(SysHasModeenv) == "run" {
+		if err := clearFactoryResetAfterReboot(m.state); err != nil {
+			logger.Noticef("cannot check pending factory reset: %v", err)
+		}
+	}
+
 	// TODO: setup proper timings measurements for this
 
 	return EarlyConfig(m.state, m.earlyPreloadGadget)
@@ -1877,9 +1890,15 @@ func (m *DeviceManager) keyPair() (asserts.PrivateKey, error) {
 		return nil, state.ErrNoState
 	}
 
+	return m.keyPairByID(device.KeyID)
+}
+
+// keyPairByID returns the device key pair with the given ID, which can be
+// different from the current one during a serial renewal.
+func (m *DeviceManager) keyPairByID(keyID string) (asserts.PrivateKey, error) {
 	var privKey asserts.PrivateKey
-	err = m.withKeypairMgr(func(keypairMgr asserts.KeypairManager) (err error) {
-		privKey, err = keypairMgr.Get(device.KeyID)
+	err := m.withKeypairMgr(func(keypairMgr asserts.KeypairManager) (err error) {
+		privKey, err = keypairMgr.Get(keyID)
 		if err != nil {
 			return fmt.Errorf("cannot read device key pair: %v", err)
 		}
@@ -2079,6 +2098,18 @@ func (m *DeviceManager) Systems() ([]*System, error) {
 	return systems, nil
 }
 
+// RecoverySystemActions returns whether the system running now was installed
+// from the given recovery system, and the actions available for that system.
+func (m *DeviceManager) RecoverySystemActions(sys *RecoverySystem) (current bool, actions []SystemAction) {
+	// it's tough luck when we cannot determine the current system seed
+	currentSys, _ := currentSystemForMode(m.state, m.SystemMode(SysAny))
+	if currentSys != nil && currentSys.System == sys.Label &&
+		currentSys.Model == sys.Model && currentSys.BrandID == sys.BrandID {
+		return true, currentSys.actions
+	}
+	return false, defaultSystemActions
+}
+
 // SystemAndGadgetAndEncryptionInfo return the system details
 // including the model assertion, gadget details and encryption info
 // for the given system label.
@@ -2334,6 +2365,11 @@ func (scb storeContextBackend) ProxyStore() (*asserts.Store, error) {
 	return proxyStore(st, config.NewTransaction(st))
 }
 
+func (scb storeContextBackend) ProxyStores() ([]*asserts.Store, error) {
+	st := scb.DeviceManager.state
+	return proxyStores(st, config.NewTransaction(st))
+}
+
 func (scb storeContextBackend) StoreOffline() (bool, error) {
 	tr := config.NewTransaction(scb.state)
 
@@ -2363,6 +2399,14 @@ func (scb storeContextBackend) SignDeviceSessionRequest(serial *asserts.Serial,
 	if err != nil {
 		return nil, err
 	}
+	if keyID := serial.DeviceKey().ID(); keyID != privKey.PublicKey().ID() {
+		// the serial was obtained for a renewed device key that is
+		// not in use yet
+		privKey, err = scb.DeviceManager.keyPairByID(keyID)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	a, err := asserts.SignWithoutAuthority(asserts.DeviceSessionRequestType, map[string]interface{}{
 		"brand-id":  serial.BrandID(),
@@ -2495,6 +2539,9 @@ func (h fdeSetupHandler) Error(err error) (bool, error) {
 var (
 	secbootEnsureRecoveryKey  = secboot.EnsureRecoveryKey
 	secbootRemoveRecoveryKeys = secboot.RemoveRecoveryKeys
+
+	secbootGetTPMLockoutStatus = secboot.GetTPMLockoutStatus
+	secbootResetTPMLockout     = secboot.ResetTPMLockout
 )
 
 // EnsureRecoveryKeys makes sure appropriate recovery keys exist and
@@ -2609,6 +2656,128 @@ func (m *DeviceManager) RemoveRecoveryKeys() error {
 	return secbootRemoveRecoveryKeys(recoveryKeyDevices)
 }
 
+// TPMLockoutReset records an attempt at resetting the dictionary attack
+// lockout of the TPM.
+type TPMLockoutReset struct {
+	Time time.Time `json:"time"`
+	// Error is set if the attempt failed.
+	Error string `json:"error,omitempty"`
+}
+
+// maxTPMLockoutResets is the number of attempts at resetting the TPM lockout
+// which are kept in the state.
+const maxTPMLockoutResets = 10
+
+// checkTPMLockoutAccess verifies that the TPM lockout can be managed, that is
+// that the system is in run mode and the encryption keys are sealed to the
+// TPM.
+func (m *DeviceManager) checkTPMLockoutAccess(op string) error {
+	mode := m.SystemMode(SysAny)
+	if mode != "run" {
+		return fmt.Errorf("cannot %s TPM lockout from system mode %q", op, mode)
+	}
+	method, err := device.SealedKeysMethod(dirs.GlobalRootDir)
+	if err == device.ErrNoSealedKeys {
+		return fmt.Errorf("system does not use TPM sealed encryption keys")
+	}
+	if err != nil {
+		return err
+	}
+	if method != device.SealingMethodTPM && method != device.SealingMethodLegacyTPM {
+		return fmt.Errorf("system does not use TPM sealed encryption keys")
+	}
+	return nil
+}
+
+// TPMLockoutStatus returns the state of the dictionary attack protection of
+// the TPM.
+func (m *DeviceManager) TPMLockoutStatus() (*secboot.TPMLockoutStatus, error) {
+	if err := m.checkTPMLockoutAccess("query"); err != nil {
+		return nil, err
+	}
+	return secbootGetTPMLockoutStatus()
+}
+
+// TPMLockoutResets returns the recorded attempts at resetting the TPM
+// lockout, oldest first.
+func (m *DeviceManager) TPMLockoutResets() ([]TPMLockoutReset, error) {
+	var resets []TPMLockoutReset
+	err := m.state.Get("tpm-lockout-resets", &resets)
+	if err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, err
+	}
+	return resets, nil
+}
+
+// ResetTPMLockout resets the dictionary attack lockout of the TPM, using the
+// lockout authorization stored at install time. The attempt is recorded in
+// the state.
+func (m *DeviceManager) ResetTPMLockout() error {
+	if err := m.checkTPMLockoutAccess("reset"); err != nil {
+		return err
+	}
+	lockoutAuthFile := device.TpmLockoutAuthUnder(dirs.SnapFDEDirUnderSave(dirs.SnapSaveDir))
+	resetErr := secbootResetTPMLockout(lockoutAuthFile)
+	if resetErr == secboot.ErrNoTPMLockoutAuth {
+		resetErr = fmt.Errorf("lockout authorization is not available, the TPM must be reprovisioned from recovery mode")
+	}
+
+	resets, err := m.TPMLockoutResets()
+	if err != nil {
+		return err
+	}
+	reset := TPMLockoutReset{Time: timeNow()}
+	if resetErr != nil {
+		reset.Error = resetErr.Error()
+	}
+	resets = append(resets, reset)
+	if len(resets) > maxTPMLockoutResets {
+		resets = resets[len(resets)-maxTPMLockoutResets:]
+	}
+	m.state.Set("tpm-lockout-resets", resets)
+
+	if resetErr != nil {
+		return fmt.Errorf("cannot reset TPM lockout: %v", resetErr)
+	}
+	return nil
+}
+
+var gadgetDiskVolumesReport = gadget.DiskVolumesReport
+
+// DiskVolumesReport reports how the partitions of the disks holding the
+// volumes of the gadget map to the gadget structures.
+func (m *DeviceManager) DiskVolumesReport() ([]gadget.DiskVolumeReport, error) {
+	deviceCtx, err := DeviceCtx(m.state, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	gadgetSnapInfo, err := snapstate.GadgetInfo(m.state, deviceCtx)
+	if err != nil {
+		return nil, err
+	}
+	model := deviceCtx.Model()
+	gadgetInfo, err := gadget.ReadInfo(gadgetSnapInfo.MountDir(), model)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read gadget: %v", err)
+	}
+
+	// the encrypted structures were recorded along with the disk mapping at
+	// install time
+	traits, err := gadget.LoadDiskVolumesDeviceTraits(dirs.SnapDeviceDir)
+	if err != nil {
+		return nil, err
+	}
+	optsPerVolume := make(map[string]*gadget.DiskVolumeValidationOptions, len(gadgetInfo.Volumes))
+	for name := range gadgetInfo.Volumes {
+		optsPerVolume[name] = &gadget.DiskVolumeValidationOptions{
+			// allow implicit system-data on pre-uc20 only
+			AllowImplicitSystemData:     model.Grade() == asserts.ModelGradeUnset,
+			ExpectedStructureEncryption: traits[name].StructureEncryption,
+		}
+	}
+	return gadgetDiskVolumesReport(gadgetInfo.Volumes, optsPerVolume)
+}
+
 // checkEncryption verifies whether encryption should be used based on the
 // model grade and the availability of a TPM device or a fde-setup hook
 // in the kernel.
@@ -223,7 +223,7 @@ func checkGadgetOrKernel(st *state.State, snapInfo, curInfo *snap.Info, _ snap.C
 	return nil
 }
 
-func checkGadgetValid(st *state.State, snapInfo, _ *snap.Info, snapf snap.Container, flags snapstate.Flags, deviceCtx snapstate.DeviceContext) error {
+func checkGadgetValid(st *state.State, snapInfo, curInfo *snap.Info, snapf snap.Container, flags snapstate.Flags, deviceCtx snapstate.DeviceContext) error {
 	if snapInfo.Type() != snap.TypeGadget {
 		// not a gadget, nothing to do
 		return nil
@@ -235,8 +235,27 @@ func checkGadgetValid(st *state.State, snapInfo, _ *snap.Info, snapf snap.Contai
 	}
 
 	// do basic precondition checks on the gadget against its model constraints
-	_, err := gadget.ReadInfoFromSnapFile(snapf, deviceCtx.Model())
-	return err
+	pendingInfo, err := gadget.ReadInfoFromSnapFile(snapf, deviceCtx.Model())
+	if err != nil {
+		return err
+	}
+
+	if release.OnClassic || curInfo == nil {
+		// not a refresh of the gadget
+		return nil
+	}
+	// fail early when the layout of the new gadget cannot be used on the
+	// existing volumes, rather than in the middle of the gadget update
+	currentData, err := gadgetDataFromInfo(curInfo, deviceCtx.Model())
+	if err != nil {
+		// the update of the gadget assets will deal with it
+		logger.Noticef("cannot read current gadget metadata, skipping compatibility check: %v", err)
+		return nil
+	}
+	if err := gadgetIsCompatible(currentData.Info, pendingInfo); err != nil {
+		return fmt.Errorf("cannot refresh to an incompatible gadget: %v", err)
+	}
+	return nil
 }
 
 var once sync.Once
@@ -255,27 +274,44 @@ func delayedCrossMgrInit() {
 }
 
 // proxyStore returns the store assertion for the proxy store if one is set.
+// If proxy.store lists more than one store the first one with a store
+// assertion is returned.
 func proxyStore(st *state.State, tr *config.Transaction) (*asserts.Store, error) {
+	stores, err := proxyStores(st, tr)
+	if err != nil {
+		return nil, err
+	}
+	return stores[0], nil
+}
+
+// proxyStores returns the store assertions for the proxy stores listed,
+// in order of preference, in proxy.store. Stores without a matching
+// store assertion are skipped.
+func proxyStores(st *state.State, tr *config.Transaction) ([]*asserts.Store, error) {
 	var proxyStore string
 	err := tr.GetMaybe("core", "proxy.store", &proxyStore)
 	if err != nil {
 		return nil, err
 	}
-	if proxyStore == "" {
-		return nil, state.ErrNoState
-	}
 
-	a, err := assertstate.DB(st).Find(asserts.StoreType, map[string]string{
-		"store": proxyStore,
-	})
-	if errors.Is(err, &asserts.NotFoundError{}) {
-		return nil, state.ErrNoState
+	var stores []*asserts.Store
+	for _, storeID := range strutil.CommaSeparatedList(proxyStore) {
+		a, err := assertstate.DB(st).Find(asserts.StoreType, map[string]string{
+			"store": storeID,
+		})
+		if errors.Is(err, &asserts.NotFoundError{}) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, a.(*asserts.Store))
 	}
-	if err != nil {
-		return nil, err
+	if len(stores) == 0 {
+		return nil, state.ErrNoState
 	}
 
-	return a.(*asserts.Store), nil
+	return stores, nil
 }
 
 // interfaceConnected returns true if the given snap/interface names
@@ -622,7 +658,7 @@ func sideInfoAndPathFromID(sis []*snap.SideInfo, paths []string, id string) *pat
 
 func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Model,
 	localSnaps []*snap.SideInfo, paths []string,
-	deviceCtx snapstate.DeviceContext, fromChange string) ([]*state.TaskSet, error) {
+	deviceCtx snapstate.DeviceContext, fromChange string, opts RemodelOptions) ([]*state.TaskSet, error) {
 
 	userID := 0
 	var tss []*state.TaskSet
@@ -641,10 +677,10 @@ func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Mo
 		return nil
 	}
 
-	// If local snaps are provided, all needed snaps must be locally
-	// provided. We check this flag whenever a snap installation/update is
-	// found needed for the remodel.
-	localSnapsRequired := len(localSnaps) > 0
+	// If local snaps are provided or the remodel is offline, all needed
+	// snaps must be locally provided. We check this flag whenever a snap
+	// installation/update is found needed for the remodel.
+	localSnapsRequired := len(localSnaps) > 0 || opts.Offline
 	remodelVar := remodelVariant{localSnapsRequired: localSnapsRequired}
 
 	// kernel
@@ -911,7 +947,22 @@ func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Mo
 		if err != nil {
 			return nil, fmt.Errorf("cannot select non-conflicting label for recovery system %q: %v", labelBase, err)
 		}
-		createRecoveryTasks, err := createRecoverySystemTasks(st, label, snapSetupTasks)
+		// snaps which are not changed by the remodel are reused from
+		// the current default recovery system
+		refLabel, err := boot.DefaultRecoverySystem(deviceCtx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot obtain the default recovery system: %v", err)
+		}
+		// the new system is tried as part of the remodel and becomes
+		// the default recovery system once the remodel is complete
+		opts := CreateRecoverySystemOptions{
+			MarkDefault:     true,
+			TestSystem:      true,
+			ReferenceSystem: refLabel,
+			// the snaps are all provided locally then
+			Offline: localSnapsRequired,
+		}
+		createRecoveryTasks, err := createRecoverySystemTasks(st, label, snapSetupTasks, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -948,6 +999,15 @@ func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Mo
 	return tss, nil
 }
 
+// RemodelOptions holds the options for a remodel.
+type RemodelOptions struct {
+	// Offline is set when the remodel must not contact the store, in
+	// which case all the snaps that need to be installed or updated must
+	// be provided as local snaps. This is implied when local snaps are
+	// provided.
+	Offline bool
+}
+
 // Remodel takes a new model assertion and generates a change that
 // takes the device from the old to the new model or an error if the
 // transition is not possible.
@@ -958,7 +1018,7 @@ func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Mo
 //     (need to check that even unchanged snaps are accessible)
 //   - Make sure this works with Core 20 as well, in the Core 20 case
 //     we must enforce the default-channels from the model as well
-func Remodel(st *state.State, new *asserts.Model, localSnaps []*snap.SideInfo, paths []string) (*state.Change, error) {
+func Remodel(st *state.State, new *asserts.Model, localSnaps []*snap.SideInfo, paths []string, opts RemodelOptions) (*state.Change, error) {
 	var seeded bool
 	err := st.Get("seeded", &seeded)
 	if err != nil && !errors.Is(err, state.ErrNoState) {
@@ -1028,26 +1088,41 @@ func Remodel(st *state.State, new *asserts.Model, localSnaps []*snap.SideInfo, p
 	if err := snapstate.CheckChangeConflictRunExclusively(st, "remodel"); err != nil {
 		return nil, err
 	}
+	if err := checkSerialRenewalConflict(st); err != nil {
+		return nil, err
+	}
 
 	remodCtx, err := remodelCtx(st, current, new)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(localSnaps) > 0 {
+		opts.Offline = true
+	}
+
 	var tss []*state.TaskSet
 	switch remodelKind {
 	case ReregRemodel:
-		if len(localSnaps) > 0 {
-			// TODO support this in the future if a serial
-			// assertion has been provided by a file. To support
-			// this case, we will pass the snaps/paths by setting
-			// local-{snaps,paths} in the task.
-			return nil, fmt.Errorf("cannot remodel offline to different brand ID / model yet")
-		}
+		// request-serial uses a serial assertion for the new model
+		// matching the device key when one was provided along with
+		// the other assertions, without contacting the device service
 		requestSerial := st.NewTask("request-serial", i18n.G("Request new device serial"))
 
 		prepare := st.NewTask("prepare-remodeling", i18n.G("Prepare remodeling"))
 		prepare.WaitFor(requestSerial)
+		if opts.Offline {
+			// being offline, the serial must have been provided
+			if _, err := findProvidedSerial(st, new); err != nil {
+				if errors.Is(err, state.ErrNoState) {
+					return nil, fmt.Errorf("cannot remodel offline to different brand ID / model without a serial assertion for the new model")
+				}
+				return nil, err
+			}
+			prepare.Set("local-snaps", localSnaps)
+			prepare.Set("local-paths", paths)
+			prepare.Set("offline", true)
+		}
 		ts := state.NewTaskSet(requestSerial, prepare)
 		tss = []*state.TaskSet{ts}
 	case StoreSwitchRemodel:
@@ -1056,16 +1131,20 @@ func Remodel(st *state.State, new *asserts.Model, localSnaps []*snap.SideInfo, p
 			return nil, fmt.Errorf("internal error: a store switch remodeling should have built a store")
 		}
 		// ensure a new session accounting for the new brand store
-		st.Unlock()
-		err := sto.EnsureDeviceSession()
-		st.Lock()
-		if err != nil {
-			return nil, fmt.Errorf("cannot get a store session based on the new model assertion: %v", err)
+		// before anything is downloaded, unless all the snaps are
+		// provided locally
+		if !opts.Offline {
+			st.Unlock()
+			err := sto.EnsureDeviceSession()
+			st.Lock()
+			if err != nil {
+				return nil, fmt.Errorf("cannot get a store session based on the new model assertion: %v", err)
+			}
 		}
 		fallthrough
 	case UpdateRemodel:
 		var err error
-		tss, err = remodelTasks(context.TODO(), st, current, new, localSnaps, paths, remodCtx, "")
+		tss, err = remodelTasks(context.TODO(), st, current, new, localSnaps, paths, remodCtx, "", opts)
 		if err != nil {
 			return nil, err
 		}
@@ -1126,6 +1205,26 @@ type recoverySystemSetup struct {
 	// SnapSetupTasks is a list of task IDs that carry snap setup
 	// information, relevant only during remodel, set when tasks are created
 	SnapSetupTasks []string `json:"snap-setup-tasks"`
+	// MarkDefault is set when the recovery system is to become the default
+	// one the bootloader falls back to, which happens only once the system
+	// has been successfully tried, set when tasks are created
+	MarkDefault bool `json:"mark-default,omitempty"`
+	// SkipTest is set when the recovery system is promoted to the list of
+	// good systems without rebooting into it first to try it, set when
+	// tasks are created
+	SkipTest bool `json:"skip-test,omitempty"`
+	// ReferenceSystem is the label of an existing recovery system whose
+	// snap files are reused by the new system when unchanged, set when
+	// tasks are created
+	ReferenceSystem string `json:"reference-system,omitempty"`
+	// Offline is set when snaps which are missing must not be downloaded
+	// from the store, set when tasks are created
+	Offline bool `json:"offline,omitempty"`
+	// NewFiles is a list of snap files that were written to the seed
+	// filesystem while creating the recovery system, both the ones shared
+	// between systems and the ones private to the system, set once the
+	// system was created and used for undo
+	NewFiles []string `json:"new-files,omitempty"`
 }
 
 func pickRecoverySystemLabel(labelBase string) (string, error) {
@@ -1157,16 +1256,13 @@ func pickRecoverySystemLabel(labelBase string) (string, error) {
 	return fmt.Sprintf("%s-%d", labelBase, maxExistingNumber+1), nil
 }
 
-func createRecoverySystemTasks(st *state.State, label string, snapSetupTasks []string) (*state.TaskSet, error) {
-	// precondition check, the directory should not exist yet
-	systemDirectory := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label)
-	exists, _, err := osutil.DirExists(systemDirectory)
-	if err != nil {
+func createRecoverySystemTasks(st *state.State, label string, snapSetupTasks []string, opts CreateRecoverySystemOptions) (*state.TaskSet, error) {
+	// precondition check, the label must be valid and the directory should
+	// not exist yet
+	if err := checkNewSystemLabel(label); err != nil {
 		return nil, err
 	}
-	if exists {
-		return nil, fmt.Errorf("recovery system %q already exists", label)
-	}
+	systemDirectory := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label)
 
 	create := st.NewTask("create-recovery-system", fmt.Sprintf("Create recovery system with label %q", label))
 	// the label we want
@@ -1174,10 +1270,17 @@ func createRecoverySystemTasks(st *state.State, label string, snapSetupTasks []s
 		Label:     label,
 		Directory: systemDirectory,
 		// IDs of the tasks carrying snap-setup
-		SnapSetupTasks: snapSetupTasks,
+		SnapSetupTasks:  snapSetupTasks,
+		MarkDefault:     opts.MarkDefault,
+		SkipTest:        !opts.TestSystem,
+		ReferenceSystem: opts.ReferenceSystem,
+		Offline:         opts.Offline,
 	})
-	// Create recovery system requires us to boot into it before finalize
-	restart.MarkTaskAsRestartBoundary(create, restart.RestartBoundaryDirectionDo)
+	if opts.TestSystem {
+		// testing the recovery system requires us to boot into it
+		// before finalize
+		restart.MarkTaskAsRestartBoundary(create, restart.RestartBoundaryDirectionDo)
+	}
 
 	finalize := st.NewTask("finalize-recovery-system", fmt.Sprintf("Finalize recovery system with label %q", label))
 	finalize.WaitFor(create)
@@ -1186,7 +1289,33 @@ func createRecoverySystemTasks(st *state.State, label string, snapSetupTasks []s
 	return state.NewTaskSet(create, finalize), nil
 }
 
-func CreateRecoverySystem(st *state.State, label string) (*state.Change, error) {
+// ErrRecoverySystemsUnsupported is returned when creating recovery systems
+// on a device without modes, i.e. one which is not UC20+ nor a classic system
+// with modes.
+var ErrRecoverySystemsUnsupported = errors.New("creating recovery systems is not supported on this system")
+
+// CreateRecoverySystemOptions carries the options for creating a new
+// recovery system.
+type CreateRecoverySystemOptions struct {
+	// MarkDefault is set when the new recovery system should become the
+	// default one.
+	MarkDefault bool
+	// TestSystem is set when the new recovery system should be tried by
+	// rebooting into it before it is considered good.
+	TestSystem bool
+	// ReferenceSystem, when set, is the label of an existing recovery
+	// system whose snap files are reused by the new system when the snaps
+	// are unchanged.
+	ReferenceSystem string
+	// Offline is set when the snaps of the new system must not be
+	// downloaded from the store during a remodel.
+	Offline bool
+}
+
+// CreateRecoverySystem creates a change that creates a new recovery system
+// with the given label, using the snaps currently installed on the device.
+// When the label is empty, a label based on the current date is picked.
+func CreateRecoverySystem(st *state.State, label string, opts CreateRecoverySystemOptions) (*state.Change, error) {
 	var seeded bool
 	err := st.Get("seeded", &seeded)
 	if err != nil && !errors.Is(err, state.ErrNoState) {
@@ -1195,8 +1324,22 @@ func CreateRecoverySystem(st *state.State, label string) (*state.Change, error)
 	if !seeded {
 		return nil, fmt.Errorf("cannot create new recovery systems until fully seeded")
 	}
+	deviceCtx, err := DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !deviceCtx.HasModeenv() {
+		return nil, ErrRecoverySystemsUnsupported
+	}
+	if label == "" {
+		labelBase := timeNow().Format("20060102")
+		label, err = pickRecoverySystemLabel(labelBase)
+		if err != nil {
+			return nil, fmt.Errorf("cannot select non-conflicting label for recovery system %q: %v", labelBase, err)
+		}
+	}
 	chg := st.NewChange("create-recovery-system", fmt.Sprintf("Create new recovery system with label %q", label))
-	ts, err := createRecoverySystemTasks(st, label, nil)
+	ts, err := createRecoverySystemTasks(st, label, nil, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -688,6 +688,14 @@ func (fs *fakeSeed) HasArtifact(relName string) bool {
 	return fs.preseedArtifact && relName == "preseed.tgz"
 }
 
+func (fs *fakeSeed) PreseedArtifactPath() string {
+	return fs.ArtifactPath("preseed.tgz")
+}
+
+func (fs *fakeSeed) HasPreseedArtifact() bool {
+	return fs.HasArtifact("preseed.tgz")
+}
+
 func (*fakeSeed) LoadAssertions(db asserts.RODatabase, commitTo func(*asserts.Batch) error) error {
 	return nil
 }
@@ -78,7 +78,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelUnhappyNotSeeded(c *C) {
 		"kernel":       "pc-kernel",
 		"gadget":       "pc",
 	})
-	_, err := devicestate.Remodel(s.state, newModel, nil, nil)
+	_, err := devicestate.Remodel(s.state, newModel, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, ErrorMatches, "cannot remodel until fully seeded")
 }
 
@@ -158,7 +158,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelUnhappy(c *C) {
 	} {
 		mergeMockModelHeaders(cur, t.new)
 		new := s.brands.Model(t.new["brand"].(string), t.new["model"].(string), t.new)
-		chg, err := devicestate.Remodel(s.state, new, nil, nil)
+		chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 		c.Check(chg, IsNil)
 		c.Check(err, ErrorMatches, t.errStr)
 	}
@@ -195,7 +195,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelFromClassicUnhappy(c *C) {
 		"classic":      cur["classic"],
 	})
 
-	_, err := devicestate.Remodel(s.state, new, nil, nil)
+	_, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Check(err, ErrorMatches, `cannot remodel from classic model`)
 }
 
@@ -233,7 +233,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelCheckGrade(c *C) {
 		c.Logf("tc: %v", idx)
 		mergeMockModelHeaders(cur, t.new)
 		new := s.brands.Model(t.new["brand"].(string), t.new["model"].(string), t.new)
-		chg, err := devicestate.Remodel(s.state, new, nil, nil)
+		chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 		c.Check(chg, IsNil)
 		c.Check(err, ErrorMatches, t.errStr)
 	}
@@ -268,7 +268,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelRequiresSerial(c *C) {
 	}
 	mergeMockModelHeaders(cur, newModelHdrs)
 	new := s.brands.Model("canonical", "pc-model", newModelHdrs)
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Check(chg, IsNil)
 	c.Check(err, ErrorMatches, "cannot remodel without a serial")
 }
@@ -367,7 +367,7 @@ func (s *deviceMgrRemodelSuite) testRemodelTasksSwitchTrack(c *C, whatRefreshes
 
 	testDeviceCtx = &snapstatetest.TrivialDeviceContext{Remodeling: true, DeviceModel: new, OldDeviceModel: current}
 
-	tss, err := devicestate.RemodelTasks(context.Background(), s.state, current, new, nil, nil, testDeviceCtx, "99")
+	tss, err := devicestate.RemodelTasks(context.Background(), s.state, current, new, nil, nil, testDeviceCtx, "99", devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	// 2 snaps, plus one track switch plus the remodel task, the
 	// wait chain is tested in TestRemodel*
@@ -550,7 +550,7 @@ func (s *deviceMgrRemodelSuite) testRemodelSwitchTasks(c *C, whatNewTrack map[st
 
 	testDeviceCtx = &snapstatetest.TrivialDeviceContext{Remodeling: true, DeviceModel: new, OldDeviceModel: current}
 
-	tss, err := devicestate.RemodelTasks(context.Background(), s.state, current, new, localSnaps, paths, testDeviceCtx, "99")
+	tss, err := devicestate.RemodelTasks(context.Background(), s.state, current, new, localSnaps, paths, testDeviceCtx, "99", devicestate.RemodelOptions{})
 	if expectedErr == "" {
 		c.Assert(err, IsNil)
 		// 1 per switch-kernel/base/gadget plus the remodel task
@@ -611,7 +611,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelRequiredSnaps(c *C) {
 		"required-snaps": []interface{}{"new-required-snap-1", "new-required-snap-2"},
 		"revision":       "1",
 	})
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg.Summary(), Equals, "Refresh model assertion from revision 0 to 1")
 
@@ -745,7 +745,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelSwitchKernelTrack(c *C) {
 		"required-snaps": []interface{}{"new-required-snap-1"},
 		"revision":       "1",
 	})
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg.Summary(), Equals, "Refresh model assertion from revision 0 to 1")
 
@@ -823,7 +823,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelLessRequiredSnaps(c *C) {
 		"base":         "core18",
 		"revision":     "1",
 	})
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg.Summary(), Equals, "Refresh model assertion from revision 0 to 1")
 
@@ -912,7 +912,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelStoreSwitch(c *C) {
 		return testStore
 	}
 
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg.Summary(), Equals, "Refresh model assertion from revision 0 to 1")
 
@@ -934,6 +934,96 @@ func (s *deviceMgrRemodelSuite) TestRemodelStoreSwitch(c *C) {
 	c.Check(remodCtx.Store(), Equals, testStore)
 }
 
+func (s *deviceMgrRemodelSuite) TestRemodelStoreSwitchLocalNoSession(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("seeded", true)
+	s.state.Set("refresh-privacy-key", "some-privacy-key")
+
+	// set a model assertion
+	s.makeModelAssertionInState(c, "canonical", "pc-model", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+		"base":         "core18",
+	})
+	s.makeSerialAssertionInState(c, "canonical", "pc-model", "1234")
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand:  "canonical",
+		Model:  "pc-model",
+		Serial: "1234",
+	})
+
+	new := s.brands.Model("canonical", "pc-model", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+		"base":         "core18",
+		"store":        "switched-store",
+		"revision":     "1",
+	})
+
+	freshStore := &freshSessionStore{}
+	s.newFakeStore = func(devBE storecontext.DeviceBackend) snapstate.StoreService {
+		return freshStore
+	}
+
+	sis := []*snap.SideInfo{{RealName: "pc-kernel"}}
+	paths := []string{"pc-kernel_1.snap"}
+	chg, err := devicestate.Remodel(s.state, new, sis, paths, devicestate.RemodelOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(chg.Summary(), Equals, "Refresh model assertion from revision 0 to 1")
+
+	// the air-gapped device cannot reach the new store, the session is
+	// only acquired once back online
+	c.Check(freshStore.ensureDeviceSession, Equals, 0)
+
+	tl := chg.Tasks()
+	c.Assert(tl, HasLen, 1)
+	c.Check(tl[0].Kind(), Equals, "set-model")
+}
+
+func (s *deviceMgrRemodelSuite) TestRemodelOfflineMissingSnapFails(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("seeded", true)
+	s.state.Set("refresh-privacy-key", "some-privacy-key")
+
+	// set a model assertion
+	s.makeModelAssertionInState(c, "canonical", "pc-model", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+		"base":         "core18",
+	})
+	s.makeSerialAssertionInState(c, "canonical", "pc-model", "1234")
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand:  "canonical",
+		Model:  "pc-model",
+		Serial: "1234",
+	})
+
+	new := s.brands.Model("canonical", "pc-model", map[string]interface{}{
+		"architecture":   "amd64",
+		"kernel":         "pc-kernel",
+		"gadget":         "pc",
+		"base":           "core18",
+		"required-snaps": []interface{}{"new-required-snap-1"},
+		"revision":       "1",
+	})
+
+	restore := devicestate.MockSnapstateInstallWithDeviceContext(func(ctx context.Context, st *state.State, name string, opts *snapstate.RevisionOptions, userID int, flags snapstate.Flags, deviceCtx snapstate.DeviceContext, fromChange string) (*state.TaskSet, error) {
+		c.Errorf("unexpected install from the store of %q", name)
+		return nil, fmt.Errorf("unexpected")
+	})
+	defer restore()
+
+	// no snaps are provided at all, yet the store must not be used
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{Offline: true})
+	c.Assert(err, ErrorMatches, `no snap file provided for "new-required-snap-1"`)
+	c.Check(chg, IsNil)
+}
+
 func (s *deviceMgrRemodelSuite) TestRemodelRereg(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -971,7 +1061,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelRereg(c *C) {
 		return nil
 	}
 
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 
 	c.Assert(chg.Summary(), Equals, "Remodel device to canonical/rereg-model (0)")
@@ -1032,11 +1122,63 @@ func (s *deviceMgrRemodelSuite) TestRemodelReregLocalFails(c *C) {
 
 	sis := []*snap.SideInfo{{RealName: "pc-kernel"}, {RealName: "pc"}}
 	paths := []string{"pc-kernel_1.snap", "pc_1.snap"}
-	chg, err := devicestate.Remodel(s.state, new, sis, paths)
-	c.Assert(err.Error(), Equals, "cannot remodel offline to different brand ID / model yet")
+	chg, err := devicestate.Remodel(s.state, new, sis, paths, devicestate.RemodelOptions{})
+	c.Assert(err.Error(), Equals, "cannot remodel offline to different brand ID / model without a serial assertion for the new model")
 	c.Assert(chg, IsNil)
 }
 
+func (s *deviceMgrRemodelSuite) TestRemodelReregLocalWithProvidedSerial(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("seeded", true)
+
+	// set a model assertion
+	s.makeModelAssertionInState(c, "canonical", "pc-model", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+		"base":         "core18",
+	})
+	s.makeSerialAssertionInState(c, "canonical", "pc-model", "orig-serial")
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand:           "canonical",
+		Model:           "pc-model",
+		Serial:          "orig-serial",
+		KeyID:           devKey.PublicKey().ID(),
+		SessionMacaroon: "old-session",
+	})
+
+	new := s.brands.Model("canonical", "rereg-model", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+		"base":         "core18",
+	})
+	// the serial for the new model was provided with the other assertions
+	s.makeSerialAssertionInState(c, "canonical", "rereg-model", "new-serial")
+
+	s.newFakeStore = func(devBE storecontext.DeviceBackend) snapstate.StoreService {
+		return nil
+	}
+
+	sis := []*snap.SideInfo{{RealName: "pc-kernel"}, {RealName: "pc"}}
+	paths := []string{"pc-kernel_1.snap", "pc_1.snap"}
+	chg, err := devicestate.Remodel(s.state, new, sis, paths, devicestate.RemodelOptions{})
+	c.Assert(err, IsNil)
+
+	tl := chg.Tasks()
+	c.Assert(tl, HasLen, 2)
+	c.Check(tl[0].Kind(), Equals, "request-serial")
+	c.Check(tl[1].Kind(), Equals, "prepare-remodeling")
+
+	var localSnaps []*snap.SideInfo
+	var localPaths []string
+	c.Assert(tl[1].Get("local-snaps", &localSnaps), IsNil)
+	c.Assert(tl[1].Get("local-paths", &localPaths), IsNil)
+	c.Check(localSnaps, DeepEquals, sis)
+	c.Check(localPaths, DeepEquals, paths)
+}
+
 func (s *deviceMgrRemodelSuite) TestRemodelClash(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -1100,7 +1242,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelClash(c *C) {
 	})
 
 	clashing = other
-	_, err := devicestate.Remodel(s.state, new, nil, nil)
+	_, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Check(err, DeepEquals, &snapstate.ChangeConflictError{
 		Message: "cannot start remodel, clashing with concurrent remodel to canonical/pc-model-other (0)",
 	})
@@ -1112,7 +1254,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelClash(c *C) {
 		Serial: "1234",
 	})
 	clashing = new
-	_, err = devicestate.Remodel(s.state, new, nil, nil)
+	_, err = devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Check(err, DeepEquals, &snapstate.ChangeConflictError{
 		Message: "cannot start remodel, clashing with concurrent remodel to canonical/pc-model (1)",
 	})
@@ -1168,7 +1310,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelClashInProgress(c *C) {
 		"revision":       "1",
 	})
 
-	_, err := devicestate.Remodel(s.state, new, nil, nil)
+	_, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Check(err, DeepEquals, &snapstate.ChangeConflictError{
 		Message:    "cannot start remodel, clashing with concurrent one",
 		ChangeKind: "remodel",
@@ -1214,7 +1356,7 @@ func (s *deviceMgrRemodelSuite) TestReregRemodelClashAnyChange(c *C) {
 	chg := s.state.NewChange("chg", "other change")
 	chg.SetStatus(state.DoingStatus)
 
-	_, err := devicestate.Remodel(s.state, new, nil, nil)
+	_, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, NotNil)
 	c.Assert(err, DeepEquals, &snapstate.ChangeConflictError{
 		ChangeKind: "chg",
@@ -1523,7 +1665,7 @@ volumes:
         type: 00000000-0000-0000-0000-0000deadbeef
 `
 
-	errMatch := `cannot remodel to an incompatible gadget: incompatible layout change: incompatible structure #0 \("foo"\) change: new valid structure size range \[20971520, 20971520\] is not compatible with current \(\[10485760, 10485760\]\)`
+	errMatch := `cannot remodel to an incompatible gadget: incompatible layout change: volume "volume": incompatible structure #0 \("foo"\) change: new valid structure size range \[20971520, 20971520\] is not compatible with current \(\[10485760, 10485760\]\)`
 	s.testCheckGadgetRemodelCompatibleWithYaml(c, compatibleTestMockOkGadget, mockBadGadgetYaml, errMatch)
 }
 
@@ -1738,7 +1880,7 @@ volumes:
 	})
 	defer restore()
 
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	s.state.Unlock()
 
@@ -1829,7 +1971,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelGadgetAssetsParanoidCheck(c *C) {
 	})
 	defer restore()
 
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	s.state.Unlock()
 
@@ -1891,7 +2033,7 @@ func (s *deviceMgrSuite) TestRemodelSwitchBase(c *C) {
 
 	testDeviceCtx = &snapstatetest.TrivialDeviceContext{Remodeling: true, DeviceModel: new, OldDeviceModel: current}
 
-	tss, err := devicestate.RemodelTasks(context.Background(), s.state, current, new, nil, nil, testDeviceCtx, "99")
+	tss, err := devicestate.RemodelTasks(context.Background(), s.state, current, new, nil, nil, testDeviceCtx, "99", devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	// 1 switch to a new base plus the remodel task
 	c.Assert(tss, HasLen, 2)
@@ -2031,7 +2173,11 @@ func (s *deviceMgrRemodelSuite) TestRemodelUC20RequiredSnapsAndRecoverySystem(c
 			},
 		},
 	})
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	// the current default recovery system is used as reference
+	c.Assert(s.bootloader.SetBootVars(map[string]string{
+		"snapd_recovery_system": "1234",
+	}), IsNil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg.Summary(), Equals, "Refresh model assertion from revision 0 to 1")
 
@@ -2118,7 +2264,9 @@ func (s *deviceMgrRemodelSuite) TestRemodelUC20RequiredSnapsAndRecoverySystem(c
 	c.Assert(systemSetupData, DeepEquals, map[string]interface{}{
 		"label":            expectedLabel,
 		"directory":        filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", expectedLabel),
+		"mark-default":     true,
 		"snap-setup-tasks": []interface{}{tDownloadSnap1.ID(), tDownloadSnap2.ID()},
+		"reference-system": "1234",
 	})
 	// cross references of to recovery system setup data
 	for _, tsk := range []*state.Task{tFinalizeRecovery, tSetModel} {
@@ -2317,7 +2465,7 @@ func (s *deviceMgrRemodelSuite) testRemodelUC20SwitchKernelGadgetBaseSnaps(c *C,
 		}
 	}
 
-	chg, err := devicestate.Remodel(s.state, new, localSnaps, paths)
+	chg, err := devicestate.Remodel(s.state, new, localSnaps, paths, devicestate.RemodelOptions{})
 	if testFlags.missingSnap {
 		c.Assert(chg, IsNil)
 		c.Assert(err.Error(), Equals, `no snap file provided for "pc" (track changed)`)
@@ -2425,11 +2573,17 @@ func (s *deviceMgrRemodelSuite) testRemodelUC20SwitchKernelGadgetBaseSnaps(c *C,
 	var systemSetupData map[string]interface{}
 	err = tCreateRecovery.Get("recovery-system-setup", &systemSetupData)
 	c.Assert(err, IsNil)
-	c.Assert(systemSetupData, DeepEquals, map[string]interface{}{
+	expectedSetupData := map[string]interface{}{
 		"label":            expectedLabel,
 		"directory":        filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", expectedLabel),
+		"mark-default":     true,
 		"snap-setup-tasks": []interface{}{tDownloadKernel.ID(), tDownloadBase.ID(), tDownloadGadget.ID()},
-	})
+	}
+	if testFlags.localSnaps {
+		// nothing is downloaded when local snaps are provided
+		expectedSetupData["offline"] = true
+	}
+	c.Assert(systemSetupData, DeepEquals, expectedSetupData)
 }
 
 func (s *deviceMgrRemodelSuite) TestRemodelUC20SwitchKernelBaseGadgetSnapsInstalledSnaps(c *C) {
@@ -2563,7 +2717,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelUC20SwitchKernelBaseGadgetSnapsInstal
 			},
 		},
 	})
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg.Summary(), Equals, "Refresh model assertion from revision 0 to 1")
 
@@ -2676,6 +2830,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelUC20SwitchKernelBaseGadgetSnapsInstal
 	c.Assert(systemSetupData, DeepEquals, map[string]interface{}{
 		"label":            expectedLabel,
 		"directory":        filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", expectedLabel),
+		"mark-default":     true,
 		"snap-setup-tasks": []interface{}{tPrepareKernel.ID(), tPrepareBase.ID(), tPrepareGadget.ID()},
 	})
 }
@@ -2900,7 +3055,7 @@ func (s *deviceMgrRemodelSuite) testRemodelUC20SwitchKernelBaseGadgetSnapsInstal
 		}
 	}
 
-	chg, err := devicestate.Remodel(s.state, new, localSnaps, paths)
+	chg, err := devicestate.Remodel(s.state, new, localSnaps, paths, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg.Summary(), Equals, "Refresh model assertion from revision 0 to 1")
 	if opts.localSnaps {
@@ -3002,16 +3157,22 @@ func (s *deviceMgrRemodelSuite) testRemodelUC20SwitchKernelBaseGadgetSnapsInstal
 	var systemSetupData map[string]interface{}
 	err = tCreateRecovery.Get("recovery-system-setup", &systemSetupData)
 	c.Assert(err, IsNil)
-	c.Assert(systemSetupData, DeepEquals, map[string]interface{}{
+	expectedSetupData := map[string]interface{}{
 		"label":     expectedLabel,
 		"directory": filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", expectedLabel),
 		// tasks carrying snap-setup are tracked
+		"mark-default": true,
 		"snap-setup-tasks": []interface{}{
 			tSwitchChannelKernel.ID(),
 			tSwitchChannelBase.ID(),
 			tSwitchChannelGadget.ID(),
 		},
-	})
+	}
+	if opts.localSnaps {
+		// nothing is downloaded when local snaps are provided
+		expectedSetupData["offline"] = true
+	}
+	c.Assert(systemSetupData, DeepEquals, expectedSetupData)
 }
 
 func (s *deviceMgrRemodelSuite) TestRemodelUC20SwitchKernelBaseSnapsInstalledSnapsWithUpdates(c *C) {
@@ -3151,7 +3312,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelUC20SwitchKernelBaseSnapsInstalledSna
 			},
 		},
 	})
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg.Summary(), Equals, "Refresh model assertion from revision 0 to 1")
 
@@ -3243,6 +3404,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelUC20SwitchKernelBaseSnapsInstalledSna
 	c.Assert(systemSetupData, DeepEquals, map[string]interface{}{
 		"label":            expectedLabel,
 		"directory":        filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", expectedLabel),
+		"mark-default":     true,
 		"snap-setup-tasks": []interface{}{tDownloadKernel.ID(), tDownloadBase.ID()},
 	})
 }
@@ -3369,7 +3531,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelUC20EssentialSnapsTrackingDifferentCh
 			},
 		},
 	})
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg.Summary(), Equals, "Refresh model assertion from revision 0 to 1")
 
@@ -3420,6 +3582,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelUC20EssentialSnapsTrackingDifferentCh
 	c.Assert(systemSetupData, DeepEquals, map[string]interface{}{
 		"label":            expectedLabel,
 		"directory":        filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", expectedLabel),
+		"mark-default":     true,
 		"snap-setup-tasks": nil,
 	})
 }
@@ -3542,7 +3705,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelUC20EssentialSnapsAlreadyInstalledAnd
 			},
 		},
 	})
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg.Summary(), Equals, "Refresh model assertion from revision 0 to 1")
 
@@ -3593,6 +3756,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelUC20EssentialSnapsAlreadyInstalledAnd
 	c.Assert(systemSetupData, DeepEquals, map[string]interface{}{
 		"label":            expectedLabel,
 		"directory":        filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", expectedLabel),
+		"mark-default":     true,
 		"snap-setup-tasks": nil,
 	})
 }
@@ -3725,7 +3889,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelUC20BaseNoDownloadSimpleChannelSwitch
 			},
 		},
 	})
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	c.Assert(chg.Summary(), Equals, "Refresh model assertion from revision 0 to 1")
 
@@ -3780,6 +3944,7 @@ func (s *deviceMgrRemodelSuite) TestRemodelUC20BaseNoDownloadSimpleChannelSwitch
 	c.Assert(systemSetupData, DeepEquals, map[string]interface{}{
 		"label":            expectedLabel,
 		"directory":        filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", expectedLabel),
+		"mark-default":     true,
 		"snap-setup-tasks": nil,
 	})
 }
@@ -3917,7 +4082,7 @@ func (s *deviceMgrRemodelSuite) testRemodelUC20LabelConflicts(c *C, tc remodelUC
 		defer os.Chmod(systemsDir, 0755)
 	}
 
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	if tc.expectedErr == "" {
 		c.Assert(err, IsNil)
 		c.Assert(chg, NotNil)
@@ -4110,6 +4275,9 @@ func (s *deviceMgrRemodelSuite) testUC20RemodelSetModel(c *C, tc uc20RemodelSetM
 		ModelSignKeyID: model.SignKeyID(),
 	}
 	c.Assert(m.WriteTo(""), IsNil)
+	c.Assert(s.bootloader.SetBootVars(map[string]string{
+		"snapd_recovery_system": "0000",
+	}), IsNil)
 
 	now := time.Now()
 	expectedLabel := now.Format("20060102")
@@ -4127,7 +4295,7 @@ func (s *deviceMgrRemodelSuite) testUC20RemodelSetModel(c *C, tc uc20RemodelSetM
 	})
 	defer restore()
 
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	var setModelTask *state.Task
 	for _, tsk := range chg.Tasks() {
@@ -4157,6 +4325,8 @@ func (s *deviceMgrRemodelSuite) testUC20RemodelSetModel(c *C, tc uc20RemodelSetM
 	}
 	if !hasError {
 		c.Check(setModelTask.Log(), HasLen, 0)
+		// the new recovery system is now the default one
+		c.Check(s.bootloader.BootVars["snapd_recovery_system"], Equals, expectedLabel)
 
 		c.Assert(seededSystems, HasLen, 2)
 		// the system was seeded after our mocked 'now' or at the same
@@ -4442,7 +4612,7 @@ func (s *deviceMgrRemodelSuite) testUC20RemodelLocalNonEssential(c *C, tc *uc20R
 		paths = append(paths, pathNotUsed)
 	}
 
-	chg, err := devicestate.Remodel(s.state, new, localSnaps, paths)
+	chg, err := devicestate.Remodel(s.state, new, localSnaps, paths, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 	if tc.isUpdate {
 		c.Check(installWithDeviceContextCalled, Equals, 0)
@@ -4749,7 +4919,7 @@ func (s *deviceMgrRemodelSuite) TestUC20RemodelSetModelWithReboot(c *C) {
 	})
 	defer restore()
 
-	chg, err := devicestate.Remodel(s.state, new, nil, nil)
+	chg, err := devicestate.Remodel(s.state, new, nil, nil, devicestate.RemodelOptions{})
 	c.Assert(err, IsNil)
 
 	// since we cannot panic in random place in code that runs under
@@ -5079,7 +5249,7 @@ plugs:
 
 	testDeviceCtx = &snapstatetest.TrivialDeviceContext{Remodeling: true}
 
-	tss, err := devicestate.RemodelTasks(context.Background(), s.state, current, new, nil, nil, testDeviceCtx, "99")
+	tss, err := devicestate.RemodelTasks(context.Background(), s.state, current, new, nil, nil, testDeviceCtx, "99", devicestate.RemodelOptions{})
 	errMsg := `cannot remodel with incomplete model, the following snaps are required but not listed: "foo-base"`
 	switch {
 	case strutil.ListContains(missingWhat, "base") && strutil.ListContains(missingWhat, "content"):
@@ -5299,7 +5469,7 @@ plugs:
 
 	testDeviceCtx = &snapstatetest.TrivialDeviceContext{Remodeling: true}
 
-	tss, err := devicestate.RemodelTasks(context.Background(), s.state, current, new, nil, nil, testDeviceCtx, "99")
+	tss, err := devicestate.RemodelTasks(context.Background(), s.state, current, new, nil, nil, testDeviceCtx, "99", devicestate.RemodelOptions{})
 	errMsg := `cannot remodel with incomplete model, the following snaps are required but not listed: "bar-base", "foo-base", "foo-content"`
 	c.Assert(err, ErrorMatches, errMsg)
 	c.Assert(tss, IsNil)
@@ -0,0 +1,220 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/storecontext"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store/storetest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type renewalSessionStore struct {
+	storetest.Store
+
+	st    *state.State
+	devBE storecontext.DeviceBackend
+	err   error
+}
+
+func (sto *renewalSessionStore) EnsureDeviceSession() error {
+	if sto.err != nil {
+		return sto.err
+	}
+	sto.st.Lock()
+	defer sto.st.Unlock()
+	serial, err := sto.devBE.Serial()
+	if err != nil {
+		return err
+	}
+	device, err := sto.devBE.Device()
+	if err != nil {
+		return err
+	}
+	device.SessionMacaroon = "session-for-" + serial.Serial()
+	return sto.devBE.SetDevice(device)
+}
+
+func (s *deviceMgrSerialSuite) setupRenewal(c *C, sessionErr error) *asserts.Serial {
+	s.makeModelAssertionInState(c, "canonical", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+	})
+	devicestatetest.MockGadget(c, s.state, "pc", snap.R(2), nil)
+
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand:           "canonical",
+		Model:           "pc",
+		KeyID:           devKey.PublicKey().ID(),
+		Serial:          "1234",
+		SessionMacaroon: "old-session",
+	})
+	devicestate.KeypairManager(s.mgr).Put(devKey)
+	serial := s.makeSerialAssertionInState(c, "canonical", "pc", "1234")
+
+	s.newFakeStore = func(devBE storecontext.DeviceBackend) snapstate.StoreService {
+		return &renewalSessionStore{st: s.state, devBE: devBE, err: sessionErr}
+	}
+	return serial
+}
+
+func (s *deviceMgrSerialSuite) runRenewal(c *C) *state.Change {
+	chg, err := devicestate.RenewSerial(s.state)
+	c.Assert(err, IsNil)
+
+	s.state.Unlock()
+	for i := 0; i < 3; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+	}
+	s.state.Lock()
+	return chg
+}
+
+func (s *deviceMgrSerialSuite) TestRenewSerialHappy(c *C) {
+	r1 := devicestate.MockKeyLength(testKeyLength)
+	defer r1()
+
+	mockServer := s.mockServer(c, "REQID-1", nil)
+	defer mockServer.Close()
+
+	r2 := devicestate.MockBaseStoreURL(mockServer.URL)
+	defer r2()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	origSerial := s.setupRenewal(c, nil)
+
+	chg := s.runRenewal(c)
+	c.Assert(chg.Err(), IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(chg.Summary(), Equals, "Renew device serial 1234")
+
+	device, err := devicestatetest.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device.Brand, Equals, "canonical")
+	c.Check(device.Model, Equals, "pc")
+	c.Check(device.Serial, Equals, "9999")
+	c.Check(device.KeyID, Not(Equals), devKey.PublicKey().ID())
+	c.Check(device.SessionMacaroon, Equals, "session-for-9999")
+
+	// the new serial is for the new key
+	a, err := s.db.Find(asserts.SerialType, map[string]string{
+		"brand-id": "canonical",
+		"model":    "pc",
+		"serial":   "9999",
+	})
+	c.Assert(err, IsNil)
+	c.Check(a.(*asserts.Serial).DeviceKey().ID(), Equals, device.KeyID)
+	_, err = devicestate.KeypairManager(s.mgr).Get(device.KeyID)
+	c.Check(err, IsNil)
+
+	// the old key is gone
+	_, err = devicestate.KeypairManager(s.mgr).Get(devKey.PublicKey().ID())
+	c.Check(err, ErrorMatches, "cannot find key pair")
+
+	// but the old serial was kept
+	previous, err := s.mgr.PreviousSerials()
+	c.Assert(err, IsNil)
+	c.Assert(previous, HasLen, 1)
+	c.Check(previous[0].Serial(), Equals, "1234")
+	c.Check(asserts.Encode(previous[0]), DeepEquals, asserts.Encode(origSerial))
+}
+
+func (s *deviceMgrSerialSuite) TestRenewSerialNoSessionKeepsCurrent(c *C) {
+	r1 := devicestate.MockKeyLength(testKeyLength)
+	defer r1()
+
+	mockServer := s.mockServer(c, "REQID-1", nil)
+	defer mockServer.Close()
+
+	r2 := devicestate.MockBaseStoreURL(mockServer.URL)
+	defer r2()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRenewal(c, fmt.Errorf("boom"))
+
+	chg := s.runRenewal(c)
+	c.Assert(chg.Err(), ErrorMatches, `(?s).*cannot get a store session with the new device serial: boom.*`)
+
+	// nothing changed
+	device, err := devicestatetest.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device, DeepEquals, &auth.DeviceState{
+		Brand:           "canonical",
+		Model:           "pc",
+		KeyID:           devKey.PublicKey().ID(),
+		Serial:          "1234",
+		SessionMacaroon: "old-session",
+	})
+	_, err = devicestate.KeypairManager(s.mgr).Get(devKey.PublicKey().ID())
+	c.Check(err, IsNil)
+
+	// the new key was removed
+	var renewed auth.DeviceState
+	c.Check(chg.Get("device", &renewed), testutil.ErrorIs, state.ErrNoState)
+
+	previous, err := s.mgr.PreviousSerials()
+	c.Assert(err, IsNil)
+	c.Check(previous, HasLen, 0)
+}
+
+func (s *deviceMgrSerialSuite) TestRenewSerialNotRegistered(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand: "canonical",
+		Model: "pc",
+		KeyID: devKey.PublicKey().ID(),
+	})
+
+	_, err := devicestate.RenewSerial(s.state)
+	c.Check(err, ErrorMatches, "cannot renew serial of a device that is not registered yet")
+}
+
+func (s *deviceMgrSerialSuite) TestRenewSerialConflict(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRenewal(c, nil)
+
+	chg, err := devicestate.RenewSerial(s.state)
+	c.Assert(err, IsNil)
+
+	_, err = devicestate.RenewSerial(s.state)
+	c.Check(err, ErrorMatches, "serial renewal in progress, no other registration changes allowed until this is done")
+	c.Assert(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+	c.Check(err.(*snapstate.ChangeConflictError).ChangeID, Equals, chg.ID())
+}
@@ -1656,6 +1656,54 @@ func (s *deviceMgrSerialSuite) TestStoreContextBackendProxyStore(c *C) {
 	c.Assert(sto.URL().String(), Equals, mockServer.URL)
 }
 
+func (s *deviceMgrSerialSuite) TestStoreContextBackendProxyStores(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	scb := s.mgr.StoreContextBackend()
+
+	// nothing in the state
+	_, err := scb.ProxyStores()
+	c.Check(err, testutil.ErrorIs, state.ErrNoState)
+
+	// have stores referenced, in order of preference
+	tr := config.NewTransaction(s.state)
+	err = tr.Set("core", "proxy.store", "foo,bar,baz")
+	tr.Commit()
+	c.Assert(err, IsNil)
+
+	_, err = scb.ProxyStores()
+	c.Check(err, testutil.ErrorIs, state.ErrNoState)
+
+	operatorAcct := assertstest.NewAccount(s.storeSigning, "foo-operator", nil, "")
+	assertstatetest.AddMany(s.state, operatorAcct)
+	// have store assertions for some of them
+	for _, sto := range []struct{ id, url string }{
+		{"baz", "http://baz.internal"},
+		{"foo", "http://foo.internal"},
+	} {
+		stoAs, err := s.storeSigning.Sign(asserts.StoreType, map[string]interface{}{
+			"store":       sto.id,
+			"operator-id": operatorAcct.AccountID(),
+			"url":         sto.url,
+			"timestamp":   time.Now().Format(time.RFC3339),
+		}, nil, "")
+		c.Assert(err, IsNil)
+		assertstatetest.AddMany(s.state, stoAs)
+	}
+
+	stores, err := scb.ProxyStores()
+	c.Assert(err, IsNil)
+	c.Assert(stores, HasLen, 2)
+	c.Check(stores[0].Store(), Equals, "foo")
+	c.Check(stores[1].Store(), Equals, "baz")
+
+	// the single proxy store is the first one
+	sto, err := scb.ProxyStore()
+	c.Assert(err, IsNil)
+	c.Check(sto.Store(), Equals, "foo")
+}
+
 func (s *deviceMgrSerialSuite) TestStoreContextBackendStoreAccess(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
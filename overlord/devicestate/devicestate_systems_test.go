@@ -21,10 +21,14 @@ package devicestate_test
 
 import (
 	"bytes"
+	"context"
+	"crypto"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -38,19 +42,25 @@ import (
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/gadget"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/assertstate/assertstatetest"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
+	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/install"
 	"github.com/snapcore/snapd/overlord/restart"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/secboot"
 	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/seed/seedtest"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -318,6 +328,62 @@ func (s *deviceMgrSystemsSuite) TestListSeedSystemsNoCurrent(c *C) {
 	}})
 }
 
+func (s *deviceMgrSystemsSuite) TestSystemSnaps(c *C) {
+	opened := 0
+	restore := devicestate.MockSeedOpen(func(seedDir, label string) (seed.Seed, error) {
+		opened++
+		return seed.Open(seedDir, label)
+	})
+	defer restore()
+	now := time.Now()
+	restore = devicestate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	snaps, err := s.mgr.SystemSnaps("20191119")
+	c.Assert(err, IsNil)
+	var names []string
+	for _, sn := range snaps {
+		names = append(names, sn.Name)
+		c.Check(sn.Revision, Equals, snap.R(1))
+		c.Check(sn.Asserted, Equals, true)
+		c.Check(sn.Size > 0, Equals, true)
+		// the snaps are used by all the systems
+		c.Check(sn.Shared, Equals, true)
+	}
+	c.Check(names, DeepEquals, []string{"snapd", "pc-kernel", "core20", "pc"})
+	c.Check(snaps[1].Channel, Equals, "20")
+	// all the systems were loaded
+	c.Check(opened, Equals, 3)
+
+	// the systems are cached for a short while
+	_, err = s.mgr.SystemSnaps("20200318")
+	c.Assert(err, IsNil)
+	c.Check(opened, Equals, 3)
+
+	now = now.Add(2 * time.Minute)
+	_, err = s.mgr.SystemSnaps("20200318")
+	c.Assert(err, IsNil)
+	c.Check(opened, Equals, 6)
+
+	// the other systems are not shared anymore once removed
+	err = os.RemoveAll(filepath.Join(dirs.SnapSeedDir, "systems", "20200318"))
+	c.Assert(err, IsNil)
+	err = os.RemoveAll(filepath.Join(dirs.SnapSeedDir, "systems", "other-20200318"))
+	c.Assert(err, IsNil)
+	now = now.Add(2 * time.Minute)
+	snaps, err = s.mgr.SystemSnaps("20191119")
+	c.Assert(err, IsNil)
+	c.Assert(snaps, HasLen, 4)
+	for _, sn := range snaps {
+		c.Check(sn.Shared, Equals, false)
+	}
+}
+
+func (s *deviceMgrSystemsSuite) TestSystemSnapsNotFound(c *C) {
+	_, err := s.mgr.SystemSnaps("missing")
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
 func (s *deviceMgrSystemsSuite) TestListSeedSystemsCurrentSingleSeeded(c *C) {
 	s.state.Lock()
 	s.state.Set("seeded-systems", []devicestate.SeededSystem{
@@ -1171,6 +1237,438 @@ func (s *deviceMgrSystemsSuite) TestDeviceManagerEnsureTriedSystemManyLabels(c *
 	c.Check(s.logbuf.String(), testutil.Contains, `tried recovery system "1234" was successful`)
 }
 
+func (s *deviceMgrSystemsSuite) TestPromoteTriedRecoverySystemHappy(c *C) {
+	err := s.bootloader.SetBootVars(map[string]string{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "tried",
+	})
+	c.Assert(err, IsNil)
+
+	modeenv := boot.Modeenv{
+		Mode: boot.ModeRun,
+		// the system is in CurrentRecoverySystems
+		CurrentRecoverySystems: []string{"29112019", "1234"},
+		GoodRecoverySystems:    []string{"29112019"},
+	}
+	err = modeenv.WriteTo("")
+	c.Assert(err, IsNil)
+
+	resealCalls := 0
+	restore := boot.MockResealKeyToModeenv(func(rootdir string, modeenv *boot.Modeenv, expectReseal bool, u boot.Unlocker) error {
+		resealCalls++
+		c.Check(modeenv.GoodRecoverySystems, DeepEquals, []string{"29112019", "1234"})
+		return nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	err = devicestate.PromoteTriedRecoverySystem(s.state, "1234")
+	c.Assert(err, IsNil)
+	// the keys are resealed only once
+	c.Check(resealCalls, Equals, 1)
+
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.CurrentRecoverySystems, DeepEquals, []string{"29112019", "1234"})
+	c.Check(m.GoodRecoverySystems, DeepEquals, []string{"29112019", "1234"})
+
+	vars, err := s.bootloader.GetBootVars("try_recovery_system", "recovery_system_status")
+	c.Assert(err, IsNil)
+	c.Check(vars, DeepEquals, map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+	})
+	var triedSystems []string
+	c.Check(s.state.Get("tried-systems", &triedSystems), testutil.ErrorIs, state.ErrNoState)
+	c.Check(s.logbuf.String(), testutil.Contains, `promoted tried recovery system "1234"`)
+}
+
+func (s *deviceMgrSystemsSuite) testPromoteTriedRecoverySystemUpdatesMenu(c *C, menuErr error) {
+	err := s.bootloader.SetBootVars(map[string]string{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "tried",
+	})
+	c.Assert(err, IsNil)
+
+	modeenv := boot.Modeenv{
+		Mode:                   boot.ModeRun,
+		CurrentRecoverySystems: []string{"29112019", "1234"},
+		GoodRecoverySystems:    []string{"29112019"},
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	restore := boot.MockResealKeyToModeenv(func(rootdir string, modeenv *boot.Modeenv, expectReseal bool, u boot.Unlocker) error {
+		return nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	si := &snap.SideInfo{RealName: "pc", Revision: snap.R(1), SnapID: s.ss.AssertedSnapID("pc")}
+	gadgetInfo := snaptest.MockSnapWithFiles(c, "name: pc\nversion: 1\ntype: gadget", si, nil)
+	snapstate.Set(s.state, "pc", &snapstate.SnapState{
+		SnapType: "gadget",
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+	})
+
+	menuCalls := 0
+	restore = devicestate.MockBootUpdateRecoverySystemsMenu(func(dev snap.Device, gadgetSnapOrDir string) error {
+		menuCalls++
+		c.Check(dev.HasModeenv(), Equals, true)
+		c.Check(gadgetSnapOrDir, Equals, gadgetInfo.MountFile())
+		// the menu is updated once the system is promoted
+		m, err := boot.ReadModeenv("")
+		c.Assert(err, IsNil)
+		c.Check(m.GoodRecoverySystems, DeepEquals, []string{"29112019", "1234"})
+		return menuErr
+	})
+	defer restore()
+
+	err = devicestate.PromoteTriedRecoverySystem(s.state, "1234")
+	c.Assert(err, IsNil)
+	c.Check(menuCalls, Equals, 1)
+}
+
+func (s *deviceMgrSystemsSuite) TestPromoteTriedRecoverySystemUpdatesMenu(c *C) {
+	s.testPromoteTriedRecoverySystemUpdatesMenu(c, nil)
+	c.Check(s.logbuf.String(), Not(testutil.Contains), "cannot update the menu of recovery systems")
+}
+
+func (s *deviceMgrSystemsSuite) TestPromoteTriedRecoverySystemUpdateMenuErrorNotFatal(c *C) {
+	s.testPromoteTriedRecoverySystemUpdatesMenu(c, errors.New("boom"))
+	c.Check(s.logbuf.String(), testutil.Contains, "cannot update the menu of recovery systems: boom")
+}
+
+func (s *deviceMgrSystemsSuite) TestPromoteTriedRecoverySystemOutcomeAlreadyObserved(c *C) {
+	// the outcome was observed after rebooting, the try boot variables
+	// have been cleared and the system is listed as tried
+	modeenv := boot.Modeenv{
+		Mode:                   boot.ModeRun,
+		CurrentRecoverySystems: []string{"29112019", "1234"},
+		GoodRecoverySystems:    []string{"29112019"},
+		// the system is being tried in the course of a remodel
+		TryModel:          "my-model-2",
+		TryBrandID:        "my-brand",
+		TryGrade:          "dangerous",
+		TryModelSignKeyID: "some-key",
+	}
+	err := modeenv.WriteTo("")
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("tried-systems", []string{"0000", "1234"})
+
+	err = devicestate.PromoteTriedRecoverySystem(s.state, "1234")
+	c.Assert(err, IsNil)
+
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.GoodRecoverySystems, DeepEquals, []string{"29112019", "1234"})
+	// the try model is kept
+	c.Check(m.TryModel, Equals, "my-model-2")
+
+	var triedSystems []string
+	c.Assert(s.state.Get("tried-systems", &triedSystems), IsNil)
+	c.Check(triedSystems, DeepEquals, []string{"0000"})
+
+	// another system was recorded as tried
+	err = devicestate.PromoteTriedRecoverySystem(s.state, "5678")
+	c.Assert(err, ErrorMatches, `cannot promote recovery system "5678": tried recovery system is "0000"`)
+	var mismatchErr *devicestate.TriedRecoverySystemMismatchError
+	c.Check(errors.As(err, &mismatchErr), Equals, true)
+}
+
+func (s *deviceMgrSystemsSuite) TestPromoteTriedRecoverySystemBeingCreated(c *C) {
+	err := s.bootloader.SetBootVars(map[string]string{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "tried",
+	})
+	c.Assert(err, IsNil)
+
+	modeenv := boot.Modeenv{
+		Mode:                   boot.ModeRun,
+		CurrentRecoverySystems: []string{"29112019", "1234"},
+		GoodRecoverySystems:    []string{"29112019"},
+	}
+	err = modeenv.WriteTo("")
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	chg := s.state.NewChange("create-recovery-system", "...")
+	t := s.state.NewTask("finalize-recovery-system", "...")
+	t.Set("recovery-system-setup", map[string]interface{}{
+		"label": "1234",
+	})
+	chg.AddTask(t)
+
+	// the system is promoted by the change
+	err = devicestate.PromoteTriedRecoverySystem(s.state, "1234")
+	c.Assert(err, ErrorMatches, `cannot promote recovery system "1234": system is being created`)
+	var conflictErr *snapstate.ChangeConflictError
+	c.Check(errors.As(err, &conflictErr), Equals, true)
+
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.GoodRecoverySystems, DeepEquals, []string{"29112019"})
+	vars, err := s.bootloader.GetBootVars("try_recovery_system", "recovery_system_status")
+	c.Assert(err, IsNil)
+	c.Check(vars, DeepEquals, map[string]string{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "tried",
+	})
+}
+
+func (s *deviceMgrSystemsSuite) TestPromoteTriedRecoverySystemMismatch(c *C) {
+	err := s.bootloader.SetBootVars(map[string]string{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "tried",
+	})
+	c.Assert(err, IsNil)
+
+	modeenv := boot.Modeenv{
+		Mode:                   boot.ModeRun,
+		CurrentRecoverySystems: []string{"29112019", "1234"},
+		GoodRecoverySystems:    []string{"29112019"},
+	}
+	err = modeenv.WriteTo("")
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	err = devicestate.PromoteTriedRecoverySystem(s.state, "5678")
+	c.Assert(err, ErrorMatches, `cannot promote recovery system "5678": tried recovery system is "1234"`)
+	var mismatchErr *devicestate.TriedRecoverySystemMismatchError
+	c.Assert(errors.As(err, &mismatchErr), Equals, true)
+	c.Check(mismatchErr.Label, Equals, "5678")
+	c.Check(mismatchErr.TriedLabel, Equals, "1234")
+
+	// nothing was changed
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.GoodRecoverySystems, DeepEquals, []string{"29112019"})
+	vars, err := s.bootloader.GetBootVars("try_recovery_system", "recovery_system_status")
+	c.Assert(err, IsNil)
+	c.Check(vars, DeepEquals, map[string]string{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "tried",
+	})
+
+	// no system was tried at all
+	err = s.bootloader.SetBootVars(map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+	})
+	c.Assert(err, IsNil)
+	err = devicestate.PromoteTriedRecoverySystem(s.state, "1234")
+	c.Assert(err, ErrorMatches, `cannot promote recovery system "1234": no recovery system was tried`)
+	c.Check(errors.As(err, &mismatchErr), Equals, true)
+}
+
+func (s *deviceMgrSystemsSuite) TestPromoteTriedRecoverySystemNotSuccessful(c *C) {
+	// the status is still try, the system failed to boot
+	err := s.bootloader.SetBootVars(map[string]string{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "try",
+	})
+	c.Assert(err, IsNil)
+
+	modeenv := boot.Modeenv{
+		Mode:                   boot.ModeRun,
+		CurrentRecoverySystems: []string{"29112019", "1234"},
+		GoodRecoverySystems:    []string{"29112019"},
+	}
+	err = modeenv.WriteTo("")
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	err = devicestate.PromoteTriedRecoverySystem(s.state, "1234")
+	c.Assert(err, ErrorMatches, `cannot promote recovery system "1234": system has not been successfully tried`)
+
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.GoodRecoverySystems, DeepEquals, []string{"29112019"})
+
+	// inconsistent boot variables
+	err = s.bootloader.SetBootVars(map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "tried",
+	})
+	c.Assert(err, IsNil)
+	err = devicestate.PromoteTriedRecoverySystem(s.state, "1234")
+	c.Assert(err, ErrorMatches, `cannot promote recovery system "1234": try recovery system is unset but status is "tried"`)
+}
+
+func (s *deviceMgrSystemsSuite) setDeviceFromSeed(c *C, idx int) {
+	sys := s.mockedSystemSeeds[idx]
+	s.setupBrands()
+	assertstatetest.AddMany(s.state, sys.model)
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand:  sys.model.BrandID(),
+		Model:  sys.model.Model(),
+		Serial: "serialserialserial",
+	})
+}
+
+func (s *deviceMgrSystemsSuite) TestPrepareFactoryResetHappy(c *C) {
+	modeenv := boot.Modeenv{
+		Mode:                   boot.ModeRun,
+		CurrentRecoverySystems: []string{"20191119", "20200318"},
+		GoodRecoverySystems:    []string{"20191119", "20200318"},
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	restore := devicestate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+	restore = devicestate.MockOsutilBootID("boot-id-1")
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setDeviceFromSeed(c, 0)
+
+	req, err := devicestate.PendingFactoryReset(s.state)
+	c.Assert(err, IsNil)
+	c.Check(req, IsNil)
+
+	err = devicestate.PrepareFactoryReset(s.state, "20191119")
+	c.Assert(err, IsNil)
+
+	m, err := s.bootloader.GetBootVars("snapd_recovery_mode", "snapd_recovery_system")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snapd_recovery_mode":   "factory-reset",
+		"snapd_recovery_system": "20191119",
+	})
+	req, err = devicestate.PendingFactoryReset(s.state)
+	c.Assert(err, IsNil)
+	c.Check(req, DeepEquals, &devicestate.FactoryResetRequest{
+		Label:  "20191119",
+		Time:   now,
+		BootID: "boot-id-1",
+	})
+	c.Check(s.logbuf.String(), testutil.Contains, `prepared factory reset from recovery system "20191119"`)
+}
+
+func (s *deviceMgrSystemsSuite) TestPendingFactoryResetClearedAfterReboot(c *C) {
+	restore := devicestate.MockOsutilBootID("boot-id-1")
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("pending-factory-reset", &devicestate.FactoryResetRequest{
+		Label:  "20191119",
+		Time:   time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC),
+		BootID: "boot-id-1",
+	})
+
+	// snapd restarted, but the device was not rebooted yet
+	c.Assert(devicestate.ClearFactoryResetAfterReboot(s.state), IsNil)
+	req, err := devicestate.PendingFactoryReset(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(req, NotNil)
+	c.Check(req.Label, Equals, "20191119")
+
+	// the device rebooted, but the reset did not wipe the state
+	restore = devicestate.MockOsutilBootID("boot-id-2")
+	defer restore()
+	c.Assert(devicestate.ClearFactoryResetAfterReboot(s.state), IsNil)
+	req, err = devicestate.PendingFactoryReset(s.state)
+	c.Assert(err, IsNil)
+	c.Check(req, IsNil)
+	c.Check(s.logbuf.String(), testutil.Contains, `factory reset from recovery system "20191119" prepared at 2023-03-01 12:00:00 +0000 UTC did not happen`)
+}
+
+func (s *deviceMgrSystemsSuite) TestPrepareFactoryResetMostRecentGoodSystem(c *C) {
+	modeenv := boot.Modeenv{
+		Mode:                   boot.ModeRun,
+		CurrentRecoverySystems: []string{"20191119", "20200318", "1234"},
+		// 1234 is being tried
+		GoodRecoverySystems: []string{"20191119", "20200318"},
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setDeviceFromSeed(c, 1)
+
+	err := devicestate.PrepareFactoryReset(s.state, "")
+	c.Assert(err, IsNil)
+
+	m, err := s.bootloader.GetBootVars("snapd_recovery_mode", "snapd_recovery_system")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snapd_recovery_mode":   "factory-reset",
+		"snapd_recovery_system": "20200318",
+	})
+	req, err := devicestate.PendingFactoryReset(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(req, NotNil)
+	c.Check(req.Label, Equals, "20200318")
+}
+
+func (s *deviceMgrSystemsSuite) TestPrepareFactoryResetErrors(c *C) {
+	modeenv := boot.Modeenv{
+		Mode:                   boot.ModeRun,
+		CurrentRecoverySystems: []string{"20191119", "20200318"},
+		GoodRecoverySystems:    []string{"20191119", "20200318"},
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setDeviceFromSeed(c, 0)
+
+	// not a good system
+	err := devicestate.PrepareFactoryReset(s.state, "1234")
+	c.Assert(err, ErrorMatches, `cannot prepare factory reset from recovery system "1234": system is not a good recovery system`)
+
+	// system of another model
+	err = devicestate.PrepareFactoryReset(s.state, "20200318")
+	c.Assert(err, ErrorMatches, `cannot prepare factory reset from recovery system "20200318": system is for model my-brand/my-model-2, not my-brand/my-model`)
+
+	// the snap files of the system are corrupted
+	snapFiles, err := filepath.Glob(filepath.Join(dirs.SnapSeedDir, "snaps", "pc-kernel_*.snap"))
+	c.Assert(err, IsNil)
+	c.Assert(snapFiles, HasLen, 1)
+	data, err := os.ReadFile(snapFiles[0])
+	c.Assert(err, IsNil)
+	data[len(data)-1] ^= 0xff
+	c.Assert(os.WriteFile(snapFiles[0], data, 0644), IsNil)
+	err = devicestate.PrepareFactoryReset(s.state, "20191119")
+	c.Assert(err, ErrorMatches, `cannot prepare factory reset from recovery system "20191119": cannot validate .* for snap "pc-kernel" .*, hash mismatch with snap-revision`)
+
+	// no good recovery systems
+	modeenv.GoodRecoverySystems = nil
+	c.Assert(modeenv.WriteTo(""), IsNil)
+	err = devicestate.PrepareFactoryReset(s.state, "")
+	c.Assert(err, ErrorMatches, `cannot prepare factory reset: no good recovery system`)
+
+	// not in run mode
+	modeenv.Mode = boot.ModeRecover
+	c.Assert(modeenv.WriteTo(""), IsNil)
+	err = devicestate.PrepareFactoryReset(s.state, "20191119")
+	c.Assert(err, ErrorMatches, `cannot prepare factory reset in "recover" mode`)
+
+	// the boot environment is left alone
+	m, err := s.bootloader.GetBootVars("snapd_recovery_mode", "snapd_recovery_system")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snapd_recovery_mode":   "",
+		"snapd_recovery_system": "",
+	})
+	req, err := devicestate.PendingFactoryReset(s.state)
+	c.Assert(err, IsNil)
+	c.Check(req, IsNil)
+}
+
 func (s *deviceMgrSystemsSuite) TestRecordSeededSystem(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -1306,6 +1804,14 @@ type deviceMgrSystemsCreateSuite struct {
 	bootloader *bootloadertest.MockRecoveryAwareTrustedAssetsBootloader
 }
 
+// recoverySystemModelDigest returns the digest of the model of a recovery
+// system, as tracked in the modeenv
+func recoverySystemModelDigest(model *asserts.Model) string {
+	h := crypto.SHA3_384.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n", model.BrandID(), model.Model(), model.Grade(), model.SignKeyID())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 func (s *deviceMgrSystemsCreateSuite) SetUpTest(c *C) {
 	s.deviceMgrSystemsBaseSuite.SetUpTest(c)
 
@@ -1319,7 +1825,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemTasks
 
 	s.state.Lock()
 	defer s.state.Unlock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
 	c.Assert(err, IsNil)
 	c.Assert(chg, NotNil)
 	tsks := chg.Tasks()
@@ -1350,23 +1856,145 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemTasks
 
 	s.state.Lock()
 	defer s.state.Unlock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
 	c.Assert(err, ErrorMatches, `recovery system "1234" already exists`)
+	c.Check(err, DeepEquals, &devicestate.ErrSystemExists{Label: "1234"})
 	c.Check(chg, IsNil)
 }
 
-func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemNotSeeded(c *C) {
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemTasksInvalidLabel(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234_foo", devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Assert(err, ErrorMatches, `invalid seed system label: "1234_foo"`)
+	c.Check(chg, IsNil)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems"), testutil.FileAbsent)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemNotSeeded(c *C) {
 	devicestate.SetBootOkRan(s.mgr, true)
 
 	s.state.Lock()
 	defer s.state.Unlock()
 	s.state.Set("seeded", nil)
 
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
 	c.Assert(err, ErrorMatches, `cannot create new recovery systems until fully seeded`)
 	c.Check(chg, IsNil)
 }
 
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemNotSupported(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	// UC18 model
+	s.makeModelAssertionInState(c, "canonical", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+		"base":         "core18",
+	})
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand: "canonical",
+		Model: "pc",
+	})
+
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{})
+	c.Assert(err, Equals, devicestate.ErrRecoverySystemsUnsupported)
+	c.Check(err, ErrorMatches, `creating recovery systems is not supported on this system`)
+	c.Check(chg, IsNil)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemPicksLabel(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+	restore := devicestate.MockTimeNow(func() time.Time {
+		return time.Date(2023, 10, 16, 1, 2, 3, 0, time.UTC)
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	chg, err := devicestate.CreateRecoverySystem(s.state, "", devicestate.CreateRecoverySystemOptions{})
+	c.Assert(err, IsNil)
+	c.Check(chg.Summary(), Equals, `Create new recovery system with label "20231016"`)
+
+	// a system with the same label exists already
+	c.Assert(os.MkdirAll(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/20231016"), 0755), IsNil)
+	chg, err = devicestate.CreateRecoverySystem(s.state, "", devicestate.CreateRecoverySystemOptions{})
+	c.Assert(err, IsNil)
+	c.Check(chg.Summary(), Equals, `Create new recovery system with label "20231016-1"`)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemNoTestHappy(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{
+		MarkDefault: true,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(chg, NotNil)
+	tsks := chg.Tasks()
+	c.Check(tsks, HasLen, 2)
+	tskCreate := tsks[0]
+	tskFinalize := tsks[1]
+	var systemSetupData map[string]interface{}
+	err = tskCreate.Get("recovery-system-setup", &systemSetupData)
+	c.Assert(err, IsNil)
+	c.Check(systemSetupData, DeepEquals, map[string]interface{}{
+		"label":            "1234",
+		"directory":        filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"),
+		"snap-setup-tasks": nil,
+		"mark-default":     true,
+		"skip-test":        true,
+	})
+
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the system is not tried
+	c.Assert(chg.Err(), IsNil)
+	c.Check(chg.IsReady(), Equals, true)
+	c.Check(tskCreate.Status(), Equals, state.DoneStatus)
+	c.Check(tskFinalize.Status(), Equals, state.DoneStatus)
+	c.Check(s.restartRequests, HasLen, 0)
+
+	validateCore20Seed(c, "1234", s.model, s.storeSigning.Trusted)
+	m, err := s.bootloader.GetBootVars("try_recovery_system", "recovery_system_status", "snapd_recovery_system")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+		// the system became the default one
+		"snapd_recovery_system": "1234",
+	})
+	modeenvAfterFinalize, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvAfterFinalize, testutil.JsonEquals, boot.Modeenv{
+		Mode:                   "run",
+		Base:                   "core20_3.snap",
+		CurrentKernels:         []string{"pc-kernel_2.snap"},
+		CurrentRecoverySystems: []string{"othersystem", "1234"},
+		GoodRecoverySystems:    []string{"othersystem", "1234"},
+		RecoverySystemModels: map[string]string{
+			"1234": recoverySystemModelDigest(s.model),
+		},
+
+		Model:          s.model.Model(),
+		BrandID:        s.model.BrandID(),
+		Grade:          string(s.model.Grade()),
+		ModelSignKeyID: s.model.SignKeyID(),
+	})
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", "1234", "snapd-new-file-log"), testutil.FileAbsent)
+}
+
 func (s *deviceMgrSystemsCreateSuite) makeSnapInState(c *C, name string, rev snap.Revision) *snap.Info {
 	snapID := s.ss.AssertedSnapID(name)
 	if rev.Unset() || rev.Local() {
@@ -1420,11 +2048,85 @@ func (s *deviceMgrSystemsCreateSuite) mockStandardSnapsModeenvAndBootloaderState
 	c.Assert(err, IsNil)
 }
 
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemValidationSetsErr(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	vsa, err := s.storeSigning.Sign(asserts.ValidationSetType, map[string]interface{}{
+		"type":         "validation-set",
+		"authority-id": "canonical",
+		"series":       "16",
+		"account-id":   "canonical",
+		"name":         "base-set",
+		"sequence":     "1",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":     "pc-kernel",
+				"id":       s.ss.AssertedSnapID("pc-kernel"),
+				"presence": "required",
+				"revision": "10",
+			},
+		},
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	c.Assert(assertstate.Add(s.state, vsa), IsNil)
+	assertstate.UpdateValidationSet(s.state, &assertstate.ValidationSetTracking{
+		AccountID: "canonical",
+		Name:      "base-set",
+		Mode:      assertstate.Enforce,
+		Current:   1,
+	})
+
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Assert(err, IsNil)
+	c.Assert(chg, NotNil)
+
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Err(), ErrorMatches, `(?s)cannot perform the following tasks:
+- Create recovery system with label "1234" \(cannot create a recovery system with label "1234" for pc-20: cannot create a system with snaps not matching the validation sets:
+- snap "pc-kernel" is required at revision 10 by sets canonical/base-set, found revision 2\)`)
+	// nothing was written
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+	c.Check(s.restartRequests, HasLen, 0)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemSeedNotWritable(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+	// ubuntu-seed got remounted read-only
+	s.AddCleanup(osutil.MockMountInfo(fmt.Sprintf(`25 27 8:2 / %s/run/mnt/ubuntu-seed ro,relatime shared:6 - vfat /dev/fakedevice0p2 ro`, dirs.GlobalRootDir)))
+
+	s.state.Lock()
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Assert(err, IsNil)
+	c.Assert(chg, NotNil)
+
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Err(), ErrorMatches, `(?s)cannot perform the following tasks:
+- Create recovery system with label "1234" \(cannot create a recovery system with label "1234" for pc-20: ubuntu-seed is not mounted writable: mounted read-only\)`)
+	tskCreate := chg.Tasks()[0]
+	c.Check(strings.Join(tskCreate.Log(), "\n"), Matches, `(?s).* INFO Cannot create recovery system "1234", ubuntu-seed is not mounted writable \(mounted read-only\). Make sure the ubuntu-seed partition is mounted read-write and its filesystem is not damaged.*`)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+	c.Check(s.restartRequests, HasLen, 0)
+}
+
 func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemHappy(c *C) {
 	devicestate.SetBootOkRan(s.mgr, true)
 
 	s.state.Lock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
 	c.Assert(err, IsNil)
 	c.Assert(chg, NotNil)
 	tsks := chg.Tasks()
@@ -1461,6 +2163,9 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemHappy
 		CurrentKernels:         []string{"pc-kernel_2.snap"},
 		CurrentRecoverySystems: []string{"othersystem", "1234"},
 		GoodRecoverySystems:    []string{"othersystem"},
+		RecoverySystemModels: map[string]string{
+			"1234": recoverySystemModelDigest(s.model),
+		},
 
 		Model:          s.model.Model(),
 		BrandID:        s.model.BrandID(),
@@ -1477,6 +2182,23 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemHappy
 	}
 	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", "1234", "snapd-new-file-log"),
 		testutil.FileEquals, expectedFilesLog.String())
+	// progress of copying the snap which completed last is reported in
+	// the task, snaps are copied in parallel so it can be any of them
+	label, done, total := tskCreate.Progress()
+	snapFiles := map[string]string{
+		`Copying snap "snapd"`:     "snapd_4.snap",
+		`Copying snap "pc-kernel"`: "pc-kernel_2.snap",
+		`Copying snap "core20"`:    "core20_3.snap",
+		`Copying snap "pc"`:        "pc_1.snap",
+	}
+	c.Assert(snapFiles[label], Not(Equals), "", Commentf("unexpected label %q", label))
+	fi, err := os.Stat(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps", snapFiles[label]))
+	c.Assert(err, IsNil)
+	c.Check(total, Equals, int(fi.Size()))
+	c.Check(done, Equals, total)
+	// the change which created the system is recorded
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", "1234", "snapd-system-info"), testutil.FileMatches,
+		fmt.Sprintf(`\{"snapd-version":".*","created":".*","change-kind":"create-recovery-system","change-id":"%s","has-unasserted-snaps":false\}`, chg.ID()))
 
 	// these things happen on snapd startup
 	restart.MockPending(s.state, restart.RestartUnset)
@@ -1512,6 +2234,9 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemHappy
 		CurrentKernels:         []string{"pc-kernel_2.snap"},
 		CurrentRecoverySystems: []string{"othersystem", "1234"},
 		GoodRecoverySystems:    []string{"othersystem", "1234"},
+		RecoverySystemModels: map[string]string{
+			"1234": recoverySystemModelDigest(s.model),
+		},
 
 		Model:          s.model.Model(),
 		BrandID:        s.model.BrandID(),
@@ -1523,6 +2248,169 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemHappy
 	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", "1234", "snapd-new-file-log"), testutil.FileAbsent)
 }
 
+func (s *deviceMgrSystemsCreateSuite) TestTaskSnapCopyProgressThrottled(c *C) {
+	s.state.Lock()
+	t := s.state.NewTask("create-recovery-system", "create")
+	s.state.Unlock()
+
+	checkProgress := func(expectedLabel string, expectedDone, expectedTotal int) {
+		s.state.Lock()
+		defer s.state.Unlock()
+		label, done, total := t.Progress()
+		c.Check(label, Equals, expectedLabel)
+		c.Check(done, Equals, expectedDone)
+		c.Check(total, Equals, expectedTotal)
+	}
+
+	// progress is reported with the state unlocked
+	report := devicestate.TaskSnapCopyProgress(t)
+	report("pc", 0, 10000)
+	checkProgress(`Copying snap "pc"`, 0, 10000)
+	// too little progress to be reported
+	report("pc", 10, 10000)
+	checkProgress(`Copying snap "pc"`, 0, 10000)
+	report("pc", 30, 10000)
+	checkProgress(`Copying snap "pc"`, 30, 10000)
+	// snaps are tracked independently
+	report("core20", 0, 5000)
+	checkProgress(`Copying snap "core20"`, 0, 5000)
+	report("pc", 35, 10000)
+	checkProgress(`Copying snap "core20"`, 0, 5000)
+	// completion is always reported
+	report("pc", 10000, 10000)
+	checkProgress(`Copying snap "pc"`, 10000, 10000)
+	// as is a snap which was linked rather than copied
+	report("pc-kernel", 3000, 3000)
+	checkProgress(`Copying snap "pc-kernel"`, 3000, 3000)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemSetsTryVarsAtOnce(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Assert(err, IsNil)
+	tskCreate := chg.Tasks()[0]
+
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+	s.bootloader.SetBootVarsCalls = 0
+	s.bootloader.SetBootVarsWrites.Calls = nil
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(tskCreate.Status(), Equals, state.WaitStatus)
+	// the try variables and the request to boot into the tried system
+	// were set in a single update
+	c.Check(s.bootloader.SetBootVarsCalls, Equals, 1)
+	c.Check(s.bootloader.SetBootVarsWrites.Calls, DeepEquals, []map[string]string{{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "try",
+		"snapd_recovery_system":  "1234",
+		"snapd_recovery_mode":    "recover",
+	}})
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemExtractedRunKernelImageBootloader(c *C) {
+	// a bootloader like sd-boot, which does not manage trusted assets
+	bl := s.deviceMgrSystemsBaseSuite.bootloader.WithRecoveryAwareExtractedRunKernelImage()
+	bootloader.Force(bl)
+
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Assert(err, IsNil)
+	tskCreate := chg.Tasks()[0]
+
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(tskCreate.Status(), Equals, state.WaitStatus)
+	c.Check(s.restartRequests, DeepEquals, []restart.RestartType{restart.RestartSystemNow})
+
+	validateCore20Seed(c, "1234", s.model, s.storeSigning.Trusted)
+	c.Check(bl.RecoverySystemDir, Equals, "/systems/1234")
+	c.Check(bl.RecoverySystemBootVars, DeepEquals, map[string]string{
+		"snapd_recovery_kernel": "/snaps/pc-kernel_2.snap",
+	})
+	m, err := bl.GetBootVars("try_recovery_system", "recovery_system_status")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "try",
+	})
+	modeenvAfterCreate, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvAfterCreate.CurrentRecoverySystems, DeepEquals, []string{"othersystem", "1234"})
+	c.Check(modeenvAfterCreate.GoodRecoverySystems, DeepEquals, []string{"othersystem"})
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemInstallsTrustedAssets(c *C) {
+	s.bootloader.TrustedAssetsList = []string{"EFI/boot/grubx64.efi"}
+	oldPcFiles := snapFiles["pc"]
+	snapFiles["pc"] = [][]string{
+		{"meta/gadget.yaml", `
+volumes:
+  pc:
+    bootloader: grub
+    structure:
+      - name: ubuntu-seed
+        role: system-seed
+        type: 21686148-6449-6E6F-744E-656564454649
+        filesystem: vfat
+        size: 20M
+        content:
+          - source: grubx64.efi
+            target: EFI/boot/grubx64.efi
+      - name: ubuntu-boot
+        role: system-boot
+        type: 21686148-6449-6E6F-744E-656564454649
+        size: 10M
+      - name: ubuntu-data
+        role: system-data
+        type: 21686148-6449-6E6F-744E-656564454649
+        size: 50M
+`},
+		{"grubx64.efi", "foobar"},
+	}
+	s.AddCleanup(func() { snapFiles["pc"] = oldPcFiles })
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Assert(err, IsNil)
+	tskCreate := chg.Tasks()[0]
+
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(tskCreate.Status(), Equals, state.WaitStatus)
+
+	// SHA3-384 of "foobar"
+	grubHash := "0fa8abfbdaf924ad307b74dd2ed183b9a4a398891a2f6bac8fd2db7041b77f068580f9c6c66f699b496c2da1cbcc7ed8"
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234/EFI/boot/grubx64.efi"), testutil.FileEquals, "foobar")
+	c.Check(filepath.Join(dirs.SnapBootAssetsDir, s.bootloader.Name(), "grubx64.efi-"+grubHash), testutil.FileEquals, "foobar")
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.CurrentTrustedRecoveryBootAssets, HasLen, 1)
+	c.Check(m.CurrentTrustedRecoveryBootAssets["grubx64.efi"], DeepEquals, []string{grubHash})
+	c.Check(m.CurrentRecoverySystems, DeepEquals, []string{"othersystem", "1234"})
+}
+
 func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemRemodelDownloadingSnapsHappy(c *C) {
 	devicestate.SetBootOkRan(s.mgr, true)
 
@@ -1553,7 +2441,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemRemod
 	tSnapsup1.Set("snap-setup", snapsupFoo)
 	tSnapsup2.Set("snap-setup", snapsupBar)
 
-	tss, err := devicestate.CreateRecoverySystemTasks(s.state, "1234", []string{tSnapsup1.ID(), tSnapsup2.ID()})
+	tss, err := devicestate.CreateRecoverySystemTasks(s.state, "1234", []string{tSnapsup1.ID(), tSnapsup2.ID()}, devicestate.CreateRecoverySystemOptions{TestSystem: true})
 	c.Assert(err, IsNil)
 	tsks := tss.Tasks()
 	c.Check(tsks, HasLen, 2)
@@ -1639,6 +2527,9 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemRemod
 		CurrentKernels:         []string{"pc-kernel_2.snap"},
 		CurrentRecoverySystems: []string{"othersystem", "1234"},
 		GoodRecoverySystems:    []string{"othersystem"},
+		RecoverySystemModels: map[string]string{
+			"1234": recoverySystemModelDigest(s.model),
+		},
 
 		Model:          s.model.Model(),
 		BrandID:        s.model.BrandID(),
@@ -1695,6 +2586,9 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemRemod
 		CurrentRecoverySystems: []string{"othersystem", "1234"},
 		// but not promoted to good systems yet
 		GoodRecoverySystems: []string{"othersystem"},
+		RecoverySystemModels: map[string]string{
+			"1234": recoverySystemModelDigest(s.model),
+		},
 
 		Model:          s.model.Model(),
 		BrandID:        s.model.BrandID(),
@@ -1706,44 +2600,198 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemRemod
 	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", "1234", "snapd-new-file-log"), testutil.FileAbsent)
 }
 
-func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemRemodelDownloadingMissingSnap(c *C) {
+type recoverySystemDownloadStore struct {
+	fakeStore
+
+	infos     map[string]*snap.Info
+	files     map[string]string
+	downloads []string
+}
+
+func (sto *recoverySystemDownloadStore) SnapAction(_ context.Context, _ []*store.CurrentSnap, actions []*store.SnapAction, _ store.AssertionQuery, _ *auth.UserState, _ *store.RefreshOptions) ([]store.SnapActionResult, []store.AssertionResult, error) {
+	sto.pokeStateLock()
+	var res []store.SnapActionResult
+	for _, a := range actions {
+		if a.Action != "download" {
+			return nil, nil, fmt.Errorf("unexpected action %q", a.Action)
+		}
+		info := sto.infos[a.InstanceName]
+		if info == nil {
+			return nil, nil, fmt.Errorf("unexpected snap %q", a.InstanceName)
+		}
+		sto.downloads = append(sto.downloads, fmt.Sprintf("%s:%s", a.InstanceName, a.Channel))
+		res = append(res, store.SnapActionResult{Info: info})
+	}
+	return res, nil, nil
+}
+
+func (sto *recoverySystemDownloadStore) Download(_ context.Context, name, targetPath string, _ *snap.DownloadInfo, _ progress.Meter, _ *auth.UserState, _ *store.DownloadOptions) error {
+	sto.pokeStateLock()
+	return osutil.CopyFile(sto.files[name], targetPath, 0)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemRemodelDownloadsMissingSnaps(c *C) {
 	devicestate.SetBootOkRan(s.mgr, true)
 
 	fooSnap := snaptest.MakeTestSnapWithFiles(c, "name: foo\nversion: 1.0\nbase: core20", nil)
+	barSnap, barInfo := snaptest.MakeTestSnapInfoWithFiles(c, "name: bar\nversion: 1.0\nbase: core20", nil,
+		&snap.SideInfo{RealName: "bar", SnapID: s.ss.AssertedSnapID("bar"), Revision: snap.R(100)})
 	s.state.Lock()
-	defer s.state.Unlock()
-	// fake downloads are a nop
-	tSnapsup1 := s.state.NewTask("fake-download", "test task carrying snap setup")
-	// both snaps are asserted
+	// foo is fetched by the remodel
+	tSnapsup := s.state.NewTask("fake-download", "test task carrying snap setup")
 	snapsupFoo := snapstate.SnapSetup{
 		SideInfo: &snap.SideInfo{RealName: "foo", SnapID: s.ss.AssertedSnapID("foo"), Revision: snap.R(99)},
 		SnapPath: fooSnap,
 	}
-	tSnapsup1.Set("snap-setup", snapsupFoo)
+	s.setupSnapDeclForNameAndID(c, "foo", s.ss.AssertedSnapID("foo"), "canonical")
+	s.setupSnapRevisionForFileAndID(c, fooSnap, s.ss.AssertedSnapID("foo"), "canonical", snap.R(99))
+	c.Assert(os.MkdirAll(filepath.Dir(snapsupFoo.MountFile()), 0755), IsNil)
+	c.Assert(os.Rename(fooSnap, snapsupFoo.MountFile()), IsNil)
+	tSnapsup.Set("snap-setup", snapsupFoo)
+
+	// while bar is neither installed nor fetched by the remodel, the
+	// store knows about it and its revision assertion
+	s.setupSnapDeclForNameAndID(c, "bar", s.ss.AssertedSnapID("bar"), "canonical")
+	sha3_384, size, err := asserts.SnapFileSHA3_384(barSnap)
+	c.Assert(err, IsNil)
+	barRev, err := s.storeSigning.Sign(asserts.SnapRevisionType, map[string]interface{}{
+		"snap-sha3-384": sha3_384,
+		"snap-size":     fmt.Sprintf("%d", size),
+		"snap-id":       s.ss.AssertedSnapID("bar"),
+		"developer-id":  "canonical",
+		"snap-revision": "100",
+		"timestamp":     time.Now().UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	c.Assert(s.storeSigning.Add(barRev), IsNil)
+	barInfo.Sha3_384 = sha3_384
+	barInfo.Size = int64(size)
+	sto := &recoverySystemDownloadStore{
+		fakeStore: fakeStore{
+			state: s.state,
+			db:    s.storeSigning,
+		},
+		infos: map[string]*snap.Info{"bar": barInfo},
+		files: map[string]string{"bar": barSnap},
+	}
+	snapstate.ReplaceStore(s.state, sto)
 
-	tss, err := devicestate.CreateRecoverySystemTasks(s.state, "1234missingdownload", []string{tSnapsup1.ID()})
+	// the reference system is not complete and cannot be used
+	tss, err := devicestate.CreateRecoverySystemTasks(s.state, "1234", []string{tSnapsup.ID()}, devicestate.CreateRecoverySystemOptions{
+		TestSystem:      true,
+		ReferenceSystem: "othersystem",
+	})
 	c.Assert(err, IsNil)
 	tsks := tss.Tasks()
 	c.Check(tsks, HasLen, 2)
 	tskCreate := tsks[0]
-	tskFinalize := tsks[1]
-	c.Assert(tskCreate.Summary(), Matches, `Create recovery system with label "1234missingdownload"`)
-	c.Check(tskFinalize.Summary(), Matches, `Finalize recovery system with label "1234missingdownload"`)
-	var systemSetupData map[string]interface{}
-	err = tskCreate.Get("recovery-system-setup", &systemSetupData)
-	c.Assert(err, IsNil)
-	c.Assert(systemSetupData, DeepEquals, map[string]interface{}{
-		"label":            "1234missingdownload",
-		"directory":        filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234missingdownload"),
-		"snap-setup-tasks": []interface{}{tSnapsup1.ID()},
-	})
-	tss.WaitFor(tSnapsup1)
-	// add the test task to the change
+	tss.WaitFor(tSnapsup)
 	chg := s.state.NewChange("create-recovery-system", "create recovery system")
-	chg.AddTask(tSnapsup1)
+	chg.AddTask(tSnapsup)
 	chg.AddAll(tss)
 
-	// downloads are only accepted if the tasks are executed as part of
+	newModel := s.brands.Model("canonical", "pc-20", map[string]interface{}{
+		"architecture": "amd64",
+		// UC20
+		"grade": "dangerous",
+		"base":  "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":     "foo",
+				"id":       s.ss.AssertedSnapID("foo"),
+				"presence": "required",
+			},
+			map[string]interface{}{
+				"name":            "bar",
+				"id":              s.ss.AssertedSnapID("bar"),
+				"presence":        "required",
+				"default-channel": "latest/edge",
+			},
+		},
+		"revision": "2",
+	})
+	chg.Set("new-model", string(asserts.Encode(newModel)))
+
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(tskCreate.Status(), Equals, state.WaitStatus)
+	// bar was downloaded from the channel in the model straight to the
+	// seed
+	c.Check(sto.downloads, DeepEquals, []string{"bar:latest/edge"})
+	c.Check(s.logbuf.String(), testutil.Contains, `cannot use incomplete recovery system "othersystem" as reference`)
+	validateCore20Seed(c, "1234", newModel, s.storeSigning.Trusted, "foo", "bar")
+	expectedFilesLog := &bytes.Buffer{}
+	for _, fname := range []string{
+		"snapd_4.snap", "pc-kernel_2.snap", "core20_3.snap", "pc_1.snap",
+		"foo_99.snap", "bar_100.snap",
+	} {
+		fmt.Fprintln(expectedFilesLog, filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps", fname))
+	}
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", "1234", "snapd-new-file-log"),
+		testutil.FileEquals, expectedFilesLog.String())
+	// the assertion of the downloaded snap is only in the new system
+	_, err = assertstate.DB(s.state).Find(asserts.SnapRevisionType, map[string]string{
+		"snap-sha3-384": sha3_384,
+	})
+	c.Check(errors.Is(err, &asserts.NotFoundError{}), Equals, true)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemRemodelDownloadingMissingSnap(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	fooSnap := snaptest.MakeTestSnapWithFiles(c, "name: foo\nversion: 1.0\nbase: core20", nil)
+	s.state.Lock()
+	defer s.state.Unlock()
+	// fake downloads are a nop
+	tSnapsup1 := s.state.NewTask("fake-download", "test task carrying snap setup")
+	// both snaps are asserted
+	snapsupFoo := snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "foo", SnapID: s.ss.AssertedSnapID("foo"), Revision: snap.R(99)},
+		SnapPath: fooSnap,
+	}
+	tSnapsup1.Set("snap-setup", snapsupFoo)
+
+	tss, err := devicestate.CreateRecoverySystemTasks(s.state, "1234missingdownload", []string{tSnapsup1.ID()}, devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Assert(err, IsNil)
+	tsks := tss.Tasks()
+	c.Check(tsks, HasLen, 2)
+	tskCreate := tsks[0]
+	tskFinalize := tsks[1]
+	c.Assert(tskCreate.Summary(), Matches, `Create recovery system with label "1234missingdownload"`)
+	c.Check(tskFinalize.Summary(), Matches, `Finalize recovery system with label "1234missingdownload"`)
+	var systemSetupData map[string]interface{}
+	err = tskCreate.Get("recovery-system-setup", &systemSetupData)
+	c.Assert(err, IsNil)
+	c.Assert(systemSetupData, DeepEquals, map[string]interface{}{
+		"label":            "1234missingdownload",
+		"directory":        filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234missingdownload"),
+		"snap-setup-tasks": []interface{}{tSnapsup1.ID()},
+	})
+	tss.WaitFor(tSnapsup1)
+	// add the test task to the change
+	chg := s.state.NewChange("create-recovery-system", "create recovery system")
+	chg.AddTask(tSnapsup1)
+	chg.AddAll(tss)
+
+	// downloads are only accepted if the tasks are executed as part of
 	// remodel, so procure a new model
 	newModel := s.brands.Model("canonical", "pc-20", map[string]interface{}{
 		"architecture": "amd64",
@@ -1779,7 +2827,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemRemod
 	s.settle(c)
 	s.state.Lock()
 
-	c.Assert(chg.Err(), ErrorMatches, `(?s).*cannot create a recovery system.*internal error: non-essential but required snap "bar" not present.`)
+	c.Assert(chg.Err(), ErrorMatches, `(?s).*cannot create a recovery system.*: non-essential but required snap "bar" not present.`)
 	c.Assert(tskCreate.Status(), Equals, state.ErrorStatus)
 	c.Assert(tskFinalize.Status(), Equals, state.HoldStatus)
 	// a reboot is expected
@@ -1800,7 +2848,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemUndo(
 	devicestate.SetBootOkRan(s.mgr, true)
 
 	s.state.Lock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234undo")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234undo", devicestate.CreateRecoverySystemOptions{TestSystem: true})
 	c.Assert(err, IsNil)
 	c.Assert(chg, NotNil)
 	tsks := chg.Tasks()
@@ -1855,6 +2903,9 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemUndo(
 		CurrentKernels:         []string{"pc-kernel_2.snap"},
 		CurrentRecoverySystems: []string{"othersystem", "1234undo"},
 		GoodRecoverySystems:    []string{"othersystem"},
+		RecoverySystemModels: map[string]string{
+			"1234undo": recoverySystemModelDigest(s.model),
+		},
 
 		Model:          s.model.Model(),
 		BrandID:        s.model.BrandID(),
@@ -1915,11 +2966,81 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemUndo(
 	})
 }
 
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemUndoNewFilesFromTaskState(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234undo", devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Assert(err, IsNil)
+	c.Assert(chg, NotNil)
+	tsks := chg.Tasks()
+	c.Check(tsks, HasLen, 2)
+	tskCreate := tsks[0]
+	tskFinalize := tsks[1]
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(tskFinalize)
+	chg.AddTask(terr)
+
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+
+	snaptest.PopulateDir(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps"), [][]string{
+		{"core20_10.snap", "canary"},
+	})
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(tskCreate.Status(), Equals, state.WaitStatus)
+
+	// the new files are tracked in the task state
+	var setup map[string]interface{}
+	c.Assert(tskCreate.Get("recovery-system-setup", &setup), IsNil)
+	c.Check(setup["new-files"], DeepEquals, []interface{}{
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_2.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_3.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_1.snap"),
+	})
+	// and the log of new files is lost
+	c.Assert(os.Remove(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234undo/snapd-new-file-log")), IsNil)
+
+	// these things happen on snapd startup
+	restart.MockPending(s.state, restart.RestartUnset)
+	s.state.Set("tried-systems", []string{"1234undo"})
+	s.bootloader.SetBootVars(map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+	})
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// simulate a restart and run change to completion
+	s.mockRestartAndSettle(c, s.state, chg)
+
+	c.Assert(chg.Err(), ErrorMatches, "(?s)cannot perform the following tasks.* provoking total undo.*")
+	c.Assert(tskCreate.Status(), Equals, state.UndoneStatus)
+	c.Assert(tskFinalize.Status(), Equals, state.UndoneStatus)
+
+	// system directory was removed
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234undo"), testutil.FileAbsent)
+	// and so were the new snaps, only the canary is left
+	p, err := filepath.Glob(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/*"))
+	c.Assert(err, IsNil)
+	c.Check(p, DeepEquals, []string{
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_10.snap"),
+	})
+}
+
 func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemFinalizeErrsWhenSystemFailed(c *C) {
 	devicestate.SetBootOkRan(s.mgr, true)
 
 	s.state.Lock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
 	c.Assert(err, IsNil)
 	c.Assert(chg, NotNil)
 	tsks := chg.Tasks()
@@ -1957,6 +3078,9 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemFinal
 		CurrentKernels:         []string{"pc-kernel_2.snap"},
 		CurrentRecoverySystems: []string{"othersystem", "1234"},
 		GoodRecoverySystems:    []string{"othersystem"},
+		RecoverySystemModels: map[string]string{
+			"1234": recoverySystemModelDigest(s.model),
+		},
 
 		Model:          s.model.Model(),
 		BrandID:        s.model.BrandID(),
@@ -2017,11 +3141,74 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemFinal
 	c.Check(p, HasLen, 0)
 }
 
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemNotBootable(c *C) {
+	// kernel without a kernel.efi
+	oldKernelFiles := snapFiles["pc-kernel"]
+	snapFiles["pc-kernel"] = nil
+	s.AddCleanup(func() { snapFiles["pc-kernel"] = oldKernelFiles })
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Assert(err, IsNil)
+	tskCreate := chg.Tasks()[0]
+
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), ErrorMatches, `(?s)cannot perform the following tasks.* \(cannot create a recovery system with label "1234" for pc-20: recovery system "1234" is missing bootloader configuration: kernel snap "pc-kernel" does not contain kernel.efi required by .* bootloader\)`)
+	c.Assert(tskCreate.Status(), Equals, state.ErrorStatus)
+	c.Check(strings.Join(tskCreate.Log(), "\n"), Matches, `(?s).*Recovery system "1234" would not boot, its bootloader configuration is incomplete \(kernel snap "pc-kernel" does not contain kernel.efi .*\)\..*`)
+	// the system was never tried
+	c.Check(s.restartRequests, HasLen, 0)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+	c.Check(s.bootloader.BootVars["try_recovery_system"], Equals, "")
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemResealErr(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	resealCalls := 0
+	restore := boot.MockResealKeyToModeenv(func(rootdir string, modeenv *boot.Modeenv, expectReseal bool, u boot.Unlocker) error {
+		resealCalls++
+		if resealCalls == 1 {
+			return fmt.Errorf("cannot reseal the encryption key: mock reseal error")
+		}
+		return nil
+	})
+	s.AddCleanup(restore)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Assert(err, IsNil)
+	tskCreate := chg.Tasks()[0]
+
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), ErrorMatches, `(?s)cannot perform the following tasks.* \(cannot attempt booting into recovery system "1234": cannot reseal the encryption key: mock reseal error\)`)
+	c.Assert(tskCreate.Status(), Equals, state.ErrorStatus)
+	c.Check(strings.Join(tskCreate.Log(), "\n"), Matches, `(?s).*Cannot reseal the encryption keys to include recovery system "1234"\..*`)
+	// when trying the system, then when clearing the tried system and
+	// when dropping it
+	c.Check(resealCalls, Equals, 3)
+	c.Check(s.restartRequests, HasLen, 0)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+}
+
 func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemErrCleanup(c *C) {
 	devicestate.SetBootOkRan(s.mgr, true)
 
 	s.state.Lock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234error")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234error", devicestate.CreateRecoverySystemOptions{TestSystem: true})
 	c.Assert(err, IsNil)
 	c.Assert(chg, NotNil)
 	tsks := chg.Tasks()
@@ -2032,14 +3219,10 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemErrCl
 	s.mockStandardSnapsModeenvAndBootloaderState(c)
 	s.bootloader.SetBootVarsCalls = 0
 
-	s.bootloader.SetErrFunc = func() error {
-		c.Logf("boot calls: %v", s.bootloader.SetBootVarsCalls)
-		// for simplicity error out only when we try to set the recovery
-		// system variables in bootenv (and not in the cleanup path)
-		if s.bootloader.SetBootVarsCalls == 1 {
-			return fmt.Errorf("mock bootloader error")
-		}
-		return nil
+	// for simplicity error out only when we try to set the recovery
+	// system variables in bootenv (and not in the cleanup path)
+	s.bootloader.SetBootVarsWrites = bootloadertest.MockVarsWrites{
+		ErrOnCall: map[int]error{1: fmt.Errorf("mock bootloader error")},
 	}
 
 	snaptest.PopulateDir(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps"), [][]string{
@@ -2086,13 +3269,311 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemErrCl
 		Grade:          string(s.model.Grade()),
 		ModelSignKeyID: s.model.SignKeyID(),
 	})
+	// the variables were cleared after the failed attempt, and the system
+	// to boot into was restored
+	c.Check(s.bootloader.SetBootVarsWrites.Calls, DeepEquals, []map[string]string{{
+		"try_recovery_system":    "1234error",
+		"recovery_system_status": "try",
+		"snapd_recovery_system":  "1234error",
+		"snapd_recovery_mode":    "recover",
+	}, {
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+		"snapd_recovery_system":  "",
+		"snapd_recovery_mode":    "",
+	}})
+}
+
+func (s *deviceMgrSystemsCreateSuite) mockGadgetPrepareRecoverySystemHook(c *C) {
+	gadgetInfo, err := snapstate.CurrentInfo(s.state, "pc")
+	c.Assert(err, IsNil)
+	hookPath := filepath.Join(gadgetInfo.MountDir(), "meta/hooks/prepare-recovery-system")
+	c.Assert(os.MkdirAll(filepath.Dir(hookPath), 0755), IsNil)
+	c.Assert(os.WriteFile(hookPath, nil, 0755), IsNil)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemPrepareHook(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	var hookCalls []string
+	restore := hookstate.MockRunHook(func(ctx *hookstate.Context, tomb *tomb.Tomb) ([]byte, error) {
+		ctx.Lock()
+		defer ctx.Unlock()
+		c.Check(ctx.IsEphemeral(), Equals, true)
+		c.Check(ctx.InstanceName(), Equals, "pc")
+		var label, dir string
+		c.Check(ctx.Get("recovery-system-label", &label), IsNil)
+		c.Check(ctx.Get("recovery-system-directory", &dir), IsNil)
+		hookCalls = append(hookCalls, fmt.Sprintf("%s:%s:%s", ctx.HookName(), label, dir))
+		// the files of the system are in place
+		c.Check(filepath.Join(dir, "model"), testutil.FilePresent)
+		// but the system is not being tried yet
+		m, err := s.bootloader.GetBootVars("try_recovery_system")
+		c.Check(err, IsNil)
+		c.Check(m["try_recovery_system"], Equals, "")
+		return nil, nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+	s.mockGadgetPrepareRecoverySystemHook(c)
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Assert(err, IsNil)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(hookCalls, DeepEquals, []string{
+		"prepare-recovery-system:1234:" + filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"),
+	})
+	c.Check(s.restartRequests, DeepEquals, []restart.RestartType{restart.RestartSystemNow})
+	m, err := s.bootloader.GetBootVars("try_recovery_system")
+	c.Assert(err, IsNil)
+	c.Check(m["try_recovery_system"], Equals, "1234")
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemPrepareHookErrCleanup(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	restore := hookstate.MockRunHook(func(ctx *hookstate.Context, tomb *tomb.Tomb) ([]byte, error) {
+		return []byte("vendor partition missing"), fmt.Errorf("hook failed")
+	})
+	defer restore()
+
+	s.state.Lock()
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+	s.mockGadgetPrepareRecoverySystemHook(c)
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Assert(err, IsNil)
+	tskCreate := chg.Tasks()[0]
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Err(), ErrorMatches, `(?s)cannot perform the following tasks.* \(cannot prepare recovery system "1234": cannot run hook for gadget "pc": run hook "prepare-recovery-system": vendor partition missing\)`)
+	c.Check(tskCreate.Status(), Equals, state.ErrorStatus)
+	c.Check(s.restartRequests, HasLen, 0)
+	// the files written for the system were removed
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+	p, err := filepath.Glob(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/*"))
+	c.Assert(err, IsNil)
+	c.Check(p, HasLen, 0)
+	m, err := s.bootloader.GetBootVars("try_recovery_system", "recovery_system_status")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+	})
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemRecoveryEnvErrCleanup(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234error", devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Assert(err, IsNil)
+	tskCreate := chg.Tasks()[0]
+
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+	s.bootloader.SetBootVarsCalls = 0
+	s.bootloader.SetRecoverySystemEnvWrites.ErrOnKey = map[string]error{
+		"snapd_recovery_kernel": fmt.Errorf("io error"),
+	}
+
+	snaptest.PopulateDir(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps"), [][]string{
+		{"core20_10.snap", "canary"},
+	})
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Err(), ErrorMatches, `(?s)cannot perform the following tasks.* \(cannot create a recovery system with label "1234error" for pc-20: cannot make candidate recovery system "1234error" bootable: cannot set recovery system environment: io error\)`)
+	c.Assert(tskCreate.Status(), Equals, state.ErrorStatus)
+	c.Check(s.restartRequests, HasLen, 0)
+	// the bootloader environment of the system was attempted once
+	c.Check(s.bootloader.SetRecoverySystemEnvWrites.Calls, HasLen, 1)
+	c.Check(s.bootloader.RecoverySystemBootVars, HasLen, 0)
+	// the copied snaps and the system were removed
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234error"), testutil.FileAbsent)
+	p, err := filepath.Glob(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/*"))
+	c.Assert(err, IsNil)
+	c.Check(p, DeepEquals, []string{
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_10.snap"),
+	})
+	// the system was never tried nor made current
+	c.Check(s.bootloader.SetBootVarsCalls, Equals, 0)
+	modeenvAfterCreate, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvAfterCreate.CurrentRecoverySystems, DeepEquals, []string{"othersystem"})
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestCreateRecoverySystemFromRunSystemHappy(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+	// the kernel was refreshed since install
+	si := &snap.SideInfo{
+		RealName: "pc-kernel",
+		SnapID:   s.ss.AssertedSnapID("pc-kernel"),
+		Revision: snap.R(5),
+	}
+	info := snaptest.MakeSnapFileAndDir(c, snapYamls["pc-kernel"], [][]string{
+		{"kernel.efi", "refreshed kernel"},
+	}, si)
+	s.setupSnapRevision(c, info, "canonical", snap.R(5))
+	snapstate.Set(s.state, "pc-kernel", &snapstate.SnapState{
+		SnapType: "kernel",
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+	})
+
+	dir, err := devicestate.CreateRecoverySystemFromRunSystem(s.state, "1234")
+	c.Assert(err, IsNil)
+	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"))
+
+	validateCore20Seed(c, "1234", s.model, s.storeSigning.Trusted)
+	for _, fname := range []string{"snapd_4.snap", "pc-kernel_5.snap", "core20_3.snap", "pc_1.snap"} {
+		c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps", fname), testutil.FilePresent)
+	}
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps", "pc-kernel_2.snap"), testutil.FileAbsent)
+	c.Check(s.bootloader.RecoverySystemBootVars["snapd_recovery_kernel"], Equals, "/snaps/pc-kernel_5.snap")
+	// the system is neither tried nor made current
+	m, err := s.bootloader.GetBootVars("try_recovery_system", "recovery_system_status")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+	})
+	modeenvAfterCreate, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvAfterCreate.CurrentRecoverySystems, DeepEquals, []string{"othersystem"})
+	c.Check(modeenvAfterCreate.GoodRecoverySystems, DeepEquals, []string{"othersystem"})
+	c.Check(s.restartRequests, HasLen, 0)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestCachedSnapInfoGetter(c *C) {
+	calls := map[string]int{}
+	getInfo := devicestate.CachedSnapInfoGetter(func(modelSnap *asserts.ModelSnap) (*snap.Info, bool, error) {
+		calls[modelSnap.SnapName()]++
+		switch modelSnap.SnapName() {
+		case "pc":
+			return &snap.Info{SuggestedName: "pc"}, true, nil
+		case "broken":
+			return nil, false, fmt.Errorf("mock failure")
+		}
+		return nil, false, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		info, present, err := getInfo(&asserts.ModelSnap{Name: "pc"})
+		c.Assert(err, IsNil)
+		c.Check(present, Equals, true)
+		c.Check(info.SnapName(), Equals, "pc")
+
+		info, present, err = getInfo(&asserts.ModelSnap{Name: "missing"})
+		c.Assert(err, IsNil)
+		c.Check(present, Equals, false)
+		c.Check(info, IsNil)
+
+		_, _, err = getInfo(&asserts.ModelSnap{Name: "broken"})
+		c.Check(err, ErrorMatches, "mock failure")
+	}
+	// errors are not cached
+	c.Check(calls, DeepEquals, map[string]int{"pc": 1, "missing": 1, "broken": 3})
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestCreateRecoverySystemFromRunSystemUnassertedDangerous(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+	s.makeSnapInState(c, "pc-kernel", snap.R(-1))
+
+	_, err := devicestate.CreateRecoverySystemFromRunSystem(s.state, "1234")
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234/snaps/pc-kernel_1.0.snap"), testutil.FilePresent)
+	c.Check(s.logbuf.String(), testutil.Contains, `system "1234" contains unasserted snaps "pc-kernel"`)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestCreateRecoverySystemFromRunSystemUnassertedSigned(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.makeModelAssertionInState(c, "canonical", "pc-20-signed", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "signed",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand:  "canonical",
+		Model:  "pc-20-signed",
+		Serial: "serialserialserial",
+	})
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+	s.makeSnapInState(c, "pc-kernel", snap.R(-1))
+
+	_, err := devicestate.CreateRecoverySystemFromRunSystem(s.state, "1234")
+	c.Assert(err, ErrorMatches, `cannot create recovery system "1234" from the run system: cannot use unasserted snap "pc-kernel" with a model of grade "signed"`)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestCreateRecoverySystemFromRunSystemErrCleanup(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockStandardSnapsModeenvAndBootloaderState(c)
+	s.bootloader.SetRecoverySystemEnvWrites.ErrOnKey = map[string]error{
+		"snapd_recovery_kernel": fmt.Errorf("io error"),
+	}
+	snaptest.PopulateDir(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps"), [][]string{
+		{"core20_10.snap", "canary"},
+	})
+
+	_, err := devicestate.CreateRecoverySystemFromRunSystem(s.state, "1234")
+	c.Assert(err, ErrorMatches, `cannot create recovery system "1234" from the run system: cannot make candidate recovery system "1234" bootable: cannot set recovery system environment: io error`)
+	// the copied snaps and the system were removed
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+	p, err := filepath.Glob(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/*"))
+	c.Assert(err, IsNil)
+	c.Check(p, DeepEquals, []string{
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_10.snap"),
+	})
+}
+
+func (s *deviceMgrSystemsCreateSuite) TestCreateRecoverySystemFromRunSystemNotSeeded(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("seeded", nil)
+
+	_, err := devicestate.CreateRecoverySystemFromRunSystem(s.state, "1234")
+	c.Assert(err, ErrorMatches, `cannot create new recovery systems until fully seeded`)
 }
 
 func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemReboot(c *C) {
 	devicestate.SetBootOkRan(s.mgr, true)
 
 	s.state.Lock()
-	chg, err := devicestate.CreateRecoverySystem(s.state, "1234reboot")
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234reboot", devicestate.CreateRecoverySystemOptions{TestSystem: true})
 	c.Assert(err, IsNil)
 	c.Assert(chg, NotNil)
 	tsks := chg.Tasks()
@@ -2127,7 +3608,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemReboo
 	c.Assert(tskFinalize.Status(), Equals, state.DoStatus)
 	// a reboot is expected
 	c.Check(s.restartRequests, DeepEquals, []restart.RestartType{restart.RestartSystemNow})
-	c.Check(s.bootloader.SetBootVarsCalls, Equals, 2)
+	c.Check(s.bootloader.SetBootVarsCalls, Equals, 1)
 	s.restartRequests = nil
 
 	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234reboot"), testutil.FilePresent)
@@ -2151,7 +3632,7 @@ func (s *deviceMgrSystemsCreateSuite) TestDeviceManagerCreateRecoverySystemReboo
 	s.state.Lock()
 	defer s.state.Unlock()
 
-	c.Assert(chg.Err(), ErrorMatches, `(?s)cannot perform the following tasks.* \(cannot create a recovery system with label "1234reboot" for pc-20: system "1234reboot" already exists\)`)
+	c.Assert(chg.Err(), ErrorMatches, `(?s)cannot perform the following tasks.* \(cannot create a recovery system with label "1234reboot" for pc-20: recovery system "1234reboot" already exists\)`)
 	c.Assert(tskCreate.Status(), Equals, state.ErrorStatus)
 	c.Assert(tskFinalize.Status(), Equals, state.HoldStatus)
 	c.Check(s.restartRequests, HasLen, 0)
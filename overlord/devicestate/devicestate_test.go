@@ -165,7 +165,8 @@ func (s *deviceMgrBaseSuite) setupBaseTest(c *C, classic bool) {
 	err = os.MkdirAll(dirs.SnapdStateDir(dirs.GlobalRootDir), 0755)
 	c.Assert(err, IsNil)
 
-	s.AddCleanup(osutil.MockMountInfo(``))
+	// ubuntu-seed is mounted such that recovery systems can be created
+	s.AddCleanup(osutil.MockMountInfo(fmt.Sprintf(mountRunMntUbuntuSeedFmt, dirs.GlobalRootDir)))
 
 	s.restartRequests = nil
 
@@ -871,6 +872,52 @@ func (s *deviceMgrSuite) TestCheckGadgetValid(c *C) {
 
 }
 
+func (s *deviceMgrSuite) TestCheckGadgetValidRefreshCompatible(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	model := fakeMyModel(map[string]interface{}{
+		"architecture": "amd64",
+		"gadget":       "gadget",
+		"kernel":       "krnl",
+	})
+	deviceCtx := &snapstatetest.TrivialDeviceContext{DeviceModel: model}
+
+	currentGadgetYaml := `
+volumes:
+  volume:
+    bootloader: grub
+    structure:
+      - name: foo
+        size: 10M
+        type: 00000000-0000-0000-0000-0000deadbeef
+`
+	curInfo := snaptest.MockSnapWithFiles(c, "{type: gadget, name: gadget, version: 0}", &snap.SideInfo{Revision: snap.R(1)}, [][]string{
+		{"meta/gadget.yaml", currentGadgetYaml},
+	})
+	gadgetInfo := snaptest.MockInfo(c, "{type: gadget, name: gadget, version: 0}", &snap.SideInfo{Revision: snap.R(2)})
+
+	// same layout
+	cont := snaptest.MockContainer(c, [][]string{
+		{"meta/gadget.yaml", currentGadgetYaml},
+	})
+	err := devicestate.CheckGadgetValid(s.state, gadgetInfo, curInfo, cont, snapstate.Flags{}, deviceCtx)
+	c.Check(err, IsNil)
+
+	// a structure changed its size
+	cont = snaptest.MockContainer(c, [][]string{
+		{"meta/gadget.yaml", strings.Replace(currentGadgetYaml, "10M", "20M", 1)},
+	})
+	err = devicestate.CheckGadgetValid(s.state, gadgetInfo, curInfo, cont, snapstate.Flags{}, deviceCtx)
+	c.Check(err, ErrorMatches, `cannot refresh to an incompatible gadget: incompatible layout change: volume "volume": incompatible structure #0 \("foo"\) change: new valid structure size range \[20971520, 20971520\] is not compatible with current \(\[10485760, 10485760\]\)`)
+
+	// the layout is not checked on classic
+	restore := release.MockOnClassic(true)
+	defer restore()
+	err = devicestate.CheckGadgetValid(s.state, gadgetInfo, curInfo, cont, snapstate.Flags{}, deviceCtx)
+	c.Check(err, IsNil)
+}
+
 func (s *deviceMgrSuite) TestCheckKernel(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -1321,6 +1368,36 @@ func (s *deviceMgrSuite) TestMarkSeededInConfig(c *C) {
 	c.Check(s.state.Changes(), HasLen, 1)
 }
 
+func (s *deviceMgrSuite) TestRecordEssentialSnapTimings(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	chg := st.NewChange("seed", "...")
+	for _, name := range []string{"core", "pc-kernel", "foo"} {
+		t := st.NewTask("mount-snap", "...")
+		t.Set("snap-setup", &snapstate.SnapSetup{
+			SideInfo: &snap.SideInfo{RealName: name, Revision: snap.R(1)},
+		})
+		chg.AddTask(t)
+	}
+	chg.AddTask(st.NewTask("mark-preseeded", "..."))
+	markSeeded := st.NewTask("mark-seeded", "...")
+	markSeeded.Set("essential-snaps", []string{"core", "pc-kernel"})
+	chg.AddTask(markSeeded)
+
+	err := devicestate.RecordEssentialSnapTimings(markSeeded)
+	c.Assert(err, IsNil)
+
+	var timings []devicestate.EssentialSnapTiming
+	err = st.Get("seed-essential-snap-timings", &timings)
+	c.Assert(err, IsNil)
+	c.Check(timings, DeepEquals, []devicestate.EssentialSnapTiming{
+		{Snap: "core"},
+		{Snap: "pc-kernel"},
+	})
+}
+
 func (s *deviceMgrSuite) TestDevicemgrCanStandby(c *C) {
 	st := state.New(nil)
 
@@ -1498,6 +1575,7 @@ func (s *deviceMgrSuite) TestDeviceManagerSystemModeInfoUC20Run(c *C) {
 }
 
 const (
+	mountRunMntUbuntuSeedFmt = `25 27 8:2 / %s/run/mnt/ubuntu-seed rw,relatime shared:6 - vfat /dev/fakedevice0p2 rw`
 	mountRunMntUbuntuSaveFmt = `26 27 8:3 / %s/run/mnt/ubuntu-save rw,relatime shared:7 - ext4 /dev/fakedevice0p1 rw,data=ordered`
 	mountSnapSaveFmt         = `26 27 8:3 / %s/var/lib/snapd/save rw,relatime shared:7 - ext4 /dev/fakedevice0p1 rw,data=ordered`
 )
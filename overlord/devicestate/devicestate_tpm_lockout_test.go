@@ -0,0 +1,163 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/device"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/secboot"
+)
+
+var _ = Suite(&deviceMgrTPMLockoutSuite{})
+
+type deviceMgrTPMLockoutSuite struct {
+	deviceMgrBaseSuite
+}
+
+func (s *deviceMgrTPMLockoutSuite) SetUpTest(c *C) {
+	s.deviceMgrBaseSuite.setupBaseTest(c, false)
+	s.setUC20PCModelInState(c)
+
+	devicestate.SetSystemMode(s.mgr, "run")
+}
+
+func (s *deviceMgrTPMLockoutSuite) TestTPMLockoutStatus(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// not encrypted
+	_, err := s.mgr.TPMLockoutStatus()
+	c.Check(err, ErrorMatches, "system does not use TPM sealed encryption keys")
+
+	// keys sealed by the fde-setup hook
+	err = device.StampSealedKeys(dirs.GlobalRootDir, device.SealingMethodFDESetupHook)
+	c.Assert(err, IsNil)
+	_, err = s.mgr.TPMLockoutStatus()
+	c.Check(err, ErrorMatches, "system does not use TPM sealed encryption keys")
+
+	err = device.StampSealedKeys(dirs.GlobalRootDir, device.SealingMethodTPM)
+	c.Assert(err, IsNil)
+	status := &secboot.TPMLockoutStatus{
+		FailedTries:     2,
+		MaxTries:        32,
+		LockoutInterval: 2 * time.Hour,
+		LockoutRecovery: 24 * time.Hour,
+	}
+	defer devicestate.MockSecbootGetTPMLockoutStatus(func() (*secboot.TPMLockoutStatus, error) {
+		return status, nil
+	})()
+	res, err := s.mgr.TPMLockoutStatus()
+	c.Assert(err, IsNil)
+	c.Check(res, Equals, status)
+}
+
+func (s *deviceMgrTPMLockoutSuite) TestTPMLockoutOtherModes(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	err := device.StampSealedKeys(dirs.GlobalRootDir, device.SealingMethodTPM)
+	c.Assert(err, IsNil)
+
+	for _, mode := range []string{"install", "recover", "factory-reset"} {
+		devicestate.SetSystemMode(s.mgr, mode)
+		_, err := s.mgr.TPMLockoutStatus()
+		c.Check(err, ErrorMatches, fmt.Sprintf(`cannot query TPM lockout from system mode %q`, mode))
+		err = s.mgr.ResetTPMLockout()
+		c.Check(err, ErrorMatches, fmt.Sprintf(`cannot reset TPM lockout from system mode %q`, mode))
+	}
+
+	resets, err := s.mgr.TPMLockoutResets()
+	c.Assert(err, IsNil)
+	c.Check(resets, HasLen, 0)
+}
+
+func (s *deviceMgrTPMLockoutSuite) TestResetTPMLockout(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	err := device.StampSealedKeys(dirs.GlobalRootDir, device.SealingMethodTPM)
+	c.Assert(err, IsNil)
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	defer devicestate.MockTimeNow(func() time.Time { return now })()
+
+	var resetErr error
+	calls := 0
+	defer devicestate.MockSecbootResetTPMLockout(func(lockoutAuthFile string) error {
+		calls++
+		c.Check(lockoutAuthFile, Equals, filepath.Join(dirs.SnapSaveDir, "device/fde/tpm-lockout-auth"))
+		return resetErr
+	})()
+
+	err = s.mgr.ResetTPMLockout()
+	c.Assert(err, IsNil)
+
+	resetErr = secboot.ErrNoTPMLockoutAuth
+	err = s.mgr.ResetTPMLockout()
+	c.Check(err, ErrorMatches, "cannot reset TPM lockout: lockout authorization is not available, the TPM must be reprovisioned from recovery mode")
+
+	resetErr = fmt.Errorf("boom")
+	err = s.mgr.ResetTPMLockout()
+	c.Check(err, ErrorMatches, "cannot reset TPM lockout: boom")
+	c.Check(calls, Equals, 3)
+
+	resets, err := s.mgr.TPMLockoutResets()
+	c.Assert(err, IsNil)
+	c.Check(resets, DeepEquals, []devicestate.TPMLockoutReset{
+		{Time: now},
+		{Time: now, Error: "lockout authorization is not available, the TPM must be reprovisioned from recovery mode"},
+		{Time: now, Error: "boom"},
+	})
+}
+
+func (s *deviceMgrTPMLockoutSuite) TestResetTPMLockoutKeepsLastResets(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	err := device.StampSealedKeys(dirs.GlobalRootDir, device.SealingMethodTPM)
+	c.Assert(err, IsNil)
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	defer devicestate.MockTimeNow(func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	})()
+	defer devicestate.MockSecbootResetTPMLockout(func(lockoutAuthFile string) error {
+		return nil
+	})()
+
+	for i := 0; i < 12; i++ {
+		err := s.mgr.ResetTPMLockout()
+		c.Assert(err, IsNil)
+	}
+
+	resets, err := s.mgr.TPMLockoutResets()
+	c.Assert(err, IsNil)
+	c.Assert(resets, HasLen, 10)
+	c.Check(resets[0].Time.Equal(time.Date(2026, 10, 1, 12, 3, 0, 0, time.UTC)), Equals, true)
+	c.Check(resets[9].Time.Equal(now), Equals, true)
+}
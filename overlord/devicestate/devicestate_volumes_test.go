@@ -0,0 +1,110 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+type deviceMgrVolumesSuite struct {
+	deviceMgrBaseSuite
+}
+
+var _ = Suite(&deviceMgrVolumesSuite{})
+
+func (s *deviceMgrVolumesSuite) SetUpTest(c *C) {
+	s.deviceMgrBaseSuite.setupBaseTest(c, false)
+	s.setUC20PCModelInState(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	si := &snap.SideInfo{
+		RealName: "pc",
+		Revision: snap.R(33),
+		SnapID:   "foo-id",
+	}
+	snapstate.Set(s.state, "pc", &snapstate.SnapState{
+		SnapType: "gadget",
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+		Active:   true,
+	})
+	snaptest.MockSnapWithFiles(c, pcGadgetSnapYaml, si, [][]string{
+		{"meta/gadget.yaml", uc20gadgetYaml},
+	})
+}
+
+func (s *deviceMgrVolumesSuite) TestDiskVolumesReport(c *C) {
+	err := gadget.SaveDiskVolumesDeviceTraits(dirs.SnapDeviceDir, map[string]gadget.DiskVolumeDeviceTraits{
+		"pc": {
+			StructureEncryption: map[string]gadget.StructureEncryptionParameters{
+				"ubuntu-data": {Method: gadget.EncryptionLUKS},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	report := []gadget.DiskVolumeReport{{Name: "pc", Device: "/dev/vda"}}
+	called := 0
+	defer devicestate.MockGadgetDiskVolumesReport(func(allVols map[string]*gadget.Volume, optsPerVolume map[string]*gadget.DiskVolumeValidationOptions) ([]gadget.DiskVolumeReport, error) {
+		called++
+		c.Check(allVols, HasLen, 1)
+		c.Check(allVols["pc"], NotNil)
+		c.Check(optsPerVolume, DeepEquals, map[string]*gadget.DiskVolumeValidationOptions{
+			"pc": {
+				ExpectedStructureEncryption: map[string]gadget.StructureEncryptionParameters{
+					"ubuntu-data": {Method: gadget.EncryptionLUKS},
+				},
+			},
+		})
+		return report, nil
+	})()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	res, err := s.mgr.DiskVolumesReport()
+	c.Assert(err, IsNil)
+	c.Check(res, DeepEquals, report)
+	c.Check(called, Equals, 1)
+}
+
+func (s *deviceMgrVolumesSuite) TestDiskVolumesReportError(c *C) {
+	defer devicestate.MockGadgetDiskVolumesReport(func(allVols map[string]*gadget.Volume, optsPerVolume map[string]*gadget.DiskVolumeValidationOptions) ([]gadget.DiskVolumeReport, error) {
+		c.Check(optsPerVolume["pc"], DeepEquals, &gadget.DiskVolumeValidationOptions{})
+		return nil, fmt.Errorf("boom")
+	})()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := s.mgr.DiskVolumesReport()
+	c.Assert(err, ErrorMatches, "boom")
+}
@@ -186,8 +186,8 @@ func MockDeviceService(c *C, bhv *DeviceServiceBehavior) (mockServer *httptest.S
 				serialStr = serialReq.Serial()
 			}
 			if serialReq.HeaderString("original-model") != "" {
-				// re-registration
-				if len(extra) != 2 {
+				// re-registration or renewal
+				if len(extra) != 2 && len(extra) != 3 {
 					w.WriteHeader(400)
 					w.Write([]byte(`{
   "error_list": [{"message": "expected model and original serial"}]
@@ -209,7 +209,23 @@ func MockDeviceService(c *C, bhv *DeviceServiceBehavior) (mockServer *httptest.S
   "error_list": [{"message": "expected model"}]
 }`))
 				}
-				c.Check(origSerial.DeviceKey(), DeepEquals, serialReq.DeviceKey())
+				if len(extra) == 3 {
+					// renewal for a new device key, proving
+					// possession of the original one
+					proof, ok := extra[2].(*asserts.SerialRequest)
+					if !ok {
+						w.WriteHeader(400)
+						w.Write([]byte(`{
+  "error_list": [{"message": "expected original device key proof"}]
+}`))
+						return
+					}
+					c.Check(asserts.SignatureCheck(proof, origSerial.DeviceKey()), IsNil)
+					c.Check(proof.RequestID(), Equals, reqID)
+					c.Check(proof.Serial(), Equals, origSerial.Serial())
+				} else {
+					c.Check(origSerial.DeviceKey(), DeepEquals, serialReq.DeviceKey())
+				}
 				// TODO: more checks once we have Original* accessors
 			} else {
 
@@ -95,6 +95,20 @@ func MockTimeNow(f func() time.Time) (restore func()) {
 	}
 }
 
+func MockOsutilBootID(bootID string) (restore func()) {
+	r := testutil.Backup(&osutilBootID)
+	osutilBootID = func() (string, error) {
+		return bootID, nil
+	}
+	return r
+}
+
+var ClearFactoryResetAfterReboot = clearFactoryResetAfterReboot
+
+func CachedSnapInfoGetter(getInfo func(modelSnap *asserts.ModelSnap) (*snap.Info, bool, error)) func(modelSnap *asserts.ModelSnap) (*snap.Info, bool, error) {
+	return cachedSnapInfoGetter(getInfo)
+}
+
 func KeypairManager(m *DeviceManager) (keypairMgr asserts.KeypairManager) {
 	// XXX expose the with... method at some point
 	err := m.withKeypairMgr(func(km asserts.KeypairManager) error {
@@ -264,6 +278,7 @@ type (
 	RegistrationContext = registrationContext
 	RemodelContext      = remodelContext
 	SeededSystem        = seededSystem
+	EssentialSnapTiming = essentialSnapTiming
 )
 
 func RegistrationCtx(m *DeviceManager, t *state.Task) (registrationContext, error) {
@@ -292,6 +307,8 @@ var (
 	CanAutoRefresh               = canAutoRefresh
 	NewEnoughProxy               = newEnoughProxy
 
+	RecordEssentialSnapTimings = recordEssentialSnapTimings
+
 	IncEnsureOperationalAttempts = incEnsureOperationalAttempts
 	EnsureOperationalAttempts    = ensureOperationalAttempts
 
@@ -310,8 +327,12 @@ var (
 	LogNewSystemSnapFile                   = logNewSystemSnapFile
 	PurgeNewSystemSnapFiles                = purgeNewSystemSnapFiles
 	CreateRecoverySystemTasks              = createRecoverySystemTasks
+	SeededSnapComponents                   = seededSnapComponents
+	TaskSnapCopyProgress                   = taskSnapCopyProgress
 )
 
+type CreateSystemOptions = createSystemOptions
+
 func MockApplyPreseededData(f func(deviceSeed seed.PreseedCapable, writableDir string) error) (restore func()) {
 	r := testutil.Backup(&applyPreseededData)
 	applyPreseededData = f
@@ -324,6 +345,24 @@ func MockSeedOpen(f func(seedDir, label string) (seed.Seed, error)) (restore fun
 	return r
 }
 
+func MockOsLink(f func(oldname, newname string) error) (restore func()) {
+	r := testutil.Backup(&osLink)
+	osLink = f
+	return r
+}
+
+// CopySnapFiles runs copy for each one of the names using the pool of workers
+// used when copying the snaps of a recovery system.
+func CopySnapFiles(names []string, workers int, copy func(name string) error) error {
+	jobs := make([]snapCopyJob, len(names))
+	for i, name := range names {
+		jobs[i] = snapCopyJob{name: name}
+	}
+	return copySnapFiles(jobs, workers, func(job snapCopyJob) error {
+		return copy(job.name)
+	})
+}
+
 func MockGadgetUpdate(mock func(model gadget.Model, current, update gadget.GadgetData, path string, policy gadget.UpdatePolicyFunc, observer gadget.ContentUpdateObserver) error) (restore func()) {
 	old := gadgetUpdate
 	gadgetUpdate = mock
@@ -352,6 +391,12 @@ func MockBootMakeSystemRunnableAfterDataReset(f func(model *asserts.Model, bootW
 	return restore
 }
 
+func MockBootUpdateRecoverySystemsMenu(f func(dev snap.Device, gadgetSnapOrDir string) error) (restore func()) {
+	restore = testutil.Backup(&bootUpdateRecoverySystemsMenu)
+	bootUpdateRecoverySystemsMenu = f
+	return restore
+}
+
 func MockBootEnsureNextBootToRunMode(f func(systemLabel string) error) (restore func()) {
 	old := bootEnsureNextBootToRunMode
 	bootEnsureNextBootToRunMode = f
@@ -499,6 +544,24 @@ func MockSecbootRemoveRecoveryKeys(f func(rkeyDevToKey map[secboot.RecoveryKeyDe
 	return restore
 }
 
+func MockSecbootGetTPMLockoutStatus(f func() (*secboot.TPMLockoutStatus, error)) (restore func()) {
+	restore = testutil.Backup(&secbootGetTPMLockoutStatus)
+	secbootGetTPMLockoutStatus = f
+	return restore
+}
+
+func MockGadgetDiskVolumesReport(f func(allVols map[string]*gadget.Volume, optsPerVolume map[string]*gadget.DiskVolumeValidationOptions) ([]gadget.DiskVolumeReport, error)) (restore func()) {
+	restore = testutil.Backup(&gadgetDiskVolumesReport)
+	gadgetDiskVolumesReport = f
+	return restore
+}
+
+func MockSecbootResetTPMLockout(f func(lockoutAuthFile string) error) (restore func()) {
+	restore = testutil.Backup(&secbootResetTPMLockout)
+	secbootResetTPMLockout = f
+	return restore
+}
+
 func MockMarkFactoryResetComplete(f func(encrypted bool) error) (restore func()) {
 	restore = testutil.Backup(&bootMarkFactoryResetComplete)
 	bootMarkFactoryResetComplete = f
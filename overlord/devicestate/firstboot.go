@@ -371,6 +371,12 @@ func (m *DeviceManager) populateStateFromSeedImpl(tm timings.Measurer) ([]*state
 		Timestamp: model.Timestamp(),
 	}
 	markSeeded.Set("seed-system", whatSeeds)
+	// remember the essential snaps to record how long seeding each took
+	essentialSnaps := make([]string, 0, len(essentialSeedSnaps))
+	for _, info := range infos[:len(essentialSeedSnaps)] {
+		essentialSnaps = append(essentialSnaps, info.InstanceName())
+	}
+	markSeeded.Set("essential-snaps", essentialSnaps)
 
 	// mark-seeded waits for the taskset of last snap, and
 	// for all the tasks in the endTs as well.
@@ -671,6 +671,17 @@ func (s *firstBoot16Suite) TestPopulateFromSeedHappy(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(seedTime.IsZero(), Equals, false)
 
+	// and how long seeding each essential snap took
+	var essentialTimings []devicestate.EssentialSnapTiming
+	err = state.Get("seed-essential-snap-timings", &essentialTimings)
+	c.Assert(err, IsNil)
+	var essentialSnaps []string
+	for _, t := range essentialTimings {
+		essentialSnaps = append(essentialSnaps, t.Snap)
+		c.Check(t.Duration >= 0, Equals, true)
+	}
+	c.Check(essentialSnaps, DeepEquals, []string{"core", "pc-kernel", "pc"})
+
 	var whatseeded []devicestate.SeededSystem
 	err = state.Get("seeded-systems", &whatseeded)
 	c.Assert(err, IsNil)
@@ -139,6 +139,44 @@ func (m *DeviceManager) recordSeededSystem(st *state.State, whatSeeded *seededSy
 	return nil
 }
 
+// essentialSnapTiming holds the time spent running the tasks of the seed
+// change for one of the essential snaps.
+type essentialSnapTiming struct {
+	Snap     string        `json:"snap"`
+	Duration time.Duration `json:"duration"`
+}
+
+// recordEssentialSnapTimings records in the state how long the tasks of
+// the seed change took for each of the essential snaps, in seeding order,
+// to help diagnose slow first boots. For preseeded images this covers
+// both the work done when preseeding and on first boot.
+func recordEssentialSnapTimings(markSeeded *state.Task) error {
+	var essentialSnaps []string
+	if err := markSeeded.Get("essential-snaps", &essentialSnaps); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	if len(essentialSnaps) == 0 {
+		return nil
+	}
+
+	durations := make(map[string]time.Duration, len(essentialSnaps))
+	for _, t := range markSeeded.Change().Tasks() {
+		snapsup, err := snapstate.TaskSnapSetup(t)
+		if err != nil {
+			// not a task operating on a snap
+			continue
+		}
+		durations[snapsup.InstanceName()] += t.DoingTime()
+	}
+
+	snapTimings := make([]essentialSnapTiming, 0, len(essentialSnaps))
+	for _, name := range essentialSnaps {
+		snapTimings = append(snapTimings, essentialSnapTiming{Snap: name, Duration: durations[name]})
+	}
+	markSeeded.State().Set("seed-essential-snap-timings", snapTimings)
+	return nil
+}
+
 func (m *DeviceManager) doMarkSeeded(t *state.Task, _ *tomb.Tomb) error {
 	st := t.State()
 	st.Lock()
@@ -182,6 +220,9 @@ func (m *DeviceManager) doMarkSeeded(t *state.Task, _ *tomb.Tomb) error {
 			return fmt.Errorf("cannot record the seeded system: %v", err)
 		}
 	}
+	if err := recordEssentialSnapTimings(t); err != nil {
+		return err
+	}
 	st.Set("seed-time", now)
 	st.Set("seeded", true)
 	// avoid possibly recording the same system multiple times etc.
@@ -429,7 +429,7 @@ func maybeApplyPreseededData(model *asserts.Model, ubuntuSeedDir, sysLabel, writ
 	// this function is for UC20+ only so sysSeed ia always PreseedCapable
 	preseedSeed := sysSeed.(seed.PreseedCapable)
 
-	if !preseedSeed.HasArtifact("preseed.tgz") {
+	if !preseedSeed.HasPreseedArtifact() {
 		return false, nil
 	}
 
@@ -19,6 +19,7 @@
 package devicestate
 
 import (
+	"errors"
 	"fmt"
 
 	"gopkg.in/tomb.v2"
@@ -132,6 +133,10 @@ func (m *DeviceManager) doSetModel(t *state.Task, _ *tomb.Tomb) (err error) {
 		if err := boot.PromoteTriedRecoverySystem(remodCtx, recoverySetup.Label, triedSystems); err != nil {
 			return err
 		}
+		updateRecoverySystemsMenu(st, remodCtx)
+		if err := markDefaultRecoverySystem(remodCtx, recoverySetup); err != nil {
+			return err
+		}
 		remodCtx.setRecoverySystemLabel(recoverySetup.Label)
 	}
 
@@ -174,21 +179,38 @@ func (m *DeviceManager) doPrepareRemodeling(t *state.Task, tmb *tomb.Tomb) error
 		return err
 	}
 
+	// snaps provided locally for an offline remodel
+	var localSnaps []*snap.SideInfo
+	var paths []string
+	if err := t.Get("local-snaps", &localSnaps); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	if err := t.Get("local-paths", &paths); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	var opts RemodelOptions
+	if err := t.Get("offline", &opts.Offline); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+
 	sto := remodCtx.Store()
 	if sto == nil {
 		return fmt.Errorf("internal error: re-registration remodeling should have built a store")
 	}
-	// ensure a new session accounting for the new brand/model
-	st.Unlock()
-	err = sto.EnsureDeviceSession()
-	st.Lock()
-	if err != nil {
-		return fmt.Errorf("cannot get a store session based on the new model assertion: %v", err)
+	// ensure a new session accounting for the new brand/model before
+	// anything is downloaded, there is no need for one when offline
+	if !opts.Offline {
+		st.Unlock()
+		err = sto.EnsureDeviceSession()
+		st.Lock()
+		if err != nil {
+			return fmt.Errorf("cannot get a store session based on the new model assertion: %v", err)
+		}
 	}
 
 	chgID := t.Change().ID()
 
-	tss, err := remodelTasks(tmb.Context(nil), st, current, remodCtx.Model(), nil, nil, remodCtx, chgID)
+	tss, err := remodelTasks(tmb.Context(nil), st, current, remodCtx.Model(), localSnaps, paths, remodCtx, chgID, opts)
 	if err != nil {
 		return err
 	}
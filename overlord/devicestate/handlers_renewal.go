@@ -0,0 +1,178 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+func (m *DeviceManager) doPrepareSerialRenewal(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	chg := t.Change()
+	var renewed auth.DeviceState
+	err := chg.Get("device", &renewed)
+	if err == nil {
+		// nothing to do
+		return nil
+	}
+	if !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+
+	device, err := m.device()
+	if err != nil {
+		return err
+	}
+
+	st.Unlock()
+	keyPair, err := generateRSAKey(keyLength)
+	st.Lock()
+	if err != nil {
+		return fmt.Errorf("cannot generate device key pair: %v", err)
+	}
+
+	privKey := asserts.RSAPrivateKey(keyPair)
+	err = m.withKeypairMgr(func(keypairMgr asserts.KeypairManager) error {
+		return keypairMgr.Put(privKey)
+	})
+	if err != nil {
+		return fmt.Errorf("cannot store device key pair: %v", err)
+	}
+
+	// the new key is used only by the renewal until it is finished
+	chg.Set("device", &auth.DeviceState{
+		Brand: device.Brand,
+		Model: device.Model,
+		KeyID: privKey.PublicKey().ID(),
+	})
+	return nil
+}
+
+func (m *DeviceManager) undoPrepareSerialRenewal(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	chg := t.Change()
+	var renewed auth.DeviceState
+	err := chg.Get("device", &renewed)
+	if errors.Is(err, state.ErrNoState) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	device, err := m.device()
+	if err != nil {
+		return err
+	}
+	if renewed.KeyID == "" || renewed.KeyID == device.KeyID {
+		// the key is in use
+		return nil
+	}
+
+	err = m.withKeypairMgr(func(keypairMgr asserts.KeypairManager) error {
+		return keypairMgr.Delete(renewed.KeyID)
+	})
+	if err != nil {
+		return fmt.Errorf("cannot delete new device key pair: %v", err)
+	}
+	chg.Set("device", nil)
+	return nil
+}
+
+func (m *DeviceManager) doFinishSerialRenewal(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	regCtx, err := m.renewalRegistrationCtx(t.Change())
+	if err != nil {
+		return err
+	}
+	renewed, err := regCtx.Device()
+	if err != nil {
+		return err
+	}
+	if renewed.Serial == "" {
+		return fmt.Errorf("internal error: cannot finish serial renewal without a new serial")
+	}
+	if m.newStore == nil {
+		return fmt.Errorf("internal error: cannot build a store for the serial renewal")
+	}
+
+	// only switch to the new key and serial once they were
+	// accepted by the store
+	sto := m.newStore(renewalDeviceBackend{
+		renewalRegistrationContext: regCtx,
+		st:                         st,
+	})
+	st.Unlock()
+	err = sto.EnsureDeviceSession()
+	st.Lock()
+	if err != nil {
+		return fmt.Errorf("cannot get a store session with the new device serial: %v", err)
+	}
+
+	// reload to get the new session
+	renewed, err = regCtx.Device()
+	if err != nil {
+		return err
+	}
+	device, err := m.device()
+	if err != nil {
+		return err
+	}
+	oldKeyID := device.KeyID
+
+	// the state is committed as a whole when unlocking, so the new
+	// device state and the record of the previous serial go together
+	if err := addPreviousSerial(st, regCtx.origSerial); err != nil {
+		return err
+	}
+	if err := m.setDevice(renewed); err != nil {
+		return err
+	}
+	t.SetStatus(state.DoneStatus)
+	// commit the switch before deleting the old key
+	st.Unlock()
+	st.Lock()
+
+	// the old key should not be used anymore
+	err = m.withKeypairMgr(func(keypairMgr asserts.KeypairManager) error {
+		return keypairMgr.Delete(oldKeyID)
+	})
+	if err != nil {
+		logger.Noticef("cannot delete replaced device key pair: %v", err)
+	}
+	return nil
+}
@@ -190,6 +190,13 @@ type registrationContext interface {
 	ForRemodeling() bool
 }
 
+// originalKeyProver is implemented by registration contexts that need
+// to prove possession of the original device key to the device service,
+// the proof is bound to the request-id of the serial request.
+type originalKeyProver interface {
+	OriginalKeyProof(requestID string) (asserts.Assertion, error)
+}
+
 // initialRegistrationContext is a thin wrapper around DeviceManager
 // implementing registrationContext for initial regitration
 type initialRegistrationContext struct {
@@ -250,6 +257,9 @@ func (m *DeviceManager) registrationCtx(t *state.Task) (registrationContext, err
 	if regCtx, ok := remodCtx.(registrationContext); ok {
 		return regCtx, nil
 	}
+	if chg := t.Change(); chg != nil && chg.Kind() == "renew-serial" {
+		return m.renewalRegistrationCtx(chg)
+	}
 	model, err := m.Model()
 	if err != nil {
 		return nil, err
@@ -419,7 +429,16 @@ func prepareSerialRequest(t *state.Task, regCtx registrationContext, privKey ass
 		return "", fmt.Errorf("cannot encode serial-request: %v", err)
 	}
 
-	for _, ancillaryAs := range regCtx.SerialRequestAncillaryAssertions() {
+	ancillary := regCtx.SerialRequestAncillaryAssertions()
+	if prover, ok := regCtx.(originalKeyProver); ok {
+		proof, err := prover.OriginalKeyProof(requestID.RequestID)
+		if err != nil {
+			return "", fmt.Errorf("cannot prove possession of the original device key: %v", err)
+		}
+		ancillary = append(ancillary, proof)
+	}
+
+	for _, ancillaryAs := range ancillary {
 		if err := encoder.Encode(ancillaryAs); err != nil {
 			return "", fmt.Errorf("cannot encode ancillary assertion: %v", err)
 		}
@@ -747,11 +766,12 @@ func (m *DeviceManager) doRequestSerial(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
-	// NB: the keyPair is fixed for now
-	privKey, err := m.keyPair()
-	if errors.Is(err, state.ErrNoState) {
+	// NB: the key pair is the one of the registration device state,
+	// which differs from the current one only for a serial renewal
+	if device.KeyID == "" {
 		return fmt.Errorf("internal error: cannot find device key pair")
 	}
+	privKey, err := m.keyPairByID(device.KeyID)
 	if err != nil {
 		return err
 	}
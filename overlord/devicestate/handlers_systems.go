@@ -21,26 +21,33 @@ package devicestate
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/boot"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/restart"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snapfile"
+	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/strutil"
 )
 
@@ -109,31 +116,118 @@ func purgeNewSystemSnapFiles(logfile string) error {
 		if fileName == "" {
 			continue
 		}
-		if !strings.HasPrefix(fileName, boot.InitramfsUbuntuSeedDir) {
-			logger.Noticef("while removing new seed snap %q: unexpected recovery system snap location", fileName)
-			continue
+		removeNewSystemSnapFile(fileName)
+	}
+	return s.Err()
+}
+
+func removeNewSystemSnapFile(fileName string) {
+	if !strings.HasPrefix(fileName, boot.InitramfsUbuntuSeedDir) {
+		logger.Noticef("while removing new seed snap %q: unexpected recovery system snap location", fileName)
+		return
+	}
+	if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+		logger.Noticef("while removing new seed snap %q: %v", fileName, err)
+	}
+}
+
+// taskSnapCopyProgress returns a function reporting the progress of copying
+// snaps in the task. Snaps are copied in parallel with the state unlocked, the
+// progress of each snap is reported through its own meter, which only updates
+// the task when enough progress was made.
+func taskSnapCopyProgress(t *state.Task) snapCopyProgressFunc {
+	var mu sync.Mutex
+	meters := make(map[string]progress.Meter)
+	return func(snapName string, copied, total int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		meter := meters[snapName]
+		if meter == nil || copied == 0 {
+			meter = snapstate.NewTaskProgressAdapterUnlocked(t)
+			meter.Start(fmt.Sprintf("Copying snap %q", snapName), float64(total))
+			meters[snapName] = meter
 		}
-		if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
-			logger.Noticef("while removing new seed snap %q: %v", fileName, err)
+		if copied == total {
+			meter.Finished()
+			return
 		}
+		meter.Set(float64(copied))
 	}
-	return s.Err()
 }
 
-func (m *DeviceManager) doCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) (err error) {
-	if release.OnClassic {
-		// TODO: this may need to be lifted in the future
-		return fmt.Errorf("cannot create recovery systems on a classic system")
+// storeSnapDownloader returns a function downloading the snaps from the store
+// of the given device context directly to the shared snaps directory of
+// ubuntu-seed. The assertions of the downloaded snaps are added to db. The
+// state must be locked when calling the function, it is released while talking
+// to the store.
+func storeSnapDownloader(ctx context.Context, st *state.State, deviceCtx snapstate.DeviceContext, db *asserts.Database) snapDownloadFunc {
+	return func(name, channel string) (string, *snap.Info, error) {
+		sto := snapstate.Store(st, deviceCtx)
+		st.Unlock()
+		defer st.Lock()
+
+		actions := []*store.SnapAction{{
+			Action:       "download",
+			InstanceName: name,
+			Channel:      channel,
+		}}
+		results, _, err := sto.SnapAction(ctx, nil, actions, nil, nil, nil)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(results) != 1 {
+			return "", nil, fmt.Errorf("internal error: unexpected number of store results: %v", len(results))
+		}
+		info := results[0].Info
+
+		retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+			return sto.Assertion(ref.Type, ref.PrimaryKey, nil)
+		}
+		save := func(a asserts.Assertion) error {
+			if err := db.Add(a); err != nil && !asserts.IsUnaccceptedUpdate(err) {
+				return err
+			}
+			return nil
+		}
+		// the assertions already known to the device are not fetched
+		// again
+		f := asserts.NewFetcherWithOptions(db, retrieve, save, &asserts.FetcherOptions{SkipPresent: true})
+		if err := snapasserts.FetchSnapAssertions(f, info.Sha3_384, info.Provenance()); err != nil {
+			return "", nil, fmt.Errorf("cannot fetch assertions: %v", err)
+		}
+
+		snapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
+		path := filepath.Join(snapsDir, info.Filename())
+		if osutil.FileExists(path) {
+			// shared with other systems already, the file is
+			// verified against the assertions once it is used
+			return path, info, nil
+		}
+		if err := os.MkdirAll(snapsDir, 0755); err != nil {
+			return "", nil, err
+		}
+		if err := sto.Download(ctx, name, path, &info.DownloadInfo, nil, nil, nil); err != nil {
+			return "", nil, err
+		}
+		return path, info, nil
 	}
+}
 
+func (m *DeviceManager) doCreateRecoverySystem(ctx context.Context, t *state.Task) (err error) {
 	st := t.State()
 	st.Lock()
 	defer st.Unlock()
 
+	perfTimings := state.TimingsForTask(t)
+	defer perfTimings.Save(st)
+
 	remodelCtx, err := DeviceCtx(st, t, nil)
 	if err != nil {
 		return err
 	}
+	if release.OnClassic && !remodelCtx.HasModeenv() {
+		return fmt.Errorf("cannot create recovery systems on a classic system without modes")
+	}
 	model := remodelCtx.Model()
 	isRemodel := remodelCtx.ForRemodeling()
 
@@ -145,7 +239,8 @@ func (m *DeviceManager) doCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) (err
 	systemDirectory := setup.Directory
 
 	// get all infos
-	infoGetter := func(name string) (info *snap.Info, present bool, err error) {
+	infoGetter := func(modelSnap *asserts.ModelSnap) (info *snap.Info, present bool, err error) {
+		name := modelSnap.SnapName()
 		// snaps are either being fetched or present in the system
 
 		if isRemodel {
@@ -184,19 +279,7 @@ func (m *DeviceManager) doCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) (err
 		// system, in which case we use the snaps that are already
 		// installed
 
-		info, err = snapstate.CurrentInfo(st, name)
-		if err == nil {
-			hash, _, err := asserts.SnapFileSHA3_384(info.MountFile())
-			if err != nil {
-				return nil, true, fmt.Errorf("cannot compute SHA3 of snap file: %v", err)
-			}
-			info.Sha3_384 = hash
-			return info, true, nil
-		}
-		if _, ok := err.(*snap.NotInstalledError); !ok {
-			return nil, false, err
-		}
-		return nil, false, nil
+		return currentSnapInfo(st, name)
 	}
 
 	observeSnapFileWrite := func(recoverySystemDir, where string) error {
@@ -204,18 +287,25 @@ func (m *DeviceManager) doCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) (err
 			return fmt.Errorf("internal error: unexpected recovery system path %q", recoverySystemDir)
 		}
 		// track all the files, both asserted shared snaps and private
-		// ones
-		return logNewSystemSnapFile(filepath.Join(recoverySystemDir, "snapd-new-file-log"), where)
+		// ones, the log file is written out immediately and survives an
+		// unexpected restart, while the task state is only updated once
+		// the system has been created
+		if err := logNewSystemSnapFile(filepath.Join(recoverySystemDir, "snapd-new-file-log"), where); err != nil {
+			return err
+		}
+		setup.NewFiles = append(setup.NewFiles, where)
+		return nil
 	}
 
 	var db asserts.RODatabase
+	var tempDB *asserts.Database
 	if isRemodel {
 		// during remodel, the model assertion is not yet present in the
 		// assertstate database, hence we need to use a temporary one to
 		// which we explicitly add the new model assertion, as
 		// createSystemForModelFromValidatedSnaps expects all relevant
 		// assertions to be present in the passed db
-		tempDB := assertstate.TemporaryDB(st)
+		tempDB = assertstate.TemporaryDB(st)
 		if err := tempDB.Add(model); err != nil {
 			return fmt.Errorf("cannot create a temporary database with model: %v", err)
 		}
@@ -252,7 +342,65 @@ func (m *DeviceManager) doCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) (err
 	// creation could have been interrupted by an unexpected reboot;
 	// consider clearing the recovery system directory and restarting from
 	// scratch
-	_, err = createSystemForModelFromValidatedSnaps(model, label, db, infoGetter, observeSnapFileWrite)
+	opts := &createSystemOptions{
+		// copying the snaps may take a while, so do not block the
+		// state in the meantime
+		Unlocker: st.Unlocker(),
+		Progress: taskSnapCopyProgress(t),
+		// seed storage can be unreliable, a corrupted snap would only
+		// be noticed when the recovery system is needed
+		VerifyCopies: true,
+		ChangeKind:   t.Change().Kind(),
+		ChangeID:     t.Change().ID(),
+		Timings:      perfTimings,
+		// stop copying when the change is aborted
+		Context: ctx,
+	}
+	if setup.ReferenceSystem != "" {
+		// the files of unchanged snaps are neither verified nor hashed
+		// again, as long as the reference system is complete, which
+		// is known from its model being in place
+		refModel := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", setup.ReferenceSystem, "model")
+		if osutil.FileExists(refModel) {
+			opts.ReferenceSystem = setup.ReferenceSystem
+		} else {
+			logger.Noticef("cannot use incomplete recovery system %q as reference", setup.ReferenceSystem)
+		}
+	}
+	// components of the snaps can only come from the system the device
+	// was seeded from, snaps fetched for a remodel have none
+	modeenv, err := boot.ReadModeenv("")
+	if err != nil {
+		return err
+	}
+	opts.GetComponents = seededSnapComponents(boot.InitramfsUbuntuSeedDir, modeenv.RecoverySystem)
+	if isRemodel && !setup.Offline {
+		// snaps required by the new model which are neither installed
+		// nor fetched by the remodel are downloaded directly to the
+		// seed, unless the remodel is offline
+		opts.Downloader = storeSnapDownloader(ctx, st, remodelCtx, tempDB)
+	}
+	if !isRemodel {
+		// the snaps of the system must satisfy the validation sets
+		// enforced on the device, during remodel the validation sets
+		// of the new model are handled as part of the remodel itself
+		vsets, err := assertstate.TrackedEnforcedValidationSets(st)
+		if err != nil {
+			return fmt.Errorf("cannot obtain enforced validation sets: %v", err)
+		}
+		opts.ValidationSets = vsets
+	}
+	_, err = createSystemForModelFromValidatedSnaps(model, label, db, infoGetter, observeSnapFileWrite, opts)
+	var notWritableErr *SeedNotWritableError
+	if errors.As(err, &notWritableErr) {
+		t.Logf("Cannot create recovery system %q, ubuntu-seed is not mounted writable (%s). Make sure the ubuntu-seed partition is mounted read-write and its filesystem is not damaged.",
+			label, notWritableErr.Reason)
+	}
+	var bootConfigErr *boot.RecoverySystemBootConfigError
+	if errors.As(err, &bootConfigErr) {
+		t.Logf("Recovery system %q would not boot, its bootloader configuration is incomplete (%s).",
+			label, bootConfigErr.Reason)
+	}
 	if err != nil {
 		return fmt.Errorf("cannot create a recovery system with label %q for %v: %v", label, model.Model(), err)
 	}
@@ -262,27 +410,74 @@ func (m *DeviceManager) doCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) (err
 	if err := setTaskRecoverySystemSetup(t, setup); err != nil {
 		return fmt.Errorf("cannot record recovery system setup state: %v", err)
 	}
-	// 3. set up boot variables for tracking the tried system state
-	if err := boot.SetTryRecoverySystem(remodelCtx, label); err != nil {
+	// 3. let the gadget prepare the system now that its files are in place
+	if err := m.runPrepareRecoverySystemHook(model, label, systemDirectory); err != nil {
+		return fmt.Errorf("cannot prepare recovery system %q: %v", label, err)
+	}
+	if setup.SkipTest {
+		// the system is not tried, it is promoted directly in finalize
+		logger.Noticef("created recovery system %q", label)
+		return nil
+	}
+	// 4. set up boot variables for tracking the tried system state and the
+	// next boot into that system, all at once such that an unexpected
+	// reboot cannot leave them inconsistent
+	if err := boot.SetTryRecoverySystemForNextBoot(remodelCtx, label); err != nil {
+		var resealErr *boot.RecoverySystemResealError
+		if errors.As(err, &resealErr) {
+			t.Logf("Cannot reseal the encryption keys to include recovery system %q.", label)
+		}
 		// rollback?
 		return fmt.Errorf("cannot attempt booting into recovery system %q: %v", label, err)
 	}
-	// 4. and set up the next boot that that system
-	if err := boot.SetRecoveryBootSystemAndMode(remodelCtx, label, "recover"); err != nil {
-		return fmt.Errorf("cannot set device to boot into candidate system %q: %v", label, err)
-	}
 
 	// this task is done, further processing happens in finalize
 	logger.Noticef("restarting into candidate system %q", label)
 	return snapstate.FinishTaskWithRestart(t, state.DoneStatus, restart.RestartSystemNow, nil)
 }
 
-func (m *DeviceManager) undoCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) error {
-	if release.OnClassic {
-		// TODO: this may need to be lifted in the future
-		return fmt.Errorf("internal error: cannot create recovery systems on a classic system")
+// runPrepareRecoverySystemHook runs the prepare-recovery-system hook of the
+// gadget of the model, if the gadget is installed and has such a hook, for the
+// recovery system with the given label created in the given directory. The
+// hook can query the system details with "snapctl recovery-system". The state
+// is released while the hook runs.
+func (m *DeviceManager) runPrepareRecoverySystemHook(model *asserts.Model, label, systemDir string) error {
+	if model.Gadget() == "" {
+		return nil
+	}
+	st := m.state
+	gadgetInfo, err := snapstate.CurrentInfo(st, model.Gadget())
+	if err != nil {
+		var notInstalledErr *snap.NotInstalledError
+		if errors.As(err, &notInstalledErr) {
+			// the gadget is only being installed as part of
+			// a remodel
+			return nil
+		}
+		return err
 	}
+	if gadgetInfo.Hooks["prepare-recovery-system"] == nil {
+		return nil
+	}
+	hooksup := &hookstate.HookSetup{
+		Snap:     gadgetInfo.InstanceName(),
+		Revision: gadgetInfo.Revision,
+		Hook:     "prepare-recovery-system",
+		Timeout:  5 * time.Minute,
+	}
+	contextData := map[string]interface{}{
+		"recovery-system-label":     label,
+		"recovery-system-directory": systemDir,
+	}
+	st.Unlock()
+	defer st.Lock()
+	if _, err := m.hookMgr.EphemeralRunHook(context.Background(), hooksup, contextData); err != nil {
+		return fmt.Errorf("cannot run hook for gadget %q: %v", gadgetInfo.InstanceName(), err)
+	}
+	return nil
+}
 
+func (m *DeviceManager) undoCreateRecoverySystem(_ context.Context, t *state.Task) error {
 	st := t.State()
 	st.Lock()
 	defer st.Unlock()
@@ -291,6 +486,9 @@ func (m *DeviceManager) undoCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) er
 	if err != nil {
 		return err
 	}
+	if release.OnClassic && !remodelCtx.HasModeenv() {
+		return fmt.Errorf("internal error: cannot create recovery systems on a classic system without modes")
+	}
 
 	setup, err := taskRecoverySystemSetup(t)
 	if err != nil {
@@ -303,6 +501,11 @@ func (m *DeviceManager) undoCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) er
 	if err := purgeNewSystemSnapFiles(filepath.Join(setup.Directory, "snapd-new-file-log")); err != nil {
 		t.Logf("when removing seed files: %v", err)
 	}
+	// the log may be gone already, but the files written when creating the
+	// system are also tracked in the task state
+	for _, fileName := range setup.NewFiles {
+		removeNewSystemSnapFile(fileName)
+	}
 	if err := os.RemoveAll(setup.Directory); err != nil && !os.IsNotExist(err) {
 		t.Logf("when removing recovery system %q: %v", label, err)
 		undoErr = err
@@ -318,11 +521,6 @@ func (m *DeviceManager) undoCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) er
 }
 
 func (m *DeviceManager) doFinalizeTriedRecoverySystem(t *state.Task, _ *tomb.Tomb) error {
-	if release.OnClassic {
-		// TODO: this may need to be lifted in the future
-		return fmt.Errorf("internal error: cannot finalize recovery systems on a classic system")
-	}
-
 	st := t.State()
 	st.Lock()
 	defer st.Unlock()
@@ -337,14 +535,10 @@ func (m *DeviceManager) doFinalizeTriedRecoverySystem(t *state.Task, _ *tomb.Tom
 	if err != nil {
 		return err
 	}
-	isRemodel := remodelCtx.ForRemodeling()
-
-	var triedSystems []string
-	// after rebooting to the recovery system and back, the system got moved
-	// to the tried-systems list in the state
-	if err := st.Get("tried-systems", &triedSystems); err != nil {
-		return fmt.Errorf("cannot obtain tried recovery systems: %v", err)
+	if release.OnClassic && !remodelCtx.HasModeenv() {
+		return fmt.Errorf("internal error: cannot finalize recovery systems on a classic system without modes")
 	}
+	isRemodel := remodelCtx.ForRemodeling()
 
 	setup, err := taskRecoverySystemSetup(t)
 	if err != nil {
@@ -354,6 +548,27 @@ func (m *DeviceManager) doFinalizeTriedRecoverySystem(t *state.Task, _ *tomb.Tom
 
 	logger.Debugf("finalize recovery system with label %q", label)
 
+	if setup.SkipTest && !isRemodel {
+		// the system was not tried, treat it as if it was tried
+		// successfully
+		if err := boot.PromoteTriedRecoverySystem(remodelCtx, label, []string{label}); err != nil {
+			return fmt.Errorf("cannot promote recovery system %q: %v", label, err)
+		}
+		updateRecoverySystemsMenu(st, remodelCtx)
+		if err := markDefaultRecoverySystem(remodelCtx, setup); err != nil {
+			return err
+		}
+		t.SetStatus(state.DoneStatus)
+		return nil
+	}
+
+	var triedSystems []string
+	// after rebooting to the recovery system and back, the system got moved
+	// to the tried-systems list in the state
+	if err := st.Get("tried-systems", &triedSystems); err != nil {
+		return fmt.Errorf("cannot obtain tried recovery systems: %v", err)
+	}
+
 	if isRemodel {
 		// so far so good, a recovery system created during remodel was
 		// tested successfully
@@ -369,9 +584,14 @@ func (m *DeviceManager) doFinalizeTriedRecoverySystem(t *state.Task, _ *tomb.Tom
 		if err := boot.PromoteTriedRecoverySystem(remodelCtx, label, triedSystems); err != nil {
 			return fmt.Errorf("cannot promote recovery system %q: %v", label, err)
 		}
+		updateRecoverySystemsMenu(st, remodelCtx)
 
 		// tried systems should be a one item list, we can clear it now
 		st.Set("tried-systems", nil)
+
+		if err := markDefaultRecoverySystem(remodelCtx, setup); err != nil {
+			return err
+		}
 	}
 
 	// we are done
@@ -380,6 +600,20 @@ func (m *DeviceManager) doFinalizeTriedRecoverySystem(t *state.Task, _ *tomb.Tom
 	return nil
 }
 
+// markDefaultRecoverySystem makes the recovery system the default one, if
+// requested when the system was set up. The system must have been tried and
+// promoted already.
+func markDefaultRecoverySystem(dev snap.Device, setup *recoverySystemSetup) error {
+	if !setup.MarkDefault {
+		return nil
+	}
+	if err := boot.SetDefaultRecoverySystem(dev, setup.Label); err != nil {
+		return fmt.Errorf("cannot mark recovery system %q as default: %v", setup.Label, err)
+	}
+	logger.Noticef("recovery system %q is now the default", setup.Label)
+	return nil
+}
+
 func (m *DeviceManager) undoFinalizeTriedRecoverySystem(t *state.Task, _ *tomb.Tomb) error {
 	st := t.State()
 	st.Lock()
@@ -399,6 +633,7 @@ func (m *DeviceManager) undoFinalizeTriedRecoverySystem(t *state.Task, _ *tomb.T
 	if err := boot.DropRecoverySystem(remodelCtx, label); err != nil {
 		return fmt.Errorf("cannot drop a good recovery system %q: %v", label, err)
 	}
+	updateRecoverySystemsMenu(st, remodelCtx)
 
 	return nil
 }
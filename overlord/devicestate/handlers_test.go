@@ -443,6 +443,78 @@ func (s *deviceMgrSuite) TestDoPrepareRemodeling(c *C) {
 	c.Check(ok, Equals, false)
 }
 
+func (s *deviceMgrSuite) TestDoPrepareRemodelingLocalSnapsNoSession(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("seeded", true)
+	s.state.Set("refresh-privacy-key", "some-privacy-key")
+
+	// set a model assertion
+	s.makeModelAssertionInState(c, "canonical", "pc-model", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+		"base":         "core18",
+	})
+	s.makeSerialAssertionInState(c, "canonical", "pc-model", "orig-serial")
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand:           "canonical",
+		Model:           "pc-model",
+		Serial:          "orig-serial",
+		SessionMacaroon: "old-session",
+	})
+
+	new := s.brands.Model("canonical", "rereg-model", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+		"base":         "core18",
+	})
+
+	freshStore := &freshSessionStore{}
+	s.newFakeStore = func(devBE storecontext.DeviceBackend) snapstate.StoreService {
+		return freshStore
+	}
+
+	cur, err := s.mgr.Model()
+	c.Assert(err, IsNil)
+
+	remodCtx, err := devicestate.RemodelCtx(s.state, cur, new)
+	c.Assert(err, IsNil)
+	c.Check(remodCtx.Kind(), Equals, devicestate.ReregRemodel)
+
+	chg := s.state.NewChange("remodel", "...")
+	remodCtx.Init(chg)
+	t := s.state.NewTask("prepare-remodeling", "...")
+	t.Set("local-snaps", []*snap.SideInfo{{RealName: "pc-kernel"}})
+	t.Set("local-paths", []string{"pc-kernel_1.snap"})
+	t.Set("offline", true)
+	chg.AddTask(t)
+
+	s.makeSerialAssertionInState(c, "canonical", "rereg-model", "orig-serial")
+	chg.Set("device", auth.DeviceState{
+		Brand:  "canonical",
+		Model:  "rereg-model",
+		Serial: "orig-serial",
+	})
+
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	c.Assert(chg.Err(), IsNil)
+
+	// offline, no session with the new store was attempted
+	c.Check(freshStore.ensureDeviceSession, Equals, 0)
+
+	tl := chg.Tasks()
+	// 1 prepare-remodeling + 1 "set-model"
+	c.Assert(tl, HasLen, 2)
+	c.Check(tl[1].Kind(), Equals, "set-model")
+}
+
 // TODO: move to preseeding_test.go
 type preseedingBaseSuite struct {
 	deviceMgrBaseSuite
@@ -20,12 +20,15 @@
 package devicestate
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate/internal"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/overlord/storecontext"
@@ -95,6 +98,34 @@ func ClassifyRemodel(oldModel, newModel *asserts.Model) RemodelKind {
 	return UpdateRemodel
 }
 
+// findProvidedSerial returns the serial assertion for the new model that
+// matches the current device key, as it can be provided ahead of a
+// re-registration remodel along with the other assertions for the new brand.
+func findProvidedSerial(st *state.State, newModel *asserts.Model) (*asserts.Serial, error) {
+	device, err := internal.Device(st)
+	if err != nil {
+		return nil, err
+	}
+	if device.KeyID == "" {
+		return nil, state.ErrNoState
+	}
+	serials, err := assertstate.DB(st).FindMany(asserts.SerialType, map[string]string{
+		"brand-id":            newModel.BrandID(),
+		"model":               newModel.Model(),
+		"device-key-sha3-384": device.KeyID,
+	})
+	if errors.Is(err, &asserts.NotFoundError{}) {
+		return nil, state.ErrNoState
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(serials) > 1 {
+		return nil, fmt.Errorf("cannot use multiple serial assertions for model %s/%s and the same device key", newModel.BrandID(), newModel.Model())
+	}
+	return serials[0].(*asserts.Serial), nil
+}
+
 type remodelCtxKey struct {
 	chgID string
 }
@@ -0,0 +1,268 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate/internal"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/storecontext"
+)
+
+// RenewSerial creates a change that generates a new device key pair and
+// requests a new serial assertion for it, proving possession of the current
+// serial and device key. The new key and serial replace the current ones
+// only once a store session could be established with them, the replaced
+// serial is then kept for auditing, see DeviceManager.PreviousSerials.
+func RenewSerial(st *state.State) (*state.Change, error) {
+	device, err := internal.Device(st)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := findSerial(st, device)
+	if errors.Is(err, state.ErrNoState) {
+		return nil, fmt.Errorf("cannot renew serial of a device that is not registered yet")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if device.KeyID == "" {
+		return nil, fmt.Errorf("internal error: inconsistent state with serial but no device key")
+	}
+
+	if err := snapstate.CheckChangeConflictRunExclusively(st, "renew-serial"); err != nil {
+		return nil, err
+	}
+	if err := checkSerialRenewalConflict(st); err != nil {
+		return nil, err
+	}
+
+	prepare := st.NewTask("prepare-serial-renewal", i18n.G("Generate new device key"))
+	requestSerial := st.NewTask("request-serial", i18n.G("Request new device serial"))
+	requestSerial.WaitFor(prepare)
+	finish := st.NewTask("finish-serial-renewal", i18n.G("Switch to new device key and serial"))
+	finish.WaitFor(requestSerial)
+
+	chg := st.NewChange("renew-serial", fmt.Sprintf(i18n.G("Renew device serial %s"), serial.Serial()))
+	// keep the serial being replaced, it might be superseded in the
+	// assertion database by a new revision for the new key
+	chg.Set("original-serial", string(asserts.Encode(serial)))
+	chg.AddAll(state.NewTaskSet(prepare, requestSerial, finish))
+
+	return chg, nil
+}
+
+// checkSerialRenewalConflict returns a conflict error if a serial renewal
+// is in progress.
+func checkSerialRenewalConflict(st *state.State) error {
+	for _, chg := range st.Changes() {
+		if chg.Kind() == "renew-serial" && !chg.IsReady() {
+			return &snapstate.ChangeConflictError{
+				Message:    "serial renewal in progress, no other registration changes allowed until this is done",
+				ChangeKind: "renew-serial",
+				ChangeID:   chg.ID(),
+			}
+		}
+	}
+	return nil
+}
+
+// renewalRegistrationContext implements registrationContext for a serial
+// renewal, the device state being registered is kept in the change until
+// the renewal is finished.
+type renewalRegistrationContext struct {
+	chg *state.Change
+
+	model      *asserts.Model
+	origSerial *asserts.Serial
+	origKey    asserts.PrivateKey
+}
+
+func (m *DeviceManager) renewalRegistrationCtx(chg *state.Change) (*renewalRegistrationContext, error) {
+	model, err := m.Model()
+	if err != nil {
+		return nil, err
+	}
+	var encoded string
+	if err := chg.Get("original-serial", &encoded); err != nil {
+		return nil, fmt.Errorf("internal error: cannot get original serial of renewal: %v", err)
+	}
+	a, err := asserts.Decode([]byte(encoded))
+	if err != nil {
+		return nil, err
+	}
+	origSerial, ok := a.(*asserts.Serial)
+	if !ok {
+		return nil, fmt.Errorf("internal error: original serial of renewal is a %s assertion", a.Type().Name)
+	}
+	// load it now, proving possession happens without the state lock
+	origKey, err := m.keyPairByID(origSerial.DeviceKey().ID())
+	if err != nil {
+		return nil, err
+	}
+	return &renewalRegistrationContext{
+		chg:        chg,
+		model:      model,
+		origSerial: origSerial,
+		origKey:    origKey,
+	}, nil
+}
+
+func (rc *renewalRegistrationContext) ForRemodeling() bool {
+	return false
+}
+
+func (rc *renewalRegistrationContext) Device() (*auth.DeviceState, error) {
+	var device auth.DeviceState
+	if err := rc.chg.Get("device", &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (rc *renewalRegistrationContext) setDevice(device *auth.DeviceState) {
+	rc.chg.Set("device", device)
+}
+
+func (rc *renewalRegistrationContext) Model() *asserts.Model {
+	return rc.model
+}
+
+func (rc *renewalRegistrationContext) GadgetForSerialRequestConfig() string {
+	return rc.model.Gadget()
+}
+
+func (rc *renewalRegistrationContext) SerialRequestExtraHeaders() map[string]interface{} {
+	return map[string]interface{}{
+		"original-brand-id": rc.origSerial.BrandID(),
+		"original-model":    rc.origSerial.Model(),
+		"original-serial":   rc.origSerial.Serial(),
+	}
+}
+
+func (rc *renewalRegistrationContext) SerialRequestAncillaryAssertions() []asserts.Assertion {
+	return []asserts.Assertion{rc.model, rc.origSerial}
+}
+
+// OriginalKeyProof returns a serial-request for the original serial signed
+// with the original device key, for the same request-id as the serial
+// request for the new key.
+func (rc *renewalRegistrationContext) OriginalKeyProof(requestID string) (asserts.Assertion, error) {
+	encodedPubKey, err := asserts.EncodePublicKey(rc.origKey.PublicKey())
+	if err != nil {
+		return nil, fmt.Errorf("internal error: cannot encode original device public key: %v", err)
+	}
+	return asserts.SignWithoutAuthority(asserts.SerialRequestType, map[string]interface{}{
+		"brand-id":   rc.origSerial.BrandID(),
+		"model":      rc.origSerial.Model(),
+		"request-id": requestID,
+		"device-key": string(encodedPubKey),
+		"serial":     rc.origSerial.Serial(),
+	}, nil, rc.origKey)
+}
+
+func (rc *renewalRegistrationContext) FinishRegistration(serial *asserts.Serial) error {
+	device, err := rc.Device()
+	if err != nil {
+		return err
+	}
+
+	device.Serial = serial.Serial()
+	rc.setDevice(device)
+	return nil
+}
+
+// renewalDeviceBackend ties a store to the device state of a serial
+// renewal, to establish a session with the new key and serial.
+type renewalDeviceBackend struct {
+	*renewalRegistrationContext
+	st *state.State
+}
+
+func (b renewalDeviceBackend) SetDevice(device *auth.DeviceState) error {
+	b.setDevice(device)
+	return nil
+}
+
+func (b renewalDeviceBackend) Model() (*asserts.Model, error) {
+	return b.model, nil
+}
+
+func (b renewalDeviceBackend) Serial() (*asserts.Serial, error) {
+	device, err := b.Device()
+	if err != nil {
+		return nil, err
+	}
+	return findSerial(b.st, device)
+}
+
+var _ storecontext.DeviceBackend = renewalDeviceBackend{}
+
+// previousSerial records a serial replaced by a renewal.
+type previousSerial struct {
+	Serial    string    `json:"serial"`
+	KeyID     string    `json:"key-id"`
+	Assertion string    `json:"assertion"`
+	Replaced  time.Time `json:"replaced"`
+}
+
+func addPreviousSerial(st *state.State, serial *asserts.Serial) error {
+	var previous []previousSerial
+	if err := st.Get("device-previous-serials", &previous); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	previous = append(previous, previousSerial{
+		Serial:    serial.Serial(),
+		KeyID:     serial.DeviceKey().ID(),
+		Assertion: string(asserts.Encode(serial)),
+		Replaced:  timeNow(),
+	})
+	st.Set("device-previous-serials", previous)
+	return nil
+}
+
+// PreviousSerials returns the serial assertions the device had before
+// renewals, oldest first.
+func (m *DeviceManager) PreviousSerials() ([]*asserts.Serial, error) {
+	var previous []previousSerial
+	if err := m.state.Get("device-previous-serials", &previous); err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, err
+	}
+	serials := make([]*asserts.Serial, 0, len(previous))
+	for _, p := range previous {
+		a, err := asserts.Decode([]byte(p.Assertion))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode previous serial %q: %v", p.Serial, err)
+		}
+		serial, ok := a.(*asserts.Serial)
+		if !ok {
+			return nil, fmt.Errorf("internal error: previous serial %q is a %s assertion", p.Serial, a.Type().Name)
+		}
+		serials = append(serials, serial)
+	}
+	return serials, nil
+}
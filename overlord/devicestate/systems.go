@@ -20,22 +20,39 @@
 package devicestate
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/boot"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/seed/seedwriter"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snapfile"
+	"github.com/snapcore/snapd/snapdtool"
 	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/timings"
 )
 
 func checkSystemRequestConflict(st *state.State, systemLabel string) error {
@@ -194,30 +211,709 @@ func seededSystemFromModeenv() (*seededSystem, error) {
 	return seededSys, nil
 }
 
-// getInfoFunc is expected to return for a given snap name a snap.Info for that
-// snap and whether the snap is present is present. The second bit is relevant
-// for non-essential snaps mentioned in the model, which if present and having
-// an 'optional' presence in the model, will be added to the recovery system.
-type getSnapInfoFunc func(name string) (info *snap.Info, snapIsPresent bool, err error)
+// getSnapInfoFunc is expected to return for a given snap of the model a
+// snap.Info for that snap and whether the snap is present. The second bit is
+// relevant for non-essential snaps mentioned in the model, which if present and
+// having an 'optional' presence in the model, will be added to the recovery
+// system. The model snap carries the type, presence and default channel of the
+// snap declared by the model, the snapd snap is passed as a required snap
+// tracking latest/stable when the model does not mention it.
+type getSnapInfoFunc func(modelSnap *asserts.ModelSnap) (info *snap.Info, snapIsPresent bool, err error)
+
+// snapInfoGetterByName adapts a function returning the snap.Info of a snap
+// given just its name, to a getSnapInfoFunc.
+func snapInfoGetterByName(getInfo func(name string) (info *snap.Info, present bool, err error)) getSnapInfoFunc {
+	return func(modelSnap *asserts.ModelSnap) (*snap.Info, bool, error) {
+		return getInfo(modelSnap.SnapName())
+	}
+}
+
+// cachedSnapInfoGetter wraps a getSnapInfoFunc such that the information of
+// each snap is obtained only once, which avoids computing the digest of a
+// snap file repeatedly when the snaps are checked before being copied.
+func cachedSnapInfoGetter(getInfo getSnapInfoFunc) getSnapInfoFunc {
+	type cachedInfo struct {
+		info    *snap.Info
+		present bool
+	}
+	cache := make(map[string]cachedInfo)
+	return func(modelSnap *asserts.ModelSnap) (*snap.Info, bool, error) {
+		name := modelSnap.SnapName()
+		if cached, ok := cache[name]; ok {
+			return cached.info, cached.present, nil
+		}
+		info, present, err := getInfo(modelSnap)
+		if err != nil {
+			return nil, false, err
+		}
+		cache[name] = cachedInfo{info: info, present: present}
+		return info, present, nil
+	}
+}
+
+// getSnapComponentsFunc is expected to return the components of a snap which
+// is added to the recovery system, given its snap.Info and the path to the
+// snap file. The component files are copied into the snaps directory of the
+// system.
+type getSnapComponentsFunc func(info *snap.Info, snapPath string) ([]*seedwriter.SeedComponent, error)
 
 // snapWriteObserveFunc is called with the recovery system directory and the
 // path to a snap file being written. The snap file may be written to a location
 // under the common snaps directory.
 type snapWriteObserveFunc func(systemDir, where string) error
 
+// ErrSystemExists is returned when a recovery system with a given label
+// already exists.
+type ErrSystemExists struct {
+	Label string
+}
+
+func (e *ErrSystemExists) Error() string {
+	return fmt.Sprintf("recovery system %q already exists", e.Label)
+}
+
+// MissingModelSnapsError is returned when a recovery system cannot be created
+// because some of the snaps required by the model are not present.
+type MissingModelSnapsError struct {
+	// Snaps lists the names of the missing snaps, in the order of the
+	// model.
+	Snaps []string
+	// kinds describes each one of the missing snaps, eg. essential
+	kinds []string
+}
+
+func (e *MissingModelSnapsError) Error() string {
+	if len(e.Snaps) == 1 {
+		return fmt.Sprintf("%s snap %q not present", e.kinds[0], e.Snaps[0])
+	}
+	var buf bytes.Buffer
+	buf.WriteString("snaps not present:")
+	for i, name := range e.Snaps {
+		fmt.Fprintf(&buf, "\n- %s snap %q", e.kinds[i], name)
+	}
+	return buf.String()
+}
+
+// SeedNotWritableError is returned when a recovery system cannot be created
+// because ubuntu-seed is not mounted, or mounted such that it cannot be
+// written to.
+type SeedNotWritableError struct {
+	Reason string
+}
+
+func (e *SeedNotWritableError) Error() string {
+	return fmt.Sprintf("ubuntu-seed is not mounted writable: %s", e.Reason)
+}
+
+// checkSeedWritable verifies that ubuntu-seed is mounted with a filesystem
+// which can hold recovery systems, and that it is mounted read-write.
+func checkSeedWritable() error {
+	entries, err := osutil.LoadMountInfo()
+	if err != nil {
+		return fmt.Errorf("cannot check ubuntu-seed mount: %v", err)
+	}
+	var seedMount *osutil.MountInfoEntry
+	for _, entry := range entries {
+		// the last mount is the one which is visible
+		if entry.MountDir == boot.InitramfsUbuntuSeedDir {
+			seedMount = entry
+		}
+	}
+	if seedMount == nil {
+		return &SeedNotWritableError{Reason: fmt.Sprintf("%s is not a mount point", boot.InitramfsUbuntuSeedDir)}
+	}
+	if seedMount.FsType != "vfat" && seedMount.FsType != "ext4" {
+		return &SeedNotWritableError{Reason: fmt.Sprintf("unsupported filesystem %q", seedMount.FsType)}
+	}
+	_, mountRO := seedMount.MountOptions["ro"]
+	_, superRO := seedMount.SuperOptions["ro"]
+	if mountRO || superRO {
+		// eg. remounted read-only after filesystem errors
+		return &SeedNotWritableError{Reason: "mounted read-only"}
+	}
+	return nil
+}
+
+// checkNewSystemLabel verifies that the label is valid for a new recovery
+// system and that no system with such label exists yet.
+func checkNewSystemLabel(label string) error {
+	if err := asserts.IsValidSystemLabel(label); err != nil {
+		return err
+	}
+	systemDirectory := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label)
+	exists, _, err := osutil.DirExists(systemDirectory)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return &ErrSystemExists{Label: label}
+	}
+	return nil
+}
+
+// name of the file in the recovery system directory carrying the creation
+// information of the system, the file is not part of the seed
+const recoverySystemCreationInfoFile = "snapd-system-info"
+
+// RecoverySystemCreationInfo carries the details of how a recovery system was
+// created by snapd.
+type RecoverySystemCreationInfo struct {
+	// SnapdVersion is the version of snapd which created the system.
+	SnapdVersion string `json:"snapd-version"`
+	// Created is the time when the system was created.
+	Created time.Time `json:"created"`
+	// ChangeKind and ChangeID identify the change which created the
+	// system, eg. a remodel.
+	ChangeKind string `json:"change-kind,omitempty"`
+	ChangeID   string `json:"change-id,omitempty"`
+	// HasUnassertedSnaps is set when the system contains unasserted snaps.
+	HasUnassertedSnaps bool `json:"has-unasserted-snaps"`
+}
+
+func writeRecoverySystemCreationInfo(systemDir string, info *RecoverySystemCreationInfo) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(filepath.Join(systemDir, recoverySystemCreationInfoFile), b, 0644, 0)
+}
+
+// readRecoverySystemCreationInfo reads the creation information of the
+// recovery system in the given directory, nil is returned when the system
+// carries no such information, eg. it was not created by snapd.
+func readRecoverySystemCreationInfo(systemDir string) (*RecoverySystemCreationInfo, error) {
+	b, err := ioutil.ReadFile(filepath.Join(systemDir, recoverySystemCreationInfoFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var info RecoverySystemCreationInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, fmt.Errorf("cannot decode creation information: %v", err)
+	}
+	return &info, nil
+}
+
+// snapDownloadFunc is expected to download the snap with the given name from
+// the given channel, returning the path to the downloaded snap file and the
+// snap information. The snap may be downloaded directly to its location in the
+// shared snaps directory of ubuntu-seed. Downloaded snaps are checked against
+// their assertions, which must have been added to the assertions database used
+// for creating the system by the time the download completes. Progress
+// reporting and retries are left to the implementation, typically by the means
+// of the store download.
+type snapDownloadFunc func(name, channel string) (path string, info *snap.Info, err error)
+
+// snapCopyProgressFunc is called with the name of the snap whose file is being
+// copied, the number of bytes copied so far and the total size of the file.
+type snapCopyProgressFunc func(snapName string, copied, total int64)
+
+// createSystemOptions carries optional settings for creating a recovery
+// system.
+type createSystemOptions struct {
+	// Progress, when set, is used for reporting the progress of copying
+	// the snap files of the new system.
+	Progress snapCopyProgressFunc
+	// ExtraSnaps is a list of paths to snap files which are not part of
+	// the model, but should be included in the new system as extra snaps.
+	// Only models of grade dangerous support extra snaps.
+	ExtraSnaps []string
+	// ValidationSets, when set, are the validation sets the snaps of the
+	// new system must satisfy.
+	ValidationSets *snapasserts.ValidationSets
+	// Downloader, when set, is used for obtaining the snaps required by
+	// the model which are not present.
+	Downloader snapDownloadFunc
+	// CopyWorkers is the number of snap files copied in parallel, when
+	// unset defaultSnapCopyWorkers is used.
+	CopyWorkers int
+	// Unlocker, when set, is called to release the state lock for the
+	// duration of copying the snap files, which may take a while. The
+	// returned function relocks the state. Progress is reported while
+	// the state is unlocked.
+	Unlocker func() (relock func())
+	// ChangeKind and ChangeID identify the change which creates the
+	// system, they are recorded in the creation information of the
+	// system.
+	ChangeKind string
+	ChangeID   string
+	// GetComponents, when set, is used for obtaining the components of the
+	// snaps required by the model, which are then included in the new
+	// system.
+	GetComponents getSnapComponentsFunc
+	// VerifyCopies, when set, makes each written snap file be verified
+	// against the digest from its snap-revision assertion, or the digest
+	// of the source file for unasserted snaps. The copy is retried once
+	// when the written file does not match.
+	VerifyCopies bool
+	// PreseedArtifact and PreseedAssertion, when set, are passed through
+	// to the new system as its preseed.tgz artifact and its preseed
+	// assertion. The assertion must be for the label of the new system.
+	PreseedArtifact  string
+	PreseedAssertion *asserts.Preseed
+	// ReferenceSystem, when set, is the label of an existing recovery
+	// system on ubuntu-seed. Asserted snaps with the same revision in
+	// that system reuse its snap files, which are neither verified nor
+	// hashed again, and their assertions are fetched directly instead of
+	// being derived from the snap files. The new system does not
+	// otherwise depend on the reference system.
+	ReferenceSystem string
+	// Timings, when set, is used to measure the fetching of the
+	// assertions of the new system.
+	Timings timings.Measurer
+	// Context, when set, stops the copying of the snap files once it is
+	// cancelled. The file being copied is removed then.
+	Context context.Context
+}
+
+// copy buffer size, which also limits how often progress is reported
+const snapCopyBufferSize = 1024 * 1024
+
+// number of snap files copied in parallel when creating a recovery system, a
+// small number is enough to make use of the bandwidth of fast storage
+const defaultSnapCopyWorkers = 2
+
+// snapCopyJob describes a snap file to be written to the recovery system.
+type snapCopyJob struct {
+	name string
+	src  string
+	dst  string
+	// digest is the asserted digest of the snap file, if known
+	digest string
+}
+
+// copySnapFiles calls copy for each one of the jobs, using the given number of
+// workers. No new copies are started once any of them fails. The returned
+// error is the one of the first failed job in the order of jobs.
+func copySnapFiles(jobs []snapCopyJob, workers int, copy func(job snapCopyJob) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	errs := make([]error, len(jobs))
+	var failed int32
+	jobIdx := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobIdx {
+				if atomic.LoadInt32(&failed) != 0 {
+					// another copy failed, skip this one
+					continue
+				}
+				if err := copy(jobs[idx]); err != nil {
+					errs[idx] = err
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+	for idx := range jobs {
+		if atomic.LoadInt32(&failed) != 0 {
+			break
+		}
+		jobIdx <- idx
+	}
+	close(jobIdx)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type snapCopyProgressWriter struct {
+	ctx      context.Context
+	name     string
+	copied   int64
+	total    int64
+	progress snapCopyProgressFunc
+}
+
+func (w *snapCopyProgressWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	w.copied += int64(len(p))
+	w.progress(w.name, w.copied, w.total)
+	return len(p), nil
+}
+
+// copySnapFileWithProgress copies the snap file from src to dst, which must
+// not exist, reporting the progress along the way. The copy stops when ctx is
+// cancelled, in which case the partially written dst is removed.
+func copySnapFileWithProgress(ctx context.Context, name, src, dst string, progress snapCopyProgressFunc) (err error) {
+	fin, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %v", src, err)
+	}
+	defer fin.Close()
+	fi, err := fin.Stat()
+	if err != nil {
+		return fmt.Errorf("unable to stat %s: %v", src, err)
+	}
+	fout, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode())
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %v", dst, err)
+	}
+	defer func() {
+		if cerr := fout.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("when closing %s: %v", dst, cerr)
+		}
+		if err != nil && ctx.Err() != nil {
+			if rerr := os.Remove(dst); rerr != nil {
+				logger.Noticef("cannot remove partially copied %s: %v", dst, rerr)
+			}
+		}
+	}()
+
+	pw := &snapCopyProgressWriter{
+		ctx:      ctx,
+		name:     name,
+		total:    fi.Size(),
+		progress: progress,
+	}
+	progress(name, 0, pw.total)
+	buf := make([]byte, snapCopyBufferSize)
+	if _, err := io.CopyBuffer(io.MultiWriter(fout, pw), fin, buf); err != nil {
+		return fmt.Errorf("unable to copy %s to %s: %v", src, dst, err)
+	}
+	if err := fout.Sync(); err != nil {
+		return fmt.Errorf("unable to sync %s: %v", dst, err)
+	}
+	return nil
+}
+
+var osLink = os.Link
+
+// linkOrCopySnapFile hard links the snap file at src to dst, which must not
+// exist, such that no space is wasted when both are on the same filesystem.
+// When linking is not possible, eg. src and dst are on different filesystems
+// or the filesystem does not support hard links, the file is copied and synced
+// instead.
+func linkOrCopySnapFile(ctx context.Context, name, src, dst string, progress snapCopyProgressFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := osLink(src, dst)
+	if err == nil {
+		if progress != nil {
+			fi, err := os.Stat(dst)
+			if err != nil {
+				return err
+			}
+			progress(name, fi.Size(), fi.Size())
+		}
+		return nil
+	}
+	logger.Debugf("cannot link %v to %v, copying instead: %v", src, dst, err)
+	if progress != nil {
+		return copySnapFileWithProgress(ctx, name, src, dst, progress)
+	}
+	return osutil.CopyFileWithContext(ctx, src, dst, osutil.CopyFlagSync)
+}
+
+// findSnapRevision finds the snap-revision assertion of an asserted snap.
+func findSnapRevision(db asserts.RODatabase, info *snap.Info) (*asserts.SnapRevision, error) {
+	as, err := db.FindMany(asserts.SnapRevisionType, map[string]string{
+		"snap-id":       info.SnapID,
+		"snap-revision": info.Revision.String(),
+	})
+	if err != nil {
+		if errors.Is(err, &asserts.NotFoundError{}) {
+			return nil, fmt.Errorf("cannot find snap-revision assertion")
+		}
+		return nil, err
+	}
+	return as[0].(*asserts.SnapRevision), nil
+}
+
+// how many times a snap file is copied before giving up when the written file
+// does not match the source
+const maxSnapCopyAttempts = 2
+
+// verifySnapFileCopy verifies that dst, a copy of the snap file at src, has the
+// expected digest. There is nothing to verify when dst is a hard link to src.
+func verifySnapFileCopy(src, dst, digest string) error {
+	srcFi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	dstFi, err := os.Stat(dst)
+	if err != nil {
+		return err
+	}
+	if os.SameFile(srcFi, dstFi) {
+		return nil
+	}
+	dstDigest, _, err := asserts.SnapFileSHA3_384(dst)
+	if err != nil {
+		return fmt.Errorf("cannot compute digest of %s: %v", dst, err)
+	}
+	if dstDigest != digest {
+		return fmt.Errorf("digest of %s does not match the expected digest", dst)
+	}
+	return nil
+}
+
+// linkOrCopySnapFileVerified links or copies the snap file like
+// linkOrCopySnapFile, and verifies that the written file has the expected
+// digest. A copy which does not match is removed and the copy is retried, if
+// the last attempt fails too, the file is left in place such that the caller
+// can clean it up.
+func linkOrCopySnapFileVerified(ctx context.Context, name, src, dst, digest string, progress snapCopyProgressFunc) error {
+	for attempt := 1; ; attempt++ {
+		if err := linkOrCopySnapFile(ctx, name, src, dst, progress); err != nil {
+			return err
+		}
+		err := verifySnapFileCopy(src, dst, digest)
+		if err == nil {
+			return nil
+		}
+		if attempt == maxSnapCopyAttempts {
+			return fmt.Errorf("cannot verify copy of snap %q: %v", name, err)
+		}
+		logger.Noticef("cannot verify copy of snap %q, retrying: %v", name, err)
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	}
+}
+
+// checkSnapFileAgainstAssertion verifies that the snap file at the given path
+// of an asserted snap matches the size and digest of its snap-revision
+// assertion. The digest is computed unless it is already known.
+func checkSnapFileAgainstAssertion(db asserts.RODatabase, info *snap.Info, path string, known *snapFileDigest) (*snapFileDigest, error) {
+	snapRev, err := findSnapRevision(db, info)
+	if err != nil {
+		return nil, err
+	}
+	d := known
+	if d == nil {
+		digest, size, err := asserts.SnapFileSHA3_384(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compute digest of snap file: %v", err)
+		}
+		d = &snapFileDigest{digest: digest, size: size}
+	}
+	if snapRev.SnapSize() != d.size {
+		return nil, fmt.Errorf("snap file size %d does not match the asserted size %d", d.size, snapRev.SnapSize())
+	}
+	if snapRev.SnapSHA3_384() != d.digest {
+		return nil, fmt.Errorf("snap file digest does not match the asserted digest")
+	}
+	return d, nil
+}
+
+// referenceSystemSnapDigests returns the digests of the asserted snaps of the
+// complete recovery system with the given label, indexed by snap ID and
+// revision. The assertions are not verified here, the digests are only
+// matched against the snap-revision assertions from the device database.
+func referenceSystemSnapDigests(label string) (map[string]string, error) {
+	systemDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label)
+	// the model is written last when creating a system
+	if !osutil.FileExists(filepath.Join(systemDir, "model")) {
+		return nil, fmt.Errorf("system is incomplete")
+	}
+	f, err := os.Open(filepath.Join(systemDir, "assertions", "snaps"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	digests := make(map[string]string)
+	dec := asserts.NewDecoder(f)
+	for {
+		a, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if snapRev, ok := a.(*asserts.SnapRevision); ok {
+			key := fmt.Sprintf("%s/%d", snapRev.SnapID(), snapRev.SnapRevision())
+			digests[key] = snapRev.SnapSHA3_384()
+		}
+	}
+	return digests, nil
+}
+
+// snapsFromReferenceSystem returns the snap-revision assertions of those
+// asserted snaps from infos, indexed by path, which are also part of the given
+// reference recovery system and whose files can be reused as they are present
+// in the seed snaps directory.
+func snapsFromReferenceSystem(db asserts.RODatabase, label, seedSnapsDir string, infos map[string]*snap.Info) (map[string]*asserts.SnapRevision, error) {
+	digests, err := referenceSystemSnapDigests(label)
+	if err != nil {
+		return nil, err
+	}
+	reused := make(map[string]*asserts.SnapRevision)
+	for path, info := range infos {
+		if info.SnapID == "" {
+			continue
+		}
+		refDigest, ok := digests[fmt.Sprintf("%s/%s", info.SnapID, info.Revision)]
+		if !ok {
+			continue
+		}
+		snapRev, err := findSnapRevision(db, info)
+		if err != nil {
+			return nil, fmt.Errorf("cannot use snap %q: %v", info.SnapName(), err)
+		}
+		if snapRev.SnapSHA3_384() != refDigest {
+			continue
+		}
+		fi, err := os.Stat(filepath.Join(seedSnapsDir, info.Filename()))
+		if err != nil || uint64(fi.Size()) != snapRev.SnapSize() {
+			continue
+		}
+		reused[path] = snapRev
+	}
+	return reused, nil
+}
+
+// snapFileDigest is the digest and size of a snap file.
+type snapFileDigest struct {
+	digest string
+	size   uint64
+}
+
+// checkSnapFilesAgainstAssertions verifies the snap files at the provided paths
+// of asserted snaps, returning an error listing every snap that does not match
+// its assertions. The digests of the files are only computed when not already
+// known. The digests of the verified files are returned, indexed by path.
+func checkSnapFilesAgainstAssertions(db asserts.RODatabase, paths []string, infos map[string]*snap.Info, known map[string]*snapFileDigest) (map[string]*snapFileDigest, error) {
+	var buf bytes.Buffer
+	verified := make(map[string]*snapFileDigest, len(paths))
+	for _, path := range paths {
+		info := infos[path]
+		d, err := checkSnapFileAgainstAssertion(db, info, path, known[path])
+		if err != nil {
+			fmt.Fprintf(&buf, "\n- snap %q (revision %s): %v", info.SnapName(), info.Revision, err)
+			continue
+		}
+		verified[path] = d
+	}
+	if buf.Len() > 0 {
+		return nil, fmt.Errorf("cannot verify snap files against their assertions:%s", buf.String())
+	}
+	return verified, nil
+}
+
+// checkSystemSnapsAgainstValidationSets verifies the snaps of a system against
+// the given validation sets, returning an error listing all the violations.
+func checkSystemSnapsAgainstValidationSets(vsets *snapasserts.ValidationSets, infos map[string]*snap.Info) error {
+	snaps := make([]*snapasserts.InstalledSnap, 0, len(infos))
+	revisions := make(map[string]snap.Revision, len(infos))
+	for _, info := range infos {
+		snaps = append(snaps, snapasserts.NewInstalledSnap(info.SnapName(), info.SnapID, info.Revision))
+		revisions[info.SnapName()] = info.Revision
+	}
+	err := vsets.CheckInstalledSnaps(snaps, nil)
+	if err == nil {
+		return nil
+	}
+	verr, ok := err.(*snapasserts.ValidationSetsValidationError)
+	if !ok {
+		return err
+	}
+
+	wantedRevisions := func(revs map[snap.Revision][]string) string {
+		wanted := make([]string, 0, len(revs))
+		for rev, sets := range revs {
+			sort.Strings(sets)
+			if rev.Unset() {
+				wanted = append(wanted, fmt.Sprintf("at any revision by sets %s", strings.Join(sets, ",")))
+			} else {
+				wanted = append(wanted, fmt.Sprintf("at revision %s by sets %s", rev, strings.Join(sets, ",")))
+			}
+		}
+		sort.Strings(wanted)
+		return strings.Join(wanted, ", ")
+	}
+
+	var violations []string
+	for name, revs := range verr.MissingSnaps {
+		violations = append(violations, fmt.Sprintf("snap %q is required %s, but is not part of the system", name, wantedRevisions(revs)))
+	}
+	for name, revs := range verr.WrongRevisionSnaps {
+		violations = append(violations, fmt.Sprintf("snap %q is required %s, found revision %s", name, wantedRevisions(revs), revisions[name]))
+	}
+	for name, sets := range verr.InvalidSnaps {
+		sort.Strings(sets)
+		violations = append(violations, fmt.Sprintf("snap %q is invalid for sets %s, found revision %s", name, strings.Join(sets, ","), revisions[name]))
+	}
+	sort.Strings(violations)
+	return fmt.Errorf("cannot create a system with snaps not matching the validation sets:\n- %s", strings.Join(violations, "\n- "))
+}
+
+// readExtraSnapInfo reads the information of an extra snap from its file.
+func readExtraSnapInfo(path string, si *snap.SideInfo) (*snap.Info, error) {
+	snapFile, err := snapfile.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open extra snap %q: %v", path, err)
+	}
+	info, err := snap.ReadInfoFromSnapFile(snapFile, si)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read extra snap %q: %v", path, err)
+	}
+	return info, nil
+}
+
+// implicitSnapdModelSnap returns the model snap entry of the snapd snap, for
+// models which do not mention it explicitly.
+func implicitSnapdModelSnap() *asserts.ModelSnap {
+	return &asserts.ModelSnap{
+		Name:           "snapd",
+		SnapType:       "snapd",
+		Modes:          []string{"run", "ephemeral"},
+		DefaultChannel: "latest/stable",
+		Presence:       "required",
+	}
+}
+
 // createSystemForModelFromValidatedSnaps creates a new recovery system for the
 // specified model with the specified label using the snaps in the database and
 // the getInfo function.
 //
-// The function returns the directory of the new recovery system as well as the
-// set of absolute file paths to the new snap files that were written for the
-// recovery system - some snaps may be in the recovery system directory while
-// others may be in the common snaps directory shared between multiple recovery
-// systems on ubuntu-seed.
-func createSystemForModelFromValidatedSnaps(model *asserts.Model, label string, db asserts.RODatabase, getInfo getSnapInfoFunc, observeWrite snapWriteObserveFunc) (dir string, err error) {
+// The function returns the directory of the new recovery system. The snap
+// files written for the recovery system are reported through observeWrite -
+// some snaps may be in the recovery system directory while others may be in
+// the common snaps directory shared between multiple recovery systems on
+// ubuntu-seed. The directory is returned even on error, as long as it may
+// have been created, such that the caller can clean it up.
+func createSystemForModelFromValidatedSnaps(model *asserts.Model, label string, db asserts.RODatabase, getInfo getSnapInfoFunc, observeWrite snapWriteObserveFunc, opts *createSystemOptions) (dir string, err error) {
+	if opts == nil {
+		opts = &createSystemOptions{}
+	}
+	tm := opts.Timings
+	if tm == nil {
+		tm = timings.New(nil)
+	}
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	if model.Grade() == asserts.ModelGradeUnset {
 		return "", fmt.Errorf("cannot create a system for pre-UC20 model")
 	}
+	// verify the label before anything gets written
+	if err := checkNewSystemLabel(label); err != nil {
+		return "", err
+	}
+	if err := checkSeedWritable(); err != nil {
+		return "", err
+	}
+	if len(opts.ExtraSnaps) > 0 && model.Grade() != asserts.ModelDangerous {
+		return "", fmt.Errorf("cannot use extra snaps with a model of grade %q", model.Grade())
+	}
 
 	logger.Noticef("creating recovery system with label %q for %q", label, model.Model())
 
@@ -230,25 +926,57 @@ func createSystemForModelFromValidatedSnaps(model *asserts.Model, label string,
 		// RW mount of ubuntu-seed
 		SeedDir: boot.InitramfsUbuntuSeedDir,
 		Label:   label,
+
+		PreseedArtifact:  opts.PreseedArtifact,
+		PreseedAssertion: opts.PreseedAssertion,
 	}
 	w, err := seedwriter.New(model, wOpts)
 	if err != nil {
 		return "", err
 	}
 
-	optsSnaps := make([]*seedwriter.OptionsSnap, 0, len(model.RequiredWithEssentialSnaps()))
 	// collect all snaps that are present
 	modelSnaps := make(map[string]*snap.Info)
+	modelInfos := make([]*snap.Info, 0, len(model.RequiredWithEssentialSnaps()))
+	modelPaths := make(map[*snap.Info]string)
+	// snaps which were downloaded by creating the system, mapped to whether
+	// they have been reported through observeWrite
+	downloadedSnaps := make(map[string]bool)
+	downloadedNames := make(map[string]bool)
+	// digests of the snap files which are already known
+	knownDigests := make(map[string]*snapFileDigest)
+	// components of the model snaps, by the path of the snap file
+	modelComponents := make(map[string][]*seedwriter.SeedComponent)
+	// snaps which are neither present nor can be downloaded, all of them
+	// are reported at once
+	missing := &MissingModelSnapsError{}
+	defer func() {
+		// files which were not reported were either downloaded outside
+		// of the seed and copied already, or cannot be cleaned up by
+		// the caller
+		for path, observed := range downloadedSnaps {
+			if observed {
+				continue
+			}
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				logger.Noticef("cannot remove downloaded snap %q: %v", path, err)
+			}
+		}
+	}()
 
-	getModelSnap := func(name string, essential bool, nonEssentialPresence string) error {
+	getModelSnap := func(modelSnap *asserts.ModelSnap, essential bool) error {
+		name := modelSnap.SnapName()
+		channel := modelSnap.DefaultChannel
+		nonEssentialPresence := ""
 		kind := "essential"
 		if !essential {
+			nonEssentialPresence = modelSnap.Presence
 			kind = "non-essential"
 			if nonEssentialPresence != "" {
 				kind = fmt.Sprintf("non-essential but %v", nonEssentialPresence)
 			}
 		}
-		info, present, err := getInfo(name)
+		info, present, err := getInfo(modelSnap)
 		if err != nil {
 			return fmt.Errorf("cannot obtain %v snap information: %v", kind, err)
 		}
@@ -259,56 +987,182 @@ func createSystemForModelFromValidatedSnaps(model *asserts.Model, label string,
 		}
 		// grab those
 		logger.Debugf("%v snap: %v", kind, name)
-		if !present {
-			return fmt.Errorf("internal error: %v snap %q not present", kind, name)
+		path := ""
+		if present {
+			path = info.MountFile()
+			if info.Sha3_384 != "" {
+				// the digest of the installed snap file is
+				// known, there is no need to compute it again
+				fi, err := os.Stat(path)
+				if err != nil {
+					return err
+				}
+				knownDigests[path] = &snapFileDigest{digest: info.Sha3_384, size: uint64(fi.Size())}
+			}
+		} else {
+			if opts.Downloader == nil {
+				missing.Snaps = append(missing.Snaps, name)
+				missing.kinds = append(missing.kinds, kind)
+				return nil
+			}
+			if downloadedNames[name] {
+				// we've already downloaded this snap
+				return nil
+			}
+			// the downloader may reuse a snap file which is already
+			// shared by other systems, such file is not owned by the
+			// new system
+			shared, err := filepath.Glob(filepath.Join(assertedSnapsDir, name+"_*.snap"))
+			if err != nil {
+				return err
+			}
+			logger.Noticef("downloading %v snap %q from channel %q", kind, name, channel)
+			path, info, err = opts.Downloader(name, channel)
+			if err != nil {
+				return fmt.Errorf("cannot download %v snap %q: %v", kind, name, err)
+			}
+			if !strutil.ListContains(shared, path) {
+				downloadedSnaps[path] = false
+			}
+			downloadedNames[name] = true
 		}
-		if _, ok := modelSnaps[info.MountFile()]; ok {
+		if _, ok := modelSnaps[path]; ok {
 			// we've already seen this snap
 			return nil
 		}
 		// present locally
 		// TODO: for grade dangerous we could have a channel here which is not
 		//       the model channel, handle that here
-		optsSnaps = append(optsSnaps, &seedwriter.OptionsSnap{
-			Path: info.MountFile(),
-		})
-		modelSnaps[info.MountFile()] = info
+		modelSnaps[path] = info
+		modelInfos = append(modelInfos, info)
+		modelPaths[info] = path
+		if opts.GetComponents != nil {
+			comps, err := opts.GetComponents(info, path)
+			if err != nil {
+				return fmt.Errorf("cannot obtain components of %v snap %q: %v", kind, name, err)
+			}
+			if len(comps) > 0 {
+				modelComponents[path] = comps
+			}
+		}
 		return nil
 	}
 
+	hasSnapd := false
 	for _, sn := range model.EssentialSnaps() {
 		const essential = true
-		if err := getModelSnap(sn.SnapName(), essential, ""); err != nil {
+		if err := getModelSnap(sn, essential); err != nil {
 			return "", err
 		}
+		if sn.SnapType == "snapd" {
+			hasSnapd = true
+		}
 	}
-	// snapd is implicitly needed
-	const snapdIsEssential = true
-	if err := getModelSnap("snapd", snapdIsEssential, ""); err != nil {
-		return "", err
+	if !hasSnapd {
+		// snapd is implicitly needed
+		const snapdIsEssential = true
+		if err := getModelSnap(implicitSnapdModelSnap(), snapdIsEssential); err != nil {
+			return "", err
+		}
 	}
 	for _, sn := range model.SnapsWithoutEssential() {
 		const essential = false
-		if err := getModelSnap(sn.SnapName(), essential, sn.Presence); err != nil {
+		if err := getModelSnap(sn, essential); err != nil {
 			return "", err
 		}
 	}
-	if err := w.SetOptionsSnaps(optsSnaps); err != nil {
+	if len(missing.Snaps) > 0 {
+		return "", missing
+	}
+	orderedInfos, err := seedwriter.OrderSnapsForSeeding(model, modelInfos)
+	if err != nil {
 		return "", err
 	}
-
-	newFetcher := func(save func(asserts.Assertion) error) asserts.Fetcher {
-		fromDB := func(ref *asserts.Ref) (asserts.Assertion, error) {
-			return ref.Resolve(db.Find)
+	optsSnaps := make([]*seedwriter.OptionsSnap, 0, len(orderedInfos)+len(opts.ExtraSnaps))
+	for _, info := range orderedInfos {
+		optsSnaps = append(optsSnaps, &seedwriter.OptionsSnap{
+			Path: modelPaths[info],
+		})
+	}
+	// extra snaps which are not part of the model
+	extraSnaps := make(map[string]bool, len(opts.ExtraSnaps))
+	for _, path := range opts.ExtraSnaps {
+		if _, ok := modelSnaps[path]; ok || extraSnaps[path] {
+			return "", fmt.Errorf("cannot use extra snap %q, it is already included in the system", path)
 		}
-		return asserts.NewFetcher(db, fromDB, save)
+		logger.Debugf("extra snap: %v", path)
+		optsSnaps = append(optsSnaps, &seedwriter.OptionsSnap{
+			Path: path,
+		})
+		extraSnaps[path] = true
 	}
-
-	sf := seedwriter.MakeSeedAssertionFetcher(newFetcher)
-	if err := w.Start(db, sf); err != nil {
+	if err := w.SetOptionsSnaps(optsSnaps); err != nil {
 		return "", err
 	}
-	// past this point the system directory is present
+
+	// asserted snaps unchanged from the reference system, their files in the
+	// seed are reused
+	var reusedSnaps map[string]*asserts.SnapRevision
+	if opts.ReferenceSystem != "" {
+		reusedSnaps, err = snapsFromReferenceSystem(db, opts.ReferenceSystem, assertedSnapsDir, modelSnaps)
+		if err != nil {
+			return "", fmt.Errorf("cannot use reference recovery system %q: %v", opts.ReferenceSystem, err)
+		}
+		logger.Debugf("reusing %d snaps from recovery system %q", len(reusedSnaps), opts.ReferenceSystem)
+	}
+
+	// verify the asserted snap files before anything gets written, so that
+	// a corrupted file does not end up in the seed
+	assertedPaths := make([]string, 0, len(modelSnaps))
+	for _, sn := range optsSnaps {
+		if _, ok := reusedSnaps[sn.Path]; ok {
+			// the file in the seed is used instead
+			continue
+		}
+		if info, ok := modelSnaps[sn.Path]; ok && info.SnapID != "" {
+			assertedPaths = append(assertedPaths, sn.Path)
+		}
+	}
+	verifiedDigests, err := checkSnapFilesAgainstAssertions(db, assertedPaths, modelSnaps, knownDigests)
+	if err != nil {
+		return "", err
+	}
+	if opts.ValidationSets != nil {
+		if err := checkSystemSnapsAgainstValidationSets(opts.ValidationSets, modelSnaps); err != nil {
+			return "", err
+		}
+	}
+	// the recovery system command line is derived from the gadget, make sure
+	// it can be used before anything gets written
+	for path, info := range modelSnaps {
+		if info.Type() != snap.TypeGadget {
+			continue
+		}
+		if _, _, err := gadget.RecoveryKernelCommandLineFromGadget(path); err != nil {
+			return "", fmt.Errorf("cannot use kernel command line from gadget %q: %v", info.SnapName(), err)
+		}
+	}
+
+	newFetcher := func(save func(asserts.Assertion) error) asserts.Fetcher {
+		fromDB := func(ref *asserts.Ref) (asserts.Assertion, error) {
+			return ref.Resolve(db.Find)
+		}
+		return asserts.NewFetcher(db, fromDB, save)
+	}
+
+	sf := seedwriter.MakeSeedAssertionFetcher(newFetcher)
+	var startErr error
+	timings.Run(tm, "fetch-model-assertions", "fetch the assertions of the model and its validation sets", func(timings.Measurer) {
+		startErr = w.Start(db, sf)
+	})
+	if err := startErr; err != nil {
+		if seedwriter.IsSytemDirectoryExistsError(err) {
+			// the system appeared in the meantime
+			return "", &ErrSystemExists{Label: label}
+		}
+		return "", err
+	}
+	// past this point the system directory is present
 
 	localSnaps, err := w.LocalSnaps()
 	if err != nil {
@@ -316,31 +1170,68 @@ func createSystemForModelFromValidatedSnaps(model *asserts.Model, label string,
 	}
 
 	localARefs := make(map[*seedwriter.SeedSnap][]*asserts.Ref)
+	fetchSpan := tm.StartSpan("fetch-snap-assertions", "fetch the assertions of the snaps of the system")
 	for _, sn := range localSnaps {
 		info, ok := modelSnaps[sn.Path]
-		if !ok {
+		if !ok && !extraSnaps[sn.Path] {
 			return recoverySystemDir, fmt.Errorf("internal error: no snap info for %q", sn.Path)
 		}
+		if snapRev, ok := reusedSnaps[sn.Path]; ok {
+			// the digest is known, no need to derive the assertions
+			// from the snap file
+			prev := len(sf.Refs())
+			if err := snapasserts.FetchSnapAssertions(sf, snapRev.SnapSHA3_384(), info.Provenance()); err != nil {
+				return recoverySystemDir, err
+			}
+			if err := w.SetInfo(sn, info); err != nil {
+				return recoverySystemDir, err
+			}
+			if comps := modelComponents[sn.Path]; len(comps) > 0 {
+				if err := w.SetComponents(sn, comps); err != nil {
+					return recoverySystemDir, err
+				}
+			}
+			localARefs[sn] = sf.Refs()[prev:]
+			continue
+		}
 		// TODO: the side info derived here can be different from what
-		// we have in snap.Info, but getting it this way can be
-		// expensive as we need to compute the hash, try to find a
-		// better way
-		_, aRefs, err := seedwriter.DeriveSideInfo(sn.Path, model, sf, db)
+		// we have in snap.Info
+		var si *snap.SideInfo
+		var aRefs []*asserts.Ref
+		if d, ok := verifiedDigests[sn.Path]; ok {
+			// the file was verified already, reuse its digest
+			si, aRefs, err = seedwriter.DeriveSideInfoFromDigestAndSize(sn.Path, d.digest, d.size, model, sf, db)
+		} else {
+			si, aRefs, err = seedwriter.DeriveSideInfo(sn.Path, model, sf, db)
+		}
 		if err != nil {
 			if !errors.Is(err, &asserts.NotFoundError{}) {
 				return recoverySystemDir, err
-			} else if info.SnapID != "" {
+			} else if info != nil && info.SnapID != "" {
 				// snap info from state must have come
 				// from the store, so it is unexpected
 				// if no assertions for it were found
 				return recoverySystemDir, fmt.Errorf("internal error: no assertions for asserted snap with ID: %v", info.SnapID)
 			}
 		}
+		if info == nil {
+			// extra snap, the information comes from the file
+			info, err = readExtraSnapInfo(sn.Path, si)
+			if err != nil {
+				return recoverySystemDir, err
+			}
+		}
 		if err := w.SetInfo(sn, info); err != nil {
 			return recoverySystemDir, err
 		}
+		if comps := modelComponents[sn.Path]; len(comps) > 0 {
+			if err := w.SetComponents(sn, comps); err != nil {
+				return recoverySystemDir, err
+			}
+		}
 		localARefs[sn] = aRefs
 	}
+	fetchSpan.Stop()
 
 	if err := w.InfoDerived(); err != nil {
 		return recoverySystemDir, err
@@ -392,7 +1283,17 @@ func createSystemForModelFromValidatedSnaps(model *asserts.Model, label string,
 		logger.Noticef("system %q contains unasserted snaps %s", label, strutil.Quoted(locals))
 	}
 
-	copySnap := func(name, src, dst string) error {
+	var copyJobs []snapCopyJob
+	queueSnapCopy := func(name, src, dst string) error {
+		if _, ok := downloadedSnaps[src]; ok && src == dst {
+			// the snap was downloaded to its place in the seed
+			// already, there is nothing to copy
+			downloadedSnaps[src] = true
+			if observeWrite != nil {
+				return observeWrite(recoverySystemDir, dst)
+			}
+			return nil
+		}
 		// if the destination snap is in the asserted snaps dir and already
 		// exists, we don't need to copy it since asserted snaps are shared
 		if strings.HasPrefix(dst, assertedSnapsDir+"/") && osutil.FileExists(dst) {
@@ -401,20 +1302,81 @@ func createSystemForModelFromValidatedSnaps(model *asserts.Model, label string,
 		// otherwise, unasserted snaps are not shared, so even if the
 		// destination already exists if it is not in the asserted snaps we
 		// should copy it
-		logger.Noticef("copying new seed snap %q from %v to %v", name, src, dst)
+
+		// all files are observed before any copy starts, such that files
+		// left behind by copies which failed or were not completed are
+		// known to the caller
 		if observeWrite != nil {
 			if err := observeWrite(recoverySystemDir, dst); err != nil {
 				return err
 			}
 		}
-		return osutil.CopyFile(src, dst, 0)
+		job := snapCopyJob{name: name, src: src, dst: dst}
+		if info, ok := modelSnaps[src]; ok && info.SnapID != "" && opts.VerifyCopies {
+			// the source file was already verified against the
+			// assertion, which is looked up now as the database
+			// cannot be used while copying
+			snapRev, err := findSnapRevision(db, info)
+			if err != nil {
+				return fmt.Errorf("cannot verify snap %q: %v", name, err)
+			}
+			job.digest = snapRev.SnapSHA3_384()
+		}
+		copyJobs = append(copyJobs, job)
+		return nil
+	}
+	copySnap := func(job snapCopyJob) error {
+		logger.Noticef("copying new seed snap %q from %v to %v", job.name, job.src, job.dst)
+		if !opts.VerifyCopies {
+			return linkOrCopySnapFile(ctx, job.name, job.src, job.dst, opts.Progress)
+		}
+		digest := job.digest
+		if digest == "" {
+			var err error
+			digest, _, err = asserts.SnapFileSHA3_384(job.src)
+			if err != nil {
+				return fmt.Errorf("cannot compute digest of snap %q: %v", job.name, err)
+			}
+		}
+		return linkOrCopySnapFileVerified(ctx, job.name, job.src, job.dst, digest, opts.Progress)
 	}
-	if err := w.SeedSnaps(copySnap); err != nil {
+	if err := w.SeedSnaps(queueSnapCopy); err != nil {
+		return recoverySystemDir, err
+	}
+	workers := opts.CopyWorkers
+	if workers == 0 {
+		workers = defaultSnapCopyWorkers
+	}
+	if opts.Unlocker != nil {
+		relock := opts.Unlocker()
+		err = copySnapFiles(copyJobs, workers, copySnap)
+		relock()
+	} else {
+		err = copySnapFiles(copyJobs, workers, copySnap)
+	}
+	if err != nil {
 		return recoverySystemDir, err
 	}
 	if err := w.WriteMeta(); err != nil {
 		return recoverySystemDir, err
 	}
+	creationInfo := &RecoverySystemCreationInfo{
+		SnapdVersion:       snapdtool.Version,
+		Created:            timeNow(),
+		ChangeKind:         opts.ChangeKind,
+		ChangeID:           opts.ChangeID,
+		HasUnassertedSnaps: len(unassertedSnaps) > 0,
+	}
+	if err := writeRecoverySystemCreationInfo(recoverySystemDir, creationInfo); err != nil {
+		return recoverySystemDir, fmt.Errorf("cannot write recovery system creation information: %v", err)
+	}
+
+	if model.Classic() && model.KernelSnap() == nil {
+		// without a kernel the system is only a seed of the snaps of
+		// the model and there is nothing to boot into
+		logger.Noticef("created recovery system %q, not bootable as the classic model has no kernel", label)
+		return recoverySystemDir, nil
+	}
 
 	bootSnaps, err := w.BootSnaps()
 	if err != nil {
@@ -433,7 +1395,1060 @@ func createSystemForModelFromValidatedSnaps(model *asserts.Model, label string,
 	if err := boot.MakeRecoverySystemBootable(model, boot.InitramfsUbuntuSeedDir, recoverySystemDirInRootDir, bootWith); err != nil {
 		return recoverySystemDir, fmt.Errorf("cannot make candidate recovery system %q bootable: %v", label, err)
 	}
+	// catch problems with the system before rebooting into it
+	if err := boot.CheckRecoverySystemBootable(boot.InitramfsUbuntuSeedDir, recoverySystemDirInRootDir, bootWith); err != nil {
+		return recoverySystemDir, err
+	}
+	dev := &groundDeviceContext{model: model, systemMode: "run"}
+	// the trusted assets of the system become part of the recovery boot
+	// chains when the system is tried
+	if err := boot.InstallRecoverySystemTrustedAssets(dev, bootWith.GadgetSnapOrDir, label); err != nil {
+		return recoverySystemDir, fmt.Errorf("cannot install trusted assets of candidate recovery system %q: %v", label, err)
+	}
 	logger.Noticef("created recovery system %q", label)
+	// the command line is only informative, inability to compute it
+	// does not make the system any less usable
+	if cmdline, err := boot.RecoverySystemCommandLine(model, label, bootWith.GadgetSnapOrDir); err != nil {
+		logger.Noticef("cannot compute kernel command line of recovery system %q: %v", label, err)
+	} else if cmdline != "" {
+		logger.Noticef("recovery system %q kernel command line: %q", label, cmdline)
+	}
 
 	return recoverySystemDir, nil
 }
+
+// CreateRecoverySystemForModel creates a new recovery system with the given
+// label for the provided model, which does not need to be the model of the
+// device, e.g. a brand may stage a recovery system for a different revision of
+// the model. The model and the assertions of the snaps are expected to have
+// been validated and be present in the provided database. Snap information is
+// obtained through getInfo. Asserted snaps are written to the snaps directory
+// shared between recovery systems, while unasserted ones are placed under the
+// snaps directory of the new system.
+//
+// The base, kernel and gadget snaps of the model must be available through
+// getInfo, and unasserted snaps can only be used with a model of dangerous
+// grade. This is verified before anything is written.
+//
+// Snap files listed in extraSnaps, which are not part of the model, are added
+// to the new system as extra snaps. This is only supported for models of
+// dangerous grade.
+//
+// The function returns the directory of the new recovery system, which is set
+// even on error if the directory may have been created.
+func CreateRecoverySystemForModel(model *asserts.Model, label string, db asserts.RODatabase, getInfo func(name string) (info *snap.Info, present bool, err error), observeWrite func(systemDir, where string) error, extraSnaps []string) (dir string, err error) {
+	if model.Grade() == asserts.ModelGradeUnset {
+		return "", fmt.Errorf("cannot create a system for pre-UC20 model")
+	}
+	getModelSnapInfo := snapInfoGetterByName(getInfo)
+	if err := checkModelSnapsAvailable(model, getModelSnapInfo); err != nil {
+		return "", fmt.Errorf("cannot create recovery system %q for model %q: %v", label, model.Model(), err)
+	}
+	opts := &createSystemOptions{
+		ExtraSnaps: extraSnaps,
+	}
+	return createSystemForModelFromValidatedSnaps(model, label, db, getModelSnapInfo, observeWrite, opts)
+}
+
+// currentSnapInfo returns the information of the currently installed revision
+// of the snap with the given name, together with the digest of its snap file.
+func currentSnapInfo(st *state.State, name string) (info *snap.Info, present bool, err error) {
+	info, err = snapstate.CurrentInfo(st, name)
+	if err == nil {
+		hash, _, err := asserts.SnapFileSHA3_384(info.MountFile())
+		if err != nil {
+			return nil, true, fmt.Errorf("cannot compute SHA3 of snap file: %v", err)
+		}
+		info.Sha3_384 = hash
+		return info, true, nil
+	}
+	if _, ok := err.(*snap.NotInstalledError); !ok {
+		return nil, false, err
+	}
+	return nil, false, nil
+}
+
+// seededSnapComponents returns a getSnapComponentsFunc which provides the
+// components the snaps were seeded with from the recovery system with the
+// given label. Components cannot be installed in the run system other than by
+// seeding, as such these are the components of the installed snaps, as long
+// as the seeded revision of the snap is still the current one. The seed is
+// only loaded when a snap declaring components is added to the system.
+func seededSnapComponents(seedDir, label string) getSnapComponentsFunc {
+	var seedSnaps map[string]*seed.Snap
+	return func(info *snap.Info, snapPath string) ([]*seedwriter.SeedComponent, error) {
+		if len(info.Components) == 0 || label == "" {
+			return nil, nil
+		}
+		if seedSnaps == nil {
+			seedSnaps = make(map[string]*seed.Snap)
+			if !osutil.IsDirectory(filepath.Join(seedDir, "systems", label)) {
+				// the system the device was seeded from is gone
+				return nil, nil
+			}
+			sd, err := loadRecoverySystemSeed(seedDir, label, metadataOnlySnapHandler{})
+			if err != nil {
+				return nil, fmt.Errorf("cannot load recovery system %q: %v", label, err)
+			}
+			sd.Iter(func(sn *seed.Snap) error {
+				seedSnaps[sn.SnapName()] = sn
+				return nil
+			})
+		}
+		sn := seedSnaps[info.SnapName()]
+		if sn == nil {
+			return nil, nil
+		}
+		seededRev := sn.SideInfo.Revision
+		if seededRev.Unset() {
+			// unasserted snaps are installed with the first
+			// local revision when seeding
+			seededRev = snap.R(-1)
+		}
+		if seededRev != info.Revision {
+			return nil, nil
+		}
+		comps := make([]*seedwriter.SeedComponent, 0, len(sn.Components))
+		for _, comp := range sn.Components {
+			comps = append(comps, &seedwriter.SeedComponent{
+				Name:     comp.Name,
+				Revision: comp.Revision,
+				Path:     comp.Path,
+			})
+		}
+		return comps, nil
+	}
+}
+
+// CreateRecoverySystemFromRunSystem creates a new recovery system with the
+// given label for the model of the device, from the revisions of the snaps
+// currently installed in the run system. The assertions of the snaps are
+// obtained from the assertions database of the device only, the store is
+// never contacted, so on air-gapped devices they can be imported beforehand
+// with assertstate.ImportBundle. The snaps must satisfy the validation sets
+// enforced on the device. As with
+// CreateRecoverySystemForModel, the essential snaps of the model must be
+// installed, and unasserted snaps can only be used with a model of dangerous
+// grade.
+//
+// The system is created directly, it is neither tried nor added to the
+// recovery systems in the modeenv. Files written for the system are removed
+// when creating it fails. The directory of the new system is returned.
+//
+// The caller must hold the state lock, which is released while the snap files
+// are copied.
+func CreateRecoverySystemFromRunSystem(st *state.State, label string) (dir string, err error) {
+	deviceCtx, err := DeviceCtx(st, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if !deviceCtx.HasModeenv() {
+		if release.OnClassic {
+			return "", fmt.Errorf("cannot create recovery systems on a classic system without modes")
+		}
+		return "", fmt.Errorf("cannot create recovery systems on a pre-UC20 system")
+	}
+	var seeded bool
+	if err := st.Get("seeded", &seeded); err != nil && !errors.Is(err, state.ErrNoState) {
+		return "", err
+	}
+	if !seeded {
+		return "", fmt.Errorf("cannot create new recovery systems until fully seeded")
+	}
+
+	model := deviceCtx.Model()
+	// the snaps are inspected both when checking the model and when
+	// creating the system, compute the digests of the snap files once
+	getInfo := cachedSnapInfoGetter(func(modelSnap *asserts.ModelSnap) (*snap.Info, bool, error) {
+		return currentSnapInfo(st, modelSnap.SnapName())
+	})
+	if err := checkModelSnapsAvailable(model, getInfo); err != nil {
+		return "", fmt.Errorf("cannot create recovery system %q from the run system: %v", label, err)
+	}
+	vsets, err := assertstate.TrackedEnforcedValidationSets(st)
+	if err != nil {
+		return "", fmt.Errorf("cannot obtain enforced validation sets: %v", err)
+	}
+
+	var newFiles []string
+	observeWrite := func(systemDir, where string) error {
+		newFiles = append(newFiles, where)
+		return nil
+	}
+	modeenv, err := boot.ReadModeenv("")
+	if err != nil {
+		return "", err
+	}
+	opts := &createSystemOptions{
+		Unlocker:       st.Unlocker(),
+		VerifyCopies:   true,
+		ValidationSets: vsets,
+		GetComponents:  seededSnapComponents(boot.InitramfsUbuntuSeedDir, modeenv.RecoverySystem),
+	}
+	dir, err = createSystemForModelFromValidatedSnaps(model, label, assertstate.DB(st), getInfo, observeWrite, opts)
+	if err != nil {
+		for _, fn := range newFiles {
+			if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+				logger.Noticef("when removing seed file %q: %v", fn, err)
+			}
+		}
+		if dir != "" {
+			if err := os.RemoveAll(dir); err != nil {
+				logger.Noticef("when removing recovery system %q: %v", label, err)
+			}
+		}
+		return "", fmt.Errorf("cannot create recovery system %q from the run system: %v", label, err)
+	}
+	return dir, nil
+}
+
+// checkModelSnapsAvailable verifies that the snaps essential for booting the
+// model are available and of the right type, and that the snaps which are
+// present are allowed by the model grade.
+func checkModelSnapsAvailable(model *asserts.Model, getInfo getSnapInfoFunc) error {
+	essentialTypes := []struct {
+		modelSnap *asserts.ModelSnap
+		typ       snap.Type
+	}{
+		{model.BaseSnap(), snap.TypeBase},
+		{model.KernelSnap(), snap.TypeKernel},
+		{model.GadgetSnap(), snap.TypeGadget},
+	}
+	for _, ess := range essentialTypes {
+		if ess.modelSnap == nil && model.Classic() {
+			// kernel and gadget are optional on classic
+			continue
+		}
+		name := ess.modelSnap.SnapName()
+		info, present, err := getInfo(ess.modelSnap)
+		if err != nil {
+			return fmt.Errorf("cannot obtain %s snap %q information: %v", ess.typ, name, err)
+		}
+		if !present {
+			return fmt.Errorf("%s snap %q is not available", ess.typ, name)
+		}
+		if info.Type() != ess.typ {
+			return fmt.Errorf("snap %q has type %q, expected %q", name, info.Type(), ess.typ)
+		}
+	}
+	if model.Grade() == asserts.ModelDangerous {
+		return nil
+	}
+	for _, snaps := range [][]*asserts.ModelSnap{model.EssentialSnaps(), model.SnapsWithoutEssential()} {
+		for _, sn := range snaps {
+			info, present, err := getInfo(sn)
+			if err != nil {
+				return fmt.Errorf("cannot obtain snap %q information: %v", sn.SnapName(), err)
+			}
+			if present && info.SnapID == "" {
+				return fmt.Errorf("cannot use unasserted snap %q with a model of grade %q", sn.SnapName(), model.Grade())
+			}
+		}
+	}
+	return nil
+}
+
+// metadataOnlySnapHandler is a seed.SnapHandler which does not compute the
+// digests of the asserted snaps, it takes the ones carried by their
+// snap-revision assertions instead. It is used when only the metadata of a
+// seed is of interest, as hashing every snap of a recovery system is
+// expensive.
+type metadataOnlySnapHandler struct{}
+
+func (metadataOnlySnapHandler) HandleAndDigestAssertedSnap(name, path string, essType snap.Type, snapRev *asserts.SnapRevision, _ func(string, uint64) (snap.Revision, error), _ timings.Measurer) (string, string, uint64, error) {
+	if snapRev == nil {
+		return "", "", 0, fmt.Errorf("internal error: snap-revision of snap %q is required", name)
+	}
+	return path, snapRev.SnapSHA3_384(), snapRev.SnapSize(), nil
+}
+
+func (metadataOnlySnapHandler) HandleUnassertedSnap(name, path string, _ timings.Measurer) (string, error) {
+	return path, nil
+}
+
+// loadRecoverySystemSeed opens the recovery system with the given label in the
+// seed directory and loads its assertions and metadata. The digests of the
+// asserted snaps are verified, unless a handler such as
+// metadataOnlySnapHandler is passed.
+func loadRecoverySystemSeed(seedDir, label string, handler seed.SnapHandler) (seed.Seed, error) {
+	sd, err := seedOpen(seedDir, label)
+	if err != nil {
+		return nil, err
+	}
+	if err := sd.LoadAssertions(nil, nil); err != nil {
+		return nil, err
+	}
+	if err := sd.LoadMeta(seed.AllModes, handler, timings.New(nil)); err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+// snapsReferencedBySystem returns the set of snap files referenced by the
+// recovery system with the given label in the seed directory.
+func snapsReferencedBySystem(seedDir, label string) (map[string]bool, error) {
+	sd, err := loadRecoverySystemSeed(seedDir, label, metadataOnlySnapHandler{})
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool, sd.NumSnaps())
+	err = sd.Iter(func(sn *seed.Snap) error {
+		referenced[sn.Path] = true
+		for _, comp := range sn.Components {
+			referenced[comp.Path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return referenced, nil
+}
+
+// checkRecoverySystemRemovable checks that the recovery system with the given
+// label is not being created, tried or finalized by a change in progress.
+func checkRecoverySystemRemovable(st *state.State, modeenv *boot.Modeenv, label string) error {
+	chg, err := recoverySystemInProgressChange(st, label)
+	if err != nil {
+		return err
+	}
+	if chg != nil {
+		return &snapstate.ChangeConflictError{
+			Message:    fmt.Sprintf("cannot remove recovery system %q: system is being created", label),
+			ChangeKind: chg.Kind(),
+			ChangeID:   chg.ID(),
+		}
+	}
+
+	var triedSystems []string
+	if err := st.Get("tried-systems", &triedSystems); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	if strutil.ListContains(triedSystems, label) {
+		return fmt.Errorf("cannot remove recovery system %q: system is being tried", label)
+	}
+	// systems are current, but not good while being tried, until they are
+	// finalized
+	if strutil.ListContains(modeenv.CurrentRecoverySystems, label) && !strutil.ListContains(modeenv.GoodRecoverySystems, label) {
+		return fmt.Errorf("cannot remove recovery system %q: system has not been finalized yet", label)
+	}
+	return nil
+}
+
+// recoverySystemInProgressChange returns the change which is still creating or
+// finalizing the recovery system with the given label, if there is one.
+func recoverySystemInProgressChange(st *state.State, label string) (*state.Change, error) {
+	for _, chg := range st.Changes() {
+		if chg.IsReady() {
+			continue
+		}
+		for _, t := range chg.Tasks() {
+			if t.Kind() != "create-recovery-system" && t.Kind() != "finalize-recovery-system" {
+				continue
+			}
+			setup, err := taskRecoverySystemSetup(t)
+			if err != nil {
+				if errors.Is(err, state.ErrNoState) {
+					continue
+				}
+				return nil, err
+			}
+			if setup.Label == label {
+				return chg, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+var bootUpdateRecoverySystemsMenu = boot.UpdateRecoverySystemsMenu
+
+// updateRecoverySystemsMenu updates the menu of recovery systems of the
+// recovery bootloader after the list of good recovery systems has changed. The
+// menu only helps picking a system to boot, as such failing to update it is
+// not fatal.
+func updateRecoverySystemsMenu(st *state.State, deviceCtx snapstate.DeviceContext) {
+	gadgetInfo, err := snapstate.GadgetInfo(st, deviceCtx)
+	if err != nil {
+		logger.Noticef("cannot update the menu of recovery systems: %v", err)
+		return
+	}
+	if err := bootUpdateRecoverySystemsMenu(deviceCtx, gadgetInfo.MountFile()); err != nil {
+		logger.Noticef("cannot update the menu of recovery systems: %v", err)
+	}
+}
+
+// snapsReferencedByOtherSystems returns the set of snap files referenced by
+// the recovery systems in the given directory, other than the one with the
+// given label. The state lock is released while the systems are loaded.
+func snapsReferencedByOtherSystems(st *state.State, systemsDir, label string) (map[string]bool, error) {
+	st.Unlock()
+	defer st.Lock()
+
+	entries, err := ioutil.ReadDir(systemsDir)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool)
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == label {
+			continue
+		}
+		snaps, err := snapsReferencedBySystem(boot.InitramfsUbuntuSeedDir, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("cannot load recovery system %q: %v", entry.Name(), err)
+		}
+		for sn := range snaps {
+			referenced[sn] = true
+		}
+	}
+	return referenced, nil
+}
+
+// RemoveRecoverySystem removes the recovery system with the given label from
+// ubuntu-seed, together with any snaps in the shared snaps directory which are
+// no longer referenced by the remaining recovery systems. The system is also
+// dropped from the list of current and good recovery systems in the modeenv.
+// Removing the system the device was seeded from, the default recovery system
+// or the last remaining good recovery system, is refused. Systems which are
+// being created or tried, or which were not yet finalized, cannot be removed
+// either.
+//
+// The caller must hold the state lock, it is released while the remaining
+// systems are loaded.
+func RemoveRecoverySystem(st *state.State, label string) error {
+	deviceCtx, err := DeviceCtx(st, nil, nil)
+	if err != nil {
+		return err
+	}
+	if !deviceCtx.HasModeenv() {
+		return fmt.Errorf("cannot remove recovery systems on a pre-UC20 system")
+	}
+	modeenv, err := boot.ReadModeenv("")
+	if err != nil {
+		return err
+	}
+
+	systemsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems")
+	systemDir := filepath.Join(systemsDir, label)
+	if !osutil.IsDirectory(systemDir) {
+		return fmt.Errorf("cannot remove recovery system %q: system does not exist", label)
+	}
+	if modeenv.RecoverySystem == label {
+		return fmt.Errorf("cannot remove recovery system %q: system was used to seed the device", label)
+	}
+	if strutil.ListContains(modeenv.GoodRecoverySystems, label) && len(modeenv.GoodRecoverySystems) == 1 {
+		return fmt.Errorf("cannot remove recovery system %q: system is the last good recovery system", label)
+	}
+	if err := checkRecoverySystemRemovable(st, modeenv, label); err != nil {
+		return err
+	}
+	defaultLabel, err := boot.DefaultRecoverySystem(deviceCtx)
+	if err != nil {
+		return fmt.Errorf("cannot obtain the default recovery system: %v", err)
+	}
+	if defaultLabel == label {
+		return fmt.Errorf("cannot remove recovery system %q: system is the default recovery system", label)
+	}
+
+	// collect all snaps used by the systems which remain, this must be done
+	// before anything is removed, such that a system that cannot be loaded
+	// does not leave us with snaps removed from under it
+	referenced, err := snapsReferencedByOtherSystems(st, systemsDir, label)
+	if err != nil {
+		return err
+	}
+	// the state lock was released, check again that the system was not
+	// picked up by a change in the meantime
+	modeenv, err = boot.ReadModeenv("")
+	if err != nil {
+		return err
+	}
+	if err := checkRecoverySystemRemovable(st, modeenv, label); err != nil {
+		return err
+	}
+
+	if err := boot.DropRecoverySystem(deviceCtx, label); err != nil {
+		return fmt.Errorf("cannot drop recovery system %q: %v", label, err)
+	}
+	if err := os.RemoveAll(systemDir); err != nil {
+		return fmt.Errorf("cannot remove recovery system %q: %v", label, err)
+	}
+	logger.Noticef("removed recovery system %q", label)
+	updateRecoverySystemsMenu(st, deviceCtx)
+
+	assertedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
+	assertedSnaps, err := filepath.Glob(filepath.Join(assertedSnapsDir, "*.snap"))
+	if err != nil {
+		return err
+	}
+	for _, sn := range assertedSnaps {
+		if referenced[sn] {
+			continue
+		}
+		logger.Noticef("removing seed snap %q no longer used by any recovery system", sn)
+		if err := os.Remove(sn); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot remove unused seed snap: %v", err)
+		}
+	}
+	return nil
+}
+
+// PruneRecoverySystems removes the recovery systems from ubuntu-seed, other
+// than the given number of most recent good recovery systems, the default
+// recovery system and the original system the device was seeded from, which
+// is never removed. Snaps in the
+// shared snaps directory which are no longer referenced by the remaining
+// systems are removed as well. Systems which are being created or tried, or
+// which were not yet finalized, are left alone. The labels of the removed
+// systems are returned.
+//
+// The caller must hold the state lock, it is released while the systems are
+// loaded.
+func PruneRecoverySystems(st *state.State, keep int) (removed []string, err error) {
+	if keep < 1 {
+		return nil, fmt.Errorf("cannot prune recovery systems: at least one good recovery system must be kept")
+	}
+	deviceCtx, err := DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !deviceCtx.HasModeenv() {
+		return nil, fmt.Errorf("cannot prune recovery systems on a pre-UC20 system")
+	}
+	modeenv, err := boot.ReadModeenv("")
+	if err != nil {
+		return nil, err
+	}
+
+	keepLabels := make(map[string]bool)
+	// good systems are appended to the list, so the most recent ones come
+	// last
+	good := modeenv.GoodRecoverySystems
+	if len(good) > keep {
+		good = good[len(good)-keep:]
+	}
+	for _, label := range good {
+		keepLabels[label] = true
+	}
+	var seeded []seededSystem
+	if err := st.Get("seeded-systems", &seeded); err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, err
+	}
+	if len(seeded) > 0 {
+		// seeded systems are prepended to the list, so the original
+		// one comes last
+		keepLabels[seeded[len(seeded)-1].System] = true
+	}
+	if modeenv.RecoverySystem != "" {
+		keepLabels[modeenv.RecoverySystem] = true
+	}
+	defaultLabel, err := boot.DefaultRecoverySystem(deviceCtx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot prune recovery systems: %v", err)
+	}
+	if defaultLabel != "" {
+		keepLabels[defaultLabel] = true
+	}
+
+	systemDirs, err := filepath.Glob(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", "*"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot prune recovery systems: %v", err)
+	}
+	for _, systemDir := range systemDirs {
+		label := filepath.Base(systemDir)
+		if keepLabels[label] || !osutil.IsDirectory(systemDir) {
+			continue
+		}
+		if err := checkRecoverySystemRemovable(st, modeenv, label); err != nil {
+			logger.Noticef("not pruning recovery system %q: %v", label, err)
+			continue
+		}
+		if err := RemoveRecoverySystem(st, label); err != nil {
+			return removed, err
+		}
+		removed = append(removed, label)
+		// the modeenv was updated by removing the system
+		modeenv, err = boot.ReadModeenv("")
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// TriedRecoverySystemMismatchError is returned by PromoteTriedRecoverySystem
+// when the recovery system recorded as tried is not the expected one.
+type TriedRecoverySystemMismatchError struct {
+	// Label of the system which was expected to be tried.
+	Label string
+	// TriedLabel is the label of the system recorded as tried, can be
+	// empty when no system was tried.
+	TriedLabel string
+}
+
+func (e *TriedRecoverySystemMismatchError) Error() string {
+	if e.TriedLabel == "" {
+		return fmt.Sprintf("cannot promote recovery system %q: no recovery system was tried", e.Label)
+	}
+	return fmt.Sprintf("cannot promote recovery system %q: tried recovery system is %q", e.Label, e.TriedLabel)
+}
+
+// PromoteTriedRecoverySystem promotes the recovery system with the given label
+// to the list of good recovery systems in the modeenv, once the system has
+// been successfully tried. The outcome of trying the system is obtained from
+// the boot environment, or from the list of tried systems in the state if the
+// outcome was already observed after rebooting. The try boot variables are
+// cleared together with promoting the system, and the system is dropped from
+// the list of tried systems. A *TriedRecoverySystemMismatchError is returned
+// when the tried system is not the one with the given label, in which case
+// neither the boot environment nor the state are modified. Systems which are
+// created as part of a change are promoted by that change and cannot be
+// promoted with this call.
+//
+// The caller must hold the state lock.
+func PromoteTriedRecoverySystem(st *state.State, label string) error {
+	deviceCtx, err := DeviceCtx(st, nil, nil)
+	if err != nil {
+		return err
+	}
+	if !deviceCtx.HasModeenv() {
+		return fmt.Errorf("cannot promote recovery systems on a pre-UC20 system")
+	}
+
+	chg, err := recoverySystemInProgressChange(st, label)
+	if err != nil {
+		return err
+	}
+	if chg != nil {
+		return &snapstate.ChangeConflictError{
+			Message:    fmt.Sprintf("cannot promote recovery system %q: system is being created", label),
+			ChangeKind: chg.Kind(),
+			ChangeID:   chg.ID(),
+		}
+	}
+
+	var triedSystems []string
+	if err := st.Get("tried-systems", &triedSystems); err != nil && !errors.Is(err, state.ErrNoState) {
+		return fmt.Errorf("cannot obtain tried recovery systems: %v", err)
+	}
+
+	outcome, triedLabel, err := boot.InspectTryRecoverySystemOutcome(deviceCtx)
+	if err != nil {
+		return fmt.Errorf("cannot promote recovery system %q: %v", label, err)
+	}
+	switch outcome {
+	case boot.TryRecoverySystemOutcomeNoneTried:
+		// the outcome has already been observed after rebooting from
+		// the tried system, in which case the system is listed as tried
+		if !strutil.ListContains(triedSystems, label) {
+			mismatch := &TriedRecoverySystemMismatchError{Label: label}
+			if len(triedSystems) != 0 {
+				mismatch.TriedLabel = triedSystems[len(triedSystems)-1]
+			}
+			return mismatch
+		}
+	case boot.TryRecoverySystemOutcomeSuccess:
+		if triedLabel != label {
+			return &TriedRecoverySystemMismatchError{
+				Label:      label,
+				TriedLabel: triedLabel,
+			}
+		}
+		if !strutil.ListContains(triedSystems, label) {
+			// record the outcome first, so that the system can
+			// still be promoted should any of the later steps fail
+			triedSystems = append(triedSystems, label)
+			st.Set("tried-systems", triedSystems)
+		}
+	default:
+		if triedLabel != label {
+			return &TriedRecoverySystemMismatchError{
+				Label:      label,
+				TriedLabel: triedLabel,
+			}
+		}
+		return fmt.Errorf("cannot promote recovery system %q: system has not been successfully tried", label)
+	}
+
+	if err := boot.PromoteAndClearTriedRecoverySystem(deviceCtx, label, triedSystems); err != nil {
+		return fmt.Errorf("cannot promote recovery system %q: %v", label, err)
+	}
+
+	var remaining []string
+	for _, tried := range triedSystems {
+		if tried != label {
+			remaining = append(remaining, tried)
+		}
+	}
+	if len(remaining) == 0 {
+		st.Set("tried-systems", nil)
+	} else {
+		st.Set("tried-systems", remaining)
+	}
+	logger.Noticef("promoted tried recovery system %q", label)
+	updateRecoverySystemsMenu(st, deviceCtx)
+	return nil
+}
+
+// FactoryResetRequest carries the details of a factory reset which was
+// prepared, but has not happened yet.
+type FactoryResetRequest struct {
+	// Label of the recovery system the device will be reset from.
+	Label string `json:"label"`
+	// Time when the factory reset was prepared.
+	Time time.Time `json:"time"`
+	// BootID of the boot during which the factory reset was prepared.
+	BootID string `json:"boot-id"`
+}
+
+var osutilBootID = osutil.BootID
+
+// PrepareFactoryReset prepares the device to be factory reset from the
+// recovery system with the given label, or from the most recent good recovery
+// system if the label is empty. The system must be a good recovery system for
+// the model of the device, its seed is loaded and the files of its asserted
+// snaps are verified against their assertions. The recovery bootloader is
+// then set up to boot into the system in factory-reset mode, and the pending
+// factory reset is recorded in the state. The reset happens on the next
+// reboot, which is left to the caller.
+//
+// The caller must hold the state lock.
+func PrepareFactoryReset(st *state.State, label string) error {
+	deviceCtx, err := DeviceCtx(st, nil, nil)
+	if err != nil {
+		return err
+	}
+	if !deviceCtx.HasModeenv() {
+		return fmt.Errorf("cannot factory reset a pre-UC20 system")
+	}
+	modeenv, err := boot.ReadModeenv("")
+	if err != nil {
+		return err
+	}
+	if modeenv.Mode != boot.ModeRun {
+		return fmt.Errorf("cannot prepare factory reset in %q mode", modeenv.Mode)
+	}
+
+	if label == "" {
+		if len(modeenv.GoodRecoverySystems) == 0 {
+			return fmt.Errorf("cannot prepare factory reset: no good recovery system")
+		}
+		label = modeenv.GoodRecoverySystems[len(modeenv.GoodRecoverySystems)-1]
+	} else if !strutil.ListContains(modeenv.GoodRecoverySystems, label) {
+		return fmt.Errorf("cannot prepare factory reset from recovery system %q: system is not a good recovery system", label)
+	}
+
+	// loading the seed verifies the digests of the asserted snaps
+	sd, err := loadRecoverySystemSeed(dirs.SnapSeedDir, label, nil)
+	if err != nil {
+		return fmt.Errorf("cannot prepare factory reset from recovery system %q: %v", label, err)
+	}
+	model := deviceCtx.Model()
+	if sd.Model().BrandID() != model.BrandID() || sd.Model().Model() != model.Model() {
+		return fmt.Errorf("cannot prepare factory reset from recovery system %q: system is for model %s/%s, not %s/%s",
+			label, sd.Model().BrandID(), sd.Model().Model(), model.BrandID(), model.Model())
+	}
+
+	bootID, err := osutilBootID()
+	if err != nil {
+		return err
+	}
+	if err := boot.SetRecoveryBootSystemAndMode(deviceCtx, label, "factory-reset"); err != nil {
+		return fmt.Errorf("cannot set device to boot into system %q in factory-reset mode: %v", label, err)
+	}
+	st.Set("pending-factory-reset", &FactoryResetRequest{
+		Label:  label,
+		Time:   timeNow(),
+		BootID: bootID,
+	})
+	logger.Noticef("prepared factory reset from recovery system %q", label)
+	return nil
+}
+
+// PendingFactoryReset returns the details of the factory reset which was
+// prepared with PrepareFactoryReset, or nil if there is none.
+//
+// The caller must hold the state lock.
+func PendingFactoryReset(st *state.State) (*FactoryResetRequest, error) {
+	var req FactoryResetRequest
+	if err := st.Get("pending-factory-reset", &req); err != nil {
+		if errors.Is(err, state.ErrNoState) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+// clearFactoryResetAfterReboot drops the record of the pending factory reset
+// once the device has rebooted since the reset was prepared. A device which
+// was reset starts over with a new state, as such, when the record is still
+// present, the reset did not happen.
+//
+// The caller must hold the state lock.
+func clearFactoryResetAfterReboot(st *state.State) error {
+	req, err := PendingFactoryReset(st)
+	if err != nil || req == nil {
+		return err
+	}
+	bootID, err := osutilBootID()
+	if err != nil {
+		return err
+	}
+	if req.BootID == bootID {
+		// the reboot into the recovery system has not happened yet
+		return nil
+	}
+	logger.Noticef("factory reset from recovery system %q prepared at %v did not happen", req.Label, req.Time)
+	st.Set("pending-factory-reset", nil)
+	return nil
+}
+
+// RecoverySystem describes a recovery system present in ubuntu-seed.
+type RecoverySystem struct {
+	// Label of the recovery system.
+	Label string
+	// Model and BrandID identify the model the system was created for.
+	Model   string
+	BrandID string
+	// DisplayName of the model.
+	DisplayName string
+	// Brand account of the model.
+	Brand *asserts.Account
+	// Timestamp of the model assertion of the system.
+	Timestamp time.Time
+	// Current is set when the system is listed as a current recovery
+	// system in the modeenv.
+	Current bool
+	// Good is set when the system is listed as a good recovery system in
+	// the modeenv.
+	Good bool
+	// HasUnassertedSnaps is set when the system contains unasserted snaps.
+	HasUnassertedSnaps bool
+	// CreationInfo carries the details of how the system was created, it
+	// is unset for systems which were not created by snapd.
+	CreationInfo *RecoverySystemCreationInfo
+	// Usage is the disk space used by the system in ubuntu-seed.
+	Usage RecoverySystemUsage
+}
+
+// RecoverySystemUsage describes the disk space used by a recovery system.
+type RecoverySystemUsage struct {
+	// Size of the files in the directory of the system, which includes
+	// its unasserted snaps.
+	Size int64
+	// SharedSize is the share of the system in the size of the asserted
+	// snap files in the snaps directory shared by the systems. The size of
+	// each file is split evenly between the systems using it.
+	SharedSize int64
+}
+
+// Total returns the total disk space attributed to the system.
+func (u RecoverySystemUsage) Total() int64 {
+	return u.Size + u.SharedSize
+}
+
+// dirSize returns the total size of the regular files under the given
+// directory.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// ListRecoverySystems returns the recovery systems present in ubuntu-seed,
+// together with the details of the model each one of them was created for,
+// their status in the modeenv and their disk usage. Systems which cannot be
+// loaded are skipped, and are not accounted for when splitting the size of the
+// shared snap files.
+func ListRecoverySystems() ([]*RecoverySystem, error) {
+	modeenv, err := boot.ReadModeenv("")
+	if err != nil {
+		return nil, fmt.Errorf("cannot list recovery systems: %v", err)
+	}
+
+	systemDirs, err := filepath.Glob(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", "*"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot list recovery systems: %v", err)
+	}
+	if len(systemDirs) == 0 {
+		return nil, ErrNoSystems
+	}
+
+	assertedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
+	// systems using each one of the shared snap files
+	sharedFiles := make(map[string][]*RecoverySystem)
+	var systems []*RecoverySystem
+	for _, systemDir := range systemDirs {
+		label := filepath.Base(systemDir)
+		sd, err := loadRecoverySystemSeed(boot.InitramfsUbuntuSeedDir, label, metadataOnlySnapHandler{})
+		if err != nil {
+			logger.Noticef("cannot load recovery system %q: %v", label, err)
+			continue
+		}
+		model := sd.Model()
+		brand, err := sd.Brand()
+		if err != nil {
+			logger.Noticef("cannot load recovery system %q: %v", label, err)
+			continue
+		}
+		system := &RecoverySystem{
+			Label:       label,
+			Model:       model.Model(),
+			BrandID:     model.BrandID(),
+			DisplayName: model.DisplayName(),
+			Brand:       brand,
+			Timestamp:   model.Timestamp(),
+			Current:     strutil.ListContains(modeenv.CurrentRecoverySystems, label),
+			Good:        strutil.ListContains(modeenv.GoodRecoverySystems, label),
+		}
+		err = sd.Iter(func(sn *seed.Snap) error {
+			if sn.SideInfo.SnapID == "" {
+				system.HasUnassertedSnaps = true
+			}
+			paths := []string{sn.Path}
+			for _, comp := range sn.Components {
+				paths = append(paths, comp.Path)
+			}
+			for _, path := range paths {
+				if filepath.Dir(path) == assertedSnapsDir {
+					sharedFiles[path] = append(sharedFiles[path], system)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		system.CreationInfo, err = readRecoverySystemCreationInfo(systemDir)
+		if err != nil {
+			logger.Noticef("cannot read creation information of recovery system %q: %v", label, err)
+		}
+		system.Usage.Size, err = dirSize(systemDir)
+		if err != nil {
+			return nil, fmt.Errorf("cannot obtain disk usage of recovery system %q: %v", label, err)
+		}
+		systems = append(systems, system)
+	}
+	for path, users := range sharedFiles {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot obtain disk usage of recovery systems: %v", err)
+		}
+		share := fi.Size() / int64(len(users))
+		for _, system := range users {
+			system.Usage.SharedSize += share
+		}
+	}
+	return systems, nil
+}
+
+// SystemSnap describes a snap included in a recovery system.
+type SystemSnap struct {
+	Name     string
+	Revision snap.Revision
+	Channel  string
+	// Asserted is true if the snap is backed by assertions in the seed.
+	Asserted bool
+	// Size is the size of the snap file.
+	Size int64
+	// Shared is true if the snap file is also used by other recovery
+	// systems.
+	Shared bool
+}
+
+// systemSnapsCacheTimeout is for how long the snaps of a recovery system
+// are cached, loading them is expensive as their digests are verified.
+var systemSnapsCacheTimeout = 1 * time.Minute
+
+type cachedSystemSnaps struct {
+	snaps  []*seed.Snap
+	loaded time.Time
+}
+
+// systemSeedSnaps returns the snaps of the recovery system with the given
+// label, reusing the recently loaded ones if any.
+func (m *DeviceManager) systemSeedSnaps(label string) ([]*seed.Snap, error) {
+	m.systemSnapsMu.Lock()
+	defer m.systemSnapsMu.Unlock()
+
+	now := timeNow()
+	if cached := m.systemSnapsCache[label]; cached != nil && now.Sub(cached.loaded) < systemSnapsCacheTimeout {
+		return cached.snaps, nil
+	}
+
+	sd, err := loadRecoverySystemSeed(dirs.SnapSeedDir, label, nil)
+	if err != nil {
+		return nil, err
+	}
+	snaps := make([]*seed.Snap, 0, sd.NumSnaps())
+	sd.Iter(func(sn *seed.Snap) error {
+		snaps = append(snaps, sn)
+		return nil
+	})
+	if m.systemSnapsCache == nil {
+		m.systemSnapsCache = make(map[string]*cachedSystemSnaps)
+	}
+	m.systemSnapsCache[label] = &cachedSystemSnaps{snaps: snaps, loaded: now}
+	return snaps, nil
+}
+
+// SystemSnaps returns the snaps included in the recovery system with the
+// given label. Reading the seed can be slow, this must be called without
+// holding the state lock.
+func (m *DeviceManager) SystemSnaps(label string) ([]*SystemSnap, error) {
+	if _, err := os.Stat(filepath.Join(dirs.SnapSeedDir, "systems", label)); err != nil {
+		return nil, err
+	}
+	seedSnaps, err := m.systemSeedSnaps(label)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load recovery system %q: %v", label, err)
+	}
+
+	// snap files of other systems, to find the shared ones
+	otherSystems, err := filepath.Glob(filepath.Join(dirs.SnapSeedDir, "systems", "*"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot list available systems: %v", err)
+	}
+	sharedPaths := make(map[string]bool)
+	for _, fpLabel := range otherSystems {
+		otherLabel := filepath.Base(fpLabel)
+		if otherLabel == label {
+			continue
+		}
+		otherSnaps, err := m.systemSeedSnaps(otherLabel)
+		if err != nil {
+			logger.Noticef("cannot load system %q seed: %v", otherLabel, err)
+			continue
+		}
+		for _, sn := range otherSnaps {
+			sharedPaths[sn.Path] = true
+		}
+	}
+
+	snaps := make([]*SystemSnap, 0, len(seedSnaps))
+	for _, sn := range seedSnaps {
+		fi, err := os.Stat(sn.Path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get size of snap %q: %v", sn.SnapName(), err)
+		}
+		snaps = append(snaps, &SystemSnap{
+			Name:     sn.SnapName(),
+			Revision: sn.SideInfo.Revision,
+			Channel:  sn.Channel,
+			Asserted: sn.SideInfo.SnapID != "",
+			Size:     fi.Size(),
+			Shared:   sharedPaths[sn.Path],
+		})
+	}
+	return snaps, nil
+}
@@ -21,25 +21,43 @@ package devicestate_test
 
 import (
 	"bytes"
+	"context"
+	"crypto"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
 
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/boot"
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/bootloader/bootloadertest"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/seed/seedtest"
+	"github.com/snapcore/snapd/seed/seedwriter"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/snapdtool"
 	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
 )
 
 type createSystemSuite struct {
@@ -57,6 +75,10 @@ var (
 	snapYamls       = map[string]string{
 		"pc-kernel":        "name: pc-kernel\nversion: 1.0\ntype: kernel",
 		"pc":               "name: pc\nversion: 1.0\ntype: gadget\nbase: core20",
+		"pc-alt":           "name: pc-alt\nversion: 1.0\ntype: gadget\nbase: core20",
+		"pc-full":          "name: pc-full\nversion: 1.0\ntype: gadget\nbase: core20",
+		"pc-both":          "name: pc-both\nversion: 1.0\ntype: gadget\nbase: core20",
+		"pc-recover":       "name: pc-recover\nversion: 1.0\ntype: gadget\nbase: core20",
 		"core20":           "name: core20\nversion: 20.1\ntype: base",
 		"core18":           "name: core18\nversion: 18.1\ntype: base",
 		"snapd":            "name: snapd\nversion: 2.2.2\ntype: snapd",
@@ -64,12 +86,34 @@ var (
 		"other-present":    fmt.Sprintf(genericSnapYaml, "other-present", "base: core20"),
 		"other-core18":     fmt.Sprintf(genericSnapYaml, "other-present", "base: core18"),
 		"other-unasserted": fmt.Sprintf(genericSnapYaml, "other-unasserted", "base: core20"),
+		"other-classic":    fmt.Sprintf(genericSnapYaml, "other-classic", "base: core20\nconfinement: classic"),
 	}
 	snapFiles = map[string][][]string{
+		"pc-kernel": {
+			{"kernel.efi", "kernel"},
+		},
 		"pc": {
 			{"meta/gadget.yaml", gadgetYaml},
 			{"cmdline.extra", "args from gadget"},
 		},
+		"pc-alt": {
+			{"meta/gadget.yaml", gadgetYaml},
+			{"cmdline.extra", "args from alternative gadget"},
+		},
+		"pc-full": {
+			{"meta/gadget.yaml", gadgetYaml},
+			{"cmdline.full", "full args from gadget"},
+		},
+		"pc-both": {
+			{"meta/gadget.yaml", gadgetYaml},
+			{"cmdline.extra", "args from gadget"},
+			{"cmdline.full", "full args from gadget"},
+		},
+		"pc-recover": {
+			{"meta/gadget.yaml", gadgetYaml},
+			{"cmdline.extra", "args from gadget"},
+			{"cmdline.full.recover", "full recovery args from gadget"},
+		},
 	}
 )
 
@@ -137,6 +181,11 @@ func validateCore20Seed(c *C, name string, expectedModel *asserts.Model, trusted
 	}
 
 	c.Assert(sd.Model(), DeepEquals, expectedModel)
+
+	// and check the consistency of the whole system
+	restore := seed.MockTrusted(trusted)
+	defer restore()
+	c.Check(seed.Validate(boot.InitramfsUbuntuSeedDir, name), IsNil)
 }
 
 func (s *createSystemSuite) TestCreateSystemFromAssertedSnaps(c *C) {
@@ -211,9 +260,9 @@ func (s *createSystemSuite) TestCreateSystemFromAssertedSnaps(c *C) {
 	})
 	expectedDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
 
-	infoGetter := func(name string) (*snap.Info, bool, error) {
-		c.Logf("called for: %q", name)
-		info, present := infos[name]
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		c.Logf("called for: %q", sn.SnapName())
+		info, present := infos[sn.SnapName()]
 		return info, present, nil
 	}
 	var newFiles []string
@@ -224,7 +273,7 @@ func (s *createSystemSuite) TestCreateSystemFromAssertedSnaps(c *C) {
 		return nil
 	}
 
-	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver)
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver, nil)
 	c.Assert(err, IsNil)
 	c.Check(newFiles, DeepEquals, []string{
 		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap"),
@@ -250,12 +299,14 @@ func (s *createSystemSuite) TestCreateSystemFromAssertedSnaps(c *C) {
 		"snapd_extra_cmdline_args": "args from gadget",
 		"snapd_recovery_kernel":    "/snaps/pc-kernel_1.snap",
 	})
+	c.Check(s.logbuf.String(), testutil.Contains,
+		`recovery system "1234" kernel command line: "snapd_recovery_mode=recover snapd_recovery_system=1234 mock static args from gadget"`)
 	// load the seed
 	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted,
 		"other-core18", "core18", "other-present", "other-required")
 }
 
-func (s *createSystemSuite) TestCreateSystemFromUnassertedSnaps(c *C) {
+func (s *createSystemSuite) TestCreateSystemGetInfoModelSnaps(c *C) {
 	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
 	// make it simple for now, no assets
 	bl.TrustedAssetsList = nil
@@ -267,8 +318,8 @@ func (s *createSystemSuite) TestCreateSystemFromUnassertedSnaps(c *C) {
 	defer s.state.Unlock()
 	s.setupBrands()
 	infos := s.makeEssentialSnapInfos(c)
-	// unasserted with local revision
-	infos["other-unasserted"] = s.makeSnap(c, "other-unasserted", snap.R(-1))
+	infos["other-present"] = s.makeSnap(c, "other-present", snap.R(5))
+	infos["other-required"] = s.makeSnap(c, "other-required", snap.R(6))
 
 	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
 		"architecture": "amd64",
@@ -288,74 +339,68 @@ func (s *createSystemSuite) TestCreateSystemFromUnassertedSnaps(c *C) {
 				"default-channel": "20",
 			},
 			map[string]interface{}{
-				"name": "snapd",
-				"id":   s.ss.AssertedSnapID("snapd"),
-				"type": "snapd",
+				"name":            "other-present",
+				"id":              s.ss.AssertedSnapID("other-present"),
+				"presence":        "optional",
+				"default-channel": "latest/edge",
 			},
-			// required
 			map[string]interface{}{
-				"name":     "other-unasserted",
+				"name":     "other-required",
+				"id":       s.ss.AssertedSnapID("other-required"),
 				"presence": "required",
 			},
 		},
 	})
-	expectedDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
 
-	infoGetter := func(name string) (*snap.Info, bool, error) {
-		c.Logf("called for: %q", name)
-		info, present := infos[name]
-		return info, present, nil
+	type modelSnapDetails struct {
+		snapType, presence, channel string
 	}
-	var newFiles []string
-	snapWriteObserver := func(dir, where string) error {
-		c.Check(dir, Equals, expectedDir)
-		c.Check(where, testutil.FileAbsent)
-		newFiles = append(newFiles, where)
-		return nil
+	requested := make(map[string]modelSnapDetails)
+	// a getter which leaves out optional snaps
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		requested[sn.SnapName()] = modelSnapDetails{sn.SnapType, sn.Presence, sn.DefaultChannel}
+		if sn.Presence == "optional" {
+			return nil, false, nil
+		}
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
 	}
 
-	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver)
+	_, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, nil, nil)
 	c.Assert(err, IsNil)
-	c.Check(newFiles, DeepEquals, []string{
-		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap"),
-		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap"),
-		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_3.snap"),
-		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_2.snap"),
-		// this snap unasserted and lands under the system
-		filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234/snaps/other-unasserted_1.0.snap"),
+	c.Check(requested, DeepEquals, map[string]modelSnapDetails{
+		// snapd is implicit
+		"snapd":          {"snapd", "required", "latest/stable"},
+		"pc-kernel":      {"kernel", "required", "20"},
+		"core20":         {"base", "required", "latest/stable"},
+		"pc":             {"gadget", "required", "20"},
+		"other-present":  {"app", "optional", "latest/edge"},
+		"other-required": {"app", "required", "latest/stable"},
 	})
-	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"))
-	// naive check for files being present
-	for _, info := range infos {
-		if info.Revision.Store() {
-			c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps", filepath.Base(info.MountFile())),
-				testutil.FileEquals,
-				testutil.FileContentRef(info.MountFile()))
-		} else {
-			fileName := fmt.Sprintf("%s_%s.snap", info.SnapName(), info.Version)
-			c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234/snaps", fileName),
-				testutil.FileEquals,
-				testutil.FileContentRef(info.MountFile()))
-		}
-	}
-	// load the seed
-	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted, "other-unasserted")
-	// we have unasserted snaps, so a warning should have been logged
-	c.Check(s.logbuf.String(), testutil.Contains, `system "1234" contains unasserted snaps "other-unasserted"`)
+	// the optional snap was left out
+	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted, "other-required")
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/other-present_5.snap"), testutil.FileAbsent)
 }
 
-func (s *createSystemSuite) TestCreateSystemWithSomeSnapsAlreadyExisting(c *C) {
+func (s *createSystemSuite) TestCreateSystemClassicWithModes(c *C) {
 	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	bl.TrustedAssetsList = nil
 	bootloader.Force(bl)
 
 	s.state.Lock()
 	defer s.state.Unlock()
 	s.setupBrands()
 	infos := s.makeEssentialSnapInfos(c)
+	infos["other-classic"] = s.makeSnap(c, "other-classic", snap.R(5))
+
+	// the rootfs comes from the archive, core20 is only the base of the
+	// snaps
 	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
 		"architecture": "amd64",
 		"grade":        "dangerous",
 		"base":         "core20",
+		"classic":      "true",
+		"distribution": "ubuntu",
 		"snaps": []interface{}{
 			map[string]interface{}{
 				"name":            "pc-kernel",
@@ -370,123 +415,94 @@ func (s *createSystemSuite) TestCreateSystemWithSomeSnapsAlreadyExisting(c *C) {
 				"default-channel": "20",
 			},
 			map[string]interface{}{
-				"name": "snapd",
-				"id":   s.ss.AssertedSnapID("snapd"),
-				"type": "snapd",
+				"name":  "other-classic",
+				"id":    s.ss.AssertedSnapID("other-classic"),
+				"modes": []interface{}{"run"},
 			},
 		},
 	})
-	expectedDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
-
-	infoGetter := func(name string) (*snap.Info, bool, error) {
-		c.Logf("called for: %q", name)
-		info, present := infos[name]
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
 		return info, present, nil
 	}
-	var newFiles []string
-	snapWriteObserver := func(dir, where string) error {
-		c.Check(dir, Equals, expectedDir)
-		// we are not called for the snap which already exists
-		c.Check(where, testutil.FileAbsent)
-		newFiles = append(newFiles, where)
-		return nil
-	}
-
-	assertedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
-	c.Assert(os.MkdirAll(assertedSnapsDir, 0755), IsNil)
-	// procure the file in place
-	err := osutil.CopyFile(infos["core20"].MountFile(), filepath.Join(assertedSnapsDir, "core20_3.snap"), 0)
-	c.Assert(err, IsNil)
 
-	// when a given snap in asserted snaps directory already exists, it is
-	// not copied over
-	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver)
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, nil, nil)
 	c.Assert(err, IsNil)
-	c.Check(newFiles, DeepEquals, []string{
-		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap"),
-		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap"),
-		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_2.snap"),
-	})
 	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"))
-	// naive check for files being present
-	for _, info := range infos {
-		c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps", filepath.Base(info.MountFile())),
-			testutil.FileEquals,
-			testutil.FileContentRef(info.MountFile()))
-	}
-	// recovery system bootenv was set
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/other-classic_5.snap"), testutil.FilePresent)
+	// the system was made bootable
 	c.Check(bl.RecoverySystemDir, Equals, "/systems/1234")
-	c.Check(bl.RecoverySystemBootVars, DeepEquals, map[string]string{
-		"snapd_full_cmdline_args":  "",
-		"snapd_extra_cmdline_args": "args from gadget",
-		"snapd_recovery_kernel":    "/snaps/pc-kernel_1.snap",
-	})
-	// load the seed
-	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted)
+	c.Check(bl.RecoverySystemBootVars["snapd_recovery_kernel"], Equals, "/snaps/pc-kernel_1.snap")
+	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted, "other-classic")
+}
 
-	// add an unasserted snap
-	infos["other-unasserted"] = s.makeSnap(c, "other-unasserted", snap.R(-1))
-	modelWithUnasserted := s.makeModelAssertionInState(c, "my-brand", "pc-with-unasserted", map[string]interface{}{
+func (s *createSystemSuite) TestCreateSystemClassicWithModesNoKernel(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := map[string]*snap.Info{}
+	infos["core20"] = s.makeSnap(c, "core20", snap.R(3))
+	infos["snapd"] = s.makeSnap(c, "snapd", snap.R(4))
+	infos["other-classic"] = s.makeSnap(c, "other-classic", snap.R(5))
+
+	// neither kernel nor gadget, those come from the archive too
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
 		"architecture": "amd64",
 		"grade":        "dangerous",
 		"base":         "core20",
+		"classic":      "true",
+		"distribution": "ubuntu",
 		"snaps": []interface{}{
 			map[string]interface{}{
-				"name":            "pc-kernel",
-				"id":              s.ss.AssertedSnapID("pc-kernel"),
-				"type":            "kernel",
-				"default-channel": "20",
-			},
-			map[string]interface{}{
-				"name":            "pc",
-				"id":              s.ss.AssertedSnapID("pc"),
-				"type":            "gadget",
-				"default-channel": "20",
-			},
-			map[string]interface{}{
-				"name": "snapd",
-				"id":   s.ss.AssertedSnapID("snapd"),
-				"type": "snapd",
-			},
-			// required
-			map[string]interface{}{
-				"name":     "other-unasserted",
-				"presence": "required",
+				"name": "other-classic",
+				"id":   s.ss.AssertedSnapID("other-classic"),
 			},
 		},
 	})
+	var requested []string
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		requested = append(requested, sn.SnapName())
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
 
-	unassertedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234unasserted/snaps")
-	c.Assert(os.MkdirAll(unassertedSnapsDir, 0755), IsNil)
-	err = osutil.CopyFile(infos["other-unasserted"].MountFile(),
-		filepath.Join(unassertedSnapsDir, "other-unasserted_1.0.snap"), 0)
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, nil, nil)
 	c.Assert(err, IsNil)
-
-	newFiles = nil
-	// the unasserted snap goes into the snaps directory under the system
-	// directory, which triggers the error in creating the directory by
-	// seed writer
-	dir, err = devicestate.CreateSystemForModelFromValidatedSnaps(modelWithUnasserted, "1234unasserted", s.db,
-		infoGetter, snapWriteObserver)
-
-	c.Assert(err, ErrorMatches, `system "1234unasserted" already exists`)
-	// we failed early, no files were written yet
-	c.Check(dir, Equals, "")
-	c.Check(newFiles, IsNil)
+	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"))
+	sort.Strings(requested)
+	c.Check(requested, DeepEquals, []string{"core20", "other-classic", "snapd"})
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/other-classic_5.snap"), testutil.FilePresent)
+	// but there is nothing to boot into
+	c.Check(bl.RecoverySystemDir, Equals, "")
+	c.Check(s.logbuf.String(), testutil.Contains, `created recovery system "1234", not bootable as the classic model has no kernel`)
+
+	restore := seed.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+	sd, err := seed.Open(boot.InitramfsUbuntuSeedDir, "1234")
+	c.Assert(err, IsNil)
+	c.Assert(sd.LoadAssertions(nil, nil), IsNil)
+	c.Assert(sd.LoadMeta(seed.AllModes, nil, timings.New(nil)), IsNil)
+	c.Check(sd.Model(), DeepEquals, model)
+	var essential []string
+	for _, sn := range sd.EssentialSnaps() {
+		essential = append(essential, sn.SnapName())
+	}
+	c.Check(essential, DeepEquals, []string{"snapd", "core20"})
 }
 
-func (s *createSystemSuite) TestCreateSystemInfoAndAssertsChecks(c *C) {
+func (s *createSystemSuite) TestCreateSystemMissingBase(c *C) {
 	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
 	bootloader.Force(bl)
-	infos := map[string]*snap.Info{}
 
 	s.state.Lock()
 	defer s.state.Unlock()
 	s.setupBrands()
-	// missing info for the pc snap
-	infos["pc-kernel"] = s.makeSnap(c, "pc-kernel", snap.R(1))
-	infos["core20"] = s.makeSnap(c, "core20", snap.R(3))
-	infos["snapd"] = s.makeSnap(c, "snapd", snap.R(4))
+	infos := s.makeEssentialSnapInfos(c)
+	infos["other-core18"] = s.makeSnap(c, "other-core18", snap.R(7))
+
 	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
 		"architecture": "amd64",
 		"grade":        "dangerous",
@@ -498,89 +514,52 @@ func (s *createSystemSuite) TestCreateSystemInfoAndAssertsChecks(c *C) {
 				"type":            "kernel",
 				"default-channel": "20",
 			},
-			// pc snap is the gadget, but we have no info for it
 			map[string]interface{}{
 				"name":            "pc",
 				"id":              s.ss.AssertedSnapID("pc"),
 				"type":            "gadget",
 				"default-channel": "20",
 			},
+			// the base of the snap is not part of the model
 			map[string]interface{}{
-				"name": "snapd",
-				"id":   s.ss.AssertedSnapID("snapd"),
-				"type": "snapd",
-			},
-			map[string]interface{}{
-				"name":     "other-required",
-				"id":       s.ss.AssertedSnapID("other-required"),
-				"presence": "required",
+				"name": "other-core18",
+				"id":   s.ss.AssertedSnapID("other-core18"),
 			},
 		},
 	})
 
-	infoGetter := func(name string) (*snap.Info, bool, error) {
-		c.Logf("called for: %q", name)
-		info, present := infos[name]
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
 		return info, present, nil
 	}
-	var observerCalls int
 	snapWriteObserver := func(dir, where string) error {
-		observerCalls++
-		return fmt.Errorf("unexpected call")
+		c.Fatalf("unexpected write of %v", where)
+		return nil
 	}
 
-	systemDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
-
-	// when a given snap in asserted snaps directory already exists, it is
-	// not copied over
-	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
-		infoGetter, snapWriteObserver)
-	c.Assert(err, ErrorMatches, `internal error: essential snap "pc" not present`)
-	c.Check(dir, Equals, "")
-	c.Check(observerCalls, Equals, 0)
-
-	// the directory shouldn't be there, as we haven't written anything yet
-	c.Check(osutil.IsDirectory(systemDir), Equals, false)
-
-	// create the info now
-	infos["pc"] = s.makeSnap(c, "pc", snap.R(2))
-
-	// and try with with a non essential snap
-	dir, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
-		infoGetter, snapWriteObserver)
-	c.Assert(err, ErrorMatches, `internal error: non-essential but required snap "other-required" not present`)
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver, nil)
+	c.Assert(err, ErrorMatches, `cannot add snap "other-core18" without also adding its base "core18" explicitly`)
+	var missingBaseErr *seedwriter.MissingBaseError
+	c.Check(errors.As(err, &missingBaseErr), Equals, true)
 	c.Check(dir, Equals, "")
-	c.Check(observerCalls, Equals, 0)
-	// the directory shouldn't be there, as we haven't written anything yet
-	c.Check(osutil.IsDirectory(systemDir), Equals, false)
-
-	// create the info now
-	infos["other-required"] = s.makeSnap(c, "other-required", snap.R(5))
-
-	// but change the file contents of 'pc' snap so that deriving side info fails
-	randomSnap := snaptest.MakeTestSnapWithFiles(c, `name: random
-version: 1`, nil)
-	c.Assert(osutil.CopyFile(randomSnap, infos["pc"].MountFile(), osutil.CopyFlagOverwrite), IsNil)
-	dir, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
-		infoGetter, snapWriteObserver)
-	c.Assert(err, ErrorMatches, `internal error: no assertions for asserted snap with ID: pcididididididididididididididid`)
-	// we're past the start, so the system directory is there
-	c.Check(dir, Equals, systemDir)
-	c.Check(osutil.IsDirectory(systemDir), Equals, true)
-	// but no files were copied
-	c.Check(observerCalls, Equals, 0)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
 }
 
-func (s *createSystemSuite) TestCreateSystemGetInfoErr(c *C) {
+func (s *createSystemSuite) TestCreateSystemFromUnassertedSnaps(c *C) {
 	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
 	bootloader.Force(bl)
 
 	s.state.Lock()
 	defer s.state.Unlock()
 	s.setupBrands()
-	// missing info for the pc snap
 	infos := s.makeEssentialSnapInfos(c)
-	infos["other-required"] = s.makeSnap(c, "other-required", snap.R(5))
+	// unasserted with local revision
+	infos["other-unasserted"] = s.makeSnap(c, "other-unasserted", snap.R(-1))
+
 	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
 		"architecture": "amd64",
 		"grade":        "dangerous",
@@ -592,7 +571,6 @@ func (s *createSystemSuite) TestCreateSystemGetInfoErr(c *C) {
 				"type":            "kernel",
 				"default-channel": "20",
 			},
-			// pc snap is the gadget, but we have no info for it
 			map[string]interface{}{
 				"name":            "pc",
 				"id":              s.ss.AssertedSnapID("pc"),
@@ -604,96 +582,2617 @@ func (s *createSystemSuite) TestCreateSystemGetInfoErr(c *C) {
 				"id":   s.ss.AssertedSnapID("snapd"),
 				"type": "snapd",
 			},
+			// required
 			map[string]interface{}{
-				"name":     "other-required",
-				"id":       s.ss.AssertedSnapID("other-required"),
+				"name":     "other-unasserted",
 				"presence": "required",
 			},
 		},
 	})
+	expectedDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
 
-	failOn := map[string]bool{}
-
-	infoGetter := func(name string) (*snap.Info, bool, error) {
-		c.Logf("called for: %q", name)
-		if failOn[name] {
-			return nil, false, fmt.Errorf("mock failure for snap %q", name)
-		}
-		info, present := infos[name]
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		c.Logf("called for: %q", sn.SnapName())
+		info, present := infos[sn.SnapName()]
 		return info, present, nil
 	}
-	var observerCalls int
+	var newFiles []string
 	snapWriteObserver := func(dir, where string) error {
-		observerCalls++
-		return fmt.Errorf("unexpected call")
+		c.Check(dir, Equals, expectedDir)
+		c.Check(where, testutil.FileAbsent)
+		newFiles = append(newFiles, where)
+		return nil
 	}
 
-	systemDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
-
-	// when a given snap in asserted snaps directory already exists, it is
-	// not copied over
-
-	failOn["pc"] = true
-	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
-		infoGetter, snapWriteObserver)
-	c.Assert(err, ErrorMatches, `cannot obtain essential snap information: mock failure for snap "pc"`)
-	c.Check(dir, Equals, "")
-	c.Check(observerCalls, Equals, 0)
-	c.Check(osutil.IsDirectory(systemDir), Equals, false)
-
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver, nil)
+	c.Assert(err, IsNil)
+	c.Check(newFiles, DeepEquals, []string{
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_3.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_2.snap"),
+		// this snap unasserted and lands under the system
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234/snaps/other-unasserted_1.0.snap"),
+	})
+	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"))
+	// naive check for files being present
+	for _, info := range infos {
+		if info.Revision.Store() {
+			c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps", filepath.Base(info.MountFile())),
+				testutil.FileEquals,
+				testutil.FileContentRef(info.MountFile()))
+		} else {
+			fileName := fmt.Sprintf("%s_%s.snap", info.SnapName(), info.Version)
+			c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234/snaps", fileName),
+				testutil.FileEquals,
+				testutil.FileContentRef(info.MountFile()))
+		}
+	}
+	// load the seed
+	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted, "other-unasserted")
+	// we have unasserted snaps, so a warning should have been logged
+	c.Check(s.logbuf.String(), testutil.Contains, `system "1234" contains unasserted snaps "other-unasserted"`)
+}
+
+func (s *createSystemSuite) testCreateSystemGadgetCommandLine(c *C, gadgetName string) (*bootloadertest.MockRecoveryAwareTrustedAssetsBootloader, error) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+	infos[gadgetName] = s.makeSnap(c, gadgetName, snap.R(12))
+
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            gadgetName,
+				"id":              s.ss.AssertedSnapID(gadgetName),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	_, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, nil, nil)
+	return bl, err
+}
+
+func (s *createSystemSuite) TestCreateSystemGadgetFullCommandLine(c *C) {
+	bl, err := s.testCreateSystemGadgetCommandLine(c, "pc-full")
+	c.Assert(err, IsNil)
+	// the full command line of the gadget is used, with no extra arguments
+	c.Check(bl.RecoverySystemDir, Equals, "/systems/1234")
+	c.Check(bl.RecoverySystemBootVars, DeepEquals, map[string]string{
+		"snapd_full_cmdline_args":  "full args from gadget",
+		"snapd_extra_cmdline_args": "",
+		"snapd_recovery_kernel":    "/snaps/pc-kernel_1.snap",
+	})
+	c.Check(s.logbuf.String(), testutil.Contains,
+		`recovery system "1234" kernel command line: "snapd_recovery_mode=recover snapd_recovery_system=1234 full args from gadget"`)
+}
+
+func (s *createSystemSuite) TestCreateSystemGadgetRecoveryCommandLine(c *C) {
+	bl, err := s.testCreateSystemGadgetCommandLine(c, "pc-recover")
+	c.Assert(err, IsNil)
+	// the recovery variant of the command line takes precedence
+	c.Check(bl.RecoverySystemDir, Equals, "/systems/1234")
+	c.Check(bl.RecoverySystemBootVars, DeepEquals, map[string]string{
+		"snapd_full_cmdline_args":  "full recovery args from gadget",
+		"snapd_extra_cmdline_args": "",
+		"snapd_recovery_kernel":    "/snaps/pc-kernel_1.snap",
+	})
+	c.Check(s.logbuf.String(), testutil.Contains,
+		`recovery system "1234" kernel command line: "snapd_recovery_mode=recover snapd_recovery_system=1234 full recovery args from gadget"`)
+}
+
+func (s *createSystemSuite) TestCreateSystemKernelNotBootable(c *C) {
+	// kernel without a kernel.efi
+	oldKernelFiles := snapFiles["pc-kernel"]
+	snapFiles["pc-kernel"] = nil
+	s.AddCleanup(func() { snapFiles["pc-kernel"] = oldKernelFiles })
+
+	bl, err := s.testCreateSystemGadgetCommandLine(c, "pc-alt")
+	c.Assert(err, ErrorMatches, `recovery system "1234" is missing bootloader configuration: kernel snap "pc-kernel" does not contain kernel.efi required by trusted bootloader`)
+	var bootConfigErr *boot.RecoverySystemBootConfigError
+	c.Check(errors.As(err, &bootConfigErr), Equals, true)
+	// the environment was set up nonetheless
+	c.Check(bl.RecoverySystemDir, Equals, "/systems/1234")
+}
+
+func (s *createSystemSuite) TestCreateSystemGadgetBothCommandLinesErr(c *C) {
+	bl, err := s.testCreateSystemGadgetCommandLine(c, "pc-both")
+	c.Assert(err, ErrorMatches, `cannot use kernel command line from gadget "pc-both": cannot support both extra and full kernel command lines`)
+	// nothing was written
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps"), testutil.FileAbsent)
+	c.Check(bl.RecoverySystemBootVars, HasLen, 0)
+}
+
+func (s *createSystemSuite) TestCreateSystemSeedNotWritable(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	infoGetter := func(*asserts.ModelSnap) (*snap.Info, bool, error) {
+		c.Fatalf("unexpected call")
+		return nil, false, nil
+	}
+
+	for _, tc := range []struct {
+		mountInfo string
+		err       string
+	}{{
+		// only ubuntu-save is mounted
+		mountInfo: `26 27 8:3 / %s/run/mnt/ubuntu-save rw,relatime shared:7 - ext4 /dev/fakedevice0p1 rw,data=ordered`,
+		err:       `ubuntu-seed is not mounted writable: .*/run/mnt/ubuntu-seed is not a mount point`,
+	}, {
+		mountInfo: `25 27 8:2 / %s/run/mnt/ubuntu-seed ro,relatime shared:6 - vfat /dev/fakedevice0p2 ro`,
+		err:       `ubuntu-seed is not mounted writable: mounted read-only`,
+	}, {
+		// remounted read-only after filesystem errors
+		mountInfo: `25 27 8:2 / %s/run/mnt/ubuntu-seed rw,relatime shared:6 - ext4 /dev/fakedevice0p2 ro,errors=remount-ro`,
+		err:       `ubuntu-seed is not mounted writable: mounted read-only`,
+	}, {
+		mountInfo: `25 27 8:2 / %s/run/mnt/ubuntu-seed rw,relatime shared:6 - squashfs /dev/fakedevice0p2 rw`,
+		err:       `ubuntu-seed is not mounted writable: unsupported filesystem "squashfs"`,
+	}} {
+		restore := osutil.MockMountInfo(fmt.Sprintf(tc.mountInfo, dirs.GlobalRootDir))
+		_, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, nil, nil)
+		restore()
+		c.Check(err, ErrorMatches, tc.err)
+		var notWritableErr *devicestate.SeedNotWritableError
+		c.Check(errors.As(err, &notWritableErr), Equals, true)
+		// nothing was written
+		c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+	}
+}
+
+func (s *createSystemSuite) TestCreateSystemWithPreseedArtifact(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+
+	// artifact of an existing system being replicated
+	artifact := filepath.Join(c.MkDir(), "preseed.tgz")
+	c.Assert(os.WriteFile(artifact, []byte("preseed-data"), 0644), IsNil)
+	sha3_384, _, err := osutil.FileDigest(artifact, crypto.SHA3_384)
+	c.Assert(err, IsNil)
+	digest, err := asserts.EncodeDigest(crypto.SHA3_384, sha3_384)
+	c.Assert(err, IsNil)
+	a, err := s.brands.Signing("my-brand").Sign(asserts.PreseedType, map[string]interface{}{
+		"type":              "preseed",
+		"series":            "16",
+		"brand-id":          "my-brand",
+		"model":             "pc",
+		"system-label":      "1234",
+		"artifact-sha3-384": digest,
+		"timestamp":         time.Now().UTC().Format(time.RFC3339),
+		"snaps": []interface{}{
+			map[string]interface{}{"name": "snapd", "id": s.ss.AssertedSnapID("snapd"), "revision": "4"},
+		},
+	}, nil, "")
+	c.Assert(err, IsNil)
+	preseedAs := a.(*asserts.Preseed)
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	snapWriteObserver := func(dir, where string) error {
+		return nil
+	}
+
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver, &devicestate.CreateSystemOptions{
+		PreseedArtifact:  artifact,
+		PreseedAssertion: preseedAs,
+	})
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(dir, "preseed.tgz"), testutil.FileEquals, "preseed-data")
+
+	restore := seed.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+	sd, err := seed.Open(boot.InitramfsUbuntuSeedDir, "1234")
+	c.Assert(err, IsNil)
+	c.Assert(sd.LoadAssertions(nil, nil), IsNil)
+	preseedSeed := sd.(seed.PreseedCapable)
+	c.Check(preseedSeed.HasPreseedArtifact(), Equals, true)
+	loaded, err := preseedSeed.LoadPreseedAssertion()
+	c.Assert(err, IsNil)
+	c.Check(loaded.SystemLabel(), Equals, "1234")
+
+	// the assertion must be for the new system
+	_, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1235", s.db, infoGetter, snapWriteObserver, &devicestate.CreateSystemOptions{
+		PreseedArtifact:  artifact,
+		PreseedAssertion: preseedAs,
+	})
+	c.Assert(err, ErrorMatches, `cannot use preseed assertion: preseed assertion system label "1234" doesn't match system label "1235"`)
+}
+
+func readSystemTree(c *C, dir string) map[string]string {
+	contents := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		c.Check(fi.Mode()&os.ModeSymlink, Equals, os.FileMode(0), Commentf("unexpected symlink %v", path))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		contents[path] = string(data)
+		return nil
+	})
+	c.Assert(err, IsNil)
+	return contents
+}
+
+func (s *createSystemSuite) TestCreateSystemWithReferenceSystem(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	var newFiles []string
+	snapWriteObserver := func(dir, where string) error {
+		newFiles = append(newFiles, where)
+		return nil
+	}
+
+	_, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver, nil)
+	c.Assert(err, IsNil)
+	refDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
+	refContents := readSystemTree(c, refDir)
+
+	// the gadget got updated
+	infos["pc"] = s.makeSnap(c, "pc", snap.R(12))
+	// the unchanged kernel is taken from the seed, the source file is not
+	// used at all (replace it, as the seed file may be a hard link)
+	c.Assert(os.Remove(infos["pc-kernel"].MountFile()), IsNil)
+	c.Assert(os.WriteFile(infos["pc-kernel"].MountFile(), []byte("corrupted"), 0644), IsNil)
+
+	newFiles = nil
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1235", s.db, infoGetter, snapWriteObserver, &devicestate.CreateSystemOptions{
+		ReferenceSystem: "1234",
+	})
+	c.Assert(err, IsNil)
+	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1235"))
+	// only the updated gadget was copied
+	c.Check(newFiles, DeepEquals, []string{
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_12.snap"),
+	})
+
+	// the reference system is untouched
+	c.Check(readSystemTree(c, refDir), DeepEquals, refContents)
+	readSystemTree(c, dir)
+	validateCore20Seed(c, "1235", model, s.storeSigning.Trusted)
+
+	// an incomplete reference system cannot be used
+	c.Assert(os.Remove(filepath.Join(refDir, "model")), IsNil)
+	_, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1236", s.db, infoGetter, snapWriteObserver, &devicestate.CreateSystemOptions{
+		ReferenceSystem: "1234",
+	})
+	c.Assert(err, ErrorMatches, `cannot use reference recovery system "1234": system is incomplete`)
+}
+
+func (s *createSystemSuite) testCreateSystemValidationSets(c *C, vsSnaps []interface{}) error {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	vsa, err := s.storeSigning.Sign(asserts.ValidationSetType, map[string]interface{}{
+		"type":         "validation-set",
+		"authority-id": "canonical",
+		"series":       "16",
+		"account-id":   "canonical",
+		"name":         "base-set",
+		"sequence":     "1",
+		"snaps":        vsSnaps,
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	vsets := snapasserts.NewValidationSets()
+	c.Assert(vsets.Add(vsa.(*asserts.ValidationSet)), IsNil)
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	_, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, nil,
+		&devicestate.CreateSystemOptions{ValidationSets: vsets})
+	return err
+}
+
+func (s *createSystemSuite) TestCreateSystemValidationSetsHappy(c *C) {
+	err := s.testCreateSystemValidationSets(c, []interface{}{
+		map[string]interface{}{
+			"name":     "pc-kernel",
+			"id":       s.ss.AssertedSnapID("pc-kernel"),
+			"presence": "required",
+			"revision": "1",
+		},
+		map[string]interface{}{
+			"name":     "pc",
+			"id":       s.ss.AssertedSnapID("pc"),
+			"presence": "required",
+		},
+		map[string]interface{}{
+			"name":     "other-present",
+			"id":       s.ss.AssertedSnapID("other-present"),
+			"presence": "optional",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234/model"), testutil.FilePresent)
+}
+
+func (s *createSystemSuite) TestCreateSystemValidationSetsWrongRevision(c *C) {
+	err := s.testCreateSystemValidationSets(c, []interface{}{
+		map[string]interface{}{
+			"name":     "pc-kernel",
+			"id":       s.ss.AssertedSnapID("pc-kernel"),
+			"presence": "required",
+			"revision": "10",
+		},
+		map[string]interface{}{
+			"name":     "pc",
+			"id":       s.ss.AssertedSnapID("pc"),
+			"presence": "required",
+			"revision": "2",
+		},
+		map[string]interface{}{
+			"name":     "core20",
+			"id":       s.ss.AssertedSnapID("core20"),
+			"presence": "invalid",
+		},
+	})
+	c.Assert(err, ErrorMatches, `cannot create a system with snaps not matching the validation sets:
+- snap "core20" is invalid for sets canonical/base-set, found revision 3
+- snap "pc-kernel" is required at revision 10 by sets canonical/base-set, found revision 1`)
+	// nothing was written
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps"), testutil.FileAbsent)
+}
+
+func (s *createSystemSuite) TestCreateSystemValidationSetsMissingSnap(c *C) {
+	err := s.testCreateSystemValidationSets(c, []interface{}{
+		map[string]interface{}{
+			"name":     "pc-kernel",
+			"id":       s.ss.AssertedSnapID("pc-kernel"),
+			"presence": "required",
+		},
+		map[string]interface{}{
+			"name":     "other-required",
+			"id":       s.ss.AssertedSnapID("other-required"),
+			"presence": "required",
+			"revision": "5",
+		},
+	})
+	c.Assert(err, ErrorMatches, `cannot create a system with snaps not matching the validation sets:
+- snap "other-required" is required at revision 5 by sets canonical/base-set, but is not part of the system`)
+	// nothing was written
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps"), testutil.FileAbsent)
+}
+
+func (s *createSystemSuite) TestCreateSystemWithSomeSnapsAlreadyExisting(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name": "snapd",
+				"id":   s.ss.AssertedSnapID("snapd"),
+				"type": "snapd",
+			},
+		},
+	})
+	expectedDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		c.Logf("called for: %q", sn.SnapName())
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	var newFiles []string
+	snapWriteObserver := func(dir, where string) error {
+		c.Check(dir, Equals, expectedDir)
+		// we are not called for the snap which already exists
+		c.Check(where, testutil.FileAbsent)
+		newFiles = append(newFiles, where)
+		return nil
+	}
+
+	assertedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
+	c.Assert(os.MkdirAll(assertedSnapsDir, 0755), IsNil)
+	// procure the file in place
+	err := osutil.CopyFile(infos["core20"].MountFile(), filepath.Join(assertedSnapsDir, "core20_3.snap"), 0)
+	c.Assert(err, IsNil)
+
+	// when a given snap in asserted snaps directory already exists, it is
+	// not copied over
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver, nil)
+	c.Assert(err, IsNil)
+	c.Check(newFiles, DeepEquals, []string{
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_2.snap"),
+	})
+	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"))
+	// naive check for files being present
+	for _, info := range infos {
+		c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps", filepath.Base(info.MountFile())),
+			testutil.FileEquals,
+			testutil.FileContentRef(info.MountFile()))
+	}
+	// recovery system bootenv was set
+	c.Check(bl.RecoverySystemDir, Equals, "/systems/1234")
+	c.Check(bl.RecoverySystemBootVars, DeepEquals, map[string]string{
+		"snapd_full_cmdline_args":  "",
+		"snapd_extra_cmdline_args": "args from gadget",
+		"snapd_recovery_kernel":    "/snaps/pc-kernel_1.snap",
+	})
+	// load the seed
+	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted)
+
+	// add an unasserted snap
+	infos["other-unasserted"] = s.makeSnap(c, "other-unasserted", snap.R(-1))
+	modelWithUnasserted := s.makeModelAssertionInState(c, "my-brand", "pc-with-unasserted", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name": "snapd",
+				"id":   s.ss.AssertedSnapID("snapd"),
+				"type": "snapd",
+			},
+			// required
+			map[string]interface{}{
+				"name":     "other-unasserted",
+				"presence": "required",
+			},
+		},
+	})
+
+	unassertedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234unasserted/snaps")
+	c.Assert(os.MkdirAll(unassertedSnapsDir, 0755), IsNil)
+	err = osutil.CopyFile(infos["other-unasserted"].MountFile(),
+		filepath.Join(unassertedSnapsDir, "other-unasserted_1.0.snap"), 0)
+	c.Assert(err, IsNil)
+
+	newFiles = nil
+	// the unasserted snap goes into the snaps directory under the system
+	// directory, which already exists
+	dir, err = devicestate.CreateSystemForModelFromValidatedSnaps(modelWithUnasserted, "1234unasserted", s.db,
+		infoGetter, snapWriteObserver, nil)
+
+	c.Assert(err, ErrorMatches, `recovery system "1234unasserted" already exists`)
+	c.Check(err, DeepEquals, &devicestate.ErrSystemExists{Label: "1234unasserted"})
+	// we failed early, no files were written yet
+	c.Check(dir, Equals, "")
+	c.Check(newFiles, IsNil)
+}
+
+func (s *createSystemSuite) TestCreateSystemLabelChecks(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	snapWriteObserver := func(dir, where string) error {
+		c.Fatalf("unexpected call")
+		return nil
+	}
+	seedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
+
+	for _, label := range []string{"", "1234_foo", "1234/../foo", "UPPER", "-1234", "1234-"} {
+		dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, label, s.db,
+			infoGetter, snapWriteObserver, nil)
+		c.Assert(err, ErrorMatches, fmt.Sprintf("invalid seed system label: %q", label))
+		c.Check(dir, Equals, "")
+	}
+	// nothing was written
+	c.Check(seedSnapsDir, testutil.FileAbsent)
+
+	// a leftover directory of a previous attempt
+	c.Assert(os.MkdirAll(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), 0755), IsNil)
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
+		infoGetter, snapWriteObserver, nil)
+	c.Assert(err, ErrorMatches, `recovery system "1234" already exists`)
+	var existsErr *devicestate.ErrSystemExists
+	c.Assert(errors.As(err, &existsErr), Equals, true)
+	c.Check(existsErr.Label, Equals, "1234")
+	c.Check(dir, Equals, "")
+	c.Check(seedSnapsDir, testutil.FileAbsent)
+}
+
+func (s *createSystemSuite) TestCreateSystemInfoAndAssertsChecks(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	bootloader.Force(bl)
+	infos := map[string]*snap.Info{}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	// missing info for the pc snap
+	infos["pc-kernel"] = s.makeSnap(c, "pc-kernel", snap.R(1))
+	infos["core20"] = s.makeSnap(c, "core20", snap.R(3))
+	infos["snapd"] = s.makeSnap(c, "snapd", snap.R(4))
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			// pc snap is the gadget, but we have no info for it
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name": "snapd",
+				"id":   s.ss.AssertedSnapID("snapd"),
+				"type": "snapd",
+			},
+			map[string]interface{}{
+				"name":     "other-required",
+				"id":       s.ss.AssertedSnapID("other-required"),
+				"presence": "required",
+			},
+		},
+	})
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		c.Logf("called for: %q", sn.SnapName())
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	var observerCalls int
+	snapWriteObserver := func(dir, where string) error {
+		observerCalls++
+		return fmt.Errorf("unexpected call")
+	}
+
+	systemDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
+
+	// when a given snap in asserted snaps directory already exists, it is
+	// not copied over
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
+		infoGetter, snapWriteObserver, nil)
+	// all missing snaps are reported at once
+	c.Assert(err, ErrorMatches, `snaps not present:
+- essential snap "pc"
+- non-essential but required snap "other-required"`)
+	var missingErr *devicestate.MissingModelSnapsError
+	c.Assert(errors.As(err, &missingErr), Equals, true)
+	c.Check(missingErr.Snaps, DeepEquals, []string{"pc", "other-required"})
+	c.Check(dir, Equals, "")
+	c.Check(observerCalls, Equals, 0)
+
+	// the directory shouldn't be there, as we haven't written anything yet
+	c.Check(osutil.IsDirectory(systemDir), Equals, false)
+
+	// create the info now
+	infos["pc"] = s.makeSnap(c, "pc", snap.R(2))
+
+	// and try with with a non essential snap
+	dir, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
+		infoGetter, snapWriteObserver, nil)
+	c.Assert(err, ErrorMatches, `non-essential but required snap "other-required" not present`)
+	c.Assert(errors.As(err, &missingErr), Equals, true)
+	c.Check(missingErr.Snaps, DeepEquals, []string{"other-required"})
+	c.Check(dir, Equals, "")
+	c.Check(observerCalls, Equals, 0)
+	// the directory shouldn't be there, as we haven't written anything yet
+	c.Check(osutil.IsDirectory(systemDir), Equals, false)
+
+	// create the info now
+	infos["other-required"] = s.makeSnap(c, "other-required", snap.R(5))
+
+	// but change the file contents of 'pc' snap so that it no longer
+	// matches its assertions
+	randomSnap := snaptest.MakeTestSnapWithFiles(c, `name: random
+version: 1`, nil)
+	c.Assert(osutil.CopyFile(randomSnap, infos["pc"].MountFile(), osutil.CopyFlagOverwrite), IsNil)
+	dir, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
+		infoGetter, snapWriteObserver, nil)
+	c.Assert(err, ErrorMatches, `cannot verify snap files against their assertions:
+- snap "pc" \(revision 2\): snap file (size|digest) .*`)
+	// the problem is caught before the system directory is created
+	c.Check(dir, Equals, "")
+	c.Check(osutil.IsDirectory(systemDir), Equals, false)
+	c.Check(observerCalls, Equals, 0)
+}
+
+func (s *createSystemSuite) TestCreateSystemSnapFilesMismatchAssertions(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	snapWriteObserver := func(dir, where string) error {
+		c.Fatalf("unexpected call")
+		return nil
+	}
+
+	// corrupt the kernel snap file, keeping its size
+	content, err := ioutil.ReadFile(infos["pc-kernel"].MountFile())
+	c.Assert(err, IsNil)
+	content[len(content)-1] ^= 0xff
+	c.Assert(ioutil.WriteFile(infos["pc-kernel"].MountFile(), content, 0644), IsNil)
+	// truncate the gadget snap file
+	c.Assert(os.Truncate(infos["pc"].MountFile(), 10), IsNil)
+	// and the snapd snap has no snap-revision assertion for its revision
+	snapdFile := infos["snapd"].MountFile()
+	infos["snapd"].Revision = snap.R(40)
+	c.Assert(os.Rename(snapdFile, infos["snapd"].MountFile()), IsNil)
+
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
+		infoGetter, snapWriteObserver, nil)
+	c.Assert(err, ErrorMatches, `cannot verify snap files against their assertions:
+- snap "snapd" \(revision 40\): cannot find snap-revision assertion
+- snap "pc-kernel" \(revision 1\): snap file digest does not match the asserted digest
+- snap "pc" \(revision 2\): snap file size 10 does not match the asserted size [0-9]+`)
+	c.Check(dir, Equals, "")
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps"), testutil.FileAbsent)
+}
+
+func (s *createSystemSuite) TestCreateSystemReusesKnownSnapDigests(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+
+	// the digest of the kernel snap is known, and is used instead of
+	// computing it from the file
+	infos["pc-kernel"].Sha3_384 = "bogus"
+	_, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
+		infoGetter, nil, nil)
+	c.Assert(err, ErrorMatches, `cannot verify snap files against their assertions:
+- snap "pc-kernel" \(revision 1\): snap file digest does not match the asserted digest`)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+
+	for _, info := range infos {
+		digest, _, err := asserts.SnapFileSHA3_384(info.MountFile())
+		c.Assert(err, IsNil)
+		info.Sha3_384 = digest
+	}
+	_, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
+		infoGetter, nil, nil)
+	c.Assert(err, IsNil)
+	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted)
+}
+
+func (s *createSystemSuite) TestCreateSystemWithComponents(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+	infos["pc-kernel"].Components = map[string]snap.Component{
+		"kmod":       {Type: snap.TestComponent},
+		"local-kmod": {Type: snap.TestComponent},
+	}
+
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	expectedDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
+
+	compsDir := c.MkDir()
+	kmodFn := filepath.Join(compsDir, "pc-kernel+kmod.comp")
+	c.Assert(os.WriteFile(kmodFn, []byte("kmod"), 0644), IsNil)
+	localKmodFn := filepath.Join(compsDir, "pc-kernel+local-kmod.comp")
+	c.Assert(os.WriteFile(localKmodFn, []byte("local-kmod"), 0644), IsNil)
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	var compsErr error
+	componentsGetter := func(info *snap.Info, snapPath string) ([]*seedwriter.SeedComponent, error) {
+		c.Check(snapPath, Equals, info.MountFile())
+		if info.SnapName() != "pc-kernel" {
+			return nil, nil
+		}
+		return []*seedwriter.SeedComponent{
+			{Name: "kmod", Path: kmodFn},
+			{Name: "local-kmod", Path: localKmodFn},
+		}, compsErr
+	}
+	var newFiles []string
+	snapWriteObserver := func(dir, where string) error {
+		c.Check(dir, Equals, expectedDir)
+		newFiles = append(newFiles, where)
+		return nil
+	}
+
+	compsErr = fmt.Errorf("mock failure")
+	_, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver, &devicestate.CreateSystemOptions{
+		GetComponents: componentsGetter,
+	})
+	c.Assert(err, ErrorMatches, `cannot obtain components of essential snap "pc-kernel": mock failure`)
+	c.Check(expectedDir, testutil.FileAbsent)
+	c.Check(newFiles, HasLen, 0)
+
+	compsErr = nil
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver, &devicestate.CreateSystemOptions{
+		GetComponents: componentsGetter,
+	})
+	c.Assert(err, IsNil)
+	c.Check(dir, Equals, expectedDir)
+	// component files are written along with their snaps, in the
+	// directory of the system
+	c.Check(newFiles, DeepEquals, []string{
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap"),
+		filepath.Join(expectedDir, "snaps/pc-kernel+kmod_1.0.comp"),
+		filepath.Join(expectedDir, "snaps/pc-kernel+local-kmod_1.0.comp"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_3.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_2.snap"),
+	})
+	c.Check(filepath.Join(expectedDir, "snaps/pc-kernel+kmod_1.0.comp"), testutil.FileEquals, "kmod")
+	c.Check(filepath.Join(expectedDir, "snaps/pc-kernel+local-kmod_1.0.comp"), testutil.FileEquals, "local-kmod")
+	// the recovery kernel is still the kernel snap
+	c.Check(bl.RecoverySystemBootVars, DeepEquals, map[string]string{
+		"snapd_full_cmdline_args":  "",
+		"snapd_extra_cmdline_args": "args from gadget",
+		"snapd_recovery_kernel":    "/snaps/pc-kernel_1.snap",
+	})
+
+	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted)
+	// the seed references the components
+	const usesSnapd = true
+	sd := seedtest.ValidateSeed(c, boot.InitramfsUbuntuSeedDir, "1234", usesSnapd, s.storeSigning.Trusted)
+	var kernel *seed.Snap
+	for _, sn := range sd.EssentialSnaps() {
+		if sn.EssentialType == snap.TypeKernel {
+			kernel = sn
+		}
+	}
+	c.Assert(kernel, NotNil)
+	c.Check(kernel.Path, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap"))
+	c.Check(kernel.Components, DeepEquals, []seed.Component{
+		{Name: "kmod", Path: filepath.Join(expectedDir, "snaps/pc-kernel+kmod_1.0.comp")},
+		{Name: "local-kmod", Path: filepath.Join(expectedDir, "snaps/pc-kernel+local-kmod_1.0.comp")},
+	})
+}
+
+func (s *createSystemSuite) TestSeededSnapComponents(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+	infos["pc-kernel"].Components = map[string]snap.Component{
+		"kmod": {Type: snap.TestComponent},
+	}
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	kmodFn := filepath.Join(c.MkDir(), "pc-kernel+kmod.comp")
+	c.Assert(os.WriteFile(kmodFn, []byte("kmod"), 0644), IsNil)
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	componentsGetter := func(info *snap.Info, snapPath string) ([]*seedwriter.SeedComponent, error) {
+		if info.SnapName() != "pc-kernel" {
+			return nil, nil
+		}
+		return []*seedwriter.SeedComponent{{Name: "kmod", Path: kmodFn}}, nil
+	}
+	_, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1111", s.db, infoGetter, nil, &devicestate.CreateSystemOptions{
+		GetComponents: componentsGetter,
+	})
+	c.Assert(err, IsNil)
+	s.AddCleanup(seed.MockTrusted(s.storeSigning.Trusted))
+
+	getComps := devicestate.SeededSnapComponents(boot.InitramfsUbuntuSeedDir, "1111")
+	// the seeded revision of the kernel gets the seeded components
+	comps, err := getComps(infos["pc-kernel"], infos["pc-kernel"].MountFile())
+	c.Assert(err, IsNil)
+	c.Check(comps, DeepEquals, []*seedwriter.SeedComponent{{
+		Name: "kmod",
+		Path: filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1111/snaps/pc-kernel+kmod_1.0.comp"),
+	}})
+	// snaps without components have none
+	comps, err = getComps(infos["pc"], infos["pc"].MountFile())
+	c.Assert(err, IsNil)
+	c.Check(comps, HasLen, 0)
+	// neither does a different revision of the kernel
+	refreshed := *infos["pc-kernel"]
+	refreshed.Revision = snap.R(2)
+	comps, err = getComps(&refreshed, refreshed.MountFile())
+	c.Assert(err, IsNil)
+	c.Check(comps, HasLen, 0)
+
+	// the seed system is gone
+	getComps = devicestate.SeededSnapComponents(boot.InitramfsUbuntuSeedDir, "2222")
+	comps, err = getComps(infos["pc-kernel"], infos["pc-kernel"].MountFile())
+	c.Assert(err, IsNil)
+	c.Check(comps, HasLen, 0)
+}
+
+func (s *createSystemSuite) TestCreateSystemGetInfoErr(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	// missing info for the pc snap
+	infos := s.makeEssentialSnapInfos(c)
+	infos["other-required"] = s.makeSnap(c, "other-required", snap.R(5))
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			// pc snap is the gadget, but we have no info for it
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name": "snapd",
+				"id":   s.ss.AssertedSnapID("snapd"),
+				"type": "snapd",
+			},
+			map[string]interface{}{
+				"name":     "other-required",
+				"id":       s.ss.AssertedSnapID("other-required"),
+				"presence": "required",
+			},
+		},
+	})
+
+	failOn := map[string]bool{}
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		c.Logf("called for: %q", sn.SnapName())
+		if failOn[sn.SnapName()] {
+			return nil, false, fmt.Errorf("mock failure for snap %q", sn.SnapName())
+		}
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	var observerCalls int
+	snapWriteObserver := func(dir, where string) error {
+		observerCalls++
+		return fmt.Errorf("unexpected call")
+	}
+
+	systemDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
+
+	// when a given snap in asserted snaps directory already exists, it is
+	// not copied over
+
+	failOn["pc"] = true
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
+		infoGetter, snapWriteObserver, nil)
+	c.Assert(err, ErrorMatches, `cannot obtain essential snap information: mock failure for snap "pc"`)
+	c.Check(dir, Equals, "")
+	c.Check(observerCalls, Equals, 0)
+	c.Check(osutil.IsDirectory(systemDir), Equals, false)
+
 	failOn["pc"] = false
 	failOn["other-required"] = true
 	dir, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
-		infoGetter, snapWriteObserver)
+		infoGetter, snapWriteObserver, nil)
 	c.Assert(err, ErrorMatches, `cannot obtain non-essential but required snap information: mock failure for snap "other-required"`)
 	c.Check(dir, Equals, "")
-	c.Check(observerCalls, Equals, 0)
-	c.Check(osutil.IsDirectory(systemDir), Equals, false)
+	c.Check(observerCalls, Equals, 0)
+	c.Check(osutil.IsDirectory(systemDir), Equals, false)
+}
+
+func (s *createSystemSuite) TestCreateSystemNonUC20(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"base":         "core18",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+	})
+
+	infoGetter := func(*asserts.ModelSnap) (*snap.Info, bool, error) {
+		c.Fatalf("unexpected call")
+		return nil, false, fmt.Errorf("unexpected call")
+	}
+	snapWriteObserver := func(dir, where string) error {
+		c.Fatalf("unexpected call")
+		return fmt.Errorf("unexpected call")
+	}
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
+		infoGetter, snapWriteObserver, nil)
+	c.Assert(err, ErrorMatches, `cannot create a system for pre-UC20 model`)
+	c.Check(dir, Equals, "")
+}
+
+func (s *createSystemSuite) TestCreateSystemImplicitSnaps(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+
+	// snapd snap is implicitly required
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		// base does not need to be listed among snaps
+		"base": "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	expectedDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		c.Logf("called for: %q", sn.SnapName())
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	var newFiles []string
+	snapWriteObserver := func(dir, where string) error {
+		c.Check(dir, Equals, expectedDir)
+		newFiles = append(newFiles, where)
+		return nil
+	}
+
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
+		infoGetter, snapWriteObserver, nil)
+	c.Assert(err, IsNil)
+	c.Check(newFiles, DeepEquals, []string{
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_3.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_2.snap"),
+	})
+	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"))
+	// validate the seed
+	validateCore20Seed(c, "1234", model, s.ss.StoreSigning.Trusted)
+}
+
+func (s *createSystemSuite) TestCreateSystemObserverErr(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+
+	// snapd snap is implicitly required
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		// base does not need to be listed among snaps
+		"base": "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	var newFiles []string
+	snapWriteObserver := func(dir, where string) error {
+		newFiles = append(newFiles, where)
+		if strings.HasSuffix(where, "/core20_3.snap") {
+			return fmt.Errorf("mocked observer failure")
+		}
+		return nil
+	}
+
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
+		infoGetter, snapWriteObserver, nil)
+	c.Assert(err, ErrorMatches, "mocked observer failure")
+	c.Check(newFiles, DeepEquals, []string{
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap"),
+		// we failed on this one
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_3.snap"),
+	})
+	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"))
+}
+
+func (s *createSystemSuite) TestCreateSystemProgress(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+
+	// snap files on different filesystems are copied
+	restore := devicestate.MockOsLink(func(oldname, newname string) error {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EXDEV}
+	})
+	defer restore()
+
+	// the state is only unlocked for copying the snaps
+	unlocked := false
+	unlocks := 0
+	unlocker := func() (relock func()) {
+		unlocks++
+		unlocked = true
+		relockState := s.state.Unlocker()()
+		return func() {
+			relockState()
+			unlocked = false
+		}
+	}
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		c.Check(unlocked, Equals, false)
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	type progressCall struct {
+		copied int64
+		total  int64
+	}
+	// snaps are copied in parallel, collect the calls for each snap
+	var mu sync.Mutex
+	calls := make(map[string][]progressCall)
+	opts := &devicestate.CreateSystemOptions{
+		Progress: func(name string, copied, total int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			c.Check(unlocked, Equals, true)
+			calls[name] = append(calls[name], progressCall{copied, total})
+		},
+		Unlocker:     unlocker,
+		VerifyCopies: true,
+	}
+
+	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
+		infoGetter, nil, opts)
+	c.Assert(err, IsNil)
+	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"))
+	c.Check(unlocks, Equals, 1)
+	c.Check(unlocked, Equals, false)
+
+	// the progress of each snap is reported, starting at 0 and finishing
+	// with the full size of the file
+	var names []string
+	for name, snapCalls := range calls {
+		names = append(names, name)
+		fi, err := os.Stat(infos[name].MountFile())
+		c.Assert(err, IsNil)
+		c.Assert(snapCalls, Not(HasLen), 0)
+		for _, call := range snapCalls {
+			c.Check(call.copied <= call.total, Equals, true)
+			c.Check(call.total, Equals, fi.Size())
+		}
+		c.Check(snapCalls[0].copied, Equals, int64(0))
+		c.Check(snapCalls[len(snapCalls)-1].copied, Equals, fi.Size())
+	}
+	sort.Strings(names)
+	c.Check(names, DeepEquals, []string{"core20", "pc", "pc-kernel", "snapd"})
+	for _, name := range []string{"snapd_4.snap", "pc-kernel_1.snap", "core20_3.snap", "pc_2.snap"} {
+		c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps", name), testutil.FilePresent)
+	}
+}
+
+func (s *createSystemSuite) TestCreateSystemCancelledMidCopy(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+
+	// snap files on different filesystems are copied
+	restore := devicestate.MockOsLink(func(oldname, newname string) error {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EXDEV}
+	})
+	defer restore()
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	var written []string
+	observeWrite := func(dir, where string) error {
+		written = append(written, where)
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	copies := 0
+	opts := &devicestate.CreateSystemOptions{
+		Progress: func(name string, copied, total int64) {
+			copies++
+			// the change gets aborted as soon as the first copy
+			// starts
+			cancel()
+		},
+		CopyWorkers: 1,
+		Context:     ctx,
+	}
+
+	_, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
+		infoGetter, observeWrite, opts)
+	c.Assert(err, ErrorMatches, `unable to copy .* to .*: context canceled`)
+	// no other copies were started
+	c.Check(copies, Equals, 1)
+	c.Assert(written, HasLen, 4)
+	// and the partially copied file was removed
+	for _, where := range written {
+		c.Check(where, testutil.FileAbsent)
+	}
+}
+
+func (s *createSystemSuite) testCreateSystemLinkOrCopy(c *C, linkErr error) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+	infos["other-unasserted"] = s.makeSnap(c, "other-unasserted", snap.R(-1))
+
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":     "other-unasserted",
+				"presence": "required",
+			},
+		},
+	})
+
+	var mu sync.Mutex
+	linkCalls := 0
+	restore := devicestate.MockOsLink(func(oldname, newname string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		linkCalls++
+		if linkErr != nil {
+			return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: linkErr}
+		}
+		return os.Link(oldname, newname)
+	})
+	defer restore()
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	var newFiles []string
+	snapWriteObserver := func(dir, where string) error {
+		newFiles = append(newFiles, where)
+		return nil
+	}
+
+	_, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver, nil)
+	c.Assert(err, IsNil)
+	expectedFiles := map[string]string{
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap"):                           infos["snapd"].MountFile(),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap"):                       infos["pc-kernel"].MountFile(),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_3.snap"):                          infos["core20"].MountFile(),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_2.snap"):                              infos["pc"].MountFile(),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234/snaps/other-unasserted_1.0.snap"): infos["other-unasserted"].MountFile(),
+	}
+	c.Check(linkCalls, Equals, len(expectedFiles))
+	// all new files are reported, whether they were linked or copied
+	c.Check(newFiles, HasLen, len(expectedFiles))
+	for _, dst := range newFiles {
+		src, ok := expectedFiles[dst]
+		c.Assert(ok, Equals, true, Commentf("unexpected file %q", dst))
+		c.Check(dst, testutil.FileEquals, testutil.FileContentRef(src))
+		srcFi, err := os.Stat(src)
+		c.Assert(err, IsNil)
+		dstFi, err := os.Stat(dst)
+		c.Assert(err, IsNil)
+		c.Check(os.SameFile(srcFi, dstFi), Equals, linkErr == nil)
+	}
+	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted, "other-unasserted")
+}
+
+func (s *createSystemSuite) TestCreateSystemLinksSnaps(c *C) {
+	s.testCreateSystemLinkOrCopy(c, nil)
+}
+
+func (s *createSystemSuite) TestCreateSystemCopiesSnapsAcrossDevices(c *C) {
+	s.testCreateSystemLinkOrCopy(c, syscall.EXDEV)
+}
+
+func (s *createSystemSuite) TestCreateSystemCopiesSnapsWhenLinksUnsupported(c *C) {
+	// eg. vfat does not support hard links
+	s.testCreateSystemLinkOrCopy(c, syscall.EPERM)
+}
+
+func (s *createSystemSuite) testCreateSystemVerifyCopies(c *C, corruptAttempts int) (newFiles []string, infos map[string]*snap.Info, model *asserts.Model, err error) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos = s.makeEssentialSnapInfos(c)
+	infos["other-unasserted"] = s.makeSnap(c, "other-unasserted", snap.R(-1))
+
+	model = s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":     "other-unasserted",
+				"presence": "required",
+			},
+		},
+	})
+
+	// the written files get corrupted, eg. by faulty storage
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	restore := devicestate.MockOsLink(func(oldname, newname string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[newname]++
+		if attempts[newname] <= corruptAttempts {
+			return ioutil.WriteFile(newname, []byte("corrupted"), 0644)
+		}
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EXDEV}
+	})
+	defer restore()
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	snapWriteObserver := func(dir, where string) error {
+		newFiles = append(newFiles, where)
+		return nil
+	}
+
+	_, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver,
+		&devicestate.CreateSystemOptions{VerifyCopies: true})
+	return newFiles, infos, model, err
+}
+
+func (s *createSystemSuite) TestCreateSystemVerifyCopiesRetries(c *C) {
+	newFiles, infos, model, err := s.testCreateSystemVerifyCopies(c, 1)
+	c.Assert(err, IsNil)
+
+	expectedFiles := map[string]string{
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap"):                           infos["snapd"].MountFile(),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap"):                       infos["pc-kernel"].MountFile(),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_3.snap"):                          infos["core20"].MountFile(),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_2.snap"):                              infos["pc"].MountFile(),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234/snaps/other-unasserted_1.0.snap"): infos["other-unasserted"].MountFile(),
+	}
+	// each file is reported once even though it was written twice
+	c.Check(newFiles, HasLen, len(expectedFiles))
+	for _, dst := range newFiles {
+		src, ok := expectedFiles[dst]
+		c.Assert(ok, Equals, true, Commentf("unexpected file %q", dst))
+		c.Check(dst, testutil.FileEquals, testutil.FileContentRef(src))
+	}
+	c.Check(s.logbuf.String(), testutil.Contains, `cannot verify copy of snap "pc-kernel", retrying: digest of `)
+	c.Check(s.logbuf.String(), testutil.Contains, `cannot verify copy of snap "other-unasserted", retrying: digest of `)
+	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted, "other-unasserted")
+}
+
+func (s *createSystemSuite) TestCreateSystemVerifyCopiesGivesUp(c *C) {
+	newFiles, _, _, err := s.testCreateSystemVerifyCopies(c, 2)
+	c.Assert(err, ErrorMatches, `cannot verify copy of snap "snapd": digest of .*/snaps/snapd_4.snap does not match the expected digest`)
+
+	// the file which failed verification is reported, such that the caller
+	// can clean it up, so are the files of copies which were never completed
+	corrupted := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap")
+	c.Check(newFiles, DeepEquals, []string{
+		corrupted,
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_3.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_2.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234/snaps/other-unasserted_1.0.snap"),
+	})
+	c.Check(corrupted, testutil.FileEquals, "corrupted")
+}
+
+func (s *createSystemSuite) TestCopySnapFilesWorkers(c *C) {
+	names := []string{"a", "b", "c", "d", "e"}
+	for _, workers := range []int{1, 2, 3} {
+		var mu sync.Mutex
+		running, maxRunning := 0, 0
+		var copied []string
+		err := devicestate.CopySnapFiles(names, workers, func(name string) error {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			copied = append(copied, name)
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		})
+		c.Assert(err, IsNil)
+		sort.Strings(copied)
+		c.Check(copied, DeepEquals, names)
+		c.Check(maxRunning <= workers, Equals, true, Commentf("workers: %v, max running: %v", workers, maxRunning))
+	}
+}
+
+func (s *createSystemSuite) TestCopySnapFilesStopsOnError(c *C) {
+	var copied []string
+	err := devicestate.CopySnapFiles([]string{"a", "b", "c"}, 1, func(name string) error {
+		copied = append(copied, name)
+		if name == "b" {
+			return fmt.Errorf("failed %s", name)
+		}
+		return nil
+	})
+	c.Assert(err, ErrorMatches, "failed b")
+	// no new copies are started after a failure
+	c.Check(copied, DeepEquals, []string{"a", "b"})
+}
+
+func (s *createSystemSuite) TestCopySnapFilesFirstErrorInOrder(c *C) {
+	bFailed := make(chan struct{})
+	err := devicestate.CopySnapFiles([]string{"a", "b"}, 2, func(name string) error {
+		switch name {
+		case "a":
+			// fail only once b has failed
+			<-bFailed
+		case "b":
+			defer close(bFailed)
+		}
+		return fmt.Errorf("failed %s", name)
+	})
+	// the error is deterministic
+	c.Assert(err, ErrorMatches, "failed a")
+}
+
+func (s *createSystemSuite) testCreateSystemDownload(c *C, downloader func(name, channel string) (string, *snap.Info, error)) (newFiles []string, model *asserts.Model, err error) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	// only some of the snaps are present
+	infos := map[string]*snap.Info{
+		"pc-kernel": s.makeSnap(c, "pc-kernel", snap.R(1)),
+		"snapd":     s.makeSnap(c, "snapd", snap.R(4)),
+	}
+
+	model = s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20/edge",
+			},
+		},
+	})
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	snapWriteObserver := func(dir, where string) error {
+		newFiles = append(newFiles, where)
+		return nil
+	}
+	opts := &devicestate.CreateSystemOptions{
+		Downloader: downloader,
+	}
+	_, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, snapWriteObserver, opts)
+	return newFiles, model, err
+}
+
+func (s *createSystemSuite) TestCreateSystemDownloadsMissingSnaps(c *C) {
+	seedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
+	otherDir := c.MkDir()
+	var downloads []string
+	downloader := func(name, channel string) (string, *snap.Info, error) {
+		downloads = append(downloads, fmt.Sprintf("%s:%s", name, channel))
+		var info *snap.Info
+		var where string
+		switch name {
+		case "pc":
+			// downloaded straight to the seed
+			info = s.makeSnap(c, name, snap.R(2))
+			where = filepath.Join(seedSnapsDir, "pc_2.snap")
+		case "core20":
+			// downloaded somewhere else
+			info = s.makeSnap(c, name, snap.R(3))
+			where = filepath.Join(otherDir, "core20.snap")
+		default:
+			return "", nil, fmt.Errorf("unexpected download of %q", name)
+		}
+		c.Assert(os.MkdirAll(filepath.Dir(where), 0755), IsNil)
+		c.Assert(os.Rename(info.MountFile(), where), IsNil)
+		return where, info, nil
+	}
+
+	newFiles, model, err := s.testCreateSystemDownload(c, downloader)
+	c.Assert(err, IsNil)
+	// snaps are downloaded from the default channel in the model
+	c.Check(downloads, DeepEquals, []string{"core20:latest/stable", "pc:20/edge"})
+	// all files are reported, including the one which was downloaded in
+	// place
+	c.Check(newFiles, DeepEquals, []string{
+		filepath.Join(seedSnapsDir, "snapd_4.snap"),
+		filepath.Join(seedSnapsDir, "pc-kernel_1.snap"),
+		filepath.Join(seedSnapsDir, "core20_3.snap"),
+		filepath.Join(seedSnapsDir, "pc_2.snap"),
+	})
+	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted)
+	// the snap downloaded outside of the seed was removed once copied
+	c.Check(filepath.Join(otherDir, "core20.snap"), testutil.FileAbsent)
+}
+
+func (s *createSystemSuite) testCreateSystemDownloadSharedSnap(c *C, corrupt bool) (newFiles []string, model *asserts.Model, err error) {
+	seedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
+	sharedPc := filepath.Join(seedSnapsDir, "pc_2.snap")
+	var pcInfo *snap.Info
+	downloader := func(name, channel string) (string, *snap.Info, error) {
+		switch name {
+		case "core20":
+			info := s.makeSnap(c, name, snap.R(3))
+			where := filepath.Join(seedSnapsDir, "core20_3.snap")
+			c.Assert(os.MkdirAll(seedSnapsDir, 0755), IsNil)
+			c.Assert(os.Rename(info.MountFile(), where), IsNil)
+			if corrupt {
+				f, err := os.OpenFile(where, os.O_WRONLY|os.O_APPEND, 0644)
+				c.Assert(err, IsNil)
+				_, err = f.Write([]byte("garbage"))
+				c.Assert(err, IsNil)
+				c.Assert(f.Close(), IsNil)
+			}
+			// meanwhile, the revision of the gadget is shared
+			// by other systems
+			pcInfo = s.makeSnap(c, "pc", snap.R(2))
+			c.Assert(os.Rename(pcInfo.MountFile(), sharedPc), IsNil)
+			return where, info, nil
+		case "pc":
+			// the download reuses the shared file
+			return sharedPc, pcInfo, nil
+		default:
+			return "", nil, fmt.Errorf("unexpected download of %q", name)
+		}
+	}
+	return s.testCreateSystemDownload(c, downloader)
+}
+
+func (s *createSystemSuite) TestCreateSystemDownloadedSharedSnapNotOwned(c *C) {
+	seedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
+	const corrupt = false
+	newFiles, model, err := s.testCreateSystemDownloadSharedSnap(c, corrupt)
+	c.Assert(err, IsNil)
+	// the shared snap is not reported as a new file, such that it is not
+	// removed when the system is removed or its creation is undone
+	c.Check(newFiles, DeepEquals, []string{
+		filepath.Join(seedSnapsDir, "snapd_4.snap"),
+		filepath.Join(seedSnapsDir, "pc-kernel_1.snap"),
+		filepath.Join(seedSnapsDir, "core20_3.snap"),
+	})
+	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted)
+}
+
+func (s *createSystemSuite) TestCreateSystemDownloadedSharedSnapKeptOnError(c *C) {
+	seedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
+	const corrupt = true
+	newFiles, _, err := s.testCreateSystemDownloadSharedSnap(c, corrupt)
+	c.Assert(err, ErrorMatches, `(?s)cannot verify snap files against their assertions:.*`)
+	c.Check(newFiles, HasLen, 0)
+	// the shared snap is kept, while the new download is removed
+	c.Check(filepath.Join(seedSnapsDir, "pc_2.snap"), testutil.FilePresent)
+	c.Check(filepath.Join(seedSnapsDir, "core20_3.snap"), testutil.FileAbsent)
+}
+
+func (s *createSystemSuite) TestCreateSystemDownloadError(c *C) {
+	downloader := func(name, channel string) (string, *snap.Info, error) {
+		return "", nil, fmt.Errorf("store is offline")
+	}
+	newFiles, _, err := s.testCreateSystemDownload(c, downloader)
+	c.Assert(err, ErrorMatches, `cannot download essential snap "core20": store is offline`)
+	c.Check(newFiles, HasLen, 0)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+}
+
+func (s *createSystemSuite) TestCreateSystemDownloadedSnapsRemovedOnError(c *C) {
+	seedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
+	downloader := func(name, channel string) (string, *snap.Info, error) {
+		rev := snap.R(2)
+		if name == "core20" {
+			rev = snap.R(3)
+		}
+		info := s.makeSnap(c, name, rev)
+		where := filepath.Join(seedSnapsDir, filepath.Base(info.MountFile()))
+		c.Assert(os.MkdirAll(seedSnapsDir, 0755), IsNil)
+		c.Assert(os.Rename(info.MountFile(), where), IsNil)
+		if name == "core20" {
+			// the download got corrupted
+			f, err := os.OpenFile(where, os.O_WRONLY|os.O_APPEND, 0644)
+			c.Assert(err, IsNil)
+			_, err = f.Write([]byte("garbage"))
+			c.Assert(err, IsNil)
+			c.Assert(f.Close(), IsNil)
+		}
+		return where, info, nil
+	}
+	newFiles, _, err := s.testCreateSystemDownload(c, downloader)
+	c.Assert(err, ErrorMatches, `(?s)cannot verify snap files against their assertions:
+- snap "core20" \(revision 3\): snap file size .* does not match the asserted size .*`)
+	c.Check(newFiles, HasLen, 0)
+	// the downloaded snaps were not reported, and are removed
+	c.Check(filepath.Join(seedSnapsDir, "pc_2.snap"), testutil.FileAbsent)
+	c.Check(filepath.Join(seedSnapsDir, "core20_3.snap"), testutil.FileAbsent)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+}
+
+func (s *createSystemSuite) TestRemoveRecoverySystemSharedSnaps(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+	infos["other-present"] = s.makeSnap(c, "other-present", snap.R(5))
+
+	essentialSnaps := []interface{}{
+		map[string]interface{}{
+			"name":            "pc-kernel",
+			"id":              s.ss.AssertedSnapID("pc-kernel"),
+			"type":            "kernel",
+			"default-channel": "20",
+		},
+		map[string]interface{}{
+			"name":            "pc",
+			"id":              s.ss.AssertedSnapID("pc"),
+			"type":            "gadget",
+			"default-channel": "20",
+		},
+	}
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps":        essentialSnaps,
+	})
+	modelWithOther := s.brands.Model("my-brand", "pc-with-other", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": append(essentialSnaps, map[string]interface{}{
+			"name":     "other-present",
+			"id":       s.ss.AssertedSnapID("other-present"),
+			"presence": "optional",
+		}),
+	})
+	c.Assert(s.db.Add(modelWithOther), IsNil)
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand: "my-brand",
+		Model: "pc",
+	})
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	_, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1111", s.db, infoGetter, nil, nil)
+	c.Assert(err, IsNil)
+	_, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "2222", s.db, infoGetter, nil, nil)
+	c.Assert(err, IsNil)
+	_, err = devicestate.CreateSystemForModelFromValidatedSnaps(modelWithOther, "3333", s.db, infoGetter, nil, nil)
+	c.Assert(err, IsNil)
+
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		RecoverySystem:         "1111",
+		CurrentRecoverySystems: []string{"1111", "2222", "3333"},
+		GoodRecoverySystems:    []string{"1111", "2222", "3333"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+	// seeds are loaded with the trusted assertions
+	s.AddCleanup(seed.MockTrusted(s.storeSigning.Trusted))
+
+	seedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
+	otherSnap := filepath.Join(seedSnapsDir, "other-present_5.snap")
+	c.Check(otherSnap, testutil.FilePresent)
+
+	// the system the device was seeded from cannot be removed
+	err = devicestate.RemoveRecoverySystem(s.state, "1111")
+	c.Assert(err, ErrorMatches, `cannot remove recovery system "1111": system was used to seed the device`)
+	// and neither can a system which does not exist
+	err = devicestate.RemoveRecoverySystem(s.state, "9999")
+	c.Assert(err, ErrorMatches, `cannot remove recovery system "9999": system does not exist`)
+
+	err = devicestate.RemoveRecoverySystem(s.state, "3333")
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/3333"), testutil.FileAbsent)
+	// snap only used by the removed system is gone
+	c.Check(otherSnap, testutil.FileAbsent)
+	// but the snaps shared with other systems are still there
+	for _, name := range []string{"snapd_4.snap", "pc-kernel_1.snap", "core20_3.snap", "pc_2.snap"} {
+		c.Check(filepath.Join(seedSnapsDir, name), testutil.FilePresent)
+	}
+
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.CurrentRecoverySystems, DeepEquals, []string{"1111", "2222"})
+	c.Check(m.GoodRecoverySystems, DeepEquals, []string{"1111", "2222"})
+
+	// removing a system which shares all of its snaps, does not remove any
+	err = devicestate.RemoveRecoverySystem(s.state, "2222")
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/2222"), testutil.FileAbsent)
+	for _, name := range []string{"snapd_4.snap", "pc-kernel_1.snap", "core20_3.snap", "pc_2.snap"} {
+		c.Check(filepath.Join(seedSnapsDir, name), testutil.FilePresent)
+	}
+	validateCore20Seed(c, "1111", model, s.storeSigning.Trusted)
+}
+
+func (s *createSystemSuite) TestListRecoverySystems(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+
+	essentialSnaps := []interface{}{
+		map[string]interface{}{
+			"name":            "pc-kernel",
+			"id":              s.ss.AssertedSnapID("pc-kernel"),
+			"type":            "kernel",
+			"default-channel": "20",
+		},
+		map[string]interface{}{
+			"name":            "pc",
+			"id":              s.ss.AssertedSnapID("pc"),
+			"type":            "gadget",
+			"default-channel": "20",
+		},
+	}
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps":        essentialSnaps,
+	})
+	otherModel := s.brands.Model("my-brand", "pc-other", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps":        essentialSnaps,
+	})
+	c.Assert(s.db.Add(otherModel), IsNil)
+
+	// no modeenv
+	_, err := devicestate.ListRecoverySystems()
+	c.Assert(err, ErrorMatches, "cannot list recovery systems: .*no such file or directory")
+
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		RecoverySystem:         "1111",
+		CurrentRecoverySystems: []string{"1111", "2222"},
+		GoodRecoverySystems:    []string{"1111"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	// no systems yet
+	_, err = devicestate.ListRecoverySystems()
+	c.Assert(err, Equals, devicestate.ErrNoSystems)
+
+	now := time.Date(2022, 5, 6, 7, 8, 9, 0, time.UTC)
+	s.AddCleanup(devicestate.MockTimeNow(func() time.Time { return now }))
+	s.AddCleanup(snapdtool.MockVersion("2.56"))
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	_, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1111", s.db, infoGetter, nil,
+		&devicestate.CreateSystemOptions{ChangeKind: "create-recovery-system", ChangeID: "12"})
+	c.Assert(err, IsNil)
+	extraSnap := snaptest.MakeTestSnapWithFiles(c, fmt.Sprintf(genericSnapYaml, "diagnostics", "base: core20"), nil)
+	_, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "2222", s.db, infoGetter, nil,
+		&devicestate.CreateSystemOptions{ExtraSnaps: []string{extraSnap}, ChangeKind: "remodel", ChangeID: "34"})
+	c.Assert(err, IsNil)
+	_, err = devicestate.CreateSystemForModelFromValidatedSnaps(otherModel, "3333", s.db, infoGetter, nil, nil)
+	c.Assert(err, IsNil)
+	// pretend the system was not created by snapd
+	c.Assert(os.Remove(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/3333/snapd-system-info")), IsNil)
+	// a broken system is skipped
+	c.Assert(os.MkdirAll(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/broken"), 0755), IsNil)
+	// seeds are loaded with the trusted assertions
+	s.AddCleanup(seed.MockTrusted(s.storeSigning.Trusted))
+
+	systems, err := devicestate.ListRecoverySystems()
+	c.Assert(err, IsNil)
+	// the disk usage is verified in TestListRecoverySystemsDiskUsage
+	for _, sys := range systems {
+		c.Check(sys.Usage.Size > 0, Equals, true)
+		c.Check(sys.Usage.SharedSize > 0, Equals, true)
+		sys.Usage = devicestate.RecoverySystemUsage{}
+		c.Assert(sys.Brand, NotNil)
+		c.Check(sys.Brand.AccountID(), Equals, "my-brand")
+		sys.Brand = nil
+	}
+	c.Check(systems, DeepEquals, []*devicestate.RecoverySystem{
+		{
+			Label:       "1111",
+			Model:       "pc",
+			BrandID:     "my-brand",
+			DisplayName: "pc",
+			Timestamp:   model.Timestamp(),
+			Current:     true,
+			Good:        true,
+			CreationInfo: &devicestate.RecoverySystemCreationInfo{
+				SnapdVersion: "2.56",
+				Created:      now,
+				ChangeKind:   "create-recovery-system",
+				ChangeID:     "12",
+			},
+		}, {
+			Label:              "2222",
+			Model:              "pc",
+			BrandID:            "my-brand",
+			DisplayName:        "pc",
+			Timestamp:          model.Timestamp(),
+			Current:            true,
+			HasUnassertedSnaps: true,
+			CreationInfo: &devicestate.RecoverySystemCreationInfo{
+				SnapdVersion:       "2.56",
+				Created:            now,
+				ChangeKind:         "remodel",
+				ChangeID:           "34",
+				HasUnassertedSnaps: true,
+			},
+		}, {
+			Label:       "3333",
+			Model:       "pc-other",
+			BrandID:     "my-brand",
+			DisplayName: "pc-other",
+			Timestamp:   otherModel.Timestamp(),
+		},
+	})
+	c.Check(s.logbuf.String(), testutil.Contains, `cannot load recovery system "broken"`)
+}
+
+// createSharingSystems creates recovery systems 1111 and 2222, which share all
+// of their snaps, and 3333, which additionally uses other-present.
+func (s *createSystemSuite) createSharingSystems(c *C, labels ...string) *asserts.Model {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+	infos["other-present"] = s.makeSnap(c, "other-present", snap.R(5))
+
+	essentialSnaps := []interface{}{
+		map[string]interface{}{
+			"name":            "pc-kernel",
+			"id":              s.ss.AssertedSnapID("pc-kernel"),
+			"type":            "kernel",
+			"default-channel": "20",
+		},
+		map[string]interface{}{
+			"name":            "pc",
+			"id":              s.ss.AssertedSnapID("pc"),
+			"type":            "gadget",
+			"default-channel": "20",
+		},
+	}
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps":        essentialSnaps,
+	})
+	modelWithOther := s.brands.Model("my-brand", "pc-with-other", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": append(essentialSnaps, map[string]interface{}{
+			"name":     "other-present",
+			"id":       s.ss.AssertedSnapID("other-present"),
+			"presence": "optional",
+		}),
+	})
+	c.Assert(s.db.Add(modelWithOther), IsNil)
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand: "my-brand",
+		Model: "pc",
+	})
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	for _, label := range labels {
+		m := model
+		if label == "3333" {
+			m = modelWithOther
+		}
+		_, err := devicestate.CreateSystemForModelFromValidatedSnaps(m, label, s.db, infoGetter, nil, nil)
+		c.Assert(err, IsNil)
+	}
+	// seeds are loaded with the trusted assertions
+	s.AddCleanup(seed.MockTrusted(s.storeSigning.Trusted))
+	return model
 }
 
-func (s *createSystemSuite) TestCreateSystemNonUC20(c *C) {
-	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
-	bootloader.Force(bl)
+func (s *createSystemSuite) TestListRecoverySystemsDiskUsage(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	model := s.createSharingSystems(c, "1111", "2222", "3333")
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		RecoverySystem:         "1111",
+		CurrentRecoverySystems: []string{"1111", "2222", "3333"},
+		GoodRecoverySystems:    []string{"1111", "2222", "3333"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	fileSize := func(path string) int64 {
+		fi, err := os.Stat(path)
+		c.Assert(err, IsNil)
+		return fi.Size()
+	}
+	systemSize := func(label string) int64 {
+		var size int64
+		err := filepath.Walk(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label), func(path string, fi os.FileInfo, err error) error {
+			c.Assert(err, IsNil)
+			if fi.Mode().IsRegular() {
+				size += fi.Size()
+			}
+			return nil
+		})
+		c.Assert(err, IsNil)
+		return size
+	}
+	seedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
+	var commonShare int64
+	for _, name := range []string{"snapd_4.snap", "pc-kernel_1.snap", "core20_3.snap", "pc_2.snap"} {
+		commonShare += fileSize(filepath.Join(seedSnapsDir, name)) / 3
+	}
+
+	systems, err := devicestate.ListRecoverySystems()
+	c.Assert(err, IsNil)
+	c.Assert(systems, HasLen, 3)
+	usage := make(map[string]devicestate.RecoverySystemUsage)
+	for _, sys := range systems {
+		usage[sys.Label] = sys.Usage
+	}
+	c.Check(usage, DeepEquals, map[string]devicestate.RecoverySystemUsage{
+		"1111": {Size: systemSize("1111"), SharedSize: commonShare},
+		"2222": {Size: systemSize("2222"), SharedSize: commonShare},
+		// the snap used only by the system is attributed to it in full
+		"3333": {Size: systemSize("3333"), SharedSize: commonShare + fileSize(filepath.Join(seedSnapsDir, "other-present_5.snap"))},
+	})
+	c.Check(usage["1111"].Total(), Equals, usage["1111"].Size+usage["1111"].SharedSize)
+}
+
+func (s *createSystemSuite) TestRemoveRecoverySystemDefault(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	model := s.createSharingSystems(c, "1111", "2222", "3333")
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		RecoverySystem:         "1111",
+		CurrentRecoverySystems: []string{"1111", "2222", "3333"},
+		GoodRecoverySystems:    []string{"1111", "2222", "3333"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+	bl, err := bootloader.Find(boot.InitramfsUbuntuSeedDir, &bootloader.Options{Role: bootloader.RoleRecovery})
+	c.Assert(err, IsNil)
+	c.Assert(bl.SetBootVars(map[string]string{"snapd_recovery_system": "2222"}), IsNil)
+
+	err = devicestate.RemoveRecoverySystem(s.state, "2222")
+	c.Assert(err, ErrorMatches, `cannot remove recovery system "2222": system is the default recovery system`)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/2222"), testutil.FilePresent)
+
+	var handlers []seed.SnapHandler
+	restore := devicestate.MockSeedOpen(func(seedDir, label string) (seed.Seed, error) {
+		// the state lock is not held while the systems are loaded
+		s.state.Lock()
+		s.state.Unlock()
+		sd, err := seed.Open(seedDir, label)
+		if err != nil {
+			return nil, err
+		}
+		return &recordingLoadMetaSeed{Seed: sd, handlers: &handlers}, nil
+	})
+	defer restore()
+
+	err = devicestate.RemoveRecoverySystem(s.state, "3333")
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/3333"), testutil.FileAbsent)
+	// the remaining systems were loaded, without hashing their snaps
+	c.Assert(handlers, HasLen, 2)
+	for _, h := range handlers {
+		c.Check(h, NotNil)
+	}
+}
+
+// recordingLoadMetaSeed records the snap handlers passed to LoadMeta.
+type recordingLoadMetaSeed struct {
+	seed.Seed
+	handlers *[]seed.SnapHandler
+}
+
+func (sd *recordingLoadMetaSeed) LoadMeta(mode string, handler seed.SnapHandler, tm timings.Measurer) error {
+	*sd.handlers = append(*sd.handlers, handler)
+	return sd.Seed.LoadMeta(mode, handler, tm)
+}
+
+func (s *createSystemSuite) TestPruneRecoverySystems(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	model := s.createSharingSystems(c, "1111", "2222", "3333", "4444", "5555")
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		CurrentRecoverySystems: []string{"1111", "2222", "3333", "4444", "5555"},
+		// 5555 is being tried
+		GoodRecoverySystems: []string{"1111", "2222", "3333", "4444"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+	// the device was originally seeded from 1111, and later remodeled
+	// with 2222
+	s.state.Set("seeded-systems", []devicestate.SeededSystem{
+		{System: "2222", Model: "pc", BrandID: "my-brand"},
+		{System: "1111", Model: "pc", BrandID: "my-brand"},
+	})
+
+	// 3333 is the default recovery system
+	bl, err := bootloader.Find(boot.InitramfsUbuntuSeedDir, &bootloader.Options{Role: bootloader.RoleRecovery})
+	c.Assert(err, IsNil)
+	c.Assert(bl.SetBootVars(map[string]string{"snapd_recovery_system": "3333"}), IsNil)
+
+	_, err = devicestate.PruneRecoverySystems(s.state, 0)
+	c.Assert(err, ErrorMatches, `cannot prune recovery systems: at least one good recovery system must be kept`)
+
+	seedSnapsDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps")
+	otherSnap := filepath.Join(seedSnapsDir, "other-present_5.snap")
+	c.Check(otherSnap, testutil.FilePresent)
+
+	removed, err := devicestate.PruneRecoverySystems(s.state, 1)
+	c.Assert(err, IsNil)
+	c.Check(removed, DeepEquals, []string{"2222"})
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/2222"), testutil.FileAbsent)
+	// the original system, the default one, the most recent good one and
+	// the one being tried remain
+	for _, label := range []string{"1111", "3333", "4444", "5555"} {
+		c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label), testutil.FilePresent)
+	}
+	c.Check(s.logbuf.String(), testutil.Contains, `not pruning recovery system "5555": cannot remove recovery system "5555": system has not been finalized yet`)
+	// the snap used by the default system is kept
+	c.Check(otherSnap, testutil.FilePresent)
+	for _, name := range []string{"snapd_4.snap", "pc-kernel_1.snap", "core20_3.snap", "pc_2.snap"} {
+		c.Check(filepath.Join(seedSnapsDir, name), testutil.FilePresent)
+	}
+
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.CurrentRecoverySystems, DeepEquals, []string{"1111", "3333", "4444", "5555"})
+	c.Check(m.GoodRecoverySystems, DeepEquals, []string{"1111", "3333", "4444"})
+
+	// nothing more to prune
+	removed, err = devicestate.PruneRecoverySystems(s.state, 1)
+	c.Assert(err, IsNil)
+	c.Check(removed, HasLen, 0)
+}
 
+func (s *createSystemSuite) TestRemoveRecoverySystemLastGood(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
 	s.setupBrands()
 	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
 		"architecture": "amd64",
-		"base":         "core18",
-		"kernel":       "pc-kernel",
-		"gadget":       "pc",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
 	})
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand: "my-brand",
+		Model: "pc",
+	})
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		RecoverySystem:         "1111",
+		CurrentRecoverySystems: []string{"1111", "2222"},
+		GoodRecoverySystems:    []string{"2222"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/2222"), 0755), IsNil)
 
-	infoGetter := func(name string) (*snap.Info, bool, error) {
-		c.Fatalf("unexpected call")
-		return nil, false, fmt.Errorf("unexpected call")
+	err := devicestate.RemoveRecoverySystem(s.state, "2222")
+	c.Assert(err, ErrorMatches, `cannot remove recovery system "2222": system is the last good recovery system`)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/2222"), testutil.FilePresent)
+}
+
+func (s *createSystemSuite) TestRemoveRecoverySystemInUse(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand: "my-brand",
+		Model: "pc",
+	})
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		RecoverySystem:         "1111",
+		CurrentRecoverySystems: []string{"1111", "2222", "3333", "4444"},
+		GoodRecoverySystems:    []string{"1111", "2222", "3333"},
+
+		Model:          model.Model(),
+		BrandID:        model.BrandID(),
+		Grade:          string(model.Grade()),
+		ModelSignKeyID: model.SignKeyID(),
 	}
-	snapWriteObserver := func(dir, where string) error {
-		c.Fatalf("unexpected call")
-		return fmt.Errorf("unexpected call")
+	c.Assert(modeenv.WriteTo(""), IsNil)
+	for _, label := range []string{"2222", "3333", "4444"} {
+		c.Assert(os.MkdirAll(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label), 0755), IsNil)
 	}
-	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
-		infoGetter, snapWriteObserver)
-	c.Assert(err, ErrorMatches, `cannot create a system for pre-UC20 model`)
-	c.Check(dir, Equals, "")
+
+	// system is being created
+	chg := s.state.NewChange("create-recovery-system", "...")
+	t := s.state.NewTask("create-recovery-system", "...")
+	t.Set("recovery-system-setup", map[string]interface{}{"label": "2222"})
+	chg.AddTask(t)
+	err := devicestate.RemoveRecoverySystem(s.state, "2222")
+	c.Assert(err, ErrorMatches, `cannot remove recovery system "2222": system is being created`)
+	c.Assert(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+	c.Check(err.(*snapstate.ChangeConflictError).ChangeID, Equals, chg.ID())
+	// but not once the change is done
+	t.SetStatus(state.DoneStatus)
+	c.Assert(chg.IsReady(), Equals, true)
+
+	// system was tried, but not yet finalized
+	s.state.Set("tried-systems", []string{"3333"})
+	err = devicestate.RemoveRecoverySystem(s.state, "3333")
+	c.Assert(err, ErrorMatches, `cannot remove recovery system "3333": system is being tried`)
+	s.state.Set("tried-systems", nil)
+
+	// system is being tried
+	err = devicestate.RemoveRecoverySystem(s.state, "4444")
+	c.Assert(err, ErrorMatches, `cannot remove recovery system "4444": system has not been finalized yet`)
+
+	for _, label := range []string{"2222", "3333", "4444"} {
+		c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label), testutil.FilePresent)
+	}
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.CurrentRecoverySystems, DeepEquals, []string{"1111", "2222", "3333", "4444"})
+	c.Check(m.GoodRecoverySystems, DeepEquals, []string{"1111", "2222", "3333"})
 }
 
-func (s *createSystemSuite) TestCreateSystemImplicitSnaps(c *C) {
+func (s *createSystemSuite) TestCreateRecoverySystemForModelDifferentGadget(c *C) {
 	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
 	bootloader.Force(bl)
 
 	s.state.Lock()
 	defer s.state.Unlock()
 	s.setupBrands()
 	infos := s.makeEssentialSnapInfos(c)
+	infos["pc-alt"] = s.makeSnap(c, "pc-alt", snap.R(11))
 
-	// snapd snap is implicitly required
-	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
+	// the model of the device uses the pc gadget
+	s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
 		"architecture": "amd64",
-		"grade":        "dangerous",
-		// base does not need to be listed among snaps
-		"base": "core20",
+		"grade":        "signed",
+		"base":         "core20",
 		"snaps": []interface{}{
 			map[string]interface{}{
 				"name":            "pc-kernel",
@@ -709,49 +3208,146 @@ func (s *createSystemSuite) TestCreateSystemImplicitSnaps(c *C) {
 			},
 		},
 	})
-	expectedDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand: "my-brand",
+		Model: "pc",
+	})
+	// while the variant uses a different one
+	variant := s.brands.Model("my-brand", "pc-variant", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "signed",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc-alt",
+				"id":              s.ss.AssertedSnapID("pc-alt"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	c.Assert(s.db.Add(variant), IsNil)
 
 	infoGetter := func(name string) (*snap.Info, bool, error) {
-		c.Logf("called for: %q", name)
 		info, present := infos[name]
 		return info, present, nil
 	}
 	var newFiles []string
 	snapWriteObserver := func(dir, where string) error {
-		c.Check(dir, Equals, expectedDir)
 		newFiles = append(newFiles, where)
 		return nil
 	}
 
-	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
-		infoGetter, snapWriteObserver)
+	dir, err := devicestate.CreateRecoverySystemForModel(variant, "1234", s.db, infoGetter, snapWriteObserver, nil)
 	c.Assert(err, IsNil)
+	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"))
 	c.Check(newFiles, DeepEquals, []string{
 		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap"),
 		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap"),
 		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_3.snap"),
-		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_2.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-alt_11.snap"),
 	})
-	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"))
-	// validate the seed
-	validateCore20Seed(c, "1234", model, s.ss.StoreSigning.Trusted)
+	// the gadget of the device is not part of the system
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_2.snap"), testutil.FileAbsent)
+	c.Check(bl.RecoverySystemBootVars, DeepEquals, map[string]string{
+		"snapd_full_cmdline_args":  "",
+		"snapd_extra_cmdline_args": "args from alternative gadget",
+		"snapd_recovery_kernel":    "/snaps/pc-kernel_1.snap",
+	})
+	validateCore20Seed(c, "1234", variant, s.storeSigning.Trusted)
 }
 
-func (s *createSystemSuite) TestCreateSystemObserverErr(c *C) {
+func (s *createSystemSuite) TestCreateRecoverySystemForModelErrors(c *C) {
 	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
 	bootloader.Force(bl)
 
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+	infos["other-unasserted"] = s.makeSnap(c, "other-unasserted", snap.R(-1))
+
+	snaps := []interface{}{
+		map[string]interface{}{
+			"name":            "pc-kernel",
+			"id":              s.ss.AssertedSnapID("pc-kernel"),
+			"type":            "kernel",
+			"default-channel": "20",
+		},
+		map[string]interface{}{
+			"name":            "pc-alt",
+			"id":              s.ss.AssertedSnapID("pc-alt"),
+			"type":            "gadget",
+			"default-channel": "20",
+		},
+		// the snap is known in the model, but the one that is
+		// available is unasserted
+		map[string]interface{}{
+			"name":     "other-unasserted",
+			"id":       s.ss.AssertedSnapID("other-unasserted"),
+			"presence": "required",
+		},
+	}
+	model := s.brands.Model("my-brand", "pc-variant", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "signed",
+		"base":         "core20",
+		"snaps":        snaps,
+	})
+	infoGetter := func(name string) (*snap.Info, bool, error) {
+		info, present := infos[name]
+		return info, present, nil
+	}
+	observer := func(dir, where string) error {
+		c.Fatalf("unexpected call")
+		return nil
+	}
+	systemDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
+
+	// the gadget is not available
+	dir, err := devicestate.CreateRecoverySystemForModel(model, "1234", s.db, infoGetter, observer, nil)
+	c.Assert(err, ErrorMatches, `cannot create recovery system "1234" for model "pc-variant": gadget snap "pc-alt" is not available`)
+	c.Check(dir, Equals, "")
+	c.Check(systemDir, testutil.FileAbsent)
+
+	// the gadget is a snap of the wrong type
+	infos["pc-alt"] = infos["core20"]
+	dir, err = devicestate.CreateRecoverySystemForModel(model, "1234", s.db, infoGetter, observer, nil)
+	c.Assert(err, ErrorMatches, `cannot create recovery system "1234" for model "pc-variant": snap "pc-alt" has type "base", expected "gadget"`)
+	c.Check(dir, Equals, "")
+	c.Check(systemDir, testutil.FileAbsent)
+
+	// unasserted snaps require dangerous grade
+	infos["pc-alt"] = s.makeSnap(c, "pc-alt", snap.R(11))
+	dir, err = devicestate.CreateRecoverySystemForModel(model, "1234", s.db, infoGetter, observer, nil)
+	c.Assert(err, ErrorMatches, `cannot create recovery system "1234" for model "pc-variant": cannot use unasserted snap "other-unasserted" with a model of grade "signed"`)
+	c.Check(dir, Equals, "")
+	c.Check(systemDir, testutil.FileAbsent)
+}
+
+func (s *createSystemSuite) TestCreateRecoverySystemForModelExtraSnaps(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	// make it simple for now, no assets
+	bl.TrustedAssetsList = nil
+	bl.StaticCommandLine = "mock static"
+	bl.CandidateStaticCommandLine = "unused"
+	bootloader.Force(bl)
+
 	s.state.Lock()
 	defer s.state.Unlock()
 	s.setupBrands()
 	infos := s.makeEssentialSnapInfos(c)
 
-	// snapd snap is implicitly required
 	model := s.makeModelAssertionInState(c, "my-brand", "pc", map[string]interface{}{
 		"architecture": "amd64",
 		"grade":        "dangerous",
-		// base does not need to be listed among snaps
-		"base": "core20",
+		"base":         "core20",
 		"snaps": []interface{}{
 			map[string]interface{}{
 				"name":            "pc-kernel",
@@ -767,6 +3363,8 @@ func (s *createSystemSuite) TestCreateSystemObserverErr(c *C) {
 			},
 		},
 	})
+	// an unasserted snap which is not part of the model
+	extraSnap := snaptest.MakeTestSnapWithFiles(c, fmt.Sprintf(genericSnapYaml, "diagnostics", "base: core20"), nil)
 
 	infoGetter := func(name string) (*snap.Info, bool, error) {
 		info, present := infos[name]
@@ -775,20 +3373,129 @@ func (s *createSystemSuite) TestCreateSystemObserverErr(c *C) {
 	var newFiles []string
 	snapWriteObserver := func(dir, where string) error {
 		newFiles = append(newFiles, where)
-		if strings.HasSuffix(where, "/core20_3.snap") {
-			return fmt.Errorf("mocked observer failure")
-		}
 		return nil
 	}
 
-	dir, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db,
-		infoGetter, snapWriteObserver)
-	c.Assert(err, ErrorMatches, "mocked observer failure")
+	dir, err := devicestate.CreateRecoverySystemForModel(model, "1234", s.db, infoGetter, snapWriteObserver, []string{extraSnap})
+	c.Assert(err, IsNil)
+	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"))
 	c.Check(newFiles, DeepEquals, []string{
 		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/snapd_4.snap"),
 		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap"),
-		// we failed on this one
 		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/core20_3.snap"),
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc_2.snap"),
+		// the extra snap is unasserted and lands under the system
+		filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234/snaps/diagnostics_1.0.snap"),
 	})
-	c.Check(dir, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"))
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234/snaps/diagnostics_1.0.snap"),
+		testutil.FileEquals, testutil.FileContentRef(extraSnap))
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234/options.yaml"),
+		testutil.FileContains, "name: diagnostics")
+	// the extra snap is loaded from the seed
+	validateCore20Seed(c, "1234", model, s.storeSigning.Trusted, "diagnostics")
+	c.Check(s.logbuf.String(), testutil.Contains, `system "1234" contains unasserted snaps "diagnostics"`)
+}
+
+func (s *createSystemSuite) TestCreateSystemExtraSnapsErrors(c *C) {
+	bl := bootloadertest.Mock("trusted", c.MkDir()).WithRecoveryAwareTrustedAssets()
+	bootloader.Force(bl)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupBrands()
+	infos := s.makeEssentialSnapInfos(c)
+
+	modelHeaders := map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "signed",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	}
+	model := s.makeModelAssertionInState(c, "my-brand", "pc", modelHeaders)
+	extraSnap := snaptest.MakeTestSnapWithFiles(c, fmt.Sprintf(genericSnapYaml, "diagnostics", "base: core20"), nil)
+
+	infoGetter := func(sn *asserts.ModelSnap) (*snap.Info, bool, error) {
+		info, present := infos[sn.SnapName()]
+		return info, present, nil
+	}
+	systemDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
+
+	// extra snaps require dangerous grade
+	_, err := devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, nil,
+		&devicestate.CreateSystemOptions{ExtraSnaps: []string{extraSnap}})
+	c.Assert(err, ErrorMatches, `cannot use extra snaps with a model of grade "signed"`)
+	c.Check(systemDir, testutil.FileAbsent)
+
+	modelHeaders["grade"] = "dangerous"
+	modelHeaders["revision"] = "1"
+	model = s.makeModelAssertionInState(c, "my-brand", "pc", modelHeaders)
+
+	// the extra snap must exist
+	_, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, nil,
+		&devicestate.CreateSystemOptions{ExtraSnaps: []string{"/does/not/exist.snap"}})
+	c.Assert(err, ErrorMatches, `local option snap "/does/not/exist.snap" does not exist`)
+	c.Check(systemDir, testutil.FileAbsent)
+
+	// snaps of the model cannot be added again
+	_, err = devicestate.CreateSystemForModelFromValidatedSnaps(model, "1234", s.db, infoGetter, nil,
+		&devicestate.CreateSystemOptions{ExtraSnaps: []string{infos["pc"].MountFile()}})
+	c.Assert(err, ErrorMatches, `cannot use extra snap ".*/pc_2.snap", it is already included in the system`)
+	c.Check(systemDir, testutil.FileAbsent)
+}
+
+func benchCopySnapFiles(b *testing.B, workers int) {
+	// prefer tmpfs, such that the storage is not the limiting factor
+	baseDir := "/dev/shm"
+	if !osutil.IsDirectory(baseDir) {
+		baseDir = ""
+	}
+	srcDir, err := ioutil.TempDir(baseDir, "bench-src")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(srcDir)
+
+	const snapSize = 16 * 1024 * 1024
+	names := []string{"snapd", "pc-kernel", "core20", "pc", "core22", "app-1", "app-2", "app-3"}
+	content := bytes.Repeat([]byte{1}, snapSize)
+	for _, name := range names {
+		if err := ioutil.WriteFile(filepath.Join(srcDir, name+".snap"), content, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		dstDir, err := ioutil.TempDir(baseDir, "bench-dst")
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		err = devicestate.CopySnapFiles(names, workers, func(name string) error {
+			return osutil.CopyFile(filepath.Join(srcDir, name+".snap"), filepath.Join(dstDir, name+".snap"), osutil.CopyFlagSync)
+		})
+		b.StopTimer()
+		os.RemoveAll(dstDir)
+		b.StartTimer()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
 }
+
+func BenchmarkCopySnapFilesSequential(b *testing.B) { benchCopySnapFiles(b, 1) }
+func BenchmarkCopySnapFilesParallel(b *testing.B)   { benchCopySnapFiles(b, 2) }
@@ -20,6 +20,7 @@
 package overlord
 
 import (
+	"io"
 	"time"
 
 	"github.com/snapcore/snapd/overlord/configstate"
@@ -116,3 +117,44 @@ func MockSystemdSdNotify(f func(notifyState string) error) (restore func()) {
 		systemdSdNotify = old
 	}
 }
+
+// ReleaseStateLock releases the state lock file without stopping the overlord,
+// as if snapd was killed.
+func (o *Overlord) ReleaseStateLock() {
+	o.stateFLock.Close()
+}
+
+type OverlordStateBackend = overlordStateBackend
+
+func NewOverlordStateBackend(path string, useWAL bool) *OverlordStateBackend {
+	return newOverlordStateBackend(path, func(time.Duration) {}, useWAL)
+}
+
+func (osb *overlordStateBackend) FoldWAL() error {
+	return osb.foldWAL()
+}
+
+func (osb *overlordStateBackend) Stats() (rewrites int, written int64) {
+	return osb.rewrites, osb.written
+}
+
+var ReplayStateWAL = replayStateWAL
+
+func MockWriteStateWALLine(f func(w io.Writer, doc interface{}) (int, error)) (restore func()) {
+	r := testutil.Backup(&writeStateWALLine)
+	writeStateWALLine = f
+	return r
+}
+
+func MockStateWALLimits(maxSize int64, foldInterval time.Duration) (restore func()) {
+	r := testutil.Backup(&stateWALMaxSize, &stateWALFoldInterval)
+	stateWALMaxSize = maxSize
+	stateWALFoldInterval = foldInterval
+	return r
+}
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	r := testutil.Backup(&timeNow)
+	timeNow = f
+	return r
+}
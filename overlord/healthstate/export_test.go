@@ -32,3 +32,5 @@ func MockCheckTimeout(t time.Duration) (restore func()) {
 }
 
 var KnownStatuses = knownStatuses
+
+var UnhealthyForAutoRefresh = unhealthyForAutoRefresh
@@ -47,6 +47,7 @@ func init() {
 	}
 
 	snapstate.CheckHealthHook = Hook
+	snapstate.UnhealthyForAutoRefresh = unhealthyForAutoRefresh
 }
 
 func Hook(st *state.State, snapName string, snapRev snap.Revision) *state.Task {
@@ -90,12 +91,16 @@ func (s HealthStatus) String() string {
 	return knownStatuses[s]
 }
 
+// HealthState is the last health reported by a snap. Only the last report
+// is kept, together with when the status last changed.
 type HealthState struct {
 	Revision  snap.Revision `json:"revision"`
 	Timestamp time.Time     `json:"timestamp"`
 	Status    HealthStatus  `json:"status"`
 	Message   string        `json:"message,omitempty"`
 	Code      string        `json:"code,omitempty"`
+	// LastTransition is when the snap started reporting Status.
+	LastTransition time.Time `json:"last-transition,omitempty"`
 }
 
 func Init(hookManager *hookstate.HookManager) {
@@ -174,7 +179,29 @@ func appendHealth(ctx *hookstate.Context, health *HealthState) error {
 		}
 		hs = map[string]*HealthState{}
 	}
-	hs[ctx.InstanceName()] = health
+	instanceName := ctx.InstanceName()
+	if prev := hs[instanceName]; prev != nil && prev.Status == health.Status && !prev.LastTransition.IsZero() {
+		health.LastTransition = prev.LastTransition
+	} else {
+		health.LastTransition = health.Timestamp
+	}
+	hs[instanceName] = health
+
+	// drop the health of snaps that were removed meanwhile
+	for name := range hs {
+		if name == instanceName {
+			continue
+		}
+		var snapst snapstate.SnapState
+		err := snapstate.Get(st, name, &snapst)
+		if errors.Is(err, state.ErrNoState) {
+			delete(hs, name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
 	st.Set("health", hs)
 
 	return nil
@@ -225,3 +252,23 @@ func Get(st *state.State, snap string) (*HealthState, error) {
 
 	return &health, nil
 }
+
+// unhealthyForAutoRefresh implements snapstate.UnhealthyForAutoRefresh,
+// snaps reporting a blocked or error status are not auto-refreshed.
+func unhealthyForAutoRefresh(st *state.State, instanceName string) (string, error) {
+	health, err := Get(st, instanceName)
+	if err != nil {
+		return "", err
+	}
+	if health == nil {
+		return "", nil
+	}
+	switch health.Status {
+	case BlockedStatus, ErrorStatus:
+		if health.Message != "" {
+			return fmt.Sprintf("snap health is %q: %s", health.Status, health.Message), nil
+		}
+		return fmt.Sprintf("snap health is %q", health.Status), nil
+	}
+	return "", nil
+}
@@ -250,3 +250,86 @@ func (s *healthSuite) TestSetFromHookContextEmpty(c *check.C) {
 	// no health in the context -> no health in state
 	c.Check(s.state.Get("health", &hs), testutil.ErrorIs, state.ErrNoState)
 }
+
+func (s *healthSuite) setHealth(c *check.C, snapName string, health *healthstate.HealthState) {
+	ctx, err := hookstate.NewContext(nil, s.state, &hookstate.HookSetup{Snap: snapName}, nil, "")
+	c.Assert(err, check.IsNil)
+
+	ctx.Lock()
+	defer ctx.Unlock()
+	ctx.Set("health", health)
+	c.Assert(healthstate.SetFromHookContext(ctx), check.IsNil)
+}
+
+func (s *healthSuite) TestSetFromHookContextLastTransition(c *check.C) {
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+	t2 := t1.Add(time.Hour)
+
+	s.setHealth(c, "test-snap", &healthstate.HealthState{Timestamp: t0, Status: healthstate.WaitingStatus})
+	// same status, the transition time is kept
+	s.setHealth(c, "test-snap", &healthstate.HealthState{Timestamp: t1, Status: healthstate.WaitingStatus, Message: "still waiting"})
+
+	s.state.Lock()
+	health, err := healthstate.Get(s.state, "test-snap")
+	s.state.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(health, check.DeepEquals, &healthstate.HealthState{
+		Timestamp:      t1,
+		Status:         healthstate.WaitingStatus,
+		Message:        "still waiting",
+		LastTransition: t0,
+	})
+
+	// the status changed
+	s.setHealth(c, "test-snap", &healthstate.HealthState{Timestamp: t2, Status: healthstate.OkayStatus})
+
+	s.state.Lock()
+	health, err = healthstate.Get(s.state, "test-snap")
+	s.state.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(health.Status, check.Equals, healthstate.OkayStatus)
+	c.Check(health.LastTransition.Equal(t2), check.Equals, true)
+}
+
+func (s *healthSuite) TestSetFromHookContextDropsRemovedSnaps(c *check.C) {
+	s.state.Lock()
+	s.state.Set("health", map[string]*healthstate.HealthState{
+		"gone-snap": {Status: healthstate.ErrorStatus},
+	})
+	s.state.Unlock()
+
+	s.setHealth(c, "test-snap", &healthstate.HealthState{Status: healthstate.OkayStatus})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	hs, err := healthstate.All(s.state)
+	c.Assert(err, check.IsNil)
+	c.Check(hs, check.HasLen, 1)
+	c.Check(hs["test-snap"], check.NotNil)
+}
+
+func (s *healthSuite) TestUnhealthyForAutoRefresh(c *check.C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	reason, err := healthstate.UnhealthyForAutoRefresh(s.state, "test-snap")
+	c.Assert(err, check.IsNil)
+	c.Check(reason, check.Equals, "")
+
+	for _, t := range []struct {
+		health *healthstate.HealthState
+		reason string
+	}{
+		{&healthstate.HealthState{Status: healthstate.OkayStatus}, ""},
+		{&healthstate.HealthState{Status: healthstate.WaitingStatus}, ""},
+		{&healthstate.HealthState{Status: healthstate.UnknownStatus}, ""},
+		{&healthstate.HealthState{Status: healthstate.BlockedStatus}, `snap health is "blocked"`},
+		{&healthstate.HealthState{Status: healthstate.ErrorStatus, Message: "database is gone"}, `snap health is "error": database is gone`},
+	} {
+		s.state.Set("health", map[string]*healthstate.HealthState{"test-snap": t.health})
+		reason, err := healthstate.UnhealthyForAutoRefresh(s.state, "test-snap")
+		c.Assert(err, check.IsNil)
+		c.Check(reason, check.Equals, t.reason, check.Commentf("%s", t.health.Status))
+	}
+}
@@ -27,13 +27,16 @@ import (
 	"time"
 
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/jsonutil"
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
 )
 
 var finalTasks map[string]bool
@@ -129,15 +132,46 @@ func queueCommand(context *hookstate.Context, tts []*state.TaskSet) error {
 	return nil
 }
 
+// serviceCommandOptions holds the options of service commands.
+type serviceCommandOptions struct {
+	// Other is set when the services belong to other snaps, connected
+	// to the snap running the command through the snap-control
+	// interface.
+	Other bool
+	// NoWait is set to not wait for the change running the command to
+	// complete.
+	NoWait bool
+}
+
 func runServiceCommand(context *hookstate.Context, inst *servicestate.Instruction) error {
+	_, err := runServiceCommandWithOptions(context, inst, nil)
+	return err
+}
+
+// runServiceCommandWithOptions runs the given service command. It returns
+// the ID of the change running the command when opts.NoWait is set, and
+// an empty string when the command is queued after the configure hook.
+func runServiceCommandWithOptions(context *hookstate.Context, inst *servicestate.Instruction, opts *serviceCommandOptions) (string, error) {
 	if context == nil {
-		return &MissingContextError{inst.Action}
+		return "", &MissingContextError{inst.Action}
+	}
+	if opts == nil {
+		opts = &serviceCommandOptions{}
 	}
 
 	st := context.State()
-	appInfos, err := getServiceInfos(st, context.InstanceName(), inst.Names)
+	var appInfos []*snap.AppInfo
+	var err error
+	summary := fmt.Sprintf("Running service command for snap %q", context.InstanceName())
+	if opts.Other {
+		var snapNames []string
+		appInfos, snapNames, err = getOtherServiceInfos(st, context.InstanceName(), inst)
+		summary = fmt.Sprintf("Running service command for snaps %s on behalf of snap %q", strutil.Quoted(snapNames), context.InstanceName())
+	} else {
+		appInfos, err = getServiceInfos(st, context.InstanceName(), inst.Names)
+	}
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	flags := &servicestate.Flags{CreateExecCommandTasks: true}
@@ -146,29 +180,89 @@ func runServiceCommand(context *hookstate.Context, inst *servicestate.Instructio
 	tts, err := servicestateControl(st, appInfos, inst, flags, context)
 	st.Unlock()
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	if !context.IsEphemeral() && context.HookName() == "configure" {
-		return queueCommand(context, tts)
+	// commands on the services of other snaps run in their own change
+	if !opts.Other && !context.IsEphemeral() && context.HookName() == "configure" {
+		return "", queueCommand(context, tts)
 	}
 
 	st.Lock()
-	chg := st.NewChange("service-control", fmt.Sprintf("Running service command for snap %q", context.InstanceName()))
+	chg := st.NewChange("service-control", summary)
 	for _, ts := range tts {
 		chg.AddAll(ts)
 	}
 	st.EnsureBefore(0)
 	st.Unlock()
 
+	if opts.NoWait {
+		return chg.ID(), nil
+	}
+
 	select {
 	case <-chg.Ready():
 		st.Lock()
 		defer st.Unlock()
-		return chg.Err()
+		return "", chg.Err()
 	case <-time.After(configstate.ConfigureHookTimeout() / 2):
-		return fmt.Errorf("%s command is taking too long", inst.Action)
+		return "", fmt.Errorf("%s command is taking too long", inst.Action)
+	}
+}
+
+// getOtherServiceInfos returns the services designated by the names of the
+// instruction, which must belong to snaps other than the given one, and
+// the names of their snaps. The snap must have a snap-control plug
+// connected to each of these snaps.
+func getOtherServiceInfos(st *state.State, snapName string, inst *servicestate.Instruction) ([]*snap.AppInfo, []string, error) {
+	var appInfos []*snap.AppInfo
+	var snapNames []string
+	for _, name := range inst.Names {
+		otherSnapName := strings.SplitN(name, ".", 2)[0]
+		if otherSnapName == snapName {
+			return nil, nil, fmt.Errorf(i18n.G("cannot use --other with services of snap %q itself"), snapName)
+		}
+		if !strutil.ListContains(snapNames, otherSnapName) {
+			st.Lock()
+			connected, err := hasSnapControlConnection(st, snapName, otherSnapName)
+			st.Unlock()
+			if err != nil {
+				return nil, nil, err
+			}
+			if !connected {
+				return nil, nil, fmt.Errorf(i18n.G("cannot %s services of snap %q: requires snap-control interface connected to it"), inst.Action, otherSnapName)
+			}
+			snapNames = append(snapNames, otherSnapName)
+		}
+		svcs, err := getServiceInfos(st, otherSnapName, []string{name})
+		if err != nil {
+			return nil, nil, err
+		}
+		appInfos = append(appInfos, svcs...)
+	}
+	return appInfos, snapNames, nil
+}
+
+// hasSnapControlConnection returns whether the given plug snap has a
+// snap-control plug connected to a slot of the given slot snap.
+func hasSnapControlConnection(st *state.State, plugSnapName, slotSnapName string) (bool, error) {
+	conns, err := ifacestate.ConnectionStates(st)
+	if err != nil {
+		return false, fmt.Errorf("internal error: cannot get connections: %s", err)
+	}
+	for refStr, connState := range conns {
+		if connState.Undesired || connState.HotplugGone || connState.Interface != "snap-control" {
+			continue
+		}
+		connRef, err := interfaces.ParseConnRef(refStr)
+		if err != nil {
+			return false, fmt.Errorf("internal error: %s", err)
+		}
+		if connRef.PlugRef.Snap == plugSnapName && connRef.SlotRef.Snap == slotSnapName {
+			return true, nil
+		}
 	}
+	return false, nil
 }
 
 // NoAttributeError indicates that an interface attribute is not set.
@@ -0,0 +1,83 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type recoverySystemCommand struct {
+	baseCommand
+}
+
+var shortRecoverySystemHelp = i18n.G("Get details of the recovery system being created")
+
+var longRecoverySystemHelp = i18n.G(`
+The recovery-system command is used inside the prepare-recovery-system hook
+of the gadget. It returns the label of the recovery system being created and
+the directory holding its files.
+
+The output is in YAML format. Example output:
+    $ snapctl recovery-system
+    label: "20230425"
+    directory: /run/mnt/ubuntu-seed/systems/20230425
+`)
+
+func init() {
+	addCommand("recovery-system", shortRecoverySystemHelp, longRecoverySystemHelp, func() command { return &recoverySystemCommand{} })
+}
+
+type recoverySystemResult struct {
+	Label     string `yaml:"label"`
+	Directory string `yaml:"directory"`
+}
+
+func (c *recoverySystemCommand) Execute(args []string) error {
+	context, err := c.ensureContext()
+	if err != nil {
+		return err
+	}
+	context.Lock()
+	defer context.Unlock()
+
+	if context.HookName() != "prepare-recovery-system" {
+		return fmt.Errorf("cannot use recovery-system outside of the prepare-recovery-system hook")
+	}
+
+	var res recoverySystemResult
+	if err := context.Get("recovery-system-label", &res.Label); err != nil {
+		return fmt.Errorf("internal error: cannot get recovery system label from context: %v", err)
+	}
+	if err := context.Get("recovery-system-directory", &res.Directory); err != nil {
+		return fmt.Errorf("internal error: cannot get recovery system directory from context: %v", err)
+	}
+
+	b, err := yaml.Marshal(res)
+	if err != nil {
+		return err
+	}
+	c.printf("%s", string(b))
+
+	return nil
+}
@@ -0,0 +1,99 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type recoverySystemSuite struct {
+	testutil.BaseTest
+
+	st          *state.State
+	mockHandler *hooktest.MockHandler
+}
+
+var _ = Suite(&recoverySystemSuite{})
+
+func (s *recoverySystemSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("/") })
+
+	s.st = state.New(nil)
+	s.mockHandler = hooktest.NewMockHandler()
+}
+
+func (s *recoverySystemSuite) TestRecoverySystem(c *C) {
+	hooksup := &hookstate.HookSetup{
+		Snap:     "pc",
+		Revision: snap.R(1),
+		Hook:     "prepare-recovery-system",
+	}
+	context, err := hookstate.NewContext(nil, s.st, hooksup, s.mockHandler, "")
+	c.Assert(err, IsNil)
+	context.Lock()
+	context.Set("recovery-system-label", "20230425")
+	context.Set("recovery-system-directory", "/run/mnt/ubuntu-seed/systems/20230425")
+	context.Unlock()
+
+	stdout, stderr, err := ctlcmd.Run(context, []string{"recovery-system"}, 0)
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, `label: "20230425"
+directory: /run/mnt/ubuntu-seed/systems/20230425
+`)
+	c.Check(string(stderr), Equals, "")
+}
+
+func (s *recoverySystemSuite) TestRecoverySystemOutsideOfHook(c *C) {
+	hooksup := &hookstate.HookSetup{
+		Snap:     "pc",
+		Revision: snap.R(1),
+		Hook:     "install-device",
+	}
+	context, err := hookstate.NewContext(nil, s.st, hooksup, s.mockHandler, "")
+	c.Assert(err, IsNil)
+
+	stdout, stderr, err := ctlcmd.Run(context, []string{"recovery-system"}, 0)
+	c.Check(err, ErrorMatches, `cannot use recovery-system outside of the prepare-recovery-system hook`)
+	c.Check(string(stdout), Equals, "")
+	c.Check(string(stderr), Equals, "")
+}
+
+func (s *recoverySystemSuite) TestRecoverySystemNoData(c *C) {
+	hooksup := &hookstate.HookSetup{
+		Snap:     "pc",
+		Revision: snap.R(1),
+		Hook:     "prepare-recovery-system",
+	}
+	context, err := hookstate.NewContext(nil, s.st, hooksup, s.mockHandler, "")
+	c.Assert(err, IsNil)
+
+	_, _, err = ctlcmd.Run(context, []string{"recovery-system"}, 0)
+	c.Check(err, ErrorMatches, `internal error: cannot get recovery system label from context: .*`)
+}
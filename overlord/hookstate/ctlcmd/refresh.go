@@ -74,6 +74,10 @@ pending refreshes and to ”inhibited” when pending refreshes are being
 held back because more or more snap applications are running with the
 “refresh app awareness” feature enabled.
 
+When pending refreshes are inhibited, 'proceed-time' is the time after which
+the refresh proceeds even if snap applications are still running. It can be
+changed with the refresh.max-inhibition system option.
+
 The "base" and "restart" flags indicate whether the base snap is going to be
 updated and/or if a restart will occur, both of which are disruptive. A base
 snap update can temporarily disrupt the starting of applications or hooks from
@@ -144,11 +148,12 @@ func (c *refreshCommand) Execute(args []string) error {
 }
 
 type updateDetails struct {
-	Pending   string `yaml:"pending,omitempty"`
-	Channel   string `yaml:"channel,omitempty"`
-	CohortKey string `yaml:"cohort,omitempty"`
-	Version   string `yaml:"version,omitempty"`
-	Revision  int    `yaml:"revision,omitempty"`
+	Pending     string `yaml:"pending,omitempty"`
+	ProceedTime string `yaml:"proceed-time,omitempty"`
+	Channel     string `yaml:"channel,omitempty"`
+	CohortKey   string `yaml:"cohort,omitempty"`
+	Version     string `yaml:"version,omitempty"`
+	Revision    int    `yaml:"revision,omitempty"`
 	// TODO: epoch
 	Base    bool `yaml:"base"`
 	Restart bool `yaml:"restart"`
@@ -209,6 +214,9 @@ func getUpdateDetails(context *hookstate.Context) (*updateDetails, error) {
 		Restart: restart,
 		Pending: pending,
 	}
+	if pending == "inhibited" {
+		up.ProceedTime = snapst.RefreshInhibitProceedTime(st).Format(time.RFC3339)
+	}
 
 	hasRefreshControl, err := hasSnapRefreshControlInterface(st, context.InstanceName())
 	if err != nil {
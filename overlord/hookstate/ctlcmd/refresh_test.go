@@ -115,7 +115,7 @@ var refreshFromHookTests = []struct {
 }, {
 	args:      []string{"refresh", "--pending"},
 	inhibited: true,
-	stdout:    "pending: inhibited\nchannel: stable\nbase: false\nrestart: false\n",
+	stdout:    "pending: inhibited\nproceed-time: \"2023-05-15T09:59:59Z\"\nchannel: stable\nbase: false\nrestart: false\n",
 }, {
 	args: []string{"refresh", "--hold"},
 	err:  `internal error: snap "snap1" is not affected by any snaps`,
@@ -149,7 +149,8 @@ version: 1
 		if test.inhibited {
 			var snapst snapstate.SnapState
 			c.Assert(snapstate.Get(s.st, "snap1", &snapst), IsNil)
-			snapst.RefreshInhibitedTime = &time.Time{}
+			inhibitedTime := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+			snapst.RefreshInhibitedTime = &inhibitedTime
 			snapstate.Set(s.st, "snap1", &snapst)
 		}
 		s.st.Unlock()
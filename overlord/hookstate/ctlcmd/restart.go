@@ -29,7 +29,12 @@ var (
 	shortRestartHelp = i18n.G("Restart services")
 	longRestartHelp  = i18n.G(`
 The restart command restarts the given services of the snap. If executed from the
-"configure" hook, the services will be restarted after the hook finishes.`)
+"configure" hook, the services will be restarted after the hook finishes.
+
+With --other, the given services belong to other snaps instead, to which the
+snap must be connected through its snap-control plug. The services are then
+restarted right away in a separate change. With --no-wait, the command does not
+wait for the change to complete but prints its ID.`)
 )
 
 func init() {
@@ -42,6 +47,8 @@ type restartCommand struct {
 		ServiceNames []string `positional-arg-name:"<service>" required:"yes"`
 	} `positional-args:"yes" required:"yes"`
 	Reload bool `long:"reload" description:"Reload the given services if they support it (see man systemctl for details)"`
+	Other  bool `long:"other" description:"Restart services of other snaps connected through the snap-control interface"`
+	NoWait bool `long:"no-wait" description:"Do not wait for the restart to complete, print the change ID instead"`
 }
 
 func (c *restartCommand) Execute(args []string) error {
@@ -52,5 +59,16 @@ func (c *restartCommand) Execute(args []string) error {
 			Reload: c.Reload,
 		},
 	}
-	return runServiceCommand(c.context(), &inst)
+	opts := &serviceCommandOptions{
+		Other:  c.Other,
+		NoWait: c.NoWait,
+	}
+	changeID, err := runServiceCommandWithOptions(c.context(), &inst, opts)
+	if err != nil {
+		return err
+	}
+	if changeID != "" {
+		c.printf("%s\n", changeID)
+	}
+	return nil
 }
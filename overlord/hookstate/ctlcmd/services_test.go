@@ -23,6 +23,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	. "gopkg.in/check.v1"
 
@@ -303,6 +304,162 @@ func (s *servicectlSuite) TestRestartCommand(c *C) {
 	c.Assert(serviceChangeFuncCalled, Equals, true)
 }
 
+func (s *servicectlSuite) mockSnapControlConnection() {
+	s.st.Lock()
+	defer s.st.Unlock()
+	s.st.Set("conns", map[string]interface{}{
+		"test-snap:snap-control other-snap:snap-control": map[string]interface{}{"interface": "snap-control"},
+	})
+}
+
+func mockServiceControlTask(c *C, called *bool) func() {
+	return ctlcmd.MockServicestateControlFunc(func(st *state.State, appInfos []*snap.AppInfo, inst *servicestate.Instruction, flags *servicestate.Flags, context *hookstate.Context) ([]*state.TaskSet, error) {
+		*called = true
+		c.Assert(appInfos, HasLen, 1)
+		c.Check(appInfos[0].Snap.InstanceName(), Equals, "other-snap")
+		c.Check(appInfos[0].Name, Equals, "test-service")
+		c.Check(inst.Action, Equals, "restart")
+		c.Check(inst.Names, DeepEquals, []string{"other-snap.test-service"})
+		return []*state.TaskSet{state.NewTaskSet(st.NewTask("service-control", "restart of [other-snap.test-service]"))}, nil
+	})
+}
+
+func (s *servicectlSuite) TestRestartCommandOtherNoWait(c *C) {
+	s.mockSnapControlConnection()
+
+	var serviceChangeFuncCalled bool
+	restore := mockServiceControlTask(c, &serviceChangeFuncCalled)
+	defer restore()
+
+	stdout, stderr, err := ctlcmd.Run(s.mockContext, []string{"restart", "--other", "--no-wait", "other-snap.test-service"}, 0)
+	c.Assert(err, IsNil)
+	c.Check(serviceChangeFuncCalled, Equals, true)
+	c.Check(string(stderr), Equals, "")
+
+	s.st.Lock()
+	defer s.st.Unlock()
+	chgs := s.st.Changes()
+	c.Assert(chgs, HasLen, 1)
+	chg := chgs[0]
+	c.Check(string(stdout), Equals, chg.ID()+"\n")
+	c.Check(chg.Kind(), Equals, "service-control")
+	c.Check(chg.Summary(), Equals, `Running service command for snaps "other-snap" on behalf of snap "test-snap"`)
+	c.Assert(chg.Tasks(), HasLen, 1)
+	c.Check(chg.Tasks()[0].Summary(), Equals, "restart of [other-snap.test-service]")
+}
+
+func (s *servicectlSuite) TestRestartCommandOtherWaits(c *C) {
+	s.mockSnapControlConnection()
+
+	var serviceChangeFuncCalled bool
+	restore := mockServiceControlTask(c, &serviceChangeFuncCalled)
+	defer restore()
+
+	// complete the change once it is created
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			s.st.Lock()
+			chgs := s.st.Changes()
+			if len(chgs) == 1 {
+				chgs[0].Tasks()[0].SetStatus(state.DoneStatus)
+				s.st.Unlock()
+				return
+			}
+			s.st.Unlock()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	stdout, _, err := ctlcmd.Run(s.mockContext, []string{"restart", "--other", "other-snap.test-service"}, 0)
+	<-done
+	c.Assert(err, IsNil)
+	c.Check(serviceChangeFuncCalled, Equals, true)
+	c.Check(string(stdout), Equals, "")
+
+	s.st.Lock()
+	defer s.st.Unlock()
+	c.Check(s.st.Changes()[0].Status(), Equals, state.DoneStatus)
+}
+
+func (s *servicectlSuite) TestRestartCommandOtherFromConfigureHook(c *C) {
+	s.mockSnapControlConnection()
+
+	var serviceChangeFuncCalled bool
+	restore := mockServiceControlTask(c, &serviceChangeFuncCalled)
+	defer restore()
+
+	s.st.Lock()
+	hookChg := s.st.NewChange("configure", "configure change")
+	task := s.st.NewTask("run-hook", "configure hook")
+	hookChg.AddTask(task)
+	s.st.Unlock()
+
+	setup := &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1), Hook: "configure"}
+	context, err := hookstate.NewContext(task, s.st, setup, s.mockHandler, "")
+	c.Assert(err, IsNil)
+
+	_, _, err = ctlcmd.Run(context, []string{"restart", "--other", "--no-wait", "other-snap.test-service"}, 0)
+	c.Assert(err, IsNil)
+	c.Check(serviceChangeFuncCalled, Equals, true)
+
+	s.st.Lock()
+	defer s.st.Unlock()
+	// the command is not queued in the change of the hook
+	c.Check(hookChg.Tasks(), HasLen, 1)
+	c.Check(s.st.Changes(), HasLen, 2)
+}
+
+func (s *servicectlSuite) TestRestartCommandOtherNotConnected(c *C) {
+	var serviceChangeFuncCalled bool
+	restore := mockServiceChangeFunc(func(appInfos []*snap.AppInfo, inst *servicestate.Instruction) {
+		serviceChangeFuncCalled = true
+	})
+	defer restore()
+
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"restart", "--other", "other-snap.test-service"}, 0)
+	c.Check(err, ErrorMatches, `cannot restart services of snap "other-snap": requires snap-control interface connected to it`)
+
+	// the connection must be from the snap running the command
+	s.st.Lock()
+	s.st.Set("conns", map[string]interface{}{
+		"other-snap:snap-control test-snap:snap-control": map[string]interface{}{"interface": "snap-control"},
+	})
+	s.st.Unlock()
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"restart", "--other", "other-snap.test-service"}, 0)
+	c.Check(err, ErrorMatches, `cannot restart services of snap "other-snap": requires snap-control interface connected to it`)
+
+	// undesired connections are ignored
+	s.st.Lock()
+	s.st.Set("conns", map[string]interface{}{
+		"test-snap:snap-control other-snap:snap-control": map[string]interface{}{"interface": "snap-control", "undesired": true},
+	})
+	s.st.Unlock()
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"restart", "--other", "other-snap.test-service"}, 0)
+	c.Check(err, ErrorMatches, `cannot restart services of snap "other-snap": requires snap-control interface connected to it`)
+
+	c.Check(serviceChangeFuncCalled, Equals, false)
+}
+
+func (s *servicectlSuite) TestRestartCommandOtherErrors(c *C) {
+	s.mockSnapControlConnection()
+
+	var serviceChangeFuncCalled bool
+	restore := mockServiceChangeFunc(func(appInfos []*snap.AppInfo, inst *servicestate.Instruction) {
+		serviceChangeFuncCalled = true
+	})
+	defer restore()
+
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"restart", "--other", "test-snap.test-service"}, 0)
+	c.Check(err, ErrorMatches, `cannot use --other with services of snap "test-snap" itself`)
+
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"restart", "--other", "other-snap.unknown"}, 0)
+	c.Check(err, ErrorMatches, `unknown service: "other-snap.unknown"`)
+
+	c.Check(serviceChangeFuncCalled, Equals, false)
+}
+
 func (s *servicectlSuite) TestConflictingChange(c *C) {
 	s.st.Lock()
 	task := s.st.NewTask("link-snap", "conflicting task")
@@ -59,6 +59,9 @@ Nested values may be modified via a dotted path:
 Configuration option may be unset with exclamation mark:
     $ snapctl set author!
 
+If the snap ships a configuration schema in meta/config-schema.json, the
+resulting configuration must match it.
+
 Plug and slot attributes may be set in the respective prepare and connect hooks
 by naming the respective plug or slot:
 
@@ -136,7 +139,9 @@ func (s *setCommand) setConfigSetting(context *hookstate.Context) error {
 		tr.Set(s.context().InstanceName(), key, value)
 	}
 
-	return nil
+	context.Lock()
+	defer context.Unlock()
+	return configstate.ValidateTransaction(tr, s.context().InstanceName())
 }
 
 func setInterfaceAttribute(context *hookstate.Context, staticAttrs map[string]interface{}, dynamicAttrs map[string]interface{}, key string, value interface{}) error {
@@ -22,17 +22,22 @@ package ctlcmd_test
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
 	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
 )
 
 type setSuite struct {
@@ -281,6 +286,30 @@ func (s *setSuite) TestCommandSavesDeltasOnly(c *C) {
 	c.Check(value, Equals, "test-value3")
 }
 
+func (s *setSuite) TestSetConfigSchemaViolation(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("")
+
+	info := snaptest.MockSnap(c, "name: test-snap\nversion: 1", &snap.SideInfo{Revision: snap.R(1)})
+	schema := `{"properties": {"debug": {"type": "boolean"}}}`
+	c.Assert(os.WriteFile(filepath.Join(info.MountDir(), "meta", "config-schema.json"), []byte(schema), 0644), IsNil)
+
+	st := s.mockContext.State()
+	st.Lock()
+	snapstate.Set(st, "test-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "test-snap", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+	})
+	st.Unlock()
+
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"set", "debug=true"}, 0)
+	c.Check(err, IsNil)
+
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"set", "-s", "debug=true"}, 0)
+	c.Check(err, ErrorMatches, `invalid configuration: debug: expected boolean, got string`)
+}
+
 func (s *setSuite) TestCommandWithoutContext(c *C) {
 	_, _, err := ctlcmd.Run(nil, []string{"set", "foo=bar"}, 0)
 	c.Check(err, ErrorMatches, `cannot invoke snapctl operation commands \(here "set"\) from outside of a snap`)
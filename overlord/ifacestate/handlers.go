@@ -139,6 +139,7 @@ func (m *InterfaceManager) doSetupProfiles(task *state.Task, tomb *tomb.Tomb) er
 	if err != nil {
 		return err
 	}
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
 
 	snapInfo, err := snap.ReadInfo(snapsup.InstanceName(), snapsup.SideInfo)
 	if err != nil {
@@ -213,6 +214,8 @@ func (m *InterfaceManager) setupProfilesForSnap(task *state.Task, _ *tomb.Tomb,
 	// - add the (new) snap to the interfaces repository
 	// - restore connections based on what is kept in the state
 	//   - if a connection cannot be restored then remove it from the state
+	//   - refresh the static attributes of the restored connections, and
+	//     remove those not allowed anymore with the new attributes
 	// - setup the security of all the affected snaps
 	disconnectedSnaps, err := m.repo.DisconnectSnap(snapName)
 	if err != nil {
@@ -237,7 +240,7 @@ func (m *InterfaceManager) setupProfilesForSnap(task *state.Task, _ *tomb.Tomb,
 	// exception that the snap being setup is always first. The affectedSnaps
 	// array may be shorter than the set of affected snaps in case any of the
 	// snaps cannot be found in the state.
-	reconnectedSnaps, err := m.reloadConnections(snapName)
+	reconnectedSnaps, err := m.reloadConnections(snapName, task)
 	if err != nil {
 		return err
 	}
@@ -307,6 +310,7 @@ func (m *InterfaceManager) doRemoveProfiles(task *state.Task, tomb *tomb.Tomb) e
 		return err
 	}
 	snapName := snapSetup.InstanceName()
+	state.TagTimingsWithSnap(perfTimings, snapName)
 
 	if err := m.removeProfilesForSnap(task, tomb, snapName, perfTimings); err != nil {
 		return err
@@ -365,6 +369,7 @@ func (m *InterfaceManager) undoSetupProfiles(task *state.Task, tomb *tomb.Tomb)
 		return err
 	}
 	snapName := snapsup.InstanceName()
+	state.TagTimingsWithSnap(perfTimings, snapName)
 
 	// Get the name from SnapSetup and use it to find the current SideInfo
 	// about the snap, if there is one.
@@ -1516,7 +1521,7 @@ func (m *InterfaceManager) transitionConnectionsCoreMigration(st *state.State, o
 	// on disk are rewritten. This is ok because core/ubuntu-core have
 	// exactly the same profiles and nothing in the generated policies
 	// has the core snap-name encoded.
-	if _, err := m.reloadConnections(newName); err != nil {
+	if _, err := m.reloadConnections(newName, nil); err != nil {
 		return err
 	}
 
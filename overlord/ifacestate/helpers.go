@@ -25,6 +25,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -314,8 +315,14 @@ func isBroken(st *state.State, snapName string) (bool, error) {
 // Using non-empty snapName the operation can be scoped to connections
 // affecting a given snap.
 //
+// When setting up the profiles of snapName with the given task, the static
+// attributes of its connections are refreshed from the current plugs and
+// slots. A connection whose attributes changed is validated by its
+// interface and checked against the policy again, and it is removed if
+// that fails.
+//
 // The return value is the list of affected snap names.
-func (m *InterfaceManager) reloadConnections(snapName string) ([]string, error) {
+func (m *InterfaceManager) reloadConnections(snapName string, setupTask *state.Task) ([]string, error) {
 	conns, err := getConns(m.state)
 	if err != nil {
 		return nil, err
@@ -323,6 +330,7 @@ func (m *InterfaceManager) reloadConnections(snapName string) ([]string, error)
 
 	connStateChanged := false
 	affected := make(map[string]bool)
+	var policyCheck interfaces.PolicyFunc
 ConnsLoop:
 	for connId, connState := range conns {
 		// Skip entries that just mark a connection as undesired. Those don't
@@ -346,9 +354,12 @@ ConnsLoop:
 		plugInfo := m.repo.Plug(connRef.PlugRef.Snap, connRef.PlugRef.Name)
 		slotInfo := m.repo.Slot(connRef.SlotRef.Snap, connRef.SlotRef.Name)
 
-		// The connection refers to a plug or slot that doesn't exist anymore, e.g. because of a refresh
+		// The connection refers to a plug or slot that doesn't exist anymore,
+		// or that is now of a different interface, e.g. because of a refresh
 		// to a new snap revision that doesn't have the given plug/slot.
-		if plugInfo == nil || slotInfo == nil {
+		interfaceGone := plugInfo != nil && slotInfo != nil && connState.Interface != "" &&
+			(plugInfo.Interface != connState.Interface || slotInfo.Interface != connState.Interface)
+		if plugInfo == nil || slotInfo == nil || interfaceGone {
 			// automatic connection can simply be removed (it will be re-created automatically if needed)
 			// as long as it wasn't disconnected manually; note that undesired flag is taken care of at
 			// the beginning of the loop.
@@ -367,21 +378,28 @@ ConnsLoop:
 				}
 				delete(conns, connId)
 				connStateChanged = true
+				continue
+			}
+			// otherwise keep it, e.g. in case of a revert, but flag it
+			// so that it can be reported.
+			if snapName != "" && !connState.Stale {
+				connState.Stale = true
+				connStateChanged = true
 			}
-			// otherwise keep it and silently ignore, e.g. in case of a revert.
 			continue
 		}
 
 		var updateStaticAttrs bool
 		staticPlugAttrs := connState.StaticPlugAttrs
 		staticSlotAttrs := connState.StaticSlotAttrs
+		var connectPolicyCheck interfaces.PolicyFunc
 
 		// XXX: Refresh the copy of the static connection attributes for "content"
-		// and "system-files" interfaces.
+		// and "system-files" (and "system-files-read-only") interfaces.
 		// This is a partial and temporary solution to https://bugs.launchpad.net/snapd/+bug/1825883
 		// and https://bugs.launchpad.net/snapd/+bug/1942266.
-		switch plugInfo.Interface {
-		case "content":
+		switch {
+		case plugInfo.Interface == "content":
 			var plugContent, slotContent string
 			plugInfo.Attr("content", &plugContent)
 			slotInfo.Attr("content", &slotContent)
@@ -393,26 +411,62 @@ ConnsLoop:
 			} else {
 				logger.Noticef("cannot refresh static attributes of the connection %q", connId)
 			}
-		case "system-files":
+		case plugInfo.Interface == "system-files" || plugInfo.Interface == "system-files-read-only" || setupTask != nil:
 			staticPlugAttrs = utils.NormalizeInterfaceAttributes(plugInfo.Attrs).(map[string]interface{})
 			staticSlotAttrs = utils.NormalizeInterfaceAttributes(slotInfo.Attrs).(map[string]interface{})
 			updateStaticAttrs = true
 		}
+		attrsChanged := updateStaticAttrs && (!reflect.DeepEqual(staticPlugAttrs, connState.StaticPlugAttrs) || !reflect.DeepEqual(staticSlotAttrs, connState.StaticSlotAttrs))
+		if attrsChanged && setupTask != nil {
+			// the connection must still be valid and allowed with the
+			// refreshed attributes
+			if policyCheck == nil {
+				policyCheck, err = refreshedConnectionsPolicyCheck(setupTask)
+				if err != nil {
+					return nil, err
+				}
+			}
+			connectPolicyCheck = policyCheck
+		}
 
-		// Note: reloaded connections are not checked against policy again, and also we don't call BeforeConnect* methods on them.
-		if _, err := m.repo.Connect(connRef, staticPlugAttrs, connState.DynamicPlugAttrs, staticSlotAttrs, connState.DynamicSlotAttrs, nil); err != nil {
+		// Note: reloaded connections are not checked against policy again,
+		// and also we don't call BeforeConnect* methods on them, unless
+		// their static attributes were refreshed.
+		conn, err := m.repo.Connect(connRef, staticPlugAttrs, connState.DynamicPlugAttrs, staticSlotAttrs, connState.DynamicSlotAttrs, connectPolicyCheck)
+		if err == nil && conn == nil {
+			err = fmt.Errorf("connection not allowed by policy")
+		}
+		if err != nil {
 			logger.Noticef("%s", err)
-		} else {
-			// If the connection succeeded update the connection state and keep
-			// track of the snaps that were affected.
-			affected[connRef.PlugRef.Snap] = true
-			affected[connRef.SlotRef.Snap] = true
-
-			if updateStaticAttrs {
-				connState.StaticPlugAttrs = staticPlugAttrs
-				connState.StaticSlotAttrs = staticSlotAttrs
+			if connectPolicyCheck != nil {
+				// fall back to disconnecting, the profiles of the
+				// other snap are regenerated as it was disconnected
+				// from the snap being setup
+				logger.Noticef("removing connection %q as it cannot be refreshed", connId)
+				delete(conns, connId)
 				connStateChanged = true
 			}
+			continue
+		}
+
+		// If the connection succeeded update the connection state and keep
+		// track of the snaps that were affected.
+		affected[connRef.PlugRef.Snap] = true
+		affected[connRef.SlotRef.Snap] = true
+
+		if connState.Stale {
+			connState.Stale = false
+			connStateChanged = true
+		}
+		if attrsChanged {
+			connState.StaticPlugAttrs = staticPlugAttrs
+			connState.StaticSlotAttrs = staticSlotAttrs
+			connStateChanged = true
+		}
+		if connectPolicyCheck != nil {
+			// the interface may have updated the dynamic attributes
+			connState.DynamicPlugAttrs = conn.Plug.DynamicAttrs()
+			connState.DynamicSlotAttrs = conn.Slot.DynamicAttrs()
 		}
 	}
 	if connStateChanged {
@@ -926,6 +980,22 @@ func (c *connectChecker) check(plug *interfaces.ConnectedPlug, slot *interfaces.
 	return true, nil
 }
 
+// refreshedConnectionsPolicyCheck returns the policy check for the
+// connections whose attributes are refreshed when setting up the profiles
+// of a snap. Connections, manual or automatic, obey the policy
+// "connection" rules then.
+func refreshedConnectionsPolicyCheck(task *state.Task) (interfaces.PolicyFunc, error) {
+	deviceCtx, err := snapstate.DeviceCtx(task.State(), task, nil)
+	if err != nil {
+		return nil, err
+	}
+	checker, err := newConnectChecker(task.State(), deviceCtx)
+	if err != nil {
+		return nil, err
+	}
+	return checker.check, nil
+}
+
 func getPlugAndSlotRefs(task *state.Task) (interfaces.PlugRef, interfaces.SlotRef, error) {
 	var plugRef interfaces.PlugRef
 	var slotRef interfaces.SlotRef
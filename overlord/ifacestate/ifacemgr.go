@@ -53,6 +53,8 @@ type InterfaceManager struct {
 	udevMon             udevmonitor.Interface
 	udevRetryTimeout    time.Time
 	udevMonitorDisabled bool
+
+	autoConnectRetryCheck time.Time
 	// indexed by interface name and device key. Reset to nil when enumeration is done.
 	enumeratedDeviceKeys map[string]map[snap.HotplugKey]bool
 	enumerationDone      bool
@@ -166,7 +168,7 @@ func (m *InterfaceManager) StartUp() error {
 	if err := removeStaleConnections(m.state); err != nil {
 		return err
 	}
-	if _, err := m.reloadConnections(""); err != nil {
+	if _, err := m.reloadConnections("", nil); err != nil {
 		return err
 	}
 	if profilesNeedRegeneration() {
@@ -196,6 +198,10 @@ func (m *InterfaceManager) Ensure() error {
 		return nil
 	}
 
+	if err := m.retryAutoConnections(); err != nil {
+		logger.Noticef("cannot retry auto-connections: %v", err)
+	}
+
 	if m.udevMonitorDisabled {
 		return nil
 	}
@@ -268,6 +274,9 @@ type ConnectionState struct {
 	StaticSlotAttrs  map[string]interface{}
 	DynamicSlotAttrs map[string]interface{}
 	HotplugGone      bool
+	// Stale indicates that the plug or slot of the connection, or their
+	// interface, no longer exist in the current revision of the snaps
+	Stale bool
 }
 
 // Active returns true if connection is not undesired and not removed by
@@ -299,6 +308,7 @@ func ConnectionStates(st *state.State) (connStateByRef map[string]ConnectionStat
 			StaticSlotAttrs:  cstate.StaticSlotAttrs,
 			DynamicSlotAttrs: cstate.DynamicSlotAttrs,
 			HotplugGone:      cstate.HotplugGone,
+			Stale:            cstate.Stale,
 		}
 	}
 	return connStateByRef, nil
@@ -3124,6 +3124,8 @@ slots:
 
 // LP:#1825883; make sure static attributes in conns state are updated from the snap yaml on snap refresh (content interface only)
 func (s *interfaceManagerSuite) testDoSetupProfilesUpdatesStaticAttributes(c *C, snapNameToSetup string) {
+	s.MockModel(c, nil)
+
 	// Put a connection in the state. The connection binds the two snaps we are
 	// adding below. The connection reflects the snaps as they are now, and
 	// carries no attribute data.
@@ -3424,6 +3426,131 @@ slots:
 	})
 }
 
+func (s *interfaceManagerSuite) testSetupProfilesRefreshesConnection(c *C, consumerV2Yaml string, beforeConnectErr error, expectedBeforeConnectCalls int, expectedConns map[string]interface{}) {
+	s.MockModel(c, nil)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":    "test",
+			"plug-static":  map[string]interface{}{"attr": "old"},
+			"plug-dynamic": map[string]interface{}{"dynamic": "value"},
+		},
+	})
+	s.state.Unlock()
+
+	const consumerV1Yaml = `
+name: consumer
+version: 1
+plugs:
+ plug:
+  interface: test
+  attr: old
+`
+	const producerYaml = `
+name: producer
+version: 1
+slots:
+ slot:
+  interface: test
+`
+	s.mockSnap(c, producerYaml)
+	s.mockSnap(c, consumerV1Yaml)
+	snaptest.MockSnapInstance(c, "", consumerV2Yaml, &snap.SideInfo{Revision: snap.R(2)})
+
+	var beforeConnectCalls int
+	s.mockIfaces(&ifacetest.TestInterface{
+		InterfaceName: "test",
+		BeforeConnectPlugCallback: func(plug *interfaces.ConnectedPlug) error {
+			beforeConnectCalls++
+			var attr string
+			c.Check(plug.Attr("attr", &attr), IsNil)
+			c.Check(attr, Equals, "new")
+			return beforeConnectErr
+		},
+	}, &ifacetest.TestInterface{InterfaceName: "test2"})
+	secBackend := &ifacetest.TestSecurityBackend{BackendName: "test"}
+	s.mockSecBackend(secBackend)
+
+	s.manager(c)
+
+	s.state.Lock()
+	snapstate.Set(s.state, "consumer", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{Revision: snap.R(1)}, {Revision: snap.R(2)}},
+		Current:  snap.R(2),
+		SnapType: string("app"),
+	})
+	change := s.state.NewChange("test", "")
+	task := s.state.NewTask("setup-profiles", "")
+	task.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "consumer", Revision: snap.R(2)}})
+	change.AddTask(task)
+	s.state.Unlock()
+
+	s.settle(c)
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+
+	// the interface validates the connection again only when its
+	// attributes changed
+	c.Check(beforeConnectCalls, Equals, expectedBeforeConnectCalls)
+
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, expectedConns)
+
+	// the producer is affected by the refresh, its profiles are updated
+	// even if the connection was removed
+	var setupSnaps []string
+	for _, call := range secBackend.SetupCalls[len(secBackend.SetupCalls)-2:] {
+		setupSnaps = append(setupSnaps, call.SnapInfo.InstanceName())
+	}
+	c.Check(setupSnaps, DeepEquals, []string{"consumer", "producer"})
+}
+
+const consumerV2AttrChangedYaml = `
+name: consumer
+version: 2
+plugs:
+ plug:
+  interface: test
+  attr: new
+`
+
+func (s *interfaceManagerSuite) TestSetupProfilesRefreshesConnectionAttributes(c *C) {
+	s.testSetupProfilesRefreshesConnection(c, consumerV2AttrChangedYaml, nil, 1, map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":    "test",
+			"plug-static":  map[string]interface{}{"attr": "new"},
+			"plug-dynamic": map[string]interface{}{"dynamic": "value"},
+		},
+	})
+}
+
+func (s *interfaceManagerSuite) TestSetupProfilesRemovesConnectionNotAllowedAnymore(c *C) {
+	s.testSetupProfilesRefreshesConnection(c, consumerV2AttrChangedYaml, fmt.Errorf("attr not allowed"), 1, map[string]interface{}{})
+}
+
+func (s *interfaceManagerSuite) TestSetupProfilesFlagsConnectionWithInterfaceGone(c *C) {
+	const consumerV2Yaml = `
+name: consumer
+version: 2
+plugs:
+ plug:
+  interface: test2
+`
+	s.testSetupProfilesRefreshesConnection(c, consumerV2Yaml, nil, 0, map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":    "test",
+			"plug-static":  map[string]interface{}{"attr": "old"},
+			"plug-dynamic": map[string]interface{}{"dynamic": "value"},
+			"stale":        true,
+		},
+	})
+}
+
 func (s *interfaceManagerSuite) TestDoSetupSnapSecurityIgnoresStrayConnection(c *C) {
 	s.MockModel(c, nil)
 
@@ -3755,8 +3882,9 @@ func (s *interfaceManagerSuite) TestSetupProfilesRemovesMissingAutoconnectedPlug
 func (s *interfaceManagerSuite) TestSetupProfilesKeepsMissingGadgetAutoconnectedPlugs(c *C) {
 	undesired := false
 	byGadget := true
+	// the connection is kept, but flagged as its plug is gone
 	s.testAutoconnectionsRemovedForMissingPlugs(c, undesired, byGadget, map[string]interface{}{
-		"snap:test1 ubuntu-core:test1": map[string]interface{}{"interface": "test1", "auto": true, "by-gadget": true},
+		"snap:test1 ubuntu-core:test1": map[string]interface{}{"interface": "test1", "auto": true, "by-gadget": true, "stale": true},
 		"snap:test2 ubuntu-core:test2": map[string]interface{}{"interface": "test2", "auto": true},
 	})
 }
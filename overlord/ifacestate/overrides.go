@@ -0,0 +1,198 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/ifacestate/schema"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Kinds of connection overrides remembered in the state.
+const (
+	// OverrideUndesired marks a connection that was manually disconnected
+	// and must not be automatically connected again.
+	OverrideUndesired = "undesired"
+	// OverrideByGadget marks an automatic connection that was requested
+	// by the gadget.
+	OverrideByGadget = "by-gadget"
+)
+
+// ErrNoConnectionOverride is returned when removing a connection override
+// that is not recorded.
+var ErrNoConnectionOverride = errors.New("no such connection override")
+
+// ConnectionOverride describes how a connection deviates from what the
+// auto-connection policy alone would establish.
+type ConnectionOverride struct {
+	Ref       interfaces.ConnRef
+	Interface string
+	Kind      string
+}
+
+func checkOverrideKind(kind string) error {
+	switch kind {
+	case OverrideUndesired, OverrideByGadget:
+		return nil
+	}
+	return fmt.Errorf("unsupported connection override kind %q", kind)
+}
+
+// ConnectionOverrides returns the connection overrides recorded in the
+// state, sorted by connection and kind.
+//
+// The state must be locked by the caller.
+func ConnectionOverrides(st *state.State) ([]*ConnectionOverride, error) {
+	conns, err := getConns(st)
+	if err != nil {
+		return nil, err
+	}
+
+	var overrides []*ConnectionOverride
+	for id, conn := range conns {
+		if !conn.Undesired && !conn.ByGadget {
+			continue
+		}
+		connRef, err := interfaces.ParseConnRef(id)
+		if err != nil {
+			return nil, err
+		}
+		// a connection can be requested by the gadget and disconnected
+		// manually afterwards
+		if conn.ByGadget {
+			overrides = append(overrides, &ConnectionOverride{Ref: *connRef, Interface: conn.Interface, Kind: OverrideByGadget})
+		}
+		if conn.Undesired {
+			overrides = append(overrides, &ConnectionOverride{Ref: *connRef, Interface: conn.Interface, Kind: OverrideUndesired})
+		}
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		if overrides[i].Ref.ID() != overrides[j].Ref.ID() {
+			return overrides[i].Ref.SortsBefore(&overrides[j].Ref)
+		}
+		return overrides[i].Kind < overrides[j].Kind
+	})
+	return overrides, nil
+}
+
+// AddConnectionOverride records the given connection override.
+//
+// An undesired override can be recorded for a connection that is not
+// established, which then must name its interface, and prevents the
+// connection from being established automatically. A by-gadget override
+// can only be recorded for an automatic connection.
+//
+// The state must be locked by the caller.
+func AddConnectionOverride(st *state.State, override *ConnectionOverride) error {
+	if err := checkOverrideKind(override.Kind); err != nil {
+		return err
+	}
+	connRef := &override.Ref
+	if err := snapstate.CheckChangeConflictMany(st, []string{connRef.PlugRef.Snap, connRef.SlotRef.Snap}, ""); err != nil {
+		return err
+	}
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+
+	id := connRef.ID()
+	conn, ok := conns[id]
+	switch override.Kind {
+	case OverrideUndesired:
+		if !ok {
+			if override.Interface == "" {
+				return fmt.Errorf("cannot record undesired connection %q: interface not specified", id)
+			}
+			if err := snap.ValidateInterfaceName(override.Interface); err != nil {
+				return fmt.Errorf("cannot record undesired connection %q: %v", id, err)
+			}
+			conn = &schema.ConnState{Auto: true, Interface: override.Interface}
+			conns[id] = conn
+		} else {
+			if override.Interface != "" && override.Interface != conn.Interface {
+				return fmt.Errorf("cannot record undesired connection %q: connection uses interface %q", id, conn.Interface)
+			}
+			if !conn.Undesired && !conn.HotplugGone {
+				return fmt.Errorf("cannot record undesired connection %q: connection is established, disconnect it instead", id)
+			}
+		}
+		conn.Undesired = true
+	case OverrideByGadget:
+		if !ok || !conn.Auto {
+			return fmt.Errorf("cannot record connection %q as requested by the gadget: no such automatic connection", id)
+		}
+		if override.Interface != "" && override.Interface != conn.Interface {
+			return fmt.Errorf("cannot record connection %q as requested by the gadget: connection uses interface %q", id, conn.Interface)
+		}
+		conn.ByGadget = true
+	}
+	setConns(st, conns)
+	return nil
+}
+
+// RemoveConnectionOverride removes the override of the given kind recorded
+// for the given connection. Removing an undesired override forgets the
+// connection, which may then be established automatically again, as with
+// "snap disconnect --forget". ErrNoConnectionOverride is returned if no
+// such override is recorded.
+//
+// The state must be locked by the caller.
+func RemoveConnectionOverride(st *state.State, connRef *interfaces.ConnRef, kind string) error {
+	if err := checkOverrideKind(kind); err != nil {
+		return err
+	}
+	if err := snapstate.CheckChangeConflictMany(st, []string{connRef.PlugRef.Snap, connRef.SlotRef.Snap}, ""); err != nil {
+		return err
+	}
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+
+	id := connRef.ID()
+	conn, ok := conns[id]
+	switch kind {
+	case OverrideUndesired:
+		if !ok || !conn.Undesired {
+			return ErrNoConnectionOverride
+		}
+		if conn.HotplugGone {
+			// keep the connection to restore it when the device
+			// is seen again
+			conn.Undesired = false
+		} else {
+			delete(conns, id)
+		}
+	case OverrideByGadget:
+		if !ok || !conn.ByGadget {
+			return ErrNoConnectionOverride
+		}
+		conn.ByGadget = false
+	}
+	setConns(st, conns)
+	return nil
+}
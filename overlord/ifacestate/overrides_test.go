@@ -0,0 +1,229 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *interfaceManagerSuite) TestConnectionOverrides(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot":   map[string]interface{}{"interface": "test", "auto": true, "by-gadget": true, "undesired": true},
+		"consumer:plug2 producer:slot2": map[string]interface{}{"interface": "test2", "auto": true, "undesired": true},
+		"consumer:plug3 producer:slot3": map[string]interface{}{"interface": "test3", "auto": true, "by-gadget": true},
+		"consumer:plug4 producer:slot4": map[string]interface{}{"interface": "test4", "auto": true},
+		"consumer:plug5 producer:slot5": map[string]interface{}{"interface": "test5"},
+	})
+
+	overrides, err := ifacestate.ConnectionOverrides(s.state)
+	c.Assert(err, IsNil)
+	ref := func(plug, slot string) interfaces.ConnRef {
+		return interfaces.ConnRef{
+			PlugRef: interfaces.PlugRef{Snap: "consumer", Name: plug},
+			SlotRef: interfaces.SlotRef{Snap: "producer", Name: slot},
+		}
+	}
+	c.Check(overrides, DeepEquals, []*ifacestate.ConnectionOverride{
+		{Ref: ref("plug", "slot"), Interface: "test", Kind: "by-gadget"},
+		{Ref: ref("plug", "slot"), Interface: "test", Kind: "undesired"},
+		{Ref: ref("plug2", "slot2"), Interface: "test2", Kind: "undesired"},
+		{Ref: ref("plug3", "slot3"), Interface: "test3", Kind: "by-gadget"},
+	})
+}
+
+func (s *interfaceManagerSuite) TestConnectionOverridesNone(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	overrides, err := ifacestate.ConnectionOverrides(s.state)
+	c.Assert(err, IsNil)
+	c.Check(overrides, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestAddConnectionOverrideUndesired(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "hotplug-gone": true, "hotplug-key": "1234"},
+	})
+	connRef := interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}
+	// a connection of a removed device
+	err := ifacestate.AddConnectionOverride(s.state, &ifacestate.ConnectionOverride{Ref: connRef, Kind: "undesired"})
+	c.Assert(err, IsNil)
+
+	// a connection never established
+	otherRef := interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "otherplug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "otherslot"},
+	}
+	err = ifacestate.AddConnectionOverride(s.state, &ifacestate.ConnectionOverride{Ref: otherRef, Interface: "test2", Kind: "undesired"})
+	c.Assert(err, IsNil)
+
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot":           map[string]interface{}{"interface": "test", "hotplug-gone": true, "hotplug-key": "1234", "undesired": true},
+		"consumer:otherplug producer:otherslot": map[string]interface{}{"interface": "test2", "auto": true, "undesired": true},
+	})
+}
+
+func (s *interfaceManagerSuite) TestAddConnectionOverrideErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot":   map[string]interface{}{"interface": "test", "auto": true},
+		"consumer:plug2 producer:slot2": map[string]interface{}{"interface": "test2"},
+	})
+	ref := func(plug, slot string) interfaces.ConnRef {
+		return interfaces.ConnRef{
+			PlugRef: interfaces.PlugRef{Snap: "consumer", Name: plug},
+			SlotRef: interfaces.SlotRef{Snap: "producer", Name: slot},
+		}
+	}
+
+	for _, t := range []struct {
+		override *ifacestate.ConnectionOverride
+		err      string
+	}{
+		{&ifacestate.ConnectionOverride{Ref: ref("plug", "slot"), Kind: "foo"}, `unsupported connection override kind "foo"`},
+		{&ifacestate.ConnectionOverride{Ref: ref("plug", "slot"), Kind: "undesired"}, `cannot record undesired connection "consumer:plug producer:slot": connection is established, disconnect it instead`},
+		{&ifacestate.ConnectionOverride{Ref: ref("plug", "slot"), Interface: "other", Kind: "undesired"}, `cannot record undesired connection "consumer:plug producer:slot": connection uses interface "test"`},
+		{&ifacestate.ConnectionOverride{Ref: ref("plug3", "slot3"), Kind: "undesired"}, `cannot record undesired connection "consumer:plug3 producer:slot3": interface not specified`},
+		{&ifacestate.ConnectionOverride{Ref: ref("plug3", "slot3"), Interface: "Bad_Name", Kind: "undesired"}, `cannot record undesired connection "consumer:plug3 producer:slot3": invalid interface name: "Bad_Name"`},
+		{&ifacestate.ConnectionOverride{Ref: ref("plug2", "slot2"), Kind: "by-gadget"}, `cannot record connection "consumer:plug2 producer:slot2" as requested by the gadget: no such automatic connection`},
+		{&ifacestate.ConnectionOverride{Ref: ref("plug3", "slot3"), Kind: "by-gadget"}, `cannot record connection "consumer:plug3 producer:slot3" as requested by the gadget: no such automatic connection`},
+		{&ifacestate.ConnectionOverride{Ref: ref("plug", "slot"), Interface: "other", Kind: "by-gadget"}, `cannot record connection "consumer:plug producer:slot" as requested by the gadget: connection uses interface "test"`},
+	} {
+		err := ifacestate.AddConnectionOverride(s.state, t.override)
+		c.Check(err, ErrorMatches, t.err)
+	}
+
+	// nothing was changed
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot":   map[string]interface{}{"interface": "test", "auto": true},
+		"consumer:plug2 producer:slot2": map[string]interface{}{"interface": "test2"},
+	})
+}
+
+func (s *interfaceManagerSuite) TestAddConnectionOverrideByGadget(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "auto": true},
+	})
+	connRef := interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}
+	err := ifacestate.AddConnectionOverride(s.state, &ifacestate.ConnectionOverride{Ref: connRef, Interface: "test", Kind: "by-gadget"})
+	c.Assert(err, IsNil)
+
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "auto": true, "by-gadget": true},
+	})
+}
+
+func (s *interfaceManagerSuite) TestRemoveConnectionOverride(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot":   map[string]interface{}{"interface": "test", "auto": true, "by-gadget": true, "undesired": true},
+		"consumer:plug2 producer:slot2": map[string]interface{}{"interface": "test2", "undesired": true, "hotplug-gone": true, "hotplug-key": "1234"},
+	})
+	ref := func(plug, slot string) *interfaces.ConnRef {
+		return &interfaces.ConnRef{
+			PlugRef: interfaces.PlugRef{Snap: "consumer", Name: plug},
+			SlotRef: interfaces.SlotRef{Snap: "producer", Name: slot},
+		}
+	}
+
+	err := ifacestate.RemoveConnectionOverride(s.state, ref("plug", "slot"), "by-gadget")
+	c.Assert(err, IsNil)
+	err = ifacestate.RemoveConnectionOverride(s.state, ref("plug", "slot"), "by-gadget")
+	c.Check(err, Equals, ifacestate.ErrNoConnectionOverride)
+
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns["consumer:plug producer:slot"], DeepEquals, map[string]interface{}{"interface": "test", "auto": true, "undesired": true})
+
+	// the connection is forgotten
+	err = ifacestate.RemoveConnectionOverride(s.state, ref("plug", "slot"), "undesired")
+	c.Assert(err, IsNil)
+	// but the connection of a removed device is kept
+	err = ifacestate.RemoveConnectionOverride(s.state, ref("plug2", "slot2"), "undesired")
+	c.Assert(err, IsNil)
+	err = ifacestate.RemoveConnectionOverride(s.state, ref("plug2", "slot2"), "undesired")
+	c.Check(err, Equals, ifacestate.ErrNoConnectionOverride)
+	err = ifacestate.RemoveConnectionOverride(s.state, ref("plug3", "slot3"), "undesired")
+	c.Check(err, Equals, ifacestate.ErrNoConnectionOverride)
+	err = ifacestate.RemoveConnectionOverride(s.state, ref("plug3", "slot3"), "foo")
+	c.Check(err, ErrorMatches, `unsupported connection override kind "foo"`)
+
+	conns = nil
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug2 producer:slot2": map[string]interface{}{"interface": "test2", "hotplug-gone": true, "hotplug-key": "1234"},
+	})
+}
+
+func (s *interfaceManagerSuite) TestConnectionOverrideConflicts(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "auto": true, "undesired": true},
+	})
+	chg := s.state.NewChange("other-chg", "...")
+	t := s.state.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "producer"},
+	})
+	chg.AddTask(t)
+
+	connRef := interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}
+	err := ifacestate.AddConnectionOverride(s.state, &ifacestate.ConnectionOverride{Ref: connRef, Kind: "by-gadget"})
+	c.Check(err, testutil.ErrorIs, &snapstate.ChangeConflictError{})
+	err = ifacestate.RemoveConnectionOverride(s.state, &connRef, "undesired")
+	c.Check(err, ErrorMatches, `snap "producer" has "other-chg" change in progress`)
+}
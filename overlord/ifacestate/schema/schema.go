@@ -41,4 +41,8 @@ type ConnState struct {
 	// slots.
 	HotplugGone bool            `json:"hotplug-gone,omitempty" yaml:"hotplug-gone,omitempty"`
 	HotplugKey  snap.HotplugKey `json:"hotplug-key,omitempty" yaml:"hotplug-key,omitempty"`
+	// Stale indicates a connection that cannot be restored because its
+	// plug or slot, or their interface, is gone from the current
+	// revision of the snaps. It is kept in case of revert.
+	Stale bool `json:"stale,omitempty" yaml:"stale,omitempty"`
 }
@@ -0,0 +1,376 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Reasons for which a plug is left unconnected.
+const (
+	// UnsatisfiedNoCandidateSlot is used when no slot of the interface
+	// of the plug is available.
+	UnsatisfiedNoCandidateSlot = "no-candidate-slot"
+	// UnsatisfiedPolicyDenied is used when slots of the interface of the
+	// plug are available but the policy does not allow to auto-connect
+	// the plug to exactly one of them.
+	UnsatisfiedPolicyDenied = "policy-denied"
+	// UnsatisfiedGadgetPending is used when the gadget requests the
+	// connection of the plug to a slot which is not available.
+	UnsatisfiedGadgetPending = "gadget-pending"
+)
+
+var (
+	// autoConnectRetryCheckInterval is the minimum interval between
+	// evaluations of the unsatisfied plugs.
+	autoConnectRetryCheckInterval = time.Minute
+	// autoConnectRetryInitialDelay is the delay before the first retry,
+	// doubled after each failed retry up to autoConnectRetryMaxDelay.
+	autoConnectRetryInitialDelay = 5 * time.Minute
+	autoConnectRetryMaxDelay     = 6 * time.Hour
+	// maxAutoConnectRetries is the number of retries after which the
+	// auto-connection of a plug is given up.
+	maxAutoConnectRetries = 10
+
+	timeNow = time.Now
+)
+
+// autoConnectRetry records the retries of the auto-connection of a plug.
+type autoConnectRetry struct {
+	Attempts int `json:"attempts"`
+	// NextRetry is zero once the auto-connection is given up.
+	NextRetry time.Time `json:"next-retry"`
+}
+
+// UnsatisfiedPlug describes a plug of a snap which is neither connected
+// nor was explicitly disconnected.
+type UnsatisfiedPlug struct {
+	Plug      interfaces.PlugRef
+	Interface string
+	// Reason is one of the Unsatisfied* constants.
+	Reason string
+	// Attempts is the number of times the auto-connection of the plug
+	// was retried.
+	Attempts int
+	// NextRetry is the time of the next retry, zero if the plug is not
+	// retried.
+	NextRetry time.Time
+}
+
+func autoConnectRetryDelay(attempts int) time.Duration {
+	delay := autoConnectRetryInitialDelay
+	for i := 0; i < attempts && delay < autoConnectRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > autoConnectRetryMaxDelay {
+		delay = autoConnectRetryMaxDelay
+	}
+	return delay
+}
+
+func getAutoConnectRetries(st *state.State) (map[string]*autoConnectRetry, error) {
+	var retries map[string]*autoConnectRetry
+	err := st.Get("auto-connect-retries", &retries)
+	if err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, fmt.Errorf("internal error: cannot get auto-connect retries: %v", err)
+	}
+	if retries == nil {
+		retries = make(map[string]*autoConnectRetry)
+	}
+	return retries, nil
+}
+
+func setAutoConnectRetries(st *state.State, retries map[string]*autoConnectRetry) {
+	if len(retries) == 0 {
+		st.Set("auto-connect-retries", nil)
+		return
+	}
+	st.Set("auto-connect-retries", retries)
+}
+
+// unsatisfiedPlugsEvaluator finds why plugs are not connected and which
+// slots they could be connected to.
+type unsatisfiedPlugsEvaluator struct {
+	repo    *interfaces.Repository
+	checker *autoConnectChecker
+	// plugged maps the plugs with a connection, including undesired
+	// ones, to true.
+	plugged map[string]bool
+	// gadgetSlots maps plugs to the slots the gadget requests them to
+	// be connected to.
+	gadgetSlots map[string]interfaces.SlotRef
+}
+
+func newUnsatisfiedPlugsEvaluator(st *state.State, repo *interfaces.Repository, deviceCtx snapstate.DeviceContext) (*unsatisfiedPlugsEvaluator, error) {
+	checker, err := newAutoConnectChecker(st, nil, repo, deviceCtx)
+	if err != nil {
+		return nil, err
+	}
+	conns, err := getConns(st)
+	if err != nil {
+		return nil, err
+	}
+	e := &unsatisfiedPlugsEvaluator{
+		repo:        repo,
+		checker:     checker,
+		plugged:     make(map[string]bool, len(conns)),
+		gadgetSlots: make(map[string]interfaces.SlotRef),
+	}
+	for id, conn := range conns {
+		if conn.HotplugGone {
+			continue
+		}
+		connRef, err := interfaces.ParseConnRef(id)
+		if err != nil {
+			return nil, err
+		}
+		e.plugged[connRef.PlugRef.String()] = true
+	}
+
+	gconns, err := snapstate.GadgetConnections(st, deviceCtx)
+	if err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, err
+	}
+	for _, gconn := range gconns {
+		plugSnapName, err := resolveSnapIDToName(st, gconn.Plug.SnapID)
+		if err != nil {
+			return nil, err
+		}
+		if plugSnapName == "" {
+			continue
+		}
+		slotSnapName, err := resolveSnapIDToName(st, gconn.Slot.SnapID)
+		if err != nil {
+			return nil, err
+		}
+		plugRef := interfaces.PlugRef{Snap: plugSnapName, Name: gconn.Plug.Plug}
+		e.gadgetSlots[plugRef.String()] = interfaces.SlotRef{Snap: slotSnapName, Name: gconn.Slot.Slot}
+	}
+	return e, nil
+}
+
+// evaluate returns the slots the given unconnected plug can be connected
+// to and whether they were requested by the gadget, or the reason why it
+// cannot be connected.
+func (e *unsatisfiedPlugsEvaluator) evaluate(plug *snap.PlugInfo) (slots []*snap.SlotInfo, byGadget bool, reason string) {
+	plugRef := interfaces.PlugRef{Snap: plug.Snap.InstanceName(), Name: plug.Name}
+	if slotRef, ok := e.gadgetSlots[plugRef.String()]; ok {
+		slot := e.repo.Slot(slotRef.Snap, slotRef.Name)
+		if slot == nil {
+			return nil, false, UnsatisfiedGadgetPending
+		}
+		return []*snap.SlotInfo{slot}, true, ""
+	}
+
+	candSlots, arities := e.repo.AutoConnectCandidateSlots(plugRef.Snap, plugRef.Name, e.checker.check)
+	candSlots, arities = filterUbuntuCoreSlots(candSlots, arities)
+	for _, arity := range arities {
+		// ATM not any (*) => none or exactly one
+		if !arity.SlotsPerPlugAny() && len(candSlots) != 1 {
+			candSlots = nil
+			break
+		}
+	}
+	if len(candSlots) > 0 {
+		return candSlots, false, ""
+	}
+	if len(e.repo.AllSlots(plug.Interface)) == 0 {
+		return nil, false, UnsatisfiedNoCandidateSlot
+	}
+	return nil, false, UnsatisfiedPolicyDenied
+}
+
+// UnsatisfiedPlugs returns the plugs which are neither connected nor were
+// explicitly disconnected, along with the reason why they are not
+// connected. Plugs which can be auto-connected and are waiting for the
+// connection to be retried are not included.
+func (m *InterfaceManager) UnsatisfiedPlugs() ([]*UnsatisfiedPlug, error) {
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	deviceCtx, err := snapstate.DeviceCtx(st, nil, nil)
+	if err != nil {
+		// no model yet, nothing can be auto-connected
+		return nil, nil
+	}
+	evaluator, err := newUnsatisfiedPlugsEvaluator(st, m.repo, deviceCtx)
+	if err != nil {
+		return nil, err
+	}
+	retries, err := getAutoConnectRetries(st)
+	if err != nil {
+		return nil, err
+	}
+
+	var unsatisfied []*UnsatisfiedPlug
+	for _, plug := range m.repo.AllPlugs("") {
+		plugRef := interfaces.PlugRef{Snap: plug.Snap.InstanceName(), Name: plug.Name}
+		if evaluator.plugged[plugRef.String()] {
+			continue
+		}
+		_, _, reason := evaluator.evaluate(plug)
+		if reason == "" {
+			continue
+		}
+		u := &UnsatisfiedPlug{
+			Plug:      plugRef,
+			Interface: plug.Interface,
+			Reason:    reason,
+		}
+		if retry := retries[plugRef.String()]; retry != nil && reason != UnsatisfiedPolicyDenied {
+			u.Attempts = retry.Attempts
+			u.NextRetry = retry.NextRetry
+		}
+		unsatisfied = append(unsatisfied, u)
+	}
+	return unsatisfied, nil
+}
+
+// retryAutoConnections re-evaluates the plugs which are neither connected
+// nor were explicitly disconnected, and connects those for which a slot
+// became available. Plugs denied by the policy are not retried, the others
+// are retried with an increasing delay, up to maxAutoConnectRetries times.
+// The retries are recorded in the state.
+func (m *InterfaceManager) retryAutoConnections() error {
+	now := timeNow()
+	if now.Before(m.autoConnectRetryCheck) {
+		return nil
+	}
+	m.autoConnectRetryCheck = now.Add(autoConnectRetryCheckInterval)
+
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	var seeded bool
+	if err := st.Get("seeded", &seeded); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	if !seeded {
+		return nil
+	}
+	deviceCtx, err := snapstate.DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil
+	}
+	evaluator, err := newUnsatisfiedPlugsEvaluator(st, m.repo, deviceCtx)
+	if err != nil {
+		return err
+	}
+	retries, err := getAutoConnectRetries(st)
+	if err != nil {
+		return err
+	}
+	changed := false
+
+	// forget about plugs which are gone or were connected since
+	plugs := m.repo.AllPlugs("")
+	current := make(map[string]bool, len(plugs))
+	for _, plug := range plugs {
+		plugRef := interfaces.PlugRef{Snap: plug.Snap.InstanceName(), Name: plug.Name}
+		if !evaluator.plugged[plugRef.String()] {
+			current[plugRef.String()] = true
+		}
+	}
+	for key := range retries {
+		if !current[key] {
+			delete(retries, key)
+			changed = true
+		}
+	}
+
+	ts := state.NewTaskSet()
+	for _, plug := range plugs {
+		plugRef := interfaces.PlugRef{Snap: plug.Snap.InstanceName(), Name: plug.Name}
+		key := plugRef.String()
+		if !current[key] {
+			continue
+		}
+		slots, byGadget, reason := evaluator.evaluate(plug)
+		if reason == UnsatisfiedPolicyDenied {
+			if _, ok := retries[key]; ok {
+				delete(retries, key)
+				changed = true
+			}
+			continue
+		}
+
+		retry := retries[key]
+		if retry == nil {
+			// give the regular auto-connection logic a chance first
+			retries[key] = &autoConnectRetry{NextRetry: now.Add(autoConnectRetryDelay(0))}
+			changed = true
+			continue
+		}
+		if retry.NextRetry.IsZero() || now.Before(retry.NextRetry) {
+			continue
+		}
+
+		if len(slots) == 0 {
+			retry.Attempts++
+			if retry.Attempts >= maxAutoConnectRetries {
+				logger.Noticef("giving up auto-connection of plug %s: %s", plug, reason)
+				retry.NextRetry = time.Time{}
+			} else {
+				retry.NextRetry = now.Add(autoConnectRetryDelay(retry.Attempts))
+			}
+			changed = true
+			continue
+		}
+
+		slotSnapNames := make([]string, 0, len(slots))
+		for _, slot := range slots {
+			slotSnapNames = append(slotSnapNames, slot.Snap.InstanceName())
+		}
+		if err := snapstate.CheckChangeConflictMany(st, append(slotSnapNames, plugRef.Snap), ""); err != nil {
+			// try again once the snaps are not busy anymore
+			continue
+		}
+		for _, slot := range slots {
+			connectTs, err := connect(st, plugRef.Snap, plugRef.Name, slot.Snap.InstanceName(), slot.Name, connectOpts{AutoConnect: true, ByGadget: byGadget})
+			if err != nil {
+				return fmt.Errorf("cannot retry auto-connection of plug %s: %v", plug, err)
+			}
+			ts.AddAll(connectTs)
+		}
+		delete(retries, key)
+		changed = true
+	}
+
+	if changed {
+		setAutoConnectRetries(st, retries)
+	}
+	if len(ts.Tasks()) > 0 {
+		chg := st.NewChange("auto-connect-retry", i18n.G("Retry auto-connection of unsatisfied plugs"))
+		chg.AddAll(ts)
+		st.EnsureBefore(0)
+	}
+	return nil
+}
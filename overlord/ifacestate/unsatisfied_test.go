@@ -0,0 +1,278 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/testutil"
+)
+
+const networkConsumerYaml = `
+name: consumer
+version: 1
+plugs:
+ network:
+`
+
+const networkCoreYaml = `
+name: core
+version: 1
+type: os
+slots:
+ network:
+`
+
+func (s *interfaceManagerSuite) mockRetryTime(c *C) *time.Time {
+	now := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	s.AddCleanup(ifacestate.MockTimeNow(func() time.Time { return now }))
+	return &now
+}
+
+func (s *interfaceManagerSuite) autoConnectRetries(c *C) map[string]interface{} {
+	s.state.Lock()
+	defer s.state.Unlock()
+	var retries map[string]interface{}
+	err := s.state.Get("auto-connect-retries", &retries)
+	if err != nil {
+		c.Assert(err, testutil.ErrorIs, state.ErrNoState)
+	}
+	return retries
+}
+
+func (s *interfaceManagerSuite) TestRetryAutoConnectionSlotSnapArrivesLater(c *C) {
+	s.MockModel(c, nil)
+	now := s.mockRetryTime(c)
+
+	s.state.Lock()
+	s.state.Set("seeded", true)
+	s.state.Unlock()
+
+	s.mockSnap(c, networkConsumerYaml)
+	mgr := s.manager(c)
+
+	// the plug is recorded for retries but not retried right away
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(s.autoConnectRetries(c), DeepEquals, map[string]interface{}{
+		"consumer:network": map[string]interface{}{
+			"attempts":   0.0,
+			"next-retry": "2023-04-01T12:05:00Z",
+		},
+	})
+	unsatisfied, err := mgr.UnsatisfiedPlugs()
+	c.Assert(err, IsNil)
+	c.Check(unsatisfied, DeepEquals, []*ifacestate.UnsatisfiedPlug{{
+		Plug:      interfaces.PlugRef{Snap: "consumer", Name: "network"},
+		Interface: "network",
+		Reason:    ifacestate.UnsatisfiedNoCandidateSlot,
+		NextRetry: now.Add(5 * time.Minute),
+	}})
+
+	// evaluations are rate limited
+	*now = now.Add(30 * time.Second)
+	c.Assert(mgr.Ensure(), IsNil)
+
+	// a retry without slot backs off
+	*now = now.Add(5 * time.Minute)
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(s.autoConnectRetries(c), DeepEquals, map[string]interface{}{
+		"consumer:network": map[string]interface{}{
+			"attempts":   1.0,
+			"next-retry": "2023-04-01T12:15:30Z",
+		},
+	})
+
+	// the slot snap arrives without the plug being auto-connected
+	coreInfo := s.mockSnap(c, networkCoreYaml)
+	c.Assert(mgr.Repository().AddSnap(coreInfo), IsNil)
+
+	// nothing happens before the next retry
+	*now = now.Add(5 * time.Minute)
+	c.Assert(mgr.Ensure(), IsNil)
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 0)
+	s.state.Unlock()
+	unsatisfied, err = mgr.UnsatisfiedPlugs()
+	c.Assert(err, IsNil)
+	c.Check(unsatisfied, HasLen, 0)
+
+	// the plug gets connected at the next retry
+	*now = now.Add(5 * time.Minute)
+	c.Assert(mgr.Ensure(), IsNil)
+	s.state.Lock()
+	chgs := s.state.Changes()
+	c.Assert(chgs, HasLen, 1)
+	c.Check(chgs[0].Kind(), Equals, "auto-connect-retry")
+	s.state.Unlock()
+	c.Check(s.autoConnectRetries(c), IsNil)
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chgs[0].Err(), IsNil)
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:network core:network": map[string]interface{}{
+			"interface": "network",
+			"auto":      true,
+		},
+	})
+}
+
+func (s *interfaceManagerSuite) TestRetryAutoConnectionGivesUp(c *C) {
+	s.MockModel(c, nil)
+	now := s.mockRetryTime(c)
+
+	s.state.Lock()
+	s.state.Set("seeded", true)
+	s.state.Unlock()
+
+	s.mockSnap(c, networkConsumerYaml)
+	mgr := s.manager(c)
+
+	c.Assert(mgr.Ensure(), IsNil)
+	for i := 0; i < 10; i++ {
+		*now = now.Add(6 * time.Hour)
+		c.Assert(mgr.Ensure(), IsNil)
+	}
+	unsatisfied, err := mgr.UnsatisfiedPlugs()
+	c.Assert(err, IsNil)
+	c.Check(unsatisfied, DeepEquals, []*ifacestate.UnsatisfiedPlug{{
+		Plug:      interfaces.PlugRef{Snap: "consumer", Name: "network"},
+		Interface: "network",
+		Reason:    ifacestate.UnsatisfiedNoCandidateSlot,
+		Attempts:  10,
+	}})
+
+	// the slot appears but the plug is not retried anymore
+	coreInfo := s.mockSnap(c, networkCoreYaml)
+	c.Assert(mgr.Repository().AddSnap(coreInfo), IsNil)
+	*now = now.Add(6 * time.Hour)
+	c.Assert(mgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestRetryAutoConnectionNotSeeded(c *C) {
+	s.MockModel(c, nil)
+	s.mockRetryTime(c)
+
+	s.mockSnap(c, networkConsumerYaml)
+	mgr := s.manager(c)
+
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(s.autoConnectRetries(c), IsNil)
+}
+
+func (s *interfaceManagerSuite) TestUnsatisfiedPlugsReasons(c *C) {
+	s.MockModel(c, nil)
+	s.mockRetryTime(c)
+
+	s.mockSnap(c, `
+name: consumer
+version: 1
+plugs:
+ data:
+  interface: content
+  content: data
+ removable-media:
+ connected:
+  interface: home
+`)
+	coreInfo := s.mockSnap(c, `
+name: core
+version: 1
+type: os
+slots:
+ removable-media:
+ home:
+`)
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:connected core:home": map[string]interface{}{"interface": "home"},
+	})
+	s.state.Unlock()
+	mgr := s.manager(c)
+	c.Assert(coreInfo, NotNil)
+
+	unsatisfied, err := mgr.UnsatisfiedPlugs()
+	c.Assert(err, IsNil)
+	c.Check(unsatisfied, DeepEquals, []*ifacestate.UnsatisfiedPlug{{
+		Plug:      interfaces.PlugRef{Snap: "consumer", Name: "data"},
+		Interface: "content",
+		Reason:    ifacestate.UnsatisfiedNoCandidateSlot,
+	}, {
+		Plug:      interfaces.PlugRef{Snap: "consumer", Name: "removable-media"},
+		Interface: "removable-media",
+		Reason:    ifacestate.UnsatisfiedPolicyDenied,
+	}})
+}
+
+func (s *interfaceManagerSuite) TestRetryAutoConnectionGadgetPending(c *C) {
+	s.setupAutoConnectGadget(c)
+	now := s.mockRetryTime(c)
+	mgr := s.manager(c)
+
+	// the slot snap is not there yet
+	producerInfo := mgr.Repository().Slot("producer", "slot").Snap
+	c.Assert(mgr.Repository().RemoveSnap("producer"), IsNil)
+
+	unsatisfied, err := mgr.UnsatisfiedPlugs()
+	c.Assert(err, IsNil)
+	c.Check(unsatisfied, DeepEquals, []*ifacestate.UnsatisfiedPlug{{
+		Plug:      interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		Interface: "test",
+		Reason:    ifacestate.UnsatisfiedGadgetPending,
+	}})
+
+	s.state.Lock()
+	s.state.Set("seeded", true)
+	s.state.Unlock()
+	c.Assert(mgr.Ensure(), IsNil)
+
+	// the slot snap arrives
+	c.Assert(mgr.Repository().AddSnap(producerInfo), IsNil)
+	*now = now.Add(5 * time.Minute)
+	c.Assert(mgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	chgs := s.state.Changes()
+	c.Assert(chgs, HasLen, 1)
+	var connectTask *state.Task
+	for _, t := range chgs[0].Tasks() {
+		if t.Kind() == "connect" {
+			connectTask = t
+		}
+	}
+	c.Assert(connectTask, NotNil)
+	var byGadget bool
+	c.Assert(connectTask.Get("by-gadget", &byGadget), IsNil)
+	c.Check(byGadget, Equals, true)
+}
@@ -23,9 +23,7 @@
 package install
 
 import (
-	"bytes"
 	"crypto"
-	"encoding/base64"
 	"fmt"
 	"io"
 	"os"
@@ -451,21 +449,9 @@ func ApplyPreseededData(preseedSeed seed.PreseedCapable, writableDir string) err
 		return err
 	}
 
-	preseedArtifact := preseedSeed.ArtifactPath("preseed.tgz")
-
-	// TODO: consider a writer that feeds the file to stdin of tar and calculates the digest at the same time.
-	sha3_384, _, err := osutil.FileDigest(preseedArtifact, crypto.SHA3_384)
-	if err != nil {
-		return fmt.Errorf("cannot calculate preseed artifact digest: %v", err)
-	}
-
-	digest, err := base64.RawURLEncoding.DecodeString(preseedAs.ArtifactSHA3_384())
-	if err != nil {
-		return fmt.Errorf("cannot decode preseed artifact digest")
-	}
-	if !bytes.Equal(sha3_384, digest) {
-		return fmt.Errorf("invalid preseed artifact digest")
-	}
+	// the artifact digest was checked against the assertion by
+	// LoadPreseedAssertion
+	preseedArtifact := preseedSeed.PreseedArtifactPath()
 
 	logger.Noticef("apply preseed data: %q, %q", writableDir, preseedArtifact)
 	cmd := exec.Command("tar", "--extract", "--preserve-permissions", "--preserve-order", "--gunzip", "--directory", writableDir, "-f", preseedArtifact)
@@ -1509,6 +1509,14 @@ func (fs *fakeSeed) HasArtifact(relName string) bool {
 	return fs.preseedArtifact && relName == "preseed.tgz"
 }
 
+func (fs *fakeSeed) PreseedArtifactPath() string {
+	return fs.ArtifactPath("preseed.tgz")
+}
+
+func (fs *fakeSeed) HasPreseedArtifact() bool {
+	return fs.HasArtifact("preseed.tgz")
+}
+
 func (*fakeSeed) LoadAssertions(db asserts.RODatabase, commitTo func(*asserts.Batch) error) error {
 	return nil
 }
@@ -1523,7 +1531,22 @@ func (fs *fakeSeed) LoadPreseedAssertion() (*asserts.Preseed, error) {
 	if err != nil {
 		return nil, err
 	}
-	return a.(*asserts.Preseed), nil
+	preseedAs := a.(*asserts.Preseed)
+	if fs.HasPreseedArtifact() {
+		// like the real seed, check the artifact digest
+		sha3_384, _, err := osutil.FileDigest(fs.PreseedArtifactPath(), crypto.SHA3_384)
+		if err != nil {
+			return nil, err
+		}
+		digest, err := asserts.EncodeDigest(crypto.SHA3_384, sha3_384)
+		if err != nil {
+			return nil, err
+		}
+		if digest != preseedAs.ArtifactSHA3_384() {
+			return nil, fmt.Errorf("invalid preseed artifact digest")
+		}
+	}
+	return preseedAs, nil
 }
 
 func (fs *fakeSeed) Model() *asserts.Model {
@@ -22,8 +22,10 @@
 package overlord
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -35,6 +37,7 @@ import (
 	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/features"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/assertstate"
@@ -72,6 +75,12 @@ var (
 
 	pruneMaxChanges = 500
 
+	// ready changes whose data, including their tasks, takes more than
+	// pruneLargeChangeSize in the state are kept for pruneLargeChangeWait
+	// only
+	pruneLargeChangeSize = 256 * 1024
+	pruneLargeChangeWait = 1 * time.Hour
+
 	defaultCachedDownloads = 5
 
 	configstateInit = configstate.Init
@@ -85,7 +94,8 @@ var pruneTickerC = func(t *time.Ticker) <-chan time.Time {
 // Overlord is the central manager of a snappy system, keeping
 // track of all available state managers and related helpers.
 type Overlord struct {
-	stateFLock *osutil.FileLock
+	stateFLock   *osutil.FileLock
+	stateBackend *overlordStateBackend
 
 	stateEng *StateEngine
 	// ensure loop
@@ -124,10 +134,8 @@ func New(restartHandler restart.Handler) (*Overlord, error) {
 		inited: true,
 	}
 
-	backend := &overlordStateBackend{
-		path:         dirs.SnapStateFile,
-		ensureBefore: o.ensureBefore,
-	}
+	backend := newOverlordStateBackend(dirs.SnapStateFile, o.ensureBefore, features.StateWriteAheadLog.IsEnabled())
+	o.stateBackend = backend
 	s, restartMgr, err := o.loadState(backend, restartHandler)
 	if err != nil {
 		return nil, err
@@ -298,11 +306,22 @@ func (o *Overlord) loadState(backend state.Backend, restartHandler restart.Handl
 		return s, restartMgr, nil
 	}
 
-	r, err := os.Open(dirs.SnapStateFile)
+	var r io.Reader
+	// changes to the state may be pending in the write-ahead log
+	replayed, err := replayStateWAL(dirs.SnapStateFile)
 	if err != nil {
-		return nil, nil, fmt.Errorf("cannot read the state file: %s", err)
+		return nil, nil, fmt.Errorf("fatal: %v", err)
+	}
+	if replayed != nil {
+		r = bytes.NewReader(replayed)
+	} else {
+		f, err := os.Open(dirs.SnapStateFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot read the state file: %s", err)
+		}
+		defer f.Close()
+		r = f
 	}
-	defer r.Close()
 
 	var s *state.State
 	timings.Run(perfTimings, "read-state", "read snapd state from disk", func(tm timings.Measurer) {
@@ -486,6 +505,7 @@ func (o *Overlord) Loop() {
 				st := o.State()
 				st.Lock()
 				st.Prune(o.startOfOperationTime, pruneWait, abortWait, pruneMaxChanges)
+				st.PruneOversizedChanges(pruneLargeChangeSize, pruneLargeChangeWait)
 				st.Unlock()
 			}
 		}
@@ -509,6 +529,14 @@ func (o *Overlord) Stop() error {
 		err = o.loopTomb.Wait()
 	}
 	o.stateEng.Stop()
+	if o.stateBackend != nil {
+		st := o.State()
+		st.Lock()
+		if err := o.stateBackend.foldWAL(); err != nil {
+			logger.Noticef("cannot fold the state write-ahead log: %v", err)
+		}
+		st.Unlock()
+	}
 	if o.stateFLock != nil {
 		// This will also unlock the file
 		o.stateFLock.Close()
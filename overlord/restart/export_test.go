@@ -32,3 +32,11 @@ var (
 func RestartParametersInit(rt *RestartParameters, snapName string, restartType RestartType, rebootInfo *boot.RebootInfo) {
 	rt.init(snapName, restartType, rebootInfo)
 }
+
+func MockBootFinishResealBatch(f func(unlocker boot.Unlocker) (int, error)) (restore func()) {
+	old := bootFinishResealBatch
+	bootFinishResealBatch = f
+	return func() {
+		bootFinishResealBatch = old
+	}
+}
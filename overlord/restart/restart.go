@@ -39,6 +39,8 @@ import (
 	"github.com/snapcore/snapd/release"
 )
 
+var bootFinishResealBatch = boot.FinishResealBatch
+
 type RestartType int
 
 const (
@@ -331,11 +333,22 @@ func Request(st *state.State, t RestartType, rebootInfo *boot.RebootInfo) {
 	switch t {
 	case RestartSystem, RestartSystemNow, RestartSystemHaltNow, RestartSystemPoweroffNow:
 		st.Set("system-restart-from-boot-id", rm.bootID)
+		finishResealBatch()
 	}
 	rm.restarting = t
 	rm.handleRestart(t, rebootInfo)
 }
 
+// finishResealBatch performs the reseal of the encryption keys which was
+// being coalesced by a reseal batch in progress, if any.
+func finishResealBatch() {
+	if requests, err := bootFinishResealBatch(nil); err != nil {
+		logger.Noticef("cannot reseal encryption keys before restart: %v", err)
+	} else if requests > 0 {
+		logger.Noticef("Resealed encryption keys once for %d requests before restart", requests)
+	}
+}
+
 func setWaitForSystemRestart(chg *state.Change) {
 	if chg == nil {
 		// nothing to do
@@ -586,6 +599,10 @@ func processRestartForChange(chg *state.Change, old, new state.Status) {
 	// clear out the restart context for this change before restarting
 	chg.Set("pending-system-restart", nil)
 
+	// the encryption keys must match the boot assets before restarting,
+	// also when the restart is left to the user
+	finishResealBatch()
+
 	// perform the restart
 	if release.OnClassic {
 		// Notify the system that a reboot is required.
@@ -380,6 +380,62 @@ func (s *restartSuite) TestProcessRestartForChangeCore(c *C) {
 	c.Check(h.rebootInfo.RebootRequired, Equals, true)
 }
 
+func (s *restartSuite) TestRequestRestartFinishesResealBatch(c *C) {
+	calls := 0
+	restore := restart.MockBootFinishResealBatch(func(unlocker boot.Unlocker) (int, error) {
+		calls++
+		return 2, nil
+	})
+	defer restore()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	h := &testHandler{}
+	_, err := restart.Manager(st, "boot-id-1", h)
+	c.Assert(err, IsNil)
+
+	restart.Request(st, restart.RestartDaemon, nil)
+	c.Check(calls, Equals, 0)
+
+	restart.Request(st, restart.RestartSystemNow, nil)
+	c.Check(calls, Equals, 1)
+	c.Check(h.restartRequested, Equals, true)
+}
+
+func (s *restartSuite) TestProcessRestartForChangeClassicFinishesResealBatch(c *C) {
+	restore := release.MockOnClassic(true)
+	defer restore()
+	calls := 0
+	restore = restart.MockBootFinishResealBatch(func(unlocker boot.Unlocker) (int, error) {
+		calls++
+		return 0, errors.New("boom")
+	})
+	defer restore()
+	buf, restore := logger.MockLogger()
+	defer restore()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	_, err := restart.Manager(st, "boot-id-1", nil)
+	c.Assert(err, IsNil)
+
+	chg := st.NewChange("test", "...")
+	t := st.NewTask("waiting", "...")
+	chg.AddTask(t)
+	restart.MarkTaskAsRestartBoundary(t, restart.RestartBoundaryDirectionDo)
+	err = restart.FinishTaskWithRestart(t, state.DoneStatus, restart.RestartSystem, "some-snap", nil)
+	c.Assert(err, IsNil)
+
+	restart.ProcessRestartForChange(chg, state.DefaultStatus, state.WaitStatus)
+	// the keys are resealed even if the restart is left to the user
+	c.Check(calls, Equals, 1)
+	c.Check(buf.String(), testutil.Contains, `cannot reseal encryption keys before restart: boom`)
+}
+
 func (s *restartSuite) TestProcessRestartForChangeMissingRebootContext(c *C) {
 	ml, restore := logger.MockLogger()
 	defer restore()
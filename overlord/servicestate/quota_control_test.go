@@ -183,7 +183,7 @@ func checkSliceState(c *C, sliceName string, resources quota.Resources) {
 		c.Assert(sliceFileName, testutil.FileContains, fmt.Sprintf("\nAllowedCPUs=%s\n", allowedCpusValue))
 	}
 	if resources.Threads != nil {
-		c.Assert(sliceFileName, testutil.FileContains, fmt.Sprintf("\nThreadsMax=%d\n", resources.Threads.Limit))
+		c.Assert(sliceFileName, testutil.FileContains, fmt.Sprintf("\nTasksMax=%d\n", resources.Threads.Limit))
 	}
 }
 
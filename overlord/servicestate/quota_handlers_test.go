@@ -2106,6 +2106,59 @@ func (s *quotaHandlersSuite) TestQuotaUpdateChangeMemLimit(c *C) {
 	c.Assert(err, ErrorMatches, "cannot update limits for group \"foo\": cannot decrease memory limit, remove and re-create it to decrease the limit")
 }
 
+func (s *quotaHandlersSuite) TestQuotaUpdateChangeCPUAndThreadLimits(c *C) {
+	r := s.mockSystemctlCalls(c, join(
+		// CreateQuota for foo
+		systemctlCallsForCreateQuota("foo", "test-snap"),
+
+		// UpdateQuota for foo - the existing slice was changed, so a
+		// daemon-reload is enough and the services are not restarted
+		[]expectedSystemctl{{expArgs: []string{"daemon-reload"}}},
+	))
+	defer r()
+
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	// setup the snap so it exists
+	snapstate.Set(s.state, "test-snap", s.testSnapState)
+	snaptest.MockSnapCurrent(c, testYaml, s.testSnapSideInfo)
+
+	// create a quota group mixing memory and cpu limits
+	qc := servicestate.QuotaControlAction{
+		Action:         "create",
+		QuotaName:      "foo",
+		ResourceLimits: quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeGiB).WithCPUCount(1).WithCPUPercentage(50).Build(),
+		AddSnaps:       []string{"test-snap"},
+	}
+	err := s.callDoQuotaControl(&qc)
+	c.Assert(err, IsNil)
+
+	sliceFile := filepath.Join(dirs.SnapServicesDir, "snap.foo.slice")
+	c.Check(sliceFile, testutil.FileContains, "\nCPUQuota=50%\n")
+	c.Check(sliceFile, Not(testutil.FileContains), "AllowedCPUs=")
+	c.Check(sliceFile, Not(testutil.FileContains), "TasksMax=")
+
+	// change the cpu limits and add cpu set and thread limits
+	qc2 := servicestate.QuotaControlAction{
+		Action:         "update",
+		QuotaName:      "foo",
+		ResourceLimits: quota.NewResourcesBuilder().WithCPUCount(1).WithCPUPercentage(25).WithCPUSet([]int{0}).WithThreadLimit(64).Build(),
+	}
+	err = s.callDoQuotaControl(&qc2)
+	c.Assert(err, IsNil)
+
+	// the memory limit is kept and the slice is rewritten with the new
+	// limits
+	checkQuotaState(c, st, map[string]quotaGroupState{
+		"foo": {
+			ResourceLimits: quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeGiB).WithCPUCount(1).WithCPUPercentage(25).WithCPUSet([]int{0}).WithThreadLimit(64).Build(),
+			Snaps:          []string{"test-snap"},
+		},
+	})
+}
+
 func (s *quotaHandlersSuite) TestQuotaUpdateJournalQuotaNotAllowedForServices(c *C) {
 	r := s.mockSystemctlCalls(c, join(
 		// CreateQuota for foo
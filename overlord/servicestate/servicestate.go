@@ -255,6 +255,12 @@ func Control(st *state.State, appInfos []*snap.AppInfo, inst *Instruction, flags
 type StatusDecorator struct {
 	sysd           systemd.Systemd
 	globalUserSysd systemd.Systemd
+
+	// userSessions is set if the status of user services in the
+	// sessions of the users in uids (or of all users with a running
+	// session if uids is empty) should be reported as well
+	userSessions bool
+	uids         []int
 }
 
 // NewStatusDecorator returns a new StatusDecorator.
@@ -267,6 +273,19 @@ func NewStatusDecorator(rep interface {
 	}
 }
 
+// NewStatusDecoratorForUsers returns a new StatusDecorator which also
+// reports the status of user services in the sessions of the users with
+// the given uids, or of all users with a running session if uids is
+// empty.
+func NewStatusDecoratorForUsers(rep interface {
+	Notify(string)
+}, uids []int) *StatusDecorator {
+	sd := NewStatusDecorator(rep)
+	sd.userSessions = true
+	sd.uids = uids
+	return sd
+}
+
 func (sd *StatusDecorator) hasEnabledActivator(appInfo *client.AppInfo) bool {
 	// Just one activator should be enabled in order for the service to be able
 	// to become enabled. For slot activated services this is always true as we
@@ -373,6 +392,13 @@ func (sd *StatusDecorator) DecorateWithStatus(appInfo *client.AppInfo, snapApp *
 	if len(appInfo.Activators) > 0 {
 		appInfo.Enabled = sd.hasEnabledActivator(appInfo)
 	}
+	if sd.userSessions && snapApp.DaemonScope == snap.UserDaemon {
+		userSts, err := UserServicesStatus([]*snap.AppInfo{snapApp}, sd.uids)
+		if err != nil {
+			return fmt.Errorf("cannot get status of user services of app %q: %v", appInfo.Name, err)
+		}
+		appInfo.Users = userSts[snapApp.ServiceName()]
+	}
 	return nil
 }
 
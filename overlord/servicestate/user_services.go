@@ -0,0 +1,201 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timeout"
+	userclient "github.com/snapcore/snapd/usersession/client"
+)
+
+// userServiceUnits returns the names of the systemd units of the given
+// apps, which must all be user services.
+func userServiceUnits(apps []*snap.AppInfo) ([]string, error) {
+	units := make([]string, 0, len(apps))
+	for _, app := range apps {
+		if !app.IsService() || app.DaemonScope != snap.UserDaemon {
+			return nil, fmt.Errorf("internal error: %s is not a user service", app)
+		}
+		units = append(units, app.ServiceName())
+	}
+	return units, nil
+}
+
+// userSessionClient returns a client talking to the session agents of the
+// given users, or of all users with a running session if uids is empty.
+func userSessionClient(uids []int) *userclient.Client {
+	if len(uids) == 0 {
+		return userclient.New()
+	}
+	return userclient.NewForUids(uids...)
+}
+
+// usersWithoutSession returns those of the given users which do not have
+// a running session agent.
+func usersWithoutSession(ctx context.Context, cli *userclient.Client, uids []int) ([]int, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	sessions, err := cli.SessionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var missing []int
+	for _, uid := range uids {
+		if _, ok := sessions[uid]; !ok {
+			missing = append(missing, uid)
+		}
+	}
+	return missing, nil
+}
+
+// UserServicesStatus returns the status of the given user services in
+// the sessions of the users with the given uids, or of all users with a
+// running session if uids is empty. The result is indexed by systemd
+// unit name and holds one entry per user, ordered by uid; users without a
+// running session are reported with NoSession set.
+func UserServicesStatus(apps []*snap.AppInfo, uids []int) (map[string][]client.AppUserStatus, error) {
+	units, err := userServiceUnits(apps)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout.DefaultTimeout))
+	defer cancel()
+	statuses, err := userSessionClient(uids).ServiceStatus(ctx, units)
+	if err != nil {
+		return nil, err
+	}
+
+	allUids := make([]int, 0, len(statuses)+len(uids))
+	for uid := range statuses {
+		allUids = append(allUids, uid)
+	}
+	for _, uid := range uids {
+		if _, ok := statuses[uid]; !ok {
+			allUids = append(allUids, uid)
+		}
+	}
+	sort.Ints(allUids)
+
+	result := make(map[string][]client.AppUserStatus, len(units))
+	for _, uid := range allUids {
+		sts, ok := statuses[uid]
+		if !ok {
+			for _, unit := range units {
+				result[unit] = append(result[unit], client.AppUserStatus{Uid: uid, NoSession: true})
+			}
+			continue
+		}
+		stByUnit := make(map[string]*userclient.ServiceUnitStatus, len(sts))
+		for _, st := range sts {
+			stByUnit[st.Name] = st
+		}
+		for _, unit := range units {
+			st := stByUnit[unit]
+			if st == nil {
+				return nil, fmt.Errorf("cannot get status of service %q for uid %d", unit, uid)
+			}
+			result[unit] = append(result[unit], client.AppUserStatus{
+				Uid:     uid,
+				Enabled: st.Enabled,
+				Active:  st.Active,
+			})
+		}
+	}
+	return result, nil
+}
+
+func sortServiceFailures(failures []userclient.ServiceFailure) {
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].Uid != failures[j].Uid {
+			return failures[i].Uid < failures[j].Uid
+		}
+		return failures[i].Service < failures[j].Service
+	})
+}
+
+// noSessionFailures reports the given services as failed for each of the
+// users without a running session.
+func noSessionFailures(uids []int, units []string) []userclient.ServiceFailure {
+	var failures []userclient.ServiceFailure
+	for _, uid := range uids {
+		for _, unit := range units {
+			failures = append(failures, userclient.ServiceFailure{
+				Uid:     uid,
+				Service: unit,
+				Error:   "no running session",
+			})
+		}
+	}
+	return failures
+}
+
+// StartUserServices starts the given user services in the sessions of
+// the users with the given uids, or of all users with a running session
+// if uids is empty. Services that failed to start, or to be stopped again
+// after another service failed to start, are reported per user. Users
+// without a running session are reported as failing to start all the
+// services.
+func StartUserServices(apps []*snap.AppInfo, uids []int) (startFailures, stopFailures []userclient.ServiceFailure, err error) {
+	units, err := userServiceUnits(apps)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout.DefaultTimeout))
+	defer cancel()
+	cli := userSessionClient(uids)
+	missing, err := usersWithoutSession(ctx, cli, uids)
+	if err != nil {
+		return nil, nil, err
+	}
+	startFailures, stopFailures, err = cli.ServicesStart(ctx, units)
+	startFailures = append(startFailures, noSessionFailures(missing, units)...)
+	sortServiceFailures(startFailures)
+	sortServiceFailures(stopFailures)
+	if err == nil && len(missing) > 0 {
+		err = fmt.Errorf("cannot start user services: no running session for uids %v", missing)
+	}
+	return startFailures, stopFailures, err
+}
+
+// StopUserServices stops the given user services in the sessions of the
+// users with the given uids, or of all users with a running session if
+// uids is empty. Services that failed to stop are reported per user.
+// There is nothing to stop for users without a running session.
+func StopUserServices(apps []*snap.AppInfo, uids []int) (stopFailures []userclient.ServiceFailure, err error) {
+	units, err := userServiceUnits(apps)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout.DefaultTimeout))
+	defer cancel()
+	stopFailures, err = userSessionClient(uids).ServicesStop(ctx, units)
+	sortServiceFailures(stopFailures)
+	return stopFailures, err
+}
@@ -0,0 +1,265 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
+	userclient "github.com/snapcore/snapd/usersession/client"
+)
+
+type userServicesSuite struct {
+	testutil.BaseTest
+
+	server *http.Server
+
+	mu sync.Mutex
+	// active holds the services active in the session of each user
+	active map[string]map[string]bool
+	// failing holds the services failing to start or stop in the
+	// session of each user
+	failing map[string]map[string]bool
+	// controlled records the service control actions for each user
+	controlled map[string][]string
+
+	info *snap.Info
+}
+
+var _ = Suite(&userServicesSuite{})
+
+const userServicesSnapYaml = `name: test-snap
+version: 1
+apps:
+  svc1:
+    daemon: simple
+    daemon-scope: user
+  svc2:
+    daemon: simple
+    daemon-scope: user
+  system-svc:
+    daemon: simple
+`
+
+func (s *userServicesSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	s.active = map[string]map[string]bool{
+		"1000": {"snap.test-snap.svc1.service": true},
+		"42":   {},
+	}
+	s.failing = map[string]map[string]bool{}
+	s.controlled = map[string][]string{}
+
+	s.server = &http.Server{Handler: s}
+	for _, uid := range []int{1000, 42} {
+		sock := fmt.Sprintf("%s/%d/snapd-session-agent.socket", dirs.XdgRuntimeDirBase, uid)
+		c.Assert(os.MkdirAll(filepath.Dir(sock), 0755), IsNil)
+		l, err := net.Listen("unix", sock)
+		c.Assert(err, IsNil)
+		go func(l net.Listener) {
+			err := s.server.Serve(l)
+			c.Check(err, Equals, http.ErrServerClosed)
+		}(l)
+	}
+	s.AddCleanup(func() {
+		c.Check(s.server.Shutdown(context.Background()), IsNil)
+	})
+
+	s.info = snaptest.MockSnap(c, userServicesSnapYaml, &snap.SideInfo{Revision: snap.R(1)})
+}
+
+// ServeHTTP implements a minimal session agent.
+func (s *userServicesSuite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uid := r.Host
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/v1/session-info":
+		w.Write([]byte(`{"type": "sync", "result": {"version": "1"}}`))
+	case "/v1/service-status":
+		var sts []userclient.ServiceUnitStatus
+		for _, svc := range strutil.CommaSeparatedList(r.URL.Query().Get("services")) {
+			sts = append(sts, userclient.ServiceUnitStatus{
+				Name:    svc,
+				Enabled: true,
+				Active:  s.active[uid][svc],
+			})
+		}
+		result, _ := json.Marshal(sts)
+		fmt.Fprintf(w, `{"type": "sync", "result": %s}`, result)
+	case "/v1/service-control":
+		var inst struct {
+			Action   string   `json:"action"`
+			Services []string `json:"services"`
+		}
+		json.NewDecoder(r.Body).Decode(&inst)
+		errs := map[string]string{}
+		for _, svc := range inst.Services {
+			s.controlled[uid] = append(s.controlled[uid], inst.Action+" "+svc)
+			if s.failing[uid][svc] {
+				errs[svc] = "mock failure"
+			}
+		}
+		if len(errs) == 0 {
+			w.Write([]byte(`{"type": "sync", "result": null}`))
+			return
+		}
+		value, _ := json.Marshal(map[string]interface{}{inst.Action + "-errors": errs})
+		w.WriteHeader(500)
+		fmt.Fprintf(w, `{"type": "error", "result": {"kind": "service-control", "message": "some user services failed to %s", "value": %s}}`, inst.Action, value)
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+func (s *userServicesSuite) userServices() []*snap.AppInfo {
+	return []*snap.AppInfo{s.info.Apps["svc1"], s.info.Apps["svc2"]}
+}
+
+func (s *userServicesSuite) TestUserServicesStatusAllSessions(c *C) {
+	sts, err := servicestate.UserServicesStatus(s.userServices(), nil)
+	c.Assert(err, IsNil)
+	c.Check(sts, DeepEquals, map[string][]client.AppUserStatus{
+		"snap.test-snap.svc1.service": {
+			{Uid: 42, Enabled: true},
+			{Uid: 1000, Enabled: true, Active: true},
+		},
+		"snap.test-snap.svc2.service": {
+			{Uid: 42, Enabled: true},
+			{Uid: 1000, Enabled: true},
+		},
+	})
+}
+
+func (s *userServicesSuite) TestUserServicesStatusNoSession(c *C) {
+	sts, err := servicestate.UserServicesStatus(s.userServices()[:1], []int{1001, 1000})
+	c.Assert(err, IsNil)
+	c.Check(sts, DeepEquals, map[string][]client.AppUserStatus{
+		"snap.test-snap.svc1.service": {
+			{Uid: 1000, Enabled: true, Active: true},
+			{Uid: 1001, NoSession: true},
+		},
+	})
+}
+
+func (s *userServicesSuite) TestUserServicesStatusNotUserService(c *C) {
+	_, err := servicestate.UserServicesStatus([]*snap.AppInfo{s.info.Apps["system-svc"]}, nil)
+	c.Check(err, ErrorMatches, `internal error: test-snap.system-svc is not a user service`)
+}
+
+func (s *userServicesSuite) TestStartUserServices(c *C) {
+	startFailures, stopFailures, err := servicestate.StartUserServices(s.userServices(), []int{42})
+	c.Assert(err, IsNil)
+	c.Check(startFailures, HasLen, 0)
+	c.Check(stopFailures, HasLen, 0)
+	c.Check(s.controlled, DeepEquals, map[string][]string{
+		"42": {"start snap.test-snap.svc1.service", "start snap.test-snap.svc2.service"},
+	})
+}
+
+func (s *userServicesSuite) TestStartUserServicesNoSession(c *C) {
+	startFailures, stopFailures, err := servicestate.StartUserServices(s.userServices()[1:], []int{1001, 1000})
+	c.Check(err, ErrorMatches, `cannot start user services: no running session for uids \[1001\]`)
+	c.Check(startFailures, DeepEquals, []userclient.ServiceFailure{
+		{Uid: 1001, Service: "snap.test-snap.svc2.service", Error: "no running session"},
+	})
+	c.Check(stopFailures, HasLen, 0)
+	c.Check(s.controlled, DeepEquals, map[string][]string{
+		"1000": {"start snap.test-snap.svc2.service"},
+	})
+}
+
+func (s *userServicesSuite) TestStopUserServicesFailures(c *C) {
+	s.failing["1000"] = map[string]bool{"snap.test-snap.svc2.service": true}
+	s.failing["42"] = map[string]bool{"snap.test-snap.svc1.service": true}
+
+	stopFailures, err := servicestate.StopUserServices(s.userServices(), nil)
+	c.Check(err, ErrorMatches, "some user services failed to stop")
+	c.Check(stopFailures, DeepEquals, []userclient.ServiceFailure{
+		{Uid: 42, Service: "snap.test-snap.svc1.service", Error: "mock failure"},
+		{Uid: 1000, Service: "snap.test-snap.svc2.service", Error: "mock failure"},
+	})
+}
+
+func (s *userServicesSuite) TestDecorateWithStatusForUsers(c *C) {
+	// make the snap active
+	c.Assert(os.Symlink("1", filepath.Join(filepath.Dir(s.info.MountDir()), "current")), IsNil)
+	r := systemd.MockSystemctl(func(args ...string) (buf []byte, err error) {
+		c.Assert(args[:3], DeepEquals, []string{"--user", "--global", "is-enabled"})
+		return []byte("enabled\n"), nil
+	})
+	defer r()
+
+	sd := servicestate.NewStatusDecoratorForUsers(nil, []int{1000, 1001})
+	app := &client.AppInfo{
+		Snap:   "test-snap",
+		Name:   "svc1",
+		Daemon: "simple",
+	}
+	err := sd.DecorateWithStatus(app, s.info.Apps["svc1"])
+	c.Assert(err, IsNil)
+	c.Check(app.Enabled, Equals, true)
+	c.Check(app.Users, DeepEquals, []client.AppUserStatus{
+		{Uid: 1000, Enabled: true, Active: true},
+		{Uid: 1001, NoSession: true},
+	})
+
+	// system services are not affected
+	r = systemd.MockSystemctl(func(args ...string) (buf []byte, err error) {
+		return []byte(`Id=snap.test-snap.system-svc.service
+Names=snap.test-snap.system-svc.service
+Type=simple
+ActiveState=active
+UnitFileState=enabled
+NeedDaemonReload=no
+`), nil
+	})
+	defer r()
+	app = &client.AppInfo{
+		Snap:   "test-snap",
+		Name:   "system-svc",
+		Daemon: "simple",
+	}
+	err = sd.DecorateWithStatus(app, s.info.Apps["system-svc"])
+	c.Assert(err, IsNil)
+	c.Check(app.Active, Equals, true)
+	c.Check(app.Users, HasLen, 0)
+}
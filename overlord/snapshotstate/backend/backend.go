@@ -731,6 +731,11 @@ type ImportFlags struct {
 	// noDuplicatedImportCheck tells import not to check for existing snapshot
 	// with same content hash (and not report DuplicatedSnapshotImportError).
 	NoDuplicatedImportCheck bool
+
+	// ValidateSnapshot, if set, is called for each of the imported
+	// snapshots once its data has been verified, and can reject the
+	// import by returning an error.
+	ValidateSnapshot func(snapshot *client.Snapshot) error
 }
 
 // Import a snapshot from the export file format
@@ -826,6 +831,10 @@ func checkDuplicatedSnapshotSetWithContentHash(ctx context.Context, contentHash
 
 func unpackVerifySnapshotImport(ctx context.Context, r io.Reader, realSetID uint64, flags *ImportFlags) (snapNames []string, err error) {
 	var exportFound bool
+	var meta exportMetadata
+	var contentHash []byte
+	var imported client.SnapshotSet
+	var importedFiles []string
 
 	tr := tar.NewReader(r)
 	var tarErr error
@@ -861,6 +870,7 @@ func unpackVerifySnapshotImport(ctx context.Context, r io.Reader, realSetID uint
 			if err := dec.Decode(&ej); err != nil {
 				return nil, err
 			}
+			contentHash = ej.ContentHash
 			if !flags.NoDuplicatedImportCheck {
 				// XXX: this is potentially slow as it needs
 				//      to open all snapshots files and read a
@@ -873,8 +883,9 @@ func unpackVerifySnapshotImport(ctx context.Context, r io.Reader, realSetID uint
 		}
 
 		if header.Name == "export.json" {
-			// XXX: read into memory and validate once we
-			// hashes in export.json
+			if err := json.NewDecoder(tr).Decode(&meta); err != nil {
+				return nil, fmt.Errorf("cannot decode export.json: %v", err)
+			}
 			exportFound = true
 			continue
 		}
@@ -905,16 +916,54 @@ func unpackVerifySnapshotImport(ctx context.Context, r io.Reader, realSetID uint
 		if err != nil {
 			return snapNames, fmt.Errorf("validation failed for %q: %v", targetPath, err)
 		}
+		if flags.ValidateSnapshot != nil {
+			if err := flags.ValidateSnapshot(&r.Snapshot); err != nil {
+				return snapNames, err
+			}
+		}
+		imported.Snapshots = append(imported.Snapshots, &r.Snapshot)
+		importedFiles = append(importedFiles, header.Name)
 	}
 
 	if !exportFound {
 		return nil, fmt.Errorf("no export.json file in uploaded data")
 	}
-	// XXX: validate using the unmarshalled export.json hashes here
+	if err := verifyImportedFiles(meta.Files, importedFiles); err != nil {
+		return snapNames, err
+	}
+	if contentHash != nil {
+		h, err := imported.ContentHash()
+		if err != nil {
+			return snapNames, fmt.Errorf("cannot calculate content hash of imported snapshot: %v", err)
+		}
+		if !bytes.Equal(h, contentHash) {
+			return snapNames, fmt.Errorf("content hash of imported snapshot does not match content.json")
+		}
+	}
 
 	return snapNames, nil
 }
 
+// verifyImportedFiles checks that the snapshot files found in an import
+// stream are the ones listed in its export.json. Exports that do not list
+// their files are not checked.
+func verifyImportedFiles(listed, found []string) error {
+	if len(listed) == 0 {
+		return nil
+	}
+	for _, name := range found {
+		if !strutil.ListContains(listed, name) {
+			return fmt.Errorf("unexpected file %q not listed in export.json", name)
+		}
+	}
+	for _, name := range listed {
+		if !strutil.ListContains(found, name) {
+			return fmt.Errorf("file %q listed in export.json not found in uploaded data", name)
+		}
+	}
+	return nil
+}
+
 type exportMetadata struct {
 	Format int       `json:"format"`
 	Date   time.Time `json:"date"`
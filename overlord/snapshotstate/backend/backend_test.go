@@ -790,7 +790,7 @@ func (s *snapshotSuite) testHappyRoundtrip(c *check.C, marker string) {
 		c.Check(diff().Run(), check.NotNil, comm)
 
 		// restore leaves things like they were (again and again)
-		rs, err := shr.Restore(context.TODO(), snap.R(0), nil, logger.Debugf, nil)
+		rs, err := shr.Restore(context.TODO(), snap.R(0), nil, nil, logger.Debugf, nil)
 		c.Assert(err, check.IsNil, comm)
 		rs.Cleanup()
 		c.Check(diff().Run(), check.IsNil, comm)
@@ -868,12 +868,93 @@ func (s *snapshotSuite) TestRestoreRoundtripDifferentRevision(c *check.C) {
 	c.Check(diff().Run(), check.NotNil)
 
 	// restore leaves things like they were, but in the new dir
-	rs, err := shr.Restore(context.TODO(), snap.R("17"), nil, logger.Debugf, nil)
+	rs, err := shr.Restore(context.TODO(), snap.R("17"), nil, nil, logger.Debugf, nil)
 	c.Assert(err, check.IsNil)
 	rs.Cleanup()
 	c.Check(diff().Run(), check.IsNil)
 }
 
+func (s *snapshotSuite) TestRestoreSelective(c *check.C) {
+	logger.SimpleSetup()
+	// only save the system data, so that no user wrapper is needed
+	defer backend.MockUsersForUsernames(func([]string, *dirs.SnapDirOptions) ([]*user.User, error) {
+		return nil, nil
+	})()
+
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "hello-snap", Revision: snap.R(42), SnapID: "hello-id"}, Version: "v1.33"}
+	shw, err := backend.Save(context.TODO(), 12, info, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+
+	shr, err := backend.Open(backend.Filename(shw), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer shr.Close()
+
+	versioned := filepath.Join(info.DataDir(), "foo")
+	common := filepath.Join(info.CommonDataDir(), "bar")
+	scribble := func() {
+		for _, fn := range []string{versioned, common} {
+			c.Assert(os.WriteFile(fn, []byte("scribble\n"), 0644), check.IsNil)
+		}
+	}
+
+	// only the common data is restored
+	scribble()
+	rs, err := shr.Restore(context.TODO(), snap.R(0), nil, &snap.SnapshotRestoreOptions{CommonOnly: true}, logger.Debugf, nil)
+	c.Assert(err, check.IsNil)
+	rs.Cleanup()
+	c.Check(versioned, testutil.FileEquals, "scribble\n")
+	c.Check(common, testutil.FileEquals, "common system canary\n")
+
+	// the system data is left alone
+	scribble()
+	rs, err = shr.Restore(context.TODO(), snap.R(0), nil, &snap.SnapshotRestoreOptions{SkipSystem: true}, logger.Debugf, nil)
+	c.Assert(err, check.IsNil)
+	rs.Cleanup()
+	c.Check(versioned, testutil.FileEquals, "scribble\n")
+	c.Check(common, testutil.FileEquals, "scribble\n")
+}
+
+func (s *snapshotSuite) TestRestoreUnknownUser(c *check.C) {
+	logger.SimpleSetup()
+	defer backend.MockUsersForUsernames(func([]string, *dirs.SnapDirOptions) ([]*user.User, error) {
+		return nil, nil
+	})()
+
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "hello-snap", Revision: snap.R(42), SnapID: "hello-id"}, Version: "v1.33"}
+	shw, err := backend.Save(context.TODO(), 12, info, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+
+	shr, err := backend.Open(backend.Filename(shw), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer shr.Close()
+	// pretend the snapshot holds the data of a user that is gone
+	shr.SHA3_384["user/ghost.tgz"] = "0123456789"
+
+	restoreOpts := &snap.SnapshotRestoreOptions{SkipSystem: true}
+	_, err = shr.Restore(context.TODO(), snap.R(0), []string{"ghost"}, restoreOpts, logger.Debugf, nil)
+	c.Check(err, check.ErrorMatches, `cannot restore snapshot data of user "ghost": user does not exist`)
+
+	// users that are gone are skipped when restoring everything
+	rs, err := shr.Restore(context.TODO(), snap.R(0), nil, restoreOpts, logger.Debugf, nil)
+	c.Assert(err, check.IsNil)
+	rs.Cleanup()
+
+	// with forced ownership the data would go into the leftover home of
+	// the user, which does not exist either
+	var logged []string
+	logf := func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+	restoreOpts.ForceOwnership = true
+	rs, err = shr.Restore(context.TODO(), snap.R(0), []string{"ghost"}, restoreOpts, logf, nil)
+	c.Assert(err, check.IsNil)
+	rs.Cleanup()
+	c.Check(logged, check.DeepEquals, []string{
+		fmt.Sprintf("Skipping restore of %q as %q doesn't exist.",
+			filepath.Join(s.root, "home/ghost/snap/hello-snap/42"), filepath.Join(s.root, "home/ghost")),
+	})
+}
+
 func (s *snapshotSuite) TestPickUserWrapperRunuser(c *check.C) {
 	n := 0
 	defer backend.MockExecLookPath(func(s string) (string, error) {
@@ -1099,6 +1180,100 @@ func (s *snapshotSuite) TestImportCheckError(c *check.C) {
 	c.Assert(err, check.ErrorMatches, `cannot import snapshot 14: validation failed for .+/14_foo_1.0_199.zip": snapshot entry "archive.tgz" expected hash \(d5ef563…\) does not match actual \(6655519…\)`)
 }
 
+func (s *snapshotSuite) TestImportListedFiles(c *check.C) {
+	allFiles := []string{"5_foo_1.0_199.zip", "5_bar_1.0_199.zip", "5_baz_1.0_199.zip"}
+	for _, t := range []struct {
+		listed []string
+		err    string
+	}{
+		{allFiles, ""},
+		{allFiles[:2], `cannot import snapshot 14: unexpected file "5_baz_1.0_199.zip" not listed in export.json`},
+		{append(allFiles, "5_quux_1.0_199.zip"), `cannot import snapshot 14: file "5_quux_1.0_199.zip" listed in export.json not found in uploaded data`},
+	} {
+		c.Assert(os.RemoveAll(dirs.SnapshotsDir), check.IsNil)
+
+		tarFile := path.Join(c.MkDir(), "exported.snapshot")
+		err := createTestExportFile(tarFile, &createTestExportFlags{exportJSON: true, listedFiles: t.listed})
+		c.Assert(err, check.IsNil)
+
+		f, err := os.Open(tarFile)
+		c.Assert(err, check.IsNil)
+		defer f.Close()
+		_, err = backend.Import(context.Background(), 14, f, nil)
+		if t.err == "" {
+			c.Check(err, check.IsNil)
+		} else {
+			c.Check(err, check.ErrorMatches, t.err)
+		}
+	}
+}
+
+func (s *snapshotSuite) TestImportValidateSnapshot(c *check.C) {
+	tarFile := path.Join(c.MkDir(), "exported.snapshot")
+	err := createTestExportFile(tarFile, &createTestExportFlags{exportJSON: true})
+	c.Assert(err, check.IsNil)
+
+	f, err := os.Open(tarFile)
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+
+	var validated []string
+	flags := &backend.ImportFlags{
+		ValidateSnapshot: func(snapshot *client.Snapshot) error {
+			validated = append(validated, snapshot.Snap)
+			if snapshot.Snap == "bar" {
+				return fmt.Errorf("cannot use bar")
+			}
+			return nil
+		},
+	}
+	_, err = backend.Import(context.Background(), 14, f, flags)
+	c.Check(err, check.ErrorMatches, `cannot import snapshot 14: cannot use bar`)
+	c.Check(validated, check.DeepEquals, []string{"foo", "bar"})
+	// the partial import was cleaned up
+	c.Check(filepath.Join(dirs.SnapshotsDir, "14_foo_1.0_199.zip"), testutil.FileAbsent)
+}
+
+func (s *snapshotSuite) TestImportContentHash(c *check.C) {
+	ctx := context.TODO()
+
+	// import once without content.json to learn the content hash
+	tarFile := path.Join(c.MkDir(), "exported.snapshot")
+	err := createTestExportFile(tarFile, &createTestExportFlags{exportJSON: true})
+	c.Assert(err, check.IsNil)
+	f, err := os.Open(tarFile)
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+	_, err = backend.Import(ctx, 14, f, nil)
+	c.Assert(err, check.IsNil)
+	sets, err := backend.List(ctx, 14, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(sets, check.HasLen, 1)
+	contentHash, err := sets[0].ContentHash()
+	c.Assert(err, check.IsNil)
+
+	noDupCheck := &backend.ImportFlags{NoDuplicatedImportCheck: true}
+
+	tarFile = path.Join(c.MkDir(), "exported.snapshot")
+	err = createTestExportFile(tarFile, &createTestExportFlags{exportJSON: true, contentHash: contentHash})
+	c.Assert(err, check.IsNil)
+	f, err = os.Open(tarFile)
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+	_, err = backend.Import(ctx, 15, f, noDupCheck)
+	c.Check(err, check.IsNil)
+
+	tarFile = path.Join(c.MkDir(), "exported.snapshot")
+	err = createTestExportFile(tarFile, &createTestExportFlags{exportJSON: true, contentHash: []byte("bad-hash")})
+	c.Assert(err, check.IsNil)
+	f, err = os.Open(tarFile)
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+	_, err = backend.Import(ctx, 16, f, noDupCheck)
+	c.Check(err, check.ErrorMatches, `cannot import snapshot 16: content hash of imported snapshot does not match content.json`)
+	c.Check(filepath.Join(dirs.SnapshotsDir, "16_foo_1.0_199.zip"), testutil.FileAbsent)
+}
+
 func (s *snapshotSuite) TestImportDuplicated(c *check.C) {
 	err := os.MkdirAll(dirs.SnapshotsDir, 0755)
 	c.Assert(err, check.IsNil)
@@ -1351,6 +1526,10 @@ type createTestExportFlags struct {
 	withDir         bool
 	withParent      bool
 	corruptChecksum bool
+	// listedFiles are the files listed in export.json
+	listedFiles []string
+	// contentHash, if set, is written out as content.json
+	contentHash []byte
 }
 
 func createTestExportFile(filename string, flags *createTestExportFlags) error {
@@ -1362,6 +1541,24 @@ func createTestExportFile(filename string, flags *createTestExportFlags) error {
 	tw := tar.NewWriter(tf)
 	defer tw.Close()
 
+	if flags.contentHash != nil {
+		content, err := json.Marshal(map[string][]byte{"content-hash": flags.contentHash})
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name: "content.json",
+			Mode: 0644,
+			Size: int64(len(content)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return err
+		}
+	}
+
 	for _, s := range []string{"foo", "bar", "baz"} {
 		fname := fmt.Sprintf("5_%s_1.0_199.zip", s)
 
@@ -1452,7 +1649,11 @@ func createTestExportFile(filename string, flags *createTestExportFlags) error {
 	}
 
 	if flags.exportJSON {
-		exp := fmt.Sprintf(`{"format":1, "date":"%s"}`, time.Now().Format(time.RFC3339))
+		files, err := json.Marshal(flags.listedFiles)
+		if err != nil {
+			return err
+		}
+		exp := fmt.Sprintf(`{"format":1, "date":"%s", "files":%s}`, time.Now().Format(time.RFC3339), files)
 		hdr := &tar.Header{
 			Name: "export.json",
 			Mode: 0644,
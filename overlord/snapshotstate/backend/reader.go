@@ -29,6 +29,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"os/user"
 	"path/filepath"
 	"sort"
 	"syscall"
@@ -197,7 +198,12 @@ type Logf func(format string, args ...interface{})
 // If successful this will replace the existing data (for the given revision,
 // or the one in the snapshot) with that contained in the snapshot. It keeps
 // track of the old data in the task so it can be undone (or cleaned up).
-func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames []string, logf Logf, opts *dirs.SnapDirOptions) (rs *RestoreState, e error) {
+//
+// The restore options, if given, limit which of the data is restored; only
+// the directories being restored are replaced. Restoring the data of a user
+// that was explicitly requested but no longer exists fails, unless
+// ForceOwnership is set.
+func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames []string, restoreOpts *snap.SnapshotRestoreOptions, logf Logf, opts *dirs.SnapDirOptions) (rs *RestoreState, e error) {
 	rs = &RestoreState{}
 	defer func() {
 		if e != nil {
@@ -207,6 +213,10 @@ func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames [
 		}
 	}()
 
+	if restoreOpts == nil {
+		restoreOpts = &snap.SnapshotRestoreOptions{}
+	}
+
 	sort.Strings(usernames)
 	isRoot := sys.Geteuid() == 0
 	si := snap.MinimalPlaceInfo(r.Snap, r.Revision)
@@ -235,6 +245,10 @@ func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames [
 				logf("Skipping restore of unknown entry %q.", entry)
 				continue
 			}
+			if restoreOpts.SkipSystem {
+				logger.Debugf("In restoring snapshot %q, skipping system data by user request.", r.Name())
+				continue
+			}
 			dest = si.DataDir()
 		} else {
 			username = entryUsername(entry)
@@ -242,10 +256,21 @@ func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames [
 				logger.Debugf("In restoring snapshot %q, skipping entry %q by user request.", r.Name(), username)
 				continue
 			}
+			orphaned := false
 			usr, err := userLookup(username)
 			if err != nil {
-				logf("Skipping restore of user %q: %v.", username, err)
-				continue
+				_, unknown := err.(user.UnknownUserError)
+				switch {
+				case unknown && restoreOpts.ForceOwnership:
+					// restore into the leftover home of the user, as root
+					usr = &user.User{Username: username, HomeDir: filepath.Join(dirs.GlobalRootDir, "/home", username)}
+					orphaned = true
+				case unknown && len(usernames) > 0:
+					return rs, fmt.Errorf("cannot restore snapshot data of user %q: user does not exist", username)
+				default:
+					logf("Skipping restore of user %q: %v.", username, err)
+					continue
+				}
 			}
 
 			dest = si.UserDataDir(usr.HomeDir, opts)
@@ -264,7 +289,9 @@ func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames [
 				continue
 			}
 
-			if st, ok := fi.Sys().(*syscall.Stat_t); ok && isRoot {
+			if orphaned {
+				username = "root"
+			} else if st, ok := fi.Sys().(*syscall.Stat_t); ok && isRoot {
 				// the mkdir below will use the uid/gid of usr.HomeDir
 				if st.Uid > 0 {
 					uid = sys.UserID(st.Uid)
@@ -364,7 +391,11 @@ func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames [
 			revdir = curdir
 		}
 
-		for _, dir := range []string{"common", revdir} {
+		restoreDirs := []string{"common", revdir}
+		if restoreOpts.CommonOnly {
+			restoreDirs = restoreDirs[:1]
+		}
+		for _, dir := range restoreDirs {
 			if err := moveFile(rs, dir, tempdir, parent); err != nil {
 				return rs, err
 			}
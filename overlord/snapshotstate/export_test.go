@@ -123,7 +123,7 @@ func MockBackendList(f func(ctx context.Context, setID uint64, snapNames []strin
 	}
 }
 
-func MockBackendRestore(f func(*backend.Reader, context.Context, snap.Revision, []string, backend.Logf, *dirs.SnapDirOptions) (*backend.RestoreState, error)) (restore func()) {
+func MockBackendRestore(f func(*backend.Reader, context.Context, snap.Revision, []string, *snap.SnapshotRestoreOptions, backend.Logf, *dirs.SnapDirOptions) (*backend.RestoreState, error)) (restore func()) {
 	old := backendRestore
 	backendRestore = f
 	return func() {
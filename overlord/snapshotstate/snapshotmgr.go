@@ -87,7 +87,8 @@ func Manager(st *state.State, runner *state.TaskRunner) *SnapshotManager {
 
 // Ensure is part of the overlord.StateManager interface.
 func (mgr *SnapshotManager) Ensure() error {
-	// process expired snapshots once a day.
+	// process expired snapshots, and automatic snapshots going over
+	// the size limit, once a day.
 	if time.Now().After(mgr.lastForgetExpiredSnapshotTime.Add(autoExpirationInterval)) {
 		return mgr.forgetExpiredSnapshots()
 	}
@@ -102,6 +103,9 @@ func (mgr *SnapshotManager) StartUp() error {
 	return nil
 }
 
+// forgetExpiredSnapshots forgets the automatic snapshots that are past their
+// expiry time, along with the oldest automatic snapshots exceeding the
+// snapshots.automatic.max-size limit, if any.
 func (mgr *SnapshotManager) forgetExpiredSnapshots() error {
 	mgr.state.Lock()
 	defer mgr.state.Unlock()
@@ -111,6 +115,23 @@ func (mgr *SnapshotManager) forgetExpiredSnapshots() error {
 		return fmt.Errorf("internal error: cannot determine expired snapshots: %v", err)
 	}
 
+	maxSize, err := AutomaticSnapshotsMaxSize(mgr.state)
+	if err != nil {
+		return err
+	}
+	if maxSize > 0 {
+		overBudget, err := overBudgetSnapshotSets(context.TODO(), mgr.state, maxSize, sets)
+		if err != nil {
+			return fmt.Errorf("cannot determine automatic snapshots over the size limit: %v", err)
+		}
+		if len(overBudget) > 0 && sets == nil {
+			sets = make(map[uint64]bool, len(overBudget))
+		}
+		for setID := range overBudget {
+			sets[setID] = true
+		}
+	}
+
 	if len(sets) == 0 {
 		return nil
 	}
@@ -172,6 +193,8 @@ type snapshotSetup struct {
 	Filename string                `json:"filename,omitempty"`
 	Current  snap.Revision         `json:"current"`
 	Auto     bool                  `json:"auto,omitempty"`
+
+	RestoreOptions *snap.SnapshotRestoreOptions `json:"restore-options,omitempty"`
 }
 
 func filename(setID uint64, si *snap.Info) string {
@@ -320,23 +343,30 @@ func doRestore(task *state.Task, tomb *tomb.Tomb) error {
 		return err
 	}
 
-	restoreState, err := backendRestore(reader, tomb.Context(nil), snapshot.Current, snapshot.Users, logf, opts)
+	restoreState, err := backendRestore(reader, tomb.Context(nil), snapshot.Current, snapshot.Users, snapshot.RestoreOptions, logf, opts)
 	if err != nil {
 		return err
 	}
 
-	raw, err := marshalSnapConfig(reader.Conf)
-	if err != nil {
-		backendRevert(restoreState)
-		return fmt.Errorf("cannot marshal saved config: %v", err)
+	// the configuration is only restored along with all of the data
+	restoreConfig := !snapshot.RestoreOptions.Partial()
+	var raw *json.RawMessage
+	if restoreConfig {
+		raw, err = marshalSnapConfig(reader.Conf)
+		if err != nil {
+			backendRevert(restoreState)
+			return fmt.Errorf("cannot marshal saved config: %v", err)
+		}
 	}
 
 	st.Lock()
 	defer st.Unlock()
 
-	if err := configSetSnapConfig(st, snapshot.Snap, raw); err != nil {
-		backendRevert(restoreState)
-		return fmt.Errorf("cannot set snap config: %v", err)
+	if restoreConfig {
+		if err := configSetSnapConfig(st, snapshot.Snap, raw); err != nil {
+			backendRevert(restoreState)
+			return fmt.Errorf("cannot set snap config: %v", err)
+		}
 	}
 
 	if err := backendDiscardSnapNamespace(&snapstateBackend.Backend{}, snapshot.Snap); err != nil {
@@ -23,6 +23,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
@@ -35,6 +36,7 @@ import (
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/snapshotstate"
 	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
@@ -168,6 +170,75 @@ func (snapshotSuite) TestEnsureForgetsSnapshotsRunsRegularly(c *check.C) {
 	c.Check(backendIterCalls, check.Equals, 2)
 }
 
+func (snapshotSuite) TestEnsureForgetsAutomaticSnapshotsOverMaxSize(c *check.C) {
+	dir := c.MkDir()
+	var files []*os.File
+	for i := 1; i <= 4; i++ {
+		f, err := os.Create(filepath.Join(dir, fmt.Sprintf("%d_foo.zip", i)))
+		c.Assert(err, check.IsNil)
+		defer f.Close()
+		files = append(files, f)
+	}
+	restore := snapshotstate.MockBackendIter(func(_ context.Context, f func(*backend.Reader) error) error {
+		for i, file := range files {
+			c.Assert(f(&backend.Reader{
+				Snapshot: client.Snapshot{SetID: uint64(i + 1), Snap: "a-snap"},
+				File:     file,
+			}), check.IsNil)
+		}
+		return nil
+	})
+	defer restore()
+
+	now := time.Now()
+	restore = snapshotstate.MockBackendList(func(context.Context, uint64, []string) ([]client.SnapshotSet, error) {
+		return []client.SnapshotSet{
+			{ID: 1, Snapshots: []*client.Snapshot{{SetID: 1, Time: now.Add(-3 * time.Hour), Size: 100}}},
+			// created by the user, and the oldest of all
+			{ID: 2, Snapshots: []*client.Snapshot{{SetID: 2, Time: now.Add(-4 * time.Hour), Size: 1000}}},
+			{ID: 3, Snapshots: []*client.Snapshot{{SetID: 3, Time: now.Add(-2 * time.Hour), Size: 100}}},
+			{ID: 4, Snapshots: []*client.Snapshot{{SetID: 4, Time: now.Add(-1 * time.Hour), Size: 100}}},
+		}, nil
+	})
+	defer restore()
+
+	var removed []string
+	restore = snapshotstate.MockOsRemove(func(fileName string) error {
+		removed = append(removed, filepath.Base(fileName))
+		return nil
+	})
+	defer restore()
+
+	st := state.New(nil)
+	runner := state.NewTaskRunner(st)
+	mgr := snapshotstate.Manager(st, runner)
+	c.Assert(mgr, check.NotNil)
+
+	st.Lock()
+	defer st.Unlock()
+
+	st.Set("snapshots", map[uint64]interface{}{
+		1: map[string]interface{}{"expiry-time": "2037-02-12T12:50:00Z"},
+		3: map[string]interface{}{"expiry-time": "2037-02-12T12:50:00Z"},
+		4: map[string]interface{}{"expiry-time": "2037-02-12T12:50:00Z"},
+	})
+	tr := config.NewTransaction(st)
+	c.Assert(tr.Set("core", "snapshots.automatic.max-size", "150"), check.IsNil)
+	tr.Commit()
+
+	st.Unlock()
+	c.Assert(mgr.Ensure(), check.IsNil)
+	st.Lock()
+
+	// the oldest automatic snapshots were forgotten, the snapshot
+	// created by the user was kept
+	c.Check(removed, check.DeepEquals, []string{"1_foo.zip", "3_foo.zip"})
+	var expirations map[uint64]interface{}
+	c.Assert(st.Get("snapshots", &expirations), check.IsNil)
+	c.Check(expirations, check.DeepEquals, map[uint64]interface{}{
+		4: map[string]interface{}{"expiry-time": "2037-02-12T12:50:00Z"}})
+}
+
 func (snapshotSuite) testEnsureForgetSnapshotsConflict(c *check.C, snapshotOp string) {
 	removeCalled := 0
 	restoreOsRemove := snapshotstate.MockOsRemove(func(string) error {
@@ -576,7 +647,7 @@ func (rs *readerSuite) SetUpTest(c *check.C) {
 			rs.calls = append(rs.calls, "open")
 			return &backend.Reader{}, nil
 		}),
-		snapshotstate.MockBackendRestore(func(*backend.Reader, context.Context, snap.Revision, []string, backend.Logf, *dirs.SnapDirOptions) (*backend.RestoreState, error) {
+		snapshotstate.MockBackendRestore(func(*backend.Reader, context.Context, snap.Revision, []string, *snap.SnapshotRestoreOptions, backend.Logf, *dirs.SnapDirOptions) (*backend.RestoreState, error) {
 			rs.calls = append(rs.calls, "restore")
 			return &backend.RestoreState{}, nil
 		}),
@@ -615,7 +686,7 @@ func (rs *readerSuite) TestDoRestore(c *check.C) {
 			Snapshot: client.Snapshot{Conf: map[string]interface{}{"hello": "there"}},
 		}, nil
 	})()
-	defer snapshotstate.MockBackendRestore(func(_ *backend.Reader, _ context.Context, _ snap.Revision, users []string, _ backend.Logf, options *dirs.SnapDirOptions) (*backend.RestoreState, error) {
+	defer snapshotstate.MockBackendRestore(func(_ *backend.Reader, _ context.Context, _ snap.Revision, users []string, _ *snap.SnapshotRestoreOptions, _ backend.Logf, options *dirs.SnapDirOptions) (*backend.RestoreState, error) {
 		rs.calls = append(rs.calls, "restore")
 		c.Check(users, check.DeepEquals, []string{"a-user", "b-user"})
 		return &backend.RestoreState{}, nil
@@ -639,6 +710,36 @@ func (rs *readerSuite) TestDoRestore(c *check.C) {
 	c.Check(v, check.DeepEquals, map[string]interface{}{"config": map[string]interface{}{"old": "conf"}})
 }
 
+func (rs *readerSuite) TestDoRestorePartial(c *check.C) {
+	st := rs.task.State()
+	st.Lock()
+	rs.task.Set("snapshot-setup", map[string]interface{}{
+		"snap":            "a-snap",
+		"filename":        "/some/1_file.zip",
+		"users":           []string{"a-user"},
+		"restore-options": map[string]interface{}{"common-only": true},
+	})
+	st.Unlock()
+
+	defer snapshotstate.MockBackendOpen(func(filename string, setID uint64) (*backend.Reader, error) {
+		rs.calls = append(rs.calls, "open")
+		return &backend.Reader{
+			Snapshot: client.Snapshot{Conf: map[string]interface{}{"hello": "there"}},
+		}, nil
+	})()
+	defer snapshotstate.MockBackendRestore(func(_ *backend.Reader, _ context.Context, _ snap.Revision, users []string, restoreOpts *snap.SnapshotRestoreOptions, _ backend.Logf, _ *dirs.SnapDirOptions) (*backend.RestoreState, error) {
+		rs.calls = append(rs.calls, "restore")
+		c.Check(users, check.DeepEquals, []string{"a-user"})
+		c.Check(restoreOpts, check.DeepEquals, &snap.SnapshotRestoreOptions{CommonOnly: true})
+		return &backend.RestoreState{}, nil
+	})()
+
+	err := snapshotstate.DoRestore(rs.task, &tomb.Tomb{})
+	c.Assert(err, check.IsNil)
+	// the configuration is left alone
+	c.Check(rs.calls, check.DeepEquals, []string{"get config", "open", "restore"})
+}
+
 func (rs *readerSuite) TestDoRestoreNoConfig(c *check.C) {
 	defer snapshotstate.MockConfigGetSnapConfig(func(_ *state.State, snapname string) (*json.RawMessage, error) {
 		rs.calls = append(rs.calls, "get config")
@@ -657,7 +758,7 @@ func (rs *readerSuite) TestDoRestoreNoConfig(c *check.C) {
 			Snapshot: client.Snapshot{Snap: "a-snap", Conf: nil},
 		}, nil
 	})()
-	defer snapshotstate.MockBackendRestore(func(_ *backend.Reader, _ context.Context, _ snap.Revision, users []string, _ backend.Logf, options *dirs.SnapDirOptions) (*backend.RestoreState, error) {
+	defer snapshotstate.MockBackendRestore(func(_ *backend.Reader, _ context.Context, _ snap.Revision, users []string, _ *snap.SnapshotRestoreOptions, _ backend.Logf, options *dirs.SnapDirOptions) (*backend.RestoreState, error) {
 		rs.calls = append(rs.calls, "restore")
 		c.Check(users, check.DeepEquals, []string{"a-user", "b-user"})
 		return &backend.RestoreState{}, nil
@@ -740,7 +841,7 @@ func (rs *readerSuite) TestDoRestoreFailsUnserialisableSnapshotConfigError(c *ch
 }
 
 func (rs *readerSuite) TestDoRestoreFailsOnRestoreError(c *check.C) {
-	defer snapshotstate.MockBackendRestore(func(*backend.Reader, context.Context, snap.Revision, []string, backend.Logf, *dirs.SnapDirOptions) (*backend.RestoreState, error) {
+	defer snapshotstate.MockBackendRestore(func(*backend.Reader, context.Context, snap.Revision, []string, *snap.SnapshotRestoreOptions, backend.Logf, *dirs.SnapDirOptions) (*backend.RestoreState, error) {
 		rs.calls = append(rs.calls, "restore")
 		return nil, errors.New("bzzt")
 	})()
@@ -29,6 +29,7 @@ import (
 	"time"
 
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
@@ -151,6 +152,30 @@ func AutomaticSnapshotExpiration(st *state.State) (time.Duration, error) {
 	return defaultAutomaticSnapshotExpiration, nil
 }
 
+// AutomaticSnapshotsMaxSize returns the maximum total size of the automatic
+// snapshots kept on the system, as set with snapshots.automatic.max-size; 0
+// means there is no limit.
+func AutomaticSnapshotsMaxSize(st *state.State) (quantity.Size, error) {
+	var maxSizeStr string
+	tr := config.NewTransaction(st)
+	err := tr.Get("core", "snapshots.automatic.max-size", &maxSizeStr)
+	if err != nil {
+		if config.IsNoOption(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if maxSizeStr == "" {
+		return 0, nil
+	}
+	maxSize, err := quantity.ParseSize(maxSizeStr)
+	if err != nil {
+		logger.Noticef("snapshots.automatic.max-size cannot be parsed: %v", err)
+		return 0, nil
+	}
+	return maxSize, nil
+}
+
 // saveExpiration saves expiration date of the given snapshot set, in the state.
 // The state needs to be locked by the caller.
 func saveExpiration(st *state.State, setID uint64, expiryTime time.Time) error {
@@ -215,6 +240,53 @@ func expiredSnapshotSets(st *state.State, cutoffTime time.Time) (map[uint64]bool
 	return expired, nil
 }
 
+// overBudgetSnapshotSets returns the automatic snapshot sets that need to be
+// forgotten, oldest first, to bring the total size of the automatic snapshots
+// under maxSize. Sets in the forgotten map are assumed to be going away
+// already and are not counted. Snapshots created by the user are never
+// returned.
+// The state needs to be locked by the caller.
+func overBudgetSnapshotSets(ctx context.Context, st *state.State, maxSize quantity.Size, forgotten map[uint64]bool) (map[uint64]bool, error) {
+	sets, err := List(ctx, st, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var automatic []client.SnapshotSet
+	var total int64
+	for _, sset := range sets {
+		if forgotten[sset.ID] || !isAutomaticSet(sset) {
+			continue
+		}
+		automatic = append(automatic, sset)
+		total += sset.Size()
+	}
+	sort.SliceStable(automatic, func(i, j int) bool {
+		return automatic[i].Time().Before(automatic[j].Time())
+	})
+
+	overBudget := make(map[uint64]bool)
+	for _, sset := range automatic {
+		if total <= int64(maxSize) {
+			break
+		}
+		overBudget[sset.ID] = true
+		total -= sset.Size()
+	}
+	return overBudget, nil
+}
+
+// isAutomaticSet returns whether the given set, as returned by List(), was
+// taken automatically on snap removal.
+func isAutomaticSet(sset client.SnapshotSet) bool {
+	for _, sh := range sset.Snapshots {
+		if !sh.Auto {
+			return false
+		}
+	}
+	return len(sset.Snapshots) > 0
+}
+
 // snapshotSnapSummaries are used internally to get useful data from a
 // snapshot set when deciding whether to check/forget/restore it.
 type snapshotSnapSummaries []*snapshotSnapSummary
@@ -335,6 +407,53 @@ func List(ctx context.Context, st *state.State, setID uint64, snapNames []string
 	return sets, nil
 }
 
+// Usage returns the disk space used by the snapshots on the system.
+// Note that the state must be locked by the caller.
+func Usage(ctx context.Context, st *state.State) (*client.SnapshotsUsage, error) {
+	sets, err := List(ctx, st, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := AutomaticSnapshotsMaxSize(st)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &client.SnapshotsUsage{
+		AutomaticMaxSize: int64(maxSize),
+	}
+	for _, sset := range sets {
+		size := sset.Size()
+		usage.Total += size
+		if isAutomaticSet(sset) {
+			usage.Automatic += size
+		}
+	}
+	return usage, nil
+}
+
+// importValidator returns a function rejecting the import of snapshots of
+// installed snaps whose current revision cannot read the snapshot data.
+func importValidator(st *state.State) func(*client.Snapshot) error {
+	return func(snapshot *client.Snapshot) error {
+		st.Lock()
+		defer st.Unlock()
+
+		info, err := snapstateCurrentInfo(st, snapshot.Snap)
+		if err != nil {
+			if _, ok := err.(*snap.NotInstalledError); ok {
+				return nil
+			}
+			return err
+		}
+		if !info.Epoch.CanRead(snapshot.Epoch) {
+			const tpl = "cannot import snapshot for %q: current snap (epoch %s) cannot read snapshot data (epoch %s)"
+			return fmt.Errorf(tpl, snapshot.Snap, &info.Epoch, &snapshot.Epoch)
+		}
+		return nil
+	}
+}
+
 // Import a given snapshot ID from an exported snapshot
 func Import(ctx context.Context, st *state.State, r io.Reader) (setID uint64, snapNames []string, err error) {
 	st.Lock()
@@ -347,7 +466,8 @@ func Import(ctx context.Context, st *state.State, r io.Reader) (setID uint64, sn
 		return 0, nil, err
 	}
 
-	snapNames, err = backendImport(ctx, setID, r, nil)
+	flags := &backend.ImportFlags{ValidateSnapshot: importValidator(st)}
+	snapNames, err = backendImport(ctx, setID, r, flags)
 	if err != nil {
 		if dupErr, ok := err.(backend.DuplicatedSnapshotImportError); ok {
 			st.Lock()
@@ -356,7 +476,7 @@ func Import(ctx context.Context, st *state.State, r io.Reader) (setID uint64, sn
 			if err := checkSnapshotConflict(st, dupErr.SetID, "forget-snapshot"); err != nil {
 				// we found an existing snapshot but it's being forgotten, so
 				// retry the import without checking for existing snapshot.
-				flags := &backend.ImportFlags{NoDuplicatedImportCheck: true}
+				flags.NoDuplicatedImportCheck = true
 				st.Unlock()
 				snapNames, err = backendImport(ctx, setID, r, flags)
 				st.Lock()
@@ -467,9 +587,10 @@ func AutomaticSnapshot(st *state.State, snapName string) (ts *state.TaskSet, err
 	return ts, nil
 }
 
-// Restore creates a taskset for restoring a snapshot's data.
+// Restore creates a taskset for restoring a snapshot's data, limited to the
+// data selected by the given options, if any.
 // Note that the state must be locked by the caller.
-func Restore(st *state.State, setID uint64, snapNames []string, users []string) (snapsFound []string, ts *state.TaskSet, err error) {
+func Restore(st *state.State, setID uint64, snapNames []string, users []string, opts *snap.SnapshotRestoreOptions) (snapsFound []string, ts *state.TaskSet, err error) {
 	summaries, err := snapSummariesInSnapshotSet(setID, snapNames)
 	if err != nil {
 		return nil, nil, err
@@ -514,11 +635,12 @@ func Restore(st *state.State, setID uint64, snapNames []string, users []string)
 		desc := fmt.Sprintf("Restore data of snap %q from snapshot set #%d", summary.snap, setID)
 		task := st.NewTask("restore-snapshot", desc)
 		snapshot := snapshotSetup{
-			SetID:    setID,
-			Snap:     summary.snap,
-			Users:    users,
-			Filename: summary.filename,
-			Current:  current,
+			SetID:          setID,
+			Snap:           summary.snap,
+			Users:          users,
+			Filename:       summary.filename,
+			Current:        current,
+			RestoreOptions: opts,
 		}
 		task.Set("snapshot-setup", &snapshot)
 		// see the note about snapshots not using lanes, above.
@@ -42,6 +42,7 @@ import (
 	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/osutil/sys"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/configstate/config"
@@ -929,7 +930,7 @@ func (snapshotSuite) TestRestoreChecksIterError(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	_, _, err := snapshotstate.Restore(st, 42, nil, nil)
+	_, _, err := snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.ErrorMatches, "bzzt")
 }
 
@@ -937,7 +938,7 @@ func (s snapshotSuite) TestRestoreChecksSnapstateConflicts(c *check.C) {
 	st, restore := s.createConflictingChange(c)
 	defer restore()
 
-	_, _, err := snapshotstate.Restore(st, 42, nil, nil)
+	_, _, err := snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.NotNil)
 	c.Check(err, check.FitsTypeOf, &snapstate.ChangeConflictError{})
 
@@ -994,7 +995,7 @@ func (snapshotSuite) TestRestoreConflictsWithSnapstate(c *check.C) {
 	})
 
 	chg := st.NewChange("snapshot-restore", "...")
-	_, restoreTasks, err := snapshotstate.Restore(st, 42, nil, nil)
+	_, restoreTasks, err := snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.IsNil)
 	chg.AddAll(restoreTasks)
 
@@ -1026,7 +1027,7 @@ func (snapshotSuite) TestRestoreChecksForgetConflicts(c *check.C) {
 	tsk.Set("snapshot-setup", map[string]int{"set-id": 42})
 	chg.AddTask(tsk)
 
-	_, _, err = snapshotstate.Restore(st, 42, nil, nil)
+	_, _, err = snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.ErrorMatches, `cannot operate on snapshot set #42 while change \"1\" is in progress`)
 }
 
@@ -1061,7 +1062,7 @@ func (snapshotSuite) TestRestoreChecksChangesToSnapID(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	_, _, err = snapshotstate.Restore(st, 42, nil, nil)
+	_, _, err = snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.ErrorMatches, `cannot restore snapshot for "a-snap": current snap \(ID 1234567…\) does not match snapshot \(ID 0987654…\)`)
 }
 
@@ -1102,7 +1103,7 @@ func (snapshotSuite) TestRestoreChecksChangesToEpoch(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	_, _, err = snapshotstate.Restore(st, 42, nil, nil)
+	_, _, err = snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.ErrorMatches, `cannot restore snapshot for "a-snap": current snap \(epoch 17\) cannot read snapshot data \(epoch 42\)`)
 }
 
@@ -1143,7 +1144,7 @@ func (snapshotSuite) TestRestoreWorksWithCompatibleEpoch(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	found, taskset, err := snapshotstate.Restore(st, 42, nil, nil)
+	found, taskset, err := snapshotstate.Restore(st, 42, nil, nil, nil)
 	c.Assert(err, check.IsNil)
 	c.Check(found, check.DeepEquals, []string{"a-snap"})
 	tasks := taskset.Tasks()
@@ -1180,7 +1181,7 @@ func (snapshotSuite) TestRestore(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	found, taskset, err := snapshotstate.Restore(st, 42, []string{"a-snap", "b-snap"}, []string{"a-user"})
+	found, taskset, err := snapshotstate.Restore(st, 42, []string{"a-snap", "b-snap"}, []string{"a-user"}, nil)
 	c.Assert(err, check.IsNil)
 	c.Check(found, check.DeepEquals, []string{"a-snap"})
 	tasks := taskset.Tasks()
@@ -1199,6 +1200,38 @@ func (snapshotSuite) TestRestore(c *check.C) {
 	})
 }
 
+func (snapshotSuite) TestRestoreWithOptions(c *check.C) {
+	shotfile, err := os.Create(filepath.Join(c.MkDir(), "yadda.zip"))
+	c.Assert(err, check.IsNil)
+	defer shotfile.Close()
+	defer snapshotstate.MockBackendIter(func(_ context.Context, f func(*backend.Reader) error) error {
+		return f(&backend.Reader{
+			Snapshot: client.Snapshot{SetID: 42, Snap: "a-snap"},
+			File:     shotfile,
+		})
+	})()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	opts := &snap.SnapshotRestoreOptions{SkipSystem: true, ForceOwnership: true}
+	_, taskset, err := snapshotstate.Restore(st, 42, nil, []string{"a-user"}, opts)
+	c.Assert(err, check.IsNil)
+	tasks := taskset.Tasks()
+	c.Assert(tasks, check.HasLen, 2)
+	var snapshot map[string]interface{}
+	c.Check(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot, check.DeepEquals, map[string]interface{}{
+		"set-id":          42.,
+		"snap":            "a-snap",
+		"filename":        shotfile.Name(),
+		"users":           []interface{}{"a-user"},
+		"current":         "unset",
+		"restore-options": map[string]interface{}{"skip-system": true, "force-ownership": true},
+	})
+}
+
 func (snapshotSuite) TestRestoreIntegration(c *check.C) {
 	testRestoreIntegration(c, dirs.UserHomeSnapDir, nil)
 }
@@ -1271,7 +1304,7 @@ func testRestoreIntegration(c *check.C, snapDataDir string, opts *dirs.SnapDirOp
 	// remove b-user's home
 	c.Assert(os.RemoveAll(homedirB), check.IsNil)
 
-	found, taskset, err := snapshotstate.Restore(st, 42, nil, []string{"a-user", "b-user"})
+	found, taskset, err := snapshotstate.Restore(st, 42, nil, []string{"a-user", "b-user"}, nil)
 	c.Assert(err, check.IsNil)
 	sort.Strings(found)
 	c.Check(found, check.DeepEquals, []string{"one-snap", "too-snap", "tri-snap"})
@@ -1351,7 +1384,7 @@ func (snapshotSuite) TestRestoreIntegrationFails(c *check.C) {
 	c.Assert(os.MkdirAll(filepath.Join(homedir, "snap"), 0755), check.IsNil)
 	c.Assert(os.MkdirAll(filepath.Join(homedir, "snap", "too-snap"), 0), check.IsNil)
 
-	found, taskset, err := snapshotstate.Restore(st, 42, nil, []string{"a-user"})
+	found, taskset, err := snapshotstate.Restore(st, 42, nil, []string{"a-user"}, nil)
 	c.Assert(err, check.IsNil)
 	sort.Strings(found)
 	c.Check(found, check.DeepEquals, []string{"one-snap", "too-snap", "tri-snap"})
@@ -1749,6 +1782,54 @@ func (snapshotSuite) TestAutomaticSnapshotDefaultUbuntuCore(c *check.C) {
 	c.Assert(du, check.Equals, time.Duration(0))
 }
 
+func (snapshotSuite) TestAutomaticSnapshotsMaxSize(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	maxSize, err := snapshotstate.AutomaticSnapshotsMaxSize(st)
+	c.Assert(err, check.IsNil)
+	c.Check(maxSize, check.Equals, quantity.Size(0))
+
+	tr := config.NewTransaction(st)
+	c.Assert(tr.Set("core", "snapshots.automatic.max-size", "2G"), check.IsNil)
+	tr.Commit()
+
+	maxSize, err = snapshotstate.AutomaticSnapshotsMaxSize(st)
+	c.Assert(err, check.IsNil)
+	c.Check(maxSize, check.Equals, 2*quantity.SizeGiB)
+}
+
+func (snapshotSuite) TestUsage(c *check.C) {
+	restore := snapshotstate.MockBackendList(func(context.Context, uint64, []string) ([]client.SnapshotSet, error) {
+		return []client.SnapshotSet{
+			{ID: 1, Snapshots: []*client.Snapshot{{SetID: 1, Snap: "foo", Size: 100}, {SetID: 1, Snap: "bar", Size: 200}}},
+			{ID: 2, Snapshots: []*client.Snapshot{{SetID: 2, Snap: "foo", Size: 400}}},
+		}, nil
+	})
+	defer restore()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	st.Set("snapshots", map[uint64]interface{}{
+		2: map[string]interface{}{"expiry-time": "2037-02-12T12:50:00Z"},
+	})
+
+	usage, err := snapshotstate.Usage(context.Background(), st)
+	c.Assert(err, check.IsNil)
+	c.Check(usage, check.DeepEquals, &client.SnapshotsUsage{Total: 700, Automatic: 400})
+
+	tr := config.NewTransaction(st)
+	c.Assert(tr.Set("core", "snapshots.automatic.max-size", "1000"), check.IsNil)
+	tr.Commit()
+
+	usage, err = snapshotstate.Usage(context.Background(), st)
+	c.Assert(err, check.IsNil)
+	c.Check(usage, check.DeepEquals, &client.SnapshotsUsage{Total: 700, Automatic: 400, AutomaticMaxSize: 1000})
+}
+
 func (snapshotSuite) TestListError(c *check.C) {
 	restore := snapshotstate.MockBackendList(func(context.Context, uint64, []string) ([]client.SnapshotSet, error) {
 		return nil, fmt.Errorf("boom")
@@ -1857,6 +1938,35 @@ func (snapshotSuite) TestImportSnapshotHappy(c *check.C) {
 	c.Check(names, check.DeepEquals, fakeSnapNames)
 }
 
+func (snapshotSuite) TestImportSnapshotValidatesEpoch(c *check.C) {
+	restore := snapshotstate.MockSnapstateCurrentInfo(func(_ *state.State, name string) (*snap.Info, error) {
+		if name != "foo" {
+			return nil, &snap.NotInstalledError{Snap: name}
+		}
+		return &snap.Info{SideInfo: snap.SideInfo{RealName: "foo"}, Epoch: snap.E("2")}, nil
+	})
+	defer restore()
+
+	var validate func(*client.Snapshot) error
+	restore = snapshotstate.MockBackendImport(func(ctx context.Context, id uint64, r io.Reader, flags *backend.ImportFlags) ([]string, error) {
+		c.Assert(flags, check.NotNil)
+		validate = flags.ValidateSnapshot
+		return []string{"foo"}, nil
+	})
+	defer restore()
+
+	st := state.New(nil)
+	_, _, err := snapshotstate.Import(context.TODO(), st, &bytes.Buffer{})
+	c.Assert(err, check.IsNil)
+	c.Assert(validate, check.NotNil)
+
+	c.Check(validate(&client.Snapshot{Snap: "foo", Epoch: snap.E("2")}), check.IsNil)
+	c.Check(validate(&client.Snapshot{Snap: "foo", Epoch: snap.E("3")}), check.ErrorMatches,
+		`cannot import snapshot for "foo": current snap \(epoch 2\) cannot read snapshot data \(epoch 3\)`)
+	// snaps that are not installed can be imported at any epoch
+	c.Check(validate(&client.Snapshot{Snap: "bar", Epoch: snap.E("3")}), check.IsNil)
+}
+
 func (snapshotSuite) TestImportSnapshotImportError(c *check.C) {
 	st := state.New(nil)
 
@@ -2035,7 +2145,8 @@ func (snapshotSuite) TestImportSnapshotConflictsWithForget(c *check.C) {
 		importCalls++
 		switch importCalls {
 		case 1:
-			c.Assert(flags, check.IsNil)
+			c.Assert(flags, check.NotNil)
+			c.Assert(flags.NoDuplicatedImportCheck, check.Equals, false)
 		case 2:
 			c.Assert(flags, check.NotNil)
 			c.Assert(flags.NoDuplicatedImportCheck, check.Equals, true)
@@ -24,8 +24,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"time"
 
+	"github.com/snapcore/snapd/cmd/snaplock/runinhibit"
 	"github.com/snapcore/snapd/httputil"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/logger"
@@ -49,11 +51,12 @@ const maxPostponement = 95 * 24 * time.Hour
 // buffer for maxPostponement when holding snaps with auto-refresh gating
 const maxPostponementBuffer = 5 * 24 * time.Hour
 
-// cannot inhibit refreshes for more than maxInhibition;
+// by default refreshes cannot be inhibited for more than defaultMaxInhibition,
+// this can be changed with refresh.max-inhibition;
 // deduct 1s so it doesn't look confusing initially when two notifications
 // get displayed in short period of time and it immediately goes from "14 days"
 // to "13 days" left.
-const maxInhibition = 14*24*time.Hour - time.Second
+const defaultMaxInhibition = 14*24*time.Hour - time.Second
 
 // maxDuration is used to represent "forever" internally (it's 290 years).
 const maxDuration = time.Duration(1<<63 - 1)
@@ -582,6 +585,82 @@ func autoRefreshSummary(updated []string) string {
 	return msg
 }
 
+// RefreshCandidateInfo describes a snap that would be refreshed by the next
+// auto-refresh.
+type RefreshCandidateInfo struct {
+	InstanceName string
+	// Current is the currently installed revision.
+	Current snap.Revision
+	// Revision is the revision the snap would be refreshed to.
+	Revision     snap.Revision
+	Channel      string
+	DownloadSize int64
+	// Deferred is set to why the next auto-refresh will not refresh the
+	// snap even though an update is available, e.g. because of its health.
+	Deferred string
+}
+
+// RefreshCandidatesPreview returns the snaps which the next auto-refresh would
+// refresh, sorted by name. The candidates are computed as for an auto-refresh,
+// they must satisfy the enforced validation sets and snaps held for
+// auto-refresh are left out, but the refresh candidates recorded in the state
+// are left untouched and no change is created. Snaps whose auto-refresh is
+// deferred because of their health are included with Deferred set. The state
+// is unlocked while the store is queried.
+func RefreshCandidatesPreview(ctx context.Context, st *state.State) ([]*RefreshCandidateInfo, error) {
+	deviceCtx, err := DevicePastSeeding(st, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	updates, stateByInstanceName, ignoreValidation, err := refreshCandidates(ctx, st, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ValidateRefreshes != nil && len(updates) != 0 {
+		const userID = 0
+		updates, err = ValidateRefreshes(st, updates, ignoreValidation, userID, deviceCtx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	held, err := HeldSnaps(st, HoldAutoRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(updates))
+	for _, update := range updates {
+		names = append(names, update.InstanceName())
+	}
+	deferred, err := autoRefreshDeferredByHealth(st, names)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*RefreshCandidateInfo, 0, len(updates))
+	for _, update := range updates {
+		name := update.InstanceName()
+		if _, ok := held[name]; ok {
+			continue
+		}
+		snapst := stateByInstanceName[name]
+		candidates = append(candidates, &RefreshCandidateInfo{
+			InstanceName: name,
+			Current:      snapst.Current,
+			Revision:     update.Revision,
+			Channel:      snapst.TrackingChannel,
+			DownloadSize: update.Size,
+			Deferred:     deferred[name],
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].InstanceName < candidates[j].InstanceName
+	})
+	return candidates, nil
+}
+
 type tooSoonError struct{}
 
 func (e tooSoonError) Error() string {
@@ -745,11 +824,102 @@ func (e *timedBusySnapError) Is(err error) bool {
 	return ok
 }
 
+// refreshMaxInhibition returns for how long running apps can inhibit a
+// refresh of their snap, as set by refresh.max-inhibition.
+func refreshMaxInhibition(st *state.State) time.Duration {
+	var maxInhibitionStr string
+	err := config.NewTransaction(st).Get("core", "refresh.max-inhibition", &maxInhibitionStr)
+	if err != nil && !config.IsNoOption(err) {
+		logger.Noticef("internal error: refresh.max-inhibition system option is not valid: %v", err)
+	}
+	if maxInhibitionStr == "" {
+		return defaultMaxInhibition
+	}
+	maxInhibition, err := time.ParseDuration(maxInhibitionStr)
+	if err != nil {
+		logger.Noticef("internal error: refresh.max-inhibition system option is not valid: %v", err)
+		return defaultMaxInhibition
+	}
+	// see defaultMaxInhibition
+	return maxInhibition - time.Second
+}
+
+// UnhealthyForAutoRefresh is set by healthstate, it returns why the health
+// of the snap with the given instance name should defer its auto-refreshes
+// when refresh.block-on-unhealthy is set, or the empty string if the snap is
+// healthy enough to be refreshed.
+var UnhealthyForAutoRefresh = func(st *state.State, instanceName string) (string, error) {
+	return "", nil
+}
+
+// autoRefreshDeferredByHealth returns, for the snaps among the given ones
+// that should not be auto-refreshed because of their health, why. Nothing
+// is deferred unless refresh.block-on-unhealthy is set.
+func autoRefreshDeferredByHealth(st *state.State, instanceNames []string) (map[string]string, error) {
+	var blockOnUnhealthy bool
+	err := config.NewTransaction(st).Get("core", "refresh.block-on-unhealthy", &blockOnUnhealthy)
+	if err != nil && !config.IsNoOption(err) {
+		logger.Noticef("internal error: refresh.block-on-unhealthy system option is not valid: %v", err)
+		return nil, nil
+	}
+	if !blockOnUnhealthy {
+		return nil, nil
+	}
+
+	var deferred map[string]string
+	for _, name := range instanceNames {
+		reason, err := UnhealthyForAutoRefresh(st, name)
+		if err != nil {
+			return nil, err
+		}
+		if reason == "" {
+			continue
+		}
+		if deferred == nil {
+			deferred = make(map[string]string)
+		}
+		deferred[name] = reason
+	}
+	return deferred, nil
+}
+
+// RefreshInhibitProceedTime returns the time after which a refresh of the
+// snap that is inhibited by running apps proceeds regardless. It returns the
+// zero time if the refresh of the snap is not inhibited.
+// The caller should be holding the state lock.
+func (snapst *SnapState) RefreshInhibitProceedTime(st *state.State) time.Time {
+	if snapst.RefreshInhibitedTime == nil {
+		return time.Time{}
+	}
+	return snapst.RefreshInhibitedTime.Add(refreshMaxInhibition(st))
+}
+
+// updateRefreshInhibition mirrors the refresh inhibition of the snap in the
+// inhibition directory, where it can be read by agents in the user session
+// to warn about the upcoming refresh.
+func updateRefreshInhibition(st *state.State, snapst *SnapState, instanceName string) error {
+	if snapst.RefreshInhibitedTime == nil {
+		return runinhibit.ClearRefreshInhibition(instanceName)
+	}
+	return runinhibit.SetRefreshInhibition(instanceName, &runinhibit.RefreshInhibition{
+		FirstInhibited: *snapst.RefreshInhibitedTime,
+		ProceedTime:    snapst.RefreshInhibitProceedTime(st),
+	})
+}
+
+// preRefreshNotify is called right before a refresh that was inhibited by
+// running apps for too long is forced, to let the user know that the snap is
+// being refreshed despite its apps running.
+var preRefreshNotify = func(st *state.State, refreshInfo *userclient.PendingSnapRefreshInfo) {
+	logger.Noticef("refresh of snap %q was inhibited by running apps for too long, proceeding", refreshInfo.InstanceName)
+	asyncPendingRefreshNotification(context.TODO(), userclient.New(), refreshInfo)
+}
+
 // inhibitRefresh returns an error if refresh is inhibited by running apps.
 //
 // Internally the snap state is updated to remember when the inhibition first
-// took place. Apps can inhibit refreshes for up to "maxInhibition", beyond
-// that period the refresh will go ahead despite application activity.
+// took place. Apps can inhibit refreshes for up to refresh.max-inhibition,
+// beyond that period the refresh will go ahead despite application activity.
 func inhibitRefresh(st *state.State, snapst *SnapState, snapsup *SnapSetup, info *snap.Info) error {
 	checkerErr := refreshAppsCheck(info)
 	if checkerErr == nil {
@@ -768,6 +938,7 @@ func inhibitRefresh(st *state.State, snapst *SnapState, snapsup *SnapSetup, info
 	// Decide on what to do depending on the state of the snap and the remaining
 	// inhibition time.
 	now := time.Now()
+	maxInhibition := refreshMaxInhibition(st)
 	switch {
 	case snapst.RefreshInhibitedTime == nil:
 		// If the snap did not have inhibited refresh yet then commence a new
@@ -786,14 +957,19 @@ func inhibitRefresh(st *state.State, snapst *SnapState, snapsup *SnapSetup, info
 	default:
 		// if the refresh inhibition window has ended, notify the user that the
 		// refresh is happening now and ignore the error
-		refreshInfo := busyErr.PendingSnapRefreshInfo()
-		asyncPendingRefreshNotification(context.TODO(), userclient.New(), refreshInfo)
+		preRefreshNotify(st, busyErr.PendingSnapRefreshInfo())
 		// important to return "nil" type here instead of
 		// setting busyErr to nil as otherwise we return a nil
 		// interface which is not the nil type
 		return nil
 	}
 
+	// the proceed time is refreshed as well, as refresh.max-inhibition may
+	// have changed in the meantime
+	if err := updateRefreshInhibition(st, snapst, info.InstanceName()); err != nil {
+		logger.Noticef("cannot record refresh inhibition of snap %q: %v", info.InstanceName(), err)
+	}
+
 	return busyErr
 }
 
@@ -763,13 +763,25 @@ var snapsToRefresh = func(gatingTask *state.Task) ([]*refreshCandidate, error) {
 		return nil, err
 	}
 
-	var skipped []string
+	names := make([]string, 0, len(snaps))
+	for name := range snaps {
+		names = append(names, name)
+	}
+	unhealthy, err := autoRefreshDeferredByHealth(gatingTask.State(), names)
+	if err != nil {
+		return nil, err
+	}
+
+	var skipped, skippedUnhealthy []string
 	var candidates []*refreshCandidate
 	for _, s := range snaps {
-		if _, ok := held[s.InstanceName()]; !ok {
-			candidates = append(candidates, s)
+		name := s.InstanceName()
+		if _, ok := held[name]; ok {
+			skipped = append(skipped, name)
+		} else if _, ok := unhealthy[name]; ok {
+			skippedUnhealthy = append(skippedUnhealthy, name)
 		} else {
-			skipped = append(skipped, s.InstanceName())
+			candidates = append(candidates, s)
 		}
 	}
 
@@ -777,6 +789,10 @@ var snapsToRefresh = func(gatingTask *state.Task) ([]*refreshCandidate, error) {
 		sort.Strings(skipped)
 		logger.Noticef("skipping refresh of held snaps: %s", strings.Join(skipped, ","))
 	}
+	if len(skippedUnhealthy) > 0 {
+		sort.Strings(skippedUnhealthy)
+		logger.Noticef("skipping refresh of unhealthy snaps: %s", strings.Join(skippedUnhealthy, ","))
+	}
 
 	return candidates, nil
 }
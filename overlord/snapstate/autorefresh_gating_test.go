@@ -2852,6 +2852,65 @@ func (s *autorefreshGatingSuite) TestHoldRefreshesBySystem(c *C) {
 	c.Check(untilTime.Equal(snapAUntilTime), Equals, true)
 }
 
+func (s *autorefreshGatingSuite) TestHoldRefreshesBySystemAndGatingSnapLongestWins(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	now, err := time.Parse(time.RFC3339, "2021-05-10T10:00:00Z")
+	c.Assert(err, IsNil)
+	restore := snapstate.MockTimeNow(func() time.Time {
+		return now
+	})
+	defer restore()
+
+	mockInstalledSnap(c, st, snapAyaml, false)
+	mockInstalledSnap(c, st, snapByaml, false)
+	mockInstalledSnap(c, st, snapDyaml, false)
+	mockLastRefreshed(c, st, "2021-05-09T10:00:00Z", "snap-a", "snap-b")
+
+	// the user holds snap-a for longer than snap-d does, and snap-b for
+	// shorter
+	err = snapstate.HoldRefreshesBySystem(st, snapstate.HoldAutoRefresh, "2021-05-13T10:00:00Z", []string{"snap-a"})
+	c.Assert(err, IsNil)
+	err = snapstate.HoldRefreshesBySystem(st, snapstate.HoldAutoRefresh, "2021-05-11T10:00:00Z", []string{"snap-b"})
+	c.Assert(err, IsNil)
+	_, err = snapstate.HoldRefresh(st, snapstate.HoldAutoRefresh, "snap-d", 24*time.Hour, "snap-a")
+	c.Assert(err, IsNil)
+	_, err = snapstate.HoldRefresh(st, snapstate.HoldAutoRefresh, "snap-d", 48*time.Hour, "snap-b")
+	c.Assert(err, IsNil)
+
+	held, err := snapstate.HeldSnaps(st, snapstate.HoldAutoRefresh)
+	c.Assert(err, IsNil)
+	c.Check(held, testutil.DeepUnsortedMatches, map[string][]string{
+		"snap-a": {"snap-d", "system"},
+		"snap-b": {"snap-d", "system"},
+	})
+
+	// the shorter holds lapse, the longer ones still apply
+	now = now.Add(36 * time.Hour)
+	held, err = snapstate.HeldSnaps(st, snapstate.HoldAutoRefresh)
+	c.Assert(err, IsNil)
+	c.Check(held, DeepEquals, map[string][]string{
+		"snap-a": {"system"},
+		"snap-b": {"snap-d"},
+	})
+
+	// removing the user holds does not affect the holds of snap-d
+	c.Assert(snapstate.ProceedWithRefresh(st, "system", []string{"snap-a", "snap-b"}), IsNil)
+	held, err = snapstate.HeldSnaps(st, snapstate.HoldAutoRefresh)
+	c.Assert(err, IsNil)
+	c.Check(held, DeepEquals, map[string][]string{
+		"snap-b": {"snap-d"},
+	})
+
+	// until all holds lapse
+	now = now.Add(24 * time.Hour)
+	held, err = snapstate.HeldSnaps(st, snapstate.HoldAutoRefresh)
+	c.Assert(err, IsNil)
+	c.Check(held, HasLen, 0)
+}
+
 func (s *autorefreshGatingSuite) TestHoldRefreshesBySystemFailsIfNotInstalled(c *C) {
 	st := s.state
 	st.Lock()
@@ -33,6 +33,7 @@ import (
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/cmd/snaplock/runinhibit"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/httputil"
 	"github.com/snapcore/snapd/logger"
@@ -1040,6 +1041,91 @@ func (s *autoRefreshTestSuite) TestInhibitRefreshRefreshesWhenOverdue(c *C) {
 	c.Check(notificationCount, Equals, 1)
 }
 
+func (s *autoRefreshTestSuite) TestInhibitRefreshRecordsRefreshInhibition(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	si := &snap.SideInfo{RealName: "pkg", Revision: snap.R(1)}
+	info := &snap.Info{SideInfo: *si}
+	snapst := &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+	}
+	snapsup := &snapstate.SnapSetup{Flags: snapstate.Flags{IsAutoRefresh: true}}
+
+	restore := snapstate.MockRefreshAppsCheck(func(si *snap.Info) error {
+		return snapstate.NewBusySnapError(si, []int{123}, nil, nil)
+	})
+	defer restore()
+
+	c.Check(snapst.RefreshInhibitProceedTime(s.state).IsZero(), Equals, true)
+
+	err := snapstate.InhibitRefresh(s.state, snapst, snapsup, info)
+	c.Assert(err, ErrorMatches, `snap "pkg" has running apps or hooks, pids: 123`)
+	c.Assert(snapst.RefreshInhibitedTime, NotNil)
+
+	proceedTime := snapst.RefreshInhibitProceedTime(s.state)
+	c.Check(proceedTime.Equal(snapst.RefreshInhibitedTime.Add(snapstate.MaxInhibition)), Equals, true)
+
+	// the inhibition is visible to agents in the user session
+	inhibition, err := runinhibit.ReadRefreshInhibition("pkg")
+	c.Assert(err, IsNil)
+	c.Assert(inhibition, NotNil)
+	c.Check(inhibition.FirstInhibited.Equal(*snapst.RefreshInhibitedTime), Equals, true)
+	c.Check(inhibition.ProceedTime.Equal(proceedTime), Equals, true)
+}
+
+func (s *autoRefreshTestSuite) TestInhibitRefreshMaxInhibitionFromConfig(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.max-inhibition", "2h")
+	tr.Commit()
+
+	var notified []*userclient.PendingSnapRefreshInfo
+	restore := snapstate.MockAsyncPendingRefreshNotification(func(ctx context.Context, client *userclient.Client, refreshInfo *userclient.PendingSnapRefreshInfo) {
+		notified = append(notified, refreshInfo)
+	})
+	defer restore()
+
+	restore = snapstate.MockRefreshAppsCheck(func(si *snap.Info) error {
+		return snapstate.NewBusySnapError(si, []int{123}, nil, nil)
+	})
+	defer restore()
+
+	pastInstant := time.Now().Add(-time.Hour)
+	si := &snap.SideInfo{RealName: "pkg", Revision: snap.R(1)}
+	info := &snap.Info{SideInfo: *si}
+	snapst := &snapstate.SnapState{
+		Sequence:             []*snap.SideInfo{si},
+		Current:              si.Revision,
+		RefreshInhibitedTime: &pastInstant,
+	}
+	snapsup := &snapstate.SnapSetup{Flags: snapstate.Flags{IsAutoRefresh: true}}
+
+	// still within the configured window
+	err := snapstate.InhibitRefresh(s.state, snapst, snapsup, info)
+	var timedErr *snapstate.TimedBusySnapError
+	c.Assert(errors.As(err, &timedErr), Equals, true)
+	// XXX: This test measures real time, with second granularity.
+	c.Check(timedErr.PendingSnapRefreshInfo().TimeRemaining, Equals, time.Hour-2*time.Second)
+	c.Check(notified, HasLen, 0)
+
+	inhibition, err := runinhibit.ReadRefreshInhibition("pkg")
+	c.Assert(err, IsNil)
+	c.Assert(inhibition, NotNil)
+	c.Check(inhibition.ProceedTime.Equal(pastInstant.Add(2*time.Hour-time.Second)), Equals, true)
+
+	// beyond the configured window the refresh proceeds after notifying
+	pastInstant = time.Now().Add(-3 * time.Hour)
+	err = snapstate.InhibitRefresh(s.state, snapst, snapsup, info)
+	c.Assert(err == nil, Equals, true)
+	c.Assert(notified, HasLen, 1)
+	c.Check(notified[0].InstanceName, Equals, "pkg")
+	c.Check(notified[0].TimeRemaining, Equals, time.Duration(0))
+}
+
 func (s *autoRefreshTestSuite) TestInhibitNoNotificationOnManualRefresh(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -20,6 +20,7 @@
 package backend
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -53,6 +54,12 @@ func MockAllUsers(f func(options *dirs.SnapDirOptions) ([]*user.User, error)) fu
 
 // CopySnapData makes a copy of oldSnap data for newSnap in its data directories.
 func (b Backend) CopySnapData(newSnap, oldSnap *snap.Info, opts *dirs.SnapDirOptions, meter progress.Meter) error {
+	return b.CopySnapDataWithContext(context.Background(), newSnap, oldSnap, opts, meter)
+}
+
+// CopySnapDataWithContext is like CopySnapData but stops copying when ctx
+// is cancelled, removing any partially copied data directory.
+func (b Backend) CopySnapDataWithContext(ctx context.Context, newSnap, oldSnap *snap.Info, opts *dirs.SnapDirOptions, meter progress.Meter) error {
 	// deal with the old data or
 	// otherwise just create an empty data dir
 
@@ -77,7 +84,7 @@ func (b Backend) CopySnapData(newSnap, oldSnap *snap.Info, opts *dirs.SnapDirOpt
 		return nil
 	}
 
-	return copySnapData(oldSnap, newSnap, opts)
+	return copySnapData(ctx, oldSnap, newSnap, opts)
 }
 
 // UndoCopySnapData removes the copy that may have been done for newInfo snap of oldInfo snap data and also the data directories that may have been created for newInfo snap.
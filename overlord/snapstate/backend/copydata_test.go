@@ -20,6 +20,7 @@
 package backend_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -28,6 +29,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"time"
 
 	. "gopkg.in/check.v1"
 
@@ -482,6 +484,27 @@ func (s *copydataSuite) TestCopyDataCopyFailure(c *C) {
 	c.Assert(err, ErrorMatches, fmt.Sprintf(`cannot copy %s to %s: .*: "cp: boom" \(3\)`, q(v1.DataDir()), q(v2.DataDir())))
 }
 
+func (s *copydataSuite) TestCopyDataWithContextCancelled(c *C) {
+	v1 := snaptest.MockSnap(c, helloYaml1, &snap.SideInfo{Revision: snap.R(10)})
+	s.populateData(c, snap.R(10))
+
+	// pretend we install a new version
+	v2 := snaptest.MockSnap(c, helloYaml2, &snap.SideInfo{Revision: snap.R(20)})
+
+	// the copy gets stuck midway
+	defer testutil.MockCommand(c, "cp", `mkdir -p "$3"; touch "$3/partial"; exec sleep 10`).Restore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	err := s.be.CopySnapDataWithContext(ctx, v2, v1, nil, progress.Null)
+	c.Assert(err, ErrorMatches, `cannot copy .*: context canceled`)
+
+	// the partially copied data is gone, the old one is untouched
+	c.Check(v2.DataDir(), testutil.FileAbsent)
+	c.Check(filepath.Join(v1.DataDir(), "random-subdir", "canary"), testutil.FilePresent)
+}
+
 func (s *copydataSuite) TestCopyDataPartialFailure(c *C) {
 	v1 := snaptest.MockSnap(c, helloYaml1, &snap.SideInfo{Revision: snap.R(10)})
 
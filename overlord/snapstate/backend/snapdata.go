@@ -20,6 +20,7 @@
 package backend
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -155,7 +156,7 @@ func snapCommonDataDirs(snap *snap.Info, opts *dirs.SnapDirOptions) ([]string, e
 
 // Copy all data for oldSnap to newSnap
 // (but never overwrite)
-func copySnapData(oldSnap, newSnap *snap.Info, opts *dirs.SnapDirOptions) (err error) {
+func copySnapData(ctx context.Context, oldSnap, newSnap *snap.Info, opts *dirs.SnapDirOptions) (err error) {
 	oldDataDirs, err := snapDataDirs(oldSnap, opts)
 	if err != nil {
 		return err
@@ -180,7 +181,7 @@ func copySnapData(oldSnap, newSnap *snap.Info, opts *dirs.SnapDirOptions) (err e
 	for _, oldDir := range oldDataDirs {
 		// replace the trailing "../$old-suffix" with the "../$new-suffix"
 		newDir := filepath.Join(filepath.Dir(oldDir), newSuffix)
-		if err := copySnapDataDirectory(oldDir, newDir); err != nil {
+		if err := copySnapDataDirectory(ctx, oldDir, newDir); err != nil {
 			return err
 		}
 		done = append(done, newDir)
@@ -249,14 +250,14 @@ func clearTrash(path string) error {
 }
 
 // Lowlevel copy the snap data (but never override existing data)
-func copySnapDataDirectory(oldPath, newPath string) (err error) {
+func copySnapDataDirectory(ctx context.Context, oldPath, newPath string) (err error) {
 	if _, err := os.Stat(oldPath); err == nil {
 		if err := trash(newPath); err != nil {
 			return err
 		}
 
 		if _, err := os.Stat(newPath); err != nil {
-			if err := osutil.CopyFile(oldPath, newPath, osutil.CopyFlagPreserveAll|osutil.CopyFlagSync); err != nil {
+			if err := osutil.CopyFileWithContext(ctx, oldPath, newPath, osutil.CopyFlagPreserveAll|osutil.CopyFlagSync); err != nil {
 				msg := fmt.Sprintf("cannot copy %q to %q: %v", oldPath, newPath, err)
 				// remove the directory, in case it was a partial success
 				if e := os.RemoveAll(newPath); e != nil && !os.IsNotExist(e) {
@@ -26,6 +26,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strings"
 	"sync"
@@ -167,11 +168,14 @@ type fakeStore struct {
 	fakeCurrentProgress int
 	fakeTotalProgress   int
 	// snap -> error map for simulating download errors
-	downloadError   map[string]error
-	state           *state.State
-	seenPrivacyKeys map[string]bool
+	downloadError map[string]error
+	// snap -> outcome of using a delta to download it
+	downloadDeltaOutcome map[string]store.DeltaOutcome
+	state                *state.State
+	seenPrivacyKeys      map[string]bool
 
 	downloadCallback func()
+	downloadHook     func(ctx context.Context, targetFn string) error
 }
 
 func (f *fakeStore) pokeStateLock() {
@@ -579,7 +583,7 @@ func (f *fakeStore) SnapAction(ctx context.Context, currentSnaps []*store.Curren
 	installErrors := make(map[string]error)
 	var res []store.SnapActionResult
 	for _, a := range sorted {
-		if a.Action != "install" && a.Action != "refresh" {
+		if a.Action != "install" && a.Action != "refresh" && a.Action != "download" {
 			panic("not supported")
 		}
 		if a.InstanceName == "" {
@@ -588,7 +592,7 @@ func (f *fakeStore) SnapAction(ctx context.Context, currentSnaps []*store.Curren
 
 		snapName, instanceKey := snap.SplitInstanceName(a.InstanceName)
 
-		if a.Action == "install" {
+		if a.Action == "install" || a.Action == "download" {
 			spec := snapSpec{
 				Name:     snapName,
 				Channel:  a.Channel,
@@ -702,13 +706,27 @@ func (f *fakeStore) Download(ctx context.Context, name, targetFn string, snapInf
 	if f.downloadCallback != nil {
 		f.downloadCallback()
 	}
+	if f.downloadHook != nil {
+		if err := f.downloadHook(ctx, targetFn); err != nil {
+			return err
+		}
+	}
 
 	var macaroon string
 	if user != nil {
 		macaroon = user.StoreMacaroon
 	}
+	if outcome, ok := f.downloadDeltaOutcome[name]; ok && dlOpts != nil && dlOpts.OnDelta != nil {
+		dlOpts.OnDelta(outcome)
+	}
+	if dlOpts != nil {
+		// the delta callback is not comparable
+		opts := *dlOpts
+		opts.OnDelta = nil
+		dlOpts = &opts
+	}
 	// only add the options if they contain anything interesting
-	if dlOpts != nil && *dlOpts == (store.DownloadOptions{}) {
+	if dlOpts != nil && reflect.DeepEqual(*dlOpts, store.DownloadOptions{}) {
 		dlOpts = nil
 	}
 	f.downloads = append(f.downloads, fakeDownload{
@@ -768,6 +786,8 @@ type fakeSnappyBackend struct {
 	linkSnapRebootFor   map[string]bool
 
 	copySnapDataFailTrigger string
+	copySnapDataWaitCh      chan int
+	copySnapDataWaitTrigger string
 	emptyContainer          snap.Container
 
 	servicesCurrentlyDisabled []string
@@ -998,8 +1018,18 @@ func (f *fakeSnappyBackend) StoreInfo(st *state.State, name, channel string, use
 	})
 }
 
-func (f *fakeSnappyBackend) CopySnapData(newInfo, oldInfo *snap.Info, opts *dirs.SnapDirOptions, p progress.Meter) error {
+func (f *fakeSnappyBackend) CopySnapDataWithContext(ctx context.Context, newInfo, oldInfo *snap.Info, opts *dirs.SnapDirOptions, p progress.Meter) error {
 	p.Notify("copy-data")
+	if newInfo.MountDir() == f.copySnapDataWaitTrigger {
+		f.copySnapDataWaitCh <- 1
+		select {
+		case <-ctx.Done():
+			f.appendOp(&fakeOp{op: "copy-data.cancelled", path: newInfo.MountDir()})
+			return ctx.Err()
+		case <-f.copySnapDataWaitCh:
+		}
+	}
+
 	op := &fakeOp{
 		op:   "copy-data",
 		path: newInfo.MountDir(),
@@ -114,6 +114,7 @@ func (bs *bootedSuite) SetUpTest(c *C) {
 		snapstate.SnapServiceOptions = oldSnapServiceOptions
 	})
 	bs.AddCleanup(osutil.MockMountInfo(""))
+	bs.AddCleanup(snapstate.MockOsutilFileExists(func(string) bool { return true }))
 }
 
 func (bs *bootedSuite) TearDownTest(c *C) {
@@ -37,6 +37,8 @@ import (
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snapdtool"
 	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/timeutil"
 )
 
 // featureSet contains the flag values that can be listed in assumes entries
@@ -576,6 +578,33 @@ func checkConfigureHooks(_ *state.State, snapInfo, curInfo *snap.Info, _ snap.Co
 	return nil
 }
 
+// timerTimezoneSystemdVersion is the first version of systemd accepting a
+// timezone in the calendar events of timers.
+const timerTimezoneSystemdVersion = 235
+
+// checkTimers refuses timers with schedules in a specific timezone when the
+// systemd of the host cannot honor those.
+func checkTimers(_ *state.State, snapInfo, _ *snap.Info, _ snap.Container, _ Flags, _ DeviceContext) error {
+	for _, app := range snapInfo.Apps {
+		if app.Timer == nil {
+			continue
+		}
+		schedule, err := timeutil.ParseSchedule(app.Timer.Timer)
+		if err != nil {
+			return fmt.Errorf("cannot parse timer of app %q: %v", app.Name, err)
+		}
+		for _, sched := range schedule {
+			if sched.Location == nil {
+				continue
+			}
+			if err := systemd.EnsureAtLeast(timerTimezoneSystemdVersion); err != nil {
+				return fmt.Errorf("cannot use timezone %q in timer of app %q: %v", sched.Location, app.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
 func init() {
 	AddCheckSnapCallback(checkCoreName)
 	AddCheckSnapCallback(checkSnapdName)
@@ -583,4 +612,5 @@ func init() {
 	AddCheckSnapCallback(checkBases)
 	AddCheckSnapCallback(checkEpochs)
 	AddCheckSnapCallback(checkConfigureHooks)
+	AddCheckSnapCallback(checkTimers)
 }
@@ -38,6 +38,7 @@ import (
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/snapdtool"
+	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -1524,3 +1525,39 @@ func (s *checkSnapSuite) TestCheckConfigureHooksUnHappy(c *C) {
 	err := snapstate.CheckSnap(s.st, "snap-path", "snap-with-default-configure", nil, nil, snapstate.Flags{}, nil)
 	c.Check(err, ErrorMatches, `cannot specify "default-configure" hook without "configure" hook`)
 }
+
+func (s *checkSnapSuite) testCheckTimers(c *C, timer string, systemdVersion int) error {
+	restore := systemd.MockSystemdVersion(systemdVersion, nil)
+	defer restore()
+
+	var openSnapFile = func(path string, si *snap.SideInfo) (*snap.Info, snap.Container, error) {
+		info := snaptest.MockInfo(c, fmt.Sprintf(`name: snap-with-timer
+version: 1.0
+apps:
+  svc:
+    command: bin/svc
+    daemon: simple
+    timer: %s
+`, timer), si)
+		return info, emptyContainer(c), nil
+	}
+	restore = snapstate.MockOpenSnapFile(openSnapFile)
+	defer restore()
+
+	return snapstate.CheckSnap(s.st, "snap-path", "snap-with-timer", nil, nil, snapstate.Flags{}, nil)
+}
+
+func (s *checkSnapSuite) TestCheckTimersTimezoneHappy(c *C) {
+	err := s.testCheckTimers(c, "mon,10:00 Europe/Berlin", 235)
+	c.Check(err, IsNil)
+}
+
+func (s *checkSnapSuite) TestCheckTimersTimezoneSystemdTooOld(c *C) {
+	err := s.testCheckTimers(c, "mon,10:00 Europe/Berlin", 234)
+	c.Check(err, ErrorMatches, `cannot use timezone "Europe/Berlin" in timer of app "svc": systemd version 234 is too old \(expected at least 235\)`)
+}
+
+func (s *checkSnapSuite) TestCheckTimersNoTimezoneOldSystemd(c *C) {
+	err := s.testCheckTimers(c, "mon,10:00", 229)
+	c.Check(err, IsNil)
+}
@@ -67,6 +67,12 @@ func MockSnapReadInfo(mock func(name string, si *snap.SideInfo) (*snap.Info, err
 	return func() { snapReadInfo = old }
 }
 
+func MockUnhealthyForAutoRefresh(mock func(st *state.State, instanceName string) (string, error)) (restore func()) {
+	old := UnhealthyForAutoRefresh
+	UnhealthyForAutoRefresh = mock
+	return func() { UnhealthyForAutoRefresh = old }
+}
+
 func MockMountPollInterval(intv time.Duration) (restore func()) {
 	old := mountPollInterval
 	mountPollInterval = intv
@@ -100,6 +106,24 @@ func MockOsutilEnsureSnapUserGroup(mock func(name string, id uint32, extraUsers
 	return func() { osutilEnsureSnapUserGroup = old }
 }
 
+func MockOsutilFileExists(mock func(string) bool) (restore func()) {
+	old := osutilFileExists
+	osutilFileExists = mock
+	return func() { osutilFileExists = old }
+}
+
+func MockOsutilDiskFree(mock func(path string) (uint64, error)) (restore func()) {
+	old := osutilDiskFree
+	osutilDiskFree = mock
+	return func() { osutilDiskFree = old }
+}
+
+func MockStorageGCCheckInterval(d time.Duration) (restore func()) {
+	old := storageGCCheckInterval
+	storageGCCheckInterval = d
+	return func() { storageGCCheckInterval = old }
+}
+
 var (
 	CoreInfoInternal       = coreInfo
 	CheckSnap              = checkSnap
@@ -276,6 +300,14 @@ var (
 	MissingDisabledServices = missingDisabledServices
 )
 
+func (m *SnapManager) BlockedTask(cand *state.Task, running []*state.Task) bool {
+	return m.blockedTask(cand, running)
+}
+
+func (m *SnapManager) EnsureMaxParallelDownloads() error {
+	return m.ensureMaxParallelDownloads()
+}
+
 func (m *SnapManager) MaybeUndoRemodelBootChanges(t *state.Task) (restartRequested, rebootRequired bool, err error) {
 	restartPoss, err := m.maybeUndoRemodelBootChanges(t)
 	if restartPoss != nil {
@@ -323,7 +355,7 @@ var (
 // autorefresh
 var (
 	InhibitRefresh = inhibitRefresh
-	MaxInhibition  = maxInhibition
+	MaxInhibition  = defaultMaxInhibition
 	MaxDuration    = maxDuration
 )
 
@@ -258,6 +258,7 @@ func (m *SnapManager) doPrerequisites(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
 
 	// os/base/kernel/gadget cannot have prerequisites other
 	// than the models default base (or core) which is installed anyway
@@ -710,7 +711,7 @@ func downloadSnapParams(st *state.State, t *state.Task) (*SnapSetup, StoreServic
 	return snapsup, sto, user, nil
 }
 
-func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
+func (m *SnapManager) doDownloadSnap(ctx context.Context, t *state.Task) error {
 	st := t.State()
 	var rate int64
 
@@ -725,6 +726,7 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
 
 	if err := waitForPreDownload(t, snapsup); err != nil {
 		return err
@@ -733,9 +735,13 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	meter := NewTaskProgressAdapterUnlocked(t)
 	targetFn := snapsup.MountFile()
 
+	var deltaOutcome *store.DeltaOutcome
 	dlOpts := &store.DownloadOptions{
 		Scheduled: snapsup.IsAutoRefresh,
 		RateLimit: rate,
+		OnDelta: func(outcome store.DeltaOutcome) {
+			deltaOutcome = &outcome
+		},
 	}
 	if snapsup.DownloadInfo == nil {
 		var storeInfo store.SnapActionResult
@@ -747,15 +753,30 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 			return err
 		}
 		timings.Run(perfTimings, "download", fmt.Sprintf("download snap %q", snapsup.SnapName()), func(timings.Measurer) {
-			err = theStore.Download(tomb.Context(nil), snapsup.SnapName(), targetFn, &storeInfo.DownloadInfo, meter, user, dlOpts)
+			err = theStore.Download(ctx, snapsup.SnapName(), targetFn, &storeInfo.DownloadInfo, meter, user, dlOpts)
 		})
 		snapsup.SideInfo = &storeInfo.SideInfo
 	} else {
 		timings.Run(perfTimings, "download", fmt.Sprintf("download snap %q", snapsup.SnapName()), func(timings.Measurer) {
-			err = theStore.Download(tomb.Context(nil), snapsup.SnapName(), targetFn, snapsup.DownloadInfo, meter, user, dlOpts)
+			err = theStore.Download(ctx, snapsup.SnapName(), targetFn, snapsup.DownloadInfo, meter, user, dlOpts)
 		})
 	}
+	if deltaOutcome != nil {
+		st.Lock()
+		recordDeltaOutcome(t, perfTimings, snapsup.InstanceName(), deltaOutcome)
+		st.Unlock()
+	}
 	if err != nil {
+		if ctx.Err() != nil {
+			st.Lock()
+			aborted := t.Status() == state.AbortStatus
+			st.Unlock()
+			if aborted {
+				// the download will not be resumed, do not leave
+				// the partially downloaded files behind
+				removePartialDownloads(targetFn)
+			}
+		}
 		return err
 	}
 
@@ -770,6 +791,41 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	return nil
 }
 
+// removePartialDownloads removes the partially downloaded snap and deltas
+// for the given target file.
+func removePartialDownloads(targetFn string) {
+	partials, err := filepath.Glob(targetFn + "*.partial")
+	if err != nil {
+		logger.Noticef("cannot find partial downloads of %q: %v", targetFn, err)
+		return
+	}
+	for _, p := range partials {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			logger.Noticef("cannot remove partial download %q: %v", p, err)
+		}
+	}
+}
+
+func (m *SnapManager) undoDownloadSnap(_ context.Context, t *state.Task) error {
+	return m.undoPrepareSnap(t, nil)
+}
+
+// recordDeltaOutcome records in the task log and in the timings whether the
+// snap was generated from a delta advertised by the store, and warns when
+// such a delta could not be used.
+//
+// The state must be locked by the caller.
+func recordDeltaOutcome(t *state.Task, perfTimings *timings.Timings, snapName string, outcome *store.DeltaOutcome) {
+	if outcome.Applied {
+		t.Logf("Downloaded snap %q using a %s delta from revision %d", snapName, outcome.Format, outcome.FromRevision)
+		perfTimings.AddTag("delta", "applied")
+		return
+	}
+	t.Logf("Cannot use %s delta from revision %d to download snap %q, downloading it in full: %v", outcome.Format, outcome.FromRevision, snapName, outcome.FallbackReason)
+	perfTimings.AddTag("delta", "fallback")
+	t.State().Warnf("cannot use delta to download snap %q, downloaded it in full instead: %v", snapName, outcome.FallbackReason)
+}
+
 func waitForPreDownload(task *state.Task, snapsup *SnapSetup) error {
 	st := task.State()
 	st.Lock()
@@ -815,18 +871,26 @@ func (m *SnapManager) doPreDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	}
 
 	targetFn := snapsup.MountFile()
+	var deltaOutcome *store.DeltaOutcome
 	dlOpts := &store.DownloadOptions{
 		// pre-downloads are only triggered in auto-refreshes
 		Scheduled: true,
 		RateLimit: autoRefreshRateLimited(st),
+		OnDelta: func(outcome store.DeltaOutcome) {
+			deltaOutcome = &outcome
+		},
 	}
 
 	perfTimings := state.TimingsForTask(t)
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
 	st.Unlock()
 	timings.Run(perfTimings, "pre-download", fmt.Sprintf("pre-download snap %q", snapsup.SnapName()), func(timings.Measurer) {
 		err = theStore.Download(tomb.Context(nil), snapsup.SnapName(), targetFn, snapsup.DownloadInfo, nil, user, dlOpts)
 	})
 	st.Lock()
+	if deltaOutcome != nil {
+		recordDeltaOutcome(t, perfTimings, snapsup.InstanceName(), deltaOutcome)
+	}
 	if err != nil {
 		return err
 	}
@@ -1131,6 +1195,7 @@ func (m *SnapManager) doMountSnap(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
 
 	curInfo, err := snapst.CurrentInfo()
 	if err != nil && err != ErrNoCurrent {
@@ -1445,6 +1510,7 @@ func (m *SnapManager) undoUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
 
 	oldInfo, err := snapst.CurrentInfo()
 	if err != nil {
@@ -1521,14 +1587,21 @@ func (m *SnapManager) undoUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 	return m.finishTaskWithMaybeRestart(t, state.UndoneStatus, restartPossibility{info: oldInfo, RebootInfo: reboot})
 }
 
-func (m *SnapManager) doCopySnapData(t *state.Task, _ *tomb.Tomb) (err error) {
+func (m *SnapManager) doCopySnapData(ctx context.Context, t *state.Task) (err error) {
 	st := t.State()
 	st.Lock()
+	perfTimings := state.TimingsForTask(t)
 	snapsup, snapst, err := snapSetupAndState(t)
 	st.Unlock()
 	if err != nil {
 		return err
 	}
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
+	defer func() {
+		st.Lock()
+		defer st.Unlock()
+		perfTimings.Save(st)
+	}()
 
 	newInfo, err := readInfo(snapsup.InstanceName(), snapsup.SideInfo, errorOnBroken)
 	if err != nil {
@@ -1556,7 +1629,11 @@ func (m *SnapManager) doCopySnapData(t *state.Task, _ *tomb.Tomb) (err error) {
 
 	dirOpts := opts.getSnapDirOpts()
 	pb := NewTaskProgressAdapterUnlocked(t)
-	if copyDataErr := m.backend.CopySnapData(newInfo, oldInfo, dirOpts, pb); copyDataErr != nil {
+	var copyDataErr error
+	timings.Run(perfTimings, "copy-snap-data", fmt.Sprintf("copy data of snap %q", snapsup.InstanceName()), func(timings.Measurer) {
+		copyDataErr = m.backend.CopySnapDataWithContext(ctx, newInfo, oldInfo, dirOpts, pb)
+	})
+	if copyDataErr != nil {
 		if oldInfo != nil {
 			// there is another revision of the snap, cannot remove
 			// shared data directory
@@ -1665,7 +1742,7 @@ func triggeredMigration(oldBase, newBase string, opts *dirMigrationOptions) migr
 	return none
 }
 
-func (m *SnapManager) undoCopySnapData(t *state.Task, _ *tomb.Tomb) error {
+func (m *SnapManager) undoCopySnapData(_ context.Context, t *state.Task) error {
 	st := t.State()
 	st.Lock()
 	snapsup, snapst, err := snapSetupAndState(t)
@@ -1948,6 +2025,7 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 	if err != nil {
 		return err
 	}
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
 
 	deviceCtx, err := DeviceCtx(st, t, nil)
 	if err != nil {
@@ -2168,6 +2246,9 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 
 	// Record the fact that the snap was refreshed successfully.
 	snapst.RefreshInhibitedTime = nil
+	if err := updateRefreshInhibition(st, snapst, snapsup.InstanceName()); err != nil {
+		logger.Noticef("cannot clear refresh inhibition of snap %q: %v", snapsup.InstanceName(), err)
+	}
 	if !snapsup.Revert {
 		now := timeNow()
 		snapst.LastRefreshTime = &now
@@ -2522,6 +2603,7 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
 
 	var oldChannel string
 	err = t.Get("old-channel", &oldChannel)
@@ -2625,6 +2707,9 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	snapst.JailMode = oldJailMode
 	snapst.Classic = oldClassic
 	snapst.RefreshInhibitedTime = oldRefreshInhibitedTime
+	if err := updateRefreshInhibition(st, snapst, snapsup.InstanceName()); err != nil {
+		logger.Noticef("cannot restore refresh inhibition of snap %q: %v", snapsup.InstanceName(), err)
+	}
 	snapst.LastRefreshTime = oldLastRefreshTime
 	snapst.CohortKey = oldCohortKey
 
@@ -2862,6 +2947,7 @@ func (m *SnapManager) startSnapServices(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
 	currentInfo, err := snapst.CurrentInfo()
 	if err != nil {
 		return err
@@ -2942,6 +3028,7 @@ func (m *SnapManager) undoStartSnapServices(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
 
 	currentInfo, err := snapst.CurrentInfo()
 	if err != nil {
@@ -2986,6 +3073,7 @@ func (m *SnapManager) stopSnapServices(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
 
 	currentInfo, err := snapst.CurrentInfo()
 	if err != nil {
@@ -3062,6 +3150,7 @@ func (m *SnapManager) undoStopSnapServices(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
 	currentInfo, err := snapst.CurrentInfo()
 	if err != nil {
 		return err
@@ -3150,6 +3239,7 @@ func (m *SnapManager) undoUnlinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	state.TagTimingsWithSnap(perfTimings, snapsup.InstanceName())
 
 	isInstalled := snapst.IsInstalled()
 	if !isInstalled {
@@ -3377,9 +3467,40 @@ func (m *SnapManager) doDiscardSnap(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 	Set(st, snapsup.InstanceName(), snapst)
+
+	if err := pruneSnapRevisionAssertions(st, snapsup.SideInfo.SnapID); err != nil {
+		logger.Noticef("Cannot prune snap-revision assertions for %q: %v", snapsup.InstanceName(), err)
+	}
 	return nil
 }
 
+// PruneSnapRevisionAssertions is set by assertstate, it removes the
+// snap-revision assertions for the given snap-id that are not for one of
+// the given revisions.
+var PruneSnapRevisionAssertions func(st *state.State, snapID string, keep []snap.Revision) error
+
+// pruneSnapRevisionAssertions removes the snap-revision assertions for the
+// given snap-id that are not for a revision still in the sequence of one
+// of the instances of the snap.
+func pruneSnapRevisionAssertions(st *state.State, snapID string) error {
+	if PruneSnapRevisionAssertions == nil || snapID == "" {
+		return nil
+	}
+	all, err := All(st)
+	if err != nil {
+		return err
+	}
+	var keep []snap.Revision
+	for _, snapst := range all {
+		for _, si := range snapst.Sequence {
+			if si.SnapID == snapID {
+				keep = append(keep, si.Revision)
+			}
+		}
+	}
+	return PruneSnapRevisionAssertions(st, snapID, keep)
+}
+
 /* aliases v2
 
 aliases v2 implementation uses the following tasks:
@@ -4535,3 +4656,38 @@ var getDirMigrationOpts = func(st *state.State, snapst *SnapState, snapsup *Snap
 
 	return opts, nil
 }
+
+func (m *SnapManager) doBeginResealBatch(t *state.Task, _ *tomb.Tomb) error {
+	return boot.BeginResealBatch()
+}
+
+func (m *SnapManager) undoBeginResealBatch(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	// the change is being undone, reseal once to the restored modeenv
+	_, err := boot.FinishResealBatch(boot.Unlocker(st.Unlocker()))
+	return err
+}
+
+func (m *SnapManager) doFinishResealBatch(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	perfTimings := state.TimingsForTask(t)
+	defer perfTimings.Save(st)
+
+	var requests int
+	var err error
+	timings.Run(perfTimings, "reseal", "reseal the encryption keys", func(timings.Measurer) {
+		requests, err = boot.FinishResealBatch(boot.Unlocker(st.Unlocker()))
+	})
+	if err != nil {
+		return err
+	}
+	// the batch may have been finished already before a restart
+	perfTimings.AddTag("coalesced-reseals", strconv.Itoa(requests))
+	return nil
+}
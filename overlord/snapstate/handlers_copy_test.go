@@ -21,10 +21,13 @@ package snapstate_test
 
 import (
 	"errors"
+	"path/filepath"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 )
@@ -81,3 +84,52 @@ func (s *copySnapDataSuite) TestDoCopySnapDataFailedRead(c *C) {
 	c.Check(chg.Status(), Equals, state.ErrorStatus)
 	c.Check(chg.Err(), ErrorMatches, `(?s).*\(some error\)`)
 }
+
+func (s *copySnapDataSuite) TestDoCopySnapDataAbortUndone(c *C) {
+	s.AddCleanup(snapstatetest.UseFallbackDeviceModel())
+
+	s.fakeBackend.copySnapDataWaitCh = make(chan int)
+	s.fakeBackend.copySnapDataWaitTrigger = filepath.Join(dirs.SnapMountDir, "pkg/43")
+
+	s.state.Lock()
+	si := &snap.SideInfo{RealName: "pkg", Revision: snap.R(42)}
+	snapstate.Set(s.state, "pkg", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+		Active:   true,
+	})
+
+	task := s.state.NewTask("copy-snap-data", "test")
+	task.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "pkg",
+			Revision: snap.R(43),
+		},
+	})
+	chg := s.state.NewChange("sample", "...")
+	chg.AddTask(task)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	// the copy is in progress
+	<-s.fakeBackend.copySnapDataWaitCh
+
+	s.state.Lock()
+	chg.Abort()
+	s.state.Unlock()
+
+	for i := 0; i < 3; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(task.Status(), Equals, state.UndoneStatus)
+	c.Check(s.fakeBackend.ops.Ops(), DeepEquals, []string{
+		"copy-data.cancelled",
+		"undo-copy-snap-data",
+		"undo-setup-snap-save-data",
+	})
+}
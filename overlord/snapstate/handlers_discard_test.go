@@ -89,6 +89,56 @@ func (s *discardSnapSuite) TestDoDiscardSnapSuccess(c *C) {
 	c.Check(t.Status(), Equals, state.DoneStatus)
 }
 
+func (s *discardSnapSuite) TestDoDiscardSnapPrunesSnapRevisionAssertions(c *C) {
+	var pruned []string
+	var kept []snap.Revision
+	oldPruneSnapRevisionAssertions := snapstate.PruneSnapRevisionAssertions
+	snapstate.PruneSnapRevisionAssertions = func(st *state.State, snapID string, keep []snap.Revision) error {
+		pruned = append(pruned, snapID)
+		kept = keep
+		return nil
+	}
+	defer func() { snapstate.PruneSnapRevisionAssertions = oldPruneSnapRevisionAssertions }()
+
+	s.state.Lock()
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", SnapID: "foo-id", Revision: snap.R(3)},
+			{RealName: "foo", SnapID: "foo-id", Revision: snap.R(33)},
+		},
+		Current:  snap.R(33),
+		SnapType: "app",
+	})
+	snapstate.Set(s.state, "foo_instance", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", SnapID: "foo-id", Revision: snap.R(4)},
+		},
+		Current:     snap.R(4),
+		SnapType:    "app",
+		InstanceKey: "instance",
+	})
+	t := s.state.NewTask("discard-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "foo-id",
+			Revision: snap.R(33),
+		},
+	})
+	s.state.NewChange("sample", "...").AddTask(t)
+
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(t.Status(), Equals, state.DoneStatus)
+	c.Check(pruned, DeepEquals, []string{"foo-id"})
+	c.Check(kept, testutil.DeepUnsortedMatches, []snap.Revision{snap.R(3), snap.R(4)})
+}
+
 func (s *discardSnapSuite) TestDoDiscardSnapInQuotaGroup(c *C) {
 	s.state.Lock()
 
@@ -20,6 +20,9 @@
 package snapstate_test
 
 import (
+	"context"
+	"errors"
+	"os"
 	"path/filepath"
 
 	. "gopkg.in/check.v1"
@@ -34,6 +37,7 @@ import (
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
 )
 
 type downloadSnapSuite struct {
@@ -175,6 +179,75 @@ func (s *downloadSnapSuite) TestDoDownloadSnapNormal(c *C) {
 	})
 }
 
+func (s *downloadSnapSuite) testDoDownloadSnapDelta(c *C, outcome store.DeltaOutcome) (*state.Task, []*timings.TimingsInfo) {
+	s.fakeStore.downloadDeltaOutcome = map[string]store.DeltaOutcome{"foo": outcome}
+
+	s.state.Lock()
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "mySnapID",
+			Revision: snap.R(11),
+		},
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/snap",
+		},
+	})
+	chg := s.state.NewChange("sample", "...")
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.Err(), IsNil)
+
+	timingsInfo, err := timings.Get(s.state, -1, func(tags map[string]string) bool {
+		return tags["task-id"] == t.ID()
+	})
+	c.Assert(err, IsNil)
+	c.Assert(timingsInfo, HasLen, 1)
+
+	return t, timingsInfo
+}
+
+func (s *downloadSnapSuite) TestDoDownloadSnapDeltaApplied(c *C) {
+	t, timingsInfo := s.testDoDownloadSnapDelta(c, store.DeltaOutcome{
+		Format:       "xdelta3",
+		FromRevision: 10,
+		ToRevision:   11,
+		Applied:      true,
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(t.Log(), HasLen, 1)
+	c.Check(t.Log()[0], Matches, `.* INFO Downloaded snap "foo" using a xdelta3 delta from revision 10`)
+	c.Check(timingsInfo[0].Tags["delta"], Equals, "applied")
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+}
+
+func (s *downloadSnapSuite) TestDoDownloadSnapDeltaFallback(c *C) {
+	t, timingsInfo := s.testDoDownloadSnapDelta(c, store.DeltaOutcome{
+		Format:         "xdelta3",
+		FromRevision:   10,
+		ToRevision:     11,
+		FallbackReason: errors.New("sha3-384 mismatch"),
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(t.Log(), HasLen, 1)
+	c.Check(t.Log()[0], Matches, `.* INFO Cannot use xdelta3 delta from revision 10 to download snap "foo", downloading it in full: sha3-384 mismatch`)
+	c.Check(timingsInfo[0].Tags["delta"], Equals, "fallback")
+	warns := s.state.AllWarnings()
+	c.Assert(warns, HasLen, 1)
+	c.Check(warns[0].String(), Equals, `cannot use delta to download snap "foo", downloaded it in full instead: sha3-384 mismatch`)
+}
+
 func (s *downloadSnapSuite) TestDoDownloadSnapWithDeviceContext(c *C) {
 	s.state.Lock()
 
@@ -259,6 +332,94 @@ func (s *downloadSnapSuite) TestDoUndoDownloadSnap(c *C) {
 
 }
 
+func (s *downloadSnapSuite) TestDoDownloadSnapAbortRemovesPartial(c *C) {
+	started := make(chan struct{})
+	s.fakeStore.downloadHook = func(ctx context.Context, targetFn string) error {
+		c.Assert(os.MkdirAll(filepath.Dir(targetFn), 0755), IsNil)
+		c.Assert(os.WriteFile(targetFn+".partial", []byte("partial"), 0644), IsNil)
+		c.Assert(os.WriteFile(targetFn+".xdelta3-11-to-33.partial", []byte("partial"), 0644), IsNil)
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	s.state.Lock()
+	si := &snap.SideInfo{
+		RealName: "foo",
+		Revision: snap.R(33),
+	}
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: si,
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://something.com/snap",
+		},
+	})
+	chg := s.state.NewChange("sample", "...")
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	<-started
+
+	s.state.Lock()
+	chg.Abort()
+	s.state.Unlock()
+
+	for i := 0; i < 3; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(t.Status(), Equals, state.UndoneStatus)
+
+	// the partial downloads were removed
+	targetFn := filepath.Join(dirs.SnapBlobDir, "foo_33.snap")
+	c.Check(targetFn+".partial", testutil.FileAbsent)
+	c.Check(targetFn+".xdelta3-11-to-33.partial", testutil.FileAbsent)
+}
+
+func (s *downloadSnapSuite) TestDoDownloadSnapStopKeepsPartial(c *C) {
+	started := make(chan struct{})
+	s.fakeStore.downloadHook = func(ctx context.Context, targetFn string) error {
+		c.Assert(os.MkdirAll(filepath.Dir(targetFn), 0755), IsNil)
+		c.Assert(os.WriteFile(targetFn+".partial", []byte("partial"), 0644), IsNil)
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	s.state.Lock()
+	si := &snap.SideInfo{
+		RealName: "foo",
+		Revision: snap.R(33),
+	}
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: si,
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://something.com/snap",
+		},
+	})
+	chg := s.state.NewChange("sample", "...")
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	<-started
+	s.runner.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the download is retried later and can be resumed
+	c.Check(t.Status(), Equals, state.DoingStatus)
+	c.Check(filepath.Join(dirs.SnapBlobDir, "foo_33.snap.partial"), testutil.FilePresent)
+}
+
 func (s *downloadSnapSuite) TestDoDownloadRateLimitedIntegration(c *C) {
 	s.state.Lock()
 
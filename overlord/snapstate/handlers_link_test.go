@@ -34,6 +34,7 @@ import (
 	"github.com/snapcore/snapd/boot/boottest"
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/bootloader/bootloadertest"
+	"github.com/snapcore/snapd/cmd/snaplock/runinhibit"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/configstate/config"
@@ -1783,6 +1784,7 @@ func (s *linkSnapSuite) TestLinkSnapResetsRefreshInhibitedTime(c *C) {
 		Current:              si.Revision,
 		RefreshInhibitedTime: &instant,
 	})
+	c.Assert(runinhibit.SetRefreshInhibition("snap", &runinhibit.RefreshInhibition{FirstInhibited: instant}), IsNil)
 
 	task := s.state.NewTask("link-snap", "")
 	task.Set("snap-setup", sup)
@@ -1805,6 +1807,7 @@ func (s *linkSnapSuite) TestLinkSnapResetsRefreshInhibitedTime(c *C) {
 	err := snapstate.Get(s.state, "snap", &snapst)
 	c.Assert(err, IsNil)
 	c.Check(snapst.RefreshInhibitedTime, IsNil)
+	c.Check(runinhibit.RefreshInhibitionFile("snap"), testutil.FileAbsent)
 
 	var oldTime time.Time
 	c.Assert(task.Get("old-refresh-inhibited-time", &oldTime), IsNil)
@@ -1851,6 +1854,12 @@ func (s *linkSnapSuite) TestDoUndoLinkSnapRestoresRefreshInhibitedTime(c *C) {
 	err := snapstate.Get(s.state, "snap", &snapst)
 	c.Assert(err, IsNil)
 	c.Check(snapst.RefreshInhibitedTime.Equal(instant), Equals, true)
+
+	inhibition, err := runinhibit.ReadRefreshInhibition("snap")
+	c.Assert(err, IsNil)
+	c.Assert(inhibition, NotNil)
+	c.Check(inhibition.FirstInhibited.Equal(instant), Equals, true)
+	c.Check(inhibition.ProceedTime.Equal(instant.Add(snapstate.MaxInhibition)), Equals, true)
 }
 
 func (s *linkSnapSuite) TestLinkSnapSetsLastRefreshTime(c *C) {
@@ -0,0 +1,148 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
+)
+
+type resealBatchSuite struct {
+	baseHandlerSuite
+
+	reseals int
+}
+
+var _ = Suite(&resealBatchSuite{})
+
+func (s *resealBatchSuite) SetUpTest(c *C) {
+	s.baseHandlerSuite.SetUpTest(c)
+
+	s.reseals = 0
+	s.AddCleanup(boot.MockResealKeyToModeenv(func(rootdir string, m *boot.Modeenv, expectReseal bool, u boot.Unlocker) error {
+		s.reseals++
+		c.Check(expectReseal, Equals, true)
+		return nil
+	}))
+	m := &boot.Modeenv{Mode: "run"}
+	c.Assert(m.WriteTo(""), IsNil)
+}
+
+func (s *resealBatchSuite) markerFile() string {
+	return filepath.Join(dirs.SnapFDEDir, "reseal-batch")
+}
+
+// mockRequestedReseals mocks a batch in progress in which reseals were
+// requested.
+func (s *resealBatchSuite) mockRequestedReseals(c *C, requests int) {
+	c.Assert(os.MkdirAll(dirs.SnapFDEDir, 0755), IsNil)
+	data := []byte(fmt.Sprintf(`{"requests":%d,"pending":true,"expect-reseal":true}`, requests))
+	c.Assert(os.WriteFile(s.markerFile(), data, 0600), IsNil)
+}
+
+func (s *resealBatchSuite) settle() {
+	for i := 0; i < 3; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+	}
+}
+
+func (s *resealBatchSuite) TestBeginAndFinishNothingRequested(c *C) {
+	s.state.Lock()
+	begin := s.state.NewTask("begin-reseal-batch", "...")
+	finish := s.state.NewTask("finish-reseal-batch", "...")
+	finish.WaitFor(begin)
+	chg := s.state.NewChange("sample", "...")
+	chg.AddTask(begin)
+	chg.AddTask(finish)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	c.Check(begin.Status(), Equals, state.DoneStatus)
+	c.Check(s.markerFile(), testutil.FilePresent)
+	s.state.Unlock()
+
+	s.settle()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.markerFile(), testutil.FileAbsent)
+	c.Check(s.reseals, Equals, 0)
+}
+
+func (s *resealBatchSuite) TestFinishResealsOnce(c *C) {
+	s.mockRequestedReseals(c, 3)
+
+	s.state.Lock()
+	t := s.state.NewTask("finish-reseal-batch", "...")
+	chg := s.state.NewChange("sample", "...")
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	s.settle()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.Err(), IsNil)
+	c.Check(s.reseals, Equals, 1)
+	c.Check(s.markerFile(), testutil.FileAbsent)
+
+	timingsInfo, err := timings.Get(s.state, -1, func(tags map[string]string) bool {
+		return tags["task-id"] == t.ID()
+	})
+	c.Assert(err, IsNil)
+	c.Assert(timingsInfo, HasLen, 1)
+	c.Check(timingsInfo[0].Tags["coalesced-reseals"], Equals, "3")
+}
+
+func (s *resealBatchSuite) TestUndoBeginReseals(c *C) {
+	s.mockRequestedReseals(c, 2)
+
+	s.state.Lock()
+	begin := s.state.NewTask("begin-reseal-batch", "...")
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(begin)
+	chg := s.state.NewChange("sample", "...")
+	chg.AddTask(begin)
+	chg.AddTask(terr)
+	s.state.Unlock()
+
+	s.settle()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(begin.Status(), Equals, state.UndoneStatus)
+	// sealing is consistent with the restored boot assets
+	c.Check(s.reseals, Equals, 1)
+	c.Check(s.markerFile(), testutil.FileAbsent)
+}
@@ -22,6 +22,7 @@ package snapstate
 import (
 	"errors"
 
+	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/overlord/restart"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
@@ -98,6 +99,22 @@ func setDefaultRestartBoundaries(ts *state.TaskSet) {
 	}
 }
 
+// addResealBatchTasks makes the reseals of the encryption keys requested by
+// the tasks updating the gadget and the kernel be coalesced into a single one,
+// as far as they drop boot assets, kernels or command lines from the boot
+// chains, see boot.BeginResealBatch.
+// The batch is finished, resealing once, when the gadget and kernel updates
+// are done or undone, or before any restart.
+func addResealBatchTasks(st *state.State, gadgetTs, kernelTs *state.TaskSet) {
+	begin := st.NewTask("begin-reseal-batch", i18n.G("Start coalescing reseals of the encryption keys"))
+	gadgetTs.WaitFor(begin)
+	gadgetTs.AddTask(begin)
+
+	finish := st.NewTask("finish-reseal-batch", i18n.G("Reseal the encryption keys"))
+	finish.WaitAll(kernelTs)
+	kernelTs.AddTask(finish)
+}
+
 // deviceModelBootBase returns the base-snap name of the current model. For UC16
 // this will return "core".
 func deviceModelBootBase(st *state.State, providedDeviceCtx DeviceContext) (string, error) {
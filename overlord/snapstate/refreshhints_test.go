@@ -574,3 +574,132 @@ func (s *refreshHintsTestSuite) TestSnapStoreOffline(c *C) {
 
 	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
 }
+
+func (s *refreshHintsTestSuite) TestRefreshCandidatesPreview(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.state.Set("seeded", true)
+	snapstate.Set(s.state, "other-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "other-snap", Revision: snap.R(1), SnapID: "other-snap-id"},
+		},
+		Current:         snap.R(1),
+		SnapType:        "app",
+		TrackingChannel: "latest/edge",
+	})
+	heldSI := &snap.SideInfo{RealName: "held-snap", Revision: snap.R(3), SnapID: "held-snap-id"}
+	snaptest.MockSnap(c, "name: held-snap\nversion: 1\n", heldSI)
+	snapstate.Set(s.state, "held-snap", &snapstate.SnapState{
+		Active:          true,
+		Sequence:        []*snap.SideInfo{heldSI},
+		Current:         snap.R(3),
+		SnapType:        "app",
+		TrackingChannel: "latest/stable",
+	})
+	c.Assert(snapstate.HoldRefreshesBySystem(s.state, snapstate.HoldAutoRefresh, "forever", []string{"held-snap"}), IsNil)
+
+	s.store.refreshedSnaps = []*snap.Info{{
+		Architectures: []string{"all"},
+		SnapType:      snap.TypeApp,
+		SideInfo: snap.SideInfo{
+			RealName: "some-snap",
+			SnapID:   "some-snap-id",
+			Revision: snap.R(6),
+		},
+		DownloadInfo: snap.DownloadInfo{
+			Size: int64(99),
+		},
+	}, {
+		Architectures: []string{"all"},
+		SnapType:      snap.TypeApp,
+		SideInfo: snap.SideInfo{
+			RealName: "other-snap",
+			SnapID:   "other-snap-id",
+			Revision: snap.R(2),
+		},
+		DownloadInfo: snap.DownloadInfo{
+			Size: int64(88),
+		},
+	}, {
+		Architectures: []string{"all"},
+		SnapType:      snap.TypeApp,
+		SideInfo: snap.SideInfo{
+			RealName: "held-snap",
+			SnapID:   "held-snap-id",
+			Revision: snap.R(4),
+		},
+	}}
+
+	candidates, err := snapstate.RefreshCandidatesPreview(auth.EnsureContextTODO(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
+	c.Check(candidates, DeepEquals, []*snapstate.RefreshCandidateInfo{{
+		InstanceName: "other-snap",
+		Current:      snap.R(1),
+		Revision:     snap.R(2),
+		Channel:      "latest/edge",
+		DownloadSize: 88,
+	}, {
+		InstanceName: "some-snap",
+		Current:      snap.R(5),
+		Revision:     snap.R(6),
+		Channel:      "stable",
+		DownloadSize: 99,
+	}})
+
+	// the recorded refresh candidates are left alone
+	var cands map[string]interface{}
+	err = s.state.Get("refresh-candidates", &cands)
+	c.Check(err, testutil.ErrorIs, state.ErrNoState)
+}
+
+func (s *refreshHintsTestSuite) TestRefreshCandidatesPreviewDeferredUnhealthy(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.state.Set("seeded", true)
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "refresh.block-on-unhealthy", true), IsNil)
+	tr.Commit()
+
+	restore := snapstate.MockUnhealthyForAutoRefresh(func(st *state.State, instanceName string) (string, error) {
+		c.Check(instanceName, Equals, "some-snap")
+		return `snap health is "blocked"`, nil
+	})
+	defer restore()
+
+	s.store.refreshedSnaps = []*snap.Info{{
+		Architectures: []string{"all"},
+		SnapType:      snap.TypeApp,
+		SideInfo: snap.SideInfo{
+			RealName: "some-snap",
+			SnapID:   "some-snap-id",
+			Revision: snap.R(6),
+		},
+		DownloadInfo: snap.DownloadInfo{
+			Size: int64(99),
+		},
+	}}
+
+	candidates, err := snapstate.RefreshCandidatesPreview(auth.EnsureContextTODO(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(candidates, DeepEquals, []*snapstate.RefreshCandidateInfo{{
+		InstanceName: "some-snap",
+		Current:      snap.R(5),
+		Revision:     snap.R(6),
+		Channel:      "stable",
+		DownloadSize: 99,
+		Deferred:     `snap health is "blocked"`,
+	}})
+}
+
+func (s *refreshHintsTestSuite) TestRefreshCandidatesPreviewNotSeeded(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := snapstate.RefreshCandidatesPreview(auth.EnsureContextTODO(), s.state)
+	c.Check(err, ErrorMatches, "too early for operation, device not yet seeded or device model not acknowledged")
+	c.Check(s.store.ops, HasLen, 0)
+}
@@ -0,0 +1,184 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+)
+
+// FetchSnapFileAssertions is set by assertstate, it fetches and
+// cross-checks the assertions supporting the given snap file and returns
+// them, prerequisites first.
+var FetchSnapFileAssertions func(st *state.State, snapPath string, info *snap.Info, userID int, deviceCtx DeviceContext) ([]asserts.Assertion, error)
+
+// DownloadSnapToDirOptions carries options for DownloadSnapToDir.
+type DownloadSnapToDirOptions struct {
+	// TargetDir is the directory the snap and assertion files are
+	// written to, it must be set.
+	TargetDir string
+	// Basename is used for the snap and assertion files, it defaults
+	// to <snap>_<revision>.
+	Basename string
+
+	Channel   string
+	Revision  snap.Revision
+	CohortKey string
+}
+
+var (
+	errDownloadRevisionAndCohort = errors.New("cannot specify both revision and cohort")
+	errDownloadPathInBasename    = errors.New("cannot specify a path in basename (use target dir for that)")
+)
+
+func (opts *DownloadSnapToDirOptions) validate() error {
+	if opts.TargetDir == "" {
+		return errors.New("internal error: target directory must be specified")
+	}
+	if strings.ContainsRune(opts.Basename, filepath.Separator) {
+		return errDownloadPathInBasename
+	}
+	if !(opts.Revision.Unset() || opts.CohortKey == "") {
+		return errDownloadRevisionAndCohort
+	}
+	return nil
+}
+
+// DownloadedSnap describes a snap downloaded with DownloadSnapToDir.
+type DownloadedSnap struct {
+	SnapPath   string
+	AssertPath string
+	Info       *snap.Info
+}
+
+// DownloadSnapToDir downloads the given snap and its supporting assertions
+// into opts.TargetDir, as <basename>.snap and <basename>.assert. A partial
+// download left behind by an earlier attempt is resumed and a complete snap
+// file with the expected hash is not downloaded again. Both files only
+// appear under their final name once complete.
+// Note that the state must be locked by the caller, it is released while
+// talking to the store.
+func DownloadSnapToDir(ctx context.Context, st *state.State, name string, opts *DownloadSnapToDirOptions, userID int) (*DownloadedSnap, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	deviceCtx, err := DeviceCtxFromState(st, nil)
+	if err != nil {
+		return nil, err
+	}
+	user, err := userFromUserID(st, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := opts.Channel
+	if !opts.Revision.Unset() {
+		channel = ""
+	}
+	action := &store.SnapAction{
+		Action:       "download",
+		InstanceName: name,
+		Revision:     opts.Revision,
+		CohortKey:    opts.CohortKey,
+		Channel:      channel,
+	}
+
+	theStore := Store(st, deviceCtx)
+	st.Unlock() // calls to the store should be done without holding the state lock
+	res, _, err := theStore.SnapAction(ctx, nil, []*store.SnapAction{action}, nil, user, nil)
+	st.Lock()
+	if err != nil {
+		return nil, err
+	}
+	if len(res) != 1 {
+		return nil, fmt.Errorf("internal error: unexpected number %v of results for a single download", len(res))
+	}
+	info := res[0].Info
+
+	baseName := opts.Basename
+	if baseName == "" {
+		baseName = strings.TrimSuffix(info.Filename(), ".snap")
+	}
+	snapPath := filepath.Join(opts.TargetDir, baseName+".snap")
+	assertPath := filepath.Join(opts.TargetDir, baseName+".assert")
+
+	if !hasExpectedSnapFile(snapPath, &info.DownloadInfo) {
+		// if something goes wrong leave the partial download to be
+		// resumed on the next attempt
+		dlOpts := &store.DownloadOptions{LeavePartialOnError: true}
+		st.Unlock()
+		err := theStore.Download(ctx, info.SnapName(), snapPath, &info.DownloadInfo, nil, user, dlOpts)
+		st.Lock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if FetchSnapFileAssertions == nil {
+		return nil, errors.New("internal error: cannot fetch snap assertions")
+	}
+	as, err := FetchSnapFileAssertions(st, snapPath, info, userID, deviceCtx)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := asserts.NewEncoder(&buf)
+	for _, a := range as {
+		if err := enc.Encode(a); err != nil {
+			return nil, err
+		}
+	}
+	if err := osutil.AtomicWriteFile(assertPath, buf.Bytes(), 0644, 0); err != nil {
+		return nil, fmt.Errorf("cannot write assertions file: %v", err)
+	}
+
+	return &DownloadedSnap{
+		SnapPath:   snapPath,
+		AssertPath: assertPath,
+		Info:       info,
+	}, nil
+}
+
+// hasExpectedSnapFile returns whether path holds a snap file with the size
+// and hash in downloadInfo.
+func hasExpectedSnapFile(path string, downloadInfo *snap.DownloadInfo) bool {
+	if !osutil.FileExists(path) {
+		return false
+	}
+	sha3_384Dgst, size, err := osutil.FileDigest(path, crypto.SHA3_384)
+	if err == nil && size == uint64(downloadInfo.Size) && fmt.Sprintf("%x", sha3_384Dgst) == downloadInfo.Sha3_384 {
+		logger.Debugf("not downloading, using existing file %s", path)
+		return true
+	}
+	return false
+}
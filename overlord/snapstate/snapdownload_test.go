@@ -0,0 +1,128 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *snapmgrTestSuite) mockFetchSnapFileAssertions(c *C, as []asserts.Assertion) (calls *int) {
+	calls = new(int)
+	old := snapstate.FetchSnapFileAssertions
+	snapstate.FetchSnapFileAssertions = func(st *state.State, snapPath string, info *snap.Info, userID int, deviceCtx snapstate.DeviceContext) ([]asserts.Assertion, error) {
+		*calls++
+		c.Check(info.SnapName(), Equals, "some-snap")
+		c.Check(filepath.Base(snapPath), Matches, `.*\.snap`)
+		return as, nil
+	}
+	s.AddCleanup(func() { snapstate.FetchSnapFileAssertions = old })
+	return calls
+}
+
+func (s *snapmgrTestSuite) TestDownloadSnapToDir(c *C) {
+	storeStack := assertstest.NewStoreStack("can0nical", nil)
+	as := []asserts.Assertion{storeStack.TrustedKey, storeStack.StoreAccountKey("")}
+	calls := s.mockFetchSnapFileAssertions(c, as)
+
+	targetDir := c.MkDir()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	dl, err := snapstate.DownloadSnapToDir(context.Background(), s.state, "some-snap", &snapstate.DownloadSnapToDirOptions{
+		TargetDir: targetDir,
+		Channel:   "channel-for-7",
+	}, 0)
+	c.Assert(err, IsNil)
+	c.Check(*calls, Equals, 1)
+	c.Check(dl.Info.Revision, Equals, snap.R(7))
+	c.Check(dl.SnapPath, Equals, filepath.Join(targetDir, "some-snap_7.snap"))
+	c.Check(dl.AssertPath, Equals, filepath.Join(targetDir, "some-snap_7.assert"))
+
+	c.Check(s.fakeStore.downloads, DeepEquals, []fakeDownload{{
+		name:   "some-snap",
+		target: dl.SnapPath,
+		// partial downloads are kept around to be resumed
+		opts: &store.DownloadOptions{LeavePartialOnError: true},
+	}})
+
+	f, err := os.Open(dl.AssertPath)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	dec := asserts.NewDecoder(f)
+	for _, expected := range as {
+		a, err := dec.Decode()
+		c.Assert(err, IsNil)
+		c.Check(a.Ref(), DeepEquals, expected.Ref())
+	}
+}
+
+func (s *snapmgrTestSuite) TestDownloadSnapToDirBasename(c *C) {
+	s.mockFetchSnapFileAssertions(c, nil)
+
+	targetDir := c.MkDir()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	dl, err := snapstate.DownloadSnapToDir(context.Background(), s.state, "some-snap", &snapstate.DownloadSnapToDirOptions{
+		TargetDir: targetDir,
+		Basename:  "foo",
+		Revision:  snap.R(3),
+	}, 0)
+	c.Assert(err, IsNil)
+	c.Check(dl.Info.Revision, Equals, snap.R(3))
+	c.Check(dl.SnapPath, Equals, filepath.Join(targetDir, "foo.snap"))
+	c.Check(dl.AssertPath, Equals, filepath.Join(targetDir, "foo.assert"))
+	c.Check(dl.AssertPath, testutil.FileEquals, "")
+}
+
+func (s *snapmgrTestSuite) TestDownloadSnapToDirErrors(c *C) {
+	calls := s.mockFetchSnapFileAssertions(c, nil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, tc := range []struct {
+		opts snapstate.DownloadSnapToDirOptions
+		err  string
+	}{
+		{snapstate.DownloadSnapToDirOptions{}, `internal error: target directory must be specified`},
+		{snapstate.DownloadSnapToDirOptions{TargetDir: "/tmp", Basename: "a/b"}, `cannot specify a path in basename \(use target dir for that\)`},
+		{snapstate.DownloadSnapToDirOptions{TargetDir: "/tmp", Revision: snap.R(1), CohortKey: "cohort"}, `cannot specify both revision and cohort`},
+	} {
+		_, err := snapstate.DownloadSnapToDir(context.Background(), s.state, "some-snap", &tc.opts, 0)
+		c.Check(err, ErrorMatches, tc.err)
+	}
+	c.Check(s.fakeStore.downloads, HasLen, 0)
+	c.Check(*calls, Equals, 0)
+}
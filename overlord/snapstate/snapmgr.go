@@ -35,6 +35,7 @@ import (
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/snapstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/randutil"
@@ -65,6 +66,12 @@ type SnapManager struct {
 	preseed bool
 
 	ensuredMountsUpdated bool
+
+	lastStorageGCCheck time.Time
+
+	// maxParallelDownloads is the refresh.max-parallel-downloads value
+	// read at the last Ensure, zero if it was not read yet
+	maxParallelDownloads int
 }
 
 // SnapSetup holds the necessary snap details to perform most snap manager tasks.
@@ -509,10 +516,10 @@ func Manager(st *state.State, runner *state.TaskRunner) (*SnapManager, error) {
 	// remove anything that is not referenced anymore
 	runner.AddHandler("prerequisites", m.doPrerequisites, nil)
 	runner.AddHandler("prepare-snap", m.doPrepareSnap, m.undoPrepareSnap)
-	runner.AddHandler("download-snap", m.doDownloadSnap, m.undoPrepareSnap)
+	runner.AddHandlerWithContext("download-snap", m.doDownloadSnap, m.undoDownloadSnap)
 	runner.AddHandler("mount-snap", m.doMountSnap, m.undoMountSnap)
 	runner.AddHandler("unlink-current-snap", m.doUnlinkCurrentSnap, m.undoUnlinkCurrentSnap)
-	runner.AddHandler("copy-snap-data", m.doCopySnapData, m.undoCopySnapData)
+	runner.AddHandlerWithContext("copy-snap-data", m.doCopySnapData, m.undoCopySnapData)
 	runner.AddCleanup("copy-snap-data", m.cleanupCopySnapData)
 	runner.AddHandler("link-snap", m.doLinkSnap, m.undoLinkSnap)
 	runner.AddHandler("start-snap-services", m.startSnapServices, m.undoStartSnapServices)
@@ -520,6 +527,8 @@ func Manager(st *state.State, runner *state.TaskRunner) (*SnapManager, error) {
 	runner.AddHandler("toggle-snap-flags", m.doToggleSnapFlags, nil)
 	runner.AddHandler("check-rerefresh", m.doCheckReRefresh, nil)
 	runner.AddHandler("conditional-auto-refresh", m.doConditionalAutoRefresh, nil)
+	runner.AddHandler("begin-reseal-batch", m.doBeginResealBatch, m.undoBeginResealBatch)
+	runner.AddHandler("finish-reseal-batch", m.doFinishResealBatch, nil)
 
 	// FIXME: drop the task entirely after a while
 	// (having this wart here avoids yet-another-patch)
@@ -610,9 +619,61 @@ func (m *SnapManager) blockedTask(cand *state.Task, running []*state.Task) bool
 		}
 	}
 
+	// Limit the number of snaps downloaded at the same time, the tasks
+	// following the downloads are still ordered through their waits.
+	if cand.Kind() == "download-snap" && cand.Status() == state.DoStatus {
+		downloading := 0
+		for _, t := range running {
+			if t.Kind() == "download-snap" && t.Status() == state.DoingStatus {
+				downloading++
+			}
+		}
+		if downloading >= m.maxDownloads() {
+			return true
+		}
+	}
+
 	return false
 }
 
+// defaultMaxParallelDownloads is the number of snaps downloaded at the
+// same time unless refresh.max-parallel-downloads is set.
+const defaultMaxParallelDownloads = 2
+
+// maxDownloads returns the maximum number of snaps downloaded at the same
+// time, as read from the configuration at the last Ensure.
+func (m *SnapManager) maxDownloads() int {
+	if m.maxParallelDownloads <= 0 {
+		return defaultMaxParallelDownloads
+	}
+	return m.maxParallelDownloads
+}
+
+// ensureMaxParallelDownloads reads the refresh.max-parallel-downloads value
+// once per Ensure, instead of for every download task considered by the task
+// runner.
+func (m *SnapManager) ensureMaxParallelDownloads() error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	m.maxParallelDownloads = maxParallelDownloads(m.state)
+	return nil
+}
+
+// maxParallelDownloads returns the refresh.max-parallel-downloads value if
+// set, or the default value.
+func maxParallelDownloads(st *state.State) int {
+	var maxDownloads int
+	err := config.NewTransaction(st).Get("core", "refresh.max-parallel-downloads", &maxDownloads)
+	if err != nil && !config.IsNoOption(err) {
+		logger.Noticef("internal error: refresh.max-parallel-downloads system option is not valid: %v", err)
+	}
+	if maxDownloads <= 0 {
+		maxDownloads = defaultMaxParallelDownloads
+	}
+	return maxDownloads
+}
+
 // NextRefresh returns the time the next update of the system's snaps
 // will be attempted.
 // The caller should be holding the state lock.
@@ -1153,6 +1214,8 @@ func (m *SnapManager) Ensure() error {
 		m.localInstallCleanup(),
 		m.ensureVulnerableSnapConfineVersionsRemovedOnClassic(),
 		m.ensureMountsUpdated(),
+		m.ensureStorageGC(),
+		m.ensureMaxParallelDownloads(),
 	}
 
 	//FIXME: use firstErr helper
@@ -86,7 +86,10 @@ const (
 
 var ErrNothingToDo = errors.New("nothing to do")
 
-var osutilCheckFreeSpace = osutil.CheckFreeSpace
+var (
+	osutilCheckFreeSpace = osutil.CheckFreeSpace
+	osutilFileExists     = osutil.FileExists
+)
 
 // TestingLeaveOutKernelUpdateGadgetAssets can be used to simulate an upgrade
 // from a broken snapd that does not generate a "update-gadget-assets" task.
@@ -1380,6 +1383,8 @@ func validatedInfoFromPathAndSideInfo(snapName, path string, si *snap.SideInfo)
 // The provided SideInfos can contain just a name which results in a
 // local revision and sideloading, or full metadata in which case
 // the snaps will appear as installed from the store.
+// Bases and default providers that are part of the provided snaps are
+// installed before the snaps that need them.
 func InstallPathMany(ctx context.Context, st *state.State, sideInfos []*snap.SideInfo, paths []string, userID int, flags *Flags) ([]*state.TaskSet, error) {
 	if flags == nil {
 		flags = &Flags{}
@@ -1392,6 +1397,7 @@ func InstallPathMany(ctx context.Context, st *state.State, sideInfos []*snap.Sid
 
 	var updates []minimalInstallInfo
 	var names []string
+	var infos []*snap.Info
 	stateByInstanceName := make(map[string]*SnapState, len(sideInfos))
 	flagsByInstanceName := make(map[string]Flags, len(sideInfos))
 
@@ -1419,6 +1425,7 @@ func InstallPathMany(ctx context.Context, st *state.State, sideInfos []*snap.Sid
 
 		updates = append(updates, pathInfo{Info: info, path: paths[i], sideInfo: si})
 		names = append(names, name)
+		infos = append(infos, info)
 		stateByInstanceName[name] = &snapst
 		flagsByInstanceName[name] = flags
 	}
@@ -1432,14 +1439,85 @@ func InstallPathMany(ctx context.Context, st *state.State, sideInfos []*snap.Sid
 		return nil, flagsByInstanceName[name], stateByInstanceName[name]
 	}
 
+	// bases are installed first by doUpdate already
 	_, updateTss, err := doUpdate(ctx, st, names, updates, params, userID, flags, deviceCtx, "")
 	if err != nil {
 		return nil, err
 	}
 
+	if err := waitForLocalDefaultProviders(updateTss.Refresh, infos); err != nil {
+		return nil, err
+	}
+
 	return updateTss.Refresh, nil
 }
 
+// waitForLocalDefaultProviders makes the tasks of the snaps installed together
+// from local files wait for the tasks of their default providers, when these
+// are installed as part of the same set. Otherwise the default providers would
+// only be found in flight when checking the prerequisites, and the snaps would
+// not be ordered. Circular dependencies between the snaps are broken
+// arbitrarily.
+func waitForLocalDefaultProviders(tss []*state.TaskSet, infos []*snap.Info) error {
+	tsByName := make(map[string]*state.TaskSet, len(tss))
+	for _, ts := range tss {
+		begin := ts.MaybeEdge(BeginEdge)
+		if begin == nil {
+			// not installing a snap, e.g. pruning aliases
+			continue
+		}
+		snapsup, err := TaskSnapSetup(begin)
+		if err != nil {
+			return err
+		}
+		tsByName[snapsup.InstanceName()] = ts
+	}
+
+	providersByName := make(map[string][]string, len(infos))
+	for _, info := range infos {
+		var providers []string
+		for provider := range snap.NeededDefaultProviders(info) {
+			if provider != info.InstanceName() && tsByName[provider] != nil {
+				providers = append(providers, provider)
+			}
+		}
+		sort.Strings(providers)
+		providersByName[info.InstanceName()] = providers
+	}
+
+	// order the snaps so that providers come before the snaps using them
+	position := make(map[string]int, len(infos))
+	visited := make(map[string]bool, len(infos))
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		for _, provider := range providersByName[name] {
+			visit(provider)
+		}
+		position[name] = len(position)
+	}
+	for _, info := range infos {
+		visit(info.InstanceName())
+	}
+
+	for _, info := range infos {
+		name := info.InstanceName()
+		ts := tsByName[name]
+		if ts == nil {
+			continue
+		}
+		for _, provider := range providersByName[name] {
+			if position[provider] < position[name] {
+				ts.WaitAll(tsByName[provider])
+			}
+		}
+	}
+	return nil
+}
+
 // InstallMany installs everything from the given list of names. When specifying
 // revisions, the checks against enforced validation sets are bypassed.
 // Note that the state must be locked by the caller.
@@ -1844,7 +1922,8 @@ func updateManyFiltered(ctx context.Context, st *state.State, names []string, re
 	return updated, updateTss, nil
 }
 
-// filterHeldSnaps filters held snaps from being updated in a general refresh.
+// filterHeldSnaps filters held snaps from being updated in a general refresh,
+// and unhealthy snaps from being updated in an auto-refresh.
 func filterHeldSnaps(st *state.State, updates []minimalInstallInfo, flags *Flags) ([]minimalInstallInfo, error) {
 	holdLevel := HoldGeneral
 	if flags.IsAutoRefresh {
@@ -1855,11 +1934,29 @@ func filterHeldSnaps(st *state.State, updates []minimalInstallInfo, flags *Flags
 		return nil, err
 	}
 
+	var unhealthy map[string]string
+	if flags.IsAutoRefresh {
+		names := make([]string, 0, len(updates))
+		for _, update := range updates {
+			names = append(names, update.InstanceName())
+		}
+		unhealthy, err = autoRefreshDeferredByHealth(st, names)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	filteredUpdates := make([]minimalInstallInfo, 0, len(updates))
 	for _, update := range updates {
-		if _, ok := heldSnaps[update.InstanceName()]; !ok {
-			filteredUpdates = append(filteredUpdates, update)
+		name := update.InstanceName()
+		if _, ok := heldSnaps[name]; ok {
+			continue
+		}
+		if reason, ok := unhealthy[name]; ok {
+			logger.Noticef("skipping auto-refresh of snap %q: %s", name, reason)
+			continue
 		}
+		filteredUpdates = append(filteredUpdates, update)
 	}
 
 	return filteredUpdates, nil
@@ -1924,10 +2021,26 @@ func doUpdate(ctx context.Context, st *state.State, names []string, updates []mi
 	// first snapd, core, kernel, bases, then rest
 	sort.Stable(byType(updates))
 	prereqs := make(map[string]*state.TaskSet)
+	refreshedPrereqs := make(map[string]bool)
 	waitPrereq := func(ts *state.TaskSet, prereqName string) {
 		preTs := prereqs[prereqName]
-		if preTs != nil {
+		if preTs == nil {
+			return
+		}
+		if !refreshedPrereqs[prereqName] {
 			ts.WaitAll(preTs)
+			return
+		}
+		// the prerequisite is installed already, checking the
+		// prerequisites and downloading do not modify the system so
+		// they do not need to wait and downloads can run in parallel,
+		// their number is limited by the snap manager
+		for _, t := range ts.Tasks() {
+			switch t.Kind() {
+			case "prerequisites", "download-snap":
+				continue
+			}
+			t.WaitAll(preTs)
 		}
 	}
 	var kernelTs, gadgetTs, bootBaseTs *state.TaskSet
@@ -1994,6 +2107,7 @@ func doUpdate(ctx context.Context, st *state.State, names []string, updates []mi
 			// also assume bases don't have hooks, otherwise
 			// they would need to wait on core or snapd
 			prereqs[update.InstanceName()] = ts
+			refreshedPrereqs[update.InstanceName()] = snapst.IsInstalled()
 			if typ == snap.TypeBase {
 				waitPrereq(ts, "snapd")
 			}
@@ -2046,6 +2160,9 @@ func doUpdate(ctx context.Context, st *state.State, names []string, updates []mi
 	// kernel aborts the wait tasks (the gadget) is put on "Hold".
 	if kernelTs != nil && gadgetTs != nil {
 		kernelTs.WaitAll(gadgetTs)
+		if deviceCtx.HasModeenv() {
+			addResealBatchTasks(st, gadgetTs, kernelTs)
+		}
 	}
 
 	// Make sure each of them are marked with default restart-boundaries to maintain the previous
@@ -3647,6 +3764,29 @@ func validateSnapNames(names []string) error {
 	return nil
 }
 
+// UnknownRevisionError is returned when reverting to a revision which is not
+// part of the sequence of the snap.
+type UnknownRevisionError struct {
+	Snap     string
+	Revision snap.Revision
+}
+
+func (e *UnknownRevisionError) Error() string {
+	return fmt.Sprintf("cannot find revision %s for snap %q", e.Revision, e.Snap)
+}
+
+// RevisionNotRetainedError is returned when reverting to a revision which is
+// part of the sequence of the snap but whose snap file is not present locally
+// anymore.
+type RevisionNotRetainedError struct {
+	Snap     string
+	Revision snap.Revision
+}
+
+func (e *RevisionNotRetainedError) Error() string {
+	return fmt.Sprintf("cannot revert snap %q to revision %s: revision is not retained locally", e.Snap, e.Revision)
+}
+
 // Revert returns a set of tasks for reverting to the previous version of the snap.
 // Note that the state must be locked by the caller.
 func Revert(st *state.State, name string, flags Flags, fromChange string) (*state.TaskSet, error) {
@@ -3664,6 +3804,9 @@ func Revert(st *state.State, name string, flags Flags, fromChange string) (*stat
 	return RevertToRevision(st, name, pi.Revision, flags, fromChange)
 }
 
+// RevertToRevision returns a set of tasks for reverting to the given
+// revision of the snap, which must still be retained locally.
+// Note that the state must be locked by the caller.
 func RevertToRevision(st *state.State, name string, rev snap.Revision, flags Flags, fromChange string) (*state.TaskSet, error) {
 	var snapst SnapState
 	err := Get(st, name, &snapst)
@@ -3680,7 +3823,10 @@ func RevertToRevision(st *state.State, name string, rev snap.Revision, flags Fla
 	}
 	i := snapst.LastIndex(rev)
 	if i < 0 {
-		return nil, fmt.Errorf("cannot find revision %s for snap %q", rev, name)
+		return nil, &UnknownRevisionError{Snap: name, Revision: rev}
+	}
+	if !osutilFileExists(snap.MountFile(name, rev)) {
+		return nil, &RevisionNotRetainedError{Snap: name, Revision: rev}
 	}
 
 	flags.Revert = true
@@ -3702,6 +3848,12 @@ func RevertToRevision(st *state.State, name string, rev snap.Revision, flags Fla
 	if err != nil {
 		return nil, err
 	}
+	// the data of the current revision is left in place, but the
+	// common data might have been migrated to an epoch the reverted
+	// revision cannot read
+	if err := earlyRevertEpochCheck(info, &snapst); err != nil {
+		return nil, err
+	}
 
 	snapsup := &SnapSetup{
 		Base:        info.Base,
@@ -3715,6 +3867,19 @@ func RevertToRevision(st *state.State, name string, rev snap.Revision, flags Fla
 	return doInstall(st, &snapst, snapsup, 0, fromChange, nil)
 }
 
+// earlyRevertEpochCheck checks that the revision to revert to (info) can
+// read the epoch of the current revision of the snap.
+func earlyRevertEpochCheck(info *snap.Info, snapst *SnapState) error {
+	cur, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+	if info.Epoch.CanRead(cur.Epoch) {
+		return nil
+	}
+	return fmt.Errorf("cannot revert %q to revision %s with epoch %s, because it can't read the current epoch of %s", info.InstanceName(), info.Revision, info.Epoch, cur.Epoch)
+}
+
 // TransitionCore transitions from an old core snap name to a new core
 // snap name. It is used for the ubuntu-core -> core transition (that
 // is not just a rename because the two snaps have different snapIDs)
@@ -5644,6 +5644,232 @@ type: base
 	c.Assert(op, IsNil)
 }
 
+func (s *snapmgrTestSuite) installPathManyLocalSnaps(c *C, yamls []string, flags *snapstate.Flags) (names []string, tss []*state.TaskSet) {
+	var paths []string
+	var sideInfos []*snap.SideInfo
+	for _, yaml := range yamls {
+		info, err := snap.InfoFromSnapYaml([]byte(yaml))
+		c.Assert(err, IsNil)
+		names = append(names, info.SnapName())
+		paths = append(paths, makeTestSnap(c, yaml))
+		// unasserted, as with --dangerous
+		sideInfos = append(sideInfos, &snap.SideInfo{RealName: info.SnapName()})
+	}
+
+	tss, err := snapstate.InstallPathMany(context.Background(), s.state, sideInfos, paths, 0, flags)
+	c.Assert(err, IsNil)
+	c.Assert(tss, HasLen, len(yamls))
+	return names, tss
+}
+
+func linkSnapOpIndex(c *C, ops fakeOps, name string) int {
+	for i, op := range ops {
+		if op.op == "link-snap" && op.path == filepath.Join(dirs.SnapMountDir, name, "x1") {
+			return i
+		}
+	}
+	c.Fatalf("cannot find link-snap op for %q", name)
+	return -1
+}
+
+func (s *snapmgrTestSuite) TestInstallPathManyWithLocalBase(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the app comes first and its base is only available as one of the
+	// local files
+	_, tss := s.installPathManyLocalSnaps(c, []string{`name: some-snap
+version: 1.0
+base: local-base
+`, `name: local-base
+version: 1.0
+type: base
+`}, nil)
+
+	// the base is ordered first and the app waits for it
+	c.Check(tss[0].Tasks()[0].Kind(), Equals, "prerequisites")
+	sup, err := snapstate.TaskSnapSetup(tss[0].Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(sup.InstanceName(), Equals, "local-base")
+	for _, t := range tss[1].Tasks() {
+		for _, baseTask := range tss[0].Tasks() {
+			c.Check(t.WaitTasks(), testutil.Contains, baseTask)
+		}
+	}
+
+	chg := s.state.NewChange("install", "install local snaps")
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+
+	defer s.se.Stop()
+	s.settle(c)
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(chg.IsReady(), Equals, true)
+
+	// nothing was installed from the store
+	c.Check(s.fakeBackend.ops.First("storesvc-snap-action"), IsNil)
+	c.Check(linkSnapOpIndex(c, s.fakeBackend.ops, "local-base") < linkSnapOpIndex(c, s.fakeBackend.ops, "some-snap"), Equals, true)
+
+	for _, name := range []string{"some-snap", "local-base"} {
+		var snapst snapstate.SnapState
+		c.Assert(snapstate.Get(s.state, name, &snapst), IsNil)
+		c.Check(snapst.Current, Equals, snap.R(-1))
+	}
+}
+
+func (s *snapmgrTestSuite) TestInstallPathManyWithLocalDefaultProvider(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the consumer comes first, the default provider is one of the other
+	// local files
+	_, tss := s.installPathManyLocalSnaps(c, []string{`name: some-snap
+version: 1.0
+plugs:
+  myplug:
+    interface: content
+    content: mycontent
+    default-provider: prereq-snap
+`, `name: prereq-snap
+version: 1.0
+slots:
+  myslot:
+    interface: content
+    content: mycontent
+`}, nil)
+
+	// the consumer waits for the provider, but not the other way around
+	for _, t := range tss[0].Tasks() {
+		for _, providerTask := range tss[1].Tasks() {
+			c.Check(t.WaitTasks(), testutil.Contains, providerTask)
+		}
+	}
+	for _, t := range tss[1].Tasks() {
+		for _, consumerTask := range tss[0].Tasks() {
+			c.Check(t.WaitTasks(), Not(testutil.Contains), consumerTask)
+		}
+	}
+
+	chg := s.state.NewChange("install", "install local snaps")
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+
+	defer s.se.Stop()
+	s.settle(c)
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(chg.IsReady(), Equals, true)
+
+	c.Check(s.fakeBackend.ops.First("storesvc-snap-action"), IsNil)
+	c.Check(linkSnapOpIndex(c, s.fakeBackend.ops, "prereq-snap") < linkSnapOpIndex(c, s.fakeBackend.ops, "some-snap"), Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestInstallPathManyWithCircularLocalDefaultProviders(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, tss := s.installPathManyLocalSnaps(c, []string{`name: some-snap
+version: 1.0
+plugs:
+  myplug:
+    interface: content
+    content: other-content
+    default-provider: other-snap
+slots:
+  myslot:
+    interface: content
+    content: some-content
+`, `name: other-snap
+version: 1.0
+plugs:
+  myplug:
+    interface: content
+    content: some-content
+    default-provider: some-snap
+slots:
+  myslot:
+    interface: content
+    content: other-content
+`}, nil)
+
+	// the cycle is broken, only some-snap waits for other-snap
+	for _, t := range tss[0].Tasks() {
+		c.Check(t.WaitTasks(), testutil.Contains, tss[1].Tasks()[0])
+	}
+	for _, t := range tss[1].Tasks() {
+		c.Check(t.WaitTasks(), Not(testutil.Contains), tss[0].Tasks()[0])
+	}
+
+	chg := s.state.NewChange("install", "install local snaps")
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+
+	defer s.se.Stop()
+	s.settle(c)
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(chg.IsReady(), Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestInstallPathManyWithLocalPrereqsPropagatesFlags(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	flags := &snapstate.Flags{DevMode: true, Unaliased: true}
+	names, tss := s.installPathManyLocalSnaps(c, []string{`name: some-snap
+version: 1.0
+base: local-base
+plugs:
+  myplug:
+    interface: content
+    content: mycontent
+    default-provider: prereq-snap
+`, `name: prereq-snap
+version: 1.0
+slots:
+  myslot:
+    interface: content
+    content: mycontent
+`, `name: local-base
+version: 1.0
+type: base
+`}, flags)
+
+	// the flags apply to the prerequisites provided as local files as well
+	seen := make(map[string]bool)
+	for _, ts := range tss {
+		sup, err := snapstate.TaskSnapSetup(ts.Tasks()[0])
+		c.Assert(err, IsNil)
+		seen[sup.InstanceName()] = true
+		c.Check(sup.DevMode, Equals, true, Commentf(sup.InstanceName()))
+		c.Check(sup.Unaliased, Equals, true, Commentf(sup.InstanceName()))
+		c.Check(sup.SideInfo.SnapID, Equals, "", Commentf(sup.InstanceName()))
+	}
+	c.Check(seen, HasLen, len(names))
+
+	chg := s.state.NewChange("install", "install local snaps")
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+
+	defer s.se.Stop()
+	s.settle(c)
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(chg.IsReady(), Equals, true)
+	c.Check(s.fakeBackend.ops.First("storesvc-snap-action"), IsNil)
+
+	for _, name := range names {
+		var snapst snapstate.SnapState
+		c.Assert(snapstate.Get(s.state, name, &snapst), IsNil)
+		c.Check(snapst.DevMode, Equals, true, Commentf(name))
+	}
+}
+
 func (s *snapmgrTestSuite) TestMigrateOnInstallWithCore24(c *C) {
 	c.Skip("TODO:Snap-folder: no automatic migration for core22 snaps to ~/Snap folder for now")
 
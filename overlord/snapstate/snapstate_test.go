@@ -207,6 +207,8 @@ func (s *snapmgrBaseTest) SetUpTest(c *C) {
 
 	s.BaseTest.AddCleanup(snapstate.MockSnapReadInfo(s.fakeBackend.ReadInfo))
 	s.BaseTest.AddCleanup(snapstate.MockOpenSnapFile(s.fakeBackend.OpenSnapFile))
+	// the snap files of the revisions to revert to are not created
+	s.BaseTest.AddCleanup(snapstate.MockOsutilFileExists(func(string) bool { return true }))
 	revDate := func(info *snap.Info) time.Time {
 		if info.Revision.Local() {
 			panic("no local revision should reach revisionDate")
@@ -1500,6 +1502,86 @@ func (s *snapmgrTestSuite) TestRevertToRevisionNoValidVersion(c *C) {
 	c.Assert(ts, IsNil)
 }
 
+func (s *snapmgrTestSuite) TestRevertToRevisionNotRetained(c *C) {
+	si := snap.SideInfo{
+		RealName: "some-snap",
+		Revision: snap.R(7),
+	}
+	si2 := snap.SideInfo{
+		RealName: "some-snap",
+		Revision: snap.R(77),
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{&si, &si2},
+		Current:  snap.R(77),
+	})
+
+	var checked []string
+	restore := snapstate.MockOsutilFileExists(func(path string) bool {
+		checked = append(checked, path)
+		return false
+	})
+	defer restore()
+
+	ts, err := snapstate.RevertToRevision(s.state, "some-snap", snap.R(7), snapstate.Flags{}, "")
+	c.Assert(err, ErrorMatches, `cannot revert snap "some-snap" to revision 7: revision is not retained locally`)
+	c.Check(err, FitsTypeOf, &snapstate.RevisionNotRetainedError{})
+	c.Assert(ts, IsNil)
+	c.Check(checked, DeepEquals, []string{filepath.Join(dirs.SnapBlobDir, "some-snap_7.snap")})
+
+	// unknown revisions are reported differently
+	ts, err = snapstate.RevertToRevision(s.state, "some-snap", snap.R(99), snapstate.Flags{}, "")
+	c.Assert(err, ErrorMatches, `cannot find revision 99 for snap "some-snap"`)
+	c.Check(err, FitsTypeOf, &snapstate.UnknownRevisionError{})
+	c.Assert(ts, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestRevertToRevisionEpochCheck(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", Revision: snap.R(3)},
+			{RealName: "some-snap", Revision: snap.R(5)},
+			{RealName: "some-snap", Revision: snap.R(7)},
+		},
+		Current:  snap.R(7),
+		SnapType: "app",
+	})
+
+	epochs := map[snap.Revision]snap.Epoch{
+		snap.R(3): snap.E("0"),
+		snap.R(5): snap.E("2"),
+		snap.R(7): snap.E("2*"),
+	}
+	restore := snapstate.MockSnapReadInfo(func(name string, si *snap.SideInfo) (*snap.Info, error) {
+		info, err := s.fakeBackend.ReadInfo(name, si)
+		if err != nil {
+			return nil, err
+		}
+		info.Epoch = epochs[si.Revision]
+		return info, nil
+	})
+	defer restore()
+
+	// revision 3 cannot read the data of epoch 2
+	ts, err := snapstate.RevertToRevision(s.state, "some-snap", snap.R(3), snapstate.Flags{}, "")
+	c.Assert(err, ErrorMatches, `cannot revert "some-snap" to revision 3 with epoch 0, because it can't read the current epoch of 2\*`)
+	c.Assert(ts, IsNil)
+
+	// but revision 5 can
+	ts, err = snapstate.RevertToRevision(s.state, "some-snap", snap.R(5), snapstate.Flags{}, "")
+	c.Assert(err, IsNil)
+	c.Check(ts.Tasks(), Not(HasLen), 0)
+}
+
 func (s *snapmgrTestSuite) TestRevertToRevisionAlreadyCurrent(c *C) {
 	si := snap.SideInfo{
 		RealName: "some-snap",
@@ -8974,6 +9056,47 @@ func (s *snapmgrTestSuite) TestSaveRefreshCandidatesOnAutoRefresh(c *C) {
 	c.Check(cands["some-other-snap"], NotNil)
 }
 
+func (s *snapmgrTestSuite) TestAutoRefreshSkipsUnhealthySnaps(c *C) {
+	logbuf, restoreLogger := logger.MockLogger()
+	defer restoreLogger()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, name := range []string{"some-snap", "some-other-snap"} {
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active: true,
+			Sequence: []*snap.SideInfo{
+				{RealName: name, SnapID: name + "-id", Revision: snap.R(1)},
+			},
+			Current:  snap.R(1),
+			SnapType: "app",
+		})
+	}
+
+	restore := snapstate.MockUnhealthyForAutoRefresh(func(st *state.State, instanceName string) (string, error) {
+		if instanceName == "some-snap" {
+			return `snap health is "error"`, nil
+		}
+		return "", nil
+	})
+	defer restore()
+
+	// health is ignored unless asked for
+	names, _, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(names, DeepEquals, []string{"some-other-snap", "some-snap"})
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "refresh.block-on-unhealthy", true), IsNil)
+	tr.Commit()
+
+	names, _, err = snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(names, DeepEquals, []string{"some-other-snap"})
+	c.Check(logbuf.String(), testutil.Contains, `skipping auto-refresh of snap "some-snap": snap health is "error"`)
+}
+
 func (s *snapmgrTestSuite) TestRefreshCandidatesMergeFlags(c *C) {
 	si := &snap.SideInfo{
 		RealName: "some-snap",
@@ -9006,3 +9129,101 @@ func (s *snapmgrTestSuite) TestRefreshCandidatesMergeFlags(c *C) {
 		},
 	})
 }
+
+func (s *snapmgrTestSuite) TestBlockedTaskLimitsParallelDownloads(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	newDownload := func(status state.Status) *state.Task {
+		t := s.state.NewTask("download-snap", "...")
+		t.SetStatus(status)
+		return t
+	}
+
+	cand := newDownload(state.DoStatus)
+	running := []*state.Task{newDownload(state.DoingStatus)}
+	// one download running, the default allows two
+	c.Check(s.snapmgr.BlockedTask(cand, running), Equals, false)
+
+	running = append(running, newDownload(state.DoingStatus))
+	c.Check(s.snapmgr.BlockedTask(cand, running), Equals, true)
+
+	// downloads being undone do not count
+	undoing := []*state.Task{running[0], newDownload(state.UndoingStatus)}
+	c.Check(s.snapmgr.BlockedTask(cand, undoing), Equals, false)
+
+	// other tasks are not blocked
+	other := s.state.NewTask("link-snap", "...")
+	c.Check(s.snapmgr.BlockedTask(other, running), Equals, false)
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "refresh.max-parallel-downloads", 3), IsNil)
+	tr.Commit()
+	// the option is only read again at the next Ensure
+	c.Check(s.snapmgr.BlockedTask(cand, running), Equals, true)
+
+	s.state.Unlock()
+	c.Assert(s.snapmgr.EnsureMaxParallelDownloads(), IsNil)
+	s.state.Lock()
+	c.Check(s.snapmgr.BlockedTask(cand, running), Equals, false)
+
+	running = append(running, newDownload(state.DoingStatus))
+	c.Check(s.snapmgr.BlockedTask(cand, running), Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyDownloadsInParallel(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	maxDownloading := 0
+	s.fakeStore.downloadCallback = func() {
+		s.state.Lock()
+		defer s.state.Unlock()
+		// the runner marks tasks as doing when starting them
+		downloading := 0
+		for _, t := range s.state.Tasks() {
+			if t.Kind() == "download-snap" && t.Status() == state.DoingStatus {
+				downloading++
+			}
+		}
+		if downloading > maxDownloading {
+			maxDownloading = downloading
+		}
+	}
+
+	for _, name := range []string{"some-snap", "some-other-snap", "snap-c"} {
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active: true,
+			Sequence: []*snap.SideInfo{
+				{RealName: name, SnapID: fmt.Sprintf("%s-id", name), Revision: snap.R(1)},
+			},
+			Current:         snap.R(1),
+			SnapType:        "app",
+			TrackingChannel: "latest/stable",
+		})
+	}
+
+	updated, tss, err := snapstate.UpdateMany(context.Background(), s.state, []string{"some-snap", "some-other-snap", "snap-c"}, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updated, HasLen, 3)
+
+	chg := s.state.NewChange("refresh", "refresh snaps")
+	for _, ts := range tss {
+		chg.AddAll(ts)
+		// prerequisites are serialized, have all downloads ready to
+		// run in the first ensure
+		for _, t := range ts.Tasks() {
+			if t.Kind() == "prerequisites" {
+				t.SetStatus(state.DoneStatus)
+			}
+		}
+	}
+
+	defer s.se.Stop()
+	s.settle(c)
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	// downloads ran in parallel, but no more than the default of two
+	c.Check(maxDownloading, Equals, 2)
+}
@@ -4894,6 +4894,86 @@ func (s *snapmgrTestSuite) TestUpdateManyTransactionallyFails(c *C) {
 	c.Check(snapSt.Current, Equals, snap.R(7))
 }
 
+func (s *snapmgrTestSuite) TestUpdateManyTransactionallyFailsAtAutoConnect(c *C) {
+	restore := release.MockOnClassic(true)
+	defer restore()
+
+	snapNames := []string{"some-snap", "some-other-snap", "snap-c"}
+
+	// the second snap fails to auto-connect, but only once the other
+	// snaps are fully refreshed such that all of them need to be reverted
+	autoConnect := func(task *state.Task, _ *tomb.Tomb) error {
+		st := task.State()
+		st.Lock()
+		defer st.Unlock()
+		snapsup, err := snapstate.TaskSnapSetup(task)
+		if err != nil {
+			return err
+		}
+		if snapsup.InstanceName() != "some-other-snap" {
+			return nil
+		}
+		for _, t := range task.Change().Tasks() {
+			if t.Kind() == "auto-connect" && t != task && t.Status() != state.DoneStatus {
+				return &state.Retry{After: time.Millisecond}
+			}
+		}
+		return errors.New("auto-connect failed")
+	}
+	s.o.TaskRunner().AddHandler("auto-connect", autoConnect, nil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, name := range snapNames {
+		si := &snap.SideInfo{
+			RealName: name,
+			SnapID:   name + "-id",
+			Revision: snap.R(1),
+		}
+		snaptest.MockSnap(c, fmt.Sprintf("name: %s", name), si)
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active:          true,
+			TrackingChannel: "latest/stable",
+			Sequence:        []*snap.SideInfo{si},
+			Current:         si.Revision,
+			SnapType:        "app",
+		})
+	}
+
+	chg := s.state.NewChange("refresh", "refresh some snaps")
+	updated, tts, err := snapstate.UpdateMany(context.Background(), s.state,
+		snapNames, nil, 0, &snapstate.Flags{Transaction: client.TransactionAllSnaps})
+	c.Assert(err, IsNil)
+	c.Check(updated, testutil.DeepUnsortedMatches, snapNames)
+	for _, ts := range tts {
+		chg.AddAll(ts)
+	}
+
+	defer s.se.Stop()
+	s.settle(c)
+
+	c.Assert(chg.Err(), ErrorMatches, `(?s)cannot perform the following tasks:\n.*\(auto-connect failed\).*`)
+	c.Assert(chg.IsReady(), Equals, true)
+
+	linked := 0
+	for _, t := range chg.Tasks() {
+		if t.Kind() != "link-snap" {
+			continue
+		}
+		// all snaps were linked to the new revision and reverted
+		c.Check(t.Status(), Equals, state.UndoneStatus, Commentf("%s", t.Summary()))
+		linked++
+	}
+	c.Check(linked, Equals, len(snapNames))
+	for _, name := range snapNames {
+		var snapst snapstate.SnapState
+		c.Assert(snapstate.Get(s.state, name, &snapst), IsNil)
+		c.Check(snapst.Current, Equals, snap.R(1), Commentf("%s", name))
+		c.Check(snapst.Active, Equals, true, Commentf("%s", name))
+	}
+}
+
 func (s *snapmgrTestSuite) TestUpdateManyFailureDoesntUndoSnapdRefresh(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -5166,11 +5246,16 @@ func (s *snapmgrTestSuite) TestUpdateManyWaitForBasesUC16(c *C) {
 
 	prereqTotal := len(tts[0].Tasks()) + len(tts[1].Tasks())
 	prereqs := map[string]bool{}
-	for i, task := range tts[2].Tasks() {
+	for _, task := range tts[2].Tasks() {
 		waitTasks := task.WaitTasks()
-		if i == 0 {
-			c.Check(len(waitTasks), Equals, prereqTotal)
-		} else if task.Kind() == "link-snap" {
+		switch task.Kind() {
+		case "prerequisites":
+			// prerequisites and download do not wait for the
+			// refresh of the bases
+			c.Check(waitTasks, HasLen, 0)
+		case "download-snap":
+			c.Check(waitTasks, HasLen, 1)
+		case "link-snap":
 			c.Check(len(waitTasks), Equals, prereqTotal+1)
 			for _, pre := range waitTasks {
 				if pre.Kind() == "link-snap" {
@@ -5248,11 +5333,16 @@ func (s *snapmgrTestSuite) TestUpdateManyWaitForBasesUC18(c *C) {
 	// base is not special to this snap and not waited for
 	prereqTotal := len(tts[0].Tasks()) + len(tts[1].Tasks())
 	prereqs := map[string]bool{}
-	for i, task := range tts[3].Tasks() {
+	for _, task := range tts[3].Tasks() {
 		waitTasks := task.WaitTasks()
-		if i == 0 {
-			c.Check(len(waitTasks), Equals, prereqTotal)
-		} else if task.Kind() == "link-snap" {
+		switch task.Kind() {
+		case "prerequisites":
+			// prerequisites and download do not wait for the
+			// refresh of snapd and the base
+			c.Check(waitTasks, HasLen, 0)
+		case "download-snap":
+			c.Check(waitTasks, HasLen, 1)
+		case "link-snap":
 			c.Check(len(waitTasks), Equals, prereqTotal+1)
 			for _, pre := range waitTasks {
 				if pre.Kind() == "link-snap" {
@@ -8825,10 +8915,16 @@ func (s *snapmgrTestSuite) TestUpdateBaseAndSnapdOrder(c *C) {
 		}
 	}
 
-	// verify that the base tasks depend on the last task of snapd
+	// verify that the base tasks depend on the last task of snapd, apart
+	// from the prerequisites and download which can run in parallel
 	snapdts := snapdTs.Tasks()
 	for _, bts := range baseTs.Tasks() {
-		c.Check(bts.WaitTasks(), testutil.Contains, snapdts[len(snapdts)-1])
+		switch bts.Kind() {
+		case "prerequisites", "download-snap":
+			c.Check(bts.WaitTasks(), Not(testutil.Contains), snapdts[len(snapdts)-1])
+		default:
+			c.Check(bts.WaitTasks(), testutil.Contains, snapdts[len(snapdts)-1])
+		}
 	}
 
 	s.fakeBackend.linkSnapMaybeReboot = true
@@ -0,0 +1,191 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var (
+	osutilDiskFree = osutil.DiskFree
+
+	storageGCCheckInterval = time.Hour
+)
+
+// storageGCCandidate is a retained revision that can be removed to free
+// disk space.
+type storageGCCandidate struct {
+	instanceName string
+	revision     snap.Revision
+	// index in the sequence of the snap, lower indexes are older
+	index int
+	size  int64
+}
+
+// storageGCFreeSpaceThreshold returns the storage.gc-free-space-threshold
+// value or 0 if it is not set.
+func storageGCFreeSpaceThreshold(st *state.State) (uint64, error) {
+	var thresholdStr string
+	tr := config.NewTransaction(st)
+	if err := tr.Get("core", "storage.gc-free-space-threshold", &thresholdStr); err != nil && !config.IsNoOption(err) {
+		return 0, err
+	}
+	if thresholdStr == "" {
+		return 0, nil
+	}
+	threshold, err := strutil.ParseByteSize(thresholdStr)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(threshold), nil
+}
+
+// storageGCCandidates returns the retained revisions of all snaps that are
+// neither the current one nor the one a revert would go back to, ordered by
+// size, largest first, and then by age, oldest first.
+func storageGCCandidates(st *state.State) ([]*storageGCCandidate, error) {
+	snapStates, err := All(st)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*storageGCCandidate
+	for instanceName, snapst := range snapStates {
+		var previous snap.Revision
+		if prev := snapst.previousSideInfo(); prev != nil {
+			previous = prev.Revision
+		}
+		for i, si := range snapst.Sequence {
+			if si.Revision == snapst.Current || si.Revision == previous {
+				continue
+			}
+			fi, err := os.Stat(snap.MountFile(instanceName, si.Revision))
+			if err != nil {
+				// nothing to gain
+				continue
+			}
+			candidates = append(candidates, &storageGCCandidate{
+				instanceName: instanceName,
+				revision:     si.Revision,
+				index:        i,
+				size:         fi.Size(),
+			})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if ci.size != cj.size {
+			return ci.size > cj.size
+		}
+		if ci.index != cj.index {
+			return ci.index < cj.index
+		}
+		if ci.instanceName != cj.instanceName {
+			return ci.instanceName < cj.instanceName
+		}
+		return ci.revision.N < cj.revision.N
+	})
+	return candidates, nil
+}
+
+// ensureStorageGC removes old retained revisions of snaps when the free
+// space on the partition holding the snap files drops below the
+// storage.gc-free-space-threshold system option, if set.
+func (m *SnapManager) ensureStorageGC() error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	now := timeNow()
+	if !m.lastStorageGCCheck.IsZero() && now.Sub(m.lastStorageGCCheck) < storageGCCheckInterval {
+		return nil
+	}
+	m.lastStorageGCCheck = now
+
+	threshold, err := storageGCFreeSpaceThreshold(m.state)
+	if err != nil {
+		logger.Noticef("internal error: storage.gc-free-space-threshold system option is not valid: %v", err)
+		return nil
+	}
+	if threshold == 0 {
+		return nil
+	}
+
+	free, err := osutilDiskFree(dirs.SnapBlobDir)
+	if err != nil {
+		logger.Noticef("cannot check free space in %q: %v", dirs.SnapBlobDir, err)
+		return nil
+	}
+	if free >= threshold {
+		return nil
+	}
+
+	candidates, err := storageGCCandidates(m.state)
+	if err != nil {
+		return err
+	}
+
+	var tss []*state.TaskSet
+	var removed []string
+	lastTs := make(map[string]*state.TaskSet)
+	for _, cand := range candidates {
+		if free >= threshold {
+			break
+		}
+		ts, err := Remove(m.state, cand.instanceName, cand.revision, &RemoveFlags{Purge: true})
+		if err != nil {
+			logger.Noticef("cannot remove revision %s of snap %q to free disk space: %v", cand.revision, cand.instanceName, err)
+			continue
+		}
+		// revisions of the same snap are removed one after the other
+		if prev := lastTs[cand.instanceName]; prev != nil {
+			ts.WaitAll(prev)
+		}
+		lastTs[cand.instanceName] = ts
+		tss = append(tss, ts)
+		removed = append(removed, fmt.Sprintf("%q (revision %s, %s)", cand.instanceName, cand.revision, strutil.SizeToStr(cand.size)))
+		free += uint64(cand.size)
+	}
+	if len(tss) == 0 {
+		return nil
+	}
+
+	chg := m.state.NewChange("gc-snap-revisions", i18n.G("Remove old snap revisions to free disk space"))
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+	m.state.Warnf("free space in %q dropped below %s, removing old snap revisions: %s", dirs.SnapBlobDir, strutil.SizeToStr(int64(threshold)), strings.Join(removed, ", "))
+	m.state.EnsureBefore(0)
+
+	return nil
+}
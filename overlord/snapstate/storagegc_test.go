@@ -0,0 +1,223 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) mockStorageGCSnaps(c *C) {
+	c.Assert(os.MkdirAll(dirs.SnapBlobDir, 0755), IsNil)
+	mockSnap := func(name string, current int, sizes map[int]int) {
+		var seq []*snap.SideInfo
+		for rev := 1; rev <= current; rev++ {
+			size, ok := sizes[rev]
+			if !ok {
+				continue
+			}
+			seq = append(seq, &snap.SideInfo{RealName: name, SnapID: name + "-id", Revision: snap.R(rev)})
+			blob := filepath.Join(dirs.SnapBlobDir, fmt.Sprintf("%s_%d.snap", name, rev))
+			c.Assert(os.WriteFile(blob, make([]byte, size), 0644), IsNil)
+		}
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active:   true,
+			Sequence: seq,
+			Current:  snap.R(current),
+			SnapType: "app",
+		})
+	}
+	// the two latest revisions are never removed
+	mockSnap("some-snap", 4, map[int]int{1: 100, 2: 300, 3: 1000, 4: 1000})
+	mockSnap("some-other-snap", 7, map[int]int{5: 200, 6: 1000, 7: 1000})
+	mockSnap("snap-c", 2, map[int]int{1: 5000, 2: 5000})
+}
+
+func (s *snapmgrTestSuite) TestEnsureStorageGCUnset(c *C) {
+	s.state.Lock()
+	s.mockStorageGCSnaps(c)
+	s.state.Unlock()
+
+	restore := snapstate.MockOsutilDiskFree(func(path string) (uint64, error) {
+		c.Fatalf("unexpected call")
+		return 0, nil
+	})
+	defer restore()
+
+	c.Assert(s.snapmgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestEnsureStorageGCEnoughSpace(c *C) {
+	s.state.Lock()
+	s.mockStorageGCSnaps(c)
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "storage.gc-free-space-threshold", "1kB"), IsNil)
+	tr.Commit()
+	s.state.Unlock()
+
+	calls := 0
+	restore := snapstate.MockOsutilDiskFree(func(path string) (uint64, error) {
+		calls++
+		c.Check(path, Equals, dirs.SnapBlobDir)
+		return 1000, nil
+	})
+	defer restore()
+
+	c.Assert(s.snapmgr.Ensure(), IsNil)
+	c.Check(calls, Equals, 1)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 0)
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestEnsureStorageGCRemovesLargestOldRevisions(c *C) {
+	s.state.Lock()
+	s.mockStorageGCSnaps(c)
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "storage.gc-free-space-threshold", "1kB"), IsNil)
+	tr.Commit()
+	s.state.Unlock()
+
+	calls := 0
+	restore := snapstate.MockOsutilDiskFree(func(path string) (uint64, error) {
+		calls++
+		return 600, nil
+	})
+	defer restore()
+
+	c.Assert(s.snapmgr.Ensure(), IsNil)
+	c.Check(calls, Equals, 1)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chgs := s.state.Changes()
+	c.Assert(chgs, HasLen, 1)
+	chg := chgs[0]
+	c.Check(chg.Kind(), Equals, "gc-snap-revisions")
+
+	// the largest old revisions are removed until enough space is freed
+	var removed []string
+	for _, t := range chg.Tasks() {
+		if t.Kind() != "discard-snap" {
+			continue
+		}
+		snapsup, err := snapstate.TaskSnapSetup(t)
+		c.Assert(err, IsNil)
+		removed = append(removed, fmt.Sprintf("%s_%s", snapsup.InstanceName(), snapsup.Revision()))
+	}
+	c.Check(removed, DeepEquals, []string{"some-snap_2", "some-other-snap_5"})
+
+	warns := s.state.AllWarnings()
+	c.Assert(warns, HasLen, 1)
+	c.Check(warns[0].String(), Equals, fmt.Sprintf(`free space in %q dropped below 1kB, removing old snap revisions: "some-snap" (revision 2, 300B), "some-other-snap" (revision 5, 200B)`, dirs.SnapBlobDir))
+}
+
+func (s *snapmgrTestSuite) TestEnsureStorageGCSkipsConflicts(c *C) {
+	s.state.Lock()
+	s.mockStorageGCSnaps(c)
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "storage.gc-free-space-threshold", "1kB"), IsNil)
+	tr.Commit()
+
+	// some-snap is busy
+	chg := s.state.NewChange("refresh-snap", "...")
+	t := s.state.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{SideInfo: &snap.SideInfo{RealName: "some-snap"}})
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	restore := snapstate.MockOsutilDiskFree(func(path string) (uint64, error) {
+		return 0, nil
+	})
+	defer restore()
+
+	c.Assert(s.snapmgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	var gcChg *state.Change
+	for _, chg := range s.state.Changes() {
+		if chg.Kind() == "gc-snap-revisions" {
+			gcChg = chg
+		}
+	}
+	c.Assert(gcChg, NotNil)
+	var removed []string
+	for _, t := range gcChg.Tasks() {
+		if t.Kind() == "discard-snap" {
+			snapsup, err := snapstate.TaskSnapSetup(t)
+			c.Assert(err, IsNil)
+			removed = append(removed, fmt.Sprintf("%s_%s", snapsup.InstanceName(), snapsup.Revision()))
+		}
+	}
+	c.Check(removed, DeepEquals, []string{"some-other-snap_5"})
+}
+
+func (s *snapmgrTestSuite) TestEnsureStorageGCRateLimited(c *C) {
+	s.state.Lock()
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "storage.gc-free-space-threshold", "1kB"), IsNil)
+	tr.Commit()
+	s.state.Unlock()
+
+	defer snapstate.MockStorageGCCheckInterval(time.Hour)()
+
+	now := time.Now()
+	restore := snapstate.MockTimeNow(func() time.Time {
+		return now
+	})
+	defer restore()
+
+	calls := 0
+	restore = snapstate.MockOsutilDiskFree(func(path string) (uint64, error) {
+		calls++
+		return 2000, nil
+	})
+	defer restore()
+
+	c.Assert(s.snapmgr.Ensure(), IsNil)
+	c.Check(calls, Equals, 1)
+
+	now = now.Add(time.Minute)
+	c.Assert(s.snapmgr.Ensure(), IsNil)
+	c.Check(calls, Equals, 1)
+
+	now = now.Add(time.Hour)
+	c.Assert(s.snapmgr.Ensure(), IsNil)
+	c.Check(calls, Equals, 2)
+}
@@ -0,0 +1,177 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package state
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// ChangeSize holds the serialized size of a change, including its tasks.
+type ChangeSize struct {
+	ID      string
+	Kind    string
+	Summary string
+	Status  Status
+	Tasks   int
+	Size    int
+}
+
+// KeySize holds the serialized size of the value of a state key.
+type KeySize struct {
+	Key  string
+	Size int
+}
+
+// SizeBreakdown describes what the serialized state is made of.
+type SizeBreakdown struct {
+	// Total is the size of the whole serialized state.
+	Total int
+	// Changes holds the size of each change, largest first.
+	Changes []ChangeSize
+	// Keys holds the size of the value of each state key, largest first.
+	Keys []KeySize
+	// OrphanTasks is the size of the tasks not part of any change.
+	OrphanTasks int
+	// Warnings is the size of the warnings.
+	Warnings int
+}
+
+// changeSize returns the serialized size of the change and its tasks.
+func (s *State) changeSize(chg *Change) (int, error) {
+	size := 0
+	data, err := json.Marshal(chg)
+	if err != nil {
+		return 0, err
+	}
+	size += len(data)
+	for _, t := range chg.Tasks() {
+		data, err := json.Marshal(t)
+		if err != nil {
+			return 0, err
+		}
+		size += len(data)
+	}
+	return size, nil
+}
+
+// SizeBreakdown returns the serialized size of the state along with the
+// changes and state keys contributing to it.
+func (s *State) SizeBreakdown() (*SizeBreakdown, error) {
+	s.reading()
+
+	total, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	breakdown := &SizeBreakdown{Total: len(total)}
+
+	for _, chg := range s.changes {
+		size, err := s.changeSize(chg)
+		if err != nil {
+			return nil, err
+		}
+		breakdown.Changes = append(breakdown.Changes, ChangeSize{
+			ID:      chg.ID(),
+			Kind:    chg.Kind(),
+			Summary: chg.Summary(),
+			Status:  chg.Status(),
+			Tasks:   len(chg.taskIDs),
+			Size:    size,
+		})
+	}
+	sort.SliceStable(breakdown.Changes, func(i, j int) bool {
+		a, b := breakdown.Changes[i], breakdown.Changes[j]
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		return a.ID < b.ID
+	})
+
+	for k, v := range s.data {
+		size := 0
+		if v != nil {
+			size = len(*v)
+		}
+		breakdown.Keys = append(breakdown.Keys, KeySize{Key: k, Size: size})
+	}
+	sort.SliceStable(breakdown.Keys, func(i, j int) bool {
+		a, b := breakdown.Keys[i], breakdown.Keys[j]
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		return a.Key < b.Key
+	})
+
+	for _, t := range s.tasks {
+		if t.Change() != nil {
+			continue
+		}
+		data, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		breakdown.OrphanTasks += len(data)
+	}
+
+	if len(s.warnings) > 0 {
+		data, err := json.Marshal(s.flattenWarnings())
+		if err != nil {
+			return nil, err
+		}
+		breakdown.Warnings = len(data)
+	}
+
+	return breakdown, nil
+}
+
+// PruneOversizedChanges removes the changes, and their tasks, whose
+// serialized size exceeds maxSize and which have been ready for longer than
+// pruneWait. It is meant to be used with a shorter pruneWait than the one
+// used with Prune, such that changes carrying a lot of data do not make the
+// state grow too much. Changes which are not ready, or for which a pending
+// change check registered with RegisterPendingChangeByAttr still holds, are
+// never removed.
+func (s *State) PruneOversizedChanges(maxSize int, pruneWait time.Duration) {
+	pruneLimit := time.Now().Add(-pruneWait)
+
+NextChange:
+	for _, chg := range s.Changes() {
+		readyTime := chg.ReadyTime()
+		if readyTime.IsZero() || !readyTime.Before(pruneLimit) {
+			continue
+		}
+		for attr, pending := range s.pendingChangeByAttr {
+			if chg.Has(attr) && pending(chg) {
+				continue NextChange
+			}
+		}
+		size, err := s.changeSize(chg)
+		if err != nil || size <= maxSize {
+			continue
+		}
+		s.writing()
+		for _, t := range chg.Tasks() {
+			delete(s.tasks, t.ID())
+		}
+		delete(s.changes, chg.ID())
+	}
+}
@@ -0,0 +1,129 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package state_test
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type sizeSuite struct {
+	st *state.State
+}
+
+var _ = Suite(&sizeSuite{})
+
+func (s *sizeSuite) SetUpTest(c *C) {
+	s.st = state.New(&fakeStateBackend{})
+}
+
+// addChange adds a change of the given kind with a task carrying data of
+// the given size, the change is ready since the given time unless it is zero.
+func (s *sizeSuite) addChange(kind string, dataSize int, ready time.Time) *state.Change {
+	chg := s.st.NewChange(kind, "...")
+	t := s.st.NewTask("foo", "...")
+	t.Set("blob", strings.Repeat("x", dataSize))
+	chg.AddTask(t)
+	if !ready.IsZero() {
+		t.SetStatus(state.DoneStatus)
+		state.MockChangeTimes(chg, ready, ready)
+	}
+	return chg
+}
+
+func (s *sizeSuite) TestSizeBreakdown(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	s.st.Set("small", "a")
+	s.st.Set("big", strings.Repeat("b", 100))
+	small := s.addChange("small", 10, time.Time{})
+	big := s.addChange("big", 1000, time.Time{})
+	// a task without a change
+	s.st.NewTask("orphan", "...")
+	s.st.Warnf("hello")
+
+	breakdown, err := s.st.SizeBreakdown()
+	c.Assert(err, IsNil)
+
+	data, err := json.Marshal(s.st)
+	c.Assert(err, IsNil)
+	c.Check(breakdown.Total, Equals, len(data))
+
+	c.Assert(breakdown.Changes, HasLen, 2)
+	c.Check(breakdown.Changes[0].ID, Equals, big.ID())
+	c.Check(breakdown.Changes[0].Kind, Equals, "big")
+	c.Check(breakdown.Changes[0].Status, Equals, state.DoStatus)
+	c.Check(breakdown.Changes[0].Tasks, Equals, 1)
+	c.Check(breakdown.Changes[0].Size > 1000, Equals, true)
+	c.Check(breakdown.Changes[1].ID, Equals, small.ID())
+	c.Check(breakdown.Changes[1].Size < breakdown.Changes[0].Size, Equals, true)
+
+	c.Check(breakdown.Keys, DeepEquals, []state.KeySize{
+		{Key: "big", Size: 102},
+		{Key: "small", Size: 3},
+	})
+	c.Check(breakdown.OrphanTasks > 0, Equals, true)
+	c.Check(breakdown.Warnings > 0, Equals, true)
+}
+
+func (s *sizeSuite) TestPruneOversizedChanges(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	now := time.Now()
+	oldBig := s.addChange("old-big", 1000, now.Add(-2*time.Hour))
+	oldSmall := s.addChange("old-small", 10, now.Add(-2*time.Hour))
+	freshBig := s.addChange("fresh-big", 1000, now.Add(-time.Minute))
+	notReadyBig := s.addChange("not-ready-big", 1000, time.Time{})
+	state.MockChangeTimes(notReadyBig, now.Add(-2*time.Hour), time.Time{})
+	oldBigTask := oldBig.Tasks()[0]
+
+	s.st.PruneOversizedChanges(500, time.Hour)
+
+	c.Check(s.st.Change(oldBig.ID()), IsNil)
+	c.Check(s.st.Task(oldBigTask.ID()), IsNil)
+	c.Check(s.st.Change(oldSmall.ID()), Equals, oldSmall)
+	c.Check(s.st.Change(freshBig.ID()), Equals, freshBig)
+	c.Check(s.st.Change(notReadyBig.ID()), Equals, notReadyBig)
+}
+
+func (s *sizeSuite) TestPruneOversizedChangesHonorsPendingChangeByAttr(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	now := time.Now()
+	pending := s.addChange("pending", 1000, now.Add(-2*time.Hour))
+	pending.Set("pending-attr", true)
+	other := s.addChange("other", 1000, now.Add(-2*time.Hour))
+
+	s.st.RegisterPendingChangeByAttr("pending-attr", func(chg *state.Change) bool {
+		return true
+	})
+	s.st.PruneOversizedChanges(500, time.Hour)
+
+	c.Check(s.st.Change(pending.ID()), Equals, pending)
+	c.Check(s.st.Change(other.ID()), IsNil)
+}
@@ -20,6 +20,7 @@
 package state
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -31,6 +32,11 @@ import (
 // HandlerFunc is the type of function for the handlers
 type HandlerFunc func(task *Task, tomb *tomb.Tomb) error
 
+// ContextHandlerFunc is the type of function for the handlers that are
+// passed a context which is cancelled when their task is aborted or the
+// task runner is stopped.
+type ContextHandlerFunc func(ctx context.Context, task *Task) error
+
 // Retry is returned from a handler to signal that is ok to rerun the
 // task at a later point. It's to be used also when a task goroutine
 // is asked to stop through its tomb. After can be used to indicate
@@ -119,6 +125,34 @@ func (r *TaskRunner) AddHandler(kind string, do, undo HandlerFunc) {
 	r.handlers[kind] = handlerPair{do, undo}
 }
 
+// AddHandlerWithContext registers the functions to concurrently call for
+// doing and undoing tasks of the given kind, like AddHandler. The functions
+// are passed a context which is cancelled when the task is aborted or the
+// runner is stopped, they are expected to stop what they are doing and
+// clean up after themselves then. An error returned after the context was
+// cancelled is treated like a Retry, the aborted task is then undone, or
+// put on hold if it has no undo handler. The undo handler may be nil.
+func (r *TaskRunner) AddHandlerWithContext(kind string, do, undo ContextHandlerFunc) {
+	r.AddHandler(kind, withContext(do), withContext(undo))
+}
+
+func withContext(handler ContextHandlerFunc) HandlerFunc {
+	if handler == nil {
+		return nil
+	}
+	return func(t *Task, tomb *tomb.Tomb) error {
+		ctx := tomb.Context(nil)
+		err := handler(ctx, t)
+		if err != nil && ctx.Err() != nil {
+			if _, ok := err.(*Wait); !ok {
+				logger.Debugf("Task %s stopped: %v", t.ID(), err)
+				return &Retry{}
+			}
+		}
+		return err
+	}
+}
+
 // AddOptionalHandler register functions for doing and undoing tasks that match
 // the given predicate if no explicit handler was registered for the task kind.
 func (r *TaskRunner) AddOptionalHandler(match func(t *Task) bool, do, undo HandlerFunc) {
@@ -20,6 +20,7 @@
 package state_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
@@ -578,6 +579,124 @@ func (ts *taskRunnerSuite) TestExternalAbort(c *C) {
 	ensureChange(c, r, sb, chg)
 }
 
+func (ts *taskRunnerSuite) testAbortWithContext(c *C, withUndo bool) *state.Task {
+	sb := &stateBackend{}
+	st := state.New(sb)
+	r := state.NewTaskRunner(st)
+	defer r.Stop()
+
+	ch := make(chan bool)
+	undone := false
+	do := func(ctx context.Context, t *state.Task) error {
+		ch <- true
+		<-ctx.Done()
+		// handlers stopping because of the cancellation report it
+		return fmt.Errorf("cannot continue: %v", ctx.Err())
+	}
+	var undo state.ContextHandlerFunc
+	if withUndo {
+		undo = func(ctx context.Context, t *state.Task) error {
+			undone = true
+			return nil
+		}
+	}
+	r.AddHandlerWithContext("blocking", do, undo)
+
+	st.Lock()
+	chg := st.NewChange("install", "...")
+	t := st.NewTask("blocking", "...")
+	chg.AddTask(t)
+	st.Unlock()
+
+	r.Ensure()
+	<-ch
+
+	st.Lock()
+	chg.Abort()
+	st.Unlock()
+
+	// The Abort above must make Ensure cancel the task context, or this
+	// will never end.
+	ensureChange(c, r, sb, chg)
+
+	c.Check(undone, Equals, withUndo)
+	return t
+}
+
+func (ts *taskRunnerSuite) TestAbortWithContextUndone(c *C) {
+	t := ts.testAbortWithContext(c, true)
+
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+	c.Check(t.Status(), Equals, state.UndoneStatus)
+	c.Check(t.Change().Err(), IsNil)
+}
+
+func (ts *taskRunnerSuite) TestAbortWithContextNoUndoHold(c *C) {
+	t := ts.testAbortWithContext(c, false)
+
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+	c.Check(t.Status(), Equals, state.HoldStatus)
+	c.Check(t.Change().Err(), IsNil)
+}
+
+func (ts *taskRunnerSuite) TestStopWithContextRetried(c *C) {
+	sb := &stateBackend{}
+	st := state.New(sb)
+	r := state.NewTaskRunner(st)
+	defer r.Stop()
+
+	ch := make(chan bool)
+	r.AddHandlerWithContext("blocking", func(ctx context.Context, t *state.Task) error {
+		ch <- true
+		<-ctx.Done()
+		return ctx.Err()
+	}, nil)
+
+	st.Lock()
+	chg := st.NewChange("install", "...")
+	t := st.NewTask("blocking", "...")
+	chg.AddTask(t)
+	st.Unlock()
+
+	r.Ensure()
+	<-ch
+	r.Stop()
+
+	st.Lock()
+	defer st.Unlock()
+	// still Doing, will be retried
+	c.Check(t.Status(), Equals, state.DoingStatus)
+}
+
+func (ts *taskRunnerSuite) TestWithContextErrorNotCancelled(c *C) {
+	sb := &stateBackend{}
+	st := state.New(sb)
+	r := state.NewTaskRunner(st)
+	defer r.Stop()
+
+	r.AddHandlerWithContext("failing", func(ctx context.Context, t *state.Task) error {
+		c.Check(ctx.Err(), IsNil)
+		return errors.New("boom")
+	}, nil)
+
+	st.Lock()
+	chg := st.NewChange("install", "...")
+	t := st.NewTask("failing", "...")
+	chg.AddTask(t)
+	st.Unlock()
+
+	ensureChange(c, r, sb, chg)
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(t.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*boom.*`)
+}
+
 func (ts *taskRunnerSuite) TestUndoSingleLane(c *C) {
 	sb := &stateBackend{}
 	st := state.New(sb)
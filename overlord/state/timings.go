@@ -42,3 +42,9 @@ func TimingsForTask(task *Task) *timings.Timings {
 func TagTimingsWithChange(t *timings.Timings, change *Change) {
 	t.AddTag("change-id", change.ID())
 }
+
+// TagTimingsWithSnap sets the "snap" tag on the Timings object, attributing
+// the measurements to the given snap instance.
+func TagTimingsWithSnap(t *timings.Timings, instanceName string) {
+	t.AddTag("snap", instanceName)
+}
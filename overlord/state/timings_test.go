@@ -100,3 +100,30 @@ func (s *timingsSuite) TestTimingsForTask(c *C) {
 		"task-status": "Doing",
 	})
 }
+
+func (s *timingsSuite) TestTagTimingsWithSnap(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	task := s.st.NewTask("kind", "...")
+	task.SetStatus(state.DoingStatus)
+	chg := s.st.NewChange("change", "...")
+	chg.AddTask(task)
+
+	troot := state.TimingsForTask(task)
+	state.TagTimingsWithSnap(troot, "some-snap_instance")
+	span := troot.StartSpan("foo", "bar")
+	span.Stop()
+	troot.Save(s.st)
+
+	tims, err := timings.Get(s.st, 1, func(tags map[string]string) bool { return true })
+	c.Assert(err, IsNil)
+	c.Assert(tims, HasLen, 1)
+	c.Check(tims[0].Tags, DeepEquals, map[string]string{
+		"change-id":   chg.ID(),
+		"task-id":     task.ID(),
+		"task-kind":   "kind",
+		"task-status": "Doing",
+		"snap":        "some-snap_instance",
+	})
+}
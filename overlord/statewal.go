@@ -0,0 +1,433 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package overlord
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/snapcore/snapd/logger"
+)
+
+// The state write-ahead log is a sequence of lines, each made of the
+// hexadecimal CRC-32C checksum of a JSON document followed by a space and the
+// document itself. The first document is the header carrying the format
+// version and the checksum of the state file the log applies to, every
+// following one is a record with the top-level state entries set or removed
+// since the previous record. The last record of a log which has been folded
+// into the state file is a marker saying so.
+
+// stateWALVersion is the version of the format of the write-ahead log.
+const stateWALVersion = 1
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+type stateWALHeader struct {
+	Version int `json:"version"`
+	// Base is the SHA-256 checksum of the state file the log applies to,
+	// a log left behind by a crash after the state file was written in
+	// full but before the log was marked as folded does not apply to the
+	// new state file.
+	Base string `json:"base"`
+}
+
+// stateWALEntry is a top-level entry of the state, in section "" for the
+// entries of the state itself or in one of the "data", "changes" and "tasks"
+// sections. An entry with no value was removed.
+type stateWALEntry struct {
+	Section string           `json:"section,omitempty"`
+	Key     string           `json:"key"`
+	Value   *json.RawMessage `json:"value,omitempty"`
+}
+
+type stateWALRecord struct {
+	Entries []stateWALEntry `json:"entries,omitempty"`
+	// Folded is set in the last record of a log which has been folded into
+	// the state file, such a log can be ignored.
+	Folded bool `json:"folded,omitempty"`
+}
+
+// stateWALSections are the entries of the state which hold a map of entries
+// tracked individually.
+var stateWALSections = []string{"data", "changes", "tasks"}
+
+type stateEntryKey struct {
+	section string
+	key     string
+}
+
+// stateEntries splits the serialized state into its top-level entries.
+func stateEntries(data []byte) (map[stateEntryKey]json.RawMessage, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, err
+	}
+	entries := make(map[stateEntryKey]json.RawMessage, len(top))
+	for k, v := range top {
+		entries[stateEntryKey{key: k}] = v
+	}
+	for _, section := range stateWALSections {
+		raw, ok := top[section]
+		if !ok {
+			continue
+		}
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, fmt.Errorf("cannot decode state %s: %v", section, err)
+		}
+		delete(entries, stateEntryKey{key: section})
+		for k, v := range m {
+			entries[stateEntryKey{section: section, key: k}] = v
+		}
+	}
+	return entries, nil
+}
+
+// marshalStateEntries is the reverse of stateEntries.
+func marshalStateEntries(entries map[stateEntryKey]json.RawMessage) ([]byte, error) {
+	top := make(map[string]interface{})
+	for _, section := range stateWALSections {
+		top[section] = map[string]json.RawMessage{}
+	}
+	for k, v := range entries {
+		if k.section == "" {
+			top[k.key] = v
+			continue
+		}
+		top[k.section].(map[string]json.RawMessage)[k.key] = v
+	}
+	return json.Marshal(top)
+}
+
+var writeStateWALLine = writeStateWALLineImpl
+
+func writeStateWALLineImpl(w io.Writer, doc interface{}) (int, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return 0, err
+	}
+	line := fmt.Sprintf("%08x %s\n", crc32.Checksum(data, crc32c), data)
+	return io.WriteString(w, line)
+}
+
+var errStateWALLineCorrupted = errors.New("checksum mismatch")
+
+// stateWALBase returns the checksum identifying the state file with the given
+// content as the base of a write-ahead log.
+func stateWALBase(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func parseStateWALLine(line []byte) ([]byte, error) {
+	if len(line) < 10 || line[8] != ' ' {
+		return nil, errStateWALLineCorrupted
+	}
+	sum, err := strconv.ParseUint(string(line[:8]), 16, 32)
+	if err != nil {
+		return nil, errStateWALLineCorrupted
+	}
+	doc := line[9:]
+	if crc32.Checksum(doc, crc32c) != uint32(sum) {
+		return nil, errStateWALLineCorrupted
+	}
+	return doc, nil
+}
+
+// readStateWAL reads the records of the write-ahead log at path. A record
+// which is incomplete or corrupted is only tolerated at the end of the log,
+// as it may have been cut short by a crash while being written, and is
+// dropped then. The log is reported as folded when it ends with a record
+// saying so; in that case it is not an error for the log to have an unknown
+// format version.
+func readStateWAL(path string) (header *stateWALHeader, records []*stateWALRecord, folded bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer f.Close()
+
+	var lines [][]byte
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, false, err
+		}
+	}
+	if len(lines) == 0 {
+		// nothing was written yet
+		return nil, nil, false, nil
+	}
+
+	var docs [][]byte
+	for i, line := range lines {
+		last := i == len(lines)-1
+		if !bytes.HasSuffix(line, []byte("\n")) {
+			// only the last line can be cut short
+			logger.Noticef("ignoring incomplete last record of state write-ahead log")
+			break
+		}
+		doc, err := parseStateWALLine(bytes.TrimSuffix(line, []byte("\n")))
+		if err != nil {
+			if last && i > 0 {
+				logger.Noticef("ignoring corrupted last record of state write-ahead log")
+				break
+			}
+			return nil, nil, false, fmt.Errorf("cannot read state write-ahead log: record %d: %v", i, err)
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return nil, nil, false, nil
+	}
+
+	// check for the folded marker first, a folded log can be ignored even
+	// if its format is not known
+	if len(docs) > 1 {
+		var marker stateWALRecord
+		if err := json.Unmarshal(docs[len(docs)-1], &marker); err == nil && marker.Folded {
+			folded = true
+		}
+	}
+
+	header = &stateWALHeader{}
+	if err := json.Unmarshal(docs[0], header); err != nil {
+		if folded {
+			return nil, nil, true, nil
+		}
+		return nil, nil, false, fmt.Errorf("cannot read state write-ahead log header: %v", err)
+	}
+	if header.Version != stateWALVersion {
+		if folded {
+			return nil, nil, true, nil
+		}
+		return nil, nil, false, fmt.Errorf("cannot use state write-ahead log with unsupported format version %d", header.Version)
+	}
+	for i, doc := range docs[1:] {
+		var rec stateWALRecord
+		if err := json.Unmarshal(doc, &rec); err != nil {
+			return nil, nil, false, fmt.Errorf("cannot decode state write-ahead log record %d: %v", i+1, err)
+		}
+		records = append(records, &rec)
+	}
+	return header, records, folded, nil
+}
+
+// replayStateWAL returns the content of the state file at statePath with the
+// records of its write-ahead log applied, if there is a log for this state
+// file which was not folded into it yet. Otherwise it returns nil.
+func replayStateWAL(statePath string) ([]byte, error) {
+	walPath := statePath + ".wal"
+	header, records, folded, err := readStateWAL(walPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if folded || len(records) == 0 {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the state file: %v", err)
+	}
+	if header.Base != stateWALBase(data) {
+		// the state file was written in full after the log, which
+		// was not marked as folded yet
+		logger.Noticef("ignoring state write-ahead log of a previous state file")
+		return nil, nil
+	}
+	entries, err := stateEntries(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the state file: %v", err)
+	}
+	for _, rec := range records {
+		for _, e := range rec.Entries {
+			k := stateEntryKey{section: e.Section, key: e.Key}
+			if e.Value == nil {
+				delete(entries, k)
+				continue
+			}
+			entries[k] = *e.Value
+		}
+	}
+	logger.Noticef("Replayed %d records of the state write-ahead log", len(records))
+	return marshalStateEntries(entries)
+}
+
+// stateWAL appends the changes to the state to a write-ahead log next to the
+// state file.
+type stateWAL struct {
+	path string
+	f    *os.File
+	size int64
+	// base is the checksum of the state file the log applies to
+	base string
+	// sums holds the checksums of the state entries as last written
+	sums map[stateEntryKey][sha256.Size]byte
+}
+
+// appendChanges appends a record with the entries of data which differ from
+// the ones last written to the log.
+func (w *stateWAL) appendChanges(data []byte) error {
+	entries, err := stateEntries(data)
+	if err != nil {
+		return err
+	}
+	var rec stateWALRecord
+	sums := make(map[stateEntryKey][sha256.Size]byte, len(entries))
+	for k, v := range entries {
+		sum := sha256.Sum256(v)
+		sums[k] = sum
+		if old, ok := w.sums[k]; ok && old == sum {
+			continue
+		}
+		v := v
+		rec.Entries = append(rec.Entries, stateWALEntry{Section: k.section, Key: k.key, Value: &v})
+	}
+	for k := range w.sums {
+		if _, ok := entries[k]; !ok {
+			rec.Entries = append(rec.Entries, stateWALEntry{Section: k.section, Key: k.key})
+		}
+	}
+	if len(rec.Entries) == 0 {
+		return nil
+	}
+	// stable order eases debugging
+	sort.Slice(rec.Entries, func(i, j int) bool {
+		a, b := rec.Entries[i], rec.Entries[j]
+		if a.Section != b.Section {
+			return a.Section < b.Section
+		}
+		return a.Key < b.Key
+	})
+
+	if w.f == nil {
+		f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		w.f = f
+		w.size = 0
+		if err := w.appendLine(stateWALHeader{Version: stateWALVersion, Base: w.base}); err != nil {
+			return err
+		}
+	}
+	if err := w.appendLine(rec); err != nil {
+		return err
+	}
+	w.sums = sums
+	return nil
+}
+
+// appendLine appends a line with doc to the log and syncs it. A line which
+// could not be written completely is cut off again, so that it does not end
+// up in the middle of the log. If that is not possible the log is dropped,
+// which makes the next checkpoint write the state file in full.
+func (w *stateWAL) appendLine(doc interface{}) error {
+	size := w.size
+	n, err := writeStateWALLine(w.f, doc)
+	w.size += int64(n)
+	if err == nil {
+		err = w.f.Sync()
+	}
+	if err == nil {
+		return nil
+	}
+	if terr := w.truncate(size); terr != nil {
+		logger.Noticef("cannot cut off incomplete record of state write-ahead log: %v", terr)
+		w.f.Close()
+		w.f = nil
+		w.size = 0
+		w.sums = nil
+	}
+	return err
+}
+
+func (w *stateWAL) truncate(size int64) error {
+	if err := w.f.Truncate(size); err != nil {
+		return err
+	}
+	if _, err := w.f.Seek(size, io.SeekStart); err != nil {
+		return err
+	}
+	w.size = size
+	return nil
+}
+
+// reset records that data was written to the state file in full, any log is
+// marked as folded and removed.
+func (w *stateWAL) reset(data []byte) error {
+	entries, err := stateEntries(data)
+	if err != nil {
+		return err
+	}
+	w.sums = make(map[stateEntryKey][sha256.Size]byte, len(entries))
+	for k, v := range entries {
+		w.sums[k] = sha256.Sum256(v)
+	}
+	w.base = stateWALBase(data)
+	return w.remove()
+}
+
+// remove marks the log, if any, as folded and removes it.
+func (w *stateWAL) remove() error {
+	if w.f == nil {
+		f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND, 0600)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		w.f = f
+	}
+	defer func() {
+		w.f.Close()
+		w.f = nil
+		w.size = 0
+	}()
+	if _, err := writeStateWALLine(w.f, stateWALRecord{Folded: true}); err != nil {
+		return err
+	}
+	if err := w.f.Sync(); err != nil {
+		return err
+	}
+	return os.Remove(w.path)
+}
@@ -0,0 +1,409 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package overlord_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/features"
+	"github.com/snapcore/snapd/overlord"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type stateWALSuite struct {
+	testutil.BaseTest
+
+	statePath string
+	walPath   string
+}
+
+var _ = Suite(&stateWALSuite{})
+
+func (s *stateWALSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.statePath = filepath.Join(c.MkDir(), "state.json")
+	s.walPath = s.statePath + ".wal"
+}
+
+func (s *stateWALSuite) set(st *state.State, key string, value interface{}) {
+	st.Lock()
+	defer st.Unlock()
+	st.Set(key, value)
+}
+
+// replayed returns the state as it would be loaded from the state file and
+// its write-ahead log.
+func (s *stateWALSuite) replayed(c *C) *state.State {
+	data, err := overlord.ReplayStateWAL(s.statePath)
+	c.Assert(err, IsNil)
+	if data == nil {
+		data, err = os.ReadFile(s.statePath)
+		c.Assert(err, IsNil)
+	}
+	st, err := state.ReadState(nil, bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	return st
+}
+
+func (s *stateWALSuite) get(c *C, st *state.State, key string) interface{} {
+	st.Lock()
+	defer st.Unlock()
+	var v interface{}
+	if err := st.Get(key, &v); err != nil {
+		c.Assert(err, testutil.ErrorIs, state.ErrNoState)
+		return nil
+	}
+	return v
+}
+
+func (s *stateWALSuite) TestAppendAndReplay(c *C) {
+	backend := overlord.NewOverlordStateBackend(s.statePath, true)
+	st := state.New(backend)
+
+	// the first checkpoint writes the state file in full
+	s.set(st, "foo", "1")
+	rewrites, _ := backend.Stats()
+	c.Check(rewrites, Equals, 1)
+	c.Check(s.walPath, testutil.FileAbsent)
+
+	// the following ones go to the log
+	s.set(st, "bar", "2")
+	s.set(st, "foo", nil)
+	st.Lock()
+	chg := st.NewChange("chg", "...")
+	chg.AddTask(st.NewTask("task", "..."))
+	st.Unlock()
+	rewrites, _ = backend.Stats()
+	c.Check(rewrites, Equals, 1)
+	c.Check(s.walPath, testutil.FilePresent)
+
+	// the state file itself is unchanged
+	data, err := os.ReadFile(s.statePath)
+	c.Assert(err, IsNil)
+	c.Check(string(data), testutil.Contains, `"foo":"1"`)
+	c.Check(string(data), Not(testutil.Contains), `"bar"`)
+
+	replayed := s.replayed(c)
+	c.Check(s.get(c, replayed, "foo"), IsNil)
+	c.Check(s.get(c, replayed, "bar"), Equals, "2")
+	replayed.Lock()
+	c.Assert(replayed.Changes(), HasLen, 1)
+	c.Check(replayed.Changes()[0].Tasks(), HasLen, 1)
+	replayed.Unlock()
+}
+
+func (s *stateWALSuite) TestOnlyChangedEntriesAppended(c *C) {
+	backend := overlord.NewOverlordStateBackend(s.statePath, true)
+	st := state.New(backend)
+
+	s.set(st, "big", strings.Repeat("x", 10000))
+	_, written := backend.Stats()
+	c.Check(written > 10000, Equals, true)
+
+	s.set(st, "small", "y")
+	_, written2 := backend.Stats()
+	// the big entry was not written again
+	c.Check(written2-written < 1000, Equals, true)
+}
+
+func (s *stateWALSuite) TestFoldWhenTooBig(c *C) {
+	defer overlord.MockStateWALLimits(100, time.Hour)()
+
+	backend := overlord.NewOverlordStateBackend(s.statePath, true)
+	st := state.New(backend)
+
+	s.set(st, "foo", "1")
+	s.set(st, "foo", strings.Repeat("x", 200))
+	c.Check(s.walPath, testutil.FilePresent)
+	rewrites, _ := backend.Stats()
+	c.Check(rewrites, Equals, 1)
+
+	// the log is too big now
+	s.set(st, "bar", "2")
+	rewrites, _ = backend.Stats()
+	c.Check(rewrites, Equals, 2)
+	c.Check(s.walPath, testutil.FileAbsent)
+	c.Check(s.get(c, s.replayed(c), "bar"), Equals, "2")
+}
+
+func (s *stateWALSuite) TestFoldAfterInterval(c *C) {
+	now := time.Now()
+	defer overlord.MockTimeNow(func() time.Time { return now })()
+
+	backend := overlord.NewOverlordStateBackend(s.statePath, true)
+	st := state.New(backend)
+
+	s.set(st, "foo", "1")
+	s.set(st, "foo", "2")
+	rewrites, _ := backend.Stats()
+	c.Check(rewrites, Equals, 1)
+
+	now = now.Add(2 * time.Hour)
+	s.set(st, "foo", "3")
+	rewrites, _ = backend.Stats()
+	c.Check(rewrites, Equals, 2)
+	c.Check(s.walPath, testutil.FileAbsent)
+}
+
+func (s *stateWALSuite) TestFoldWAL(c *C) {
+	backend := overlord.NewOverlordStateBackend(s.statePath, true)
+	st := state.New(backend)
+
+	// the initial checkpoint writes the state file in full
+	st.Lock()
+	st.Unlock()
+	st.Lock()
+	// nothing to fold
+	c.Assert(backend.FoldWAL(), IsNil)
+	st.Unlock()
+	rewrites, _ := backend.Stats()
+	c.Check(rewrites, Equals, 1)
+
+	s.set(st, "foo", "1")
+	s.set(st, "foo", "2")
+	st.Lock()
+	c.Assert(backend.FoldWAL(), IsNil)
+	st.Unlock()
+	rewrites, _ = backend.Stats()
+	c.Check(rewrites, Equals, 2)
+	c.Check(s.walPath, testutil.FileAbsent)
+	c.Check(s.statePath, testutil.FileContains, `"foo":"2"`)
+}
+
+func (s *stateWALSuite) TestDisabledRemovesLeftoverWAL(c *C) {
+	backend := overlord.NewOverlordStateBackend(s.statePath, true)
+	st := state.New(backend)
+	s.set(st, "foo", "1")
+	s.set(st, "foo", "2")
+	c.Check(s.walPath, testutil.FilePresent)
+
+	// the log is not used anymore
+	st = s.replayed(c)
+	backend = overlord.NewOverlordStateBackend(s.statePath, false)
+	st.Lock()
+	data, err := st.MarshalJSON()
+	st.Unlock()
+	c.Assert(err, IsNil)
+	c.Assert(backend.Checkpoint(data), IsNil)
+	c.Check(s.walPath, testutil.FileAbsent)
+	c.Check(s.statePath, testutil.FileContains, `"foo":"2"`)
+}
+
+func (s *stateWALSuite) writeWAL(c *C) {
+	backend := overlord.NewOverlordStateBackend(s.statePath, true)
+	st := state.New(backend)
+	s.set(st, "foo", "1")
+	s.set(st, "foo", "2")
+	s.set(st, "bar", "3")
+}
+
+func (s *stateWALSuite) TestReplayIgnoresCutShortRecord(c *C) {
+	s.writeWAL(c)
+	data, err := os.ReadFile(s.walPath)
+	c.Assert(err, IsNil)
+	// the last record was being written
+	c.Assert(os.WriteFile(s.walPath, data[:len(data)-5], 0600), IsNil)
+
+	replayed := s.replayed(c)
+	c.Check(s.get(c, replayed, "foo"), Equals, "2")
+	c.Check(s.get(c, replayed, "bar"), IsNil)
+}
+
+func (s *stateWALSuite) TestReplayIgnoresCorruptedLastRecord(c *C) {
+	s.writeWAL(c)
+	data, err := os.ReadFile(s.walPath)
+	c.Assert(err, IsNil)
+	data = bytes.Replace(data, []byte(`"3"`), []byte(`"4"`), 1)
+	c.Assert(os.WriteFile(s.walPath, data, 0600), IsNil)
+
+	replayed := s.replayed(c)
+	c.Check(s.get(c, replayed, "foo"), Equals, "2")
+	c.Check(s.get(c, replayed, "bar"), IsNil)
+}
+
+func (s *stateWALSuite) TestReplayCorruptedRecordError(c *C) {
+	s.writeWAL(c)
+	data, err := os.ReadFile(s.walPath)
+	c.Assert(err, IsNil)
+	data = bytes.Replace(data, []byte(`"2"`), []byte(`"5"`), 1)
+	c.Assert(os.WriteFile(s.walPath, data, 0600), IsNil)
+
+	_, err = overlord.ReplayStateWAL(s.statePath)
+	c.Check(err, ErrorMatches, `cannot read state write-ahead log: record 1: checksum mismatch`)
+}
+
+func (s *stateWALSuite) TestReplayIgnoresWALOfPreviousStateFile(c *C) {
+	s.writeWAL(c)
+	// the state file was written in full but the log was not marked as
+	// folded yet
+	c.Assert(os.WriteFile(s.statePath, []byte(`{"data":{"foo":"4"}}`), 0600), IsNil)
+
+	data, err := overlord.ReplayStateWAL(s.statePath)
+	c.Assert(err, IsNil)
+	c.Check(data, IsNil)
+	c.Check(s.get(c, s.replayed(c), "foo"), Equals, "4")
+}
+
+func (s *stateWALSuite) TestFailedAppendIsCutOff(c *C) {
+	backend := overlord.NewOverlordStateBackend(s.statePath, true)
+	st := state.New(backend)
+	s.set(st, "foo", "1")
+	s.set(st, "foo", "2")
+
+	st.Lock()
+	st.Set("bar", "3")
+	data, err := st.MarshalJSON()
+	c.Assert(err, IsNil)
+	restore := overlord.MockWriteStateWALLine(func(w io.Writer, doc interface{}) (int, error) {
+		n, _ := io.WriteString(w, "0123abcd {\"entr")
+		return n, errors.New("no space left on device")
+	})
+	c.Check(backend.Checkpoint(data), ErrorMatches, "no space left on device")
+	restore()
+	// checkpoints again
+	st.Unlock()
+
+	// the next record is appended after the previous complete one
+	s.set(st, "baz", "4")
+	c.Check(s.walPath, Not(testutil.FileContains), "0123abcd")
+	replayed := s.replayed(c)
+	c.Check(s.get(c, replayed, "foo"), Equals, "2")
+	c.Check(s.get(c, replayed, "bar"), Equals, "3")
+	c.Check(s.get(c, replayed, "baz"), Equals, "4")
+}
+
+func walLine(doc string) string {
+	return fmt.Sprintf("%08x %s\n", crc32.Checksum([]byte(doc), crc32.MakeTable(crc32.Castagnoli)), doc)
+}
+
+func (s *stateWALSuite) TestReplayUnknownVersion(c *C) {
+	c.Assert(os.WriteFile(s.statePath, []byte(`{"data":{"foo":"1"}}`), 0600), IsNil)
+	wal := walLine(`{"version":2}`) + walLine(`{"something":"else"}`)
+	c.Assert(os.WriteFile(s.walPath, []byte(wal), 0600), IsNil)
+
+	_, err := overlord.ReplayStateWAL(s.statePath)
+	c.Check(err, ErrorMatches, `cannot use state write-ahead log with unsupported format version 2`)
+
+	// unless it is marked as folded
+	wal += walLine(`{"folded":true}`)
+	c.Assert(os.WriteFile(s.walPath, []byte(wal), 0600), IsNil)
+	data, err := overlord.ReplayStateWAL(s.statePath)
+	c.Assert(err, IsNil)
+	c.Check(data, IsNil)
+}
+
+func (s *stateWALSuite) TestReplayFolded(c *C) {
+	s.writeWAL(c)
+	data, err := os.ReadFile(s.walPath)
+	c.Assert(err, IsNil)
+	// the log was folded but not removed
+	data = append(data, walLine(`{"folded":true}`)...)
+	c.Assert(os.WriteFile(s.walPath, data, 0600), IsNil)
+
+	data, err = overlord.ReplayStateWAL(s.statePath)
+	c.Assert(err, IsNil)
+	c.Check(data, IsNil)
+}
+
+func (ovs *overlordSuite) TestNewReplaysStateWAL(c *C) {
+	c.Assert(os.MkdirAll(dirs.FeaturesDir, 0755), IsNil)
+	c.Assert(os.WriteFile(features.StateWriteAheadLog.ControlFile(), nil, 0644), IsNil)
+
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	st := o.State()
+	st.Lock()
+	st.Set("foo", "bar")
+	st.Unlock()
+	c.Check(dirs.SnapStateFile+".wal", testutil.FilePresent)
+	c.Assert(o.Stop(), IsNil)
+	// the log was folded at shutdown
+	c.Check(dirs.SnapStateFile+".wal", testutil.FileAbsent)
+
+	o, err = overlord.New(nil)
+	c.Assert(err, IsNil)
+	st = o.State()
+	st.Lock()
+	st.Set("foo", "baz")
+	st.Unlock()
+	c.Check(dirs.SnapStateFile+".wal", testutil.FilePresent)
+
+	// as if snapd was killed
+	o.ReleaseStateLock()
+	o, err = overlord.New(nil)
+	c.Assert(err, IsNil)
+	st = o.State()
+	st.Lock()
+	defer st.Unlock()
+	var foo string
+	c.Assert(st.Get("foo", &foo), IsNil)
+	c.Check(foo, Equals, "baz")
+}
+
+func benchmarkStateCheckpoint(b *testing.B, useWAL bool) {
+	statePath := filepath.Join(b.TempDir(), "state.json")
+	backend := overlord.NewOverlordStateBackend(statePath, useWAL)
+	st := state.New(backend)
+
+	// a state of a device with a fair amount of history
+	st.Lock()
+	for i := 0; i < 200; i++ {
+		chg := st.NewChange("refresh-snap", "...")
+		for j := 0; j < 10; j++ {
+			t := st.NewTask("task", "...")
+			t.Set("snap-setup", strings.Repeat("x", 500))
+			chg.AddTask(t)
+		}
+	}
+	st.Unlock()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		st.Lock()
+		st.Set("counter", i)
+		st.Unlock()
+	}
+	b.StopTimer()
+
+	rewrites, written := backend.Stats()
+	b.ReportMetric(float64(rewrites)/float64(b.N), "rewrites/op")
+	b.ReportMetric(float64(written)/float64(b.N), "written-bytes/op")
+}
+
+func BenchmarkStateCheckpointRewrite(b *testing.B) {
+	benchmarkStateCheckpoint(b, false)
+}
+
+func BenchmarkStateCheckpointWAL(b *testing.B) {
+	benchmarkStateCheckpoint(b, true)
+}
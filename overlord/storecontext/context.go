@@ -72,6 +72,9 @@ type DeviceSessionRequestSigner interface {
 type StoreOptions interface {
 	// ProxyStore returns the store assertion for the proxy store if one is set.
 	ProxyStore() (*asserts.Store, error)
+	// ProxyStores returns the store assertions for the proxy stores,
+	// in order of preference, if any are set.
+	ProxyStores() ([]*asserts.Store, error)
 
 	// StoreOffline returns a string indicating whether the store should have
 	// network access or not
@@ -251,6 +254,40 @@ func (sc *storeContext) ProxyStoreParams(defaultURL *url.URL) (proxyStoreID stri
 	return "", defaultURL, nil
 }
 
+// ProxyStoreURLs returns the base URLs of the proxy stores, in order of
+// preference, if any are set.
+func (sc *storeContext) ProxyStoreURLs() ([]*url.URL, error) {
+	sc.state.Lock()
+	defer sc.state.Unlock()
+
+	stores, err := sc.storeOptions.ProxyStores()
+	if err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, err
+	}
+
+	urls := make([]*url.URL, 0, len(stores))
+	for _, sto := range stores {
+		if u := sto.URL(); u != nil {
+			urls = append(urls, u)
+		}
+	}
+	return urls, nil
+}
+
+// StoreURLSwitched records a warning that the store switched from
+// using the from base URL to using the to one because of reason, or
+// because the more preferred to is reachable again if reason is nil.
+func (sc *storeContext) StoreURLSwitched(from, to *url.URL, reason error) {
+	sc.state.Lock()
+	defer sc.state.Unlock()
+
+	if reason == nil {
+		sc.state.Warnf("store at %s is reachable again, switched back to using it instead of %s", to, from)
+		return
+	}
+	sc.state.Warnf("store at %s is not reachable, switched to using %s instead: %v", from, to, reason)
+}
+
 func (sc *storeContext) StoreOffline() (bool, error) {
 	sc.state.Lock()
 	defer sc.state.Unlock()
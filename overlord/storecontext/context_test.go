@@ -371,6 +371,14 @@ func (b *testBackend) ProxyStore() (*asserts.Store, error) {
 	return a.(*asserts.Store), nil
 }
 
+func (b *testBackend) ProxyStores() ([]*asserts.Store, error) {
+	sto, err := b.ProxyStore()
+	if err != nil {
+		return nil, err
+	}
+	return []*asserts.Store{sto}, nil
+}
+
 func (b *testBackend) StoreOffline() (bool, error) {
 	if b.nothing {
 		return false, state.ErrNoState
@@ -394,6 +402,10 @@ func (s *storeCtxSuite) TestMissingDeviceAssertions(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(proxyStoreID, Equals, "")
 	c.Check(proxyStoreURL, Equals, s.defURL)
+
+	proxyStoreURLs, err := storeCtx.ProxyStoreURLs()
+	c.Assert(err, IsNil)
+	c.Check(proxyStoreURLs, HasLen, 0)
 }
 
 func (s *storeCtxSuite) TestWithDeviceAssertions(c *C) {
@@ -430,6 +442,33 @@ func (s *storeCtxSuite) TestWithDeviceAssertions(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(proxyStoreID, Equals, "foo")
 	c.Check(proxyStoreURL, DeepEquals, fooURL)
+
+	proxyStoreURLs, err := storeCtx.ProxyStoreURLs()
+	c.Assert(err, IsNil)
+	c.Check(proxyStoreURLs, DeepEquals, []*url.URL{fooURL})
+}
+
+func (s *storeCtxSuite) TestStoreURLSwitched(c *C) {
+	storeCtx := storecontext.New(s.state, &testBackend{})
+
+	fooURL, err := url.Parse("http://foo.internal")
+	c.Assert(err, IsNil)
+	storeURL, err := url.Parse("http://store")
+	c.Assert(err, IsNil)
+
+	storeCtx.StoreURLSwitched(fooURL, storeURL, errors.New("connection refused"))
+	storeCtx.StoreURLSwitched(storeURL, fooURL, nil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	var msgs []string
+	for _, w := range s.state.AllWarnings() {
+		msgs = append(msgs, w.String())
+	}
+	c.Check(msgs, DeepEquals, []string{
+		"store at http://foo.internal is not reachable, switched to using http://store instead: connection refused",
+		"store at http://foo.internal is reachable again, switched back to using it instead of http://store",
+	})
 }
 
 func (s *storeCtxSuite) TestWithDeviceAssertionsGenericClassicModel(c *C) {
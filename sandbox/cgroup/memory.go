@@ -38,6 +38,17 @@ var cgroupsFilePath = "/proc/cgroups"
 // 0 => false => disabled
 // 1 => true => enabled
 func CheckMemoryCgroup() error {
+	return checkCgroupControllerEnabled("memory")
+}
+
+// CheckCpusetCgroup checks if the cpuset cgroup is enabled, which is
+// needed to restrict the set of CPUs a group of processes can use. It
+// will return an error if not. See CheckMemoryCgroup for details.
+func CheckCpusetCgroup() error {
+	return checkCgroupControllerEnabled("cpuset")
+}
+
+func checkCgroupControllerEnabled(controller string) error {
 	cgroupsFile, err := os.Open(cgroupsFilePath)
 	if err != nil {
 		return fmt.Errorf("cannot open cgroups file: %v", err)
@@ -47,15 +58,15 @@ func CheckMemoryCgroup() error {
 	scanner := bufio.NewScanner(cgroupsFile)
 	for scanner.Scan() {
 		line := scanner.Text()
-		if strings.HasPrefix(line, "memory\t") {
-			memoryCgroupValues := strings.Fields(line)
-			if len(memoryCgroupValues) < 4 {
+		if strings.HasPrefix(line, controller+"\t") {
+			cgroupValues := strings.Fields(line)
+			if len(cgroupValues) < 4 {
 				// change in size, should investigate the new structure
 				return fmt.Errorf("cannot parse cgroups file: invalid line %q", line)
 			}
-			isMemoryEnabled := memoryCgroupValues[3] == "1"
-			if !isMemoryEnabled {
-				return fmt.Errorf("memory cgroup is disabled on this system")
+			isEnabled := cgroupValues[3] == "1"
+			if !isEnabled {
+				return fmt.Errorf("%s cgroup is disabled on this system", controller)
 			}
 			return nil
 		}
@@ -65,6 +76,6 @@ func CheckMemoryCgroup() error {
 		return fmt.Errorf("cannot read %s contents: %v", cgroupsFilePath, err)
 	}
 
-	// no errors so far but the only path here is the cgroups file without the memory line
-	return fmt.Errorf("cannot find memory cgroup in %s", cgroupsFilePath)
+	// no errors so far but the only path here is the cgroups file without the controller line
+	return fmt.Errorf("cannot find %s cgroup in %s", controller, cgroupsFilePath)
 }
@@ -95,3 +95,32 @@ func (s *memorySuite) TestCheckMemoryCgroupDisabled(c *C) {
 	err = cgroup.CheckMemoryCgroup()
 	c.Assert(err, ErrorMatches, "memory cgroup is disabled on this system")
 }
+
+func (s *memorySuite) TestCheckCpusetCgroupHappy(c *C) {
+	err := os.WriteFile(s.mockCgroupsFile, []byte(cgroupContentCommon), 0644)
+	c.Assert(err, IsNil)
+	err = cgroup.CheckCpusetCgroup()
+	c.Assert(err, IsNil)
+}
+
+func (s *memorySuite) TestCheckCpusetCgroupNoCpusetEntry(c *C) {
+	content := `#subsys_name	hierarchy	num_cgroups	enabled
+cpu	3	133	1
+memory	2	223	1`
+
+	err := os.WriteFile(s.mockCgroupsFile, []byte(content), 0644)
+	c.Assert(err, IsNil)
+	err = cgroup.CheckCpusetCgroup()
+	c.Assert(err, ErrorMatches, "cannot find cpuset cgroup in .*/cgroups")
+}
+
+func (s *memorySuite) TestCheckCpusetCgroupDisabled(c *C) {
+	content := `#subsys_name	hierarchy	num_cgroups	enabled
+cpuset	6	3	0
+cpu	3	133	1`
+
+	err := os.WriteFile(s.mockCgroupsFile, []byte(content), 0644)
+	c.Assert(err, IsNil)
+	err = cgroup.CheckCpusetCgroup()
+	c.Assert(err, ErrorMatches, "cpuset cgroup is disabled on this system")
+}
@@ -231,6 +231,12 @@ func MockSbTPMDictionaryAttackLockReset(f func(tpm *sb_tpm2.Connection, lockCont
 	return restore
 }
 
+func MockTPMGetCapabilityTPMProperties(f func(tpm *sb_tpm2.Connection, first tpm2.Property, count uint32) (tpm2.TaggedTPMPropertyList, error)) (restore func()) {
+	restore = testutil.Backup(&tpmGetCapabilityTPMProperties)
+	tpmGetCapabilityTPMProperties = f
+	return restore
+}
+
 func MockSbLockoutAuthSet(f func(tpm *sb_tpm2.Connection) bool) (restore func()) {
 	restore = testutil.Backup(&lockoutAuthSet)
 	lockoutAuthSet = f
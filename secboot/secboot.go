@@ -26,6 +26,8 @@ package secboot
 
 import (
 	"crypto/ecdsa"
+	"errors"
+	"time"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/bootloader"
@@ -195,6 +197,39 @@ type UnlockResult struct {
 	UnlockMethod UnlockMethod
 }
 
+// TPMLockoutStatus is the state of the dictionary attack (DA) protection of
+// the TPM.
+type TPMLockoutStatus struct {
+	// InLockout is set when the TPM refuses the authorizations subject to
+	// the DA protection, like the ones unsealing the encryption keys.
+	InLockout bool
+	// FailedTries is the number of authorization failures counted by the
+	// TPM.
+	FailedTries uint32
+	// MaxTries is the number of authorization failures after which the
+	// TPM enters lockout.
+	MaxTries uint32
+	// LockoutInterval is the time after which an authorization failure is
+	// forgotten by the TPM.
+	LockoutInterval time.Duration
+	// LockoutRecovery is the time after which the lockout authorization
+	// can be used again after it failed.
+	LockoutRecovery time.Duration
+}
+
+// RemainingTries returns the number of authorization failures left before
+// the TPM enters lockout.
+func (s *TPMLockoutStatus) RemainingTries() uint32 {
+	if s.InLockout || s.FailedTries >= s.MaxTries {
+		return 0
+	}
+	return s.MaxTries - s.FailedTries
+}
+
+// ErrNoTPMLockoutAuth is returned when the TPM lockout authorization stored
+// at install time is not available.
+var ErrNoTPMLockoutAuth = errors.New("TPM lockout authorization is not available")
+
 // EncryptedPartitionName returns the name/label used by an encrypted partition
 // corresponding to a given name.
 func EncryptedPartitionName(name string) string {
@@ -59,3 +59,11 @@ func ReleasePCRResourceHandles(handles ...uint32) error {
 func resetLockoutCounter(lockoutAuthFile string) error {
 	return errBuildWithoutSecboot
 }
+
+func GetTPMLockoutStatus() (*TPMLockoutStatus, error) {
+	return nil, errBuildWithoutSecboot
+}
+
+func ResetTPMLockout(lockoutAuthFile string) error {
+	return errBuildWithoutSecboot
+}
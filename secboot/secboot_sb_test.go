@@ -31,6 +31,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"time"
 
 	"github.com/canonical/go-tpm2"
 	"github.com/canonical/go-tpm2/linux"
@@ -2206,3 +2207,96 @@ func (s *secbootSuite) testMarkSuccessfulEncrypted(c *C, sealingMethod device.Se
 
 	c.Check(daLockResetCalls, Equals, expectedDaLockResetCalls)
 }
+
+func (s *secbootSuite) TestGetTPMLockoutStatus(c *C) {
+	conn, restore := mockSbTPMConnection(c, nil)
+	defer restore()
+
+	for _, tc := range []struct {
+		permanent   tpm2.PermanentAttributes
+		failedTries uint32
+		remaining   uint32
+	}{
+		{0, 0, 32},
+		{0, 3, 29},
+		{tpm2.AttrInLockout, 32, 0},
+	} {
+		restore = secboot.MockTPMGetCapabilityTPMProperties(func(tpm *sb_tpm2.Connection, first tpm2.Property, count uint32) (tpm2.TaggedTPMPropertyList, error) {
+			c.Check(tpm, Equals, conn)
+			c.Check(first, Equals, tpm2.PropertyPermanent)
+			c.Check(count, Equals, uint32(tpm2.PropertyLockoutRecovery-tpm2.PropertyPermanent+1))
+			return tpm2.TaggedTPMPropertyList{
+				{Property: tpm2.PropertyPermanent, Value: uint32(tc.permanent)},
+				{Property: tpm2.PropertyLockoutCounter, Value: tc.failedTries},
+				{Property: tpm2.PropertyMaxAuthFail, Value: 32},
+				{Property: tpm2.PropertyLockoutInterval, Value: 7200},
+				{Property: tpm2.PropertyLockoutRecovery, Value: 86400},
+			}, nil
+		})
+		defer restore()
+
+		status, err := secboot.GetTPMLockoutStatus()
+		c.Assert(err, IsNil)
+		c.Check(status, DeepEquals, &secboot.TPMLockoutStatus{
+			InLockout:       tc.permanent&tpm2.AttrInLockout != 0,
+			FailedTries:     tc.failedTries,
+			MaxTries:        32,
+			LockoutInterval: 2 * time.Hour,
+			LockoutRecovery: 24 * time.Hour,
+		})
+		c.Check(status.RemainingTries(), Equals, tc.remaining)
+	}
+}
+
+func (s *secbootSuite) TestGetTPMLockoutStatusErrors(c *C) {
+	_, restore := mockSbTPMConnection(c, fmt.Errorf("no tpm"))
+	defer restore()
+
+	_, err := secboot.GetTPMLockoutStatus()
+	c.Check(err, ErrorMatches, "cannot connect to TPM: no tpm")
+
+	_, restore = mockSbTPMConnection(c, nil)
+	defer restore()
+
+	restore = secboot.MockTPMGetCapabilityTPMProperties(func(tpm *sb_tpm2.Connection, first tpm2.Property, count uint32) (tpm2.TaggedTPMPropertyList, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	defer restore()
+	_, err = secboot.GetTPMLockoutStatus()
+	c.Check(err, ErrorMatches, "cannot obtain TPM properties: boom")
+
+	restore = secboot.MockTPMGetCapabilityTPMProperties(func(tpm *sb_tpm2.Connection, first tpm2.Property, count uint32) (tpm2.TaggedTPMPropertyList, error) {
+		return tpm2.TaggedTPMPropertyList{
+			{Property: tpm2.PropertyPermanent, Value: 0},
+			{Property: tpm2.PropertyMaxAuthFail, Value: 32},
+		}, nil
+	})
+	defer restore()
+	_, err = secboot.GetTPMLockoutStatus()
+	c.Check(err, ErrorMatches, "cannot obtain TPM property 0x20e")
+}
+
+func (s *secbootSuite) TestResetTPMLockout(c *C) {
+	_, restore := mockSbTPMConnection(c, nil)
+	defer restore()
+
+	daLockResetCalls := 0
+	restore = secboot.MockSbTPMDictionaryAttackLockReset(func(tpm *sb_tpm2.Connection, lockContext tpm2.ResourceContext, lockContextAuthSession tpm2.SessionContext, sessions ...tpm2.SessionContext) error {
+		daLockResetCalls++
+		return nil
+	})
+	defer restore()
+
+	lockoutAuthFile := filepath.Join(c.MkDir(), "tpm-lockout-auth")
+
+	// no lockout auth
+	err := secboot.ResetTPMLockout(lockoutAuthFile)
+	c.Check(err, Equals, secboot.ErrNoTPMLockoutAuth)
+	c.Check(daLockResetCalls, Equals, 0)
+
+	err = os.WriteFile(lockoutAuthFile, []byte("tpm-lockout-auth-key"), 0600)
+	c.Assert(err, IsNil)
+	err = secboot.ResetTPMLockout(lockoutAuthFile)
+	c.Check(err, IsNil)
+	c.Check(daLockResetCalls, Equals, 1)
+}
@@ -28,6 +28,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/canonical/go-tpm2"
 	"github.com/canonical/go-tpm2/mu"
@@ -73,6 +74,9 @@ var (
 	tpmReleaseResources                 = tpmReleaseResourcesImpl
 
 	sbTPMDictionaryAttackLockReset = (*sb_tpm2.Connection).DictionaryAttackLockReset
+	tpmGetCapabilityTPMProperties  = func(tpm *sb_tpm2.Connection, first tpm2.Property, count uint32) (tpm2.TaggedTPMPropertyList, error) {
+		return tpm.GetCapabilityTPMProperties(first, count)
+	}
 
 	// check whether the interfaces match
 	_ (sb.SnapModel) = ModelForSealing(nil)
@@ -715,3 +719,48 @@ func resetLockoutCounter(lockoutAuthFile string) error {
 
 	return nil
 }
+
+// GetTPMLockoutStatus returns the state of the dictionary attack protection
+// of the TPM.
+func GetTPMLockoutStatus() (*TPMLockoutStatus, error) {
+	tpm, err := sbConnectToDefaultTPM()
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to TPM: %v", err)
+	}
+	defer tpm.Close()
+
+	// the properties from TPM_PT_PERMANENT to TPM_PT_LOCKOUT_RECOVERY
+	first := tpm2.PropertyPermanent
+	count := uint32(tpm2.PropertyLockoutRecovery - tpm2.PropertyPermanent + 1)
+	props, err := tpmGetCapabilityTPMProperties(tpm, first, count)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain TPM properties: %v", err)
+	}
+	values := make(map[tpm2.Property]uint32, len(props))
+	for _, prop := range props {
+		values[prop.Property] = prop.Value
+	}
+	for _, p := range []tpm2.Property{tpm2.PropertyPermanent, tpm2.PropertyLockoutCounter, tpm2.PropertyMaxAuthFail} {
+		if _, ok := values[p]; !ok {
+			return nil, fmt.Errorf("cannot obtain TPM property %#x", uint32(p))
+		}
+	}
+	permanent := tpm2.PermanentAttributes(values[tpm2.PropertyPermanent])
+	return &TPMLockoutStatus{
+		InLockout:       permanent&tpm2.AttrInLockout != 0,
+		FailedTries:     values[tpm2.PropertyLockoutCounter],
+		MaxTries:        values[tpm2.PropertyMaxAuthFail],
+		LockoutInterval: time.Duration(values[tpm2.PropertyLockoutInterval]) * time.Second,
+		LockoutRecovery: time.Duration(values[tpm2.PropertyLockoutRecovery]) * time.Second,
+	}, nil
+}
+
+// ResetTPMLockout resets the dictionary attack lockout of the TPM, using the
+// lockout authorization from the given file, which is stored there at install
+// time. ErrNoTPMLockoutAuth is returned if the file does not exist.
+func ResetTPMLockout(lockoutAuthFile string) error {
+	if !osutil.FileExists(lockoutAuthFile) {
+		return ErrNoTPMLockoutAuth
+	}
+	return resetLockoutCounter(lockoutAuthFile)
+}
@@ -0,0 +1,116 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package internal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/naming"
+)
+
+// Component20 carries the details of a component of a seed snap.
+type Component20 struct {
+	Name string `yaml:"name"`
+	// Revision is reserved for asserted components, which are not
+	// supported yet, the files of components are found in the snaps
+	// directory of the system
+	Revision snap.Revision `yaml:"revision,omitempty"`
+	// File has the filename of the component
+	File string `yaml:"file"`
+}
+
+// SnapComponents20 lists the components of a seed snap.
+type SnapComponents20 struct {
+	Snap       string         `yaml:"snap"`
+	Components []*Component20 `yaml:"components"`
+}
+
+type Components20 struct {
+	Snaps []*SnapComponents20 `yaml:"snaps"`
+}
+
+func ReadComponents20(componentsFn string) (*Components20, error) {
+	errPrefix := "cannot read components yaml"
+
+	yamlData, err := ioutil.ReadFile(componentsFn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", errPrefix, err)
+	}
+
+	var components Components20
+	if err := yaml.Unmarshal(yamlData, &components); err != nil {
+		return nil, fmt.Errorf("%s: cannot unmarshal %q: %s", errPrefix, yamlData, err)
+	}
+
+	seenSnaps := make(map[string]bool, len(components.Snaps))
+	// validate
+	for _, sn := range components.Snaps {
+		if sn == nil {
+			return nil, fmt.Errorf("%s: empty snaps element", errPrefix)
+		}
+		if err := naming.ValidateSnap(sn.Snap); err != nil {
+			return nil, fmt.Errorf("%s: %v", errPrefix, err)
+		}
+		if seenSnaps[sn.Snap] {
+			return nil, fmt.Errorf("%s: snap name %q must be unique", errPrefix, sn.Snap)
+		}
+		seenSnaps[sn.Snap] = true
+
+		seenComps := make(map[string]bool, len(sn.Components))
+		for _, comp := range sn.Components {
+			if comp == nil {
+				return nil, fmt.Errorf("%s: empty components element of snap %q", errPrefix, sn.Snap)
+			}
+			// component names follow the snap names rules
+			if err := naming.ValidateSnap(comp.Name); err != nil {
+				return nil, fmt.Errorf("%s: invalid component name of snap %q: %v", errPrefix, sn.Snap, err)
+			}
+			if seenComps[comp.Name] {
+				return nil, fmt.Errorf("%s: component name %q of snap %q must be unique", errPrefix, comp.Name, sn.Snap)
+			}
+			seenComps[comp.Name] = true
+			if comp.File == "" {
+				return nil, fmt.Errorf("%s: file of component %q of snap %q must be set", errPrefix, comp.Name, sn.Snap)
+			}
+			if strings.Contains(comp.File, "/") {
+				return nil, fmt.Errorf("%s: %q must be a filename, not a path", errPrefix, comp.File)
+			}
+		}
+	}
+
+	return &components, nil
+}
+
+func (components *Components20) Write(componentsFn string) error {
+	data, err := yaml.Marshal(components)
+	if err != nil {
+		return err
+	}
+	if err := osutil.AtomicWriteFile(componentsFn, data, 0644, 0); err != nil {
+		return err
+	}
+	return nil
+}
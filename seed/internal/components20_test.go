@@ -0,0 +1,178 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2023 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/seed/internal"
+	"github.com/snapcore/snapd/snap"
+)
+
+type components20Suite struct{}
+
+var _ = Suite(&components20Suite{})
+
+var mockComponents20 = []byte(`
+snaps:
+ - snap: pc-kernel
+   components:
+    - name: kmod
+      revision: 3
+      file: pc-kernel+kmod_3.comp
+    - name: local-kmod
+      file: pc-kernel+local-kmod_1.0.comp
+`)
+
+func (s *components20Suite) TestSimple(c *C) {
+	fn := filepath.Join(c.MkDir(), "components.yaml")
+	err := os.WriteFile(fn, mockComponents20, 0644)
+	c.Assert(err, IsNil)
+
+	components20, err := internal.ReadComponents20(fn)
+	c.Assert(err, IsNil)
+	c.Assert(components20.Snaps, DeepEquals, []*internal.SnapComponents20{{
+		Snap: "pc-kernel",
+		Components: []*internal.Component20{{
+			Name:     "kmod",
+			Revision: snap.R(3),
+			File:     "pc-kernel+kmod_3.comp",
+		}, {
+			Name: "local-kmod",
+			File: "pc-kernel+local-kmod_1.0.comp",
+		}},
+	}})
+}
+
+func (s *components20Suite) TestWriteRoundTrip(c *C) {
+	fn := filepath.Join(c.MkDir(), "components.yaml")
+	components20 := &internal.Components20{
+		Snaps: []*internal.SnapComponents20{{
+			Snap: "pc-kernel",
+			Components: []*internal.Component20{{
+				Name:     "kmod",
+				Revision: snap.R(3),
+				File:     "pc-kernel+kmod_3.comp",
+			}, {
+				Name: "local-kmod",
+				File: "pc-kernel+local-kmod_1.0.comp",
+			}},
+		}},
+	}
+	c.Assert(components20.Write(fn), IsNil)
+
+	read, err := internal.ReadComponents20(fn)
+	c.Assert(err, IsNil)
+	c.Check(read, DeepEquals, components20)
+
+	// the revision of unasserted components is omitted
+	data, err := os.ReadFile(fn)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `snaps:
+- snap: pc-kernel
+  components:
+  - name: kmod
+    revision: "3"
+    file: pc-kernel+kmod_3.comp
+  - name: local-kmod
+    file: pc-kernel+local-kmod_1.0.comp
+`)
+}
+
+func (s *components20Suite) TestValidateUnhappy(c *C) {
+	for _, tc := range []struct {
+		data string
+		err  string
+	}{{
+		data: `
+snaps:
+ -
+`,
+		err: `empty snaps element`,
+	}, {
+		data: `
+snaps:
+ - snap: foo_1
+`,
+		err: `invalid snap name: "foo_1"`,
+	}, {
+		data: `
+snaps:
+ - snap: foo
+ - snap: foo
+`,
+		err: `snap name "foo" must be unique`,
+	}, {
+		data: `
+snaps:
+ - snap: foo
+   components:
+    -
+`,
+		err: `empty components element of snap "foo"`,
+	}, {
+		data: `
+snaps:
+ - snap: foo
+   components:
+    - name: invalid--name
+      file: foo+comp.comp
+`,
+		err: `invalid component name of snap "foo": invalid snap name: "invalid--name"`,
+	}, {
+		data: `
+snaps:
+ - snap: foo
+   components:
+    - name: comp
+      file: foo+comp_1.comp
+    - name: comp
+      file: foo+comp_2.comp
+`,
+		err: `component name "comp" of snap "foo" must be unique`,
+	}, {
+		data: `
+snaps:
+ - snap: foo
+   components:
+    - name: comp
+`,
+		err: `file of component "comp" of snap "foo" must be set`,
+	}, {
+		data: `
+snaps:
+ - snap: foo
+   components:
+    - name: comp
+      file: ../foo+comp.comp
+`,
+		err: `"../foo\+comp.comp" must be a filename, not a path`,
+	}} {
+		fn := filepath.Join(c.MkDir(), "components.yaml")
+		err := os.WriteFile(fn, []byte(tc.data), 0644)
+		c.Assert(err, IsNil)
+
+		_, err = internal.ReadComponents20(fn)
+		c.Check(err, ErrorMatches, "cannot read components yaml: "+tc.err, Commentf(tc.data))
+	}
+}
@@ -20,8 +20,15 @@
 package internal
 
 import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"fmt"
+
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap/naming"
+	"github.com/snapcore/snapd/strutil"
 )
 
 func MakeSystemSnap(snapName string, defaultChannel string, modes []string) *asserts.ModelSnap {
@@ -34,3 +41,46 @@ func MakeSystemSnap(snapName string, defaultChannel string, modes []string) *ass
 		Presence:       "required",
 	}
 }
+
+// PreseedArtifactName is the name of the preseed artifact file in a
+// UC20+ system directory.
+const PreseedArtifactName = "preseed.tgz"
+
+// CheckPreseedAssertion checks that the preseed assertion was signed by
+// an authority allowed by the model and that it matches the model and
+// the system label.
+func CheckPreseedAssertion(preseedAs *asserts.Preseed, model *asserts.Model, sysLabel string) error {
+	if !strutil.ListContains(model.PreseedAuthority(), preseedAs.AuthorityID()) {
+		return fmt.Errorf("preseed authority-id %q is not allowed by the model", preseedAs.AuthorityID())
+	}
+
+	switch {
+	case preseedAs.SystemLabel() != sysLabel:
+		return fmt.Errorf("preseed assertion system label %q doesn't match system label %q", preseedAs.SystemLabel(), sysLabel)
+	case preseedAs.Model() != model.Model():
+		return fmt.Errorf("preseed assertion model %q doesn't match the model %q", preseedAs.Model(), model.Model())
+	case preseedAs.BrandID() != model.BrandID():
+		return fmt.Errorf("preseed assertion brand %q doesn't match model brand %q", preseedAs.BrandID(), model.BrandID())
+	case preseedAs.Series() != model.Series():
+		return fmt.Errorf("preseed assertion series %q doesn't match model series %q", preseedAs.Series(), model.Series())
+	}
+	return nil
+}
+
+// CheckPreseedArtifact checks that the digest of the preseed artifact
+// at artifactPath matches the one recorded in the preseed assertion.
+func CheckPreseedArtifact(artifactPath string, preseedAs *asserts.Preseed) error {
+	sha3_384, _, err := osutil.FileDigest(artifactPath, crypto.SHA3_384)
+	if err != nil {
+		return fmt.Errorf("cannot calculate preseed artifact digest: %v", err)
+	}
+
+	digest, err := base64.RawURLEncoding.DecodeString(preseedAs.ArtifactSHA3_384())
+	if err != nil {
+		return fmt.Errorf("cannot decode preseed artifact digest")
+	}
+	if !bytes.Equal(sha3_384, digest) {
+		return fmt.Errorf("invalid preseed artifact digest")
+	}
+	return nil
+}
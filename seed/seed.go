@@ -35,6 +35,10 @@ var (
 	ErrNoAssertions       = errors.New("no seed assertions")
 	ErrNoPreseedAssertion = errors.New("no seed preseed assertion")
 	ErrNoMeta             = errors.New("no seed metadata")
+	// ErrIncompleteSystem is returned when loading a UC20+ system whose
+	// creation was not completed, as indicated by the system directory
+	// lacking the model assertion file which is written last.
+	ErrIncompleteSystem = errors.New("incomplete seed system")
 
 	open = Open
 )
@@ -56,6 +60,18 @@ type Snap struct {
 	Channel string
 	DevMode bool
 	Classic bool
+
+	// Components of the snap which are present in the seed.
+	Components []Component
+}
+
+// Component holds the details of a component of a seed snap.
+type Component struct {
+	// Name of the component, without the snap name.
+	Name string
+	Path string
+	// Revision of the component, unset for unasserted components.
+	Revision snap.Revision
 }
 
 func (s *Snap) SnapName() string {
@@ -190,6 +206,21 @@ type AutoImportAssertionsLoaderSeed interface {
 	LoadAutoImportAssertions(commitTo func(*asserts.Batch) error) error
 }
 
+// A RemainingMetaLoaderSeed can load the seed snaps' metadata in two
+// steps: first only the essential snaps via LoadEssentialMeta, and
+// then, if and when needed, the remaining snaps via LoadRemaining
+// without reopening the seed. This lets callers that only mount the
+// essential snaps avoid reading and verifying the other ones.
+type RemainingMetaLoaderSeed interface {
+	Seed
+	// LoadRemaining loads the metadata for all the snaps of the
+	// seed for all modes, reusing the already verified essential
+	// snaps. Afterwards the seed behaves as if LoadMeta was
+	// called with AllModes. It returns an error if
+	// LoadEssentialMeta was not called successfully before.
+	LoadRemaining(tm timings.Measurer) error
+}
+
 // PreseedCapable seeds can support preseeding data in them.
 type PreseedCapable interface {
 	Seed
@@ -197,8 +228,16 @@ type PreseedCapable interface {
 	HasArtifact(relName string) bool
 	// ArtifactPath returns the path of an artifact file in the seed.
 	ArtifactPath(relName string) string
+	// HasPreseedArtifact returns whether the preseed.tgz artifact
+	// is present in the seed.
+	HasPreseedArtifact() bool
+	// PreseedArtifactPath returns the path of the preseed.tgz
+	// artifact in the seed.
+	PreseedArtifactPath() string
 	// LoadPreesdAssertion tries to load the preseed assertion from the seed
 	// if any. It returns ErrNoPressedAssertion if there is none.
+	// If the preseed.tgz artifact is present its digest is checked
+	// against the one recorded in the assertion.
 	// It will panic if called before LoadAssertions.
 	// Any assertion will be committed using the commitTo provided
 	// to LoadAssertions.
@@ -67,6 +67,9 @@ type seed20 struct {
 
 	auxInfos map[string]*internal.AuxInfo20
 
+	// components of the seed snaps by snap name
+	components map[string][]*internal.Component20
+
 	metaFilesLoaded bool
 
 	snapsToConsiderCh chan snapToConsider
@@ -74,6 +77,15 @@ type seed20 struct {
 	essCache   map[string]*Snap
 	essCacheMu sync.Mutex
 
+	// onSnapLoadError, if set, is given the errors about loading
+	// individual snaps, which are then skipped instead of stopping
+	// the loading, this is used by Validate
+	onSnapLoadError func(err error)
+
+	// essentialMetaLoaded is set once only the essential snaps
+	// metadata has been loaded via LoadEssentialMeta*
+	essentialMetaLoaded bool
+
 	mode string
 
 	snaps []*Snap
@@ -92,6 +104,12 @@ func (s *seed20) LoadAssertions(db asserts.RODatabase, commitTo func(*asserts.Ba
 		}
 	}
 
+	modelFn := filepath.Join(s.systemDir, "model")
+	if !osutil.FileExists(modelFn) && osutil.IsDirectory(s.systemDir) {
+		// the model is written last when creating a system
+		return ErrIncompleteSystem
+	}
+
 	assertsDir := filepath.Join(s.systemDir, "assertions")
 	// collect assertions that are not the model
 	var declRefs []*asserts.Ref
@@ -113,7 +131,7 @@ func (s *seed20) LoadAssertions(db asserts.RODatabase, commitTo func(*asserts.Ba
 		return err
 	}
 
-	refs, err := readAsserts(batch, filepath.Join(s.systemDir, "model"))
+	refs, err := readAsserts(batch, modelFn)
 	if err != nil {
 		return fmt.Errorf("cannot read model assertion: %v", err)
 	}
@@ -259,6 +277,50 @@ func (s *seed20) loadAuxInfos() error {
 	return nil
 }
 
+func (s *seed20) loadComponents() error {
+	componentsFn := filepath.Join(s.systemDir, "components.yaml")
+	if !osutil.FileExists(componentsFn) {
+		// missing
+		return nil
+	}
+	components20, err := internal.ReadComponents20(componentsFn)
+	if err != nil {
+		return err
+	}
+	s.components = make(map[string][]*internal.Component20, len(components20.Snaps))
+	for _, sn := range components20.Snaps {
+		s.components[sn.Snap] = sn.Components
+	}
+	return nil
+}
+
+// lookupComponents returns the components of the given snap, whose files are
+// found in the snaps directory of the system. Asserted components cannot be
+// verified without snap resource assertions, which are not supported yet, and
+// are refused.
+func (s *seed20) lookupComponents(snapName string) ([]Component, error) {
+	comps := s.components[snapName]
+	if len(comps) == 0 {
+		return nil, nil
+	}
+	seedComps := make([]Component, 0, len(comps))
+	for _, comp := range comps {
+		if !comp.Revision.Unset() {
+			return nil, fmt.Errorf("cannot use asserted component %q of snap %q: snap resource assertions are not supported yet", comp.Name, snapName)
+		}
+		path := filepath.Join(s.systemDir, "snaps", comp.File)
+		if !osutil.FileExists(path) {
+			return nil, fmt.Errorf("cannot find component %q of snap %q: %q does not exist", comp.Name, snapName, path)
+		}
+		seedComps = append(seedComps, Component{
+			Name:     comp.Name,
+			Path:     path,
+			Revision: comp.Revision,
+		})
+	}
+	return seedComps, nil
+}
+
 type noSnapDeclarationError struct {
 	snapRef naming.SnapRef
 }
@@ -436,6 +498,10 @@ func (s *seed20) doLoadMetaOne(sntoc *snapToConsider, handler SnapHandler, tm ti
 	seedSnap.Essential = essential
 	seedSnap.Required = required
 	seedSnap.Classic = classic
+	seedSnap.Components, err = s.lookupComponents(seedSnap.SnapName())
+	if err != nil {
+		return nil, err
+	}
 	if essential {
 		if sntoc.modelSnap.SnapType == "gadget" {
 			// validity
@@ -526,6 +592,10 @@ func (s *seed20) doLoadMeta(handler SnapHandler, tm timings.Measurer) error {
 						if err == errSkipped {
 							continue
 						}
+						if s.onSnapLoadError != nil {
+							s.onSnapLoadError(err)
+							continue
+						}
 						outcomesCh <- err
 						return
 					}
@@ -646,9 +716,21 @@ func (s *seed20) LoadEssentialMetaWithSnapHandler(essentialTypes []snap.Type, ha
 		return fmt.Errorf("model does not specify all the requested essential snaps: %v", essentialTypes)
 	}
 
+	s.essentialMetaLoaded = true
 	return nil
 }
 
+// LoadRemaining loads the metadata for all the snaps of the seed that
+// were not loaded by a previous LoadEssentialMeta call. Essential
+// snaps that were already loaded and verified are not read again,
+// unless a SnapHandler was used to load them.
+func (s *seed20) LoadRemaining(tm timings.Measurer) error {
+	if !s.essentialMetaLoaded {
+		return fmt.Errorf("cannot load remaining seed snaps metadata before the essential snaps metadata")
+	}
+	return s.LoadMeta(AllModes, nil, tm)
+}
+
 func (s *seed20) loadMetaFiles() error {
 	if s.metaFilesLoaded {
 		return nil
@@ -662,6 +744,10 @@ func (s *seed20) loadMetaFiles() error {
 		return err
 	}
 
+	if err := s.loadComponents(); err != nil {
+		return err
+	}
+
 	s.metaFilesLoaded = true
 	return nil
 }
@@ -672,6 +758,7 @@ func (s *seed20) resetSnaps() {
 	s.snaps = nil
 	s.modes = nil
 	s.essentialSnapsNum = 0
+	s.essentialMetaLoaded = false
 }
 
 func (s *seed20) queueEssentialMeta(filterEssential func(*asserts.ModelSnap) bool, otherSnapsFollow bool, tm timings.Measurer) error {
@@ -806,6 +893,14 @@ func (s *seed20) ArtifactPath(relName string) string {
 	return filepath.Join(s.systemDir, relName)
 }
 
+func (s *seed20) HasPreseedArtifact() bool {
+	return s.HasArtifact(internal.PreseedArtifactName)
+}
+
+func (s *seed20) PreseedArtifactPath() string {
+	return s.ArtifactPath(internal.PreseedArtifactName)
+}
+
 func (s *seed20) LoadPreseedAssertion() (*asserts.Preseed, error) {
 	model := s.Model()
 	sysLabel := filepath.Base(s.systemDir)
@@ -838,19 +933,13 @@ func (s *seed20) LoadPreseedAssertion() (*asserts.Preseed, error) {
 	}
 	preseedAs := a.(*asserts.Preseed)
 
-	if !strutil.ListContains(model.PreseedAuthority(), preseedAs.AuthorityID()) {
-		return nil, fmt.Errorf("preseed authority-id %q is not allowed by the model", preseedAs.AuthorityID())
+	if err := internal.CheckPreseedAssertion(preseedAs, model, sysLabel); err != nil {
+		return nil, err
 	}
-
-	switch {
-	case preseedAs.SystemLabel() != sysLabel:
-		return nil, fmt.Errorf("preseed assertion system label %q doesn't match system label %q", preseedAs.SystemLabel(), sysLabel)
-	case preseedAs.Model() != model.Model():
-		return nil, fmt.Errorf("preseed assertion model %q doesn't match the model %q", preseedAs.Model(), model.Model())
-	case preseedAs.BrandID() != model.BrandID():
-		return nil, fmt.Errorf("preseed assertion brand %q doesn't match model brand %q", preseedAs.BrandID(), model.BrandID())
-	case preseedAs.Series() != model.Series():
-		return nil, fmt.Errorf("preseed assertion series %q doesn't match model series %q", preseedAs.Series(), model.Series())
+	if s.HasPreseedArtifact() {
+		if err := internal.CheckPreseedArtifact(s.PreseedArtifactPath(), preseedAs); err != nil {
+			return nil, err
+		}
 	}
 	return preseedAs, nil
 }
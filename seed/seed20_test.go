@@ -277,6 +277,98 @@ func (s *seed20Suite) makeCore20MinimalSeed(c *C, sysLabel string) string {
 	return filepath.Join(s.SeedDir, "systems", sysLabel)
 }
 
+func (s *seed20Suite) TestLoadMetaCore20Components(c *C) {
+	sysLabel := "20230105"
+	systemDir := s.makeCore20MinimalSeed(c, sysLabel)
+
+	// two components of the kernel
+	c.Assert(os.MkdirAll(filepath.Join(systemDir, "snaps"), 0755), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(systemDir, "snaps", "pc-kernel+kmod_1.0.comp"), nil, 0644), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(systemDir, "snaps", "pc-kernel+local-kmod_1.0.comp"), nil, 0644), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(systemDir, "components.yaml"), []byte(`
+snaps:
+ - snap: pc-kernel
+   components:
+    - name: kmod
+      file: pc-kernel+kmod_1.0.comp
+    - name: local-kmod
+      file: pc-kernel+local-kmod_1.0.comp
+`), 0644), IsNil)
+
+	seed20, err := seed.Open(s.SeedDir, sysLabel)
+	c.Assert(err, IsNil)
+
+	err = seed20.LoadAssertions(s.db, s.commitTo)
+	c.Assert(err, IsNil)
+
+	err = seed20.LoadMeta(seed.AllModes, nil, s.perfTimings)
+	c.Assert(err, IsNil)
+
+	essSnaps := seed20.EssentialSnaps()
+	c.Assert(essSnaps, HasLen, 4)
+	c.Check(essSnaps[1].SnapName(), Equals, "pc-kernel")
+	// the kernel snap is still the snap file
+	c.Check(essSnaps[1].Path, Equals, s.expectedPath("pc-kernel"))
+	c.Check(essSnaps[1].Components, DeepEquals, []seed.Component{
+		{
+			Name: "kmod",
+			Path: filepath.Join(systemDir, "snaps", "pc-kernel+kmod_1.0.comp"),
+		}, {
+			Name: "local-kmod",
+			Path: filepath.Join(systemDir, "snaps", "pc-kernel+local-kmod_1.0.comp"),
+		},
+	})
+	for _, sn := range []*seed.Snap{essSnaps[0], essSnaps[2], essSnaps[3]} {
+		c.Check(sn.Components, IsNil)
+	}
+}
+
+func (s *seed20Suite) TestLoadMetaCore20ComponentMissing(c *C) {
+	sysLabel := "20230105"
+	systemDir := s.makeCore20MinimalSeed(c, sysLabel)
+
+	c.Assert(os.WriteFile(filepath.Join(systemDir, "components.yaml"), []byte(`
+snaps:
+ - snap: pc-kernel
+   components:
+    - name: kmod
+      file: pc-kernel+kmod_1.0.comp
+`), 0644), IsNil)
+
+	seed20, err := seed.Open(s.SeedDir, sysLabel)
+	c.Assert(err, IsNil)
+
+	err = seed20.LoadAssertions(s.db, s.commitTo)
+	c.Assert(err, IsNil)
+
+	err = seed20.LoadMeta(seed.AllModes, nil, s.perfTimings)
+	c.Assert(err, ErrorMatches, `cannot find component "kmod" of snap "pc-kernel": ".*/snaps/pc-kernel\+kmod_1.0.comp" does not exist`)
+}
+
+func (s *seed20Suite) TestLoadMetaCore20AssertedComponentRefused(c *C) {
+	sysLabel := "20230105"
+	systemDir := s.makeCore20MinimalSeed(c, sysLabel)
+
+	c.Assert(os.WriteFile(filepath.Join(s.SeedDir, "snaps", "pc-kernel+kmod_3.comp"), nil, 0644), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(systemDir, "components.yaml"), []byte(`
+snaps:
+ - snap: pc-kernel
+   components:
+    - name: kmod
+      revision: 3
+      file: pc-kernel+kmod_3.comp
+`), 0644), IsNil)
+
+	seed20, err := seed.Open(s.SeedDir, sysLabel)
+	c.Assert(err, IsNil)
+
+	err = seed20.LoadAssertions(s.db, s.commitTo)
+	c.Assert(err, IsNil)
+
+	err = seed20.LoadMeta(seed.AllModes, nil, s.perfTimings)
+	c.Assert(err, ErrorMatches, `cannot use asserted component "kmod" of snap "pc-kernel": snap resource assertions are not supported yet`)
+}
+
 func (s *seed20Suite) TestLoadAssertionsModelTempDBHappy(c *C) {
 	r := seed.MockTrusted(s.StoreSigning.Trusted)
 	defer r()
@@ -314,6 +406,27 @@ func (s *seed20Suite) TestLoadAssertionsMultiModels(c *C) {
 	c.Check(err, ErrorMatches, `system cannot have any model assertion but the one in the system model assertion file`)
 }
 
+func (s *seed20Suite) TestLoadAssertionsIncompleteSystem(c *C) {
+	sysLabel := "20191031"
+	sysDir := s.makeCore20MinimalSeed(c, sysLabel)
+
+	// the model is written last when creating a system
+	c.Assert(os.Remove(filepath.Join(sysDir, "model")), IsNil)
+
+	seed20, err := seed.Open(s.SeedDir, sysLabel)
+	c.Assert(err, IsNil)
+
+	err = seed20.LoadAssertions(s.db, s.commitTo)
+	c.Check(err, Equals, seed.ErrIncompleteSystem)
+
+	// a system which does not exist at all is not incomplete
+	seed20, err = seed.Open(s.SeedDir, "20200101")
+	c.Assert(err, IsNil)
+
+	err = seed20.LoadAssertions(s.db, s.commitTo)
+	c.Check(err, Equals, seed.ErrNoAssertions)
+}
+
 func (s *seed20Suite) TestLoadAssertionsInvalidModelAssertFile(c *C) {
 	sysLabel := "20191031"
 	sysDir := s.makeCore20MinimalSeed(c, sysLabel)
@@ -1459,6 +1572,90 @@ func (s *seed20Suite) TestLoadEssentialAndMetaCore20(c *C) {
 
 }
 
+func (s *seed20Suite) TestLoadEssentialMetaThenRemainingCore20(c *C) {
+	r := seed.MockTrusted(s.StoreSigning.Trusted)
+	defer r()
+
+	s.makeSnap(c, "snapd", "")
+	s.makeSnap(c, "core20", "")
+	s.makeSnap(c, "pc-kernel=20", "")
+	s.makeSnap(c, "pc=20", "")
+	s.makeSnap(c, "core18", "")
+	s.makeSnap(c, "required18", "developerid")
+
+	sysLabel := "20191018"
+	s.MakeSeed(c, sysLabel, "my-brand", "my-model", map[string]interface{}{
+		"display-name": "my model",
+		"architecture": "amd64",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name": "core18",
+				"id":   s.AssertedSnapID("core18"),
+				"type": "base",
+			},
+			map[string]interface{}{
+				"name": "required18",
+				"id":   s.AssertedSnapID("required18"),
+			}},
+	}, nil)
+
+	seed20, err := seed.Open(s.SeedDir, sysLabel)
+	c.Assert(err, IsNil)
+
+	err = seed20.LoadAssertions(nil, nil)
+	c.Assert(err, IsNil)
+
+	loader, ok := seed20.(seed.RemainingMetaLoaderSeed)
+	c.Assert(ok, Equals, true)
+
+	// essential snaps must be loaded first
+	err = loader.LoadRemaining(s.perfTimings)
+	c.Check(err, ErrorMatches, "cannot load remaining seed snaps metadata before the essential snaps metadata")
+
+	err = seed20.LoadEssentialMeta([]snap.Type{snap.TypeKernel, snap.TypeBase}, s.perfTimings)
+	c.Assert(err, IsNil)
+
+	essSnaps := seed20.EssentialSnaps()
+	c.Assert(essSnaps, HasLen, 2)
+	c.Check(essSnaps[0].SnapName(), Equals, "pc-kernel")
+	c.Check(essSnaps[1].SnapName(), Equals, "core20")
+	c.Check(seed20.NumSnaps(), Equals, 2)
+
+	// the already loaded essential snaps are not read again
+	unhide := hideSnaps(c, essSnaps, nil)
+	defer unhide()
+
+	err = loader.LoadRemaining(s.perfTimings)
+	c.Assert(err, IsNil)
+
+	var names []string
+	err = seed20.Iter(func(sn *seed.Snap) error {
+		names = append(names, sn.SnapName())
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Check(names, DeepEquals, []string{"snapd", "pc-kernel", "core20", "pc", "core18", "required18"})
+
+	essSnaps = seed20.EssentialSnaps()
+	c.Check(essSnaps, HasLen, 4)
+	runSnaps, err := seed20.ModeSnaps("run")
+	c.Assert(err, IsNil)
+	c.Check(runSnaps, HasLen, 2)
+}
+
 func (s *seed20Suite) makeLocalSnap(c *C, yamlKey string) (fname string) {
 	return snaptest.MakeTestSnapWithFiles(c, snapYaml[yamlKey], nil)
 }
@@ -3507,3 +3704,91 @@ func (s *seed20Suite) TestPreseedCapableSeedAlternateAuthority(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(preseedAs2, DeepEquals, preseedAs)
 }
+
+func (s *seed20Suite) makeValidateSeed(c *C, sysLabel string) {
+	s.makeSnap(c, "snapd", "")
+	s.makeSnap(c, "core20", "")
+	s.makeSnap(c, "pc-kernel=20", "")
+	s.makeSnap(c, "pc=20", "")
+	s.makeSnap(c, "core18", "")
+	s.makeSnap(c, "required18", "developerid")
+
+	s.MakeSeed(c, sysLabel, "my-brand", "my-model", map[string]interface{}{
+		"display-name": "my model",
+		"architecture": "amd64",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name": "core18",
+				"id":   s.AssertedSnapID("core18"),
+				"type": "base",
+			},
+			map[string]interface{}{
+				"name": "required18",
+				"id":   s.AssertedSnapID("required18"),
+			}},
+	}, nil)
+}
+
+func (s *seed20Suite) TestValidateHappy(c *C) {
+	r := seed.MockTrusted(s.StoreSigning.Trusted)
+	defer r()
+
+	s.makeValidateSeed(c, "20191018")
+
+	err := seed.Validate(s.SeedDir, "20191018")
+	c.Check(err, IsNil)
+}
+
+func (s *seed20Suite) TestValidateReportsAllSnapProblems(c *C) {
+	r := seed.MockTrusted(s.StoreSigning.Trusted)
+	defer r()
+
+	s.makeValidateSeed(c, "20191018")
+
+	// truncate one snap
+	kernelPath := s.expectedPath("pc-kernel")
+	c.Assert(os.Truncate(kernelPath, 10), IsNil)
+	// and corrupt another one keeping its size
+	corePath := s.expectedPath("core18")
+	data, err := os.ReadFile(corePath)
+	c.Assert(err, IsNil)
+	data[len(data)-1] ^= 0xff
+	c.Assert(os.WriteFile(corePath, data, 0644), IsNil)
+
+	err = seed.Validate(s.SeedDir, "20191018")
+	c.Assert(err, FitsTypeOf, &seed.ValidationError{})
+	c.Check(err, ErrorMatches, fmt.Sprintf(`cannot validate seed system "20191018":
+ - cannot validate %q for snap "pc-kernel" \(snap-id "%s"\), wrong size
+ - cannot validate %q for snap "core18" \(snap-id "%s"\), hash mismatch with snap-revision
+ - cannot use snap "required18": base "core18" is missing`, kernelPath, s.AssertedSnapID("pc-kernel"), corePath, s.AssertedSnapID("core18")))
+}
+
+func (s *seed20Suite) TestValidateAssertionsProblem(c *C) {
+	r := seed.MockTrusted(s.StoreSigning.Trusted)
+	defer r()
+
+	s.makeValidateSeed(c, "20191018")
+	c.Assert(os.Remove(filepath.Join(s.SeedDir, "systems/20191018/model")), IsNil)
+
+	err := seed.Validate(s.SeedDir, "20191018")
+	c.Check(err, ErrorMatches, `cannot validate seed system "20191018":
+ - incomplete seed system`)
+}
+
+func (s *seed20Suite) TestValidateNoLabel(c *C) {
+	err := seed.Validate(s.SeedDir, "")
+	c.Check(err, ErrorMatches, "system label cannot be empty")
+}
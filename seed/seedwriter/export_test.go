@@ -24,11 +24,14 @@ import (
 )
 
 type (
-	InternalSnap16 = internal.Snap16
-	InternalSnap20 = internal.Snap20
+	InternalSnap16           = internal.Snap16
+	InternalSnap20           = internal.Snap20
+	InternalSnapComponents20 = internal.SnapComponents20
+	InternalComponent20      = internal.Component20
 )
 
 var (
-	InternalReadSeedYaml  = internal.ReadSeedYaml
-	InternalReadOptions20 = internal.ReadOptions20
+	InternalReadSeedYaml     = internal.ReadSeedYaml
+	InternalReadOptions20    = internal.ReadOptions20
+	InternalReadComponents20 = internal.ReadComponents20
 )
@@ -100,6 +100,79 @@ func (s seedSnapsByType) Less(i, j int) bool {
 	return s[i].Info.Type().SortsBefore(s[j].Info.Type())
 }
 
+// MissingBaseError is returned by OrderSnapsForSeeding when the base of a
+// snap is not among the snaps to seed.
+type MissingBaseError struct {
+	SnapName string
+	Base     string
+}
+
+func (e *MissingBaseError) Error() string {
+	return fmt.Sprintf("cannot add snap %q without also adding its base %q explicitly", e.SnapName, e.Base)
+}
+
+// OrderSnapsForSeeding returns the given snaps in the order in which they are
+// seeded for the model: the snapd snap first, then the essential snaps in the
+// model order (kernel, boot base and gadget), then the other snaps listed in
+// the model in the model order and finally any snaps not mentioned in the
+// model in the given order. It also checks that the base of each snap is among
+// the given snaps, returning a *MissingBaseError otherwise.
+func OrderSnapsForSeeding(model *asserts.Model, infos []*snap.Info) ([]*snap.Info, error) {
+	byName := make(map[string]*snap.Info, len(infos))
+	for _, info := range infos {
+		if _, ok := byName[info.SnapName()]; ok {
+			return nil, fmt.Errorf("cannot order snaps for seeding: snap %q is listed more than once", info.SnapName())
+		}
+		byName[info.SnapName()] = info
+	}
+
+	for _, info := range infos {
+		base := info.Base
+		if base == "none" {
+			// explicitly does not need a base snap
+			continue
+		}
+		if base == "" {
+			if info.Type() != snap.TypeGadget && info.Type() != snap.TypeApp {
+				continue
+			}
+			base = "core"
+		}
+		if _, ok := byName[base]; ok {
+			continue
+		}
+		if _, ok := byName["core"]; ok && base == "core16" {
+			continue
+		}
+		return nil, &MissingBaseError{SnapName: info.SnapName(), Base: base}
+	}
+
+	ordered := make([]*snap.Info, 0, len(infos))
+	seen := make(map[*snap.Info]bool, len(infos))
+	add := func(info *snap.Info) {
+		if info == nil || seen[info] {
+			return
+		}
+		ordered = append(ordered, info)
+		seen[info] = true
+	}
+	for _, info := range infos {
+		if info.Type() == snap.TypeSnapd {
+			add(info)
+		}
+	}
+	for _, modSnap := range model.EssentialSnaps() {
+		add(byName[modSnap.SnapName()])
+	}
+	for _, modSnap := range model.SnapsWithoutEssential() {
+		add(byName[modSnap.SnapName()])
+	}
+	for _, info := range infos {
+		add(info)
+	}
+	return ordered, nil
+}
+
 // DeriveSideInfo tries to construct a SideInfo for the given snap
 // using its digest to fetch the relevant snap assertions. It will
 // fail with an asserts.NotFoundError if it cannot find them.
@@ -110,6 +183,12 @@ func DeriveSideInfo(snapPath string, model *asserts.Model, sf SeedAssertionFetch
 	if err != nil {
 		return nil, nil, err
 	}
+	return DeriveSideInfoFromDigestAndSize(snapPath, digest, size, model, sf, db)
+}
+
+// DeriveSideInfoFromDigestAndSize is like DeriveSideInfo, but uses the
+// already known digest and size of the snap file instead of computing them.
+func DeriveSideInfoFromDigestAndSize(snapPath string, digest string, size uint64, model *asserts.Model, sf SeedAssertionFetcher, db asserts.RODatabase) (*snap.SideInfo, []*asserts.Ref, error) {
 	// XXX assume that the input to the writer is trusted or the whole
 	// build is isolated
 	snapf, err := snapfile.Open(snapPath)
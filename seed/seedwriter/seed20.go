@@ -27,6 +27,7 @@ import (
 	"path/filepath"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/seed/internal"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/channel"
@@ -170,6 +171,19 @@ func (pol *policy20) ignoreUndeterminedSystemSnap() bool {
 	return false
 }
 
+func checkPreseedOptions(model *asserts.Model, opts *Options) error {
+	if opts.PreseedArtifact == "" && opts.PreseedAssertion == nil {
+		return nil
+	}
+	if opts.PreseedArtifact == "" || opts.PreseedAssertion == nil {
+		return fmt.Errorf("cannot include a preseed artifact without both the artifact and its preseed assertion")
+	}
+	if err := internal.CheckPreseedAssertion(opts.PreseedAssertion, model, opts.Label); err != nil {
+		return fmt.Errorf("cannot use preseed assertion: %v", err)
+	}
+	return nil
+}
+
 type tree20 struct {
 	grade asserts.ModelGrade
 	opts  *Options
@@ -237,6 +251,19 @@ func (tr *tree20) localSnapPath(sn *SeedSnap) (string, error) {
 	return filepath.Join(sysSnapsDir, fmt.Sprintf("%s_%s.snap", sn.SnapName(), sn.Info.Version)), nil
 }
 
+func (tr *tree20) componentPath(sn *SeedSnap, comp *SeedComponent) (string, error) {
+	if !comp.Revision.Unset() {
+		return "", fmt.Errorf("internal error: cannot place asserted component %q of snap %q", comp.Name, sn.SnapName())
+	}
+	// components are unasserted, they are placed with the unasserted
+	// snaps of the system
+	sysSnapsDir, err := tr.ensureSystemSnapsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(sysSnapsDir, fmt.Sprintf("%s+%s_%s.comp", sn.SnapName(), comp.Name, sn.Info.Version)), nil
+}
+
 func (tr *tree20) writeAssertions(db asserts.RODatabase, modelRefs []*asserts.Ref, snapsFromModel []*SeedSnap, extraSnaps []*SeedSnap) error {
 	assertsDir := filepath.Join(tr.systemDir, "assertions")
 	if err := os.MkdirAll(assertsDir, 0755); err != nil {
@@ -244,11 +271,18 @@ func (tr *tree20) writeAssertions(db asserts.RODatabase, modelRefs []*asserts.Re
 	}
 
 	writeByRefs := func(fname string, refsGen func(stop <-chan struct{}) <-chan *asserts.Ref) error {
-		f, err := os.OpenFile(filepath.Join(assertsDir, fname), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		fpath := filepath.Join(assertsDir, fname)
+		if osutil.FileExists(fpath) {
+			return fmt.Errorf("cannot write %s: file already exists", fname)
+		}
+		// files are written atomically so that a partially written
+		// system is never mistaken for a complete one
+		f, err := osutil.NewAtomicFile(fpath, 0644, 0, osutil.NoChown, osutil.NoChown)
 		if err != nil {
 			return err
 		}
-		defer f.Close()
+		// Cancel once Committed is a NOP
+		defer f.Cancel()
 
 		stop := make(chan struct{})
 		defer close(stop)
@@ -268,7 +302,7 @@ func (tr *tree20) writeAssertions(db asserts.RODatabase, modelRefs []*asserts.Re
 				return err
 			}
 		}
-		return nil
+		return f.Commit()
 	}
 
 	pushRef := func(refs chan<- *asserts.Ref, ref *asserts.Ref, stop <-chan struct{}) bool {
@@ -301,10 +335,6 @@ func (tr *tree20) writeAssertions(db asserts.RODatabase, modelRefs []*asserts.Re
 		}
 	}
 
-	if err := writeByRefs("../model", modelRefsGen(modelOnly)); err != nil {
-		return err
-	}
-
 	if err := writeByRefs("model-etc", modelRefsGen(excludeModel)); err != nil {
 		return err
 	}
@@ -336,7 +366,50 @@ func (tr *tree20) writeAssertions(db asserts.RODatabase, modelRefs []*asserts.Re
 		}
 	}
 
-	return nil
+	if tr.opts.PreseedAssertion != nil {
+		if err := tr.writePreseed(db); err != nil {
+			return err
+		}
+	}
+
+	// the model is written last, its presence marks the system as complete
+	return writeByRefs("../model", modelRefsGen(modelOnly))
+}
+
+// writePreseed copies the preseed artifact into the system directory
+// and writes the preseed assertion together with its signing
+// account-key next to it, after checking that they match.
+func (tr *tree20) writePreseed(db asserts.RODatabase) error {
+	preseedAs := tr.opts.PreseedAssertion
+	if err := internal.CheckPreseedArtifact(tr.opts.PreseedArtifact, preseedAs); err != nil {
+		return fmt.Errorf("cannot use preseed artifact: %v", err)
+	}
+	accKey, err := db.Find(asserts.AccountKeyType, map[string]string{
+		"public-key-sha3-384": preseedAs.SignKeyID(),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot find signing key of the preseed assertion: %v", err)
+	}
+
+	dst := filepath.Join(tr.systemDir, internal.PreseedArtifactName)
+	if err := osutil.CopyFile(tr.opts.PreseedArtifact, dst, osutil.CopyFlagDefault); err != nil {
+		return err
+	}
+
+	f, err := osutil.NewAtomicFile(filepath.Join(tr.systemDir, "preseed"), 0644, 0, osutil.NoChown, osutil.NoChown)
+	if err != nil {
+		return err
+	}
+	// Cancel once Committed is a NOP
+	defer f.Cancel()
+
+	enc := asserts.NewEncoder(f)
+	for _, a := range []asserts.Assertion{preseedAs, accKey} {
+		if err := enc.Encode(a); err != nil {
+			return err
+		}
+	}
+	return f.Commit()
 }
 
 func (tr *tree20) writeMeta(snapsFromModel []*SeedSnap, extraSnaps []*SeedSnap) error {
@@ -391,6 +464,35 @@ func (tr *tree20) writeMeta(snapsFromModel []*SeedSnap, extraSnaps []*SeedSnap)
 		}
 	}
 
+	var snapsComponents []*internal.SnapComponents20
+	addComponents := func(seedSnaps []*SeedSnap) {
+		for _, sn := range seedSnaps {
+			if len(sn.Components) == 0 {
+				continue
+			}
+			comps := make([]*internal.Component20, 0, len(sn.Components))
+			for _, comp := range sn.Components {
+				comps = append(comps, &internal.Component20{
+					Name:     comp.Name,
+					Revision: comp.Revision,
+					File:     filepath.Base(comp.Path),
+				})
+			}
+			snapsComponents = append(snapsComponents, &internal.SnapComponents20{
+				Snap:       sn.SnapName(),
+				Components: comps,
+			})
+		}
+	}
+	addComponents(snapsFromModel)
+	addComponents(extraSnaps)
+	if len(snapsComponents) != 0 {
+		components20 := &internal.Components20{Snaps: snapsComponents}
+		if err := components20.Write(filepath.Join(tr.systemDir, "components.yaml")); err != nil {
+			return err
+		}
+	}
+
 	auxInfos := make(map[string]*internal.AuxInfo20)
 
 	addAuxInfos := func(seedSnaps []*SeedSnap) {
@@ -419,16 +521,9 @@ func (tr *tree20) writeMeta(snapsFromModel []*SeedSnap, extraSnaps []*SeedSnap)
 		return err
 	}
 
-	f, err := os.OpenFile(filepath.Join(tr.systemDir, "snaps", "aux-info.json"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	data, err := json.Marshal(auxInfos)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	enc := json.NewEncoder(f)
-
-	if err := enc.Encode(auxInfos); err != nil {
-		return err
-	}
-
-	return nil
+	return osutil.AtomicWriteFile(filepath.Join(tr.systemDir, "snaps", "aux-info.json"), data, 0644, 0)
 }
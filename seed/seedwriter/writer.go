@@ -54,6 +54,16 @@ type Options struct {
 	// ManifestPath if set, specifies the file path where the
 	// seed.manifest file should be written.
 	ManifestPath string
+
+	// PreseedArtifact optionally specifies the path of a preseed
+	// artifact to be included as preseed.tgz in the Core20 recovery
+	// system. It requires PreseedAssertion to be set as well.
+	PreseedArtifact string
+	// PreseedAssertion is the preseed assertion for the system
+	// matching PreseedArtifact. It is written together with its
+	// signing account-key, which needs to be available in the
+	// database passed to Start.
+	PreseedAssertion *asserts.Preseed
 }
 
 // manifest returns either the manifest already provided by the
@@ -101,11 +111,27 @@ type SeedSnap struct {
 	// the database passed to Writer.Start.
 	aRefs []*asserts.Ref
 
+	// Components of a local snap, set via Writer.SetComponents.
+	Components []*SeedComponent
+
 	local      bool
 	modelSnap  *asserts.ModelSnap
 	optionSnap *OptionsSnap
 }
 
+// SeedComponent holds the details of a component of a local seed snap.
+type SeedComponent struct {
+	// Name of the component, without the snap name.
+	Name string
+	// Revision of the component, unset for unasserted components.
+	// Asserted components cannot be verified without snap resource
+	// assertions and are refused for now.
+	Revision snap.Revision
+	// Path of the component file, it is updated to the destination in
+	// the seed by Writer.SeedSnaps.
+	Path string
+}
+
 func (sn *SeedSnap) modes() []string {
 	if sn.modelSnap == nil {
 		// run is the assumed mode for extra snaps not listed
@@ -255,6 +281,8 @@ type tree interface {
 
 	localSnapPath(*SeedSnap) (string, error)
 
+	componentPath(*SeedSnap, *SeedComponent) (string, error)
+
 	writeAssertions(db asserts.RODatabase, modelRefs []*asserts.Ref, snapsFromModel []*SeedSnap, extraSnaps []*SeedSnap) error
 
 	writeMeta(snapsFromModel []*SeedSnap, extraSnaps []*SeedSnap) error
@@ -287,9 +315,15 @@ func New(model *asserts.Model, opts *Options) (*Writer, error) {
 		if err := asserts.IsValidSystemLabel(opts.Label); err != nil {
 			return nil, err
 		}
+		if err := checkPreseedOptions(model, opts); err != nil {
+			return nil, err
+		}
 		pol = &policy20{model: model, opts: opts, warningf: w.warningf}
 		treeImpl = &tree20{grade: model.Grade(), opts: opts}
 	} else {
+		if opts.PreseedArtifact != "" || opts.PreseedAssertion != nil {
+			return nil, fmt.Errorf("cannot include a preseed artifact in a pre-UC20 seed")
+		}
 		pol = &policy16{model: model, opts: opts, warningf: w.warningf}
 		treeImpl = &tree16{opts: opts}
 	}
@@ -677,6 +711,41 @@ func (w *Writer) SetInfo(sn *SeedSnap, info *snap.Info) error {
 	return nil
 }
 
+// SetComponents sets the components of a local SeedSnap, whose files are
+// then copied into the seed together with the snap by SeedSnaps. The
+// components must be declared by the snap and be unasserted, as asserted
+// components cannot be verified without snap resource assertions, which are
+// not supported yet. It must be called after SetInfo.
+func (w *Writer) SetComponents(sn *SeedSnap, comps []*SeedComponent) error {
+	if !sn.local {
+		return fmt.Errorf("internal error: cannot set components for non-local snap %q", sn.SnapName())
+	}
+	if sn.Info == nil {
+		return fmt.Errorf("internal error: before using seedwriter.Writer.SetComponents snap %q Info should have been set", sn.Path)
+	}
+	if w.model.Grade() == asserts.ModelGradeUnset {
+		return fmt.Errorf("cannot use components of snap %q with a pre-UC20 model", sn.SnapName())
+	}
+	seen := make(map[string]bool, len(comps))
+	for _, comp := range comps {
+		if _, ok := sn.Info.Components[comp.Name]; !ok {
+			return fmt.Errorf("snap %q has no component %q", sn.SnapName(), comp.Name)
+		}
+		if seen[comp.Name] {
+			return fmt.Errorf("component %q of snap %q is used more than once", comp.Name, sn.SnapName())
+		}
+		seen[comp.Name] = true
+		if !comp.Revision.Unset() {
+			if sn.Info.ID() == "" {
+				return fmt.Errorf("cannot use asserted component %q of unasserted snap %q", comp.Name, sn.SnapName())
+			}
+			return fmt.Errorf("cannot use asserted component %q of snap %q: snap resource assertions are not supported yet", comp.Name, sn.SnapName())
+		}
+	}
+	sn.Components = comps
+	return nil
+}
+
 // SetRedirectChannel sets the redirect channel for the SeedSnap
 // for the in case there is a default track for it.
 func (w *Writer) SetRedirectChannel(sn *SeedSnap, redirectChannel string) error {
@@ -1403,6 +1472,16 @@ func (w *Writer) SeedSnaps(copySnap func(name, src, dst string) error) error {
 				}
 				// record final destination path
 				sn.Path = dst
+				for _, comp := range sn.Components {
+					compDst, err := w.tree.componentPath(sn, comp)
+					if err != nil {
+						return err
+					}
+					if err := copySnap(fmt.Sprintf("%s+%s", info.SnapName(), comp.Name), comp.Path, compDst); err != nil {
+						return err
+					}
+					comp.Path = compDst
+				}
 			}
 			if !info.Revision.Unset() {
 				if err := w.manifest.MarkSnapRevisionSeeded(sn.Info.SnapName(), sn.Info.Revision); err != nil {
@@ -1456,11 +1535,14 @@ func (w *Writer) WriteMeta() error {
 	snapsFromModel := w.snapsFromModel
 	extraSnaps := w.extraSnaps
 
-	if err := w.tree.writeAssertions(w.db, w.modelRefs, snapsFromModel, extraSnaps); err != nil {
+	// the metadata is written before the assertions, such that for UC20+
+	// the system model assertion file is the last file being written and
+	// its presence implies that the system is complete
+	if err := w.tree.writeMeta(snapsFromModel, extraSnaps); err != nil {
 		return err
 	}
 
-	return w.tree.writeMeta(snapsFromModel, extraSnaps)
+	return w.tree.writeAssertions(w.db, w.modelRefs, snapsFromModel, extraSnaps)
 }
 
 // query accessors
@@ -1475,12 +1557,13 @@ func (w *Writer) checkSnapsAccessor() error {
 // BootSnaps returns the seed snaps involved in the boot process.
 // It can be invoked only after Downloaded returns complete ==
 // true. It returns an error for classic models as for those no snaps
-// participate in boot before user space.
+// participate in boot before user space, unless the model has modes
+// and a kernel.
 func (w *Writer) BootSnaps() ([]*SeedSnap, error) {
 	if err := w.checkSnapsAccessor(); err != nil {
 		return nil, err
 	}
-	if w.model.Classic() {
+	if w.model.Classic() && (w.model.Grade() == asserts.ModelGradeUnset || w.model.KernelSnap() == nil) {
 		return nil, fmt.Errorf("no snaps participating in boot on classic")
 	}
 	var bootSnaps []*SeedSnap
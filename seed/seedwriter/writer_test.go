@@ -20,6 +20,7 @@
 package seedwriter_test
 
 import (
+	"crypto"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -36,6 +37,7 @@ import (
 	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/seed/seedtest"
 	"github.com/snapcore/snapd/seed/seedwriter"
 	"github.com/snapcore/snapd/snap"
@@ -43,6 +45,7 @@ import (
 	"github.com/snapcore/snapd/snap/snapfile"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
 )
 
 func Test(t *testing.T) { TestingT(t) }
@@ -173,6 +176,15 @@ version: 1.0
 type: app
 version: 1
 confinement: devmode
+`,
+	"pc-kernel=20+comps": `name: pc-kernel
+type: kernel
+version: 1.0
+components:
+  kmod:
+    type: test
+  local-kmod:
+    type: test
 `,
 })
 
@@ -266,6 +278,82 @@ func (s *writerSuite) TestNewDefaultChannelError(c *C) {
 	c.Check(err, ErrorMatches, `cannot use global default option channel: invalid risk in channel name: foo/bar`)
 }
 
+func (s *writerSuite) TestOrderSnapsForSeeding(c *C) {
+	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
+		"display-name": "my model",
+		"architecture": "amd64",
+		"base":         "core20",
+		"grade":        "dangerous",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name": "required18",
+				"id":   s.AssertedSnapID("required18"),
+			},
+			map[string]interface{}{
+				"name": "core18",
+				"id":   s.AssertedSnapID("core18"),
+				"type": "base",
+			},
+			map[string]interface{}{
+				"name": "required20",
+				"id":   s.AssertedSnapID("required20"),
+			},
+		},
+	})
+
+	infos := make(map[string]*snap.Info)
+	for _, yamlKey := range []string{"required20", "core18", "pc=20", "required18", "core20", "snapd", "pc-kernel=20", "optional20-a"} {
+		info := snaptest.MockInfo(c, snapYaml[yamlKey], nil)
+		infos[info.SnapName()] = info
+	}
+	ordered, err := seedwriter.OrderSnapsForSeeding(model, []*snap.Info{
+		infos["required20"], infos["optional20-a"], infos["core18"], infos["pc"],
+		infos["required18"], infos["core20"], infos["snapd"], infos["pc-kernel"],
+	})
+	c.Assert(err, IsNil)
+	var names []string
+	for _, info := range ordered {
+		names = append(names, info.SnapName())
+	}
+	c.Check(names, DeepEquals, []string{
+		// snapd, the essential snaps in the model order
+		"snapd", "pc-kernel", "core20", "pc",
+		// the other model snaps in the model order
+		"required18", "core18", "required20",
+		// snaps not in the model
+		"optional20-a",
+	})
+
+	// the base of required18 is missing
+	_, err = seedwriter.OrderSnapsForSeeding(model, []*snap.Info{
+		infos["required20"], infos["pc"], infos["required18"],
+		infos["core20"], infos["snapd"], infos["pc-kernel"],
+	})
+	c.Assert(err, ErrorMatches, `cannot add snap "required18" without also adding its base "core18" explicitly`)
+	var missingBaseErr *seedwriter.MissingBaseError
+	c.Assert(errors.As(err, &missingBaseErr), Equals, true)
+	c.Check(missingBaseErr.SnapName, Equals, "required18")
+	c.Check(missingBaseErr.Base, Equals, "core18")
+
+	// a snap cannot be listed twice
+	_, err = seedwriter.OrderSnapsForSeeding(model, []*snap.Info{
+		infos["core20"], infos["core20"],
+	})
+	c.Assert(err, ErrorMatches, `cannot order snaps for seeding: snap "core20" is listed more than once`)
+}
+
 func (s writerSuite) TestSetOptionsSnapsErrors(c *C) {
 	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
 		"display-name":   "my model",
@@ -1126,6 +1214,33 @@ func (s *writerSuite) TestLocalSnaps(c *C) {
 	c.Check(localSnaps[3].Path, Equals, contConsumerFn)
 }
 
+func (s *writerSuite) TestDeriveSideInfoFromDigestAndSize(c *C) {
+	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
+		"display-name":   "my model",
+		"architecture":   "amd64",
+		"base":           "core18",
+		"gadget":         "pc=18",
+		"kernel":         "pc-kernel=18",
+		"required-snaps": []interface{}{"cont-producer"},
+	})
+
+	s.makeSnap(c, "cont-producer", "developerid")
+	fn := s.AssertedSnap("cont-producer")
+	digest, size, err := asserts.SnapFileSHA3_384(fn)
+	c.Assert(err, IsNil)
+
+	si, aRefs, err := seedwriter.DeriveSideInfoFromDigestAndSize(fn, digest, size, model, s.rf, s.db)
+	c.Assert(err, IsNil)
+	c.Check(si.RealName, Equals, "cont-producer")
+	c.Check(si.SnapID, Equals, s.AssertedSnapID("cont-producer"))
+	c.Check(si.Revision, Equals, snap.R(1))
+	c.Check(aRefs, Not(HasLen), 0)
+
+	// the size is checked against the assertion
+	_, _, err = seedwriter.DeriveSideInfoFromDigestAndSize(fn, digest, size+1, model, s.rf, s.db)
+	c.Check(err, ErrorMatches, `snap ".*" does not have expected size according to signatures \(broken or tampered\): .*`)
+}
+
 func (s *writerSuite) TestLocalSnapsCore18FullUse(c *C) {
 	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
 		"display-name":   "my model",
@@ -3573,6 +3688,184 @@ func (s *writerSuite) TestSeedSnapsWriteMetaCore20LocalAssertedSnaps(c *C) {
 	})
 }
 
+func (s *writerSuite) TestSeedSnapsWriteMetaCore20LocalSnapComponents(c *C) {
+	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
+		"display-name": "my model",
+		"architecture": "amd64",
+		"base":         "core20",
+		"grade":        "dangerous",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			}},
+	})
+
+	s.makeSnap(c, "snapd", "")
+	s.makeSnap(c, "core20", "")
+	s.makeSnap(c, "pc-kernel=20+comps", "")
+	s.makeSnap(c, "pc=20", "")
+
+	compsDir := c.MkDir()
+	kmodFn := filepath.Join(compsDir, "kmod.comp")
+	c.Assert(os.WriteFile(kmodFn, []byte("kmod"), 0644), IsNil)
+	localKmodFn := filepath.Join(compsDir, "local-kmod.comp")
+	c.Assert(os.WriteFile(localKmodFn, []byte("local-kmod"), 0644), IsNil)
+
+	s.opts.Label = "20230105"
+	w, err := seedwriter.New(model, s.opts)
+	c.Assert(err, IsNil)
+
+	err = w.SetOptionsSnaps([]*seedwriter.OptionsSnap{{Path: s.AssertedSnap("pc-kernel")}})
+	c.Assert(err, IsNil)
+
+	err = w.Start(s.db, s.rf)
+	c.Assert(err, IsNil)
+
+	localSnaps, err := w.LocalSnaps()
+	c.Assert(err, IsNil)
+	c.Assert(localSnaps, HasLen, 1)
+	sn := localSnaps[0]
+
+	f, err := snapfile.Open(sn.Path)
+	c.Assert(err, IsNil)
+	// unasserted snaps cannot carry asserted components
+	info, err := snap.ReadInfoFromSnapFile(f, nil)
+	c.Assert(err, IsNil)
+	c.Assert(w.SetInfo(sn, info), IsNil)
+	err = w.SetComponents(sn, []*seedwriter.SeedComponent{{Name: "kmod", Revision: snap.R(3), Path: kmodFn}})
+	c.Check(err, ErrorMatches, `cannot use asserted component "kmod" of unasserted snap "pc-kernel"`)
+
+	si, aRefs, err := seedwriter.DeriveSideInfo(sn.Path, model, s.rf, s.db)
+	c.Assert(err, IsNil)
+	info, err = snap.ReadInfoFromSnapFile(f, si)
+	c.Assert(err, IsNil)
+	c.Assert(w.SetInfo(sn, info), IsNil)
+	s.aRefs[sn.SnapName()] = aRefs
+
+	err = w.SetComponents(sn, []*seedwriter.SeedComponent{{Name: "other", Path: kmodFn}})
+	c.Check(err, ErrorMatches, `snap "pc-kernel" has no component "other"`)
+	err = w.SetComponents(sn, []*seedwriter.SeedComponent{{Name: "kmod", Path: kmodFn}, {Name: "kmod", Path: kmodFn}})
+	c.Check(err, ErrorMatches, `component "kmod" of snap "pc-kernel" is used more than once`)
+	// asserted components cannot be verified
+	err = w.SetComponents(sn, []*seedwriter.SeedComponent{{Name: "kmod", Revision: snap.R(3), Path: kmodFn}})
+	c.Check(err, ErrorMatches, `cannot use asserted component "kmod" of snap "pc-kernel": snap resource assertions are not supported yet`)
+
+	err = w.SetComponents(sn, []*seedwriter.SeedComponent{
+		{Name: "kmod", Path: kmodFn},
+		{Name: "local-kmod", Path: localKmodFn},
+	})
+	c.Assert(err, IsNil)
+
+	err = w.InfoDerived()
+	c.Assert(err, IsNil)
+
+	for {
+		snaps, err := w.SnapsToDownload()
+		c.Assert(err, IsNil)
+		for _, sn := range snaps {
+			s.fillDownloadedSnap(c, w, sn)
+		}
+		complete, err := w.Downloaded(s.fetchAsserts(c))
+		c.Assert(err, IsNil)
+		if complete {
+			break
+		}
+	}
+
+	var copied []string
+	copySnap := func(name, src, dst string) error {
+		copied = append(copied, name)
+		return osutil.CopyFile(src, dst, 0)
+	}
+
+	err = w.SeedSnaps(copySnap)
+	c.Assert(err, IsNil)
+	c.Check(copied, DeepEquals, []string{"pc-kernel", "pc-kernel+kmod", "pc-kernel+local-kmod"})
+
+	err = w.WriteMeta()
+	c.Assert(err, IsNil)
+
+	systemDir := filepath.Join(s.opts.SeedDir, "systems", s.opts.Label)
+	// components are placed with the unasserted snaps of the system
+	c.Check(filepath.Join(systemDir, "snaps", "pc-kernel+kmod_1.0.comp"), testutil.FileEquals, "kmod")
+	c.Check(filepath.Join(systemDir, "snaps", "pc-kernel+local-kmod_1.0.comp"), testutil.FileEquals, "local-kmod")
+
+	components20, err := seedwriter.InternalReadComponents20(filepath.Join(systemDir, "components.yaml"))
+	c.Assert(err, IsNil)
+	c.Check(components20.Snaps, DeepEquals, []*seedwriter.InternalSnapComponents20{{
+		Snap: "pc-kernel",
+		Components: []*seedwriter.InternalComponent20{
+			{Name: "kmod", File: "pc-kernel+kmod_1.0.comp"},
+			{Name: "local-kmod", File: "pc-kernel+local-kmod_1.0.comp"},
+		},
+	}})
+
+	// the components are exposed when loading the seed
+	r := seed.MockTrusted(s.StoreSigning.Trusted)
+	defer r()
+	sd, err := seed.Open(s.opts.SeedDir, s.opts.Label)
+	c.Assert(err, IsNil)
+	c.Assert(sd.LoadAssertions(nil, nil), IsNil)
+	c.Assert(sd.LoadMeta(seed.AllModes, nil, timings.New(nil)), IsNil)
+	var kernel *seed.Snap
+	for _, sn := range sd.EssentialSnaps() {
+		if sn.EssentialType == snap.TypeKernel {
+			kernel = sn
+		}
+	}
+	c.Assert(kernel, NotNil)
+	c.Check(kernel.Path, Equals, filepath.Join(s.opts.SeedDir, "snaps", "pc-kernel_1.snap"))
+	c.Check(kernel.Components, DeepEquals, []seed.Component{
+		{Name: "kmod", Path: filepath.Join(systemDir, "snaps", "pc-kernel+kmod_1.0.comp")},
+		{Name: "local-kmod", Path: filepath.Join(systemDir, "snaps", "pc-kernel+local-kmod_1.0.comp")},
+	})
+}
+
+func (s *writerSuite) TestSetComponentsPreUC20(c *C) {
+	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
+		"display-name":   "my model",
+		"architecture":   "amd64",
+		"gadget":         "pc",
+		"kernel":         "pc-kernel",
+		"required-snaps": []interface{}{"required"},
+	})
+
+	s.makeSnap(c, "core", "")
+	s.makeSnap(c, "pc-kernel", "")
+	s.makeSnap(c, "pc", "")
+	s.makeSnap(c, "required", "")
+
+	w, err := seedwriter.New(model, s.opts)
+	c.Assert(err, IsNil)
+	err = w.SetOptionsSnaps([]*seedwriter.OptionsSnap{{Path: s.AssertedSnap("required")}})
+	c.Assert(err, IsNil)
+	err = w.Start(s.db, s.rf)
+	c.Assert(err, IsNil)
+
+	localSnaps, err := w.LocalSnaps()
+	c.Assert(err, IsNil)
+	c.Assert(localSnaps, HasLen, 1)
+	sn := localSnaps[0]
+	info := &snap.Info{
+		SuggestedName: "required",
+		Components: map[string]snap.Component{
+			"comp": {Type: snap.TestComponent},
+		},
+	}
+	c.Assert(w.SetInfo(sn, info), IsNil)
+	err = w.SetComponents(sn, []*seedwriter.SeedComponent{{Name: "comp", Path: "comp.comp"}})
+	c.Check(err, ErrorMatches, `cannot use components of snap "required" with a pre-UC20 model`)
+}
+
 func (s *writerSuite) TestSeedSnapsWriteMetaCore20SignedLocalAssertedSnaps(c *C) {
 	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
 		"display-name": "my model",
@@ -3707,6 +4000,265 @@ func (s *writerSuite) TestSeedSnapsWriteCore20ErrWhenDirExists(c *C) {
 	c.Assert(seedwriter.IsSytemDirectoryExistsError(err), Equals, true)
 }
 
+func (s *writerSuite) TestSeedSnapsWriteMetaCore20ModelWrittenLast(c *C) {
+	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
+		"display-name": "my model",
+		"architecture": "amd64",
+		"base":         "core20",
+		"grade":        "signed",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			}},
+	})
+
+	s.makeSnap(c, "snapd", "")
+	s.makeSnap(c, "core20", "")
+	s.makeSnap(c, "pc-kernel=20", "")
+	s.makeSnap(c, "pc=20", "")
+
+	s.opts.Label = "20191003"
+	complete, w, err := s.upToDownloaded(c, model, s.fillDownloadedSnap, s.fetchAsserts(c))
+	c.Assert(err, IsNil)
+	c.Check(complete, Equals, true)
+
+	err = w.SeedSnaps(nil)
+	c.Assert(err, IsNil)
+
+	// make writing the snap assertions fail
+	systemDir := filepath.Join(s.opts.SeedDir, "systems", s.opts.Label)
+	assertsDir := filepath.Join(systemDir, "assertions")
+	c.Assert(os.MkdirAll(assertsDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(assertsDir, "snaps"), nil, 0644), IsNil)
+
+	err = w.WriteMeta()
+	c.Assert(err, ErrorMatches, "cannot write snaps: file already exists")
+
+	// the model, which is written last, is not there
+	c.Check(filepath.Join(systemDir, "model"), testutil.FileAbsent)
+	c.Check(filepath.Join(assertsDir, "model-etc"), testutil.FilePresent)
+	// and no temporary files are left behind
+	leftovers, err := filepath.Glob(filepath.Join(systemDir, "*~"))
+	c.Assert(err, IsNil)
+	c.Check(leftovers, HasLen, 0)
+	leftovers, err = filepath.Glob(filepath.Join(assertsDir, "*~"))
+	c.Assert(err, IsNil)
+	c.Check(leftovers, HasLen, 0)
+
+	// the system is recognized as incomplete
+	sd, err := seed.Open(s.opts.SeedDir, s.opts.Label)
+	c.Assert(err, IsNil)
+	err = sd.LoadAssertions(nil, nil)
+	c.Check(err, Equals, seed.ErrIncompleteSystem)
+}
+
+func (s *writerSuite) makePreseed(c *C, label string, content []byte, overrides map[string]interface{}) (artifactPath string, preseedAs *asserts.Preseed) {
+	artifactPath = filepath.Join(c.MkDir(), "preseed.tgz")
+	c.Assert(os.WriteFile(artifactPath, content, 0644), IsNil)
+	sha3_384, _, err := osutil.FileDigest(artifactPath, crypto.SHA3_384)
+	c.Assert(err, IsNil)
+	digest, err := asserts.EncodeDigest(crypto.SHA3_384, sha3_384)
+	c.Assert(err, IsNil)
+
+	headers := map[string]interface{}{
+		"type":              "preseed",
+		"series":            "16",
+		"brand-id":          "my-brand",
+		"model":             "my-model",
+		"system-label":      label,
+		"artifact-sha3-384": digest,
+		"timestamp":         time.Now().UTC().Format(time.RFC3339),
+		"snaps": []interface{}{
+			map[string]interface{}{"name": "snapd", "id": s.AssertedSnapID("snapd"), "revision": "1"},
+		},
+	}
+	for k, v := range overrides {
+		headers[k] = v
+	}
+	a, err := s.Brands.Signing("my-brand").Sign(asserts.PreseedType, headers, nil, "")
+	c.Assert(err, IsNil)
+	return artifactPath, a.(*asserts.Preseed)
+}
+
+func (s *writerSuite) TestSeedSnapsWriteMetaCore20Preseed(c *C) {
+	r := seed.MockTrusted(s.StoreSigning.Trusted)
+	defer r()
+
+	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
+		"display-name": "my model",
+		"architecture": "amd64",
+		"base":         "core20",
+		"grade":        "signed",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			}},
+	})
+
+	s.makeSnap(c, "snapd", "")
+	s.makeSnap(c, "core20", "")
+	s.makeSnap(c, "pc-kernel=20", "")
+	s.makeSnap(c, "pc=20", "")
+
+	s.opts.Label = "20191003"
+	s.opts.PreseedArtifact, s.opts.PreseedAssertion = s.makePreseed(c, s.opts.Label, []byte("preseed-data"), nil)
+
+	complete, w, err := s.upToDownloaded(c, model, s.fillDownloadedSnap, s.fetchAsserts(c))
+	c.Assert(err, IsNil)
+	c.Check(complete, Equals, true)
+
+	err = w.SeedSnaps(nil)
+	c.Assert(err, IsNil)
+
+	err = w.WriteMeta()
+	c.Assert(err, IsNil)
+
+	systemDir := filepath.Join(s.opts.SeedDir, "systems", s.opts.Label)
+	c.Check(filepath.Join(systemDir, "preseed.tgz"), testutil.FileEquals, "preseed-data")
+
+	sd, err := seed.Open(s.opts.SeedDir, s.opts.Label)
+	c.Assert(err, IsNil)
+	err = sd.LoadAssertions(nil, nil)
+	c.Assert(err, IsNil)
+
+	preseedSeed := sd.(seed.PreseedCapable)
+	c.Check(preseedSeed.HasPreseedArtifact(), Equals, true)
+	c.Check(preseedSeed.PreseedArtifactPath(), Equals, filepath.Join(systemDir, "preseed.tgz"))
+
+	preseedAs, err := preseedSeed.LoadPreseedAssertion()
+	c.Assert(err, IsNil)
+	c.Check(preseedAs, DeepEquals, s.opts.PreseedAssertion)
+
+	// the digest of the artifact is checked on load
+	c.Assert(os.WriteFile(filepath.Join(systemDir, "preseed.tgz"), []byte("tampered"), 0644), IsNil)
+	_, err = preseedSeed.LoadPreseedAssertion()
+	c.Check(err, ErrorMatches, "invalid preseed artifact digest")
+}
+
+func (s *writerSuite) TestSeedSnapsWriteMetaCore20PreseedDigestMismatch(c *C) {
+	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
+		"display-name": "my model",
+		"architecture": "amd64",
+		"base":         "core20",
+		"grade":        "signed",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			}},
+	})
+
+	s.makeSnap(c, "snapd", "")
+	s.makeSnap(c, "core20", "")
+	s.makeSnap(c, "pc-kernel=20", "")
+	s.makeSnap(c, "pc=20", "")
+
+	s.opts.Label = "20191003"
+	s.opts.PreseedArtifact, s.opts.PreseedAssertion = s.makePreseed(c, s.opts.Label, []byte("preseed-data"), nil)
+	c.Assert(os.WriteFile(s.opts.PreseedArtifact, []byte("other-data"), 0644), IsNil)
+
+	complete, w, err := s.upToDownloaded(c, model, s.fillDownloadedSnap, s.fetchAsserts(c))
+	c.Assert(err, IsNil)
+	c.Check(complete, Equals, true)
+
+	err = w.SeedSnaps(nil)
+	c.Assert(err, IsNil)
+
+	err = w.WriteMeta()
+	c.Assert(err, ErrorMatches, "cannot use preseed artifact: invalid preseed artifact digest")
+
+	// the system is incomplete
+	systemDir := filepath.Join(s.opts.SeedDir, "systems", s.opts.Label)
+	c.Check(filepath.Join(systemDir, "preseed.tgz"), testutil.FileAbsent)
+	c.Check(filepath.Join(systemDir, "model"), testutil.FileAbsent)
+}
+
+func (s *writerSuite) TestCore20PreseedOptionsErrors(c *C) {
+	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
+		"display-name": "my model",
+		"architecture": "amd64",
+		"base":         "core20",
+		"grade":        "signed",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			}},
+	})
+
+	label := "20191003"
+	artifact, preseedAs := s.makePreseed(c, label, nil, nil)
+	_, otherLabelAs := s.makePreseed(c, label, nil, map[string]interface{}{"system-label": "20191004"})
+	_, otherModelAs := s.makePreseed(c, label, nil, map[string]interface{}{"model": "other-model"})
+
+	tests := []struct {
+		artifact  string
+		preseedAs *asserts.Preseed
+		err       string
+	}{
+		{artifact: artifact, err: `cannot include a preseed artifact without both the artifact and its preseed assertion`},
+		{preseedAs: preseedAs, err: `cannot include a preseed artifact without both the artifact and its preseed assertion`},
+		{artifact: artifact, preseedAs: otherLabelAs, err: `cannot use preseed assertion: preseed assertion system label "20191004" doesn't match system label "20191003"`},
+		{artifact: artifact, preseedAs: otherModelAs, err: `cannot use preseed assertion: preseed assertion model "other-model" doesn't match the model "my-model"`},
+	}
+
+	for _, t := range tests {
+		_, err := seedwriter.New(model, &seedwriter.Options{
+			SeedDir:          s.opts.SeedDir,
+			Label:            label,
+			PreseedArtifact:  t.artifact,
+			PreseedAssertion: t.preseedAs,
+		})
+		c.Check(err, ErrorMatches, t.err)
+	}
+
+	// not supported for pre-UC20 models
+	_, err := seedwriter.New(s.Brands.Model("my-brand", "my-model", map[string]interface{}{
+		"architecture": "amd64",
+		"gadget":       "pc",
+		"kernel":       "pc-kernel",
+	}), &seedwriter.Options{
+		SeedDir:          s.opts.SeedDir,
+		PreseedArtifact:  artifact,
+		PreseedAssertion: preseedAs,
+	})
+	c.Check(err, ErrorMatches, `cannot include a preseed artifact in a pre-UC20 seed`)
+}
+
 func (s *writerSuite) testDownloadedCore20CheckClassic(c *C, modelGrade asserts.ModelGrade, classicFlag bool) error {
 	classicSnap := map[string]interface{}{
 		"name":  "classic-snap",
@@ -3776,6 +4328,81 @@ func (s *writerSuite) TestDownloadedCore20CheckClassicSignedWithFlag(c *C) {
 	c.Check(err, IsNil)
 }
 
+func (s *writerSuite) TestBootSnapsCore20Classic(c *C) {
+	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
+		"display-name": "my model",
+		"architecture": "amd64",
+		"base":         "core20",
+		"classic":      "true",
+		"distribution": "ubuntu",
+		"grade":        "dangerous",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+
+	s.makeSnap(c, "snapd", "")
+	s.makeSnap(c, "core20", "")
+	s.makeSnap(c, "pc-kernel=20", "")
+	s.makeSnap(c, "pc=20", "")
+
+	s.opts.Label = "20221125"
+	complete, w, err := s.upToDownloaded(c, model, s.fillDownloadedSnap, s.fetchAsserts(c))
+	c.Assert(err, IsNil)
+	c.Check(complete, Equals, true)
+
+	bootSnaps, err := w.BootSnaps()
+	c.Assert(err, IsNil)
+	var names []string
+	for _, sn := range bootSnaps {
+		names = append(names, sn.SnapName())
+	}
+	c.Check(names, DeepEquals, []string{"snapd", "pc-kernel", "core20", "pc"})
+}
+
+func (s *writerSuite) TestBootSnapsCore20ClassicNoKernel(c *C) {
+	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
+		"display-name": "my model",
+		"architecture": "amd64",
+		"base":         "core20",
+		"classic":      "true",
+		"distribution": "ubuntu",
+		"grade":        "dangerous",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name": "required20",
+				"id":   s.AssertedSnapID("required20"),
+			},
+		},
+	})
+
+	s.makeSnap(c, "snapd", "")
+	s.makeSnap(c, "core20", "")
+	s.makeSnap(c, "required20", "developerid")
+
+	// no kernel
+	s.expectedKernSnap = ""
+
+	s.opts.Label = "20221125"
+	complete, w, err := s.upToDownloaded(c, model, s.fillDownloadedSnap, s.fetchAsserts(c))
+	c.Assert(err, IsNil)
+	c.Check(complete, Equals, true)
+
+	_, err = w.BootSnaps()
+	c.Check(err, ErrorMatches, "no snaps participating in boot on classic")
+}
+
 func (s *writerSuite) setupValidationSets(c *C) {
 	valSetA, err := s.StoreSigning.Sign(asserts.ValidationSetType, map[string]interface{}{
 		"type":         "validation-set",
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"sort"
+	"sync"
 
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snapfile"
@@ -107,16 +108,68 @@ func ValidateFromYaml(seedYamlFile string) error {
 	}
 
 	ve := &ValidationError{}
-	// read the snap infos
+	validateSnaps(seed, "", ve)
+	if ve.hasErrors() {
+		return ve
+	}
+
+	return nil
+}
+
+// Validate validates the Core 20+ recovery system seed specified by
+// seedDir and label. The seed assertions are loaded into a temporary
+// database, which checks their signatures and the model, brand and
+// account-key chains, then the size and SHA3-384 digest of every
+// snap file are verified against its snap-revision assertion.
+// Problems with individual snaps do not stop the validation, they are
+// all reported together in the returned *ValidationError.
+func Validate(seedDir, label string) error {
+	if label == "" {
+		return fmt.Errorf("system label cannot be empty")
+	}
+	seed, err := Open(seedDir, label)
+	if err != nil {
+		return newValidationError(label, err)
+	}
+
+	if err := seed.LoadAssertions(nil, nil); err != nil {
+		return newValidationError(label, err)
+	}
+
+	ve := &ValidationError{}
+	var mu sync.Mutex
+	seed.(*seed20).onSnapLoadError = func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		ve.addErr(label, err)
+	}
+
+	tm := timings.New(nil)
+	if err := seed.LoadMeta(AllModes, nil, tm); err != nil {
+		ve.addErr(label, err)
+		return ve
+	}
+
+	validateSnaps(seed, label, ve)
+	if ve.hasErrors() {
+		return ve
+	}
+
+	return nil
+}
+
+// validateSnaps reads the infos of the loaded seed snaps and checks that
+// their bases and default providers are in the seed.
+func validateSnaps(seed Seed, label string, ve *ValidationError) {
 	snapInfos := make([]*snap.Info, 0, seed.NumSnaps())
 	seed.Iter(func(sn *Snap) error {
 		snapf, err := snapfile.Open(sn.Path)
 		if err != nil {
-			ve.addErr("", err)
+			ve.addErr(label, err)
 		} else {
 			info, err := snap.ReadInfoFromSnapFile(snapf, sn.SideInfo)
 			if err != nil {
-				ve.addErr("", fmt.Errorf("cannot use snap %q: %v", sn.Path, err))
+				ve.addErr(label, fmt.Errorf("cannot use snap %q: %v", sn.Path, err))
 			} else {
 				snapInfos = append(snapInfos, info)
 			}
@@ -125,11 +178,6 @@ func ValidateFromYaml(seedYamlFile string) error {
 	})
 
 	if errs2 := snap.ValidateBasesAndProviders(snapInfos); errs2 != nil {
-		ve.addErr("", errs2...)
-	}
-	if ve.hasErrors() {
-		return ve
+		ve.addErr(label, errs2...)
 	}
-
-	return nil
 }
@@ -26,6 +26,7 @@ import (
 var supportedHooks = []*HookType{
 	NewHookType(regexp.MustCompile("^prepare-device$")),
 	NewHookType(regexp.MustCompile("^install-device$")),
+	NewHookType(regexp.MustCompile("^prepare-recovery-system$")),
 	NewHookType(regexp.MustCompile("^default-configure$")),
 	NewHookType(regexp.MustCompile("^configure$")),
 	NewHookType(regexp.MustCompile("^install$")),
@@ -989,6 +989,13 @@ type SocketInfo struct {
 	Name         string
 	ListenStream string
 	SocketMode   os.FileMode
+	// SocketUser and SocketGroup own the socket, they must be declared in
+	// the system-usernames of the snap.
+	SocketUser  string
+	SocketGroup string
+	// Backlog is the length of the listen queue of the socket, the
+	// systemd default is used if unset.
+	Backlog int
 }
 
 // TimerInfo provides information on application timer.
@@ -996,6 +1003,9 @@ type TimerInfo struct {
 	App *AppInfo
 
 	Timer string
+	// RandDelay is the maximum random delay added to the activation of
+	// the timer.
+	RandDelay timeout.Timeout
 }
 
 // StopModeType is the type for the "stop-mode:" of a snap app
@@ -105,7 +105,8 @@ type appYaml struct {
 	After  []string `yaml:"after,omitempty"`
 	Before []string `yaml:"before,omitempty"`
 
-	Timer string `yaml:"timer,omitempty"`
+	Timer          string          `yaml:"timer,omitempty"`
+	TimerRandDelay timeout.Timeout `yaml:"timer-rand-delay,omitempty"`
 
 	Autostart string `yaml:"autostart,omitempty"`
 }
@@ -130,6 +131,9 @@ type layoutYaml struct {
 type socketsYaml struct {
 	ListenStream string      `yaml:"listen-stream,omitempty"`
 	SocketMode   os.FileMode `yaml:"socket-mode,omitempty"`
+	SocketUser   string      `yaml:"socket-user,omitempty"`
+	SocketGroup  string      `yaml:"socket-group,omitempty"`
+	Backlog      int         `yaml:"backlog,omitempty"`
 }
 
 // InfoFromSnapYaml creates a new info based on the given snap.yaml data
@@ -456,13 +460,19 @@ func setAppsFromSnapYaml(y snapYaml, snap *Info, strk *scopedTracker) error {
 				Name:         name,
 				ListenStream: data.ListenStream,
 				SocketMode:   data.SocketMode,
+				SocketUser:   data.SocketUser,
+				SocketGroup:  data.SocketGroup,
+				Backlog:      data.Backlog,
 			}
 		}
 		if yApp.Timer != "" {
 			app.Timer = &TimerInfo{
-				App:   app,
-				Timer: yApp.Timer,
+				App:       app,
+				Timer:     yApp.Timer,
+				RandDelay: yApp.TimerRandDelay,
 			}
+		} else if yApp.TimerRandDelay != 0 {
+			return fmt.Errorf("cannot use timer-rand-delay for app %q without a timer", appName)
 		}
 		// collect all common IDs
 		if app.CommonID != "" {
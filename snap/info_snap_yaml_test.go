@@ -1627,6 +1627,32 @@ apps:
 	})
 }
 
+func (s *YamlSuite) TestDaemonSocketOwnerAndBacklog(c *C) {
+	y := []byte(`name: wat
+version: 42
+system-usernames:
+  snap_daemon: shared
+apps:
+ svc:
+   command: svc
+   daemon: simple
+   sockets:
+     sock:
+       listen-stream: $SNAP_DATA/sock.socket
+       socket-user: snap_daemon
+       socket-group: snap_daemon
+       backlog: 256
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+
+	sock := info.Apps["svc"].Sockets["sock"]
+	c.Assert(sock, NotNil)
+	c.Check(sock.SocketUser, Equals, "snap_daemon")
+	c.Check(sock.SocketGroup, Equals, "snap_daemon")
+	c.Check(sock.Backlog, Equals, 256)
+}
+
 func (s *YamlSuite) TestDaemonInvalidSocketMode(c *C) {
 	y := []byte(`name: wat
 version: 42
@@ -1867,6 +1893,37 @@ apps:
 	c.Check(app.Timer, DeepEquals, &snap.TimerInfo{App: app, Timer: "mon,10:00-12:00"})
 }
 
+func (s *YamlSuite) TestSnapYamlAppTimerRandDelay(c *C) {
+	y := []byte(`name: wat
+version: 42
+apps:
+ foo:
+   daemon: oneshot
+   timer: mon,10:00-12:00 UTC
+   timer-rand-delay: 15m
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+	app := info.Apps["foo"]
+	c.Check(app.Timer, DeepEquals, &snap.TimerInfo{
+		App:       app,
+		Timer:     "mon,10:00-12:00 UTC",
+		RandDelay: timeout.Timeout(15 * time.Minute),
+	})
+}
+
+func (s *YamlSuite) TestSnapYamlAppTimerRandDelayWithoutTimer(c *C) {
+	y := []byte(`name: wat
+version: 42
+apps:
+ foo:
+   daemon: oneshot
+   timer-rand-delay: 15m
+`)
+	_, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, ErrorMatches, `cannot use timer-rand-delay for app "foo" without a timer`)
+}
+
 func (s *YamlSuite) TestSnapYamlAppAutostart(c *C) {
 	yAutostart := []byte(`name: wat
 version: 42
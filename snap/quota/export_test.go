@@ -63,6 +63,12 @@ func MockCgroupVerErr(mockErr error) (restore func()) {
 	return r
 }
 
+func MockCgroupCheckCpusetCgroupErr(mockErr error) (restore func()) {
+	r := testutil.Backup(&cgroupCheckCpusetCgroupErr)
+	cgroupCheckCpusetCgroupErr = mockErr
+	return r
+}
+
 func MockRuntimeNumCPU(mock func() int) (restore func()) {
 	r := testutil.Backup(&runtimeNumCPU)
 	runtimeNumCPU = mock
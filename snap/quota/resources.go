@@ -32,11 +32,13 @@ var (
 	cgroupVerErr error
 
 	cgroupCheckMemoryCgroupErr error
+	cgroupCheckCpusetCgroupErr error
 )
 
 func init() {
 	cgroupVer, cgroupVerErr = cgroup.Version()
 	cgroupCheckMemoryCgroupErr = cgroup.CheckMemoryCgroup()
+	cgroupCheckCpusetCgroupErr = cgroup.CheckCpusetCgroup()
 }
 
 type ResourceMemory struct {
@@ -198,6 +200,9 @@ func (qr *Resources) CheckFeatureRequirements() error {
 		if cgroupVer < 2 {
 			return fmt.Errorf("cannot use CPU set with cgroup version %d", cgroupVer)
 		}
+		if cgroupCheckCpusetCgroupErr != nil {
+			return fmt.Errorf("cannot use CPU set: %v", cgroupCheckCpusetCgroupErr)
+		}
 	}
 	if qr.Memory != nil && cgroupCheckMemoryCgroupErr != nil {
 		return fmt.Errorf("cannot use memory quota: %v", cgroupCheckMemoryCgroupErr)
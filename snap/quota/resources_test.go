@@ -84,6 +84,24 @@ func (s *resourcesTestSuite) TestResourceCheckFeatureRequirementsCgroupv1Err(c *
 	c.Check(bad.CheckFeatureRequirements(), ErrorMatches, "some cgroup detection error")
 }
 
+func (s *resourcesTestSuite) TestResourceCheckFeatureRequirementsNoCpuset(c *C) {
+	r := quota.MockCgroupVer(2)
+	defer r()
+	r = quota.MockCgroupCheckCpusetCgroupErr(fmt.Errorf("cpuset cgroup is disabled on this system"))
+	defer r()
+
+	// cpu and thread limits do not need the cpuset controller
+	good := quota.NewResourcesBuilder().WithCPUCount(1).WithCPUPercentage(50).WithThreadLimit(32).Build()
+	c.Check(good.CheckFeatureRequirements(), IsNil)
+
+	bad := quota.NewResourcesBuilder().WithCPUPercentage(50).WithCPUSet([]int{0, 1}).Build()
+	c.Check(bad.CheckFeatureRequirements(), ErrorMatches, "cannot use CPU set: cpuset cgroup is disabled on this system")
+
+	r = quota.MockCgroupCheckCpusetCgroupErr(nil)
+	defer r()
+	c.Check(bad.CheckFeatureRequirements(), IsNil)
+}
+
 func (s *resourcesTestSuite) TestQuotaValidationPasses(c *C) {
 	tests := []struct {
 		limits quota.Resources
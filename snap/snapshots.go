@@ -45,6 +45,27 @@ type SnapshotOptions struct {
 	Exclude []string `yaml:"exclude" json:"exclude,omitempty"`
 }
 
+// SnapshotRestoreOptions limits which data of a snapshot gets restored.
+type SnapshotRestoreOptions struct {
+	// SkipSystem skips restoring the system data of the snap, along
+	// with its configuration, so that only the data of users is
+	// restored.
+	SkipSystem bool `json:"skip-system,omitempty"`
+	// CommonOnly restores only the common data directories of the
+	// snap, leaving the revisioned data directories untouched.
+	CommonOnly bool `json:"common-only,omitempty"`
+	// ForceOwnership restores the data of users that no longer exist
+	// into their leftover home directories, owned by root, instead of
+	// failing.
+	ForceOwnership bool `json:"force-ownership,omitempty"`
+}
+
+// Partial returns whether the options restore only a part of the data of
+// a snapshot.
+func (opts *SnapshotRestoreOptions) Partial() bool {
+	return opts != nil && (opts.SkipSystem || opts.CommonOnly)
+}
+
 const (
 	snapshotManifestPath = "meta/snapshots.yaml"
 )
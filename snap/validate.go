@@ -583,9 +583,33 @@ func validateAppSocket(socket *SocketInfo) error {
 	if err := validateSocketMode(socket.SocketMode); err != nil {
 		return err
 	}
+	if err := validateSocketOwner(socket, "socket-user", socket.SocketUser); err != nil {
+		return err
+	}
+	if err := validateSocketOwner(socket, "socket-group", socket.SocketGroup); err != nil {
+		return err
+	}
+	if socket.Backlog < 0 {
+		return fmt.Errorf("backlog cannot be negative: %d", socket.Backlog)
+	}
 	return validateSocketAddr(socket, "listen-stream", socket.ListenStream)
 }
 
+// validateSocketOwner checks that the user or group owning a socket is a
+// system username declared by the snap.
+func validateSocketOwner(socket *SocketInfo, fieldName, owner string) error {
+	if owner == "" {
+		return nil
+	}
+	if socket.App.DaemonScope == UserDaemon {
+		return fmt.Errorf("%s cannot be used by user daemons", fieldName)
+	}
+	if _, ok := socket.App.Snap.SystemUsernames[owner]; !ok {
+		return fmt.Errorf("%s %q must be declared in system-usernames", fieldName, owner)
+	}
+	return nil
+}
+
 // validateAppOrderCycles checks for cycles in app ordering dependencies
 func validateAppOrderCycles(apps []*AppInfo) error {
 	if _, err := SortServices(apps); err != nil {
@@ -654,6 +678,10 @@ func validateAppTimer(app *AppInfo) error {
 		return fmt.Errorf("timer has invalid format: %v", err)
 	}
 
+	if app.Timer.RandDelay < 0 {
+		return errors.New("timer-rand-delay cannot be negative")
+	}
+
 	return nil
 }
 
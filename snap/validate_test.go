@@ -190,6 +190,50 @@ func (s *ValidateSuite) TestValidateAppSocketsWrongPerms(c *C) {
 	c.Assert(err, ErrorMatches, `invalid definition of socket "sock": cannot use mode: 2322`)
 }
 
+func (s *ValidateSuite) TestValidateAppSocketsOwnerAndBacklog(c *C) {
+	app := createSampleApp()
+	app.Snap.SystemUsernames = map[string]*SystemUsernameInfo{
+		"snap_daemon": {Name: "snap_daemon", Scope: "shared"},
+	}
+	app.Sockets["sock"].SocketUser = "snap_daemon"
+	app.Sockets["sock"].SocketGroup = "snap_daemon"
+	app.Sockets["sock"].Backlog = 128
+	c.Check(ValidateApp(app), IsNil)
+}
+
+func (s *ValidateSuite) TestValidateAppSocketsOwnerNotDeclared(c *C) {
+	app := createSampleApp()
+	app.Sockets["sock"].SocketUser = "snap_daemon"
+	err := ValidateApp(app)
+	c.Check(err, ErrorMatches, `invalid definition of socket "sock": socket-user "snap_daemon" must be declared in system-usernames`)
+
+	app = createSampleApp()
+	app.Snap.SystemUsernames = map[string]*SystemUsernameInfo{
+		"snap_daemon": {Name: "snap_daemon", Scope: "shared"},
+	}
+	app.Sockets["sock"].SocketGroup = "root"
+	err = ValidateApp(app)
+	c.Check(err, ErrorMatches, `invalid definition of socket "sock": socket-group "root" must be declared in system-usernames`)
+}
+
+func (s *ValidateSuite) TestValidateAppSocketsOwnerUserDaemon(c *C) {
+	app := createSampleApp()
+	app.DaemonScope = UserDaemon
+	app.Snap.SystemUsernames = map[string]*SystemUsernameInfo{
+		"snap_daemon": {Name: "snap_daemon", Scope: "shared"},
+	}
+	app.Sockets["sock"].SocketUser = "snap_daemon"
+	err := ValidateApp(app)
+	c.Check(err, ErrorMatches, `invalid definition of socket "sock": socket-user cannot be used by user daemons`)
+}
+
+func (s *ValidateSuite) TestValidateAppSocketsNegativeBacklog(c *C) {
+	app := createSampleApp()
+	app.Sockets["sock"].Backlog = -1
+	err := ValidateApp(app)
+	c.Check(err, ErrorMatches, `invalid definition of socket "sock": backlog cannot be negative: -1`)
+}
+
 func (s *ValidateSuite) TestValidateAppSocketsMissingNetworkBindPlug(c *C) {
 	app := createSampleApp()
 	delete(app.Plugs, "network-bind")
@@ -1627,6 +1671,26 @@ apps:
     daemon: oneshot
     timer: mon,10:00-12:00,mon2-wed3
 `)
+	timezoneRandDelay := []byte(`
+apps:
+  foo:
+    daemon: oneshot
+    timer: mon,10:00-12:00 Europe/Berlin
+    timer-rand-delay: 10m
+`)
+	badTimezone := []byte(`
+apps:
+  foo:
+    daemon: oneshot
+    timer: 9:00,,mon,10:00-12:00 Mars/Olympus
+`)
+	negativeRandDelay := []byte(`
+apps:
+  foo:
+    daemon: oneshot
+    timer: mon,10:00-12:00
+    timer-rand-delay: -10m
+`)
 
 	tcs := []struct {
 		name string
@@ -1643,6 +1707,17 @@ apps:
 		name: "invalid timer",
 		desc: badTimer,
 		err:  `timer has invalid format: cannot parse "mon2-wed3": invalid schedule fragment`,
+	}, {
+		name: "timezone and randomized delay",
+		desc: timezoneRandDelay,
+	}, {
+		name: "invalid timezone",
+		desc: badTimezone,
+		err:  `timer has invalid format: cannot parse "mon,10:00-12:00 Mars/Olympus": "Mars/Olympus" is not a valid timezone`,
+	}, {
+		name: "negative randomized delay",
+		desc: negativeRandDelay,
+		err:  `timer-rand-delay cannot be negative`,
 	}}
 	for _, tc := range tcs {
 		c.Logf("trying %q", tc.name)
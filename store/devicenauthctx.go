@@ -39,6 +39,13 @@ type DeviceAndAuthContext interface {
 
 	DeviceSessionRequestParams(nonce string) (*DeviceSessionRequestParams, error)
 	ProxyStoreParams(defaultURL *url.URL) (proxyStoreID string, proxySroreURL *url.URL, err error)
+	// ProxyStoreURLs returns the base URLs of the proxy stores to
+	// try, in order of preference, before falling back to the
+	// default store.
+	ProxyStoreURLs() ([]*url.URL, error)
+	// StoreURLSwitched is called when the store switches base URL
+	// because the one in use was found unreachable.
+	StoreURLSwitched(from, to *url.URL, reason error)
 
 	CloudInfo() (*auth.CloudInfo, error)
 
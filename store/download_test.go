@@ -607,6 +607,7 @@ var deltaTests = []struct {
 	downloads       downloadBehaviour
 	info            snap.DownloadInfo
 	expectedContent string
+	fallbackReason  string
 }{{
 	// The full snap is not downloaded, but rather the delta
 	// is downloaded and applied.
@@ -615,8 +616,9 @@ var deltaTests = []struct {
 	},
 	info: snap.DownloadInfo{
 		DownloadURL: "full-snap-url",
+		Sha3_384:    "sha3",
 		Deltas: []snap.DeltaInfo{
-			{DownloadURL: "delta-url", Format: "xdelta3"},
+			{DownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
 		},
 	},
 	expectedContent: "snap-content-via-delta",
@@ -629,11 +631,27 @@ var deltaTests = []struct {
 	},
 	info: snap.DownloadInfo{
 		DownloadURL: "full-snap-url",
+		Sha3_384:    "sha3",
 		Deltas: []snap.DeltaInfo{
-			{DownloadURL: "delta-url", Format: "xdelta3"},
+			{DownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
+		},
+	},
+	expectedContent: "full-snap-url-content",
+	fallbackReason:  "Bang",
+}, {
+	// Without a digest to verify the result of applying the delta, the
+	// full snap is downloaded.
+	downloads: downloadBehaviour{
+		{url: "full-snap-url"},
+	},
+	info: snap.DownloadInfo{
+		DownloadURL: "full-snap-url",
+		Deltas: []snap.DeltaInfo{
+			{DownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
 		},
 	},
 	expectedContent: "full-snap-url-content",
+	fallbackReason:  "cannot verify the snap generated from the delta: no sha3-384 for revision 26",
 }, {
 	// If more than one matching delta is returned by the store
 	// we ignore deltas and do the full download.
@@ -642,6 +660,7 @@ var deltaTests = []struct {
 	},
 	info: snap.DownloadInfo{
 		DownloadURL: "full-snap-url",
+		Sha3_384:    "sha3",
 		Deltas: []snap.DeltaInfo{
 			{DownloadURL: "delta-url", Format: "xdelta3"},
 			{DownloadURL: "delta-url-2", Format: "xdelta3"},
@@ -671,20 +690,217 @@ func (s *downloadSuite) TestDownloadWithDelta(c *C) {
 		defer restore()
 		restore = store.MockApplyDelta(func(_ *store.Store, name string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
 			c.Check(deltaInfo, Equals, &testCase.info.Deltas[0])
+			c.Check(targetSha3_384, Equals, "sha3")
 			err := os.WriteFile(targetPath, []byte("snap-content-via-delta"), 0644)
 			c.Assert(err, IsNil)
 			return nil
 		})
 		defer restore()
 
+		var outcomes []store.DeltaOutcome
+		dlOpts := &store.DownloadOptions{
+			OnDelta: func(outcome store.DeltaOutcome) {
+				outcomes = append(outcomes, outcome)
+			},
+		}
+
 		theStore := store.New(&store.Config{}, nil)
 		path := filepath.Join(c.MkDir(), "subdir", "downloaded-file")
-		err := theStore.Download(context.TODO(), "foo", path, &testCase.info, nil, nil, nil)
+		err := theStore.Download(context.TODO(), "foo", path, &testCase.info, nil, nil, dlOpts)
 
 		c.Assert(err, IsNil)
 		defer os.Remove(path)
 		c.Assert(path, testutil.FileEquals, testCase.expectedContent)
+		c.Check(downloadIndex, Equals, len(testCase.downloads))
+
+		switch {
+		case len(testCase.info.Deltas) != 1:
+			c.Check(outcomes, HasLen, 0)
+		case testCase.fallbackReason == "":
+			c.Check(outcomes, DeepEquals, []store.DeltaOutcome{{
+				Format:       "xdelta3",
+				FromRevision: 24,
+				ToRevision:   26,
+				Applied:      true,
+			}})
+		default:
+			c.Assert(outcomes, HasLen, 1)
+			c.Check(outcomes[0].Applied, Equals, false)
+			c.Check(outcomes[0].FallbackReason, ErrorMatches, testCase.fallbackReason)
+		}
+		// the partial delta is always removed
+		matches, err := filepath.Glob(path + ".xdelta3-*")
+		c.Assert(err, IsNil)
+		c.Check(matches, HasLen, 0)
+	}
+}
+
+func (s *downloadSuite) TestDownloadWithDeltaNoXdelta3(c *C) {
+	s.mockXdelta.Restore()
+	origPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", origPath)
+	os.Setenv("PATH", "")
+	origUseDeltas := os.Getenv("SNAPD_USE_DELTAS_EXPERIMENTAL")
+	defer os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", origUseDeltas)
+	c.Assert(os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", "1"), IsNil)
+
+	restore := store.MockSnapdtoolCommandFromSystemSnap(func(name string, args ...string) (*exec.Cmd, error) {
+		return nil, errors.New("no xdelta3 in the system snap")
+	})
+	defer restore()
+	restore = store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		c.Check(url, Equals, "full-snap-url")
+		w.Write([]byte("full-snap-url-content"))
+		return nil
+	})
+	defer restore()
+
+	var outcomes []store.DeltaOutcome
+	dlOpts := &store.DownloadOptions{
+		OnDelta: func(outcome store.DeltaOutcome) {
+			outcomes = append(outcomes, outcome)
+		},
+	}
+	info := &snap.DownloadInfo{
+		DownloadURL: "full-snap-url",
+		Sha3_384:    "sha3",
+		Size:        int64(len("full-snap-url-content")),
+		Deltas: []snap.DeltaInfo{
+			{DownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
+		},
+	}
+	theStore := store.New(&store.Config{}, nil)
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := theStore.Download(context.TODO(), "foo", path, info, nil, nil, dlOpts)
+	c.Assert(err, IsNil)
+	c.Check(path, testutil.FileEquals, "full-snap-url-content")
+	c.Assert(outcomes, HasLen, 1)
+	c.Check(outcomes[0].Applied, Equals, false)
+	c.Check(outcomes[0].FallbackReason, ErrorMatches, "no xdelta3 available from the system snap or the host")
+}
+
+func (s *downloadSuite) TestDownloadWithDeltaDisabledNotReported(c *C) {
+	origUseDeltas := os.Getenv("SNAPD_USE_DELTAS_EXPERIMENTAL")
+	defer os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", origUseDeltas)
+	c.Assert(os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", "0"), IsNil)
+
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		c.Check(url, Equals, "full-snap-url")
+		w.Write([]byte("full-snap-url-content"))
+		return nil
+	})
+	defer restore()
+
+	dlOpts := &store.DownloadOptions{
+		OnDelta: func(outcome store.DeltaOutcome) {
+			c.Errorf("unexpected delta outcome: %+v", outcome)
+		},
+	}
+	info := &snap.DownloadInfo{
+		DownloadURL: "full-snap-url",
+		Sha3_384:    "sha3",
+		Size:        int64(len("full-snap-url-content")),
+		Deltas: []snap.DeltaInfo{
+			{DownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
+		},
+	}
+	theStore := store.New(&store.Config{}, nil)
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := theStore.Download(context.TODO(), "foo", path, info, nil, nil, dlOpts)
+	c.Assert(err, IsNil)
+	c.Check(path, testutil.FileEquals, "full-snap-url-content")
+}
+
+func (s *downloadSuite) TestDownloadWithDeltaResumesPartialDelta(c *C) {
+	origUseDeltas := os.Getenv("SNAPD_USE_DELTAS_EXPERIMENTAL")
+	defer os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", origUseDeltas)
+	c.Assert(os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", "1"), IsNil)
+
+	info := &snap.DownloadInfo{
+		DownloadURL: "full-snap-url",
+		Sha3_384:    "sha3",
+		Deltas: []snap.DeltaInfo{
+			{DownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26, Size: 10},
+		},
+	}
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	deltaPath := path + ".xdelta3-24-to-26.partial"
+
+	// the first attempt is cancelled mid way
+	ctx, cancel := context.WithCancel(context.Background())
+	restore := store.MockDownload(func(_ context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		c.Check(url, Equals, "delta-url")
+		c.Check(resume, Equals, int64(0))
+		w.Write([]byte("delta"))
+		cancel()
+		return errors.New("the download has been cancelled: context canceled")
+	})
+	defer restore()
+	restore = store.MockApplyDelta(func(_ *store.Store, name string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
+		c.Fatalf("unexpected delta application")
+		return nil
+	})
+	defer restore()
+
+	theStore := store.New(&store.Config{}, nil)
+	err := theStore.Download(ctx, "foo", path, info, nil, nil, nil)
+	c.Assert(err, ErrorMatches, "the download has been cancelled: context canceled")
+	// the partial delta is kept, and no full download was attempted
+	c.Check(deltaPath, testutil.FileEquals, "delta")
+	c.Check(path+".partial", testutil.FileAbsent)
+
+	// the next attempt resumes the delta download
+	restore = store.MockDownload(func(_ context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		c.Check(url, Equals, "delta-url")
+		c.Check(resume, Equals, int64(len("delta")))
+		w.Write([]byte("-rest"))
+		return nil
+	})
+	defer restore()
+	restore = store.MockApplyDelta(func(_ *store.Store, name string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
+		c.Check(deltaPath, testutil.FileEquals, "delta-rest")
+		return os.WriteFile(targetPath, []byte("snap-content-via-delta"), 0644)
+	})
+	defer restore()
+
+	err = theStore.Download(context.Background(), "foo", path, info, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(path, testutil.FileEquals, "snap-content-via-delta")
+	c.Check(deltaPath, testutil.FileAbsent)
+}
+
+func (s *downloadSuite) TestDownloadWithDeltaResumesPartialFullDownload(c *C) {
+	origUseDeltas := os.Getenv("SNAPD_USE_DELTAS_EXPERIMENTAL")
+	defer os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", origUseDeltas)
+	c.Assert(os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", "1"), IsNil)
+
+	info := &snap.DownloadInfo{
+		DownloadURL: "full-snap-url",
+		Sha3_384:    "sha3",
+		Size:        int64(len("full-snap-url-content")),
+		Deltas: []snap.DeltaInfo{
+			{DownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
+		},
 	}
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	c.Assert(os.WriteFile(path+".partial", []byte("full-"), 0600), IsNil)
+
+	restore := store.MockDownload(func(_ context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		c.Check(url, Equals, "full-snap-url")
+		c.Check(resume, Equals, int64(len("full-")))
+		w.Write([]byte("snap-url-content"))
+		return nil
+	})
+	defer restore()
+
+	theStore := store.New(&store.Config{}, nil)
+	err := theStore.Download(context.Background(), "foo", path, info, nil, nil, &store.DownloadOptions{
+		OnDelta: func(outcome store.DeltaOutcome) {
+			c.Errorf("unexpected delta outcome: %+v", outcome)
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(path, testutil.FileEquals, "full-snap-url-content")
 }
 
 func (s *downloadSuite) TestActualDownloadRateLimited(c *C) {
@@ -183,8 +183,8 @@ func (sto *Store) SetDeltaFormat(dfmt string) {
 	sto.deltaFormat = dfmt
 }
 
-func (sto *Store) DownloadDelta(deltaName string, downloadInfo *snap.DownloadInfo, w io.ReadWriteSeeker, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) error {
-	return sto.downloadDelta(deltaName, downloadInfo, w, pbar, user, dlOpts)
+func (sto *Store) DownloadDelta(deltaName string, downloadInfo *snap.DownloadInfo, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) error {
+	return sto.downloadDelta(context.TODO(), deltaName, downloadInfo, w, resume, pbar, user, dlOpts)
 }
 
 func (sto *Store) DoRequest(ctx context.Context, client *http.Client, reqOptions *requestOptions, user *auth.UserState) (*http.Response, error) {
@@ -254,6 +254,22 @@ func MockRequestTimeout(d time.Duration) (restore func()) {
 	}
 }
 
+func MockTimeNow(f func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = f
+	return func() {
+		timeNow = old
+	}
+}
+
+func MockStoreRecheckInterval(d time.Duration) (restore func()) {
+	old := storeRecheckInterval
+	storeRecheckInterval = d
+	return func() {
+		storeRecheckInterval = old
+	}
+}
+
 type (
 	ErrorListEntryJSON   = errorListEntry
 	SnapActionResultJSON = snapActionResult
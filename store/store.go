@@ -176,6 +176,19 @@ type Store struct {
 	shouldUseDeltas *bool
 	// which xdelta3 we picked when we checked the deltas
 	xdelta3CmdFunc func(args ...string) *exec.Cmd
+	// why deltas cannot be used, unless disabled by the environment
+	noDeltasReason error
+
+	baseURLMu sync.Mutex
+	// the proxy store base URL in use, nil when using the default one
+	activeBaseURL *url.URL
+	// when the base URL in use was picked or last checked
+	activeBaseURLSince time.Time
+	// why a request to the base URL in use failed, if one did
+	activeBaseURLFailure error
+
+	assertionsMu     sync.Mutex
+	assertionFetches map[string]*assertionFetch
 }
 
 var ErrTooManyRequests = errors.New("too many requests")
@@ -486,21 +499,6 @@ func (s *Store) defaultSnapQuery() url.Values {
 	return q
 }
 
-func (s *Store) baseURL(defaultURL *url.URL) *url.URL {
-	u := defaultURL
-	if s.dauthCtx != nil {
-		var err error
-		_, u, err = s.dauthCtx.ProxyStoreParams(defaultURL)
-		if err != nil {
-			logger.Debugf("cannot get proxy store parameters from state: %v", err)
-		}
-	}
-	if u != nil {
-		return u
-	}
-	return defaultURL
-}
-
 func (s *Store) endpointURL(p string, query url.Values) (*url.URL, error) {
 	if err := s.checkStoreOnline(); err != nil {
 		return nil, err
@@ -732,6 +730,7 @@ func (s *Store) doRequest(ctx context.Context, client *http.Client, reqOptions *
 
 		resp, err := client.Do(req)
 		if err != nil {
+			s.baseURLFailed(ctx, req.URL, err)
 			return nil, err
 		}
 
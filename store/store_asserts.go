@@ -23,12 +23,14 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"path"
 	"strconv"
+	"time"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/httputil"
@@ -98,31 +100,125 @@ func (s *Store) Assertion(assertType *asserts.AssertionType, primaryKey []string
 		return nil, err
 	}
 
-	var asrt asserts.Assertion
+	var userID int
+	if user != nil {
+		userID = user.ID
+	}
+	key := fmt.Sprintf("%d %s", userID, u)
+	return s.cachedAssertion(key, func() (asserts.Assertion, error) {
+		var asrt asserts.Assertion
 
-	err = s.downloadAssertions(u, func(r io.Reader) error {
-		// decode assertion
-		dec := asserts.NewDecoder(r)
-		var e error
-		asrt, e = dec.Decode()
-		return e
-	}, func(svcErr *assertionSvcError) error {
-		// error-list indicates v2 error response.
-		if svcErr.isNotFound() {
-			// best-effort
-			headers, _ := asserts.HeadersFromPrimaryKey(assertType, primaryKey)
-			return &asserts.NotFoundError{
-				Type:    assertType,
-				Headers: headers,
+		err := s.downloadAssertions(u, func(r io.Reader) error {
+			// decode assertion
+			dec := asserts.NewDecoder(r)
+			var e error
+			asrt, e = dec.Decode()
+			return e
+		}, func(svcErr *assertionSvcError) error {
+			// error-list indicates v2 error response.
+			if svcErr.isNotFound() {
+				// best-effort
+				headers, _ := asserts.HeadersFromPrimaryKey(assertType, primaryKey)
+				return &asserts.NotFoundError{
+					Type:    assertType,
+					Headers: headers,
+				}
 			}
+			// default error
+			return nil
+		}, "fetch assertion", user)
+		if err != nil {
+			return nil, err
+		}
+		return asrt, nil
+	})
+}
+
+var (
+	// how long a fetched assertion is reused for, this spares the
+	// assertion service repeated requests for prerequisites shared
+	// by the assertions of many snaps
+	assertionCacheTTL = 1 * time.Minute
+	// how long an assertion is remembered as not found
+	assertionNotFoundCacheTTL = 30 * time.Second
+	// how many results to remember before pruning
+	assertionCacheSize = 256
+)
+
+// assertionFetch is an in-flight or finished assertion fetch.
+type assertionFetch struct {
+	done chan struct{}
+	asrt asserts.Assertion
+	err  error
+	// zero while in-flight
+	expires time.Time
+}
+
+// cachedAssertion returns the still valid result of a previous fetch for
+// key, or waits for and shares the result of a fetch in-flight for key,
+// otherwise it calls fetch. Only successful and not found results are
+// remembered.
+func (s *Store) cachedAssertion(key string, fetch func() (asserts.Assertion, error)) (asserts.Assertion, error) {
+	s.assertionsMu.Lock()
+	if f := s.assertionFetches[key]; f != nil {
+		if f.expires.IsZero() {
+			s.assertionsMu.Unlock()
+			<-f.done
+			return f.asrt, f.err
+		}
+		if timeNow().Before(f.expires) {
+			s.assertionsMu.Unlock()
+			return f.asrt, f.err
+		}
+	}
+	f := &assertionFetch{done: make(chan struct{})}
+	if s.assertionFetches == nil {
+		s.assertionFetches = make(map[string]*assertionFetch)
+	}
+	s.assertionFetches[key] = f
+	s.assertionsMu.Unlock()
+
+	asrt, err := fetch()
+
+	s.assertionsMu.Lock()
+	defer s.assertionsMu.Unlock()
+	f.asrt, f.err = asrt, err
+	now := timeNow()
+	switch {
+	case err == nil:
+		f.expires = now.Add(assertionCacheTTL)
+	case errors.Is(err, &asserts.NotFoundError{}):
+		f.expires = now.Add(assertionNotFoundCacheTTL)
+	default:
+		// only shared with the fetches that were waiting
+		f.expires = now
+		delete(s.assertionFetches, key)
+	}
+	close(f.done)
+	s.pruneAssertionFetches(now)
+	return asrt, err
+}
+
+// pruneAssertionFetches drops expired results if there are too many, and
+// all finished ones if that is not enough.
+// It must be called with assertionsMu held.
+func (s *Store) pruneAssertionFetches(now time.Time) {
+	if len(s.assertionFetches) <= assertionCacheSize {
+		return
+	}
+	for key, f := range s.assertionFetches {
+		if !f.expires.IsZero() && !now.Before(f.expires) {
+			delete(s.assertionFetches, key)
+		}
+	}
+	if len(s.assertionFetches) <= assertionCacheSize {
+		return
+	}
+	for key, f := range s.assertionFetches {
+		if !f.expires.IsZero() {
+			delete(s.assertionFetches, key)
 		}
-		// default error
-		return nil
-	}, "fetch assertion", user)
-	if err != nil {
-		return nil, err
 	}
-	return asrt, nil
 }
 
 // SeqFormingAssertion retrieves the sequence-forming assertion for the given
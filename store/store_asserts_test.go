@@ -26,6 +26,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -295,6 +297,131 @@ func (s *storeAssertsSuite) TestAssertion500(c *C) {
 	c.Assert(n, Equals, 5)
 }
 
+func (s *storeAssertsSuite) TestAssertionCoalescedAndCached(c *C) {
+	var n int32
+	release := make(chan struct{})
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequest(c, r, "GET", "/v2/assertions/.*")
+		c.Check(r.URL.Path, Matches, ".*/snap-declaration/16/snapidfoo")
+		atomic.AddInt32(&n, 1)
+		<-release
+		io.WriteString(w, testAssertion)
+	}))
+
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	now := time.Now()
+	restore := store.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	mockServerURL, _ := url.Parse(mockServer.URL)
+	cfg := store.Config{
+		AssertionsBaseURL: mockServerURL,
+	}
+	sto := store.New(&cfg, nil)
+
+	const concurrent = 5
+	var wg sync.WaitGroup
+	results := make([]asserts.Assertion, concurrent)
+	for i := 0; i < concurrent; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			a, err := sto.Assertion(asserts.SnapDeclarationType, []string{"16", "snapidfoo"}, nil)
+			c.Check(err, IsNil)
+			results[i] = a
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	c.Check(atomic.LoadInt32(&n), Equals, int32(1))
+	for _, a := range results {
+		c.Assert(a, NotNil)
+		c.Check(a.Type(), Equals, asserts.SnapDeclarationType)
+	}
+
+	// served from the cache
+	_, err := sto.Assertion(asserts.SnapDeclarationType, []string{"16", "snapidfoo"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(atomic.LoadInt32(&n), Equals, int32(1))
+
+	// until it expires
+	now = now.Add(2 * time.Minute)
+	_, err = sto.Assertion(asserts.SnapDeclarationType, []string{"16", "snapidfoo"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(atomic.LoadInt32(&n), Equals, int32(2))
+}
+
+func (s *storeAssertsSuite) TestAssertionNotFoundCached(c *C) {
+	n := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequest(c, r, "GET", "/v2/assertions/.*")
+		n++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(404)
+		io.WriteString(w, `{"error-list":[{"code":"not-found","message":"not found: no ..."}]}`)
+	}))
+
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	now := time.Now()
+	restore := store.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	mockServerURL, _ := url.Parse(mockServer.URL)
+	cfg := store.Config{
+		AssertionsBaseURL: mockServerURL,
+	}
+	sto := store.New(&cfg, nil)
+
+	for i := 0; i < 3; i++ {
+		_, err := sto.Assertion(asserts.SnapDeclarationType, []string{"16", "snapidfoo"}, nil)
+		c.Check(errors.Is(err, &asserts.NotFoundError{}), Equals, true)
+	}
+	c.Check(n, Equals, 1)
+
+	// not found results are remembered for a shorter time
+	now = now.Add(45 * time.Second)
+	_, err := sto.Assertion(asserts.SnapDeclarationType, []string{"16", "snapidfoo"}, nil)
+	c.Check(errors.Is(err, &asserts.NotFoundError{}), Equals, true)
+	c.Check(n, Equals, 2)
+
+	// a different primary key is fetched on its own
+	_, err = sto.Assertion(asserts.SnapDeclarationType, []string{"16", "snapidbar"}, nil)
+	c.Check(errors.Is(err, &asserts.NotFoundError{}), Equals, true)
+	c.Check(n, Equals, 3)
+}
+
+func (s *storeAssertsSuite) TestAssertionErrorNotCached(c *C) {
+	n := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequest(c, r, "GET", "/v2/assertions/.*")
+		n++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(400)
+		io.WriteString(w, `{"error-list":[{"code":"invalid-request","message":"invalid request"}]}`)
+	}))
+
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	mockServerURL, _ := url.Parse(mockServer.URL)
+	cfg := store.Config{
+		AssertionsBaseURL: mockServerURL,
+	}
+	sto := store.New(&cfg, nil)
+
+	_, err := sto.Assertion(asserts.SnapDeclarationType, []string{"16", "snapidfoo"}, nil)
+	c.Check(err, ErrorMatches, `assertion service error: "invalid request"`)
+	_, err = sto.Assertion(asserts.SnapDeclarationType, []string{"16", "snapidfoo"}, nil)
+	c.Check(err, ErrorMatches, `assertion service error: "invalid request"`)
+	c.Check(n, Equals, 2)
+}
+
 func (s *storeAssertsSuite) TestDownloadAssertionsSimple(c *C) {
 	assertstest.AddMany(s.db, s.storeSigning.StoreAccountKey(""), s.dev1Acct)
 
@@ -0,0 +1,185 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/httputil"
+	"github.com/snapcore/snapd/logger"
+)
+
+var (
+	timeNow = time.Now
+
+	// how long to wait for a proxy store to answer a health check
+	storeHealthCheckTimeout = 5 * time.Second
+	// how often to check whether a more preferred proxy store is
+	// reachable again while using a less preferred one
+	storeRecheckInterval = 10 * time.Minute
+)
+
+// baseURL returns the base URL to use for store requests. The proxy
+// stores, if any, are preferred in order over defaultURL. Once a base
+// URL is picked it is kept in use until a request to it fails, at
+// which point the proxy stores are health checked again in order and
+// the first reachable one is used, falling back to defaultURL.
+func (s *Store) baseURL(defaultURL *url.URL) *url.URL {
+	if s.dauthCtx == nil {
+		return defaultURL
+	}
+	candidates, err := s.dauthCtx.ProxyStoreURLs()
+	if err != nil {
+		logger.Debugf("cannot get proxy store parameters from state: %v", err)
+		return defaultURL
+	}
+	if len(candidates) == 0 {
+		return defaultURL
+	}
+
+	s.baseURLMu.Lock()
+	prev, reason, switched := s.selectBaseURL(candidates)
+	active := s.activeBaseURL
+	s.baseURLMu.Unlock()
+
+	if active == nil {
+		active = defaultURL
+	}
+	if switched {
+		if prev == nil {
+			prev = defaultURL
+		}
+		s.dauthCtx.StoreURLSwitched(prev, active, reason)
+	}
+	return active
+}
+
+// selectBaseURL updates the active base URL out of the candidates if
+// needed, it returns the previously active base URL, why it was
+// abandoned and whether a switch happened.
+// It must be called with baseURLMu held.
+func (s *Store) selectBaseURL(candidates []*url.URL) (prev *url.URL, reason error, switched bool) {
+	idx := -1
+	if s.activeBaseURL != nil {
+		for i, u := range candidates {
+			if u.String() == s.activeBaseURL.String() {
+				idx = i
+				break
+			}
+		}
+	}
+
+	now := timeNow()
+	switch {
+	case s.activeBaseURLSince.IsZero() || (s.activeBaseURL != nil && idx == -1):
+		// first use or the proxy stores changed, optimistically
+		// start from the most preferred one
+		s.activeBaseURL = candidates[0]
+		s.activeBaseURLSince = now
+		s.activeBaseURLFailure = nil
+		return nil, nil, false
+	case s.activeBaseURLFailure != nil:
+	case idx != 0 && now.Sub(s.activeBaseURLSince) > storeRecheckInterval:
+	default:
+		return nil, nil, false
+	}
+
+	prev, reason = s.activeBaseURL, s.activeBaseURLFailure
+	var next *url.URL
+	for _, u := range candidates {
+		if reason != nil && u == candidates[idx] {
+			continue
+		}
+		if err := s.storeHealthCheck(u); err != nil {
+			logger.Noticef("cannot reach store at %s: %v", u, err)
+			continue
+		}
+		next = u
+		break
+	}
+
+	s.activeBaseURLSince = now
+	s.activeBaseURLFailure = nil
+	if sameBaseURL(next, prev) {
+		return nil, nil, false
+	}
+	s.activeBaseURL = next
+	return prev, reason, true
+}
+
+func sameBaseURL(u1, u2 *url.URL) bool {
+	if u1 == nil || u2 == nil {
+		return u1 == u2
+	}
+	return u1.String() == u2.String()
+}
+
+// storeHealthCheck checks whether the store at the given base URL is
+// reachable, any HTTP response is good enough for this.
+func (s *Store) storeHealthCheck(u *url.URL) error {
+	cli := s.newHTTPClient(&httputil.ClientOptions{
+		Timeout: storeHealthCheckTimeout,
+	})
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", s.userAgent)
+	resp, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// baseURLFailed records that a request to u failed with the given
+// error, if u is under the active proxy store base URL the next store
+// operation will look for a reachable alternative.
+func (s *Store) baseURLFailed(ctx context.Context, u *url.URL, err error) {
+	if ctx != nil && ctx.Err() != nil {
+		// cancelled, the store is not at fault
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	s.baseURLMu.Lock()
+	defer s.baseURLMu.Unlock()
+	active := s.activeBaseURL
+	if active == nil || s.activeBaseURLFailure != nil {
+		return
+	}
+	if u.Scheme != active.Scheme || u.Host != active.Host || !strings.HasPrefix(u.Path, active.Path) {
+		return
+	}
+	s.activeBaseURLFailure = err
+}
+
+// ActiveBaseURL returns the base URL currently used for store requests.
+func (s *Store) ActiveBaseURL() *url.URL {
+	return s.baseURL(s.cfg.StoreBaseURL)
+}
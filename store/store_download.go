@@ -140,12 +140,14 @@ func (s *Store) useDeltas() (use bool) {
 	if err != nil {
 		// no xdelta3 in the env, so no deltas
 		logger.Noticef("no host system xdelta3 available to use deltas")
+		s.noDeltasReason = errors.New("no xdelta3 available from the system snap or the host")
 		return false
 	}
 
 	if err := exec.Command(loc, "config").Run(); err != nil {
 		// xdelta3 in the env failed to run, so no deltas
 		logger.Noticef("unable to use host system xdelta3, running config command failed: %v", err)
+		s.noDeltasReason = fmt.Errorf("cannot run host system xdelta3: %v", err)
 		return false
 	}
 
@@ -181,10 +183,39 @@ func (e HashError) Error() string {
 	return fmt.Sprintf("sha3-384 mismatch for %q: got %s but expected %s", e.name, e.sha3_384, e.targetSha3_384)
 }
 
+// DeltaOutcome describes how a delta advertised by the store for a snap was
+// used when downloading it.
+type DeltaOutcome struct {
+	Format       string
+	FromRevision int
+	ToRevision   int
+	// Applied is set when the snap was generated from the delta.
+	Applied bool
+	// FallbackReason is set when the delta could not be used, in which
+	// case the snap is downloaded in full.
+	FallbackReason error
+}
+
 type DownloadOptions struct {
 	RateLimit           int64
 	Scheduled           bool
 	LeavePartialOnError bool
+	// OnDelta, if set, is called with the outcome of trying to use the
+	// delta advertised by the store, if any.
+	OnDelta func(DeltaOutcome)
+}
+
+func (opts *DownloadOptions) reportDelta(deltaInfo *snap.DeltaInfo, fallbackReason error) {
+	if opts == nil || opts.OnDelta == nil {
+		return
+	}
+	opts.OnDelta(DeltaOutcome{
+		Format:         deltaInfo.Format,
+		FromRevision:   deltaInfo.FromRevision,
+		ToRevision:     deltaInfo.ToRevision,
+		Applied:        fallbackReason == nil,
+		FallbackReason: fallbackReason,
+	})
 }
 
 // Download downloads the snap addressed by download info and returns its
@@ -208,20 +239,35 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		return nil
 	}
 
-	if s.useDeltas() {
+	partialPath := targetPath + ".partial"
+
+	// a download of the full snap that was interrupted is resumed rather
+	// than started over from a delta
+	if len(downloadInfo.Deltas) == 1 && !osutil.FileExists(partialPath) {
+		deltaInfo := &downloadInfo.Deltas[0]
 		logger.Debugf("Available deltas returned by store: %v", downloadInfo.Deltas)
 
-		if len(downloadInfo.Deltas) == 1 {
-			err := s.downloadAndApplyDelta(name, targetPath, downloadInfo, pbar, user, dlOpts)
+		var err error
+		if s.useDeltas() {
+			err = s.downloadAndApplyDelta(ctx, name, targetPath, downloadInfo, pbar, user, dlOpts)
 			if err == nil {
-				return nil
+				dlOpts.reportDelta(deltaInfo, nil)
+				return s.cacher.Put(downloadInfo.Sha3_384, targetPath)
+			}
+			if cancelled(ctx) {
+				// the partial delta is kept to resume from
+				return err
 			}
 			// We revert to normal downloads if there is any error.
 			logger.Noticef("Cannot download or apply deltas for %s: %v", name, err)
+		} else {
+			err = s.noDeltasReason
+		}
+		if err != nil {
+			dlOpts.reportDelta(deltaInfo, err)
 		}
 	}
 
-	partialPath := targetPath + ".partial"
 	w, err := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE, 0600)
 	if err != nil {
 		return err
@@ -647,8 +693,9 @@ func doDownloadReqImpl(ctx context.Context, storeURL *url.URL, cdnHeader string,
 	return s.doRequest(ctx, cli, reqOptions, user)
 }
 
-// downloadDelta downloads the delta for the preferred format, returning the path.
-func (s *Store) downloadDelta(deltaName string, downloadInfo *snap.DownloadInfo, w io.ReadWriteSeeker, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) error {
+// downloadDelta downloads the delta for the preferred format, resuming at the
+// given offset.
+func (s *Store) downloadDelta(ctx context.Context, deltaName string, downloadInfo *snap.DownloadInfo, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) error {
 
 	if len(downloadInfo.Deltas) != 1 {
 		return errors.New("store returned more than one download delta")
@@ -662,7 +709,26 @@ func (s *Store) downloadDelta(deltaName string, downloadInfo *snap.DownloadInfo,
 
 	url := deltaInfo.DownloadURL
 
-	return download(context.TODO(), deltaName, deltaInfo.Sha3_384, url, user, s, w, 0, pbar, dlOpts)
+	if deltaInfo.Size > 0 && resume >= deltaInfo.Size {
+		// the delta was fully downloaded already, check it
+		h := crypto.SHA3_384.New()
+		if _, err := w.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.Copy(h, w); err != nil {
+			return err
+		}
+		actualSha3 := fmt.Sprintf("%x", h.Sum(nil))
+		if deltaInfo.Sha3_384 != "" && deltaInfo.Sha3_384 != actualSha3 {
+			return HashError{deltaName, actualSha3, deltaInfo.Sha3_384}
+		}
+		return nil
+	}
+	if resume > 0 {
+		logger.Debugf("Resuming download of %s at %d.", deltaName, resume)
+	}
+
+	return download(ctx, deltaName, deltaInfo.Sha3_384, url, user, s, w, resume, pbar, dlOpts)
 }
 
 // applyDelta generates a target snap from a previously downloaded snap and a downloaded delta.
@@ -725,13 +791,20 @@ func (s *Store) applyDeltaImpl(name string, deltaPath string, deltaInfo *snap.De
 }
 
 // downloadAndApplyDelta downloads and then applies the delta to the current snap.
-func (s *Store) downloadAndApplyDelta(name, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) error {
+//
+// The partially downloaded delta is kept when ctx is cancelled, so that the
+// download can be resumed.
+func (s *Store) downloadAndApplyDelta(ctx context.Context, name, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) (err error) {
 	deltaInfo := &downloadInfo.Deltas[0]
 
+	if downloadInfo.Sha3_384 == "" {
+		return fmt.Errorf("cannot verify the snap generated from the delta: no sha3-384 for revision %d", deltaInfo.ToRevision)
+	}
+
 	deltaPath := fmt.Sprintf("%s.%s-%d-to-%d.partial", targetPath, deltaInfo.Format, deltaInfo.FromRevision, deltaInfo.ToRevision)
 	deltaName := fmt.Sprintf(i18n.G("%s (delta)"), name)
 
-	w, err := os.OpenFile(deltaPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	w, err := os.OpenFile(deltaPath, os.O_RDWR|os.O_CREATE, 0600)
 	if err != nil {
 		return err
 	}
@@ -739,10 +812,16 @@ func (s *Store) downloadAndApplyDelta(name, targetPath string, downloadInfo *sna
 		if cerr := w.Close(); cerr != nil && err == nil {
 			err = cerr
 		}
-		os.Remove(deltaPath)
+		if !cancelled(ctx) {
+			os.Remove(deltaPath)
+		}
 	}()
+	resume, err := w.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
 
-	err = s.downloadDelta(deltaName, downloadInfo, w, pbar, user, dlOpts)
+	err = s.downloadDelta(ctx, deltaName, downloadInfo, w, resume, pbar, user, dlOpts)
 	if err != nil {
 		return err
 	}
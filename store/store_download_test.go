@@ -608,7 +608,7 @@ func (s *storeDownloadSuite) TestDownloadDelta(c *C) {
 			authedUser = nil
 		}
 
-		err = sto.DownloadDelta("snapname", &testCase.info, w, nil, authedUser, &store.DownloadOptions{Scheduled: true})
+		err = sto.DownloadDelta("snapname", &testCase.info, w, 0, nil, authedUser, &store.DownloadOptions{Scheduled: true})
 
 		if testCase.expectError {
 			c.Assert(err, NotNil)
@@ -619,6 +619,36 @@ func (s *storeDownloadSuite) TestDownloadDelta(c *C) {
 	}
 }
 
+func (s *storeDownloadSuite) TestDownloadDeltaAlreadyDownloaded(c *C) {
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, _ *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		c.Fatalf("unexpected download")
+		return nil
+	})
+	defer restore()
+
+	w, err := ioutil.TempFile("", "")
+	c.Assert(err, IsNil)
+	defer os.Remove(w.Name())
+	defer w.Close()
+	_, err = w.Write([]byte("delta-content"))
+	c.Assert(err, IsNil)
+
+	info := &snap.DownloadInfo{
+		Deltas: []snap.DeltaInfo{{
+			DownloadURL: "delta-url",
+			Format:      "xdelta3",
+			Size:        int64(len("delta-content")),
+			Sha3_384:    fmt.Sprintf("%x", sha3.Sum384([]byte("delta-content"))),
+		}},
+	}
+	err = s.store.DownloadDelta("snapname", info, w, int64(len("delta-content")), nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	info.Deltas[0].Sha3_384 = "other-sha3"
+	err = s.store.DownloadDelta("snapname", info, w, int64(len("delta-content")), nil, nil, nil)
+	c.Assert(err, FitsTypeOf, store.HashError{})
+}
+
 var applyDeltaTests = []struct {
 	deltaInfo       snap.DeltaInfo
 	currentRevision uint
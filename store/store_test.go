@@ -240,8 +240,11 @@ type testDauthContext struct {
 
 	user *auth.UserState
 
-	proxyStoreID  string
-	proxyStoreURL *url.URL
+	proxyStoreID   string
+	proxyStoreURL  *url.URL
+	proxyStoreURLs []*url.URL
+
+	storeURLSwitches []string
 
 	storeID string
 
@@ -317,6 +320,20 @@ func (dac *testDauthContext) ProxyStoreParams(defaultURL *url.URL) (string, *url
 	return "", defaultURL, nil
 }
 
+func (dac *testDauthContext) ProxyStoreURLs() ([]*url.URL, error) {
+	if dac.proxyStoreURLs != nil {
+		return dac.proxyStoreURLs, nil
+	}
+	if dac.proxyStoreURL != nil {
+		return []*url.URL{dac.proxyStoreURL}, nil
+	}
+	return nil, nil
+}
+
+func (dac *testDauthContext) StoreURLSwitched(from, to *url.URL, reason error) {
+	dac.storeURLSwitches = append(dac.storeURLSwitches, fmt.Sprintf("%s -> %s: %v", from, to, reason))
+}
+
 func (dac *testDauthContext) StoreOffline() (bool, error) {
 	return dac.storeOffline, nil
 }
@@ -1855,6 +1872,153 @@ func (s *storeTestSuite) TestProxyStoreFromAuthContextURLFallback(c *C) {
 	c.Check(result.InstanceName(), Equals, "hello-world")
 }
 
+func (s *storeTestSuite) TestProxyStoresFailover(c *C) {
+	n := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			// health check
+			w.WriteHeader(404)
+			return
+		}
+		assertRequest(c, r, "GET", infoPathPattern)
+		n++
+
+		w.WriteHeader(200)
+		io.WriteString(w, mockInfoJSON)
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	deadServer := httptest.NewServer(nil)
+	deadServer.Close()
+
+	mockServerURL, _ := url.Parse(mockServer.URL)
+	deadServerURL, _ := url.Parse(deadServer.URL)
+	nowhereURL, err := url.Parse("http://nowhere.invalid")
+	c.Assert(err, IsNil)
+	cfg := store.DefaultConfig()
+	cfg.StoreBaseURL = nowhereURL
+	dauthCtx := &testDauthContext{
+		c:              c,
+		device:         s.device,
+		proxyStoreURLs: []*url.URL{deadServerURL, mockServerURL},
+	}
+	sto := store.New(cfg, dauthCtx)
+
+	spec := store.SnapSpec{
+		Name: "hello-world",
+	}
+	// the most preferred proxy store is tried first
+	c.Check(sto.ActiveBaseURL(), DeepEquals, deadServerURL)
+	_, err = sto.SnapInfo(s.ctx, spec, nil)
+	c.Assert(err, NotNil)
+	c.Check(dauthCtx.storeURLSwitches, HasLen, 0)
+
+	// the next operation switches to the next reachable one
+	result, err := sto.SnapInfo(s.ctx, spec, nil)
+	c.Assert(err, IsNil)
+	c.Check(result.InstanceName(), Equals, "hello-world")
+	c.Check(n, Equals, 1)
+	c.Assert(dauthCtx.storeURLSwitches, HasLen, 1)
+	c.Check(dauthCtx.storeURLSwitches[0], Matches, fmt.Sprintf("%s -> %s: .*connection refused", deadServer.URL, mockServer.URL))
+
+	// and sticks to it
+	_, err = sto.SnapInfo(s.ctx, spec, nil)
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 2)
+	c.Check(sto.ActiveBaseURL(), DeepEquals, mockServerURL)
+	c.Check(dauthCtx.storeURLSwitches, HasLen, 1)
+}
+
+func (s *storeTestSuite) TestProxyStoresAllUnreachableFallbackToDefault(c *C) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequest(c, r, "GET", infoPathPattern)
+
+		w.WriteHeader(200)
+		io.WriteString(w, mockInfoJSON)
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	deadServer := httptest.NewServer(nil)
+	deadServer.Close()
+
+	mockServerURL, _ := url.Parse(mockServer.URL)
+	deadServerURL, _ := url.Parse(deadServer.URL)
+	cfg := store.DefaultConfig()
+	cfg.StoreBaseURL = mockServerURL
+	dauthCtx := &testDauthContext{
+		c:              c,
+		device:         s.device,
+		proxyStoreURLs: []*url.URL{deadServerURL},
+	}
+	sto := store.New(cfg, dauthCtx)
+
+	spec := store.SnapSpec{
+		Name: "hello-world",
+	}
+	_, err := sto.SnapInfo(s.ctx, spec, nil)
+	c.Assert(err, NotNil)
+
+	result, err := sto.SnapInfo(s.ctx, spec, nil)
+	c.Assert(err, IsNil)
+	c.Check(result.InstanceName(), Equals, "hello-world")
+	c.Check(sto.ActiveBaseURL(), DeepEquals, mockServerURL)
+	c.Assert(dauthCtx.storeURLSwitches, HasLen, 1)
+	c.Check(dauthCtx.storeURLSwitches[0], Matches, fmt.Sprintf("%s -> %s: .*connection refused", deadServer.URL, mockServer.URL))
+}
+
+func (s *storeTestSuite) TestProxyStoresRecheckPreferred(c *C) {
+	up := false
+	preferredServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			// make the connection fail
+			panic(http.ErrAbortHandler)
+		}
+		w.WriteHeader(404)
+	}))
+	defer preferredServer.Close()
+	fallbackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	}))
+	defer fallbackServer.Close()
+
+	now := time.Now()
+	restore := store.MockTimeNow(func() time.Time { return now })
+	defer restore()
+	restore = store.MockStoreRecheckInterval(time.Hour)
+	defer restore()
+
+	preferredURL, _ := url.Parse(preferredServer.URL)
+	fallbackURL, _ := url.Parse(fallbackServer.URL)
+	nowhereURL, err := url.Parse("http://nowhere.invalid")
+	c.Assert(err, IsNil)
+	cfg := store.DefaultConfig()
+	cfg.StoreBaseURL = nowhereURL
+	dauthCtx := &testDauthContext{
+		c:              c,
+		device:         s.device,
+		proxyStoreURLs: []*url.URL{preferredURL, fallbackURL},
+	}
+	sto := store.New(cfg, dauthCtx)
+
+	_, err = sto.SnapInfo(s.ctx, store.SnapSpec{Name: "hello-world"}, nil)
+	c.Assert(err, NotNil)
+	c.Check(sto.ActiveBaseURL(), DeepEquals, fallbackURL)
+	c.Check(dauthCtx.storeURLSwitches, HasLen, 1)
+
+	// not rechecked before the interval has passed
+	up = true
+	now = now.Add(30 * time.Minute)
+	c.Check(sto.ActiveBaseURL(), DeepEquals, fallbackURL)
+	c.Check(dauthCtx.storeURLSwitches, HasLen, 1)
+
+	now = now.Add(time.Hour)
+	c.Check(sto.ActiveBaseURL(), DeepEquals, preferredURL)
+	c.Assert(dauthCtx.storeURLSwitches, HasLen, 2)
+	c.Check(dauthCtx.storeURLSwitches[1], Equals, fmt.Sprintf("%s -> %s: <nil>", fallbackServer.URL, preferredServer.URL))
+}
+
 func (s *storeTestSuite) TestInfoOopses(c *C) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assertRequest(c, r, "GET", infoPathPattern)
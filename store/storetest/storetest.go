@@ -22,6 +22,7 @@ package storetest
 import (
 	"context"
 	"io"
+	"net/url"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/client"
@@ -111,6 +112,10 @@ func (Store) ConnectivityCheck() (map[string]bool, error) {
 	panic("ConnectivityCheck not expected")
 }
 
+func (Store) ActiveBaseURL() *url.URL {
+	panic("ActiveBaseURL not expected")
+}
+
 func (Store) CreateCohorts(context.Context, []string) (map[string]string, error) {
 	panic("CreateCohort not expected")
 }
@@ -367,6 +367,16 @@ func (tsto *ToolingStore) DownloadMany(toDownload []SnapToDownload, curSnaps []*
 	return downloadedSnaps, nil
 }
 
+// assertionFetcherOptions are used by the fetchers of assertions from the
+// store, independent prerequisites are retrieved concurrently and
+// assertions which are present in the database already are not retrieved
+// again, as it is common for the assertions of many snaps to share their
+// prerequisites when building seeds.
+var assertionFetcherOptions = &asserts.FetcherOptions{
+	Workers:     4,
+	SkipPresent: true,
+}
+
 // AssertionFetcher creates an asserts.Fetcher for assertions, the fetcher will
 // add assertions in the given database and after that also call save for each of them.
 func (tsto *ToolingStore) AssertionFetcher(db *asserts.Database, save func(asserts.Assertion) error) asserts.Fetcher {
@@ -384,7 +394,7 @@ func (tsto *ToolingStore) AssertionFetcher(db *asserts.Database, save func(asser
 		}
 		return save(a)
 	}
-	return asserts.NewFetcher(db, retrieve, save2)
+	return asserts.NewFetcherWithOptions(db, retrieve, save2, assertionFetcherOptions)
 }
 
 // AssertionSequenceFormingFetcher creates an asserts.SequenceFormingFetcher for
@@ -408,7 +418,7 @@ func (tsto *ToolingStore) AssertionSequenceFormingFetcher(db *asserts.Database,
 		}
 		return save(a)
 	}
-	return asserts.NewSequenceFormingFetcher(db, retrieve, retrieveSeq, save2)
+	return asserts.NewSequenceFormingFetcherWithOptions(db, retrieve, retrieveSeq, save2, assertionFetcherOptions)
 }
 
 // Find provides the snapsserts.Finder interface for snapasserts.DerviceSideInfo
@@ -385,6 +385,17 @@ func (ts ClockSpan) ClockSpans() []ClockSpan {
 type Schedule struct {
 	WeekSpans  []WeekSpan
 	ClockSpans []ClockSpan
+	// Location is the timezone of the schedule, when nil the schedule is in
+	// local time.
+	Location *time.Location
+}
+
+// inLocation returns t in the timezone of the schedule.
+func (sched *Schedule) inLocation(t time.Time) time.Time {
+	if sched.Location == nil {
+		return t
+	}
+	return t.In(sched.Location)
 }
 
 func (sched *Schedule) String() string {
@@ -407,6 +418,11 @@ func (sched *Schedule) String() string {
 		}
 		buf.WriteString(span.String())
 	}
+
+	if sched.Location != nil {
+		buf.WriteByte(' ')
+		buf.WriteString(sched.Location.String())
+	}
 	return buf.String()
 }
 
@@ -455,6 +471,7 @@ func (s ScheduleWindow) IsZero() bool {
 // Next returns the earliest window after last according to the schedule.
 func (sched *Schedule) Next(last time.Time) ScheduleWindow {
 	now := timeNow()
+	last = sched.inLocation(last)
 
 	tspans := sched.flattenedClockSpans()
 
@@ -648,6 +665,8 @@ func ParseLegacySchedule(scheduleSpec string) ([]*Schedule, error) {
 // mon-wed1 (from the 1st Wednesday of the month to the prior Monday)
 // mon1 (1st Monday of the month)
 // mon1-mon (from the 1st Monday of the month to the following Monday)
+// mon,2:00 Europe/Berlin (Monday at 2:00 in Berlin, each event set can end
+// with a timezone, otherwise the local time is used)
 //
 // Returns a slice of schedules or an error if parsing failed
 func ParseSchedule(scheduleSpec string) ([]*Schedule, error) {
@@ -803,8 +822,33 @@ const (
 	countToken  = "/"
 )
 
+// parseTimezone parses the optional timezone suffix of an event set, it
+// returns the event set without it.
+func parseTimezone(s string) (eventSet string, loc *time.Location, err error) {
+	idx := strings.IndexByte(s, ' ')
+	if idx < 0 {
+		return s, nil, nil
+	}
+	eventSet, tz := s[:idx], s[idx+1:]
+	// "Local" is accepted by time.LoadLocation but is not a timezone
+	// systemd knows about
+	if tz == "" || tz == "Local" || strings.ContainsRune(tz, ' ') {
+		return "", nil, fmt.Errorf("cannot parse %q: %q is not a valid timezone", s, tz)
+	}
+	loc, err = time.LoadLocation(tz)
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot parse %q: %q is not a valid timezone", s, tz)
+	}
+	return eventSet, loc, nil
+}
+
 // Parse event set into a Schedule
 func parseEventSet(s string) (*Schedule, error) {
+	s, loc, err := parseTimezone(s)
+	if err != nil {
+		return nil, err
+	}
+
 	var fragments []string
 	// split eventset into fragments
 	//     eventset = wdaylist / timelist / wdaylist "," timelist
@@ -854,6 +898,7 @@ func parseEventSet(s string) (*Schedule, error) {
 			return nil, fmt.Errorf("cannot parse %q: invalid schedule fragment", fragment)
 		}
 	}
+	schedule.Location = loc
 
 	return &schedule, nil
 }
@@ -862,6 +907,7 @@ func parseEventSet(s string) (*Schedule, error) {
 // the schedule. A single time schedule eg. '10:00' is treated as spanning the
 // time [10:00, 10:01)
 func (sched *Schedule) Includes(t time.Time) bool {
+	t = sched.inLocation(t)
 	if len(sched.WeekSpans) > 0 {
 		var weekMatch bool
 		for _, week := range sched.WeekSpans {
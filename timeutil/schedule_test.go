@@ -105,6 +105,16 @@ func (ts *timeutilSuite) TestScheduleString(c *C) {
 					{Start: timeutil.Week{Weekday: time.Monday}, End: timeutil.Week{Weekday: time.Monday}}},
 			},
 			"mon,13:41-14:59",
+		}, {
+			timeutil.Schedule{
+				ClockSpans: []timeutil.ClockSpan{
+					{Start: timeutil.Clock{Hour: 13, Minute: 41}, End: timeutil.Clock{Hour: 14, Minute: 59}},
+				},
+				WeekSpans: []timeutil.WeekSpan{
+					{Start: timeutil.Week{Weekday: time.Monday}, End: timeutil.Week{Weekday: time.Monday}}},
+				Location: time.UTC,
+			},
+			"mon,13:41-14:59 UTC",
 		}, {
 			timeutil.Schedule{
 				ClockSpans: []timeutil.ClockSpan{
@@ -343,6 +353,11 @@ func (ts *timeutilSuite) TestParseSchedule(c *C) {
 		{"9:00-11:00/3/3/3", nil, `cannot parse "9:00-11:00/3/3/3": not a valid interval`},
 		{"9:00-11:00///3", nil, `cannot parse "9:00-11:00///3": not a valid interval`},
 		{"9:00-9:00-10:00/3", nil, `cannot parse "9:00-9:00-10:00/3": not a valid time`},
+		{"mon,9:00 Mars/Olympus", nil, `cannot parse "mon,9:00 Mars/Olympus": "Mars/Olympus" is not a valid timezone`},
+		{"mon,9:00 ", nil, `cannot parse "mon,9:00 ": "" is not a valid timezone`},
+		{"mon,9:00 Local", nil, `cannot parse "mon,9:00 Local": "Local" is not a valid timezone`},
+		{"mon,9:00 UTC UTC", nil, `cannot parse "mon,9:00 UTC UTC": "UTC UTC" is not a valid timezone`},
+		{"mon,9:00,,fri,9:00 Mars/Olympus", nil, `cannot parse "fri,9:00 Mars/Olympus": "Mars/Olympus" is not a valid timezone`},
 		{"9:00,,,9:00-10:00/3", nil, `cannot parse ",9:00-10:00/3": not a valid fragment`},
 		{",,,", nil, `cannot parse "": not a valid fragment`},
 		{",,", nil, `cannot parse "": not a valid fragment`},
@@ -493,6 +508,20 @@ func (ts *timeutilSuite) TestParseSchedule(c *C) {
 				WeekSpans: []timeutil.WeekSpan{
 					{Start: timeutil.Week{Weekday: time.Monday}, End: timeutil.Week{Weekday: time.Monday, Pos: 2}}},
 			}},
+		}, {
+			in: "mon,9:00 UTC,,fri,10:00",
+			expected: []*timeutil.Schedule{{
+				ClockSpans: []timeutil.ClockSpan{
+					{Start: timeutil.Clock{Hour: 9}, End: timeutil.Clock{Hour: 9}}},
+				WeekSpans: []timeutil.WeekSpan{
+					{Start: timeutil.Week{Weekday: time.Monday}, End: timeutil.Week{Weekday: time.Monday}}},
+				Location: time.UTC,
+			}, {
+				ClockSpans: []timeutil.ClockSpan{
+					{Start: timeutil.Clock{Hour: 10}, End: timeutil.Clock{Hour: 10}}},
+				WeekSpans: []timeutil.WeekSpan{
+					{Start: timeutil.Week{Weekday: time.Friday}, End: timeutil.Week{Weekday: time.Friday}}},
+			}},
 		},
 	} {
 		c.Logf("trying %+v", t)
@@ -1004,6 +1033,43 @@ func (ts *timeutilSuite) TestScheduleIncludes(c *C) {
 	}
 }
 
+func (ts *timeutilSuite) TestScheduleIncludesTimezone(c *C) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	c.Assert(err, IsNil)
+
+	sched, err := timeutil.ParseSchedule("mon,9:00-10:00 Europe/Berlin")
+	c.Assert(err, IsNil)
+	c.Assert(sched, HasLen, 1)
+	c.Check(sched[0].Location.String(), Equals, "Europe/Berlin")
+
+	// Mon 9:30 in Berlin (CET) is 8:30 UTC
+	now := time.Date(2017, 2, 6, 8, 30, 0, 0, time.UTC)
+	c.Check(timeutil.Includes(sched, now), Equals, true)
+	c.Check(timeutil.Includes(sched, now.In(berlin)), Equals, true)
+	// Mon 9:30 UTC is 10:30 in Berlin
+	now = time.Date(2017, 2, 6, 9, 30, 0, 0, time.UTC)
+	c.Check(timeutil.Includes(sched, now), Equals, false)
+	// Sun 23:30 UTC is already Monday in Berlin, but too early
+	now = time.Date(2017, 2, 5, 23, 30, 0, 0, time.UTC)
+	c.Check(timeutil.Includes(sched, now), Equals, false)
+}
+
+func (ts *timeutilSuite) TestScheduleNextTimezone(c *C) {
+	sched, err := timeutil.ParseSchedule("9:00 UTC")
+	c.Assert(err, IsNil)
+
+	// 8:00 UTC, expressed in a timezone 5 hours ahead
+	loc := time.FixedZone("east", 5*60*60)
+	last := time.Date(2017, 2, 6, 13, 0, 0, 0, loc)
+	restore := timeutil.MockTimeNow(func() time.Time { return last })
+	defer restore()
+
+	// the next event is at 9:00 UTC on the same day, not at 9:00 in the
+	// timezone of last
+	next := timeutil.Next(sched, last, 24*time.Hour)
+	c.Check(next, Equals, time.Hour)
+}
+
 func (ts *timeutilSuite) TestClockSpans(c *C) {
 	for _, t := range []struct {
 		clockspan  string
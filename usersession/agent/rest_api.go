@@ -34,6 +34,7 @@ import (
 	"github.com/snapcore/snapd/desktop/notification"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/usersession/client"
 )
@@ -42,6 +43,7 @@ var restApi = []*Command{
 	rootCmd,
 	sessionInfoCmd,
 	serviceControlCmd,
+	serviceStatusCmd,
 	pendingRefreshNotificationCmd,
 	finishRefreshNotificationCmd,
 }
@@ -62,6 +64,11 @@ var (
 		POST: postServiceControl,
 	}
 
+	serviceStatusCmd = &Command{
+		Path: "/v1/service-status",
+		GET:  serviceStatus,
+	}
+
 	pendingRefreshNotificationCmd = &Command{
 		Path: "/v1/notifications/pending-refresh",
 		POST: postPendingRefreshNotification,
@@ -220,6 +227,37 @@ func postServiceControl(c *Command, r *http.Request) Response {
 	return impl(&inst, sysd)
 }
 
+func serviceStatus(c *Command, r *http.Request) Response {
+	services := strutil.CommaSeparatedList(r.URL.Query().Get("services"))
+	if len(services) == 0 {
+		return BadRequest("no services specified")
+	}
+	// Refuse to query non-snap services
+	for _, service := range services {
+		if !strings.HasPrefix(service, "snap.") {
+			return InternalError("cannot query non-snap service %v", service)
+		}
+	}
+
+	// Prevent multiple systemd actions from being carried out simultaneously
+	systemdLock.Lock()
+	defer systemdLock.Unlock()
+	sysd := systemd.New(systemd.UserMode, noopReporter{})
+	sts, err := sysd.Status(services)
+	if err != nil {
+		return InternalError("cannot get status of services: %v", err)
+	}
+	statuses := make([]client.ServiceUnitStatus, 0, len(sts))
+	for _, st := range sts {
+		statuses = append(statuses, client.ServiceUnitStatus{
+			Name:    st.Name,
+			Enabled: st.Enabled,
+			Active:  st.Active,
+		})
+	}
+	return SyncResponse(statuses)
+}
+
 func postPendingRefreshNotification(c *Command, r *http.Request) Response {
 	if ok, resp := validateJSONRequest(r); !ok {
 		return resp
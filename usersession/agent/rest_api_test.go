@@ -361,6 +361,101 @@ func (s *restSuite) TestServicesStopReportsError(c *C) {
 	})
 }
 
+func (s *restSuite) TestServiceStatus(c *C) {
+	// the agent.ServiceStatus end point only supports GET requests
+	c.Assert(agent.ServiceStatusCmd.GET, NotNil)
+	c.Check(agent.ServiceStatusCmd.PUT, IsNil)
+	c.Check(agent.ServiceStatusCmd.POST, IsNil)
+	c.Check(agent.ServiceStatusCmd.DELETE, IsNil)
+
+	c.Check(agent.ServiceStatusCmd.Path, Equals, "/v1/service-status")
+
+	restore := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		return []byte(`Id=snap.foo.service
+Names=snap.foo.service
+ActiveState=active
+UnitFileState=enabled
+Type=simple
+NeedDaemonReload=no
+
+Id=snap.bar.service
+Names=snap.bar.service
+ActiveState=inactive
+UnitFileState=disabled
+Type=simple
+NeedDaemonReload=no
+`), nil
+	})
+	defer restore()
+
+	req := httptest.NewRequest("GET", "/v1/service-status?services=snap.foo.service,snap.bar.service", nil)
+	rec := httptest.NewRecorder()
+	agent.ServiceStatusCmd.GET(agent.ServiceStatusCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 200)
+	c.Check(rec.Header().Get("Content-Type"), Equals, "application/json")
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+	c.Check(rsp.Result, DeepEquals, []interface{}{
+		map[string]interface{}{"name": "snap.foo.service", "enabled": true, "active": true},
+		map[string]interface{}{"name": "snap.bar.service", "enabled": false, "active": false},
+	})
+
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "show", "--property=Id,ActiveState,UnitFileState,Type,Names,NeedDaemonReload", "snap.foo.service", "snap.bar.service"},
+	})
+}
+
+func (s *restSuite) TestServiceStatusNonSnap(c *C) {
+	req := httptest.NewRequest("GET", "/v1/service-status?services=snap.foo.service,not-snap.bar.service", nil)
+	rec := httptest.NewRecorder()
+	agent.ServiceStatusCmd.GET(agent.ServiceStatusCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 500)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeError)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{
+		"message": "cannot query non-snap service not-snap.bar.service",
+	})
+	c.Check(s.sysdLog, HasLen, 0)
+}
+
+func (s *restSuite) TestServiceStatusNoServices(c *C) {
+	req := httptest.NewRequest("GET", "/v1/service-status", nil)
+	rec := httptest.NewRecorder()
+	agent.ServiceStatusCmd.GET(agent.ServiceStatusCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 400)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeError)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{
+		"message": "no services specified",
+	})
+}
+
+func (s *restSuite) TestServiceStatusReportsError(c *C) {
+	restore := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		return nil, errors.New("mock systemctl error")
+	})
+	defer restore()
+
+	req := httptest.NewRequest("GET", "/v1/service-status?services=snap.foo.service", nil)
+	rec := httptest.NewRecorder()
+	agent.ServiceStatusCmd.GET(agent.ServiceStatusCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 500)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeError)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{
+		"message": "cannot get status of services: mock systemctl error",
+	})
+}
+
 func (s *restSuite) TestPostPendingRefreshNotificationMalformedContentType(c *C) {
 	req := httptest.NewRequest("POST", "/v1/notifications/pending-refresh", bytes.NewBufferString(""))
 	req.Header.Set("Content-Type", "text/plain/joke")
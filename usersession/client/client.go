@@ -31,6 +31,7 @@ import (
 	"net/url"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -307,6 +308,43 @@ func (client *Client) ServicesStop(ctx context.Context, services []string) (stop
 	return stopFailures, err
 }
 
+// ServiceUnitStatus holds the status of a service unit in the session of
+// a user.
+type ServiceUnitStatus struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Active  bool   `json:"active"`
+}
+
+// ServiceStatus returns the status of the given services in the session
+// of each user, indexed by uid.
+func (client *Client) ServiceStatus(ctx context.Context, services []string) (statuses map[int][]*ServiceUnitStatus, err error) {
+	query := url.Values{"services": []string{strings.Join(services, ",")}}
+	responses, err := client.doMany(ctx, "GET", "/v1/service-status", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses = make(map[int][]*ServiceUnitStatus)
+	for _, resp := range responses {
+		if resp.err != nil {
+			if err == nil {
+				err = resp.err
+			}
+			continue
+		}
+		var sts []*ServiceUnitStatus
+		if decodeErr := json.Unmarshal(resp.Result, &sts); decodeErr != nil {
+			if err == nil {
+				err = decodeErr
+			}
+			continue
+		}
+		statuses[resp.uid] = sts
+	}
+	return statuses, err
+}
+
 // PendingSnapRefreshInfo holds information about pending snap refresh provided to userd.
 type PendingSnapRefreshInfo struct {
 	InstanceName        string        `json:"instance-name"`
@@ -449,6 +449,59 @@ func (s *clientSuite) TestServicesStopFailure(c *C) {
 	})
 }
 
+func (s *clientSuite) TestServiceStatus(c *C) {
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v1/service-status")
+		c.Check(r.URL.Query().Get("services"), Equals, "service1.service,service2.service")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write([]byte(`{
+  "type": "sync",
+  "result": [
+    {"name": "service1.service", "enabled": true, "active": true},
+    {"name": "service2.service", "enabled": false, "active": false}
+  ]
+}`))
+	})
+	statuses, err := s.cli.ServiceStatus(context.Background(), []string{"service1.service", "service2.service"})
+	c.Assert(err, IsNil)
+	expected := []*client.ServiceUnitStatus{
+		{Name: "service1.service", Enabled: true, Active: true},
+		{Name: "service2.service"},
+	}
+	c.Check(statuses, DeepEquals, map[int][]*client.ServiceUnitStatus{
+		42:   expected,
+		1000: expected,
+	})
+}
+
+func (s *clientSuite) TestServiceStatusOneAgentFailure(c *C) {
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Host == "42" {
+			w.WriteHeader(500)
+			w.Write([]byte(`{
+  "type": "error",
+  "result": {
+    "message": "cannot get status of services: boom"
+  }
+}`))
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{
+  "type": "sync",
+  "result": [{"name": "service1.service", "enabled": true}]
+}`))
+	})
+	statuses, err := s.cli.ServiceStatus(context.Background(), []string{"service1.service"})
+	c.Check(err, ErrorMatches, "cannot get status of services: boom")
+	c.Check(statuses, DeepEquals, map[int][]*client.ServiceUnitStatus{
+		1000: {{Name: "service1.service", Enabled: true}},
+	})
+}
+
 func (s *clientSuite) TestPendingRefreshNotification(c *C) {
 	var n int32
 	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
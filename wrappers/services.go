@@ -1426,6 +1426,15 @@ ListenStream={{.ListenStream}}
 {{- if .SocketInfo.SocketMode}}
 SocketMode={{.SocketInfo.SocketMode | printf "%04o"}}
 {{- end}}
+{{- if .SocketInfo.SocketUser}}
+SocketUser={{.SocketInfo.SocketUser}}
+{{- end}}
+{{- if .SocketInfo.SocketGroup}}
+SocketGroup={{.SocketInfo.SocketGroup}}
+{{- end}}
+{{- if .SocketInfo.Backlog}}
+Backlog={{.SocketInfo.Backlog}}
+{{- end}}
 
 [Install]
 WantedBy={{.SocketsTarget}}
@@ -1528,6 +1537,8 @@ X-Snappy=yes
 Unit={{.ServiceFileName}}
 {{ range .Schedules }}OnCalendar={{ . }}
 {{ end }}
+{{- if .RandomizedDelay }}RandomizedDelaySec={{ .RandomizedDelay.Seconds }}
+{{ end }}
 [Install]
 WantedBy={{.TimersTarget}}
 `
@@ -1548,12 +1559,14 @@ WantedBy={{.TimersTarget}}
 		TimerName       string
 		MountUnit       string
 		Schedules       []string
+		RandomizedDelay timeout.Timeout
 	}{
 		App:             app,
 		ServiceFileName: filepath.Base(app.ServiceFile()),
 		TimersTarget:    systemd.TimersTarget,
 		TimerName:       app.Name,
 		Schedules:       schedules,
+		RandomizedDelay: app.Timer.RandDelay,
 	}
 	switch app.DaemonScope {
 	case snap.SystemDaemon:
@@ -1773,15 +1786,22 @@ func generateOnCalendarSchedules(schedule []*timeutil.Schedule) []string {
 			}
 		}
 
+		var timezone string
+		if sched.Location != nil {
+			// systemd accepts the timezone at the end of the
+			// calendar event
+			timezone = " " + sched.Location.String()
+		}
+
 		for _, day := range days {
 			if len(startTimes) == 0 {
 				// current schedule is days only
-				calendarEvents = append(calendarEvents, day)
+				calendarEvents = append(calendarEvents, day+timezone)
 				continue
 			}
 
 			for _, startTime := range startTimes {
-				calendarEvents = append(calendarEvents, fmt.Sprintf("%s %s", day, startTime))
+				calendarEvents = append(calendarEvents, fmt.Sprintf("%s %s%s", day, startTime, timezone))
 			}
 		}
 	}
@@ -1882,6 +1902,9 @@ type RestartServicesFlags struct {
 
 // Restart or reload active services in `svcs`.
 // If reload flag is set then "systemctl reload-or-restart" is attempted.
+// Enabled socket and timer units of the services which are not running
+// are started, and inactive activated services which were not explicitly
+// requested are left to be started by their activators.
 // The services mentioned in `explicitServices` should be a subset of the
 // services in svcs. The services included in explicitServices are always
 // restarted, regardless of their state. The services in the `svcs` argument
@@ -1921,6 +1944,15 @@ func RestartServices(apps []*snap.AppInfo, explicitServices []string,
 				logger.Noticef("not restarting disabled and inactive unit %s", unitName)
 				continue
 			}
+			if len(st.activators) > 0 {
+				// an inactive activated service is started on
+				// demand, make sure its activators are running
+				// instead of starting the service directly
+				if err := startInactiveActivators(sysd, st, tm); err != nil {
+					return err
+				}
+				continue
+			}
 		}
 
 		var err error
@@ -1936,10 +1968,34 @@ func RestartServices(apps []*snap.AppInfo, explicitServices []string,
 			// there is nothing we can do about failed service
 			return err
 		}
+		if err := startInactiveActivators(sysd, st, tm); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// startInactiveActivators starts the enabled socket and timer units of
+// the service which are not running, so that a restarted service can
+// still be activated by them.
+func startInactiveActivators(sysd systemd.Systemd, st *serviceStatus, tm timings.Measurer) error {
+	var units []string
+	for _, a := range st.activators {
+		if a.Enabled && !a.Active {
+			units = append(units, a.Name)
+		}
+	}
+	if len(units) == 0 {
+		return nil
+	}
+
+	var err error
+	timings.Run(tm, "start-activators", fmt.Sprintf("start activators of service %s", st.service.Name), func(nested timings.Measurer) {
+		err = sysd.Start(units)
+	})
+	return err
+}
+
 // ServicesEnableState returns a map of service names from the given snap,
 // together with their enable/disable status.
 func ServicesEnableState(s *snap.Info, inter Interacter) (map[string]bool, error) {
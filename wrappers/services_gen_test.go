@@ -691,6 +691,71 @@ WantedBy=timers.target
 	c.Assert(string(generatedWrapper), Equals, expectedService)
 }
 
+func (s *servicesWrapperGenSuite) TestServiceTimerUnitTimezoneRandDelay(c *C) {
+	const expectedServiceFmt = `[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Timer app for snap application snap.app
+Requires=%s-snap-44.mount
+After=%s-snap-44.mount
+X-Snappy=yes
+
+[Timer]
+Unit=snap.snap.app.service
+OnCalendar=*-*-* 10:00 Europe/Berlin
+OnCalendar=*-*-* 11:00 Europe/Berlin
+RandomizedDelaySec=900
+
+[Install]
+WantedBy=timers.target
+`
+
+	expectedService := fmt.Sprintf(expectedServiceFmt, mountUnitPrefix, mountUnitPrefix)
+	service := &snap.AppInfo{
+		Snap: &snap.Info{
+			SuggestedName: "snap",
+			Version:       "0.3.4",
+			SideInfo:      snap.SideInfo{Revision: snap.R(44)},
+		},
+		Name:        "app",
+		Command:     "bin/foo start",
+		Daemon:      "simple",
+		DaemonScope: snap.SystemDaemon,
+		StopTimeout: timeout.DefaultTimeout,
+		Timer: &snap.TimerInfo{
+			Timer:     "10:00-12:00/2 Europe/Berlin",
+			RandDelay: timeout.Timeout(15 * time.Minute),
+		},
+	}
+	service.Timer.App = service
+
+	generatedWrapper, err := wrappers.GenerateSnapTimerFile(service)
+	c.Assert(err, IsNil)
+	c.Assert(string(generatedWrapper), Equals, expectedService)
+}
+
+func (s *servicesWrapperGenSuite) TestServiceTimerUnitBadTimezone(c *C) {
+	service := &snap.AppInfo{
+		Snap: &snap.Info{
+			SuggestedName: "snap",
+			Version:       "0.3.4",
+			SideInfo:      snap.SideInfo{Revision: snap.R(44)},
+		},
+		Name:        "app",
+		Command:     "bin/foo start",
+		Daemon:      "simple",
+		DaemonScope: snap.SystemDaemon,
+		StopTimeout: timeout.DefaultTimeout,
+		Timer: &snap.TimerInfo{
+			Timer: "10:00,,mon,11:00 Mars/Olympus",
+		},
+	}
+	service.Timer.App = service
+
+	generatedWrapper, err := wrappers.GenerateSnapTimerFile(service)
+	c.Assert(err, ErrorMatches, `cannot parse "mon,11:00 Mars/Olympus": "Mars/Olympus" is not a valid timezone`)
+	c.Assert(generatedWrapper, IsNil)
+}
+
 func (s *servicesWrapperGenSuite) TestServiceTimerUnitBadTimer(c *C) {
 	service := &snap.AppInfo{
 		Snap: &snap.Info{
@@ -872,6 +937,9 @@ func (s *servicesWrapperGenSuite) TestTimerGenerateSchedules(c *C) {
 	}, {
 		in:       "mon2,mon1",
 		expected: []string{"Mon *-*-8,9,10,11,12,13,14", "Mon *-*-1,2,3,4,5,6,7"},
+	}, {
+		in:       "mon,10:00 Europe/Berlin,,fri UTC,,11:00",
+		expected: []string{"Mon *-*-* 10:00 Europe/Berlin", "Fri *-*-* UTC", "*-*-* 11:00"},
 	}} {
 		c.Logf("trying %+v", t)
 
@@ -3892,6 +3892,49 @@ ListenStream=%s
 	c.Check(sock3File, testutil.FileContains, expected)
 }
 
+func (s *servicesTestSuite) TestAddSnapSocketFilesOwnerAndBacklog(c *C) {
+	const snapYaml = `name: hello-snap
+version: 1.10
+system-usernames:
+  snap_daemon: shared
+apps:
+ svc1:
+  daemon: simple
+  plugs: [network-bind]
+  sockets:
+    sock1:
+      listen-stream: $SNAP_COMMON/sock1.socket
+      socket-mode: 0660
+      socket-user: snap_daemon
+      socket-group: snap_daemon
+      backlog: %d
+`
+	info := snaptest.MockSnap(c, fmt.Sprintf(snapYaml, 128), &snap.SideInfo{Revision: snap.R(12)})
+	sock1File := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.sock1.socket")
+
+	err := s.addSnapServices(info, false)
+	c.Assert(err, IsNil)
+
+	expected := `[Socket]
+Service=snap.hello-snap.svc1.service
+FileDescriptorName=sock1
+ListenStream=%s
+SocketMode=0660
+SocketUser=snap_daemon
+SocketGroup=snap_daemon
+Backlog=%d
+
+`
+	sockPath := filepath.Join(s.tempdir, "/var/snap/hello-snap/common/sock1.socket")
+	c.Check(sock1File, testutil.FileContains, fmt.Sprintf(expected, sockPath, 128))
+
+	// a refresh picks up the changes
+	info = snaptest.MockSnap(c, fmt.Sprintf(snapYaml, 512), &snap.SideInfo{Revision: snap.R(13)})
+	err = s.addSnapServices(info, false)
+	c.Assert(err, IsNil)
+	c.Check(sock1File, testutil.FileContains, fmt.Sprintf(expected, sockPath, 512))
+}
+
 func (s *servicesTestSuite) TestAddSnapUserSocketFiles(c *C) {
 	info := snaptest.MockSnap(c, packageHelloNoSrv+`
  svc1:
@@ -4901,6 +4944,103 @@ apps:
 	})
 }
 
+func (s *servicesTestSuite) TestRestartActivatedServices(c *C) {
+	const activatedServicesYaml = `name: test-snap
+version: 1.0
+apps:
+  svc1:
+    command: bin/foo
+    daemon: simple
+    plugs: [network-bind]
+    sockets:
+      sock1:
+        listen-stream: $SNAP_COMMON/sock1.socket
+  svc2:
+    command: bin/foo
+    daemon: oneshot
+    timer: 10:00-12:00
+`
+	srvFile1 := "snap.test-snap.svc1.service"
+	sockFile1 := "snap.test-snap.svc1.sock1.socket"
+	srvFile2 := "snap.test-snap.svc2.service"
+	timerFile2 := "snap.test-snap.svc2.timer"
+
+	info := snaptest.MockSnap(c, activatedServicesYaml, &snap.SideInfo{Revision: snap.R(1)})
+
+	states := map[string]systemdtest.ServiceState{
+		srvFile1:   {ActiveState: "active", UnitFileState: "static"},
+		sockFile1:  {ActiveState: "inactive", UnitFileState: "enabled"},
+		srvFile2:   {ActiveState: "inactive", UnitFileState: "static"},
+		timerFile2: {ActiveState: "inactive", UnitFileState: "enabled"},
+	}
+	r := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		if out := systemdtest.HandleMockAllUnitsActiveOutput(cmd, states); out != nil {
+			return out, nil
+		}
+		if cmd[0] == "show" && cmd[1] == "--property=Id,ActiveState,UnitFileState,Names" {
+			// activators are queried separately
+			var out []string
+			for _, unit := range cmd[2:] {
+				out = append(out, fmt.Sprintf("Id=%s\nNames=%s\nActiveState=%s\nUnitFileState=%s\n",
+					unit, unit, states[unit].ActiveState, states[unit].UnitFileState))
+			}
+			return []byte(strings.Join(out, "\n")), nil
+		}
+		return []byte("ActiveState=inactive\n"), nil
+	})
+	defer r()
+
+	err := s.addSnapServices(info, false)
+	c.Assert(err, IsNil)
+
+	services := info.Services()
+	sort.Sort(snap.AppInfoBySnapApp(services))
+
+	// the running service is restarted and its stopped socket started
+	// again, the inactive timer activated service is not run but its
+	// timer is started
+	s.sysdLog = nil
+	c.Assert(wrappers.RestartServices(services, nil, &wrappers.RestartServicesFlags{Reload: true, AlsoEnabledNonActive: true}, progress.Null, s.perfTimings), IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"show", "--property=Id,ActiveState,UnitFileState,Type,Names,NeedDaemonReload", srvFile1, srvFile2},
+		{"show", "--property=Id,ActiveState,UnitFileState,Names", sockFile1, timerFile2},
+		{"reload-or-restart", srvFile1},
+		{"start", sockFile1},
+		{"start", timerFile2},
+	})
+
+	// an explicitly mentioned activated service is restarted regardless
+	// of its state
+	s.sysdLog = nil
+	c.Assert(wrappers.RestartServices(services, []string{srvFile2}, &wrappers.RestartServicesFlags{AlsoEnabledNonActive: true}, progress.Null, s.perfTimings), IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"show", "--property=Id,ActiveState,UnitFileState,Type,Names,NeedDaemonReload", srvFile1, srvFile2},
+		{"show", "--property=Id,ActiveState,UnitFileState,Names", sockFile1, timerFile2},
+		{"stop", srvFile1},
+		{"show", "--property=ActiveState", srvFile1},
+		{"start", srvFile1},
+		{"start", sockFile1},
+		{"stop", srvFile2},
+		{"show", "--property=ActiveState", srvFile2},
+		{"start", srvFile2},
+		{"start", timerFile2},
+	})
+
+	// disabled activators are left alone
+	states[sockFile1] = systemdtest.ServiceState{ActiveState: "inactive", UnitFileState: "disabled"}
+	states[timerFile2] = systemdtest.ServiceState{ActiveState: "active", UnitFileState: "enabled"}
+	s.sysdLog = nil
+	c.Assert(wrappers.RestartServices(services, nil, &wrappers.RestartServicesFlags{AlsoEnabledNonActive: true}, progress.Null, s.perfTimings), IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"show", "--property=Id,ActiveState,UnitFileState,Type,Names,NeedDaemonReload", srvFile1, srvFile2},
+		{"show", "--property=Id,ActiveState,UnitFileState,Names", sockFile1, timerFile2},
+		{"stop", srvFile1},
+		{"show", "--property=ActiveState", srvFile1},
+		{"start", srvFile1},
+	})
+}
+
 func (s *servicesTestSuite) TestStopAndDisableServices(c *C) {
 	info := snaptest.MockSnap(c, packageHelloNoSrv+`
  svc1: